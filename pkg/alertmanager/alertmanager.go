@@ -104,6 +104,8 @@ type Config struct {
 	Store             alertstore.AlertStore
 	PersisterConfig   PersisterConfig
 
+	NotificationRetryQueue NotificationRetryQueueConfig
+
 	GrafanaAlertmanagerCompatibility bool
 }
 
@@ -116,6 +118,7 @@ type Alertmanager struct {
 	persister       *statePersister
 	nflog           *nflog.Log
 	silences        *silence.Silences
+	silencer        *indexedSilencer
 	marker          types.Marker
 	alerts          *mem.Alerts
 	dispatcher      *dispatch.Dispatcher
@@ -133,6 +136,11 @@ type Alertmanager struct {
 	emailCfgMtx     sync.RWMutex
 	emailCfg        alertingReceivers.EmailSenderConfig
 
+	// The routing tree and inhibition rules of the current configuration, used to dry-run sample alerts.
+	routingMtx   sync.RWMutex
+	route        *dispatch.Route
+	inhibitRules []*inhibit.InhibitRule
+
 	// usingGrafanaState indicates if the Grafana Alertmanager state is being used.
 	usingGrafanaState atomic.Bool
 
@@ -149,6 +157,9 @@ type Alertmanager struct {
 	configHashMetric prometheus.Gauge
 
 	rateLimitedNotifications *prometheus.CounterVec
+
+	// retryQueue is nil when the notification retry queue is disabled.
+	retryQueue *notificationRetryQueue
 }
 
 var (
@@ -255,6 +266,8 @@ func New(cfg *Config, reg *prometheus.Registry) (*Alertmanager, error) {
 		return nil, fmt.Errorf("failed to create silences: %v", err)
 	}
 
+	am.silencer = newIndexedSilencer(am.silences, am.marker, log.With(am.logger, "component", "silencer"))
+
 	c = am.state.AddState(silencesStateKeyPrefix+cfg.UserID, am.silences, am.registry)
 	am.silences.SetBroadcast(c.Broadcast)
 
@@ -269,6 +282,20 @@ func New(cfg *Config, reg *prometheus.Registry) (*Alertmanager, error) {
 
 	am.pipelineBuilder = notify.NewPipelineBuilder(am.registry, cfg.Features)
 
+	if cfg.NotificationRetryQueue.Enabled {
+		am.retryQueue, err = newNotificationRetryQueue(cfg.NotificationRetryQueue, cfg.TenantDataDir, am.nflog, am.logger, am.registry)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create notification retry queue: %v", err)
+		}
+
+		// Run the notification retry queue in a dedicated goroutine.
+		am.wg.Add(1)
+		go func() {
+			am.retryQueue.run(am.maintenanceStop)
+			am.wg.Done()
+		}()
+	}
+
 	// Run the silences maintenance in a dedicated goroutine.
 	am.wg.Add(1)
 	go func() {
@@ -307,6 +334,8 @@ func New(cfg *Config, reg *prometheus.Registry) (*Alertmanager, error) {
 
 	ui.Register(router, webReload, log.With(am.logger, "component", "ui"))
 	am.mux = am.api.Register(router, am.cfg.ExternalURL.Path)
+	am.mux.Handle("/api/v1/alerts/test", http.HandlerFunc(am.TestAlertsHandler))
+	am.mux.Handle("/api/v1/receivers/test", http.HandlerFunc(am.TestNotificationHandler))
 
 	// Override some extra paths registered in the router (eg. /metrics which by default exposes prometheus.DefaultRegisterer).
 	// Entire router is registered in Mux to "/" path, so there is no conflict with overwriting specific paths.
@@ -502,6 +531,15 @@ func (am *Alertmanager) ApplyConfig(conf *definition.PostableApiAlertingConfig,
 
 	route := dispatch.NewRoute(cfg.Route, nil)
 
+	inhibitRules := make([]*inhibit.InhibitRule, 0, len(conf.InhibitRules))
+	for _, r := range conf.InhibitRules {
+		inhibitRules = append(inhibitRules, inhibit.NewInhibitRule(r))
+	}
+	am.routingMtx.Lock()
+	am.route = route
+	am.inhibitRules = inhibitRules
+	am.routingMtx.Unlock()
+
 	receivers := make([]*nfstatus.Receiver, 0, len(integrationsMap))
 	activeReceivers := alertingNotify.GetActiveReceiversMap(route)
 
@@ -520,7 +558,9 @@ func (am *Alertmanager) ApplyConfig(conf *definition.PostableApiAlertingConfig,
 	am.tmplExternalURL = tmplExternalURL
 	am.templatesMtx.Unlock()
 
-	pipeline := am.pipelineBuilder.New(
+	// The indexed silencer marks the silences applying to the alerts before they go through the pipeline, so that
+	// muting them doesn't require matching them against every silence.
+	pipeline := notify.MultiStage{am.silencer, am.pipelineBuilder.New(
 		baseIntegrationsMap,
 		waitFunc,
 		am.inhibitor,
@@ -528,7 +568,7 @@ func (am *Alertmanager) ApplyConfig(conf *definition.PostableApiAlertingConfig,
 		intervener,
 		am.nflog,
 		am.state,
-	)
+	)}
 	am.lastPipeline = pipeline
 	am.dispatcher = dispatch.NewDispatcher(
 		am.alerts,
@@ -596,7 +636,7 @@ func (am *Alertmanager) buildIntegrationsMap(gCfg *config.GlobalConfig, nc []*de
 	// Create a firewall binded to the per-tenant config.
 	firewallDialer := util_net.NewFirewallDialer(newFirewallDialerConfigProvider(am.cfg.UserID, am.cfg.Limits))
 
-	// Create a function that wraps a notifier with rate limiting.
+	// Create a function that wraps a notifier with rate limiting, and splits the notifications with too many alerts.
 	nw := func(integrationName string, notifier notify.Notifier) notify.Notifier {
 		if am.cfg.Limits != nil {
 			rl := &tenantRateLimits{
@@ -605,11 +645,34 @@ func (am *Alertmanager) buildIntegrationsMap(gCfg *config.GlobalConfig, nc []*de
 				integration: integrationName,
 			}
 
-			return newRateLimitedNotifier(notifier, rl, 10*time.Second, am.rateLimitedNotifications.WithLabelValues(integrationName))
+			// Each split notification counts towards the rate limit.
+			notifier = newRateLimitedNotifier(notifier, rl, 10*time.Second, am.rateLimitedNotifications.WithLabelValues(integrationName))
+			if splitNotificationsIntegrations[integrationName] {
+				notifier = newSplitNotifier(notifier, am.cfg.UserID, am.cfg.Limits)
+			}
 		}
 		return notifier
 	}
 
+	// Create a function that wraps the notifiers of a receiver with rate limiting and the notification retry queue.
+	retryNotifiers := map[string]notify.Notifier{}
+	receiverWrapper := func(receiverName string) func(string, notify.Notifier) notify.Notifier {
+		indexes := map[string]int{}
+
+		return func(integrationName string, notifier notify.Notifier) notify.Notifier {
+			notifier = nw(integrationName, notifier)
+			if am.retryQueue == nil {
+				return notifier
+			}
+
+			idx := indexes[integrationName]
+			indexes[integrationName]++
+
+			retryNotifiers[retryNotifierKey(receiverName, integrationName, idx)] = notifier
+			return am.retryQueue.wrap(receiverName, integrationName, idx, notifier)
+		}
+	}
+
 	emailCfg := alertingReceivers.EmailSenderConfig{
 		AuthPassword:  string(gCfg.SMTPAuthPassword),
 		AuthUser:      gCfg.SMTPAuthUsername,
@@ -644,7 +707,7 @@ func (am *Alertmanager) buildIntegrationsMap(gCfg *config.GlobalConfig, nc []*de
 			}
 			integrations, err = buildGrafanaReceiverIntegrations(emailCfg, alertingNotify.PostableAPIReceiverToAPIReceiver(rcv), gTmpl, am.logger)
 		} else {
-			integrations, err = buildReceiverIntegrations(rcv.Receiver, tmpl, firewallDialer, am.logger, nw)
+			integrations, err = buildReceiverIntegrations(rcv.Receiver, tmpl, firewallDialer, am.logger, receiverWrapper(rcv.Name))
 		}
 		if err != nil {
 			return nil, err
@@ -653,6 +716,7 @@ func (am *Alertmanager) buildIntegrationsMap(gCfg *config.GlobalConfig, nc []*de
 		integrationsMap[rcv.Name] = integrations
 	}
 
+	am.retryQueue.setNotifiers(retryNotifiers)
 	return integrationsMap, nil
 }
 
@@ -78,6 +78,12 @@ type alertmanagerMetrics struct {
 	insertAlertFailures      *prometheus.Desc
 	alertsLimiterAlertsCount *prometheus.Desc
 	alertsLimiterAlertsSize  *prometheus.Desc
+
+	// exported metrics, gathered from the notification retry queue
+	notificationRetryQueueLength  *prometheus.Desc
+	notificationRetryQueueQueued  *prometheus.Desc
+	notificationRetryQueueRetries *prometheus.Desc
+	notificationRetryQueueDropped *prometheus.Desc
 }
 
 func newAlertmanagerMetrics(logger log.Logger) *alertmanagerMetrics {
@@ -271,6 +277,22 @@ func newAlertmanagerMetrics(logger log.Logger) *alertmanagerMetrics {
 			"cortex_alertmanager_alerts_limiter_current_alerts_size_bytes",
 			"Total size of alerts tracked by alerts limiter.",
 			[]string{"user"}, nil),
+		notificationRetryQueueLength: prometheus.NewDesc(
+			"cortex_alertmanager_notification_retry_queue_length",
+			"Number of notifications waiting to be retried.",
+			[]string{"user"}, nil),
+		notificationRetryQueueQueued: prometheus.NewDesc(
+			"cortex_alertmanager_notification_retry_queue_queued_total",
+			"Total number of notifications queued to be retried per integration.",
+			[]string{"user", "integration"}, nil),
+		notificationRetryQueueRetries: prometheus.NewDesc(
+			"cortex_alertmanager_notification_retry_queue_retries_total",
+			"Total number of delivery attempts of queued notifications per integration.",
+			[]string{"user", "integration"}, nil),
+		notificationRetryQueueDropped: prometheus.NewDesc(
+			"cortex_alertmanager_notification_retry_queue_dropped_total",
+			"Total number of queued notifications dropped without being delivered per integration.",
+			[]string{"user", "integration", "reason"}, nil),
 	}
 }
 
@@ -332,6 +354,10 @@ func (m *alertmanagerMetrics) Describe(out chan<- *prometheus.Desc) {
 	out <- m.insertAlertFailures
 	out <- m.alertsLimiterAlertsCount
 	out <- m.alertsLimiterAlertsSize
+	out <- m.notificationRetryQueueLength
+	out <- m.notificationRetryQueueQueued
+	out <- m.notificationRetryQueueRetries
+	out <- m.notificationRetryQueueDropped
 }
 
 func (m *alertmanagerMetrics) Collect(out chan<- prometheus.Metric) {
@@ -391,4 +417,9 @@ func (m *alertmanagerMetrics) Collect(out chan<- prometheus.Metric) {
 	data.SendSumOfCountersPerTenant(out, m.insertAlertFailures, "alertmanager_alerts_insert_limited_total")
 	data.SendSumOfGaugesPerTenant(out, m.alertsLimiterAlertsCount, "alertmanager_alerts_limiter_current_alerts")
 	data.SendSumOfGaugesPerTenant(out, m.alertsLimiterAlertsSize, "alertmanager_alerts_limiter_current_alerts_size_bytes")
+
+	data.SendSumOfGaugesPerTenant(out, m.notificationRetryQueueLength, "alertmanager_notification_retry_queue_length")
+	data.SendSumOfCountersPerTenant(out, m.notificationRetryQueueQueued, "alertmanager_notification_retry_queue_queued_total", dskit_metrics.WithLabels("integration"), dskit_metrics.WithSkipZeroValueMetrics)
+	data.SendSumOfCountersPerTenant(out, m.notificationRetryQueueRetries, "alertmanager_notification_retry_queue_retries_total", dskit_metrics.WithLabels("integration"), dskit_metrics.WithSkipZeroValueMetrics)
+	data.SendSumOfCountersPerTenant(out, m.notificationRetryQueueDropped, "alertmanager_notification_retry_queue_dropped_total", dskit_metrics.WithLabels("integration", "reason"), dskit_metrics.WithSkipZeroValueMetrics)
 }
@@ -0,0 +1,230 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package alertmanager
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/alertmanager/dispatch"
+	"github.com/prometheus/alertmanager/inhibit"
+	"github.com/prometheus/alertmanager/silence"
+	"github.com/prometheus/alertmanager/types"
+	"github.com/prometheus/common/model"
+
+	util_log "github.com/grafana/mimir/pkg/util/log"
+)
+
+const (
+	// testAlertSourceRequest and testAlertSourceActive tell whether an inhibiting alert is one of the
+	// sample alerts of the request, or an alert currently active in the tenant's Alertmanager.
+	testAlertSourceRequest = "request"
+	testAlertSourceActive  = "active"
+)
+
+// TestAlertsRequest is the payload of the API used to dry-run a set of sample alerts against the
+// tenant's Alertmanager configuration.
+type TestAlertsRequest struct {
+	Alerts []TestAlert `json:"alerts"`
+}
+
+// TestAlert is a sample alert.
+type TestAlert struct {
+	Labels model.LabelSet `json:"labels"`
+}
+
+// TestAlertsResponse is the response of the API used to dry-run a set of sample alerts.
+type TestAlertsResponse struct {
+	Alerts []TestAlertResult `json:"alerts"`
+}
+
+// TestAlertResult reports how a sample alert would be handled by the tenant's Alertmanager.
+type TestAlertResult struct {
+	Labels model.LabelSet `json:"labels"`
+	// Routes are the routes of the routing tree matching the alert.
+	Routes []TestAlertRoute `json:"routes"`
+	// InhibitedBy are the inhibitions muting the alert. The alert is not inhibited if empty.
+	InhibitedBy []TestAlertInhibition `json:"inhibited_by,omitempty"`
+	// SilencedBy are the IDs of the active silences muting the alert. The alert is not silenced if empty.
+	SilencedBy []string `json:"silenced_by,omitempty"`
+}
+
+// TestAlertRoute is a route matching a sample alert.
+type TestAlertRoute struct {
+	// ID uniquely identifies the route in the routing tree.
+	ID string `json:"id"`
+	// Matchers are the matchers of the route and all its parents, from the root of the routing tree.
+	Matchers            string   `json:"matchers"`
+	Receiver            string   `json:"receiver"`
+	GroupBy             []string `json:"group_by,omitempty"`
+	Continue            bool     `json:"continue"`
+	MuteTimeIntervals   []string `json:"mute_time_intervals,omitempty"`
+	ActiveTimeIntervals []string `json:"active_time_intervals,omitempty"`
+}
+
+// TestAlertInhibition is an inhibition muting a sample alert.
+type TestAlertInhibition struct {
+	// Rule is the index of the inhibition rule in the tenant's Alertmanager configuration.
+	Rule           int      `json:"rule"`
+	SourceMatchers string   `json:"source_matchers"`
+	TargetMatchers string   `json:"target_matchers"`
+	Equal          []string `json:"equal,omitempty"`
+	// SourceLabels are the labels of the alert inhibiting the sample alert, which is either one of the sample
+	// alerts (source "request") or an alert currently active in the Alertmanager (source "active").
+	SourceLabels model.LabelSet `json:"source_labels"`
+	Source       string         `json:"source"`
+}
+
+// TestAlertsHandler reports, for each of the sample alerts of the request, which routes of the current
+// configuration match the alert and whether it would be inhibited or silenced. The alerts are not sent to
+// the Alertmanager, and no notification is sent.
+func (am *Alertmanager) TestAlertsHandler(w http.ResponseWriter, r *http.Request) {
+	logger := util_log.WithContext(r.Context(), am.logger)
+
+	req := TestAlertsRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("error unmarshalling test alerts JSON: %s", err.Error()), http.StatusBadRequest)
+		return
+	}
+	for _, a := range req.Alerts {
+		if err := a.Labels.Validate(); err != nil {
+			http.Error(w, fmt.Sprintf("invalid alert labels %s: %s", a.Labels.String(), err.Error()), http.StatusBadRequest)
+			return
+		}
+	}
+
+	am.routingMtx.RLock()
+	route, inhibitRules := am.route, am.inhibitRules
+	am.routingMtx.RUnlock()
+
+	if route == nil {
+		http.Error(w, "the Alertmanager is not configured", http.StatusPreconditionFailed)
+		return
+	}
+
+	// Alerts currently active in the Alertmanager can inhibit the sample alerts too.
+	var active []model.LabelSet
+	if len(inhibitRules) > 0 {
+		it := am.alerts.GetPending()
+		for a := range it.Next() {
+			if !a.Resolved() {
+				active = append(active, a.Labels)
+			}
+		}
+		it.Close()
+		if err := it.Err(); err != nil {
+			level.Error(logger).Log("msg", "failed to list the active alerts", "err", err)
+			http.Error(w, fmt.Sprintf("error listing the active alerts: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	res := TestAlertsResponse{Alerts: make([]TestAlertResult, 0, len(req.Alerts))}
+	for _, a := range req.Alerts {
+		result := TestAlertResult{
+			Labels: a.Labels,
+			Routes: testAlertRoutes(route, a.Labels),
+		}
+
+		for idx, rule := range inhibitRules {
+			result.InhibitedBy = append(result.InhibitedBy, testAlertInhibitions(idx, rule, a.Labels, req.Alerts, active)...)
+		}
+
+		silences, _, err := am.silences.Query(silence.QState(types.SilenceStateActive), silence.QMatches(a.Labels))
+		if err != nil {
+			level.Error(logger).Log("msg", "failed to query the silences", "err", err)
+			http.Error(w, fmt.Sprintf("error querying the silences: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+		for _, s := range silences {
+			result.SilencedBy = append(result.SilencedBy, s.Id)
+		}
+		sort.Strings(result.SilencedBy)
+
+		res.Alerts = append(res.Alerts, result)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(res); err != nil {
+		level.Error(logger).Log("msg", "failed to write the test alerts response", "err", err)
+	}
+}
+
+func testAlertRoutes(route *dispatch.Route, lset model.LabelSet) []TestAlertRoute {
+	matches := route.Match(lset)
+	routes := make([]TestAlertRoute, 0, len(matches))
+	for _, r := range matches {
+		groupBy := make([]string, 0, len(r.RouteOpts.GroupBy))
+		for ln := range r.RouteOpts.GroupBy {
+			groupBy = append(groupBy, string(ln))
+		}
+		sort.Strings(groupBy)
+		if r.RouteOpts.GroupByAll {
+			groupBy = []string{"..."}
+		}
+
+		routes = append(routes, TestAlertRoute{
+			ID:                  r.ID(),
+			Matchers:            r.Key(),
+			Receiver:            r.RouteOpts.Receiver,
+			GroupBy:             groupBy,
+			Continue:            r.Continue,
+			MuteTimeIntervals:   r.RouteOpts.MuteTimeIntervals,
+			ActiveTimeIntervals: r.RouteOpts.ActiveTimeIntervals,
+		})
+	}
+	return routes
+}
+
+// testAlertInhibitions returns the alerts, among the sample and the active ones, inhibiting the target alert
+// according to the rule. It follows the same logic as the upstream inhibitor.
+func testAlertInhibitions(idx int, rule *inhibit.InhibitRule, target model.LabelSet, sample []TestAlert, active []model.LabelSet) []TestAlertInhibition {
+	if !rule.TargetMatchers.Matches(target) {
+		return nil
+	}
+
+	// If the target alert also matches the source matchers, the alerts matching both the source and
+	// target matchers can't inhibit it, in order to prevent alerts from inhibiting themselves.
+	excludeTwoSidedMatch := rule.SourceMatchers.Matches(target)
+
+	equal := make([]string, 0, len(rule.Equal))
+	for ln := range rule.Equal {
+		equal = append(equal, string(ln))
+	}
+	sort.Strings(equal)
+
+	var inhibitions []TestAlertInhibition
+	check := func(source model.LabelSet, kind string) {
+		if !rule.SourceMatchers.Matches(source) {
+			return
+		}
+		if excludeTwoSidedMatch && rule.TargetMatchers.Matches(source) {
+			return
+		}
+		for ln := range rule.Equal {
+			if source[ln] != target[ln] {
+				return
+			}
+		}
+
+		inhibitions = append(inhibitions, TestAlertInhibition{
+			Rule:           idx,
+			SourceMatchers: rule.SourceMatchers.String(),
+			TargetMatchers: rule.TargetMatchers.String(),
+			Equal:          equal,
+			SourceLabels:   source,
+			Source:         kind,
+		})
+	}
+
+	for _, a := range sample {
+		check(a.Labels, testAlertSourceRequest)
+	}
+	for _, lset := range active {
+		check(lset, testAlertSourceActive)
+	}
+	return inhibitions
+}
@@ -0,0 +1,163 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package alertmanager
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/alerting/definition"
+	alertingTemplates "github.com/grafana/alerting/templates"
+	"github.com/grafana/dskit/test"
+	"github.com/prometheus/alertmanager/featurecontrol"
+	"github.com/prometheus/alertmanager/silence/silencepb"
+	"github.com/prometheus/alertmanager/types"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAlertmanager_TestAlertsHandler(t *testing.T) {
+	am, err := New(&Config{
+		UserID:            "test",
+		Logger:            log.NewNopLogger(),
+		Limits:            &mockAlertManagerLimits{maxDispatcherAggregationGroups: 10},
+		Features:          featurecontrol.NoopFlags{},
+		TenantDataDir:     t.TempDir(),
+		ExternalURL:       &url.URL{Path: "/am"},
+		ShardingEnabled:   true,
+		Store:             prepareInMemoryAlertStore(),
+		Replicator:        &stubReplicator{},
+		ReplicationFactor: 1,
+		PersisterConfig:   PersisterConfig{Interval: time.Hour},
+	}, prometheus.NewPedanticRegistry())
+	require.NoError(t, err)
+	defer am.StopAndWait()
+
+	testAlerts := func(body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		am.mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/alerts/test", strings.NewReader(body)))
+		return rec
+	}
+
+	// The Alertmanager is not configured yet.
+	require.Equal(t, http.StatusPreconditionFailed, testAlerts(`{"alerts": []}`).Code)
+
+	cfgRaw := `receivers:
+- name: 'default'
+- name: 'pager'
+- name: 'database'
+
+route:
+  receiver: 'default'
+  group_by: ['alertname']
+  routes:
+  - receiver: 'pager'
+    matchers: ['severity="critical"']
+    continue: true
+  - receiver: 'database'
+    matchers: ['team="db"']
+    group_by: ['...']
+
+inhibit_rules:
+- source_matchers: ['severity="critical"']
+  target_matchers: ['severity="warning"']
+  equal: ['cluster']`
+
+	cfg, err := definition.LoadCompat([]byte(cfgRaw))
+	require.NoError(t, err)
+	require.NoError(t, am.ApplyConfig(cfg, []alertingTemplates.TemplateDefinition{}, cfgRaw, &url.URL{}, nil))
+
+	// An alert currently active in the Alertmanager.
+	now := time.Now()
+	require.NoError(t, am.alerts.Put(&types.Alert{
+		Alert: model.Alert{
+			Labels:   model.LabelSet{"alertname": "NodeDown", "severity": "critical", "cluster": "eu"},
+			StartsAt: now,
+			EndsAt:   now.Add(time.Hour),
+		},
+		UpdatedAt: now,
+	}))
+
+	// Wait until the active alert has been processed by the inhibitor, so that it's running
+	// and gets stopped at the end of the test.
+	test.Poll(t, 5*time.Second, true, func() interface{} {
+		return am.inhibitor.Mutes(model.LabelSet{"severity": "warning", "cluster": "eu"})
+	})
+
+	// An active silence.
+	am.silences.SetBroadcast(func(_ []byte) {})
+	sil := &silencepb.Silence{
+		Matchers: []*silencepb.Matcher{{Name: "alertname", Pattern: "Noisy"}},
+		StartsAt: now,
+		EndsAt:   now.Add(time.Hour),
+	}
+	require.NoError(t, am.silences.Set(sil))
+
+	t.Run("invalid request", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, testAlerts(`{"alerts": [`).Code)
+		assert.Equal(t, http.StatusBadRequest, testAlerts(`{"alerts": [{"labels": {"0invalid": "value"}}]}`).Code)
+	})
+
+	t.Run("routing, inhibition and silencing", func(t *testing.T) {
+		rec := testAlerts(`{"alerts": [
+			{"labels": {"alertname": "DBDown", "severity": "critical", "team": "db", "cluster": "us"}},
+			{"labels": {"alertname": "HighLatency", "severity": "warning", "cluster": "us"}},
+			{"labels": {"alertname": "HighLatency", "severity": "warning", "cluster": "eu"}},
+			{"labels": {"alertname": "Noisy"}}
+		]}`)
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		res := TestAlertsResponse{}
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+		require.Len(t, res.Alerts, 4)
+
+		receivers := func(r TestAlertResult) []string {
+			var names []string
+			for _, route := range r.Routes {
+				names = append(names, route.Receiver)
+			}
+			return names
+		}
+
+		// The critical alert matches the first route, which continues to the second one.
+		dbDown := res.Alerts[0]
+		assert.Equal(t, []string{"pager", "database"}, receivers(dbDown))
+		assert.Equal(t, []string{"alertname"}, dbDown.Routes[0].GroupBy)
+		assert.Equal(t, []string{"..."}, dbDown.Routes[1].GroupBy)
+		assert.Empty(t, dbDown.InhibitedBy)
+		assert.Empty(t, dbDown.SilencedBy)
+
+		// The warning alert is inhibited by the critical sample alert in the same cluster.
+		usLatency := res.Alerts[1]
+		assert.Equal(t, []string{"default"}, receivers(usLatency))
+		require.Len(t, usLatency.InhibitedBy, 1)
+		assert.Equal(t, TestAlertInhibition{
+			Rule:           0,
+			SourceMatchers: `{severity="critical"}`,
+			TargetMatchers: `{severity="warning"}`,
+			Equal:          []string{"cluster"},
+			SourceLabels:   dbDown.Labels,
+			Source:         testAlertSourceRequest,
+		}, usLatency.InhibitedBy[0])
+
+		// The warning alert is inhibited by the critical alert active in the Alertmanager in the same cluster.
+		euLatency := res.Alerts[2]
+		require.Len(t, euLatency.InhibitedBy, 1)
+		assert.Equal(t, testAlertSourceActive, euLatency.InhibitedBy[0].Source)
+		assert.Equal(t, model.LabelValue("NodeDown"), euLatency.InhibitedBy[0].SourceLabels["alertname"])
+
+		// The noisy alert is silenced.
+		noisy := res.Alerts[3]
+		assert.Equal(t, []string{"default"}, receivers(noisy))
+		assert.Empty(t, noisy.InhibitedBy)
+		assert.Equal(t, []string{sil.Id}, noisy.SilencedBy)
+	})
+}
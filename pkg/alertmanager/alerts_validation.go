@@ -0,0 +1,39 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package alertmanager
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/grafana/mimir/pkg/util/validation"
+)
+
+// validatePostedAlerts validates the labels of the alerts posted to the Alertmanager API according
+// to the tenant's alert label validation scheme. Alerts sent by the ruler are received through the same API.
+func (am *MultitenantAlertmanager) validatePostedAlerts(userID string, body []byte) error {
+	var alerts []struct {
+		Labels map[string]string `json:"labels"`
+	}
+	if err := json.Unmarshal(body, &alerts); err != nil {
+		// Malformed requests are rejected by the Alertmanager API.
+		return nil
+	}
+
+	scheme := am.limits.AlertmanagerAlertLabelValidationScheme(userID)
+	for i, alert := range alerts {
+		names := make([]string, 0, len(alert.Labels))
+		for name := range alert.Labels {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			if err := validation.ValidateAlertLabel(name, alert.Labels[name], scheme); err != nil {
+				return fmt.Errorf("invalid alert at index %d: %w", i, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package alertmanager
+
+import (
+	"testing"
+
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMultitenantAlertmanager_validatePostedAlerts(t *testing.T) {
+	for name, tc := range map[string]struct {
+		body        string
+		scheme      model.ValidationScheme
+		expectedErr string
+	}{
+		"valid alerts with legacy scheme": {
+			body:   `[{"labels":{"alertname":"a","job":"api"}},{"labels":{"alertname":"b"}}]`,
+			scheme: model.LegacyValidation,
+		},
+		"UTF-8 label name with legacy scheme": {
+			body:        `[{"labels":{"alertname":"a"}},{"labels":{"alertname":"b","service.name":"api"}}]`,
+			scheme:      model.LegacyValidation,
+			expectedErr: `invalid alert at index 1: invalid label name "service.name"`,
+		},
+		"UTF-8 label name with UTF-8 scheme": {
+			body:   `[{"labels":{"alertname":"a","service.name":"api"}}]`,
+			scheme: model.UTF8Validation,
+		},
+		"empty label name with UTF-8 scheme": {
+			body:        `[{"labels":{"alertname":"a","":"api"}}]`,
+			scheme:      model.UTF8Validation,
+			expectedErr: `invalid alert at index 0: invalid label name ""`,
+		},
+		"malformed body is left to the Alertmanager API": {
+			body:   `{"labels":`,
+			scheme: model.LegacyValidation,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			am := &MultitenantAlertmanager{
+				limits: &mockAlertManagerLimits{alertLabelValidationScheme: tc.scheme},
+			}
+
+			err := am.validatePostedAlerts("user-1", []byte(tc.body))
+			if tc.expectedErr == "" {
+				require.NoError(t, err)
+			} else {
+				require.EqualError(t, err, tc.expectedErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package alertspb
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// AlertConfigVersion describes a version of a tenant's Alertmanager configuration.
+type AlertConfigVersion struct {
+	// ID of the version. IDs sort in the order the versions have been created.
+	ID string `json:"id" yaml:"id"`
+
+	// CreatedAt is when the version has been created.
+	CreatedAt time.Time `json:"created_at" yaml:"created_at"`
+
+	// CreatedByUnverified is who created the version, as declared by the client which stored the configuration.
+	// It's not verified against the authenticated identity, so it must not be trusted for auditing.
+	CreatedByUnverified string `json:"created_by_unverified,omitempty" yaml:"created_by_unverified,omitempty"`
+
+	// Hash of the configuration, computed by HashAlertConfig.
+	Hash string `json:"hash" yaml:"hash"`
+}
+
+// AlertConfigVersionDesc is a version of a tenant's Alertmanager configuration, along with the configuration.
+type AlertConfigVersionDesc struct {
+	AlertConfigVersion
+
+	Config AlertConfigDesc `json:"config"`
+}
+
+// NewAlertConfigVersion returns a new version of the input Alertmanager configuration, created at the given time.
+func NewAlertConfigVersion(cfg AlertConfigDesc, createdBy string, createdAt time.Time) AlertConfigVersionDesc {
+	return AlertConfigVersionDesc{
+		AlertConfigVersion: AlertConfigVersion{
+			// The zero-padded timestamp guarantees the IDs sort lexicographically by creation time.
+			ID:                  fmt.Sprintf("%020d", createdAt.UnixNano()),
+			CreatedAt:           createdAt.UTC(),
+			CreatedByUnverified: createdBy,
+			Hash:                HashAlertConfig(cfg),
+		},
+		Config: cfg,
+	}
+}
+
+// HashAlertConfig returns the hash of the Alertmanager configuration and templates of the input config.
+// The hash doesn't depend on the order of the templates.
+func HashAlertConfig(cfg AlertConfigDesc) string {
+	templates := make([]*TemplateDesc, len(cfg.Templates))
+	copy(templates, cfg.Templates)
+	sort.Slice(templates, func(i, j int) bool {
+		return templates[i].Filename < templates[j].Filename
+	})
+
+	h := sha256.New()
+	_, _ = h.Write([]byte(cfg.RawConfig))
+	for _, t := range templates {
+		_, _ = h.Write([]byte{0})
+		_, _ = h.Write([]byte(t.Filename))
+		_, _ = h.Write([]byte{0})
+		_, _ = h.Write([]byte(t.Body))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
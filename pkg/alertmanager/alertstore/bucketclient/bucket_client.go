@@ -8,7 +8,10 @@ package bucketclient
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"io"
+	"slices"
+	"sort"
 	"strings"
 	"sync"
 
@@ -39,6 +42,13 @@ const (
 	//		grafana_alertmanager/<user-id>/<object>
 	GrafanaAlertmanagerPrefix = "grafana_alertmanager"
 
+	// AlertConfigVersionsPrefix is the bucket prefix under which the versions of the tenants alertmanager configs are stored.
+	// Note that objects stored under this prefix follow the pattern:
+	//     alertmanager_config_versions/<user-id>/<version-id>
+	// along with the index of the versions, stored at:
+	//     alertmanager_config_versions/<user-id>/index.json
+	AlertConfigVersionsPrefix = "alertmanager_config_versions"
+
 	// The name of alertmanager full state objects (notification log + silences).
 	fullStateName = "fullstate"
 
@@ -47,14 +57,25 @@ const (
 
 	grafanaConfigName = "grafana_config"
 	grafanaStateName  = "grafana_fullstate"
+
+	// The name of the index of the alertmanager config versions. Version IDs are made of digits only,
+	// so the index name can't clash with a version.
+	alertConfigVersionsIndexName = "index.json"
 )
 
+// alertConfigVersionsIndex is the index of the versions of a tenant's alertmanager config. It holds the metadata
+// of the versions, so that they can be listed without fetching every version.
+type alertConfigVersionsIndex struct {
+	Versions []alertspb.AlertConfigVersion `json:"versions"`
+}
+
 // BucketAlertStore is used to support the AlertStore interface against an object storage backend. It is implemented
 // using the Thanos objstore.Bucket interface
 type BucketAlertStore struct {
 	alertsBucket    objstore.Bucket
 	amBucket        objstore.Bucket
 	grafanaAMBucket objstore.Bucket
+	versionsBucket  objstore.Bucket
 
 	cfgProvider     bucket.TenantConfigProvider
 	fetchGrafanaCfg bool
@@ -71,6 +92,7 @@ func NewBucketAlertStore(cfg BucketAlertStoreConfig, bkt objstore.Bucket, cfgPro
 		alertsBucket:    bucket.NewPrefixedBucketClient(bkt, AlertsPrefix),
 		amBucket:        bucket.NewPrefixedBucketClient(bkt, AlertmanagerPrefix),
 		grafanaAMBucket: bucket.NewPrefixedBucketClient(bkt, GrafanaAlertmanagerPrefix),
+		versionsBucket:  bucket.NewPrefixedBucketClient(bkt, AlertConfigVersionsPrefix),
 		cfgProvider:     cfgProvider,
 		fetchGrafanaCfg: cfg.FetchGrafanaConfig,
 		logger:          logger,
@@ -165,6 +187,74 @@ func (s *BucketAlertStore) DeleteAlertConfig(ctx context.Context, userID string)
 	userBkt := s.getUserBucket(userID)
 
 	err := userBkt.Delete(ctx, userID)
+	if err != nil && !userBkt.IsObjNotFoundErr(err) {
+		return err
+	}
+
+	// The versions of the config may contain receivers credentials too, so they're deleted along with the config.
+	if _, err := bucket.DeletePrefix(ctx, s.getAlertConfigVersionsUserBucket(userID), "", s.logger); err != nil {
+		return errors.Wrap(err, "failed to delete alertmanager config versions")
+	}
+	return nil
+}
+
+// ListAlertConfigVersions implements alertstore.AlertStore.
+func (s *BucketAlertStore) ListAlertConfigVersions(ctx context.Context, userID string) ([]alertspb.AlertConfigVersion, error) {
+	index, err := s.getAlertConfigVersionsIndex(ctx, s.getAlertConfigVersionsUserBucket(userID))
+	if err != nil {
+		return nil, err
+	}
+
+	// Sort the versions from the most recent one.
+	versions := index.Versions
+	sort.Slice(versions, func(i, j int) bool {
+		return versions[i].ID > versions[j].ID
+	})
+	return versions, nil
+}
+
+// GetAlertConfigVersion implements alertstore.AlertStore.
+func (s *BucketAlertStore) GetAlertConfigVersion(ctx context.Context, userID, versionID string) (alertspb.AlertConfigVersionDesc, error) {
+	userBkt := s.getAlertConfigVersionsUserBucket(userID)
+
+	version, err := s.getAlertConfigVersion(ctx, userBkt, versionID)
+	if userBkt.IsObjNotFoundErr(err) {
+		return version, alertspb.ErrNotFound
+	}
+	return version, err
+}
+
+// SetAlertConfigVersion implements alertstore.AlertStore.
+func (s *BucketAlertStore) SetAlertConfigVersion(ctx context.Context, version alertspb.AlertConfigVersionDesc) error {
+	versionBytes, err := json.Marshal(version)
+	if err != nil {
+		return err
+	}
+
+	userBkt := s.getAlertConfigVersionsUserBucket(version.Config.User)
+	if err := userBkt.Upload(ctx, version.ID, bytes.NewBuffer(versionBytes)); err != nil {
+		return err
+	}
+
+	// The index is updated after the version has been stored, so that it never lists a version which doesn't exist.
+	return s.updateAlertConfigVersionsIndex(ctx, userBkt, func(versions []alertspb.AlertConfigVersion) []alertspb.AlertConfigVersion {
+		return append(removeAlertConfigVersion(versions, version.ID), version.AlertConfigVersion)
+	})
+}
+
+// DeleteAlertConfigVersion implements alertstore.AlertStore.
+func (s *BucketAlertStore) DeleteAlertConfigVersion(ctx context.Context, userID, versionID string) error {
+	userBkt := s.getAlertConfigVersionsUserBucket(userID)
+
+	// The version is removed from the index before being deleted, so that the index never lists a version which doesn't exist.
+	err := s.updateAlertConfigVersionsIndex(ctx, userBkt, func(versions []alertspb.AlertConfigVersion) []alertspb.AlertConfigVersion {
+		return removeAlertConfigVersion(versions, versionID)
+	})
+	if err != nil {
+		return err
+	}
+
+	err = userBkt.Delete(ctx, versionID)
 	if userBkt.IsObjNotFoundErr(err) {
 		return nil
 	}
@@ -293,6 +383,69 @@ func (s *BucketAlertStore) getGrafanaAlertConfig(ctx context.Context, userID str
 	return config, err
 }
 
+func (s *BucketAlertStore) getAlertConfigVersion(ctx context.Context, userBkt objstore.Bucket, versionID string) (alertspb.AlertConfigVersionDesc, error) {
+	version := alertspb.AlertConfigVersionDesc{}
+
+	readCloser, err := userBkt.Get(ctx, versionID)
+	if err != nil {
+		return version, err
+	}
+
+	defer runutil.CloseWithLogOnErr(s.logger, readCloser, "close bucket reader")
+
+	if err := json.NewDecoder(readCloser).Decode(&version); err != nil {
+		return version, errors.Wrapf(err, "failed to deserialize alertmanager config version %s", versionID)
+	}
+	return version, nil
+}
+
+func (s *BucketAlertStore) getAlertConfigVersionsIndex(ctx context.Context, userBkt objstore.Bucket) (alertConfigVersionsIndex, error) {
+	index := alertConfigVersionsIndex{}
+
+	readCloser, err := userBkt.Get(ctx, alertConfigVersionsIndexName)
+	if userBkt.IsObjNotFoundErr(err) {
+		return index, nil
+	}
+	if err != nil {
+		return index, err
+	}
+
+	defer runutil.CloseWithLogOnErr(s.logger, readCloser, "close bucket reader")
+
+	if err := json.NewDecoder(readCloser).Decode(&index); err != nil {
+		return index, errors.Wrap(err, "failed to deserialize alertmanager config versions index")
+	}
+	return index, nil
+}
+
+// updateAlertConfigVersionsIndex replaces the versions listed in the index with the ones returned by update.
+// The index isn't updated if the versions didn't change.
+func (s *BucketAlertStore) updateAlertConfigVersionsIndex(ctx context.Context, userBkt objstore.Bucket, update func([]alertspb.AlertConfigVersion) []alertspb.AlertConfigVersion) error {
+	index, err := s.getAlertConfigVersionsIndex(ctx, userBkt)
+	if err != nil {
+		return err
+	}
+
+	versions := update(slices.Clone(index.Versions))
+	if slices.Equal(versions, index.Versions) {
+		return nil
+	}
+	index.Versions = versions
+
+	indexBytes, err := json.Marshal(index)
+	if err != nil {
+		return err
+	}
+	return userBkt.Upload(ctx, alertConfigVersionsIndexName, bytes.NewBuffer(indexBytes))
+}
+
+// removeAlertConfigVersion returns the input versions without the one with the given ID.
+func removeAlertConfigVersion(versions []alertspb.AlertConfigVersion, versionID string) []alertspb.AlertConfigVersion {
+	return slices.DeleteFunc(versions, func(v alertspb.AlertConfigVersion) bool {
+		return v.ID == versionID
+	})
+}
+
 func (s *BucketAlertStore) get(ctx context.Context, bkt objstore.Bucket, name string, msg proto.Message) error {
 	readCloser, err := bkt.Get(ctx, name)
 	if err != nil {
@@ -323,6 +476,10 @@ func (s *BucketAlertStore) getAlertmanagerUserBucket(userID string) objstore.Buc
 	return bucket.NewUserBucketClient(userID, s.amBucket, s.cfgProvider).WithExpectedErrs(s.amBucket.IsObjNotFoundErr)
 }
 
+func (s *BucketAlertStore) getAlertConfigVersionsUserBucket(userID string) objstore.Bucket {
+	return bucket.NewUserBucketClient(userID, s.versionsBucket, s.cfgProvider).WithExpectedErrs(s.versionsBucket.IsObjNotFoundErr)
+}
+
 func (s *BucketAlertStore) getGrafanaAlertmanagerUserBucket(userID string) objstore.Bucket {
 	return bucket.NewUserBucketClient(userID, s.grafanaAMBucket, s.cfgProvider).WithExpectedErrs(s.amBucket.IsObjNotFoundErr)
 }
@@ -25,6 +25,7 @@ const (
 var (
 	errReadOnly              = errors.New("local alertmanager config storage is read-only")
 	errState                 = errors.New("local alertmanager storage does not support state persistency")
+	errVersions              = errors.New("local alertmanager storage does not support config versions")
 	errGrafanaStateAndConfig = errors.New("local alertmanager storage does not support Grafana configuration endpoints")
 )
 
@@ -118,6 +119,26 @@ func (f *Store) DeleteAlertConfig(_ context.Context, _ string) error {
 	return errReadOnly
 }
 
+// ListAlertConfigVersions implements alertstore.AlertStore.
+func (f *Store) ListAlertConfigVersions(_ context.Context, _ string) ([]alertspb.AlertConfigVersion, error) {
+	return nil, errVersions
+}
+
+// GetAlertConfigVersion implements alertstore.AlertStore.
+func (f *Store) GetAlertConfigVersion(_ context.Context, _, _ string) (alertspb.AlertConfigVersionDesc, error) {
+	return alertspb.AlertConfigVersionDesc{}, errVersions
+}
+
+// SetAlertConfigVersion implements alertstore.AlertStore.
+func (f *Store) SetAlertConfigVersion(_ context.Context, _ alertspb.AlertConfigVersionDesc) error {
+	return errVersions
+}
+
+// DeleteAlertConfigVersion implements alertstore.AlertStore.
+func (f *Store) DeleteAlertConfigVersion(_ context.Context, _, _ string) error {
+	return errVersions
+}
+
 func (f *Store) GetGrafanaAlertConfig(_ context.Context, _ string) (alertspb.GrafanaAlertConfigDesc, error) {
 	return alertspb.GrafanaAlertConfigDesc{}, errGrafanaStateAndConfig
 }
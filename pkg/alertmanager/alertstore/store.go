@@ -34,10 +34,24 @@ type AlertStore interface {
 	// SetAlertConfig stores the alertmanager configuration for a user.
 	SetAlertConfig(ctx context.Context, cfg alertspb.AlertConfigDesc) error
 
-	// DeleteAlertConfig deletes the alertmanager configuration for a user.
+	// DeleteAlertConfig deletes the alertmanager configuration for a user, along with its versions.
 	// If configuration for the user doesn't exist, no error is reported.
 	DeleteAlertConfig(ctx context.Context, user string) error
 
+	// ListAlertConfigVersions returns the versions of the alertmanager configuration for a user,
+	// sorted from the most recent one.
+	ListAlertConfigVersions(ctx context.Context, user string) ([]alertspb.AlertConfigVersion, error)
+
+	// GetAlertConfigVersion returns a version of the alertmanager configuration for a user.
+	GetAlertConfigVersion(ctx context.Context, user, versionID string) (alertspb.AlertConfigVersionDesc, error)
+
+	// SetAlertConfigVersion stores a version of the alertmanager configuration for a user.
+	SetAlertConfigVersion(ctx context.Context, version alertspb.AlertConfigVersionDesc) error
+
+	// DeleteAlertConfigVersion deletes a version of the alertmanager configuration for a user.
+	// If the version doesn't exist, no error is reported.
+	DeleteAlertConfigVersion(ctx context.Context, user, versionID string) error
+
 	// GetGrafanaAlertConfig returns the Grafana Alertmanager configuration for a user.
 	GetGrafanaAlertConfig(ctx context.Context, user string) (alertspb.GrafanaAlertConfigDesc, error)
 
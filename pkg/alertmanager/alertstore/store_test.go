@@ -7,6 +7,7 @@ package alertstore
 
 import (
 	"context"
+	"strings"
 	"testing"
 	"time"
 
@@ -191,9 +192,11 @@ func TestAlertStore_DeleteAlertConfig(t *testing.T) {
 	user1Cfg := alertspb.AlertConfigDesc{User: "user-1", RawConfig: "content-1"}
 	user2Cfg := alertspb.AlertConfigDesc{User: "user-2", RawConfig: "content-2"}
 
-	// Upload the config for 2 users.
+	// Upload the config for 2 users, along with a version of it.
 	require.NoError(t, store.SetAlertConfig(ctx, user1Cfg))
 	require.NoError(t, store.SetAlertConfig(ctx, user2Cfg))
+	require.NoError(t, store.SetAlertConfigVersion(ctx, alertspb.NewAlertConfigVersion(user1Cfg, "", time.Unix(10, 0))))
+	require.NoError(t, store.SetAlertConfigVersion(ctx, alertspb.NewAlertConfigVersion(user2Cfg, "", time.Unix(10, 0))))
 
 	// Ensure the config has been correctly uploaded.
 	config, err := store.GetAlertConfig(ctx, "user-1")
@@ -207,7 +210,7 @@ func TestAlertStore_DeleteAlertConfig(t *testing.T) {
 	// Delete the config for user-1.
 	require.NoError(t, store.DeleteAlertConfig(ctx, "user-1"))
 
-	// Ensure the correct config has been deleted.
+	// Ensure the correct config has been deleted, along with its versions.
 	_, err = store.GetAlertConfig(ctx, "user-1")
 	assert.Equal(t, alertspb.ErrNotFound, err)
 
@@ -215,6 +218,17 @@ func TestAlertStore_DeleteAlertConfig(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, user2Cfg, config)
 
+	var objects []string
+	require.NoError(t, bucket.Iter(ctx, "alertmanager_config_versions/user-1/", func(name string) error {
+		objects = append(objects, name)
+		return nil
+	}))
+	assert.Empty(t, objects)
+
+	versions, err := store.ListAlertConfigVersions(ctx, "user-2")
+	require.NoError(t, err)
+	assert.Len(t, versions, 1)
+
 	// Delete again (should be idempotent).
 	require.NoError(t, store.DeleteAlertConfig(ctx, "user-1"))
 }
@@ -430,3 +444,69 @@ func TestBucketAlertStore_GetSetDeleteGrafanaAlertConfig(t *testing.T) {
 		require.NoError(t, store.DeleteGrafanaAlertConfig(ctx, "user-1"))
 	}
 }
+
+func TestBucketAlertStore_GetSetDeleteAlertConfigVersions(t *testing.T) {
+	bucket := objstore.NewInMemBucket()
+	store := bucketclient.NewBucketAlertStore(bucketclient.BucketAlertStoreConfig{}, bucket, nil, log.NewNopLogger())
+	ctx := context.Background()
+
+	version1 := alertspb.NewAlertConfigVersion(alertspb.AlertConfigDesc{User: "user-1", RawConfig: "config-1"}, "alice", time.Unix(10, 0))
+	version2 := alertspb.NewAlertConfigVersion(alertspb.AlertConfigDesc{User: "user-1", RawConfig: "config-2"}, "bob", time.Unix(20, 0))
+
+	// The storage is empty.
+	{
+		versions, err := store.ListAlertConfigVersions(ctx, "user-1")
+		require.NoError(t, err)
+		assert.Empty(t, versions)
+
+		_, err = store.GetAlertConfigVersion(ctx, "user-1", version1.ID)
+		assert.Equal(t, alertspb.ErrNotFound, err)
+	}
+
+	// The storage contains versions.
+	{
+		require.NoError(t, store.SetAlertConfigVersion(ctx, version1))
+		require.NoError(t, store.SetAlertConfigVersion(ctx, version2))
+
+		versions, err := store.ListAlertConfigVersions(ctx, "user-1")
+		require.NoError(t, err)
+		assert.Equal(t, []alertspb.AlertConfigVersion{version2.AlertConfigVersion, version1.AlertConfigVersion}, versions)
+
+		res, err := store.GetAlertConfigVersion(ctx, "user-1", version1.ID)
+		require.NoError(t, err)
+		assert.Equal(t, version1, res)
+
+		// Versions are not visible to other users.
+		versions, err = store.ListAlertConfigVersions(ctx, "user-2")
+		require.NoError(t, err)
+		assert.Empty(t, versions)
+
+		// Versions are stored in a dedicated location, so they're not listed as alertmanager configs.
+		exists, err := bucket.Exists(ctx, "alertmanager_config_versions/user-1/"+version1.ID)
+		require.NoError(t, err)
+		assert.True(t, exists)
+
+		// Versions are listed from the index, without fetching the versions.
+		require.NoError(t, bucket.Upload(ctx, "alertmanager_config_versions/user-1/"+version2.ID, strings.NewReader("invalid")))
+		versions, err = store.ListAlertConfigVersions(ctx, "user-1")
+		require.NoError(t, err)
+		assert.Equal(t, []alertspb.AlertConfigVersion{version2.AlertConfigVersion, version1.AlertConfigVersion}, versions)
+		require.NoError(t, store.SetAlertConfigVersion(ctx, version2))
+
+		users, err := store.ListAllUsers(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, users)
+	}
+
+	// The storage has had a version deleted.
+	{
+		require.NoError(t, store.DeleteAlertConfigVersion(ctx, "user-1", version1.ID))
+
+		versions, err := store.ListAlertConfigVersions(ctx, "user-1")
+		require.NoError(t, err)
+		assert.Equal(t, []alertspb.AlertConfigVersion{version2.AlertConfigVersion}, versions)
+
+		// Delete again (should be idempotent).
+		require.NoError(t, store.DeleteAlertConfigVersion(ctx, "user-1", version1.ID))
+	}
+}
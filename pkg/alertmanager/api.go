@@ -152,7 +152,7 @@ func (am *MultitenantAlertmanager) SetUserConfig(w http.ResponseWriter, r *http.
 		return
 	}
 
-	err = am.store.SetAlertConfig(r.Context(), cfgDesc)
+	err = am.storeUserConfig(r.Context(), logger, r, cfgDesc)
 	if err != nil {
 		level.Error(logger).Log("msg", errStoringConfiguration, "err", err.Error())
 		http.Error(w, fmt.Sprintf("%s: %s", errStoringConfiguration, err.Error()), http.StatusInternalServerError)
@@ -0,0 +1,213 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package alertmanager
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/gorilla/mux"
+	"github.com/grafana/dskit/tenant"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+
+	"github.com/grafana/mimir/pkg/alertmanager/alertspb"
+	util_log "github.com/grafana/mimir/pkg/util/log"
+)
+
+const (
+	// ConfigAuthorHeader is the optional HTTP header through which the clients changing the tenant's Alertmanager
+	// configuration can declare who made the change. It's recorded in the configuration version as unverified,
+	// because it's not checked against the authenticated identity.
+	ConfigAuthorHeader = "X-Mimir-Config-Author"
+
+	// maxConfigAuthorLength is the max length, in runes, of the recorded configuration author.
+	maxConfigAuthorLength = 256
+
+	errListingConfigVersions     = "unable to list the Alertmanager config versions"
+	errReadingConfigVersion      = "unable to read the Alertmanager config version"
+	errRecordingConfigVersion    = "unable to record the Alertmanager config version"
+	errConfigVersionNotFound     = "Alertmanager config version not found"
+	errConfigVersionInvalid      = "invalid Alertmanager config version"
+	errValidatingConfigToRestore = "error validating the Alertmanager config version to restore"
+)
+
+// UserConfigVersions is the response of the config versions listing API.
+type UserConfigVersions struct {
+	Versions []alertspb.AlertConfigVersion `yaml:"versions"`
+}
+
+// ListUserConfigVersions returns the stored versions of the tenant's Alertmanager configuration, from the most recent one.
+func (am *MultitenantAlertmanager) ListUserConfigVersions(w http.ResponseWriter, r *http.Request) {
+	logger := util_log.WithContext(r.Context(), am.logger)
+
+	userID, err := tenant.TenantID(r.Context())
+	if err != nil {
+		level.Error(logger).Log("msg", errNoOrgID, "err", err.Error())
+		http.Error(w, fmt.Sprintf("%s: %s", errNoOrgID, err.Error()), http.StatusUnauthorized)
+		return
+	}
+
+	versions, err := am.store.ListAlertConfigVersions(r.Context(), userID)
+	if err != nil {
+		level.Error(logger).Log("msg", errListingConfigVersions, "err", err.Error())
+		http.Error(w, fmt.Sprintf("%s: %s", errListingConfigVersions, err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	writeConfigVersionsResponse(w, logger, &UserConfigVersions{Versions: versions})
+}
+
+// GetUserConfigVersion returns a stored version of the tenant's Alertmanager configuration, in the same format
+// of the configuration returned by GetUserConfig.
+func (am *MultitenantAlertmanager) GetUserConfigVersion(w http.ResponseWriter, r *http.Request) {
+	logger := util_log.WithContext(r.Context(), am.logger)
+
+	_, version, ok := am.getUserConfigVersion(w, r, logger)
+	if !ok {
+		return
+	}
+
+	writeConfigVersionsResponse(w, logger, &UserConfig{
+		TemplateFiles:      alertspb.ParseTemplates(version.Config),
+		AlertmanagerConfig: version.Config.RawConfig,
+	})
+}
+
+// RollbackUserConfig replaces the tenant's Alertmanager configuration with a stored version of it. The configuration
+// is validated against the current limits, and the rollback is recorded as a new version.
+func (am *MultitenantAlertmanager) RollbackUserConfig(w http.ResponseWriter, r *http.Request) {
+	logger := util_log.WithContext(r.Context(), am.logger)
+
+	userID, version, ok := am.getUserConfigVersion(w, r, logger)
+	if !ok {
+		return
+	}
+
+	cfgDesc := version.Config
+	cfgDesc.User = userID
+	if err := validateUserConfig(logger, cfgDesc, am.limits, userID, am.cfg.UTF8MigrationLogging); err != nil {
+		level.Warn(logger).Log("msg", errValidatingConfigToRestore, "version", version.ID, "err", err.Error())
+		http.Error(w, fmt.Sprintf("%s: %s", errValidatingConfigToRestore, err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	if err := am.storeUserConfig(r.Context(), logger, r, cfgDesc); err != nil {
+		level.Error(logger).Log("msg", errStoringConfiguration, "err", err.Error())
+		http.Error(w, fmt.Sprintf("%s: %s", errStoringConfiguration, err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	level.Info(logger).Log("msg", "rolled back the Alertmanager config", "version", version.ID)
+	w.WriteHeader(http.StatusCreated)
+}
+
+// getUserConfigVersion returns the tenant's Alertmanager configuration version requested by r. If it returns
+// false, the error response has already been written.
+func (am *MultitenantAlertmanager) getUserConfigVersion(w http.ResponseWriter, r *http.Request, logger log.Logger) (string, alertspb.AlertConfigVersionDesc, bool) {
+	userID, err := tenant.TenantID(r.Context())
+	if err != nil {
+		level.Error(logger).Log("msg", errNoOrgID, "err", err.Error())
+		http.Error(w, fmt.Sprintf("%s: %s", errNoOrgID, err.Error()), http.StatusUnauthorized)
+		return "", alertspb.AlertConfigVersionDesc{}, false
+	}
+
+	// Version IDs are made of digits only, so checking it also guarantees the ID is safe to be used as object name.
+	versionID := mux.Vars(r)["version"]
+	if _, err := strconv.ParseUint(versionID, 10, 64); err != nil {
+		http.Error(w, errConfigVersionInvalid, http.StatusBadRequest)
+		return "", alertspb.AlertConfigVersionDesc{}, false
+	}
+
+	version, err := am.store.GetAlertConfigVersion(r.Context(), userID, versionID)
+	if errors.Is(err, alertspb.ErrNotFound) {
+		http.Error(w, errConfigVersionNotFound, http.StatusNotFound)
+		return "", alertspb.AlertConfigVersionDesc{}, false
+	}
+	if err != nil {
+		level.Error(logger).Log("msg", errReadingConfigVersion, "err", err.Error())
+		http.Error(w, fmt.Sprintf("%s: %s", errReadingConfigVersion, err.Error()), http.StatusInternalServerError)
+		return "", alertspb.AlertConfigVersionDesc{}, false
+	}
+
+	return userID, version, true
+}
+
+// storeUserConfig stores the tenant's Alertmanager configuration and, if config versioning is enabled,
+// records it as a new version. Failing to record the version doesn't fail the request, because the
+// configuration has already been stored.
+func (am *MultitenantAlertmanager) storeUserConfig(ctx context.Context, logger log.Logger, r *http.Request, cfgDesc alertspb.AlertConfigDesc) error {
+	if err := am.store.SetAlertConfig(ctx, cfgDesc); err != nil {
+		return err
+	}
+
+	if am.cfg.MaxConfigVersions > 0 {
+		if err := am.recordUserConfigVersion(ctx, cfgDesc, sanitizeConfigAuthor(r.Header.Get(ConfigAuthorHeader))); err != nil {
+			level.Warn(logger).Log("msg", errRecordingConfigVersion, "err", err)
+		}
+	}
+	return nil
+}
+
+// recordUserConfigVersion stores the input configuration as a new version, unless it's equal to the most recent
+// version, and deletes the oldest versions exceeding the max number of versions to keep.
+func (am *MultitenantAlertmanager) recordUserConfigVersion(ctx context.Context, cfgDesc alertspb.AlertConfigDesc, createdBy string) error {
+	versions, err := am.store.ListAlertConfigVersions(ctx, cfgDesc.User)
+	if err != nil {
+		return errors.Wrap(err, "list versions")
+	}
+
+	version := alertspb.NewAlertConfigVersion(cfgDesc, createdBy, time.Now())
+	if len(versions) > 0 && versions[0].Hash == version.Hash {
+		return nil
+	}
+
+	if err := am.store.SetAlertConfigVersion(ctx, version); err != nil {
+		return errors.Wrap(err, "store version")
+	}
+
+	// The new version is the most recent one, and it's not included in the listed versions.
+	for i := am.cfg.MaxConfigVersions - 1; i >= 0 && i < len(versions); i++ {
+		if err := am.store.DeleteAlertConfigVersion(ctx, cfgDesc.User, versions[i].ID); err != nil {
+			return errors.Wrapf(err, "delete version %s", versions[i].ID)
+		}
+	}
+	return nil
+}
+
+// sanitizeConfigAuthor returns the configuration author declared by the client, without non-printable characters
+// and truncated to maxConfigAuthorLength runes.
+func sanitizeConfigAuthor(author string) string {
+	author = strings.Map(func(r rune) rune {
+		if !unicode.IsPrint(r) {
+			return -1
+		}
+		return r
+	}, author)
+
+	if runes := []rune(author); len(runes) > maxConfigAuthorLength {
+		author = string(runes[:maxConfigAuthorLength])
+	}
+	return author
+}
+
+func writeConfigVersionsResponse(w http.ResponseWriter, logger log.Logger, res any) {
+	d, err := yaml.Marshal(res)
+	if err != nil {
+		level.Error(logger).Log("msg", errMarshallingYAML, "err", err)
+		http.Error(w, fmt.Sprintf("%s: %s", errMarshallingYAML, err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/yaml")
+	if _, err := w.Write(d); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
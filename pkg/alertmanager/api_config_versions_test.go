@@ -0,0 +1,188 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package alertmanager
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/grafana/dskit/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+
+	"github.com/grafana/mimir/pkg/util/test"
+)
+
+func TestMultitenantAlertmanager_ConfigVersionsAPI(t *testing.T) {
+	const userID = "user-1"
+
+	configWithReceiver := func(receiver string) string {
+		return fmt.Sprintf(`
+alertmanager_config: |
+  route:
+    receiver: %s
+  receivers:
+    - name: %s
+template_files:
+  first.tpl: '{{ define "first" }}%s{{ end }}'
+`, receiver, receiver, receiver)
+	}
+
+	cfg := mockAlertmanagerConfig(t)
+	cfg.MaxConfigVersions = 2
+
+	store := prepareInMemoryAlertStore()
+	am := &MultitenantAlertmanager{
+		cfg:    cfg,
+		store:  store,
+		logger: test.NewTestingLogger(t),
+		limits: &mockAlertManagerLimits{},
+	}
+
+	router := mux.NewRouter()
+	router.Path("/api/v1/alerts").Methods(http.MethodGet).HandlerFunc(am.GetUserConfig)
+	router.Path("/api/v1/alerts").Methods(http.MethodPost).HandlerFunc(am.SetUserConfig)
+	router.Path("/api/v1/alerts").Methods(http.MethodDelete).HandlerFunc(am.DeleteUserConfig)
+	router.Path("/api/v1/alerts/versions").Methods(http.MethodGet).HandlerFunc(am.ListUserConfigVersions)
+	router.Path("/api/v1/alerts/versions/{version}").Methods(http.MethodGet).HandlerFunc(am.GetUserConfigVersion)
+	router.Path("/api/v1/alerts/versions/{version}/rollback").Methods(http.MethodPost).HandlerFunc(am.RollbackUserConfig)
+
+	do := func(method, path, body, author string) (int, string) {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req = req.WithContext(user.InjectOrgID(context.Background(), userID))
+		if author != "" {
+			req.Header.Set(ConfigAuthorHeader, author)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		resp, err := io.ReadAll(rec.Result().Body)
+		require.NoError(t, err)
+		return rec.Code, string(resp)
+	}
+
+	listVersions := func() UserConfigVersions {
+		code, body := do(http.MethodGet, "/api/v1/alerts/versions", "", "")
+		require.Equal(t, http.StatusOK, code)
+
+		res := UserConfigVersions{}
+		require.NoError(t, yaml.Unmarshal([]byte(body), &res))
+		return res
+	}
+
+	// No versions have been stored yet.
+	assert.Empty(t, listVersions().Versions)
+
+	code, _ := do(http.MethodPost, "/api/v1/alerts", configWithReceiver("first"), "alice")
+	require.Equal(t, http.StatusCreated, code)
+
+	// Storing the same configuration again doesn't create a new version.
+	code, _ = do(http.MethodPost, "/api/v1/alerts", configWithReceiver("first"), "alice")
+	require.Equal(t, http.StatusCreated, code)
+
+	versions := listVersions().Versions
+	require.Len(t, versions, 1)
+	assert.Equal(t, "alice", versions[0].CreatedByUnverified)
+	assert.NotEmpty(t, versions[0].Hash)
+	firstVersion := versions[0].ID
+
+	code, _ = do(http.MethodPost, "/api/v1/alerts", configWithReceiver("second"), "bob")
+	require.Equal(t, http.StatusCreated, code)
+
+	versions = listVersions().Versions
+	require.Len(t, versions, 2)
+	assert.Equal(t, "bob", versions[0].CreatedByUnverified)
+	assert.Equal(t, firstVersion, versions[1].ID)
+
+	t.Run("get a version", func(t *testing.T) {
+		code, body := do(http.MethodGet, "/api/v1/alerts/versions/"+firstVersion, "", "")
+		require.Equal(t, http.StatusOK, code)
+
+		res := UserConfig{}
+		require.NoError(t, yaml.Unmarshal([]byte(body), &res))
+		assert.Contains(t, res.AlertmanagerConfig, "receiver: first")
+		assert.Equal(t, map[string]string{"first.tpl": `{{ define "first" }}first{{ end }}`}, res.TemplateFiles)
+
+		code, _ = do(http.MethodGet, "/api/v1/alerts/versions/1", "", "")
+		require.Equal(t, http.StatusNotFound, code)
+
+		code, _ = do(http.MethodGet, "/api/v1/alerts/versions/invalid", "", "")
+		require.Equal(t, http.StatusBadRequest, code)
+	})
+
+	t.Run("roll back to a version", func(t *testing.T) {
+		code, _ := do(http.MethodPost, "/api/v1/alerts/versions/"+firstVersion+"/rollback", "", "carol")
+		require.Equal(t, http.StatusCreated, code)
+
+		code, body := do(http.MethodGet, "/api/v1/alerts", "", "")
+		require.Equal(t, http.StatusOK, code)
+		assert.Contains(t, body, "receiver: first")
+
+		// The rollback has been recorded as a new version, and the oldest version has been deleted.
+		versions := listVersions().Versions
+		require.Len(t, versions, 2)
+		assert.Equal(t, "carol", versions[0].CreatedByUnverified)
+		assert.Equal(t, "bob", versions[1].CreatedByUnverified)
+
+		code, _ = do(http.MethodPost, "/api/v1/alerts/versions/"+firstVersion+"/rollback", "", "carol")
+		require.Equal(t, http.StatusNotFound, code)
+	})
+
+	t.Run("roll back to a version which doesn't pass the current limits", func(t *testing.T) {
+		am.limits = &mockAlertManagerLimits{maxSizeOfTemplate: 10}
+		t.Cleanup(func() { am.limits = &mockAlertManagerLimits{} })
+
+		code, _ := do(http.MethodPost, "/api/v1/alerts/versions/"+listVersions().Versions[1].ID+"/rollback", "", "")
+		require.Equal(t, http.StatusBadRequest, code)
+
+		code, body := do(http.MethodGet, "/api/v1/alerts", "", "")
+		require.Equal(t, http.StatusOK, code)
+		assert.Contains(t, body, "receiver: first")
+	})
+
+	t.Run("delete the config", func(t *testing.T) {
+		code, _ := do(http.MethodDelete, "/api/v1/alerts", "", "")
+		require.Equal(t, http.StatusOK, code)
+
+		// The versions are deleted along with the config.
+		assert.Empty(t, listVersions().Versions)
+
+		code, _ = do(http.MethodGet, "/api/v1/alerts/versions/"+versions[0].ID, "", "")
+		require.Equal(t, http.StatusNotFound, code)
+	})
+}
+
+func TestSanitizeConfigAuthor(t *testing.T) {
+	assert.Equal(t, "alice", sanitizeConfigAuthor("alice"))
+	assert.Equal(t, "alice bob", sanitizeConfigAuthor("alice\n\x00 bob"))
+	assert.Equal(t, strings.Repeat("é", maxConfigAuthorLength), sanitizeConfigAuthor(strings.Repeat("é", maxConfigAuthorLength+1)))
+}
+
+func TestMultitenantAlertmanager_ConfigVersionsDisabled(t *testing.T) {
+	const userID = "user-1"
+
+	store := prepareInMemoryAlertStore()
+	am := &MultitenantAlertmanager{
+		cfg:    mockAlertmanagerConfig(t),
+		store:  store,
+		logger: test.NewTestingLogger(t),
+		limits: &mockAlertManagerLimits{},
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/alerts", strings.NewReader("alertmanager_config: |\n  route:\n    receiver: default\n  receivers:\n    - name: default\n"))
+	req = req.WithContext(user.InjectOrgID(context.Background(), userID))
+	rec := httptest.NewRecorder()
+	am.SetUserConfig(rec, req)
+	require.Equal(t, http.StatusCreated, rec.Code)
+
+	versions, err := store.ListAlertConfigVersions(context.Background(), userID)
+	require.NoError(t, err)
+	assert.Empty(t, versions)
+}
@@ -0,0 +1,460 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package alertmanager
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-kit/log/level"
+	"github.com/grafana/dskit/tenant"
+	"github.com/pkg/errors"
+	"github.com/prometheus/alertmanager/pkg/labels"
+	"gopkg.in/yaml.v3"
+
+	"github.com/grafana/mimir/pkg/alertmanager/alertspb"
+	util_log "github.com/grafana/mimir/pkg/util/log"
+)
+
+const (
+	errReadingGrafanaImport    = "unable to read the Grafana alerting resources"
+	errConvertingGrafanaImport = "unable to convert the Grafana alerting resources"
+
+	// grafanaRedactedValue is the value of the secure settings of Grafana contact points exported without decryption.
+	grafanaRedactedValue = "[REDACTED]"
+
+	slackPostMessageURL = "https://slack.com/api/chat.postMessage"
+)
+
+// grafanaAlertingResources are the Grafana alerting resources, in the file provisioning format
+// also used by the Grafana alerting export API. Both YAML and JSON are supported.
+type grafanaAlertingResources struct {
+	ContactPoints []grafanaContactPoint `yaml:"contactPoints"`
+	Policies      []grafanaPolicy       `yaml:"policies"`
+	MuteTimes     []grafanaMuteTime     `yaml:"muteTimes"`
+}
+
+type grafanaContactPoint struct {
+	OrgID     int64                `yaml:"orgId"`
+	Name      string               `yaml:"name"`
+	Receivers []grafanaIntegration `yaml:"receivers"`
+}
+
+type grafanaIntegration struct {
+	UID                   string                 `yaml:"uid"`
+	Type                  string                 `yaml:"type"`
+	Settings              map[string]interface{} `yaml:"settings"`
+	DisableResolveMessage bool                   `yaml:"disableResolveMessage"`
+}
+
+type grafanaPolicy struct {
+	OrgID        int64 `yaml:"orgId"`
+	grafanaRoute `yaml:",inline"`
+}
+
+// grafanaRoute is a Grafana notification policy. Once converted, it's also the route of the imported configuration.
+type grafanaRoute struct {
+	Receiver            string          `yaml:"receiver,omitempty"`
+	GroupBy             []string        `yaml:"group_by,omitempty"`
+	Matchers            []string        `yaml:"matchers,omitempty"`
+	ObjectMatchers      [][]string      `yaml:"object_matchers,omitempty"`
+	MuteTimeIntervals   []string        `yaml:"mute_time_intervals,omitempty"`
+	ActiveTimeIntervals []string        `yaml:"active_time_intervals,omitempty"`
+	Continue            bool            `yaml:"continue,omitempty"`
+	GroupWait           string          `yaml:"group_wait,omitempty"`
+	GroupInterval       string          `yaml:"group_interval,omitempty"`
+	RepeatInterval      string          `yaml:"repeat_interval,omitempty"`
+	Routes              []*grafanaRoute `yaml:"routes,omitempty"`
+}
+
+type grafanaMuteTime struct {
+	OrgID         int64     `yaml:"orgId,omitempty"`
+	Name          string    `yaml:"name"`
+	TimeIntervals yaml.Node `yaml:"time_intervals"`
+}
+
+// importedConfig is the Alertmanager configuration resulting from the import of Grafana alerting resources.
+type importedConfig struct {
+	Global        yaml.Node           `yaml:"global,omitempty"`
+	Templates     yaml.Node           `yaml:"templates,omitempty"`
+	Route         *grafanaRoute       `yaml:"route"`
+	Receivers     []*importedReceiver `yaml:"receivers"`
+	TimeIntervals []grafanaMuteTime   `yaml:"time_intervals,omitempty"`
+}
+
+type importedReceiver struct {
+	Name             string                   `yaml:"name"`
+	DiscordConfigs   []map[string]interface{} `yaml:"discord_configs,omitempty"`
+	EmailConfigs     []map[string]interface{} `yaml:"email_configs,omitempty"`
+	PagerdutyConfigs []map[string]interface{} `yaml:"pagerduty_configs,omitempty"`
+	SlackConfigs     []map[string]interface{} `yaml:"slack_configs,omitempty"`
+	WebhookConfigs   []map[string]interface{} `yaml:"webhook_configs,omitempty"`
+	OpsGenieConfigs  []map[string]interface{} `yaml:"opsgenie_configs,omitempty"`
+	PushoverConfigs  []map[string]interface{} `yaml:"pushover_configs,omitempty"`
+	TelegramConfigs  []map[string]interface{} `yaml:"telegram_configs,omitempty"`
+	MSTeamsConfigs   []map[string]interface{} `yaml:"msteams_configs,omitempty"`
+}
+
+// ImportGrafanaConfig converts Grafana alerting contact points, notification policies and mute timings
+// into the tenant's Alertmanager configuration. The routing tree, the receivers and the time intervals of the
+// current configuration are replaced, while the global section and the templates are kept.
+// If the "dry_run" parameter is true, the converted configuration is returned instead of being stored.
+func (am *MultitenantAlertmanager) ImportGrafanaConfig(w http.ResponseWriter, r *http.Request) {
+	logger := util_log.WithContext(r.Context(), am.logger)
+	userID, err := tenant.TenantID(r.Context())
+	if err != nil {
+		level.Error(logger).Log("msg", errNoOrgID, "err", err.Error())
+		http.Error(w, fmt.Sprintf("%s: %s", errNoOrgID, err.Error()), http.StatusUnauthorized)
+		return
+	}
+
+	var input io.Reader = r.Body
+	maxConfigSize := am.limits.AlertmanagerMaxConfigSize(userID)
+	if maxConfigSize > 0 {
+		// Allow one extra byte to check if we have read too many bytes.
+		input = io.LimitReader(r.Body, int64(maxConfigSize)+1)
+	}
+	payload, err := io.ReadAll(input)
+	if err != nil {
+		level.Error(logger).Log("msg", errReadingGrafanaImport, "err", err.Error())
+		http.Error(w, fmt.Sprintf("%s: %s", errReadingGrafanaImport, err.Error()), http.StatusBadRequest)
+		return
+	}
+	if maxConfigSize > 0 && len(payload) > maxConfigSize {
+		msg := fmt.Sprintf(errConfigurationTooBig, maxConfigSize)
+		level.Warn(logger).Log("msg", msg)
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	resources := grafanaAlertingResources{}
+	if err := yaml.Unmarshal(payload, &resources); err != nil {
+		level.Warn(logger).Log("msg", errReadingGrafanaImport, "err", err.Error())
+		http.Error(w, fmt.Sprintf("%s: %s", errReadingGrafanaImport, err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	imported, err := convertGrafanaAlertingResources(resources)
+	if err != nil {
+		level.Warn(logger).Log("msg", errConvertingGrafanaImport, "err", err.Error())
+		http.Error(w, fmt.Sprintf("%s: %s", errConvertingGrafanaImport, err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	// Keep the global section and the templates of the current configuration, if any.
+	cfgDesc, err := am.store.GetAlertConfig(r.Context(), userID)
+	if err != nil && !errors.Is(err, alertspb.ErrNotFound) {
+		level.Error(logger).Log("msg", "unable to get the current Alertmanager config", "err", err.Error())
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err == nil {
+		current := struct {
+			Global    yaml.Node `yaml:"global"`
+			Templates yaml.Node `yaml:"templates"`
+		}{}
+		if err := yaml.Unmarshal([]byte(cfgDesc.RawConfig), &current); err != nil {
+			level.Error(logger).Log("msg", errParsingConfig, "err", err, "user", userID)
+			http.Error(w, fmt.Sprintf("%s: %s", errParsingConfig, err.Error()), http.StatusInternalServerError)
+			return
+		}
+		imported.Global = current.Global
+		imported.Templates = current.Templates
+	}
+	cfgDesc.User = userID
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	err = encoder.Encode(imported)
+	if err == nil {
+		err = encoder.Close()
+	}
+	if err != nil {
+		level.Error(logger).Log("msg", errMarshallingYAML, "err", err, "user", userID)
+		http.Error(w, fmt.Sprintf("%s: %s", errMarshallingYAML, err.Error()), http.StatusInternalServerError)
+		return
+	}
+	cfgDesc.RawConfig = buf.String()
+
+	if maxConfigSize > 0 && len(cfgDesc.RawConfig) > maxConfigSize {
+		msg := fmt.Sprintf(errConfigurationTooBig, maxConfigSize)
+		level.Warn(logger).Log("msg", msg)
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	if err := validateUserConfig(logger, cfgDesc, am.limits, userID, am.cfg.UTF8MigrationLogging); err != nil {
+		level.Warn(logger).Log("msg", errValidatingConfig, "err", err.Error())
+		http.Error(w, fmt.Sprintf("%s: %s", errValidatingConfig, err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	if dryRun, _ := strconv.ParseBool(r.URL.Query().Get("dry_run")); dryRun {
+		w.Header().Set("Content-Type", "application/yaml")
+		if _, err := w.Write(buf.Bytes()); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	if err := am.storeUserConfig(r.Context(), logger, r, cfgDesc); err != nil {
+		level.Error(logger).Log("msg", errStoringConfiguration, "err", err.Error())
+		http.Error(w, fmt.Sprintf("%s: %s", errStoringConfiguration, err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+}
+
+// convertGrafanaAlertingResources converts the Grafana alerting resources of a single organization
+// into an Alertmanager configuration.
+func convertGrafanaAlertingResources(resources grafanaAlertingResources) (*importedConfig, error) {
+	orgs := map[int64]struct{}{}
+	for _, cp := range resources.ContactPoints {
+		orgs[cp.OrgID] = struct{}{}
+	}
+	for _, p := range resources.Policies {
+		orgs[p.OrgID] = struct{}{}
+	}
+	for _, mt := range resources.MuteTimes {
+		orgs[mt.OrgID] = struct{}{}
+	}
+	if len(orgs) > 1 {
+		return nil, errors.New("the resources belong to multiple Grafana organizations, import one organization at a time")
+	}
+	if len(resources.Policies) != 1 {
+		return nil, fmt.Errorf("expected exactly one notification policy tree, got %d", len(resources.Policies))
+	}
+
+	imported := &importedConfig{}
+
+	route := resources.Policies[0].grafanaRoute
+	if err := convertGrafanaRoute(&route); err != nil {
+		return nil, err
+	}
+	imported.Route = &route
+
+	for _, cp := range resources.ContactPoints {
+		receiver := &importedReceiver{Name: cp.Name}
+		for _, integration := range cp.Receivers {
+			if err := convertGrafanaIntegration(receiver, integration); err != nil {
+				return nil, fmt.Errorf("contact point %q: %w", cp.Name, err)
+			}
+		}
+		imported.Receivers = append(imported.Receivers, receiver)
+	}
+
+	for _, mt := range resources.MuteTimes {
+		mt.OrgID = 0
+		imported.TimeIntervals = append(imported.TimeIntervals, mt)
+	}
+
+	return imported, nil
+}
+
+// convertGrafanaRoute converts the object matchers of the route and its children to Alertmanager matchers.
+func convertGrafanaRoute(route *grafanaRoute) error {
+	for _, m := range route.ObjectMatchers {
+		if len(m) != 3 {
+			return fmt.Errorf("invalid object matcher %v: expected a label name, an operator and a value", m)
+		}
+
+		var matchType labels.MatchType
+		switch m[1] {
+		case "=":
+			matchType = labels.MatchEqual
+		case "!=":
+			matchType = labels.MatchNotEqual
+		case "=~":
+			matchType = labels.MatchRegexp
+		case "!~":
+			matchType = labels.MatchNotRegexp
+		default:
+			return fmt.Errorf("invalid object matcher %v: unsupported operator %q", m, m[1])
+		}
+
+		matcher, err := labels.NewMatcher(matchType, m[0], m[2])
+		if err != nil {
+			return fmt.Errorf("invalid object matcher %v: %w", m, err)
+		}
+		route.Matchers = append(route.Matchers, matcher.String())
+	}
+	route.ObjectMatchers = nil
+
+	for _, child := range route.Routes {
+		if err := convertGrafanaRoute(child); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// convertGrafanaIntegration adds the Alertmanager notifier configs equivalent to the Grafana integration to the receiver.
+// Message and title templates are not converted, because they usually reference Grafana's templates.
+func convertGrafanaIntegration(receiver *importedReceiver, integration grafanaIntegration) error {
+	settings := grafanaSettings(integration.Settings)
+	for key, value := range settings {
+		if value == grafanaRedactedValue {
+			return fmt.Errorf("integration %q of type %q: the setting %q is redacted, export the contact points with decrypted secure settings", integration.UID, integration.Type, key)
+		}
+	}
+
+	newConfig := func() map[string]interface{} {
+		return map[string]interface{}{"send_resolved": !integration.DisableResolveMessage}
+	}
+	missing := func(key string) error {
+		return fmt.Errorf("integration %q of type %q: the setting %q is required", integration.UID, integration.Type, key)
+	}
+
+	switch integration.Type {
+	case "email":
+		addresses := strings.FieldsFunc(settings.str("addresses"), func(r rune) bool { return r == ';' || r == ',' || r == '\n' })
+		for i := range addresses {
+			addresses[i] = strings.TrimSpace(addresses[i])
+		}
+		if len(addresses) == 0 {
+			return missing("addresses")
+		}
+		if settings.bool("singleEmail") {
+			addresses = []string{strings.Join(addresses, ", ")}
+		}
+		for _, address := range addresses {
+			cfg := newConfig()
+			cfg["to"] = address
+			receiver.EmailConfigs = append(receiver.EmailConfigs, cfg)
+		}
+
+	case "slack":
+		cfg := newConfig()
+		switch {
+		case settings.str("url") != "":
+			cfg["api_url"] = settings.str("url")
+		case settings.str("token") != "":
+			cfg["api_url"] = slackPostMessageURL
+			cfg["http_config"] = map[string]interface{}{"authorization": map[string]interface{}{"credentials": settings.str("token")}}
+		default:
+			return missing("url")
+		}
+		settings.copyTo(cfg, map[string]string{"recipient": "channel", "username": "username", "icon_emoji": "icon_emoji", "icon_url": "icon_url"})
+		receiver.SlackConfigs = append(receiver.SlackConfigs, cfg)
+
+	case "webhook":
+		if settings.str("url") == "" {
+			return missing("url")
+		}
+		if method := settings.str("httpMethod"); method != "" && method != http.MethodPost {
+			return fmt.Errorf("integration %q of type %q: unsupported HTTP method %q, only POST is supported", integration.UID, integration.Type, method)
+		}
+		cfg := newConfig()
+		cfg["url"] = settings.str("url")
+		if settings.str("username") != "" {
+			cfg["http_config"] = map[string]interface{}{"basic_auth": map[string]interface{}{"username": settings.str("username"), "password": settings.str("password")}}
+		}
+		if maxAlerts, err := strconv.Atoi(settings.str("maxAlerts")); err == nil && maxAlerts > 0 {
+			cfg["max_alerts"] = maxAlerts
+		}
+		receiver.WebhookConfigs = append(receiver.WebhookConfigs, cfg)
+
+	case "pagerduty":
+		if settings.str("integrationKey") == "" {
+			return missing("integrationKey")
+		}
+		cfg := newConfig()
+		cfg["routing_key"] = settings.str("integrationKey")
+		settings.copyTo(cfg, map[string]string{"severity": "severity", "class": "class", "component": "component", "group": "group", "client": "client", "client_url": "client_url"})
+		receiver.PagerdutyConfigs = append(receiver.PagerdutyConfigs, cfg)
+
+	case "opsgenie":
+		if settings.str("apiKey") == "" {
+			return missing("apiKey")
+		}
+		cfg := newConfig()
+		cfg["api_key"] = settings.str("apiKey")
+		if apiURL := settings.str("apiUrl"); apiURL != "" {
+			// Grafana configures the URL of the alerts API, while Alertmanager configures the base URL of the API.
+			cfg["api_url"] = strings.TrimSuffix(strings.TrimSuffix(apiURL, "/"), "/v2/alerts") + "/"
+		}
+		receiver.OpsGenieConfigs = append(receiver.OpsGenieConfigs, cfg)
+
+	case "pushover":
+		if settings.str("userKey") == "" {
+			return missing("userKey")
+		}
+		if settings.str("apiToken") == "" {
+			return missing("apiToken")
+		}
+		cfg := newConfig()
+		cfg["user_key"] = settings.str("userKey")
+		cfg["token"] = settings.str("apiToken")
+		settings.copyTo(cfg, map[string]string{"priority": "priority", "sound": "sound"})
+		receiver.PushoverConfigs = append(receiver.PushoverConfigs, cfg)
+
+	case "telegram":
+		if settings.str("bottoken") == "" {
+			return missing("bottoken")
+		}
+		chatID, err := strconv.ParseInt(settings.str("chatid"), 10, 64)
+		if err != nil {
+			return fmt.Errorf("integration %q of type %q: invalid chat ID %q", integration.UID, integration.Type, settings.str("chatid"))
+		}
+		cfg := newConfig()
+		cfg["bot_token"] = settings.str("bottoken")
+		cfg["chat_id"] = chatID
+		settings.copyTo(cfg, map[string]string{"parse_mode": "parse_mode"})
+		if settings.bool("disable_notifications") {
+			cfg["disable_notifications"] = true
+		}
+		receiver.TelegramConfigs = append(receiver.TelegramConfigs, cfg)
+
+	case "discord":
+		if settings.str("url") == "" {
+			return missing("url")
+		}
+		cfg := newConfig()
+		cfg["webhook_url"] = settings.str("url")
+		receiver.DiscordConfigs = append(receiver.DiscordConfigs, cfg)
+
+	case "teams":
+		if settings.str("url") == "" {
+			return missing("url")
+		}
+		cfg := newConfig()
+		cfg["webhook_url"] = settings.str("url")
+		receiver.MSTeamsConfigs = append(receiver.MSTeamsConfigs, cfg)
+
+	default:
+		return fmt.Errorf("integration %q: unsupported type %q", integration.UID, integration.Type)
+	}
+
+	return nil
+}
+
+// grafanaSettings are the settings of a Grafana integration, whose values may be strings, numbers or booleans.
+type grafanaSettings map[string]interface{}
+
+func (s grafanaSettings) str(key string) string {
+	value, ok := s[key]
+	if !ok || value == nil {
+		return ""
+	}
+	return fmt.Sprint(value)
+}
+
+func (s grafanaSettings) bool(key string) bool {
+	b, _ := strconv.ParseBool(s.str(key))
+	return b
+}
+
+// copyTo copies the non-empty settings to the notifier config, mapping the Grafana setting names to the Alertmanager ones.
+func (s grafanaSettings) copyTo(cfg map[string]interface{}, names map[string]string) {
+	for from, to := range names {
+		if value := s.str(from); value != "" {
+			cfg[to] = value
+		}
+	}
+}
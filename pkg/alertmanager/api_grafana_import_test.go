@@ -0,0 +1,195 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package alertmanager
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/grafana/dskit/user"
+	"github.com/prometheus/alertmanager/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/mimir/pkg/alertmanager/alertspb"
+	"github.com/grafana/mimir/pkg/util/test"
+)
+
+const grafanaAlertingResourcesExport = `
+apiVersion: 1
+contactPoints:
+  - orgId: 1
+    name: ops
+    receivers:
+      - uid: slack-uid
+        type: slack
+        settings:
+          url: https://hooks.slack.example.com/services/secret
+          recipient: '#ops'
+      - uid: email-uid
+        type: email
+        settings:
+          addresses: a@example.com;b@example.com
+          singleEmail: false
+        disableResolveMessage: true
+  - orgId: 1
+    name: team-a
+    receivers:
+      - uid: pagerduty-uid
+        type: pagerduty
+        settings:
+          integrationKey: secret-key
+          severity: critical
+      - uid: telegram-uid
+        type: telegram
+        settings:
+          bottoken: bot-token
+          chatid: "-100123"
+policies:
+  - orgId: 1
+    receiver: ops
+    group_by: [grafana_folder, alertname]
+    routes:
+      - receiver: team-a
+        object_matchers:
+          - [team, =, a]
+          - [severity, "=~", "critical|warning"]
+        mute_time_intervals: [weekends]
+        repeat_interval: 1h
+muteTimes:
+  - orgId: 1
+    name: weekends
+    time_intervals:
+      - weekdays: [saturday, sunday]
+`
+
+func TestMultitenantAlertmanager_ImportGrafanaConfig(t *testing.T) {
+	const userID = "user-1"
+
+	store := prepareInMemoryAlertStore()
+	am := &MultitenantAlertmanager{
+		cfg:    mockAlertmanagerConfig(t),
+		store:  store,
+		logger: test.NewTestingLogger(t),
+		limits: &mockAlertManagerLimits{},
+	}
+
+	do := func(path, body string) (int, string) {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		req = req.WithContext(user.InjectOrgID(context.Background(), userID))
+		rec := httptest.NewRecorder()
+		am.ImportGrafanaConfig(rec, req)
+
+		resp, err := io.ReadAll(rec.Result().Body)
+		require.NoError(t, err)
+		return rec.Code, string(resp)
+	}
+
+	// The global section and the templates of the current configuration are kept.
+	require.NoError(t, store.SetAlertConfig(context.Background(), alertspb.AlertConfigDesc{
+		User: userID,
+		RawConfig: `
+global:
+  smtp_smarthost: smtp.example.com:587
+  smtp_from: alertmanager@example.com
+templates:
+  - custom.tmpl
+route:
+  receiver: old
+receivers:
+  - name: old
+`,
+		Templates: []*alertspb.TemplateDesc{{Filename: "custom.tmpl", Body: `{{ define "custom" }}custom{{ end }}`}},
+	}))
+
+	t.Run("dry run", func(t *testing.T) {
+		code, body := do("/api/v1/alerts/import/grafana?dry_run=true", grafanaAlertingResourcesExport)
+		require.Equal(t, http.StatusOK, code, body)
+		assert.Contains(t, body, "receiver: team-a")
+
+		// The configuration has not been stored.
+		cfgDesc, err := store.GetAlertConfig(context.Background(), userID)
+		require.NoError(t, err)
+		assert.Contains(t, cfgDesc.RawConfig, "receiver: old")
+	})
+
+	t.Run("import", func(t *testing.T) {
+		code, body := do("/api/v1/alerts/import/grafana", grafanaAlertingResourcesExport)
+		require.Equal(t, http.StatusCreated, code, body)
+
+		cfgDesc, err := store.GetAlertConfig(context.Background(), userID)
+		require.NoError(t, err)
+		require.Len(t, cfgDesc.Templates, 1)
+
+		cfg, err := config.Load(cfgDesc.RawConfig)
+		require.NoError(t, err)
+		assert.Equal(t, "smtp.example.com:587", cfg.Global.SMTPSmarthost.String())
+		assert.Equal(t, []string{"custom.tmpl"}, cfg.Templates)
+
+		assert.Equal(t, "ops", cfg.Route.Receiver)
+		require.Len(t, cfg.Route.Routes, 1)
+		child := cfg.Route.Routes[0]
+		assert.Equal(t, "team-a", child.Receiver)
+		require.Len(t, child.Matchers, 2)
+		assert.ElementsMatch(t, []string{`team="a"`, `severity=~"critical|warning"`}, []string{child.Matchers[0].String(), child.Matchers[1].String()})
+		assert.Equal(t, []string{"weekends"}, child.MuteTimeIntervals)
+
+		require.Len(t, cfg.Receivers, 2)
+		ops := cfg.Receivers[0]
+		require.Len(t, ops.SlackConfigs, 1)
+		assert.Equal(t, "https://hooks.slack.example.com/services/secret", ops.SlackConfigs[0].APIURL.String())
+		assert.Equal(t, "#ops", ops.SlackConfigs[0].Channel)
+		require.Len(t, ops.EmailConfigs, 2)
+		assert.Equal(t, "a@example.com", ops.EmailConfigs[0].To)
+		assert.Equal(t, "b@example.com", ops.EmailConfigs[1].To)
+		assert.False(t, ops.EmailConfigs[0].SendResolved())
+
+		teamA := cfg.Receivers[1]
+		require.Len(t, teamA.PagerdutyConfigs, 1)
+		assert.Equal(t, "secret-key", string(teamA.PagerdutyConfigs[0].RoutingKey))
+		assert.Equal(t, "critical", teamA.PagerdutyConfigs[0].Severity)
+		require.Len(t, teamA.TelegramConfigs, 1)
+		assert.Equal(t, int64(-100123), teamA.TelegramConfigs[0].ChatID)
+
+		require.Len(t, cfg.TimeIntervals, 1)
+		assert.Equal(t, "weekends", cfg.TimeIntervals[0].Name)
+	})
+
+	t.Run("invalid resources", func(t *testing.T) {
+		for name, tc := range map[string]struct {
+			body        string
+			expectedErr string
+		}{
+			"unsupported integration type": {
+				body:        "contactPoints:\n  - name: cp\n    receivers:\n      - uid: x\n        type: kafka\npolicies:\n  - receiver: cp\n",
+				expectedErr: `contact point "cp": integration "x": unsupported type "kafka"`,
+			},
+			"redacted secure setting": {
+				body:        "contactPoints:\n  - name: cp\n    receivers:\n      - uid: x\n        type: slack\n        settings:\n          url: '[REDACTED]'\npolicies:\n  - receiver: cp\n",
+				expectedErr: `the setting "url" is redacted`,
+			},
+			"multiple organizations": {
+				body:        "contactPoints:\n  - orgId: 1\n    name: cp\npolicies:\n  - orgId: 2\n    receiver: cp\n",
+				expectedErr: "multiple Grafana organizations",
+			},
+			"no notification policy": {
+				body:        "contactPoints:\n  - name: cp\n",
+				expectedErr: "expected exactly one notification policy tree, got 0",
+			},
+			"unknown receiver": {
+				body:        "contactPoints:\n  - name: cp\npolicies:\n  - receiver: unknown\n",
+				expectedErr: `undefined receiver "unknown"`,
+			},
+		} {
+			t.Run(name, func(t *testing.T) {
+				code, body := do("/api/v1/alerts/import/grafana", tc.body)
+				require.Equal(t, http.StatusBadRequest, code)
+				assert.Contains(t, body, tc.expectedErr)
+			})
+		}
+	})
+}
@@ -0,0 +1,470 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package alertmanager
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/gorilla/mux"
+	"github.com/grafana/dskit/tenant"
+	"github.com/pkg/errors"
+	"github.com/prometheus/alertmanager/config"
+	"gopkg.in/yaml.v3"
+
+	"github.com/grafana/mimir/pkg/alertmanager/alertspb"
+	util_log "github.com/grafana/mimir/pkg/util/log"
+)
+
+const (
+	errParsingConfig             = "unable to parse the Alertmanager config"
+	errTimeIntervalNotFound      = "time interval not found"
+	errTimeIntervalNameMissing   = "time interval name is missing"
+	errTimeIntervalInvalid       = "invalid time interval"
+	errTimeIntervalStillReferred = "time interval is still referenced by routes"
+
+	timeIntervalsKey     = "time_intervals"
+	muteTimeIntervalsKey = "mute_time_intervals"
+)
+
+// UserTimeIntervals is the response of the time intervals listing API.
+type UserTimeIntervals struct {
+	TimeIntervals []*UserTimeInterval `yaml:"time_intervals"`
+}
+
+// UserTimeInterval is a named time interval of the tenant's Alertmanager configuration,
+// along with the routes referencing it.
+type UserTimeInterval struct {
+	Name          string                  `yaml:"name"`
+	TimeIntervals yaml.Node               `yaml:"time_intervals,omitempty"`
+	ReferencedBy  []TimeIntervalReference `yaml:"referenced_by,omitempty"`
+}
+
+// TimeIntervalReference is a route referencing a time interval.
+type TimeIntervalReference struct {
+	// Route is the path of the route in the routing tree, e.g. "route.routes[0].routes[1]".
+	Route    string   `yaml:"route"`
+	Receiver string   `yaml:"receiver,omitempty"`
+	Matchers []string `yaml:"matchers,omitempty"`
+	// Usage is either "mute_time_intervals" or "active_time_intervals".
+	Usage string `yaml:"usage"`
+}
+
+// timeIntervalInput is the payload of the API used to create or replace a time interval.
+type timeIntervalInput struct {
+	TimeIntervals yaml.Node `yaml:"time_intervals"`
+}
+
+// ListTimeIntervals returns all the time intervals defined in the tenant's Alertmanager configuration.
+func (am *MultitenantAlertmanager) ListTimeIntervals(w http.ResponseWriter, r *http.Request) {
+	logger := util_log.WithContext(r.Context(), am.logger)
+
+	_, cfgDesc, doc, ok := am.getTimeIntervalsConfig(w, r, logger)
+	if !ok {
+		return
+	}
+
+	intervals, err := listTimeIntervals(cfgDesc.RawConfig, doc)
+	if err != nil {
+		level.Error(logger).Log("msg", errParsingConfig, "err", err)
+		http.Error(w, fmt.Sprintf("%s: %s", errParsingConfig, err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	writeTimeIntervalsResponse(w, logger, &UserTimeIntervals{TimeIntervals: intervals})
+}
+
+// GetTimeInterval returns a single time interval defined in the tenant's Alertmanager configuration.
+func (am *MultitenantAlertmanager) GetTimeInterval(w http.ResponseWriter, r *http.Request) {
+	logger := util_log.WithContext(r.Context(), am.logger)
+
+	name, ok := timeIntervalNameFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	_, cfgDesc, doc, ok := am.getTimeIntervalsConfig(w, r, logger)
+	if !ok {
+		return
+	}
+
+	intervals, err := listTimeIntervals(cfgDesc.RawConfig, doc)
+	if err != nil {
+		level.Error(logger).Log("msg", errParsingConfig, "err", err)
+		http.Error(w, fmt.Sprintf("%s: %s", errParsingConfig, err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	for _, interval := range intervals {
+		if interval.Name == name {
+			writeTimeIntervalsResponse(w, logger, interval)
+			return
+		}
+	}
+
+	http.Error(w, errTimeIntervalNotFound, http.StatusNotFound)
+}
+
+// SetTimeInterval creates or replaces a time interval in the tenant's Alertmanager configuration.
+// The rest of the configuration is left untouched.
+func (am *MultitenantAlertmanager) SetTimeInterval(w http.ResponseWriter, r *http.Request) {
+	logger := util_log.WithContext(r.Context(), am.logger)
+
+	name, ok := timeIntervalNameFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	userID, cfgDesc, doc, ok := am.getTimeIntervalsConfig(w, r, logger)
+	if !ok {
+		return
+	}
+
+	var input io.Reader = r.Body
+	maxConfigSize := am.limits.AlertmanagerMaxConfigSize(userID)
+	if maxConfigSize > 0 {
+		// The time interval can't be bigger than the whole configuration.
+		input = io.LimitReader(r.Body, int64(maxConfigSize)+1)
+	}
+
+	payload, err := io.ReadAll(input)
+	if err != nil {
+		level.Error(logger).Log("msg", errReadingConfiguration, "err", err.Error())
+		http.Error(w, fmt.Sprintf("%s: %s", errReadingConfiguration, err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	interval := timeIntervalInput{}
+	decoder := yaml.NewDecoder(bytes.NewReader(payload))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&interval); err != nil || interval.TimeIntervals.Kind == 0 {
+		if err == nil {
+			err = errors.New("time_intervals is missing")
+		}
+		http.Error(w, fmt.Sprintf("%s: %s", errTimeIntervalInvalid, err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	entry := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Content: []*yaml.Node{
+		{Kind: yaml.ScalarNode, Tag: "!!str", Value: "name"},
+		{Kind: yaml.ScalarNode, Tag: "!!str", Value: name},
+		{Kind: yaml.ScalarNode, Tag: "!!str", Value: timeIntervalsKey},
+		&interval.TimeIntervals,
+	}}
+
+	root := doc.Content[0]
+	created := false
+	if seq, idx := findTimeInterval(root, name); seq != nil {
+		seq.Content[idx] = entry
+	} else {
+		seq := mappingValue(root, timeIntervalsKey)
+		if seq == nil || seq.Kind != yaml.SequenceNode {
+			seq = &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+			setMappingValue(root, timeIntervalsKey, seq)
+		}
+		seq.Content = append(seq.Content, entry)
+		created = true
+	}
+
+	if !am.storeTimeIntervalsConfig(w, r, logger, userID, cfgDesc, doc) {
+		return
+	}
+
+	if created {
+		w.WriteHeader(http.StatusCreated)
+	} else {
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// DeleteTimeInterval removes a time interval from the tenant's Alertmanager configuration.
+// A time interval still referenced by any route can't be deleted.
+func (am *MultitenantAlertmanager) DeleteTimeInterval(w http.ResponseWriter, r *http.Request) {
+	logger := util_log.WithContext(r.Context(), am.logger)
+
+	name, ok := timeIntervalNameFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	userID, cfgDesc, doc, ok := am.getTimeIntervalsConfig(w, r, logger)
+	if !ok {
+		return
+	}
+
+	root := doc.Content[0]
+	seq, idx := findTimeInterval(root, name)
+	if seq == nil {
+		http.Error(w, errTimeIntervalNotFound, http.StatusNotFound)
+		return
+	}
+
+	amCfg, err := config.Load(cfgDesc.RawConfig)
+	if err != nil {
+		level.Error(logger).Log("msg", errParsingConfig, "err", err)
+		http.Error(w, fmt.Sprintf("%s: %s", errParsingConfig, err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	if refs := timeIntervalReferences(amCfg.Route)[name]; len(refs) > 0 {
+		routes := make([]string, 0, len(refs))
+		for _, ref := range refs {
+			routes = append(routes, ref.Route)
+		}
+		http.Error(w, fmt.Sprintf("%s: %s", errTimeIntervalStillReferred, strings.Join(routes, ", ")), http.StatusConflict)
+		return
+	}
+
+	seq.Content = append(seq.Content[:idx], seq.Content[idx+1:]...)
+	if len(seq.Content) == 0 {
+		for _, key := range []string{timeIntervalsKey, muteTimeIntervalsKey} {
+			if mappingValue(root, key) == seq {
+				deleteMappingValue(root, key)
+			}
+		}
+	}
+
+	if !am.storeTimeIntervalsConfig(w, r, logger, userID, cfgDesc, doc) {
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// getTimeIntervalsConfig reads the tenant's Alertmanager configuration from the store and parses it
+// into a YAML document. Editing the YAML document instead of the parsed Alertmanager configuration
+// preserves the rest of the configuration as is (e.g. secrets would be masked otherwise).
+// If the returned bool is false, an error has already been written to the response.
+func (am *MultitenantAlertmanager) getTimeIntervalsConfig(w http.ResponseWriter, r *http.Request, logger log.Logger) (string, alertspb.AlertConfigDesc, *yaml.Node, bool) {
+	userID, err := tenant.TenantID(r.Context())
+	if err != nil {
+		level.Error(logger).Log("msg", errNoOrgID, "err", err.Error())
+		http.Error(w, fmt.Sprintf("%s: %s", errNoOrgID, err.Error()), http.StatusUnauthorized)
+		return "", alertspb.AlertConfigDesc{}, nil, false
+	}
+
+	cfgDesc, err := am.store.GetAlertConfig(r.Context(), userID)
+	if err != nil {
+		if errors.Is(err, alertspb.ErrNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+		} else {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return "", alertspb.AlertConfigDesc{}, nil, false
+	}
+
+	doc := &yaml.Node{}
+	err = yaml.Unmarshal([]byte(cfgDesc.RawConfig), doc)
+	if err == nil && (len(doc.Content) != 1 || doc.Content[0].Kind != yaml.MappingNode) {
+		err = errors.New("the configuration is not a YAML mapping")
+	}
+	if err != nil {
+		level.Error(logger).Log("msg", errParsingConfig, "err", err, "user", userID)
+		http.Error(w, fmt.Sprintf("%s: %s", errParsingConfig, err.Error()), http.StatusInternalServerError)
+		return "", alertspb.AlertConfigDesc{}, nil, false
+	}
+
+	return userID, cfgDesc, doc, true
+}
+
+// storeTimeIntervalsConfig validates and stores the updated YAML document as the tenant's Alertmanager
+// configuration. If the returned bool is false, an error has already been written to the response.
+func (am *MultitenantAlertmanager) storeTimeIntervalsConfig(w http.ResponseWriter, r *http.Request, logger log.Logger, userID string, cfgDesc alertspb.AlertConfigDesc, doc *yaml.Node) bool {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(doc); err != nil {
+		level.Error(logger).Log("msg", errMarshallingYAML, "err", err, "user", userID)
+		http.Error(w, fmt.Sprintf("%s: %s", errMarshallingYAML, err.Error()), http.StatusInternalServerError)
+		return false
+	}
+	if err := encoder.Close(); err != nil {
+		level.Error(logger).Log("msg", errMarshallingYAML, "err", err, "user", userID)
+		http.Error(w, fmt.Sprintf("%s: %s", errMarshallingYAML, err.Error()), http.StatusInternalServerError)
+		return false
+	}
+
+	if maxConfigSize := am.limits.AlertmanagerMaxConfigSize(userID); maxConfigSize > 0 && buf.Len() > maxConfigSize {
+		msg := fmt.Sprintf(errConfigurationTooBig, maxConfigSize)
+		level.Warn(logger).Log("msg", msg)
+		http.Error(w, msg, http.StatusBadRequest)
+		return false
+	}
+
+	cfgDesc.RawConfig = buf.String()
+	if err := validateUserConfig(logger, cfgDesc, am.limits, userID, am.cfg.UTF8MigrationLogging); err != nil {
+		level.Warn(logger).Log("msg", errValidatingConfig, "err", err.Error())
+		http.Error(w, fmt.Sprintf("%s: %s", errValidatingConfig, err.Error()), http.StatusBadRequest)
+		return false
+	}
+
+	if err := am.storeUserConfig(r.Context(), logger, r, cfgDesc); err != nil {
+		level.Error(logger).Log("msg", errStoringConfiguration, "err", err.Error())
+		http.Error(w, fmt.Sprintf("%s: %s", errStoringConfiguration, err.Error()), http.StatusInternalServerError)
+		return false
+	}
+
+	return true
+}
+
+func timeIntervalNameFromRequest(w http.ResponseWriter, r *http.Request) (string, bool) {
+	name := mux.Vars(r)["name"]
+	if name == "" {
+		http.Error(w, errTimeIntervalNameMissing, http.StatusBadRequest)
+		return "", false
+	}
+	return name, true
+}
+
+func writeTimeIntervalsResponse(w http.ResponseWriter, logger log.Logger, v interface{}) {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(v); err != nil {
+		level.Error(logger).Log("msg", errMarshallingYAML, "err", err)
+		http.Error(w, fmt.Sprintf("%s: %s", errMarshallingYAML, err.Error()), http.StatusInternalServerError)
+		return
+	}
+	_ = encoder.Close()
+
+	w.Header().Set("Content-Type", "application/yaml")
+	if _, err := w.Write(buf.Bytes()); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// listTimeIntervals returns the time intervals defined in the configuration, both under
+// "time_intervals" and the deprecated "mute_time_intervals", with the routes referencing them.
+func listTimeIntervals(rawConfig string, doc *yaml.Node) ([]*UserTimeInterval, error) {
+	amCfg, err := config.Load(rawConfig)
+	if err != nil {
+		return nil, err
+	}
+	refs := timeIntervalReferences(amCfg.Route)
+
+	intervals := []*UserTimeInterval{}
+	root := doc.Content[0]
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if key := root.Content[i].Value; key != timeIntervalsKey && key != muteTimeIntervalsKey {
+			continue
+		}
+		if root.Content[i+1].Kind != yaml.SequenceNode {
+			continue
+		}
+
+		for _, entry := range root.Content[i+1].Content {
+			name := mappingValue(entry, "name")
+			if name == nil {
+				continue
+			}
+
+			interval := &UserTimeInterval{
+				Name:         name.Value,
+				ReferencedBy: refs[name.Value],
+			}
+			if ti := mappingValue(entry, timeIntervalsKey); ti != nil {
+				interval.TimeIntervals = *ti
+			}
+			intervals = append(intervals, interval)
+		}
+	}
+
+	return intervals, nil
+}
+
+// findTimeInterval returns the sequence node containing the time interval with the input name, and
+// the index of the time interval within the sequence. The returned sequence is nil if not found.
+func findTimeInterval(root *yaml.Node, name string) (*yaml.Node, int) {
+	for _, key := range []string{timeIntervalsKey, muteTimeIntervalsKey} {
+		seq := mappingValue(root, key)
+		if seq == nil || seq.Kind != yaml.SequenceNode {
+			continue
+		}
+
+		for idx, entry := range seq.Content {
+			if n := mappingValue(entry, "name"); n != nil && n.Value == name {
+				return seq, idx
+			}
+		}
+	}
+
+	return nil, 0
+}
+
+// timeIntervalReferences returns the routes referencing each time interval, by time interval name.
+func timeIntervalReferences(route *config.Route) map[string][]TimeIntervalReference {
+	refs := map[string][]TimeIntervalReference{}
+
+	var walk func(r *config.Route, path string)
+	walk = func(r *config.Route, path string) {
+		if r == nil {
+			return
+		}
+
+		matchers := make([]string, 0, len(r.Matchers))
+		for _, m := range r.Matchers {
+			matchers = append(matchers, m.String())
+		}
+
+		for _, usage := range []struct {
+			names []string
+			usage string
+		}{
+			{names: r.MuteTimeIntervals, usage: "mute_time_intervals"},
+			{names: r.ActiveTimeIntervals, usage: "active_time_intervals"},
+		} {
+			for _, name := range usage.names {
+				refs[name] = append(refs[name], TimeIntervalReference{
+					Route:    path,
+					Receiver: r.Receiver,
+					Matchers: matchers,
+					Usage:    usage.usage,
+				})
+			}
+		}
+
+		for i, child := range r.Routes {
+			walk(child, fmt.Sprintf("%s.routes[%d]", path, i))
+		}
+	}
+	walk(route, "route")
+
+	return refs
+}
+
+func mappingValue(mapping *yaml.Node, key string) *yaml.Node {
+	if mapping.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+	return nil
+}
+
+func setMappingValue(mapping *yaml.Node, key string, value *yaml.Node) {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			mapping.Content[i+1] = value
+			return
+		}
+	}
+	mapping.Content = append(mapping.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, value)
+}
+
+func deleteMappingValue(mapping *yaml.Node, key string) {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			mapping.Content = append(mapping.Content[:i], mapping.Content[i+2:]...)
+			return
+		}
+	}
+}
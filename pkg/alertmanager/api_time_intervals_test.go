@@ -0,0 +1,161 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package alertmanager
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/grafana/dskit/user"
+	"github.com/prometheus/alertmanager/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+
+	"github.com/grafana/mimir/pkg/alertmanager/alertspb"
+	"github.com/grafana/mimir/pkg/util/test"
+)
+
+func TestMultitenantAlertmanager_TimeIntervalsAPI(t *testing.T) {
+	const (
+		userID    = "user-1"
+		rawConfig = `
+route:
+  receiver: default
+  routes:
+    - receiver: team
+      matchers:
+        - team="a"
+      mute_time_intervals:
+        - maintenance
+receivers:
+  - name: default
+    slack_configs:
+      - api_url: http://slack.example.com/secret
+        channel: alerts
+  - name: team
+time_intervals:
+  - name: maintenance
+    time_intervals:
+      - weekdays: ['saturday']
+mute_time_intervals:
+  - name: weekends
+    time_intervals:
+      - weekdays: ['saturday', 'sunday']
+`
+	)
+
+	store := prepareInMemoryAlertStore()
+	am := &MultitenantAlertmanager{
+		cfg:    mockAlertmanagerConfig(t),
+		store:  store,
+		logger: test.NewTestingLogger(t),
+		limits: &mockAlertManagerLimits{},
+	}
+
+	router := mux.NewRouter()
+	router.Path("/api/v1/alerts/time_intervals").Methods(http.MethodGet).HandlerFunc(am.ListTimeIntervals)
+	router.Path("/api/v1/alerts/time_intervals/{name}").Methods(http.MethodGet).HandlerFunc(am.GetTimeInterval)
+	router.Path("/api/v1/alerts/time_intervals/{name}").Methods(http.MethodPut).HandlerFunc(am.SetTimeInterval)
+	router.Path("/api/v1/alerts/time_intervals/{name}").Methods(http.MethodDelete).HandlerFunc(am.DeleteTimeInterval)
+
+	do := func(method, path, body string) (int, string) {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req = req.WithContext(user.InjectOrgID(context.Background(), userID))
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		resp, err := io.ReadAll(rec.Result().Body)
+		require.NoError(t, err)
+		return rec.Code, string(resp)
+	}
+
+	// No configuration has been uploaded for the tenant yet.
+	code, _ := do(http.MethodGet, "/api/v1/alerts/time_intervals", "")
+	require.Equal(t, http.StatusNotFound, code)
+
+	require.NoError(t, store.SetAlertConfig(context.Background(), alertspb.AlertConfigDesc{User: userID, RawConfig: rawConfig}))
+
+	t.Run("list time intervals", func(t *testing.T) {
+		code, body := do(http.MethodGet, "/api/v1/alerts/time_intervals", "")
+		require.Equal(t, http.StatusOK, code)
+
+		res := UserTimeIntervals{}
+		require.NoError(t, yaml.Unmarshal([]byte(body), &res))
+		require.Len(t, res.TimeIntervals, 2)
+
+		assert.Equal(t, "maintenance", res.TimeIntervals[0].Name)
+		assert.Equal(t, []TimeIntervalReference{{
+			Route:    "route.routes[0]",
+			Receiver: "team",
+			Matchers: []string{`team="a"`},
+			Usage:    "mute_time_intervals",
+		}}, res.TimeIntervals[0].ReferencedBy)
+
+		assert.Equal(t, "weekends", res.TimeIntervals[1].Name)
+		assert.Empty(t, res.TimeIntervals[1].ReferencedBy)
+	})
+
+	t.Run("get a time interval", func(t *testing.T) {
+		code, body := do(http.MethodGet, "/api/v1/alerts/time_intervals/weekends", "")
+		require.Equal(t, http.StatusOK, code)
+		assert.Contains(t, body, "sunday")
+
+		code, _ = do(http.MethodGet, "/api/v1/alerts/time_intervals/unknown", "")
+		require.Equal(t, http.StatusNotFound, code)
+	})
+
+	t.Run("create and replace time intervals", func(t *testing.T) {
+		code, _ := do(http.MethodPut, "/api/v1/alerts/time_intervals/holidays", "time_intervals:\n  - months: ['december']\n")
+		require.Equal(t, http.StatusCreated, code)
+
+		code, _ = do(http.MethodPut, "/api/v1/alerts/time_intervals/weekends", "time_intervals:\n  - weekdays: ['sunday']\n")
+		require.Equal(t, http.StatusOK, code)
+
+		cfgDesc, err := store.GetAlertConfig(context.Background(), userID)
+		require.NoError(t, err)
+
+		amCfg, err := config.Load(cfgDesc.RawConfig)
+		require.NoError(t, err)
+		require.Len(t, amCfg.TimeIntervals, 2)
+		assert.Equal(t, "holidays", amCfg.TimeIntervals[1].Name)
+		require.Len(t, amCfg.MuteTimeIntervals, 1)
+		require.Len(t, amCfg.MuteTimeIntervals[0].TimeIntervals[0].Weekdays, 1)
+
+		// The rest of the configuration, including secrets, is preserved.
+		assert.Contains(t, cfgDesc.RawConfig, "http://slack.example.com/secret")
+	})
+
+	t.Run("reject invalid time intervals", func(t *testing.T) {
+		code, _ := do(http.MethodPut, "/api/v1/alerts/time_intervals/invalid", "time_intervals:\n  - weekdays: ['someday']\n")
+		require.Equal(t, http.StatusBadRequest, code)
+
+		code, _ = do(http.MethodPut, "/api/v1/alerts/time_intervals/invalid", "unknown_field: true\n")
+		require.Equal(t, http.StatusBadRequest, code)
+
+		code, _ = do(http.MethodGet, "/api/v1/alerts/time_intervals/invalid", "")
+		require.Equal(t, http.StatusNotFound, code)
+	})
+
+	t.Run("delete time intervals", func(t *testing.T) {
+		code, body := do(http.MethodDelete, "/api/v1/alerts/time_intervals/maintenance", "")
+		require.Equal(t, http.StatusConflict, code)
+		assert.Contains(t, body, "route.routes[0]")
+
+		code, _ = do(http.MethodDelete, "/api/v1/alerts/time_intervals/weekends", "")
+		require.Equal(t, http.StatusOK, code)
+
+		code, _ = do(http.MethodDelete, "/api/v1/alerts/time_intervals/weekends", "")
+		require.Equal(t, http.StatusNotFound, code)
+
+		cfgDesc, err := store.GetAlertConfig(context.Background(), userID)
+		require.NoError(t, err)
+		assert.NotContains(t, cfgDesc.RawConfig, "mute_time_intervals:\n  -")
+		assert.NotContains(t, cfgDesc.RawConfig, "weekends")
+	})
+}
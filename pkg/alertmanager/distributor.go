@@ -43,11 +43,18 @@ type Distributor struct {
 	alertmanagerRing        ring.ReadRing
 	alertmanagerClientsPool ClientsPool
 
+	// validateAlerts, if set, validates the body of the requests posting alerts before they're forwarded
+	// to the alertmanagers.
+	validateAlerts PostedAlertsValidator
+
 	logger log.Logger
 }
 
+// PostedAlertsValidator validates the body of a request posting alerts for the tenant.
+type PostedAlertsValidator func(userID string, body []byte) error
+
 // NewDistributor constructs a new Distributor
-func NewDistributor(cfg ClientConfig, maxRecvMsgSize int64, alertmanagersRing *ring.Ring, alertmanagerClientsPool ClientsPool, logger log.Logger, reg prometheus.Registerer) (d *Distributor, err error) {
+func NewDistributor(cfg ClientConfig, maxRecvMsgSize int64, alertmanagersRing *ring.Ring, alertmanagerClientsPool ClientsPool, validateAlerts PostedAlertsValidator, logger log.Logger, reg prometheus.Registerer) (d *Distributor, err error) {
 	if alertmanagerClientsPool == nil {
 		alertmanagerClientsPool = newAlertmanagerClientsPool(client.NewRingServiceDiscovery(alertmanagersRing), cfg, logger, reg)
 	}
@@ -58,6 +65,7 @@ func NewDistributor(cfg ClientConfig, maxRecvMsgSize int64, alertmanagersRing *r
 		maxRecvMsgSize:          maxRecvMsgSize,
 		alertmanagerRing:        alertmanagersRing,
 		alertmanagerClientsPool: alertmanagerClientsPool,
+		validateAlerts:          validateAlerts,
 	}
 
 	d.Service = services.NewBasicService(nil, d.running, nil)
@@ -84,6 +92,12 @@ func (d *Distributor) isUnaryWritePath(p string) bool {
 	if strings.HasSuffix(p, "/api/v1/grafana/receivers/test") {
 		return true
 	}
+	if strings.HasSuffix(p, "/api/v1/alerts/test") {
+		return true
+	}
+	if strings.HasSuffix(p, "/api/v1/receivers/test") {
+		return true
+	}
 	return false
 }
 
@@ -134,7 +148,7 @@ func (d *Distributor) DistributeRequest(w http.ResponseWriter, r *http.Request)
 
 	if r.Method == http.MethodPost {
 		if d.isQuorumWritePath(r.URL.Path) {
-			d.doQuorum(userID, w, r, logger, merger.Noop{})
+			d.doQuorumWrite(userID, w, r, logger)
 			return
 		}
 		if d.isUnaryWritePath(r.URL.Path) {
@@ -237,26 +251,56 @@ func (d *Distributor) doAll(userID string, w http.ResponseWriter, r *http.Reques
 	}
 }
 
-func (d *Distributor) doQuorum(userID string, w http.ResponseWriter, r *http.Request, logger log.Logger, m merger.Merger) {
-	var body []byte
-	var err error
-	if r.Body != nil {
-		body, err = io.ReadAll(http.MaxBytesReader(w, r.Body, d.maxRecvMsgSize))
-		if err != nil {
-			if util.IsRequestBodyTooLarge(err) {
-				http.Error(w, "Request body too large", http.StatusRequestEntityTooLarge)
-				return
-			}
-			level.Error(logger).Log("msg", "failed to read the request body during write", "err", err)
-			w.WriteHeader(http.StatusInternalServerError)
+// doQuorumWrite forwards the request posting alerts to the alertmanagers, once the alerts have been validated.
+func (d *Distributor) doQuorumWrite(userID string, w http.ResponseWriter, r *http.Request, logger log.Logger) {
+	body, ok := d.readBody(w, r, logger)
+	if !ok {
+		return
+	}
+
+	if d.validateAlerts != nil && len(body) > 0 {
+		if err := d.validateAlerts(userID, body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
 	}
 
+	d.doQuorumWithBody(userID, w, r, body, logger, merger.Noop{})
+}
+
+func (d *Distributor) doQuorum(userID string, w http.ResponseWriter, r *http.Request, logger log.Logger, m merger.Merger) {
+	body, ok := d.readBody(w, r, logger)
+	if !ok {
+		return
+	}
+
+	d.doQuorumWithBody(userID, w, r, body, logger, m)
+}
+
+// readBody reads the body of the request, if any. If the body can't be read, it responds with an error and returns false.
+func (d *Distributor) readBody(w http.ResponseWriter, r *http.Request, logger log.Logger) ([]byte, bool) {
+	if r.Body == nil {
+		return nil, true
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, d.maxRecvMsgSize))
+	if err != nil {
+		if util.IsRequestBodyTooLarge(err) {
+			http.Error(w, "Request body too large", http.StatusRequestEntityTooLarge)
+			return nil, false
+		}
+		level.Error(logger).Log("msg", "failed to read the request body during write", "err", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return nil, false
+	}
+	return body, true
+}
+
+func (d *Distributor) doQuorumWithBody(userID string, w http.ResponseWriter, r *http.Request, body []byte, logger log.Logger, m merger.Merger) {
 	var responses []*httpgrpc.HTTPResponse
 	var responsesMtx sync.Mutex
 	grpcHeaders := httpToHttpgrpcHeaders(r.Header)
-	err = ring.DoBatchWithOptions(r.Context(), RingOp, d.alertmanagerRing, []uint32{shardByUser(userID)}, func(am ring.InstanceDesc, _ []int) error {
+	err := ring.DoBatchWithOptions(r.Context(), RingOp, d.alertmanagerRing, []uint32{shardByUser(userID)}, func(am ring.InstanceDesc, _ []int) error {
 		// Use a background context to make sure all alertmanagers get the request even if we return early.
 		localCtx := user.InjectOrgID(context.Background(), userID)
 		sp, localCtx := opentracing.StartSpanFromContext(localCtx, "Distributor.doQuorum")
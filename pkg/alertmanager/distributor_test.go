@@ -54,6 +54,7 @@ func TestDistributor_DistributeRequest(t *testing.T) {
 		expectedTotalCalls  int
 		headersNotPreserved bool
 		route               string
+		validateAlerts      PostedAlertsValidator
 		// Paths where responses are merged, we need to supply a valid response body.
 		// Note that the actual merging logic is tested elsewhere (merger_test.go).
 		responseBody []byte
@@ -90,6 +91,32 @@ func TestDistributor_DistributeRequest(t *testing.T) {
 			expStatusCode:      http.StatusOK,
 			expectedTotalCalls: 3,
 			route:              "/alerts",
+		}, {
+			name:                "Write /alerts, alerts rejected by the validator",
+			numAM:               3,
+			numHappyAM:          3,
+			replicationFactor:   3,
+			expStatusCode:       http.StatusBadRequest,
+			expectedTotalCalls:  0,
+			headersNotPreserved: true,
+			route:               "/alerts",
+			validateAlerts: func(string, []byte) error {
+				return errors.New("invalid alert")
+			},
+		}, {
+			name:               "Write /v2/alerts, alerts accepted by the validator",
+			numAM:              3,
+			numHappyAM:         3,
+			replicationFactor:  3,
+			expStatusCode:      http.StatusOK,
+			expectedTotalCalls: 3,
+			route:              "/v2/alerts",
+			validateAlerts: func(userID string, body []byte) error {
+				if userID != "1" || !bytes.Equal(body, []byte{1, 2, 3, 4}) {
+					return errors.New("unexpected request")
+				}
+				return nil
+			},
 		}, {
 			name:               "Read /v2/alerts is sent to 3 AMs",
 			numAM:              5,
@@ -227,7 +254,7 @@ func TestDistributor_DistributeRequest(t *testing.T) {
 	for _, c := range cases {
 		t.Run(c.name, func(t *testing.T) {
 			route := "/alertmanager/api/v1" + c.route
-			d, ams, cleanup := prepare(t, c.numAM, c.numHappyAM, c.replicationFactor, c.responseBody)
+			d, ams, cleanup := prepare(t, c.numAM, c.numHappyAM, c.replicationFactor, c.responseBody, c.validateAlerts)
 			t.Cleanup(cleanup)
 
 			ctx := user.InjectOrgID(context.Background(), "1")
@@ -278,7 +305,7 @@ func TestDistributor_DistributeRequest(t *testing.T) {
 
 }
 
-func prepare(t *testing.T, numAM, numHappyAM, replicationFactor int, responseBody []byte) (*Distributor, []*mockAlertmanager, func()) {
+func prepare(t *testing.T, numAM, numHappyAM, replicationFactor int, responseBody []byte, validateAlerts PostedAlertsValidator) (*Distributor, []*mockAlertmanager, func()) {
 	ams := []*mockAlertmanager{}
 	for i := 0; i < numHappyAM; i++ {
 		ams = append(ams, newMockAlertmanager(i, true, responseBody))
@@ -330,7 +357,7 @@ func prepare(t *testing.T, numAM, numHappyAM, replicationFactor int, responseBod
 	cfg := &MultitenantAlertmanagerConfig{}
 	flagext.DefaultValues(cfg)
 
-	d, err := NewDistributor(cfg.AlertmanagerClient, cfg.MaxRecvMsgSize, amRing, newMockAlertmanagerClientFactory(amByAddr), utiltest.NewTestingLogger(t), prometheus.NewRegistry())
+	d, err := NewDistributor(cfg.AlertmanagerClient, cfg.MaxRecvMsgSize, amRing, newMockAlertmanagerClientFactory(amByAddr), validateAlerts, utiltest.NewTestingLogger(t), prometheus.NewRegistry())
 	require.NoError(t, err)
 	require.NoError(t, services.StartAndAwaitRunning(context.Background(), d))
 
@@ -37,6 +37,7 @@ import (
 	"github.com/prometheus/alertmanager/featurecontrol"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/common/model"
 	"golang.org/x/time/rate"
 	"gopkg.in/yaml.v3"
 
@@ -84,6 +85,8 @@ type MultitenantAlertmanagerConfig struct {
 	EnableAPI                               bool `yaml:"enable_api" category:"advanced"`
 	GrafanaAlertmanagerCompatibilityEnabled bool `yaml:"grafana_alertmanager_compatibility_enabled" category:"experimental"`
 
+	MaxConfigVersions int `yaml:"max_config_versions" category:"experimental"`
+
 	MaxConcurrentGetRequestsPerTenant int `yaml:"max_concurrent_get_requests_per_tenant" category:"advanced"`
 
 	// For distributor.
@@ -92,6 +95,8 @@ type MultitenantAlertmanagerConfig struct {
 	// For the state persister.
 	Persister PersisterConfig `yaml:",inline"`
 
+	NotificationRetryQueue NotificationRetryQueueConfig `yaml:"notification_retry_queue"`
+
 	// Allow disabling of full_state object cleanup.
 	EnableStateCleanup bool `yaml:"enable_state_cleanup" category:"advanced"`
 
@@ -126,12 +131,14 @@ func (cfg *MultitenantAlertmanagerConfig) RegisterFlags(f *flag.FlagSet, logger
 
 	f.BoolVar(&cfg.EnableAPI, "alertmanager.enable-api", true, "Enable the alertmanager config API.")
 	f.BoolVar(&cfg.GrafanaAlertmanagerCompatibilityEnabled, "alertmanager.grafana-alertmanager-compatibility-enabled", false, "Enable routes to support the migration and operation of the Grafana Alertmanager.")
+	f.IntVar(&cfg.MaxConfigVersions, "alertmanager.max-config-versions", 0, "Maximum number of versions of each tenant's Alertmanager configuration to keep in the storage. A new version is stored every time the configuration is changed through the config API, and tenants can roll back to any of the stored versions. 0 to disable config versioning.")
 	f.IntVar(&cfg.MaxConcurrentGetRequestsPerTenant, "alertmanager.max-concurrent-get-requests-per-tenant", 0, "Maximum number of concurrent GET requests allowed per tenant. The zero value (and negative values) result in a limit of GOMAXPROCS or 8, whichever is larger. Status code 503 is served for GET requests that would exceed the concurrency limit.")
 
 	f.BoolVar(&cfg.EnableStateCleanup, "alertmanager.enable-state-cleanup", true, "Enables periodic cleanup of alertmanager stateful data (notification logs and silences) from object storage. When enabled, data is removed for any tenant that does not have a configuration.")
 
 	cfg.AlertmanagerClient.RegisterFlagsWithPrefix("alertmanager.alertmanager-client", f)
 	cfg.Persister.RegisterFlagsWithPrefix("alertmanager", f)
+	cfg.NotificationRetryQueue.RegisterFlagsWithPrefix("alertmanager.notification-retry-queue", f)
 	cfg.ShardingRing.RegisterFlags(f, logger)
 
 	f.DurationVar(&cfg.PeerTimeout, "alertmanager.peer-timeout", defaultPeerTimeout, "Time to wait between peers to send notifications.")
@@ -166,6 +173,10 @@ func (cfg *MultitenantAlertmanagerConfig) Validate() error {
 		return err
 	}
 
+	if err := cfg.NotificationRetryQueue.Validate(); err != nil {
+		return err
+	}
+
 	if cfg.ShardingRing.ZoneAwarenessEnabled && cfg.ShardingRing.InstanceZone == "" {
 		return errZoneAwarenessEnabledWithoutZoneInfo
 	}
@@ -262,6 +273,12 @@ type Limits interface {
 	// AlertmanagerMaxAlertsSizeBytes returns total max size of alerts that tenant can have active at the same time. 0 = no limit.
 	// Size of the alert is computed from alert labels, annotations and generator URL.
 	AlertmanagerMaxAlertsSizeBytes(tenant string) int
+
+	// AlertmanagerMaxAlertsPerNotification returns max number of alerts sent in a single notification by the receiver. 0 = no limit.
+	AlertmanagerMaxAlertsPerNotification(tenant, receiver string) int
+
+	// AlertmanagerAlertLabelValidationScheme returns the validation scheme of the label names and values of the alerts.
+	AlertmanagerAlertLabelValidationScheme(tenant string) model.ValidationScheme
 }
 
 // A MultitenantAlertmanager manages Alertmanager instances for multiple
@@ -443,7 +460,7 @@ func createMultitenantAlertmanager(cfg *MultitenantAlertmanagerConfig, fallbackC
 	am.grpcServer = server.NewServer(&handlerForGRPCServer{am: am}, server.WithReturn4XXErrors)
 
 	am.alertmanagerClientsPool = newAlertmanagerClientsPool(client.NewRingServiceDiscovery(am.ring), cfg.AlertmanagerClient, logger, am.registry)
-	am.distributor, err = NewDistributor(cfg.AlertmanagerClient, cfg.MaxRecvMsgSize, am.ring, am.alertmanagerClientsPool, log.With(logger, "component", "AlertmanagerDistributor"), am.registry)
+	am.distributor, err = NewDistributor(cfg.AlertmanagerClient, cfg.MaxRecvMsgSize, am.ring, am.alertmanagerClientsPool, am.validatePostedAlerts, log.With(logger, "component", "AlertmanagerDistributor"), am.registry)
 	if err != nil {
 		return nil, errors.Wrap(err, "create distributor")
 	}
@@ -929,6 +946,7 @@ func (am *MultitenantAlertmanager) newAlertmanager(userID string, amConfig *defi
 		ReplicationFactor:                 am.cfg.ShardingRing.ReplicationFactor,
 		Store:                             am.store,
 		PersisterConfig:                   am.cfg.Persister,
+		NotificationRetryQueue:            am.cfg.NotificationRetryQueue,
 		Limits:                            am.limits,
 		Features:                          am.features,
 		GrafanaAlertmanagerCompatibility:  am.cfg.GrafanaAlertmanagerCompatibilityEnabled,
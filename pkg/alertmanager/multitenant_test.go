@@ -3301,6 +3301,8 @@ type mockAlertManagerLimits struct {
 	maxDispatcherAggregationGroups int
 	maxAlertsCount                 int
 	maxAlertsSizeBytes             int
+	maxAlertsPerNotification       int
+	alertLabelValidationScheme     model.ValidationScheme
 }
 
 func (m *mockAlertManagerLimits) AlertmanagerMaxConfigSize(string) int {
@@ -3352,3 +3354,11 @@ func (m *mockAlertManagerLimits) AlertmanagerMaxAlertsCount(_ string) int {
 func (m *mockAlertManagerLimits) AlertmanagerMaxAlertsSizeBytes(_ string) int {
 	return m.maxAlertsSizeBytes
 }
+
+func (m *mockAlertManagerLimits) AlertmanagerMaxAlertsPerNotification(_, _ string) int {
+	return m.maxAlertsPerNotification
+}
+
+func (m *mockAlertManagerLimits) AlertmanagerAlertLabelValidationScheme(_ string) model.ValidationScheme {
+	return m.alertLabelValidationScheme
+}
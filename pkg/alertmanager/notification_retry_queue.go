@@ -0,0 +1,531 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package alertmanager
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/grafana/dskit/concurrency"
+	"github.com/oklog/ulid"
+	"github.com/pkg/errors"
+	"github.com/prometheus/alertmanager/nflog"
+	"github.com/prometheus/alertmanager/nflog/nflogpb"
+	"github.com/prometheus/alertmanager/notify"
+	"github.com/prometheus/alertmanager/types"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/common/model"
+
+	"github.com/grafana/mimir/pkg/util/atomicfs"
+)
+
+const (
+	// notificationRetryQueueDir is the directory, within the tenant directory, where the queued notifications are stored.
+	notificationRetryQueueDir = "notification-retry-queue"
+
+	// notificationRetryQueueCheckInterval is how frequently the queue is checked for notifications to retry.
+	notificationRetryQueueCheckInterval = time.Second
+
+	// notificationRetryTimeout is the timeout of a single delivery attempt of a queued notification.
+	notificationRetryTimeout = 30 * time.Second
+
+	// notificationRetryConcurrency is the max number of queued notifications retried concurrently.
+	notificationRetryConcurrency = 16
+
+	notificationRetryDroppedReasonMaxAttempts     = "max_attempts"
+	notificationRetryDroppedReasonNonRetryable    = "non_retryable"
+	notificationRetryDroppedReasonReceiverRemoved = "receiver_removed"
+	notificationRetryDroppedReasonSuperseded      = "superseded"
+)
+
+var (
+	errInvalidNotificationRetryQueueBackoff     = errors.New("invalid alertmanager notification retry queue backoff, the min backoff must be greater than zero and less than or equal to the max backoff")
+	errInvalidNotificationRetryQueueMaxAttempts = errors.New("invalid alertmanager notification retry queue max attempts, must be greater than zero")
+	errInvalidNotificationRetryQueueMaxQueued   = errors.New("invalid alertmanager notification retry queue max queued notifications per receiver, must be greater than zero")
+
+	errNotificationRetryQueueFull = errors.New("the notification retry queue for the receiver is full")
+)
+
+type NotificationRetryQueueConfig struct {
+	Enabled              bool          `yaml:"enabled" category:"experimental"`
+	MinBackoff           time.Duration `yaml:"min_backoff" category:"experimental"`
+	MaxBackoff           time.Duration `yaml:"max_backoff" category:"experimental"`
+	MaxAttempts          int           `yaml:"max_attempts" category:"experimental"`
+	MaxQueuedPerReceiver int           `yaml:"max_queued_per_receiver" category:"experimental"`
+}
+
+func (cfg *NotificationRetryQueueConfig) RegisterFlagsWithPrefix(prefix string, f *flag.FlagSet) {
+	f.BoolVar(&cfg.Enabled, prefix+".enabled", false, "Enable the durable retry queue for notifications. When enabled, notifications which failed to be delivered with a retryable error are stored on disk, in the Alertmanager storage path, and retried with an exponential backoff, also across Alertmanager restarts.")
+	f.DurationVar(&cfg.MinBackoff, prefix+".min-backoff", 5*time.Second, "Minimum backoff before retrying a queued notification.")
+	f.DurationVar(&cfg.MaxBackoff, prefix+".max-backoff", 5*time.Minute, "Maximum backoff before retrying a queued notification.")
+	f.IntVar(&cfg.MaxAttempts, prefix+".max-attempts", 10, "Maximum number of delivery attempts of a queued notification, after which the notification is dropped.")
+	f.IntVar(&cfg.MaxQueuedPerReceiver, prefix+".max-queued-per-receiver", 100, "Maximum number of queued notifications per receiver. When the limit is reached, failed notifications are not queued and are only retried in memory.")
+}
+
+func (cfg *NotificationRetryQueueConfig) Validate() error {
+	if !cfg.Enabled {
+		return nil
+	}
+	if cfg.MinBackoff <= 0 || cfg.MinBackoff > cfg.MaxBackoff {
+		return errInvalidNotificationRetryQueueBackoff
+	}
+	if cfg.MaxAttempts <= 0 {
+		return errInvalidNotificationRetryQueueMaxAttempts
+	}
+	if cfg.MaxQueuedPerReceiver <= 0 {
+		return errInvalidNotificationRetryQueueMaxQueued
+	}
+	return nil
+}
+
+// queuedNotification is a notification waiting to be retried, as stored on disk.
+type queuedNotification struct {
+	ID          string         `json:"id"`
+	Receiver    string         `json:"receiver"`
+	Integration string         `json:"integration"`
+	Index       int            `json:"index"`
+	GroupKey    string         `json:"group_key"`
+	GroupLabels model.LabelSet `json:"group_labels"`
+	Alerts      []*types.Alert `json:"alerts"`
+
+	// FiringAlerts, ResolvedAlerts and Expiry are recorded in the notification log once the notification
+	// is delivered, like the notification pipeline does for the notifications delivered without being queued.
+	FiringAlerts   []uint64      `json:"firing_alerts"`
+	ResolvedAlerts []uint64      `json:"resolved_alerts"`
+	Expiry         time.Duration `json:"expiry"`
+
+	// QueuedAt is when the notification has been queued. A queued notification is superseded, and dropped,
+	// once a newer notification of the same group is queued or delivered.
+	QueuedAt time.Time `json:"queued_at"`
+
+	// Attempts is the number of delivery attempts done so far, including the one which failed
+	// before the notification was queued.
+	Attempts      int       `json:"attempts"`
+	NextAttemptAt time.Time `json:"next_attempt_at"`
+}
+
+// notificationRetryQueue is a disk-backed queue of notifications whose delivery failed with a
+// retryable error. Queued notifications are periodically retried with an exponential backoff,
+// until they're delivered, superseded by a newer notification of the same group, or the retry
+// budget is exhausted. A queued notification is recorded in the notification log only once it's
+// delivered, so that the notification pipeline keeps notifying the group in the meanwhile.
+// Nil queue doesn't wrap notifiers.
+type notificationRetryQueue struct {
+	cfg    NotificationRetryQueueConfig
+	dir    string
+	nflog  notify.NotificationLog
+	logger log.Logger
+
+	mtx     sync.Mutex
+	entries map[string]*queuedNotification
+
+	// notifiers are the notifiers of the currently loaded configuration, by retryNotifierKey().
+	notifiersMtx sync.RWMutex
+	notifiers    map[string]notify.Notifier
+
+	queueLength prometheus.Gauge
+	queued      *prometheus.CounterVec
+	retries     *prometheus.CounterVec
+	dropped     *prometheus.CounterVec
+}
+
+func newNotificationRetryQueue(cfg NotificationRetryQueueConfig, tenantDir string, nflog notify.NotificationLog, logger log.Logger, reg prometheus.Registerer) (*notificationRetryQueue, error) {
+	q := &notificationRetryQueue{
+		cfg:     cfg,
+		dir:     filepath.Join(tenantDir, notificationRetryQueueDir),
+		nflog:   nflog,
+		logger:  log.With(logger, "component", "notification_retry_queue"),
+		entries: map[string]*queuedNotification{},
+		queueLength: promauto.With(reg).NewGauge(prometheus.GaugeOpts{
+			Name: "alertmanager_notification_retry_queue_length",
+			Help: "Number of notifications waiting to be retried.",
+		}),
+		queued: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "alertmanager_notification_retry_queue_queued_total",
+			Help: "Number of notifications queued to be retried per integration.",
+		}, []string{"integration"}),
+		retries: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "alertmanager_notification_retry_queue_retries_total",
+			Help: "Number of delivery attempts of queued notifications per integration.",
+		}, []string{"integration"}),
+		dropped: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "alertmanager_notification_retry_queue_dropped_total",
+			Help: "Number of queued notifications dropped without being delivered per integration.",
+		}, []string{"integration", "reason"}),
+	}
+
+	if err := os.MkdirAll(q.dir, 0o777); err != nil {
+		return nil, errors.Wrapf(err, "failed to create notification retry queue directory %v", q.dir)
+	}
+	if err := q.load(); err != nil {
+		return nil, err
+	}
+
+	return q, nil
+}
+
+// load reads the notifications queued before a restart.
+func (q *notificationRetryQueue) load() error {
+	files, err := os.ReadDir(q.dir)
+	if err != nil {
+		return errors.Wrapf(err, "failed to read notification retry queue directory %v", q.dir)
+	}
+
+	for _, f := range files {
+		if f.IsDir() || filepath.Ext(f.Name()) != ".json" {
+			continue
+		}
+
+		path := filepath.Join(q.dir, f.Name())
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return errors.Wrapf(err, "failed to read queued notification %v", path)
+		}
+
+		entry := &queuedNotification{}
+		if err := json.Unmarshal(data, entry); err != nil || entry.ID+".json" != f.Name() {
+			// The file may have been partially written before a crash.
+			level.Warn(q.logger).Log("msg", "removing corrupted queued notification", "file", path, "err", err)
+			_ = os.Remove(path)
+			continue
+		}
+
+		q.entries[entry.ID] = entry
+	}
+
+	q.queueLength.Set(float64(len(q.entries)))
+	return nil
+}
+
+func retryNotifierKey(receiver, integration string, idx int) string {
+	return strings.Join([]string{receiver, integration, strconv.Itoa(idx)}, "/")
+}
+
+// setNotifiers replaces the notifiers used to retry the queued notifications.
+func (q *notificationRetryQueue) setNotifiers(notifiers map[string]notify.Notifier) {
+	if q == nil {
+		return
+	}
+
+	q.notifiersMtx.Lock()
+	q.notifiers = notifiers
+	q.notifiersMtx.Unlock()
+}
+
+// wrap returns a notifier which queues the notifications failed with a retryable error.
+func (q *notificationRetryQueue) wrap(receiver, integration string, idx int, upstream notify.Notifier) notify.Notifier {
+	if q == nil {
+		return upstream
+	}
+
+	return &retryQueueNotifier{
+		queue:       q,
+		upstream:    upstream,
+		receiver:    receiver,
+		integration: integration,
+		idx:         idx,
+	}
+}
+
+// enqueue stores the failed notification to be retried later, replacing the notifications of the
+// same group queued before.
+func (q *notificationRetryQueue) enqueue(ctx context.Context, receiver, integration string, idx int, alerts []*types.Alert, now time.Time) error {
+	groupKey, ok := notify.GroupKey(ctx)
+	if !ok {
+		return errors.New("group key missing")
+	}
+	groupLabels, _ := notify.GroupLabels(ctx)
+	firing, _ := notify.FiringAlerts(ctx)
+	resolved, _ := notify.ResolvedAlerts(ctx)
+	repeat, _ := notify.RepeatInterval(ctx)
+
+	entry := &queuedNotification{
+		ID:             ulid.MustNew(ulid.Timestamp(now), rand.Reader).String(),
+		Receiver:       receiver,
+		Integration:    integration,
+		Index:          idx,
+		GroupKey:       groupKey,
+		GroupLabels:    groupLabels,
+		Alerts:         alerts,
+		FiringAlerts:   firing,
+		ResolvedAlerts: resolved,
+		Expiry:         2 * repeat,
+		QueuedAt:       now,
+		Attempts:       1,
+		NextAttemptAt:  now.Add(q.backoff(1)),
+	}
+
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+
+	queued := 0
+	for _, e := range q.entries {
+		if e.Receiver == receiver && !entry.supersedes(e) {
+			queued++
+		}
+	}
+	if queued >= q.cfg.MaxQueuedPerReceiver {
+		return errNotificationRetryQueueFull
+	}
+
+	if err := q.persist(entry); err != nil {
+		return err
+	}
+	q.removeSupersededLocked(entry.Receiver, entry.Integration, entry.Index, entry.GroupKey, entry.ID)
+
+	q.entries[entry.ID] = entry
+	q.queueLength.Set(float64(len(q.entries)))
+	q.queued.WithLabelValues(integration).Inc()
+	return nil
+}
+
+// supersedes returns whether the notification is for the same group and integration of the other notification.
+func (e *queuedNotification) supersedes(other *queuedNotification) bool {
+	return e.Receiver == other.Receiver && e.Integration == other.Integration && e.Index == other.Index && e.GroupKey == other.GroupKey
+}
+
+// removeSuperseded removes the queued notifications of the group, because a newer notification of the group
+// has been delivered.
+func (q *notificationRetryQueue) removeSuperseded(receiver, integration string, idx int, groupKey string) {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+
+	q.removeSupersededLocked(receiver, integration, idx, groupKey, "")
+}
+
+// removeSupersededLocked removes the queued notifications of the group, except the one with the given ID.
+// Must be called with mtx held.
+func (q *notificationRetryQueue) removeSupersededLocked(receiver, integration string, idx int, groupKey, keepID string) {
+	for id, e := range q.entries {
+		if id == keepID || e.Receiver != receiver || e.Integration != integration || e.Index != idx || e.GroupKey != groupKey {
+			continue
+		}
+		q.removeLocked(e, notificationRetryDroppedReasonSuperseded)
+	}
+}
+
+// backoff returns the backoff after the given number of failed attempts.
+func (q *notificationRetryQueue) backoff(attempts int) time.Duration {
+	backoff := q.cfg.MinBackoff
+	for n := 1; n < attempts && backoff < q.cfg.MaxBackoff; n++ {
+		backoff *= 2
+	}
+	return min(backoff, q.cfg.MaxBackoff)
+}
+
+func (q *notificationRetryQueue) persist(entry *queuedNotification) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return errors.Wrap(err, "failed to encode queued notification")
+	}
+
+	return errors.Wrap(atomicfs.CreateFile(q.entryPath(entry.ID), bytes.NewReader(data)), "failed to write queued notification")
+}
+
+func (q *notificationRetryQueue) entryPath(id string) string {
+	return filepath.Join(q.dir, id+".json")
+}
+
+// run retries the queued notifications until stop is closed.
+func (q *notificationRetryQueue) run(stop <-chan struct{}) {
+	ticker := time.NewTicker(notificationRetryQueueCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			q.retryDue(stop, time.Now())
+		case <-stop:
+			return
+		}
+	}
+}
+
+// retryDue tries to deliver the queued notifications whose backoff expired, concurrently.
+func (q *notificationRetryQueue) retryDue(stop <-chan struct{}, now time.Time) {
+	q.mtx.Lock()
+	var due []*queuedNotification
+	for _, entry := range q.entries {
+		if !entry.NextAttemptAt.After(now) {
+			due = append(due, entry)
+		}
+	}
+	q.mtx.Unlock()
+
+	if len(due) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	// The notifications of the same group are never retried concurrently, because only the newest one is kept in the queue.
+	_ = concurrency.ForEachJob(ctx, len(due), notificationRetryConcurrency, func(ctx context.Context, idx int) error {
+		q.retry(ctx, due[idx], now)
+		return nil
+	})
+}
+
+func (q *notificationRetryQueue) retry(ctx context.Context, entry *queuedNotification, now time.Time) {
+	logger := log.With(q.logger, "receiver", entry.Receiver, "integration", entry.Integration, "group_key", entry.GroupKey, "attempts", entry.Attempts+1)
+	recv := &nflogpb.Receiver{GroupName: entry.Receiver, Integration: entry.Integration, Idx: uint32(entry.Index)}
+
+	// The notification may have been removed, because superseded, while waiting to be retried.
+	q.mtx.Lock()
+	_, queued := q.entries[entry.ID]
+	q.mtx.Unlock()
+	if !queued {
+		return
+	}
+
+	// Skip the notification if a newer notification of the group has been delivered since it's been queued,
+	// for example by another replica, so that a stale notification is never delivered after a newer one.
+	if q.deliveredSince(recv, entry.GroupKey, entry.QueuedAt) {
+		level.Debug(logger).Log("msg", "dropping queued notification superseded by a newer notification")
+		q.remove(entry, notificationRetryDroppedReasonSuperseded)
+		return
+	}
+
+	q.notifiersMtx.RLock()
+	notifier, ok := q.notifiers[retryNotifierKey(entry.Receiver, entry.Integration, entry.Index)]
+	q.notifiersMtx.RUnlock()
+
+	if !ok {
+		level.Warn(logger).Log("msg", "dropping queued notification because the receiver doesn't exist anymore")
+		q.remove(entry, notificationRetryDroppedReasonReceiverRemoved)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, notificationRetryTimeout)
+	defer cancel()
+
+	ctx = notify.WithReceiverName(ctx, entry.Receiver)
+	ctx = notify.WithGroupKey(ctx, entry.GroupKey)
+	ctx = notify.WithGroupLabels(ctx, entry.GroupLabels)
+	ctx = notify.WithNow(ctx, now)
+
+	q.retries.WithLabelValues(entry.Integration).Inc()
+	retry, err := notifier.Notify(ctx, entry.Alerts...)
+
+	switch {
+	case err == nil:
+		level.Info(logger).Log("msg", "delivered queued notification")
+		q.remove(entry, "")
+
+		// Record the delivery in the notification log, so that the notification pipeline doesn't notify it again.
+		if err := q.nflog.Log(recv, entry.GroupKey, entry.FiringAlerts, entry.ResolvedAlerts, entry.Expiry); err != nil {
+			level.Warn(logger).Log("msg", "failed to record queued notification in the notification log", "err", err)
+		}
+	case !retry:
+		level.Warn(logger).Log("msg", "dropping queued notification because of a non-retryable error", "err", err)
+		q.remove(entry, notificationRetryDroppedReasonNonRetryable)
+	case entry.Attempts+1 >= q.cfg.MaxAttempts:
+		level.Warn(logger).Log("msg", "dropping queued notification because the max number of attempts has been reached", "err", err)
+		q.remove(entry, notificationRetryDroppedReasonMaxAttempts)
+	default:
+		level.Debug(logger).Log("msg", "failed to deliver queued notification, will retry", "err", err)
+
+		q.mtx.Lock()
+		entry.Attempts++
+		entry.NextAttemptAt = now.Add(q.backoff(entry.Attempts))
+		persistErr := q.persist(entry)
+		q.mtx.Unlock()
+
+		if persistErr != nil {
+			level.Warn(logger).Log("msg", "failed to update queued notification", "err", persistErr)
+		}
+	}
+}
+
+// deliveredSince returns whether the notification log has a notification of the group delivered after the given time.
+func (q *notificationRetryQueue) deliveredSince(recv *nflogpb.Receiver, groupKey string, since time.Time) bool {
+	entries, err := q.nflog.Query(nflog.QGroupKey(groupKey), nflog.QReceiver(recv))
+	if err != nil {
+		// No entry is found if the group has never been notified.
+		return false
+	}
+	for _, e := range entries {
+		if e.Timestamp.After(since) {
+			return true
+		}
+	}
+	return false
+}
+
+func (q *notificationRetryQueue) remove(entry *queuedNotification, droppedReason string) {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+
+	q.removeLocked(entry, droppedReason)
+}
+
+// removeLocked removes the notification from the queue. Must be called with mtx held.
+func (q *notificationRetryQueue) removeLocked(entry *queuedNotification, droppedReason string) {
+	if _, ok := q.entries[entry.ID]; !ok {
+		return
+	}
+	if droppedReason != "" {
+		q.dropped.WithLabelValues(entry.Integration, droppedReason).Inc()
+	}
+
+	delete(q.entries, entry.ID)
+	q.queueLength.Set(float64(len(q.entries)))
+
+	if err := os.Remove(q.entryPath(entry.ID)); err != nil && !os.IsNotExist(err) {
+		level.Warn(q.logger).Log("msg", "failed to remove queued notification", "id", entry.ID, "err", err)
+	}
+}
+
+// retryQueueNotifier queues the notifications failed with a retryable error, so that they're
+// retried by the notificationRetryQueue instead of being retried in memory by the notification pipeline.
+// The notifications delivered without being queued supersede the queued notifications of the same group.
+type retryQueueNotifier struct {
+	queue       *notificationRetryQueue
+	upstream    notify.Notifier
+	receiver    string
+	integration string
+	idx         int
+}
+
+func (n *retryQueueNotifier) Notify(ctx context.Context, alerts ...*types.Alert) (bool, error) {
+	retry, err := n.upstream.Notify(ctx, alerts...)
+	if err == nil {
+		if groupKey, ok := notify.GroupKey(ctx); ok {
+			n.queue.removeSuperseded(n.receiver, n.integration, n.idx, groupKey)
+		}
+		return retry, err
+	}
+	if !retry {
+		return retry, err
+	}
+
+	if qerr := n.queue.enqueue(ctx, n.receiver, n.integration, n.idx, alerts, time.Now()); qerr != nil {
+		level.Warn(n.queue.logger).Log("msg", "failed to queue notification to be retried", "receiver", n.receiver, "integration", n.integration, "err", qerr)
+		return retry, err
+	}
+
+	level.Info(n.queue.logger).Log("msg", "notification failed, queued to be retried", "receiver", n.receiver, "integration", n.integration, "err", err)
+
+	// The queue now owns the retries of the notification, so the notification pipeline must not retry it in memory.
+	// The error is still returned, so that the notification isn't recorded in the notification log until it's delivered.
+	return false, err
+}
@@ -0,0 +1,281 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package alertmanager
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/alertmanager/nflog"
+	"github.com/prometheus/alertmanager/nflog/nflogpb"
+	"github.com/prometheus/alertmanager/notify"
+	"github.com/prometheus/alertmanager/types"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotificationRetryQueueConfig_Validate(t *testing.T) {
+	tests := map[string]struct {
+		setup    func(cfg *NotificationRetryQueueConfig)
+		expected error
+	}{
+		"should pass with the default config": {
+			setup: func(*NotificationRetryQueueConfig) {},
+		},
+		"should pass when enabled with the default config": {
+			setup: func(cfg *NotificationRetryQueueConfig) {
+				cfg.Enabled = true
+			},
+		},
+		"should fail if the min backoff is greater than the max backoff": {
+			setup: func(cfg *NotificationRetryQueueConfig) {
+				cfg.Enabled = true
+				cfg.MinBackoff = time.Hour
+			},
+			expected: errInvalidNotificationRetryQueueBackoff,
+		},
+		"should fail if max attempts is 0": {
+			setup: func(cfg *NotificationRetryQueueConfig) {
+				cfg.Enabled = true
+				cfg.MaxAttempts = 0
+			},
+			expected: errInvalidNotificationRetryQueueMaxAttempts,
+		},
+		"should fail if max queued per receiver is 0": {
+			setup: func(cfg *NotificationRetryQueueConfig) {
+				cfg.Enabled = true
+				cfg.MaxQueuedPerReceiver = 0
+			},
+			expected: errInvalidNotificationRetryQueueMaxQueued,
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			cfg := defaultNotificationRetryQueueConfig()
+			testData.setup(&cfg)
+			assert.Equal(t, testData.expected, cfg.Validate())
+		})
+	}
+}
+
+func TestNotificationRetryQueue_ShouldRetryQueuedNotificationsAfterRestart(t *testing.T) {
+	dir := t.TempDir()
+	cfg := defaultNotificationRetryQueueConfig()
+	nfl := newTestNotificationLog(t)
+
+	queue, err := newNotificationRetryQueue(cfg, dir, nfl, log.NewNopLogger(), nil)
+	require.NoError(t, err)
+
+	failing := &recordingNotifier{retry: true, err: errors.New("service unavailable")}
+	queue.setNotifiers(map[string]notify.Notifier{retryNotifierKey("receiver", "slack", 0): failing})
+	notifier := queue.wrap("receiver", "slack", 0, failing)
+
+	alert := &types.Alert{Alert: model.Alert{Labels: model.LabelSet{"alertname": "test"}}}
+	retry, err := notifier.Notify(notificationContext("group-1"), alert)
+	assert.False(t, retry)
+	assert.Equal(t, failing.err, err)
+	assert.Equal(t, 1, failing.calls)
+
+	// The notification isn't recorded in the notification log until it's delivered.
+	_, err = nfl.Query(nflog.QGroupKey("group-1"), nflog.QReceiver(testNflogReceiver))
+	require.ErrorIs(t, err, nflog.ErrNotFound)
+
+	files, err := os.ReadDir(filepath.Join(dir, notificationRetryQueueDir))
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+
+	// Simulate a restart.
+	reg := prometheus.NewPedanticRegistry()
+	queue, err = newNotificationRetryQueue(cfg, dir, nfl, log.NewNopLogger(), reg)
+	require.NoError(t, err)
+	require.Len(t, queue.entries, 1)
+
+	succeeding := &recordingNotifier{}
+	queue.setNotifiers(map[string]notify.Notifier{retryNotifierKey("receiver", "slack", 0): succeeding})
+
+	// The notification is not retried before the backoff expires.
+	queue.retryDue(nil, time.Now())
+	assert.Equal(t, 0, succeeding.calls)
+
+	queue.retryDue(nil, time.Now().Add(cfg.MinBackoff))
+	assert.Equal(t, 1, succeeding.calls)
+	assert.Equal(t, "group-1", succeeding.groupKey)
+	assert.Equal(t, model.LabelSet{"alertname": "test"}, succeeding.alerts[0].Labels)
+	assert.Empty(t, queue.entries)
+
+	files, err = os.ReadDir(filepath.Join(dir, notificationRetryQueueDir))
+	require.NoError(t, err)
+	assert.Empty(t, files)
+
+	entries, err := nfl.Query(nflog.QGroupKey("group-1"), nflog.QReceiver(testNflogReceiver))
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, []uint64{1}, entries[0].FiringAlerts)
+
+	assert.Equal(t, float64(1), testutil.ToFloat64(queue.retries.WithLabelValues("slack")))
+	assert.Equal(t, float64(0), testutil.ToFloat64(queue.queueLength))
+}
+
+func TestNotificationRetryQueue_ShouldDropNotificationsAfterMaxAttempts(t *testing.T) {
+	cfg := defaultNotificationRetryQueueConfig()
+	cfg.MaxAttempts = 3
+
+	queue, err := newNotificationRetryQueue(cfg, t.TempDir(), newTestNotificationLog(t), log.NewNopLogger(), nil)
+	require.NoError(t, err)
+
+	failing := &recordingNotifier{retry: true, err: errors.New("service unavailable")}
+	queue.setNotifiers(map[string]notify.Notifier{retryNotifierKey("receiver", "slack", 0): failing})
+
+	now := time.Now()
+	require.NoError(t, queue.enqueue(notificationContext("group-1"), "receiver", "slack", 0, []*types.Alert{{}}, now))
+
+	// The backoff is exponential.
+	now = now.Add(cfg.MinBackoff)
+	queue.retryDue(nil, now)
+	require.Len(t, queue.entries, 1)
+	for _, entry := range queue.entries {
+		assert.Equal(t, 2, entry.Attempts)
+		assert.Equal(t, now.Add(2*cfg.MinBackoff), entry.NextAttemptAt)
+	}
+
+	queue.retryDue(nil, now.Add(2*cfg.MinBackoff))
+	assert.Empty(t, queue.entries)
+	assert.Equal(t, 2, failing.calls)
+	assert.Equal(t, float64(1), testutil.ToFloat64(queue.dropped.WithLabelValues("slack", notificationRetryDroppedReasonMaxAttempts)))
+}
+
+func TestNotificationRetryQueue_ShouldDropNotificationsOfRemovedReceivers(t *testing.T) {
+	cfg := defaultNotificationRetryQueueConfig()
+
+	queue, err := newNotificationRetryQueue(cfg, t.TempDir(), newTestNotificationLog(t), log.NewNopLogger(), nil)
+	require.NoError(t, err)
+
+	now := time.Now()
+	require.NoError(t, queue.enqueue(notificationContext("group-1"), "receiver", "slack", 0, []*types.Alert{{}}, now))
+
+	queue.setNotifiers(map[string]notify.Notifier{})
+	queue.retryDue(nil, now.Add(cfg.MinBackoff))
+	assert.Empty(t, queue.entries)
+	assert.Equal(t, float64(1), testutil.ToFloat64(queue.dropped.WithLabelValues("slack", notificationRetryDroppedReasonReceiverRemoved)))
+}
+
+func TestNotificationRetryQueue_ShouldEnforceMaxQueuedPerReceiver(t *testing.T) {
+	cfg := defaultNotificationRetryQueueConfig()
+	cfg.MaxQueuedPerReceiver = 2
+
+	queue, err := newNotificationRetryQueue(cfg, t.TempDir(), newTestNotificationLog(t), log.NewNopLogger(), nil)
+	require.NoError(t, err)
+
+	failing := &recordingNotifier{retry: true, err: errors.New("service unavailable")}
+	notifier := queue.wrap("receiver-1", "slack", 0, failing)
+
+	for _, groupKey := range []string{"group-1", "group-2"} {
+		retry, err := notifier.Notify(notificationContext(groupKey), &types.Alert{})
+		require.Error(t, err)
+		require.False(t, retry)
+	}
+
+	// The queue is full for the receiver, so the error is returned to be retried in memory.
+	retry, err := notifier.Notify(notificationContext("group-3"), &types.Alert{})
+	assert.True(t, retry)
+	assert.Equal(t, failing.err, err)
+
+	// Other receivers are not affected.
+	require.NoError(t, queue.enqueue(notificationContext("group-1"), "receiver-2", "slack", 0, []*types.Alert{{}}, time.Now()))
+	assert.Len(t, queue.entries, 3)
+}
+
+func TestNotificationRetryQueue_ShouldNotQueueNonRetryableErrors(t *testing.T) {
+	queue, err := newNotificationRetryQueue(defaultNotificationRetryQueueConfig(), t.TempDir(), newTestNotificationLog(t), log.NewNopLogger(), nil)
+	require.NoError(t, err)
+
+	failing := &recordingNotifier{retry: false, err: errors.New("bad request")}
+	retry, err := queue.wrap("receiver", "slack", 0, failing).Notify(notificationContext("group-1"), &types.Alert{})
+	assert.False(t, retry)
+	assert.Equal(t, failing.err, err)
+	assert.Empty(t, queue.entries)
+}
+
+func TestNotificationRetryQueue_ShouldDropSupersededNotifications(t *testing.T) {
+	cfg := defaultNotificationRetryQueueConfig()
+	nfl := newTestNotificationLog(t)
+
+	queue, err := newNotificationRetryQueue(cfg, t.TempDir(), nfl, log.NewNopLogger(), nil)
+	require.NoError(t, err)
+
+	// A newer notification of the same group replaces the queued one.
+	now := time.Now()
+	require.NoError(t, queue.enqueue(notificationContext("group-1"), "receiver", "slack", 0, []*types.Alert{{}}, now))
+	require.NoError(t, queue.enqueue(notificationContext("group-1"), "receiver", "slack", 0, []*types.Alert{{}}, now.Add(time.Second)))
+	require.NoError(t, queue.enqueue(notificationContext("group-2"), "receiver", "slack", 0, []*types.Alert{{}}, now))
+	require.Len(t, queue.entries, 2)
+	assert.Equal(t, float64(1), testutil.ToFloat64(queue.dropped.WithLabelValues("slack", notificationRetryDroppedReasonSuperseded)))
+
+	// A newer notification of the same group delivered without being queued replaces the queued one.
+	succeeding := &recordingNotifier{}
+	_, err = queue.wrap("receiver", "slack", 0, succeeding).Notify(notificationContext("group-1"), &types.Alert{})
+	require.NoError(t, err)
+	require.Len(t, queue.entries, 1)
+
+	// A newer notification of the same group recorded in the notification log, for example by another replica,
+	// replaces the queued one.
+	require.NoError(t, nfl.Log(testNflogReceiver, "group-2", nil, nil, time.Hour))
+	queue.setNotifiers(map[string]notify.Notifier{retryNotifierKey("receiver", "slack", 0): succeeding})
+	queue.retryDue(nil, now.Add(cfg.MinBackoff))
+	assert.Empty(t, queue.entries)
+	assert.Equal(t, 1, succeeding.calls)
+	assert.Equal(t, float64(3), testutil.ToFloat64(queue.dropped.WithLabelValues("slack", notificationRetryDroppedReasonSuperseded)))
+}
+
+var testNflogReceiver = &nflogpb.Receiver{GroupName: "receiver", Integration: "slack", Idx: 0}
+
+func newTestNotificationLog(t *testing.T) *nflog.Log {
+	l, err := nflog.New(nflog.Options{Retention: time.Hour})
+	require.NoError(t, err)
+	return l
+}
+
+func defaultNotificationRetryQueueConfig() NotificationRetryQueueConfig {
+	cfg := NotificationRetryQueueConfig{}
+	cfg.RegisterFlagsWithPrefix("", flag.NewFlagSet("", flag.PanicOnError))
+	return cfg
+}
+
+func notificationContext(groupKey string) context.Context {
+	ctx := notify.WithGroupKey(context.Background(), groupKey)
+	ctx = notify.WithFiringAlerts(ctx, []uint64{1})
+	ctx = notify.WithResolvedAlerts(ctx, nil)
+	ctx = notify.WithRepeatInterval(ctx, time.Hour)
+	return notify.WithReceiverName(ctx, "receiver")
+}
+
+type recordingNotifier struct {
+	retry bool
+	err   error
+
+	mtx      sync.Mutex
+	calls    int
+	groupKey string
+	alerts   []*types.Alert
+}
+
+func (n *recordingNotifier) Notify(ctx context.Context, alerts ...*types.Alert) (bool, error) {
+	n.mtx.Lock()
+	defer n.mtx.Unlock()
+
+	n.calls++
+	n.groupKey, _ = notify.GroupKey(ctx)
+	n.alerts = alerts
+	return n.retry, n.err
+}
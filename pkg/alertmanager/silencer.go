@@ -0,0 +1,222 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package alertmanager
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/alertmanager/pkg/labels"
+	"github.com/prometheus/alertmanager/silence"
+	"github.com/prometheus/alertmanager/silence/silencepb"
+	"github.com/prometheus/alertmanager/types"
+	"github.com/prometheus/common/model"
+)
+
+// indexedSilencer implements types.Muter like silence.Silencer, but looks up the silences matching an alert in an
+// index of the active and pending silences, instead of matching the alert against every silence. This keeps the
+// cost of muting an alert low for tenants with a large number of silences.
+//
+// The notification pipeline can only be built with a silence.Silencer, so indexedSilencer is run as a stage before
+// it. It marks the silences applying to each alert, so that silence.Silencer only needs to check the state of the
+// marked silences instead of matching the alert against every silence.
+type indexedSilencer struct {
+	silences *silence.Silences
+	marker   types.Marker
+	logger   log.Logger
+
+	mtx   sync.Mutex
+	index *silencesIndex
+	// entries caches the indexed silences by ID, so that their matchers don't need to be compiled
+	// again when the index is rebuilt. The matchers of a silence never change.
+	entries map[string]*indexedSilence
+}
+
+func newIndexedSilencer(s *silence.Silences, m types.Marker, l log.Logger) *indexedSilencer {
+	return &indexedSilencer{
+		silences: s,
+		marker:   m,
+		logger:   l,
+	}
+}
+
+// Exec implements notify.Stage. It marks the silences applying to the alerts, without filtering them.
+func (s *indexedSilencer) Exec(ctx context.Context, _ log.Logger, alerts ...*types.Alert) (context.Context, []*types.Alert, error) {
+	for _, a := range alerts {
+		s.Mutes(a.Labels)
+	}
+	return ctx, alerts, nil
+}
+
+// Mutes implements types.Muter.
+func (s *indexedSilencer) Mutes(lset model.LabelSet) bool {
+	fp := lset.Fingerprint()
+	activeIDs, pendingIDs, markerVersion, _ := s.marker.Silenced(fp)
+
+	idx, err := s.getIndex()
+	if err != nil {
+		level.Error(s.logger).Log("msg", "Querying silences failed, alerts might not get silenced correctly", "err", err)
+		return false
+	}
+
+	var ids []string
+	if markerVersion == idx.version {
+		// The silences haven't changed since the alert was last checked, so we only need to check which of
+		// the silences applying to the alert are still active or pending.
+		ids = append(append(make([]string, 0, len(activeIDs)+len(pendingIDs)), activeIDs...), pendingIDs...)
+	} else {
+		ids = idx.matching(lset)
+	}
+	if len(ids) == 0 {
+		s.marker.SetActiveOrSilenced(fp, idx.version, nil, nil)
+		return false
+	}
+
+	// The index only tracks which silences match the alert, because the matchers of a silence never change. The
+	// silences are looked up by ID to get their current state, since it changes when they're updated or expired.
+	sils, _, err := s.silences.Query(
+		silence.QIDs(ids...),
+		silence.QState(types.SilenceStateActive, types.SilenceStatePending),
+	)
+	if err != nil {
+		level.Error(s.logger).Log("msg", "Querying silences failed, alerts might not get silenced correctly", "err", err)
+	}
+
+	activeIDs, pendingIDs = nil, nil
+	for _, sil := range sils {
+		switch types.CalcSilenceState(sil.StartsAt, sil.EndsAt) {
+		case types.SilenceStatePending:
+			pendingIDs = append(pendingIDs, sil.Id)
+		case types.SilenceStateActive:
+			activeIDs = append(activeIDs, sil.Id)
+		default:
+			// Do nothing, silence has expired in the meantime.
+		}
+	}
+	sort.Strings(activeIDs)
+	sort.Strings(pendingIDs)
+
+	s.marker.SetActiveOrSilenced(fp, idx.version, activeIDs, pendingIDs)
+
+	return len(activeIDs) > 0
+}
+
+// getIndex returns the index of the active and pending silences, rebuilding it if the silences have changed.
+func (s *indexedSilencer) getIndex() (*silencesIndex, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if s.index != nil && s.index.version == s.silences.Version() {
+		return s.index, nil
+	}
+
+	sils, version, err := s.silences.Query(silence.QState(types.SilenceStateActive, types.SilenceStatePending))
+	if err != nil {
+		return nil, err
+	}
+
+	idx := newSilencesIndex(version)
+	entries := make(map[string]*indexedSilence, len(sils))
+	for _, sil := range sils {
+		entry, ok := s.entries[sil.Id]
+		if !ok {
+			matchers, err := silenceMatchers(sil)
+			if err != nil {
+				level.Warn(s.logger).Log("msg", "failed to index silence, the silence will not be applied", "silence", sil.Id, "err", err)
+				continue
+			}
+			entry = &indexedSilence{id: sil.Id, matchers: matchers}
+		}
+		entries[sil.Id] = entry
+		idx.add(entry)
+	}
+	s.index = idx
+	s.entries = entries
+
+	return idx, nil
+}
+
+type indexedSilence struct {
+	id       string
+	matchers labels.Matchers
+}
+
+// silencesIndex indexes silences by the name and value of one of their equality matchers. Silences
+// without an equality matcher on a non-empty value can't be indexed and are checked against every alert.
+type silencesIndex struct {
+	version   int
+	byLabel   map[string]map[string][]*indexedSilence
+	unindexed []*indexedSilence
+}
+
+func newSilencesIndex(version int) *silencesIndex {
+	return &silencesIndex{
+		version: version,
+		byLabel: map[string]map[string][]*indexedSilence{},
+	}
+}
+
+func (idx *silencesIndex) add(entry *indexedSilence) {
+	for _, m := range entry.matchers {
+		if m.Type != labels.MatchEqual || m.Value == "" {
+			continue
+		}
+		byValue, ok := idx.byLabel[m.Name]
+		if !ok {
+			byValue = map[string][]*indexedSilence{}
+			idx.byLabel[m.Name] = byValue
+		}
+		byValue[m.Value] = append(byValue[m.Value], entry)
+		return
+	}
+
+	idx.unindexed = append(idx.unindexed, entry)
+}
+
+// matching returns the IDs of the silences matching the label set. Each silence is indexed
+// by a single label, so it can't be found more than once.
+func (idx *silencesIndex) matching(lset model.LabelSet) []string {
+	var ids []string
+	for name, value := range lset {
+		for _, entry := range idx.byLabel[string(name)][string(value)] {
+			if entry.matchers.Matches(lset) {
+				ids = append(ids, entry.id)
+			}
+		}
+	}
+	for _, entry := range idx.unindexed {
+		if entry.matchers.Matches(lset) {
+			ids = append(ids, entry.id)
+		}
+	}
+	return ids
+}
+
+func silenceMatchers(sil *silencepb.Silence) (labels.Matchers, error) {
+	matchers := make(labels.Matchers, 0, len(sil.Matchers))
+	for _, m := range sil.Matchers {
+		var t labels.MatchType
+		switch m.Type {
+		case silencepb.Matcher_EQUAL:
+			t = labels.MatchEqual
+		case silencepb.Matcher_NOT_EQUAL:
+			t = labels.MatchNotEqual
+		case silencepb.Matcher_REGEXP:
+			t = labels.MatchRegexp
+		case silencepb.Matcher_NOT_REGEXP:
+			t = labels.MatchNotRegexp
+		default:
+			return nil, fmt.Errorf("unknown matcher type %q", m.Type)
+		}
+		matcher, err := labels.NewMatcher(t, m.Name, m.Pattern)
+		if err != nil {
+			return nil, err
+		}
+		matchers = append(matchers, matcher)
+	}
+	return matchers, nil
+}
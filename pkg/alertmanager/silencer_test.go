@@ -0,0 +1,145 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package alertmanager
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/alertmanager/silence"
+	"github.com/prometheus/alertmanager/silence/silencepb"
+	"github.com/prometheus/alertmanager/types"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIndexedSilencer(t *testing.T) {
+	silences, err := silence.New(silence.Options{Retention: time.Hour})
+	require.NoError(t, err)
+
+	now := time.Now()
+	newSilence := func(startsAt time.Time, matchers ...*silencepb.Matcher) string {
+		sil := &silencepb.Silence{Matchers: matchers, StartsAt: startsAt, EndsAt: now.Add(time.Hour)}
+		require.NoError(t, silences.Set(sil))
+		return sil.Id
+	}
+	equal := func(name, value string) *silencepb.Matcher {
+		return &silencepb.Matcher{Type: silencepb.Matcher_EQUAL, Name: name, Pattern: value}
+	}
+
+	byAlertName := newSilence(now, equal("alertname", "HighLatency"), equal("cluster", "eu"))
+	byRegexp := newSilence(now, &silencepb.Matcher{Type: silencepb.Matcher_REGEXP, Name: "cluster", Pattern: "us-.*"})
+	byNotEqual := newSilence(now, equal("alertname", "DiskFull"), &silencepb.Matcher{Type: silencepb.Matcher_NOT_EQUAL, Name: "env", Pattern: "dev"})
+	pending := newSilence(now.Add(time.Hour/2), equal("alertname", "HighErrorRate"))
+
+	tests := []struct {
+		lset            model.LabelSet
+		expectedMuted   bool
+		expectedActive  []string
+		expectedPending []string
+	}{
+		{
+			lset:           model.LabelSet{"alertname": "HighLatency", "cluster": "eu"},
+			expectedMuted:  true,
+			expectedActive: []string{byAlertName},
+		},
+		{
+			lset: model.LabelSet{"alertname": "HighLatency", "cluster": "ap"},
+		},
+		{
+			lset:           model.LabelSet{"alertname": "HighLatency", "cluster": "us-east"},
+			expectedMuted:  true,
+			expectedActive: []string{byRegexp},
+		},
+		{
+			lset:           model.LabelSet{"alertname": "DiskFull", "env": "prod"},
+			expectedMuted:  true,
+			expectedActive: []string{byNotEqual},
+		},
+		{
+			lset: model.LabelSet{"alertname": "DiskFull", "env": "dev"},
+		},
+		{
+			lset:            model.LabelSet{"alertname": "HighErrorRate"},
+			expectedPending: []string{pending},
+		},
+	}
+
+	marker := types.NewMarker(prometheus.NewRegistry())
+	silencer := newIndexedSilencer(silences, marker, log.NewNopLogger())
+	upstreamMarker := types.NewMarker(prometheus.NewRegistry())
+	upstreamSilencer := silence.NewSilencer(silences, upstreamMarker, log.NewNopLogger())
+
+	check := func(t *testing.T, lset model.LabelSet, expectedMuted bool, expectedActive, expectedPending []string) {
+		assert.Equal(t, expectedMuted, silencer.Mutes(lset))
+		assert.Equal(t, expectedMuted, upstreamSilencer.Mutes(lset))
+
+		activeIDs, pendingIDs, _, _ := marker.Silenced(lset.Fingerprint())
+		assert.Equal(t, expectedActive, activeIDs)
+		assert.Equal(t, expectedPending, pendingIDs)
+	}
+
+	for i, tc := range tests {
+		t.Run(fmt.Sprintf("test case %d", i), func(t *testing.T) {
+			check(t, tc.lset, tc.expectedMuted, tc.expectedActive, tc.expectedPending)
+
+			// The result is the same when the silences haven't changed since the alert was last checked.
+			check(t, tc.lset, tc.expectedMuted, tc.expectedActive, tc.expectedPending)
+		})
+	}
+
+	// Silences added or expired after the index was built are taken into account.
+	require.NoError(t, silences.Expire(byAlertName))
+	check(t, model.LabelSet{"alertname": "HighLatency", "cluster": "eu"}, false, nil, nil)
+
+	byCluster := newSilence(now, equal("cluster", "ap"))
+	check(t, model.LabelSet{"alertname": "HighLatency", "cluster": "ap"}, true, []string{byCluster}, nil)
+
+	// The matchers of the silences are compiled once, and not cached anymore once the silences have expired.
+	cached := silencer.entries[byRegexp]
+	require.NotNil(t, cached)
+	require.NotContains(t, silencer.entries, byAlertName)
+
+	require.NoError(t, silences.Expire(byCluster))
+	check(t, model.LabelSet{"alertname": "HighLatency", "cluster": "ap"}, false, nil, nil)
+	assert.Same(t, cached, silencer.entries[byRegexp])
+	assert.NotContains(t, silencer.entries, byCluster)
+}
+
+func TestIndexedSilencer_Exec(t *testing.T) {
+	silences, err := silence.New(silence.Options{Retention: time.Hour})
+	require.NoError(t, err)
+
+	sil := &silencepb.Silence{
+		Matchers: []*silencepb.Matcher{{Type: silencepb.Matcher_EQUAL, Name: "alertname", Pattern: "HighLatency"}},
+		StartsAt: time.Now(),
+		EndsAt:   time.Now().Add(time.Hour),
+	}
+	require.NoError(t, silences.Set(sil))
+
+	marker := types.NewMarker(prometheus.NewRegistry())
+	silencer := newIndexedSilencer(silences, marker, log.NewNopLogger())
+
+	silenced := &types.Alert{}
+	silenced.Labels = model.LabelSet{"alertname": "HighLatency"}
+	notSilenced := &types.Alert{}
+	notSilenced.Labels = model.LabelSet{"alertname": "DiskFull"}
+
+	// The stage doesn't filter the alerts, but marks the silences applying to them.
+	_, alerts, err := silencer.Exec(context.Background(), log.NewNopLogger(), silenced, notSilenced)
+	require.NoError(t, err)
+	assert.Equal(t, []*types.Alert{silenced, notSilenced}, alerts)
+
+	activeIDs, _, version, _ := marker.Silenced(silenced.Labels.Fingerprint())
+	assert.Equal(t, []string{sil.Id}, activeIDs)
+	assert.Equal(t, silences.Version(), version)
+
+	activeIDs, _, version, _ = marker.Silenced(notSilenced.Labels.Fingerprint())
+	assert.Empty(t, activeIDs)
+	assert.Equal(t, silences.Version(), version)
+}
@@ -0,0 +1,145 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package alertmanager
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/prometheus/alertmanager/notify"
+	"github.com/prometheus/alertmanager/types"
+	"github.com/prometheus/common/model"
+)
+
+// splitNotificationsIntegrations are the integrations whose notifications can be split into multiple messages.
+// The other integrations, like PagerDuty or Opsgenie, deduplicate the notifications by group key, so each
+// message would overwrite the previous one.
+var splitNotificationsIntegrations = map[string]bool{
+	"slack":   true,
+	"webhook": true,
+}
+
+// splitNotifierStateTTL is how long the alerts already sent by a partially failed notification are remembered,
+// so that they're not sent again when the notification is retried.
+const splitNotifierStateTTL = time.Hour
+
+type maxAlertsPerNotificationLimits interface {
+	AlertmanagerMaxAlertsPerNotification(tenant, receiver string) int
+}
+
+// splitNotifier splits the notifications with more alerts than the limit of the tenant's receiver into
+// multiple notifications, so that the messages sent by the receiver integrations don't get too big.
+type splitNotifier struct {
+	upstream notify.Notifier
+	tenant   string
+	limits   maxAlertsPerNotificationLimits
+
+	mtx sync.Mutex
+	// sent tracks, by group key, the alerts already sent by the notifications which failed after
+	// sending some of their messages, so that the retries only send the messages which failed.
+	sent map[string]*splitNotificationState
+}
+
+type splitNotificationState struct {
+	updatedAt time.Time
+	alerts    map[splitAlertKey]struct{}
+}
+
+// splitAlertKey identifies an alert in a notification. An alert which has been resolved
+// since it was sent is sent again.
+type splitAlertKey struct {
+	fingerprint model.Fingerprint
+	resolved    bool
+}
+
+func newSplitNotifier(upstream notify.Notifier, tenant string, limits maxAlertsPerNotificationLimits) *splitNotifier {
+	return &splitNotifier{
+		upstream: upstream,
+		tenant:   tenant,
+		limits:   limits,
+		sent:     map[string]*splitNotificationState{},
+	}
+}
+
+func (s *splitNotifier) Notify(ctx context.Context, alerts ...*types.Alert) (bool, error) {
+	receiver, _ := notify.ReceiverName(ctx)
+	maxAlerts := s.limits.AlertmanagerMaxAlertsPerNotification(s.tenant, receiver)
+	if maxAlerts <= 0 {
+		return s.upstream.Notify(ctx, alerts...)
+	}
+
+	groupKey, _ := notify.GroupKey(ctx)
+	alerts = s.unsent(groupKey, alerts)
+	if len(alerts) == 0 {
+		s.markSent(groupKey, nil, true)
+		return false, nil
+	}
+
+	for len(alerts) > 0 {
+		batch := alerts[:min(maxAlerts, len(alerts))]
+
+		// Stop at the first failure, remembering the batches which have been sent,
+		// so that only the remaining ones are sent if the notification is retried.
+		if retry, err := s.upstream.Notify(ctx, batch...); err != nil {
+			return retry, err
+		}
+
+		alerts = alerts[len(batch):]
+		s.markSent(groupKey, batch, len(alerts) == 0)
+	}
+
+	return false, nil
+}
+
+// unsent returns the alerts which haven't been sent yet by a previous attempt of the notification.
+func (s *splitNotifier) unsent(groupKey string, alerts []*types.Alert) []*types.Alert {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	now := time.Now()
+	for key, state := range s.sent {
+		if now.Sub(state.updatedAt) > splitNotifierStateTTL {
+			delete(s.sent, key)
+		}
+	}
+
+	state, ok := s.sent[groupKey]
+	if !ok {
+		return alerts
+	}
+
+	unsent := make([]*types.Alert, 0, len(alerts))
+	for _, a := range alerts {
+		if _, ok := state.alerts[newSplitAlertKey(a)]; !ok {
+			unsent = append(unsent, a)
+		}
+	}
+	return unsent
+}
+
+// markSent records the alerts sent by the notification of the group. Once the whole notification
+// has been sent, the state of the group is removed.
+func (s *splitNotifier) markSent(groupKey string, alerts []*types.Alert, done bool) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if done {
+		delete(s.sent, groupKey)
+		return
+	}
+
+	state, ok := s.sent[groupKey]
+	if !ok {
+		state = &splitNotificationState{alerts: map[splitAlertKey]struct{}{}}
+		s.sent[groupKey] = state
+	}
+	state.updatedAt = time.Now()
+	for _, a := range alerts {
+		state.alerts[newSplitAlertKey(a)] = struct{}{}
+	}
+}
+
+func newSplitAlertKey(a *types.Alert) splitAlertKey {
+	return splitAlertKey{fingerprint: a.Fingerprint(), resolved: a.Resolved()}
+}
@@ -0,0 +1,143 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package alertmanager
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/prometheus/alertmanager/notify"
+	"github.com/prometheus/alertmanager/types"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitNotifier(t *testing.T) {
+	alerts := make([]*types.Alert, 5)
+	for i := range alerts {
+		alerts[i] = &types.Alert{Alert: model.Alert{Labels: model.LabelSet{"id": model.LabelValue(rune('a' + i))}}}
+	}
+
+	tests := map[string]struct {
+		maxAlerts       int
+		failAt          int
+		expectedBatches [][]*types.Alert
+		expectedErr     bool
+	}{
+		"no limit": {
+			maxAlerts:       0,
+			expectedBatches: [][]*types.Alert{alerts},
+		},
+		"limit higher than the number of alerts": {
+			maxAlerts:       10,
+			expectedBatches: [][]*types.Alert{alerts},
+		},
+		"limit equal to the number of alerts": {
+			maxAlerts:       5,
+			expectedBatches: [][]*types.Alert{alerts},
+		},
+		"limit lower than the number of alerts": {
+			maxAlerts:       2,
+			expectedBatches: [][]*types.Alert{alerts[0:2], alerts[2:4], alerts[4:5]},
+		},
+		"stops at the first failed notification": {
+			maxAlerts:       2,
+			failAt:          2,
+			expectedBatches: [][]*types.Alert{alerts[0:2], alerts[2:4]},
+			expectedErr:     true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			upstream := &batchRecordingNotifier{failAt: tc.failAt}
+			notifier := newSplitNotifier(upstream, "user", &mockAlertManagerLimits{maxAlertsPerNotification: tc.maxAlerts})
+
+			retry, err := notifier.Notify(notificationContext("group"), alerts...)
+			if tc.expectedErr {
+				require.Error(t, err)
+				assert.True(t, retry)
+			} else {
+				require.NoError(t, err)
+				assert.False(t, retry)
+			}
+			assert.Equal(t, tc.expectedBatches, upstream.batches)
+		})
+	}
+}
+
+func TestSplitNotifier_ShouldOnlyRetryTheFailedBatches(t *testing.T) {
+	alerts := make([]*types.Alert, 5)
+	for i := range alerts {
+		alerts[i] = &types.Alert{Alert: model.Alert{Labels: model.LabelSet{"id": model.LabelValue(rune('a' + i))}}}
+	}
+
+	upstream := &batchRecordingNotifier{failAt: 2}
+	notifier := newSplitNotifier(upstream, "user", &mockAlertManagerLimits{maxAlertsPerNotification: 2})
+
+	// The second batch fails.
+	_, err := notifier.Notify(notificationContext("group-1"), alerts...)
+	require.Error(t, err)
+	assert.Equal(t, [][]*types.Alert{alerts[0:2], alerts[2:4]}, upstream.batches)
+
+	// The notifications of other groups are not affected.
+	upstream.batches, upstream.failAt = nil, 0
+	_, err = notifier.Notify(notificationContext("group-2"), alerts[0:2]...)
+	require.NoError(t, err)
+	assert.Equal(t, [][]*types.Alert{alerts[0:2]}, upstream.batches)
+
+	// The retry only sends the alerts which haven't been sent yet.
+	upstream.batches = nil
+	_, err = notifier.Notify(notificationContext("group-1"), alerts...)
+	require.NoError(t, err)
+	assert.Equal(t, [][]*types.Alert{alerts[2:4], alerts[4:5]}, upstream.batches)
+	assert.Empty(t, notifier.sent)
+
+	// Once the notification has been sent, the next one sends all the alerts again.
+	upstream.batches = nil
+	_, err = notifier.Notify(notificationContext("group-1"), alerts...)
+	require.NoError(t, err)
+	assert.Equal(t, [][]*types.Alert{alerts[0:2], alerts[2:4], alerts[4:5]}, upstream.batches)
+}
+
+func TestSplitNotifier_ShouldApplyTheReceiverLimit(t *testing.T) {
+	alerts := make([]*types.Alert, 3)
+	for i := range alerts {
+		alerts[i] = &types.Alert{Alert: model.Alert{Labels: model.LabelSet{"id": model.LabelValue(rune('a' + i))}}}
+	}
+
+	limits := receiverMaxAlertsPerNotificationLimits{"slack-receiver": 1}
+	upstream := &batchRecordingNotifier{}
+	notifier := newSplitNotifier(upstream, "user", limits)
+
+	_, err := notifier.Notify(notify.WithReceiverName(notificationContext("group"), "slack-receiver"), alerts...)
+	require.NoError(t, err)
+	assert.Equal(t, [][]*types.Alert{alerts[0:1], alerts[1:2], alerts[2:3]}, upstream.batches)
+
+	upstream.batches = nil
+	_, err = notifier.Notify(notify.WithReceiverName(notificationContext("group"), "other-receiver"), alerts...)
+	require.NoError(t, err)
+	assert.Equal(t, [][]*types.Alert{alerts}, upstream.batches)
+}
+
+type receiverMaxAlertsPerNotificationLimits map[string]int
+
+func (l receiverMaxAlertsPerNotificationLimits) AlertmanagerMaxAlertsPerNotification(_, receiver string) int {
+	return l[receiver]
+}
+
+// batchRecordingNotifier records the alerts of each notification, and fails the failAt-th notification (starting from 1).
+type batchRecordingNotifier struct {
+	failAt  int
+	batches [][]*types.Alert
+}
+
+func (n *batchRecordingNotifier) Notify(_ context.Context, alerts ...*types.Alert) (bool, error) {
+	n.batches = append(n.batches, alerts)
+	if len(n.batches) == n.failAt {
+		return true, errors.New("notification failed")
+	}
+	return false, nil
+}
@@ -0,0 +1,169 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package alertmanager
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-kit/log/level"
+	"github.com/grafana/alerting/notify/nfstatus"
+	"github.com/prometheus/alertmanager/notify"
+	"github.com/prometheus/alertmanager/types"
+	"github.com/prometheus/common/model"
+
+	util_log "github.com/grafana/mimir/pkg/util/log"
+)
+
+const (
+	// testNotificationTimeout is the max time to send the test notification through each integration of the receiver.
+	testNotificationTimeout = 30 * time.Second
+
+	testNotificationStatusOK     = "ok"
+	testNotificationStatusFailed = "failed"
+)
+
+// TestNotificationRequest is the payload of the API used to send a test notification through a receiver
+// of the tenant's Alertmanager configuration.
+type TestNotificationRequest struct {
+	Receiver string `json:"receiver"`
+	// Labels and Annotations are added to the ones of the synthetic test alert.
+	Labels      model.LabelSet `json:"labels,omitempty"`
+	Annotations model.LabelSet `json:"annotations,omitempty"`
+}
+
+// TestNotificationResponse is the response of the API used to send a test notification through a receiver.
+type TestNotificationResponse struct {
+	Receiver     string                              `json:"receiver"`
+	Alert        model.Alert                         `json:"alert"`
+	Integrations []TestNotificationIntegrationResult `json:"integrations"`
+}
+
+// TestNotificationIntegrationResult is the delivery result of the test notification through an integration of the receiver.
+type TestNotificationIntegrationResult struct {
+	Name   string `json:"name"`
+	Index  int    `json:"index"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+	// Retryable tells whether the delivery failure is retried when sending a real notification.
+	Retryable bool `json:"retryable,omitempty"`
+}
+
+// TestNotificationHandler sends a synthetic test alert through each integration of a receiver of the current
+// configuration and reports the delivery result of each integration. The test alert isn't stored, routed,
+// inhibited or silenced, and the delivery isn't retried.
+func (am *Alertmanager) TestNotificationHandler(w http.ResponseWriter, r *http.Request) {
+	logger := util_log.WithContext(r.Context(), am.logger)
+
+	req := TestNotificationRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("error unmarshalling test notification JSON: %s", err.Error()), http.StatusBadRequest)
+		return
+	}
+	if req.Receiver == "" {
+		http.Error(w, "the receiver is required", http.StatusBadRequest)
+		return
+	}
+	if err := req.Labels.Validate(); err != nil {
+		http.Error(w, fmt.Sprintf("invalid alert labels %s: %s", req.Labels.String(), err.Error()), http.StatusBadRequest)
+		return
+	}
+	if err := req.Annotations.Validate(); err != nil {
+		http.Error(w, fmt.Sprintf("invalid alert annotations %s: %s", req.Annotations.String(), err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	am.receiversMtx.Lock()
+	receivers := am.receivers
+	am.receiversMtx.Unlock()
+
+	if receivers == nil {
+		http.Error(w, "the Alertmanager is not configured", http.StatusPreconditionFailed)
+		return
+	}
+
+	var receiver *nfstatus.Receiver
+	for _, rcv := range receivers {
+		if rcv.Name() == req.Receiver {
+			receiver = rcv
+			break
+		}
+	}
+	if receiver == nil {
+		http.Error(w, fmt.Sprintf("receiver %q not found", req.Receiver), http.StatusNotFound)
+		return
+	}
+
+	now := time.Now()
+	alert := newTestNotificationAlert(req, now)
+
+	res := TestNotificationResponse{
+		Receiver:     req.Receiver,
+		Alert:        alert.Alert,
+		Integrations: make([]TestNotificationIntegrationResult, 0, len(receiver.Integrations())),
+	}
+	for _, integration := range receiver.Integrations() {
+		result := TestNotificationIntegrationResult{
+			Name:   integration.Name(),
+			Index:  integration.Index(),
+			Status: testNotificationStatusOK,
+		}
+
+		if retry, err := sendTestNotification(r.Context(), integration, req.Receiver, alert, now); err != nil {
+			level.Info(logger).Log("msg", "failed to send test notification", "receiver", req.Receiver, "integration", integration.String(), "err", err)
+			result.Status = testNotificationStatusFailed
+			result.Error = err.Error()
+			result.Retryable = retry
+		}
+
+		res.Integrations = append(res.Integrations, result)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(res); err != nil {
+		level.Error(logger).Log("msg", "failed to write the test notification response", "err", err)
+	}
+}
+
+func newTestNotificationAlert(req TestNotificationRequest, now time.Time) *types.Alert {
+	alert := &types.Alert{
+		Alert: model.Alert{
+			Labels: model.LabelSet{
+				model.AlertNameLabel: "TestNotification",
+				"receiver":           model.LabelValue(req.Receiver),
+			},
+			Annotations: model.LabelSet{
+				"summary":     "Test notification",
+				"description": "This is a test notification sent through the Mimir Alertmanager to verify the receiver configuration.",
+			},
+			StartsAt: now,
+			EndsAt:   now.Add(5 * time.Minute),
+		},
+		UpdatedAt: now,
+	}
+	for ln, lv := range req.Labels {
+		alert.Labels[ln] = lv
+	}
+	for ln, lv := range req.Annotations {
+		alert.Annotations[ln] = lv
+	}
+	return alert
+}
+
+// sendTestNotification sends the alert through the integration, preparing the context the same way the
+// notification pipeline does.
+func sendTestNotification(ctx context.Context, integration *nfstatus.Integration, receiver string, alert *types.Alert, now time.Time) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, testNotificationTimeout)
+	defer cancel()
+
+	ctx = notify.WithGroupKey(ctx, fmt.Sprintf("%s-%s-%d", receiver, alert.Labels.Fingerprint(), now.Unix()))
+	ctx = notify.WithGroupLabels(ctx, alert.Labels)
+	ctx = notify.WithReceiverName(ctx, receiver)
+	ctx = notify.WithNow(ctx, now)
+	ctx = notify.WithRepeatInterval(ctx, time.Hour)
+
+	return integration.Notify(ctx, alert)
+}
@@ -0,0 +1,122 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package alertmanager
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/alerting/definition"
+	alertingTemplates "github.com/grafana/alerting/templates"
+	"github.com/grafana/dskit/flagext"
+	"github.com/prometheus/alertmanager/featurecontrol"
+	"github.com/prometheus/alertmanager/notify/webhook"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/atomic"
+
+	"github.com/grafana/mimir/pkg/util/validation"
+)
+
+func TestAlertmanager_TestNotificationHandler(t *testing.T) {
+	var limits validation.Limits
+	flagext.DefaultValues(&limits)
+	overrides, err := validation.NewOverrides(limits, nil)
+	require.NoError(t, err)
+
+	am, err := New(&Config{
+		UserID:            "test",
+		Logger:            log.NewNopLogger(),
+		Limits:            overrides,
+		Features:          featurecontrol.NoopFlags{},
+		TenantDataDir:     t.TempDir(),
+		ExternalURL:       &url.URL{Path: "/am"},
+		ShardingEnabled:   true,
+		Store:             prepareInMemoryAlertStore(),
+		Replicator:        &stubReplicator{},
+		ReplicationFactor: 1,
+		PersisterConfig:   PersisterConfig{Interval: time.Hour},
+	}, prometheus.NewPedanticRegistry())
+	require.NoError(t, err)
+	defer am.StopAndWait()
+
+	testNotification := func(body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		am.mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/receivers/test", strings.NewReader(body)))
+		return rec
+	}
+
+	// The Alertmanager is not configured yet.
+	require.Equal(t, http.StatusPreconditionFailed, testNotification(`{"receiver": "webhooks"}`).Code)
+
+	received := atomic.NewInt64(0)
+	var receivedMsg webhook.Message
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received.Inc()
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&receivedMsg))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer healthy.Close()
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer failing.Close()
+
+	cfgRaw := fmt.Sprintf(`receivers:
+- name: 'default'
+- name: 'webhooks'
+  webhook_configs:
+  - url: '%s'
+  - url: '%s'
+
+route:
+  receiver: 'default'`, healthy.URL, failing.URL)
+
+	cfg, err := definition.LoadCompat([]byte(cfgRaw))
+	require.NoError(t, err)
+	require.NoError(t, am.ApplyConfig(cfg, []alertingTemplates.TemplateDefinition{}, cfgRaw, &url.URL{}, nil))
+
+	t.Run("invalid request", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, testNotification(`{"receiver": `).Code)
+		assert.Equal(t, http.StatusBadRequest, testNotification(`{}`).Code)
+		assert.Equal(t, http.StatusBadRequest, testNotification(`{"receiver": "webhooks", "labels": {"0invalid": "value"}}`).Code)
+	})
+
+	t.Run("unknown receiver", func(t *testing.T) {
+		assert.Equal(t, http.StatusNotFound, testNotification(`{"receiver": "unknown"}`).Code)
+	})
+
+	t.Run("delivery result of each integration", func(t *testing.T) {
+		rec := testNotification(`{"receiver": "webhooks", "labels": {"severity": "critical"}, "annotations": {"summary": "Check the wiring"}}`)
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		res := TestNotificationResponse{}
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+		assert.Equal(t, "webhooks", res.Receiver)
+		assert.Equal(t, model.LabelValue("TestNotification"), res.Alert.Labels[model.AlertNameLabel])
+		assert.Equal(t, model.LabelValue("critical"), res.Alert.Labels["severity"])
+		assert.Equal(t, model.LabelValue("Check the wiring"), res.Alert.Annotations["summary"])
+
+		require.Len(t, res.Integrations, 2)
+		assert.Equal(t, TestNotificationIntegrationResult{Name: "webhook", Index: 0, Status: testNotificationStatusOK}, res.Integrations[0])
+		assert.Equal(t, "webhook", res.Integrations[1].Name)
+		assert.Equal(t, 1, res.Integrations[1].Index)
+		assert.Equal(t, testNotificationStatusFailed, res.Integrations[1].Status)
+		assert.Contains(t, res.Integrations[1].Error, "400")
+
+		require.Equal(t, int64(1), received.Load())
+		assert.Equal(t, "webhooks", receivedMsg.Receiver)
+		require.Len(t, receivedMsg.Alerts, 1)
+		assert.Equal(t, "critical", receivedMsg.Alerts[0].Labels["severity"])
+	})
+}
@@ -88,6 +88,9 @@ type API struct {
 	logger    log.Logger
 	sourceIPs *middleware.SourceIPExtractor
 	indexPage *IndexPageContent
+
+	// runtimeConfig returns the currently loaded runtime configuration, if any.
+	runtimeConfig func() interface{}
 }
 
 func New(cfg Config, federationCfg tenantfederation.Config, serverCfg server.Config, s *server.Server, logger log.Logger) (*API, error) {
@@ -209,6 +212,21 @@ func (a *API) RegisterAlertmanager(am *alertmanager.MultitenantAlertmanager, api
 		a.RegisterRoute("/api/v1/alerts", http.HandlerFunc(am.SetUserConfig), true, true, "POST")
 		a.RegisterRoute("/api/v1/alerts", http.HandlerFunc(am.DeleteUserConfig), true, true, "DELETE")
 
+		a.RegisterRoute("/api/v1/alerts/time_intervals", http.HandlerFunc(am.ListTimeIntervals), true, true, http.MethodGet)
+		a.RegisterRoute("/api/v1/alerts/time_intervals/{name}", http.HandlerFunc(am.GetTimeInterval), true, true, http.MethodGet)
+		a.RegisterRoute("/api/v1/alerts/time_intervals/{name}", http.HandlerFunc(am.SetTimeInterval), true, true, http.MethodPut)
+		a.RegisterRoute("/api/v1/alerts/time_intervals/{name}", http.HandlerFunc(am.DeleteTimeInterval), true, true, http.MethodDelete)
+
+		a.RegisterRoute("/api/v1/alerts/import/grafana", http.HandlerFunc(am.ImportGrafanaConfig), true, true, http.MethodPost)
+
+		a.RegisterRoute("/api/v1/alerts/versions", http.HandlerFunc(am.ListUserConfigVersions), true, true, http.MethodGet)
+		a.RegisterRoute("/api/v1/alerts/versions/{version}", http.HandlerFunc(am.GetUserConfigVersion), true, true, http.MethodGet)
+		a.RegisterRoute("/api/v1/alerts/versions/{version}/rollback", http.HandlerFunc(am.RollbackUserConfig), true, true, http.MethodPost)
+
+		// These APIs are handled by the per-tenant Alertmanager, so they're handled by the distributor.
+		a.RegisterRoute("/api/v1/alerts/test", am, true, true, http.MethodPost)
+		a.RegisterRoute("/api/v1/receivers/test", am, true, true, http.MethodPost)
+
 		if grafanaCompatEnabled {
 			level.Info(a.logger).Log("msg", "enabled experimental grafana routes")
 
@@ -229,7 +247,7 @@ func (a *API) RegisterAlertmanager(am *alertmanager.MultitenantAlertmanager, api
 }
 
 // RegisterAPI registers the standard endpoints associated with a running Mimir.
-func (a *API) RegisterAPI(httpPathPrefix string, actualCfg interface{}, defaultCfg interface{}, buildInfoHandler http.Handler) {
+func (a *API) RegisterAPI(httpPathPrefix string, actualCfg interface{}, defaultCfg interface{}, flags *flag.FlagSet, buildInfoHandler http.Handler) {
 	a.indexPage.AddLinks(configWeight, "Current config", []IndexPageLink{
 		{Desc: "Including the default values", Path: "/config"},
 		{Desc: "Only values that differ from the defaults", Path: "/config?mode=diff"},
@@ -240,12 +258,16 @@ func (a *API) RegisterAPI(httpPathPrefix string, actualCfg interface{}, defaultC
 	a.RegisterRoutesWithPrefix("/static/", http.StripPrefix(httpPathPrefix, http.FileServer(http.FS(staticFiles))), false, true, "GET")
 	a.RegisterRoute("/debug/fgprof", fgprof.Handler(), false, true, "GET")
 	a.RegisterRoute("/api/v1/status/buildinfo", buildInfoHandler, false, true, "GET")
-	a.RegisterRoute("/api/v1/status/config", a.cfg.statusConfigHandler(), false, true, "GET")
-	a.RegisterRoute("/api/v1/status/flags", a.cfg.statusFlagsHandler(), false, true, "GET")
+	a.RegisterRoute("/api/v1/status/config", a.statusConfigHandler(actualCfg), false, true, "GET")
+	a.RegisterRoute("/api/v1/status/flags", statusFlagsHandler(flags), false, true, "GET")
 }
 
-// RegisterRuntimeConfig registers the endpoints associates with the runtime configuration
-func (a *API) RegisterRuntimeConfig(runtimeConfigHandler http.HandlerFunc, userLimitsHandler http.HandlerFunc) {
+// RegisterRuntimeConfig registers the endpoints associates with the runtime configuration.
+// The runtimeConfig function returns the currently loaded runtime configuration, which is
+// also included in the resolved configuration exposed by /api/v1/status/config.
+func (a *API) RegisterRuntimeConfig(runtimeConfigHandler http.HandlerFunc, userLimitsHandler http.HandlerFunc, runtimeConfig func() interface{}) {
+	a.runtimeConfig = runtimeConfig
+
 	a.indexPage.AddLinks(runtimeConfigWeight, "Current runtime config", []IndexPageLink{
 		{Desc: "Entire runtime config (including overrides)", Path: "/runtime_config"},
 		{Desc: "Only values that differ from the defaults", Path: "/runtime_config?mode=diff"},
@@ -274,6 +296,7 @@ func (a *API) RegisterDistributor(d *distributor.Distributor, pushConfig distrib
 	a.RegisterRoute("/distributor/ring", d, false, true, "GET", "POST")
 	a.RegisterRoute("/distributor/all_user_stats", http.HandlerFunc(d.AllUserStatsHandler), false, true, "GET")
 	a.RegisterRoute("/distributor/ha_tracker", d.HATracker, false, true, "GET")
+	a.RegisterRoute("/distributor/metric_registry/conformance", http.HandlerFunc(d.MetricRegistryConformanceHandler), true, true, "GET")
 }
 
 // Ingester is defined as an interface to allow for alternative implementations
@@ -288,12 +311,22 @@ type Ingester interface {
 	UserRegistryHandler(http.ResponseWriter, *http.Request)
 	TenantsHandler(http.ResponseWriter, *http.Request)
 	TenantTSDBHandler(http.ResponseWriter, *http.Request)
+	TenantTSDBDebugBundleHandler(http.ResponseWriter, *http.Request)
 	PrepareInstanceRingDownscaleHandler(http.ResponseWriter, *http.Request)
 }
 
+// pushDecoderProvider is implemented by the ingesters decoding the push requests sent with the client.PushCodecName codec.
+type pushDecoderProvider interface {
+	PushDecoder() client.PushDecoder
+}
+
 // RegisterIngester registers the ingester HTTP and gRPC services.
 func (a *API) RegisterIngester(i Ingester) {
-	client.RegisterIngesterServer(a.server.GRPC, i)
+	if p, ok := i.(pushDecoderProvider); ok {
+		client.RegisterIngesterServerWithPushDecoder(a.server.GRPC, i, p.PushDecoder())
+	} else {
+		client.RegisterIngesterServer(a.server.GRPC, i)
+	}
 
 	a.indexPage.AddLinks(dangerousWeight, "Dangerous", []IndexPageLink{
 		{Dangerous: true, Desc: "Trigger a flush of data from ingester to storage", Path: "/ingester/flush"},
@@ -317,6 +350,7 @@ func (a *API) RegisterIngester(i Ingester) {
 
 	a.RegisterRoute("/ingester/tenants", http.HandlerFunc(i.TenantsHandler), false, true, "GET")
 	a.RegisterRoute("/ingester/tsdb/{tenant}", http.HandlerFunc(i.TenantTSDBHandler), false, true, "GET")
+	a.RegisterRoute("/ingester/tsdb/{tenant}/debug-bundle", http.HandlerFunc(i.TenantTSDBDebugBundleHandler), false, true, "GET")
 }
 
 // RegisterRuler registers routes associated with the Ruler service.
@@ -350,8 +384,13 @@ func (a *API) RegisterRulerAPI(r *ruler.API, configAPIEnabled bool, buildInfoHan
 		a.RegisterRoute(path.Join(a.cfg.PrometheusHTTPPrefix, "/config/v1/rules/{namespace}"), http.HandlerFunc(r.ListRules), true, true, "GET")
 		a.RegisterRoute(path.Join(a.cfg.PrometheusHTTPPrefix, "/config/v1/rules/{namespace}/{groupName}"), http.HandlerFunc(r.GetRuleGroup), true, true, "GET")
 		a.RegisterRoute(path.Join(a.cfg.PrometheusHTTPPrefix, "/config/v1/rules/{namespace}"), http.HandlerFunc(r.CreateRuleGroup), true, true, "POST")
+		a.RegisterRoute(path.Join(a.cfg.PrometheusHTTPPrefix, "/config/v1/rules/{namespace}/dry-run"), http.HandlerFunc(r.DryRunRuleGroup), true, true, "POST")
 		a.RegisterRoute(path.Join(a.cfg.PrometheusHTTPPrefix, "/config/v1/rules/{namespace}/{groupName}"), http.HandlerFunc(r.DeleteRuleGroup), true, true, "DELETE")
 		a.RegisterRoute(path.Join(a.cfg.PrometheusHTTPPrefix, "/config/v1/rules/{namespace}"), http.HandlerFunc(r.DeleteNamespace), true, true, "DELETE")
+		a.RegisterRoute(path.Join(a.cfg.PrometheusHTTPPrefix, "/config/v1/rule-versions/{namespace}"), http.HandlerFunc(r.ListRuleNamespaceVersions), true, true, "GET")
+		a.RegisterRoute(path.Join(a.cfg.PrometheusHTTPPrefix, "/config/v1/rule-versions/{namespace}/{version}"), http.HandlerFunc(r.GetRuleNamespaceVersion), true, true, "GET")
+		a.RegisterRoute(path.Join(a.cfg.PrometheusHTTPPrefix, "/config/v1/rule-versions/{namespace}/{version}/diff"), http.HandlerFunc(r.DiffRuleNamespaceVersion), true, true, "GET")
+		a.RegisterRoute(path.Join(a.cfg.PrometheusHTTPPrefix, "/config/v1/rule-versions/{namespace}/{version}/rollback"), http.HandlerFunc(r.RollbackRuleNamespace), true, true, "POST")
 	}
 }
 
@@ -382,6 +421,9 @@ func (a *API) RegisterStoreGateway(s *storegateway.StoreGateway) {
 	a.RegisterRoute("/store-gateway/ring", http.HandlerFunc(s.RingHandler), false, true, "GET", "POST")
 	a.RegisterRoute("/store-gateway/tenants", http.HandlerFunc(s.TenantsHandler), false, true, "GET")
 	a.RegisterRoute("/store-gateway/tenant/{tenant}/blocks", http.HandlerFunc(s.BlocksHandler), false, true, "GET")
+	a.RegisterRoute("/store-gateway/tenant/{tenant}/block_query_stats", http.HandlerFunc(s.BlockQueryStatsHandler), false, true, "GET")
+	a.RegisterRoute("/store-gateway/tenant/{tenant}/inventory", http.HandlerFunc(s.BlockInventoryHandler), false, true, "GET")
+	a.RegisterRoute("/store-gateway/tenant/{tenant}/warmup", http.HandlerFunc(s.WarmupHandler), false, true, "POST")
 	a.RegisterRoute("/store-gateway/prepare-shutdown", http.HandlerFunc(s.PrepareShutdownHandler), false, true, "GET", "POST", "DELETE")
 }
 
@@ -400,6 +442,7 @@ func (a *API) RegisterCompactor(c *compactor.MultitenantCompactor) {
 	a.RegisterRoute("/compactor/delete_tenant_status", http.HandlerFunc(c.DeleteTenantStatus), true, true, "GET")
 	a.RegisterRoute("/compactor/tenants", http.HandlerFunc(c.TenantsHandler), false, true, "GET")
 	a.RegisterRoute("/compactor/tenant/{tenant}/planned_jobs", http.HandlerFunc(c.PlannedJobsHandler), false, true, "GET")
+	a.RegisterRoute("/compactor/tenant/{tenant}/quarantined_jobs", http.HandlerFunc(c.QuarantinedJobsHandler), false, true, "GET")
 }
 
 func (a *API) DisableServerHTTPTimeouts(next http.Handler) http.Handler {
@@ -448,6 +491,7 @@ func (a *API) RegisterQueryAPI(handler http.Handler, buildInfoHandler http.Handl
 	a.RegisterRoute(path.Join(a.cfg.PrometheusHTTPPrefix, "/api/v1/cardinality/active_series"), handler, true, true, "GET", "POST")
 	a.RegisterRoute(path.Join(a.cfg.PrometheusHTTPPrefix, "/api/v1/cardinality/active_native_histogram_metrics"), handler, true, true, "GET", "POST")
 	a.RegisterRoute(path.Join(a.cfg.PrometheusHTTPPrefix, "/api/v1/format_query"), handler, true, true, "GET", "POST")
+	a.RegisterRoute(path.Join(a.cfg.PrometheusHTTPPrefix, "/federate"), handler, true, true, "GET")
 }
 
 // RegisterQueryFrontendHandler registers the Prometheus routes supported by the
@@ -495,6 +539,8 @@ func (a *API) RegisterServiceMapHandler(handler http.Handler) {
 func (a *API) RegisterMemberlistKV(pathPrefix string, kvs *memberlist.KVInitService) {
 	a.indexPage.AddLinks(memberlistWeight, "Memberlist", []IndexPageLink{
 		{Desc: "Status", Path: "/memberlist"},
+		{Desc: "Snapshot (JSON)", Path: "/memberlist/snapshot"},
 	})
 	a.RegisterRoute("/memberlist", memberlistStatusHandler(pathPrefix, kvs), false, true, "GET")
+	a.RegisterRoute("/memberlist/snapshot", memberlistSnapshotHandler(kvs), false, true, "GET")
 }
@@ -7,16 +7,21 @@ package api
 
 import (
 	"context"
+	"crypto/sha256"
 	"embed"
+	"encoding/hex"
+	"flag"
 	"html/template"
 	"net/http"
 	"path"
 	"sort"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/go-kit/log"
 	"github.com/gorilla/mux"
+	"github.com/grafana/dskit/flagext"
 	"github.com/grafana/dskit/instrument"
 	"github.com/grafana/dskit/kv/memberlist"
 	"github.com/grafana/dskit/middleware"
@@ -30,6 +35,7 @@ import (
 	"github.com/prometheus/prometheus/promql"
 	"github.com/prometheus/prometheus/storage"
 	v1 "github.com/prometheus/prometheus/web/api/v1"
+	"gopkg.in/yaml.v3"
 
 	"github.com/grafana/mimir/pkg/querier"
 	querierapi "github.com/grafana/mimir/pkg/querier/api"
@@ -181,13 +187,93 @@ type configResponse struct {
 	Config map[string]string `json:"data"`
 }
 
-func (cfg *Config) statusConfigHandler() http.HandlerFunc {
+// instanceSpecificConfigFields are the configuration fields which are expected to differ between the
+// replicas of a component, and so are excluded from the configuration hash.
+var instanceSpecificConfigFields = map[string]struct{}{
+	"instance_id":                {},
+	"instance_addr":              {},
+	"instance_port":              {},
+	"instance_interface_names":   {},
+	"instance_availability_zone": {},
+	"instance_weight":            {},
+	"advertise_addr":             {},
+	"advertise_port":             {},
+	"node_name":                  {},
+}
+
+// statusConfigHandler returns the resolved configuration of the running process, including the
+// runtime configuration, and a hash of them, which can be compared across replicas to detect
+// configuration drift. Secrets are redacted. The "yaml" field, expected by Prometheus clients,
+// is left empty because the configuration isn't a valid Prometheus configuration.
+func (a *API) statusConfigHandler(actualCfg interface{}) http.HandlerFunc {
 	return func(w http.ResponseWriter, _ *http.Request) {
-		response := configResponse{
+		cfgYAML, err := yaml.Marshal(actualCfg)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		data := map[string]string{
+			"yaml":   "",
+			"config": string(cfgYAML),
+		}
+
+		hashedCfgYAML, err := instanceAgnosticConfigYAML(actualCfg)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		hash := sha256.New()
+		_, _ = hash.Write(hashedCfgYAML)
+
+		if a.runtimeConfig != nil {
+			if runtimeCfg := a.runtimeConfig(); runtimeCfg != nil {
+				runtimeCfgYAML, err := yaml.Marshal(runtimeCfg)
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusInternalServerError)
+					return
+				}
+
+				data["runtimeConfig"] = string(runtimeCfgYAML)
+				_, _ = hash.Write(runtimeCfgYAML)
+			}
+		}
+
+		data["hash"] = hex.EncodeToString(hash.Sum(nil))
+
+		util.WriteJSONResponse(w, configResponse{
 			Status: "success",
-			Config: map[string]string{},
+			Config: data,
+		})
+	}
+}
+
+// instanceAgnosticConfigYAML returns the configuration marshalled to YAML, without the instance specific fields.
+func instanceAgnosticConfigYAML(cfg interface{}) ([]byte, error) {
+	cfgObj, err := util.YAMLMarshalUnmarshal(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	removeInstanceSpecificConfigFields(cfgObj)
+	return yaml.Marshal(cfgObj)
+}
+
+func removeInstanceSpecificConfigFields(obj interface{}) {
+	switch obj := obj.(type) {
+	case map[string]interface{}:
+		for k, v := range obj {
+			if _, ok := instanceSpecificConfigFields[k]; ok {
+				delete(obj, k)
+				continue
+			}
+			removeInstanceSpecificConfigFields(v)
+		}
+	case []interface{}:
+		for _, v := range obj {
+			removeInstanceSpecificConfigFields(v)
 		}
-		util.WriteJSONResponse(w, response)
 	}
 }
 
@@ -196,16 +282,38 @@ type flagsResponse struct {
 	Flags  map[string]string `json:"data"`
 }
 
-func (cfg *Config) statusFlagsHandler() http.HandlerFunc {
+// statusFlagsHandler returns the values of all the flags, with secrets redacted.
+func statusFlagsHandler(fs *flag.FlagSet) http.HandlerFunc {
 	return func(w http.ResponseWriter, _ *http.Request) {
 		response := flagsResponse{
 			Status: "success",
 			Flags:  map[string]string{},
 		}
+
+		if fs != nil {
+			fs.VisitAll(func(f *flag.Flag) {
+				response.Flags[f.Name] = redactedFlagValue(f.Value)
+			})
+		}
+
 		util.WriteJSONResponse(w, response)
 	}
 }
 
+func redactedFlagValue(v flag.Value) string {
+	switch v := v.(type) {
+	case *flagext.Secret:
+		if v.String() != "" {
+			return "********"
+		}
+	case *flagext.URLValue:
+		if v.URL != nil {
+			return v.URL.Redacted()
+		}
+	}
+	return v.String()
+}
+
 // NewQuerierHandler returns a HTTP handler that can be used by the querier service to
 // either register with the frontend worker query processor or with the external HTTP
 // server to fulfill the Prometheus query API.
@@ -219,6 +327,8 @@ func NewQuerierHandler(
 	reg prometheus.Registerer,
 	logger log.Logger,
 	limits *validation.Overrides,
+	instantQueryCache middleware.Interface,
+	lookbackDelta time.Duration,
 ) http.Handler {
 	// Prometheus histograms for requests to the querier.
 	querierRequestDuration := promauto.With(reg).NewHistogramVec(prometheus.HistogramOpts{
@@ -320,11 +430,17 @@ func NewQuerierHandler(
 	metadataQueryStats := usagestats.NewRequestsMiddleware("querier_metadata_query_requests")
 	cardinalityQueryStats := usagestats.NewRequestsMiddleware("querier_cardinality_query_requests")
 	formattingQueryStats := usagestats.NewRequestsMiddleware("querier_formatting_requests")
+	federateStats := usagestats.NewRequestsMiddleware("querier_federate_requests")
+
+	instantQueryHandler := http.Handler(promRouter)
+	if instantQueryCache != nil {
+		instantQueryHandler = instantQueryCache.Wrap(instantQueryHandler)
+	}
 
 	// TODO(gotjosh): This custom handler is temporary until we're able to vendor the changes in:
 	// https://github.com/prometheus/prometheus/pull/7125/files
 	router.Path(path.Join(prefix, "/api/v1/read")).Methods("POST").Handler(remoteReadStats.Wrap(querier.RemoteReadHandler(queryable, logger)))
-	router.Path(path.Join(prefix, "/api/v1/query")).Methods("GET", "POST").Handler(instantQueryStats.Wrap(promRouter))
+	router.Path(path.Join(prefix, "/api/v1/query")).Methods("GET", "POST").Handler(instantQueryStats.Wrap(instantQueryHandler))
 	router.Path(path.Join(prefix, "/api/v1/query_range")).Methods("GET", "POST").Handler(rangeQueryStats.Wrap(promRouter))
 	router.Path(path.Join(prefix, "/api/v1/query_exemplars")).Methods("GET", "POST").Handler(exemplarsQueryStats.Wrap(promRouter))
 	router.Path(path.Join(prefix, "/api/v1/labels")).Methods("GET", "POST").Handler(labelsQueryStats.Wrap(promRouter))
@@ -336,6 +452,7 @@ func NewQuerierHandler(
 	router.Path(path.Join(prefix, "/api/v1/cardinality/active_series")).Methods("GET", "POST").Handler(cardinalityQueryStats.Wrap(querier.ActiveSeriesCardinalityHandler(distributor, limits)))
 	router.Path(path.Join(prefix, "/api/v1/cardinality/active_native_histogram_metrics")).Methods("GET", "POST").Handler(cardinalityQueryStats.Wrap(querier.ActiveNativeHistogramMetricsHandler(distributor, limits)))
 	router.Path(path.Join(prefix, "/api/v1/format_query")).Methods("GET", "POST").Handler(formattingQueryStats.Wrap(promRouter))
+	router.Path(path.Join(prefix, "/federate")).Methods("GET").Handler(federateStats.Wrap(querier.FederateHandler(querier.NewErrorTranslateSampleAndChunkQueryable(queryable), lookbackDelta, logger)))
 
 	// Track execution time.
 	return stats.NewWallTimeMiddleware().Wrap(router)
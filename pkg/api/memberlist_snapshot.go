@@ -0,0 +1,149 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package api
+
+import (
+	"context"
+	"html/template"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/grafana/dskit/kv/memberlist"
+
+	"github.com/grafana/mimir/pkg/util"
+)
+
+// memberlistSnapshot is a point-in-time view of the memberlist cluster and KV store, as seen by this instance.
+type memberlistSnapshot struct {
+	Now         time.Time                  `json:"now"`
+	HealthScore int                        `json:"health_score"`
+	Members     []memberlistSnapshotMember `json:"members"`
+	Store       []memberlistSnapshotValue  `json:"store"`
+}
+
+type memberlistSnapshotMember struct {
+	Name    string `json:"name"`
+	Address string `json:"address"`
+	State   string `json:"state"`
+}
+
+type memberlistSnapshotValue struct {
+	Key     string `json:"key"`
+	Codec   string `json:"codec"`
+	Version uint   `json:"version"`
+	// Value is the value decoded by its codec, with tombstones removed.
+	Value interface{} `json:"value"`
+}
+
+// memberlistSnapshotHandler returns a JSON snapshot of the memberlist cluster members and the KV store content,
+// with each value decoded by its codec. The keys can be filtered with the "prefix" query parameter.
+// The snapshot doesn't initialize the memberlist KV if this instance doesn't use memberlist.
+func memberlistSnapshotHandler(kvs *memberlist.KVInitService) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		data, ok := memberlistStatusPageData(req.Context(), kvs)
+		if !ok {
+			http.Error(w, "This instance doesn't use memberlist.", http.StatusNotFound)
+			return
+		}
+
+		// The status page data is only built if the memberlist KV has already been initialized,
+		// so this call doesn't initialize it.
+		kv, err := kvs.GetMemberlistKV()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		snapshot, err := newMemberlistSnapshot(data, kv, req.URL.Query().Get("prefix"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		util.WriteJSONResponse(w, snapshot)
+	})
+}
+
+// memberlistStatusPageData returns the data of the memberlist status page, which is the only way to get the
+// memberlist cluster members from the memberlist KV. It returns false if the memberlist KV hasn't been initialized.
+func memberlistStatusPageData(ctx context.Context, kvs *memberlist.KVInitService) (memberlist.StatusPageData, bool) {
+	var (
+		data memberlist.StatusPageData
+		ok   bool
+	)
+
+	templ := template.New("memberlist_status_page_data")
+	templ.Funcs(map[string]interface{}{
+		"Capture": func(d memberlist.StatusPageData) string {
+			data, ok = d, true
+			return ""
+		},
+	})
+	template.Must(templ.Parse("{{ Capture . }}"))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "/", nil)
+	if err != nil {
+		return data, false
+	}
+	memberlist.NewHTTPStatusHandler(kvs, templ).ServeHTTP(discardResponseWriter{header: http.Header{}}, req)
+
+	return data, ok
+}
+
+func newMemberlistSnapshot(data memberlist.StatusPageData, kv *memberlist.KV, prefix string) (memberlistSnapshot, error) {
+	snapshot := memberlistSnapshot{
+		Now:         data.Now,
+		HealthScore: data.Memberlist.GetHealthScore(),
+		Members:     make([]memberlistSnapshotMember, 0, len(data.SortedMembers)),
+		Store:       []memberlistSnapshotValue{},
+	}
+
+	for _, m := range data.SortedMembers {
+		snapshot.Members = append(snapshot.Members, memberlistSnapshotMember{
+			Name:    m.Name,
+			Address: m.Address(),
+			State:   memberlistNodeStateNames[int(m.State)],
+		})
+	}
+
+	for key, desc := range data.Store {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+
+		value, err := kv.Get(key, kv.GetCodec(desc.CodecID))
+		if err != nil {
+			return memberlistSnapshot{}, err
+		}
+
+		snapshot.Store = append(snapshot.Store, memberlistSnapshotValue{
+			Key:     key,
+			Codec:   desc.CodecID,
+			Version: desc.Version,
+			Value:   value,
+		})
+	}
+	sort.Slice(snapshot.Store, func(i, j int) bool {
+		return snapshot.Store[i].Key < snapshot.Store[j].Key
+	})
+
+	return snapshot, nil
+}
+
+var memberlistNodeStateNames = map[int]string{
+	0: "alive",
+	1: "suspect",
+	2: "dead",
+	3: "left",
+}
+
+// discardResponseWriter is a http.ResponseWriter discarding the response.
+type discardResponseWriter struct {
+	header http.Header
+}
+
+func (w discardResponseWriter) Header() http.Header         { return w.header }
+func (w discardResponseWriter) Write(b []byte) (int, error) { return io.Discard.Write(b) }
+func (w discardResponseWriter) WriteHeader(int)             {}
@@ -0,0 +1,88 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/dskit/kv/codec"
+	"github.com/grafana/dskit/kv/memberlist"
+	"github.com/grafana/dskit/ring"
+	"github.com/grafana/dskit/services"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemberlistSnapshotHandler(t *testing.T) {
+	cfg := memberlist.KVConfig{}
+	cfg.RegisterFlags(flag.NewFlagSet("", flag.PanicOnError))
+	cfg.NodeName = "test-node"
+	cfg.TCPTransport.BindAddrs = []string{"127.0.0.1"}
+	cfg.TCPTransport.BindPort = 0
+	cfg.Codecs = []codec.Codec{ring.GetCodec()}
+
+	kvs := memberlist.NewKVInitService(&cfg, log.NewNopLogger(), nil, prometheus.NewRegistry())
+	handler := memberlistSnapshotHandler(kvs)
+
+	t.Run("should not initialize the memberlist KV", func(t *testing.T) {
+		resp := httptest.NewRecorder()
+		handler.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/memberlist/snapshot", nil))
+
+		assert.Equal(t, http.StatusNotFound, resp.Code)
+		assert.Equal(t, "This instance doesn't use memberlist.\n", resp.Body.String())
+	})
+
+	kv, err := kvs.GetMemberlistKV()
+	require.NoError(t, err)
+	require.NoError(t, services.StartAndAwaitRunning(context.Background(), kvs))
+	require.NoError(t, kv.AwaitRunning(context.Background()))
+	t.Cleanup(func() {
+		require.NoError(t, services.StopAndAwaitTerminated(context.Background(), kvs))
+	})
+
+	for _, key := range []string{"ring", "other-ring"} {
+		require.NoError(t, kv.CAS(context.Background(), key, ring.GetCodec(), func(interface{}) (interface{}, bool, error) {
+			desc := ring.NewDesc()
+			desc.AddIngester("ingester-1", "127.0.0.1:9095", "zone-a", []uint32{1, 2}, ring.ACTIVE, time.Now(), false, time.Time{})
+			return desc, true, nil
+		}))
+	}
+
+	t.Run("should return a snapshot of the memberlist cluster and KV store", func(t *testing.T) {
+		resp := httptest.NewRecorder()
+		handler.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/memberlist/snapshot?prefix=ring", nil))
+
+		require.Equal(t, http.StatusOK, resp.Code)
+		assert.Equal(t, "application/json", resp.Header().Get("Content-Type"))
+
+		var snapshot struct {
+			HealthScore int                        `json:"health_score"`
+			Members     []memberlistSnapshotMember `json:"members"`
+			Store       []struct {
+				Key   string    `json:"key"`
+				Codec string    `json:"codec"`
+				Value ring.Desc `json:"value"`
+			} `json:"store"`
+		}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&snapshot))
+
+		require.Len(t, snapshot.Members, 1)
+		assert.True(t, strings.HasPrefix(snapshot.Members[0].Name, "test-node"))
+		assert.Equal(t, "alive", snapshot.Members[0].State)
+		assert.True(t, strings.HasPrefix(snapshot.Members[0].Address, "127.0.0.1:"))
+
+		require.Len(t, snapshot.Store, 1)
+		assert.Equal(t, "ring", snapshot.Store[0].Key)
+		assert.Equal(t, ring.GetCodec().CodecID(), snapshot.Store[0].Codec)
+		assert.Equal(t, []uint32{1, 2}, snapshot.Store[0].Value.Ingesters["ingester-1"].Tokens)
+	})
+}
@@ -66,7 +66,17 @@ func New(
 		return nil, fmt.Errorf("no partitions assigned to instance %s", b.cfg.InstanceID)
 	}
 
-	bucketClient, err := bucket.NewClient(context.Background(), cfg.BlocksStorage.Bucket, "block-builder", logger, reg)
+	bucketCfg := cfg.BlocksStorage.Bucket
+	if bucketCfg.Retries.Upload.MaxRetries == 0 {
+		// If there is a network hiccup, we prefer to wait longer retrying, than fail the whole section.
+		bucketCfg.Retries.Upload = bucket.RetryPolicyConfig{
+			MinBackoff: 100 * time.Millisecond,
+			MaxBackoff: time.Minute,
+			MaxRetries: 10,
+		}
+	}
+
+	bucketClient, err := bucket.NewClient(context.Background(), bucketCfg, "block-builder", logger, reg)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create the bucket client: %w", err)
 	}
@@ -533,20 +543,8 @@ func (b *BlockBuilder) uploadBlocks(ctx context.Context, tenantID, dbDir string,
 			meta.Thanos.Labels[mimir_tsdb.OutOfOrderExternalLabel] = mimir_tsdb.OutOfOrderExternalLabelValue
 		}
 
-		boff := backoff.New(ctx, backoff.Config{
-			MinBackoff: 100 * time.Millisecond,
-			MaxBackoff: time.Minute, // If there is a network hiccup, we prefer to wait longer retrying, than fail the whole section.
-			MaxRetries: 10,
-		})
-		for boff.Ongoing() {
-			err := block.Upload(ctx, b.logger, buc, blockDir, meta)
-			if err == nil {
-				break
-			}
-			level.Warn(b.logger).Log("msg", "failed to upload block; will retry", "err", err, "block", bid, "tenant", tenantID)
-			boff.Wait()
-		}
-		if err := boff.ErrCause(); err != nil {
+		// Failed uploads of the block files are retried by the bucket client.
+		if err := block.Upload(ctx, b.logger, buc, blockDir, meta); err != nil {
 			return fmt.Errorf("upload block %s (tenant %s): %w", bid, tenantID, err)
 		}
 	}
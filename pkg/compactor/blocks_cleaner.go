@@ -43,7 +43,11 @@ type BlocksCleanerConfig struct {
 	TenantCleanupDelay         time.Duration // Delay before removing tenant deletion mark and "debug".
 	DeleteBlocksConcurrency    int
 	NoBlocksFileCleanupEnabled bool
+	CompactionSummaryEnabled   bool
 	CompactionBlockRanges      mimir_tsdb.DurationList // Used for estimating compaction jobs.
+
+	// If true, blocks with a corrupted meta.json are deleted like partial blocks.
+	PartialBlockDeletionIncludeCorruptedMeta bool
 }
 
 type BlocksCleaner struct {
@@ -277,6 +281,13 @@ func (c *BlocksCleaner) deleteRemainingData(ctx context.Context, userBucket objs
 	}
 	level.Info(userLogger).Log("msg", "deleted bucket index for tenant with no blocks remaining")
 
+	// Delete compaction summary
+	if c.cfg.CompactionSummaryEnabled {
+		if err := bucketindex.DeleteSummary(ctx, c.bucketClient, userID, c.cfgProvider); err != nil {
+			return errors.Wrap(err, "failed to delete compaction summary file")
+		}
+	}
+
 	// Delete markers folder
 	if deleted, err := bucket.DeletePrefix(ctx, userBucket, block.MarkersPathname, userLogger); err != nil {
 		return errors.Wrap(err, "failed to delete marker files")
@@ -307,6 +318,12 @@ func (c *BlocksCleaner) deleteUserMarkedForDeletion(ctx context.Context, userID
 	}
 	c.tenantBucketIndexLastUpdate.DeleteLabelValues(userID)
 
+	if c.cfg.CompactionSummaryEnabled {
+		if err := bucketindex.DeleteSummary(ctx, c.bucketClient, userID, c.cfgProvider); err != nil {
+			return err
+		}
+	}
+
 	var deletedBlocks, failed int
 	err := userBucket.Iter(ctx, "", func(name string) error {
 		if err := ctx.Err(); err != nil {
@@ -335,6 +352,20 @@ func (c *BlocksCleaner) deleteUserMarkedForDeletion(ctx context.Context, userID
 		return err
 	}
 
+	// The Parquet exports of the blocks and the WAL shipped by the ingesters contain samples too,
+	// so they're deleted along with the blocks.
+	if deleted, err := bucket.DeletePrefix(ctx, userBucket, ParquetExportPrefix, userLogger); err != nil {
+		return errors.Wrap(err, "failed to delete Parquet exports")
+	} else if deleted > 0 {
+		level.Info(userLogger).Log("msg", "deleted Parquet exports for tenant marked for deletion", "count", deleted)
+	}
+
+	if deleted, err := bucket.DeletePrefix(ctx, userBucket, mimir_tsdb.WALShippingDir, userLogger); err != nil {
+		return errors.Wrap(err, "failed to delete shipped WAL")
+	} else if deleted > 0 {
+		level.Info(userLogger).Log("msg", "deleted shipped WAL for tenant marked for deletion", "count", deleted)
+	}
+
 	if failed > 0 {
 		// The number of blocks left in the storage is equal to the number of blocks we failed
 		// to delete. We also consider them all marked for deletion given the next run will try
@@ -387,6 +418,18 @@ func (c *BlocksCleaner) deleteUserMarkedForDeletion(ctx context.Context, userID
 		level.Info(userLogger).Log("msg", "deleted files under "+block.DebugMetas+" for tenant marked for deletion", "count", deleted)
 	}
 
+	if deleted, err := bucket.DeletePrefix(ctx, userBucket, JobFailuresPrefix, userLogger); err != nil {
+		return errors.Wrap(err, "failed to delete compaction job failures")
+	} else if deleted > 0 {
+		level.Info(userLogger).Log("msg", "deleted compaction job failures for tenant marked for deletion", "count", deleted)
+	}
+
+	if deleted, err := bucket.DeletePrefix(ctx, userBucket, mimir_tsdb.BlockQueryStatsDir, userLogger); err != nil {
+		return errors.Wrap(err, "failed to delete blocks query statistics")
+	} else if deleted > 0 {
+		level.Info(userLogger).Log("msg", "deleted blocks query statistics for tenant marked for deletion", "count", deleted)
+	}
+
 	// Tenant deletion mark file is inside Markers as well.
 	if deleted, err := bucket.DeletePrefix(ctx, userBucket, block.MarkersPathname, userLogger); err != nil {
 		return errors.Wrap(err, "failed to delete marker files")
@@ -433,6 +476,9 @@ func (c *BlocksCleaner) cleanUser(ctx context.Context, userID string, userLogger
 
 	// Generate an updated in-memory version of the bucket index.
 	w := bucketindex.NewUpdater(c.bucketClient, userID, c.cfgProvider, userLogger)
+	if c.cfg.PartialBlockDeletionIncludeCorruptedMeta {
+		w.EnableMetaBlockIDCheck()
+	}
 	idx, partials, err := w.UpdateIndex(ctx, idx)
 	if err != nil {
 		return err
@@ -465,6 +511,12 @@ func (c *BlocksCleaner) cleanUser(ctx context.Context, userID string, userLogger
 		if err := bucketindex.WriteIndex(ctx, c.bucketClient, userID, c.cfgProvider, idx); err != nil {
 			return err
 		}
+
+		if c.cfg.CompactionSummaryEnabled {
+			if err := bucketindex.WriteSummary(ctx, c.bucketClient, userID, c.cfgProvider, bucketindex.NewSummary(idx)); err != nil {
+				return err
+			}
+		}
 	}
 
 	c.tenantBlocks.WithLabelValues(userID).Set(float64(len(idx.Blocks)))
@@ -521,6 +573,13 @@ func (c *BlocksCleaner) deleteBlocksMarkedForDeletion(ctx context.Context, idx *
 	_ = concurrency.ForEachJob(ctx, len(blocksToDelete), c.cfg.DeleteBlocksConcurrency, func(ctx context.Context, jobIdx int) error {
 		blockID := blocksToDelete[jobIdx]
 
+		// The Parquet export of the block is deleted first, so that it's never left behind once the block is deleted.
+		if err := deleteParquetExport(ctx, userBucket, blockID); err != nil {
+			c.blocksFailedTotal.Inc()
+			level.Warn(userLogger).Log("msg", "failed to delete Parquet export of block marked for deletion", "block", blockID, "err", err)
+			return nil
+		}
+
 		if err := block.Delete(ctx, userLogger, userBucket, blockID); err != nil {
 			c.blocksFailedTotal.Inc()
 			level.Warn(userLogger).Log("msg", "failed to delete block marked for deletion", "block", blockID, "err", err)
@@ -545,8 +604,10 @@ func (c *BlocksCleaner) cleanUserPartialBlocks(ctx context.Context, partials map
 	blocks := make([]ulid.ULID, 0, len(partials))
 
 	for blockID, blockErr := range partials {
-		// We can safely delete only blocks which are partial because the meta.json is missing.
-		if !errors.Is(blockErr, bucketindex.ErrBlockMetaNotFound) {
+		// We can safely delete only blocks which are partial because the meta.json is missing,
+		// or corrupted if enabled.
+		if !errors.Is(blockErr, bucketindex.ErrBlockMetaNotFound) &&
+			!(c.cfg.PartialBlockDeletionIncludeCorruptedMeta && errors.Is(blockErr, bucketindex.ErrBlockMetaCorrupted)) {
 			continue
 		}
 		blocks = append(blocks, blockID)
@@ -573,7 +634,12 @@ func (c *BlocksCleaner) cleanUserPartialBlocks(ctx context.Context, partials map
 		}
 
 		// Hard-delete partial blocks having a deletion mark, even if the deletion threshold has not
-		// been reached yet.
+		// been reached yet. The Parquet export of the block is deleted first, like for the other blocks.
+		if err := deleteParquetExport(ctx, userBucket, blockID); err != nil {
+			c.blocksFailedTotal.Inc()
+			level.Warn(userLogger).Log("msg", "error deleting Parquet export of partial block marked for deletion", "block", blockID, "err", err)
+			return nil
+		}
 		if err := block.Delete(ctx, userLogger, userBucket, blockID); err != nil {
 			c.blocksFailedTotal.Inc()
 			level.Warn(userLogger).Log("msg", "error deleting partial block marked for deletion", "block", blockID, "err", err)
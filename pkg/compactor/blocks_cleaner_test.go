@@ -101,6 +101,15 @@ func testBlocksCleanerWithOptions(t *testing.T, options testBlocksCleanerOptions
 	user4DebugMetaFile := path.Join("user-4", block.DebugMetas, "meta.json")
 	require.NoError(t, bucketClient.Upload(context.Background(), user4DebugMetaFile, strings.NewReader("some random content here")))
 
+	// Parquet exports of blocks.
+	for _, id := range []ulid.ULID{block1, block2, block3, block5} {
+		require.NoError(t, bucketClient.Upload(ctx, path.Join("user-1", ParquetExportPath(id)), strings.NewReader("parquet")))
+	}
+	// Parquet export pending markers of blocks.
+	for _, id := range []ulid.ULID{block2, block3} {
+		require.NoError(t, bucketClient.Upload(ctx, path.Join("user-1", parquetExportPendingMarkerPath(id)), strings.NewReader("{}")))
+	}
+
 	cfg := BlocksCleanerConfig{
 		DeletionDelay:           deletionDelay,
 		CleanupInterval:         time.Minute,
@@ -138,6 +147,13 @@ func testBlocksCleanerWithOptions(t *testing.T, options testBlocksCleanerOptions
 		{path: path.Join("user-1", block.DeletionMarkFilepath(block5)), expectedExists: false},
 		// Should not delete a partial block without deletion mark.
 		{path: path.Join("user-1", block6.String(), "index"), expectedExists: true},
+		// Should delete the Parquet exports and pending markers of the deleted blocks only.
+		{path: path.Join("user-1", ParquetExportPath(block1)), expectedExists: true},
+		{path: path.Join("user-1", ParquetExportPath(block2)), expectedExists: true},
+		{path: path.Join("user-1", ParquetExportPath(block3)), expectedExists: false},
+		{path: path.Join("user-1", ParquetExportPath(block5)), expectedExists: false},
+		{path: path.Join("user-1", parquetExportPendingMarkerPath(block2)), expectedExists: true},
+		{path: path.Join("user-1", parquetExportPendingMarkerPath(block3)), expectedExists: false},
 		// Should completely delete blocks for user-3, marked for deletion
 		{path: path.Join("user-3", block9.String(), block.MetaFilename), expectedExists: false},
 		{path: path.Join("user-3", block9.String(), "index"), expectedExists: false},
@@ -220,6 +236,70 @@ func testBlocksCleanerWithOptions(t *testing.T, options testBlocksCleanerOptions
 	))
 }
 
+func TestBlocksCleaner_ShouldDeleteAllTenantDataForTenantMarkedForDeletion(t *testing.T) {
+	const userID = "user-1"
+
+	bucketClient, _ := mimir_testutil.PrepareFilesystemBucket(t)
+	bucketClient = block.BucketWithGlobalMarkers(bucketClient)
+
+	ctx := context.Background()
+	block1 := createTSDBBlock(t, bucketClient, userID, 10, 20, 2, nil)
+
+	// The tenant deletion has finished longer than the tenant cleanup delay ago.
+	mark := tsdb.NewTenantDeletionMark(time.Now())
+	mark.FinishedTime = util.UnixSecondsFromTime(time.Now().Add(-time.Minute))
+	require.NoError(t, tsdb.WriteTenantDeletionMark(ctx, bucketClient, userID, nil, mark))
+
+	// Data written by the other components in the tenant's bucket.
+	files := []string{
+		path.Join(userID, block.DebugMetas, block1.String()+".json"),
+		path.Join(userID, JobFailuresPrefix, "job"+jobFailuresExtension),
+		path.Join(userID, tsdb.BlockQueryStatsDir, "store-gateway-1.json"),
+	}
+	// Data which must be deleted along with the blocks.
+	dataFiles := []string{
+		path.Join(userID, block1.String(), block.MetaFilename),
+		path.Join(userID, tsdb.WALShippingDir, "ingester-1", "wal", "00000000"),
+		path.Join(userID, ParquetExportPath(block1)),
+	}
+	for _, file := range append(files, dataFiles[1:]...) {
+		require.NoError(t, bucketClient.Upload(ctx, file, strings.NewReader("content")))
+	}
+
+	cfg := BlocksCleanerConfig{
+		DeletionDelay:           time.Hour,
+		CleanupInterval:         time.Minute,
+		CleanupConcurrency:      1,
+		DeleteBlocksConcurrency: 1,
+	}
+
+	cleaner := NewBlocksCleaner(cfg, bucketClient, tsdb.AllUsers, newMockConfigProvider(), log.NewNopLogger(), nil)
+	require.NoError(t, services.StartAndAwaitRunning(ctx, cleaner))
+	t.Cleanup(func() { require.NoError(t, services.StopAndAwaitTerminated(ctx, cleaner)) })
+
+	// The first run deletes the blocks, and updates the finished time of the tenant deletion mark.
+	for _, file := range files {
+		exists, err := bucketClient.Exists(ctx, file)
+		require.NoError(t, err)
+		assert.True(t, exists, file)
+	}
+	for _, file := range dataFiles {
+		exists, err := bucketClient.Exists(ctx, file)
+		require.NoError(t, err)
+		assert.False(t, exists, file)
+	}
+
+	// The next run cleans up the tenant, given there's no tenant cleanup delay.
+	require.NoError(t, cleaner.runCleanupWithErr(ctx))
+
+	var remaining []string
+	require.NoError(t, bucketClient.Iter(ctx, userID, func(name string) error {
+		remaining = append(remaining, name)
+		return nil
+	}, objstore.WithRecursiveIter))
+	assert.Empty(t, remaining)
+}
+
 func TestBlocksCleaner_ShouldContinueOnBlockDeletionFailure(t *testing.T) {
 	const userID = "user-1"
 
@@ -779,6 +859,50 @@ func TestBlocksCleaner_ShouldCleanUpFilesWhenNoMoreBlocksRemain(t *testing.T) {
 	require.ErrorIs(t, err, bucketindex.ErrIndexNotFound)
 }
 
+func TestBlocksCleaner_ShouldWriteCompactionSummaryWhenEnabled(t *testing.T) {
+	for _, enabled := range []bool{false, true} {
+		t.Run(fmt.Sprintf("enabled=%t", enabled), func(t *testing.T) {
+			bucketClient, _ := mimir_testutil.PrepareFilesystemBucket(t)
+			bucketClient = block.BucketWithGlobalMarkers(bucketClient)
+
+			const userID = "user-1"
+			ctx := context.Background()
+
+			createTSDBBlock(t, bucketClient, userID, 20, 30, 2, nil)
+			createTSDBBlock(t, bucketClient, userID, 10, 20, 2, map[string]string{tsdb.CompactorShardIDExternalLabel: "1_of_2"})
+
+			cfg := BlocksCleanerConfig{
+				DeletionDelay:            time.Hour,
+				CleanupInterval:          time.Minute,
+				CleanupConcurrency:       1,
+				DeleteBlocksConcurrency:  1,
+				CompactionSummaryEnabled: enabled,
+			}
+
+			logger := test.NewTestingLogger(t)
+			cleaner := NewBlocksCleaner(cfg, bucketClient, tsdb.AllUsers, newMockConfigProvider(), logger, prometheus.NewPedanticRegistry())
+			require.NoError(t, cleaner.runCleanupWithErr(ctx))
+
+			summary, err := bucketindex.ReadSummary(ctx, bucketClient, userID, nil, logger)
+			if !enabled {
+				require.ErrorIs(t, err, bucketindex.ErrSummaryNotFound)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Len(t, summary.Blocks, 2)
+			assert.Equal(t, int64(10), summary.Blocks[0].MinTime)
+			assert.Equal(t, int64(20), summary.Blocks[0].MaxTime)
+			assert.Equal(t, uint64(2), summary.Blocks[0].NumSeries)
+			assert.Equal(t, "1_of_2", summary.Blocks[0].Labels[tsdb.CompactorShardIDExternalLabel])
+			assert.Equal(t, int64(20), summary.Blocks[1].MinTime)
+			assert.Equal(t, int64(30), summary.Blocks[1].MaxTime)
+			assert.True(t, summary.HasDataWithin(15, 25))
+			assert.False(t, summary.HasDataWithin(30, 40))
+		})
+	}
+}
+
 func TestBlocksCleaner_ShouldRemovePartialBlocksOutsideDelayPeriod(t *testing.T) {
 	bucketClient, _ := mimir_testutil.PrepareFilesystemBucket(t)
 	bucketClient = block.BucketWithGlobalMarkers(bucketClient)
@@ -930,6 +1054,70 @@ func TestBlocksCleaner_ShouldNotRemovePartialBlocksInsideDelayPeriod(t *testing.
 	))
 }
 
+func TestBlocksCleaner_ShouldRemovePartialBlocksWithCorruptedMetaIfEnabled(t *testing.T) {
+	bucketClient, _ := mimir_testutil.PrepareFilesystemBucket(t)
+	bucketClient = block.BucketWithGlobalMarkers(bucketClient)
+
+	ts := func(hours int) int64 {
+		return time.Now().Add(time.Duration(hours)*time.Hour).Unix() * 1000
+	}
+
+	block1 := createTSDBBlock(t, bucketClient, "user-1", ts(-10), ts(-8), 2, nil)
+	block2 := createTSDBBlock(t, bucketClient, "user-1", ts(-8), ts(-6), 2, nil)
+	block3 := createTSDBBlock(t, bucketClient, "user-1", ts(-6), ts(-4), 2, nil)
+
+	cfg := BlocksCleanerConfig{
+		DeletionDelay:           time.Hour,
+		CleanupInterval:         time.Minute,
+		CleanupConcurrency:      1,
+		DeleteBlocksConcurrency: 1,
+
+		PartialBlockDeletionIncludeCorruptedMeta: true,
+	}
+
+	ctx := context.Background()
+	logger := test.NewTestingLogger(t)
+	reg := prometheus.NewPedanticRegistry()
+	cfgProvider := newMockConfigProvider()
+	cfgProvider.userPartialBlockDelay["user-1"] = 1 * time.Nanosecond
+
+	cleaner := NewBlocksCleaner(cfg, bucketClient, tsdb.AllUsers, cfgProvider, logger, reg)
+
+	// Corrupt the meta.json of a block, and replace the meta.json of another block with the one of the first block.
+	require.NoError(t, bucketClient.Upload(ctx, path.Join("user-1", block2.String(), block.MetaFilename), strings.NewReader("corrupted file contents")))
+	block1Meta, err := bucketClient.Get(ctx, path.Join("user-1", block1.String(), block.MetaFilename))
+	require.NoError(t, err)
+	require.NoError(t, bucketClient.Upload(ctx, path.Join("user-1", block3.String(), block.MetaFilename), block1Meta))
+
+	require.NoError(t, cleaner.cleanUser(ctx, "user-1", logger))
+	checkBlock(t, "user-1", bucketClient, block1, true, false)
+	checkBlock(t, "user-1", bucketClient, block2, true, true)
+	checkBlock(t, "user-1", bucketClient, block3, true, true)
+
+	require.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(`
+			# HELP cortex_bucket_blocks_count Total number of blocks in the bucket. Includes blocks marked for deletion, but not partial blocks.
+			# TYPE cortex_bucket_blocks_count gauge
+			cortex_bucket_blocks_count{user="user-1"} 1
+			# HELP cortex_bucket_blocks_partials_count Total number of partial blocks.
+			# TYPE cortex_bucket_blocks_partials_count gauge
+			cortex_bucket_blocks_partials_count{user="user-1"} 2
+			# HELP cortex_compactor_blocks_marked_for_deletion_total Total number of blocks marked for deletion in compactor.
+			# TYPE cortex_compactor_blocks_marked_for_deletion_total counter
+			cortex_compactor_blocks_marked_for_deletion_total{reason="partial"} 2
+			cortex_compactor_blocks_marked_for_deletion_total{reason="retention"} 0
+			`),
+		"cortex_bucket_blocks_count",
+		"cortex_bucket_blocks_partials_count",
+		"cortex_compactor_blocks_marked_for_deletion_total",
+	))
+
+	// Blocks marked for deletion are deleted at the next cleanup.
+	require.NoError(t, cleaner.cleanUser(ctx, "user-1", logger))
+	checkBlock(t, "user-1", bucketClient, block1, true, false)
+	checkBlock(t, "user-1", bucketClient, block2, false, false)
+	checkBlock(t, "user-1", bucketClient, block3, false, false)
+}
+
 func TestBlocksCleaner_ShouldNotRemovePartialBlocksIfConfiguredDelayIsInvalid(t *testing.T) {
 	ctx := context.Background()
 	reg := prometheus.NewPedanticRegistry()
@@ -1501,6 +1689,8 @@ type mockConfigProvider struct {
 	userPartialBlockDelayInvalid map[string]bool
 	verifyChunks                 map[string]bool
 	perTenantInMemoryCache       map[string]int
+	tenantPool                   map[string]string
+	firstLevelOnlyUntil          map[string]time.Time
 }
 
 func newMockConfigProvider() *mockConfigProvider {
@@ -1515,6 +1705,8 @@ func newMockConfigProvider() *mockConfigProvider {
 		userPartialBlockDelayInvalid: make(map[string]bool),
 		verifyChunks:                 make(map[string]bool),
 		perTenantInMemoryCache:       make(map[string]int),
+		tenantPool:                   make(map[string]string),
+		firstLevelOnlyUntil:          make(map[string]time.Time),
 	}
 }
 
@@ -1570,6 +1762,14 @@ func (m *mockConfigProvider) CompactorInMemoryTenantMetaCacheSize(userID string)
 	return m.perTenantInMemoryCache[userID]
 }
 
+func (m *mockConfigProvider) CompactorTenantPool(userID string) string {
+	return m.tenantPool[userID]
+}
+
+func (m *mockConfigProvider) CompactorFirstLevelOnlyUntil(userID string) time.Time {
+	return m.firstLevelOnlyUntil[userID]
+}
+
 func (m *mockConfigProvider) S3SSEType(string) string {
 	return ""
 }
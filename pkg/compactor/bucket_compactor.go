@@ -387,6 +387,7 @@ func (c *BucketCompactor) runCompactionJob(ctx context.Context, job *Job) (shoul
 
 	uploadBegin := time.Now()
 	uploadedBlocks := atomic.NewInt64(0)
+	uploadedNotExportedBlocks := atomic.NewInt64(0)
 
 	if err = verifyCompactedBlocksTimeRanges(compIDs, toCompactMinTime.UnixMilli(), toCompactMaxTime.UnixMilli(), subDir); err != nil {
 		level.Warn(jobLogger).Log("msg", "compacted blocks verification failed", "err", err)
@@ -426,6 +427,13 @@ func (c *BucketCompactor) runCompactionJob(ctx context.Context, job *Job) (shoul
 			return errors.Wrapf(err, "invalid result block %s", bdir)
 		}
 
+		// Precompute the series hashes, so that store-gateways don't need to hash the series labels for sharded queries.
+		if c.uploadSeriesHashes {
+			if _, err := block.WriteSeriesHashes(ctx, bdir); err != nil {
+				return errors.Wrapf(err, "failed to write the series hashes of block %s", bdir)
+			}
+		}
+
 		begin := time.Now()
 		if err := block.Upload(ctx, jobLogger, c.bkt, bdir, nil); err != nil {
 			return errors.Wrapf(err, "upload of %s failed", blockToUpload.ulid)
@@ -433,6 +441,25 @@ func (c *BucketCompactor) runCompactionJob(ctx context.Context, job *Job) (shoul
 
 		elapsed := time.Since(begin)
 		level.Info(jobLogger).Log("msg", "uploaded block", "result_block", blockToUpload.ulid, "duration", elapsed, "duration_ms", elapsed.Milliseconds(), "external_labels", labels.FromMap(newLabels))
+
+		// The Parquet export is best-effort: a failure doesn't fail the compaction job.
+		if c.parquetExportMinLevel == 0 || newMeta.Compaction.Level < c.parquetExportMinLevel {
+			uploadedNotExportedBlocks.Inc()
+		} else if err := exportParquet(ctx, jobLogger, c.bkt, bdir, blockToUpload.ulid); err != nil {
+			uploadedNotExportedBlocks.Inc()
+			c.metrics.parquetExportFailures.Inc()
+			level.Warn(jobLogger).Log("msg", "failed to export compacted block to Parquet, the export will be retried on the next compaction", "result_block", blockToUpload.ulid, "err", err)
+
+			sources := make([]ulid.ULID, 0, len(toCompact))
+			for _, meta := range toCompact {
+				sources = append(sources, meta.ULID)
+			}
+			if err := writeParquetExportPendingMarker(ctx, c.bkt, blockToUpload.ulid, sources); err != nil {
+				level.Warn(jobLogger).Log("msg", "failed to write Parquet export pending marker", "result_block", blockToUpload.ulid, "err", err)
+			}
+		} else {
+			c.metrics.parquetExportedBlocks.Inc()
+		}
 		return nil
 	})
 	if err != nil {
@@ -456,6 +483,16 @@ func (c *BucketCompactor) runCompactionJob(ctx context.Context, job *Job) (shoul
 		if err := deleteBlock(c.bkt, meta.ULID, filepath.Join(subDir, meta.ULID.String()), jobLogger, c.metrics.blocksMarkedForDeletion); err != nil {
 			return false, nil, errors.Wrapf(err, "mark old block for deletion from bucket")
 		}
+
+		// The data of the compacted blocks is now in the Parquet export of the new blocks, so the exports of the
+		// compacted blocks are deleted to avoid exporting the same samples twice. They're kept if any new block has
+		// not been exported: when its export failed, they're deleted once the export is retried successfully, and
+		// when the Parquet export min level has been raised, they're only deleted along with their blocks.
+		if c.parquetExportMinLevel > 0 && uploadedNotExportedBlocks.Load() == 0 {
+			if err := deleteParquetExport(ctx, c.bkt, meta.ULID); err != nil {
+				level.Warn(jobLogger).Log("msg", "failed to delete Parquet export of compacted block", "block", meta.ULID, "err", err)
+			}
+		}
 	}
 
 	return true, compIDs, nil
@@ -672,6 +709,10 @@ type BucketCompactorMetrics struct {
 	blocksMaxTimeDelta                 prometheus.Histogram
 	compactionJobDuration              *prometheus.HistogramVec
 	compactionJobBlocks                *prometheus.HistogramVec
+	jobsQuarantined                    prometheus.Counter
+	quarantinedJobsSkipped             prometheus.Counter
+	parquetExportedBlocks              prometheus.Counter
+	parquetExportFailures              prometheus.Counter
 }
 
 // NewBucketCompactorMetrics makes a new BucketCompactorMetrics.
@@ -731,6 +772,22 @@ func NewBucketCompactorMetrics(blocksMarkedForDeletion prometheus.Counter, reg p
 			Help:    "Difference between now and the max time of a block being compacted in seconds.",
 			Buckets: prometheus.LinearBuckets(86400, 43200, 8), // 1 to 5 days, in 12 hour intervals
 		}),
+		jobsQuarantined: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "cortex_compactor_jobs_quarantined_total",
+			Help: "Total number of compaction jobs quarantined because they failed too many times.",
+		}),
+		quarantinedJobsSkipped: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "cortex_compactor_quarantined_jobs_skipped_total",
+			Help: "Total number of times a quarantined compaction job has been skipped.",
+		}),
+		parquetExportedBlocks: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "cortex_compactor_parquet_exported_blocks_total",
+			Help: "Total number of compacted blocks exported to Parquet.",
+		}),
+		parquetExportFailures: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "cortex_compactor_parquet_export_failures_total",
+			Help: "Total number of compacted blocks which failed to be exported to Parquet.",
+		}),
 	}
 	bcm.blocksMarkedForNoCompact.WithLabelValues(block.OutOfOrderChunksNoCompactReason).Add(0)
 	bcm.blocksMarkedForNoCompact.WithLabelValues(block.CriticalNoCompactReason).Add(0)
@@ -747,20 +804,23 @@ var ownAllJobs = func(*Job) (bool, error) {
 
 // BucketCompactor compacts blocks in a bucket.
 type BucketCompactor struct {
-	logger               log.Logger
-	sy                   *metaSyncer
-	grouper              Grouper
-	comp                 Compactor
-	planner              Planner
-	compactDir           string
-	bkt                  objstore.Bucket
-	concurrency          int
-	skipUnhealthyBlocks  bool
-	ownJob               ownCompactionJobFunc
-	sortJobs             JobsOrderFunc
-	waitPeriod           time.Duration
-	blockSyncConcurrency int
-	metrics              *BucketCompactorMetrics
+	logger                log.Logger
+	sy                    *metaSyncer
+	grouper               Grouper
+	comp                  Compactor
+	planner               Planner
+	compactDir            string
+	bkt                   objstore.Bucket
+	concurrency           int
+	skipUnhealthyBlocks   bool
+	ownJob                ownCompactionJobFunc
+	sortJobs              JobsOrderFunc
+	waitPeriod            time.Duration
+	blockSyncConcurrency  int
+	uploadSeriesHashes    bool
+	parquetExportMinLevel int
+	jobFailures           *jobFailuresTracker
+	metrics               *BucketCompactorMetrics
 }
 
 // NewBucketCompactor creates a new bucket compactor.
@@ -778,26 +838,37 @@ func NewBucketCompactor(
 	sortJobs JobsOrderFunc,
 	waitPeriod time.Duration,
 	blockSyncConcurrency int,
+	uploadSeriesHashes bool,
+	parquetExportMinLevel int,
+	maxJobFailures int,
 	metrics *BucketCompactorMetrics,
 ) (*BucketCompactor, error) {
 	if concurrency <= 0 {
 		return nil, errors.Errorf("invalid concurrency level (%d), concurrency level must be > 0", concurrency)
 	}
+
+	var jobFailures *jobFailuresTracker
+	if maxJobFailures > 0 {
+		jobFailures = newJobFailuresTracker(bkt, logger, maxJobFailures, metrics)
+	}
 	return &BucketCompactor{
-		logger:               logger,
-		sy:                   sy,
-		grouper:              grouper,
-		planner:              planner,
-		comp:                 comp,
-		compactDir:           compactDir,
-		bkt:                  bkt,
-		concurrency:          concurrency,
-		skipUnhealthyBlocks:  skipUnhealthyBlocks,
-		ownJob:               ownJob,
-		sortJobs:             sortJobs,
-		waitPeriod:           waitPeriod,
-		blockSyncConcurrency: blockSyncConcurrency,
-		metrics:              metrics,
+		logger:                logger,
+		sy:                    sy,
+		grouper:               grouper,
+		planner:               planner,
+		comp:                  comp,
+		compactDir:            compactDir,
+		bkt:                   bkt,
+		concurrency:           concurrency,
+		skipUnhealthyBlocks:   skipUnhealthyBlocks,
+		ownJob:                ownJob,
+		sortJobs:              sortJobs,
+		waitPeriod:            waitPeriod,
+		blockSyncConcurrency:  blockSyncConcurrency,
+		uploadSeriesHashes:    uploadSeriesHashes,
+		parquetExportMinLevel: parquetExportMinLevel,
+		jobFailures:           jobFailures,
+		metrics:               metrics,
 	}, nil
 }
 
@@ -857,6 +928,9 @@ func (c *BucketCompactor) Compact(ctx context.Context, maxCompactionTime time.Du
 					shouldRerunJob, compactedBlockIDs, err := c.runCompactionJob(workCtx, g)
 					if err == nil {
 						c.metrics.groupCompactionRunsCompleted.Inc()
+						if c.jobFailures != nil {
+							c.jobFailures.recordSuccess(workCtx, g)
+						}
 						if hasNonZeroULIDs(compactedBlockIDs) {
 							c.metrics.groupCompactions.Inc()
 						}
@@ -921,6 +995,12 @@ func (c *BucketCompactor) Compact(ctx context.Context, maxCompactionTime time.Du
 						}
 					}
 
+					// Quarantine the job if it keeps failing, so that it doesn't block the compaction of
+					// the other jobs of the tenant on each compaction run. A cancelled job isn't a failure of the job.
+					if c.jobFailures != nil && workCtx.Err() == nil && c.jobFailures.recordFailure(ctx, g, err) {
+						continue
+					}
+
 					errChan <- errors.Wrapf(err, "group %s", g.Key())
 					return
 				}
@@ -944,6 +1024,15 @@ func (c *BucketCompactor) Compact(ctx context.Context, maxCompactionTime time.Du
 			return errors.Wrap(err, "build compaction jobs")
 		}
 
+		// Skip jobs which have been quarantined because they failed too many times. The filter runs on all
+		// the jobs of the tenant, because it also cleans up the failures tracked for jobs owned by other compactors.
+		if c.jobFailures != nil {
+			jobs, err = c.jobFailures.filterQuarantinedJobs(ctx, jobs, c.sy.Metas())
+			if err != nil {
+				return err
+			}
+		}
+
 		// There is another check just before we start processing the job, but we can avoid sending it
 		// to the goroutine in the first place.
 		jobs, err = c.filterOwnJobs(jobs)
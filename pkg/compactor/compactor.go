@@ -10,6 +10,7 @@ import (
 	"flag"
 	"fmt"
 	"hash/fnv"
+	"math"
 	"math/rand"
 	"os"
 	"path"
@@ -19,6 +20,7 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/alecthomas/units"
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
 	"github.com/grafana/dskit/backoff"
@@ -29,6 +31,7 @@ import (
 	"github.com/pkg/errors"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/prometheus/tsdb/chunks"
 	"github.com/thanos-io/objstore"
 	"go.uber.org/atomic"
 
@@ -46,6 +49,14 @@ const (
 	// ringAutoForgetUnhealthyPeriods is how many consecutive timeout periods an unhealthy instance
 	// in the ring will be automatically removed after.
 	ringAutoForgetUnhealthyPeriods = 10
+
+	// defaultConsolidatedChunkSegmentSize is the default max size of the chunk segment files
+	// of the blocks written with consolidated chunk segments.
+	defaultConsolidatedChunkSegmentSize = flagext.Bytes(2 * units.GiB)
+
+	// maxConsolidatedChunkSegmentSize is the max size of a chunk segment file, given the offset
+	// of a chunk in the chunk reference is a 32 bits integer.
+	maxConsolidatedChunkSegmentSize = math.MaxUint32
 )
 
 const (
@@ -60,6 +71,8 @@ var (
 	errInvalidMaxClosingBlocksConcurrency         = fmt.Errorf("invalid max-closing-blocks-concurrency value, must be positive")
 	errInvalidSymbolFlushersConcurrency           = fmt.Errorf("invalid symbols-flushers-concurrency value, must be positive")
 	errInvalidMaxBlockUploadValidationConcurrency = fmt.Errorf("invalid max-block-upload-validation-concurrency value, can't be negative")
+	errInvalidConsolidatedChunkSegmentSize        = fmt.Errorf("invalid consolidated-chunk-segment-size value, must be between %d and %d bytes", chunks.SegmentHeaderSize+1, maxConsolidatedChunkSegmentSize)
+	errInvalidMaxJobFailures                      = fmt.Errorf("invalid max-job-failures value, can't be negative")
 	RingOp                                        = ring.NewOp([]ring.InstanceState{ring.ACTIVE}, nil)
 
 	// compactionIgnoredLabels defines the external labels that compactor will
@@ -105,6 +118,18 @@ type Config struct {
 	TenantCleanupDelay         time.Duration           `yaml:"tenant_cleanup_delay" category:"advanced"`
 	MaxCompactionTime          time.Duration           `yaml:"max_compaction_time" category:"advanced"`
 	NoBlocksFileCleanupEnabled bool                    `yaml:"no_blocks_file_cleanup_enabled" category:"experimental"`
+	CompactionSummaryEnabled   bool                    `yaml:"compaction_summary_enabled" category:"experimental"`
+	UploadSeriesHashes         bool                    `yaml:"upload_series_hashes" category:"experimental"`
+	MaxJobFailures             int                     `yaml:"max_job_failures" category:"experimental"`
+
+	PartialBlockDeletionIncludeCorruptedMeta bool `yaml:"partial_block_deletion_include_corrupted_meta" category:"experimental"`
+
+	// Consolidated chunk segments options.
+	ConsolidatedChunkSegmentsMinLevel int           `yaml:"consolidated_chunk_segments_min_level" category:"experimental"`
+	ConsolidatedChunkSegmentSize      flagext.Bytes `yaml:"consolidated_chunk_segment_size" category:"experimental"`
+
+	// Parquet export options.
+	ParquetExportMinLevel int `yaml:"parquet_export_min_level" category:"experimental"`
 
 	// Compactor concurrency options
 	MaxOpeningBlocksConcurrency         int `yaml:"max_opening_blocks_concurrency" category:"advanced"`          // Number of goroutines opening blocks before compaction.
@@ -129,6 +154,9 @@ type Config struct {
 	// Allow downstream projects to customise the blocks compactor.
 	BlocksGrouperFactory   BlocksGrouperFactory   `yaml:"-"`
 	BlocksCompactorFactory BlocksCompactorFactory `yaml:"-"`
+
+	// TenantDeletedFn is called when a tenant has been marked for deletion through the API.
+	TenantDeletedFn func(userID string) `yaml:"-"`
 }
 
 // RegisterFlags registers the MultitenantCompactor flags.
@@ -156,6 +184,14 @@ func (cfg *Config) RegisterFlags(f *flag.FlagSet, logger log.Logger) {
 		"If 0, blocks will be deleted straight away. Note that deleting blocks immediately can cause query failures.")
 	f.DurationVar(&cfg.TenantCleanupDelay, "compactor.tenant-cleanup-delay", 6*time.Hour, "For tenants marked for deletion, this is the time between deletion of the last block, and doing final cleanup (marker files, debug files) of the tenant.")
 	f.BoolVar(&cfg.NoBlocksFileCleanupEnabled, "compactor.no-blocks-file-cleanup-enabled", false, "If enabled, will delete the bucket-index, markers and debug files in the tenant bucket when there are no blocks left in the index.")
+	f.BoolVar(&cfg.CompactionSummaryEnabled, "compactor.compaction-summary-enabled", false, "If enabled, the compactor uploads a compact summary of the tenant's blocks (time range, number of series and external labels of each block) alongside the bucket index. The summary is used by the query-frontend to skip queries targeting time ranges with no data in the storage.")
+	f.BoolVar(&cfg.UploadSeriesHashes, "compactor.upload-series-hashes", false, "If enabled, the compactor computes the hash of each series of the compacted blocks and uploads them alongside the block. Store-gateways can load the hashes to select the series of sharded queries without hashing their labels.")
+	f.IntVar(&cfg.MaxJobFailures, "compactor.max-job-failures", 0, "Max number of consecutive failures of a compaction job, tracked in the tenant's bucket across compaction runs and compactor restarts, after which the job is quarantined and skipped by the compactors. A quarantined job is compacted again once its blocks change. 0 = disabled.")
+	f.BoolVar(&cfg.PartialBlockDeletionIncludeCorruptedMeta, "compactor.partial-block-deletion-include-corrupted-meta", false, "If enabled, blocks whose meta.json can't be parsed or doesn't match the block ID are handled as partial blocks: they're marked for deletion once they haven't been modified for -compactor.partial-block-deletion-delay. Such blocks are left behind by uploads interrupted while writing the meta.json, and are never queried nor compacted.")
+	f.IntVar(&cfg.ConsolidatedChunkSegmentsMinLevel, "compactor.consolidated-chunk-segments-min-level", 0, "Minimum compaction level of the blocks written with consolidated chunk segments. Such blocks are written with fewer and larger chunk segment files, reducing the number of objects and requests to the object storage when querying historical data. 0 = disabled.")
+	cfg.ConsolidatedChunkSegmentSize = defaultConsolidatedChunkSegmentSize
+	f.Var(&cfg.ConsolidatedChunkSegmentSize, "compactor.consolidated-chunk-segment-size", "Max size of the chunk segment files of the blocks written with consolidated chunk segments.")
+	f.IntVar(&cfg.ParquetExportMinLevel, "compactor.parquet-export-min-level", 0, "Minimum compaction level of the compacted blocks exported to Parquet. The float samples of such blocks are exported to a Parquet file, one row per sample, uploaded to the "+ParquetExportPrefix+"/ prefix of the tenant's bucket, so that they can be analyzed offline without querying Mimir. The exports of the blocks compacted into a new exported block are deleted, and the exports are deleted along with their blocks. The export runs after the block upload. A failed export doesn't fail the compaction job, and is retried on the next compaction. 0 = disabled.")
 	// compactor concurrency options
 	f.IntVar(&cfg.MaxOpeningBlocksConcurrency, "compactor.max-opening-blocks-concurrency", 1, "Number of goroutines opening blocks before compaction.")
 	f.IntVar(&cfg.MaxClosingBlocksConcurrency, "compactor.max-closing-blocks-concurrency", 1, "Max number of blocks that can be closed concurrently during split compaction. Note that closing a newly compacted block uses a lot of memory for writing the index.")
@@ -195,9 +231,15 @@ func (cfg *Config) Validate(logger log.Logger) error {
 	if cfg.MaxBlockUploadValidationConcurrency < 0 {
 		return errInvalidMaxBlockUploadValidationConcurrency
 	}
+	if cfg.MaxJobFailures < 0 {
+		return errInvalidMaxJobFailures
+	}
 	if !util.StringsContain(CompactionOrders, cfg.CompactionJobsOrder) {
 		return errInvalidCompactionOrder
 	}
+	if cfg.ConsolidatedChunkSegmentsMinLevel > 0 && (cfg.ConsolidatedChunkSegmentSize <= chunks.SegmentHeaderSize || cfg.ConsolidatedChunkSegmentSize > maxConsolidatedChunkSegmentSize) {
+		return errInvalidConsolidatedChunkSegmentSize
+	}
 
 	return nil
 }
@@ -238,6 +280,13 @@ type ConfigProvider interface {
 
 	// CompactorInMemoryTenantMetaCacheSize returns number of parsed *Meta objects that we can keep in memory for the user between compactions.
 	CompactorInMemoryTenantMetaCacheSize(userID string) int
+
+	// CompactorTenantPool returns the name of the compactors pool compacting the given user. Empty = default pool.
+	CompactorTenantPool(userID string) string
+
+	// CompactorFirstLevelOnlyUntil returns the time until which only the compaction jobs of the first
+	// block range are run for the given user. The zero time means the setting is disabled.
+	CompactorFirstLevelOnlyUntil(userID string) time.Time
 }
 
 // MultitenantCompactor is a multi-tenant TSDB block compactor based on Thanos.
@@ -272,6 +321,7 @@ type MultitenantCompactor struct {
 	ring                   *ring.Ring
 	ringSubservices        *services.Manager
 	ringSubservicesWatcher *services.FailureWatcher
+	ringKV                 kv.Client
 
 	shardingStrategy shardingStrategy
 	jobsOrder        JobsOrderFunc
@@ -284,6 +334,7 @@ type MultitenantCompactor struct {
 	compactionRunsLastSuccess      prometheus.Gauge
 	compactionRunDiscoveredTenants prometheus.Gauge
 	compactionRunSkippedTenants    prometheus.Gauge
+	tenantsInPoolsWithoutCompactor prometheus.Gauge
 	compactionRunSucceededTenants  prometheus.Gauge
 	compactionRunFailedTenants     prometheus.Gauge
 	compactionRunInterval          prometheus.Gauge
@@ -384,6 +435,10 @@ func newMultitenantCompactor(
 			Name: "cortex_compactor_tenants_skipped",
 			Help: "Number of tenants skipped during the current compaction run. Reset to 0 when compactor is idle.",
 		}),
+		tenantsInPoolsWithoutCompactor: promauto.With(registerer).NewGauge(prometheus.GaugeOpts{
+			Name: "cortex_compactor_tenants_in_pools_without_compactors",
+			Help: "Number of tenants discovered during the last compaction run which are assigned to a compactors pool without any healthy compactor, and are not compacted.",
+		}),
 		compactionRunSucceededTenants: promauto.With(registerer).NewGauge(prometheus.GaugeOpts{
 			Name: "cortex_compactor_tenants_processing_succeeded",
 			Help: "Number of tenants successfully processed during the current compaction run. Reset to 0 when compactor is idle.",
@@ -468,7 +523,7 @@ func (c *MultitenantCompactor) starting(ctx context.Context) error {
 	c.bucketClient = block.BucketWithGlobalMarkers(c.bucketClient)
 
 	// Initialize the compactors ring if sharding is enabled.
-	c.ring, c.ringLifecycler, err = newRingAndLifecycler(c.compactorCfg.ShardingRing, c.logger, c.registerer)
+	c.ring, c.ringLifecycler, c.ringKV, err = newRingAndLifecycler(c.compactorCfg.ShardingRing, c.logger, c.registerer)
 	if err != nil {
 		return err
 	}
@@ -517,7 +572,7 @@ func (c *MultitenantCompactor) starting(ctx context.Context) error {
 	}
 
 	allowedTenants := util.NewAllowedTenants(c.compactorCfg.EnabledTenants, c.compactorCfg.DisabledTenants)
-	c.shardingStrategy = newSplitAndMergeShardingStrategy(allowedTenants, c.ring, c.ringLifecycler, c.cfgProvider)
+	c.shardingStrategy = newSplitAndMergeShardingStrategy(allowedTenants, c.compactorCfg.ShardingRing.Pool, c.ring, c.ringLifecycler, c.cfgProvider)
 
 	// Create the blocks cleaner (service).
 	c.blocksCleaner = NewBlocksCleaner(BlocksCleanerConfig{
@@ -527,7 +582,10 @@ func (c *MultitenantCompactor) starting(ctx context.Context) error {
 		TenantCleanupDelay:         c.compactorCfg.TenantCleanupDelay,
 		DeleteBlocksConcurrency:    defaultDeleteBlocksConcurrency,
 		NoBlocksFileCleanupEnabled: c.compactorCfg.NoBlocksFileCleanupEnabled,
+		CompactionSummaryEnabled:   c.compactorCfg.CompactionSummaryEnabled,
 		CompactionBlockRanges:      c.compactorCfg.BlockRanges,
+
+		PartialBlockDeletionIncludeCorruptedMeta: c.compactorCfg.PartialBlockDeletionIncludeCorruptedMeta,
 	}, c.bucketClient, c.shardingStrategy.blocksCleanerOwnsUser, c.cfgProvider, c.parentLogger, c.registerer)
 
 	// Start blocks cleaner asynchronously, don't wait until initial cleanup is finished.
@@ -539,16 +597,16 @@ func (c *MultitenantCompactor) starting(ctx context.Context) error {
 	return nil
 }
 
-func newRingAndLifecycler(cfg RingConfig, logger log.Logger, reg prometheus.Registerer) (*ring.Ring, *ring.BasicLifecycler, error) {
+func newRingAndLifecycler(cfg RingConfig, logger log.Logger, reg prometheus.Registerer) (*ring.Ring, *ring.BasicLifecycler, kv.Client, error) {
 	reg = prometheus.WrapRegistererWithPrefix("cortex_", reg)
 	kvStore, err := kv.NewClient(cfg.Common.KVStore, ring.GetCodec(), kv.RegistererWithKVName(reg, "compactor-lifecycler"), logger)
 	if err != nil {
-		return nil, nil, errors.Wrap(err, "failed to initialize compactors' KV store")
+		return nil, nil, nil, errors.Wrap(err, "failed to initialize compactors' KV store")
 	}
 
 	lifecyclerCfg, err := cfg.ToBasicLifecyclerConfig(logger)
 	if err != nil {
-		return nil, nil, errors.Wrap(err, "failed to build compactors' lifecycler config")
+		return nil, nil, nil, errors.Wrap(err, "failed to build compactors' lifecycler config")
 	}
 
 	var delegate ring.BasicLifecyclerDelegate
@@ -556,17 +614,17 @@ func newRingAndLifecycler(cfg RingConfig, logger log.Logger, reg prometheus.Regi
 	delegate = ring.NewLeaveOnStoppingDelegate(delegate, logger)
 	delegate = ring.NewAutoForgetDelegate(ringAutoForgetUnhealthyPeriods*lifecyclerCfg.HeartbeatTimeout, delegate, logger)
 
-	compactorsLifecycler, err := ring.NewBasicLifecycler(lifecyclerCfg, "compactor", ringKey, kvStore, delegate, logger, reg)
+	compactorsLifecycler, err := ring.NewBasicLifecycler(lifecyclerCfg, "compactor", cfg.ringKey(), kvStore, delegate, logger, reg)
 	if err != nil {
-		return nil, nil, errors.Wrap(err, "failed to initialize compactors' lifecycler")
+		return nil, nil, nil, errors.Wrap(err, "failed to initialize compactors' lifecycler")
 	}
 
-	compactorsRing, err := ring.New(cfg.toRingConfig(), "compactor", ringKey, logger, reg)
+	compactorsRing, err := ring.New(cfg.toRingConfig(), "compactor", cfg.ringKey(), logger, reg)
 	if err != nil {
-		return nil, nil, errors.Wrap(err, "failed to initialize compactors' ring client")
+		return nil, nil, nil, errors.Wrap(err, "failed to initialize compactors' ring client")
 	}
 
-	return compactorsRing, compactorsLifecycler, nil
+	return compactorsRing, compactorsLifecycler, kvStore, nil
 }
 
 func (c *MultitenantCompactor) stopping(_ error) error {
@@ -633,6 +691,7 @@ func (c *MultitenantCompactor) compactUsers(ctx context.Context) {
 
 	level.Info(c.logger).Log("msg", "discovered users from bucket", "users", len(users))
 	c.compactionRunDiscoveredTenants.Set(float64(len(users)))
+	c.checkTenantsPools(ctx, users)
 
 	// When starting multiple compactor replicas nearly at the same time, running in a cluster with
 	// a large number of tenants, we may end up in a situation where the 1st user is compacted by
@@ -821,12 +880,26 @@ func (c *MultitenantCompactor) compactUser(ctx context.Context, userID string) e
 		c.jobsOrder,
 		c.compactorCfg.CompactionWaitPeriod,
 		c.compactorCfg.BlockSyncConcurrency,
+		c.compactorCfg.UploadSeriesHashes,
+		c.compactorCfg.ParquetExportMinLevel,
+		c.compactorCfg.MaxJobFailures,
 		c.bucketCompactorMetrics,
 	)
 	if err != nil {
 		return errors.Wrap(err, "failed to create bucket compactor")
 	}
 
+	// The Parquet exports which failed in the previous compactions are retried by a single compactor of the tenant's shard.
+	if c.compactorCfg.ParquetExportMinLevel > 0 {
+		if owned, err := c.shardingStrategy.blocksCleanerOwnsUser(userID); err != nil {
+			level.Warn(userLogger).Log("msg", "unable to check if the tenant's failed Parquet exports should be retried", "err", err)
+		} else if owned {
+			if err := retryParquetExports(ctx, userLogger, userBucket, path.Join(c.compactorCfg.DataDir, "parquet-export"), c.bucketCompactorMetrics); err != nil {
+				level.Warn(userLogger).Log("msg", "failed to retry Parquet exports", "err", err)
+			}
+		}
+	}
+
 	if err := compactor.Compact(ctx, c.compactorCfg.MaxCompactionTime); err != nil {
 		return errors.Wrap(err, "compaction")
 	}
@@ -865,6 +938,56 @@ func (c *MultitenantCompactor) discoverUsers(ctx context.Context) ([]string, err
 	return mimir_tsdb.ListUsers(ctx, c.bucketClient)
 }
 
+// checkTenantsPools warns about the tenants assigned to a compactors pool without any healthy compactor,
+// because the compactors of the other pools skip them, so they would never be compacted nor cleaned up.
+func (c *MultitenantCompactor) checkTenantsPools(ctx context.Context, users []string) {
+	tenantsByPool := map[string]int{}
+	for _, userID := range users {
+		// This compactor is a healthy compactor of its own pool.
+		if pool := c.cfgProvider.CompactorTenantPool(userID); pool != c.compactorCfg.ShardingRing.Pool {
+			tenantsByPool[pool]++
+		}
+	}
+
+	tenantsWithoutCompactor := 0
+	for pool, tenants := range tenantsByPool {
+		ok, err := c.poolHasHealthyCompactors(ctx, pool)
+		if err != nil {
+			level.Warn(c.logger).Log("msg", "unable to check the compactors of the pool", "pool", pool, "err", err)
+			continue
+		}
+		if !ok {
+			level.Warn(c.logger).Log("msg", "tenants are assigned to a compactors pool without any healthy compactor, and are not compacted", "pool", pool, "tenants", tenants)
+			tenantsWithoutCompactor += tenants
+		}
+	}
+	c.tenantsInPoolsWithoutCompactor.Set(float64(tenantsWithoutCompactor))
+}
+
+// poolHasHealthyCompactors returns whether the ring of the compactors pool has at least one healthy compactor.
+func (c *MultitenantCompactor) poolHasHealthyCompactors(ctx context.Context, pool string) (bool, error) {
+	cfg := c.compactorCfg.ShardingRing
+	cfg.Pool = pool
+
+	value, err := c.ringKV.Get(ctx, cfg.ringKey())
+	if err != nil {
+		return false, err
+	}
+
+	desc, ok := value.(*ring.Desc)
+	if !ok || desc == nil {
+		return false, nil
+	}
+
+	now := time.Now()
+	for _, instance := range desc.GetIngesters() {
+		if instance.IsHealthy(RingOp, cfg.Common.HeartbeatTimeout, now) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
 // shardingStrategy describes whether compactor "owns" given user or job.
 type shardingStrategy interface {
 	compactorOwnsUser(userID string) (bool, error)
@@ -881,23 +1004,31 @@ type shardingStrategy interface {
 // Only one of compactors from user's shard will do cleanup.
 type splitAndMergeShardingStrategy struct {
 	allowedTenants *util.AllowedTenants
+	pool           string
 	ring           *ring.Ring
 	ringLifecycler *ring.BasicLifecycler
 	configProvider ConfigProvider
 }
 
-func newSplitAndMergeShardingStrategy(allowedTenants *util.AllowedTenants, ring *ring.Ring, ringLifecycler *ring.BasicLifecycler, configProvider ConfigProvider) *splitAndMergeShardingStrategy {
+func newSplitAndMergeShardingStrategy(allowedTenants *util.AllowedTenants, pool string, ring *ring.Ring, ringLifecycler *ring.BasicLifecycler, configProvider ConfigProvider) *splitAndMergeShardingStrategy {
 	return &splitAndMergeShardingStrategy{
 		allowedTenants: allowedTenants,
+		pool:           pool,
 		ring:           ring,
 		ringLifecycler: ringLifecycler,
 		configProvider: configProvider,
 	}
 }
 
+// isAllowed returns whether the user is allowed to be compacted by this compactor, based on the enabled and disabled
+// tenants and on the compactors pool the user is assigned to. The ring only contains the compactors of this pool.
+func (s *splitAndMergeShardingStrategy) isAllowed(userID string) bool {
+	return s.allowedTenants.IsAllowed(userID) && s.configProvider.CompactorTenantPool(userID) == s.pool
+}
+
 // Only a single instance in the subring can run the blocks cleaner for the given user. blocksCleanerOwnsUser is concurrency-safe.
 func (s *splitAndMergeShardingStrategy) blocksCleanerOwnsUser(userID string) (bool, error) {
-	if !s.allowedTenants.IsAllowed(userID) {
+	if !s.isAllowed(userID) {
 		return false, nil
 	}
 
@@ -908,7 +1039,7 @@ func (s *splitAndMergeShardingStrategy) blocksCleanerOwnsUser(userID string) (bo
 
 // ALL compactors should plan jobs for all users.
 func (s *splitAndMergeShardingStrategy) compactorOwnsUser(userID string) (bool, error) {
-	if !s.allowedTenants.IsAllowed(userID) {
+	if !s.isAllowed(userID) {
 		return false, nil
 	}
 
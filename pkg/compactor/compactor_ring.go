@@ -37,6 +37,9 @@ type RingConfig struct {
 
 	WaitActiveInstanceTimeout time.Duration `yaml:"wait_active_instance_timeout" category:"advanced"`
 
+	// Pool is the name of the compactors pool this compactor belongs to.
+	Pool string `yaml:"pool" category:"experimental"`
+
 	ObservePeriod time.Duration `yaml:"-"`
 }
 
@@ -53,6 +56,18 @@ func (cfg *RingConfig) RegisterFlags(f *flag.FlagSet, logger log.Logger) {
 
 	// Timeout durations
 	f.DurationVar(&cfg.WaitActiveInstanceTimeout, flagNamePrefix+"wait-active-instance-timeout", 10*time.Minute, "Timeout for waiting on compactor to become ACTIVE in the ring.")
+
+	f.StringVar(&cfg.Pool, flagNamePrefix+"pool", "", "Name of the compactors pool this compactor belongs to. Compactors of a pool join a ring dedicated to the pool, and only compact the tenants assigned to the pool with -compactor.tenant-pool. An empty value means the default pool, compacting all the tenants that are not assigned to a pool.")
+}
+
+// ringKey returns the key under which the ring of the compactors pool is stored in the KV store.
+// Each pool has its own ring, because the ring instances have no metadata to label them with the pool
+// other than the zone, which is already used by the zone-awareness.
+func (cfg *RingConfig) ringKey() string {
+	if cfg.Pool == "" {
+		return ringKey
+	}
+	return ringKey + "-" + cfg.Pool
 }
 
 func (cfg *RingConfig) ToBasicLifecyclerConfig(logger log.Logger) (ring.BasicLifecyclerConfig, error) {
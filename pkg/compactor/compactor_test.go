@@ -128,6 +128,20 @@ func TestConfig_Validate(t *testing.T) {
 			setup:    func(cfg *Config) { cfg.SymbolsFlushersConcurrency = 0 },
 			expected: errInvalidSymbolFlushersConcurrency.Error(),
 		},
+		"should pass on valid consolidated chunk segments options": {
+			setup: func(cfg *Config) {
+				cfg.ConsolidatedChunkSegmentsMinLevel = 3
+				cfg.ConsolidatedChunkSegmentSize = maxConsolidatedChunkSegmentSize
+			},
+			expected: "",
+		},
+		"should fail on invalid value of consolidated-chunk-segment-size": {
+			setup: func(cfg *Config) {
+				cfg.ConsolidatedChunkSegmentsMinLevel = 3
+				cfg.ConsolidatedChunkSegmentSize = maxConsolidatedChunkSegmentSize + 1
+			},
+			expected: errInvalidConsolidatedChunkSegmentSize.Error(),
+		},
 	}
 
 	for testName, testData := range tests {
@@ -765,6 +779,8 @@ func TestMultitenantCompactor_ShouldNotCompactBlocksMarkedForDeletion(t *testing
 		"user-1/markers/01DTW0ZCPDDNV4BV83Q2SV4QAZ-deletion-mark.json",
 	}, nil)
 
+	bucketClient.MockDelete("user-1/parquet-export/01DTW0ZCPDDNV4BV83Q2SV4QAZ.parquet", nil)
+	bucketClient.MockDelete("user-1/parquet-export/01DTW0ZCPDDNV4BV83Q2SV4QAZ.pending", nil)
 	bucketClient.MockDelete("user-1/01DTW0ZCPDDNV4BV83Q2SV4QAZ/meta.json", nil)
 	bucketClient.MockDelete("user-1/01DTW0ZCPDDNV4BV83Q2SV4QAZ/deletion-mark.json", nil)
 	bucketClient.MockDelete("user-1/markers/01DTW0ZCPDDNV4BV83Q2SV4QAZ-deletion-mark.json", nil)
@@ -927,6 +943,8 @@ func TestMultitenantCompactor_ShouldNotCompactBlocksForUsersMarkedForDeletion(t
 	bucketClient.MockDelete("user-1/01DTVP434PA9VFXSW2JKB3392D/meta.json", nil)
 	bucketClient.MockDelete("user-1/01DTVP434PA9VFXSW2JKB3392D/index", nil)
 	bucketClient.MockDelete("user-1/bucket-index.json.gz", nil)
+	bucketClient.MockIter("user-1/parquet-export", nil, nil)
+	bucketClient.MockIter("user-1/wal-shipping", nil, nil)
 
 	c, _, tsdbPlanner, logs, registry := prepare(t, cfg, bucketClient)
 
@@ -2051,6 +2069,8 @@ func TestOwnUser(t *testing.T) {
 		enabledUsers    []string
 		disabledUsers   []string
 		compactorShards map[string]int
+		compactorPools  map[int]string    // Compactor index to pool. Compactors not in the map are in the default pool.
+		tenantPools     map[string]string // Tenant to pool. Tenants not in the map are in the default pool.
 
 		check func(t *testing.T, comps []*MultitenantCompactor)
 	}
@@ -2098,6 +2118,39 @@ func TestOwnUser(t *testing.T) {
 				require.Len(t, owningCompactors(t, comps, user2, ownUserReasonBlocksCleaner), 1)
 			},
 		},
+
+		"5 compactors, 2 of them in a dedicated pool": {
+			compactors:     5,
+			compactorPools: map[int]string{3: "isolated", 4: "isolated"},
+			tenantPools:    map[string]string{user2: "isolated"},
+
+			check: func(t *testing.T, comps []*MultitenantCompactor) {
+				require.Equal(t, []string{"compactor-0", "compactor-1", "compactor-2"}, owningCompactors(t, comps, user1, ownUserReasonCompactor))
+				require.Len(t, owningCompactors(t, comps, user1, ownUserReasonBlocksCleaner), 1)
+				require.Subset(t, owningCompactors(t, comps, user1, ownUserReasonCompactor), owningCompactors(t, comps, user1, ownUserReasonBlocksCleaner))
+
+				require.Equal(t, []string{"compactor-3", "compactor-4"}, owningCompactors(t, comps, user2, ownUserReasonCompactor))
+				require.Len(t, owningCompactors(t, comps, user2, ownUserReasonBlocksCleaner), 1)
+				require.Subset(t, owningCompactors(t, comps, user2, ownUserReasonCompactor), owningCompactors(t, comps, user2, ownUserReasonBlocksCleaner))
+
+				// Both pools have compactors.
+				require.Equal(t, []float64{0, 0, 0, 0, 0}, tenantsInPoolsWithoutCompactor(comps, user1, user2))
+			},
+		},
+
+		"5 compactors, tenant assigned to a pool with no compactors": {
+			compactors:  5,
+			tenantPools: map[string]string{user2: "isolated"},
+
+			check: func(t *testing.T, comps []*MultitenantCompactor) {
+				require.Len(t, owningCompactors(t, comps, user1, ownUserReasonCompactor), 5)
+				require.Empty(t, owningCompactors(t, comps, user2, ownUserReasonCompactor))
+				require.Empty(t, owningCompactors(t, comps, user2, ownUserReasonBlocksCleaner))
+
+				// Every compactor warns about the tenant which is never compacted.
+				require.Equal(t, []float64{1, 1, 1, 1, 1}, tenantsInPoolsWithoutCompactor(comps, user1, user2))
+			},
+		},
 	}
 
 	for name, tc := range testCases {
@@ -2125,9 +2178,13 @@ func TestOwnUser(t *testing.T) {
 				cfg.ShardingRing.WaitStabilityMinDuration = 0
 				cfg.ShardingRing.WaitStabilityMaxDuration = 0
 				cfg.ShardingRing.Common.KVStore.Mock = kvStore
+				cfg.ShardingRing.Pool = tc.compactorPools[i]
 
 				limits := newMockConfigProvider()
 				limits.instancesShardSize = tc.compactorShards
+				if tc.tenantPools != nil {
+					limits.tenantPool = tc.tenantPools
+				}
 
 				c, _, _, _, _ := prepareWithConfigProvider(t, cfg, inmem, limits)
 				require.NoError(t, services.StartAndAwaitRunning(context.Background(), c))
@@ -2136,14 +2193,18 @@ func TestOwnUser(t *testing.T) {
 				compactors = append(compactors, c)
 			}
 
-			// Make sure all compactors see all other compactors in the ring before running tests.
+			// Make sure all compactors see all other compactors of their pool in the ring before running tests.
+			poolSizes := map[string]int{}
+			for i := range compactors {
+				poolSizes[tc.compactorPools[i]]++
+			}
 			test.Poll(t, 2*time.Second, true, func() interface{} {
 				for _, c := range compactors {
 					rs, err := c.ring.GetAllHealthy(RingOp)
 					if err != nil {
 						return false
 					}
-					if len(rs.Instances) != len(compactors) {
+					if len(rs.Instances) != poolSizes[c.compactorCfg.ShardingRing.Pool] {
 						return false
 					}
 				}
@@ -2175,6 +2236,15 @@ func owningCompactors(t *testing.T, comps []*MultitenantCompactor, user string,
 	return result
 }
 
+func tenantsInPoolsWithoutCompactor(comps []*MultitenantCompactor, users ...string) []float64 {
+	result := []float64(nil)
+	for _, c := range comps {
+		c.checkTenantsPools(context.Background(), users)
+		result = append(result, prom_testutil.ToFloat64(c.tenantsInPoolsWithoutCompactor))
+	}
+	return result
+}
+
 func stopServiceFn(t *testing.T, serv services.Service) func() {
 	return func() {
 		require.NoError(t, services.StopAndAwaitTerminated(context.Background(), serv))
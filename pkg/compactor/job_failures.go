@@ -0,0 +1,298 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package compactor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/gorilla/mux"
+	"github.com/oklog/ulid"
+	"github.com/pkg/errors"
+	"github.com/thanos-io/objstore"
+
+	"github.com/grafana/mimir/pkg/storage/bucket"
+	"github.com/grafana/mimir/pkg/storage/tsdb/block"
+	"github.com/grafana/mimir/pkg/util"
+)
+
+const (
+	// JobFailuresPrefix is the prefix of the objects tracking the failures of the compaction jobs, in the tenant's bucket.
+	JobFailuresPrefix = "compaction-job-failures"
+
+	jobFailuresExtension = ".json"
+
+	// maxJobFailureErrorLength is the max length of the error stored in the job failure.
+	maxJobFailureErrorLength = 1024
+)
+
+// JobFailure tracks the consecutive failures of a compaction job. It's stored in
+// the tenant's bucket, so that its failures are tracked across compactor restarts and
+// across the compactors owning the job over time.
+type JobFailure struct {
+	// Key is the key of the compaction job.
+	Key string `json:"key"`
+
+	// Blocks are the IDs of the blocks compacted by the job. The failures are reset when the blocks of the job change.
+	Blocks []ulid.ULID `json:"blocks"`
+
+	// Failures is the number of consecutive failures of the job.
+	Failures int `json:"failures"`
+
+	// LastError is the error of the last failure, truncated.
+	LastError string `json:"last_error"`
+
+	// LastFailureTime is the time of the last failure, in Unix seconds.
+	LastFailureTime int64 `json:"last_failure_time"`
+
+	// Quarantined is true when the job has failed too many times, and it's skipped by the compactors.
+	Quarantined bool `json:"quarantined"`
+}
+
+// sameBlocks returns whether the job failure was tracked for the same blocks of the job.
+func (f *JobFailure) sameBlocks(job *Job) bool {
+	return slices.Equal(sortedULIDs(job.IDs()), sortedULIDs(f.Blocks))
+}
+
+func sortedULIDs(ids []ulid.ULID) []ulid.ULID {
+	sorted := slices.Clone(ids)
+	slices.SortFunc(sorted, func(a, b ulid.ULID) int { return a.Compare(b) })
+	return sorted
+}
+
+func jobFailurePath(key string) string {
+	return path.Join(JobFailuresPrefix, key+jobFailuresExtension)
+}
+
+// ReadJobFailures returns the failures of the compaction jobs tracked in the tenant's bucket.
+func ReadJobFailures(ctx context.Context, userBkt objstore.InstrumentedBucketReader) ([]*JobFailure, error) {
+	var failures []*JobFailure
+	err := userBkt.Iter(ctx, JobFailuresPrefix+objstore.DirDelim, func(name string) error {
+		if !strings.HasSuffix(name, jobFailuresExtension) {
+			return nil
+		}
+
+		f, err := readJobFailure(ctx, userBkt, name)
+		if err != nil {
+			return err
+		}
+		if f != nil {
+			failures = append(failures, f)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "list compaction job failures")
+	}
+	return failures, nil
+}
+
+// readJobFailure reads the job failure stored in the given object. Returns nil if the object doesn't exist.
+func readJobFailure(ctx context.Context, userBkt objstore.InstrumentedBucketReader, name string) (*JobFailure, error) {
+	r, err := userBkt.ReaderWithExpectedErrs(userBkt.IsObjNotFoundErr).Get(ctx, name)
+	if userBkt.IsObjNotFoundErr(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "read compaction job failure %s", name)
+	}
+	defer func() { _ = r.Close() }()
+
+	f := &JobFailure{}
+	if err := json.NewDecoder(r).Decode(f); err != nil {
+		return nil, errors.Wrapf(err, "decode compaction job failure %s", name)
+	}
+	return f, nil
+}
+
+type quarantinedJobsResponse struct {
+	Tenant string        `json:"tenant"`
+	Jobs   []*JobFailure `json:"jobs"`
+}
+
+// QuarantinedJobsHandler lists the compaction jobs of the tenant which have been quarantined because they failed too many times.
+func (c *MultitenantCompactor) QuarantinedJobsHandler(w http.ResponseWriter, req *http.Request) {
+	tenantID := mux.Vars(req)["tenant"]
+	if tenantID == "" {
+		http.Error(w, "Tenant ID can't be empty", http.StatusBadRequest)
+		return
+	}
+
+	userBkt := bucket.NewUserBucketClient(tenantID, c.bucketClient, c.cfgProvider)
+	failures, err := ReadJobFailures(req.Context(), objstore.WithNoopInstr(userBkt))
+	if err != nil {
+		level.Error(c.logger).Log("msg", "failed to read compaction job failures", "user", tenantID, "err", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	res := quarantinedJobsResponse{Tenant: tenantID, Jobs: []*JobFailure{}}
+	for _, f := range failures {
+		if f.Quarantined {
+			res.Jobs = append(res.Jobs, f)
+		}
+	}
+	util.WriteJSONResponse(w, res)
+}
+
+// jobFailuresTracker tracks the failures of the compaction jobs of a tenant, and quarantines the jobs
+// failing at least maxFailures consecutive times. The failures of each job are stored in a dedicated
+// object, so that compactors running different jobs of the same tenant don't conflict.
+type jobFailuresTracker struct {
+	bkt         objstore.InstrumentedBucket
+	logger      log.Logger
+	maxFailures int
+	metrics     *BucketCompactorMetrics
+
+	// tracked are the keys of the jobs with failures stored in the bucket.
+	trackedMx sync.Mutex
+	tracked   map[string]struct{}
+}
+
+func newJobFailuresTracker(bkt objstore.Bucket, logger log.Logger, maxFailures int, metrics *BucketCompactorMetrics) *jobFailuresTracker {
+	return &jobFailuresTracker{
+		bkt:         objstore.WithNoopInstr(bkt),
+		logger:      logger,
+		maxFailures: maxFailures,
+		metrics:     metrics,
+		tracked:     map[string]struct{}{},
+	}
+}
+
+// filterQuarantinedJobs removes the quarantined jobs from the input jobs, and removes the failures
+// tracked for jobs whose blocks have changed or whose blocks no longer exist in the synced metas.
+// The input jobs must be all the jobs of the tenant, not only the jobs owned by this compactor, because
+// the failures of the jobs owned by other compactors are stored in the same bucket.
+func (t *jobFailuresTracker) filterQuarantinedJobs(ctx context.Context, jobs []*Job, metas map[ulid.ULID]*block.Meta) ([]*Job, error) {
+	failures, err := ReadJobFailures(ctx, t.bkt)
+	if err != nil {
+		return nil, err
+	}
+
+	byKey := make(map[string]*JobFailure, len(failures))
+	for _, f := range failures {
+		byKey[f.Key] = f
+	}
+
+	filtered := jobs[:0]
+	for _, job := range jobs {
+		f, ok := byKey[job.Key()]
+		if !ok {
+			filtered = append(filtered, job)
+			continue
+		}
+		delete(byKey, job.Key())
+
+		if !f.sameBlocks(job) {
+			// The blocks of the job have changed, so the job gets a new chance.
+			t.deleteFailure(ctx, job.Key())
+			filtered = append(filtered, job)
+			continue
+		}
+
+		t.setTracked(job.Key(), true)
+		if !f.Quarantined {
+			filtered = append(filtered, job)
+			continue
+		}
+
+		level.Warn(t.logger).Log("msg", "skipping quarantined compaction job", "groupKey", job.Key(), "failures", f.Failures, "last_error", f.LastError)
+		t.metrics.quarantinedJobsSkipped.Inc()
+	}
+
+	// The remaining failures are tracked for jobs which weren't planned from the synced metas. A compactor
+	// may have a stale view of the bucket, so the failures are only removed once none of their blocks exist.
+	for key, f := range byKey {
+		if !anyBlockExists(f.Blocks, metas) {
+			t.deleteFailure(ctx, key)
+		}
+	}
+
+	return filtered, nil
+}
+
+func anyBlockExists(ids []ulid.ULID, metas map[ulid.ULID]*block.Meta) bool {
+	for _, id := range ids {
+		if _, ok := metas[id]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// recordFailure tracks a failure of the job, and returns whether the job has been quarantined.
+func (t *jobFailuresTracker) recordFailure(ctx context.Context, job *Job, jobErr error) bool {
+	f, err := readJobFailure(ctx, t.bkt, jobFailurePath(job.Key()))
+	if err != nil {
+		level.Warn(t.logger).Log("msg", "failed to read compaction job failures", "groupKey", job.Key(), "err", err)
+		return false
+	}
+	if f == nil || !f.sameBlocks(job) {
+		f = &JobFailure{Key: job.Key(), Blocks: sortedULIDs(job.IDs())}
+	}
+
+	f.Failures++
+	f.LastError = jobErr.Error()
+	if len(f.LastError) > maxJobFailureErrorLength {
+		f.LastError = f.LastError[:maxJobFailureErrorLength]
+	}
+	f.LastFailureTime = time.Now().Unix()
+	f.Quarantined = f.Failures >= t.maxFailures
+
+	data, err := json.Marshal(f)
+	if err != nil {
+		level.Warn(t.logger).Log("msg", "failed to encode compaction job failures", "groupKey", job.Key(), "err", err)
+		return false
+	}
+	if err := t.bkt.Upload(ctx, jobFailurePath(job.Key()), bytes.NewReader(data)); err != nil {
+		level.Warn(t.logger).Log("msg", "failed to upload compaction job failures", "groupKey", job.Key(), "err", err)
+		return false
+	}
+	t.setTracked(job.Key(), true)
+
+	if f.Quarantined {
+		level.Error(t.logger).Log("msg", "quarantined compaction job because it failed too many times", "groupKey", job.Key(), "failures", f.Failures, "err", jobErr)
+		t.metrics.jobsQuarantined.Inc()
+	}
+	return f.Quarantined
+}
+
+// recordSuccess resets the failures of the job, if any.
+func (t *jobFailuresTracker) recordSuccess(ctx context.Context, job *Job) {
+	t.trackedMx.Lock()
+	_, ok := t.tracked[job.Key()]
+	t.trackedMx.Unlock()
+
+	if ok {
+		t.deleteFailure(ctx, job.Key())
+	}
+}
+
+func (t *jobFailuresTracker) deleteFailure(ctx context.Context, key string) {
+	err := t.bkt.Delete(ctx, jobFailurePath(key))
+	if err != nil && !t.bkt.IsObjNotFoundErr(err) {
+		level.Warn(t.logger).Log("msg", "failed to delete compaction job failures", "groupKey", key, "err", err)
+		return
+	}
+	t.setTracked(key, false)
+}
+
+func (t *jobFailuresTracker) setTracked(key string, tracked bool) {
+	t.trackedMx.Lock()
+	defer t.trackedMx.Unlock()
+
+	if tracked {
+		t.tracked[key] = struct{}{}
+	} else {
+		delete(t.tracked, key)
+	}
+}
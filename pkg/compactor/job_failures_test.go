@@ -0,0 +1,110 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package compactor
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/oklog/ulid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/objstore"
+
+	"github.com/grafana/mimir/pkg/storage/tsdb/block"
+)
+
+func TestJobFailuresTracker(t *testing.T) {
+	ctx := context.Background()
+	bkt := objstore.NewInMemBucket()
+	metrics := NewBucketCompactorMetrics(promauto.With(nil).NewCounter(prometheus.CounterOpts{}), nil)
+	tracker := newJobFailuresTracker(bkt, log.NewNopLogger(), 2, metrics)
+
+	block1 := ulid.MustParse("01DTVP434PA9VFXSW2JK000001")
+	block2 := ulid.MustParse("01DTVP434PA9VFXSW2JK000002")
+	block3 := ulid.MustParse("01DTVP434PA9VFXSW2JK000003")
+
+	newTestJob := func(key string, ids ...ulid.ULID) *Job {
+		job := newJob("user", key, labels.EmptyLabels(), 0, false, 0, "")
+		for _, id := range ids {
+			require.NoError(t, job.AppendMeta(blockMeta(id.String(), 100, 200, nil)))
+		}
+		return job
+	}
+
+	metas := map[ulid.ULID]*block.Meta{}
+	for _, id := range []ulid.ULID{block1, block2, block3} {
+		metas[id] = blockMeta(id.String(), 100, 200, nil)
+	}
+
+	jobErr := errors.New("compaction failed")
+	failing := newTestJob("failing", block1, block2)
+	healthy := newTestJob("healthy", block3)
+
+	// The first failure doesn't quarantine the job.
+	assert.False(t, tracker.recordFailure(ctx, failing, jobErr))
+	jobs, err := tracker.filterQuarantinedJobs(ctx, []*Job{failing, healthy}, metas)
+	require.NoError(t, err)
+	assert.Equal(t, []*Job{failing, healthy}, jobs)
+
+	// The second consecutive failure quarantines the job.
+	assert.True(t, tracker.recordFailure(ctx, failing, jobErr))
+	jobs, err = tracker.filterQuarantinedJobs(ctx, []*Job{failing, healthy}, metas)
+	require.NoError(t, err)
+	assert.Equal(t, []*Job{healthy}, jobs)
+	assert.Equal(t, float64(1), testutil.ToFloat64(metrics.jobsQuarantined))
+	assert.Equal(t, float64(1), testutil.ToFloat64(metrics.quarantinedJobsSkipped))
+
+	failures, err := ReadJobFailures(ctx, objstore.WithNoopInstr(bkt))
+	require.NoError(t, err)
+	require.Len(t, failures, 1)
+	assert.Equal(t, "failing", failures[0].Key)
+	assert.Equal(t, []ulid.ULID{block1, block2}, failures[0].Blocks)
+	assert.Equal(t, 2, failures[0].Failures)
+	assert.Equal(t, jobErr.Error(), failures[0].LastError)
+	assert.True(t, failures[0].Quarantined)
+
+	// Once the blocks of the job change, the job gets a new chance.
+	changed := newTestJob("failing", block1)
+	jobs, err = tracker.filterQuarantinedJobs(ctx, []*Job{changed, healthy}, metas)
+	require.NoError(t, err)
+	assert.Equal(t, []*Job{changed, healthy}, jobs)
+
+	failures, err = ReadJobFailures(ctx, objstore.WithNoopInstr(bkt))
+	require.NoError(t, err)
+	assert.Empty(t, failures)
+
+	// A successful run resets the failures of the job.
+	assert.False(t, tracker.recordFailure(ctx, changed, jobErr))
+	tracker.recordSuccess(ctx, changed)
+
+	failures, err = ReadJobFailures(ctx, objstore.WithNoopInstr(bkt))
+	require.NoError(t, err)
+	assert.Empty(t, failures)
+
+	// The failures of jobs which aren't planned, but whose blocks still exist, are kept: the job may be
+	// planned by another compactor with a more recent view of the bucket.
+	assert.False(t, tracker.recordFailure(ctx, healthy, jobErr))
+	_, err = tracker.filterQuarantinedJobs(ctx, []*Job{changed}, metas)
+	require.NoError(t, err)
+
+	failures, err = ReadJobFailures(ctx, objstore.WithNoopInstr(bkt))
+	require.NoError(t, err)
+	require.Len(t, failures, 1)
+	assert.Equal(t, "healthy", failures[0].Key)
+
+	// The failures of jobs whose blocks no longer exist are removed.
+	delete(metas, block3)
+	_, err = tracker.filterQuarantinedJobs(ctx, []*Job{changed}, metas)
+	require.NoError(t, err)
+
+	failures, err = ReadJobFailures(ctx, objstore.WithNoopInstr(bkt))
+	require.NoError(t, err)
+	assert.Empty(t, failures)
+}
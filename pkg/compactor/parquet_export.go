@@ -0,0 +1,215 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package compactor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/grafana/dskit/runutil"
+	"github.com/oklog/ulid"
+	"github.com/pkg/errors"
+	"github.com/thanos-io/objstore"
+
+	"github.com/grafana/mimir/pkg/storage/tsdb/block"
+)
+
+const (
+	// ParquetExportPrefix is the prefix, in the tenant's bucket, of the Parquet exports of the compacted blocks.
+	ParquetExportPrefix = "parquet-export"
+
+	parquetExportPendingMarkerExtension = ".pending"
+)
+
+// ParquetExportPath returns the path, in the tenant's bucket, of the Parquet export of the block.
+func ParquetExportPath(id ulid.ULID) string {
+	return path.Join(ParquetExportPrefix, id.String()+block.ParquetFilenameExtension)
+}
+
+// parquetExportPendingMarkerPath returns the path, in the tenant's bucket, of the marker of a block whose
+// Parquet export has failed.
+func parquetExportPendingMarkerPath(id ulid.ULID) string {
+	return path.Join(ParquetExportPrefix, id.String()+parquetExportPendingMarkerExtension)
+}
+
+// parquetExportPendingMarker is uploaded when the Parquet export of a compacted block fails, so that the export
+// is retried on the next compaction cycle.
+type parquetExportPendingMarker struct {
+	// Sources are the blocks compacted into the block. Their Parquet exports are deleted once the block has been exported.
+	Sources []ulid.ULID `json:"sources"`
+}
+
+// exportParquet writes the Parquet export of the block in blockDir and uploads it to the bucket.
+func exportParquet(ctx context.Context, logger log.Logger, bkt objstore.Bucket, blockDir string, id ulid.ULID) (err error) {
+	// The export is written next to the block, so that it's cleaned up along with the compaction directory.
+	localPath := filepath.Join(filepath.Dir(blockDir), id.String()+block.ParquetFilenameExtension)
+	defer func() {
+		if removeErr := os.Remove(localPath); removeErr != nil && !os.IsNotExist(removeErr) {
+			level.Warn(logger).Log("msg", "failed to remove local Parquet export", "path", localPath, "err", removeErr)
+		}
+	}()
+
+	f, err := os.Create(localPath)
+	if err != nil {
+		return errors.Wrap(err, "create Parquet export file")
+	}
+	numSamples, err := block.WriteParquet(ctx, blockDir, f)
+	if err != nil {
+		runutil.CloseWithLogOnErr(logger, f, "close Parquet export file")
+		return errors.Wrap(err, "write Parquet export")
+	}
+	if err := f.Close(); err != nil {
+		return errors.Wrap(err, "close Parquet export file")
+	}
+
+	if err := objstore.UploadFile(ctx, logger, bkt, localPath, ParquetExportPath(id)); err != nil {
+		return errors.Wrap(err, "upload Parquet export")
+	}
+
+	level.Info(logger).Log("msg", "exported block to Parquet", "block", id, "samples", numSamples, "path", ParquetExportPath(id))
+	return nil
+}
+
+// deleteParquetExport deletes the Parquet export of the block and its pending marker, if any.
+func deleteParquetExport(ctx context.Context, bkt objstore.Bucket, id ulid.ULID) error {
+	for _, p := range []string{ParquetExportPath(id), parquetExportPendingMarkerPath(id)} {
+		if err := bkt.Delete(ctx, p); err != nil && !bkt.IsObjNotFoundErr(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeParquetExportPendingMarker uploads the marker of a block whose Parquet export has failed.
+func writeParquetExportPendingMarker(ctx context.Context, bkt objstore.Bucket, id ulid.ULID, sources []ulid.ULID) error {
+	data, err := json.Marshal(parquetExportPendingMarker{Sources: sources})
+	if err != nil {
+		return errors.Wrap(err, "json encode Parquet export pending marker")
+	}
+	return errors.Wrap(bkt.Upload(ctx, parquetExportPendingMarkerPath(id), bytes.NewReader(data)), "upload Parquet export pending marker")
+}
+
+// readParquetExportPendingMarkers returns the pending markers in the tenant's bucket, by block ID.
+func readParquetExportPendingMarkers(ctx context.Context, logger log.Logger, bkt objstore.Bucket) (map[ulid.ULID]parquetExportPendingMarker, error) {
+	markers := map[ulid.ULID]parquetExportPendingMarker{}
+	err := bkt.Iter(ctx, ParquetExportPrefix, func(name string) error {
+		base := path.Base(name)
+		if !strings.HasSuffix(base, parquetExportPendingMarkerExtension) {
+			return nil
+		}
+		id, err := ulid.Parse(strings.TrimSuffix(base, parquetExportPendingMarkerExtension))
+		if err != nil {
+			return nil
+		}
+
+		r, err := bkt.Get(ctx, name)
+		if bkt.IsObjNotFoundErr(err) {
+			return nil
+		}
+		if err != nil {
+			return errors.Wrapf(err, "get %s", name)
+		}
+		defer runutil.CloseWithLogOnErr(logger, r, "close Parquet export pending marker")
+
+		var marker parquetExportPendingMarker
+		if err := json.NewDecoder(r).Decode(&marker); err != nil {
+			level.Warn(logger).Log("msg", "failed to decode Parquet export pending marker", "block", id, "err", err)
+			return nil
+		}
+		markers[id] = marker
+		return nil
+	})
+	return markers, err
+}
+
+// retryParquetExports retries the Parquet exports which failed after the upload of their compacted blocks, downloading
+// the blocks to dir. The marker of a block which has been deleted or marked for deletion is dropped without exporting
+// the block, because its data is exported along with the block it has been compacted into.
+func retryParquetExports(ctx context.Context, logger log.Logger, bkt objstore.Bucket, dir string, metrics *BucketCompactorMetrics) error {
+	pending, err := readParquetExportPendingMarkers(ctx, logger, bkt)
+	if err != nil {
+		return errors.Wrap(err, "read Parquet export pending markers")
+	}
+
+	for id, marker := range pending {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		exported, err := retryParquetExport(ctx, logger, bkt, dir, id)
+		if err != nil {
+			metrics.parquetExportFailures.Inc()
+			level.Warn(logger).Log("msg", "failed to retry Parquet export of compacted block", "block", id, "err", err)
+			continue
+		}
+		delete(pending, id)
+
+		if exported {
+			metrics.parquetExportedBlocks.Inc()
+
+			// The exports of the source blocks are kept while another block compacted from them hasn't been exported yet.
+			for _, source := range marker.Sources {
+				if isParquetExportPendingSource(pending, source) {
+					continue
+				}
+				delete(pending, source)
+				if err := deleteParquetExport(ctx, bkt, source); err != nil {
+					level.Warn(logger).Log("msg", "failed to delete Parquet export of compacted block", "block", source, "err", err)
+				}
+			}
+		}
+
+		if err := bkt.Delete(ctx, parquetExportPendingMarkerPath(id)); err != nil && !bkt.IsObjNotFoundErr(err) {
+			level.Warn(logger).Log("msg", "failed to delete Parquet export pending marker", "block", id, "err", err)
+		}
+	}
+	return nil
+}
+
+// retryParquetExport exports the block to Parquet, unless it has been deleted or marked for deletion.
+func retryParquetExport(ctx context.Context, logger log.Logger, bkt objstore.Bucket, dir string, id ulid.ULID) (exported bool, err error) {
+	metaExists, err := bkt.Exists(ctx, path.Join(id.String(), block.MetaFilename))
+	if err != nil {
+		return false, errors.Wrap(err, "check block meta.json")
+	}
+	deletionMarkExists, err := bkt.Exists(ctx, path.Join(id.String(), block.DeletionMarkFilename))
+	if err != nil {
+		return false, errors.Wrap(err, "check block deletion mark")
+	}
+	if !metaExists || deletionMarkExists {
+		level.Info(logger).Log("msg", "dropping Parquet export pending marker of deleted block", "block", id)
+		return false, nil
+	}
+
+	blockDir := filepath.Join(dir, id.String())
+	defer func() {
+		if removeErr := os.RemoveAll(blockDir); removeErr != nil {
+			level.Warn(logger).Log("msg", "failed to remove downloaded block", "path", blockDir, "err", removeErr)
+		}
+	}()
+
+	if err := block.Download(ctx, logger, bkt, id, blockDir); err != nil {
+		return false, errors.Wrap(err, "download block")
+	}
+	if err := exportParquet(ctx, logger, bkt, blockDir, id); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func isParquetExportPendingSource(pending map[ulid.ULID]parquetExportPendingMarker, id ulid.ULID) bool {
+	for _, marker := range pending {
+		if slices.Contains(marker.Sources, id) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,267 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package compactor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/dskit/flagext"
+	"github.com/grafana/dskit/services"
+	"github.com/grafana/dskit/test"
+	"github.com/oklog/ulid"
+	"github.com/parquet-go/parquet-go"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/objstore"
+	"go.uber.org/atomic"
+
+	"github.com/grafana/mimir/pkg/storage/bucket"
+	mimir_tsdb "github.com/grafana/mimir/pkg/storage/tsdb"
+	"github.com/grafana/mimir/pkg/storage/tsdb/block"
+)
+
+func TestMultitenantCompactor_ShouldExportCompactedBlocksToParquet(t *testing.T) {
+	const (
+		userID    = "user-1"
+		numSeries = 10
+	)
+
+	storageDir := t.TempDir()
+
+	storageCfg := mimir_tsdb.BlocksStorageConfig{}
+	flagext.DefaultValues(&storageCfg)
+	storageCfg.Bucket.Backend = bucket.Filesystem
+	storageCfg.Bucket.Filesystem.Directory = storageDir
+
+	compactorCfg := prepareConfig(t)
+	compactorCfg.DataDir = t.TempDir()
+	compactorCfg.BlockRanges = mimir_tsdb.DurationList{2 * time.Hour, 4 * time.Hour}
+	compactorCfg.ParquetExportMinLevel = 2
+
+	logger := log.NewLogfmtLogger(os.Stdout)
+	reg := prometheus.NewPedanticRegistry()
+	ctx := context.Background()
+
+	bucketClient, err := bucket.NewClient(ctx, storageCfg.Bucket, "test", logger, nil)
+	require.NoError(t, err)
+	userBucket := bucket.NewUserBucketClient(userID, bucketClient, nil)
+
+	// Create two level 1 blocks which get compacted together. The first one has a stale Parquet export, which
+	// is expected to be deleted once its data is exported along with the compacted block.
+	hour := time.Hour.Milliseconds()
+	block1 := createTSDBBlock(t, bucketClient, userID, 0, hour, numSeries, nil)
+	createTSDBBlock(t, bucketClient, userID, hour, 2*hour, numSeries, nil)
+	require.NoError(t, userBucket.Upload(ctx, ParquetExportPath(block1), strings.NewReader("stale")))
+
+	c, err := NewMultitenantCompactor(compactorCfg, storageCfg, newMockConfigProvider(), logger, reg)
+	require.NoError(t, err)
+	require.NoError(t, services.StartAndAwaitRunning(ctx, c))
+	t.Cleanup(func() {
+		require.NoError(t, services.StopAndAwaitTerminated(ctx, c))
+	})
+
+	// Wait until the first compaction run completed.
+	test.Poll(t, 15*time.Second, nil, func() interface{} {
+		return testutil.GatherAndCompare(reg, strings.NewReader(`
+			# HELP cortex_compactor_runs_completed_total Total number of compaction runs successfully completed.
+			# TYPE cortex_compactor_runs_completed_total counter
+			cortex_compactor_runs_completed_total 1
+		`), "cortex_compactor_runs_completed_total")
+	})
+
+	assert.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(`
+		# HELP cortex_compactor_parquet_export_failures_total Total number of compacted blocks which failed to be exported to Parquet.
+		# TYPE cortex_compactor_parquet_export_failures_total counter
+		cortex_compactor_parquet_export_failures_total 0
+		# HELP cortex_compactor_parquet_exported_blocks_total Total number of compacted blocks exported to Parquet.
+		# TYPE cortex_compactor_parquet_exported_blocks_total counter
+		cortex_compactor_parquet_exported_blocks_total 1
+	`), "cortex_compactor_parquet_exported_blocks_total", "cortex_compactor_parquet_export_failures_total"))
+
+	fetcher, err := block.NewMetaFetcher(logger, 1, userBucket, t.TempDir(), reg, nil, nil)
+	require.NoError(t, err)
+	metas, _, err := fetcher.FetchWithoutMarkedForDeletion(ctx)
+	require.NoError(t, err)
+	require.Len(t, metas, 1)
+
+	var exported []string
+	require.NoError(t, userBucket.Iter(ctx, ParquetExportPrefix, func(name string) error {
+		exported = append(exported, name)
+		return nil
+	}))
+
+	for id, meta := range metas {
+		require.Equal(t, 2, meta.Compaction.Level)
+		require.Equal(t, []string{ParquetExportPath(id)}, exported)
+
+		reader, err := userBucket.Get(ctx, ParquetExportPath(id))
+		require.NoError(t, err)
+		data, err := io.ReadAll(reader)
+		require.NoError(t, err)
+		require.NoError(t, reader.Close())
+
+		file, err := parquet.OpenFile(bytes.NewReader(data), int64(len(data)))
+		require.NoError(t, err)
+		assert.Equal(t, int64(2*numSeries), file.NumRows())
+	}
+}
+
+func TestMultitenantCompactor_ShouldRetryTheParquetExportOnTheNextCompactionIfItFails(t *testing.T) {
+	const (
+		userID    = "user-1"
+		numSeries = 10
+	)
+
+	storageDir := t.TempDir()
+
+	storageCfg := mimir_tsdb.BlocksStorageConfig{}
+	flagext.DefaultValues(&storageCfg)
+	storageCfg.Bucket.Backend = bucket.Filesystem
+	storageCfg.Bucket.Filesystem.Directory = storageDir
+
+	compactorCfg := prepareConfig(t)
+	compactorCfg.DataDir = t.TempDir()
+	compactorCfg.BlockRanges = mimir_tsdb.DurationList{2 * time.Hour, 4 * time.Hour}
+	compactorCfg.ParquetExportMinLevel = 2
+	compactorCfg.CompactionInterval = 10 * time.Minute // The second compaction is run manually.
+	configureSplitAndMergeCompactor(&compactorCfg)
+
+	logger := log.NewLogfmtLogger(os.Stdout)
+	reg := prometheus.NewPedanticRegistry()
+	ctx := context.Background()
+
+	bucketClient, err := bucket.NewClient(ctx, storageCfg.Bucket, "test", logger, nil)
+	require.NoError(t, err)
+	userBucket := bucket.NewUserBucketClient(userID, bucketClient, nil)
+
+	// Create two level 1 blocks which get compacted together. The first one has a Parquet export, which is
+	// expected to be kept until the compacted block has been exported.
+	hour := time.Hour.Milliseconds()
+	block1 := createTSDBBlock(t, bucketClient, userID, 0, hour, numSeries, nil)
+	block2 := createTSDBBlock(t, bucketClient, userID, hour, 2*hour, numSeries, nil)
+	require.NoError(t, userBucket.Upload(ctx, ParquetExportPath(block1), strings.NewReader("exported")))
+
+	// The upload of the Parquet exports fails until the failure is cleared.
+	exportFails := atomic.NewBool(true)
+	exportFailingBucket := &bucket.ErrorInjectedBucketClient{
+		Bucket: bucketClient,
+		Injector: func(op bucket.Operation, name string) error {
+			if exportFails.Load() && op == bucket.OpUpload && strings.HasSuffix(name, block.ParquetFilenameExtension) {
+				return errors.New("injected upload failure")
+			}
+			return nil
+		},
+	}
+	bucketClientFactory := func(context.Context) (objstore.Bucket, error) {
+		return exportFailingBucket, nil
+	}
+
+	c, err := newMultitenantCompactor(compactorCfg, storageCfg, newMockConfigProvider(), logger, reg, bucketClientFactory, compactorCfg.BlocksGrouperFactory, compactorCfg.BlocksCompactorFactory)
+	require.NoError(t, err)
+	require.NoError(t, services.StartAndAwaitRunning(ctx, c))
+	t.Cleanup(func() {
+		require.NoError(t, services.StopAndAwaitTerminated(ctx, c))
+	})
+
+	// Wait until the first compaction run completed.
+	test.Poll(t, 15*time.Second, nil, func() interface{} {
+		return testutil.GatherAndCompare(reg, strings.NewReader(`
+			# HELP cortex_compactor_runs_completed_total Total number of compaction runs successfully completed.
+			# TYPE cortex_compactor_runs_completed_total counter
+			cortex_compactor_runs_completed_total 1
+		`), "cortex_compactor_runs_completed_total")
+	})
+
+	assert.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(`
+		# HELP cortex_compactor_parquet_export_failures_total Total number of compacted blocks which failed to be exported to Parquet.
+		# TYPE cortex_compactor_parquet_export_failures_total counter
+		cortex_compactor_parquet_export_failures_total 1
+		# HELP cortex_compactor_parquet_exported_blocks_total Total number of compacted blocks exported to Parquet.
+		# TYPE cortex_compactor_parquet_exported_blocks_total counter
+		cortex_compactor_parquet_exported_blocks_total 0
+	`), "cortex_compactor_parquet_exported_blocks_total", "cortex_compactor_parquet_export_failures_total"))
+
+	// The compacted block has been uploaded, and the compacted blocks have been marked for deletion.
+	fetcher, err := block.NewMetaFetcher(logger, 1, userBucket, t.TempDir(), reg, nil, nil)
+	require.NoError(t, err)
+	metas, _, err := fetcher.FetchWithoutMarkedForDeletion(ctx)
+	require.NoError(t, err)
+	require.Len(t, metas, 1)
+	require.NotContains(t, metas, block1)
+	require.NotContains(t, metas, block2)
+
+	var compactedBlock ulid.ULID
+	for id, meta := range metas {
+		require.Equal(t, 2, meta.Compaction.Level)
+		compactedBlock = id
+	}
+
+	// The Parquet export of the compacted block has been kept, and the failed export has been recorded.
+	listExports := func() []string {
+		var exported []string
+		require.NoError(t, userBucket.Iter(ctx, ParquetExportPrefix, func(name string) error {
+			exported = append(exported, name)
+			return nil
+		}))
+		return exported
+	}
+	assert.ElementsMatch(t, []string{ParquetExportPath(block1), parquetExportPendingMarkerPath(compactedBlock)}, listExports())
+
+	// The export is retried, and succeeds, on the next compaction.
+	exportFails.Store(false)
+	c.compactUsers(ctx)
+
+	assert.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(`
+		# HELP cortex_compactor_parquet_export_failures_total Total number of compacted blocks which failed to be exported to Parquet.
+		# TYPE cortex_compactor_parquet_export_failures_total counter
+		cortex_compactor_parquet_export_failures_total 1
+		# HELP cortex_compactor_parquet_exported_blocks_total Total number of compacted blocks exported to Parquet.
+		# TYPE cortex_compactor_parquet_exported_blocks_total counter
+		cortex_compactor_parquet_exported_blocks_total 1
+	`), "cortex_compactor_parquet_exported_blocks_total", "cortex_compactor_parquet_export_failures_total"))
+
+	// The export of the compacted block replaces the exports of its source blocks, and the marker has been deleted.
+	assert.Equal(t, []string{ParquetExportPath(compactedBlock)}, listExports())
+}
+
+func TestRetryParquetExports_ShouldDropTheMarkersOfDeletedBlocks(t *testing.T) {
+	ctx := context.Background()
+	bkt := objstore.NewInMemBucket()
+	reg := prometheus.NewPedanticRegistry()
+	metrics := NewBucketCompactorMetrics(promauto.With(reg).NewCounter(prometheus.CounterOpts{Name: "blocks_marked_for_deletion_total"}), reg)
+
+	// The first block has been deleted, while the second one has been marked for deletion.
+	deletedBlock := ulid.MustNew(1, nil)
+	markedBlock := ulid.MustNew(2, nil)
+	sourceBlock := ulid.MustNew(3, nil)
+	require.NoError(t, bkt.Upload(ctx, path.Join(markedBlock.String(), block.MetaFilename), strings.NewReader("{}")))
+	require.NoError(t, bkt.Upload(ctx, path.Join(markedBlock.String(), block.DeletionMarkFilename), strings.NewReader("{}")))
+	require.NoError(t, bkt.Upload(ctx, ParquetExportPath(sourceBlock), strings.NewReader("exported")))
+	for _, id := range []ulid.ULID{deletedBlock, markedBlock} {
+		require.NoError(t, writeParquetExportPendingMarker(ctx, bkt, id, []ulid.ULID{sourceBlock}))
+	}
+
+	require.NoError(t, retryParquetExports(ctx, log.NewNopLogger(), bkt, t.TempDir(), metrics))
+
+	// The markers have been dropped without exporting the blocks, and the export of the source block is kept.
+	var exported []string
+	require.NoError(t, bkt.Iter(ctx, ParquetExportPrefix, func(name string) error {
+		exported = append(exported, name)
+		return nil
+	}))
+	assert.Equal(t, []string{ParquetExportPath(sourceBlock)}, exported)
+	assert.Equal(t, float64(0), testutil.ToFloat64(metrics.parquetExportedBlocks))
+	assert.Equal(t, float64(0), testutil.ToFloat64(metrics.parquetExportFailures))
+}
@@ -4,24 +4,46 @@ package compactor
 
 import (
 	"context"
+	"time"
 
 	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/oklog/ulid"
+	"github.com/pkg/errors"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/prometheus/tsdb"
+
+	"github.com/grafana/mimir/pkg/storage/tsdb/block"
 )
 
 func splitAndMergeGrouperFactory(_ context.Context, cfg Config, cfgProvider ConfigProvider, userID string, logger log.Logger, _ prometheus.Registerer) Grouper {
+	ranges := cfg.BlockRanges.ToMilliseconds()
+
+	// Only plan the compaction jobs of the first block range until the configured time, so that the
+	// most recent blocks are merged but not merged with larger (e.g. backfilled) blocks.
+	if until := cfgProvider.CompactorFirstLevelOnlyUntil(userID); len(ranges) > 1 && time.Now().Before(until) {
+		level.Info(logger).Log("msg", "only running the first level compaction for the tenant", "until", until.Format(time.RFC3339))
+		ranges = ranges[:1]
+	}
+
 	return NewSplitAndMergeGrouper(
 		userID,
-		cfg.BlockRanges.ToMilliseconds(),
+		ranges,
 		uint32(cfgProvider.CompactorSplitAndMergeShards(userID)),
 		uint32(cfgProvider.CompactorSplitGroups(userID)),
 		logger)
 }
 
 func splitAndMergeCompactorFactory(ctx context.Context, cfg Config, logger log.Logger, reg prometheus.Registerer) (Compactor, Planner, error) {
+	// When blocks are also written with consolidated chunk segments, both TSDB compactors register the
+	// same metrics, so they're told apart by the chunk_segments label.
+	defaultReg := reg
+	if cfg.ConsolidatedChunkSegmentsMinLevel > 0 {
+		defaultReg = prometheus.WrapRegistererWith(prometheus.Labels{"chunk_segments": "default"}, reg)
+	}
+
 	// We don't need to customise the TSDB compactor so we're just using the Prometheus one.
-	compactor, err := tsdb.NewLeveledCompactor(ctx, reg, logger, cfg.BlockRanges.ToMilliseconds(), nil, nil)
+	compactor, err := tsdb.NewLeveledCompactor(ctx, defaultReg, logger, cfg.BlockRanges.ToMilliseconds(), nil, nil)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -34,7 +56,69 @@ func splitAndMergeCompactorFactory(ctx context.Context, cfg Config, logger log.L
 	compactor.SetConcurrencyOptions(opts)
 
 	planner := NewSplitAndMergePlanner(cfg.BlockRanges.ToMilliseconds())
-	return compactor, planner, nil
+	if cfg.ConsolidatedChunkSegmentsMinLevel <= 0 {
+		return compactor, planner, nil
+	}
+
+	consolidatedReg := prometheus.WrapRegistererWith(prometheus.Labels{"chunk_segments": "consolidated"}, reg)
+	consolidated, err := tsdb.NewLeveledCompactorWithChunkSize(ctx, consolidatedReg, logger, cfg.BlockRanges.ToMilliseconds(), nil, int64(cfg.ConsolidatedChunkSegmentSize), nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	consolidated.SetConcurrencyOptions(opts)
+
+	return &levelAwareCompactor{
+		Compactor:    compactor,
+		consolidated: consolidated,
+		minLevel:     cfg.ConsolidatedChunkSegmentsMinLevel,
+		readMetaFn:   block.ReadMetaFromDir,
+	}, planner, nil
+}
+
+// levelAwareCompactor writes the blocks whose compaction level is at least minLevel using
+// the consolidated compactor, which writes fewer and larger chunk segment files. The chunk
+// references stored in the block index already hold the offset of each chunk in its segment
+// file, so no change is required to read such blocks.
+type levelAwareCompactor struct {
+	Compactor
+
+	consolidated Compactor
+	minLevel     int
+	readMetaFn   func(dir string) (*block.Meta, error)
+}
+
+func (c *levelAwareCompactor) Compact(dest string, dirs []string, open []*tsdb.Block) ([]ulid.ULID, error) {
+	comp, err := c.compactorFor(dirs)
+	if err != nil {
+		return nil, err
+	}
+	return comp.Compact(dest, dirs, open)
+}
+
+func (c *levelAwareCompactor) CompactWithSplitting(dest string, dirs []string, open []*tsdb.Block, shardCount uint64) ([]ulid.ULID, error) {
+	comp, err := c.compactorFor(dirs)
+	if err != nil {
+		return nil, err
+	}
+	return comp.CompactWithSplitting(dest, dirs, open, shardCount)
+}
+
+// compactorFor returns the compactor to use to compact the input blocks. The compaction level
+// of the resulting block is one more than the highest compaction level of the input blocks.
+func (c *levelAwareCompactor) compactorFor(dirs []string) (Compactor, error) {
+	maxLevel := 0
+	for _, dir := range dirs {
+		meta, err := c.readMetaFn(dir)
+		if err != nil {
+			return nil, errors.Wrapf(err, "read meta of block %s", dir)
+		}
+		maxLevel = max(maxLevel, meta.Compaction.Level)
+	}
+
+	if maxLevel+1 >= c.minLevel {
+		return c.consolidated, nil
+	}
+	return c.Compactor, nil
 }
 
 // configureSplitAndMergeCompactor updates the provided configuration injecting the split-and-merge compactor.
@@ -5,6 +5,7 @@ package compactor
 import (
 	"context"
 	"os"
+	"path"
 	"path/filepath"
 	"strconv"
 	"strings"
@@ -19,8 +20,11 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/storage"
 	"github.com/prometheus/prometheus/tsdb"
+	"github.com/prometheus/prometheus/tsdb/chunks"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 	"github.com/thanos-io/objstore"
 
@@ -712,6 +716,7 @@ func TestMultitenantCompactor_ShouldGuaranteeSeriesShardingConsistencyOverTheTim
 	compactorCfg := prepareConfig(t)
 	compactorCfg.DataDir = workDir
 	compactorCfg.BlockRanges = compactionRanges
+	compactorCfg.UploadSeriesHashes = true
 
 	cfgProvider := newMockConfigProvider()
 	cfgProvider.splitAndMergeShards[userID] = numShards
@@ -797,6 +802,21 @@ func TestMultitenantCompactor_ShouldGuaranteeSeriesShardingConsistencyOverTheTim
 		}
 
 		require.NoError(t, postings.Err())
+
+		// Ensure the uploaded series hashes match the shard of the block.
+		shardIndex, shardCount, err := sharding.ParseShardIDLabelValue(actualMeta.Thanos.Labels[mimir_tsdb.CompactorShardIDExternalLabel])
+		require.NoError(t, err)
+
+		hashes, err := userBucket.Get(ctx, path.Join(actualMeta.ULID.String(), block.SeriesHashesFilename))
+		require.NoError(t, err)
+
+		numHashes := 0
+		require.NoError(t, block.ReadSeriesHashes(hashes, func(_ storage.SeriesRef, hash uint64) {
+			assert.Equal(t, shardIndex, hash%shardCount)
+			numHashes++
+		}))
+		require.NoError(t, hashes.Close())
+		assert.Equal(t, len(expectedSeriesIDs), numHashes)
 	}
 }
 
@@ -807,3 +827,145 @@ func convertMetasMapToSlice(metas map[ulid.ULID]*block.Meta) []*block.Meta {
 	}
 	return out
 }
+
+func TestSplitAndMergeGrouperFactory_FirstLevelOnlyUntil(t *testing.T) {
+	cfg := Config{BlockRanges: mimir_tsdb.DurationList{2 * time.Hour, 12 * time.Hour, 24 * time.Hour}}
+
+	cfgProvider := newMockConfigProvider()
+	cfgProvider.firstLevelOnlyUntil["user-until-future"] = time.Now().Add(time.Hour)
+	cfgProvider.firstLevelOnlyUntil["user-until-past"] = time.Now().Add(-time.Hour)
+
+	tests := map[string][]int64{
+		"user-disabled":     cfg.BlockRanges.ToMilliseconds(),
+		"user-until-future": {(2 * time.Hour).Milliseconds()},
+		"user-until-past":   cfg.BlockRanges.ToMilliseconds(),
+	}
+
+	for userID, expectedRanges := range tests {
+		t.Run(userID, func(t *testing.T) {
+			grouper := splitAndMergeGrouperFactory(context.Background(), cfg, cfgProvider, userID, log.NewNopLogger(), nil)
+			assert.Equal(t, expectedRanges, grouper.(*SplitAndMergeGrouper).ranges)
+		})
+	}
+}
+
+func TestLevelAwareCompactor(t *testing.T) {
+	levels := map[string]int{"level-1": 1, "level-2": 2, "level-3": 3}
+	readMeta := func(dir string) (*block.Meta, error) {
+		level, ok := levels[dir]
+		if !ok {
+			return nil, os.ErrNotExist
+		}
+		return &block.Meta{BlockMeta: tsdb.BlockMeta{Compaction: tsdb.BlockMetaCompaction{Level: level}}}, nil
+	}
+
+	defaultID := ulid.MustNew(1, nil)
+	consolidatedID := ulid.MustNew(2, nil)
+
+	defaultCompactor := &tsdbCompactorMock{}
+	defaultCompactor.On("Compact", "dest", mock.Anything, mock.Anything).Return([]ulid.ULID{defaultID}, nil)
+	defaultCompactor.On("CompactWithSplitting", "dest", mock.Anything, mock.Anything, uint64(2)).Return([]ulid.ULID{defaultID}, nil)
+	consolidatedCompactor := &tsdbCompactorMock{}
+	consolidatedCompactor.On("Compact", "dest", mock.Anything, mock.Anything).Return([]ulid.ULID{consolidatedID}, nil)
+	consolidatedCompactor.On("CompactWithSplitting", "dest", mock.Anything, mock.Anything, uint64(2)).Return([]ulid.ULID{consolidatedID}, nil)
+
+	c := &levelAwareCompactor{
+		Compactor:    defaultCompactor,
+		consolidated: consolidatedCompactor,
+		minLevel:     3,
+		readMetaFn:   readMeta,
+	}
+
+	// The resulting block is level 2.
+	ids, err := c.Compact("dest", []string{"level-1", "level-1"}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []ulid.ULID{defaultID}, ids)
+
+	// The resulting block is level 3.
+	ids, err = c.Compact("dest", []string{"level-1", "level-2"}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []ulid.ULID{consolidatedID}, ids)
+
+	ids, err = c.CompactWithSplitting("dest", []string{"level-1"}, nil, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []ulid.ULID{defaultID}, ids)
+
+	ids, err = c.CompactWithSplitting("dest", []string{"level-3"}, nil, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []ulid.ULID{consolidatedID}, ids)
+
+	// The compaction fails if the meta of an input block can't be read.
+	_, err = c.Compact("dest", []string{"level-1", "unknown"}, nil)
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestLevelAwareCompactor_ShouldWriteConsolidatedChunkSegments(t *testing.T) {
+	const segmentSize = 16 * 1024
+
+	var (
+		ctx    = context.Background()
+		logger = log.NewNopLogger()
+		srcDir = t.TempDir()
+		dstDir = t.TempDir()
+	)
+
+	cfg := Config{}
+	flagext.DefaultValues(&cfg)
+
+	// Generate two level-2 blocks with enough chunks to span several default segment files.
+	var dirs []string
+	for i := 0; i < 2; i++ {
+		specs := block.SeriesSpecs{}
+		for s := 0; s < 1000; s++ {
+			series := labels.FromStrings("series_id", strconv.Itoa(s), "block", strconv.Itoa(i))
+			samples := make([]chunks.Sample, 0, 120)
+			for ts := int64(0); ts < 120; ts++ {
+				samples = append(samples, newSample(int64(i)*1000+ts, float64(ts*int64(s)), nil, nil))
+			}
+			specs = append(specs, &block.SeriesSpec{Labels: series, Chunks: []chunks.Meta{must(chunks.ChunkFromSamples(samples))}})
+		}
+
+		meta, err := block.GenerateBlockFromSpec(srcDir, specs)
+		require.NoError(t, err)
+
+		meta.Compaction.Level = 2
+		blockDir := filepath.Join(srcDir, meta.ULID.String())
+		require.NoError(t, meta.WriteToDir(logger, blockDir))
+		dirs = append(dirs, blockDir)
+	}
+
+	countSegments := func(id ulid.ULID) int {
+		entries, err := os.ReadDir(filepath.Join(dstDir, id.String(), block.ChunksDirname))
+		require.NoError(t, err)
+		return len(entries)
+	}
+
+	// Compact with a small default segment size, to check the consolidated one is used.
+	defaultCompactor, err := tsdb.NewLeveledCompactorWithChunkSize(ctx, nil, logger, cfg.BlockRanges.ToMilliseconds(), nil, segmentSize, nil)
+	require.NoError(t, err)
+	ids, err := defaultCompactor.Compact(dstDir, dirs, nil)
+	require.NoError(t, err)
+	require.Len(t, ids, 1)
+	require.Greater(t, countSegments(ids[0]), 1)
+
+	cfg.ConsolidatedChunkSegmentsMinLevel = 3
+	reg := prometheus.NewPedanticRegistry()
+	comp, _, err := splitAndMergeCompactorFactory(ctx, cfg, logger, reg)
+	require.NoError(t, err)
+
+	ids, err = comp.Compact(dstDir, dirs, nil)
+	require.NoError(t, err)
+	require.Len(t, ids, 1)
+	assert.Equal(t, 1, countSegments(ids[0]))
+
+	assert.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(`
+		# HELP prometheus_tsdb_compactions_total Total number of compactions that were executed for the partition.
+		# TYPE prometheus_tsdb_compactions_total counter
+		prometheus_tsdb_compactions_total{chunk_segments="consolidated"} 1
+		prometheus_tsdb_compactions_total{chunk_segments="default"} 0
+	`), "prometheus_tsdb_compactions_total"))
+
+	meta, err := block.ReadMetaFromDir(filepath.Join(dstDir, ids[0].String()))
+	require.NoError(t, err)
+	assert.Equal(t, 3, meta.Compaction.Level)
+}
@@ -41,6 +41,10 @@ func (c *MultitenantCompactor) DeleteTenant(w http.ResponseWriter, r *http.Reque
 
 	level.Info(c.logger).Log("msg", "tenant deletion mark in blocks storage created", "user", userID)
 
+	if c.compactorCfg.TenantDeletedFn != nil {
+		c.compactorCfg.TenantDeletedFn(userID)
+	}
+
 	w.WriteHeader(http.StatusOK)
 }
 
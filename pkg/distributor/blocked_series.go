@@ -0,0 +1,99 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package distributor
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-kit/log/level"
+	"github.com/grafana/dskit/tenant"
+	"github.com/prometheus/prometheus/model/labels"
+
+	"github.com/grafana/mimir/pkg/mimirpb"
+	"github.com/grafana/mimir/pkg/util"
+	util_log "github.com/grafana/mimir/pkg/util/log"
+	"github.com/grafana/mimir/pkg/util/validation"
+)
+
+// prePushBlockedSeriesMiddleware drops the series matching the tenant's blocked series selectors,
+// before any other processing of the series.
+func (d *Distributor) prePushBlockedSeriesMiddleware(next PushFunc) PushFunc {
+	return func(ctx context.Context, pushReq *Request) error {
+		next, maybeCleanup := NextOrCleanup(next, pushReq)
+		defer maybeCleanup()
+
+		userID, err := tenant.TenantID(ctx)
+		if err != nil {
+			return err
+		}
+
+		blocked := d.limits.BlockedSeries(userID)
+		if len(blocked) == 0 {
+			return next(ctx, pushReq)
+		}
+
+		req, err := pushReq.WriteRequest()
+		if err != nil {
+			return err
+		}
+
+		selectors := make([][]*labels.Matcher, 0, len(blocked))
+		for _, b := range blocked {
+			m, err := b.Matchers()
+			if err != nil {
+				// The selectors are validated when the limits are loaded, so this should never happen.
+				level.Warn(util_log.WithContext(ctx, d.log)).Log("msg", "skipping blocked series with invalid series selector", "match", b.Match, "err", err)
+			}
+			selectors = append(selectors, m)
+		}
+
+		var (
+			removeTsIndexes []int
+			droppedSamples  int
+		)
+		for tsIdx := 0; tsIdx < len(req.Timeseries); tsIdx++ {
+			ts := req.Timeseries[tsIdx]
+			if matchingSelector(mimirpb.FromLabelAdaptersToLabels(ts.Labels), selectors) < 0 {
+				continue
+			}
+
+			removeTsIndexes = append(removeTsIndexes, tsIdx)
+			droppedSamples += len(ts.Samples) + len(ts.Histograms)
+		}
+
+		if len(removeTsIndexes) > 0 {
+			group := d.activeGroups.UpdateActiveGroupTimestamp(userID, validation.GroupLabel(d.limits, userID, req.Timeseries), time.Now())
+			d.discardedSamplesBlockedSeries.WithLabelValues(userID, group).Add(float64(droppedSamples))
+
+			for _, removeTsIndex := range removeTsIndexes {
+				mimirpb.ReusePreallocTimeseries(&req.Timeseries[removeTsIndex])
+			}
+			req.Timeseries = util.RemoveSliceIndexes(req.Timeseries, removeTsIndexes)
+		}
+
+		return next(ctx, pushReq)
+	}
+}
+
+// matchingSelector returns the index of the first series selector matching the series, or -1 if none matches.
+// Empty selectors never match.
+func matchingSelector(lbls labels.Labels, selectors [][]*labels.Matcher) int {
+	for idx, matchers := range selectors {
+		if len(matchers) == 0 {
+			continue
+		}
+
+		matches := true
+		for _, m := range matchers {
+			if !m.Matches(lbls.Get(m.Name)) {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			return idx
+		}
+	}
+	return -1
+}
@@ -0,0 +1,71 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package distributor
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/grafana/dskit/flagext"
+	"github.com/grafana/dskit/user"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/mimir/pkg/mimirpb"
+	"github.com/grafana/mimir/pkg/util/validation"
+)
+
+func TestBlockedSeriesMiddleware(t *testing.T) {
+	ctx := user.InjectOrgID(context.Background(), "user")
+
+	var limits validation.Limits
+	flagext.DefaultValues(&limits)
+	limits.BlockedSeries = []*validation.BlockedSeries{
+		{Match: `http_requests_total{job="garbage"}`},
+		{Match: `{__name__=~"debug_.+"}`},
+	}
+	ds, _, regs, _ := prepare(t, prepConfig{
+		numDistributors: 1,
+		limits:          &limits,
+	})
+
+	var got *mimirpb.WriteRequest
+	next := func(_ context.Context, pushReq *Request) error {
+		var err error
+		got, err = pushReq.WriteRequest()
+		require.NoError(t, err)
+		pushReq.CleanUp()
+		return nil
+	}
+	middleware := ds[0].prePushBlockedSeriesMiddleware(next)
+
+	req := &mimirpb.WriteRequest{Timeseries: []mimirpb.PreallocTimeseries{
+		makeTimeseries([]string{labels.MetricName, "http_requests_total", "job", "garbage"}, makeSamples(10, 1), nil),
+		makeTimeseries([]string{labels.MetricName, "http_requests_total", "job", "api"}, makeSamples(10, 2), nil),
+		makeTimeseries([]string{labels.MetricName, "debug_allocations", "job", "api"}, []mimirpb.Sample{{TimestampMs: 10, Value: 3}, {TimestampMs: 20, Value: 4}}, nil),
+		{TimeSeries: &mimirpb.TimeSeries{
+			Labels:     mimirpb.FromLabelsToLabelAdapters(labels.FromStrings(labels.MetricName, "debug_latency", "job", "api")),
+			Histograms: []mimirpb.Histogram{mimirpb.FromHistogramToHistogramProto(10, generateTestHistogram(1))},
+		}},
+		makeTimeseries([]string{labels.MetricName, "up", "job", "garbage"}, makeSamples(10, 1), nil),
+	}}
+	require.NoError(t, middleware(ctx, NewParsedRequest(req)))
+
+	var actual []string
+	for _, ts := range got.Timeseries {
+		actual = append(actual, mimirpb.FromLabelAdaptersToLabels(ts.Labels).String())
+	}
+	assert.Equal(t, []string{
+		`{__name__="http_requests_total", job="api"}`,
+		`{__name__="up", job="garbage"}`,
+	}, actual)
+
+	assert.NoError(t, testutil.GatherAndCompare(regs[0], strings.NewReader(`
+		# HELP cortex_discarded_samples_total The total number of samples that were discarded.
+		# TYPE cortex_discarded_samples_total counter
+		cortex_discarded_samples_total{group="",reason="blocked_series",user="user"} 4
+	`), "cortex_discarded_samples_total"))
+}
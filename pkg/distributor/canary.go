@@ -0,0 +1,259 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package distributor
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/grafana/dskit/concurrency"
+	"github.com/grafana/dskit/flagext"
+	"github.com/grafana/dskit/services"
+	"github.com/grafana/dskit/user"
+	"github.com/prometheus/client_golang/api"
+	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/labels"
+
+	"github.com/grafana/mimir/pkg/mimirpb"
+)
+
+const (
+	// canaryMetricName is the name of the series written by the canary. Each distributor writes its own series,
+	// identified by the canaryInstanceLabel label.
+	canaryMetricName    = "mimir_distributor_canary"
+	canaryInstanceLabel = "distributor"
+
+	canaryResultSuccess      = "success"
+	canaryResultWriteFailure = "write_failure"
+	canaryResultReadTimeout  = "read_timeout"
+)
+
+var (
+	errInvalidCanaryTenants      = errors.New("at least one tenant must be configured when the distributor canary is enabled")
+	errInvalidCanaryQueryAddress = errors.New("the query address must be configured when the distributor canary is enabled")
+	errInvalidCanaryInterval     = errors.New("the distributor canary interval must be greater than 0")
+	errInvalidCanaryTimeout      = errors.New("the distributor canary timeout must be greater than 0 and not greater than the interval")
+)
+
+type CanaryConfig struct {
+	Enabled      bool                   `yaml:"enabled" category:"experimental"`
+	Tenants      flagext.StringSliceCSV `yaml:"tenants" category:"experimental"`
+	QueryAddress flagext.URLValue       `yaml:"query_address" category:"experimental"`
+	Interval     time.Duration          `yaml:"interval" category:"experimental"`
+	Timeout      time.Duration          `yaml:"timeout" category:"experimental"`
+}
+
+func (cfg *CanaryConfig) RegisterFlags(f *flag.FlagSet) {
+	f.BoolVar(&cfg.Enabled, "distributor.canary.enabled", false, "Enable the canary, which periodically writes a synthetic sample for each of the configured tenants and checks that it can be queried through the read path.")
+	f.Var(&cfg.Tenants, "distributor.canary.tenants", "Comma-separated list of tenants for which the canary writes and reads the synthetic series "+canaryMetricName+".")
+	f.Var(&cfg.QueryAddress, "distributor.canary.query-address", "Base URL of the Prometheus HTTP API used by the canary to query the synthetic series, typically exposed by the query-frontend, so that the query runs through the full read path. The URL should have no trailing slash, for example: http://query-frontend:8080/prometheus.")
+	f.DurationVar(&cfg.Interval, "distributor.canary.interval", time.Minute, "How frequently the canary writes a synthetic sample.")
+	f.DurationVar(&cfg.Timeout, "distributor.canary.timeout", 30*time.Second, "Maximum time after the write within which the synthetic sample must be queryable. Samples not queryable within this time are reported as failed checks.")
+}
+
+func (cfg *CanaryConfig) Validate() error {
+	if !cfg.Enabled {
+		return nil
+	}
+	if len(cfg.Tenants) == 0 {
+		return errInvalidCanaryTenants
+	}
+	if cfg.QueryAddress.URL == nil {
+		return errInvalidCanaryQueryAddress
+	}
+	if cfg.Interval <= 0 {
+		return errInvalidCanaryInterval
+	}
+	if cfg.Timeout <= 0 || cfg.Timeout > cfg.Interval {
+		return errInvalidCanaryTimeout
+	}
+	return nil
+}
+
+// canaryQueryFunc returns whether the series matching the matchers has a sample at the given timestamp.
+type canaryQueryFunc func(ctx context.Context, ts int64, matchers ...*labels.Matcher) (bool, error)
+
+// canary periodically writes a synthetic sample through the distributor write path and checks that it can be
+// read back within the configured timeout, measuring the end-to-end freshness of the ingested data.
+type canary struct {
+	services.Service
+
+	cfg          CanaryConfig
+	instanceID   string
+	push         PushFunc
+	query        canaryQueryFunc
+	pollInterval time.Duration
+	logger       log.Logger
+
+	checks      *prometheus.CounterVec
+	freshness   *prometheus.HistogramVec
+	lastSuccess *prometheus.GaugeVec
+}
+
+func newCanary(cfg CanaryConfig, instanceID string, push PushFunc, query canaryQueryFunc, logger log.Logger, reg prometheus.Registerer) *canary {
+	c := &canary{
+		cfg:          cfg,
+		instanceID:   instanceID,
+		push:         push,
+		query:        query,
+		pollInterval: time.Second,
+		logger:       logger,
+
+		checks: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "cortex_distributor_canary_checks_total",
+			Help: "Total number of canary checks, by result.",
+		}, []string{"user", "result"}),
+		freshness: promauto.With(reg).NewHistogramVec(prometheus.HistogramOpts{
+			Name:                            "cortex_distributor_canary_freshness_seconds",
+			Help:                            "Time between the write of the canary sample and the moment it was queryable.",
+			Buckets:                         []float64{.1, .25, .5, 1, 2.5, 5, 10, 30, 60},
+			NativeHistogramBucketFactor:     1.1,
+			NativeHistogramMaxBucketNumber:  100,
+			NativeHistogramMinResetDuration: 1 * time.Hour,
+		}, []string{"user"}),
+		lastSuccess: promauto.With(reg).NewGaugeVec(prometheus.GaugeOpts{
+			Name: "cortex_distributor_canary_last_success_timestamp_seconds",
+			Help: "Unix timestamp (in seconds) of the last canary sample written and successfully queried.",
+		}, []string{"user"}),
+	}
+
+	c.Service = services.NewTimerService(cfg.Interval, nil, c.iteration, nil)
+	return c
+}
+
+func (c *canary) iteration(ctx context.Context) error {
+	// Failed checks are tracked by the metrics, so they don't stop the service.
+	_ = concurrency.ForEachJob(ctx, len(c.cfg.Tenants), len(c.cfg.Tenants), func(ctx context.Context, idx int) error {
+		c.check(ctx, c.cfg.Tenants[idx])
+		return nil
+	})
+	return nil
+}
+
+// check writes a canary sample for the tenant and waits until it's queryable or the timeout expires.
+func (c *canary) check(ctx context.Context, userID string) {
+	ctx = user.InjectOrgID(ctx, userID)
+	logger := log.With(c.logger, "user", userID)
+
+	start := time.Now()
+	ts := start.UnixMilli()
+	lbls := []mimirpb.LabelAdapter{
+		{Name: labels.MetricName, Value: canaryMetricName},
+		{Name: canaryInstanceLabel, Value: c.instanceID},
+	}
+
+	req := NewParsedRequest(&mimirpb.WriteRequest{
+		Timeseries: []mimirpb.PreallocTimeseries{{TimeSeries: &mimirpb.TimeSeries{
+			Labels:  lbls,
+			Samples: []mimirpb.Sample{{TimestampMs: ts, Value: float64(start.Unix())}},
+		}}},
+		Source: mimirpb.API,
+	})
+	if err := c.push(ctx, req); err != nil {
+		level.Warn(logger).Log("msg", "failed to write the canary sample", "err", err)
+		c.checks.WithLabelValues(userID, canaryResultWriteFailure).Inc()
+		return
+	}
+
+	matchers := []*labels.Matcher{
+		labels.MustNewMatcher(labels.MatchEqual, labels.MetricName, canaryMetricName),
+		labels.MustNewMatcher(labels.MatchEqual, canaryInstanceLabel, c.instanceID),
+	}
+
+	readCtx, cancel := context.WithDeadline(ctx, start.Add(c.cfg.Timeout))
+	defer cancel()
+
+	ticker := time.NewTicker(c.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		found, err := c.query(readCtx, ts, matchers...)
+		if err != nil && readCtx.Err() == nil {
+			level.Warn(logger).Log("msg", "failed to query the canary sample", "err", err)
+		}
+		if found {
+			c.freshness.WithLabelValues(userID).Observe(time.Since(start).Seconds())
+			c.lastSuccess.WithLabelValues(userID).SetToCurrentTime()
+			c.checks.WithLabelValues(userID, canaryResultSuccess).Inc()
+			return
+		}
+
+		select {
+		case <-ticker.C:
+		case <-readCtx.Done():
+			if ctx.Err() != nil {
+				// The canary is stopping.
+				return
+			}
+			level.Warn(logger).Log("msg", "the canary sample was not queryable within the timeout", "timeout", c.cfg.Timeout)
+			c.checks.WithLabelValues(userID, canaryResultReadTimeout).Inc()
+			return
+		}
+	}
+}
+
+// newCanaryHTTPQueryFunc returns a canaryQueryFunc which runs the query through the Prometheus HTTP API
+// at the given address, on behalf of the tenant in the context.
+func newCanaryHTTPQueryFunc(address string) (canaryQueryFunc, error) {
+	client, err := api.NewClient(api.Config{
+		Address:      address,
+		RoundTripper: &canaryRoundTripper{next: http.DefaultTransport},
+	})
+	if err != nil {
+		return nil, err
+	}
+	promAPI := v1.NewAPI(client)
+
+	return func(ctx context.Context, ts int64, matchers ...*labels.Matcher) (bool, error) {
+		selectors := make([]string, 0, len(matchers))
+		for _, m := range matchers {
+			selectors = append(selectors, m.String())
+		}
+
+		// The timestamp of the sample is queried, so that an older canary sample within the lookback
+		// delta is not mistaken for the one just written.
+		query := fmt.Sprintf("timestamp({%s})", strings.Join(selectors, ", "))
+		value, _, err := promAPI.Query(ctx, query, time.UnixMilli(ts))
+		if err != nil {
+			return false, err
+		}
+
+		vector, ok := value.(model.Vector)
+		if !ok {
+			return false, fmt.Errorf("unexpected query result type %s", value.Type())
+		}
+		for _, sample := range vector {
+			if float64(sample.Value) == float64(ts)/1000 {
+				return true, nil
+			}
+		}
+		return false, nil
+	}, nil
+}
+
+// canaryRoundTripper sets the tenant ID header from the request context, and disables the results cache so
+// that an empty result cached before the canary sample was queryable isn't returned.
+type canaryRoundTripper struct {
+	next http.RoundTripper
+}
+
+func (rt *canaryRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	if err := user.InjectOrgIDIntoHTTPRequest(req.Context(), req); err != nil {
+		return nil, err
+	}
+	// Despite the name, the "no-store" directive also disables results cache lookup in Mimir.
+	req.Header.Set("Cache-Control", "no-store")
+
+	return rt.next.RoundTrip(req)
+}
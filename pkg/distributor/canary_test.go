@@ -0,0 +1,203 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package distributor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/dskit/flagext"
+	"github.com/grafana/dskit/user"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/mimir/pkg/mimirpb"
+)
+
+func TestCanaryConfig_Validate(t *testing.T) {
+	queryAddress := flagext.URLValue{URL: &url.URL{Scheme: "http", Host: "query-frontend:8080", Path: "/prometheus"}}
+
+	tests := map[string]struct {
+		cfg      CanaryConfig
+		expected error
+	}{
+		"disabled": {
+			cfg: CanaryConfig{},
+		},
+		"valid": {
+			cfg: CanaryConfig{Enabled: true, Tenants: []string{"user-1"}, QueryAddress: queryAddress, Interval: time.Minute, Timeout: 30 * time.Second},
+		},
+		"no tenants": {
+			cfg:      CanaryConfig{Enabled: true, QueryAddress: queryAddress, Interval: time.Minute, Timeout: 30 * time.Second},
+			expected: errInvalidCanaryTenants,
+		},
+		"no query address": {
+			cfg:      CanaryConfig{Enabled: true, Tenants: []string{"user-1"}, Interval: time.Minute, Timeout: 30 * time.Second},
+			expected: errInvalidCanaryQueryAddress,
+		},
+		"invalid interval": {
+			cfg:      CanaryConfig{Enabled: true, Tenants: []string{"user-1"}, QueryAddress: queryAddress, Timeout: 30 * time.Second},
+			expected: errInvalidCanaryInterval,
+		},
+		"timeout greater than interval": {
+			cfg:      CanaryConfig{Enabled: true, Tenants: []string{"user-1"}, QueryAddress: queryAddress, Interval: time.Minute, Timeout: 2 * time.Minute},
+			expected: errInvalidCanaryTimeout,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, tc.cfg.Validate())
+		})
+	}
+}
+
+func TestCanary_check(t *testing.T) {
+	cfg := CanaryConfig{Enabled: true, Tenants: []string{"user-1"}, Interval: time.Minute, Timeout: 100 * time.Millisecond}
+
+	tests := map[string]struct {
+		pushErr        error
+		queryableAfter int
+		expectedResult string
+	}{
+		"sample immediately queryable": {
+			expectedResult: canaryResultSuccess,
+		},
+		"sample queryable after a few attempts": {
+			queryableAfter: 3,
+			expectedResult: canaryResultSuccess,
+		},
+		"sample not queryable within the timeout": {
+			queryableAfter: 1000,
+			expectedResult: canaryResultReadTimeout,
+		},
+		"write failure": {
+			pushErr:        errors.New("push failed"),
+			expectedResult: canaryResultWriteFailure,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			var pushed *mimirpb.WriteRequest
+			push := func(ctx context.Context, req *Request) error {
+				userID, err := user.ExtractOrgID(ctx)
+				require.NoError(t, err)
+				assert.Equal(t, "user-1", userID)
+
+				pushed, err = req.WriteRequest()
+				require.NoError(t, err)
+				return tc.pushErr
+			}
+
+			queries := 0
+			query := func(_ context.Context, ts int64, matchers ...*labels.Matcher) (bool, error) {
+				queries++
+				require.Len(t, pushed.Timeseries, 1)
+				assert.Equal(t, ts, pushed.Timeseries[0].Samples[0].TimestampMs)
+				assert.True(t, labels.Selector(matchers).Matches(mimirpb.FromLabelAdaptersToLabels(pushed.Timeseries[0].Labels)))
+				return queries > tc.queryableAfter, nil
+			}
+
+			reg := prometheus.NewPedanticRegistry()
+			c := newCanary(cfg, "distributor-1", push, query, log.NewNopLogger(), reg)
+			c.pollInterval = time.Millisecond
+			c.check(context.Background(), "user-1")
+
+			assert.Equal(t, float64(1), testutil.ToFloat64(c.checks.WithLabelValues("user-1", tc.expectedResult)))
+			if tc.expectedResult == canaryResultSuccess {
+				assert.Equal(t, 1, testutil.CollectAndCount(reg, "cortex_distributor_canary_freshness_seconds"))
+				assert.NotZero(t, testutil.ToFloat64(c.lastSuccess.WithLabelValues("user-1")))
+			} else {
+				assert.Equal(t, 0, testutil.CollectAndCount(reg, "cortex_distributor_canary_freshness_seconds"))
+			}
+		})
+	}
+}
+
+func TestCanaryHTTPQueryFunc(t *testing.T) {
+	const ts = int64(1600000000123)
+
+	tests := map[string]struct {
+		resultTimestamp string
+		expectedFound   bool
+	}{
+		"sample found": {
+			resultTimestamp: "1600000000.123",
+			expectedFound:   true,
+		},
+		"only an older sample found": {
+			resultTimestamp: "1599999940.123",
+			expectedFound:   false,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				require.NoError(t, r.ParseForm())
+				assert.Equal(t, "/prometheus/api/v1/query", r.URL.Path)
+				assert.Equal(t, "user-1", r.Header.Get(user.OrgIDHeaderName))
+				assert.Equal(t, "no-store", r.Header.Get("Cache-Control"))
+				assert.Equal(t, `timestamp({__name__="mimir_distributor_canary", distributor="distributor-1"})`, r.Form.Get("query"))
+				assert.Equal(t, "1600000000.123", r.Form.Get("time"))
+
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = fmt.Fprintf(w, `{"status":"success","data":{"resultType":"vector","result":[{"metric":{"distributor":"distributor-1"},"value":[1600000000.123,%q]}]}}`, tc.resultTimestamp)
+			}))
+			t.Cleanup(server.Close)
+
+			query, err := newCanaryHTTPQueryFunc(server.URL + "/prometheus")
+			require.NoError(t, err)
+
+			found, err := query(user.InjectOrgID(context.Background(), "user-1"), ts,
+				labels.MustNewMatcher(labels.MatchEqual, labels.MetricName, canaryMetricName),
+				labels.MustNewMatcher(labels.MatchEqual, canaryInstanceLabel, "distributor-1"),
+			)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedFound, found)
+		})
+	}
+}
+
+func TestDistributor_Canary(t *testing.T) {
+	// Mock the read path, always returning a sample at the queried timestamp.
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprintf(w, `{"status":"success","data":{"resultType":"vector","result":[{"metric":{},"value":[%s,%q]}]}}`, r.Form.Get("time"), r.Form.Get("time"))
+	}))
+	t.Cleanup(server.Close)
+
+	queryAddress := flagext.URLValue{}
+	require.NoError(t, queryAddress.Set(server.URL))
+
+	ds, _, regs, _ := prepare(t, prepConfig{
+		numIngesters:    3,
+		happyIngesters:  3,
+		numDistributors: 1,
+		configure: func(cfg *Config) {
+			cfg.CanaryConfig = CanaryConfig{Enabled: true, Tenants: []string{"user-1", "user-2"}, QueryAddress: queryAddress, Interval: time.Minute, Timeout: 5 * time.Second}
+		},
+	})
+
+	require.NoError(t, ds[0].canary.iteration(context.Background()))
+
+	assert.NoError(t, testutil.GatherAndCompare(regs[0], strings.NewReader(`
+		# HELP cortex_distributor_canary_checks_total Total number of canary checks, by result.
+		# TYPE cortex_distributor_canary_checks_total counter
+		cortex_distributor_canary_checks_total{result="success",user="user-1"} 1
+		cortex_distributor_canary_checks_total{result="success",user="user-2"} 1
+	`), "cortex_distributor_canary_checks_total"))
+}
@@ -0,0 +1,99 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package distributor
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/grafana/dskit/httpgrpc"
+	"github.com/grafana/dskit/mtime"
+	"github.com/grafana/dskit/tenant"
+)
+
+const (
+	// RequestTimeoutHeader is the HTTP header through which remote-write clients can optionally set the deadline of
+	// a push request. The deadline is expressed as a duration relative to the time the request is received by the
+	// distributor (e.g. "10s"), so that it isn't affected by clock skews between the client and the distributor.
+	RequestTimeoutHeader = "X-Mimir-Request-Timeout"
+
+	clientDeadlineKey ctxKey = 2
+)
+
+// contextWithClientDeadline returns a context carrying the deadline set by the client through the
+// RequestTimeoutHeader of the HTTP request r, if any. Invalid or non-positive values are ignored.
+func contextWithClientDeadline(ctx context.Context, r *http.Request) context.Context {
+	value := r.Header.Get(RequestTimeoutHeader)
+	if value == "" {
+		return ctx
+	}
+
+	timeout, err := time.ParseDuration(value)
+	if err != nil || timeout <= 0 {
+		return ctx
+	}
+
+	return context.WithValue(ctx, clientDeadlineKey, mtime.Now().Add(timeout))
+}
+
+// clientDeadlineFromContext returns the deadline set by the client of the request, if any.
+func clientDeadlineFromContext(ctx context.Context) (time.Time, bool) {
+	deadline, ok := ctx.Value(clientDeadlineKey).(time.Time)
+	return deadline, ok
+}
+
+// clientDeadlineMiddleware rejects the requests whose client deadline has already expired, and propagates
+// the client deadline of the other ones to the request context. It's only used when honoring the client
+// deadline is enabled.
+func (d *Distributor) clientDeadlineMiddleware(next PushFunc) PushFunc {
+	return func(ctx context.Context, pushReq *Request) error {
+		deadline, ok := clientDeadlineFromContext(ctx)
+		if !ok {
+			return next(ctx, pushReq)
+		}
+
+		if err := d.checkClientDeadline(ctx, deadline); err != nil {
+			pushReq.CleanUp()
+			return err
+		}
+
+		ctx, cancel := context.WithDeadline(ctx, deadline)
+		defer cancel()
+
+		return next(ctx, pushReq)
+	}
+}
+
+// checkClientDeadline returns an error if the client deadline has expired, because the client isn't
+// waiting for the response anymore and processing the request would be a waste of resources.
+func (d *Distributor) checkClientDeadline(ctx context.Context, deadline time.Time) error {
+	if mtime.Now().Before(deadline) {
+		return nil
+	}
+
+	if userID, err := tenant.TenantID(ctx); err == nil {
+		d.discardedRequestsClientDeadlineExceeded.WithLabelValues(userID).Inc()
+	}
+	return newClientDeadlineExceededError()
+}
+
+// remoteTimeout returns the timeout of the requests sent to the backends (e.g. ingesters) for the input
+// push request. It's the configured remote timeout, capped to the time left before the client deadline
+// if honoring the client deadline is enabled and the client has set one.
+func (d *Distributor) remoteTimeout(ctx context.Context) time.Duration {
+	if !d.cfg.ClientDeadlineEnabled {
+		return d.cfg.RemoteTimeout
+	}
+
+	deadline, ok := clientDeadlineFromContext(ctx)
+	if !ok {
+		return d.cfg.RemoteTimeout
+	}
+	return min(d.cfg.RemoteTimeout, deadline.Sub(mtime.Now()))
+}
+
+func newClientDeadlineExceededError() error {
+	return httpgrpc.Error(http.StatusRequestTimeout, fmt.Sprintf("the request has been rejected because its deadline, set by the client through the %s header, has expired before it could be processed", RequestTimeoutHeader))
+}
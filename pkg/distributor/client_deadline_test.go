@@ -0,0 +1,190 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package distributor
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/dskit/mtime"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/mimir/pkg/mimirpb"
+	"github.com/grafana/mimir/pkg/util/validation"
+)
+
+func TestContextWithClientDeadline(t *testing.T) {
+	now := time.Now()
+	mtime.NowForce(now)
+	t.Cleanup(mtime.NowReset)
+
+	tests := map[string]struct {
+		header           string
+		expectedDeadline time.Time
+		expectedOK       bool
+	}{
+		"no header": {},
+		"valid timeout": {
+			header:           "1500ms",
+			expectedDeadline: now.Add(1500 * time.Millisecond),
+			expectedOK:       true,
+		},
+		"invalid timeout": {
+			header: "1 second",
+		},
+		"zero timeout": {
+			header: "0s",
+		},
+		"negative timeout": {
+			header: "-1s",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/api/v1/push", nil)
+			if tc.header != "" {
+				r.Header.Set(RequestTimeoutHeader, tc.header)
+			}
+
+			deadline, ok := clientDeadlineFromContext(contextWithClientDeadline(context.Background(), r))
+			require.Equal(t, tc.expectedOK, ok)
+			assert.Equal(t, tc.expectedDeadline, deadline)
+		})
+	}
+}
+
+func TestDistributor_Push_ClientDeadline(t *testing.T) {
+	tests := map[string]struct {
+		enabled             bool
+		timeout             string
+		elapsedBeforePush   time.Duration
+		expectedStatusCode  int
+		expectedIngested    bool
+		expectedHasDeadline bool
+		expectedDiscarded   int
+	}{
+		"disabled, deadline not expired": {
+			timeout:            "10s",
+			expectedStatusCode: http.StatusOK,
+			expectedIngested:   true,
+		},
+		"disabled, deadline expired": {
+			timeout:            "10s",
+			elapsedBeforePush:  time.Minute,
+			expectedStatusCode: http.StatusOK,
+			expectedIngested:   true,
+		},
+		"enabled, no deadline": {
+			enabled:            true,
+			expectedStatusCode: http.StatusOK,
+			expectedIngested:   true,
+		},
+		"enabled, deadline not expired": {
+			enabled:             true,
+			timeout:             "10s",
+			expectedStatusCode:  http.StatusOK,
+			expectedIngested:    true,
+			expectedHasDeadline: true,
+		},
+		"enabled, deadline expired": {
+			enabled:            true,
+			timeout:            "10s",
+			elapsedBeforePush:  time.Minute,
+			expectedStatusCode: http.StatusRequestTimeout,
+			expectedDiscarded:  1,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			now := time.Now()
+			mtime.NowForce(now)
+			t.Cleanup(mtime.NowReset)
+
+			ds, ingesters, regs, _ := prepare(t, prepConfig{
+				numIngesters:      1,
+				happyIngesters:    1,
+				numDistributors:   1,
+				replicationFactor: 1,
+				configure: func(cfg *Config) {
+					cfg.ClientDeadlineEnabled = tc.enabled
+					cfg.RemoteTimeout = time.Minute
+				},
+			})
+
+			var (
+				ingesterDeadlinesMx sync.Mutex
+				ingesterDeadlines   []time.Duration
+			)
+			ingesters[0].registerBeforePushHook(func(ctx context.Context, _ *mimirpb.WriteRequest) (*mimirpb.WriteResponse, error, bool) {
+				if deadline, ok := ctx.Deadline(); ok {
+					ingesterDeadlinesMx.Lock()
+					ingesterDeadlines = append(ingesterDeadlines, time.Until(deadline))
+					ingesterDeadlinesMx.Unlock()
+				}
+				return nil, nil, false
+			})
+
+			// Simulate the time spent before the request is processed by the distributor.
+			push := func(ctx context.Context, req *Request) error {
+				mtime.NowForce(now.Add(tc.elapsedBeforePush))
+				return ds[0].PushWithMiddlewares(ctx, req)
+			}
+			handler := Handler(100000, nil, nil, false, validation.MockDefaultOverrides(), RetryConfig{}, push, newPushMetrics(nil), log.NewNopLogger())
+
+			data, err := makeWriteRequest(now.UnixMilli(), 1, 0, false, false, "foo").Marshal()
+			require.NoError(t, err)
+
+			req := createRequest(t, data)
+			if tc.timeout != "" {
+				req.Header.Set(RequestTimeoutHeader, tc.timeout)
+			}
+			resp := httptest.NewRecorder()
+			handler.ServeHTTP(resp, req)
+			require.Equal(t, tc.expectedStatusCode, resp.Code, resp.Body.String())
+
+			ingesterDeadlinesMx.Lock()
+			defer ingesterDeadlinesMx.Unlock()
+
+			if tc.expectedIngested {
+				assert.Len(t, ingesters[0].series(), 1)
+				assert.Len(t, ingesterDeadlines, 1)
+			} else {
+				assert.Empty(t, ingesters[0].series())
+				assert.Empty(t, ingesterDeadlines)
+			}
+
+			for _, remaining := range ingesterDeadlines {
+				if tc.expectedHasDeadline {
+					// The timeout of the request to the ingester is capped to the client deadline.
+					assert.LessOrEqual(t, remaining, 10*time.Second)
+				} else {
+					assert.Greater(t, remaining, 10*time.Second)
+				}
+			}
+
+			assert.NoError(t, testutil.GatherAndCompare(regs[0], strings.NewReader(discardedRequestsMetric(tc.expectedDiscarded)), "cortex_discarded_requests_total"))
+		})
+	}
+}
+
+func discardedRequestsMetric(count int) string {
+	if count == 0 {
+		return ""
+	}
+	return fmt.Sprintf(`
+		# HELP cortex_discarded_requests_total The total number of requests that were discarded due to rate limiting.
+		# TYPE cortex_discarded_requests_total counter
+		cortex_discarded_requests_total{reason="client_deadline_exceeded",user="test"} %d
+	`, count)
+}
@@ -65,6 +65,8 @@ var (
 	// Validation errors.
 	errInvalidTenantShardSize = errors.New("invalid tenant shard size, the value must be greater than or equal to zero")
 
+	errInvalidIngestionRateGossipUpdatePeriod = errors.New("invalid ingestion rate gossip update period, the value must be greater than zero and not greater than 1m")
+
 	reasonDistributorMaxIngestionRate             = globalerror.DistributorMaxIngestionRate.LabelValue()
 	reasonDistributorMaxInflightPushRequests      = globalerror.DistributorMaxInflightPushRequests.LabelValue()
 	reasonDistributorMaxInflightPushRequestsBytes = globalerror.DistributorMaxInflightPushRequestsBytes.LabelValue()
@@ -113,6 +115,13 @@ type Distributor struct {
 	requestRateLimiter   *limiter.RateLimiter
 	ingestionRateLimiter *limiter.RateLimiter
 
+	// ingestionRateGossip is used to share the per-tenant ingestion rates with the other distributors.
+	// It's nil if the ingestion rate gossip is disabled.
+	ingestionRateGossip *ingestionRateGossip
+
+	// Periodically writes and reads a synthetic series, if enabled.
+	canary *canary
+
 	// Manager for subservices (HA Tracker, distributor ring and client pool)
 	subservices        *services.Manager
 	subservicesWatcher *services.FailureWatcher
@@ -146,10 +155,14 @@ type Distributor struct {
 	// Metrics for data rejected for hitting per-tenant limits
 	discardedSamplesTooManyHaClusters *prometheus.CounterVec
 	discardedSamplesRateLimited       *prometheus.CounterVec
+	discardedSamplesBlockedSeries     *prometheus.CounterVec
 	discardedRequestsRateLimited      *prometheus.CounterVec
 	discardedExemplarsRateLimited     *prometheus.CounterVec
 	discardedMetadataRateLimited      *prometheus.CounterVec
 
+	// Metrics for requests rejected because the client deadline has expired
+	discardedRequestsClientDeadlineExceeded *prometheus.CounterVec
+
 	// Metrics for data rejected for hitting per-instance limits
 	rejectedRequests *prometheus.CounterVec
 
@@ -157,6 +170,8 @@ type Distributor struct {
 	exemplarValidationMetrics *exemplarValidationMetrics
 	metadataValidationMetrics *metadataValidationMetrics
 
+	metricRegistry *metricRegistry
+
 	// Metrics to be passed to distributor push handlers
 	PushMetrics *PushMetrics
 
@@ -186,6 +201,7 @@ type Config struct {
 
 	RetryConfig     RetryConfig     `yaml:"retry_after_header"`
 	HATrackerConfig HATrackerConfig `yaml:"ha_tracker"`
+	CanaryConfig    CanaryConfig    `yaml:"canary"`
 
 	MaxRecvMsgSize           int           `yaml:"max_recv_msg_size" category:"advanced"`
 	MaxOTLPRequestSize       int           `yaml:"max_otlp_request_size" category:"experimental"`
@@ -226,6 +242,11 @@ type Config struct {
 
 	// DirectOTLPTranslationEnabled allows reverting to the older way of translating from OTLP write requests via Prometheus, in case of problems.
 	DirectOTLPTranslationEnabled bool `yaml:"direct_otlp_translation_enabled" category:"experimental"`
+
+	IngestionRateGossipEnabled      bool          `yaml:"ingestion_rate_gossip_enabled" category:"experimental"`
+	IngestionRateGossipUpdatePeriod time.Duration `yaml:"ingestion_rate_gossip_update_period" category:"experimental"`
+
+	ClientDeadlineEnabled bool `yaml:"client_deadline_enabled" category:"experimental"`
 }
 
 // PushWrapper wraps around a push. It is similar to middleware.Interface.
@@ -235,6 +256,7 @@ type PushWrapper func(next PushFunc) PushFunc
 func (cfg *Config) RegisterFlags(f *flag.FlagSet, logger log.Logger) {
 	cfg.PoolConfig.RegisterFlags(f)
 	cfg.HATrackerConfig.RegisterFlags(f)
+	cfg.CanaryConfig.RegisterFlags(f)
 	cfg.DistributorRing.RegisterFlags(f, logger)
 	cfg.RetryConfig.RegisterFlags(f)
 
@@ -245,6 +267,9 @@ func (cfg *Config) RegisterFlags(f *flag.FlagSet, logger log.Logger) {
 	f.BoolVar(&cfg.WriteRequestsBufferPoolingEnabled, "distributor.write-requests-buffer-pooling-enabled", true, "Enable pooling of buffers used for marshaling write requests.")
 	f.IntVar(&cfg.ReusableIngesterPushWorkers, "distributor.reusable-ingester-push-workers", 2000, "Number of pre-allocated workers used to forward push requests to the ingesters. If 0, no workers will be used and a new goroutine will be spawned for each ingester push request. If not enough workers available, new goroutine will be spawned. (Note: this is a performance optimization, not a limiting feature.)")
 	f.BoolVar(&cfg.DirectOTLPTranslationEnabled, "distributor.direct-otlp-translation-enabled", true, "When enabled, OTLP write requests are directly translated to Mimir equivalents, for optimum performance.")
+	f.BoolVar(&cfg.IngestionRateGossipEnabled, "distributor.ingestion-rate-gossip-enabled", false, "When enabled, distributors share the per-tenant ingestion rate they receive through the distributors ring KV store, and split each tenant's ingestion rate limit proportionally to the rate received by each distributor, instead of evenly. This avoids throttling tenants below their limit when the traffic isn't evenly balanced across distributors.")
+	f.DurationVar(&cfg.IngestionRateGossipUpdatePeriod, "distributor.ingestion-rate-gossip-update-period", 5*time.Second, "How frequently each distributor publishes the per-tenant ingestion rate it receives, when -distributor.ingestion-rate-gossip-enabled is true.")
+	f.BoolVar(&cfg.ClientDeadlineEnabled, "distributor.client-deadline-enabled", false, "When enabled, the distributor honors the deadline set by remote-write and OTLP clients through the "+RequestTimeoutHeader+" HTTP header. Requests whose deadline has expired are rejected before being processed or sent to ingesters, and the timeout of the requests to ingesters is capped to the time left before the deadline.")
 
 	cfg.DefaultLimits.RegisterFlags(f)
 }
@@ -255,9 +280,16 @@ func (cfg *Config) Validate(limits validation.Limits) error {
 		return errInvalidTenantShardSize
 	}
 
+	if cfg.IngestionRateGossipEnabled && (cfg.IngestionRateGossipUpdatePeriod <= 0 || cfg.IngestionRateGossipUpdatePeriod > time.Minute) {
+		return errInvalidIngestionRateGossipUpdatePeriod
+	}
+
 	if err := cfg.HATrackerConfig.Validate(); err != nil {
 		return err
 	}
+	if err := cfg.CanaryConfig.Validate(); err != nil {
+		return err
+	}
 	return cfg.RetryConfig.Validate()
 }
 
@@ -418,10 +450,13 @@ func New(cfg Config, clientConfig ingester_client.Config, limits *validation.Ove
 
 		discardedSamplesTooManyHaClusters: validation.DiscardedSamplesCounter(reg, reasonTooManyHAClusters),
 		discardedSamplesRateLimited:       validation.DiscardedSamplesCounter(reg, reasonRateLimited),
+		discardedSamplesBlockedSeries:     validation.DiscardedSamplesCounter(reg, reasonBlockedSeries),
 		discardedRequestsRateLimited:      validation.DiscardedRequestsCounter(reg, reasonRateLimited),
 		discardedExemplarsRateLimited:     validation.DiscardedExemplarsCounter(reg, reasonRateLimited),
 		discardedMetadataRateLimited:      validation.DiscardedMetadataCounter(reg, reasonRateLimited),
 
+		discardedRequestsClientDeadlineExceeded: validation.DiscardedRequestsCounter(reg, reasonClientDeadlineExceeded),
+
 		rejectedRequests: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
 			Name: "cortex_distributor_instance_rejected_requests_total",
 			Help: "Requests discarded for hitting per-instance limits",
@@ -430,6 +465,7 @@ func New(cfg Config, clientConfig ingester_client.Config, limits *validation.Ove
 		sampleValidationMetrics:   newSampleValidationMetrics(reg),
 		exemplarValidationMetrics: newExemplarValidationMetrics(reg),
 		metadataValidationMetrics: newMetadataValidationMetrics(reg),
+		metricRegistry:            newMetricRegistry(limits, reg),
 
 		hashCollisionCount: promauto.With(reg).NewCounter(prometheus.CounterOpts{
 			Name: "cortex_distributor_hash_collisions_total",
@@ -507,6 +543,17 @@ func New(cfg Config, clientConfig ingester_client.Config, limits *validation.Ove
 		subservices = append(subservices, distributorsLifecycler, distributorsRing)
 		requestRateStrategy = newGlobalRateStrategy(newRequestRateStrategy(limits), d)
 		ingestionRateStrategy = newGlobalRateStrategyWithBurstFactor(limits, d)
+
+		if cfg.IngestionRateGossipEnabled {
+			kvStore, err := kv.NewClient(cfg.DistributorRing.Common.KVStore, GetIngestionRatesCodec(), kv.RegistererWithKVName(prometheus.WrapRegistererWithPrefix("cortex_", reg), "distributor-ingestion-rates"), log)
+			if err != nil {
+				return nil, errors.Wrap(err, "failed to initialize distributors' ingestion rates KV store")
+			}
+
+			d.ingestionRateGossip = newIngestionRateGossip(cfg.DistributorRing.Common.InstanceID, cfg.IngestionRateGossipUpdatePeriod, kvStore, log)
+			subservices = append(subservices, d.ingestionRateGossip)
+			ingestionRateStrategy = newGossipIngestionRateStrategy(limits, d, d.ingestionRateGossip)
+		}
 	}
 
 	d.requestRateLimiter = limiter.NewRateLimiter(requestRateStrategy, 10*time.Second)
@@ -521,6 +568,15 @@ func New(cfg Config, clientConfig ingester_client.Config, limits *validation.Ove
 
 	subservices = append(subservices, d.ingesterPool, d.activeUsers)
 
+	if cfg.CanaryConfig.Enabled {
+		canaryQuery, err := newCanaryHTTPQueryFunc(cfg.CanaryConfig.QueryAddress.String())
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to create the canary query client")
+		}
+		d.canary = newCanary(cfg.CanaryConfig, cfg.DistributorRing.Common.InstanceID, d.PushWithMiddlewares, canaryQuery, log, reg)
+		subservices = append(subservices, d.canary)
+	}
+
 	if cfg.ReusableIngesterPushWorkers > 0 {
 		wp := concurrency.NewReusableGoroutinesPool(cfg.ReusableIngesterPushWorkers)
 		d.doBatchPushWorkers = wp.Go
@@ -662,20 +718,25 @@ func (d *Distributor) cleanupInactiveUser(userID string) {
 	d.dedupedSamples.DeletePartialMatch(filter)
 	d.discardedSamplesTooManyHaClusters.DeletePartialMatch(filter)
 	d.discardedSamplesRateLimited.DeletePartialMatch(filter)
+	d.discardedSamplesBlockedSeries.DeletePartialMatch(filter)
 	d.discardedRequestsRateLimited.DeleteLabelValues(userID)
 	d.discardedExemplarsRateLimited.DeleteLabelValues(userID)
 	d.discardedMetadataRateLimited.DeleteLabelValues(userID)
+	d.discardedRequestsClientDeadlineExceeded.DeleteLabelValues(userID)
 
 	d.sampleValidationMetrics.deleteUserMetrics(userID)
 	d.exemplarValidationMetrics.deleteUserMetrics(userID)
 	d.metadataValidationMetrics.deleteUserMetrics(userID)
+	d.metricRegistry.deleteUser(userID)
 }
 
 func (d *Distributor) RemoveGroupMetricsForUser(userID, group string) {
 	d.dedupedSamples.DeleteLabelValues(userID, group)
 	d.discardedSamplesTooManyHaClusters.DeleteLabelValues(userID, group)
 	d.discardedSamplesRateLimited.DeleteLabelValues(userID, group)
+	d.discardedSamplesBlockedSeries.DeleteLabelValues(userID, group)
 	d.sampleValidationMetrics.deleteUserMetricsForGroup(userID, group)
+	d.metricRegistry.discardedSamples.DeleteLabelValues(userID, group)
 }
 
 // Called after distributor is asked to stop via StopAsync.
@@ -714,12 +775,27 @@ func (d *Distributor) checkSample(ctx context.Context, userID, cluster, replica
 // The returned error may retain the series labels.
 // It uses the passed nowt time to observe the delay of sample timestamps.
 func (d *Distributor) validateSeries(nowt time.Time, ts *mimirpb.PreallocTimeseries, userID, group string, skipLabelNameValidation bool, minExemplarTS, maxExemplarTS int64) error {
+	if d.limits.TruncateLabelValueOverMaxLength(userID) && truncateLabelValues(d.sampleValidationMetrics, d.limits, userID, group, ts.Labels) {
+		// We can't reuse the raw unmarshalled data of the timeseries after changing its labels.
+		ts.SetLabels(ts.Labels)
+	}
+
 	if err := validateLabels(d.sampleValidationMetrics, d.limits, userID, group, ts.Labels, skipLabelNameValidation); err != nil {
 		return err
 	}
 
 	now := model.TimeFromUnixNano(nowt.UnixNano())
 
+	if maxSkew := d.limits.MaxTimestampSkewCorrection(userID); maxSkew > 0 {
+		samplesUpdated, histogramsUpdated := correctTimestampSkew(d.sampleValidationMetrics, now, d.limits, userID, group, ts, maxSkew)
+		if samplesUpdated {
+			ts.SamplesUpdated()
+		}
+		if histogramsUpdated {
+			ts.HistogramsUpdated()
+		}
+	}
+
 	for _, s := range ts.Samples {
 		if err := validateSample(d.sampleValidationMetrics, now, d.limits, userID, group, ts.Labels, s); err != nil {
 			return err
@@ -794,9 +870,13 @@ func (d *Distributor) wrapPushWithMiddlewares(next PushFunc) PushFunc {
 	// The middlewares will be applied to the request (!) in the specified order, from first to last.
 	// To guarantee that, middleware functions will be called in reversed order, wrapping the
 	// result from previous call.
+	if d.cfg.ClientDeadlineEnabled {
+		middlewares = append(middlewares, d.clientDeadlineMiddleware) // Runs before any other middleware, to not process requests the client isn't waiting for anymore.
+	}
 	middlewares = append(middlewares, d.limitsMiddleware) // Should run first because it checks limits before other middlewares need to read the request body.
 	middlewares = append(middlewares, d.metricsMiddleware)
 	middlewares = append(middlewares, d.prePushHaDedupeMiddleware)
+	middlewares = append(middlewares, d.prePushBlockedSeriesMiddleware)
 	middlewares = append(middlewares, d.prePushRelabelMiddleware)
 	middlewares = append(middlewares, d.prePushSortAndFilterMiddleware)
 	middlewares = append(middlewares, d.prePushValidationMiddleware)
@@ -1039,6 +1119,10 @@ func (d *Distributor) prePushValidationMiddleware(next PushFunc) PushFunc {
 		var removeIndexes []int
 		totalSamples, totalExemplars := 0, 0
 
+		// The metric registry index is nil if the tenant has no metric registry.
+		registryIdx := d.metricRegistry.index(userID)
+		enforceRegistry := registryIdx != nil && d.limits.MetricRegistryEnforcementEnabled(userID)
+
 		labelValuesWithNewlines := 0
 		for tsIdx, ts := range req.Timeseries {
 			totalSamples += len(ts.Samples)
@@ -1054,6 +1138,10 @@ func (d *Distributor) prePushValidationMiddleware(next PushFunc) PushFunc {
 			// Note that validateSeries may drop some data in ts.
 			validationErr := d.validateSeries(now, &req.Timeseries[tsIdx], userID, group, skipLabelNameValidation, minExemplarTS, maxExemplarTS)
 
+			if validationErr == nil && registryIdx != nil {
+				validationErr = d.metricRegistry.validateSeries(userID, group, registryIdx, enforceRegistry, req.Timeseries[tsIdx])
+			}
+
 			// Errors in validation are considered non-fatal, as one series in a request may contain
 			// invalid data but all the remaining series could be perfectly valid.
 			if validationErr != nil {
@@ -1085,7 +1173,11 @@ func (d *Distributor) prePushValidationMiddleware(next PushFunc) PushFunc {
 		}
 
 		for mIdx, m := range req.Metadata {
-			if validationErr := cleanAndValidateMetadata(d.metadataValidationMetrics, d.limits, userID, m); validationErr != nil {
+			validationErr := cleanAndValidateMetadata(d.metadataValidationMetrics, d.limits, userID, m)
+			if validationErr == nil && registryIdx != nil {
+				validationErr = d.metricRegistry.validateMetadata(userID, registryIdx, enforceRegistry, m)
+			}
+			if validationErr != nil {
 				if firstPartialErr == nil {
 					// The series are never retained by validationErr. This is guaranteed by the way the latter is built.
 					firstPartialErr = newValidationError(validationErr)
@@ -1106,6 +1198,10 @@ func (d *Distributor) prePushValidationMiddleware(next PushFunc) PushFunc {
 		}
 
 		totalN := validatedSamples + validatedExemplars + validatedMetadata
+		if d.ingestionRateGossip != nil {
+			// Track the received rate, including the rate limited data, to split the limit according to the demand.
+			d.ingestionRateGossip.record(userID, totalN)
+		}
 		if !d.ingestionRateLimiter.AllowN(now, userID, totalN) {
 			d.discardedSamplesRateLimited.WithLabelValues(userID, group).Add(float64(validatedSamples))
 			d.discardedExemplarsRateLimited.WithLabelValues(userID).Add(float64(validatedExemplars))
@@ -1470,6 +1566,13 @@ func (d *Distributor) push(ctx context.Context, pushReq *Request) error {
 		ingestersSubring = d.ingestersRing.ShuffleShard(userID, d.limits.IngestionTenantShardSize(userID))
 	}
 
+	// Do not send the request to the backends if the client isn't waiting for the response anymore.
+	if deadline, ok := clientDeadlineFromContext(ctx); ok && d.cfg.ClientDeadlineEnabled {
+		if err := d.checkClientDeadline(ctx, deadline); err != nil {
+			return err
+		}
+	}
+
 	// we must not re-use buffers now until all writes to backends (e.g. ingesters) have completed, which can happen
 	// even after this function returns. For this reason, it's unsafe to cleanup in the defer and we'll do the cleanup
 	// once all backend requests have completed (see cleanup function passed to sendWriteRequestToBackends()).
@@ -1500,7 +1603,7 @@ func (d *Distributor) sendWriteRequestToBackends(ctx context.Context, tenantID s
 	// It will still take a while to lookup the ring and calculate which instance gets which series,
 	// so we'll start the remote timeout once the first callback is called.
 	remoteRequestContextAndCancel := sync.OnceValues(func() (context.Context, context.CancelFunc) {
-		return context.WithTimeout(context.WithoutCancel(ctx), d.cfg.RemoteTimeout)
+		return context.WithTimeout(context.WithoutCancel(ctx), d.remoteTimeout(ctx))
 	})
 
 	remoteRequestContext := func() context.Context {
@@ -19,6 +19,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/cespare/xxhash/v2"
 	"github.com/go-kit/log"
 	"github.com/gogo/status"
 	"github.com/grafana/dskit/flagext"
@@ -1287,6 +1288,56 @@ func TestDistributor_Push_LabelNameValidation(t *testing.T) {
 	}
 }
 
+func TestDistributor_Push_LabelValueTruncation(t *testing.T) {
+	ctx := user.InjectOrgID(context.Background(), "user")
+	inputLabels := labels.FromStrings(model.MetricNameLabel, "foo", "bar", strings.Repeat("x", 30))
+
+	tests := map[string]struct {
+		truncate       bool
+		expectedErr    string
+		expectedLabels labels.Labels
+	}{
+		"label value over the limit is rejected by default": {
+			expectedErr: "received a series whose label value length exceeds the limit",
+		},
+		"label value over the limit is truncated if enabled": {
+			truncate:       true,
+			expectedLabels: labels.FromStrings(model.MetricNameLabel, "foo", "bar", fmt.Sprintf("xxxxx(truncated:%08x)", uint32(xxhash.Sum64String(strings.Repeat("x", 30))))),
+		},
+	}
+
+	for testName, tc := range tests {
+		t.Run(testName, func(t *testing.T) {
+			limits := prepareDefaultLimits()
+			limits.MaxLabelValueLength = 25
+			limits.TruncateLabelValueOverMaxLength = tc.truncate
+
+			ds, ingesters, _, _ := prepare(t, prepConfig{
+				numIngesters:    2,
+				happyIngesters:  2,
+				numDistributors: 1,
+				limits:          limits,
+			})
+
+			_, err := ds[0].Push(ctx, mockWriteRequest(inputLabels, 1, 1))
+			if tc.expectedErr != "" {
+				fromError, _ := grpcutil.ErrorToStatus(err)
+				assert.Contains(t, fromError.Message(), tc.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+
+			for i := range ingesters {
+				timeseries := ingesters[i].series()
+				require.Len(t, timeseries, 1)
+				for _, series := range timeseries {
+					assert.Equal(t, tc.expectedLabels, mimirpb.FromLabelAdaptersToLabels(series.Labels))
+				}
+			}
+		})
+	}
+}
+
 func TestDistributor_Push_ExemplarValidation(t *testing.T) {
 	ctx := user.InjectOrgID(context.Background(), "user")
 	manyLabels := []string{model.MetricNameLabel, "test"}
@@ -5975,7 +6026,7 @@ func (i *mockIngester) QueryStream(ctx context.Context, req *client.QueryRequest
 			}
 		}
 
-		if i.disableStreamingResponse || req.StreamingChunksBatchSize == 0 {
+		if i.disableStreamingResponse {
 			nonStreamingResponses = append(nonStreamingResponses, &client.QueryStreamResponse{
 				Chunkseries: []client.TimeSeriesChunk{
 					{
@@ -0,0 +1,235 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package distributor
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/grafana/dskit/kv"
+	"github.com/grafana/dskit/kv/codec"
+	"github.com/grafana/dskit/services"
+	"golang.org/x/time/rate"
+
+	"github.com/grafana/mimir/pkg/util/instancestate"
+	util_math "github.com/grafana/mimir/pkg/util/math"
+	"github.com/grafana/mimir/pkg/util/validation"
+)
+
+const (
+	// ingestionRatesKey is the KV store key holding the per-tenant ingestion rates observed by each distributor.
+	ingestionRatesKey = "distributor-ingestion-rates"
+
+	// ingestionRatesMinRate is the rate below which a tenant is not published anymore.
+	ingestionRatesMinRate = 0.01
+)
+
+// IngestionRatesDesc holds the per-tenant ingestion rates observed by each distributor.
+type IngestionRatesDesc = instancestate.Desc[InstanceIngestionRates]
+
+// InstanceIngestionRates holds the per-tenant ingestion rates observed by a distributor.
+type InstanceIngestionRates struct {
+	// Timestamp is the unix timestamp, in milliseconds, of the last update.
+	Timestamp int64 `json:"timestamp"`
+
+	// Rates is the per-tenant rate of samples, exemplars and metadata received by the distributor, per second.
+	Rates map[string]float64 `json:"rates"`
+}
+
+// GetTimestamp implements instancestate.State.
+func (r InstanceIngestionRates) GetTimestamp() int64 {
+	return r.Timestamp
+}
+
+// Clone implements instancestate.State.
+func (r InstanceIngestionRates) Clone() InstanceIngestionRates {
+	rates := make(map[string]float64, len(r.Rates))
+	for userID, rate := range r.Rates {
+		rates[userID] = rate
+	}
+	return InstanceIngestionRates{Timestamp: r.Timestamp, Rates: rates}
+}
+
+// GetIngestionRatesCodec returns the codec used to store the IngestionRatesDesc in the KV store.
+func GetIngestionRatesCodec() codec.Codec {
+	return instancestate.NewCodec[InstanceIngestionRates]("ingestionRatesDesc")
+}
+
+// ingestionRateGossip tracks the per-tenant ingestion rate received by this distributor, periodically
+// publishes it to the KV store and keeps track of the rates published by the other distributors.
+type ingestionRateGossip struct {
+	services.Service
+
+	instanceID   string
+	updatePeriod time.Duration
+	client       kv.Client
+	logger       log.Logger
+
+	localMtx sync.Mutex
+	local    map[string]*util_math.EwmaRate
+
+	remoteMtx sync.RWMutex
+	remote    *IngestionRatesDesc
+}
+
+func newIngestionRateGossip(instanceID string, updatePeriod time.Duration, client kv.Client, logger log.Logger) *ingestionRateGossip {
+	g := &ingestionRateGossip{
+		instanceID:   instanceID,
+		updatePeriod: updatePeriod,
+		client:       client,
+		logger:       logger,
+		local:        map[string]*util_math.EwmaRate{},
+		remote:       instancestate.NewDesc[InstanceIngestionRates](),
+	}
+
+	g.Service = services.NewBasicService(nil, g.running, nil)
+	return g
+}
+
+// record counts n samples, exemplars or metadata received for the tenant.
+func (g *ingestionRateGossip) record(userID string, n int) {
+	g.localMtx.Lock()
+	r, ok := g.local[userID]
+	if !ok {
+		r = util_math.NewEWMARate(0.2, g.updatePeriod)
+		g.local[userID] = r
+	}
+	g.localMtx.Unlock()
+
+	r.Add(int64(n))
+}
+
+// rates returns the rate received for the tenant by this distributor, and the sum of the rates received
+// for the tenant by all distributors.
+func (g *ingestionRateGossip) rates(userID string) (local, total float64) {
+	g.localMtx.Lock()
+	if r, ok := g.local[userID]; ok {
+		local = r.Rate()
+	}
+	g.localMtx.Unlock()
+
+	total = local
+	minTimestamp := time.Now().Add(-3 * g.updatePeriod).UnixMilli()
+
+	g.remoteMtx.RLock()
+	defer g.remoteMtx.RUnlock()
+
+	for id, entry := range g.remote.Instances {
+		// Ignore our own entry, which is older than the local rate, and the entries of distributors
+		// which stopped publishing their rates.
+		if id == g.instanceID || entry.Timestamp < minTimestamp {
+			continue
+		}
+		total += entry.Rates[userID]
+	}
+
+	return local, total
+}
+
+func (g *ingestionRateGossip) running(ctx context.Context) error {
+	go g.client.WatchKey(ctx, ingestionRatesKey, func(value interface{}) bool {
+		desc, ok := value.(*IngestionRatesDesc)
+		if !ok || desc == nil {
+			return true
+		}
+
+		g.remoteMtx.Lock()
+		g.remote = desc.Clone().(*IngestionRatesDesc)
+		g.remoteMtx.Unlock()
+		return true
+	})
+
+	ticker := time.NewTicker(g.updatePeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := g.publish(ctx, g.tick()); err != nil {
+				level.Warn(g.logger).Log("msg", "failed to publish ingestion rates to the KV store", "err", err)
+			}
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// tick updates the local rates and returns them. Tenants which stopped sending data are removed.
+func (g *ingestionRateGossip) tick() map[string]float64 {
+	g.localMtx.Lock()
+	defer g.localMtx.Unlock()
+
+	res := make(map[string]float64, len(g.local))
+	for userID, r := range g.local {
+		r.Tick()
+
+		if rate := r.Rate(); rate >= ingestionRatesMinRate {
+			res[userID] = rate
+		} else {
+			delete(g.local, userID)
+		}
+	}
+	return res
+}
+
+func (g *ingestionRateGossip) publish(ctx context.Context, rates map[string]float64) error {
+	return g.client.CAS(ctx, ingestionRatesKey, func(in interface{}) (out interface{}, retry bool, err error) {
+		desc, ok := in.(*IngestionRatesDesc)
+		if !ok || desc == nil {
+			desc = instancestate.NewDesc[InstanceIngestionRates]()
+		}
+
+		now := time.Now()
+		desc.Set(g.instanceID, InstanceIngestionRates{Timestamp: now.UnixMilli(), Rates: rates}, now)
+
+		return desc, true, nil
+	})
+}
+
+// gossipIngestionRateStrategy splits the tenant's ingestion rate limit across distributors proportionally
+// to the rate each distributor receives, based on the rates gossiped by all distributors. Unlike
+// globalIngestionStrategyWithBurstFactor, the sum of the limits enforced by all distributors is the tenant's
+// limit even when the traffic isn't evenly balanced across distributors.
+type gossipIngestionRateStrategy struct {
+	limits *validation.Overrides
+	ring   ReadLifecycler
+	rates  *ingestionRateGossip
+}
+
+func newGossipIngestionRateStrategy(limits *validation.Overrides, ring ReadLifecycler, rates *ingestionRateGossip) *gossipIngestionRateStrategy {
+	return &gossipIngestionRateStrategy{
+		limits: limits,
+		ring:   ring,
+		rates:  rates,
+	}
+}
+
+func (s *gossipIngestionRateStrategy) Limit(tenantID string) float64 {
+	limit := s.limits.IngestionRate(tenantID)
+	numDistributors := s.ring.HealthyInstancesCount()
+
+	if numDistributors == 0 || limit == float64(rate.Inf) {
+		return limit
+	}
+
+	local, total := s.rates.rates(tenantID)
+	if total <= 0 {
+		// Fallback to an even split until the rates are known.
+		return limit / float64(numDistributors)
+	}
+
+	if total <= limit {
+		// Each distributor can grow by an even share of the unused limit.
+		return local + (limit-total)/float64(numDistributors)
+	}
+
+	// The tenant is over the limit: split the limit proportionally to the received rate.
+	return limit * local / total
+}
+
+func (s *gossipIngestionRateStrategy) Burst(tenantID string) int {
+	return ingestionBurst(s.limits, tenantID, s.Limit)
+}
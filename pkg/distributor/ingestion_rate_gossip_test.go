@@ -0,0 +1,116 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package distributor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/dskit/kv/consul"
+	"github.com/grafana/dskit/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/mimir/pkg/util/validation"
+)
+
+func TestIngestionRatesCodec(t *testing.T) {
+	desc := &IngestionRatesDesc{Instances: map[string]InstanceIngestionRates{
+		"distributor-1": {Timestamp: 1000, Rates: map[string]float64{"user-1": 10.5}},
+	}}
+
+	c := GetIngestionRatesCodec()
+	data, err := c.Encode(desc)
+	require.NoError(t, err)
+
+	decoded, err := c.Decode(data)
+	require.NoError(t, err)
+	assert.Equal(t, desc, decoded)
+}
+
+func TestGossipIngestionRateStrategy(t *testing.T) {
+	overrides, err := validation.NewOverrides(validation.Limits{
+		IngestionRate:        1000,
+		IngestionBurstFactor: 2,
+	}, nil)
+	require.NoError(t, err)
+
+	mockRing := newReadLifecyclerMock()
+	mockRing.On("HealthyInstancesCount").Return(2)
+
+	kvStore, closer := consul.NewInMemoryClient(GetIngestionRatesCodec(), log.NewNopLogger(), nil)
+	t.Cleanup(func() { assert.NoError(t, closer.Close()) })
+
+	const updatePeriod = 100 * time.Millisecond
+	gossip1 := newIngestionRateGossip("distributor-1", updatePeriod, kvStore, log.NewNopLogger())
+	gossip2 := newIngestionRateGossip("distributor-2", updatePeriod, kvStore, log.NewNopLogger())
+	for _, g := range []*ingestionRateGossip{gossip1, gossip2} {
+		require.NoError(t, services.StartAndAwaitRunning(context.Background(), g))
+		t.Cleanup(func() {
+			require.NoError(t, services.StopAndAwaitTerminated(context.Background(), g))
+		})
+	}
+
+	strategy1 := newGossipIngestionRateStrategy(overrides, mockRing, gossip1)
+	strategy2 := newGossipIngestionRateStrategy(overrides, mockRing, gossip2)
+
+	// The limit is evenly split until the rates are known.
+	assert.Equal(t, float64(500), strategy1.Limit("user-1"))
+	assert.Equal(t, float64(500), strategy2.Limit("user-1"))
+	assert.Equal(t, 1000, strategy1.Burst("user-1"))
+
+	// Simulate 1200 samples/sec received by distributor-1 and 300 samples/sec received by distributor-2.
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+
+		ticker := time.NewTicker(updatePeriod / 10)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				gossip1.record("user-1", 120/10)
+				gossip2.record("user-1", 30/10)
+			}
+		}
+	}()
+	t.Cleanup(func() {
+		close(stop)
+		<-done
+	})
+
+	// The limit is split proportionally to the received rate, and the sum of the limits is the tenant's limit.
+	require.Eventually(t, func() bool {
+		limit1, limit2 := strategy1.Limit("user-1"), strategy2.Limit("user-1")
+		return limit1 > 700 && limit2 > 100 && limit2 < 300 && limit1+limit2 > 999 && limit1+limit2 < 1001
+	}, 10*time.Second, updatePeriod)
+
+	// Other tenants are not affected.
+	assert.Equal(t, float64(500), strategy1.Limit("user-2"))
+}
+
+func TestGossipIngestionRateStrategy_ShouldShareUnusedLimit(t *testing.T) {
+	overrides, err := validation.NewOverrides(validation.Limits{IngestionRate: 1000}, nil)
+	require.NoError(t, err)
+
+	mockRing := newReadLifecyclerMock()
+	mockRing.On("HealthyInstancesCount").Return(2)
+
+	gossip := newIngestionRateGossip("distributor-1", time.Second, nil, log.NewNopLogger())
+	gossip.record("user-1", 100)
+	gossip.tick()
+	gossip.remote = &IngestionRatesDesc{Instances: map[string]InstanceIngestionRates{
+		"distributor-1": {Timestamp: time.Now().UnixMilli(), Rates: map[string]float64{"user-1": 1000}},
+		"distributor-2": {Timestamp: time.Now().UnixMilli(), Rates: map[string]float64{"user-1": 300}},
+		"distributor-3": {Timestamp: time.Now().Add(-time.Minute).UnixMilli(), Rates: map[string]float64{"user-1": 1000}},
+	}}
+
+	// The own published entry and the stale entries are ignored. The total rate is 400, so
+	// the distributor can grow by half of the 600 unused.
+	assert.Equal(t, float64(100+300), newGossipIngestionRateStrategy(overrides, mockRing, gossip).Limit("user-1"))
+}
@@ -0,0 +1,347 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package distributor
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/grafana/dskit/tenant"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/labels"
+
+	"github.com/grafana/mimir/pkg/mimirpb"
+	"github.com/grafana/mimir/pkg/util"
+	"github.com/grafana/mimir/pkg/util/extract"
+	"github.com/grafana/mimir/pkg/util/globalerror"
+	"github.com/grafana/mimir/pkg/util/validation"
+)
+
+const (
+	// metricRegistryMaxViolationsPerTenant is the maximum number of distinct violations tracked for a single tenant.
+	metricRegistryMaxViolationsPerTenant = 1000
+
+	metricRegistryReasonUndeclaredMetric = "undeclared_metric"
+	metricRegistryReasonLabelNotAllowed  = "label_not_allowed"
+	metricRegistryReasonTypeMismatch     = "type_mismatch"
+)
+
+var (
+	reasonMetricRegistryViolation = globalerror.MetricRegistryViolation.LabelValue()
+
+	metricRegistryUndeclaredSeriesMsgFormat = globalerror.MetricRegistryViolation.Message(
+		"received a series whose metric name is not declared in the metric registry, series: '%.200s'",
+	)
+	metricRegistryLabelNotAllowedMsgFormat = globalerror.MetricRegistryViolation.Message(
+		"received a series with a label not allowed by the metric registry, label: '%.200s' series: '%.200s'",
+	)
+	metricRegistryUndeclaredMetadataMsgFormat = globalerror.MetricRegistryViolation.Message(
+		"received a metric metadata whose metric name is not declared in the metric registry, metric name: '%.200s'",
+	)
+	metricRegistryTypeMismatchMsgFormat = globalerror.MetricRegistryViolation.Message(
+		"received a metric metadata whose type doesn't match the metric registry, metric name: '%.200s' type: '%s' expected type: '%s'",
+	)
+)
+
+// MetricRegistryViolation is a violation of the tenant's metric registry observed by a distributor.
+type MetricRegistryViolation struct {
+	MetricName string    `json:"metric_name"`
+	Reason     string    `json:"reason"`
+	Detail     string    `json:"detail,omitempty"`
+	Count      uint64    `json:"count"`
+	LastSeen   time.Time `json:"last_seen"`
+}
+
+// MetricRegistryConformanceReport is the report of the violations of the tenant's metric registry observed by a distributor.
+type MetricRegistryConformanceReport struct {
+	DeclaredMetrics    int                       `json:"declared_metrics"`
+	EnforcementEnabled bool                      `json:"enforcement_enabled"`
+	Violations         []MetricRegistryViolation `json:"violations"`
+}
+
+type metricRegistryViolationKey struct {
+	metricName string
+	reason     string
+	detail     string
+}
+
+// metricRegistry validates series and metadata against the tenants' metric registries,
+// and keeps track of the observed violations.
+type metricRegistry struct {
+	limits *validation.Overrides
+
+	mtx        sync.Mutex
+	violations map[string]map[metricRegistryViolationKey]*MetricRegistryViolation
+
+	indexesMtx sync.RWMutex
+	indexes    map[string]cachedMetricRegistryIndex
+
+	violationsTotal   *prometheus.CounterVec
+	discardedSamples  *prometheus.CounterVec
+	discardedMetadata *prometheus.CounterVec
+}
+
+func newMetricRegistry(limits *validation.Overrides, reg prometheus.Registerer) *metricRegistry {
+	return &metricRegistry{
+		limits:     limits,
+		violations: map[string]map[metricRegistryViolationKey]*MetricRegistryViolation{},
+		indexes:    map[string]cachedMetricRegistryIndex{},
+		violationsTotal: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "cortex_distributor_metric_registry_violations_total",
+			Help: "The total number of series and metadata which don't conform to the tenant's metric registry.",
+		}, []string{"user", "reason"}),
+		discardedSamples:  validation.DiscardedSamplesCounter(reg, reasonMetricRegistryViolation),
+		discardedMetadata: validation.DiscardedMetadataCounter(reg, reasonMetricRegistryViolation),
+	}
+}
+
+// metricRegistryIndex is the tenant's metric registry indexed by metric name.
+type metricRegistryIndex map[string]*validation.MetricDefinition
+
+// cachedMetricRegistryIndex is the index of a tenant's metric registry, built from the given definitions.
+type cachedMetricRegistryIndex struct {
+	definitions []*validation.MetricDefinition
+	idx         metricRegistryIndex
+}
+
+// builtFrom returns whether the index has been built from the given version of the tenant's metric registry.
+// The limits are replaced, and not modified, when the runtime config is reloaded, so comparing the backing
+// array of the definitions is enough to detect a new version.
+func (c cachedMetricRegistryIndex) builtFrom(definitions []*validation.MetricDefinition) bool {
+	return len(c.definitions) == len(definitions) && &c.definitions[0] == &definitions[0]
+}
+
+// index returns the tenant's metric registry indexed by metric name, or nil if the tenant has no metric registry.
+// The index is cached until the tenant's metric registry changes.
+func (r *metricRegistry) index(userID string) metricRegistryIndex {
+	definitions := r.limits.MetricRegistry(userID)
+	if len(definitions) == 0 {
+		return nil
+	}
+
+	r.indexesMtx.RLock()
+	cached, ok := r.indexes[userID]
+	r.indexesMtx.RUnlock()
+	if ok && cached.builtFrom(definitions) {
+		return cached.idx
+	}
+
+	idx := make(metricRegistryIndex, len(definitions))
+	for _, def := range definitions {
+		idx[def.Name] = def
+	}
+
+	r.indexesMtx.Lock()
+	r.indexes[userID] = cachedMetricRegistryIndex{definitions: definitions, idx: idx}
+	r.indexesMtx.Unlock()
+
+	return idx
+}
+
+// lookupSeries returns the definition of the metric the given series metric name belongs to,
+// taking into account the suffixes of the classic histogram and summary series.
+func (idx metricRegistryIndex) lookupSeries(metricName string) *validation.MetricDefinition {
+	if def, ok := idx[metricName]; ok {
+		return def
+	}
+
+	for _, suffix := range []string{"_bucket", "_sum", "_count"} {
+		familyName, ok := strings.CutSuffix(metricName, suffix)
+		if !ok {
+			continue
+		}
+
+		def, ok := idx[familyName]
+		if !ok {
+			continue
+		}
+
+		switch def.Type {
+		case model.MetricTypeHistogram, model.MetricTypeGaugeHistogram:
+			return def
+		case model.MetricTypeSummary:
+			if suffix != "_bucket" {
+				return def
+			}
+		}
+	}
+
+	return nil
+}
+
+// isLabelAllowed returns whether a series of the metric defined by def can have the given label name.
+func isLabelAllowed(def *validation.MetricDefinition, name string) bool {
+	if len(def.AllowedLabels) == 0 || name == labels.MetricName {
+		return true
+	}
+
+	switch {
+	case name == labels.BucketLabel && (def.Type == model.MetricTypeHistogram || def.Type == model.MetricTypeGaugeHistogram):
+		return true
+	case name == model.QuantileLabel && def.Type == model.MetricTypeSummary:
+		return true
+	}
+
+	for _, allowed := range def.AllowedLabels {
+		if name == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// validateSeries checks the series against the tenant's metric registry. If the series doesn't conform
+// to the registry the violation is tracked and, if the enforcement is enabled, an error is returned.
+func (r *metricRegistry) validateSeries(userID, group string, idx metricRegistryIndex, enforce bool, ts mimirpb.PreallocTimeseries) error {
+	metricName, err := extract.UnsafeMetricNameFromLabelAdapters(ts.Labels)
+	if err != nil {
+		// Series without metric name are rejected by the series validation.
+		return nil
+	}
+
+	def := idx.lookupSeries(metricName)
+	if def == nil {
+		r.trackViolation(userID, metricName, metricRegistryReasonUndeclaredMetric, "")
+		if !enforce {
+			return nil
+		}
+		r.discardedSamples.WithLabelValues(userID, group).Add(float64(len(ts.Samples) + len(ts.Histograms)))
+		return fmt.Errorf(metricRegistryUndeclaredSeriesMsgFormat, mimirpb.FromLabelAdaptersToString(ts.Labels))
+	}
+
+	for _, l := range ts.Labels {
+		if isLabelAllowed(def, l.Name) {
+			continue
+		}
+
+		r.trackViolation(userID, def.Name, metricRegistryReasonLabelNotAllowed, l.Name)
+		if !enforce {
+			return nil
+		}
+		r.discardedSamples.WithLabelValues(userID, group).Add(float64(len(ts.Samples) + len(ts.Histograms)))
+		return fmt.Errorf(metricRegistryLabelNotAllowedMsgFormat, l.Name, mimirpb.FromLabelAdaptersToString(ts.Labels))
+	}
+
+	return nil
+}
+
+// validateMetadata checks the metadata against the tenant's metric registry. If the metadata doesn't conform
+// to the registry the violation is tracked and, if the enforcement is enabled, an error is returned.
+func (r *metricRegistry) validateMetadata(userID string, idx metricRegistryIndex, enforce bool, metadata *mimirpb.MetricMetadata) error {
+	metricName := metadata.GetMetricFamilyName()
+	if metricName == "" {
+		// Metadata without metric name are handled by the metadata validation.
+		return nil
+	}
+
+	def, ok := idx[metricName]
+	if !ok {
+		r.trackViolation(userID, metricName, metricRegistryReasonUndeclaredMetric, "")
+		if !enforce {
+			return nil
+		}
+		r.discardedMetadata.WithLabelValues(userID).Inc()
+		return fmt.Errorf(metricRegistryUndeclaredMetadataMsgFormat, metricName)
+	}
+
+	metricType := model.MetricType(strings.ToLower(metadata.GetType().String()))
+	if def.Type != "" && metricType != def.Type {
+		r.trackViolation(userID, metricName, metricRegistryReasonTypeMismatch, string(metricType))
+		if !enforce {
+			return nil
+		}
+		r.discardedMetadata.WithLabelValues(userID).Inc()
+		return fmt.Errorf(metricRegistryTypeMismatchMsgFormat, metricName, metricType, def.Type)
+	}
+
+	return nil
+}
+
+func (r *metricRegistry) trackViolation(userID, metricName, reason, detail string) {
+	r.violationsTotal.WithLabelValues(userID, reason).Inc()
+
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	userViolations, ok := r.violations[userID]
+	if !ok {
+		userViolations = map[metricRegistryViolationKey]*MetricRegistryViolation{}
+		r.violations[userID] = userViolations
+	}
+
+	key := metricRegistryViolationKey{metricName: metricName, reason: reason, detail: detail}
+	v, ok := userViolations[key]
+	if !ok {
+		if len(userViolations) >= metricRegistryMaxViolationsPerTenant {
+			return
+		}
+		v = &MetricRegistryViolation{MetricName: metricName, Reason: reason, Detail: detail}
+		userViolations[key] = v
+	}
+
+	v.Count++
+	v.LastSeen = time.Now()
+}
+
+// report returns the conformance report of the given tenant.
+func (r *metricRegistry) report(userID string) MetricRegistryConformanceReport {
+	report := MetricRegistryConformanceReport{
+		DeclaredMetrics:    len(r.limits.MetricRegistry(userID)),
+		EnforcementEnabled: r.limits.MetricRegistryEnforcementEnabled(userID),
+		Violations:         []MetricRegistryViolation{},
+	}
+
+	r.mtx.Lock()
+	for _, v := range r.violations[userID] {
+		report.Violations = append(report.Violations, *v)
+	}
+	r.mtx.Unlock()
+
+	sort.Slice(report.Violations, func(i, j int) bool {
+		vi, vj := report.Violations[i], report.Violations[j]
+		if vi.Count != vj.Count {
+			return vi.Count > vj.Count
+		}
+		if vi.MetricName != vj.MetricName {
+			return vi.MetricName < vj.MetricName
+		}
+		if vi.Reason != vj.Reason {
+			return vi.Reason < vj.Reason
+		}
+		return vi.Detail < vj.Detail
+	})
+
+	return report
+}
+
+func (r *metricRegistry) deleteUser(userID string) {
+	r.violationsTotal.DeletePartialMatch(prometheus.Labels{"user": userID})
+	r.discardedSamples.DeletePartialMatch(prometheus.Labels{"user": userID})
+	r.discardedMetadata.DeleteLabelValues(userID)
+
+	r.mtx.Lock()
+	delete(r.violations, userID)
+	r.mtx.Unlock()
+
+	r.indexesMtx.Lock()
+	delete(r.indexes, userID)
+	r.indexesMtx.Unlock()
+}
+
+// MetricRegistryConformanceHandler returns the report of the violations of the tenant's
+// metric registry observed by this distributor.
+func (d *Distributor) MetricRegistryConformanceHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := tenant.TenantID(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	util.WriteJSONResponse(w, d.metricRegistry.report(userID))
+}
@@ -0,0 +1,228 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package distributor
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/grafana/dskit/user"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/mimir/pkg/mimirpb"
+	"github.com/grafana/mimir/pkg/util/validation"
+)
+
+func TestMetricRegistry_ValidateSeries(t *testing.T) {
+	const userID = "user"
+
+	registry := []*validation.MetricDefinition{
+		{Name: "http_requests_total", Type: model.MetricTypeCounter, AllowedLabels: []string{"method", "status"}},
+		{Name: "request_duration_seconds", Type: model.MetricTypeHistogram, AllowedLabels: []string{"method"}},
+		{Name: "rpc_duration_seconds", Type: model.MetricTypeSummary, AllowedLabels: []string{"method"}},
+		{Name: "up"},
+	}
+
+	tests := map[string]struct {
+		series         []string
+		expectedReason string
+		expectedDetail string
+	}{
+		"declared metric with allowed labels": {
+			series: []string{model.MetricNameLabel, "http_requests_total", "method", "GET", "status", "200"},
+		},
+		"declared metric without allowed labels list": {
+			series: []string{model.MetricNameLabel, "up", "job", "test", "instance", "localhost"},
+		},
+		"classic histogram bucket series": {
+			series: []string{model.MetricNameLabel, "request_duration_seconds_bucket", "method", "GET", "le", "0.1"},
+		},
+		"classic histogram count series": {
+			series: []string{model.MetricNameLabel, "request_duration_seconds_count", "method", "GET"},
+		},
+		"summary quantile series": {
+			series: []string{model.MetricNameLabel, "rpc_duration_seconds", "method", "GET", "quantile", "0.99"},
+		},
+		"undeclared metric": {
+			series:         []string{model.MetricNameLabel, "foo"},
+			expectedReason: metricRegistryReasonUndeclaredMetric,
+		},
+		"summary doesn't have bucket series": {
+			series:         []string{model.MetricNameLabel, "rpc_duration_seconds_bucket", "le", "0.1"},
+			expectedReason: metricRegistryReasonUndeclaredMetric,
+		},
+		"label not allowed": {
+			series:         []string{model.MetricNameLabel, "http_requests_total", "method", "GET", "path", "/"},
+			expectedReason: metricRegistryReasonLabelNotAllowed,
+			expectedDetail: "path",
+		},
+		"quantile label not allowed on histograms": {
+			series:         []string{model.MetricNameLabel, "request_duration_seconds_bucket", "quantile", "0.99"},
+			expectedReason: metricRegistryReasonLabelNotAllowed,
+			expectedDetail: "quantile",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			for _, enforce := range []bool{false, true} {
+				limits := validation.MockOverrides(func(defaults *validation.Limits, _ map[string]*validation.Limits) {
+					defaults.MetricRegistry = registry
+				})
+				r := newMetricRegistry(limits, prometheus.NewPedanticRegistry())
+				idx := r.index(userID)
+				require.NotNil(t, idx)
+
+				ts := mimirpb.PreallocTimeseries{TimeSeries: &mimirpb.TimeSeries{
+					Labels:  mimirpb.FromLabelsToLabelAdapters(labels.FromStrings(tc.series...)),
+					Samples: []mimirpb.Sample{{Value: 1, TimestampMs: 1}},
+				}}
+
+				err := r.validateSeries(userID, "", idx, enforce, ts)
+				report := r.report(userID)
+
+				if tc.expectedReason == "" {
+					require.NoError(t, err)
+					assert.Empty(t, report.Violations)
+					continue
+				}
+
+				if enforce {
+					require.Error(t, err)
+					assert.Contains(t, err.Error(), "err-mimir-metric-registry-violation")
+					assert.Equal(t, float64(1), testutil.ToFloat64(r.discardedSamples.WithLabelValues(userID, "")))
+				} else {
+					require.NoError(t, err)
+					assert.Equal(t, float64(0), testutil.ToFloat64(r.discardedSamples.WithLabelValues(userID, "")))
+				}
+				require.Len(t, report.Violations, 1)
+				assert.Equal(t, tc.expectedReason, report.Violations[0].Reason)
+				assert.Equal(t, tc.expectedDetail, report.Violations[0].Detail)
+				assert.Equal(t, uint64(1), report.Violations[0].Count)
+				assert.Equal(t, float64(1), testutil.ToFloat64(r.violationsTotal.WithLabelValues(userID, tc.expectedReason)))
+			}
+		})
+	}
+}
+
+func TestMetricRegistry_ValidateMetadata(t *testing.T) {
+	const userID = "user"
+
+	limits := validation.MockOverrides(func(defaults *validation.Limits, _ map[string]*validation.Limits) {
+		defaults.MetricRegistry = []*validation.MetricDefinition{
+			{Name: "http_requests_total", Type: model.MetricTypeCounter},
+			{Name: "up"},
+		}
+		defaults.MetricRegistryEnforcementEnabled = true
+	})
+	r := newMetricRegistry(limits, prometheus.NewPedanticRegistry())
+	idx := r.index(userID)
+
+	require.NoError(t, r.validateMetadata(userID, idx, true, &mimirpb.MetricMetadata{MetricFamilyName: "http_requests_total", Type: mimirpb.COUNTER}))
+	require.NoError(t, r.validateMetadata(userID, idx, true, &mimirpb.MetricMetadata{MetricFamilyName: "up", Type: mimirpb.GAUGE}))
+
+	err := r.validateMetadata(userID, idx, true, &mimirpb.MetricMetadata{MetricFamilyName: "http_requests_total", Type: mimirpb.GAUGE})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "expected type: 'counter'")
+
+	err = r.validateMetadata(userID, idx, true, &mimirpb.MetricMetadata{MetricFamilyName: "foo", Type: mimirpb.GAUGE})
+	require.Error(t, err)
+	err = r.validateMetadata(userID, idx, true, &mimirpb.MetricMetadata{MetricFamilyName: "foo", Type: mimirpb.GAUGE})
+	require.Error(t, err)
+
+	assert.Equal(t, float64(3), testutil.ToFloat64(r.discardedMetadata.WithLabelValues(userID)))
+
+	report := r.report(userID)
+	assert.Equal(t, 2, report.DeclaredMetrics)
+	assert.True(t, report.EnforcementEnabled)
+	require.Len(t, report.Violations, 2)
+	assert.Equal(t, "foo", report.Violations[0].MetricName)
+	assert.Equal(t, metricRegistryReasonUndeclaredMetric, report.Violations[0].Reason)
+	assert.Equal(t, uint64(2), report.Violations[0].Count)
+	assert.Equal(t, "http_requests_total", report.Violations[1].MetricName)
+	assert.Equal(t, metricRegistryReasonTypeMismatch, report.Violations[1].Reason)
+	assert.Equal(t, "gauge", report.Violations[1].Detail)
+
+	r.deleteUser(userID)
+	assert.Empty(t, r.report(userID).Violations)
+}
+
+func TestMetricRegistry_NoRegistry(t *testing.T) {
+	limits := validation.MockOverrides(func(*validation.Limits, map[string]*validation.Limits) {})
+	r := newMetricRegistry(limits, prometheus.NewPedanticRegistry())
+	assert.Nil(t, r.index("user"))
+}
+
+func TestMetricRegistry_Index(t *testing.T) {
+	const userID = "user"
+
+	tenantLimits := map[string]*validation.Limits{}
+	withRegistry := func(names ...string) *validation.Limits {
+		l := validation.MockDefaultLimits()
+		for _, name := range names {
+			l.MetricRegistry = append(l.MetricRegistry, &validation.MetricDefinition{Name: name})
+		}
+		return l
+	}
+	tenantLimits[userID] = withRegistry("up")
+
+	limits, err := validation.NewOverrides(*validation.MockDefaultLimits(), validation.NewMockTenantLimits(tenantLimits))
+	require.NoError(t, err)
+	r := newMetricRegistry(limits, prometheus.NewPedanticRegistry())
+
+	// The index is cached until the tenant's metric registry changes.
+	idx := r.index(userID)
+	require.Len(t, idx, 1)
+	assert.Equal(t, reflect.ValueOf(idx).Pointer(), reflect.ValueOf(r.index(userID)).Pointer())
+
+	tenantLimits[userID] = withRegistry("up", "http_requests_total")
+	updated := r.index(userID)
+	require.Len(t, updated, 2)
+	assert.NotNil(t, updated["http_requests_total"])
+	assert.Equal(t, reflect.ValueOf(updated).Pointer(), reflect.ValueOf(r.index(userID)).Pointer())
+
+	r.deleteUser(userID)
+	assert.Empty(t, r.indexes)
+}
+
+func TestDistributor_MetricRegistryConformanceHandler(t *testing.T) {
+	limits := validation.MockOverrides(func(defaults *validation.Limits, _ map[string]*validation.Limits) {
+		defaults.MetricRegistry = []*validation.MetricDefinition{{Name: "up"}}
+	})
+	d := &Distributor{metricRegistry: newMetricRegistry(limits, prometheus.NewPedanticRegistry())}
+	d.metricRegistry.trackViolation("user-1", "foo", metricRegistryReasonUndeclaredMetric, "")
+	d.metricRegistry.trackViolation("user-2", "bar", metricRegistryReasonUndeclaredMetric, "")
+
+	t.Run("should return the report of the tenant", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/distributor/metric_registry/conformance", nil)
+		req = req.WithContext(user.InjectOrgID(req.Context(), "user-1"))
+		rec := httptest.NewRecorder()
+		d.MetricRegistryConformanceHandler(rec, req)
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		var report MetricRegistryConformanceReport
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&report))
+		assert.Equal(t, 1, report.DeclaredMetrics)
+		assert.False(t, report.EnforcementEnabled)
+		require.Len(t, report.Violations, 1)
+		assert.Equal(t, "foo", report.Violations[0].MetricName)
+	})
+
+	t.Run("should fail without tenant", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/distributor/metric_registry/conformance", nil)
+		rec := httptest.NewRecorder()
+		d.MetricRegistryConformanceHandler(rec, req)
+
+		require.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.True(t, strings.Contains(rec.Body.String(), "no org id"))
+	})
+}
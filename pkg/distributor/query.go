@@ -417,11 +417,14 @@ func (d *Distributor) queryIngesterStream(ctx context.Context, replicationSets [
 		resp.Timeseries = append(resp.Timeseries, series)
 	}
 
-	reqStats.AddFetchedSeries(uint64(len(resp.Chunkseries) + len(resp.Timeseries) + len(resp.StreamingSeries)))
+	fetchedSeries := uint64(len(resp.Chunkseries) + len(resp.Timeseries) + len(resp.StreamingSeries))
+	reqStats.AddFetchedSeries(fetchedSeries)
 
 	// Stats for streaming series are handled in streamingChunkSeries.
+	fetchedChunks := uint64(ingester_client.ChunksCount(resp.Chunkseries))
 	reqStats.AddFetchedChunkBytes(uint64(ingester_client.ChunksSize(resp.Chunkseries)))
-	reqStats.AddFetchedChunks(uint64(ingester_client.ChunksCount(resp.Chunkseries)))
+	reqStats.AddFetchedChunks(fetchedChunks)
+	reqStats.AddFetchedFromIngesters(fetchedSeries, fetchedChunks)
 
 	return resp, nil
 }
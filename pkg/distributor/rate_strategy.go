@@ -44,17 +44,22 @@ func (s *globalIngestionStrategyWithBurstFactor) Limit(tenantID string) float64
 }
 
 func (s *globalIngestionStrategyWithBurstFactor) Burst(tenantID string) int {
-	burstFactor := s.limits.IngestionBurstFactor(tenantID)
+	return ingestionBurst(s.limits, tenantID, s.Limit)
+}
+
+// ingestionBurst returns the ingestion burst size for the tenant, given the function returning the
+// ingestion rate limit enforced by the distributor.
+func ingestionBurst(limits *validation.Overrides, tenantID string, limit func(string) float64) int {
+	burstFactor := limits.IngestionBurstFactor(tenantID)
 	if burstFactor > 0 {
-		limit := s.Limit(tenantID)
-		burstByFactor := burstFactor * limit
+		burstByFactor := burstFactor * limit(tenantID)
 		// If the ingestion rate * burst factor is too large we want to set it to the max possible burst value
 		if burstByFactor >= math.MaxInt {
 			return math.MaxInt
 		}
 		return int(math.Ceil(burstByFactor))
 	}
-	return s.limits.IngestionBurstSize(tenantID)
+	return limits.IngestionBurstSize(tenantID)
 }
 
 type globalStrategy struct {
@@ -8,12 +8,15 @@ package distributor
 import (
 	"errors"
 	"fmt"
+	"math"
 	"strings"
 	"time"
 	"unicode"
 	"unicode/utf8"
 
+	"github.com/cespare/xxhash/v2"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
 	"github.com/prometheus/common/model"
 
 	"github.com/grafana/mimir/pkg/mimirpb"
@@ -26,6 +29,11 @@ const (
 	// The combined length of the label names and values of an Exemplar's LabelSet MUST NOT exceed 128 UTF-8 characters
 	// https://github.com/OpenObservability/OpenMetrics/blob/main/specification/OpenMetrics.md#exemplars
 	ExemplarMaxLabelSetLength = 128
+
+	// labelValueTruncatedSuffixFormat is the format of the marker appended to the label values truncated to the maximum
+	// length. The marker includes a short hash of the original value, so that distinct label values sharing the same
+	// prefix are truncated to distinct values.
+	labelValueTruncatedSuffixFormat = "(truncated:%08x)"
 )
 
 var (
@@ -62,6 +70,12 @@ var (
 	// reasonTooManyHAClusters is one of the reasons for discarding samples.
 	reasonTooManyHAClusters = "too_many_ha_clusters"
 
+	// reasonBlockedSeries is the reason for discarding the samples of the series matching the tenant's blocked series.
+	reasonBlockedSeries = "blocked_series"
+
+	// reasonClientDeadlineExceeded is the reason for discarding the requests whose deadline set by the client has expired.
+	reasonClientDeadlineExceeded = "client_deadline_exceeded"
+
 	labelNameTooLongMsgFormat = globalerror.SeriesLabelNameTooLong.MessageWithPerTenantLimitConfig(
 		"received a series whose label name length exceeds the limit, label: '%.200s' series: '%.200s'",
 		validation.MaxLabelNameLengthFlag,
@@ -132,6 +146,11 @@ type sampleValidationMetrics struct {
 	duplicateLabelNames          *prometheus.CounterVec
 	tooFarInFuture               *prometheus.CounterVec
 	tooFarInPast                 *prometheus.CounterVec
+
+	// labelValuesTruncated is not a discarded samples counter: it tracks label values truncated to the maximum length.
+	labelValuesTruncated *prometheus.CounterVec
+	// timestampsCorrected is not a discarded samples counter: it tracks samples whose timestamp has been corrected.
+	timestampsCorrected *prometheus.CounterVec
 }
 
 func (m *sampleValidationMetrics) deleteUserMetrics(userID string) {
@@ -147,6 +166,8 @@ func (m *sampleValidationMetrics) deleteUserMetrics(userID string) {
 	m.duplicateLabelNames.DeletePartialMatch(filter)
 	m.tooFarInFuture.DeletePartialMatch(filter)
 	m.tooFarInPast.DeletePartialMatch(filter)
+	m.labelValuesTruncated.DeletePartialMatch(filter)
+	m.timestampsCorrected.DeletePartialMatch(filter)
 }
 
 func (m *sampleValidationMetrics) deleteUserMetricsForGroup(userID, group string) {
@@ -161,6 +182,8 @@ func (m *sampleValidationMetrics) deleteUserMetricsForGroup(userID, group string
 	m.duplicateLabelNames.DeleteLabelValues(userID, group)
 	m.tooFarInFuture.DeleteLabelValues(userID, group)
 	m.tooFarInPast.DeleteLabelValues(userID, group)
+	m.labelValuesTruncated.DeleteLabelValues(userID, group)
+	m.timestampsCorrected.DeleteLabelValues(userID, group)
 }
 
 func newSampleValidationMetrics(r prometheus.Registerer) *sampleValidationMetrics {
@@ -176,6 +199,14 @@ func newSampleValidationMetrics(r prometheus.Registerer) *sampleValidationMetric
 		duplicateLabelNames:          validation.DiscardedSamplesCounter(r, reasonDuplicateLabelNames),
 		tooFarInFuture:               validation.DiscardedSamplesCounter(r, reasonTooFarInFuture),
 		tooFarInPast:                 validation.DiscardedSamplesCounter(r, reasonTooFarInPast),
+		labelValuesTruncated: promauto.With(r).NewCounterVec(prometheus.CounterOpts{
+			Name: "cortex_distributor_label_values_truncated_total",
+			Help: "The total number of label values truncated because longer than the configured limit.",
+		}, []string{"user", "group"}),
+		timestampsCorrected: promauto.With(r).NewCounterVec(prometheus.CounterOpts{
+			Name: "cortex_distributor_sample_timestamps_corrected_total",
+			Help: "The total number of samples and histograms whose timestamp has been shifted because of a clock skew within the configured limit.",
+		}, []string{"user", "group"}),
 	}
 }
 
@@ -345,6 +376,7 @@ type labelValidationConfig interface {
 	MaxLabelNamesPerSeries(userID string) int
 	MaxLabelNameLength(userID string) int
 	MaxLabelValueLength(userID string) int
+	TruncateLabelValueOverMaxLength(userID string) bool
 }
 
 func removeNonASCIIChars(in string) (out string) {
@@ -410,6 +442,93 @@ func validateLabels(m *sampleValidationMetrics, cfg labelValidationConfig, userI
 	return nil
 }
 
+// truncateLabelValues truncates the label values longer than the maximum length in-place, and
+// returns whether any label value has been truncated. The metric name is never truncated.
+func truncateLabelValues(m *sampleValidationMetrics, cfg labelValidationConfig, userID, group string, ls []mimirpb.LabelAdapter) bool {
+	maxLabelValueLength := cfg.MaxLabelValueLength(userID)
+	truncated := false
+	for i, l := range ls {
+		if len(l.Value) <= maxLabelValueLength || l.Name == model.MetricNameLabel {
+			continue
+		}
+
+		ls[i].Value = truncateLabelValue(l.Value, maxLabelValueLength)
+		m.labelValuesTruncated.WithLabelValues(userID, group).Inc()
+		truncated = true
+	}
+	return truncated
+}
+
+// truncateLabelValue returns a copy of the input value, truncated to maxLength bytes including the
+// truncation marker. The value is never cut in the middle of a UTF-8 character.
+func truncateLabelValue(value string, maxLength int) string {
+	hash := uint32(xxhash.Sum64String(value))
+	suffix := fmt.Sprintf(labelValueTruncatedSuffixFormat, hash)
+	if len(suffix) > maxLength {
+		// Keep at least the hash of the original value if the whole marker doesn't fit.
+		suffix = fmt.Sprintf("%08x", hash)
+	}
+	if len(suffix) > maxLength {
+		suffix = ""
+	}
+
+	cut := maxLength - len(suffix)
+	for cut > 0 && !utf8.RuneStart(value[cut]) {
+		cut--
+	}
+
+	// The label value may be a yolo string referencing the request buffer, so never return a substring of it.
+	return strings.Clone(value[:cut]) + suffix
+}
+
+// correctTimestampSkew shifts, in-place, the timestamps of all the float samples and histograms of the series by a single
+// offset, so that the samples outside of the accepted time window by no more than maxSkew are moved to the nearest edge
+// of the window and not rejected by the validation. Shifting the whole series keeps the order of its samples and doesn't
+// make their timestamps collide. The series isn't corrected if it can't fit into the window by shifting it by no more
+// than maxSkew. It returns whether the float samples and the histograms have been updated.
+func correctTimestampSkew(m *sampleValidationMetrics, now model.Time, cfg sampleValidationConfig, userID, group string, ts *mimirpb.PreallocTimeseries, maxSkew time.Duration) (samplesUpdated, histogramsUpdated bool) {
+	if len(ts.Samples) == 0 && len(ts.Histograms) == 0 {
+		return false, false
+	}
+
+	maxT := int64(now.Add(cfg.CreationGracePeriod(userID)))
+	minT := int64(math.MinInt64)
+	if pastGracePeriod := cfg.PastGracePeriod(userID); pastGracePeriod > 0 {
+		minT = int64(now.Add(-pastGracePeriod).Add(-cfg.OutOfOrderTimeWindow(userID)))
+	}
+
+	minTs, maxTs := int64(math.MaxInt64), int64(math.MinInt64)
+	for _, s := range ts.Samples {
+		minTs, maxTs = min(minTs, s.TimestampMs), max(maxTs, s.TimestampMs)
+	}
+	for _, h := range ts.Histograms {
+		minTs, maxTs = min(minTs, h.Timestamp), max(maxTs, h.Timestamp)
+	}
+
+	var offset int64
+	switch {
+	case maxTs > maxT:
+		offset = maxT - maxTs
+	case minTs < minT:
+		offset = minT - minTs
+	default:
+		return false, false
+	}
+	if offset < -maxSkew.Milliseconds() || offset > maxSkew.Milliseconds() || minTs+offset < minT || maxTs+offset > maxT {
+		return false, false
+	}
+
+	for i := range ts.Samples {
+		ts.Samples[i].TimestampMs += offset
+	}
+	for i := range ts.Histograms {
+		ts.Histograms[i].Timestamp += offset
+	}
+
+	m.timestampsCorrected.WithLabelValues(userID, group).Add(float64(len(ts.Samples) + len(ts.Histograms)))
+	return len(ts.Samples) > 0, len(ts.Histograms) > 0
+}
+
 // metadataValidationMetrics is a collection of metrics used by metadata validation.
 type metadataValidationMetrics struct {
 	missingMetricName *prometheus.CounterVec
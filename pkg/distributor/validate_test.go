@@ -13,6 +13,7 @@ import (
 	"time"
 	"unicode/utf8"
 
+	"github.com/cespare/xxhash/v2"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/prometheus/common/model"
@@ -24,9 +25,10 @@ import (
 )
 
 type validateLabelsCfg struct {
-	maxLabelNamesPerSeries int
-	maxLabelNameLength     int
-	maxLabelValueLength    int
+	maxLabelNamesPerSeries          int
+	maxLabelNameLength              int
+	maxLabelValueLength             int
+	truncateLabelValueOverMaxLength bool
 }
 
 func (v validateLabelsCfg) MaxLabelNamesPerSeries(_ string) int {
@@ -41,6 +43,10 @@ func (v validateLabelsCfg) MaxLabelValueLength(_ string) int {
 	return v.maxLabelValueLength
 }
 
+func (v validateLabelsCfg) TruncateLabelValueOverMaxLength(_ string) bool {
+	return v.truncateLabelValueOverMaxLength
+}
+
 type validateMetadataCfg struct {
 	enforceMetadataMetricName bool
 	maxMetadataLength         int
@@ -183,6 +189,161 @@ func TestValidateLabels(t *testing.T) {
 	`), "cortex_discarded_samples_total"))
 }
 
+func TestTruncateLabelValues(t *testing.T) {
+	reg := prometheus.NewPedanticRegistry()
+	s := newSampleValidationMetrics(reg)
+
+	cfg := validateLabelsCfg{
+		maxLabelNamesPerSeries:          10,
+		maxLabelNameLength:              25,
+		maxLabelValueLength:             25,
+		truncateLabelValueOverMaxLength: true,
+	}
+	userID := "testUser"
+
+	hash := func(value string) string {
+		return fmt.Sprintf("%08x", uint32(xxhash.Sum64String(value)))
+	}
+
+	for name, c := range map[string]struct {
+		labels    []mimirpb.LabelAdapter
+		expected  []mimirpb.LabelAdapter
+		truncated bool
+	}{
+		"no label value over the limit": {
+			labels:   []mimirpb.LabelAdapter{{Name: model.MetricNameLabel, Value: "foo"}, {Name: "bar", Value: "1234567890123456789012345"}},
+			expected: []mimirpb.LabelAdapter{{Name: model.MetricNameLabel, Value: "foo"}, {Name: "bar", Value: "1234567890123456789012345"}},
+		},
+		"label value over the limit": {
+			labels:    []mimirpb.LabelAdapter{{Name: model.MetricNameLabel, Value: "foo"}, {Name: "bar", Value: "12345678901234567890123456"}},
+			expected:  []mimirpb.LabelAdapter{{Name: model.MetricNameLabel, Value: "foo"}, {Name: "bar", Value: "12345(truncated:" + hash("12345678901234567890123456") + ")"}},
+			truncated: true,
+		},
+		"label value isn't cut in the middle of a character": {
+			labels:    []mimirpb.LabelAdapter{{Name: model.MetricNameLabel, Value: "foo"}, {Name: "bar", Value: "1234éééééééééééé"}},
+			expected:  []mimirpb.LabelAdapter{{Name: model.MetricNameLabel, Value: "foo"}, {Name: "bar", Value: "1234(truncated:" + hash("1234éééééééééééé") + ")"}},
+			truncated: true,
+		},
+		"metric name over the limit isn't truncated": {
+			labels:   []mimirpb.LabelAdapter{{Name: model.MetricNameLabel, Value: "foo_bar_baz_qux_quux_corge"}},
+			expected: []mimirpb.LabelAdapter{{Name: model.MetricNameLabel, Value: "foo_bar_baz_qux_quux_corge"}},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, c.truncated, truncateLabelValues(s, cfg, userID, "custom label", c.labels))
+			assert.Equal(t, c.expected, c.labels)
+		})
+	}
+
+	// Distinct label values sharing the same prefix are truncated to distinct values.
+	assert.NotEqual(t, truncateLabelValue("12345678901234567890123456", 25), truncateLabelValue("12345678901234567890123457", 25))
+
+	// The maximum length is always honored, even when it's shorter than the truncation marker.
+	assert.Equal(t, "12"+hash("1234567890"), truncateLabelValue("1234567890", 10))
+	assert.Equal(t, "1234", truncateLabelValue("1234567890", 4))
+
+	require.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(`
+			# HELP cortex_distributor_label_values_truncated_total The total number of label values truncated because longer than the configured limit.
+			# TYPE cortex_distributor_label_values_truncated_total counter
+			cortex_distributor_label_values_truncated_total{group="custom label",user="testUser"} 2
+	`), "cortex_distributor_label_values_truncated_total"))
+
+	s.deleteUserMetrics(userID)
+
+	require.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(""), "cortex_distributor_label_values_truncated_total"))
+}
+
+func TestCorrectTimestampSkew(t *testing.T) {
+	reg := prometheus.NewPedanticRegistry()
+	s := newSampleValidationMetrics(reg)
+	userID := "testUser"
+
+	now := model.Time(100 * time.Hour.Milliseconds())
+	cfg := sampleValidationCfg{
+		creationGracePeriod:  10 * time.Minute,
+		pastGracePeriod:      time.Hour,
+		outOfOrderTimeWindow: 30 * time.Minute,
+	}
+	maxSkew := time.Minute
+
+	for name, c := range map[string]struct {
+		cfg                       sampleValidationCfg
+		samples                   []mimirpb.Sample
+		histograms                []mimirpb.Histogram
+		expectedSamples           []mimirpb.Sample
+		expectedHistograms        []mimirpb.Histogram
+		expectedSamplesUpdated    bool
+		expectedHistogramsUpdated bool
+	}{
+		"samples within the accepted time window are not corrected": {
+			cfg:             cfg,
+			samples:         []mimirpb.Sample{{TimestampMs: int64(now.Add(10 * time.Minute))}, {TimestampMs: int64(now.Add(-90 * time.Minute))}},
+			expectedSamples: []mimirpb.Sample{{TimestampMs: int64(now.Add(10 * time.Minute))}, {TimestampMs: int64(now.Add(-90 * time.Minute))}},
+		},
+		"series in the future by no more than the max skew is shifted to the end of the accepted time window": {
+			cfg:                    cfg,
+			samples:                []mimirpb.Sample{{TimestampMs: int64(now.Add(10*time.Minute + 30*time.Second))}, {TimestampMs: int64(now.Add(11 * time.Minute))}},
+			expectedSamples:        []mimirpb.Sample{{TimestampMs: int64(now.Add(9*time.Minute + 30*time.Second))}, {TimestampMs: int64(now.Add(10 * time.Minute))}},
+			expectedSamplesUpdated: true,
+		},
+		"series in the past by no more than the max skew is shifted to the start of the accepted time window": {
+			cfg:                    cfg,
+			samples:                []mimirpb.Sample{{TimestampMs: int64(now.Add(-91 * time.Minute))}, {TimestampMs: int64(now.Add(-90*time.Minute - 30*time.Second))}},
+			expectedSamples:        []mimirpb.Sample{{TimestampMs: int64(now.Add(-90 * time.Minute))}, {TimestampMs: int64(now.Add(-89*time.Minute - 30*time.Second))}},
+			expectedSamplesUpdated: true,
+		},
+		"samples outside of the accepted time window by more than the max skew are not corrected": {
+			cfg:             cfg,
+			samples:         []mimirpb.Sample{{TimestampMs: int64(now.Add(11*time.Minute + time.Millisecond))}},
+			expectedSamples: []mimirpb.Sample{{TimestampMs: int64(now.Add(11*time.Minute + time.Millisecond))}},
+		},
+		"series which doesn't fit into the accepted time window once shifted is not corrected": {
+			cfg:             cfg,
+			samples:         []mimirpb.Sample{{TimestampMs: int64(now.Add(-90 * time.Minute))}, {TimestampMs: int64(now.Add(10*time.Minute + 30*time.Second))}},
+			expectedSamples: []mimirpb.Sample{{TimestampMs: int64(now.Add(-90 * time.Minute))}, {TimestampMs: int64(now.Add(10*time.Minute + 30*time.Second))}},
+		},
+		"samples in the past are not corrected if the past grace period is disabled": {
+			cfg:             sampleValidationCfg{creationGracePeriod: 10 * time.Minute},
+			samples:         []mimirpb.Sample{{TimestampMs: int64(now.Add(-91 * time.Minute))}},
+			expectedSamples: []mimirpb.Sample{{TimestampMs: int64(now.Add(-91 * time.Minute))}},
+		},
+		"histograms outside of the accepted time window by no more than the max skew are corrected": {
+			cfg:                       cfg,
+			histograms:                []mimirpb.Histogram{{Timestamp: int64(now)}, {Timestamp: int64(now.Add(11 * time.Minute))}},
+			expectedHistograms:        []mimirpb.Histogram{{Timestamp: int64(now.Add(-time.Minute))}, {Timestamp: int64(now.Add(10 * time.Minute))}},
+			expectedHistogramsUpdated: true,
+		},
+		"float samples and histograms of the series are shifted by the same offset": {
+			cfg:                       cfg,
+			samples:                   []mimirpb.Sample{{TimestampMs: int64(now.Add(10*time.Minute + 30*time.Second))}},
+			histograms:                []mimirpb.Histogram{{Timestamp: int64(now.Add(11 * time.Minute))}},
+			expectedSamples:           []mimirpb.Sample{{TimestampMs: int64(now.Add(9*time.Minute + 30*time.Second))}},
+			expectedHistograms:        []mimirpb.Histogram{{Timestamp: int64(now.Add(10 * time.Minute))}},
+			expectedSamplesUpdated:    true,
+			expectedHistogramsUpdated: true,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			ts := &mimirpb.PreallocTimeseries{TimeSeries: &mimirpb.TimeSeries{Samples: c.samples, Histograms: c.histograms}}
+			samplesUpdated, histogramsUpdated := correctTimestampSkew(s, now, c.cfg, userID, "custom label", ts, maxSkew)
+			assert.Equal(t, c.expectedSamplesUpdated, samplesUpdated)
+			assert.Equal(t, c.expectedHistogramsUpdated, histogramsUpdated)
+			assert.Equal(t, c.expectedSamples, ts.Samples)
+			assert.Equal(t, c.expectedHistograms, ts.Histograms)
+		})
+	}
+
+	require.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(`
+			# HELP cortex_distributor_sample_timestamps_corrected_total The total number of samples and histograms whose timestamp has been shifted because of a clock skew within the configured limit.
+			# TYPE cortex_distributor_sample_timestamps_corrected_total counter
+			cortex_distributor_sample_timestamps_corrected_total{group="custom label",user="testUser"} 8
+	`), "cortex_distributor_sample_timestamps_corrected_total"))
+
+	s.deleteUserMetrics(userID)
+
+	require.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(""), "cortex_distributor_sample_timestamps_corrected_total"))
+}
+
 func TestValidateExemplars(t *testing.T) {
 	reg := prometheus.NewPedanticRegistry()
 	m := newExemplarValidationMetrics(reg)
@@ -390,20 +551,23 @@ func TestValidateLabelDuplication(t *testing.T) {
 }
 
 type sampleValidationCfg struct {
+	creationGracePeriod                 time.Duration
+	pastGracePeriod                     time.Duration
+	outOfOrderTimeWindow                time.Duration
 	maxNativeHistogramBuckets           int
 	reduceNativeHistogramOverMaxBuckets bool
 }
 
 func (c sampleValidationCfg) CreationGracePeriod(_ string) time.Duration {
-	return 0
+	return c.creationGracePeriod
 }
 
 func (c sampleValidationCfg) PastGracePeriod(_ string) time.Duration {
-	return 0
+	return c.pastGracePeriod
 }
 
 func (c sampleValidationCfg) OutOfOrderTimeWindow(_ string) time.Duration {
-	return 0
+	return c.outOfOrderTimeWindow
 }
 
 func (c sampleValidationCfg) MaxNativeHistogramBuckets(_ string) int {
@@ -19,8 +19,11 @@ import (
 	v1 "github.com/grafana/mimir/pkg/frontend/v1"
 	v2 "github.com/grafana/mimir/pkg/frontend/v2"
 	"github.com/grafana/mimir/pkg/scheduler/schedulerdiscovery"
+	"github.com/grafana/mimir/pkg/util/validation"
 )
 
+var errQueryBytesBudgetRequiresQueryStats = errors.Errorf("the daily budget of bytes fetched by queries (-%s) requires the query statistics (-query-frontend.query-stats-enabled) to be enabled", validation.MaxQueryBytesPerDayFlag)
+
 // CombinedFrontendConfig combines several configuration options together to preserve backwards compatibility.
 type CombinedFrontendConfig struct {
 	Handler    transport.HandlerConfig `yaml:",inline"`
@@ -51,6 +54,15 @@ func (cfg *CombinedFrontendConfig) Validate() error {
 	return nil
 }
 
+// ValidateLimits validates the limits that can be set for each tenant against the query-frontend config.
+func (cfg *CombinedFrontendConfig) ValidateLimits(limits validation.Limits) error {
+	// The bytes fetched by queries are tracked by the query statistics.
+	if limits.MaxQueryBytesPerDay > 0 && !cfg.Handler.QueryStatsEnabled {
+		return errQueryBytesBudgetRequiresQueryStats
+	}
+	return nil
+}
+
 // InitFrontend initializes frontend (either V1 -- without scheduler, or V2 -- with scheduler) or no frontend at
 // all if downstream Prometheus URL is used instead.
 //
@@ -303,3 +303,7 @@ func (l limits) MaxQueriersPerUser(_ string) int {
 func (l limits) QueryIngestersWithin(string) time.Duration {
 	return l.queryIngestersWithin
 }
+
+func (l limits) MaxQueryPriority(string) int {
+	return 0
+}
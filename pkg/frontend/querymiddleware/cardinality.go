@@ -98,6 +98,11 @@ func (c *cardinalityEstimation) Do(ctx context.Context, request MetricsQueryRequ
 	actualCardinality := statistics.GetFetchedSeriesCount()
 	spanLog.LogFields(otlog.Uint64("actual cardinality", actualCardinality))
 
+	// The cardinality of the queries being explained is unknown, because the downstream requests haven't been executed.
+	if QueryExplanationFromContext(ctx) != nil {
+		return res, nil
+	}
+
 	if !estimateAvailable || !isCardinalitySimilar(actualCardinality, estimatedCardinality) {
 		c.storeCardinalityForKey(k, actualCardinality)
 		spanLog.LogFields(otlog.Bool("cache updated", true))
@@ -316,6 +316,12 @@ func (c prometheusCodec) decodeRangeQueryRequest(r *http.Request) (MetricsQueryR
 		return nil, err
 	}
 
+	// The response transformation is applied when encoding the response, but we validate it upfront
+	// to not run the query if it's invalid.
+	if _, err := parseResponseTransformation(reqValues); err != nil {
+		return nil, err
+	}
+
 	query := reqValues.Get("query")
 	queryExpr, err := parser.ParseExpr(query)
 	if err != nil {
@@ -777,6 +783,18 @@ func (c prometheusCodec) EncodeResponse(ctx context.Context, req *http.Request,
 		sp.LogFields(otlog.Int("series", len(a.Data.Result)))
 	}
 
+	if req.URL != nil && IsRangeQuery(req.URL.Path) {
+		reqValues, err := util.ParseRequestFormWithoutConsumingBody(req)
+		if err != nil {
+			return nil, apierror.New(apierror.TypeBadData, err.Error())
+		}
+		transformation, err := parseResponseTransformation(reqValues)
+		if err != nil {
+			return nil, err
+		}
+		a = transformation.apply(a)
+	}
+
 	selectedContentType, formatter := c.negotiateContentType(req.Header.Get("Accept"))
 	if formatter == nil {
 		return nil, apierror.New(apierror.TypeNotAcceptable, "none of the content types in the Accept header are supported")
@@ -0,0 +1,143 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querymiddleware
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/grafana/dskit/tenant"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/prometheus/promql"
+	"github.com/prometheus/prometheus/storage"
+
+	"github.com/grafana/mimir/pkg/storage/tsdb/bucketindex"
+	"github.com/grafana/mimir/pkg/util"
+	"github.com/grafana/mimir/pkg/util/spanlogger"
+	"github.com/grafana/mimir/pkg/util/validation"
+)
+
+// CompactionSummaryReader returns the compaction summary of a tenant, uploaded to the storage by the compactor.
+type CompactionSummaryReader interface {
+	GetSummary(ctx context.Context, userID string) (*bucketindex.Summary, error)
+}
+
+// emptyQueryable is a storage.Queryable with no data.
+var emptyQueryable = storage.QueryableFunc(func(_, _ int64) (storage.Querier, error) {
+	return storage.NoopQuerier(), nil
+})
+
+type compactionSummaryPruningMiddleware struct {
+	next           MetricsQueryHandler
+	summaries      CompactionSummaryReader
+	maxStalePeriod time.Duration
+	limits         Limits
+	engine         *promql.Engine
+	logger         log.Logger
+
+	prunedQueries prometheus.Counter
+}
+
+// newCompactionSummaryPruningMiddleware creates a middleware that skips the execution of queries targeting
+// a time range with no data in the storage according to the compaction summary. Such queries are evaluated
+// by the query-frontend against an empty storage, so that the result is the same one the queriers would
+// have returned (e.g. for queries using absent() or vector()).
+func newCompactionSummaryPruningMiddleware(summaries CompactionSummaryReader, maxStalePeriod time.Duration, limits Limits, engine *promql.Engine, logger log.Logger, registerer prometheus.Registerer) MetricsQueryMiddleware {
+	prunedQueries := promauto.With(registerer).NewCounter(prometheus.CounterOpts{
+		Name: "cortex_frontend_queries_pruned_by_compaction_summary_total",
+		Help: "Total number of queries (or partial queries) whose execution has been skipped because the compaction summary reported no data in the queried time range.",
+	})
+
+	return MetricsQueryMiddlewareFunc(func(next MetricsQueryHandler) MetricsQueryHandler {
+		return &compactionSummaryPruningMiddleware{
+			next:           next,
+			summaries:      summaries,
+			maxStalePeriod: maxStalePeriod,
+			limits:         limits,
+			engine:         engine,
+			logger:         logger,
+			prunedQueries:  prunedQueries,
+		}
+	})
+}
+
+func (m *compactionSummaryPruningMiddleware) Do(ctx context.Context, r MetricsQueryRequest) (Response, error) {
+	spanLog, ctx := spanlogger.NewWithLogger(ctx, m.logger, "compactionSummaryPruningMiddleware.Do")
+	defer spanLog.Finish()
+
+	tenantIDs, err := tenant.TenantIDs(ctx)
+	if err != nil {
+		return m.next.Do(ctx, r)
+	}
+
+	if !m.canPrune(ctx, spanLog, tenantIDs, r) {
+		return m.next.Do(ctx, r)
+	}
+
+	qry, err := newQuery(ctx, r, m.engine, emptyQueryable)
+	if err != nil {
+		// The query will be rejected downstream, with a better error message.
+		return m.next.Do(ctx, r)
+	}
+	defer qry.Close()
+
+	res := qry.Exec(ctx)
+	extracted, err := promqlResultToSamples(res)
+	if err != nil {
+		return nil, mapEngineError(err)
+	}
+
+	m.prunedQueries.Inc()
+	level.Debug(spanLog).Log(
+		"msg", "skipped the execution of the query because the compaction summary reports no data in the queried time range",
+		"minT", util.FormatTimeMillis(r.GetMinT()),
+		"maxT", util.FormatTimeMillis(r.GetMaxT()),
+	)
+
+	warn, info := res.Warnings.AsStrings(r.GetQuery(), 0, 0)
+	return &PrometheusResponse{
+		Status: statusSuccess,
+		Data: &PrometheusData{
+			ResultType: string(res.Value.Type()),
+			Result:     extracted,
+		},
+		Warnings: warn,
+		Infos:    info,
+	}, nil
+}
+
+// canPrune returns whether the time range of data queried by the request is only stored in the
+// storage (it's not queried from ingesters) and the compaction summaries of all tenants report
+// no data in that time range.
+func (m *compactionSummaryPruningMiddleware) canPrune(ctx context.Context, spanLog *spanlogger.SpanLogger, tenantIDs []string, r MetricsQueryRequest) bool {
+	// A query ingesters within set to 0 means ingesters are always queried.
+	if validation.MinDurationPerTenant(tenantIDs, m.limits.QueryIngestersWithin) <= 0 {
+		return false
+	}
+	queryIngestersWithin := validation.MaxDurationPerTenant(tenantIDs, m.limits.QueryIngestersWithin)
+	if r.GetMaxT() >= util.TimeToMillis(time.Now().Add(-queryIngestersWithin)) {
+		return false
+	}
+
+	for _, tenantID := range tenantIDs {
+		summary, err := m.summaries.GetSummary(ctx, tenantID)
+		if err != nil {
+			level.Debug(spanLog).Log("msg", "unable to get the compaction summary", "user", tenantID, "err", err)
+			return false
+		}
+
+		if m.maxStalePeriod > 0 && time.Since(summary.GetUpdatedAt()) > m.maxStalePeriod {
+			level.Debug(spanLog).Log("msg", "the compaction summary is stale", "user", tenantID, "updated_at", summary.GetUpdatedAt())
+			return false
+		}
+
+		if summary.HasDataWithin(r.GetMinT(), r.GetMaxT()) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,179 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querymiddleware
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/dskit/user"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/atomic"
+
+	"github.com/grafana/mimir/pkg/storage/tsdb/bucketindex"
+	"github.com/grafana/mimir/pkg/util"
+)
+
+type mockCompactionSummaryReader map[string]*bucketindex.Summary
+
+func (m mockCompactionSummaryReader) GetSummary(_ context.Context, userID string) (*bucketindex.Summary, error) {
+	summary, ok := m[userID]
+	if !ok {
+		return nil, bucketindex.ErrSummaryNotFound
+	}
+	return summary, nil
+}
+
+func TestCompactionSummaryPruningMiddleware(t *testing.T) {
+	const queryIngestersWithin = 13 * time.Hour
+
+	now := time.Now()
+	freshUpdate := now.Add(-time.Minute).Unix()
+
+	// Query a time range of 1 hour, 2 days ago.
+	start := util.TimeToMillis(now.Add(-48 * time.Hour))
+	end := util.TimeToMillis(now.Add(-47 * time.Hour))
+
+	blockOutsideRange := bucketindex.SummaryBlock{MinTime: util.TimeToMillis(now.Add(-96 * time.Hour)), MaxTime: util.TimeToMillis(now.Add(-72 * time.Hour))}
+	blockWithinRange := bucketindex.SummaryBlock{MinTime: util.TimeToMillis(now.Add(-72 * time.Hour)), MaxTime: util.TimeToMillis(now.Add(-24 * time.Hour))}
+
+	tests := map[string]struct {
+		query                string
+		start, end           int64
+		summaries            mockCompactionSummaryReader
+		queryIngestersWithin time.Duration
+		expectedPruned       bool
+		expectedSamples      int
+	}{
+		"should prune the query if the summary has no block within the queried time range": {
+			query:                "sum(metric)",
+			summaries:            mockCompactionSummaryReader{"user-1": {Blocks: []bucketindex.SummaryBlock{blockOutsideRange}, UpdatedAt: freshUpdate}},
+			queryIngestersWithin: queryIngestersWithin,
+			expectedPruned:       true,
+		},
+		"should evaluate the pruned query against an empty storage": {
+			query:                "absent(metric)",
+			summaries:            mockCompactionSummaryReader{"user-1": {UpdatedAt: freshUpdate}},
+			queryIngestersWithin: queryIngestersWithin,
+			expectedPruned:       true,
+			expectedSamples:      61,
+		},
+		"should not prune the query if the summary has a block within the queried time range": {
+			query:                "sum(metric)",
+			summaries:            mockCompactionSummaryReader{"user-1": {Blocks: []bucketindex.SummaryBlock{blockOutsideRange, blockWithinRange}, UpdatedAt: freshUpdate}},
+			queryIngestersWithin: queryIngestersWithin,
+		},
+		"should not prune the query if the range selector reaches a block": {
+			query:                "sum(rate(metric[2d]))",
+			summaries:            mockCompactionSummaryReader{"user-1": {Blocks: []bucketindex.SummaryBlock{blockOutsideRange}, UpdatedAt: freshUpdate}},
+			queryIngestersWithin: queryIngestersWithin,
+		},
+		"should not prune the query if the queried time range could be in the ingesters": {
+			query:                "sum(metric)",
+			summaries:            mockCompactionSummaryReader{"user-1": {UpdatedAt: freshUpdate}},
+			queryIngestersWithin: 72 * time.Hour,
+		},
+		"should not prune the query if ingesters are always queried": {
+			query:                "sum(metric)",
+			summaries:            mockCompactionSummaryReader{"user-1": {UpdatedAt: freshUpdate}},
+			queryIngestersWithin: 0,
+		},
+		"should not prune the query if the summary doesn't exist": {
+			query:                "sum(metric)",
+			summaries:            mockCompactionSummaryReader{},
+			queryIngestersWithin: queryIngestersWithin,
+		},
+		"should not prune the query if the summary is stale": {
+			query:                "sum(metric)",
+			summaries:            mockCompactionSummaryReader{"user-1": {UpdatedAt: now.Add(-2 * time.Hour).Unix()}},
+			queryIngestersWithin: queryIngestersWithin,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			downstreamCalls := atomic.NewInt64(0)
+			downstream := HandlerFunc(func(context.Context, MetricsQueryRequest) (Response, error) {
+				downstreamCalls.Inc()
+				return newEmptyPrometheusResponse(), nil
+			})
+
+			mw := newCompactionSummaryPruningMiddleware(tc.summaries, time.Hour, mockLimits{queryIngestersWithin: tc.queryIngestersWithin}, newEngine(), log.NewNopLogger(), prometheus.NewPedanticRegistry())
+
+			req := NewPrometheusRangeQueryRequest("/api/v1/query_range", nil, start, end, time.Minute.Milliseconds(), lookbackDelta, parseQuery(t, tc.query), Options{}, nil)
+			res, err := mw.Wrap(downstream).Do(user.InjectOrgID(context.Background(), "user-1"), req)
+			require.NoError(t, err)
+
+			if !tc.expectedPruned {
+				assert.Equal(t, int64(1), downstreamCalls.Load())
+				return
+			}
+
+			assert.Equal(t, int64(0), downstreamCalls.Load())
+			samples := 0
+			for _, s := range res.(*PrometheusResponse).Data.Result {
+				samples += len(s.Samples)
+			}
+			assert.Equal(t, tc.expectedSamples, samples)
+		})
+	}
+}
+
+func TestCompactionSummaryPruningMiddleware_MultipleTenants(t *testing.T) {
+	now := time.Now()
+	start := util.TimeToMillis(now.Add(-48 * time.Hour))
+	end := util.TimeToMillis(now.Add(-47 * time.Hour))
+
+	summaries := mockCompactionSummaryReader{
+		"user-1": {UpdatedAt: now.Unix()},
+		"user-2": {Blocks: []bucketindex.SummaryBlock{{MinTime: start, MaxTime: end}}, UpdatedAt: now.Unix()},
+	}
+
+	downstreamCalls := atomic.NewInt64(0)
+	downstream := HandlerFunc(func(context.Context, MetricsQueryRequest) (Response, error) {
+		downstreamCalls.Inc()
+		return newEmptyPrometheusResponse(), nil
+	})
+
+	mw := newCompactionSummaryPruningMiddleware(summaries, time.Hour, mockLimits{queryIngestersWithin: 13 * time.Hour}, newEngine(), log.NewNopLogger(), nil)
+	req := NewPrometheusInstantQueryRequest("/api/v1/query", nil, end, lookbackDelta, parseQuery(t, "metric"), Options{}, nil)
+
+	// The query can't be pruned because one of the tenants has data in the queried time range.
+	_, err := mw.Wrap(downstream).Do(user.InjectOrgID(context.Background(), "user-1|user-2"), req)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), downstreamCalls.Load())
+
+	_, err = mw.Wrap(downstream).Do(user.InjectOrgID(context.Background(), "user-1"), req)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), downstreamCalls.Load())
+}
+
+func TestCompactionSummaryPruningMiddleware_ShouldNotPruneOnSummaryError(t *testing.T) {
+	downstreamCalls := atomic.NewInt64(0)
+	downstream := HandlerFunc(func(context.Context, MetricsQueryRequest) (Response, error) {
+		downstreamCalls.Inc()
+		return newEmptyPrometheusResponse(), nil
+	})
+
+	reader := compactionSummaryReaderFunc(func(context.Context, string) (*bucketindex.Summary, error) {
+		return nil, errors.New("storage unavailable")
+	})
+
+	mw := newCompactionSummaryPruningMiddleware(reader, time.Hour, mockLimits{queryIngestersWithin: 13 * time.Hour}, newEngine(), log.NewNopLogger(), nil)
+	req := NewPrometheusInstantQueryRequest("/api/v1/query", nil, util.TimeToMillis(time.Now().Add(-48*time.Hour)), lookbackDelta, parseQuery(t, "metric"), Options{}, nil)
+
+	_, err := mw.Wrap(downstream).Do(user.InjectOrgID(context.Background(), "user-1"), req)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), downstreamCalls.Load())
+}
+
+type compactionSummaryReaderFunc func(ctx context.Context, userID string) (*bucketindex.Summary, error)
+
+func (f compactionSummaryReaderFunc) GetSummary(ctx context.Context, userID string) (*bucketindex.Summary, error) {
+	return f(ctx, userID)
+}
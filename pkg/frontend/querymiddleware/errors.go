@@ -28,3 +28,17 @@ func newMaxQueryExpressionSizeBytesError(actualSizeBytes, maxQuerySizeBytes int)
 func newQueryBlockedError() error {
 	return apierror.New(apierror.TypeBadData, globalerror.QueryBlocked.Message("the request has been blocked by the cluster administrator"))
 }
+
+func newQueryBytesBudgetExhaustedError(maxQueryBytesPerDay int) error {
+	return apierror.New(apierror.TypeTooManyRequests, globalerror.QueryBytesBudgetExhausted.MessageWithPerTenantLimitConfig(
+		fmt.Sprintf("the daily budget of bytes fetched by queries has been exhausted (limit: %d bytes)", maxQueryBytesPerDay),
+		validation.MaxQueryBytesPerDayFlag,
+	))
+}
+
+func newExperimentalFunctionNotEnabledError(name string) error {
+	return apierror.New(apierror.TypeBadData, globalerror.QueryExperimentalFunction.MessageWithPerTenantLimitConfig(
+		fmt.Sprintf("the query uses the experimental PromQL function or aggregation %q, which is not enabled", name),
+		validation.EnabledPromQLExperimentalFunctionsFlag,
+	))
+}
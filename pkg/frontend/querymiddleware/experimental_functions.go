@@ -0,0 +1,102 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querymiddleware
+
+import (
+	"context"
+	"slices"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/grafana/dskit/tenant"
+	"github.com/prometheus/prometheus/promql/parser"
+
+	apierror "github.com/grafana/mimir/pkg/api/error"
+	util_log "github.com/grafana/mimir/pkg/util/log"
+	"github.com/grafana/mimir/pkg/util/validation"
+)
+
+// experimentalAggregations are the experimental PromQL aggregations, which, unlike the
+// experimental functions, aren't flagged as such by the PromQL parser.
+var experimentalAggregations = map[parser.ItemType]string{
+	parser.LIMITK:      "limitk",
+	parser.LIMIT_RATIO: "limit_ratio",
+}
+
+// experimentalFunctionsMiddleware rejects the queries using experimental PromQL functions or
+// aggregations which are not enabled for the tenant. It must run before the query is split,
+// sharded or cached, so that a disabled function is never executed nor served from the cache.
+type experimentalFunctionsMiddleware struct {
+	next   MetricsQueryHandler
+	limits Limits
+	logger log.Logger
+}
+
+func newExperimentalFunctionsMiddleware(limits Limits, logger log.Logger) MetricsQueryMiddleware {
+	return MetricsQueryMiddlewareFunc(func(next MetricsQueryHandler) MetricsQueryHandler {
+		return &experimentalFunctionsMiddleware{
+			next:   next,
+			limits: limits,
+			logger: logger,
+		}
+	})
+}
+
+func (m *experimentalFunctionsMiddleware) Do(ctx context.Context, req MetricsQueryRequest) (Response, error) {
+	tenantIDs, err := tenant.TenantIDs(ctx)
+	if err != nil {
+		return nil, apierror.New(apierror.TypeBadData, err.Error())
+	}
+
+	enabledByTenant := make(map[string][]string, len(tenantIDs))
+	for _, tenantID := range tenantIDs {
+		enabled := m.limits.EnabledPromQLExperimentalFunctions(tenantID)
+		if !slices.Contains(enabled, validation.AllPromQLExperimentalFunctions) {
+			enabledByTenant[tenantID] = enabled
+		}
+	}
+
+	// Skip parsing the query if all the experimental functions are enabled for all tenants.
+	if len(enabledByTenant) == 0 {
+		return m.next.Do(ctx, req)
+	}
+
+	expr, err := parser.ParseExpr(req.GetQuery())
+	if err != nil {
+		// The query can't be parsed: let the downstream middlewares handle the error.
+		return m.next.Do(ctx, req)
+	}
+
+	for _, name := range experimentalFunctions(expr) {
+		for tenantID, enabled := range enabledByTenant {
+			if !slices.Contains(enabled, name) {
+				level.Debug(util_log.WithContext(ctx, m.logger)).Log("msg", "rejected query using an experimental PromQL function which is not enabled", "user", tenantID, "function", name)
+				return nil, newExperimentalFunctionNotEnabledError(name)
+			}
+		}
+	}
+
+	return m.next.Do(ctx, req)
+}
+
+// experimentalFunctions returns the names of the experimental PromQL functions and aggregations used by the input expression.
+func experimentalFunctions(expr parser.Expr) []string {
+	var names []string
+	parser.Inspect(expr, func(node parser.Node, _ []parser.Node) error {
+		var name string
+		switch n := node.(type) {
+		case *parser.Call:
+			if n.Func.Experimental {
+				name = n.Func.Name
+			}
+		case *parser.AggregateExpr:
+			name = experimentalAggregations[n.Op]
+		}
+
+		if name != "" && !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+		return nil
+	})
+	return names
+}
@@ -0,0 +1,120 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querymiddleware
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/dskit/user"
+	"github.com/prometheus/prometheus/promql/parser"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/mimir/pkg/util/globalerror"
+	"github.com/grafana/mimir/pkg/util/validation"
+)
+
+func TestExperimentalFunctionsMiddleware(t *testing.T) {
+	t.Cleanup(func() { parser.EnableExperimentalFunctions = false })
+	parser.EnableExperimentalFunctions = true
+
+	tests := map[string]struct {
+		query            string
+		enabled          []string
+		expectedRejected bool
+	}{
+		"query without experimental functions, all disabled": {
+			query:   `sum(rate(metric_counter[5m]))`,
+			enabled: []string{},
+		},
+		"query with experimental function, all enabled": {
+			query:   `sort_by_label(metric_counter, "pod")`,
+			enabled: []string{validation.AllPromQLExperimentalFunctions},
+		},
+		"query with experimental function, all disabled": {
+			query:            `sort_by_label(metric_counter, "pod")`,
+			enabled:          []string{},
+			expectedRejected: true,
+		},
+		"query with experimental function, function enabled": {
+			query:   `sort_by_label(metric_counter, "pod")`,
+			enabled: []string{"sort_by_label"},
+		},
+		"query with experimental function, another function enabled": {
+			query:            `sort_by_label(metric_counter, "pod")`,
+			enabled:          []string{"sort_by_label_desc"},
+			expectedRejected: true,
+		},
+		"query with nested experimental function, function enabled": {
+			query:   `sum(mad_over_time(metric_gauge[5m])) / 2`,
+			enabled: []string{"mad_over_time"},
+		},
+		"query with experimental aggregation, aggregation disabled": {
+			query:            `limitk(5, metric_counter)`,
+			enabled:          []string{"sort_by_label"},
+			expectedRejected: true,
+		},
+		"query with experimental aggregation, aggregation enabled": {
+			query:   `limitk(5, metric_counter)`,
+			enabled: []string{"limitk"},
+		},
+		"query with multiple experimental functions, only some enabled": {
+			query:            `sort_by_label(limit_ratio(0.5, metric_counter), "pod")`,
+			enabled:          []string{"sort_by_label"},
+			expectedRejected: true,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			reqs := map[string]MetricsQueryRequest{
+				"range query": &PrometheusRangeQueryRequest{
+					queryExpr: parseQuery(t, tt.query),
+				},
+				"instant query": &PrometheusInstantQueryRequest{
+					queryExpr: parseQuery(t, tt.query),
+				},
+			}
+
+			for reqType, req := range reqs {
+				t.Run(reqType, func(t *testing.T) {
+					limits := mockLimits{enabledPromQLExperimentalFunctions: tt.enabled}
+					mw := newExperimentalFunctionsMiddleware(limits, log.NewNopLogger())
+					_, err := mw.Wrap(&mockNextHandler{t: t, shouldContinue: !tt.expectedRejected}).Do(user.InjectOrgID(context.Background(), "test"), req)
+
+					if tt.expectedRejected {
+						require.Error(t, err)
+						require.Contains(t, err.Error(), globalerror.QueryExperimentalFunction)
+					} else {
+						require.NoError(t, err)
+					}
+				})
+			}
+		})
+	}
+}
+
+func TestExperimentalFunctionsMiddleware_MultipleTenants(t *testing.T) {
+	t.Cleanup(func() { parser.EnableExperimentalFunctions = false })
+	parser.EnableExperimentalFunctions = true
+
+	limits := multiTenantMockLimits{
+		byTenant: map[string]mockLimits{
+			"tenant-1": {enabledPromQLExperimentalFunctions: []string{validation.AllPromQLExperimentalFunctions}},
+			"tenant-2": {enabledPromQLExperimentalFunctions: []string{"sort_by_label"}},
+			"tenant-3": {enabledPromQLExperimentalFunctions: []string{}},
+		},
+	}
+	req := &PrometheusRangeQueryRequest{queryExpr: parseQuery(t, `sort_by_label(metric_counter, "pod")`)}
+	mw := newExperimentalFunctionsMiddleware(limits, log.NewNopLogger())
+
+	// The query is accepted only if the function is enabled for all the queried tenants.
+	_, err := mw.Wrap(&mockNextHandler{t: t, shouldContinue: true}).Do(user.InjectOrgID(context.Background(), "tenant-1|tenant-2"), req)
+	require.NoError(t, err)
+
+	_, err = mw.Wrap(&mockNextHandler{t: t, shouldContinue: false}).Do(user.InjectOrgID(context.Background(), "tenant-1|tenant-3"), req)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), globalerror.QueryExperimentalFunction)
+}
@@ -0,0 +1,285 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querymiddleware
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/grafana/dskit/tenant"
+	"github.com/prometheus/prometheus/model/timestamp"
+	"github.com/prometheus/prometheus/promql/parser"
+
+	apierror "github.com/grafana/mimir/pkg/api/error"
+	"github.com/grafana/mimir/pkg/querier"
+	"github.com/grafana/mimir/pkg/util"
+	"github.com/grafana/mimir/pkg/util/validation"
+)
+
+const explainParam = "explain"
+
+// QueryExplanation describes how the query-frontend processes a range or instant query: how the query is split
+// and sharded, which parts of it are served from the results cache, and which requests would be sent to the
+// queriers. It's built when the query is run with the explain parameter, in which case the downstream requests
+// are not executed.
+type QueryExplanation struct {
+	Query string    `json:"query"`
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+	Step  int64     `json:"step,omitempty"`
+
+	// SplitQueries are the time ranges the query has been split into by the split by interval middleware.
+	SplitQueries []QueryExplanationTimeRange `json:"split_queries,omitempty"`
+	// CachedExtents are the time ranges served from the results cache.
+	CachedExtents []QueryExplanationTimeRange `json:"cached_extents,omitempty"`
+	// InstantSplitQueries is the number of queries an instant query has been split into.
+	InstantSplitQueries int `json:"instant_split_queries,omitempty"`
+	// TotalShards is the highest number of shards used to shard the query, and ShardedQueries is the number of
+	// sharded queries across all the split queries.
+	TotalShards    int `json:"total_shards,omitempty"`
+	ShardedQueries int `json:"sharded_queries,omitempty"`
+
+	// DownstreamRequests are the requests which would be sent to the queriers.
+	DownstreamRequests []QueryExplanationRequest `json:"downstream_requests"`
+
+	mtx                  sync.Mutex
+	now                  time.Time
+	queryIngestersWithin time.Duration
+	queryStoreAfter      time.Duration
+}
+
+// QueryExplanationTimeRange is a time range of a QueryExplanation.
+type QueryExplanationTimeRange struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// QueryExplanationRequest is a request that would be sent to the queriers, along with the time ranges
+// which would be queried from the ingesters and the store-gateways.
+type QueryExplanationRequest struct {
+	Query string    `json:"query"`
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+
+	Ingesters     *QueryExplanationTimeRange `json:"ingesters,omitempty"`
+	StoreGateways *QueryExplanationTimeRange `json:"store_gateways,omitempty"`
+}
+
+type explanationContextKey int
+
+const queryExplanationContextKey = explanationContextKey(0)
+
+// ContextWithQueryExplanation returns a context with the given QueryExplanation.
+func ContextWithQueryExplanation(ctx context.Context, explanation *QueryExplanation) context.Context {
+	return context.WithValue(ctx, queryExplanationContextKey, explanation)
+}
+
+// QueryExplanationFromContext returns the QueryExplanation from the context, or nil if the query
+// is not being explained.
+func QueryExplanationFromContext(ctx context.Context) *QueryExplanation {
+	o := ctx.Value(queryExplanationContextKey)
+	if o == nil {
+		return nil
+	}
+	return o.(*QueryExplanation)
+}
+
+func (e *QueryExplanation) setRequest(req MetricsQueryRequest) {
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+
+	e.Query = req.GetQuery()
+	e.Start = timestamp.Time(req.GetStart())
+	e.End = timestamp.Time(req.GetEnd())
+	e.Step = req.GetStep()
+}
+
+func (e *QueryExplanation) addSplitQuery(start, end int64) {
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+
+	e.SplitQueries = append(e.SplitQueries, newQueryExplanationTimeRange(start, end))
+}
+
+func (e *QueryExplanation) addCachedExtent(start, end int64) {
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+
+	e.CachedExtents = append(e.CachedExtents, newQueryExplanationTimeRange(start, end))
+}
+
+func (e *QueryExplanation) addInstantSplitQueries(count int) {
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+
+	e.InstantSplitQueries += count
+}
+
+func (e *QueryExplanation) addShardedQueries(totalShards, shardedQueries int) {
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+
+	e.TotalShards = max(e.TotalShards, totalShards)
+	e.ShardedQueries += shardedQueries
+}
+
+func (e *QueryExplanation) addDownstreamRequest(req MetricsQueryRequest) {
+	res := QueryExplanationRequest{
+		Query: req.GetQuery(),
+		Start: timestamp.Time(req.GetStart()),
+		End:   timestamp.Time(req.GetEnd()),
+	}
+
+	// Mimic the querier, which queries the ingesters and the store-gateways based on the time range
+	// of the data which is queried.
+	minT, maxT := req.GetMinT(), req.GetMaxT()
+	if querier.ShouldQueryIngesters(e.queryIngestersWithin, e.now, maxT) {
+		ingestersMinT := minT
+		if e.queryIngestersWithin != 0 {
+			ingestersMinT = max(minT, util.TimeToMillis(e.now.Add(-e.queryIngestersWithin)))
+		}
+		r := newQueryExplanationTimeRange(ingestersMinT, maxT)
+		res.Ingesters = &r
+	}
+	if querier.ShouldQueryBlockStore(e.queryStoreAfter, e.now, minT) {
+		storeMaxT := maxT
+		if e.queryStoreAfter != 0 {
+			storeMaxT = min(maxT, util.TimeToMillis(e.now.Add(-e.queryStoreAfter)))
+		}
+		r := newQueryExplanationTimeRange(minT, storeMaxT)
+		res.StoreGateways = &r
+	}
+
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+
+	e.DownstreamRequests = append(e.DownstreamRequests, res)
+}
+
+func newQueryExplanationTimeRange(start, end int64) QueryExplanationTimeRange {
+	return QueryExplanationTimeRange{Start: timestamp.Time(start), End: timestamp.Time(end)}
+}
+
+// explainRoundTripper returns the QueryExplanation of the range and instant queries with the explain
+// parameter, instead of their result.
+type explainRoundTripper struct {
+	next            http.RoundTripper
+	codec           Codec
+	limits          Limits
+	queryStoreAfter time.Duration
+}
+
+func newExplainRoundTripper(next http.RoundTripper, codec Codec, limits Limits, queryStoreAfter time.Duration) http.RoundTripper {
+	return &explainRoundTripper{
+		next:            next,
+		codec:           codec,
+		limits:          limits,
+		queryStoreAfter: queryStoreAfter,
+	}
+}
+
+func (rt *explainRoundTripper) RoundTrip(r *http.Request) (*http.Response, error) {
+	// The form may have already been parsed, in which case the body has already been consumed.
+	params := r.Form
+	if params == nil {
+		var err error
+		if params, err = util.ParseRequestFormWithoutConsumingBody(r); err != nil {
+			return rt.next.RoundTrip(r)
+		}
+	}
+	if params.Get(explainParam) == "" {
+		return rt.next.RoundTrip(r)
+	}
+
+	explain, err := strconv.ParseBool(params.Get(explainParam))
+	if err != nil {
+		return nil, apierror.Newf(apierror.TypeBadData, "invalid %s parameter: %s", explainParam, err.Error())
+	}
+	if !explain {
+		return rt.next.RoundTrip(r)
+	}
+
+	tenantIDs, err := tenant.TenantIDs(r.Context())
+	if err != nil {
+		return nil, apierror.New(apierror.TypeBadData, err.Error())
+	}
+
+	req, err := rt.codec.DecodeMetricsQueryRequest(r.Context(), r)
+	if err != nil {
+		return nil, err
+	}
+
+	explanation := &QueryExplanation{
+		now:                  time.Now(),
+		queryIngestersWithin: validation.MaxDurationPerTenant(tenantIDs, rt.limits.QueryIngestersWithin),
+		queryStoreAfter:      rt.queryStoreAfter,
+	}
+	explanation.setRequest(req)
+
+	res, err := rt.next.RoundTrip(r.WithContext(ContextWithQueryExplanation(r.Context(), explanation)))
+	if err != nil {
+		return nil, err
+	}
+	// The response of the query is empty, because the downstream requests haven't been executed.
+	_, _ = io.Copy(io.Discard, res.Body)
+	_ = res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, apierror.Newf(apierror.TypeInternal, "failed to explain the query: unexpected status code %d", res.StatusCode)
+	}
+
+	explanation.mtx.Lock()
+	defer explanation.mtx.Unlock()
+
+	body, err := json.Marshal(struct {
+		Status string            `json:"status"`
+		Data   *QueryExplanation `json:"data"`
+	}{
+		Status: statusSuccess,
+		Data:   explanation,
+	})
+	if err != nil {
+		return nil, apierror.New(apierror.TypeInternal, err.Error())
+	}
+
+	return &http.Response{
+		Header: http.Header{
+			"Content-Type": []string{jsonMimeType},
+		},
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		StatusCode:    http.StatusOK,
+		ContentLength: int64(len(body)),
+	}, nil
+}
+
+// explainHandler records the downstream requests of the queries being explained, and returns an empty
+// response instead of executing them.
+type explainHandler struct {
+	next MetricsQueryHandler
+}
+
+func (h explainHandler) Do(ctx context.Context, r MetricsQueryRequest) (Response, error) {
+	explanation := QueryExplanationFromContext(ctx)
+	if explanation == nil {
+		return h.next.Do(ctx, r)
+	}
+
+	explanation.addDownstreamRequest(r)
+
+	resultType := parser.ValueTypeMatrix
+	if _, ok := r.(*PrometheusInstantQueryRequest); ok {
+		resultType = parser.ValueTypeVector
+	}
+	return &PrometheusResponse{
+		Status: statusSuccess,
+		Data: &PrometheusData{
+			ResultType: string(resultType),
+			Result:     []SampleStream{},
+		},
+	}, nil
+}
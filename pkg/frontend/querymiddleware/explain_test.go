@@ -0,0 +1,231 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querymiddleware
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/dskit/cache"
+	"github.com/grafana/dskit/user"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/promql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/atomic"
+
+	"github.com/grafana/mimir/pkg/mimirpb"
+)
+
+func TestExplainRoundTripper(t *testing.T) {
+	// The query is split into two days. The first day is before the ingesters' query window, which
+	// starts in the middle of the second day.
+	end := time.Now().UTC().Truncate(24 * time.Hour).Add(-time.Hour)
+	start := end.Add(-46 * time.Hour)
+	queryIngestersWithin := time.Since(end.Add(-12 * time.Hour))
+
+	downstreamCalls := atomic.NewInt32(0)
+	downstream := RoundTripFunc(func(*http.Request) (*http.Response, error) {
+		downstreamCalls.Inc()
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Type": []string{jsonMimeType}},
+			Body:       io.NopCloser(strings.NewReader(`{"status":"success","data":{"resultType":"matrix","result":[]}}`)),
+		}, nil
+	})
+
+	tw, err := NewTripperware(
+		Config{
+			SplitQueriesByInterval: 24 * time.Hour,
+			ShardedQueries:         true,
+		},
+		log.NewNopLogger(),
+		mockLimits{totalShards: 2, queryIngestersWithin: queryIngestersWithin},
+		newTestPrometheusCodec(),
+		nil,
+		promql.EngineOpts{
+			Logger:     log.NewNopLogger(),
+			MaxSamples: 1000,
+			Timeout:    time.Minute,
+		},
+		true,
+		nil,
+		prometheus.NewPedanticRegistry(),
+	)
+	require.NoError(t, err)
+	rt := tw(downstream)
+
+	explain := func(t *testing.T, path string, params url.Values) *QueryExplanation {
+		ctx := user.InjectOrgID(context.Background(), "user-1")
+		req := httptest.NewRequest("GET", path+"?"+params.Encode(), http.NoBody).WithContext(ctx)
+		require.NoError(t, user.InjectOrgIDIntoHTTPRequest(ctx, req))
+
+		res, err := rt.RoundTrip(req)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, res.StatusCode)
+
+		body, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+
+		var explanation struct {
+			Status string            `json:"status"`
+			Data   *QueryExplanation `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(body, &explanation))
+		return explanation.Data
+	}
+
+	t.Run("range query", func(t *testing.T) {
+		downstreamCalls.Store(0)
+
+		explanation := explain(t, "/api/v1/query_range", url.Values{
+			"query":   []string{"sum(rate(metric[5m]))"},
+			"start":   []string{start.Format(time.RFC3339)},
+			"end":     []string{end.Format(time.RFC3339)},
+			"step":    []string{"3600"},
+			"explain": []string{"true"},
+		})
+
+		assert.Zero(t, downstreamCalls.Load())
+		assert.Equal(t, "sum(rate(metric[5m]))", explanation.Query)
+		assert.Len(t, explanation.SplitQueries, 2)
+		assert.Empty(t, explanation.CachedExtents)
+		assert.Equal(t, 2, explanation.TotalShards)
+		assert.Equal(t, 4, explanation.ShardedQueries)
+
+		// Each split query is sharded.
+		require.Len(t, explanation.DownstreamRequests, 4)
+		for _, req := range explanation.DownstreamRequests {
+			assert.Contains(t, req.Query, "__query_shard__")
+
+			// The first day is only queried from the store-gateways, while the second one is
+			// queried from both the ingesters and the store-gateways.
+			require.NotNil(t, req.StoreGateways)
+			if !req.End.After(end.Add(-24 * time.Hour)) {
+				assert.Nil(t, req.Ingesters)
+			} else {
+				require.NotNil(t, req.Ingesters)
+				assert.WithinDuration(t, end.Add(-12*time.Hour), req.Ingesters.Start, time.Minute)
+				assert.Equal(t, req.End, req.Ingesters.End)
+			}
+		}
+	})
+
+	t.Run("instant query", func(t *testing.T) {
+		downstreamCalls.Store(0)
+
+		explanation := explain(t, "/api/v1/query", url.Values{
+			"query":   []string{"metric"},
+			"time":    []string{end.Format(time.RFC3339)},
+			"explain": []string{"1"},
+		})
+
+		assert.Zero(t, downstreamCalls.Load())
+		assert.Empty(t, explanation.SplitQueries)
+		require.Len(t, explanation.DownstreamRequests, 1)
+		assert.Equal(t, "metric", explanation.DownstreamRequests[0].Query)
+	})
+
+	t.Run("explain disabled", func(t *testing.T) {
+		downstreamCalls.Store(0)
+
+		ctx := user.InjectOrgID(context.Background(), "user-1")
+		req := httptest.NewRequest("GET", "/api/v1/query_range?"+url.Values{
+			"query":   []string{"metric"},
+			"start":   []string{start.Format(time.RFC3339)},
+			"end":     []string{end.Format(time.RFC3339)},
+			"step":    []string{"3600"},
+			"explain": []string{"false"},
+		}.Encode(), http.NoBody).WithContext(ctx)
+		require.NoError(t, user.InjectOrgIDIntoHTTPRequest(ctx, req))
+
+		res, err := rt.RoundTrip(req)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, int32(2), downstreamCalls.Load())
+	})
+
+	t.Run("invalid explain parameter", func(t *testing.T) {
+		ctx := user.InjectOrgID(context.Background(), "user-1")
+		req := httptest.NewRequest("GET", "/api/v1/query?query=metric&explain=maybe", http.NoBody).WithContext(ctx)
+		require.NoError(t, user.InjectOrgIDIntoHTTPRequest(ctx, req))
+
+		_, err := rt.RoundTrip(req)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid explain parameter")
+	})
+}
+
+func TestSplitAndCacheMiddleware_Explain(t *testing.T) {
+	cacheBackend := cache.NewInstrumentedMockCache()
+
+	mw := newSplitAndCacheMiddleware(
+		true,
+		true,
+		24*time.Hour,
+		mockLimits{maxCacheFreshness: 10 * time.Minute, resultsCacheTTL: resultsCacheTTL, resultsCacheOutOfOrderWindowTTL: resultsCacheLowerTTL},
+		newTestPrometheusCodec(),
+		cacheBackend,
+		DefaultCacheKeyGenerator{interval: day},
+		PrometheusResponseExtractor{},
+		resultsCacheAlwaysEnabled,
+		log.NewNopLogger(),
+		prometheus.NewPedanticRegistry(),
+	)
+
+	downstreamReqs := 0
+	handler := mw.Wrap(explainHandler{next: HandlerFunc(func(context.Context, MetricsQueryRequest) (Response, error) {
+		downstreamReqs++
+		return &PrometheusResponse{
+			Status: statusSuccess,
+			Data: &PrometheusData{
+				ResultType: model.ValMatrix.String(),
+				Result: []SampleStream{{
+					Labels:  []mimirpb.LabelAdapter{{Name: "foo", Value: "bar"}},
+					Samples: []mimirpb.Sample{{Value: 1, TimestampMs: 1634292000000}},
+				}},
+			},
+		}, nil
+	})})
+
+	step := int64(120 * 1000)
+	req := MetricsQueryRequest(&PrometheusRangeQueryRequest{
+		path:      "/api/v1/query_range",
+		start:     parseTimeRFC3339(t, "2021-10-15T10:00:00Z").Unix() * 1000,
+		end:       parseTimeRFC3339(t, "2021-10-15T12:00:00Z").Unix() * 1000,
+		step:      step,
+		queryExpr: parseQuery(t, `{__name__=~".+"}`),
+	})
+
+	// Populate the results cache.
+	ctx := user.InjectOrgID(context.Background(), "1")
+	_, err := handler.Do(ctx, req)
+	require.NoError(t, err)
+	require.Equal(t, 1, downstreamReqs)
+	require.Equal(t, 1, cacheBackend.CountStoreCalls())
+
+	// Explain the query with a later end time, which is partially cached.
+	req, err = req.WithStartEnd(req.GetStart(), req.GetEnd()+step)
+	require.NoError(t, err)
+
+	explanation := &QueryExplanation{now: time.Now()}
+	_, err = handler.Do(ContextWithQueryExplanation(ctx, explanation), req)
+	require.NoError(t, err)
+
+	// The downstream request is not executed, and the results cache is not updated.
+	assert.Equal(t, 1, downstreamReqs)
+	assert.Equal(t, 1, cacheBackend.CountStoreCalls())
+
+	assert.Equal(t, []QueryExplanationTimeRange{newQueryExplanationTimeRange(req.GetStart(), req.GetEnd())}, explanation.SplitQueries)
+	assert.Equal(t, []QueryExplanationTimeRange{newQueryExplanationTimeRange(req.GetStart(), req.GetEnd()-step)}, explanation.CachedExtents)
+	require.Len(t, explanation.DownstreamRequests, 1)
+	assert.Equal(t, time.UnixMilli(req.GetEnd()-step).UTC(), explanation.DownstreamRequests[0].Start.UTC())
+}
@@ -99,6 +99,9 @@ type Limits interface {
 	// BlockedQueries returns the blocked queries.
 	BlockedQueries(userID string) []*validation.BlockedQuery
 
+	// EnabledPromQLExperimentalFunctions returns the experimental PromQL functions and aggregations the tenant's queries can use.
+	EnabledPromQLExperimentalFunctions(userID string) []string
+
 	// AlignQueriesWithStep returns if queries should be adjusted to be step-aligned
 	AlignQueriesWithStep(userID string) bool
 
@@ -107,6 +110,12 @@ type Limits interface {
 
 	// IngestStorageReadConsistency returns the default read consistency for the tenant.
 	IngestStorageReadConsistency(userID string) string
+
+	// SplitQueriesByIntervalTimezone returns the IANA timezone name used to align the range queries split boundaries.
+	SplitQueriesByIntervalTimezone(userID string) string
+
+	// MaxQueryBytesPerDay returns the maximum number of bytes the tenant's queries can fetch per day. 0 means "unlimited".
+	MaxQueryBytesPerDay(userID string) int
 }
 
 type limitsMiddleware struct {
@@ -201,9 +210,11 @@ type limitedParallelismRoundTripper struct {
 // newLimitedParallelismRoundTripper creates a new roundtripper that enforces MaxQueryParallelism to the `next` roundtripper across `middlewares`.
 func newLimitedParallelismRoundTripper(next http.RoundTripper, codec Codec, limits Limits, middlewares ...MetricsQueryMiddleware) http.RoundTripper {
 	return limitedParallelismRoundTripper{
-		downstream: roundTripperHandler{
-			next:  next,
-			codec: codec,
+		downstream: explainHandler{
+			next: roundTripperHandler{
+				next:  next,
+				codec: codec,
+			},
 		},
 		codec:      codec,
 		limits:     limits,
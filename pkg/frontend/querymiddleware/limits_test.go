@@ -593,6 +593,18 @@ func (m multiTenantMockLimits) IngestStorageReadConsistency(userID string) strin
 	return m.byTenant[userID].ingestStorageReadConsistency
 }
 
+func (m multiTenantMockLimits) SplitQueriesByIntervalTimezone(userID string) string {
+	return m.byTenant[userID].splitQueriesByIntervalTimezone
+}
+
+func (m multiTenantMockLimits) MaxQueryBytesPerDay(userID string) int {
+	return m.byTenant[userID].maxQueryBytesPerDay
+}
+
+func (m multiTenantMockLimits) EnabledPromQLExperimentalFunctions(userID string) []string {
+	return m.byTenant[userID].EnabledPromQLExperimentalFunctions("")
+}
+
 type mockLimits struct {
 	maxQueryLookback                     time.Duration
 	maxQueryLength                       time.Duration
@@ -619,6 +631,9 @@ type mockLimits struct {
 	alignQueriesWithStep                 bool
 	queryIngestersWithin                 time.Duration
 	ingestStorageReadConsistency         string
+	splitQueriesByIntervalTimezone       string
+	maxQueryBytesPerDay                  int
+	enabledPromQLExperimentalFunctions   []string
 }
 
 func (m mockLimits) MaxQueryLookback(string) time.Duration {
@@ -723,6 +738,22 @@ func (m mockLimits) IngestStorageReadConsistency(string) string {
 	return m.ingestStorageReadConsistency
 }
 
+func (m mockLimits) SplitQueriesByIntervalTimezone(string) string {
+	return m.splitQueriesByIntervalTimezone
+}
+
+func (m mockLimits) MaxQueryBytesPerDay(string) int {
+	return m.maxQueryBytesPerDay
+}
+
+func (m mockLimits) EnabledPromQLExperimentalFunctions(string) []string {
+	// Enable all the experimental functions by default, like the default limits do.
+	if m.enabledPromQLExperimentalFunctions == nil {
+		return []string{validation.AllPromQLExperimentalFunctions}
+	}
+	return m.enabledPromQLExperimentalFunctions
+}
+
 type mockHandler struct {
 	mock.Mock
 }
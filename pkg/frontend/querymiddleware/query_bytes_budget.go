@@ -0,0 +1,198 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querymiddleware
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/grafana/dskit/tenant"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	util_log "github.com/grafana/mimir/pkg/util/log"
+)
+
+// queryBytesUsage is the number of bytes fetched by a tenant's queries during a day.
+type queryBytesUsage struct {
+	day int64
+
+	// bytes is the number of bytes fetched by the completed queries.
+	bytes int
+	// queries is the number of completed queries.
+	queries int
+	// reserved is the number of bytes reserved by the in-flight queries.
+	reserved int
+}
+
+// queryBytesReservation is the number of bytes reserved by an in-flight query in the tenant's daily budget.
+type queryBytesReservation struct {
+	tenantID string
+	day      int64
+	bytes    int
+}
+
+// queryBytesBudget tracks the bytes fetched by the tenants' queries during the current day, in UTC,
+// and rejects the queries of the tenants which have exhausted their daily budget.
+//
+// The budget is tracked in memory by each query-frontend replica, independently of the other replicas,
+// and it's reset when the query-frontend restarts.
+type queryBytesBudget struct {
+	limits Limits
+	logger log.Logger
+	now    func() time.Time
+
+	rejectedQueries *prometheus.CounterVec
+
+	mtx   sync.Mutex
+	usage map[string]*queryBytesUsage
+}
+
+func newQueryBytesBudget(limits Limits, logger log.Logger, registerer prometheus.Registerer) *queryBytesBudget {
+	return &queryBytesBudget{
+		limits: limits,
+		logger: logger,
+		now:    time.Now,
+		rejectedQueries: promauto.With(registerer).NewCounterVec(prometheus.CounterOpts{
+			Name: "cortex_query_frontend_query_bytes_budget_rejected_queries_total",
+			Help: "Number of queries rejected because the tenant's daily budget of bytes fetched by queries is exhausted.",
+		}, []string{"user"}),
+		usage: map[string]*queryBytesUsage{},
+	}
+}
+
+// Wrap implements MetricsQueryMiddleware.
+func (b *queryBytesBudget) Wrap(next MetricsQueryHandler) MetricsQueryHandler {
+	return HandlerFunc(func(ctx context.Context, req MetricsQueryRequest) (Response, error) {
+		tenantIDs, err := tenant.TenantIDs(ctx)
+		if err != nil {
+			return next.Do(ctx, req)
+		}
+
+		// The fetched bytes are tracked by the querier stats, which are only available when the query stats are enabled.
+		details := QueryDetailsFromContext(ctx)
+		if details == nil || details.QuerierStats == nil {
+			return next.Do(ctx, req)
+		}
+
+		reservations := make([]queryBytesReservation, 0, len(tenantIDs))
+		for _, tenantID := range tenantIDs {
+			maxBytes := b.limits.MaxQueryBytesPerDay(tenantID)
+			if maxBytes <= 0 {
+				continue
+			}
+
+			reservation, ok := b.reserve(tenantID, maxBytes)
+			if !ok {
+				b.cancel(reservations)
+				b.rejectedQueries.WithLabelValues(tenantID).Inc()
+				level.Info(util_log.WithContext(ctx, b.logger)).Log("msg", "rejected query because the daily query bytes budget is exhausted", "user", tenantID, "limit", maxBytes)
+				return nil, newQueryBytesBudgetExhaustedError(maxBytes)
+			}
+			reservations = append(reservations, reservation)
+		}
+
+		if len(reservations) == 0 {
+			return next.Do(ctx, req)
+		}
+
+		// The querier stats are shared by the whole request, so only account the bytes fetched from now on.
+		before := details.QuerierStats.LoadFetchedChunkBytes() + details.QuerierStats.LoadFetchedIndexBytes()
+		defer func() {
+			fetched := details.QuerierStats.LoadFetchedChunkBytes() + details.QuerierStats.LoadFetchedIndexBytes() - before
+
+			// Queries spanning multiple tenants are accounted in full to each of them.
+			b.release(reservations, int(fetched))
+		}()
+
+		return next.Do(ctx, req)
+	})
+}
+
+// reserve checks whether the tenant's daily budget is exhausted and, if it's not, reserves in the budget
+// the bytes which the query is expected to fetch, based on the average of the tenant's completed queries.
+// The check and the reservation are atomic, so that concurrent queries can't go over the budget by more
+// than the difference between the bytes they fetch and the reserved ones.
+func (b *queryBytesBudget) reserve(tenantID string, maxBytes int) (queryBytesReservation, bool) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	u := b.todayUsage(tenantID)
+	if u.bytes+u.reserved >= maxBytes {
+		return queryBytesReservation{}, false
+	}
+
+	var expected int
+	if u.queries > 0 {
+		expected = u.bytes / u.queries
+	}
+	u.reserved += expected
+
+	return queryBytesReservation{tenantID: tenantID, day: u.day, bytes: expected}, true
+}
+
+// release replaces the bytes reserved by a completed query with the bytes it actually fetched.
+func (b *queryBytesBudget) release(reservations []queryBytesReservation, fetched int) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	for _, r := range reservations {
+		u := b.unreserve(r)
+		u.bytes += fetched
+		u.queries++
+	}
+}
+
+// cancel releases the bytes reserved by a query which hasn't been run.
+func (b *queryBytesBudget) cancel(reservations []queryBytesReservation) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	for _, r := range reservations {
+		b.unreserve(r)
+	}
+}
+
+// unreserve releases the bytes of the reservation, and returns the tenant's usage of the current day.
+// It must be called with the lock held.
+func (b *queryBytesBudget) unreserve(r queryBytesReservation) *queryBytesUsage {
+	u := b.todayUsage(r.tenantID)
+	// The reservations of the previous days have been dropped along with their usage.
+	if u.day == r.day {
+		u.reserved -= r.bytes
+	}
+	return u
+}
+
+// usedBytes returns the bytes fetched by the tenant's completed queries during the current day.
+func (b *queryBytesBudget) usedBytes(tenantID string) int {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	return b.todayUsage(tenantID).bytes
+}
+
+// todayUsage returns the tenant's usage of the current day. It must be called with the lock held.
+func (b *queryBytesBudget) todayUsage(tenantID string) *queryBytesUsage {
+	today := b.today()
+	u, ok := b.usage[tenantID]
+	if !ok || u.day != today {
+		// Drop the usage of the previous days, so that the tenants which stopped querying don't leak memory.
+		for id, other := range b.usage {
+			if other.day != today {
+				delete(b.usage, id)
+			}
+		}
+		u = &queryBytesUsage{day: today}
+		b.usage[tenantID] = u
+	}
+	return u
+}
+
+// today returns the current day, as the number of days since the Unix epoch in UTC.
+func (b *queryBytesBudget) today() int64 {
+	return b.now().Unix() / int64(24*time.Hour/time.Second)
+}
@@ -0,0 +1,116 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querymiddleware
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/dskit/user"
+	"github.com/prometheus/client_golang/prometheus"
+	promtest "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/mimir/pkg/util/globalerror"
+)
+
+func TestQueryBytesBudget(t *testing.T) {
+	now := time.Date(2024, 6, 1, 10, 0, 0, 0, time.UTC)
+
+	reg := prometheus.NewPedanticRegistry()
+	budget := newQueryBytesBudget(multiTenantMockLimits{byTenant: map[string]mockLimits{
+		"limited":   {maxQueryBytesPerDay: 1000},
+		"unlimited": {},
+	}}, log.NewNopLogger(), reg)
+	budget.now = func() time.Time { return now }
+
+	// Each query fetches 400 bytes of chunks and 200 bytes of index.
+	handler := budget.Wrap(HandlerFunc(func(ctx context.Context, _ MetricsQueryRequest) (Response, error) {
+		if details := QueryDetailsFromContext(ctx); details != nil {
+			details.QuerierStats.AddFetchedChunkBytes(400)
+			details.QuerierStats.AddFetchedIndexBytes(200)
+		}
+		return &PrometheusResponse{Status: statusSuccess}, nil
+	}))
+
+	do := func(tenantID string) error {
+		_, ctx := ContextWithEmptyDetails(user.InjectOrgID(context.Background(), tenantID))
+		_, err := handler.Do(ctx, &PrometheusRangeQueryRequest{queryExpr: parseQuery(t, "up")})
+		return err
+	}
+
+	// The budget is checked before running the query, so the query exceeding the budget succeeds.
+	require.NoError(t, do("limited"))
+	require.NoError(t, do("limited"))
+	err := do("limited")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), string(globalerror.QueryBytesBudgetExhausted))
+
+	// Tenants without a budget aren't limited.
+	for i := 0; i < 3; i++ {
+		require.NoError(t, do("unlimited"))
+	}
+
+	// Queries not tracking stats aren't limited.
+	_, err = handler.Do(user.InjectOrgID(context.Background(), "limited"), &PrometheusRangeQueryRequest{queryExpr: parseQuery(t, "up")})
+	require.NoError(t, err)
+
+	// The budget is reset the next day.
+	now = now.Add(14 * time.Hour)
+	require.NoError(t, do("limited"))
+	assert.Equal(t, 600, budget.usedBytes("limited"))
+
+	require.NoError(t, promtest.GatherAndCompare(reg, strings.NewReader(`
+		# HELP cortex_query_frontend_query_bytes_budget_rejected_queries_total Number of queries rejected because the tenant's daily budget of bytes fetched by queries is exhausted.
+		# TYPE cortex_query_frontend_query_bytes_budget_rejected_queries_total counter
+		cortex_query_frontend_query_bytes_budget_rejected_queries_total{user="limited"} 1
+	`)))
+}
+
+func TestQueryBytesBudget_ShouldReserveTheExpectedBytesOfInFlightQueries(t *testing.T) {
+	now := time.Date(2024, 6, 1, 10, 0, 0, 0, time.UTC)
+
+	budget := newQueryBytesBudget(multiTenantMockLimits{byTenant: map[string]mockLimits{
+		"limited": {maxQueryBytesPerDay: 1000},
+	}}, log.NewNopLogger(), nil)
+	budget.now = func() time.Time { return now }
+
+	// Nothing is reserved until the average bytes fetched by a query is known.
+	r, ok := budget.reserve("limited", 1000)
+	require.True(t, ok)
+	assert.Equal(t, 0, r.bytes)
+	budget.release([]queryBytesReservation{r}, 300)
+
+	// Each in-flight query reserves the average bytes fetched by the completed queries, so that
+	// concurrent queries can't start once the reserved bytes exhaust the budget.
+	var reservations []queryBytesReservation
+	for i := 0; i < 3; i++ {
+		r, ok := budget.reserve("limited", 1000)
+		require.True(t, ok)
+		assert.Equal(t, 300, r.bytes)
+		reservations = append(reservations, r)
+	}
+	_, ok = budget.reserve("limited", 1000)
+	require.False(t, ok)
+
+	// Cancelled reservations are released without accounting a completed query.
+	budget.cancel(reservations[1:])
+	assert.Equal(t, 300, budget.usedBytes("limited"))
+
+	// Completed queries replace the reserved bytes with the fetched ones.
+	budget.release(reservations[:1], 100)
+	assert.Equal(t, 400, budget.usedBytes("limited"))
+	r, ok = budget.reserve("limited", 1000)
+	require.True(t, ok)
+	assert.Equal(t, 200, r.bytes)
+
+	// The reservations of the previous day don't affect the budget of the current day.
+	now = now.Add(24 * time.Hour)
+	budget.release([]queryBytesReservation{r}, 100)
+	assert.Equal(t, 100, budget.usedBytes("limited"))
+	assert.Equal(t, 0, budget.usage["limited"].reserved)
+}
@@ -146,6 +146,10 @@ func (s *querySharding) Do(ctx context.Context, r MetricsQueryRequest) (Response
 	queryStats := stats.FromContext(ctx)
 	queryStats.AddShardedQueries(uint32(shardingStats.GetShardedQueries()))
 
+	if explanation := QueryExplanationFromContext(ctx); explanation != nil {
+		explanation.addShardedQueries(totalShards, shardingStats.GetShardedQueries())
+	}
+
 	r, err = r.WithQuery(shardedQuery)
 	if err != nil {
 		return nil, apierror.New(apierror.TypeBadData, err.Error())
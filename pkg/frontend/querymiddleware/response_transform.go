@@ -0,0 +1,119 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querymiddleware
+
+import (
+	"fmt"
+	"math"
+	"net/url"
+	"strconv"
+
+	"github.com/prometheus/common/model"
+
+	"github.com/grafana/mimir/pkg/mimirpb"
+)
+
+const (
+	// precisionParam is the optional range query parameter to round the float sample values to the given number of decimal places.
+	precisionParam = "precision"
+
+	// maxPointsPerSeriesParam is the optional range query parameter to downsample each series to at most the given number of points.
+	maxPointsPerSeriesParam = "max_points_per_series"
+
+	maxPrecision = 15
+)
+
+// responseTransformation is an opt-in transformation of the range query response, reducing its size for export use cases.
+type responseTransformation struct {
+	// precision is the number of decimal places float sample values are rounded to, or -1 if disabled.
+	precision int
+
+	// maxPointsPerSeries is the maximum number of float samples and histograms kept for each series, or 0 if disabled.
+	maxPointsPerSeries int
+}
+
+// parseResponseTransformation parses the response transformation from the range query request parameters.
+func parseResponseTransformation(values url.Values) (responseTransformation, error) {
+	t := responseTransformation{precision: -1}
+
+	if value := values.Get(precisionParam); value != "" {
+		precision, err := strconv.Atoi(value)
+		if err != nil || precision < 0 || precision > maxPrecision {
+			return t, decorateWithParamName(fmt.Errorf("must be an integer between 0 and %d", maxPrecision), precisionParam)
+		}
+		t.precision = precision
+	}
+
+	if value := values.Get(maxPointsPerSeriesParam); value != "" {
+		maxPoints, err := strconv.Atoi(value)
+		if err != nil || maxPoints < 1 {
+			return t, decorateWithParamName(fmt.Errorf("must be a positive integer"), maxPointsPerSeriesParam)
+		}
+		t.maxPointsPerSeries = maxPoints
+	}
+
+	return t, nil
+}
+
+func (t responseTransformation) enabled() bool {
+	return t.precision >= 0 || t.maxPointsPerSeries > 0
+}
+
+// apply returns a copy of the response with the transformation applied. The input response is not modified,
+// because it may be shared with the results cache.
+func (t responseTransformation) apply(resp *PrometheusResponse) *PrometheusResponse {
+	if !t.enabled() || resp.Data == nil || resp.Data.ResultType != model.ValMatrix.String() {
+		return resp
+	}
+
+	result := make([]SampleStream, 0, len(resp.Data.Result))
+	for _, stream := range resp.Data.Result {
+		samples := downsample(stream.Samples, t.maxPointsPerSeries)
+		if t.precision >= 0 {
+			rounded := make([]mimirpb.Sample, len(samples))
+			for i, s := range samples {
+				rounded[i] = mimirpb.Sample{TimestampMs: s.TimestampMs, Value: roundToPrecision(s.Value, t.precision)}
+			}
+			samples = rounded
+		}
+
+		result = append(result, SampleStream{
+			Labels:     stream.Labels,
+			Samples:    samples,
+			Histograms: downsample(stream.Histograms, t.maxPointsPerSeries),
+		})
+	}
+
+	transformed := *resp
+	transformed.Data = &PrometheusData{ResultType: resp.Data.ResultType, Result: result}
+	return &transformed
+}
+
+// downsample returns at most maxPoints evenly spaced points of the input, always including the first one.
+// The input is returned as is if maxPoints is 0 or the input has no more points than maxPoints.
+func downsample[T any](points []T, maxPoints int) []T {
+	if maxPoints <= 0 || len(points) <= maxPoints {
+		return points
+	}
+
+	every := (len(points) + maxPoints - 1) / maxPoints
+	out := make([]T, 0, maxPoints)
+	for i := 0; i < len(points); i += every {
+		out = append(out, points[i])
+	}
+	return out
+}
+
+func roundToPrecision(value float64, precision int) float64 {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return value
+	}
+
+	pow := math.Pow10(precision)
+	rounded := math.Round(value*pow) / pow
+	if math.IsInf(rounded, 0) || math.IsNaN(rounded) {
+		// The value is too large to be multiplied without overflowing, so it has no decimal places to round anyway.
+		return value
+	}
+	return rounded
+}
@@ -0,0 +1,155 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querymiddleware
+
+import (
+	"context"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/mimir/pkg/mimirpb"
+)
+
+func TestParseResponseTransformation(t *testing.T) {
+	for name, tc := range map[string]struct {
+		values      url.Values
+		expected    responseTransformation
+		expectedErr string
+	}{
+		"no parameters": {
+			values:   url.Values{},
+			expected: responseTransformation{precision: -1},
+		},
+		"precision and max points per series": {
+			values:   url.Values{precisionParam: []string{"2"}, maxPointsPerSeriesParam: []string{"100"}},
+			expected: responseTransformation{precision: 2, maxPointsPerSeries: 100},
+		},
+		"zero precision": {
+			values:   url.Values{precisionParam: []string{"0"}},
+			expected: responseTransformation{precision: 0},
+		},
+		"negative precision": {
+			values:      url.Values{precisionParam: []string{"-1"}},
+			expectedErr: `invalid parameter "precision": must be an integer between 0 and 15`,
+		},
+		"precision too high": {
+			values:      url.Values{precisionParam: []string{"16"}},
+			expectedErr: `invalid parameter "precision": must be an integer between 0 and 15`,
+		},
+		"invalid max points per series": {
+			values:      url.Values{maxPointsPerSeriesParam: []string{"0"}},
+			expectedErr: `invalid parameter "max_points_per_series": must be a positive integer`,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			actual, err := parseResponseTransformation(tc.values)
+			if tc.expectedErr != "" {
+				require.EqualError(t, err, tc.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestResponseTransformation_Apply(t *testing.T) {
+	series := mimirpb.FromLabelsToLabelAdapters(labels.FromStrings("__name__", "metric"))
+	resp := &PrometheusResponse{
+		Status: statusSuccess,
+		Data: &PrometheusData{
+			ResultType: "matrix",
+			Result: []SampleStream{{
+				Labels: series,
+				Samples: []mimirpb.Sample{
+					{TimestampMs: 0, Value: 1.23456},
+					{TimestampMs: 1, Value: 2.34567},
+					{TimestampMs: 2, Value: 3.45678},
+					{TimestampMs: 3, Value: math.NaN()},
+					{TimestampMs: 4, Value: math.Inf(1)},
+				},
+			}},
+		},
+	}
+
+	t.Run("precision", func(t *testing.T) {
+		actual := responseTransformation{precision: 2}.apply(resp)
+		require.Len(t, actual.Data.Result, 1)
+		samples := actual.Data.Result[0].Samples
+		require.Len(t, samples, 5)
+		assert.Equal(t, []float64{1.23, 2.35, 3.46}, []float64{samples[0].Value, samples[1].Value, samples[2].Value})
+		assert.True(t, math.IsNaN(samples[3].Value))
+		assert.True(t, math.IsInf(samples[4].Value, 1))
+
+		// The input response must not be modified.
+		assert.Equal(t, 1.23456, resp.Data.Result[0].Samples[0].Value)
+	})
+
+	t.Run("max points per series", func(t *testing.T) {
+		actual := responseTransformation{precision: -1, maxPointsPerSeries: 2}.apply(resp)
+		require.Len(t, actual.Data.Result, 1)
+		assert.Equal(t, series, actual.Data.Result[0].Labels)
+
+		samples := actual.Data.Result[0].Samples
+		require.Len(t, samples, 2)
+		assert.Equal(t, int64(0), samples[0].TimestampMs)
+		assert.Equal(t, int64(3), samples[1].TimestampMs)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		assert.Same(t, resp, responseTransformation{precision: -1}.apply(resp))
+	})
+
+	t.Run("not a matrix", func(t *testing.T) {
+		vector := &PrometheusResponse{Status: statusSuccess, Data: &PrometheusData{ResultType: "vector"}}
+		assert.Same(t, vector, responseTransformation{precision: 2}.apply(vector))
+	})
+}
+
+func TestPrometheusCodec_EncodeResponse_ShouldApplyResponseTransformation(t *testing.T) {
+	codec := NewPrometheusCodec(prometheus.NewPedanticRegistry(), 0*time.Minute, formatJSON)
+	resp := &PrometheusResponse{
+		Status: statusSuccess,
+		Data: &PrometheusData{
+			ResultType: "matrix",
+			Result: []SampleStream{{
+				Labels:  mimirpb.FromLabelsToLabelAdapters(labels.FromStrings("__name__", "metric")),
+				Samples: []mimirpb.Sample{{TimestampMs: 0, Value: 1.23456}, {TimestampMs: 1000, Value: 2.34567}, {TimestampMs: 2000, Value: 3.45678}},
+			}},
+		},
+	}
+
+	for name, tc := range map[string]struct {
+		path     string
+		expected string
+	}{
+		"range query": {
+			path:     "/api/v1/query_range?query=metric&start=0&end=2&step=1&precision=1&max_points_per_series=2",
+			expected: `{"status":"success","data":{"resultType":"matrix","result":[{"metric":{"__name__":"metric"},"values":[[0,"1.2"],[2,"3.5"]]}]}}`,
+		},
+		"instant query": {
+			path:     "/api/v1/query?query=metric&time=2&precision=1&max_points_per_series=2",
+			expected: `{"status":"success","data":{"resultType":"matrix","result":[{"metric":{"__name__":"metric"},"values":[[0,"1.23456"],[1,"2.34567"],[2,"3.45678"]]}]}}`,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			encoded, err := codec.EncodeResponse(context.Background(), req, resp)
+			require.NoError(t, err)
+
+			body, err := io.ReadAll(encoded.Body)
+			require.NoError(t, err)
+			assert.JSONEq(t, tc.expected, string(body))
+		})
+	}
+}
@@ -218,17 +218,26 @@ func NewDefaultCacheKeyGenerator(codec Codec, interval time.Duration) DefaultCac
 	}
 }
 
-// QueryRequest generates a cache key based on the userID, MetricsQueryRequest and interval.
-func (g DefaultCacheKeyGenerator) QueryRequest(_ context.Context, userID string, r MetricsQueryRequest) string {
-	startInterval := r.GetStart() / g.interval.Milliseconds()
+// QueryRequest generates a cache key based on the userID, MetricsQueryRequest and interval. The interval
+// is aligned to the timezone the split boundaries are aligned to, as found in the context.
+func (g DefaultCacheKeyGenerator) QueryRequest(ctx context.Context, userID string, r MetricsQueryRequest) string {
+	loc := SplitLocationFromContext(ctx)
+	startInterval := (r.GetStart() + zoneOffsetMillis(r.GetStart(), loc)) / g.interval.Milliseconds()
 	stepOffset := r.GetStart() % r.GetStep()
 
 	// Use original format for step-aligned request, so that we can use existing cached results for such requests.
+	var key string
 	if stepOffset == 0 {
-		return fmt.Sprintf("%s:%s:%d:%d", userID, r.GetQuery(), r.GetStep(), startInterval)
+		key = fmt.Sprintf("%s:%s:%d:%d", userID, r.GetQuery(), r.GetStep(), startInterval)
+	} else {
+		key = fmt.Sprintf("%s:%s:%d:%d:%d", userID, r.GetQuery(), r.GetStep(), startInterval, stepOffset)
 	}
 
-	return fmt.Sprintf("%s:%s:%d:%d:%d", userID, r.GetQuery(), r.GetStep(), startInterval, stepOffset)
+	// Do not mix up extents split with different boundaries.
+	if loc != time.UTC {
+		key += ":" + loc.String()
+	}
+	return key
 }
 
 // shouldCacheFn checks whether the current request should go to cache
@@ -444,24 +453,33 @@ func toExtent(ctx context.Context, req MetricsQueryRequest, res Response, queryT
 
 // partitionCacheExtents calculates the required requests to satisfy req given the cached data.
 // extents must be in order by start time.
+// isCacheExtentUsable returns whether the cached extent can be used to serve part of the request.
+func isCacheExtentUsable(req MetricsQueryRequest, extent Extent, minCacheExtent int64) bool {
+	// If there is no overlap, ignore this extent.
+	if extent.GetEnd() < req.GetStart() || extent.Start > req.GetEnd() {
+		return false
+	}
+
+	// If this extent is tiny and request is not tiny, discard it: more efficient to do a few larger queries.
+	// Hopefully tiny request can make tiny extent into not-so-tiny extent.
+
+	// However if the step is large enough, the split_query_by_interval middleware would generate a query with same start and end.
+	// For example, if the step size is more than 12h and the interval is 24h.
+	// This means the extent's start and end time would be same, even if the timerange covers several hours.
+	if (req.GetStart() != req.GetEnd()) && (req.GetEnd()-req.GetStart() > minCacheExtent) && (extent.End-extent.Start < minCacheExtent) {
+		return false
+	}
+
+	return true
+}
+
 func partitionCacheExtents(req MetricsQueryRequest, extents []Extent, minCacheExtent int64, extractor Extractor) ([]MetricsQueryRequest, []Response, error) {
 	var requests []MetricsQueryRequest
 	var cachedResponses []Response
 	start := req.GetStart()
 
 	for _, extent := range extents {
-		// If there is no overlap, ignore this extent.
-		if extent.GetEnd() < start || extent.Start > req.GetEnd() {
-			continue
-		}
-
-		// If this extent is tiny and request is not tiny, discard it: more efficient to do a few larger queries.
-		// Hopefully tiny request can make tiny extent into not-so-tiny extent.
-
-		// However if the step is large enough, the split_query_by_interval middleware would generate a query with same start and end.
-		// For example, if the step size is more than 12h and the interval is 24h.
-		// This means the extent's start and end time would be same, even if the timerange covers several hours.
-		if (req.GetStart() != req.GetEnd()) && (req.GetEnd()-req.GetStart() > minCacheExtent) && (extent.End-extent.Start < minCacheExtent) {
+		if !isCacheExtentUsable(req, extent, minCacheExtent) {
 			continue
 		}
 
@@ -596,6 +596,22 @@ func TestDefaultSplitter_QueryRequest(t *testing.T) {
 	}
 }
 
+func TestDefaultSplitter_QueryRequest_WithSplitLocation(t *testing.T) {
+	loc, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+
+	ctx := ContextWithSplitLocation(context.Background(), loc)
+	splitter := DefaultCacheKeyGenerator{interval: 24 * time.Hour}
+
+	// Berlin is UTC+1 in January 1970, so the local day 1 starts at 23:00 UTC of day 0.
+	assert.Equal(t, "fake:foo:10:0:Europe/Berlin", splitter.QueryRequest(ctx, "fake", &PrometheusRangeQueryRequest{start: toMs(22 * time.Hour), step: 10, queryExpr: parseQuery(t, "foo")}))
+	assert.Equal(t, "fake:foo:10:1:Europe/Berlin", splitter.QueryRequest(ctx, "fake", &PrometheusRangeQueryRequest{start: toMs(23 * time.Hour), step: 10, queryExpr: parseQuery(t, "foo")}))
+
+	// UTC keys are unchanged.
+	ctx = ContextWithSplitLocation(context.Background(), time.UTC)
+	assert.Equal(t, "fake:foo:10:0", splitter.QueryRequest(ctx, "fake", &PrometheusRangeQueryRequest{start: toMs(23 * time.Hour), step: 10, queryExpr: parseQuery(t, "foo")}))
+}
+
 func toMs(t time.Duration) int64 {
 	return int64(t / time.Millisecond)
 }
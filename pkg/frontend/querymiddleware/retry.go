@@ -8,18 +8,115 @@ package querymiddleware
 import (
 	"context"
 	"errors"
+	"flag"
+	"net/http"
 
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
+	"github.com/grafana/dskit/grpcutil"
 	"github.com/grafana/dskit/httpgrpc"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
+	"google.golang.org/grpc/codes"
 
 	apierror "github.com/grafana/mimir/pkg/api/error"
 	util_log "github.com/grafana/mimir/pkg/util/log"
 	"github.com/grafana/mimir/pkg/util/spanlogger"
 )
 
+// errorClass is the class of an error returned by the downstream, used to pick the retry policy.
+type errorClass string
+
+const (
+	// errorClassNetwork is for errors caused by the downstream being unreachable or unavailable.
+	errorClassNetwork errorClass = "network"
+	// errorClassDeadline is for errors caused by the downstream not answering in time.
+	errorClassDeadline errorClass = "deadline"
+	// errorClassResourceExhausted is for errors caused by the downstream rejecting the request because of its limits.
+	errorClassResourceExhausted errorClass = "resource_exhausted"
+	// errorClassBadData is for errors caused by the request itself, and API errors other than internal errors,
+	// which fail in the same way when retried.
+	errorClassBadData errorClass = "bad_data"
+	// errorClassInternal is for any other server error.
+	errorClassInternal errorClass = "internal"
+)
+
+// RetryPolicyConfig configures the maximum number of retries, for each class of errors returned by the downstream.
+// Errors caused by the request itself are never retried.
+type RetryPolicyConfig struct {
+	NetworkErrorsMaxRetries           int `yaml:"network_errors_max_retries" category:"experimental"`
+	DeadlineErrorsMaxRetries          int `yaml:"deadline_errors_max_retries" category:"experimental"`
+	ResourceExhaustedErrorsMaxRetries int `yaml:"resource_exhausted_errors_max_retries" category:"experimental"`
+	InternalErrorsMaxRetries          int `yaml:"internal_errors_max_retries" category:"experimental"`
+}
+
+func (cfg *RetryPolicyConfig) RegisterFlagsWithPrefix(prefix string, f *flag.FlagSet) {
+	f.IntVar(&cfg.NetworkErrorsMaxRetries, prefix+"network-errors-max-retries", 5, "Maximum number of retries for a single request failing because the downstream is unreachable or unavailable. The number of retries is also limited by -query-frontend.max-retries-per-request. 0 to disable retries for these errors.")
+	f.IntVar(&cfg.DeadlineErrorsMaxRetries, prefix+"deadline-errors-max-retries", 5, "Maximum number of retries for a single request failing because the downstream didn't answer in time. The number of retries is also limited by -query-frontend.max-retries-per-request. 0 to disable retries for these errors.")
+	f.IntVar(&cfg.ResourceExhaustedErrorsMaxRetries, prefix+"resource-exhausted-errors-max-retries", 0, "Maximum number of retries for a single request rejected because of the downstream limits. The number of retries is also limited by -query-frontend.max-retries-per-request. 0 to disable retries for these errors.")
+	f.IntVar(&cfg.InternalErrorsMaxRetries, prefix+"internal-errors-max-retries", 5, "Maximum number of retries for a single request failing with any other server error. The number of retries is also limited by -query-frontend.max-retries-per-request. 0 to disable retries for these errors.")
+}
+
+// maxRetries returns the maximum number of retries for the error class.
+func (cfg RetryPolicyConfig) maxRetries(class errorClass) int {
+	switch class {
+	case errorClassNetwork:
+		return cfg.NetworkErrorsMaxRetries
+	case errorClassDeadline:
+		return cfg.DeadlineErrorsMaxRetries
+	case errorClassResourceExhausted:
+		return cfg.ResourceExhaustedErrorsMaxRetries
+	case errorClassInternal:
+		return cfg.InternalErrorsMaxRetries
+	default:
+		return 0
+	}
+}
+
+// classifyError returns the class of the error returned by the downstream. With the default retry policy,
+// the errors are retried as before the errors were classified: HTTP 5xx and non-HTTP errors are retried,
+// while HTTP 4xx and API errors other than internal errors are not.
+func classifyError(err error) errorClass {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return errorClassDeadline
+	}
+
+	if apierror.IsNonRetryableAPIError(err) {
+		return errorClassBadData
+	}
+
+	var apiErr *apierror.APIError
+	if errors.As(err, &apiErr) {
+		return errorClassInternal
+	}
+
+	if httpResp, ok := httpgrpc.HTTPResponseFromError(err); ok {
+		switch code := int(httpResp.Code); {
+		case code == http.StatusGatewayTimeout:
+			return errorClassDeadline
+		case code == http.StatusBadGateway || code == http.StatusServiceUnavailable:
+			return errorClassNetwork
+		case code == http.StatusTooManyRequests || code == http.StatusRequestEntityTooLarge:
+			return errorClassResourceExhausted
+		case code/100 == 5:
+			return errorClassInternal
+		default:
+			return errorClassBadData
+		}
+	}
+
+	// Errors which aren't HTTP responses are returned when failing to get a response from
+	// the downstream, so they're not caused by the request itself.
+	switch grpcutil.ErrorToStatusCode(err) {
+	case codes.DeadlineExceeded:
+		return errorClassDeadline
+	case codes.Unavailable:
+		return errorClassNetwork
+	default:
+		return errorClassInternal
+	}
+}
+
 type retryMiddlewareMetrics struct {
 	retriesCount prometheus.Histogram
 }
@@ -43,13 +140,14 @@ type retry struct {
 	log        log.Logger
 	next       MetricsQueryHandler
 	maxRetries int
+	policy     RetryPolicyConfig
 
 	metrics prometheus.Observer
 }
 
-// newRetryMiddleware returns a middleware that retries requests if they
-// fail with 500 or a non-HTTP error.
-func newRetryMiddleware(log log.Logger, maxRetries int, metrics prometheus.Observer) MetricsQueryMiddleware {
+// newRetryMiddleware returns a middleware that retries failed requests, up to the
+// maximum number of retries configured in the policy for the class of the error.
+func newRetryMiddleware(log log.Logger, maxRetries int, policy RetryPolicyConfig, metrics prometheus.Observer) MetricsQueryMiddleware {
 	if metrics == nil {
 		metrics = newRetryMiddlewareMetrics(nil)
 	}
@@ -59,6 +157,7 @@ func newRetryMiddleware(log log.Logger, maxRetries int, metrics prometheus.Obser
 			log:        log,
 			next:       next,
 			maxRetries: maxRetries,
+			policy:     policy,
 			metrics:    metrics,
 		}
 	})
@@ -78,19 +177,19 @@ func (r retry) Do(ctx context.Context, req MetricsQueryRequest) (Response, error
 			return resp, nil
 		}
 
-		if apierror.IsNonRetryableAPIError(err) || errors.Is(err, context.Canceled) {
+		if errors.Is(err, context.Canceled) {
 			return nil, err
 		}
-		// Retry if we get a HTTP 500 or a non-HTTP error.
-		httpResp, ok := httpgrpc.HTTPResponseFromError(err)
-		if !ok || httpResp.Code/100 == 5 {
-			lastErr = err
-			log := util_log.WithContext(ctx, spanlogger.FromContext(ctx, r.log))
-			level.Error(log).Log("msg", "error processing request", "try", tries, "err", err)
-			continue
+
+		class := classifyError(err)
+		if tries >= r.policy.maxRetries(class) {
+			// Don't retry errors which are expected to fail in the same way or have been retried too many times already.
+			return nil, err
 		}
 
-		return nil, err
+		lastErr = err
+		log := util_log.WithContext(ctx, spanlogger.FromContext(ctx, r.log))
+		level.Error(log).Log("msg", "error processing request", "try", tries, "error_class", class, "err", err)
 	}
 	return nil, lastErr
 }
@@ -16,10 +16,14 @@ import (
 	"github.com/grafana/dskit/httpgrpc"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/atomic"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	apierror "github.com/grafana/mimir/pkg/api/error"
 )
 
+var defaultRetryPolicy = RetryPolicyConfig{NetworkErrorsMaxRetries: 5, DeadlineErrorsMaxRetries: 5, InternalErrorsMaxRetries: 5}
+
 func TestRetry(t *testing.T) {
 	var try atomic.Int32
 
@@ -90,7 +94,7 @@ func TestRetry(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			try.Store(0)
 			mockMetrics := mockRetryMetrics{}
-			h := newRetryMiddleware(log.NewNopLogger(), 5, &mockMetrics).Wrap(tc.handler)
+			h := newRetryMiddleware(log.NewNopLogger(), 5, defaultRetryPolicy, &mockMetrics).Wrap(tc.handler)
 			resp, err := h.Do(context.Background(), nil)
 			require.Equal(t, tc.err, err)
 			require.Equal(t, tc.resp, resp)
@@ -99,6 +103,139 @@ func TestRetry(t *testing.T) {
 	}
 }
 
+func TestRetry_Policy(t *testing.T) {
+	policy := RetryPolicyConfig{
+		NetworkErrorsMaxRetries:           3,
+		DeadlineErrorsMaxRetries:          1,
+		ResourceExhaustedErrorsMaxRetries: 0,
+		InternalErrorsMaxRetries:          2,
+	}
+
+	for _, tc := range []struct {
+		name          string
+		err           error
+		expectedClass errorClass
+		expectedTries int32
+	}{
+		{
+			name:          "non-HTTP error",
+			err:           errors.New("connection refused"),
+			expectedClass: errorClassInternal,
+			expectedTries: 3,
+		},
+		{
+			name:          "gRPC unavailable",
+			err:           status.Error(codes.Unavailable, "unavailable"),
+			expectedClass: errorClassNetwork,
+			expectedTries: 4,
+		},
+		{
+			name:          "HTTP 503",
+			err:           httpgrpc.Errorf(http.StatusServiceUnavailable, "unavailable"),
+			expectedClass: errorClassNetwork,
+			expectedTries: 4,
+		},
+		{
+			name:          "context deadline exceeded",
+			err:           context.DeadlineExceeded,
+			expectedClass: errorClassDeadline,
+			expectedTries: 2,
+		},
+		{
+			name:          "HTTP 504",
+			err:           httpgrpc.Errorf(http.StatusGatewayTimeout, "timeout"),
+			expectedClass: errorClassDeadline,
+			expectedTries: 2,
+		},
+		{
+			name:          "gRPC deadline exceeded",
+			err:           status.Error(codes.DeadlineExceeded, "deadline exceeded"),
+			expectedClass: errorClassDeadline,
+			expectedTries: 2,
+		},
+		{
+			name:          "API timeout",
+			err:           apierror.New(apierror.TypeTimeout, "query timed out"),
+			expectedClass: errorClassBadData,
+			expectedTries: 1,
+		},
+		{
+			name:          "HTTP 429",
+			err:           httpgrpc.Errorf(http.StatusTooManyRequests, "too many requests"),
+			expectedClass: errorClassResourceExhausted,
+			expectedTries: 1,
+		},
+		{
+			name:          "gRPC resource exhausted",
+			err:           status.Error(codes.ResourceExhausted, "too busy"),
+			expectedClass: errorClassInternal,
+			expectedTries: 3,
+		},
+		{
+			name:          "API too many requests",
+			err:           apierror.New(apierror.TypeTooManyRequests, "too many requests"),
+			expectedClass: errorClassBadData,
+			expectedTries: 1,
+		},
+		{
+			name:          "API unavailable",
+			err:           apierror.New(apierror.TypeUnavailable, "store-gateways unavailable"),
+			expectedClass: errorClassBadData,
+			expectedTries: 1,
+		},
+		{
+			name:          "HTTP 400",
+			err:           httpgrpc.Errorf(http.StatusBadRequest, "bad request"),
+			expectedClass: errorClassBadData,
+			expectedTries: 1,
+		},
+		{
+			name:          "API execution error",
+			err:           apierror.New(apierror.TypeExec, "expanding series: limit exceeded"),
+			expectedClass: errorClassBadData,
+			expectedTries: 1,
+		},
+		{
+			name:          "HTTP 500",
+			err:           httpgrpc.Errorf(http.StatusInternalServerError, "internal error"),
+			expectedClass: errorClassInternal,
+			expectedTries: 3,
+		},
+		{
+			name:          "API internal error",
+			err:           apierror.New(apierror.TypeInternal, "failed to fetch series from store-gateways"),
+			expectedClass: errorClassInternal,
+			expectedTries: 3,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expectedClass, classifyError(tc.err))
+
+			var tries atomic.Int32
+			_, err := newRetryMiddleware(log.NewNopLogger(), 5, policy, nil).Wrap(
+				HandlerFunc(func(context.Context, MetricsQueryRequest) (Response, error) {
+					tries.Inc()
+					return nil, tc.err
+				}),
+			).Do(context.Background(), nil)
+			require.Equal(t, tc.err, err)
+			require.Equal(t, tc.expectedTries, tries.Load())
+		})
+	}
+
+	t.Run("the number of retries is limited by the max retries", func(t *testing.T) {
+		var tries atomic.Int32
+		_, err := newRetryMiddleware(log.NewNopLogger(), 2, policy, nil).Wrap(
+			HandlerFunc(func(context.Context, MetricsQueryRequest) (Response, error) {
+				tries.Inc()
+				return nil, errors.New("connection refused")
+			}),
+		).Do(context.Background(), nil)
+		require.Error(t, err)
+		require.Equal(t, int32(2), tries.Load())
+	})
+}
+
 type mockRetryMetrics struct {
 	retries float64
 }
@@ -111,7 +248,7 @@ func Test_RetryMiddlewareCancel(t *testing.T) {
 	var try atomic.Int32
 	ctx, cancel := context.WithCancel(context.Background())
 	cancel()
-	_, err := newRetryMiddleware(log.NewNopLogger(), 5, nil).Wrap(
+	_, err := newRetryMiddleware(log.NewNopLogger(), 5, defaultRetryPolicy, nil).Wrap(
 		HandlerFunc(func(context.Context, MetricsQueryRequest) (Response, error) {
 			try.Inc()
 			return nil, ctx.Err()
@@ -121,7 +258,7 @@ func Test_RetryMiddlewareCancel(t *testing.T) {
 	require.Equal(t, ctx.Err(), err)
 
 	ctx, cancel = context.WithCancel(context.Background())
-	_, err = newRetryMiddleware(log.NewNopLogger(), 5, nil).Wrap(
+	_, err = newRetryMiddleware(log.NewNopLogger(), 5, defaultRetryPolicy, nil).Wrap(
 		HandlerFunc(func(context.Context, MetricsQueryRequest) (Response, error) {
 			try.Inc()
 			cancel()
@@ -55,22 +55,33 @@ var (
 
 // Config for query_range middleware chain.
 type Config struct {
-	SplitQueriesByInterval   time.Duration `yaml:"split_queries_by_interval" category:"advanced"`
-	ResultsCacheConfig       `yaml:"results_cache"`
-	CacheResults             bool          `yaml:"cache_results"`
-	CacheErrors              bool          `yaml:"cache_errors" category:"experimental"`
-	MaxRetries               int           `yaml:"max_retries" category:"advanced"`
-	NotRunningTimeout        time.Duration `yaml:"not_running_timeout" category:"advanced"`
-	ShardedQueries           bool          `yaml:"parallelize_shardable_queries"`
-	PrunedQueries            bool          `yaml:"prune_queries" category:"experimental"`
-	TargetSeriesPerShard     uint64        `yaml:"query_sharding_target_series_per_shard" category:"advanced"`
-	ShardActiveSeriesQueries bool          `yaml:"shard_active_series_queries" category:"experimental"`
-	UseActiveSeriesDecoder   bool          `yaml:"use_active_series_decoder" category:"experimental"`
+	SplitQueriesByInterval          time.Duration `yaml:"split_queries_by_interval" category:"advanced"`
+	ResultsCacheConfig              `yaml:"results_cache"`
+	CacheResults                    bool              `yaml:"cache_results"`
+	CacheErrors                     bool              `yaml:"cache_errors" category:"experimental"`
+	MaxRetries                      int               `yaml:"max_retries" category:"advanced"`
+	RetryPolicy                     RetryPolicyConfig `yaml:"retry_policy"`
+	NotRunningTimeout               time.Duration     `yaml:"not_running_timeout" category:"advanced"`
+	ShardedQueries                  bool              `yaml:"parallelize_shardable_queries"`
+	PrunedQueries                   bool              `yaml:"prune_queries" category:"experimental"`
+	TargetSeriesPerShard            uint64            `yaml:"query_sharding_target_series_per_shard" category:"advanced"`
+	ShardActiveSeriesQueries        bool              `yaml:"shard_active_series_queries" category:"experimental"`
+	UseActiveSeriesDecoder          bool              `yaml:"use_active_series_decoder" category:"experimental"`
+	PruneQueriesByCompactionSummary bool              `yaml:"prune_queries_by_compaction_summary" category:"experimental"`
 
 	// CacheKeyGenerator allows to inject a CacheKeyGenerator to use for generating cache keys.
 	// If nil, the querymiddleware package uses a DefaultCacheKeyGenerator with SplitQueriesByInterval.
 	CacheKeyGenerator CacheKeyGenerator `yaml:"-"`
 
+	// CompactionSummaryReader and CompactionSummaryMaxStalePeriod are used to prune queries targeting time
+	// ranges with no data when PruneQueriesByCompactionSummary is enabled.
+	CompactionSummaryReader         CompactionSummaryReader `yaml:"-"`
+	CompactionSummaryMaxStalePeriod time.Duration           `yaml:"-"`
+
+	// QueryStoreAfter is the querier's -querier.query-store-after, used to explain which time ranges
+	// of a query are fetched from the store-gateways.
+	QueryStoreAfter time.Duration `yaml:"-"`
+
 	// ExtraInstantQueryMiddlewares and ExtraRangeQueryMiddlewares allows to
 	// inject custom middlewares into the middleware chain of instant and
 	// range queries. These middlewares will be placed right after default
@@ -95,6 +106,8 @@ func (cfg *Config) RegisterFlags(f *flag.FlagSet) {
 	f.StringVar(&cfg.QueryResultResponseFormat, "query-frontend.query-result-response-format", formatProtobuf, fmt.Sprintf("Format to use when retrieving query results from queriers. Supported values: %s", strings.Join(allFormats, ", ")))
 	f.BoolVar(&cfg.ShardActiveSeriesQueries, "query-frontend.shard-active-series-queries", false, "True to enable sharding of active series queries.")
 	f.BoolVar(&cfg.UseActiveSeriesDecoder, "query-frontend.use-active-series-decoder", false, "Set to true to use the zero-allocation response decoder for active series queries.")
+	f.BoolVar(&cfg.PruneQueriesByCompactionSummary, "query-frontend.prune-queries-by-compaction-summary", false, "True to skip the execution of queries, and partial queries after time-based splitting, targeting a time range with no data in the long-term storage according to the compaction summary uploaded by the compactor. Such queries are evaluated by the query-frontend against an empty storage. Requires the compactor to run with -compactor.compaction-summary-enabled=true.")
+	cfg.RetryPolicy.RegisterFlagsWithPrefix("query-frontend.retry-policy.", f)
 	cfg.ResultsCacheConfig.RegisterFlags(f)
 }
 
@@ -249,6 +262,10 @@ func newQueryTripperware(
 		instant := newLimitedParallelismRoundTripper(next, codec, limits, queryInstantMiddleware...)
 		remoteRead := newRemoteReadRoundTripper(next, remoteReadMiddleware...)
 
+		// Explain the range and instant queries with the explain parameter, instead of executing them.
+		queryrange = newExplainRoundTripper(queryrange, codec, limits, cfg.QueryStoreAfter)
+		instant = newExplainRoundTripper(instant, codec, limits, cfg.QueryStoreAfter)
+
 		// Wrap next for cardinality, labels queries and all other queries.
 		// That attempts to parse "start" and "end" from the HTTP request and set them in the request's QueryDetails.
 		// range and instant queries have more accurate logic for query details.
@@ -322,19 +339,24 @@ func newQueryMiddlewares(
 	// Metric used to keep track of each middleware execution duration.
 	metrics := newInstrumentMiddlewareMetrics(registerer)
 	queryBlockerMiddleware := newQueryBlockerMiddleware(limits, log, registerer)
+	queryBytesBudgetMiddleware := newQueryBytesBudget(limits, log, registerer)
 	queryStatsMiddleware := newQueryStatsMiddleware(registerer, engine)
+	experimentalFunctionsMiddleware := newExperimentalFunctionsMiddleware(limits, log)
 
 	remoteReadMiddleware = append(remoteReadMiddleware,
 		// Track query range statistics. Added first before any subsequent middleware modifies the request.
 		queryStatsMiddleware,
 		newLimitsMiddleware(limits, log),
-		queryBlockerMiddleware)
+		queryBlockerMiddleware,
+		queryBytesBudgetMiddleware)
 
 	queryRangeMiddleware = append(queryRangeMiddleware,
 		// Track query range statistics. Added first before any subsequent middleware modifies the request.
 		queryStatsMiddleware,
 		newLimitsMiddleware(limits, log),
+		experimentalFunctionsMiddleware,
 		queryBlockerMiddleware,
+		queryBytesBudgetMiddleware,
 		newInstrumentMiddleware("step_align", metrics),
 		newStepAlignMiddleware(limits, log, registerer),
 	)
@@ -368,6 +390,9 @@ func newQueryMiddlewares(
 		// Track query range statistics. Added first before any subsequent middleware modifies the request.
 		queryStatsMiddleware,
 		newLimitsMiddleware(limits, log),
+		experimentalFunctionsMiddleware,
+		// Run before splitting the query, so that the bytes fetched by all the split queries are accounted once.
+		queryBytesBudgetMiddleware,
 		newSplitInstantQueryByIntervalMiddleware(limits, log, engine, registerer),
 		queryBlockerMiddleware,
 	)
@@ -381,6 +406,20 @@ func newQueryMiddlewares(
 		queryRangeMiddleware = append(queryRangeMiddleware, cfg.ExtraRangeQueryMiddlewares...)
 	}
 
+	if cfg.PruneQueriesByCompactionSummary && cfg.CompactionSummaryReader != nil {
+		compactionSummaryPruningMiddleware := newCompactionSummaryPruningMiddleware(cfg.CompactionSummaryReader, cfg.CompactionSummaryMaxStalePeriod, limits, engine, log, registerer)
+		queryRangeMiddleware = append(
+			queryRangeMiddleware,
+			newInstrumentMiddleware("compaction_summary_pruning", metrics),
+			compactionSummaryPruningMiddleware,
+		)
+		queryInstantMiddleware = append(
+			queryInstantMiddleware,
+			newInstrumentMiddleware("compaction_summary_pruning", metrics),
+			compactionSummaryPruningMiddleware,
+		)
+	}
+
 	if cfg.PrunedQueries {
 		pruneMiddleware := newPruneMiddleware(log)
 		queryRangeMiddleware = append(
@@ -435,8 +474,8 @@ func newQueryMiddlewares(
 
 	if cfg.MaxRetries > 0 {
 		retryMiddlewareMetrics := newRetryMiddlewareMetrics(registerer)
-		queryRangeMiddleware = append(queryRangeMiddleware, newInstrumentMiddleware("retry", metrics), newRetryMiddleware(log, cfg.MaxRetries, retryMiddlewareMetrics))
-		queryInstantMiddleware = append(queryInstantMiddleware, newInstrumentMiddleware("retry", metrics), newRetryMiddleware(log, cfg.MaxRetries, retryMiddlewareMetrics))
+		queryRangeMiddleware = append(queryRangeMiddleware, newInstrumentMiddleware("retry", metrics), newRetryMiddleware(log, cfg.MaxRetries, cfg.RetryPolicy, retryMiddlewareMetrics))
+		queryInstantMiddleware = append(queryInstantMiddleware, newInstrumentMiddleware("retry", metrics), newRetryMiddleware(log, cfg.MaxRetries, cfg.RetryPolicy, retryMiddlewareMetrics))
 	}
 
 	return
@@ -585,6 +585,7 @@ func TestMiddlewaresConsistency(t *testing.T) {
 				"splitInstantQueryByIntervalMiddleware", // Not applicable because specific to instant queries.
 				"stepAlignMiddleware",                   // Not applicable because remote read requests don't take step in account when running in Mimir.
 				"pruneMiddleware",                       // No query pruning support.
+				"experimentalFunctionsMiddleware",       // No PromQL support.
 			},
 		},
 	}
@@ -143,11 +143,19 @@ func (s *splitAndCacheMiddleware) Do(ctx context.Context, req MetricsQueryReques
 
 	// Split the input requests by the configured interval (eg. day).
 	// Returns the input request if splitting is disabled.
-	splitReqs, err := s.splitRequestByInterval(req)
+	splitLoc := s.splitLocation(tenantIDs)
+	splitReqs, err := s.splitRequestByInterval(req, splitLoc)
 	if err != nil {
 		return nil, err
 	}
 
+	explanation := QueryExplanationFromContext(ctx)
+	if explanation != nil && s.splitEnabled {
+		for _, splitReq := range splitReqs {
+			explanation.addSplitQuery(splitReq.orig.GetStart(), splitReq.orig.GetEnd())
+		}
+	}
+
 	isCacheEnabled := s.cacheEnabled && (s.shouldCacheReq == nil || s.shouldCacheReq(req))
 	maxCacheFreshness := validation.MaxDurationPerTenant(tenantIDs, s.limits.MaxCacheFreshness)
 	maxCacheTime := int64(model.Now().Add(-maxCacheFreshness))
@@ -170,7 +178,7 @@ func (s *splitAndCacheMiddleware) Do(ctx context.Context, req MetricsQueryReques
 				continue
 			}
 
-			splitReq.cacheKey = s.splitter.QueryRequest(ctx, tenant.JoinTenantIDs(tenantIDs), splitReq.orig)
+			splitReq.cacheKey = s.splitter.QueryRequest(ContextWithSplitLocation(ctx, splitLoc), tenant.JoinTenantIDs(tenantIDs), splitReq.orig)
 			lookupKeys = append(lookupKeys, splitReq.cacheKey)
 			lookupReqs = append(lookupReqs, splitReq)
 		}
@@ -192,6 +200,15 @@ func (s *splitAndCacheMiddleware) Do(ctx context.Context, req MetricsQueryReques
 				return nil, err
 			}
 
+			if explanation != nil {
+				orig := lookupReqs[lookupIdx].orig
+				for _, extent := range extents {
+					if isCacheExtentUsable(orig, extent, defaultMinCacheExtent) {
+						explanation.addCachedExtent(max(extent.Start, orig.GetStart()), min(extent.End, orig.GetEnd()))
+					}
+				}
+			}
+
 			if len(requests) == 0 {
 				// The full response has been picked up from the cache so we can merge it and store it.
 				response, err := s.merger.MergeResponse(responses...)
@@ -243,8 +260,9 @@ func (s *splitAndCacheMiddleware) Do(ctx context.Context, req MetricsQueryReques
 		}
 	}
 
-	// Store the updated response in the results cache.
-	if isCacheEnabled && len(execReqs) > 0 {
+	// Store the updated response in the results cache. The responses of the queries being explained
+	// are empty, because the downstream requests haven't been executed, so they're never stored.
+	if isCacheEnabled && len(execReqs) > 0 && explanation == nil {
 		for _, splitReq := range splitReqs {
 			// If there are no downstream requests it means the response was entirely picked up from the cache
 			// so there's no need to store it again in the cache (because nothing has changed).
@@ -307,13 +325,58 @@ func (s *splitAndCacheMiddleware) Do(ctx context.Context, req MetricsQueryReques
 	return s.merger.MergeResponse(responses...)
 }
 
-// splitRequestByInterval splits the given MetricsQueryRequest by configured interval. Returns the input request if splitting is disabled.
-func (s *splitAndCacheMiddleware) splitRequestByInterval(req MetricsQueryRequest) (splitRequests, error) {
+// splitLocation returns the timezone the split boundaries are aligned to. If the tenants have
+// different timezones, or a timezone can't be loaded, boundaries are aligned to UTC.
+func (s *splitAndCacheMiddleware) splitLocation(tenantIDs []string) *time.Location {
+	if !s.splitEnabled || len(tenantIDs) == 0 {
+		return time.UTC
+	}
+
+	name := s.limits.SplitQueriesByIntervalTimezone(tenantIDs[0])
+	for _, tenantID := range tenantIDs[1:] {
+		if s.limits.SplitQueriesByIntervalTimezone(tenantID) != name {
+			return time.UTC
+		}
+	}
+	if name == "" {
+		return time.UTC
+	}
+
+	loc, err := loadSplitLocation(name)
+	if err != nil {
+		level.Warn(s.logger).Log("msg", "failed to load the timezone used to split queries, falling back to UTC", "timezone", name, "err", err)
+		return time.UTC
+	}
+	return loc
+}
+
+// splitLocations caches the timezones loaded by loadSplitLocation by name. The timezones are validated
+// when the limits are loaded, so the number of cached timezones is bounded by the IANA timezone database.
+var splitLocations sync.Map
+
+// loadSplitLocation returns the timezone with the given name, loading it from the timezone database
+// only the first time it's requested.
+func loadSplitLocation(name string) (*time.Location, error) {
+	if loc, ok := splitLocations.Load(name); ok {
+		return loc.(*time.Location), nil
+	}
+
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, err
+	}
+	splitLocations.Store(name, loc)
+	return loc, nil
+}
+
+// splitRequestByInterval splits the given MetricsQueryRequest by configured interval, aligning the boundaries
+// to the midnight of the given location. Returns the input request if splitting is disabled.
+func (s *splitAndCacheMiddleware) splitRequestByInterval(req MetricsQueryRequest, loc *time.Location) (splitRequests, error) {
 	if !s.splitEnabled {
 		return splitRequests{{orig: req}}, nil
 	}
 
-	splitReqs, err := splitQueryByInterval(req, s.splitInterval)
+	splitReqs, err := splitQueryByInterval(req, s.splitInterval, loc)
 	if err != nil {
 		return nil, err
 	}
@@ -637,7 +700,7 @@ func doRequests(ctx context.Context, downstream MetricsQueryHandler, reqs []Metr
 	return resps, g.Wait()
 }
 
-func splitQueryByInterval(req MetricsQueryRequest, interval time.Duration) ([]MetricsQueryRequest, error) {
+func splitQueryByInterval(req MetricsQueryRequest, interval time.Duration, loc *time.Location) ([]MetricsQueryRequest, error) {
 	// Replace @ modifier function to their respective constant values in the query.
 	// This way subqueries will be evaluated at the same time as the parent query.
 	query, err := evaluateAtModifierFunction(req.GetQuery(), req.GetStart(), req.GetEnd())
@@ -646,7 +709,7 @@ func splitQueryByInterval(req MetricsQueryRequest, interval time.Duration) ([]Me
 	}
 	var reqs []MetricsQueryRequest
 	for start := req.GetStart(); start <= req.GetEnd(); {
-		end := nextIntervalBoundary(start, req.GetStep(), interval)
+		end := nextIntervalBoundary(start, req.GetStep(), interval, loc)
 		if end > req.GetEnd() {
 			end = req.GetEnd()
 		}
@@ -704,10 +767,20 @@ func evaluateAtModifierFunction(query string, start, end int64) (string, error)
 	return expr.String(), nil
 }
 
-// Round up to the step before the next interval boundary.
-func nextIntervalBoundary(t, step int64, interval time.Duration) int64 {
+// Round up to the step before the next interval boundary. Boundaries are aligned to the midnight of the given location.
+func nextIntervalBoundary(t, step int64, interval time.Duration, loc *time.Location) int64 {
 	intervalMillis := interval.Milliseconds()
-	startOfNextInterval := ((t / intervalMillis) + 1) * intervalMillis
+	offset := zoneOffsetMillis(t, loc)
+	startOfNextInterval := (((t+offset)/intervalMillis)+1)*intervalMillis - offset
+
+	// Take in account the offset change when the next boundary is across a daylight saving time transition.
+	if nextOffset := zoneOffsetMillis(startOfNextInterval, loc); nextOffset != offset {
+		startOfNextInterval += offset - nextOffset
+		if startOfNextInterval <= t {
+			startOfNextInterval += intervalMillis
+		}
+	}
+
 	// ensure that target is a multiple of steps away from the start time
 	target := startOfNextInterval - ((startOfNextInterval - t) % step)
 	if target == startOfNextInterval {
@@ -715,3 +788,28 @@ func nextIntervalBoundary(t, step int64, interval time.Duration) int64 {
 	}
 	return target
 }
+
+var splitLocationCtxKey = contextKey(1)
+
+// ContextWithSplitLocation returns a context holding the timezone the split boundaries are aligned to,
+// so that the CacheKeyGenerator can build the cache keys consistently with the split boundaries.
+func ContextWithSplitLocation(ctx context.Context, loc *time.Location) context.Context {
+	return context.WithValue(ctx, splitLocationCtxKey, loc)
+}
+
+// SplitLocationFromContext returns the timezone the split boundaries are aligned to. Defaults to UTC.
+func SplitLocationFromContext(ctx context.Context) *time.Location {
+	if loc, ok := ctx.Value(splitLocationCtxKey).(*time.Location); ok && loc != nil {
+		return loc
+	}
+	return time.UTC
+}
+
+// zoneOffsetMillis returns the offset, in milliseconds, of the location from UTC at the given timestamp.
+func zoneOffsetMillis(t int64, loc *time.Location) int64 {
+	if loc == nil || loc == time.UTC {
+		return 0
+	}
+	_, offset := time.UnixMilli(t).In(loc).Zone()
+	return int64(offset) * time.Second.Milliseconds()
+}
@@ -1737,7 +1737,7 @@ func TestNextIntervalBoundary(t *testing.T) {
 		{toMs(time.Hour) + 15*seconds, 35 * seconds, 2*toMs(time.Hour) - 15*seconds, time.Hour},
 	} {
 		t.Run(strconv.Itoa(i), func(t *testing.T) {
-			require.Equal(t, tc.out, nextIntervalBoundary(tc.in, tc.step, tc.interval))
+			require.Equal(t, tc.out, nextIntervalBoundary(tc.in, tc.step, tc.interval, time.UTC))
 		})
 	}
 }
@@ -1914,13 +1914,57 @@ func TestSplitQueryByInterval(t *testing.T) {
 		},
 	} {
 		t.Run(fmt.Sprintf("%d: start: %v, end: %v, step: %v", i, tc.input.GetStart(), tc.input.GetEnd(), tc.input.GetStep()), func(t *testing.T) {
-			days, err := splitQueryByInterval(tc.input, tc.interval)
+			days, err := splitQueryByInterval(tc.input, tc.interval, time.UTC)
 			require.NoError(t, err)
 			require.Equal(t, tc.expected, days)
 		})
 	}
 }
 
+func TestSplitQueryByInterval_WithTimezone(t *testing.T) {
+	loc, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+
+	queryFooExpr, err := parser.ParseExpr("foo")
+	require.NoError(t, err)
+
+	step := time.Hour.Milliseconds()
+	newRequest := func(start, end string) *PrometheusRangeQueryRequest {
+		return &PrometheusRangeQueryRequest{start: timeToMillis(t, start), end: timeToMillis(t, end), minT: timeToMillis(t, start), maxT: timeToMillis(t, end), step: step, queryExpr: queryFooExpr}
+	}
+
+	// Daylight saving time ends on 2021-10-31 in Berlin, so the local day is 25 hours long.
+	input := &PrometheusRangeQueryRequest{start: timeToMillis(t, "2021-10-30T12:00:00Z"), end: timeToMillis(t, "2021-11-01T12:00:00Z"), step: step, queryExpr: queryFooExpr}
+	expected := []MetricsQueryRequest{
+		newRequest("2021-10-30T12:00:00Z", "2021-10-30T21:00:00Z"),
+		newRequest("2021-10-30T22:00:00Z", "2021-10-31T22:00:00Z"),
+		newRequest("2021-10-31T23:00:00Z", "2021-11-01T12:00:00Z"),
+	}
+
+	actual, err := splitQueryByInterval(input, day, loc)
+	require.NoError(t, err)
+	require.Equal(t, expected, actual)
+}
+
+func TestSplitAndCacheMiddleware_SplitLocation(t *testing.T) {
+	limits := multiTenantMockLimits{byTenant: map[string]mockLimits{
+		"user-1": {splitQueriesByIntervalTimezone: "Europe/Berlin"},
+		"user-2": {splitQueriesByIntervalTimezone: "Europe/Berlin"},
+		"user-3": {splitQueriesByIntervalTimezone: "America/New_York"},
+		"user-4": {},
+	}}
+
+	mw := newSplitAndCacheMiddleware(true, false, day, limits, newTestPrometheusCodec(), nil, nil, nil, nil, log.NewNopLogger(), nil).Wrap(nil).(*splitAndCacheMiddleware)
+
+	assert.Equal(t, "Europe/Berlin", mw.splitLocation([]string{"user-1"}).String())
+	assert.Equal(t, "Europe/Berlin", mw.splitLocation([]string{"user-1", "user-2"}).String())
+	assert.Equal(t, time.UTC, mw.splitLocation([]string{"user-1", "user-3"}))
+	assert.Equal(t, time.UTC, mw.splitLocation([]string{"user-4"}))
+
+	// The timezones are loaded once.
+	assert.Same(t, mw.splitLocation([]string{"user-1"}), mw.splitLocation([]string{"user-2"}))
+}
+
 func timeToMillis(t *testing.T, input string) int64 {
 	r, err := time.Parse(time.RFC3339, input)
 	require.NoError(t, err)
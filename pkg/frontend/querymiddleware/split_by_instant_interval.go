@@ -165,6 +165,10 @@ func (s *splitInstantQueryByIntervalMiddleware) Do(ctx context.Context, req Metr
 	queryStats := stats.FromContext(ctx)
 	queryStats.AddSplitQueries(uint32(mapperStats.GetSplitQueries()))
 
+	if explanation := QueryExplanationFromContext(ctx); explanation != nil {
+		explanation.addInstantSplitQueries(mapperStats.GetSplitQueries())
+	}
+
 	// Update metrics.
 	s.metrics.splittingSuccesses.Inc()
 	s.metrics.splitQueries.Add(float64(mapperStats.GetSplitQueries()))
@@ -58,6 +58,8 @@ type HandlerConfig struct {
 	MaxBodySize              int64                  `yaml:"max_body_size" category:"advanced"`
 	QueryStatsEnabled        bool                   `yaml:"query_stats_enabled" category:"advanced"`
 	ActiveSeriesWriteTimeout time.Duration          `yaml:"active_series_write_timeout" category:"experimental"`
+	QueryTimeoutBudget       time.Duration          `yaml:"query_timeout_budget" category:"experimental"`
+	QueryPriorityHeader      string                 `yaml:"query_priority_header" category:"experimental"`
 }
 
 func (cfg *HandlerConfig) RegisterFlags(f *flag.FlagSet) {
@@ -66,6 +68,8 @@ func (cfg *HandlerConfig) RegisterFlags(f *flag.FlagSet) {
 	f.Int64Var(&cfg.MaxBodySize, "query-frontend.max-body-size", 10*1024*1024, "Max body size for downstream prometheus.")
 	f.BoolVar(&cfg.QueryStatsEnabled, "query-frontend.query-stats-enabled", true, "False to disable query statistics tracking. When enabled, a message with some statistics is logged for every query.")
 	f.DurationVar(&cfg.ActiveSeriesWriteTimeout, "query-frontend.active-series-write-timeout", 5*time.Minute, "Timeout for writing active series responses. 0 means the value from `-server.http-write-timeout` is used.")
+	f.DurationVar(&cfg.QueryTimeoutBudget, "query-frontend.query-timeout-budget", 0, "Maximum time a query can take end-to-end, from when it's received by the query-frontend. The time left is propagated to queriers, ingesters and store-gateways, which stop processing the query as soon as the budget is exhausted. 0 to disable.")
+	f.StringVar(&cfg.QueryPriorityHeader, "query-frontend.query-priority-header", "", "Name of the HTTP request header from which the priority of a query is read. The priority is an integer: the query-scheduler dequeues the queries with a higher priority before the queries with a lower priority of the same tenant. The priority is capped to the tenant's max query priority. Only set this header name if it's set by a trusted proxy or client. Empty to disable.")
 }
 
 // Handler accepts queries and forwards them to RoundTripper. It can wait on in-flight requests and log slow queries,
@@ -219,6 +223,19 @@ func (f *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		r = r.WithContext(ctx)
 	}
 
+	if f.cfg.QueryPriorityHeader != "" {
+		if priority, ok := querierapi.DecodeQueryPriority(r.Header.Get(f.cfg.QueryPriorityHeader)); ok {
+			r = r.WithContext(querierapi.ContextWithQueryPriority(r.Context(), priority))
+		}
+	}
+
+	if f.cfg.QueryTimeoutBudget > 0 {
+		ctx, cancel := context.WithTimeoutCause(r.Context(), f.cfg.QueryTimeoutBudget,
+			cancellation.NewErrorf("query timeout budget exhausted (budget: %v)", f.cfg.QueryTimeoutBudget))
+		defer cancel()
+		r = r.WithContext(ctx)
+	}
+
 	startTime := time.Now()
 	resp, err := f.roundTripper.RoundTrip(r)
 	queryResponseTime := time.Since(startTime)
@@ -332,8 +349,20 @@ func (f *Handler) reportQueryStats(
 		"estimated_series_count", stats.GetEstimatedSeriesCount(),
 		"queue_time_seconds", stats.LoadQueueTime().Seconds(),
 		"encode_time_seconds", stats.LoadEncodeTime().Seconds(),
+		"fetched_series_from_ingesters_count", stats.LoadFetchedSeriesFromIngesters(),
+		"fetched_chunks_from_ingesters_count", stats.LoadFetchedChunksFromIngesters(),
+		"fetched_series_from_store_gateways_count", stats.LoadFetchedSeriesFromStoreGateways(),
+		"fetched_chunks_from_store_gateways_count", stats.LoadFetchedChunksFromStoreGateways(),
+		"queried_blocks_count", stats.LoadQueriedBlocks(),
 	}, formatQueryString(details, queryString)...)
 
+	if blocksMinT, blocksMaxT, ok := stats.LoadQueriedBlocksTimeRange(); ok {
+		logMessage = append(logMessage,
+			"queried_blocks_min_time", time.UnixMilli(blocksMinT).UTC().Format(time.RFC3339Nano),
+			"queried_blocks_max_time", time.UnixMilli(blocksMaxT).UTC().Format(time.RFC3339Nano),
+		)
+	}
+
 	if details != nil {
 		// Start and End may be zero when the request wasn't a query (e.g. /metadata)
 		// or if the query was a constant expression and didn't need to process samples.
@@ -484,6 +513,9 @@ func writeServiceTimingHeader(queryResponseTime time.Duration, headers http.Head
 		parts := make([]string, 0)
 		parts = append(parts, statsValue("querier_wall_time", stats.LoadWallTime()))
 		parts = append(parts, statsValue("response_time", queryResponseTime))
+		parts = append(parts, statsCount("fetched_series_from_ingesters", stats.LoadFetchedSeriesFromIngesters()))
+		parts = append(parts, statsCount("fetched_series_from_store_gateways", stats.LoadFetchedSeriesFromStoreGateways()))
+		parts = append(parts, statsCount("queried_blocks", stats.LoadQueriedBlocks()))
 		headers.Set(ServiceTimingHeaderName, strings.Join(parts, ", "))
 	}
 }
@@ -493,6 +525,11 @@ func statsValue(name string, d time.Duration) string {
 	return name + ";dur=" + durationInMs
 }
 
+// statsCount formats a count as a Server-Timing metric without duration.
+func statsCount(name string, v uint64) string {
+	return name + ";desc=" + strconv.FormatUint(v, 10)
+}
+
 func httpRequestActivity(request *http.Request, userAgent string, requestParams url.Values) string {
 	tenantID := "(unknown)"
 	if tenantIDs, err := tenant.TenantIDs(request.Context()); err == nil {
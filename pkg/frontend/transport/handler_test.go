@@ -822,6 +822,120 @@ func TestHandler_ActiveSeriesWriteTimeout(t *testing.T) {
 	}
 }
 
+type grpcRoundTripperFunc func(context.Context, *httpgrpc.HTTPRequest) (*httpgrpc.HTTPResponse, io.ReadCloser, error)
+
+func (f grpcRoundTripperFunc) RoundTripGRPC(ctx context.Context, req *httpgrpc.HTTPRequest) (*httpgrpc.HTTPResponse, io.ReadCloser, error) {
+	return f(ctx, req)
+}
+
+func TestHandler_QueryTimeoutBudget(t *testing.T) {
+	const budget = time.Minute
+
+	for name, tc := range map[string]struct {
+		budget         time.Duration
+		expectedHeader bool
+	}{
+		"timeout budget disabled": {
+			budget:         0,
+			expectedHeader: false,
+		},
+		"timeout budget enabled": {
+			budget:         budget,
+			expectedHeader: true,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var budgetHeaders []string
+			roundTripper := AdaptGrpcRoundTripperToHTTPRoundTripper(grpcRoundTripperFunc(func(_ context.Context, req *httpgrpc.HTTPRequest) (*httpgrpc.HTTPResponse, io.ReadCloser, error) {
+				for _, h := range req.Headers {
+					if h.Key == api.QueryTimeoutBudgetHeader {
+						budgetHeaders = append(budgetHeaders, h.Values...)
+					}
+				}
+				return &httpgrpc.HTTPResponse{Code: http.StatusOK, Body: []byte("{}")}, nil, nil
+			}))
+
+			handler := NewHandler(HandlerConfig{MaxBodySize: 1024, QueryTimeoutBudget: tc.budget}, roundTripper, log.NewNopLogger(), nil, nil)
+
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/query?query=up", nil)
+			// The timeout budget set by the client must be ignored.
+			req.Header.Set(api.QueryTimeoutBudgetHeader, api.EncodeQueryTimeoutBudget(time.Hour))
+			resp := httptest.NewRecorder()
+			handler.ServeHTTP(resp, req)
+			require.Equal(t, http.StatusOK, resp.Code)
+
+			if !tc.expectedHeader {
+				assert.Empty(t, budgetHeaders)
+				return
+			}
+
+			require.Len(t, budgetHeaders, 1)
+			actual, ok := api.DecodeQueryTimeoutBudget(budgetHeaders[0])
+			require.True(t, ok)
+			assert.LessOrEqual(t, actual, budget)
+			assert.Greater(t, actual, budget-10*time.Second)
+		})
+	}
+}
+
+func TestHandler_QueryPriority(t *testing.T) {
+	const priorityHeader = "X-Query-Priority"
+
+	for name, tc := range map[string]struct {
+		headerName       string
+		headerValue      string
+		expectedPriority int
+		expectedOK       bool
+	}{
+		"query priority header disabled": {
+			headerValue: "10",
+		},
+		"query priority header enabled, no priority": {
+			headerName: priorityHeader,
+		},
+		"query priority header enabled, invalid priority": {
+			headerName:  priorityHeader,
+			headerValue: "high",
+		},
+		"query priority header enabled, valid priority": {
+			headerName:       priorityHeader,
+			headerValue:      "10",
+			expectedPriority: 10,
+			expectedOK:       true,
+		},
+		"query priority header enabled, negative priority": {
+			headerName:       priorityHeader,
+			headerValue:      "-1",
+			expectedPriority: -1,
+			expectedOK:       true,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var (
+				actualPriority int
+				actualOK       bool
+			)
+			roundTripper := AdaptGrpcRoundTripperToHTTPRoundTripper(grpcRoundTripperFunc(func(ctx context.Context, _ *httpgrpc.HTTPRequest) (*httpgrpc.HTTPResponse, io.ReadCloser, error) {
+				actualPriority, actualOK = api.QueryPriorityFromContext(ctx)
+				return &httpgrpc.HTTPResponse{Code: http.StatusOK, Body: []byte("{}")}, nil, nil
+			}))
+
+			handler := NewHandler(HandlerConfig{MaxBodySize: 1024, QueryPriorityHeader: tc.headerName}, roundTripper, log.NewNopLogger(), nil, nil)
+
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/query?query=up", nil)
+			if tc.headerValue != "" {
+				req.Header.Set(priorityHeader, tc.headerValue)
+			}
+			resp := httptest.NewRecorder()
+			handler.ServeHTTP(resp, req)
+			require.Equal(t, http.StatusOK, resp.Code)
+
+			assert.Equal(t, tc.expectedOK, actualOK)
+			assert.Equal(t, tc.expectedPriority, actualPriority)
+		})
+	}
+}
+
 type testLogger struct {
 	logMessages []map[string]interface{}
 	duplicates  []string
@@ -11,8 +11,12 @@ import (
 	"io"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/grafana/dskit/httpgrpc"
+
+	querierapi "github.com/grafana/mimir/pkg/querier/api"
 )
 
 // GrpcRoundTripper is similar to http.RoundTripper, but works with HTTP requests converted to protobuf messages.
@@ -44,6 +48,8 @@ func (a *grpcRoundTripperAdapter) RoundTrip(r *http.Request) (*http.Response, er
 		return nil, err
 	}
 
+	setQueryTimeoutBudgetHeader(r.Context(), req)
+
 	resp, body, err := a.roundTripper.RoundTripGRPC(r.Context(), req)
 	if err != nil {
 		var ok bool
@@ -81,3 +87,26 @@ func (a *grpcRoundTripperAdapter) RoundTrip(r *http.Request) (*http.Response, er
 
 	return httpResp, nil
 }
+
+// setQueryTimeoutBudgetHeader sets the time left before the deadline of the input context expires as the timeout
+// budget of the request, so that the querier executing it doesn't keep working once the deadline can't be met.
+// Any timeout budget header set by the client is removed.
+func setQueryTimeoutBudgetHeader(ctx context.Context, req *httpgrpc.HTTPRequest) {
+	headers := req.Headers[:0]
+	for _, h := range req.Headers {
+		if !strings.EqualFold(h.Key, querierapi.QueryTimeoutBudgetHeader) {
+			headers = append(headers, h)
+		}
+	}
+	req.Headers = headers
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return
+	}
+
+	req.Headers = append(req.Headers, &httpgrpc.Header{
+		Key:    querierapi.QueryTimeoutBudgetHeader,
+		Values: []string{querierapi.EncodeQueryTimeoutBudget(time.Until(deadline))},
+	})
+}
@@ -92,6 +92,9 @@ func (cfg *Config) Validate() error {
 type Limits interface {
 	// QueryIngestersWithin returns the maximum lookback beyond which queries are not sent to ingester.
 	QueryIngestersWithin(user string) time.Duration
+
+	// MaxQueryPriority returns the maximum priority the user's queries can be assigned through a request header.
+	MaxQueryPriority(user string) int
 }
 
 // Frontend implements GrpcRoundTripper. It queues HTTP requests,
@@ -13,6 +13,7 @@ import (
 
 	"github.com/grafana/mimir/pkg/frontend/querymiddleware"
 	"github.com/grafana/mimir/pkg/querier"
+	querierapi "github.com/grafana/mimir/pkg/querier/api"
 	"github.com/grafana/mimir/pkg/scheduler/schedulerpb"
 	"github.com/grafana/mimir/pkg/util/validation"
 )
@@ -34,6 +35,18 @@ func (a *frontendToSchedulerAdapter) frontendToSchedulerEnqueueRequest(
 		return nil, err
 	}
 
+	priority, err := a.queryPriority(req.ctx, req.userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// The deadline is sent to the query-scheduler, so that it doesn't replay the persisted requests
+	// which have expired in the meantime.
+	var deadlineUnixMillis int64
+	if deadline, ok := req.ctx.Deadline(); ok {
+		deadlineUnixMillis = deadline.UnixMilli()
+	}
+
 	return &schedulerpb.FrontendToScheduler{
 		Type:                      schedulerpb.ENQUEUE,
 		QueryID:                   req.queryID,
@@ -42,9 +55,31 @@ func (a *frontendToSchedulerAdapter) frontendToSchedulerEnqueueRequest(
 		FrontendAddress:           frontendAddr,
 		StatsEnabled:              req.statsEnabled,
 		AdditionalQueueDimensions: addlQueueDims,
+		Priority:                  priority,
+		DeadlineUnixMillis:        deadlineUnixMillis,
 	}, nil
 }
 
+// queryPriority returns the priority of the request in the query-scheduler queue. The priority requested
+// by the client is capped to the max query priority of the tenant, or to the smallest one among all tenants
+// if the request spans multiple tenants. Requests without a priority have the default priority 0.
+func (a *frontendToSchedulerAdapter) queryPriority(ctx context.Context, userID string) (int32, error) {
+	priority, ok := querierapi.QueryPriorityFromContext(ctx)
+	if !ok {
+		return 0, nil
+	}
+
+	tenantIDs, err := tenant.TenantIDsFromOrgID(userID)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, tenantID := range tenantIDs {
+		priority = min(priority, a.limits.MaxQueryPriority(tenantID))
+	}
+	return int32(priority), nil
+}
+
 const ShouldQueryIngestersQueueDimension = "ingester"
 const ShouldQueryStoreGatewayQueueDimension = "store-gateway"
 const ShouldQueryIngestersAndStoreGatewayQueueDimension = "ingester-and-store-gateway"
@@ -10,12 +10,14 @@ import (
 	"testing"
 	"time"
 
+	"github.com/go-kit/log"
 	"github.com/grafana/dskit/httpgrpc"
 	"github.com/grafana/dskit/user"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/stretchr/testify/require"
 
 	"github.com/grafana/mimir/pkg/frontend/querymiddleware"
+	querierapi "github.com/grafana/mimir/pkg/querier/api"
 )
 
 const rangeURLFormat = "/api/v1/query_range?end=%d&query=go_goroutines{}&start=%d&step=%d"
@@ -249,3 +251,66 @@ func TestQueryDecoding(t *testing.T) {
 		require.Contains(t, errHTTPDecode.Error(), "net/http")
 	})
 }
+
+func TestQueryPriority(t *testing.T) {
+	adapter := &frontendToSchedulerAdapter{
+		limits: limits{maxQueryPriority: 5},
+	}
+
+	for testName, testData := range map[string]struct {
+		ctx              context.Context
+		expectedPriority int32
+	}{
+		"request without priority has the default priority": {
+			ctx:              context.Background(),
+			expectedPriority: 0,
+		},
+		"priority lower than the max is honored": {
+			ctx:              querierapi.ContextWithQueryPriority(context.Background(), 3),
+			expectedPriority: 3,
+		},
+		"negative priority is honored": {
+			ctx:              querierapi.ContextWithQueryPriority(context.Background(), -10),
+			expectedPriority: -10,
+		},
+		"priority higher than the max is capped": {
+			ctx:              querierapi.ContextWithQueryPriority(context.Background(), 100),
+			expectedPriority: 5,
+		},
+	} {
+		t.Run(testName, func(t *testing.T) {
+			priority, err := adapter.queryPriority(testData.ctx, "tenant-0|tenant-1")
+			require.NoError(t, err)
+			require.Equal(t, testData.expectedPriority, priority)
+		})
+	}
+}
+
+func TestFrontendToSchedulerEnqueueRequest_ShouldSendTheRequestDeadline(t *testing.T) {
+	adapter := &frontendToSchedulerAdapter{
+		log:    log.NewNopLogger(),
+		limits: limits{},
+		codec:  querymiddleware.NewPrometheusCodec(prometheus.NewPedanticRegistry(), 0*time.Minute, "json"),
+	}
+
+	newRequest := func(ctx context.Context) *frontendRequest {
+		return &frontendRequest{
+			queryID: 1,
+			userID:  "tenant-0",
+			request: &httpgrpc.HTTPRequest{Method: "GET", Url: "/hello"},
+			ctx:     user.InjectOrgID(ctx, "tenant-0"),
+		}
+	}
+
+	msg, err := adapter.frontendToSchedulerEnqueueRequest(newRequest(context.Background()), "frontend-0")
+	require.NoError(t, err)
+	require.Zero(t, msg.DeadlineUnixMillis)
+
+	deadline := time.Now().Add(time.Minute)
+	ctx, cancel := context.WithDeadline(context.Background(), deadline)
+	defer cancel()
+
+	msg, err = adapter.frontendToSchedulerEnqueueRequest(newRequest(ctx), "frontend-0")
+	require.NoError(t, err)
+	require.Equal(t, deadline.UnixMilli(), msg.DeadlineUnixMillis)
+}
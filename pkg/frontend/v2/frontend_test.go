@@ -736,8 +736,13 @@ func makeLabels(namesAndValues ...string) []*dto.LabelPair {
 
 type limits struct {
 	queryIngestersWithin time.Duration
+	maxQueryPriority     int
 }
 
 func (l limits) QueryIngestersWithin(string) time.Duration {
 	return l.queryIngestersWithin
 }
+
+func (l limits) MaxQueryPriority(string) int {
+	return l.maxQueryPriority
+}
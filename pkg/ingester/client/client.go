@@ -52,6 +52,9 @@ func MakeIngesterClient(inst ring.InstanceDesc, cfg Config, metrics *Metrics) (H
 
 	ingClient := NewIngesterClient(conn)
 	ingClient = newBufferPoolingIngesterClient(ingClient, conn)
+	if cfg.PushSnappyCodec {
+		ingClient = &pushCodecIngesterClient{IngesterClient: ingClient}
+	}
 
 	return &closableHealthAndIngesterClient{
 		IngesterClient: ingClient,
@@ -67,12 +70,14 @@ func (c *closableHealthAndIngesterClient) Close() error {
 // Config is the configuration struct for the ingester client
 type Config struct {
 	GRPCClientConfig grpcclient.Config `yaml:"grpc_client_config" doc:"description=Configures the gRPC client used to communicate with ingesters from distributors, queriers and rulers."`
+	PushSnappyCodec  bool              `yaml:"push_snappy_codec" category:"experimental"`
 }
 
 // RegisterFlags registers configuration settings used by the ingester client config.
 func (cfg *Config) RegisterFlags(f *flag.FlagSet) {
 	cfg.GRPCClientConfig.CustomCompressors = []string{s2.Name}
 	cfg.GRPCClientConfig.RegisterFlagsWithPrefix("ingester.client", f)
+	f.BoolVar(&cfg.PushSnappyCodec, "ingester.client.push-snappy-codec", false, "Compress the push requests sent to ingesters with snappy in the gRPC codec, instead of the gRPC compression configured for the client. This allows ingesters to decompress and unmarshal them in the pool of workers configured by -ingester.push-decoding-workers. Enable it only once all ingesters run a version supporting it.")
 }
 
 func (cfg *Config) Validate() error {
@@ -0,0 +1,135 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package client
+
+import (
+	"context"
+	"slices"
+
+	"github.com/golang/snappy"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/encoding"
+	"google.golang.org/grpc/encoding/proto"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/grafana/mimir/pkg/mimirpb"
+)
+
+// PushCodecName is the gRPC content-subtype of the push requests whose payload is compressed with snappy by the
+// gRPC codec, instead of the gRPC compression. Unlike the gRPC compression, which is applied by the gRPC server
+// before the request reaches its handler, this allows the ingester to decompress the payload in the Push handler.
+const PushCodecName = "mimir-push-snappy"
+
+func init() {
+	encoding.RegisterCodec(pushCodec{Codec: encoding.GetCodec(proto.Name)})
+}
+
+// pushCodecCallOptions are the call options used to send push requests with the push codec. The gRPC compression
+// is disabled, since the payload is already compressed by the codec.
+var pushCodecCallOptions = []grpc.CallOption{
+	grpc.CallContentSubtype(PushCodecName),
+	grpc.UseCompressor(encoding.Identity),
+}
+
+// pushCodec is a gRPC codec compressing the marshalled messages with snappy.
+type pushCodec struct {
+	encoding.Codec
+}
+
+func (c pushCodec) Name() string {
+	return PushCodecName
+}
+
+func (c pushCodec) Marshal(v any) ([]byte, error) {
+	data, err := c.Codec.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return snappy.Encode(nil, data), nil
+}
+
+func (c pushCodec) Unmarshal(data []byte, v any) error {
+	// The compressed push requests are decoded by the Push handler.
+	if req, ok := v.(*compressedPushRequest); ok {
+		req.data = data
+		return nil
+	}
+
+	decoded, err := snappy.Decode(nil, data)
+	if err != nil {
+		return err
+	}
+	return c.Codec.Unmarshal(decoded, v)
+}
+
+// compressedPushRequest holds the snappy-compressed payload of a push request sent with the push codec.
+// The payload references the gRPC receive buffer, which is not reused because the gRPC server receive
+// buffer pools are disabled.
+type compressedPushRequest struct {
+	data []byte
+}
+
+// pushCodecIngesterClient is an IngesterClient sending the push requests with the push codec.
+type pushCodecIngesterClient struct {
+	IngesterClient
+}
+
+func (c *pushCodecIngesterClient) Push(ctx context.Context, in *mimirpb.WriteRequest, opts ...grpc.CallOption) (*mimirpb.WriteResponse, error) {
+	return c.IngesterClient.Push(ctx, in, append(opts, pushCodecCallOptions...)...)
+}
+
+// PushDecoder decodes the snappy-compressed payload of a push request sent with the push codec. The returned
+// function is called once the request has been handled, to release the resources referenced by the request.
+type PushDecoder func(ctx context.Context, compressed []byte) (*mimirpb.WriteRequest, func(), error)
+
+// RegisterIngesterServerWithPushDecoder is like RegisterIngesterServer, but the push requests sent with the push
+// codec are decoded by the given decoder. The other push requests are handled as usual.
+func RegisterIngesterServerWithPushDecoder(s *grpc.Server, srv IngesterServer, decoder PushDecoder) {
+	desc := _Ingester_serviceDesc
+	desc.Methods = slices.Clone(desc.Methods)
+	for i := range desc.Methods {
+		if desc.Methods[i].MethodName == "Push" {
+			desc.Methods[i].Handler = pushHandlerWithDecoder(decoder)
+		}
+	}
+	s.RegisterService(&desc, srv)
+}
+
+func pushHandlerWithDecoder(decoder PushDecoder) func(any, context.Context, func(any) error, grpc.UnaryServerInterceptor) (any, error) {
+	return func(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+		if !isPushCodecRequest(ctx) {
+			return _Ingester_Push_Handler(srv, ctx, dec, interceptor)
+		}
+
+		compressed := &compressedPushRequest{}
+		if err := dec(compressed); err != nil {
+			return nil, err
+		}
+		in, release, err := decoder(ctx, compressed.data)
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+
+		if interceptor == nil {
+			return srv.(IngesterServer).Push(ctx, in)
+		}
+		info := &grpc.UnaryServerInfo{
+			Server:     srv,
+			FullMethod: "/cortex.Ingester/Push",
+		}
+		handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+			return srv.(IngesterServer).Push(ctx, req.(*mimirpb.WriteRequest))
+		}
+		return interceptor(ctx, in, info, handler)
+	}
+}
+
+// isPushCodecRequest returns whether the request in the context has been sent with the push codec.
+func isPushCodecRequest(ctx context.Context) bool {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return false
+	}
+	return slices.Contains(md.Get("content-type"), "application/grpc+"+PushCodecName)
+}
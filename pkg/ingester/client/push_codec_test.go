@@ -0,0 +1,101 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package client
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/golang/snappy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/encoding"
+
+	"github.com/grafana/mimir/pkg/mimirpb"
+	"github.com/grafana/mimir/pkg/util/pool"
+)
+
+func TestRegisterIngesterServerWithPushDecoder(t *testing.T) {
+	var decoded, released, intercepted int
+	decoder := func(_ context.Context, compressed []byte) (*mimirpb.WriteRequest, func(), error) {
+		decoded++
+		data, err := snappy.Decode(nil, compressed)
+		if err != nil {
+			return nil, nil, err
+		}
+		req := &mimirpb.WriteRequest{}
+		if err := req.Unmarshal(data); err != nil {
+			return nil, nil, err
+		}
+		return req, func() { released++ }, nil
+	}
+	interceptor := func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		intercepted++
+		assert.Equal(t, "/cortex.Ingester/Push", info.FullMethod)
+		return handler(ctx, req)
+	}
+
+	server := grpc.NewServer(grpc.UnaryInterceptor(interceptor))
+	ingServ := &mockServer{}
+	RegisterIngesterServerWithPushDecoder(server, ingServ, decoder)
+
+	l, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	go func() {
+		_ = server.Serve(l)
+	}()
+	t.Cleanup(server.GracefulStop)
+
+	conn, err := grpc.NewClient(l.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = conn.Close()
+	})
+
+	req := createRequest("test", 10)
+
+	// Requests sent with the push codec are decoded by the decoder, even when marshalled in pooled buffers.
+	pushCodecClient := &pushCodecIngesterClient{IngesterClient: newBufferPoolingIngesterClient(NewIngesterClient(conn), conn)}
+	ctx := WithSlabPool(context.Background(), pool.NewFastReleasingSlabPool[byte](&pool.TrackedPool{Parent: &sync.Pool{}}, 512*1024))
+	_, err = pushCodecClient.Push(ctx, req)
+	require.NoError(t, err)
+	assert.Equal(t, 1, decoded)
+	assert.Equal(t, 1, released)
+	assert.Equal(t, 1, intercepted)
+
+	// Other requests are unmarshalled as usual.
+	_, err = NewIngesterClient(conn).Push(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, 1, decoded)
+	assert.Equal(t, 2, intercepted)
+
+	require.Len(t, ingServ.requests(), 2)
+	for _, received := range ingServ.requests() {
+		assert.Equal(t, req, received)
+	}
+}
+
+func TestPushCodec(t *testing.T) {
+	codec := encoding.GetCodec(PushCodecName)
+	require.NotNil(t, codec)
+
+	req := createRequest("test", 10)
+	data, err := codec.Marshal(req)
+	require.NoError(t, err)
+
+	// The push requests are kept compressed, to be decoded by the Push handler.
+	compressed := &compressedPushRequest{}
+	require.NoError(t, codec.Unmarshal(data, compressed))
+	assert.Equal(t, data, compressed.data)
+
+	actual := &mimirpb.WriteRequest{}
+	require.NoError(t, codec.Unmarshal(data, actual))
+	actual.ClearTimeseriesUnmarshalData()
+	assert.Equal(t, req, actual)
+
+	require.Error(t, codec.Unmarshal([]byte("invalid"), &mimirpb.WriteRequest{}))
+}
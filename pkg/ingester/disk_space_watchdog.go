@@ -0,0 +1,192 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ingester
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/grafana/dskit/services"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/atomic"
+
+	"github.com/grafana/mimir/pkg/util/globalerror"
+)
+
+const (
+	diskSpaceReadOnlyThresholdFlag = "ingester.disk-space-watchdog.read-only-threshold"
+)
+
+var (
+	errDiskSpaceReadOnly = newInstanceLimitReachedError(globalerror.IngesterDiskSpaceReadOnly.MessageWithPerInstanceLimitConfig("the write request has been rejected because the ingester is running out of disk space and switched to read-only mode", diskSpaceReadOnlyThresholdFlag))
+
+	errInvalidDiskSpaceThresholds    = errors.New("the disk space watchdog thresholds must be between 0 and 1, and the read-only threshold must be less than the early compaction threshold")
+	errInvalidDiskSpaceCheckInterval = errors.New("the disk space watchdog check interval must be greater than 0")
+	errInvalidDiskSpaceCooldown      = errors.New("the disk space watchdog early compaction cooldown must be greater than or equal to 0")
+)
+
+// DiskSpaceWatchdogConfig configures the monitoring of the free disk space of the TSDB directory.
+type DiskSpaceWatchdogConfig struct {
+	CheckInterval            time.Duration `yaml:"check_interval" category:"experimental"`
+	EarlyCompactionThreshold float64       `yaml:"early_compaction_threshold" category:"experimental"`
+	EarlyCompactionCooldown  time.Duration `yaml:"early_compaction_cooldown" category:"experimental"`
+	ReadOnlyThreshold        float64       `yaml:"read_only_threshold" category:"experimental"`
+}
+
+func (cfg *DiskSpaceWatchdogConfig) RegisterFlagsWithPrefix(prefix string, f *flag.FlagSet) {
+	f.DurationVar(&cfg.CheckInterval, prefix+"check-interval", 30*time.Second, "How frequently the free disk space of the TSDB directory is checked.")
+	f.Float64Var(&cfg.EarlyCompactionThreshold, prefix+"early-compaction-threshold", 0, "Ratio of free disk space of the TSDB directory below which the ingester compacts the TSDB Head of all tenants and ships the resulting blocks, in order to truncate the WAL. Use 0 to disable it.")
+	f.DurationVar(&cfg.EarlyCompactionCooldown, prefix+"early-compaction-cooldown", 15*time.Minute, "Minimum time between two compactions triggered by the early compaction threshold. While the free disk space stays below the threshold, for example because the disk is filled up by something else, the TSDB Heads are compacted at most once per cooldown period, to avoid creating many small blocks.")
+	f.Float64Var(&cfg.ReadOnlyThreshold, prefix+"read-only-threshold", 0, "Ratio of free disk space of the TSDB directory below which the ingester switches to read-only mode and rejects write requests, instead of failing to write the WAL once the disk is full. The ingester accepts write requests again once the free disk space is back above the threshold. Use 0 to disable it.")
+}
+
+func (cfg *DiskSpaceWatchdogConfig) Validate() error {
+	if !cfg.enabled() {
+		return nil
+	}
+	if cfg.CheckInterval <= 0 {
+		return errInvalidDiskSpaceCheckInterval
+	}
+	if cfg.EarlyCompactionCooldown < 0 {
+		return errInvalidDiskSpaceCooldown
+	}
+	if cfg.EarlyCompactionThreshold < 0 || cfg.EarlyCompactionThreshold >= 1 || cfg.ReadOnlyThreshold < 0 || cfg.ReadOnlyThreshold >= 1 {
+		return errInvalidDiskSpaceThresholds
+	}
+	if cfg.EarlyCompactionThreshold > 0 && cfg.ReadOnlyThreshold >= cfg.EarlyCompactionThreshold {
+		return errInvalidDiskSpaceThresholds
+	}
+	return nil
+}
+
+func (cfg *DiskSpaceWatchdogConfig) enabled() bool {
+	return cfg.EarlyCompactionThreshold > 0 || cfg.ReadOnlyThreshold > 0
+}
+
+// diskSpaceFunc returns the available space and the total capacity, in bytes, of the filesystem holding the dir.
+type diskSpaceFunc func(dir string) (available, capacity uint64, err error)
+
+// diskSpaceWatchdog periodically checks the free disk space of the TSDB directory. When it's below the early
+// compaction threshold, the TSDB Heads are compacted and shipped, so that the WAL gets truncated, at most once per
+// cooldown period. When it's below the read-only threshold, write requests are rejected until enough disk space
+// is freed up.
+type diskSpaceWatchdog struct {
+	services.Service
+
+	cfg       DiskSpaceWatchdogConfig
+	dir       string
+	diskSpace diskSpaceFunc
+	flush     func(ctx context.Context)
+	logger    log.Logger
+
+	readOnly atomic.Bool
+
+	// lastEarlyCompaction is the time of the last early compaction. It's only accessed by the iterations.
+	lastEarlyCompaction time.Time
+
+	availableBytes   prometheus.Gauge
+	capacityBytes    prometheus.Gauge
+	readOnlyGauge    prometheus.Gauge
+	earlyCompactions prometheus.Counter
+}
+
+func newDiskSpaceWatchdog(cfg DiskSpaceWatchdogConfig, dir string, diskSpace diskSpaceFunc, flush func(ctx context.Context), logger log.Logger, reg prometheus.Registerer) *diskSpaceWatchdog {
+	w := &diskSpaceWatchdog{
+		cfg:       cfg,
+		dir:       dir,
+		diskSpace: diskSpace,
+		flush:     flush,
+		logger:    logger,
+
+		availableBytes: promauto.With(reg).NewGauge(prometheus.GaugeOpts{
+			Name: "cortex_ingester_tsdb_disk_available_bytes",
+			Help: "Disk space, in bytes, available to the ingester in the filesystem holding the TSDB directory.",
+		}),
+		capacityBytes: promauto.With(reg).NewGauge(prometheus.GaugeOpts{
+			Name: "cortex_ingester_tsdb_disk_capacity_bytes",
+			Help: "Total capacity, in bytes, of the filesystem holding the TSDB directory.",
+		}),
+		readOnlyGauge: promauto.With(reg).NewGauge(prometheus.GaugeOpts{
+			Name: "cortex_ingester_disk_space_read_only",
+			Help: "1 if the ingester rejects write requests because it's running out of disk space, 0 otherwise.",
+		}),
+		earlyCompactions: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "cortex_ingester_disk_space_early_compactions_total",
+			Help: "Total number of TSDB Head compactions triggered because the ingester was running out of disk space.",
+		}),
+	}
+
+	w.Service = services.NewTimerService(cfg.CheckInterval, nil, w.iteration, nil)
+	return w
+}
+
+// isReadOnly returns whether write requests should be rejected because the ingester is running out of disk space.
+func (w *diskSpaceWatchdog) isReadOnly() bool {
+	return w.readOnly.Load()
+}
+
+func (w *diskSpaceWatchdog) iteration(ctx context.Context) error {
+	ratio, err := w.freeSpaceRatio()
+	if err != nil {
+		// Keep the previous state, because the disk space is unknown.
+		level.Warn(w.logger).Log("msg", "failed to check the free disk space of the TSDB directory", "dir", w.dir, "err", err)
+		return nil
+	}
+
+	w.setReadOnly(w.cfg.ReadOnlyThreshold > 0 && ratio < w.cfg.ReadOnlyThreshold, ratio)
+
+	if w.cfg.EarlyCompactionThreshold > 0 && ratio < w.cfg.EarlyCompactionThreshold {
+		if time.Since(w.lastEarlyCompaction) < w.cfg.EarlyCompactionCooldown {
+			level.Debug(w.logger).Log("msg", "the free disk space of the TSDB directory is below the early compaction threshold, but the last early compaction is too recent", "free_space_ratio", ratio, "threshold", w.cfg.EarlyCompactionThreshold, "last_early_compaction", w.lastEarlyCompaction)
+			return nil
+		}
+
+		level.Warn(w.logger).Log("msg", "the free disk space of the TSDB directory is below the early compaction threshold, compacting and shipping TSDB blocks", "free_space_ratio", ratio, "threshold", w.cfg.EarlyCompactionThreshold)
+		w.earlyCompactions.Inc()
+		w.lastEarlyCompaction = time.Now()
+		w.flush(ctx)
+
+		// Check again, so that the ingester doesn't keep rejecting writes until the next check
+		// if the compaction freed up enough disk space.
+		if ratio, err = w.freeSpaceRatio(); err == nil {
+			w.setReadOnly(w.cfg.ReadOnlyThreshold > 0 && ratio < w.cfg.ReadOnlyThreshold, ratio)
+		}
+	}
+
+	return nil
+}
+
+func (w *diskSpaceWatchdog) freeSpaceRatio() (float64, error) {
+	available, capacity, err := w.diskSpace(w.dir)
+	if err != nil {
+		return 0, err
+	}
+
+	w.availableBytes.Set(float64(available))
+	w.capacityBytes.Set(float64(capacity))
+
+	if capacity == 0 {
+		// The capacity is unknown, so the disk is not considered to be running out of space.
+		return 1, nil
+	}
+	return float64(available) / float64(capacity), nil
+}
+
+func (w *diskSpaceWatchdog) setReadOnly(readOnly bool, ratio float64) {
+	if w.readOnly.Swap(readOnly) == readOnly {
+		return
+	}
+
+	if readOnly {
+		level.Error(w.logger).Log("msg", "the free disk space of the TSDB directory is below the read-only threshold, rejecting write requests", "free_space_ratio", ratio, "threshold", w.cfg.ReadOnlyThreshold)
+		w.readOnlyGauge.Set(1)
+	} else {
+		level.Info(w.logger).Log("msg", "the free disk space of the TSDB directory is back above the read-only threshold, accepting write requests", "free_space_ratio", ratio, "threshold", w.cfg.ReadOnlyThreshold)
+		w.readOnlyGauge.Set(0)
+	}
+}
@@ -0,0 +1,209 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ingester
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/dskit/services"
+	"github.com/grafana/dskit/test"
+	"github.com/grafana/dskit/user"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiskSpaceWatchdogConfig_Validate(t *testing.T) {
+	tests := map[string]struct {
+		cfg      DiskSpaceWatchdogConfig
+		expected error
+	}{
+		"disabled": {
+			cfg: DiskSpaceWatchdogConfig{},
+		},
+		"only early compaction enabled": {
+			cfg: DiskSpaceWatchdogConfig{CheckInterval: time.Minute, EarlyCompactionThreshold: 0.2},
+		},
+		"only read-only enabled": {
+			cfg: DiskSpaceWatchdogConfig{CheckInterval: time.Minute, ReadOnlyThreshold: 0.05},
+		},
+		"both enabled": {
+			cfg: DiskSpaceWatchdogConfig{CheckInterval: time.Minute, EarlyCompactionThreshold: 0.2, ReadOnlyThreshold: 0.05},
+		},
+		"invalid early compaction cooldown": {
+			cfg:      DiskSpaceWatchdogConfig{CheckInterval: time.Minute, EarlyCompactionThreshold: 0.2, EarlyCompactionCooldown: -time.Minute},
+			expected: errInvalidDiskSpaceCooldown,
+		},
+		"invalid check interval": {
+			cfg:      DiskSpaceWatchdogConfig{ReadOnlyThreshold: 0.05},
+			expected: errInvalidDiskSpaceCheckInterval,
+		},
+		"threshold out of range": {
+			cfg:      DiskSpaceWatchdogConfig{CheckInterval: time.Minute, EarlyCompactionThreshold: 1},
+			expected: errInvalidDiskSpaceThresholds,
+		},
+		"read-only threshold greater than early compaction threshold": {
+			cfg:      DiskSpaceWatchdogConfig{CheckInterval: time.Minute, EarlyCompactionThreshold: 0.05, ReadOnlyThreshold: 0.2},
+			expected: errInvalidDiskSpaceThresholds,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, tc.cfg.Validate())
+		})
+	}
+}
+
+func TestDiskSpaceWatchdog_iteration(t *testing.T) {
+	cfg := DiskSpaceWatchdogConfig{CheckInterval: time.Minute, EarlyCompactionThreshold: 0.2, ReadOnlyThreshold: 0.05}
+
+	var (
+		available uint64
+		diskErr   error
+		flushes   int
+		freedUp   uint64
+	)
+	diskSpace := func(string) (uint64, uint64, error) {
+		return available, 100, diskErr
+	}
+	flush := func(context.Context) {
+		flushes++
+		available += freedUp
+	}
+
+	reg := prometheus.NewPedanticRegistry()
+	w := newDiskSpaceWatchdog(cfg, "dir", diskSpace, flush, log.NewNopLogger(), reg)
+
+	// Enough free disk space.
+	available = 50
+	require.NoError(t, w.iteration(context.Background()))
+	assert.False(t, w.isReadOnly())
+	assert.Equal(t, 0, flushes)
+
+	// Below the early compaction threshold.
+	available = 10
+	require.NoError(t, w.iteration(context.Background()))
+	assert.False(t, w.isReadOnly())
+	assert.Equal(t, 1, flushes)
+
+	// Below the read-only threshold, and the compaction doesn't free up enough disk space.
+	available = 1
+	require.NoError(t, w.iteration(context.Background()))
+	assert.True(t, w.isReadOnly())
+	assert.Equal(t, 2, flushes)
+
+	// The state doesn't change if the disk space can't be checked.
+	diskErr = errors.New("failed")
+	require.NoError(t, w.iteration(context.Background()))
+	assert.True(t, w.isReadOnly())
+	assert.Equal(t, 2, flushes)
+
+	// The compaction frees up enough disk space to accept writes again.
+	diskErr = nil
+	freedUp = 9
+	require.NoError(t, w.iteration(context.Background()))
+	assert.False(t, w.isReadOnly())
+	assert.Equal(t, 3, flushes)
+
+	assert.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(`
+		# HELP cortex_ingester_disk_space_early_compactions_total Total number of TSDB Head compactions triggered because the ingester was running out of disk space.
+		# TYPE cortex_ingester_disk_space_early_compactions_total counter
+		cortex_ingester_disk_space_early_compactions_total 3
+
+		# HELP cortex_ingester_disk_space_read_only 1 if the ingester rejects write requests because it's running out of disk space, 0 otherwise.
+		# TYPE cortex_ingester_disk_space_read_only gauge
+		cortex_ingester_disk_space_read_only 0
+
+		# HELP cortex_ingester_tsdb_disk_available_bytes Disk space, in bytes, available to the ingester in the filesystem holding the TSDB directory.
+		# TYPE cortex_ingester_tsdb_disk_available_bytes gauge
+		cortex_ingester_tsdb_disk_available_bytes 10
+
+		# HELP cortex_ingester_tsdb_disk_capacity_bytes Total capacity, in bytes, of the filesystem holding the TSDB directory.
+		# TYPE cortex_ingester_tsdb_disk_capacity_bytes gauge
+		cortex_ingester_tsdb_disk_capacity_bytes 100
+	`)))
+}
+
+func TestDiskSpaceWatchdog_iteration_EarlyCompactionCooldown(t *testing.T) {
+	cfg := DiskSpaceWatchdogConfig{CheckInterval: time.Minute, EarlyCompactionThreshold: 0.2, EarlyCompactionCooldown: time.Hour, ReadOnlyThreshold: 0.05}
+
+	available := uint64(10)
+	diskSpace := func(string) (uint64, uint64, error) {
+		return available, 100, nil
+	}
+	flushes := 0
+	flush := func(context.Context) {
+		flushes++
+	}
+
+	w := newDiskSpaceWatchdog(cfg, "dir", diskSpace, flush, log.NewNopLogger(), prometheus.NewPedanticRegistry())
+
+	require.NoError(t, w.iteration(context.Background()))
+	assert.Equal(t, 1, flushes)
+
+	// The TSDB Heads are not compacted again until the cooldown period has elapsed,
+	// but the ingester still switches to read-only mode.
+	available = 1
+	require.NoError(t, w.iteration(context.Background()))
+	assert.Equal(t, 1, flushes)
+	assert.True(t, w.isReadOnly())
+
+	w.lastEarlyCompaction = time.Now().Add(-cfg.EarlyCompactionCooldown)
+	require.NoError(t, w.iteration(context.Background()))
+	assert.Equal(t, 2, flushes)
+}
+
+func TestIngester_DiskSpaceWatchdog(t *testing.T) {
+	cfg := defaultIngesterTestConfig(t)
+	cfg.DiskSpaceWatchdog = DiskSpaceWatchdogConfig{CheckInterval: time.Hour, EarlyCompactionThreshold: 0.2, ReadOnlyThreshold: 0.05}
+
+	i, err := prepareIngesterWithBlocksStorage(t, cfg, nil, prometheus.NewPedanticRegistry())
+	require.NoError(t, err)
+	require.NoError(t, services.StartAndAwaitRunning(context.Background(), i))
+	t.Cleanup(func() {
+		require.NoError(t, services.StopAndAwaitTerminated(context.Background(), i))
+	})
+
+	test.Poll(t, time.Second, 1, func() interface{} {
+		return i.lifecycler.HealthyInstancesCount()
+	})
+
+	available := uint64(50)
+	i.diskSpaceWatchdog.diskSpace = func(string) (uint64, uint64, error) {
+		return available, 100, nil
+	}
+
+	ctx := user.InjectOrgID(context.Background(), userID)
+	push := func() error {
+		req, _, _, _ := mockWriteRequest(t, labels.FromStrings(labels.MetricName, "test"), 1, time.Now().UnixMilli())
+		_, err := pushWithSimulatedGRPCHandler(ctx, i, req)
+		return err
+	}
+
+	require.NoError(t, push())
+	require.Equal(t, uint64(1), i.getTSDB(userID).Head().NumSeries())
+
+	// Below the early compaction threshold, the TSDB Head gets compacted.
+	available = 10
+	require.NoError(t, i.diskSpaceWatchdog.iteration(context.Background()))
+	assert.Equal(t, uint64(0), i.getTSDB(userID).Head().NumSeries())
+	require.NoError(t, push())
+
+	// Below the read-only threshold, write requests are rejected.
+	available = 1
+	require.NoError(t, i.diskSpaceWatchdog.iteration(context.Background()))
+	require.ErrorIs(t, push(), errDiskSpaceReadOnly)
+
+	// Above the read-only threshold, write requests are accepted again.
+	available = 10
+	require.NoError(t, i.diskSpaceWatchdog.iteration(context.Background()))
+	require.NoError(t, push())
+}
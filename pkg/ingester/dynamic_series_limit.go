@@ -0,0 +1,257 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ingester
+
+import (
+	"context"
+	"flag"
+	"math"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/grafana/dskit/kv"
+	"github.com/grafana/dskit/kv/codec"
+	"github.com/grafana/dskit/services"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/atomic"
+
+	"github.com/grafana/mimir/pkg/util/instancestate"
+)
+
+const (
+	// memoryUtilizationKey is the key, in the ingesters ring KV store, holding the memory utilization
+	// advertised by each ingester.
+	memoryUtilizationKey = "ingester-memory-utilization"
+
+	// dynamicSeriesLimitRelaxStep is how much the series limit factor is increased at every update
+	// while the cluster memory utilization is below the target.
+	dynamicSeriesLimitRelaxStep = 0.05
+)
+
+var (
+	errInvalidDynamicSeriesLimitTargetUtilization = errors.New("the dynamic series limit target utilization must be greater than 0 and less than or equal to 1")
+	errInvalidDynamicSeriesLimitFactors           = errors.New("the dynamic series limit min factor must be greater than 0 and less than or equal to the max factor")
+	errInvalidDynamicSeriesLimitUpdatePeriod      = errors.New("the dynamic series limit update period must be greater than 0")
+)
+
+// DynamicSeriesLimitConfig configures the adjustment of the per-tenant series limits based on the
+// cluster-wide memory headroom.
+type DynamicSeriesLimitConfig struct {
+	MemoryCapacityBytes uint64        `yaml:"memory_capacity_bytes" category:"experimental"`
+	TargetUtilization   float64       `yaml:"target_utilization" category:"experimental"`
+	MinFactor           float64       `yaml:"min_factor" category:"experimental"`
+	MaxFactor           float64       `yaml:"max_factor" category:"experimental"`
+	UpdatePeriod        time.Duration `yaml:"update_period" category:"experimental"`
+}
+
+func (cfg *DynamicSeriesLimitConfig) RegisterFlagsWithPrefix(prefix string, f *flag.FlagSet) {
+	f.Uint64Var(&cfg.MemoryCapacityBytes, prefix+"memory-capacity-bytes", 0, "Memory, in bytes, of the ingester heap which is considered fully utilized. If set, the ingesters advertise their memory utilization through the ingesters ring KV store, and the per-tenant series limits are tightened when the cluster-wide memory utilization is above the target, and relaxed afterwards. Use 0 to disable it.")
+	f.Float64Var(&cfg.TargetUtilization, prefix+"target-utilization", 0.8, "Cluster-wide memory utilization, as a ratio of the memory capacity, above which the per-tenant series limits are tightened.")
+	f.Float64Var(&cfg.MinFactor, prefix+"min-factor", 0.5, "Minimum factor applied to the per-tenant series limits.")
+	f.Float64Var(&cfg.MaxFactor, prefix+"max-factor", 1, "Maximum factor applied to the per-tenant series limits. Values greater than 1 allow the tenants to exceed their configured series limits while the cluster has enough memory headroom.")
+	f.DurationVar(&cfg.UpdatePeriod, prefix+"update-period", 15*time.Second, "How frequently the ingesters advertise their memory utilization and update the per-tenant series limits factor.")
+}
+
+func (cfg *DynamicSeriesLimitConfig) Validate() error {
+	if !cfg.enabled() {
+		return nil
+	}
+	if cfg.TargetUtilization <= 0 || cfg.TargetUtilization > 1 {
+		return errInvalidDynamicSeriesLimitTargetUtilization
+	}
+	if cfg.MinFactor <= 0 || cfg.MinFactor > cfg.MaxFactor {
+		return errInvalidDynamicSeriesLimitFactors
+	}
+	if cfg.UpdatePeriod <= 0 {
+		return errInvalidDynamicSeriesLimitUpdatePeriod
+	}
+	return nil
+}
+
+func (cfg *DynamicSeriesLimitConfig) enabled() bool {
+	return cfg.MemoryCapacityBytes > 0
+}
+
+// MemoryUtilizationDesc holds the memory utilization advertised by each ingester.
+type MemoryUtilizationDesc = instancestate.Desc[InstanceMemoryUtilization]
+
+// InstanceMemoryUtilization holds the memory utilization advertised by an ingester.
+type InstanceMemoryUtilization struct {
+	// Timestamp is the unix timestamp, in milliseconds, of the last update.
+	Timestamp int64 `json:"timestamp"`
+
+	// Utilization is the ratio between the ingester heap in use and its memory capacity.
+	Utilization float64 `json:"utilization"`
+}
+
+// GetTimestamp implements instancestate.State.
+func (u InstanceMemoryUtilization) GetTimestamp() int64 {
+	return u.Timestamp
+}
+
+// Clone implements instancestate.State.
+func (u InstanceMemoryUtilization) Clone() InstanceMemoryUtilization {
+	return u
+}
+
+// GetMemoryUtilizationCodec returns the codec used to store the MemoryUtilizationDesc in the KV store.
+func GetMemoryUtilizationCodec() codec.Codec {
+	return instancestate.NewCodec[InstanceMemoryUtilization]("memoryUtilizationDesc")
+}
+
+// dynamicSeriesLimit periodically advertises the memory utilization of this ingester, and adjusts the
+// factor applied to the per-tenant series limits based on the memory utilization of all ingesters.
+// Nil dynamicSeriesLimit doesn't change the limits.
+type dynamicSeriesLimit struct {
+	services.Service
+
+	cfg        DynamicSeriesLimitConfig
+	instanceID string
+	client     kv.Client
+	logger     log.Logger
+
+	// heapInuse returns the heap in use, in bytes. It can be overridden in tests.
+	heapInuse func() uint64
+
+	factor             atomic.Float64
+	clusterUtilization atomic.Float64
+
+	remoteMtx sync.RWMutex
+	remote    *MemoryUtilizationDesc
+}
+
+func newDynamicSeriesLimit(cfg DynamicSeriesLimitConfig, instanceID string, client kv.Client, logger log.Logger, reg prometheus.Registerer) *dynamicSeriesLimit {
+	l := &dynamicSeriesLimit{
+		cfg:        cfg,
+		instanceID: instanceID,
+		client:     client,
+		logger:     logger,
+		heapInuse: func() uint64 {
+			var m runtime.MemStats
+			runtime.ReadMemStats(&m)
+			return m.HeapInuse
+		},
+		remote: instancestate.NewDesc[InstanceMemoryUtilization](),
+	}
+	l.factor.Store(math.Max(cfg.MinFactor, math.Min(cfg.MaxFactor, 1)))
+
+	promauto.With(reg).NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "cortex_ingester_dynamic_series_limit_factor",
+		Help: "Factor currently applied to the per-tenant series limits based on the cluster-wide memory utilization.",
+	}, l.factor.Load)
+	promauto.With(reg).NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "cortex_ingester_cluster_memory_utilization",
+		Help: "Average memory utilization advertised by the ingesters, used to compute the dynamic series limit factor.",
+	}, l.clusterUtilization.Load)
+
+	l.Service = services.NewBasicService(nil, l.running, nil)
+	return l
+}
+
+// apply returns the limit adjusted by the current factor. A disabled limit (0) is left unchanged.
+func (l *dynamicSeriesLimit) apply(limit int) int {
+	if l == nil || limit <= 0 {
+		return limit
+	}
+	return max(1, int(float64(limit)*l.factor.Load()))
+}
+
+func (l *dynamicSeriesLimit) running(ctx context.Context) error {
+	go l.client.WatchKey(ctx, memoryUtilizationKey, func(value interface{}) bool {
+		desc, ok := value.(*MemoryUtilizationDesc)
+		if !ok || desc == nil {
+			return true
+		}
+
+		l.remoteMtx.Lock()
+		l.remote = desc.Clone().(*MemoryUtilizationDesc)
+		l.remoteMtx.Unlock()
+		return true
+	})
+
+	ticker := time.NewTicker(l.cfg.UpdatePeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			l.update(ctx, time.Now())
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+func (l *dynamicSeriesLimit) update(ctx context.Context, now time.Time) {
+	utilization := float64(l.heapInuse()) / float64(l.cfg.MemoryCapacityBytes)
+
+	if err := l.publish(ctx, utilization, now); err != nil {
+		level.Warn(l.logger).Log("msg", "failed to advertise memory utilization to the KV store", "err", err)
+	}
+
+	clusterUtilization := l.averageUtilization(utilization, now)
+	l.clusterUtilization.Store(clusterUtilization)
+
+	prev := l.factor.Load()
+	next := nextDynamicSeriesLimitFactor(prev, clusterUtilization, l.cfg)
+	l.factor.Store(next)
+
+	if next != prev {
+		level.Info(l.logger).Log("msg", "updated dynamic series limit factor", "cluster_memory_utilization", clusterUtilization, "previous_factor", prev, "factor", next)
+	}
+}
+
+func (l *dynamicSeriesLimit) publish(ctx context.Context, utilization float64, now time.Time) error {
+	return l.client.CAS(ctx, memoryUtilizationKey, func(in interface{}) (out interface{}, retry bool, err error) {
+		desc, ok := in.(*MemoryUtilizationDesc)
+		if !ok || desc == nil {
+			desc = instancestate.NewDesc[InstanceMemoryUtilization]()
+		}
+
+		desc.Set(l.instanceID, InstanceMemoryUtilization{Timestamp: now.UnixMilli(), Utilization: utilization}, now)
+
+		return desc, true, nil
+	})
+}
+
+// averageUtilization returns the average memory utilization of the ingesters which recently advertised it,
+// including the local utilization of this ingester.
+func (l *dynamicSeriesLimit) averageUtilization(local float64, now time.Time) float64 {
+	minTimestamp := now.Add(-3 * l.cfg.UpdatePeriod).UnixMilli()
+	sum, count := local, 1
+
+	l.remoteMtx.RLock()
+	defer l.remoteMtx.RUnlock()
+
+	for id, entry := range l.remote.Instances {
+		if id == l.instanceID || entry.Timestamp < minTimestamp {
+			continue
+		}
+		sum += entry.Utilization
+		count++
+	}
+
+	return sum / float64(count)
+}
+
+// nextDynamicSeriesLimitFactor returns the factor which brings the cluster memory utilization back to the target,
+// computed from the configured limits rather than from the current factor, so that the adjustments don't compound
+// across updates. The factor is tightened immediately, and slowly relaxed while the utilization is below the target.
+// The returned factor is always within the configured bounds.
+func nextDynamicSeriesLimitFactor(curr, utilization float64, cfg DynamicSeriesLimitConfig) float64 {
+	next := cfg.MaxFactor
+	if utilization > 0 {
+		next = cfg.TargetUtilization / utilization
+	}
+
+	if next > curr {
+		next = math.Min(next, curr+dynamicSeriesLimitRelaxStep)
+	}
+
+	return math.Max(cfg.MinFactor, math.Min(cfg.MaxFactor, next))
+}
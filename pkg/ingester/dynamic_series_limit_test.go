@@ -0,0 +1,230 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ingester
+
+import (
+	"context"
+	"flag"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/dskit/kv/consul"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/mimir/pkg/util/instancestate"
+	"github.com/grafana/mimir/pkg/util/validation"
+)
+
+func TestDynamicSeriesLimitConfig_Validate(t *testing.T) {
+	tests := map[string]struct {
+		setup    func(cfg *DynamicSeriesLimitConfig)
+		expected error
+	}{
+		"should pass with the default config": {
+			setup: func(*DynamicSeriesLimitConfig) {},
+		},
+		"should pass when enabled with the default config": {
+			setup: func(cfg *DynamicSeriesLimitConfig) {
+				cfg.MemoryCapacityBytes = 1024
+			},
+		},
+		"should ignore invalid settings when disabled": {
+			setup: func(cfg *DynamicSeriesLimitConfig) {
+				cfg.TargetUtilization = 2
+			},
+		},
+		"should fail if the target utilization is greater than 1": {
+			setup: func(cfg *DynamicSeriesLimitConfig) {
+				cfg.MemoryCapacityBytes = 1024
+				cfg.TargetUtilization = 1.1
+			},
+			expected: errInvalidDynamicSeriesLimitTargetUtilization,
+		},
+		"should fail if the min factor is greater than the max factor": {
+			setup: func(cfg *DynamicSeriesLimitConfig) {
+				cfg.MemoryCapacityBytes = 1024
+				cfg.MinFactor = 0.9
+				cfg.MaxFactor = 0.8
+			},
+			expected: errInvalidDynamicSeriesLimitFactors,
+		},
+		"should fail if the min factor is 0": {
+			setup: func(cfg *DynamicSeriesLimitConfig) {
+				cfg.MemoryCapacityBytes = 1024
+				cfg.MinFactor = 0
+			},
+			expected: errInvalidDynamicSeriesLimitFactors,
+		},
+		"should fail if the update period is 0": {
+			setup: func(cfg *DynamicSeriesLimitConfig) {
+				cfg.MemoryCapacityBytes = 1024
+				cfg.UpdatePeriod = 0
+			},
+			expected: errInvalidDynamicSeriesLimitUpdatePeriod,
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			cfg := defaultDynamicSeriesLimitConfig()
+			testData.setup(&cfg)
+			assert.Equal(t, testData.expected, cfg.Validate())
+		})
+	}
+}
+
+func TestMemoryUtilizationCodec(t *testing.T) {
+	desc := &MemoryUtilizationDesc{Instances: map[string]InstanceMemoryUtilization{
+		"ingester-1": {Timestamp: 1000, Utilization: 0.75},
+	}}
+
+	c := GetMemoryUtilizationCodec()
+	data, err := c.Encode(desc)
+	require.NoError(t, err)
+
+	decoded, err := c.Decode(data)
+	require.NoError(t, err)
+	assert.Equal(t, desc, decoded)
+}
+
+func TestNextDynamicSeriesLimitFactor(t *testing.T) {
+	cfg := defaultDynamicSeriesLimitConfig()
+	cfg.MemoryCapacityBytes = 1024
+
+	tests := map[string]struct {
+		curr        float64
+		utilization float64
+		expected    float64
+	}{
+		"should not change the factor when the utilization is at the target and the factor is at the base": {
+			curr:        1,
+			utilization: 0.8,
+			expected:    1,
+		},
+		"should tighten the factor proportionally when the utilization is above the target": {
+			curr:        1,
+			utilization: 1,
+			expected:    0.8,
+		},
+		"should not compound the factor when the utilization stays above the target": {
+			curr:        0.8,
+			utilization: 1,
+			expected:    0.8,
+		},
+		"should not tighten the factor below the min factor": {
+			curr:        0.6,
+			utilization: 1.6,
+			expected:    0.5,
+		},
+		"should relax the factor when the utilization is below the target": {
+			curr:        0.6,
+			utilization: 0.5,
+			expected:    0.65,
+		},
+		"should relax the factor up to the one matching the target when the utilization is above the target": {
+			curr:        0.87,
+			utilization: 0.9,
+			expected:    0.8 / 0.9,
+		},
+		"should not relax the factor above the max factor": {
+			curr:        0.98,
+			utilization: 0.1,
+			expected:    1,
+		},
+		"should relax the factor when the utilization is unknown": {
+			curr:        0.5,
+			utilization: 0,
+			expected:    0.55,
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			assert.InDelta(t, testData.expected, nextDynamicSeriesLimitFactor(testData.curr, testData.utilization, cfg), 1e-9)
+		})
+	}
+}
+
+func TestDynamicSeriesLimit(t *testing.T) {
+	cfg := defaultDynamicSeriesLimitConfig()
+	cfg.MemoryCapacityBytes = 1000
+
+	kvStore, closer := consul.NewInMemoryClient(GetMemoryUtilizationCodec(), log.NewNopLogger(), nil)
+	t.Cleanup(func() { assert.NoError(t, closer.Close()) })
+
+	l1 := newDynamicSeriesLimit(cfg, "ingester-1", kvStore, log.NewNopLogger(), prometheus.NewPedanticRegistry())
+	l2 := newDynamicSeriesLimit(cfg, "ingester-2", kvStore, log.NewNopLogger(), prometheus.NewPedanticRegistry())
+	l1.heapInuse = func() uint64 { return 1000 }
+	l2.heapInuse = func() uint64 { return 800 }
+
+	assert.Equal(t, 1000, l1.apply(1000))
+
+	// The utilization of ingester-2 is not known yet, so ingester-1 only considers its own utilization.
+	now := time.Now()
+	l1.update(context.Background(), now)
+	assert.InDelta(t, 1, l1.clusterUtilization.Load(), 1e-9)
+	assert.InDelta(t, 0.8, l1.factor.Load(), 1e-9)
+	assert.Equal(t, 800, l1.apply(1000))
+
+	// ingester-2 reads the utilization published by ingester-1 from the KV store.
+	desc, err := kvStore.Get(context.Background(), memoryUtilizationKey)
+	require.NoError(t, err)
+	l2.remote = desc.(*MemoryUtilizationDesc)
+
+	l2.update(context.Background(), now)
+	assert.InDelta(t, 0.9, l2.clusterUtilization.Load(), 1e-9)
+	assert.InDelta(t, 0.8/0.9, l2.factor.Load(), 1e-9)
+
+	// Both ingesters published their utilization.
+	desc, err = kvStore.Get(context.Background(), memoryUtilizationKey)
+	require.NoError(t, err)
+	assert.Len(t, desc.(*MemoryUtilizationDesc).Instances, 2)
+
+	// Once the incident is over, the limits are relaxed up to the max factor.
+	l1.heapInuse = func() uint64 { return 100 }
+	l1.remote = instancestate.NewDesc[InstanceMemoryUtilization]()
+	for n := 0; n < 10; n++ {
+		l1.update(context.Background(), now)
+	}
+	assert.InDelta(t, 1, l1.factor.Load(), 1e-9)
+	assert.Equal(t, 1000, l1.apply(1000))
+
+	// Disabled limits are left unchanged.
+	l1.factor.Store(0.5)
+	assert.Equal(t, 0, l1.apply(0))
+}
+
+func TestLimiter_maxSeriesPerUser_WithDynamicSeriesLimit(t *testing.T) {
+	limits, err := validation.NewOverrides(validation.Limits{MaxGlobalSeriesPerUser: 1000}, nil)
+	require.NoError(t, err)
+
+	cfg := defaultDynamicSeriesLimitConfig()
+	cfg.MemoryCapacityBytes = 1000
+
+	limiter := NewLimiter(limits, singleIngesterLimiterStrategy{})
+	limiter.dynamicSeriesLimit = newDynamicSeriesLimit(cfg, "ingester-1", nil, log.NewNopLogger(), nil)
+	limiter.dynamicSeriesLimit.factor.Store(0.5)
+
+	assert.Equal(t, 500, limiter.maxSeriesPerUser("user-1", 0))
+	assert.Equal(t, 600, limiter.maxSeriesPerUser("user-1", 600))
+}
+
+func defaultDynamicSeriesLimitConfig() DynamicSeriesLimitConfig {
+	cfg := DynamicSeriesLimitConfig{}
+	cfg.RegisterFlagsWithPrefix("", flag.NewFlagSet("", flag.PanicOnError))
+	return cfg
+}
+
+// singleIngesterLimiterStrategy enforces the global limits as local limits.
+type singleIngesterLimiterStrategy struct{}
+
+func (singleIngesterLimiterStrategy) convertGlobalToLocalLimit(_ string, globalLimit int) int {
+	return globalLimit
+}
+
+func (singleIngesterLimiterStrategy) getShardSize(string) int {
+	return 0
+}
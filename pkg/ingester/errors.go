@@ -254,6 +254,39 @@ var _ ingesterError = perUserSeriesLimitReachedError{}
 // Ensure that perUserSeriesLimitReachedError is an softError.
 var _ softError = perUserSeriesLimitReachedError{}
 
+// perUserNewSeriesRateLimitReachedError is an ingesterError indicating that a per-user new series rate limit has been reached.
+type perUserNewSeriesRateLimitReachedError struct {
+	limit int
+}
+
+// newPerUserNewSeriesRateLimitReachedError creates a new perUserNewSeriesRateLimitReachedError indicating that a per-user new series rate limit has been reached.
+func newPerUserNewSeriesRateLimitReachedError(limit int) perUserNewSeriesRateLimitReachedError {
+	return perUserNewSeriesRateLimitReachedError{
+		limit: limit,
+	}
+}
+
+func (e perUserNewSeriesRateLimitReachedError) Error() string {
+	return globalerror.MaxNewSeriesPerMinute.MessageWithPerTenantLimitConfig(
+		fmt.Sprintf("per-user new series limit of %d per minute exceeded", e.limit),
+		validation.MaxNewSeriesPerMinuteFlag,
+	)
+}
+
+// errorCause is INGESTION_RATE_LIMITED, because the rejected series can be created once the rate allows it,
+// so the clients must retry instead of dropping the data.
+func (e perUserNewSeriesRateLimitReachedError) errorCause() mimirpb.ErrorCause {
+	return mimirpb.INGESTION_RATE_LIMITED
+}
+
+func (e perUserNewSeriesRateLimitReachedError) soft() {}
+
+// Ensure that perUserNewSeriesRateLimitReachedError is an ingesterError.
+var _ ingesterError = perUserNewSeriesRateLimitReachedError{}
+
+// Ensure that perUserNewSeriesRateLimitReachedError is an softError.
+var _ softError = perUserNewSeriesRateLimitReachedError{}
+
 // perUserMetadataLimitReachedError is an ingesterError indicating that a per-user metadata limit has been reached.
 type perUserMetadataLimitReachedError struct {
 	limit int
@@ -357,6 +390,35 @@ var _ ingesterError = perMetricMetadataLimitReachedError{}
 // Ensure that perMetricMetadataLimitReachedError is an softError.
 var _ softError = perMetricMetadataLimitReachedError{}
 
+// labelValuesLimitReachedError is an ingesterError indicating that the per-request limit on the number of
+// label values has been reached.
+type labelValuesLimitReachedError struct {
+	limit int
+}
+
+// newLabelValuesLimitReachedError creates a new labelValuesLimitReachedError indicating that the per-request limit
+// on the number of label values has been reached.
+func newLabelValuesLimitReachedError(limit int) labelValuesLimitReachedError {
+	return labelValuesLimitReachedError{
+		limit: limit,
+	}
+}
+
+func (e labelValuesLimitReachedError) Error() string {
+	return globalerror.MaxLabelValuesPerRequest.MessageWithStrategyAndPerTenantLimitConfig(
+		fmt.Sprintf("the label values request matched more than %d values", e.limit),
+		"Consider adding more label matchers to the request, or reducing its time range",
+		validation.MaxLabelValuesPerRequestFlag,
+	)
+}
+
+func (e labelValuesLimitReachedError) errorCause() mimirpb.ErrorCause {
+	return mimirpb.TENANT_LIMIT
+}
+
+// Ensure that labelValuesLimitReachedError is an ingesterError.
+var _ ingesterError = labelValuesLimitReachedError{}
+
 // nativeHistogramValidationError indicates that native histogram bucket counts did not add up to the overall count.
 type nativeHistogramValidationError struct {
 	id           globalerror.ID
@@ -507,6 +569,7 @@ type ingesterErrSamplers struct {
 	maxSeriesPerMetricLimitExceeded   *log.Sampler
 	maxMetadataPerMetricLimitExceeded *log.Sampler
 	maxSeriesPerUserLimitExceeded     *log.Sampler
+	maxNewSeriesPerMinuteExceeded     *log.Sampler
 	maxMetadataPerUserLimitExceeded   *log.Sampler
 	nativeHistogramValidationError    *log.Sampler
 }
@@ -523,6 +586,7 @@ func newIngesterErrSamplers(freq int64) ingesterErrSamplers {
 		log.NewSampler(freq),
 		log.NewSampler(freq),
 		log.NewSampler(freq),
+		log.NewSampler(freq),
 	}
 }
 
@@ -539,6 +603,8 @@ func mapPushErrorToErrorWithStatus(err error) error {
 			errCode = codes.InvalidArgument
 		case mimirpb.TENANT_LIMIT:
 			errCode = codes.FailedPrecondition
+		case mimirpb.INGESTION_RATE_LIMITED:
+			errCode = codes.ResourceExhausted
 		case mimirpb.SERVICE_UNAVAILABLE:
 			errCode = codes.Unavailable
 		case mimirpb.INSTANCE_LIMIT:
@@ -571,6 +637,8 @@ func mapReadErrorToErrorWithStatus(err error) error {
 			return newErrorWithStatus(err, codes.Unimplemented)
 		case mimirpb.CIRCUIT_BREAKER_OPEN:
 			return newErrorWithStatus(err, codes.Unavailable)
+		case mimirpb.TENANT_LIMIT:
+			errCode = codes.FailedPrecondition
 		}
 	}
 	return newErrorWithStatus(err, errCode)
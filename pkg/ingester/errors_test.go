@@ -88,6 +88,22 @@ func TestNewPerUserSeriesLimitError(t *testing.T) {
 	checkIngesterError(t, wrappedErr, mimirpb.TENANT_LIMIT, true)
 }
 
+func TestNewPerUserNewSeriesRateLimitError(t *testing.T) {
+	limit := 100
+	err := newPerUserNewSeriesRateLimitReachedError(limit)
+	expectedErrMsg := globalerror.MaxNewSeriesPerMinute.MessageWithPerTenantLimitConfig(
+		fmt.Sprintf("per-user new series limit of %d per minute exceeded", limit),
+		validation.MaxNewSeriesPerMinuteFlag,
+	)
+	require.Equal(t, expectedErrMsg, err.Error())
+	checkIngesterError(t, err, mimirpb.INGESTION_RATE_LIMITED, true)
+
+	wrappedErr := wrapOrAnnotateWithUser(err, userID)
+	require.ErrorIs(t, wrappedErr, err)
+	require.ErrorAs(t, wrappedErr, &perUserNewSeriesRateLimitReachedError{})
+	checkIngesterError(t, wrappedErr, mimirpb.INGESTION_RATE_LIMITED, true)
+}
+
 func TestNewPerUserMetadataLimitError(t *testing.T) {
 	limit := 100
 	err := newPerUserMetadataLimitReachedError(limit)
@@ -478,6 +494,12 @@ func TestMapPushErrorToErrorWithStatus(t *testing.T) {
 			expectedMessage: fmt.Sprintf("wrapped: %s", newPerUserSeriesLimitReachedError(10).Error()),
 			expectedDetails: &mimirpb.ErrorDetails{Cause: mimirpb.TENANT_LIMIT},
 		},
+		"a perUserNewSeriesRateLimitReachedError gets translated into an ErrorWithStatus ResourceExhausted error with details": {
+			err:             newPerUserNewSeriesRateLimitReachedError(10),
+			expectedCode:    codes.ResourceExhausted,
+			expectedMessage: newPerUserNewSeriesRateLimitReachedError(10).Error(),
+			expectedDetails: &mimirpb.ErrorDetails{Cause: mimirpb.INGESTION_RATE_LIMITED},
+		},
 		"a perUserMetadataLimitReachedError gets translated into an ErrorWithStatus FailedPrecondition error with details": {
 			err:             newPerUserMetadataLimitReachedError(10),
 			expectedCode:    codes.FailedPrecondition,
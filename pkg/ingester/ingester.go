@@ -63,6 +63,7 @@ import (
 	"github.com/grafana/mimir/pkg/storage/tsdb/block"
 	"github.com/grafana/mimir/pkg/usagestats"
 	"github.com/grafana/mimir/pkg/util"
+	"github.com/grafana/mimir/pkg/util/fs"
 	"github.com/grafana/mimir/pkg/util/globalerror"
 	"github.com/grafana/mimir/pkg/util/limiter"
 	util_log "github.com/grafana/mimir/pkg/util/log"
@@ -84,6 +85,9 @@ const (
 	// Period at which to attempt purging metadata from memory.
 	metadataPurgePeriod = 5 * time.Minute
 
+	// How frequently the ring is checked for changes to refresh the share of tokens owned by the ingester, used to compute the local limits.
+	ingesterTokensShareRefreshInterval = 10 * time.Second
+
 	// How frequently update the usage statistics.
 	usageStatsUpdateInterval = usagestats.DefaultReportSendInterval / 10
 
@@ -106,8 +110,10 @@ const (
 	reasonNewValueForTimestamp   = "new-value-for-timestamp"
 	reasonSampleOutOfBounds      = "sample-out-of-bounds"
 	reasonPerUserSeriesLimit     = "per_user_series_limit"
+	reasonPerUserNewSeriesRate   = "per_user_new_series_rate_limit"
 	reasonPerMetricSeriesLimit   = "per_metric_series_limit"
 	reasonInvalidNativeHistogram = "invalid-native-histogram"
+	reasonSampleIntervalTooShort = "sample-interval-too-short"
 
 	replicationFactorStatsName             = "ingester_replication_factor"
 	ringStoreStatsName                     = "ingester_ring_store"
@@ -209,11 +215,22 @@ type Config struct {
 	PushCircuitBreaker CircuitBreakerConfig `yaml:"push_circuit_breaker"`
 	ReadCircuitBreaker CircuitBreakerConfig `yaml:"read_circuit_breaker"`
 
+	DynamicSeriesLimit DynamicSeriesLimitConfig `yaml:"dynamic_series_limit"`
+
+	DiskSpaceWatchdog DiskSpaceWatchdogConfig `yaml:"disk_space_watchdog"`
+
 	PushGrpcMethodEnabled bool `yaml:"push_grpc_method_enabled" category:"experimental" doc:"hidden"`
+	PushDecodingWorkers   int  `yaml:"push_decoding_workers" category:"experimental"`
+
+	LabelValuesPostingsFastPathEnabled bool `yaml:"label_values_postings_fast_path_enabled" category:"experimental"`
 
 	// This config is dynamically injected because defined outside the ingester config.
 	IngestStorageConfig ingest.Config `yaml:"-"`
 
+	// The max size of the push requests once decompressed. This config is dynamically injected
+	// because it's the max size of the messages received by the gRPC server.
+	PushDecodingMaxMessageSize int `yaml:"-"`
+
 	// This config can be overridden in tests.
 	limitMetricsUpdatePeriod time.Duration `yaml:"-"`
 }
@@ -226,6 +243,8 @@ func (cfg *Config) RegisterFlags(f *flag.FlagSet, logger log.Logger) {
 	cfg.ActiveSeriesMetrics.RegisterFlags(f)
 	cfg.PushCircuitBreaker.RegisterFlagsWithPrefix("ingester.push-circuit-breaker.", f, circuitBreakerDefaultPushTimeout)
 	cfg.ReadCircuitBreaker.RegisterFlagsWithPrefix("ingester.read-circuit-breaker.", f, circuitBreakerDefaultReadTimeout)
+	cfg.DynamicSeriesLimit.RegisterFlagsWithPrefix("ingester.dynamic-series-limit.", f)
+	cfg.DiskSpaceWatchdog.RegisterFlagsWithPrefix("ingester.disk-space-watchdog.", f)
 
 	f.DurationVar(&cfg.MetadataRetainPeriod, "ingester.metadata-retain-period", 10*time.Minute, "Period at which metadata we have not seen will remain in memory before being deleted.")
 	f.DurationVar(&cfg.RateUpdatePeriod, "ingester.rate-update-period", 15*time.Second, "Period with which to update the per-tenant ingestion rates.")
@@ -240,6 +259,8 @@ func (cfg *Config) RegisterFlags(f *flag.FlagSet, logger log.Logger) {
 	f.BoolVar(&cfg.UpdateIngesterOwnedSeries, "ingester.track-ingester-owned-series", false, "This option enables tracking of ingester-owned series based on ring state, even if -ingester.use-ingester-owned-series-for-limits is disabled.")
 	f.DurationVar(&cfg.OwnedSeriesUpdateInterval, "ingester.owned-series-update-interval", 15*time.Second, "How often to check for ring changes and possibly recompute owned series as a result of detected change.")
 	f.BoolVar(&cfg.PushGrpcMethodEnabled, "ingester.push-grpc-method-enabled", true, "Enables Push gRPC method on ingester. Can be only disabled when using ingest-storage to make sure ingesters only receive data from Kafka.")
+	f.IntVar(&cfg.PushDecodingWorkers, "ingester.push-decoding-workers", 0, "Number of workers used to decompress and unmarshal the push requests sent by the distributors with -ingester.client.push-snappy-codec enabled, instead of doing it in the gRPC goroutine of each request. This bounds the CPU used to decode bursts of push requests. Use 0 to disable it.")
+	f.BoolVar(&cfg.LabelValuesPostingsFastPathEnabled, "ingester.label-values-postings-fast-path-enabled", false, "Compute the values of label values requests with matchers from the postings index only, without looking up the labels of the matching series. This bounds the cost of the requests selecting many series, such as dashboard variable queries, by the number of values of the label.")
 
 	// Hardcoded config (can only be overridden in tests).
 	cfg.limitMetricsUpdatePeriod = time.Second * 15
@@ -250,6 +271,18 @@ func (cfg *Config) Validate(log.Logger) error {
 		return fmt.Errorf("error sample rate cannot be a negative number")
 	}
 
+	if err := cfg.DynamicSeriesLimit.Validate(); err != nil {
+		return err
+	}
+
+	if err := cfg.DiskSpaceWatchdog.Validate(); err != nil {
+		return err
+	}
+
+	if cfg.PushDecodingWorkers < 0 {
+		return fmt.Errorf("the number of push decoding workers cannot be a negative number")
+	}
+
 	return cfg.IngesterRing.Validate()
 }
 
@@ -289,6 +322,7 @@ type Ingester struct {
 	limiter               *Limiter
 	subservicesWatcher    *services.FailureWatcher
 	ownedSeriesService    *ownedSeriesService
+	tokensShare           *ingesterTokensShare
 	compactionService     services.Service
 	metricsUpdaterService services.Service
 	metadataPurgerService services.Service
@@ -299,11 +333,15 @@ type Ingester struct {
 
 	bucket objstore.Bucket
 
+	// Ring of the ingesters, used to find the WAL shipped by the ingesters which are not in the ring anymore. May be nil.
+	ingestersRing ring.ReadRing
+
 	// Value used by shipper as external label.
 	shipperIngesterID string
 
 	// Metrics shared across all per-tenant shippers.
-	shipperMetrics *shipperMetrics
+	shipperMetrics    *shipperMetrics
+	walShipperMetrics *walShipperMetrics
 
 	subservicesForPartitionReplay          *services.Manager
 	subservicesAfterIngesterRingLifecycler *services.Manager
@@ -335,6 +373,8 @@ type Ingester struct {
 
 	utilizationBasedLimiter utilizationBasedLimiter
 
+	pushDecoder *pushDecoder
+
 	errorSamplers ingesterErrSamplers
 
 	// The following is used by ingest storage (when enabled).
@@ -343,6 +383,9 @@ type Ingester struct {
 	ingestPartitionLifecycler *ring.PartitionInstanceLifecycler
 
 	circuitBreaker ingesterCircuitBreaker
+
+	// Monitors the free disk space of the TSDB directory. Nil if disabled.
+	diskSpaceWatchdog *diskSpaceWatchdog
 }
 
 func newIngester(cfg Config, limits *validation.Overrides, registerer prometheus.Registerer, logger log.Logger) (*Ingester, error) {
@@ -369,6 +412,7 @@ func newIngester(cfg Config, limits *validation.Overrides, registerer prometheus
 		bucket:              bucketClient,
 		tsdbMetrics:         newTSDBMetrics(registerer, logger),
 		shipperMetrics:      newShipperMetrics(registerer),
+		walShipperMetrics:   newWALShipperMetrics(registerer),
 		forceCompactTrigger: make(chan requestWithUsersAndCallback),
 		shipTrigger:         make(chan requestWithUsersAndCallback),
 		seriesHashCache:     hashcache.NewSeriesHashCache(cfg.BlocksStorageConfig.TSDB.SeriesHashCacheMaxBytes),
@@ -383,6 +427,7 @@ func New(cfg Config, limits *validation.Overrides, ingestersRing ring.ReadRing,
 	if err != nil {
 		return nil, err
 	}
+	i.ingestersRing = ingestersRing
 	i.ingestionRate = util_math.NewEWMARate(0.2, instanceIngestionRateTickInterval)
 	i.metrics = newIngesterMetrics(registerer, cfg.ActiveSeriesMetrics.Enabled, i.getInstanceLimits, i.ingestionRate, &i.inflightPushRequests, &i.inflightPushRequestsBytes)
 	i.activeGroups = activeGroupsCleanupService
@@ -421,6 +466,8 @@ func New(cfg Config, limits *validation.Overrides, ingestersRing ring.ReadRing,
 			prometheus.WrapRegistererWithPrefix("cortex_ingester_", registerer))
 	}
 
+	i.pushDecoder = newPushDecoder(cfg.PushDecodingWorkers, cfg.PushDecodingMaxMessageSize, registerer)
+
 	i.shipperIngesterID = i.lifecycler.ID
 
 	// Apply positive jitter only to ensure that the minimum timeout is adhered to.
@@ -466,12 +513,27 @@ func New(cfg Config, limits *validation.Overrides, ingestersRing ring.ReadRing,
 		limiterStrategy = newPartitionRingLimiterStrategy(partitionRingWatcher, i.limits.IngestionPartitionsTenantShardSize)
 		ownedSeriesStrategy = newOwnedSeriesPartitionRingStrategy(i.ingestPartitionID, partitionRingWatcher, i.limits.IngestionPartitionsTenantShardSize)
 	} else {
-		limiterStrategy = newIngesterRingLimiterStrategy(ingestersRing, cfg.IngesterRing.ReplicationFactor, cfg.IngesterRing.ZoneAwarenessEnabled, cfg.IngesterRing.InstanceZone, i.limits.IngestionTenantShardSize)
+		ringLimiterStrategy := newIngesterRingLimiterStrategy(ingestersRing, cfg.IngesterRing.ReplicationFactor, cfg.IngesterRing.ZoneAwarenessEnabled, cfg.IngesterRing.InstanceZone, i.limits.IngestionTenantShardSize)
+		if ingestersRing != nil {
+			// Ingesters may have different weights, so the local limits follow the share of tokens owned by this ingester.
+			i.tokensShare = newIngesterTokensShare(ingestersRing, cfg.IngesterRing.InstanceID, cfg.IngesterRing.ZoneAwarenessEnabled, cfg.IngesterRing.InstanceZone, ingesterTokensShareRefreshInterval)
+			ringLimiterStrategy.tokensShare = i.tokensShare.get
+		}
+		limiterStrategy = ringLimiterStrategy
 		ownedSeriesStrategy = newOwnedSeriesIngesterRingStrategy(i.lifecycler.ID, ingestersRing, i.limits.IngestionTenantShardSize)
 	}
 
 	i.limiter = NewLimiter(limits, limiterStrategy)
 
+	if cfg.DynamicSeriesLimit.enabled() {
+		memoryUtilizationKV, err := kv.NewClient(cfg.IngesterRing.KVStore, GetMemoryUtilizationCodec(), kv.RegistererWithKVName(registerer, "ingester-memory-utilization"), logger)
+		if err != nil {
+			return nil, errors.Wrap(err, "creating KV store for ingester memory utilization")
+		}
+
+		i.limiter.dynamicSeriesLimit = newDynamicSeriesLimit(cfg.DynamicSeriesLimit, i.lifecycler.ID, memoryUtilizationKV, log.With(logger, "component", "dynamic series limit"), registerer)
+	}
+
 	if cfg.UseIngesterOwnedSeriesForLimits || cfg.UpdateIngesterOwnedSeries {
 		i.ownedSeriesService = newOwnedSeriesService(i.cfg.OwnedSeriesUpdateInterval, ownedSeriesStrategy, log.With(i.logger, "component", "owned series"), registerer, i.limiter.maxSeriesPerUser, i.getTSDBUsers, i.getTSDB)
 
@@ -479,6 +541,12 @@ func New(cfg Config, limits *validation.Overrides, ingestersRing ring.ReadRing,
 		i.subservicesWatcher.WatchService(i.ownedSeriesService)
 	}
 
+	if cfg.DiskSpaceWatchdog.enabled() {
+		i.diskSpaceWatchdog = newDiskSpaceWatchdog(cfg.DiskSpaceWatchdog, cfg.BlocksStorageConfig.TSDB.Dir, fs.DiskSpace, func(ctx context.Context) {
+			i.compactAndShipBlocks(ctx, nil)
+		}, log.With(i.logger, "component", "disk space watchdog"), registerer)
+	}
+
 	// Init compaction service, responsible to periodically run TSDB head compactions.
 	i.compactionService = services.NewBasicService(nil, i.compactionServiceRunning, nil)
 	i.subservicesWatcher.WatchService(i.compactionService)
@@ -616,10 +684,26 @@ func (i *Ingester) starting(ctx context.Context) (err error) {
 		servs = append(servs, i.utilizationBasedLimiter)
 	}
 
+	if i.pushDecoder.pool != nil {
+		servs = append(servs, i.pushDecoder.pool)
+	}
+
 	if i.ingestPartitionLifecycler != nil {
 		servs = append(servs, i.ingestPartitionLifecycler)
 	}
 
+	if i.limiter.dynamicSeriesLimit != nil {
+		servs = append(servs, i.limiter.dynamicSeriesLimit)
+	}
+
+	if i.tokensShare != nil {
+		servs = append(servs, i.tokensShare)
+	}
+
+	if i.diskSpaceWatchdog != nil {
+		servs = append(servs, i.diskSpaceWatchdog)
+	}
+
 	// Since subservices are conditional, We add an idle service if there are no subservices to
 	// guarantee there's at least 1 service to run otherwise the service manager fails to start.
 	if len(servs) == 0 {
@@ -957,8 +1041,10 @@ type pushStats struct {
 	sampleTooFarInFutureCount   int
 	newValueForTimestampCount   int
 	perUserSeriesLimitCount     int
+	perUserNewSeriesRateCount   int
 	perMetricSeriesLimitCount   int
 	invalidNativeHistogramCount int
+	sampleIntervalTooShortCount int
 }
 
 type ctxKey int
@@ -1011,6 +1097,10 @@ func (i *Ingester) startPushRequest(ctx context.Context, reqSize int64) (context
 		return nil, false, err
 	}
 
+	if i.diskSpaceWatchdog != nil && i.diskSpaceWatchdog.isReadOnly() {
+		return nil, false, errDiskSpaceReadOnly
+	}
+
 	if st := getPushRequestState(ctx); st != nil {
 		// If state is already in context, this means we already passed through StartPushRequest for this request.
 		return ctx, false, nil
@@ -1186,7 +1276,7 @@ func (i *Ingester) PushWithCleanup(ctx context.Context, req *mimirpb.WriteReques
 
 	minAppendTime, minAppendTimeAvailable := db.Head().AppendableMinValidTime()
 
-	if pushSamplesToAppenderErr := i.pushSamplesToAppender(userID, req.Timeseries, app, startAppend, &stats, updateFirstPartial, activeSeries, i.limits.OutOfOrderTimeWindow(userID), minAppendTimeAvailable, minAppendTime); pushSamplesToAppenderErr != nil {
+	if pushSamplesToAppenderErr := i.pushSamplesToAppender(userID, req.Timeseries, app, startAppend, &stats, updateFirstPartial, activeSeries, &db.lastSampleTimes, i.limits.OutOfOrderTimeWindow(userID), minAppendTimeAvailable, minAppendTime); pushSamplesToAppenderErr != nil {
 		if err := app.Rollback(); err != nil {
 			level.Warn(i.logger).Log("msg", "failed to rollback appender on error", "user", userID, "err", err)
 		}
@@ -1259,12 +1349,18 @@ func (i *Ingester) updateMetricsFromPushStats(userID string, group string, stats
 	if stats.perUserSeriesLimitCount > 0 {
 		discarded.perUserSeriesLimit.WithLabelValues(userID, group).Add(float64(stats.perUserSeriesLimitCount))
 	}
+	if stats.perUserNewSeriesRateCount > 0 {
+		discarded.perUserNewSeriesRate.WithLabelValues(userID, group).Add(float64(stats.perUserNewSeriesRateCount))
+	}
 	if stats.perMetricSeriesLimitCount > 0 {
 		discarded.perMetricSeriesLimit.WithLabelValues(userID, group).Add(float64(stats.perMetricSeriesLimitCount))
 	}
 	if stats.invalidNativeHistogramCount > 0 {
 		discarded.invalidNativeHistogram.WithLabelValues(userID, group).Add(float64(stats.invalidNativeHistogramCount))
 	}
+	if stats.sampleIntervalTooShortCount > 0 {
+		discarded.sampleIntervalTooShort.WithLabelValues(userID, group).Add(float64(stats.sampleIntervalTooShortCount))
+	}
 	if stats.succeededSamplesCount > 0 {
 		i.ingestionRate.Add(int64(stats.succeededSamplesCount))
 
@@ -1281,7 +1377,7 @@ func (i *Ingester) updateMetricsFromPushStats(userID string, group string, stats
 // must be of type softError.
 func (i *Ingester) pushSamplesToAppender(userID string, timeseries []mimirpb.PreallocTimeseries, app extendedAppender, startAppend time.Time,
 	stats *pushStats, updateFirstPartial func(sampler *util_log.Sampler, errFn softErrorFunction), activeSeries *activeseries.ActiveSeries,
-	outOfOrderWindow time.Duration, minAppendTimeAvailable bool, minAppendTime int64) error {
+	lastSampleTimes *seriesLastSampleTimes, outOfOrderWindow time.Duration, minAppendTimeAvailable bool, minAppendTime int64) error {
 
 	// Return true if handled as soft error, and we can ingest more series.
 	handleAppendError := func(err error, timestamp int64, labels []mimirpb.LabelAdapter) bool {
@@ -1334,6 +1430,13 @@ func (i *Ingester) pushSamplesToAppender(userID string, timeseries []mimirpb.Pre
 			})
 			return true
 
+		case errors.Is(err, globalerror.MaxNewSeriesPerMinute):
+			stats.perUserNewSeriesRateCount++
+			updateFirstPartial(i.errorSamplers.maxNewSeriesPerMinuteExceeded, func() softError {
+				return newPerUserNewSeriesRateLimitReachedError(i.limiter.limits.MaxGlobalNewSeriesPerMinute(userID))
+			})
+			return true
+
 		case errors.Is(err, globalerror.MaxSeriesPerMetric):
 			stats.perMetricSeriesLimitCount++
 			updateFirstPartial(i.errorSamplers.maxSeriesPerMetricLimitExceeded, func() softError {
@@ -1381,6 +1484,7 @@ func (i *Ingester) pushSamplesToAppender(userID string, timeseries []mimirpb.Pre
 		nativeHistogramsIngestionEnabled = i.limits.NativeHistogramsIngestionEnabled(userID)
 		maxTimestampMs                   = startAppend.Add(i.limits.CreationGracePeriod(userID)).UnixMilli()
 		minTimestampMs                   = int64(math.MinInt64)
+		minSampleIntervalMs              = i.limits.MinSampleInterval(userID).Milliseconds()
 	)
 	if i.limits.PastGracePeriod(userID) > 0 {
 		minTimestampMs = startAppend.Add(-i.limits.PastGracePeriod(userID)).Add(-i.limits.OutOfOrderTimeWindow(userID)).UnixMilli()
@@ -1445,6 +1549,22 @@ func (i *Ingester) pushSamplesToAppender(userID string, timeseries []mimirpb.Pre
 		// To find out if any sample was added to this series, we keep old value.
 		oldSucceededSamplesCount := stats.succeededSamplesCount
 
+		// The samples closer than the minimum interval to the last sample appended to the series, including
+		// the samples appended by previous requests, are dropped. Out-of-order samples are left untouched.
+		lastSampleTs, lastSampleTsFound := int64(0), false
+		if minSampleIntervalMs > 0 && ref != 0 {
+			lastSampleTs, lastSampleTsFound = lastSampleTimes.get(ref)
+		}
+		withinMinSampleInterval := func(ts int64) bool {
+			return minSampleIntervalMs > 0 && lastSampleTsFound && ts >= lastSampleTs && ts-lastSampleTs < minSampleIntervalMs
+		}
+		sampleAppended := func(ts int64) {
+			stats.succeededSamplesCount++
+			if !lastSampleTsFound || ts > lastSampleTs {
+				lastSampleTs, lastSampleTsFound = ts, true
+			}
+		}
+
 		for _, s := range ts.Samples {
 			var err error
 
@@ -1457,10 +1577,15 @@ func (i *Ingester) pushSamplesToAppender(userID string, timeseries []mimirpb.Pre
 				continue
 			}
 
+			if withinMinSampleInterval(s.TimestampMs) {
+				stats.sampleIntervalTooShortCount++
+				continue
+			}
+
 			// If the cached reference exists, we try to use it.
 			if ref != 0 {
 				if _, err = app.Append(ref, copiedLabels, s.TimestampMs, s.Value); err == nil {
-					stats.succeededSamplesCount++
+					sampleAppended(s.TimestampMs)
 					continue
 				}
 			} else {
@@ -1469,7 +1594,7 @@ func (i *Ingester) pushSamplesToAppender(userID string, timeseries []mimirpb.Pre
 
 				// Retain the reference in case there are multiple samples for the series.
 				if ref, err = app.Append(0, copiedLabels, s.TimestampMs, s.Value); err == nil {
-					stats.succeededSamplesCount++
+					sampleAppended(s.TimestampMs)
 					continue
 				}
 			}
@@ -1500,6 +1625,11 @@ func (i *Ingester) pushSamplesToAppender(userID string, timeseries []mimirpb.Pre
 					continue
 				}
 
+				if withinMinSampleInterval(h.Timestamp) {
+					stats.sampleIntervalTooShortCount++
+					continue
+				}
+
 				if h.IsFloatHistogram() {
 					fh = mimirpb.FromFloatHistogramProtoToFloatHistogram(&h)
 				} else {
@@ -1509,7 +1639,7 @@ func (i *Ingester) pushSamplesToAppender(userID string, timeseries []mimirpb.Pre
 				// If the cached reference exists, we try to use it.
 				if ref != 0 {
 					if _, err = app.AppendHistogram(ref, copiedLabels, h.Timestamp, ih, fh); err == nil {
-						stats.succeededSamplesCount++
+						sampleAppended(h.Timestamp)
 						continue
 					}
 				} else {
@@ -1518,7 +1648,7 @@ func (i *Ingester) pushSamplesToAppender(userID string, timeseries []mimirpb.Pre
 
 					// Retain the reference in case there are multiple samples for the series.
 					if ref, err = app.AppendHistogram(0, copiedLabels, h.Timestamp, ih, fh); err == nil {
-						stats.succeededSamplesCount++
+						sampleAppended(h.Timestamp)
 						continue
 					}
 				}
@@ -1549,6 +1679,10 @@ func (i *Ingester) pushSamplesToAppender(userID string, timeseries []mimirpb.Pre
 			activeSeries.UpdateSeries(nonCopiedLabels, ref, startAppend, numNativeHistogramBuckets)
 		}
 
+		if minSampleIntervalMs > 0 && ref != 0 && stats.succeededSamplesCount > oldSucceededSamplesCount {
+			lastSampleTimes.set(ref, lastSampleTs)
+		}
+
 		if len(ts.Exemplars) > 0 && i.limits.MaxGlobalExemplarsPerUser(userID) > 0 {
 			// app.AppendExemplar currently doesn't create the series, it must
 			// already exist.  If it does not then drop.
@@ -1707,6 +1841,17 @@ func (i *Ingester) LabelValues(ctx context.Context, req *client.LabelValuesReque
 		return &client.LabelValuesResponse{}, nil
 	}
 
+	limit := i.limits.MaxLabelValuesPerRequest(userID)
+	if i.cfg.LabelValuesPostingsFastPathEnabled && len(matchers) > 0 {
+		vals, err := labelValuesFromPostings(ctx, db, labelName, startTimestampMs, endTimestampMs, limit, matchers)
+		if err != nil {
+			return nil, err
+		}
+		return &client.LabelValuesResponse{
+			LabelValues: vals,
+		}, nil
+	}
+
 	q, err := db.Querier(startTimestampMs, endTimestampMs)
 	if err != nil {
 		return nil, err
@@ -1718,6 +1863,9 @@ func (i *Ingester) LabelValues(ctx context.Context, req *client.LabelValuesReque
 	if err != nil {
 		return nil, err
 	}
+	if limit > 0 && len(vals) > limit {
+		return nil, newLabelValuesLimitReachedError(limit)
+	}
 
 	// The label value strings are sometimes pointing to memory mapped file
 	// regions that may become unmapped anytime after Querier.Close is called.
@@ -2653,6 +2801,18 @@ func (i *Ingester) createTSDB(userID string, walReplayConcurrency int) (*userTSD
 	userDB.triggerRecomputeOwnedSeries(recomputeOwnedSeriesReasonNewUser)
 
 	oooTW := i.limits.OutOfOrderTimeWindow(userID)
+
+	walSegmentSize := i.cfg.BlocksStorageConfig.TSDB.WALSegmentSizeBytes
+	memorySnapshotOnShutdown := i.cfg.BlocksStorageConfig.TSDB.MemorySnapshotOnShutdown
+	if i.limits.WALDisabled(userID) {
+		// A negative segment size disables the WAL. The memory snapshot is disabled too,
+		// because it's replayed on top of the WAL. There's no per-tenant fsync policy, because
+		// the WAL doesn't fsync each write, only each completed segment.
+		walSegmentSize = -1
+		memorySnapshotOnShutdown = false
+		level.Warn(userLogger).Log("msg", "the TSDB write-ahead log is disabled for this tenant, samples not yet compacted into a block will be lost on restart")
+	}
+
 	// Create a new user database
 	db, err := tsdb.Open(udir, userLogger, tsdbPromReg, &tsdb.Options{
 		RetentionDuration:                     i.cfg.BlocksStorageConfig.TSDB.Retention.Milliseconds(),
@@ -2663,14 +2823,14 @@ func (i *Ingester) createTSDB(userID string, walReplayConcurrency int) (*userTSD
 		HeadChunksWriteBufferSize:             i.cfg.BlocksStorageConfig.TSDB.HeadChunksWriteBufferSize,
 		HeadChunksEndTimeVariance:             i.cfg.BlocksStorageConfig.TSDB.HeadChunksEndTimeVariance,
 		WALCompression:                        i.cfg.BlocksStorageConfig.TSDB.WALCompressionType(),
-		WALSegmentSize:                        i.cfg.BlocksStorageConfig.TSDB.WALSegmentSizeBytes,
+		WALSegmentSize:                        walSegmentSize,
 		WALReplayConcurrency:                  walReplayConcurrency,
 		SeriesLifecycleCallback:               userDB,
 		BlocksToDelete:                        userDB.blocksToDelete,
 		EnableExemplarStorage:                 true, // enable for everyone so we can raise the limit later
 		MaxExemplars:                          int64(i.limiter.maxExemplarsPerUser(userID)),
 		SeriesHashCache:                       i.seriesHashCache,
-		EnableMemorySnapshotOnShutdown:        i.cfg.BlocksStorageConfig.TSDB.MemorySnapshotOnShutdown,
+		EnableMemorySnapshotOnShutdown:        memorySnapshotOnShutdown,
 		IsolationDisabled:                     true,
 		HeadChunksWriteQueueSize:              i.cfg.BlocksStorageConfig.TSDB.HeadChunksWriteQueueSize,
 		EnableOverlappingCompaction:           false,                // always false since Mimir only uploads lvl 1 compacted blocks
@@ -2742,6 +2902,16 @@ func (i *Ingester) createTSDB(userID string, walReplayConcurrency int) (*userTSD
 		if err := userDB.updateCachedShippedBlocks(); err != nil {
 			level.Error(userLogger).Log("msg", "failed to update cached shipped blocks after shipper initialisation", "err", err)
 		}
+
+		if i.cfg.BlocksStorageConfig.TSDB.WALShippingEnabled {
+			userDB.walShipper = newWALShipper(
+				userLogger,
+				i.cfg.IngesterRing.InstanceID,
+				i.walShipperMetrics,
+				udir,
+				bucket.NewUserBucketClient(userID, i.bucket, i.limits),
+			)
+		}
 	}
 
 	i.tsdbMetrics.setRegistryForUser(userID, tsdbPromReg)
@@ -3056,6 +3226,23 @@ func (i *Ingester) shipBlocks(ctx context.Context, allowed *util.AllowedTenants)
 			}
 		}
 
+		if userDB.walShipper != nil {
+			uploaded, err := userDB.walShipper.Sync(ctx)
+			if err != nil {
+				level.Warn(i.logger).Log("msg", "failed to ship TSDB WAL to the storage", "user", userID, "uploaded", uploaded, "err", err)
+			} else {
+				level.Debug(i.logger).Log("msg", "successfully shipped TSDB WAL to the storage", "user", userID, "uploaded", uploaded)
+			}
+
+			if i.ingestersRing != nil {
+				if deleted, err := userDB.walShipper.SweepOrphans(ctx, i.ingestersRing.HasInstance); err != nil {
+					level.Warn(i.logger).Log("msg", "failed to delete the TSDB WAL shipped by the ingesters not in the ring anymore", "user", userID, "err", err)
+				} else if deleted > 0 {
+					level.Info(i.logger).Log("msg", "deleted the TSDB WAL shipped by the ingesters not in the ring anymore", "user", userID, "ingesters", deleted)
+				}
+			}
+		}
+
 		return nil
 	})
 }
@@ -3382,6 +3569,13 @@ func (i *Ingester) closeAndDeleteUserTSDBIfIdle(userID string) tsdbCloseCheckRes
 		return tsdbDataRemovalFailed
 	}
 
+	// The data in the shipped WAL has been shipped in blocks too, so it's not needed anymore.
+	if userDB.walShipper != nil {
+		if err := userDB.walShipper.DeleteAll(context.Background()); err != nil {
+			level.Warn(i.logger).Log("msg", "failed to delete shipped TSDB WAL from the storage", "user", userID, "err", err)
+		}
+	}
+
 	if tenantDeleted {
 		level.Info(i.logger).Log("msg", "deleted local TSDB, user marked for deletion", "user", userID, "dir", dir)
 		return tsdbTenantMarkedForDeletion
@@ -3454,49 +3648,10 @@ func (i *Ingester) FlushHandler(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		compactionCallbackCh := make(chan struct{})
-
-		level.Info(i.logger).Log("msg", "flushing TSDB blocks: triggering compaction")
-		select {
-		case i.forceCompactTrigger <- requestWithUsersAndCallback{users: allowedUsers, callback: compactionCallbackCh}:
-			// Compacting now.
-		case <-ingCtx.Done():
-			level.Warn(i.logger).Log("msg", "failed to compact TSDB blocks, ingester not running anymore")
-			return
-		}
-
-		// Wait until notified about compaction being finished.
-		select {
-		case <-compactionCallbackCh:
-			level.Info(i.logger).Log("msg", "finished compacting TSDB blocks")
-		case <-ingCtx.Done():
-			level.Warn(i.logger).Log("msg", "failed to compact TSDB blocks, ingester not running anymore")
+		if !i.compactAndShipBlocks(ingCtx, allowedUsers) {
 			return
 		}
 
-		if i.cfg.BlocksStorageConfig.TSDB.IsBlocksShippingEnabled() {
-			shippingCallbackCh := make(chan struct{}) // must be new channel, as compactionCallbackCh is closed now.
-
-			level.Info(i.logger).Log("msg", "flushing TSDB blocks: triggering shipping")
-
-			select {
-			case i.shipTrigger <- requestWithUsersAndCallback{users: allowedUsers, callback: shippingCallbackCh}:
-				// shipping now
-			case <-ingCtx.Done():
-				level.Warn(i.logger).Log("msg", "failed to ship TSDB blocks, ingester not running anymore")
-				return
-			}
-
-			// Wait until shipping finished.
-			select {
-			case <-shippingCallbackCh:
-				level.Info(i.logger).Log("msg", "shipping of TSDB blocks finished")
-			case <-ingCtx.Done():
-				level.Warn(i.logger).Log("msg", "failed to ship TSDB blocks, ingester not running anymore")
-				return
-			}
-		}
-
 		level.Info(i.logger).Log("msg", "flushing TSDB blocks: finished")
 	}
 
@@ -3510,6 +3665,56 @@ func (i *Ingester) FlushHandler(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// compactAndShipBlocks forces the compaction of the TSDB Head of the allowed tenants, then ships the resulting
+// blocks if shipping is enabled. It waits until both operations are done, and returns false if the context
+// has been canceled in the meanwhile.
+func (i *Ingester) compactAndShipBlocks(ctx context.Context, allowed *util.AllowedTenants) bool {
+	compactionCallbackCh := make(chan struct{})
+
+	level.Info(i.logger).Log("msg", "flushing TSDB blocks: triggering compaction")
+	select {
+	case i.forceCompactTrigger <- requestWithUsersAndCallback{users: allowed, callback: compactionCallbackCh}:
+		// Compacting now.
+	case <-ctx.Done():
+		level.Warn(i.logger).Log("msg", "failed to compact TSDB blocks, ingester not running anymore")
+		return false
+	}
+
+	// Wait until notified about compaction being finished.
+	select {
+	case <-compactionCallbackCh:
+		level.Info(i.logger).Log("msg", "finished compacting TSDB blocks")
+	case <-ctx.Done():
+		level.Warn(i.logger).Log("msg", "failed to compact TSDB blocks, ingester not running anymore")
+		return false
+	}
+
+	if i.cfg.BlocksStorageConfig.TSDB.IsBlocksShippingEnabled() {
+		shippingCallbackCh := make(chan struct{}) // must be new channel, as compactionCallbackCh is closed now.
+
+		level.Info(i.logger).Log("msg", "flushing TSDB blocks: triggering shipping")
+
+		select {
+		case i.shipTrigger <- requestWithUsersAndCallback{users: allowed, callback: shippingCallbackCh}:
+			// shipping now
+		case <-ctx.Done():
+			level.Warn(i.logger).Log("msg", "failed to ship TSDB blocks, ingester not running anymore")
+			return false
+		}
+
+		// Wait until shipping finished.
+		select {
+		case <-shippingCallbackCh:
+			level.Info(i.logger).Log("msg", "shipping of TSDB blocks finished")
+		case <-ctx.Done():
+			level.Warn(i.logger).Log("msg", "failed to ship TSDB blocks, ingester not running anymore")
+			return false
+		}
+	}
+
+	return true
+}
+
 func (i *Ingester) getInstanceLimits() *InstanceLimits {
 	// Don't apply any limits while starting. We especially don't want to apply series in memory limit while replaying WAL.
 	if i.State() == services.Starting {
@@ -3861,6 +4066,11 @@ func (i *Ingester) Push(ctx context.Context, req *mimirpb.WriteRequest) (*mimirp
 	return &mimirpb.WriteResponse{}, err
 }
 
+// PushDecoder returns the decoder of the push requests sent with the client.PushCodecName codec.
+func (i *Ingester) PushDecoder() client.PushDecoder {
+	return i.pushDecoder.decode
+}
+
 func (i *Ingester) mapReadErrorToErrorWithStatus(err error) error {
 	if err == nil {
 		return nil
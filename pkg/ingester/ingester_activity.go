@@ -35,6 +35,10 @@ func (i *ActivityTrackerWrapper) Push(ctx context.Context, request *mimirpb.Writ
 	return i.ing.Push(ctx, request)
 }
 
+func (i *ActivityTrackerWrapper) PushDecoder() client.PushDecoder {
+	return i.ing.PushDecoder()
+}
+
 func (i *ActivityTrackerWrapper) QueryStream(request *client.QueryRequest, server client.Ingester_QueryStreamServer) error {
 	ix := i.tracker.Insert(func() string {
 		return requestActivity(server.Context(), "Ingester/QueryStream", request)
@@ -215,6 +219,15 @@ func (i *ActivityTrackerWrapper) TenantTSDBHandler(w http.ResponseWriter, r *htt
 	i.ing.TenantTSDBHandler(w, r)
 }
 
+func (i *ActivityTrackerWrapper) TenantTSDBDebugBundleHandler(w http.ResponseWriter, r *http.Request) {
+	ix := i.tracker.Insert(func() string {
+		return requestActivity(r.Context(), "Ingester/TenantTSDBDebugBundleHandler", nil)
+	})
+	defer i.tracker.Delete(ix)
+
+	i.ing.TenantTSDBDebugBundleHandler(w, r)
+}
+
 func requestActivity(ctx context.Context, name string, req interface{}) string {
 	userID, _ := tenant.TenantID(ctx)
 	traceID, _ := tracing.ExtractSampledTraceID(ctx)
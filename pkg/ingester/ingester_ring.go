@@ -15,12 +15,15 @@ import (
 	"github.com/grafana/dskit/kv"
 	"github.com/grafana/dskit/netutil"
 	"github.com/grafana/dskit/ring"
+
+	"github.com/grafana/mimir/pkg/util"
 )
 
 const (
 	flagTokensFilePath                  = "tokens-file-path"
 	flagTokenGenerationStrategy         = "token-generation-strategy"
 	flagSpreadMinimizingJoinRingInOrder = "spread-minimizing-join-ring-in-order"
+	flagInstanceWeight                  = "instance-weight"
 	// allowed values for token-generation-strategy
 	tokenGenerationRandom           = "random"
 	tokenGenerationSpreadMinimizing = "spread-minimizing"
@@ -43,8 +46,9 @@ type RingConfig struct {
 	ExcludedZones        flagext.StringSliceCSV `yaml:"excluded_zones" category:"advanced"`
 
 	// Tokens
-	TokensFilePath string `yaml:"tokens_file_path"`
-	NumTokens      int    `yaml:"num_tokens" category:"advanced"`
+	TokensFilePath string  `yaml:"tokens_file_path"`
+	NumTokens      int     `yaml:"num_tokens" category:"advanced"`
+	InstanceWeight float64 `yaml:"instance_weight" category:"experimental"`
 
 	// Instance details
 	InstanceID             string   `yaml:"instance_id" category:"advanced" doc:"default=<hostname>"`
@@ -73,6 +77,10 @@ type RingConfig struct {
 }
 
 func (cfg *RingConfig) Validate() error {
+	if cfg.InstanceWeight <= 0 {
+		return fmt.Errorf("%q must be greater than 0", flagInstanceWeight)
+	}
+
 	if cfg.TokenGenerationStrategy != tokenGenerationRandom && cfg.TokenGenerationStrategy != tokenGenerationSpreadMinimizing {
 		return fmt.Errorf("unsupported token generation strategy (%q) has been chosen for %s", cfg.TokenGenerationStrategy, flagTokenGenerationStrategy)
 	}
@@ -81,6 +89,9 @@ func (cfg *RingConfig) Validate() error {
 		if cfg.TokensFilePath != "" {
 			return fmt.Errorf("%w: strategy requires %q to be empty", ErrSpreadMinimizingValidation, flagTokensFilePath)
 		}
+		if cfg.InstanceWeight != 1 {
+			return fmt.Errorf("%w: strategy requires %q to be 1", ErrSpreadMinimizingValidation, flagInstanceWeight)
+		}
 		_, err := ring.NewSpreadMinimizingTokenGenerator(cfg.InstanceID, cfg.InstanceZone, cfg.SpreadMinimizingZones, cfg.SpreadMinimizingJoinRingInOrder)
 		if err != nil {
 			return fmt.Errorf("%w: %w", ErrSpreadMinimizingValidation, err)
@@ -118,6 +129,7 @@ func (cfg *RingConfig) RegisterFlags(f *flag.FlagSet, logger log.Logger) {
 
 	f.StringVar(&cfg.TokensFilePath, prefix+flagTokensFilePath, "", fmt.Sprintf("File path where tokens are stored. If empty, tokens are not stored at shutdown and restored at startup. Must be empty if -%s is set to %q.", prefix+flagTokenGenerationStrategy, tokenGenerationSpreadMinimizing))
 	f.IntVar(&cfg.NumTokens, prefix+"num-tokens", 128, "Number of tokens for each ingester.")
+	f.Float64Var(&cfg.InstanceWeight, prefix+flagInstanceWeight, 1, fmt.Sprintf("Weight of this ingester in the ring. The number of tokens registered by the ingester is the number of tokens multiplied by the weight, so that ingesters running on bigger machines can own a proportionally larger share of the series. The per-tenant limits local to the ingester follow its share of tokens. Lowering the weight doesn't remove the tokens already owned by the ingester, for example when they're loaded from the tokens file. Must be 1 if -%s is set to %q.", prefix+flagTokenGenerationStrategy, tokenGenerationSpreadMinimizing))
 
 	// Instance flags
 	f.StringVar(&cfg.InstanceID, prefix+"instance-id", hostname, "Instance ID to register in the ring.")
@@ -167,7 +179,7 @@ func (cfg *RingConfig) ToLifecyclerConfig() ring.LifecyclerConfig {
 	flagext.DefaultValues(&lc)
 
 	lc.RingConfig = cfg.ToRingConfig()
-	lc.NumTokens = cfg.NumTokens
+	lc.NumTokens = util.WeightedNumTokens(cfg.NumTokens, cfg.InstanceWeight)
 	lc.HeartbeatPeriod = cfg.HeartbeatPeriod
 	lc.HeartbeatTimeout = cfg.HeartbeatTimeout
 	lc.ObservePeriod = cfg.ObservePeriod
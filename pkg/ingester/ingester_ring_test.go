@@ -112,6 +112,17 @@ func TestRingConfig_CustomConfigToLifecyclerConfig(t *testing.T) {
 	assert.Equal(t, expected, cfg.ToLifecyclerConfig())
 }
 
+func TestRingConfig_InstanceWeightToLifecyclerConfig(t *testing.T) {
+	cfg := RingConfig{}
+	flagext.DefaultValues(&cfg)
+	cfg.NumTokens = 128
+
+	for weight, expectedNumTokens := range map[float64]int{1: 128, 1.5: 192, 0.5: 64, 0.001: 1} {
+		cfg.InstanceWeight = weight
+		assert.Equal(t, expectedNumTokens, cfg.ToLifecyclerConfig().NumTokens, "weight: %v", weight)
+	}
+}
+
 func TestRingConfig_Validate(t *testing.T) {
 	tests := map[string]struct {
 		instanceID                      string
@@ -121,6 +132,7 @@ func TestRingConfig_Validate(t *testing.T) {
 		spreadMinimizingZones           []string
 		expectedError                   error
 		tokensFilePath                  string
+		instanceWeight                  float64
 	}{
 		"spread-minimizing and correct zones pass validation": {
 			instanceID:              instanceID,
@@ -156,6 +168,14 @@ func TestRingConfig_Validate(t *testing.T) {
 			tokensFilePath:          "/path/tokens",
 			expectedError:           fmt.Errorf("strategy requires %q to be empty", flagTokensFilePath),
 		},
+		"spread-minimizing and instance-weight other than 1 don't pass validation": {
+			instanceID:              instanceID,
+			zone:                    instanceZone,
+			tokenGenerationStrategy: tokenGenerationSpreadMinimizing,
+			spreadMinimizingZones:   spreadMinimizingZones,
+			instanceWeight:          2,
+			expectedError:           fmt.Errorf("strategy requires %q to be 1", flagInstanceWeight),
+		},
 		"spread-minimizing and spread-minimizing-join-ring-in-order pass validation": {
 			instanceID:                      instanceID,
 			zone:                            instanceZone,
@@ -174,6 +194,19 @@ func TestRingConfig_Validate(t *testing.T) {
 			tokenGenerationStrategy: tokenGenerationRandom,
 			tokensFilePath:          "/path/tokens",
 		},
+		"random and instance-weight other than 1 pass validation": {
+			instanceID:              instanceID,
+			zone:                    instanceZone,
+			tokenGenerationStrategy: tokenGenerationRandom,
+			instanceWeight:          2.5,
+		},
+		"negative instance-weight doesn't pass validation": {
+			instanceID:              instanceID,
+			zone:                    instanceZone,
+			tokenGenerationStrategy: tokenGenerationRandom,
+			instanceWeight:          -1,
+			expectedError:           fmt.Errorf("%q must be greater than 0", flagInstanceWeight),
+		},
 		"random and spread-minimizing-join-ring-in-order don't pass validation": {
 			instanceID:                      instanceID,
 			zone:                            instanceZone,
@@ -197,6 +230,10 @@ func TestRingConfig_Validate(t *testing.T) {
 		cfg.SpreadMinimizingJoinRingInOrder = testData.spreadMinimizingJoinRingInOrder
 		cfg.SpreadMinimizingZones = testData.spreadMinimizingZones
 		cfg.TokensFilePath = testData.tokensFilePath
+		cfg.InstanceWeight = 1
+		if testData.instanceWeight != 0 {
+			cfg.InstanceWeight = testData.instanceWeight
+		}
 		err := cfg.Validate()
 		if testData.expectedError == nil {
 			require.NoError(t, err)
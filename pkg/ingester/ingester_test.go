@@ -8550,6 +8550,62 @@ func TestIngester_Push_SeriesWithBlankLabel(t *testing.T) {
 	assert.Equal(t, expected, res)
 }
 
+func TestIngester_Push_MinSampleInterval(t *testing.T) {
+	limits := defaultLimitsTestConfig()
+	limits.MinSampleInterval = model.Duration(10 * time.Second)
+
+	reg := prometheus.NewPedanticRegistry()
+	ing, err := prepareIngesterWithBlocksStorageAndLimits(t, defaultIngesterTestConfig(t), limits, nil, "", reg)
+	require.NoError(t, err)
+	require.NoError(t, services.StartAndAwaitRunning(context.Background(), ing))
+	defer services.StopAndAwaitTerminated(context.Background(), ing) //nolint:errcheck
+
+	// Wait until the ingester is healthy
+	test.Poll(t, 100*time.Millisecond, 1, func() interface{} {
+		return ing.lifecycler.HealthyInstancesCount()
+	})
+
+	ctx := user.InjectOrgID(context.Background(), userID)
+
+	push := func(samples ...mimirpb.Sample) {
+		req := &mimirpb.WriteRequest{
+			Timeseries: []mimirpb.PreallocTimeseries{{TimeSeries: &mimirpb.TimeSeries{
+				Labels:  []mimirpb.LabelAdapter{{Name: labels.MetricName, Value: "testmetric"}},
+				Samples: samples,
+			}}},
+			Source: mimirpb.API,
+		}
+		_, err := ing.Push(ctx, req)
+		require.NoError(t, err)
+	}
+
+	// The interval is enforced within a write request.
+	push(mimirpb.Sample{TimestampMs: 0, Value: 0}, mimirpb.Sample{TimestampMs: 5000, Value: 1}, mimirpb.Sample{TimestampMs: 10000, Value: 2})
+
+	// The interval is enforced against the samples appended by previous write requests too.
+	push(mimirpb.Sample{TimestampMs: 15000, Value: 3})
+	push(mimirpb.Sample{TimestampMs: 20000, Value: 4})
+
+	res, _, err := runTestQuery(ctx, t, ing, labels.MatchEqual, labels.MetricName, "testmetric")
+	require.NoError(t, err)
+	assert.Equal(t, model.Matrix{
+		{
+			Metric: model.Metric{labels.MetricName: "testmetric"},
+			Values: []model.SamplePair{
+				{Timestamp: 0, Value: 0},
+				{Timestamp: 10000, Value: 2},
+				{Timestamp: 20000, Value: 4},
+			},
+		},
+	}, res)
+
+	require.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(fmt.Sprintf(`
+		# HELP cortex_discarded_samples_total The total number of samples that were discarded.
+		# TYPE cortex_discarded_samples_total counter
+		cortex_discarded_samples_total{group="",reason="sample-interval-too-short",user="%s"} 2
+	`, userID)), "cortex_discarded_samples_total"))
+}
+
 func TestIngesterUserLimitExceeded(t *testing.T) {
 	limits := defaultLimitsTestConfig()
 	limits.MaxGlobalSeriesPerUser = 1
@@ -8653,6 +8709,53 @@ func TestIngesterUserLimitExceeded(t *testing.T) {
 
 }
 
+func TestIngesterNewSeriesRateLimitExceeded(t *testing.T) {
+	limits := defaultLimitsTestConfig()
+	limits.MaxGlobalNewSeriesPerMinute = 2
+
+	cfg := defaultIngesterTestConfig(t)
+	// Set RF=1 here to ensure the limit is actually set to 2 instead of being divided by 3.
+	cfg.IngesterRing.ReplicationFactor = 1
+	registry := prometheus.NewRegistry()
+	ing, err := prepareIngesterWithBlocksStorageAndLimits(t, cfg, limits, nil, "", registry)
+	require.NoError(t, err)
+	require.NoError(t, services.StartAndAwaitRunning(context.Background(), ing))
+	defer services.StopAndAwaitTerminated(context.Background(), ing) //nolint:errcheck
+
+	// Wait until it's healthy
+	test.Poll(t, time.Second, 1, func() interface{} {
+		return ing.lifecycler.HealthyInstancesCount()
+	})
+
+	userID := "1"
+	ctx := user.InjectOrgID(context.Background(), userID)
+	series := func(value string) []mimirpb.LabelAdapter {
+		return []mimirpb.LabelAdapter{{Name: labels.MetricName, Value: "testmetric"}, {Name: "foo", Value: value}}
+	}
+
+	// Create as many series as the limit, expect no error.
+	_, err = ing.Push(ctx, mimirpb.ToWriteRequest([][]mimirpb.LabelAdapter{series("a"), series("b")}, []mimirpb.Sample{{TimestampMs: 0, Value: 1}, {TimestampMs: 0, Value: 1}}, nil, nil, mimirpb.API))
+	require.NoError(t, err)
+
+	// Append to an existing series and create a new one, expect the new one to be rejected.
+	_, err = ing.Push(ctx, mimirpb.ToWriteRequest([][]mimirpb.LabelAdapter{series("a"), series("c")}, []mimirpb.Sample{{TimestampMs: 1, Value: 2}, {TimestampMs: 1, Value: 2}}, nil, nil, mimirpb.API))
+	expectedErr := newErrorWithStatus(wrapOrAnnotateWithUser(newPerUserNewSeriesRateLimitReachedError(2), userID), codes.ResourceExhausted)
+	checkErrorWithStatus(t, err, expectedErr)
+
+	res, _, err := runTestQuery(ctx, t, ing, labels.MatchEqual, model.MetricNameLabel, "testmetric")
+	require.NoError(t, err)
+	require.Len(t, res, 2)
+	assert.Equal(t, mimirpb.FromLabelAdaptersToMetric(series("a")), res[0].Metric)
+	assert.Len(t, res[0].Values, 2)
+	assert.Equal(t, mimirpb.FromLabelAdaptersToMetric(series("b")), res[1].Metric)
+
+	assert.NoError(t, testutil.GatherAndCompare(registry, strings.NewReader(`
+		# HELP cortex_discarded_samples_total The total number of samples that were discarded.
+		# TYPE cortex_discarded_samples_total counter
+		cortex_discarded_samples_total{group="",reason="per_user_new_series_rate_limit",user="1"} 1
+	`), "cortex_discarded_samples_total"))
+}
+
 func TestIngesterMetricLimitExceeded(t *testing.T) {
 	limits := defaultLimitsTestConfig()
 	limits.MaxGlobalSeriesPerMetric = 1
@@ -10153,6 +10256,41 @@ func testIngesterOutOfOrderCompactHeadStillActive(t *testing.T,
 	require.Equal(t, 3, active)
 }
 
+func TestIngester_WALDisabledPerTenant(t *testing.T) {
+	cfg := defaultIngesterTestConfig(t)
+
+	walDisabledLimits := defaultLimitsTestConfig()
+	walDisabledLimits.WALDisabled = true
+
+	override, err := validation.NewOverrides(defaultLimitsTestConfig(), validation.NewMockTenantLimits(map[string]*validation.Limits{
+		"user-1": &walDisabledLimits,
+	}))
+	require.NoError(t, err)
+
+	i, err := prepareIngesterWithBlockStorageAndOverrides(t, cfg, override, nil, "", "", nil)
+	require.NoError(t, err)
+	require.NoError(t, services.StartAndAwaitRunning(context.Background(), i))
+	defer services.StopAndAwaitTerminated(context.Background(), i) //nolint:errcheck
+
+	// Wait until it's healthy
+	test.Poll(t, 1*time.Second, 1, func() any { return i.lifecycler.HealthyInstancesCount() })
+
+	for _, userID := range []string{"user-1", "user-2"} {
+		req, _, _, _ := mockWriteRequest(t, labels.FromStrings(labels.MetricName, "test"), 1, 100)
+		_, err := i.Push(user.InjectOrgID(context.Background(), userID), req)
+		require.NoError(t, err)
+	}
+
+	// The WAL directory should only exist for the tenant with the WAL enabled.
+	assert.NoDirExists(t, filepath.Join(i.cfg.BlocksStorageConfig.TSDB.BlocksDir("user-1"), "wal"))
+	assert.DirExists(t, filepath.Join(i.cfg.BlocksStorageConfig.TSDB.BlocksDir("user-2"), "wal"))
+
+	// The series have been ingested in the head of both tenants.
+	for _, userID := range []string{"user-1", "user-2"} {
+		require.Equal(t, uint64(1), i.getTSDB(userID).Head().NumSeries())
+	}
+}
+
 // Test_Ingester_ShipperLabelsOutOfOrderBlocksOnUpload tests whether out-of-order
 // data is compacted and uploaded into a block that is labeled as being out-of-order.
 func Test_Ingester_ShipperLabelsOutOfOrderBlocksOnUpload(t *testing.T) {
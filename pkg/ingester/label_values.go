@@ -0,0 +1,133 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ingester
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/prometheus/prometheus/tsdb"
+	"github.com/prometheus/prometheus/tsdb/index"
+)
+
+// checkContextEveryNLabelValues is the number of label values processed between two checks of the context.
+const checkContextEveryNLabelValues = 128
+
+// labelValuesFromPostings returns the sorted values of the label name of the series matching the matchers,
+// in the head and in the blocks overlapping the [mint, maxt] range. Differently from the TSDB querier, it
+// only reads the postings index, and never looks up the series labels, so its cost depends on the
+// number of values of the label rather than the number of matching series.
+// The time range is only used to select the head and blocks, so the returned values may include the
+// values of series with no samples within the range.
+// If limit is greater than 0, it returns a labelValuesLimitReachedError as soon as more than limit
+// values are found.
+func labelValuesFromPostings(ctx context.Context, db *userTSDB, name string, mint, maxt int64, limit int, matchers []*labels.Matcher) ([]string, error) {
+	var readers []tsdb.IndexReader
+	defer func() {
+		for _, r := range readers {
+			_ = r.Close()
+		}
+	}()
+
+	if h := db.Head(); h.MinTime() <= maxt && mint <= h.MaxTime() {
+		r, err := h.Index()
+		if err != nil {
+			return nil, err
+		}
+		readers = append(readers, r)
+	}
+	for _, b := range db.Blocks() {
+		if !b.OverlapsClosedInterval(mint, maxt) {
+			continue
+		}
+		r, err := b.Index()
+		if err != nil {
+			return nil, err
+		}
+		readers = append(readers, r)
+	}
+
+	// The matchers on the requested label name are applied to the values directly,
+	// while the other ones are used to select the postings of the matching series.
+	var nameMatchers, otherMatchers []*labels.Matcher
+	for _, m := range matchers {
+		if m.Name == name {
+			nameMatchers = append(nameMatchers, m)
+		} else {
+			otherMatchers = append(otherMatchers, m)
+		}
+	}
+
+	values := map[string]struct{}{}
+	for _, r := range readers {
+		if err := collectLabelValuesFromPostings(ctx, r, name, nameMatchers, otherMatchers, limit, values); err != nil {
+			return nil, err
+		}
+	}
+
+	result := make([]string, 0, len(values))
+	for v := range values {
+		result = append(result, v)
+	}
+	slices.Sort(result)
+	return result, nil
+}
+
+func collectLabelValuesFromPostings(ctx context.Context, r tsdb.IndexReader, name string, nameMatchers, otherMatchers []*labels.Matcher, limit int, values map[string]struct{}) error {
+	candidates, err := r.SortedLabelValues(ctx, name)
+	if err != nil {
+		return errors.Wrapf(err, "fetching values of label %s", name)
+	}
+
+	var matching []storage.SeriesRef
+	if len(otherMatchers) > 0 {
+		p, err := r.PostingsForMatchers(ctx, false, otherMatchers...)
+		if err != nil {
+			return errors.Wrap(err, "fetching postings for matchers")
+		}
+		matching, err = index.ExpandPostings(p)
+		if err != nil {
+			return errors.Wrap(err, "expanding postings for matchers")
+		}
+		if len(matching) == 0 {
+			return nil
+		}
+	}
+
+candidatesLoop:
+	for i, v := range candidates {
+		if i%checkContextEveryNLabelValues == 0 && ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if _, ok := values[v]; ok {
+			continue
+		}
+		for _, m := range nameMatchers {
+			if !m.Matches(v) {
+				continue candidatesLoop
+			}
+		}
+
+		if matching != nil {
+			p, err := r.Postings(ctx, name, v)
+			if err != nil {
+				return errors.Wrapf(err, "fetching postings for %s=%q", name, v)
+			}
+			if !index.Intersect(p, index.NewListPostings(matching)).Next() {
+				continue
+			}
+		}
+
+		// The label value strings may point to memory mapped file regions
+		// that may become unmapped once the index reader is closed.
+		values[strings.Clone(v)] = struct{}{}
+		if limit > 0 && len(values) > limit {
+			return newLabelValuesLimitReachedError(limit)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ingester
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/grafana/dskit/grpcutil"
+	"github.com/grafana/dskit/services"
+	"github.com/grafana/dskit/test"
+	"github.com/grafana/dskit/user"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+
+	"github.com/grafana/mimir/pkg/ingester/client"
+)
+
+func TestIngester_LabelValues_PostingsFastPath(t *testing.T) {
+	blockSeries := []labels.Labels{
+		labels.FromStrings(labels.MetricName, "http_requests_total", "status", "200", "route", "get_user"),
+		labels.FromStrings(labels.MetricName, "http_requests_total", "status", "500", "route", "get_user"),
+		labels.FromStrings(labels.MetricName, "grpc_requests_total", "status", "OK", "route", "get_user"),
+	}
+	headSeries := []labels.Labels{
+		labels.FromStrings(labels.MetricName, "http_requests_total", "status", "404", "route", "put_user"),
+		labels.FromStrings(labels.MetricName, "up", "job", "api"),
+	}
+
+	for _, fastPathEnabled := range []bool{false, true} {
+		t.Run(fmt.Sprintf("fast path enabled: %t", fastPathEnabled), func(t *testing.T) {
+			cfg := defaultIngesterTestConfig(t)
+			cfg.LabelValuesPostingsFastPathEnabled = fastPathEnabled
+
+			limits := defaultLimitsTestConfig()
+			limits.MaxLabelValuesPerRequest = 3
+
+			i, err := prepareIngesterWithBlocksStorageAndLimits(t, cfg, limits, nil, "", nil)
+			require.NoError(t, err)
+			require.NoError(t, services.StartAndAwaitRunning(context.Background(), i))
+			t.Cleanup(func() {
+				require.NoError(t, services.StopAndAwaitTerminated(context.Background(), i))
+			})
+
+			test.Poll(t, 1*time.Second, 1, func() interface{} {
+				return i.lifecycler.HealthyInstancesCount()
+			})
+
+			ctx := user.InjectOrgID(context.Background(), "test")
+			for _, lbls := range blockSeries {
+				req, _, _, _ := mockWriteRequest(t, lbls, 1, 100000)
+				_, err := i.Push(ctx, req)
+				require.NoError(t, err)
+			}
+
+			// Compact the head, so that the values are read from both a block and the head.
+			i.compactBlocks(ctx, true, math.MaxInt64, nil)
+			require.Len(t, i.getTSDB("test").Blocks(), 1)
+
+			for _, lbls := range headSeries {
+				req, _, _, _ := mockWriteRequest(t, lbls, 1, 300000)
+				_, err := i.Push(ctx, req)
+				require.NoError(t, err)
+			}
+
+			tests := map[string]struct {
+				labelName      string
+				matchers       []*labels.Matcher
+				expectedValues []string
+				expectedErr    bool
+			}{
+				"values of series matching other labels": {
+					labelName:      "status",
+					matchers:       []*labels.Matcher{labels.MustNewMatcher(labels.MatchEqual, labels.MetricName, "http_requests_total")},
+					expectedValues: []string{"200", "404", "500"},
+				},
+				"values matching a matcher on the same label": {
+					labelName: "status",
+					matchers: []*labels.Matcher{
+						labels.MustNewMatcher(labels.MatchEqual, labels.MetricName, "http_requests_total"),
+						labels.MustNewMatcher(labels.MatchRegexp, "status", "[45].*"),
+					},
+					expectedValues: []string{"404", "500"},
+				},
+				"values of series matching a regexp": {
+					labelName:      "route",
+					matchers:       []*labels.Matcher{labels.MustNewMatcher(labels.MatchRegexp, "status", "2..|OK")},
+					expectedValues: []string{"get_user"},
+				},
+				"no matching series": {
+					labelName:      "status",
+					matchers:       []*labels.Matcher{labels.MustNewMatcher(labels.MatchEqual, labels.MetricName, "unknown")},
+					expectedValues: []string{},
+				},
+				"more values than the limit": {
+					labelName:   "status",
+					matchers:    []*labels.Matcher{labels.MustNewMatcher(labels.MatchRegexp, "route", ".+")},
+					expectedErr: true,
+				},
+				"more values than the limit without matchers": {
+					labelName:   "status",
+					expectedErr: true,
+				},
+			}
+
+			for name, tc := range tests {
+				t.Run(name, func(t *testing.T) {
+					req, err := client.ToLabelValuesRequest(model.LabelName(tc.labelName), 0, math.MaxInt64, tc.matchers)
+					require.NoError(t, err)
+
+					res, err := i.LabelValues(ctx, req)
+					if tc.expectedErr {
+						stat, ok := grpcutil.ErrorToStatus(err)
+						require.True(t, ok)
+						require.Equal(t, codes.FailedPrecondition, stat.Code())
+						require.Contains(t, stat.Message(), "err-mimir-max-label-values-per-request")
+						return
+					}
+					require.NoError(t, err)
+					assert.ElementsMatch(t, tc.expectedValues, res.LabelValues)
+				})
+			}
+		})
+	}
+}
@@ -6,9 +6,13 @@
 package ingester
 
 import (
+	"context"
 	"math"
+	"time"
 
 	"github.com/grafana/dskit/ring"
+	"github.com/grafana/dskit/services"
+	"go.uber.org/atomic"
 
 	"github.com/grafana/mimir/pkg/util"
 	util_math "github.com/grafana/mimir/pkg/util/math"
@@ -21,6 +25,7 @@ type limiterTenantLimits interface {
 	MaxGlobalMetadataPerMetric(userID string) int
 	MaxGlobalMetricsWithMetadataPerUser(userID string) int
 	MaxGlobalExemplarsPerUser(userID string) int
+	MaxGlobalNewSeriesPerMinute(userID string) int
 }
 
 // Limiter implements primitives to get the maximum number of series, exemplars, metadata, etc.
@@ -28,6 +33,9 @@ type limiterTenantLimits interface {
 type Limiter struct {
 	limits       limiterTenantLimits
 	ringStrategy limiterRingStrategy
+
+	// dynamicSeriesLimit adjusts the per-tenant series limit based on the cluster memory headroom. Optional.
+	dynamicSeriesLimit *dynamicSeriesLimit
 }
 
 // NewLimiter makes a new in-memory series limiter
@@ -75,7 +83,9 @@ func (l *Limiter) maxMetadataPerMetric(userID string) int {
 }
 
 func (l *Limiter) maxSeriesPerUser(userID string, minLocalLimit int) int {
-	return l.convertGlobalToLocalLimitOrUnlimited(userID, l.limits.MaxGlobalSeriesPerUser, minLocalLimit)
+	return l.convertGlobalToLocalLimitOrUnlimited(userID, func(userID string) int {
+		return l.dynamicSeriesLimit.apply(l.limits.MaxGlobalSeriesPerUser(userID))
+	}, minLocalLimit)
 }
 
 func (l *Limiter) maxMetadataPerUser(userID string) int {
@@ -97,6 +107,19 @@ func (l *Limiter) maxExemplarsPerUser(userID string) int {
 	return globalLimit
 }
 
+// maxNewSeriesPerMinute returns the maximum number of new series that can be created per minute for the tenant
+// in this ingester, or 0 if the limit is disabled.
+func (l *Limiter) maxNewSeriesPerMinute(userID string) int {
+	globalLimit := l.limits.MaxGlobalNewSeriesPerMinute(userID)
+
+	// Like for exemplars, 0 means disabled, so we fallback to the global limit if the local one can't be computed.
+	localLimit := l.ringStrategy.convertGlobalToLocalLimit(userID, globalLimit)
+	if localLimit > 0 {
+		return localLimit
+	}
+	return globalLimit
+}
+
 func (l *Limiter) convertGlobalToLocalLimitOrUnlimited(userID string, globalLimitFn func(string) int, minLocalLimit int) int {
 	// We can assume that series/metadata are evenly distributed across ingesters
 	globalLimit := globalLimitFn(userID)
@@ -134,6 +157,10 @@ type ingesterRingLimiterStrategy struct {
 	ingesterZone         string
 
 	getIngestionTenantShardSize func(userID string) int
+
+	// tokensShare returns the share of tokens owned by this ingester, relative to the average of the ingesters
+	// in the same zone. Optional: if nil, the ingester is assumed to own the average share.
+	tokensShare func() float64
 }
 
 func newIngesterRingLimiterStrategy(ring ingesterRingLimiterRingCount, replicationFactor int, zoneAwarenessEnabled bool, ingesterZone string, getIngestionTenantShardSize func(userID string) int) *ingesterRingLimiterStrategy {
@@ -179,9 +206,13 @@ func (is *ingesterRingLimiterStrategy) convertGlobalToLocalLimit(userID string,
 	}
 
 	// Global limit is equally distributed among all the active zones.
-	// The portion of global limit related to each zone is then equally distributed
-	// among all the ingesters belonging to that zone.
-	return int((float64(globalLimit*is.replicationFactor) / float64(zonesCount)) / float64(ingestersInZoneCount))
+	// The portion of global limit related to each zone is then distributed among all the ingesters
+	// belonging to that zone, proportionally to their share of tokens, which depends on their weight.
+	localLimit := (float64(globalLimit*is.replicationFactor) / float64(zonesCount)) / float64(ingestersInZoneCount)
+	if is.tokensShare != nil {
+		localLimit *= is.tokensShare()
+	}
+	return int(localLimit)
 }
 
 func (is *ingesterRingLimiterStrategy) getShardSize(userID string) int {
@@ -195,6 +226,85 @@ func (is *ingesterRingLimiterStrategy) getZonesCount() int {
 	return 1
 }
 
+// ingesterTokensShareRing is the subset of the ring used to compute the share of tokens owned by an ingester.
+type ingesterTokensShareRing interface {
+	GetAllHealthy(op ring.Operation) (ring.ReplicationSet, error)
+}
+
+// ingesterTokensShare computes the number of tokens owned by an ingester relative to the average number of tokens
+// owned by the ingesters in the same zone (or in the whole ring, if zone-awareness is disabled). Ingesters with a
+// higher weight register more tokens, and so receive proportionally more series. The share is used whenever a local
+// limit is computed, so it's computed in the background when the ring changes and get only loads it.
+type ingesterTokensShare struct {
+	services.Service
+
+	ring                 ingesterTokensShareRing
+	instanceID           string
+	zoneAwarenessEnabled bool
+	instanceZone         string
+
+	// previousRing is only accessed by the service goroutine.
+	previousRing ring.ReplicationSet
+	share        atomic.Float64
+}
+
+func newIngesterTokensShare(ring ingesterTokensShareRing, instanceID string, zoneAwarenessEnabled bool, instanceZone string, refreshInterval time.Duration) *ingesterTokensShare {
+	s := &ingesterTokensShare{
+		ring:                 ring,
+		instanceID:           instanceID,
+		zoneAwarenessEnabled: zoneAwarenessEnabled,
+		instanceZone:         instanceZone,
+	}
+	s.share.Store(1)
+	s.Service = services.NewTimerService(refreshInterval, s.update, s.update, nil)
+	return s
+}
+
+func (s *ingesterTokensShare) get() float64 {
+	return s.share.Load()
+}
+
+// update recomputes the share of tokens if the ring has changed since the previous update.
+func (s *ingesterTokensShare) update(_ context.Context) error {
+	rs, err := s.ring.GetAllHealthy(ring.WriteNoExtend)
+	if err != nil {
+		// Reset the previous ring, so that the share is recomputed once the ring can be read again.
+		s.previousRing = ring.ReplicationSet{}
+		s.share.Store(1)
+		return nil
+	}
+
+	// Ignore state and IP address changes, since they have no impact on token distribution.
+	if !ring.HasReplicationSetChangedWithoutStateOrAddr(s.previousRing, rs) {
+		return nil
+	}
+	s.previousRing = rs
+	s.share.Store(s.compute(rs))
+	return nil
+}
+
+// compute returns the share of tokens owned by the ingester in the input replication set, or 1 if it can't be computed.
+func (s *ingesterTokensShare) compute(rs ring.ReplicationSet) float64 {
+	var instanceTokens, zoneTokens, zoneInstances int
+	for _, inst := range rs.Instances {
+		if len(inst.Tokens) == 0 || (s.zoneAwarenessEnabled && inst.Zone != s.instanceZone) {
+			continue
+		}
+
+		zoneTokens += len(inst.Tokens)
+		zoneInstances++
+		if inst.Id == s.instanceID {
+			instanceTokens = len(inst.Tokens)
+		}
+	}
+
+	// The ingester may not be in the ring yet, for example while it's joining.
+	if instanceTokens == 0 || zoneTokens == 0 {
+		return 1
+	}
+	return float64(instanceTokens) * float64(zoneInstances) / float64(zoneTokens)
+}
+
 // Interface for mocking.
 type partitionRingWatcher interface {
 	PartitionRing() *ring.PartitionRing
@@ -6,11 +6,13 @@
 package ingester
 
 import (
+	"context"
 	"math"
 	"testing"
 	"time"
 
 	"github.com/grafana/dskit/ring"
+	"github.com/grafana/dskit/services"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
@@ -900,6 +902,110 @@ func TestLimiter_IsWithinMaxMetricsWithMetadataPerUser_WithPartitionsRing(t *tes
 	}
 }
 
+func TestIngesterRingLimiterStrategy_ShouldFollowTheTokensShare(t *testing.T) {
+	limits, err := validation.NewOverrides(validation.Limits{MaxGlobalSeriesPerUser: 900}, nil)
+	require.NoError(t, err)
+
+	// 3 ingesters and replication factor 3, so each ingester gets 900 series with an even share of tokens.
+	strategy := newIngesterRingLimiterStrategy(&ringCountMock{instancesCount: 3, zonesCount: 1}, 3, false, "", limits.IngestionTenantShardSize)
+
+	for share, expected := range map[float64]int{1: 900, 1.5: 1350, 0.5: 450} {
+		strategy.tokensShare = func() float64 { return share }
+		assert.Equal(t, expected, NewLimiter(limits, strategy).maxSeriesPerUser("test", 0), "tokens share: %v", share)
+	}
+}
+
+func TestIngesterTokensShare(t *testing.T) {
+	instance := func(id, zone string, numTokens int) ring.InstanceDesc {
+		return ring.InstanceDesc{Id: id, Zone: zone, Tokens: make([]uint32, numTokens)}
+	}
+
+	tests := map[string]struct {
+		instances            []ring.InstanceDesc
+		zoneAwarenessEnabled bool
+		expected             float64
+	}{
+		"all ingesters have the same number of tokens": {
+			instances: []ring.InstanceDesc{instance("ingester-1", "a", 128), instance("ingester-2", "a", 128)},
+			expected:  1,
+		},
+		"the ingester has twice the tokens of the other ingester": {
+			instances: []ring.InstanceDesc{instance("ingester-1", "a", 256), instance("ingester-2", "a", 128)},
+			expected:  float64(256*2) / float64(256+128),
+		},
+		"the ingester has half the tokens of the other ingesters": {
+			instances: []ring.InstanceDesc{instance("ingester-1", "a", 64), instance("ingester-2", "a", 128), instance("ingester-3", "a", 128)},
+			expected:  float64(64*3) / float64(64+128+128),
+		},
+		"ingesters in other zones are ignored with zone-awareness enabled": {
+			instances:            []ring.InstanceDesc{instance("ingester-1", "a", 256), instance("ingester-2", "a", 256), instance("ingester-3", "b", 64)},
+			zoneAwarenessEnabled: true,
+			expected:             1,
+		},
+		"ingesters without tokens are ignored": {
+			instances: []ring.InstanceDesc{instance("ingester-1", "a", 128), instance("ingester-2", "a", 0)},
+			expected:  1,
+		},
+		"the ingester is not in the ring": {
+			instances: []ring.InstanceDesc{instance("ingester-2", "a", 128), instance("ingester-3", "a", 256)},
+			expected:  1,
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			r := &tokensShareRingMock{instances: testData.instances}
+			share := newIngesterTokensShare(r, "ingester-1", testData.zoneAwarenessEnabled, "a", time.Hour)
+			require.NoError(t, services.StartAndAwaitRunning(context.Background(), share))
+			t.Cleanup(func() {
+				require.NoError(t, services.StopAndAwaitTerminated(context.Background(), share))
+			})
+
+			assert.InDelta(t, testData.expected, share.get(), 0.0001)
+		})
+	}
+
+	t.Run("the share is recomputed when the ring changes", func(t *testing.T) {
+		r := &tokensShareRingMock{instances: []ring.InstanceDesc{instance("ingester-1", "a", 256), instance("ingester-2", "a", 256)}}
+		share := newIngesterTokensShare(r, "ingester-1", false, "a", time.Hour)
+		require.Equal(t, float64(1), share.get())
+
+		require.NoError(t, share.update(context.Background()))
+		require.Equal(t, float64(1), share.get())
+
+		r.instances = []ring.InstanceDesc{instance("ingester-1", "a", 256), instance("ingester-2", "a", 128)}
+		require.Equal(t, float64(1), share.get())
+
+		require.NoError(t, share.update(context.Background()))
+		require.InDelta(t, float64(256*2)/float64(256+128), share.get(), 0.0001)
+	})
+
+	t.Run("the share is 1 if the ring can't be read", func(t *testing.T) {
+		r := &tokensShareRingMock{instances: []ring.InstanceDesc{instance("ingester-1", "a", 256), instance("ingester-2", "a", 128)}}
+		share := newIngesterTokensShare(r, "ingester-1", false, "a", time.Hour)
+		require.NoError(t, share.update(context.Background()))
+		require.InDelta(t, float64(256*2)/float64(256+128), share.get(), 0.0001)
+
+		r.err = ring.ErrEmptyRing
+		require.NoError(t, share.update(context.Background()))
+		require.Equal(t, float64(1), share.get())
+
+		// The share is recomputed once the ring can be read again, even if it hasn't changed.
+		r.err = nil
+		require.NoError(t, share.update(context.Background()))
+		require.InDelta(t, float64(256*2)/float64(256+128), share.get(), 0.0001)
+	})
+}
+
+type tokensShareRingMock struct {
+	instances []ring.InstanceDesc
+	err       error
+}
+
+func (m *tokensShareRingMock) GetAllHealthy(ring.Operation) (ring.ReplicationSet, error) {
+	return ring.ReplicationSet{Instances: m.instances}, m.err
+}
+
 type ringCountMock struct {
 	instancesCount       int
 	instancesInZoneCount int
@@ -426,8 +426,10 @@ type discardedMetrics struct {
 	sampleTooFarInFuture   *prometheus.CounterVec
 	newValueForTimestamp   *prometheus.CounterVec
 	perUserSeriesLimit     *prometheus.CounterVec
+	perUserNewSeriesRate   *prometheus.CounterVec
 	perMetricSeriesLimit   *prometheus.CounterVec
 	invalidNativeHistogram *prometheus.CounterVec
+	sampleIntervalTooShort *prometheus.CounterVec
 }
 
 func newDiscardedMetrics(r prometheus.Registerer) *discardedMetrics {
@@ -438,8 +440,10 @@ func newDiscardedMetrics(r prometheus.Registerer) *discardedMetrics {
 		sampleTooFarInFuture:   validation.DiscardedSamplesCounter(r, reasonSampleTooFarInFuture),
 		newValueForTimestamp:   validation.DiscardedSamplesCounter(r, reasonNewValueForTimestamp),
 		perUserSeriesLimit:     validation.DiscardedSamplesCounter(r, reasonPerUserSeriesLimit),
+		perUserNewSeriesRate:   validation.DiscardedSamplesCounter(r, reasonPerUserNewSeriesRate),
 		perMetricSeriesLimit:   validation.DiscardedSamplesCounter(r, reasonPerMetricSeriesLimit),
 		invalidNativeHistogram: validation.DiscardedSamplesCounter(r, reasonInvalidNativeHistogram),
+		sampleIntervalTooShort: validation.DiscardedSamplesCounter(r, reasonSampleIntervalTooShort),
 	}
 }
 
@@ -450,8 +454,10 @@ func (m *discardedMetrics) DeletePartialMatch(filter prometheus.Labels) {
 	m.sampleTooFarInFuture.DeletePartialMatch(filter)
 	m.newValueForTimestamp.DeletePartialMatch(filter)
 	m.perUserSeriesLimit.DeletePartialMatch(filter)
+	m.perUserNewSeriesRate.DeletePartialMatch(filter)
 	m.perMetricSeriesLimit.DeletePartialMatch(filter)
 	m.invalidNativeHistogram.DeletePartialMatch(filter)
+	m.sampleIntervalTooShort.DeletePartialMatch(filter)
 }
 
 func (m *discardedMetrics) DeleteLabelValues(userID string, group string) {
@@ -461,8 +467,10 @@ func (m *discardedMetrics) DeleteLabelValues(userID string, group string) {
 	m.sampleTooFarInFuture.DeleteLabelValues(userID, group)
 	m.newValueForTimestamp.DeleteLabelValues(userID, group)
 	m.perUserSeriesLimit.DeleteLabelValues(userID, group)
+	m.perUserNewSeriesRate.DeleteLabelValues(userID, group)
 	m.perMetricSeriesLimit.DeleteLabelValues(userID, group)
 	m.invalidNativeHistogram.DeleteLabelValues(userID, group)
+	m.sampleIntervalTooShort.DeleteLabelValues(userID, group)
 }
 
 // TSDB metrics collector. Each tenant has its own registry, that TSDB code uses.
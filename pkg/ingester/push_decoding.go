@@ -0,0 +1,157 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ingester
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/grafana/dskit/services"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/grafana/mimir/pkg/mimirpb"
+)
+
+var errPushDecodingPoolNotRunning = errors.New("push decoding pool is not running")
+
+// pushDecoder decodes the push requests sent with the client.PushCodecName codec, whose payload is compressed
+// with snappy. The requests are decompressed in pooled buffers, which are released once the requests have been
+// handled. If a pool is set, the requests are decompressed and unmarshalled by its workers, instead of the gRPC
+// goroutine of each request.
+type pushDecoder struct {
+	pool           *pushDecodingPool
+	maxMessageSize int
+	buffers        sync.Pool
+}
+
+func newPushDecoder(workers, maxMessageSize int, reg prometheus.Registerer) *pushDecoder {
+	d := &pushDecoder{
+		maxMessageSize: maxMessageSize,
+		buffers: sync.Pool{
+			New: func() any {
+				return new([]byte)
+			},
+		},
+	}
+	if workers > 0 {
+		d.pool = newPushDecodingPool(workers, reg)
+	}
+	return d
+}
+
+// decode implements client.PushDecoder.
+func (d *pushDecoder) decode(ctx context.Context, compressed []byte) (*mimirpb.WriteRequest, func(), error) {
+	size, err := snappy.DecodedLen(compressed)
+	if err != nil {
+		return nil, nil, status.Errorf(codes.Internal, "failed to decompress the push request: %v", err)
+	}
+	if d.maxMessageSize > 0 && size > d.maxMessageSize {
+		return nil, nil, status.Errorf(codes.ResourceExhausted, "received push request larger than max after decompression (%d vs. %d)", size, d.maxMessageSize)
+	}
+
+	buf := d.buffers.Get().(*[]byte)
+	release := func() {
+		d.buffers.Put(buf)
+	}
+
+	req := &mimirpb.WriteRequest{}
+	decode := func() error {
+		if cap(*buf) < size {
+			*buf = make([]byte, size)
+		}
+		decompressed, err := snappy.Decode((*buf)[:size], compressed)
+		if err != nil {
+			return status.Errorf(codes.Internal, "failed to decompress the push request: %v", err)
+		}
+		if err := req.Unmarshal(decompressed); err != nil {
+			return status.Errorf(codes.Internal, "failed to unmarshal the push request: %v", err)
+		}
+		return nil
+	}
+
+	if d.pool == nil {
+		err = decode()
+	} else {
+		err = d.pool.run(ctx, decode)
+	}
+	if err != nil {
+		release()
+		return nil, nil, err
+	}
+	return req, release, nil
+}
+
+// pushDecodingPool is a bounded pool of workers decoding push requests.
+type pushDecodingPool struct {
+	services.Service
+
+	workers int
+	jobs    chan func()
+
+	waitDuration prometheus.Histogram
+}
+
+func newPushDecodingPool(workers int, reg prometheus.Registerer) *pushDecodingPool {
+	p := &pushDecodingPool{
+		workers: workers,
+		jobs:    make(chan func()),
+		waitDuration: promauto.With(reg).NewHistogram(prometheus.HistogramOpts{
+			Name:                            "cortex_ingester_push_decoding_wait_duration_seconds",
+			Help:                            "Time spent waiting for a push decoding worker to be available.",
+			Buckets:                         prometheus.ExponentialBuckets(0.0001, 4, 8),
+			NativeHistogramBucketFactor:     1.1,
+			NativeHistogramMaxBucketNumber:  100,
+			NativeHistogramMinResetDuration: 1 * time.Hour,
+		}),
+	}
+	p.Service = services.NewBasicService(nil, p.running, nil)
+	return p
+}
+
+func (p *pushDecodingPool) running(ctx context.Context) error {
+	wg := sync.WaitGroup{}
+	wg.Add(p.workers)
+	for i := 0; i < p.workers; i++ {
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case f := <-p.jobs:
+					f()
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	return nil
+}
+
+// run runs f in a worker of the pool, and returns its error once it has completed. If the context is canceled
+// while waiting for a worker to be available, run returns the context error without running f.
+func (p *pushDecodingPool) run(ctx context.Context, f func() error) error {
+	if p.State() != services.Running {
+		return status.Error(codes.Unavailable, errPushDecodingPoolNotRunning.Error())
+	}
+
+	start := time.Now()
+	done := make(chan error, 1)
+	job := func() {
+		p.waitDuration.Observe(time.Since(start).Seconds())
+		done <- f()
+	}
+
+	select {
+	case p.jobs <- job:
+		return <-done
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
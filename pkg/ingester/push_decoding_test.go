@@ -0,0 +1,216 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ingester
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/golang/snappy"
+	"github.com/grafana/dskit/services"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/encoding"
+	"google.golang.org/grpc/status"
+
+	"github.com/grafana/mimir/pkg/ingester/client"
+	"github.com/grafana/mimir/pkg/mimirpb"
+)
+
+type pushDecodingTestServer struct {
+	client.UnimplementedIngesterServer
+
+	received chan *mimirpb.WriteRequest
+}
+
+func (s *pushDecodingTestServer) Push(_ context.Context, req *mimirpb.WriteRequest) (*mimirpb.WriteResponse, error) {
+	s.received <- req
+	return &mimirpb.WriteResponse{}, nil
+}
+
+func TestPushDecoder(t *testing.T) {
+	const maxMessageSize = 64 * 1024
+
+	reg := prometheus.NewPedanticRegistry()
+	decoder := newPushDecoder(2, maxMessageSize, reg)
+	require.NoError(t, services.StartAndAwaitRunning(context.Background(), decoder.pool))
+	t.Cleanup(func() {
+		require.NoError(t, services.StopAndAwaitTerminated(context.Background(), decoder.pool))
+	})
+
+	srv := &pushDecodingTestServer{received: make(chan *mimirpb.WriteRequest, 1)}
+	serv := grpc.NewServer()
+	t.Cleanup(serv.GracefulStop)
+	client.RegisterIngesterServerWithPushDecoder(serv, srv, decoder.decode)
+
+	listener, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	go func() {
+		_ = serv.Serve(listener)
+	}()
+
+	conn, err := grpc.NewClient(listener.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	c := client.NewIngesterClient(conn)
+	pushCodecOptions := []grpc.CallOption{grpc.CallContentSubtype(client.PushCodecName), grpc.UseCompressor(encoding.Identity)}
+
+	req := writeRequestSingleSeries(labels.FromStrings(labels.MetricName, "test"), []mimirpb.Sample{{TimestampMs: 1, Value: 2}})
+
+	// Requests sent with the push codec are decoded by the workers.
+	_, err = c.Push(context.Background(), req, pushCodecOptions...)
+	require.NoError(t, err)
+
+	received := <-srv.received
+	require.Len(t, received.Timeseries, 1)
+	assert.Equal(t, req.Timeseries[0].Samples, received.Timeseries[0].Samples)
+	assert.Equal(t, 1, testutil.CollectAndCount(reg, "cortex_ingester_push_decoding_wait_duration_seconds"))
+	assert.NoError(t, testutil.GatherAndCompare(reg, bytes.NewBufferString(`
+		# HELP cortex_ingester_push_decoding_wait_duration_seconds Time spent waiting for a push decoding worker to be available.
+		# TYPE cortex_ingester_push_decoding_wait_duration_seconds histogram
+		cortex_ingester_push_decoding_wait_duration_seconds_count 1
+	`), "cortex_ingester_push_decoding_wait_duration_seconds_count"))
+
+	// Other requests are handled as usual.
+	_, err = c.Push(context.Background(), req)
+	require.NoError(t, err)
+
+	received = <-srv.received
+	require.Len(t, received.Timeseries, 1)
+	assert.Equal(t, req.Timeseries[0].Samples, received.Timeseries[0].Samples)
+
+	// Requests larger than the max size once decompressed are rejected.
+	largeReq := writeRequestSingleSeries(labels.FromStrings(labels.MetricName, "test", "large", string(bytes.Repeat([]byte("x"), 2*maxMessageSize))), nil)
+	_, err = c.Push(context.Background(), largeReq, pushCodecOptions...)
+	require.Error(t, err)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+}
+
+func TestPushDecoder_Decode(t *testing.T) {
+	req := writeRequestSingleSeries(labels.FromStrings(labels.MetricName, "test"), []mimirpb.Sample{{TimestampMs: 1, Value: 2}})
+	data, err := req.Marshal()
+	require.NoError(t, err)
+	compressed := snappy.Encode(nil, data)
+
+	// Without workers, the requests are decoded by the calling goroutine.
+	decoder := newPushDecoder(0, 0, nil)
+	require.Nil(t, decoder.pool)
+
+	// Decode a few times, to make sure the pooled buffers are reused correctly.
+	for i := 0; i < 3; i++ {
+		actual, release, err := decoder.decode(context.Background(), compressed)
+		require.NoError(t, err)
+		assert.Equal(t, req.Timeseries[0].Samples, actual.Timeseries[0].Samples)
+		release()
+	}
+
+	// Invalid requests are rejected.
+	_, _, err = decoder.decode(context.Background(), []byte("invalid"))
+	require.Error(t, err)
+	assert.Equal(t, codes.Internal, status.Code(err))
+
+	_, _, err = decoder.decode(context.Background(), snappy.Encode(nil, []byte{0xff}))
+	require.Error(t, err)
+	assert.Equal(t, codes.Internal, status.Code(err))
+}
+
+func TestPushDecodingPool_Run(t *testing.T) {
+	pool := newPushDecodingPool(1, nil)
+
+	// The pool rejects the jobs when not running.
+	err := pool.run(context.Background(), func() error { return nil })
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+
+	require.NoError(t, services.StartAndAwaitRunning(context.Background(), pool))
+
+	// The error of the job is returned.
+	assert.EqualError(t, pool.run(context.Background(), func() error { return fmt.Errorf("failed") }), "failed")
+
+	// Keep the only worker busy.
+	started := make(chan struct{})
+	unblock := make(chan struct{})
+	go func() {
+		_ = pool.run(context.Background(), func() error {
+			close(started)
+			<-unblock
+			return nil
+		})
+	}()
+	<-started
+
+	// Waiting for a worker stops once the context is canceled.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Equal(t, context.Canceled, pool.run(ctx, func() error {
+		t.Error("the job should not run")
+		return nil
+	}))
+
+	close(unblock)
+
+	// The workers stop with the pool.
+	require.NoError(t, services.StopAndAwaitTerminated(context.Background(), pool))
+}
+
+func BenchmarkPushDecoder_Decode(b *testing.B) {
+	series := make([]mimirpb.PreallocTimeseries, 0, 1000)
+	for i := 0; i < cap(series); i++ {
+		series = append(series, mimirpb.PreallocTimeseries{TimeSeries: &mimirpb.TimeSeries{
+			Labels:  mimirpb.FromLabelsToLabelAdapters(labels.FromStrings(labels.MetricName, "test", "series", fmt.Sprintf("%d", i))),
+			Samples: []mimirpb.Sample{{TimestampMs: 1, Value: float64(i)}},
+		}})
+	}
+	data, err := (&mimirpb.WriteRequest{Timeseries: series}).Marshal()
+	require.NoError(b, err)
+	compressed := snappy.Encode(nil, data)
+
+	b.Run("snappy decode and unmarshal", func(b *testing.B) {
+		b.ReportAllocs()
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				decompressed, err := snappy.Decode(nil, compressed)
+				if err != nil {
+					b.Fatal(err)
+				}
+				req := &mimirpb.WriteRequest{}
+				if err := req.Unmarshal(decompressed); err != nil {
+					b.Fatal(err)
+				}
+				mimirpb.ReuseSlice(req.Timeseries)
+			}
+		})
+	})
+
+	for name, workers := range map[string]int{"push decoder without workers": 0, "push decoder with workers": 4} {
+		b.Run(name, func(b *testing.B) {
+			decoder := newPushDecoder(workers, 0, nil)
+			if decoder.pool != nil {
+				require.NoError(b, services.StartAndAwaitRunning(context.Background(), decoder.pool))
+				b.Cleanup(func() {
+					require.NoError(b, services.StopAndAwaitTerminated(context.Background(), decoder.pool))
+				})
+			}
+
+			b.ReportAllocs()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					req, release, err := decoder.decode(context.Background(), compressed)
+					if err != nil {
+						b.Fatal(err)
+					}
+					mimirpb.ReuseSlice(req.Timeseries)
+					release()
+				}
+			})
+		})
+	}
+}
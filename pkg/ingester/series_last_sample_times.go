@@ -0,0 +1,57 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ingester
+
+import (
+	"sync"
+
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/prometheus/prometheus/tsdb/chunks"
+)
+
+const seriesLastSampleTimesStripes = 128
+
+// seriesLastSampleTimes tracks the timestamp of the last sample appended to each in-memory series of a tenant,
+// which is used to enforce the minimum interval between the samples of a series across write requests.
+// The zero value is ready to use, and series are only tracked once set.
+type seriesLastSampleTimes struct {
+	stripes [seriesLastSampleTimesStripes]struct {
+		mtx   sync.Mutex
+		times map[storage.SeriesRef]int64
+	}
+}
+
+// get returns the timestamp of the last sample appended to the series, and whether the series is tracked.
+func (t *seriesLastSampleTimes) get(ref storage.SeriesRef) (int64, bool) {
+	s := &t.stripes[ref%seriesLastSampleTimesStripes]
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	ts, ok := s.times[ref]
+	return ts, ok
+}
+
+// set updates the timestamp of the last sample appended to the series, unless it's older than the tracked one.
+func (t *seriesLastSampleTimes) set(ref storage.SeriesRef, ts int64) {
+	s := &t.stripes[ref%seriesLastSampleTimesStripes]
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if s.times == nil {
+		s.times = map[storage.SeriesRef]int64{}
+	}
+	if prev, ok := s.times[ref]; !ok || ts > prev {
+		s.times[ref] = ts
+	}
+}
+
+// delete stops tracking the series deleted from the TSDB head.
+func (t *seriesLastSampleTimes) delete(series map[chunks.HeadSeriesRef]labels.Labels) {
+	for ref := range series {
+		s := &t.stripes[storage.SeriesRef(ref)%seriesLastSampleTimesStripes]
+		s.mtx.Lock()
+		delete(s.times, storage.SeriesRef(ref))
+		s.mtx.Unlock()
+	}
+}
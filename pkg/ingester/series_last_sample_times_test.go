@@ -0,0 +1,43 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ingester
+
+import (
+	"testing"
+
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/tsdb/chunks"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSeriesLastSampleTimes(t *testing.T) {
+	var times seriesLastSampleTimes
+
+	_, ok := times.get(1)
+	require.False(t, ok)
+
+	times.set(1, 100)
+	times.set(129, 200)
+
+	ts, ok := times.get(1)
+	require.True(t, ok)
+	require.Equal(t, int64(100), ts)
+
+	// An older timestamp doesn't override the tracked one.
+	times.set(1, 50)
+	ts, _ = times.get(1)
+	require.Equal(t, int64(100), ts)
+
+	times.set(1, 150)
+	ts, _ = times.get(1)
+	require.Equal(t, int64(150), ts)
+
+	// Deleted series are not tracked anymore.
+	times.delete(map[chunks.HeadSeriesRef]labels.Labels{1: labels.FromStrings("a", "1")})
+	_, ok = times.get(1)
+	require.False(t, ok)
+
+	ts, ok = times.get(129)
+	require.True(t, ok)
+	require.Equal(t, int64(200), ts)
+}
@@ -0,0 +1,402 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ingester
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/tsdb"
+	"github.com/prometheus/prometheus/tsdb/index"
+	"github.com/prometheus/prometheus/tsdb/wlog"
+
+	"github.com/grafana/mimir/pkg/util"
+)
+
+const (
+	// tenantDebugBundleCardinalityLimit is the max number of entries of each cardinality statistic in the debug bundle.
+	tenantDebugBundleCardinalityLimit = 100
+
+	scrubLabelValuesParam = "scrub_label_values"
+	headSnapshotParam     = "head_snapshot"
+
+	// tenantDebugBundleHeadSnapshotDir is the directory of the debug bundle where the head snapshot block is written.
+	tenantDebugBundleHeadSnapshotDir = "head-snapshot"
+)
+
+type tenantDebugBundleHead struct {
+	NumSeries  uint64 `json:"num_series"`
+	MinTime    int64  `json:"min_time"`
+	MaxTime    int64  `json:"max_time"`
+	MinOOOTime int64  `json:"min_ooo_time"`
+	MaxOOOTime int64  `json:"max_ooo_time"`
+
+	SeriesCountByMetricName     []index.Stat `json:"series_count_by_metric_name"`
+	LabelValueCountByLabelName  []index.Stat `json:"label_value_count_by_label_name"`
+	MemoryInBytesByLabelName    []index.Stat `json:"memory_in_bytes_by_label_name"`
+	SeriesCountByLabelValuePair []index.Stat `json:"series_count_by_label_value_pair"`
+}
+
+type tenantDebugBundleWAL struct {
+	Enabled      bool  `json:"enabled"`
+	FirstSegment int   `json:"first_segment"`
+	LastSegment  int   `json:"last_segment"`
+	SizeBytes    int64 `json:"size_bytes"`
+}
+
+type tenantDebugBundleLimits struct {
+	InMemorySeries        int     `json:"in_memory_series"`
+	SeriesCountForLimits  int     `json:"series_count_for_limits"`
+	MaxSeriesPerUser      int     `json:"max_series_per_user"`
+	MaxSeriesPerMetric    int     `json:"max_series_per_metric"`
+	MaxNewSeriesPerMinute int     `json:"max_new_series_per_minute"`
+	ActiveSeries          int     `json:"active_series"`
+	ActiveNativeHistogram int     `json:"active_native_histogram_series"`
+	IngestedSamplesRate   float64 `json:"ingested_samples_rate"`
+	IngestedRuleSamples   float64 `json:"ingested_rule_samples_rate"`
+}
+
+// TenantTSDBDebugBundleHandler returns a zip archive with the TSDB Head statistics, the WAL statistics, the limit
+// counters and the series of the tenant's TSDB, so that they can be analyzed offline. The label values, except
+// the metric names, are replaced with their keyed hash if the scrub_label_values parameter is true. The in-order
+// TSDB Head is snapshotted to a TSDB block in the archive if the head_snapshot parameter is true. The head snapshot
+// can't be scrubbed, because it's a TSDB block storing the label values.
+func (i *Ingester) TenantTSDBDebugBundleHandler(w http.ResponseWriter, req *http.Request) {
+	tenant := mux.Vars(req)["tenant"]
+	if tenant == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		util.WriteTextResponse(w, "Tenant ID can't be empty")
+		return
+	}
+
+	scrub, ok := parseDebugBundleBoolParam(w, req, scrubLabelValuesParam)
+	if !ok {
+		return
+	}
+	snapshot, ok := parseDebugBundleBoolParam(w, req, headSnapshotParam)
+	if !ok {
+		return
+	}
+	if scrub && snapshot {
+		w.WriteHeader(http.StatusBadRequest)
+		util.WriteTextResponse(w, fmt.Sprintf("The %s and %s parameters can't be used together, because the head snapshot stores the label values", scrubLabelValuesParam, headSnapshotParam))
+		return
+	}
+
+	var scrubber *labelValueScrubber
+	if scrub {
+		var err error
+		if scrubber, err = newLabelValueScrubber(); err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			util.WriteTextResponse(w, "Failed to create the label values scrubbing key")
+			return
+		}
+	}
+
+	db := i.getTSDB(tenant)
+	if db == nil {
+		w.WriteHeader(http.StatusNotFound)
+		util.WriteTextResponse(w, "TSDB not found for tenant "+tenant)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s-debug-bundle-%s.zip"`, tenant, time.Now().UTC().Format("20060102T150405Z")))
+
+	// The response has already started, so errors can only be logged.
+	if err := i.writeTenantDebugBundle(req.Context(), w, db, scrubber, snapshot); err != nil {
+		level.Warn(i.logger).Log("msg", "failed to write tenant TSDB debug bundle", "user", tenant, "err", err)
+	}
+}
+
+func parseDebugBundleBoolParam(w http.ResponseWriter, req *http.Request, name string) (value, ok bool) {
+	v := req.URL.Query().Get(name)
+	if v == "" {
+		return false, true
+	}
+
+	value, err := strconv.ParseBool(v)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		util.WriteTextResponse(w, fmt.Sprintf("Invalid %s parameter: %s", name, v))
+		return false, false
+	}
+	return value, true
+}
+
+func (i *Ingester) writeTenantDebugBundle(ctx context.Context, w http.ResponseWriter, db *userTSDB, scrubber *labelValueScrubber, snapshot bool) error {
+	archive := zip.NewWriter(w)
+
+	if err := writeDebugBundleJSON(archive, "head.json", tenantDebugBundleHeadStats(db, scrubber)); err != nil {
+		return err
+	}
+
+	walStats, err := tenantDebugBundleWALStats(db)
+	if err != nil {
+		return err
+	}
+	if err := writeDebugBundleJSON(archive, "wal.json", walStats); err != nil {
+		return err
+	}
+
+	if err := writeDebugBundleJSON(archive, "limits.json", tenantDebugBundleLimitStats(db)); err != nil {
+		return err
+	}
+
+	if err := writeDebugBundleSeries(archive, db, scrubber); err != nil {
+		return err
+	}
+
+	if snapshot {
+		if err := writeDebugBundleHeadSnapshot(ctx, archive, db, i.logger); err != nil {
+			return err
+		}
+	}
+
+	return archive.Close()
+}
+
+func tenantDebugBundleHeadStats(db *userTSDB, scrubber *labelValueScrubber) tenantDebugBundleHead {
+	head := db.Head()
+	stats := head.Stats(labels.MetricName, tenantDebugBundleCardinalityLimit)
+
+	res := tenantDebugBundleHead{
+		NumSeries:  stats.NumSeries,
+		MinTime:    stats.MinTime,
+		MaxTime:    stats.MaxTime,
+		MinOOOTime: head.MinOOOTime(),
+		MaxOOOTime: head.MaxOOOTime(),
+
+		SeriesCountByMetricName:     stats.IndexPostingStats.CardinalityMetricsStats,
+		LabelValueCountByLabelName:  stats.IndexPostingStats.CardinalityLabelStats,
+		MemoryInBytesByLabelName:    stats.IndexPostingStats.LabelValueStats,
+		SeriesCountByLabelValuePair: stats.IndexPostingStats.LabelValuePairsStats,
+	}
+
+	if scrubber != nil {
+		pairs := make([]index.Stat, 0, len(res.SeriesCountByLabelValuePair))
+		for _, s := range res.SeriesCountByLabelValuePair {
+			// Label value pairs are formatted as "name=value".
+			if name, value, ok := strings.Cut(s.Name, "="); ok {
+				s.Name = name + "=" + scrubber.scrub(name, value)
+			}
+			pairs = append(pairs, s)
+		}
+		res.SeriesCountByLabelValuePair = pairs
+	}
+
+	return res
+}
+
+func tenantDebugBundleWALStats(db *userTSDB) (tenantDebugBundleWAL, error) {
+	dir := filepath.Join(db.db.Dir(), "wal")
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		return tenantDebugBundleWAL{Enabled: false}, nil
+	}
+
+	first, last, err := wlog.Segments(dir)
+	if err != nil {
+		return tenantDebugBundleWAL{}, errors.Wrap(err, "read WAL segments")
+	}
+
+	size, err := walDirSize(dir)
+	if err != nil {
+		return tenantDebugBundleWAL{}, errors.Wrap(err, "compute WAL size")
+	}
+
+	return tenantDebugBundleWAL{
+		Enabled:      true,
+		FirstSegment: first,
+		LastSegment:  last,
+		SizeBytes:    size,
+	}, nil
+}
+
+func walDirSize(dir string) (int64, error) {
+	var size int64
+	err := filepath.Walk(dir, func(_ string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size, err
+}
+
+func tenantDebugBundleLimitStats(db *userTSDB) tenantDebugBundleLimits {
+	count, minLocalLimit := db.getSeriesCountAndMinLocalLimit()
+	activeSeries, activeNativeHistograms, _ := db.activeSeries.Active()
+
+	return tenantDebugBundleLimits{
+		InMemorySeries:        int(db.Head().NumSeries()),
+		SeriesCountForLimits:  count,
+		MaxSeriesPerUser:      db.limiter.maxSeriesPerUser(db.userID, minLocalLimit),
+		MaxSeriesPerMetric:    db.limiter.maxSeriesPerMetric(db.userID),
+		MaxNewSeriesPerMinute: db.limiter.maxNewSeriesPerMinute(db.userID),
+		ActiveSeries:          activeSeries,
+		ActiveNativeHistogram: activeNativeHistograms,
+		IngestedSamplesRate:   db.ingestedAPISamples.Rate(),
+		IngestedRuleSamples:   db.ingestedRuleSamples.Rate(),
+	}
+}
+
+// writeDebugBundleSeries writes the labels of all the series in the TSDB Head, one series per line.
+func writeDebugBundleSeries(archive *zip.Writer, db *userTSDB, scrubber *labelValueScrubber) error {
+	f, err := archive.Create("series.txt")
+	if err != nil {
+		return err
+	}
+
+	ir, err := db.Head().Index()
+	if err != nil {
+		return errors.Wrap(err, "open TSDB Head index")
+	}
+	defer ir.Close()
+
+	name, value := index.AllPostingsKey()
+	postings, err := ir.Postings(context.Background(), name, value)
+	if err != nil {
+		return errors.Wrap(err, "read TSDB Head postings")
+	}
+
+	var (
+		builder  labels.ScratchBuilder
+		scrubbed labels.ScratchBuilder
+	)
+	for postings.Next() {
+		if err := ir.Series(postings.At(), &builder, nil); err != nil {
+			// The series may have been garbage collected in the meanwhile.
+			continue
+		}
+
+		lbls := builder.Labels()
+		if scrubber != nil {
+			scrubbed.Reset()
+			lbls.Range(func(l labels.Label) {
+				scrubbed.Add(l.Name, scrubber.scrub(l.Name, l.Value))
+			})
+			lbls = scrubbed.Labels()
+		}
+
+		if _, err := fmt.Fprintln(f, lbls.String()); err != nil {
+			return err
+		}
+	}
+
+	return postings.Err()
+}
+
+// writeDebugBundleHeadSnapshot writes the in-order TSDB Head to a TSDB block, and adds the block to the archive.
+// The block is written to a temporary directory, which is removed once the block is added to the archive.
+func writeDebugBundleHeadSnapshot(ctx context.Context, archive *zip.Writer, db *userTSDB, logger log.Logger) error {
+	head := db.Head()
+	mint, maxt := head.MinTime(), head.MaxTime()
+	if mint > maxt {
+		// The TSDB Head is empty.
+		return nil
+	}
+
+	dir, err := os.MkdirTemp("", "tenant-debug-bundle-")
+	if err != nil {
+		return errors.Wrap(err, "create head snapshot directory")
+	}
+	defer os.RemoveAll(dir)
+
+	compactor, err := tsdb.NewLeveledCompactor(ctx, nil, logger, []int64{maxt - mint + 1}, nil, nil)
+	if err != nil {
+		return errors.Wrap(err, "create head snapshot compactor")
+	}
+
+	// The max time of a block is exclusive.
+	if _, err := compactor.Write(dir, tsdb.NewRangeHead(head, mint, maxt), mint, maxt+1, nil); err != nil {
+		return errors.Wrap(err, "write head snapshot")
+	}
+
+	return filepath.Walk(dir, func(file string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+
+		rel, err := filepath.Rel(dir, file)
+		if err != nil {
+			return err
+		}
+		return addDebugBundleFile(archive, path.Join(tenantDebugBundleHeadSnapshotDir, filepath.ToSlash(rel)), file)
+	})
+}
+
+func addDebugBundleFile(archive *zip.Writer, name, file string) error {
+	src, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := archive.Create(name)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(dst, src)
+	return err
+}
+
+func writeDebugBundleJSON(archive *zip.Writer, name string, v any) error {
+	f, err := archive.Create(name)
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// labelValueScrubber replaces the label values with their HMAC, keyed with a random key generated for each
+// debug bundle. Distinct values keep being distinct within a bundle, so the cardinality of the scrubbed series
+// can still be analyzed, while the key isn't known to the recipients of the bundle, so they can't recover
+// low-entropy label values by hashing candidate values.
+type labelValueScrubber struct {
+	key []byte
+}
+
+func newLabelValueScrubber() (*labelValueScrubber, error) {
+	key := make([]byte, sha256.Size)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	return &labelValueScrubber{key: key}, nil
+}
+
+// scrub returns the scrubbed label value, except for the metric name which is kept.
+func (s *labelValueScrubber) scrub(name, value string) string {
+	if name == labels.MetricName {
+		return value
+	}
+
+	h := hmac.New(sha256.New, s.key)
+	h.Write([]byte(value))
+	return hex.EncodeToString(h.Sum(nil)[:8])
+}
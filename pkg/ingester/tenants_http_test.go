@@ -3,16 +3,28 @@
 package ingester
 
 import (
+	"archive/zip"
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/grafana/dskit/services"
 	"github.com/grafana/dskit/test"
+	"github.com/grafana/dskit/user"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/tsdb"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -70,3 +82,130 @@ func TestIngester_TenantsHandlers(t *testing.T) {
 		require.Contains(t, rec.Body.String(), "TSDB not found for tenant unknown")
 	})
 }
+
+func TestIngester_TenantTSDBDebugBundleHandler(t *testing.T) {
+	ctx := context.Background()
+	cfg := defaultIngesterTestConfig(t)
+
+	i, err := prepareIngesterWithBlocksStorage(t, cfg, nil, nil)
+	require.NoError(t, err)
+
+	require.NoError(t, services.StartAndAwaitRunning(ctx, i))
+	defer services.StopAndAwaitTerminated(ctx, i) //nolint:errcheck
+
+	test.Poll(t, 1*time.Second, 1, func() interface{} {
+		return i.lifecycler.HealthyInstancesCount()
+	})
+
+	req, _, _, _ := mockWriteRequest(t, labels.FromStrings(labels.MetricName, "test", "pod", "secret-pod"), 1, time.Now().UnixMilli())
+	_, err = i.Push(user.InjectOrgID(ctx, userID), req)
+	require.NoError(t, err)
+
+	getBundle := func(t *testing.T, tenant, query string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req, err := http.NewRequest("GET", "/tsdb/debug-bundle"+query, nil)
+		require.NoError(t, err)
+
+		req = mux.SetURLVars(req, map[string]string{"tenant": tenant})
+		i.TenantTSDBDebugBundleHandler(rec, req)
+		return rec
+	}
+
+	readBundle := func(t *testing.T, rec *httptest.ResponseRecorder) map[string]string {
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Equal(t, "application/zip", rec.Header().Get("Content-Type"))
+
+		archive, err := zip.NewReader(bytes.NewReader(rec.Body.Bytes()), int64(rec.Body.Len()))
+		require.NoError(t, err)
+
+		files := map[string]string{}
+		for _, f := range archive.File {
+			r, err := f.Open()
+			require.NoError(t, err)
+			content, err := io.ReadAll(r)
+			require.NoError(t, err)
+			require.NoError(t, r.Close())
+			files[f.Name] = string(content)
+		}
+		return files
+	}
+
+	t.Run("bundle for valid tenant", func(t *testing.T) {
+		files := readBundle(t, getBundle(t, userID, ""))
+		require.Len(t, files, 4)
+		for _, name := range []string{"head.json", "wal.json", "limits.json", "series.txt"} {
+			require.Contains(t, files, name)
+		}
+
+		head := tenantDebugBundleHead{}
+		require.NoError(t, json.Unmarshal([]byte(files["head.json"]), &head))
+		assert.Equal(t, uint64(1), head.NumSeries)
+
+		wal := tenantDebugBundleWAL{}
+		require.NoError(t, json.Unmarshal([]byte(files["wal.json"]), &wal))
+		assert.True(t, wal.Enabled)
+
+		limits := tenantDebugBundleLimits{}
+		require.NoError(t, json.Unmarshal([]byte(files["limits.json"]), &limits))
+		assert.Equal(t, 1, limits.InMemorySeries)
+
+		assert.Equal(t, `{__name__="test", pod="secret-pod"}`+"\n", files["series.txt"])
+	})
+
+	t.Run("bundle with scrubbed label values", func(t *testing.T) {
+		files := readBundle(t, getBundle(t, userID, "?scrub_label_values=true"))
+		for name, content := range files {
+			assert.NotContains(t, content, "secret-pod", name)
+		}
+
+		series := regexp.MustCompile(`^\{__name__="test", pod="([0-9a-f]{16})"\}\n$`).FindStringSubmatch(files["series.txt"])
+		require.Len(t, series, 2)
+		assert.Contains(t, files["head.json"], `"Name": "pod=`+series[1]+`"`)
+
+		// The label values are scrubbed with a different key in each bundle.
+		otherFiles := readBundle(t, getBundle(t, userID, "?scrub_label_values=true"))
+		assert.NotEqual(t, files["series.txt"], otherFiles["series.txt"])
+	})
+
+	t.Run("bundle with head snapshot", func(t *testing.T) {
+		files := readBundle(t, getBundle(t, userID, "?head_snapshot=true"))
+
+		dir := t.TempDir()
+		var blockDir string
+		for name, content := range files {
+			rel, ok := strings.CutPrefix(name, tenantDebugBundleHeadSnapshotDir+"/")
+			if !ok {
+				continue
+			}
+			file := filepath.Join(dir, filepath.FromSlash(rel))
+			require.NoError(t, os.MkdirAll(filepath.Dir(file), os.ModePerm))
+			require.NoError(t, os.WriteFile(file, []byte(content), 0o644))
+			if filepath.Base(file) == "meta.json" {
+				blockDir = filepath.Dir(file)
+			}
+		}
+		require.NotEmpty(t, blockDir)
+
+		b, err := tsdb.OpenBlock(nil, blockDir, nil)
+		require.NoError(t, err)
+		t.Cleanup(func() { require.NoError(t, b.Close()) })
+		assert.Equal(t, uint64(1), b.Meta().Stats.NumSeries)
+		assert.Equal(t, uint64(1), b.Meta().Stats.NumSamples)
+	})
+
+	t.Run("invalid scrub parameter", func(t *testing.T) {
+		rec := getBundle(t, userID, "?scrub_label_values=maybe")
+		require.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("head snapshot with scrubbed label values", func(t *testing.T) {
+		rec := getBundle(t, userID, "?scrub_label_values=true&head_snapshot=true")
+		require.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("bundle for unknown tenant", func(t *testing.T) {
+		rec := getBundle(t, "unknown", "")
+		require.Equal(t, http.StatusNotFound, rec.Code)
+		require.Contains(t, rec.Body.String(), "TSDB not found for tenant unknown")
+	})
+}
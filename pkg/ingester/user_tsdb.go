@@ -23,6 +23,7 @@ import (
 	"github.com/prometheus/prometheus/tsdb"
 	"github.com/prometheus/prometheus/tsdb/chunks"
 	"go.uber.org/atomic"
+	"golang.org/x/time/rate"
 
 	"github.com/grafana/mimir/pkg/ingester/activeseries"
 	"github.com/grafana/mimir/pkg/util/extract"
@@ -114,6 +115,9 @@ type userTSDB struct {
 	// Thanos shipper used to upload blocks to the storage.
 	shipper BlocksUploader
 
+	// WAL shipper used to upload the WAL to the storage. Nil if WAL shipping is disabled.
+	walShipper *walShipper
+
 	// When deletion marker is found for the tenant (checked before shipping),
 	// shipping stops and TSDB is closed before reaching idle timeout time (if enabled).
 	deletionMarkFound atomic.Bool
@@ -142,6 +146,13 @@ type userTSDB struct {
 	ownedTokenRanges ring.TokenRanges
 
 	requiresOwnedSeriesUpdate atomic.String // Non-empty string means that we need to recompute "owned series" for the user. Value will be used in the log message.
+
+	// Rate limiter of the series creation, lazily initialized when the new series per minute limit is enabled.
+	newSeriesLimiterMtx sync.Mutex
+	newSeriesLimiter    *rate.Limiter
+
+	// Timestamp of the last sample appended to each series, only tracked when the minimum sample interval is enabled.
+	lastSampleTimes seriesLastSampleTimes
 }
 
 func (u *userTSDB) Appender(ctx context.Context) storage.Appender {
@@ -308,9 +319,30 @@ func (u *userTSDB) PreCreation(metric labels.Labels) error {
 		return globalerror.MaxSeriesPerMetric
 	}
 
+	// New series rate limit. It's checked last, so that series rejected by the other limits don't consume the rate.
+	if limit := u.limiter.maxNewSeriesPerMinute(u.userID); limit > 0 && !u.allowNewSeries(limit) {
+		return globalerror.MaxNewSeriesPerMinute
+	}
+
 	return nil
 }
 
+// allowNewSeries returns whether a new series can be created without exceeding the given per-minute limit.
+func (u *userTSDB) allowNewSeries(limitPerMinute int) bool {
+	u.newSeriesLimiterMtx.Lock()
+	defer u.newSeriesLimiterMtx.Unlock()
+
+	if u.newSeriesLimiter == nil {
+		// The limiter starts with a full burst, so that a tenant can create up to limit series right away.
+		u.newSeriesLimiter = rate.NewLimiter(rate.Limit(float64(limitPerMinute)/60), limitPerMinute)
+	} else if u.newSeriesLimiter.Burst() != limitPerMinute {
+		u.newSeriesLimiter.SetLimit(rate.Limit(float64(limitPerMinute) / 60))
+		u.newSeriesLimiter.SetBurst(limitPerMinute)
+	}
+
+	return u.newSeriesLimiter.Allow()
+}
+
 // getSeriesCountAndMinLocalLimit returns current number of series and minimum local limit that should be used for computing
 // series limit.
 func (u *userTSDB) getSeriesCountAndMinLocalLimit() (int, int) {
@@ -343,6 +375,7 @@ func (u *userTSDB) PostCreation(metric labels.Labels) {
 
 func (u *userTSDB) PostDeletion(metrics map[chunks.HeadSeriesRef]labels.Labels) {
 	u.instanceSeriesCount.Sub(int64(len(metrics)))
+	u.lastSampleTimes.delete(metrics)
 
 	for _, lbls := range metrics {
 		metricName, err := extract.MetricNameFromLabels(lbls)
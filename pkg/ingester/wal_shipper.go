@@ -0,0 +1,398 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ingester
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/grafana/dskit/runutil"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/prometheus/tsdb/record"
+	"github.com/prometheus/prometheus/tsdb/wlog"
+	"github.com/thanos-io/objstore"
+
+	mimir_tsdb "github.com/grafana/mimir/pkg/storage/tsdb"
+)
+
+const (
+	walDirName              = "wal"
+	walCheckpointNamePrefix = "checkpoint."
+
+	// walPartsSuffix is the suffix of the directory holding the parts of the shipped segment being written.
+	walPartsSuffix = ".parts"
+
+	// walShippingOrphansSweepInterval is how often the WAL shipped by the ingesters not in the ring anymore is looked up.
+	walShippingOrphansSweepInterval = time.Hour
+)
+
+// walShipperMetrics holds the WAL shipper metrics. Mimir runs 1 WAL shipper for each tenant but
+// the metrics instance is shared across all tenants.
+type walShipperMetrics struct {
+	uploads                  prometheus.Counter
+	uploadFailures           prometheus.Counter
+	lastSuccessfulUploadTime prometheus.Gauge
+}
+
+func newWALShipperMetrics(reg prometheus.Registerer) *walShipperMetrics {
+	return &walShipperMetrics{
+		uploads: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "cortex_ingester_wal_shipper_uploads_total",
+			Help: "Total number of uploaded WAL segments and checkpoints.",
+		}),
+		uploadFailures: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "cortex_ingester_wal_shipper_upload_failures_total",
+			Help: "Total number of WAL segment and checkpoint upload failures.",
+		}),
+		lastSuccessfulUploadTime: promauto.With(reg).NewGauge(prometheus.GaugeOpts{
+			Name: "cortex_ingester_wal_shipper_last_successful_upload_timestamp_seconds",
+			Help: "Unix timestamp (in seconds) of the last successful WAL segment or checkpoint uploaded to the object storage.",
+		}),
+	}
+}
+
+// shippedSegments is the range of segments of a WAL directory which may be in the storage.
+// Both indexes are -1 if no segment has been shipped.
+type shippedSegments struct {
+	first int
+	last  int
+
+	// active is the segment being written whose parts have been shipped, or -1 if none, and
+	// activeOffset is the size of the segment already shipped.
+	active       int
+	activeOffset int64
+}
+
+// walShipper uploads the WAL and the out-of-order WBL of a TSDB to the storage, so that they can be
+// replayed by a standby ingester. The storage layout mirrors the TSDB directory: the content of
+// <tenant>/wal-shipping/<ingester ID>/ can be downloaded to <tsdb dir>/<tenant>/.
+//
+// Closed segments are never modified afterwards, so each closed segment is uploaded once. The segment being
+// written is append-only, so it's uploaded incrementally: the bytes appended since the previous sync are uploaded
+// to <segment>.parts/<offset>, and the concatenation of the parts is the segment. Once the segment is closed,
+// it's uploaded as a whole and its parts are deleted.
+// The shipped state is only kept in memory: after a restart, the storage is listed once to find the segments
+// to delete, and all local segments are uploaded again.
+// walShipper is not concurrency-safe.
+type walShipper struct {
+	logger     log.Logger
+	instanceID string
+	dir        string
+	userBucket objstore.Bucket
+	bucket     objstore.Bucket
+	metrics    *walShipperMetrics
+
+	shippedSegments   map[string]shippedSegments
+	shippedCheckpoint string
+
+	// The ingesters found missing from the ring in the last orphans sweep.
+	lastOrphansSweep time.Time
+	orphanCandidates map[string]struct{}
+}
+
+// newWALShipper creates a new WAL shipper uploading the WAL of the TSDB in dir to the tenant's userBucket.
+func newWALShipper(logger log.Logger, instanceID string, metrics *walShipperMetrics, dir string, userBucket objstore.Bucket) *walShipper {
+	return &walShipper{
+		logger:          logger,
+		instanceID:      instanceID,
+		dir:             dir,
+		userBucket:      userBucket,
+		bucket:          objstore.NewPrefixedBucket(userBucket, path.Join(mimir_tsdb.WALShippingDir, instanceID)),
+		metrics:         metrics,
+		shippedSegments: map[string]shippedSegments{},
+	}
+}
+
+// Sync uploads the WAL segments closed since the previous call, the bytes appended to the segment being written,
+// and the latest checkpoint. Segments and checkpoints which have been removed from the local WAL are deleted from the storage.
+func (s *walShipper) Sync(ctx context.Context) (uploaded int, err error) {
+	for _, name := range []string{walDirName, wlog.WblDirName} {
+		n, err := s.syncSegments(ctx, name)
+		uploaded += n
+		if err != nil {
+			return uploaded, errors.Wrapf(err, "ship %s", name)
+		}
+	}
+
+	n, err := s.syncCheckpoint(ctx)
+	uploaded += n
+	if err != nil {
+		return uploaded, errors.Wrap(err, "ship WAL checkpoint")
+	}
+
+	return uploaded, nil
+}
+
+func (s *walShipper) syncSegments(ctx context.Context, name string) (uploaded int, err error) {
+	dir := filepath.Join(s.dir, name)
+
+	first, last, err := wlog.Segments(dir)
+	if os.IsNotExist(err) {
+		// The out-of-order WBL is only created once the first out-of-order sample is ingested.
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	if first < 0 {
+		return 0, nil
+	}
+
+	shipped, ok := s.shippedSegments[name]
+	if !ok {
+		// The segments previously shipped by this ingester are unknown, so the storage is listed once.
+		var parts []int
+		shipped, parts, err = s.listShippedSegments(ctx, name)
+		if err != nil {
+			return 0, err
+		}
+
+		// The offsets of the parts shipped before the restart are unknown, so they're deleted: the segments
+		// closed since then are uploaded as a whole, and the segment being written is shipped again from the start.
+		for _, idx := range parts {
+			if err := deleteBucketDir(ctx, s.bucket, walPartsDir(name, idx)+"/"); err != nil {
+				return 0, err
+			}
+		}
+		s.shippedSegments[name] = shipped
+	}
+
+	// The last segment is the one being written.
+	for idx := max(first, shipped.last+1); idx < last; idx++ {
+		segment := wlog.SegmentName(dir, idx)
+		if err := s.upload(ctx, segment, path.Join(name, filepath.Base(segment))); err != nil {
+			return uploaded, err
+		}
+		uploaded++
+
+		// The parts of the segment are superseded by the whole segment.
+		if shipped.active == idx {
+			if err := deleteBucketDir(ctx, s.bucket, walPartsDir(name, idx)+"/"); err != nil {
+				return uploaded, err
+			}
+			shipped.active, shipped.activeOffset = -1, 0
+		}
+
+		shipped.last = idx
+		if shipped.first < 0 {
+			shipped.first = idx
+		}
+		s.shippedSegments[name] = shipped
+	}
+
+	// Segments before the first local one have been truncated, because their data has been compacted to a block.
+	for ; shipped.first >= 0 && shipped.first < first; shipped.first++ {
+		objectName := path.Join(name, filepath.Base(wlog.SegmentName(dir, shipped.first)))
+		if err := s.bucket.Delete(ctx, objectName); err != nil && !s.bucket.IsObjNotFoundErr(err) {
+			s.shippedSegments[name] = shipped
+			return uploaded, err
+		}
+	}
+	s.shippedSegments[name] = shipped
+
+	n, err := s.syncActiveSegment(ctx, name, last)
+	return uploaded + n, err
+}
+
+// syncActiveSegment uploads the bytes appended to the segment being written since the previous sync as a new part.
+func (s *walShipper) syncActiveSegment(ctx context.Context, name string, idx int) (uploaded int, err error) {
+	shipped := s.shippedSegments[name]
+	if shipped.active != idx {
+		shipped.active, shipped.activeOffset = idx, 0
+		s.shippedSegments[name] = shipped
+	}
+
+	f, err := os.Open(wlog.SegmentName(filepath.Join(s.dir, name), idx))
+	if err != nil {
+		return 0, err
+	}
+	defer runutil.CloseWithErrCapture(&err, f, "close WAL segment")
+
+	info, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+	size := info.Size()
+	if size <= shipped.activeOffset {
+		return 0, nil
+	}
+
+	part := path.Join(walPartsDir(name, idx), fmt.Sprintf("%010d", shipped.activeOffset))
+	if err := s.uploadReader(ctx, io.NewSectionReader(f, shipped.activeOffset, size-shipped.activeOffset), part); err != nil {
+		return 0, err
+	}
+
+	shipped.activeOffset = size
+	s.shippedSegments[name] = shipped
+	return 1, nil
+}
+
+// walPartsDir returns the directory holding the parts of the segment idx of the WAL directory name.
+func walPartsDir(name string, idx int) string {
+	return path.Join(name, fmt.Sprintf("%08d", idx)+walPartsSuffix)
+}
+
+// listShippedSegments returns the range of segments of the WAL directory name found in the storage, and the
+// segments whose parts are found in the storage. The segments are not considered uploaded, because they may
+// have been uploaded by a previous version which also shipped the segment being written.
+func (s *walShipper) listShippedSegments(ctx context.Context, name string) (shippedSegments, []int, error) {
+	shipped := shippedSegments{first: -1, last: -1, active: -1}
+	var parts []int
+	err := s.bucket.Iter(ctx, name+"/", func(objectName string) error {
+		base := path.Base(objectName)
+		if segment, ok := strings.CutSuffix(base, walPartsSuffix); ok {
+			if idx, err := strconv.Atoi(segment); err == nil {
+				parts = append(parts, idx)
+			}
+			return nil
+		}
+
+		idx, err := strconv.Atoi(base)
+		if err != nil {
+			return nil
+		}
+		if shipped.first < 0 || idx < shipped.first {
+			shipped.first = idx
+		}
+		return nil
+	})
+	return shipped, parts, err
+}
+
+func (s *walShipper) syncCheckpoint(ctx context.Context) (uploaded int, err error) {
+	checkpointDir, _, err := wlog.LastCheckpoint(filepath.Join(s.dir, walDirName))
+	if errors.Is(err, record.ErrNotFound) || os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	// A checkpoint is never modified once created, so it's only uploaded once.
+	checkpoint := filepath.Base(checkpointDir)
+	if checkpoint == s.shippedCheckpoint {
+		return 0, nil
+	}
+
+	if err := objstore.UploadDir(ctx, s.logger, s.bucket, checkpointDir, path.Join(walDirName, checkpoint)); err != nil {
+		s.metrics.uploadFailures.Inc()
+		return 0, err
+	}
+	s.metrics.uploads.Inc()
+	s.metrics.lastSuccessfulUploadTime.SetToCurrentTime()
+	uploaded++
+
+	// Only the latest checkpoint is required to replay the WAL.
+	var previous []string
+	err = s.bucket.Iter(ctx, walDirName+"/", func(objectName string) error {
+		if name := path.Base(objectName); strings.HasPrefix(name, walCheckpointNamePrefix) && name != checkpoint {
+			previous = append(previous, objectName)
+		}
+		return nil
+	})
+	if err != nil {
+		return uploaded, err
+	}
+	for _, dir := range previous {
+		if err := deleteBucketDir(ctx, s.bucket, dir); err != nil {
+			return uploaded, err
+		}
+	}
+
+	// The checkpoint is considered shipped once the previous ones are deleted, so that the deletion is retried on failure.
+	s.shippedCheckpoint = checkpoint
+	return uploaded, nil
+}
+
+func (s *walShipper) upload(ctx context.Context, src, dst string) error {
+	if err := objstore.UploadFile(ctx, s.logger, s.bucket, src, dst); err != nil {
+		s.metrics.uploadFailures.Inc()
+		return err
+	}
+
+	s.metrics.uploads.Inc()
+	s.metrics.lastSuccessfulUploadTime.SetToCurrentTime()
+	level.Debug(s.logger).Log("msg", "shipped WAL segment", "segment", dst)
+	return nil
+}
+
+func (s *walShipper) uploadReader(ctx context.Context, r io.Reader, dst string) error {
+	if err := s.bucket.Upload(ctx, dst, r); err != nil {
+		s.metrics.uploadFailures.Inc()
+		return err
+	}
+
+	s.metrics.uploads.Inc()
+	s.metrics.lastSuccessfulUploadTime.SetToCurrentTime()
+	level.Debug(s.logger).Log("msg", "shipped WAL segment part", "part", dst)
+	return nil
+}
+
+// DeleteAll deletes the whole shipped WAL from the storage.
+func (s *walShipper) DeleteAll(ctx context.Context) error {
+	s.shippedSegments = map[string]shippedSegments{}
+	s.shippedCheckpoint = ""
+
+	return deleteBucketDir(ctx, s.bucket, "")
+}
+
+// SweepOrphans deletes the WAL shipped by the other ingesters which are not in the ring anymore, for example
+// because they've been scaled down or renamed. The WAL of an ingester is only deleted once the ingester is
+// missing from the ring in two consecutive sweeps, so that the WAL of a restarting ingester is kept.
+// The storage is looked up at most once every walShippingOrphansSweepInterval.
+func (s *walShipper) SweepOrphans(ctx context.Context, inRing func(instanceID string) bool) (deleted int, err error) {
+	if time.Since(s.lastOrphansSweep) < walShippingOrphansSweepInterval {
+		return 0, nil
+	}
+
+	var orphans []string
+	candidates := map[string]struct{}{}
+	err = s.userBucket.Iter(ctx, mimir_tsdb.WALShippingDir+"/", func(dir string) error {
+		instanceID := path.Base(dir)
+		if instanceID == s.instanceID || inRing(instanceID) {
+			return nil
+		}
+		if _, ok := s.orphanCandidates[instanceID]; ok {
+			orphans = append(orphans, dir)
+		} else {
+			candidates[instanceID] = struct{}{}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	for _, dir := range orphans {
+		if err := deleteBucketDir(ctx, s.userBucket, dir); err != nil {
+			return deleted, err
+		}
+		deleted++
+	}
+
+	s.orphanCandidates = candidates
+	s.lastOrphansSweep = time.Now()
+	return deleted, nil
+}
+
+// deleteBucketDir deletes all objects in the bucket under dir. The objects concurrently deleted
+// by other ingesters are ignored.
+func deleteBucketDir(ctx context.Context, bkt objstore.Bucket, dir string) error {
+	return bkt.Iter(ctx, dir, func(name string) error {
+		if err := bkt.Delete(ctx, name); err != nil && !bkt.IsObjNotFoundErr(err) {
+			return err
+		}
+		return nil
+	}, objstore.WithRecursiveIter)
+}
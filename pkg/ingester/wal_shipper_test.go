@@ -0,0 +1,254 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ingester
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/prometheus/tsdb/wlog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/objstore"
+	"go.uber.org/atomic"
+)
+
+func TestWALShipper_Sync(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	bkt := &iterCountingBucket{Bucket: objstore.NewInMemBucket()}
+	reg := prometheus.NewPedanticRegistry()
+
+	w, err := wlog.NewSize(log.NewNopLogger(), nil, filepath.Join(dir, walDirName), wlog.DefaultSegmentSize, wlog.CompressionNone)
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, w.Close()) })
+
+	s := newWALShipper(log.NewNopLogger(), "ingester-1", newWALShipperMetrics(reg), dir, objstore.NewPrefixedBucket(bkt, "user-1"))
+
+	listObjects := func() []string {
+		var names []string
+		require.NoError(t, bkt.Iter(ctx, "", func(name string) error {
+			names = append(names, name)
+			return nil
+		}, objstore.WithRecursiveIter))
+		return names
+	}
+
+	requireShippedSegment := func(name string) {
+		expected, err := os.ReadFile(filepath.Join(dir, name))
+		require.NoError(t, err)
+
+		r, err := bkt.Get(ctx, "user-1/wal-shipping/ingester-1/"+name)
+		require.NoError(t, err)
+		actual, err := io.ReadAll(r)
+		require.NoError(t, err)
+		require.True(t, bytes.Equal(expected, actual), "shipped segment %s doesn't match the local one", name)
+	}
+
+	requireShippedParts := func(name string) {
+		expected, err := os.ReadFile(filepath.Join(dir, name))
+		require.NoError(t, err)
+
+		var actual []byte
+		require.NoError(t, bkt.Iter(ctx, "user-1/wal-shipping/ingester-1/"+name+walPartsSuffix+"/", func(part string) error {
+			r, err := bkt.Get(ctx, part)
+			if err != nil {
+				return err
+			}
+			data, err := io.ReadAll(r)
+			actual = append(actual, data...)
+			return err
+		}))
+		require.True(t, bytes.Equal(expected, actual), "shipped parts of segment %s don't match the local one", name)
+	}
+
+	// The segment being written is shipped incrementally.
+	require.NoError(t, w.Log([]byte("record-1")))
+	uploaded, err := s.Sync(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, uploaded)
+	assert.Equal(t, []string{"user-1/wal-shipping/ingester-1/wal/00000000.parts/0000000000"}, listObjects())
+	requireShippedParts("wal/00000000")
+
+	// Nothing is shipped if the segment being written hasn't changed.
+	uploaded, err = s.Sync(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, uploaded)
+
+	// Only the bytes appended to the segment being written are shipped.
+	require.NoError(t, w.Log([]byte("record-2")))
+	uploaded, err = s.Sync(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, uploaded)
+	assert.Len(t, listObjects(), 2)
+	requireShippedParts("wal/00000000")
+
+	// Closed segments are shipped as a whole, replacing their parts.
+	_, err = w.NextSegment()
+	require.NoError(t, err)
+	require.NoError(t, w.Log([]byte("record-3")))
+	uploaded, err = s.Sync(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, uploaded)
+	assert.Equal(t, []string{
+		"user-1/wal-shipping/ingester-1/wal/00000000",
+		"user-1/wal-shipping/ingester-1/wal/00000001.parts/0000000000",
+	}, listObjects())
+	requireShippedSegment("wal/00000000")
+	requireShippedParts("wal/00000001")
+
+	// Closed segments aren't shipped again.
+	require.NoError(t, w.Log([]byte("record-4")))
+	uploaded, err = s.Sync(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, uploaded)
+	requireShippedParts("wal/00000001")
+
+	_, err = w.NextSegment()
+	require.NoError(t, err)
+	_, err = w.NextSegment()
+	require.NoError(t, err)
+	uploaded, err = s.Sync(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, uploaded)
+	assert.Equal(t, []string{
+		"user-1/wal-shipping/ingester-1/wal/00000000",
+		"user-1/wal-shipping/ingester-1/wal/00000001",
+		"user-1/wal-shipping/ingester-1/wal/00000002",
+	}, listObjects())
+	requireShippedSegment("wal/00000001")
+	requireShippedSegment("wal/00000002")
+
+	// Checkpoints are shipped, and truncated segments are deleted from the storage.
+	writeCheckpoint(t, dir, "checkpoint.00000000")
+	require.NoError(t, w.Truncate(1))
+	_, err = s.Sync(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"user-1/wal-shipping/ingester-1/wal/00000001",
+		"user-1/wal-shipping/ingester-1/wal/00000002",
+		"user-1/wal-shipping/ingester-1/wal/checkpoint.00000000/00000000",
+	}, listObjects())
+
+	// Only the latest checkpoint is kept in the storage.
+	writeCheckpoint(t, dir, "checkpoint.00000001")
+	require.NoError(t, os.RemoveAll(filepath.Join(dir, walDirName, "checkpoint.00000000")))
+	_, err = s.Sync(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"user-1/wal-shipping/ingester-1/wal/00000001",
+		"user-1/wal-shipping/ingester-1/wal/00000002",
+		"user-1/wal-shipping/ingester-1/wal/checkpoint.00000001/00000000",
+	}, listObjects())
+
+	// The storage is only listed on the first sync of each WAL directory, and when a new checkpoint is shipped.
+	bkt.iters.Store(0)
+	require.NoError(t, w.Log([]byte("record-5")))
+	_, err = s.Sync(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), bkt.iters.Load())
+	requireShippedParts("wal/00000003")
+
+	// After a restart, the storage is listed once to find the segments to delete, and the
+	// segment being written is shipped again from the start.
+	s = newWALShipper(log.NewNopLogger(), "ingester-1", newWALShipperMetrics(nil), dir, objstore.NewPrefixedBucket(bkt, "user-1"))
+	require.NoError(t, w.Log([]byte("record-6")))
+	require.NoError(t, w.Truncate(2))
+	_, err = s.Sync(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"user-1/wal-shipping/ingester-1/wal/00000002",
+		"user-1/wal-shipping/ingester-1/wal/00000003.parts/0000000000",
+		"user-1/wal-shipping/ingester-1/wal/checkpoint.00000001/00000000",
+	}, listObjects())
+	requireShippedParts("wal/00000003")
+
+	assert.NoError(t, testutil.GatherAndCompare(reg, bytes.NewBufferString(`
+		# HELP cortex_ingester_wal_shipper_upload_failures_total Total number of WAL segment and checkpoint upload failures.
+		# TYPE cortex_ingester_wal_shipper_upload_failures_total counter
+		cortex_ingester_wal_shipper_upload_failures_total 0
+	`), "cortex_ingester_wal_shipper_upload_failures_total"))
+
+	// The whole shipped WAL is deleted.
+	require.NoError(t, s.DeleteAll(ctx))
+	assert.Empty(t, listObjects())
+}
+
+// iterCountingBucket counts the Iter calls.
+type iterCountingBucket struct {
+	objstore.Bucket
+	iters atomic.Int64
+}
+
+func (b *iterCountingBucket) Iter(ctx context.Context, dir string, f func(string) error, options ...objstore.IterOption) error {
+	b.iters.Inc()
+	return b.Bucket.Iter(ctx, dir, f, options...)
+}
+
+func writeCheckpoint(t *testing.T, dir, name string) {
+	checkpointDir := filepath.Join(dir, walDirName, name)
+	require.NoError(t, os.MkdirAll(checkpointDir, os.ModePerm))
+	require.NoError(t, os.WriteFile(filepath.Join(checkpointDir, "00000000"), []byte("checkpoint"), 0o644))
+}
+
+func TestWALShipper_SweepOrphans(t *testing.T) {
+	ctx := context.Background()
+	bkt := objstore.NewInMemBucket()
+	userBkt := objstore.NewPrefixedBucket(bkt, "user-1")
+
+	for _, instanceID := range []string{"ingester-1", "ingester-2", "ingester-3"} {
+		require.NoError(t, userBkt.Upload(ctx, "wal-shipping/"+instanceID+"/wal/00000000", strings.NewReader("segment")))
+		require.NoError(t, userBkt.Upload(ctx, "wal-shipping/"+instanceID+"/wal/checkpoint.00000000/00000000", strings.NewReader("checkpoint")))
+	}
+
+	s := newWALShipper(log.NewNopLogger(), "ingester-1", newWALShipperMetrics(nil), t.TempDir(), userBkt)
+
+	// ingester-3 is not in the ring anymore, while ingester-1 is this ingester.
+	inRing := func(instanceID string) bool { return instanceID == "ingester-2" }
+
+	listInstances := func() []string {
+		var instances []string
+		require.NoError(t, userBkt.Iter(ctx, "wal-shipping/", func(name string) error {
+			instances = append(instances, path.Base(name))
+			return nil
+		}))
+		return instances
+	}
+
+	// The WAL of an ingester missing from the ring is not deleted on the first sweep.
+	deleted, err := s.SweepOrphans(ctx, inRing)
+	require.NoError(t, err)
+	assert.Equal(t, 0, deleted)
+	assert.Equal(t, []string{"ingester-1", "ingester-2", "ingester-3"}, listInstances())
+
+	// The storage isn't looked up again before the sweep interval.
+	deleted, err = s.SweepOrphans(ctx, inRing)
+	require.NoError(t, err)
+	assert.Equal(t, 0, deleted)
+
+	// The WAL of an ingester which came back to the ring is kept.
+	s.lastOrphansSweep = time.Time{}
+	deleted, err = s.SweepOrphans(ctx, func(string) bool { return true })
+	require.NoError(t, err)
+	assert.Equal(t, 0, deleted)
+	assert.Equal(t, []string{"ingester-1", "ingester-2", "ingester-3"}, listInstances())
+
+	// The WAL of an ingester missing from the ring in two consecutive sweeps is deleted.
+	for i := 0; i < 2; i++ {
+		s.lastOrphansSweep = time.Time{}
+		deleted, err = s.SweepOrphans(ctx, inRing)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 1, deleted)
+	assert.Equal(t, []string{"ingester-1", "ingester-2"}, listInstances())
+}
@@ -0,0 +1,90 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package mimir
+
+import (
+	"context"
+	"sync"
+
+	"github.com/grafana/dskit/kv/memberlist"
+	"github.com/grafana/dskit/ring"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// memberlistConflictsTracker tracks the conflicts resolved while merging the ring updates received through the
+// memberlist KV. When multiple instances own the same token, the ring merge keeps the token only to one of them,
+// so a conflict shows up as a token moving from an instance to another one which are both still in the ring.
+//
+// The memberlist KV doesn't expose its merges, so the tracker watches the rings stored in the KV. It starts
+// watching once the memberlist KV has been initialized by a component using it.
+type memberlistConflictsTracker struct {
+	getKV func() (*memberlist.KV, error)
+	once  sync.Once
+
+	conflicts *prometheus.CounterVec
+}
+
+func newMemberlistConflictsTracker(getKV func() (*memberlist.KV, error), reg prometheus.Registerer) *memberlistConflictsTracker {
+	return &memberlistConflictsTracker{
+		getKV: getKV,
+		conflicts: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "cortex_memberlist_kv_merge_conflicts_total",
+			Help: "Number of ring tokens owned by multiple instances, and assigned to one of them while merging the ring updates received through memberlist.",
+		}, []string{"key"}),
+	}
+}
+
+// GetMemberlistKV returns the memberlist KV, and starts tracking the conflicts the first time it's called.
+func (t *memberlistConflictsTracker) GetMemberlistKV() (*memberlist.KV, error) {
+	kv, err := t.getKV()
+	if err != nil {
+		return kv, err
+	}
+
+	t.once.Do(func() {
+		// Watching ends when the memberlist KV is stopped.
+		go t.watch(context.Background(), kv)
+	})
+	return kv, nil
+}
+
+func (t *memberlistConflictsTracker) watch(ctx context.Context, kv *memberlist.KV) {
+	owners := map[string]map[uint32]string{}
+
+	kv.WatchPrefix(ctx, "", ring.GetCodec(), func(key string, value interface{}) bool {
+		desc, ok := value.(*ring.Desc)
+		if !ok {
+			return true
+		}
+
+		var conflicts int
+		owners[key], conflicts = ringTokenConflicts(owners[key], desc)
+		if conflicts > 0 {
+			t.conflicts.WithLabelValues(key).Add(float64(conflicts))
+		}
+		return true
+	})
+}
+
+// ringTokenConflicts returns the owner of each token of the ring, and the number of tokens which moved from
+// an instance in previousOwners to another one, while the previous owner is still in the ring.
+func ringTokenConflicts(previousOwners map[uint32]string, desc *ring.Desc) (map[uint32]string, int) {
+	owners := make(map[uint32]string, len(previousOwners))
+	conflicts := 0
+
+	for id, instance := range desc.GetIngesters() {
+		for _, token := range instance.GetTokens() {
+			owners[token] = id
+
+			previous, ok := previousOwners[token]
+			if !ok || previous == id {
+				continue
+			}
+			if previousInstance, ok := desc.GetIngesters()[previous]; ok && previousInstance.GetState() != ring.LEFT {
+				conflicts++
+			}
+		}
+	}
+	return owners, conflicts
+}
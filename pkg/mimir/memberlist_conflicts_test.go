@@ -0,0 +1,41 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package mimir
+
+import (
+	"testing"
+	"time"
+
+	"github.com/grafana/dskit/ring"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRingTokenConflicts(t *testing.T) {
+	now := time.Now()
+
+	desc := ring.NewDesc()
+	desc.AddIngester("ingester-1", "127.0.0.1:9095", "", []uint32{1, 2}, ring.ACTIVE, now, false, time.Time{})
+	desc.AddIngester("ingester-2", "127.0.0.2:9095", "", []uint32{3, 4}, ring.ACTIVE, now, false, time.Time{})
+
+	// The first ring has no previous owners to compare with.
+	owners, conflicts := ringTokenConflicts(nil, desc)
+	assert.Equal(t, map[uint32]string{1: "ingester-1", 2: "ingester-1", 3: "ingester-2", 4: "ingester-2"}, owners)
+	assert.Equal(t, 0, conflicts)
+
+	// A new instance taking unowned tokens isn't a conflict.
+	desc.AddIngester("ingester-3", "127.0.0.3:9095", "", []uint32{5}, ring.JOINING, now, false, time.Time{})
+	owners, conflicts = ringTokenConflicts(owners, desc)
+	assert.Equal(t, 0, conflicts)
+
+	// A token moving to another instance, while its previous owner is still in the ring, is a conflict.
+	desc.AddIngester("ingester-1", "127.0.0.1:9095", "", []uint32{1}, ring.ACTIVE, now, false, time.Time{})
+	desc.AddIngester("ingester-3", "127.0.0.3:9095", "", []uint32{2, 5}, ring.JOINING, now, false, time.Time{})
+	owners, conflicts = ringTokenConflicts(owners, desc)
+	assert.Equal(t, 1, conflicts)
+
+	// Tokens taken over from an instance which left the ring aren't conflicts.
+	desc.RemoveIngester("ingester-2")
+	desc.AddIngester("ingester-4", "127.0.0.4:9095", "", []uint32{3, 4}, ring.ACTIVE, now, false, time.Time{})
+	_, conflicts = ringTokenConflicts(owners, desc)
+	assert.Equal(t, 0, conflicts)
+}
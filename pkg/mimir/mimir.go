@@ -23,6 +23,7 @@ import (
 	"github.com/grafana/dskit/flagext"
 	"github.com/grafana/dskit/grpcutil"
 	"github.com/grafana/dskit/kv/memberlist"
+	"github.com/grafana/dskit/middleware"
 	"github.com/grafana/dskit/modules"
 	"github.com/grafana/dskit/multierror"
 	"github.com/grafana/dskit/ring"
@@ -77,6 +78,8 @@ import (
 	util_log "github.com/grafana/mimir/pkg/util/log"
 	"github.com/grafana/mimir/pkg/util/noauth"
 	"github.com/grafana/mimir/pkg/util/process"
+	"github.com/grafana/mimir/pkg/util/tenantmapping"
+	"github.com/grafana/mimir/pkg/util/tenantwebhooks"
 	"github.com/grafana/mimir/pkg/util/tracing"
 	"github.com/grafana/mimir/pkg/util/validation"
 	"github.com/grafana/mimir/pkg/util/validation/exporter"
@@ -142,6 +145,8 @@ type Config struct {
 	UsageStats          usagestats.Config                          `yaml:"usage_stats"`
 	ContinuousTest      continuoustest.Config                      `yaml:"-"`
 	OverridesExporter   exporter.Config                            `yaml:"overrides_exporter"`
+	TenantWebhooks      tenantwebhooks.Config                      `yaml:"tenant_webhooks"`
+	TenantMapping       tenantmapping.Config                       `yaml:"tenant_mapping"`
 
 	Common CommonConfig `yaml:"common"`
 
@@ -201,6 +206,8 @@ func (c *Config) RegisterFlags(f *flag.FlagSet, logger log.Logger) {
 	c.UsageStats.RegisterFlags(f)
 	c.ContinuousTest.RegisterFlags(f)
 	c.OverridesExporter.RegisterFlags(f, logger)
+	c.TenantWebhooks.RegisterFlags(f)
+	c.TenantMapping.RegisterFlags(f)
 
 	c.Common.RegisterFlags(f)
 }
@@ -311,6 +318,12 @@ func (c *Config) Validate(log log.Logger) error {
 	if err := c.OverridesExporter.Validate(); err != nil {
 		return errors.Wrap(err, "invalid overrides-exporter config")
 	}
+	if err := c.TenantWebhooks.Validate(); err != nil {
+		return errors.Wrap(err, "invalid tenant webhooks config")
+	}
+	if err := c.TenantMapping.Validate(); err != nil {
+		return errors.Wrap(err, "invalid tenant mapping config")
+	}
 	// validate the default limits
 	if err := c.ValidateLimits(c.LimitsConfig); err != nil {
 		return err
@@ -324,6 +337,11 @@ func (c *Config) ValidateLimits(limits validation.Limits) error {
 	if err := c.Querier.ValidateLimits(limits); err != nil {
 		return errors.Wrap(err, "invalid limits config for querier")
 	}
+	if c.isAnyModuleEnabled(All, QueryFrontend, Read) {
+		if err := c.Frontend.ValidateLimits(limits); err != nil {
+			return errors.Wrap(err, "invalid limits config for query-frontend")
+		}
+	}
 	return nil
 }
 
@@ -735,6 +753,7 @@ type Mimir struct {
 	UsageStatsReporter              *usagestats.Reporter
 	BlockBuilder                    *blockbuilder.BlockBuilder
 	ContinuousTestManager           *continuoustest.Manager
+	TenantWebhooks                  *tenantwebhooks.Notifier
 	BuildInfoHandler                http.Handler
 }
 
@@ -769,6 +788,10 @@ func New(cfg Config, reg prometheus.Registerer) (*Mimir, error) {
 			"/schedulerpb.SchedulerForQuerier/QuerierLoop",
 			"/schedulerpb.SchedulerForQuerier/NotifyQuerierShutdown",
 		})
+	if cfg.TenantMapping.Enabled() {
+		// The tenant IDs are mapped on both the write and read paths, before any tenant ID validation.
+		cfg.API.HTTPAuthMiddleware = middleware.Merge(cfg.API.HTTPAuthMiddleware, tenantmapping.Middleware(cfg.TenantMapping))
+	}
 
 	// Do not allow to configure potentially unsafe options until we've properly tested them in Mimir.
 	// These configuration options are hidden in the auto-generated documentation (see pkg/util/configdoc).
@@ -504,6 +504,34 @@ func TestConfig_ValidateLimits(t *testing.T) {
 			}(),
 			hasError: true,
 		},
+		{
+			name: "daily query bytes budget with query stats disabled should return error",
+			testConfig: func() *Config {
+				c := newDefaultConfig()
+				c.Frontend.Handler.QueryStatsEnabled = false
+				return c
+			}(),
+			limitsConfig: func() validation.Limits {
+				limits := newDefaultConfig().LimitsConfig
+				limits.MaxQueryBytesPerDay = 1000
+				return limits
+			}(),
+			hasError: true,
+		},
+		{
+			name: "daily query bytes budget with query stats disabled should pass validation when the query-frontend is not running",
+			testConfig: func() *Config {
+				c := newDefaultConfig()
+				c.Target = []string{Querier}
+				c.Frontend.Handler.QueryStatsEnabled = false
+				return c
+			}(),
+			limitsConfig: func() validation.Limits {
+				limits := newDefaultConfig().LimitsConfig
+				limits.MaxQueryBytesPerDay = 1000
+				return limits
+			}(),
+		},
 	} {
 		t.Run(tc.name, func(t *testing.T) {
 			err := tc.testConfig.ValidateLimits(tc.limitsConfig)
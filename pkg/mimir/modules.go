@@ -10,6 +10,7 @@ import (
 	"flag"
 	"fmt"
 	"net/http"
+	"path"
 	"strconv"
 	"time"
 
@@ -19,16 +20,19 @@ import (
 	httpgrpc_server "github.com/grafana/dskit/httpgrpc/server"
 	"github.com/grafana/dskit/kv"
 	"github.com/grafana/dskit/kv/memberlist"
+	"github.com/grafana/dskit/middleware"
 	"github.com/grafana/dskit/modules"
 	"github.com/grafana/dskit/ring"
 	"github.com/grafana/dskit/server"
 	"github.com/grafana/dskit/services"
+	"github.com/grafana/dskit/tenant"
 	"github.com/opentracing-contrib/go-stdlib/nethttp"
 	"github.com/opentracing/opentracing-go"
 	"github.com/pkg/errors"
 	"github.com/prometheus/alertmanager/featurecontrol"
 	"github.com/prometheus/alertmanager/matchers/compat"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
 	"github.com/prometheus/common/config"
 	"github.com/prometheus/prometheus/model/labels"
 	"github.com/prometheus/prometheus/rules"
@@ -56,11 +60,15 @@ import (
 	"github.com/grafana/mimir/pkg/scheduler"
 	"github.com/grafana/mimir/pkg/storage/bucket"
 	"github.com/grafana/mimir/pkg/storage/ingest"
+	"github.com/grafana/mimir/pkg/storage/tsdb"
+	"github.com/grafana/mimir/pkg/storage/tsdb/bucketindex"
 	"github.com/grafana/mimir/pkg/storegateway"
 	"github.com/grafana/mimir/pkg/usagestats"
 	"github.com/grafana/mimir/pkg/util"
 	"github.com/grafana/mimir/pkg/util/activitytracker"
 	util_log "github.com/grafana/mimir/pkg/util/log"
+	"github.com/grafana/mimir/pkg/util/tenantmapping"
+	"github.com/grafana/mimir/pkg/util/tenantwebhooks"
 	"github.com/grafana/mimir/pkg/util/validation"
 	"github.com/grafana/mimir/pkg/util/validation/exporter"
 	"github.com/grafana/mimir/pkg/util/version"
@@ -101,6 +109,7 @@ const (
 	Vault                           string = "vault"
 	TenantFederation                string = "tenant-federation"
 	UsageStats                      string = "usage-stats"
+	TenantWebhooks                  string = "tenant-webhooks"
 	BlockBuilder                    string = "block-builder"
 	ContinuousTest                  string = "continuous-test"
 	All                             string = "all"
@@ -143,7 +152,7 @@ func (t *Mimir) initAPI() (services.Service, error) {
 		})
 
 	t.API = a
-	t.API.RegisterAPI(t.Cfg.Server.PathPrefix, t.Cfg, newDefaultConfig(), t.BuildInfoHandler)
+	t.API.RegisterAPI(t.Cfg.Server.PathPrefix, t.Cfg, newDefaultConfig(), flag.CommandLine, t.BuildInfoHandler)
 
 	return nil, nil
 }
@@ -188,6 +197,40 @@ func (t *Mimir) initActivityTracker() (services.Service, error) {
 	}), nil
 }
 
+func (t *Mimir) initTenantWebhooks() (services.Service, error) {
+	if t.Cfg.TenantWebhooks.URL == "" {
+		return nil, nil
+	}
+
+	// The tenants already notified are recorded in the blocks storage, so that they're notified once across replicas and restarts.
+	bucketClient, err := bucket.NewClient(context.Background(), t.Cfg.BlocksStorage.Bucket, TenantWebhooks, util_log.Logger, t.Registerer)
+	if err != nil {
+		return nil, errors.Wrapf(err, "create %s bucket client", TenantWebhooks)
+	}
+	listTenants := func(ctx context.Context) ([]string, error) {
+		return tsdb.ListUsers(ctx, bucketClient)
+	}
+	markersBucketClient := bucket.NewPrefixedBucketClient(bucketClient, path.Join(bucket.MimirInternalsPrefix, tenantwebhooks.BucketPrefix))
+
+	t.TenantWebhooks = tenantwebhooks.NewNotifier(t.Cfg.TenantWebhooks, markersBucketClient, listTenants, util_log.Logger, t.Registerer)
+	return t.TenantWebhooks, nil
+}
+
+// tenantWebhooksPushWrapper returns a distributor.PushWrapper notifying the first write of each tenant.
+func tenantWebhooksPushWrapper(n *tenantwebhooks.Notifier) distributor.PushWrapper {
+	return func(next distributor.PushFunc) distributor.PushFunc {
+		return func(ctx context.Context, req *distributor.Request) error {
+			err := next(ctx, req)
+			if err == nil {
+				if userID, tenantErr := tenant.TenantID(ctx); tenantErr == nil {
+					n.Notify(userID, tenantwebhooks.EventFirstWrite)
+				}
+			}
+			return err
+		}
+	}
+}
+
 func (t *Mimir) initVault() (services.Service, error) {
 	if !t.Cfg.Vault.Enabled {
 		return nil, nil
@@ -392,7 +435,7 @@ func (t *Mimir) initRuntimeConfig() (services.Service, error) {
 	}
 
 	t.RuntimeConfig = serv
-	t.API.RegisterRuntimeConfig(runtimeConfigHandler(t.RuntimeConfig, t.Cfg.LimitsConfig), validation.UserLimitsHandler(t.Cfg.LimitsConfig, t.TenantLimits))
+	t.API.RegisterRuntimeConfig(runtimeConfigHandler(t.RuntimeConfig, t.Cfg.LimitsConfig), validation.UserLimitsHandler(t.Cfg.LimitsConfig, t.TenantLimits), runtimeConfigValuesFunc(t.RuntimeConfig))
 
 	// Update config fields using runtime config. Only if multiKV is used for given ring these returned functions will be
 	// called and register the listener.
@@ -460,6 +503,10 @@ func (t *Mimir) initDistributorService() (serv services.Service, err error) {
 	t.Cfg.Distributor.PreferAvailabilityZone = t.Cfg.Querier.PreferAvailabilityZone
 	t.Cfg.Distributor.IngestStorageConfig = t.Cfg.IngestStorage
 
+	if t.TenantWebhooks != nil {
+		t.Cfg.Distributor.PushWrappers = append(t.Cfg.Distributor.PushWrappers, tenantWebhooksPushWrapper(t.TenantWebhooks))
+	}
+
 	t.Distributor, err = distributor.New(t.Cfg.Distributor, t.Cfg.IngesterClient, t.Overrides, t.ActiveGroupsCleanup, t.IngesterRing, t.IngesterPartitionInstanceRing, canJoinDistributorsRing, t.Registerer, util_log.Logger)
 	if err != nil {
 		return
@@ -491,6 +538,11 @@ func (t *Mimir) initQueryable() (serv services.Service, err error) {
 		return nil, fmt.Errorf("could not create queryable: %w", err)
 	}
 
+	if t.Cfg.TenantMapping.Enabled() {
+		// The queries of a renamed tenant read the data stored under its former tenant IDs too.
+		t.QuerierQueryable = querier.NewSampleAndChunkQueryable(tenantmapping.NewQueryable(t.Cfg.TenantMapping, t.QuerierQueryable))
+	}
+
 	// Use the distributor to return metric metadata by default
 	t.MetadataSupplier = t.Distributor
 
@@ -573,6 +625,14 @@ func (t *Mimir) initQuerier() (serv services.Service, err error) {
 	t.Cfg.Worker.MaxConcurrentRequests = t.Cfg.Querier.EngineConfig.MaxConcurrent
 	t.Cfg.Worker.QuerySchedulerDiscovery = t.Cfg.QueryScheduler.ServiceDiscovery
 
+	var instantQueryCache middleware.Interface
+	if t.Cfg.Querier.InstantQueryCacheTTL > 0 {
+		instantQueryCache, err = querier.NewInstantQueryCache(t.Cfg.Querier.InstantQueryCacheTTL, t.Cfg.Querier.InstantQueryCacheMaxEntries, t.Registerer)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	// Create an internal HTTP handler that is configured with the Prometheus API routes and points
 	// to a Prometheus API struct instantiated with the Mimir Queryable.
 	internalQuerierRouter := api.NewQuerierHandler(
@@ -585,6 +645,8 @@ func (t *Mimir) initQuerier() (serv services.Service, err error) {
 		t.Registerer,
 		util_log.Logger,
 		t.Overrides,
+		instantQueryCache,
+		t.Cfg.Querier.EngineConfig.LookbackDelta,
 	)
 
 	// If the querier is running standalone without the query-frontend or query-scheduler, we must register it's internal
@@ -592,7 +654,11 @@ func (t *Mimir) initQuerier() (serv services.Service, err error) {
 	// to ensure requests it processes use the default middleware instrumentation.
 	if !t.Cfg.isAnyModuleEnabled(QueryFrontend, QueryScheduler, Read, All) {
 		// First, register the internal querier handler with the external HTTP server
-		t.API.RegisterQueryAPI(internalQuerierRouter, t.BuildInfoHandler)
+		externalQuerierRouter := internalQuerierRouter
+		if t.TenantWebhooks != nil {
+			externalQuerierRouter = t.TenantWebhooks.Middleware(tenantwebhooks.EventFirstQuery).Wrap(externalQuerierRouter)
+		}
+		t.API.RegisterQueryAPI(externalQuerierRouter, t.BuildInfoHandler)
 
 		// Second, set the http.Handler that the frontend worker will use to process requests to point to
 		// the external HTTP server. This will allow the querier to consolidate query metrics both external
@@ -651,6 +717,7 @@ func (t *Mimir) initIngesterService() (serv services.Service, err error) {
 	t.Cfg.Ingester.StreamTypeFn = ingesterChunkStreaming(t.RuntimeConfig)
 	t.Cfg.Ingester.InstanceLimitsFn = ingesterInstanceLimits(t.RuntimeConfig)
 	t.Cfg.Ingester.IngestStorageConfig = t.Cfg.IngestStorage
+	t.Cfg.Ingester.PushDecodingMaxMessageSize = t.Cfg.Server.GRPCServerMaxRecvMsgSize
 	t.tsdbIngesterConfig()
 
 	t.Ingester, err = ingester.New(t.Cfg.Ingester, t.Overrides, t.IngesterRing, t.IngesterPartitionRingWatcher, t.ActiveGroupsCleanup, t.Registerer, util_log.Logger)
@@ -735,6 +802,18 @@ func (t *Mimir) initQueryFrontendTripperware() (serv services.Service, err error
 
 	engineOpts, _, engineExperimentalFunctionsEnabled := engine.NewPromQLEngineOptions(t.Cfg.Querier.EngineConfig, t.ActivityTracker, util_log.Logger, promqlEngineRegisterer)
 
+	if t.Cfg.Frontend.QueryMiddleware.PruneQueriesByCompactionSummary {
+		bucketClient, err := bucket.NewClient(context.Background(), t.Cfg.BlocksStorage.Bucket, "query-frontend", util_log.Logger, t.Registerer)
+		if err != nil {
+			return nil, errors.Wrap(err, "create query-frontend bucket client")
+		}
+
+		t.Cfg.Frontend.QueryMiddleware.CompactionSummaryReader = bucketindex.NewSummaryReader(bucketClient, t.Overrides, t.Cfg.BlocksStorage.BucketStore.SyncInterval, util_log.Logger)
+		t.Cfg.Frontend.QueryMiddleware.CompactionSummaryMaxStalePeriod = t.Cfg.BlocksStorage.BucketStore.BucketIndex.MaxStalePeriod
+	}
+
+	t.Cfg.Frontend.QueryMiddleware.QueryStoreAfter = t.Cfg.Querier.QueryStoreAfter
+
 	tripperware, err := querymiddleware.NewTripperware(
 		t.Cfg.Frontend.QueryMiddleware,
 		util_log.Logger,
@@ -791,7 +870,11 @@ func (t *Mimir) initQueryFrontend() (serv services.Service, err error) {
 	}
 
 	handler := transport.NewHandler(t.Cfg.Frontend.Handler, roundTripper, util_log.Logger, t.Registerer, t.ActivityTracker)
-	t.API.RegisterQueryFrontendHandler(handler, t.BuildInfoHandler)
+	if t.TenantWebhooks != nil {
+		t.API.RegisterQueryFrontendHandler(t.TenantWebhooks.Middleware(tenantwebhooks.EventFirstQuery).Wrap(handler), t.BuildInfoHandler)
+	} else {
+		t.API.RegisterQueryFrontendHandler(handler, t.BuildInfoHandler)
+	}
 
 	w := services.NewFailureWatcher()
 	return services.NewBasicService(func(_ context.Context) error {
@@ -846,22 +929,10 @@ func (t *Mimir) initRuler() (serv services.Service, err error) {
 	var embeddedQueryable prom_storage.Queryable
 	var queryFunc rules.QueryFunc
 
-	if t.Cfg.Ruler.QueryFrontend.Address != "" {
-		queryFrontendClient, err := ruler.DialQueryFrontend(t.Cfg.Ruler.QueryFrontend)
-		if err != nil {
-			return nil, err
-		}
-		remoteQuerier := ruler.NewRemoteQuerier(queryFrontendClient, t.Cfg.Querier.EngineConfig.Timeout, t.Cfg.Ruler.QueryFrontend.QueryResultResponseFormat, t.Cfg.API.PrometheusHTTPPrefix, util_log.Logger, ruler.WithOrgIDMiddleware)
+	remoteEvaluationEnabled := t.Cfg.Ruler.QueryFrontend.Address != ""
+	longLookbackOffloadingEnabled := remoteEvaluationEnabled && t.Cfg.Ruler.QueryFrontend.LongLookbackOffloadingThreshold > 0
 
-		embeddedQueryable = prom_remote.NewSampleAndChunkQueryableClient(
-			remoteQuerier,
-			labels.Labels{},
-			nil,
-			true,
-			func() (int64, error) { return 0, nil },
-		)
-		queryFunc = remoteQuerier.Query
-	} else {
+	if !remoteEvaluationEnabled || longLookbackOffloadingEnabled {
 		var queryable, federatedQueryable prom_storage.Queryable
 
 		// TODO: Consider wrapping logger to differentiate from querier module logger
@@ -872,6 +943,7 @@ func (t *Mimir) initRuler() (serv services.Service, err error) {
 			return nil, fmt.Errorf("could not create queryable for ruler: %w", err)
 		}
 
+		queryable = tenantmapping.NewQueryable(t.Cfg.TenantMapping, queryable)
 		queryable = querier.NewErrorTranslateQueryableWithFn(queryable, ruler.WrapQueryableErrors)
 
 		if t.Cfg.Ruler.TenantFederation.Enabled {
@@ -897,6 +969,33 @@ func (t *Mimir) initRuler() (serv services.Service, err error) {
 		}
 	}
 
+	if remoteEvaluationEnabled {
+		queryFrontendClient, err := ruler.DialQueryFrontend(t.Cfg.Ruler.QueryFrontend)
+		if err != nil {
+			return nil, err
+		}
+		remoteQuerier := ruler.NewRemoteQuerier(queryFrontendClient, t.Cfg.Querier.EngineConfig.Timeout, t.Cfg.Ruler.QueryFrontend.QueryResultResponseFormat, t.Cfg.API.PrometheusHTTPPrefix, util_log.Logger, ruler.WithOrgIDMiddleware)
+
+		if longLookbackOffloadingEnabled {
+			// Only the expressions with a long lookback are offloaded to the query-frontend,
+			// while the embedded querier is used for everything else.
+			offloadedQueries := promauto.With(t.Registerer).NewCounter(prometheus.CounterOpts{
+				Name: "cortex_ruler_long_lookback_queries_offloaded_total",
+				Help: "Number of rule queries evaluated through the query-frontend because their lookback exceeds the configured threshold.",
+			})
+			queryFunc = ruler.LongLookbackOffloadingQueryFunc(queryFunc, remoteQuerier.Query, t.Cfg.Ruler.QueryFrontend.LongLookbackOffloadingThreshold, offloadedQueries)
+		} else {
+			embeddedQueryable = prom_remote.NewSampleAndChunkQueryableClient(
+				remoteQuerier,
+				labels.Labels{},
+				nil,
+				true,
+				func() (int64, error) { return 0, nil },
+			)
+			queryFunc = remoteQuerier.Query
+		}
+	}
+
 	var concurrencyController ruler.MultiTenantRuleConcurrencyController
 	concurrencyController = &ruler.NoopMultiTenantConcurrencyController{}
 	if t.Cfg.Ruler.MaxIndependentRuleEvaluationConcurrency > 0 {
@@ -951,7 +1050,7 @@ func (t *Mimir) initRuler() (serv services.Service, err error) {
 	t.API.RegisterRuler(t.Ruler)
 
 	// Expose HTTP configuration and prometheus-compatible Ruler APIs
-	t.API.RegisterRulerAPI(ruler.NewAPI(t.Ruler, t.RulerDirectStorage, util_log.Logger), t.Cfg.Ruler.EnableAPI, t.BuildInfoHandler)
+	t.API.RegisterRulerAPI(ruler.NewAPI(t.Ruler, t.RulerDirectStorage, ruler.NewDryRunEvaluator(t.Cfg.Ruler, queryFunc, t.Overrides, util_log.Logger), util_log.Logger), t.Cfg.Ruler.EnableAPI, t.BuildInfoHandler)
 
 	return t.Ruler, nil
 }
@@ -996,6 +1095,12 @@ func (t *Mimir) initAlertManager() (serv services.Service, err error) {
 func (t *Mimir) initCompactor() (serv services.Service, err error) {
 	t.Cfg.Compactor.ShardingRing.Common.ListenPort = t.Cfg.Server.GRPCListenPort
 
+	if t.TenantWebhooks != nil {
+		t.Cfg.Compactor.TenantDeletedFn = func(userID string) {
+			t.TenantWebhooks.Notify(userID, tenantwebhooks.EventDeleted)
+		}
+	}
+
 	t.Compactor, err = compactor.NewMultitenantCompactor(t.Cfg.Compactor, t.Cfg.BlocksStorage, t.Overrides, util_log.Logger, t.Registerer)
 	if err != nil {
 		return
@@ -1023,6 +1128,8 @@ func (t *Mimir) initMemberlistKV() (services.Service, error) {
 	// Append to the list of codecs instead of overwriting the value to allow third parties to inject their own codecs.
 	t.Cfg.MemberlistKV.Codecs = append(t.Cfg.MemberlistKV.Codecs, ring.GetCodec())
 	t.Cfg.MemberlistKV.Codecs = append(t.Cfg.MemberlistKV.Codecs, ring.GetPartitionRingCodec())
+	t.Cfg.MemberlistKV.Codecs = append(t.Cfg.MemberlistKV.Codecs, distributor.GetIngestionRatesCodec())
+	t.Cfg.MemberlistKV.Codecs = append(t.Cfg.MemberlistKV.Codecs, ingester.GetMemoryUtilizationCodec())
 
 	dnsProviderReg := prometheus.WrapRegistererWithPrefix(
 		"cortex_",
@@ -1035,16 +1142,19 @@ func (t *Mimir) initMemberlistKV() (services.Service, error) {
 	t.MemberlistKV = memberlist.NewKVInitService(&t.Cfg.MemberlistKV, util_log.Logger, dnsProvider, t.Registerer)
 	t.API.RegisterMemberlistKV(t.Cfg.Server.PathPrefix, t.MemberlistKV)
 
+	// The merge conflicts are tracked once the memberlist KV is initialized by a component using it.
+	getMemberlistKV := newMemberlistConflictsTracker(t.MemberlistKV.GetMemberlistKV, t.Registerer).GetMemberlistKV
+
 	// Update the config.
-	t.Cfg.Distributor.DistributorRing.Common.KVStore.MemberlistKV = t.MemberlistKV.GetMemberlistKV
-	t.Cfg.Ingester.IngesterRing.KVStore.MemberlistKV = t.MemberlistKV.GetMemberlistKV
-	t.Cfg.Ingester.IngesterPartitionRing.KVStore.MemberlistKV = t.MemberlistKV.GetMemberlistKV
-	t.Cfg.StoreGateway.ShardingRing.KVStore.MemberlistKV = t.MemberlistKV.GetMemberlistKV
-	t.Cfg.Compactor.ShardingRing.Common.KVStore.MemberlistKV = t.MemberlistKV.GetMemberlistKV
-	t.Cfg.Ruler.Ring.Common.KVStore.MemberlistKV = t.MemberlistKV.GetMemberlistKV
-	t.Cfg.Alertmanager.ShardingRing.Common.KVStore.MemberlistKV = t.MemberlistKV.GetMemberlistKV
-	t.Cfg.QueryScheduler.ServiceDiscovery.SchedulerRing.KVStore.MemberlistKV = t.MemberlistKV.GetMemberlistKV
-	t.Cfg.OverridesExporter.Ring.Common.KVStore.MemberlistKV = t.MemberlistKV.GetMemberlistKV
+	t.Cfg.Distributor.DistributorRing.Common.KVStore.MemberlistKV = getMemberlistKV
+	t.Cfg.Ingester.IngesterRing.KVStore.MemberlistKV = getMemberlistKV
+	t.Cfg.Ingester.IngesterPartitionRing.KVStore.MemberlistKV = getMemberlistKV
+	t.Cfg.StoreGateway.ShardingRing.KVStore.MemberlistKV = getMemberlistKV
+	t.Cfg.Compactor.ShardingRing.Common.KVStore.MemberlistKV = getMemberlistKV
+	t.Cfg.Ruler.Ring.Common.KVStore.MemberlistKV = getMemberlistKV
+	t.Cfg.Alertmanager.ShardingRing.Common.KVStore.MemberlistKV = getMemberlistKV
+	t.Cfg.QueryScheduler.ServiceDiscovery.SchedulerRing.KVStore.MemberlistKV = getMemberlistKV
+	t.Cfg.OverridesExporter.Ring.Common.KVStore.MemberlistKV = getMemberlistKV
 
 	return t.MemberlistKV, nil
 }
@@ -1148,6 +1258,7 @@ func (t *Mimir) setupModuleManager() error {
 	mm.RegisterModule(BlockBuilder, t.initBlockBuilder)
 	mm.RegisterModule(ContinuousTest, t.initContinuousTest)
 	mm.RegisterModule(Vault, t.initVault, modules.UserInvisibleModule)
+	mm.RegisterModule(TenantWebhooks, t.initTenantWebhooks, modules.UserInvisibleModule)
 	mm.RegisterModule(Write, nil)
 	mm.RegisterModule(Read, nil)
 	mm.RegisterModule(Backend, nil)
@@ -1164,21 +1275,21 @@ func (t *Mimir) setupModuleManager() error {
 		Overrides:                       {RuntimeConfig},
 		OverridesExporter:               {Overrides, MemberlistKV, Vault},
 		Distributor:                     {DistributorService, API, ActiveGroupsCleanupService, Vault},
-		DistributorService:              {IngesterRing, IngesterPartitionRing, Overrides, Vault},
+		DistributorService:              {IngesterRing, IngesterPartitionRing, Overrides, Vault, TenantWebhooks},
 		Ingester:                        {IngesterService, API, ActiveGroupsCleanupService, Vault},
 		IngesterService:                 {IngesterRing, IngesterPartitionRing, Overrides, RuntimeConfig, MemberlistKV},
 		Flusher:                         {Overrides, API},
 		Queryable:                       {Overrides, DistributorService, IngesterRing, IngesterPartitionRing, API, StoreQueryable, MemberlistKV},
-		Querier:                         {TenantFederation, Vault},
+		Querier:                         {TenantFederation, Vault, TenantWebhooks},
 		StoreQueryable:                  {Overrides, MemberlistKV},
 		QueryFrontendTripperware:        {API, Overrides, QueryFrontendCodec, QueryFrontendTopicOffsetsReader},
-		QueryFrontend:                   {QueryFrontendTripperware, MemberlistKV, Vault},
+		QueryFrontend:                   {QueryFrontendTripperware, MemberlistKV, Vault, TenantWebhooks},
 		QueryFrontendTopicOffsetsReader: {IngesterPartitionRing},
 		QueryScheduler:                  {API, Overrides, MemberlistKV, Vault},
 		Ruler:                           {DistributorService, StoreQueryable, RulerStorage, Vault},
 		RulerStorage:                    {Overrides},
 		AlertManager:                    {API, MemberlistKV, Overrides, Vault},
-		Compactor:                       {API, MemberlistKV, Overrides, Vault},
+		Compactor:                       {API, MemberlistKV, Overrides, Vault, TenantWebhooks},
 		StoreGateway:                    {API, Overrides, MemberlistKV, Vault},
 		TenantFederation:                {Queryable},
 		BlockBuilder:                    {API, Overrides},
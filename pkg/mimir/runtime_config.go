@@ -6,9 +6,12 @@
 package mimir
 
 import (
+	"bytes"
 	"errors"
+	"fmt"
 	"io"
 	"net/http"
+	"slices"
 
 	"github.com/go-kit/log"
 	"github.com/grafana/dskit/kv"
@@ -32,6 +35,10 @@ var (
 type runtimeConfigValues struct {
 	TenantLimits map[string]*validation.Limits `yaml:"overrides"`
 
+	// OverridesGroups are named sets of limits that tenants can inherit from. Groups can be nested
+	// through their parent (e.g. org -> team), with the nearest group taking precedence.
+	OverridesGroups map[string]*overridesGroup `yaml:"overrides_groups"`
+
 	Multi kv.MultiRuntimeConfig `yaml:"multi_kv_config"`
 
 	IngesterChunkStreaming *bool `yaml:"ingester_stream_chunks_when_using_blocks"`
@@ -40,6 +47,27 @@ type runtimeConfigValues struct {
 	DistributorLimits *distributor.InstanceLimits `yaml:"distributor_limits"`
 }
 
+// overridesGroup is a named set of per-tenant limits, inherited by the tenants listed in it and by all its child groups.
+type overridesGroup struct {
+	Parent  string   `yaml:"parent,omitempty"`
+	Tenants []string `yaml:"tenants,omitempty"`
+	Limits  *rawYAML `yaml:"limits,omitempty"`
+}
+
+// rawYAML keeps the YAML node as is, postponing its decoding.
+type rawYAML struct {
+	node yaml.Node
+}
+
+func (r *rawYAML) UnmarshalYAML(value *yaml.Node) error {
+	r.node = *value
+	return nil
+}
+
+func (r *rawYAML) MarshalYAML() (interface{}, error) {
+	return &r.node, nil
+}
+
 // runtimeConfigTenantLimits provides per-tenant limit overrides based on a runtimeconfig.Manager
 // that reads limits from a configuration file on disk and periodically reloads them.
 type runtimeConfigTenantLimits struct {
@@ -75,7 +103,13 @@ type runtimeConfigLoader struct {
 func (l *runtimeConfigLoader) load(r io.Reader) (interface{}, error) {
 	var overrides = &runtimeConfigValues{}
 
-	decoder := yaml.NewDecoder(r)
+	// Keep a copy of the raw config, because it may be required to resolve the overrides groups.
+	raw, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(raw))
 	decoder.KnownFields(true)
 
 	// Decode the first document. An empty document (EOF) is OK.
@@ -88,6 +122,12 @@ func (l *runtimeConfigLoader) load(r io.Reader) (interface{}, error) {
 		return nil, errMultipleDocuments
 	}
 
+	if len(overrides.OverridesGroups) > 0 {
+		if err := resolveOverridesGroups(overrides, raw); err != nil {
+			return nil, err
+		}
+	}
+
 	if l.validate != nil {
 		for _, limits := range overrides.TenantLimits {
 			if limits == nil {
@@ -102,6 +142,142 @@ func (l *runtimeConfigLoader) load(r io.Reader) (interface{}, error) {
 	return overrides, nil
 }
 
+// resolveOverridesGroups computes the limits of each tenant belonging to an overrides group. The precedence of
+// each limit, from the highest to the lowest, is: the tenant's own overrides, the group listing the tenant,
+// the group's ancestors (from the nearest to the farthest) and finally the default limits.
+func resolveOverridesGroups(cfg *runtimeConfigValues, raw []byte) error {
+	// The tenants' overrides have already been decoded, but the raw YAML is needed to know which limits
+	// have been explicitly set for each tenant.
+	var rawOverrides struct {
+		TenantLimits map[string]rawYAML `yaml:"overrides"`
+	}
+	if err := yaml.Unmarshal(raw, &rawOverrides); err != nil {
+		return err
+	}
+
+	tenantGroups := map[string]string{}
+	for name, group := range cfg.OverridesGroups {
+		if group == nil {
+			return fmt.Errorf("invalid overrides group %q: the group is empty", name)
+		}
+
+		// Ensure the group's limits are valid on their own, so that a misconfiguration
+		// is reported even if the group has no tenants.
+		if group.Limits != nil {
+			if err := decodeYAMLNodeStrict(&group.Limits.node, &validation.Limits{}); err != nil {
+				return fmt.Errorf("invalid limits in overrides group %q: %w", name, err)
+			}
+		}
+
+		for _, tenantID := range group.Tenants {
+			if other, ok := tenantGroups[tenantID]; ok {
+				return fmt.Errorf("tenant %q belongs to multiple overrides groups: %q and %q", tenantID, other, name)
+			}
+			tenantGroups[tenantID] = name
+		}
+	}
+
+	// Ensure the hierarchy of every group is valid, including the groups without tenants,
+	// so that the misconfiguration is not only reported once a tenant is assigned to them.
+	groupNames := make([]string, 0, len(cfg.OverridesGroups))
+	for name := range cfg.OverridesGroups {
+		groupNames = append(groupNames, name)
+	}
+	slices.Sort(groupNames)
+	for _, name := range groupNames {
+		if _, err := overridesGroupsChain(cfg.OverridesGroups, name); err != nil {
+			return err
+		}
+	}
+
+	if cfg.TenantLimits == nil {
+		cfg.TenantLimits = map[string]*validation.Limits{}
+	}
+
+	for tenantID, groupName := range tenantGroups {
+		chain, err := overridesGroupsChain(cfg.OverridesGroups, groupName)
+		if err != nil {
+			return err
+		}
+
+		// Merge the limits from the farthest ancestor to the tenant's own overrides,
+		// so that the latest merged value wins.
+		nodes := make([]*yaml.Node, 0, len(chain)+1)
+		for i := len(chain) - 1; i >= 0; i-- {
+			if chain[i].Limits != nil {
+				nodes = append(nodes, &chain[i].Limits.node)
+			}
+		}
+		if tenantLimits, ok := rawOverrides.TenantLimits[tenantID]; ok {
+			nodes = append(nodes, &tenantLimits.node)
+		}
+
+		limits := &validation.Limits{}
+		if err := decodeYAMLNodeStrict(mergeYAMLMappingNodes(nodes), limits); err != nil {
+			return fmt.Errorf("invalid limits for tenant %q in overrides group %q: %w", tenantID, groupName, err)
+		}
+		cfg.TenantLimits[tenantID] = limits
+	}
+
+	return nil
+}
+
+// overridesGroupsChain returns the group with the given name followed by all its ancestors, from the nearest to the farthest.
+func overridesGroupsChain(groups map[string]*overridesGroup, name string) ([]*overridesGroup, error) {
+	var chain []*overridesGroup
+	visited := map[string]bool{}
+
+	for name != "" {
+		if visited[name] {
+			return nil, fmt.Errorf("overrides group %q has a cyclic parent relationship", name)
+		}
+		visited[name] = true
+
+		group, ok := groups[name]
+		if !ok || group == nil {
+			return nil, fmt.Errorf("overrides group %q does not exist", name)
+		}
+		chain = append(chain, group)
+		name = group.Parent
+	}
+
+	return chain, nil
+}
+
+// decodeYAMLNodeStrict decodes the node into out, rejecting the unknown fields like the runtime config decoder does,
+// because yaml.Node.Decode() ignores them.
+func decodeYAMLNodeStrict(node *yaml.Node, out interface{}) error {
+	return node.DecodeWithOptions(out, yaml.DecodeOptions{KnownFields: true})
+}
+
+// mergeYAMLMappingNodes merges the top-level keys of the input mapping nodes into a new mapping node.
+// When the same key is set in multiple nodes, the value from the last one wins.
+func mergeYAMLMappingNodes(nodes []*yaml.Node) *yaml.Node {
+	merged := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	index := map[string]int{}
+
+	for _, node := range nodes {
+		for node.Kind == yaml.AliasNode && node.Alias != nil {
+			node = node.Alias
+		}
+		if node.Kind != yaml.MappingNode {
+			continue
+		}
+
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			if pos, ok := index[key.Value]; ok {
+				merged.Content[pos+1] = value
+				continue
+			}
+			index[key.Value] = len(merged.Content)
+			merged.Content = append(merged.Content, key, value)
+		}
+	}
+
+	return merged
+}
+
 func multiClientRuntimeConfigChannel(manager *runtimeconfig.Manager) func() <-chan kv.MultiRuntimeConfig {
 	if manager == nil {
 		return nil
@@ -179,6 +355,16 @@ func distributorInstanceLimits(manager *runtimeconfig.Manager) func() *distribut
 	}
 }
 
+// runtimeConfigValuesFunc returns a function returning the currently loaded runtime configuration, or nil if none.
+func runtimeConfigValuesFunc(runtimeCfgManager *runtimeconfig.Manager) func() interface{} {
+	return func() interface{} {
+		if cfg, ok := runtimeCfgManager.GetConfig().(*runtimeConfigValues); ok && cfg != nil {
+			return cfg
+		}
+		return nil
+	}
+}
+
 func runtimeConfigHandler(runtimeCfgManager *runtimeconfig.Manager, defaultLimits validation.Limits) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		cfg, ok := runtimeCfgManager.GetConfig().(*runtimeConfigValues)
@@ -166,6 +166,154 @@ overrides:
 	}
 }
 
+func TestRuntimeConfigLoader_ShouldResolveOverridesGroups(t *testing.T) {
+	yamlFile := strings.NewReader(`
+overrides_groups:
+  org:
+    limits:
+      ingestion_rate: 1000
+      ingestion_burst_size: 10000
+      max_global_series_per_user: 5000
+  team:
+    parent: org
+    tenants: ['tenant-1', 'tenant-2']
+    limits:
+      ingestion_rate: 2000
+  other:
+    tenants: ['tenant-3']
+overrides:
+  'tenant-1':
+    ingestion_burst_size: 30000
+  'tenant-4':
+    ingestion_rate: 4000
+`)
+
+	loader := &runtimeConfigLoader{}
+	runtimeCfg, err := loader.load(yamlFile)
+	require.NoError(t, err)
+
+	loadedLimits := runtimeCfg.(*runtimeConfigValues).TenantLimits
+	require.Len(t, loadedLimits, 4)
+
+	// The tenant's own overrides take precedence over the group, which takes precedence over the parent group.
+	expected := getDefaultLimits()
+	expected.IngestionRate = 2000
+	expected.IngestionBurstSize = 30000
+	expected.MaxGlobalSeriesPerUser = 5000
+	assert.True(t, cmp.Equal(expected, *loadedLimits["tenant-1"], cmp.AllowUnexported(validation.Limits{})))
+
+	// A tenant without its own overrides inherits the limits from the group and its parent.
+	expected = getDefaultLimits()
+	expected.IngestionRate = 2000
+	expected.IngestionBurstSize = 10000
+	expected.MaxGlobalSeriesPerUser = 5000
+	assert.True(t, cmp.Equal(expected, *loadedLimits["tenant-2"], cmp.AllowUnexported(validation.Limits{})))
+
+	// A group without limits falls back to the defaults.
+	assert.True(t, cmp.Equal(getDefaultLimits(), *loadedLimits["tenant-3"], cmp.AllowUnexported(validation.Limits{})))
+
+	// Tenants not belonging to any group are unaffected.
+	expected = getDefaultLimits()
+	expected.IngestionRate = 4000
+	assert.True(t, cmp.Equal(expected, *loadedLimits["tenant-4"], cmp.AllowUnexported(validation.Limits{})))
+}
+
+func TestRuntimeConfigLoader_ShouldReturnErrorOnInvalidOverridesGroups(t *testing.T) {
+	tests := map[string]struct {
+		config      string
+		expectedErr string
+	}{
+		"unknown parent": {
+			config: `
+overrides_groups:
+  team:
+    parent: org
+    tenants: ['tenant-1']
+`,
+			expectedErr: `overrides group "org" does not exist`,
+		},
+		"cyclic parents": {
+			config: `
+overrides_groups:
+  a:
+    parent: b
+    tenants: ['tenant-1']
+  b:
+    parent: a
+`,
+			expectedErr: "cyclic parent relationship",
+		},
+		"unknown parent of a group without tenants": {
+			config: `
+overrides_groups:
+  team:
+    parent: org
+`,
+			expectedErr: `overrides group "org" does not exist`,
+		},
+		"cyclic parents of groups without tenants": {
+			config: `
+overrides_groups:
+  a:
+    parent: b
+  b:
+    parent: a
+`,
+			expectedErr: "cyclic parent relationship",
+		},
+		"tenant in multiple groups": {
+			config: `
+overrides_groups:
+  a:
+    tenants: ['tenant-1']
+  b:
+    tenants: ['tenant-1']
+`,
+			expectedErr: `tenant "tenant-1" belongs to multiple overrides groups`,
+		},
+		"unknown limit in group": {
+			config: `
+overrides_groups:
+  a:
+    limits:
+      unknown_limit: 1
+`,
+			expectedErr: "field unknown_limit not found",
+		},
+		"misspelled limit in group with tenants": {
+			config: `
+overrides_groups:
+  a:
+    tenants: ['tenant-1']
+    limits:
+      ingestion_rat: 100
+`,
+			expectedErr: "field ingestion_rat not found",
+		},
+		"misspelled limit in parent group": {
+			config: `
+overrides_groups:
+  org:
+    limits:
+      ingestion_rat: 100
+  team:
+    parent: org
+    tenants: ['tenant-1']
+`,
+			expectedErr: "field ingestion_rat not found",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			loader := &runtimeConfigLoader{}
+			_, err := loader.load(strings.NewReader(tc.config))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tc.expectedErr)
+		})
+	}
+}
+
 func getDefaultLimits() validation.Limits {
 	limits := validation.Limits{}
 	flagext.DefaultValues(&limits)
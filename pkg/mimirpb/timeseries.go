@@ -183,6 +183,11 @@ func (p *PreallocTimeseries) HistogramsUpdated() {
 	p.clearUnmarshalData()
 }
 
+// SamplesUpdated must be called after the float samples or histograms of the timeseries have been removed.
+func (p *PreallocTimeseries) SamplesUpdated() {
+	p.clearUnmarshalData()
+}
+
 // DeleteExemplarByMovingLast deletes the exemplar by moving the last one on top and shortening the slice.
 func (p *PreallocTimeseries) DeleteExemplarByMovingLast(ix int) {
 	last := len(p.Exemplars) - 1
@@ -0,0 +1,88 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package client
+
+import (
+	"context"
+	"io"
+	"net/url"
+	"time"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+
+	"github.com/grafana/mimir/pkg/mimirtool/rules/rwrulefmt"
+)
+
+const rulerVersionsAPIPath = "/prometheus/config/v1/rule-versions"
+
+// RuleNamespaceVersion describes a stored version of a rule namespace.
+type RuleNamespaceVersion struct {
+	ID         string    `yaml:"id"`
+	CreatedAt  time.Time `yaml:"created_at"`
+	CreatedBy  string    `yaml:"created_by"`
+	Hash       string    `yaml:"hash"`
+	RuleGroups int       `yaml:"rule_groups"`
+}
+
+// ListRuleNamespaceVersions retrieves the stored versions of a rule namespace, from the most recent one.
+func (r *MimirClient) ListRuleNamespaceVersions(ctx context.Context, namespace string) ([]RuleNamespaceVersion, error) {
+	path := rulerVersionsAPIPath + "/" + url.PathEscape(namespace)
+
+	res, err := r.doRequest(ctx, path, "GET", nil, -1)
+	if err != nil {
+		return nil, err
+	}
+
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	versions := struct {
+		Versions []RuleNamespaceVersion `yaml:"versions"`
+	}{}
+	if err := yaml.Unmarshal(body, &versions); err != nil {
+		return nil, errors.Wrap(err, "unable to unmarshal response")
+	}
+
+	return versions.Versions, nil
+}
+
+// GetRuleNamespaceVersion retrieves the rule groups of a stored version of a rule namespace.
+func (r *MimirClient) GetRuleNamespaceVersion(ctx context.Context, namespace, version string) ([]rwrulefmt.RuleGroup, error) {
+	path := rulerVersionsAPIPath + "/" + url.PathEscape(namespace) + "/" + url.PathEscape(version)
+
+	res, err := r.doRequest(ctx, path, "GET", nil, -1)
+	if err != nil {
+		return nil, err
+	}
+
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	ruleSet := map[string][]rwrulefmt.RuleGroup{}
+	if err := yaml.Unmarshal(body, &ruleSet); err != nil {
+		return nil, errors.Wrap(err, "unable to unmarshal response")
+	}
+
+	return ruleSet[namespace], nil
+}
+
+// RollbackRuleNamespace replaces the rule groups of a rule namespace with the ones of a stored version.
+func (r *MimirClient) RollbackRuleNamespace(ctx context.Context, namespace, version string) error {
+	path := rulerVersionsAPIPath + "/" + url.PathEscape(namespace) + "/" + url.PathEscape(version) + "/rollback"
+
+	res, err := r.doRequest(ctx, path, "POST", nil, -1)
+	if err != nil {
+		return err
+	}
+
+	res.Body.Close()
+
+	return nil
+}
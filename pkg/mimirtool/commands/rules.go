@@ -61,6 +61,15 @@ type ruleCommandClient interface {
 
 	// DeleteNamespace delete all the rule groups in a namespace including the namespace itself.
 	DeleteNamespace(ctx context.Context, namespace string) error
+
+	// ListRuleNamespaceVersions retrieves the stored versions of a rule namespace.
+	ListRuleNamespaceVersions(ctx context.Context, namespace string) ([]client.RuleNamespaceVersion, error)
+
+	// GetRuleNamespaceVersion retrieves the rule groups of a stored version of a rule namespace.
+	GetRuleNamespaceVersion(ctx context.Context, namespace, version string) ([]rwrulefmt.RuleGroup, error)
+
+	// RollbackRuleNamespace replaces the rule groups of a rule namespace with the ones of a stored version.
+	RollbackRuleNamespace(ctx context.Context, namespace, version string) error
 }
 
 // RuleCommand configures and executes rule related mimir operations
@@ -77,6 +86,9 @@ type RuleCommand struct {
 	RuleGroup string
 	OutputDir string
 
+	// Rule Namespace Versions Config
+	Version string
+
 	// Load Rules Config
 	RuleFilesList []string
 	RuleFilesPath string
@@ -162,9 +174,21 @@ func (r *RuleCommand) Register(app *kingpin.Application, envVars EnvVarNames, re
 	deleteNamespaceCmd := rulesCmd.
 		Command("delete-namespace", "Delete a namespace from the ruler.").
 		Action(r.deleteNamespace)
+	listVersionsCmd := rulesCmd.
+		Command("list-versions", "List the stored versions of a namespace in the ruler.").
+		Action(r.listVersions)
+	getVersionCmd := rulesCmd.
+		Command("get-version", "Retrieve the rule groups of a stored version of a namespace from the ruler.").
+		Action(r.getVersion)
+	diffVersionCmd := rulesCmd.
+		Command("diff-version", "Diff the rule groups currently in a namespace of the ruler with a stored version of the namespace.").
+		Action(r.diffVersion)
+	rollbackCmd := rulesCmd.
+		Command("rollback", "Roll back a namespace in the ruler to a stored version of it.").
+		Action(r.rollback)
 
 	// Require Mimir cluster address and tenant ID on all these commands
-	for _, c := range []*kingpin.CmdClause{listCmd, printRulesCmd, getRuleGroupCmd, deleteRuleGroupCmd, loadRulesCmd, diffRulesCmd, syncRulesCmd, deleteNamespaceCmd} {
+	for _, c := range []*kingpin.CmdClause{listCmd, printRulesCmd, getRuleGroupCmd, deleteRuleGroupCmd, loadRulesCmd, diffRulesCmd, syncRulesCmd, deleteNamespaceCmd, listVersionsCmd, getVersionCmd, diffVersionCmd, rollbackCmd} {
 		c.Flag("address", "Address of the Grafana Mimir cluster; alternatively, set "+envVars.Address+".").
 			Envar(envVars.Address).
 			Required().
@@ -291,6 +315,26 @@ func (r *RuleCommand) Register(app *kingpin.Application, envVars EnvVarNames, re
 	// Delete Namespace Command
 	deleteNamespaceCmd.Arg("namespace", "Namespace to delete.").Required().StringVar(&r.Namespace)
 
+	// List Versions Command
+	listVersionsCmd.Arg("namespace", "Namespace to list the versions of.").Required().StringVar(&r.Namespace)
+
+	// Get Version Command
+	getVersionCmd.Arg("namespace", "Namespace of the version to retrieve.").Required().StringVar(&r.Namespace)
+	getVersionCmd.Arg("version", "ID of the version to retrieve.").Required().StringVar(&r.Version)
+	getVersionCmd.Flag("disable-color", "disable colored output").BoolVar(&r.DisableColor)
+	getVersionCmd.Flag("force-color", "force colored output").BoolVar(&r.ForceColor)
+
+	// Diff Version Command
+	diffVersionCmd.Arg("namespace", "Namespace to diff.").Required().StringVar(&r.Namespace)
+	diffVersionCmd.Arg("version", "ID of the version to diff the namespace with.").Required().StringVar(&r.Version)
+	diffVersionCmd.Flag("disable-color", "disable colored output").BoolVar(&r.DisableColor)
+	diffVersionCmd.Flag("force-color", "force colored output").BoolVar(&r.ForceColor)
+	diffVersionCmd.Flag("verbose", "show diff output with rules changes").BoolVar(&r.Verbose)
+
+	// Rollback Command
+	rollbackCmd.Arg("namespace", "Namespace to roll back.").Required().StringVar(&r.Namespace)
+	rollbackCmd.Arg("version", "ID of the version to roll back the namespace to.").Required().StringVar(&r.Version)
+
 }
 
 func (r *RuleCommand) setup(_ *kingpin.ParseContext, reg prometheus.Registerer) error {
@@ -900,3 +944,64 @@ func (r *RuleCommand) deleteNamespace(_ *kingpin.ParseContext) error {
 	}
 	return nil
 }
+
+func (r *RuleCommand) listVersions(_ *kingpin.ParseContext) error {
+	versions, err := r.cli.ListRuleNamespaceVersions(context.Background(), r.Namespace)
+	if err != nil {
+		log.Fatalf("Unable to read namespace versions from Grafana Mimir, %v", err)
+	}
+
+	p := printer.New(r.DisableColor, r.ForceColor, term.IsTerminal(int(os.Stdout.Fd())))
+	return p.PrintRuleNamespaceVersions(versions, os.Stdout)
+}
+
+func (r *RuleCommand) getVersion(_ *kingpin.ParseContext) error {
+	groups, err := r.cli.GetRuleNamespaceVersion(context.Background(), r.Namespace, r.Version)
+	if err != nil {
+		if errors.Is(err, client.ErrResourceNotFound) {
+			log.Infof("this namespace version does not currently exist")
+			return nil
+		}
+		log.Fatalf("Unable to read namespace version from Grafana Mimir, %v", err)
+	}
+
+	p := printer.New(r.DisableColor, r.ForceColor, term.IsTerminal(int(os.Stdout.Fd())))
+	return p.PrintRuleGroups(map[string][]rwrulefmt.RuleGroup{r.Namespace: groups})
+}
+
+// diffVersion prints the changes rolling back the namespace to the version would apply.
+func (r *RuleCommand) diffVersion(_ *kingpin.ParseContext) error {
+	versionGroups, err := r.cli.GetRuleNamespaceVersion(context.Background(), r.Namespace, r.Version)
+	if err != nil {
+		return errors.Wrap(err, "diff operation unsuccessful, unable to read namespace version")
+	}
+
+	currentNamespaceMap, err := r.cli.ListRules(context.Background(), r.Namespace)
+	if err != nil && !errors.Is(err, client.ErrResourceNotFound) {
+		return errors.Wrap(err, "diff operation unsuccessful, unable to contact Grafana Mimir API")
+	}
+
+	current := rules.RuleNamespace{Namespace: r.Namespace, Groups: currentNamespaceMap[r.Namespace]}
+	version := rules.RuleNamespace{Namespace: r.Namespace, Groups: versionGroups}
+
+	var change rules.NamespaceChange
+	switch {
+	case len(current.Groups) == 0:
+		change = rules.NamespaceChange{State: rules.Created, Namespace: r.Namespace, GroupsCreated: version.Groups}
+	case len(version.Groups) == 0:
+		change = rules.NamespaceChange{State: rules.Deleted, Namespace: r.Namespace, GroupsDeleted: current.Groups}
+	default:
+		change = rules.CompareNamespaces(current, version)
+	}
+
+	p := printer.New(r.DisableColor, r.ForceColor, term.IsTerminal(int(os.Stdout.Fd())))
+	return p.PrintComparisonResult([]rules.NamespaceChange{change}, r.Verbose)
+}
+
+func (r *RuleCommand) rollback(_ *kingpin.ParseContext) error {
+	err := r.cli.RollbackRuleNamespace(context.Background(), r.Namespace, r.Version)
+	if err != nil {
+		log.Fatalf("Unable to roll back namespace in Grafana Mimir, %v", err)
+	}
+	return nil
+}
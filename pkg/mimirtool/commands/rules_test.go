@@ -18,6 +18,7 @@ import (
 	"github.com/stretchr/testify/require"
 	"gopkg.in/yaml.v3"
 
+	"github.com/grafana/mimir/pkg/mimirtool/client"
 	"github.com/grafana/mimir/pkg/mimirtool/rules"
 	"github.com/grafana/mimir/pkg/mimirtool/rules/rwrulefmt"
 )
@@ -330,3 +331,18 @@ func (m *ruleCommandClientMock) DeleteNamespace(ctx context.Context, namespace s
 	args := m.Called(ctx, namespace)
 	return args.Error(0)
 }
+
+func (m *ruleCommandClientMock) ListRuleNamespaceVersions(ctx context.Context, namespace string) ([]client.RuleNamespaceVersion, error) {
+	args := m.Called(ctx, namespace)
+	return args.Get(0).([]client.RuleNamespaceVersion), args.Error(1)
+}
+
+func (m *ruleCommandClientMock) GetRuleNamespaceVersion(ctx context.Context, namespace, version string) ([]rwrulefmt.RuleGroup, error) {
+	args := m.Called(ctx, namespace, version)
+	return args.Get(0).([]rwrulefmt.RuleGroup), args.Error(1)
+}
+
+func (m *ruleCommandClientMock) RollbackRuleNamespace(ctx context.Context, namespace, version string) error {
+	args := m.Called(ctx, namespace, version)
+	return args.Error(0)
+}
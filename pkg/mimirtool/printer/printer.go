@@ -12,12 +12,14 @@ import (
 	"os"
 	"strings"
 	"text/tabwriter"
+	"time"
 
 	"github.com/alecthomas/chroma/v2/quick"
 	"github.com/mitchellh/colorstring"
 	"golang.org/x/exp/slices"
 	"gopkg.in/yaml.v3"
 
+	"github.com/grafana/mimir/pkg/mimirtool/client"
 	"github.com/grafana/mimir/pkg/mimirtool/rules"
 	"github.com/grafana/mimir/pkg/mimirtool/rules/rwrulefmt"
 )
@@ -115,6 +117,18 @@ func (p *Printer) PrintRuleGroup(rule rwrulefmt.RuleGroup) error {
 	return nil
 }
 
+// PrintRuleNamespaceVersions prints the stored versions of a rule namespace
+func (p *Printer) PrintRuleNamespaceVersions(versions []client.RuleNamespaceVersion, writer io.Writer) error {
+	w := tabwriter.NewWriter(writer, 0, 0, 1, ' ', tabwriter.Debug)
+
+	fmt.Fprintln(w, "Version\t Created At\t Created By\t Rule Groups")
+	for _, v := range versions {
+		fmt.Fprintf(w, "%s\t %s\t %s\t %d\n", v.ID, v.CreatedAt.Format(time.RFC3339), v.CreatedBy, v.RuleGroups)
+	}
+
+	return w.Flush()
+}
+
 // PrintComparisonResult prints the differences between the staged rules namespace
 // and active rules namespace
 func (p *Printer) PrintComparisonResult(results []rules.NamespaceChange, verbose bool) error {
@@ -196,7 +196,8 @@ func rulesEqual(a, b *rulefmt.RuleNode) bool {
 	if a.Alert.Value != b.Alert.Value ||
 		a.Record.Value != b.Record.Value ||
 		a.Expr.Value != b.Expr.Value ||
-		a.For != b.For {
+		a.For != b.For ||
+		a.KeepFiringFor != b.KeepFiringFor {
 		return false
 	}
 
@@ -130,6 +130,19 @@ func Test_rulesEqual(t *testing.T) {
 			},
 			want: false,
 		},
+		{
+			name: "rule_node_keep_firing_for_diff",
+			a: &rulefmt.RuleNode{
+				Alert: yaml.Node{Value: "one"},
+				Expr:  yaml.Node{Value: "up"},
+			},
+			b: &rulefmt.RuleNode{
+				Alert:         yaml.Node{Value: "one"},
+				Expr:          yaml.Node{Value: "up"},
+				KeepFiringFor: model.Duration(5 * time.Minute),
+			},
+			want: false,
+		},
 		{
 			name: "rule_node_yaml_diff",
 			a: &rulefmt.RuleNode{
@@ -0,0 +1,35 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package api
+
+import (
+	"context"
+	"strconv"
+)
+
+const queryPriorityContextKey contextKey = 3
+
+// ContextWithQueryPriority returns a new context with the given query priority.
+// The priority can be retrieved with QueryPriorityFromContext.
+func ContextWithQueryPriority(parent context.Context, priority int) context.Context {
+	return context.WithValue(parent, queryPriorityContextKey, priority)
+}
+
+// QueryPriorityFromContext returns the query priority from the context if set via ContextWithQueryPriority.
+// The second return value is true if the priority was found in the context.
+func QueryPriorityFromContext(ctx context.Context) (int, bool) {
+	priority, ok := ctx.Value(queryPriorityContextKey).(int)
+	return priority, ok
+}
+
+// DecodeQueryPriority decodes the query priority read from a request header. Priorities are integers:
+// queries with a higher priority are executed before the queries with a lower priority of the same tenant.
+// The second return value is false if the input value is not a valid priority.
+func DecodeQueryPriority(value string) (int, bool) {
+	priority, err := strconv.ParseInt(value, 10, 32)
+	if err != nil {
+		return 0, false
+	}
+
+	return int(priority), true
+}
@@ -0,0 +1,28 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package api
+
+import (
+	"strconv"
+	"time"
+)
+
+// QueryTimeoutBudgetHeader is the header used by the query-frontend to propagate to queriers the time left
+// to execute a query before the deadline of the client request is reached.
+const QueryTimeoutBudgetHeader = "X-Mimir-Query-Timeout-Budget"
+
+// EncodeQueryTimeoutBudget encodes the timeout budget to be set in the QueryTimeoutBudgetHeader.
+func EncodeQueryTimeoutBudget(budget time.Duration) string {
+	return strconv.FormatInt(budget.Milliseconds(), 10)
+}
+
+// DecodeQueryTimeoutBudget decodes the timeout budget read from the QueryTimeoutBudgetHeader.
+// The second return value is false if the input value is not a valid timeout budget.
+func DecodeQueryTimeoutBudget(value string) (time.Duration, bool) {
+	millis, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+
+	return time.Duration(millis) * time.Millisecond, true
+}
@@ -286,6 +286,7 @@ func (s *storeGatewayStreamReader) readStream(log *spanlogger.SpanLogger) error
 		}
 
 		s.stats.AddFetchedChunks(uint64(numChunks))
+		s.stats.AddFetchedFromStoreGateways(0, uint64(numChunks))
 		s.stats.AddFetchedChunkBytes(uint64(chunkBytes))
 
 		if err := s.sendBatch(batch); err != nil {
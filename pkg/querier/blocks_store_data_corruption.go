@@ -0,0 +1,193 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querier
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/gogo/protobuf/types"
+	"github.com/grafana/dskit/grpcutil"
+	"github.com/oklog/ulid"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+
+	"github.com/grafana/mimir/pkg/mimirpb"
+	"github.com/grafana/mimir/pkg/querier/stats"
+	"github.com/grafana/mimir/pkg/storegateway/hintspb"
+	"github.com/grafana/mimir/pkg/storegateway/storepb"
+	"github.com/grafana/mimir/pkg/util"
+	"github.com/grafana/mimir/pkg/util/limiter"
+)
+
+// isDataCorruptionError returns true if the error has been returned by a store-gateway
+// which found corrupted data while reading a block.
+func isDataCorruptionError(err error) bool {
+	if st, ok := grpcutil.ErrorToStatus(err); ok {
+		return st.Code() == codes.DataLoss
+	}
+	return false
+}
+
+// trackDataCorruptionError reports a data corruption error returned by the store-gateway at the given address.
+func (q *blocksStoreQuerier) trackDataCorruptionError(logger log.Logger, remoteAddress string, blockIDs []ulid.ULID, err error) {
+	q.metrics.dataCorruptionErrors.WithLabelValues(remoteAddress).Inc()
+	level.Warn(logger).Log("msg", "store-gateway returned a data corruption error, the blocks are going to be queried from another replica", "remote", remoteAddress, "requested blocks", strings.Join(convertULIDsToString(blockIDs), " "), "err", err)
+}
+
+// fetchSeriesChunksFromReplica fetches the chunks of the series with the given labels from the requested blocks,
+// excluding the store-gateway at failedAddress. It's used to recover from a data corruption error returned by a
+// store-gateway while streaming the chunks, when the series have already been received from it. All series are
+// fetched with a single request per store-gateway, running the original matchers again, and the returned chunks
+// are keyed by the series labels.
+func (q *blocksStoreQuerier) fetchSeriesChunksFromReplica(ctx context.Context, tenantID, failedAddress string, blockIDs []ulid.ULID, minT, maxT int64, matchers []storepb.LabelMatcher, series [][]mimirpb.LabelAdapter) (map[string][]storepb.AggrChunk, error) {
+	exclude := make(map[ulid.ULID][]string, len(blockIDs))
+	for _, id := range blockIDs {
+		exclude[id] = []string{failedAddress}
+	}
+
+	clients, err := q.stores.GetClientsFor(tenantID, blockIDs, exclude)
+	if err != nil {
+		return nil, errors.Wrap(err, "no store-gateway replica left to fetch the series chunks from")
+	}
+
+	requested := make(map[string]struct{}, len(series))
+	for _, lbls := range series {
+		requested[mimirpb.FromLabelAdaptersToKeyString(lbls)] = struct{}{}
+	}
+
+	var (
+		chunksBySeries = make(map[string][]storepb.AggrChunk, len(series))
+		queriedBlocks  = map[ulid.ULID]struct{}{}
+		queryLimiter   = limiter.QueryLimiterFromContextWithFallback(ctx)
+		reqStats       = stats.FromContext(ctx)
+	)
+
+	for c, ids := range clients {
+		// Disable the streaming of chunks, so that the chunks are received along with the series.
+		req, err := createSeriesRequest(minT, maxT, matchers, false, ids, 0)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to create series request")
+		}
+
+		stream, err := c.Series(ctx, req)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to fetch series chunks from %s", c.RemoteAddress())
+		}
+
+		for {
+			resp, err := stream.Recv()
+			if errors.Is(err, io.EOF) {
+				util.CloseAndExhaust[*storepb.SeriesResponse](stream) //nolint:errcheck
+				break
+			}
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to receive series chunks from %s", c.RemoteAddress())
+			}
+
+			if s := resp.GetSeries(); s != nil {
+				// The series whose chunks have been already received from the failed store-gateway are skipped.
+				key := mimirpb.FromLabelAdaptersToKeyString(s.Labels)
+				if _, ok := requested[key]; !ok {
+					continue
+				}
+
+				chunksCount, chunksSize := countChunksAndBytes(s)
+				if err := queryLimiter.AddChunkBytes(chunksSize); err != nil {
+					return nil, err
+				}
+				if err := queryLimiter.AddChunks(chunksCount); err != nil {
+					return nil, err
+				}
+				reqStats.AddFetchedChunks(uint64(chunksCount))
+				reqStats.AddFetchedChunkBytes(uint64(chunksSize))
+				q.metrics.chunksTotal.Add(float64(chunksCount))
+
+				chunksBySeries[key] = append(chunksBySeries[key], s.Chunks...)
+			}
+
+			if h := resp.GetHints(); h != nil {
+				hints := hintspb.SeriesResponseHints{}
+				if err := types.UnmarshalAny(h, &hints); err != nil {
+					return nil, errors.Wrapf(err, "failed to unmarshal series hints from %s", c.RemoteAddress())
+				}
+
+				ids, err := convertBlockHintsToULIDs(hints.QueriedBlocks)
+				if err != nil {
+					return nil, errors.Wrapf(err, "failed to parse queried block IDs from received hints")
+				}
+				for _, id := range ids {
+					queriedBlocks[id] = struct{}{}
+				}
+			}
+		}
+	}
+
+	for _, id := range blockIDs {
+		if _, ok := queriedBlocks[id]; !ok {
+			return nil, fmt.Errorf("block %s couldn't be queried from another store-gateway replica", id)
+		}
+	}
+
+	q.metrics.dataCorruptionRefetches.Add(float64(len(requested)))
+	return chunksBySeries, nil
+}
+
+// replicaFallbackChunkReader is a chunkStreamReader which, once the chunks stream of a store-gateway fails because
+// of a data corruption error, fetches the chunks of the remaining series from another store-gateway replica.
+type replicaFallbackChunkReader struct {
+	reader chunkStreamReader
+	series []*storepb.StreamingSeries
+
+	// fetch returns the chunks of the series with the given labels from another replica, keyed by the series labels.
+	fetch func(series [][]mimirpb.LabelAdapter) (map[string][]storepb.AggrChunk, error)
+	// onCorruption is called once, when the chunks stream fails because of a data corruption error.
+	onCorruption func(err error)
+
+	fallback bool
+	fetched  map[string][]storepb.AggrChunk
+}
+
+func (r *replicaFallbackChunkReader) GetChunks(seriesIndex uint64) ([]storepb.AggrChunk, error) {
+	if !r.fallback {
+		chks, err := r.reader.GetChunks(seriesIndex)
+		if err == nil || !isDataCorruptionError(err) {
+			return chks, err
+		}
+
+		r.fallback = true
+		r.onCorruption(err)
+	}
+
+	if seriesIndex >= uint64(len(r.series)) {
+		return nil, fmt.Errorf("attempted to read series at index %v from store-gateway chunks stream, but the stream has %v series", seriesIndex, len(r.series))
+	}
+
+	// The chunks of all the remaining series are fetched at once, the first time they're needed.
+	if r.fetched == nil {
+		remaining := make([][]mimirpb.LabelAdapter, 0, len(r.series)-int(seriesIndex))
+		for _, s := range r.series[seriesIndex:] {
+			remaining = append(remaining, s.Labels)
+		}
+
+		fetched, err := r.fetch(remaining)
+		if err != nil {
+			return nil, err
+		}
+		if fetched == nil {
+			fetched = map[string][]storepb.AggrChunk{}
+		}
+		r.fetched = fetched
+	}
+
+	// The same series may be returned multiple times in a row by the store-gateway, with different chunks.
+	// The chunks fetched from the other replica are the chunks of all of them, so they're returned only once.
+	key := mimirpb.FromLabelAdaptersToKeyString(r.series[seriesIndex].Labels)
+	chks := r.fetched[key]
+	delete(r.fetched, key)
+	return chks, nil
+}
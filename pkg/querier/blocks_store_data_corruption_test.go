@@ -0,0 +1,246 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querier
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/dskit/user"
+	"github.com/oklog/ulid"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/prometheus/prometheus/tsdb/chunkenc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/grafana/mimir/pkg/mimirpb"
+	"github.com/grafana/mimir/pkg/storage/tsdb/bucketindex"
+	"github.com/grafana/mimir/pkg/storegateway/storegatewaypb"
+	"github.com/grafana/mimir/pkg/storegateway/storepb"
+	"github.com/grafana/mimir/pkg/util/limiter"
+)
+
+func TestBlocksStoreQuerier_Select_DataCorruption(t *testing.T) {
+	const (
+		metricName = "test_metric"
+		minT       = int64(10)
+		maxT       = int64(20)
+	)
+
+	var (
+		block1        = ulid.MustNew(1, nil)
+		series1Label  = labels.FromStrings(labels.MetricName, metricName, "series", "1")
+		corruptionErr = status.Error(codes.DataLoss, "corrupted data in block")
+	)
+
+	tests := map[string]struct {
+		corruptedReplica BlocksStoreClient
+		streaming        bool
+	}{
+		"corruption detected while fetching the series": {
+			corruptedReplica: &storeGatewayClientMock{
+				remoteAddr: "1.1.1.1",
+				mockedSeriesResponses: []*storepb.SeriesResponse{
+					mockSeriesResponse(series1Label, minT, 1),
+				},
+				mockedSeriesErr: corruptionErr,
+			},
+		},
+		"corruption detected while streaming the chunks": {
+			corruptedReplica: &failingStoreGatewayClientMock{
+				storeGatewayClientMock: storeGatewayClientMock{
+					remoteAddr: "1.1.1.1",
+					mockedSeriesResponses: []*storepb.SeriesResponse{
+						mockStreamingSeriesBatchResponse(false, mimirpb.FromLabelsToLabelAdapters(series1Label)),
+						mockHintsResponse(block1),
+						mockStreamingSeriesBatchResponse(true),
+						storepb.NewStreamingChunksEstimate(1),
+					},
+				},
+				recvErr: corruptionErr,
+			},
+			streaming: true,
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			reg := prometheus.NewPedanticRegistry()
+
+			healthyReplica := &storeGatewayClientMock{remoteAddr: "2.2.2.2", mockedSeriesResponses: []*storepb.SeriesResponse{
+				mockSeriesResponse(series1Label, minT, 2),
+				mockHintsResponse(block1),
+			}}
+			stores := &blocksStoreSetMock{mockedResponses: []interface{}{
+				map[BlocksStoreClient][]ulid.ULID{testData.corruptedReplica: {block1}},
+				map[BlocksStoreClient][]ulid.ULID{healthyReplica: {block1}},
+			}}
+
+			finder := &blocksFinderMock{}
+			finder.On("GetBlocks", mock.Anything, "user-1", minT, maxT).Return(bucketindex.Blocks{{ID: block1}}, nil)
+
+			ctx := limiter.AddQueryLimiterToContext(context.Background(), limiter.NewQueryLimiter(0, 0, 0, 0, nil))
+			ctx = user.InjectOrgID(ctx, "user-1")
+
+			q := &blocksStoreQuerier{
+				minT:        minT,
+				maxT:        maxT,
+				finder:      finder,
+				stores:      stores,
+				consistency: NewBlocksConsistency(0, reg),
+				logger:      log.NewNopLogger(),
+				metrics:     newBlocksStoreQueryableMetrics(reg),
+				limits:      &blocksStoreLimitsMock{},
+			}
+			if testData.streaming {
+				q.streamingChunksBatchSize = 10
+			}
+
+			sp := &storage.SelectHints{Start: minT, End: maxT}
+			set := q.Select(ctx, true, sp, labels.MustNewMatcher(labels.MatchEqual, labels.MetricName, metricName))
+
+			// The series is returned with the samples of the healthy replica.
+			require.True(t, set.Next())
+			assert.Equal(t, series1Label, set.At().Labels())
+
+			it := set.At().Iterator(nil)
+			require.Equal(t, chunkenc.ValFloat, it.Next())
+			ts, v := it.At()
+			assert.Equal(t, minT, ts)
+			assert.Equal(t, float64(2), v)
+			require.Equal(t, chunkenc.ValNone, it.Next())
+			require.NoError(t, it.Err())
+
+			require.False(t, set.Next())
+			require.NoError(t, set.Err())
+
+			expectedRefetches := 0
+			if testData.streaming {
+				expectedRefetches = 1
+			}
+			assert.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(fmt.Sprintf(`
+				# HELP cortex_querier_storegateway_data_corruption_errors_total Number of data corruption errors returned by store-gateway instances. The blocks are queried from another replica.
+				# TYPE cortex_querier_storegateway_data_corruption_errors_total counter
+				cortex_querier_storegateway_data_corruption_errors_total{store_gateway="1.1.1.1"} 1
+
+				# HELP cortex_querier_storegateway_data_corruption_refetches_total Number of series whose chunks have been re-fetched from another store-gateway replica after a data corruption error.
+				# TYPE cortex_querier_storegateway_data_corruption_refetches_total counter
+				cortex_querier_storegateway_data_corruption_refetches_total %d
+			`, expectedRefetches)), "cortex_querier_storegateway_data_corruption_errors_total", "cortex_querier_storegateway_data_corruption_refetches_total"))
+		})
+	}
+}
+
+func TestReplicaFallbackChunkReader(t *testing.T) {
+	series1 := mimirpb.FromLabelsToLabelAdapters(labels.FromStrings("series", "1"))
+	series2 := mimirpb.FromLabelsToLabelAdapters(labels.FromStrings("series", "2"))
+	series3 := mimirpb.FromLabelsToLabelAdapters(labels.FromStrings("series", "3"))
+
+	streamedChunks := []storepb.AggrChunk{{MinTime: 1, MaxTime: 2}}
+	fetchedChunks := []storepb.AggrChunk{{MinTime: 3, MaxTime: 4}}
+	otherFetchedChunks := []storepb.AggrChunk{{MinTime: 5, MaxTime: 6}}
+
+	t.Run("should fetch the chunks of all the remaining series from another replica at once after a data corruption error", func(t *testing.T) {
+		var fetched [][][]mimirpb.LabelAdapter
+		corruptions := 0
+
+		r := &replicaFallbackChunkReader{
+			reader: &chunkStreamReaderMock{chunks: [][]storepb.AggrChunk{streamedChunks}, err: status.Error(codes.DataLoss, "corrupted")},
+			// The second series is split across two entries of the stream.
+			series: []*storepb.StreamingSeries{{Labels: series1}, {Labels: series2}, {Labels: series2}, {Labels: series3}},
+			fetch: func(series [][]mimirpb.LabelAdapter) (map[string][]storepb.AggrChunk, error) {
+				fetched = append(fetched, series)
+				return map[string][]storepb.AggrChunk{
+					mimirpb.FromLabelAdaptersToKeyString(series2): fetchedChunks,
+					mimirpb.FromLabelAdaptersToKeyString(series3): otherFetchedChunks,
+				}, nil
+			},
+			onCorruption: func(error) { corruptions++ },
+		}
+
+		chks, err := r.GetChunks(0)
+		require.NoError(t, err)
+		assert.Equal(t, streamedChunks, chks)
+
+		chks, err = r.GetChunks(1)
+		require.NoError(t, err)
+		assert.Equal(t, fetchedChunks, chks)
+
+		chks, err = r.GetChunks(2)
+		require.NoError(t, err)
+		assert.Empty(t, chks)
+
+		chks, err = r.GetChunks(3)
+		require.NoError(t, err)
+		assert.Equal(t, otherFetchedChunks, chks)
+
+		assert.Equal(t, [][][]mimirpb.LabelAdapter{{series2, series2, series3}}, fetched)
+		assert.Equal(t, 1, corruptions)
+	})
+
+	t.Run("should return the other errors", func(t *testing.T) {
+		r := &replicaFallbackChunkReader{
+			reader: &chunkStreamReaderMock{err: io.ErrUnexpectedEOF},
+			series: []*storepb.StreamingSeries{{Labels: series1}},
+			fetch: func([][]mimirpb.LabelAdapter) (map[string][]storepb.AggrChunk, error) {
+				require.FailNow(t, "unexpected fetch from another replica")
+				return nil, nil
+			},
+		}
+
+		_, err := r.GetChunks(0)
+		require.ErrorIs(t, err, io.ErrUnexpectedEOF)
+	})
+}
+
+// chunkStreamReaderMock returns the mocked chunks by series index, and the mocked error afterwards.
+type chunkStreamReaderMock struct {
+	chunks [][]storepb.AggrChunk
+	err    error
+}
+
+func (m *chunkStreamReaderMock) GetChunks(seriesIndex uint64) ([]storepb.AggrChunk, error) {
+	if seriesIndex < uint64(len(m.chunks)) {
+		return m.chunks[seriesIndex], nil
+	}
+	return nil, m.err
+}
+
+// failingStoreGatewayClientMock returns the mocked series responses, and then fails with the mocked error.
+type failingStoreGatewayClientMock struct {
+	storeGatewayClientMock
+	recvErr error
+}
+
+func (m *failingStoreGatewayClientMock) Series(ctx context.Context, req *storepb.SeriesRequest, opts ...grpc.CallOption) (storegatewaypb.StoreGateway_SeriesClient, error) {
+	client, err := m.storeGatewayClientMock.Series(ctx, req, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &failingSeriesClientMock{StoreGateway_SeriesClient: client, err: m.recvErr}, nil
+}
+
+type failingSeriesClientMock struct {
+	storegatewaypb.StoreGateway_SeriesClient
+	err error
+}
+
+func (m *failingSeriesClientMock) Recv() (*storepb.SeriesResponse, error) {
+	res, err := m.StoreGateway_SeriesClient.Recv()
+	if errors.Is(err, io.EOF) {
+		return nil, m.err
+	}
+	return res, err
+}
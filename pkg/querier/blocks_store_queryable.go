@@ -100,6 +100,7 @@ type BlocksStoreLimits interface {
 	MaxLabelsQueryLength(userID string) time.Duration
 	MaxChunksPerQuery(userID string) int
 	StoreGatewayTenantShardSize(userID string) int
+	DedupReplicaExternalLabels(userID string) []string
 }
 
 type blocksStoreQueryableMetrics struct {
@@ -111,6 +112,9 @@ type blocksStoreQueryableMetrics struct {
 	blocksWithCompactorShardButIncompatibleQueryShard prometheus.Counter
 	// The total number of chunks received from store-gateways that were used to evaluate queries
 	chunksTotal prometheus.Counter
+
+	dataCorruptionErrors    *prometheus.CounterVec
+	dataCorruptionRefetches prometheus.Counter
 }
 
 func newBlocksStoreQueryableMetrics(reg prometheus.Registerer) *blocksStoreQueryableMetrics {
@@ -144,6 +148,14 @@ func newBlocksStoreQueryableMetrics(reg prometheus.Registerer) *blocksStoreQuery
 			Name: "cortex_querier_query_storegateway_chunks_total",
 			Help: "Number of chunks received from store gateways at query time.",
 		}),
+		dataCorruptionErrors: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "cortex_querier_storegateway_data_corruption_errors_total",
+			Help: "Number of data corruption errors returned by store-gateway instances. The blocks are queried from another replica.",
+		}, []string{"store_gateway"}),
+		dataCorruptionRefetches: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "cortex_querier_storegateway_data_corruption_refetches_total",
+			Help: "Number of series whose chunks have been re-fetched from another store-gateway replica after a data corruption error.",
+		}),
 	}
 }
 
@@ -359,7 +371,7 @@ func (q *blocksStoreQuerier) LabelNames(ctx context.Context, _ *storage.LabelHin
 		convertedMatchers = convertMatchersToLabelMatcher(matchers)
 	)
 
-	queryF := func(clients map[BlocksStoreClient][]ulid.ULID, minT, maxT int64) ([]ulid.ULID, error) {
+	queryF := func(clients map[BlocksStoreClient][]ulid.ULID, _ bucketindex.Blocks, minT, maxT int64) ([]ulid.ULID, error) {
 		nameSets, warnings, queriedBlocks, err := q.fetchLabelNamesFromStore(ctx, clients, minT, maxT, tenantID, convertedMatchers)
 		if err != nil {
 			return nil, err
@@ -403,7 +415,7 @@ func (q *blocksStoreQuerier) LabelValues(ctx context.Context, name string, _ *st
 		resWarnings  annotations.Annotations
 	)
 
-	queryF := func(clients map[BlocksStoreClient][]ulid.ULID, minT, maxT int64) ([]ulid.ULID, error) {
+	queryF := func(clients map[BlocksStoreClient][]ulid.ULID, _ bucketindex.Blocks, minT, maxT int64) ([]ulid.ULID, error) {
 		valueSets, warnings, queriedBlocks, err := q.fetchLabelValuesFromStore(ctx, name, clients, minT, maxT, tenantID, matchers...)
 		if err != nil {
 			return nil, err
@@ -434,7 +446,8 @@ func (q *blocksStoreQuerier) selectSorted(ctx context.Context, sp *storage.Selec
 
 	var (
 		convertedMatchers = convertMatchersToLabelMatcher(matchers)
-		resSeriesSets     = []storage.SeriesSet(nil)
+		replicaLabels     = q.limits.DedupReplicaExternalLabels(tenantID)
+		resSeriesSets     = map[string][]storage.SeriesSet{}
 		resWarnings       annotations.Annotations
 		streamStarters    []func()
 		chunkEstimators   []func() int
@@ -446,18 +459,46 @@ func (q *blocksStoreQuerier) selectSorted(ctx context.Context, sp *storage.Selec
 		return storage.ErrSeriesSet(err)
 	}
 
-	queryF := func(clients map[BlocksStoreClient][]ulid.ULID, minT, maxT int64) ([]ulid.ULID, error) {
-		seriesSets, queriedBlocks, warnings, startStreamingChunks, chunkEstimator, err := q.fetchSeriesFromStores(ctx, sp, clients, minT, maxT, tenantID, convertedMatchers)
-		if err != nil {
-			return nil, err
+	queryF := func(clients map[BlocksStoreClient][]ulid.ULID, knownBlocks bucketindex.Blocks, minT, maxT int64) ([]ulid.ULID, error) {
+		// When deduplicating replicas, the blocks of each replica are queried separately,
+		// so that the series of different replicas are not merged by the store-gateways.
+		clientsByReplica := map[string]map[BlocksStoreClient][]ulid.ULID{"": clients}
+		if len(replicaLabels) > 0 {
+			clientsByReplica = groupClientsByReplica(clients, knownBlocks, replicaLabels)
 		}
 
-		resSeriesSets = append(resSeriesSets, seriesSets...)
-		resWarnings.Merge(warnings)
-		streamStarters = append(streamStarters, startStreamingChunks)
-		chunkEstimators = append(chunkEstimators, chunkEstimator)
+		var (
+			// We don't derive the context from the group, because the series are streamed
+			// from the store-gateways once all the replicas have been fetched.
+			g                errgroup.Group
+			mtx              sync.Mutex
+			allQueriedBlocks []ulid.ULID
+		)
+
+		// The replicas are fetched concurrently.
+		for replica, replicaClients := range clientsByReplica {
+			g.Go(func() error {
+				seriesSets, queriedBlocks, warnings, startStreamingChunks, chunkEstimator, err := q.fetchSeriesFromStores(ctx, sp, replicaClients, minT, maxT, tenantID, convertedMatchers)
+				if err != nil {
+					return err
+				}
 
-		return queriedBlocks, nil
+				mtx.Lock()
+				defer mtx.Unlock()
+
+				resSeriesSets[replica] = append(resSeriesSets[replica], seriesSets...)
+				resWarnings.Merge(warnings)
+				streamStarters = append(streamStarters, startStreamingChunks)
+				chunkEstimators = append(chunkEstimators, chunkEstimator)
+				allQueriedBlocks = append(allQueriedBlocks, queriedBlocks...)
+				return nil
+			})
+		}
+
+		if err := g.Wait(); err != nil {
+			return nil, err
+		}
+		return allQueriedBlocks, nil
 	}
 
 	err = q.queryWithConsistencyCheck(ctx, spanLog, minT, maxT, tenantID, shard, queryF)
@@ -487,12 +528,10 @@ func (q *blocksStoreQuerier) selectSorted(ctx context.Context, sp *storage.Selec
 		}
 	}
 
-	return series.NewSeriesSetWithWarnings(
-		storage.NewMergeSeriesSet(resSeriesSets, storage.ChainedSeriesMerge),
-		resWarnings)
+	return series.NewSeriesSetWithWarnings(mergeReplicaSeriesSets(resSeriesSets), resWarnings)
 }
 
-type queryFunc func(clients map[BlocksStoreClient][]ulid.ULID, minT, maxT int64) ([]ulid.ULID, error)
+type queryFunc func(clients map[BlocksStoreClient][]ulid.ULID, knownBlocks bucketindex.Blocks, minT, maxT int64) ([]ulid.ULID, error)
 
 func (q *blocksStoreQuerier) queryWithConsistencyCheck(
 	ctx context.Context, spanLog *spanlogger.SpanLogger, minT, maxT int64, tenantID string, shard *sharding.ShardSelector, queryF queryFunc,
@@ -533,6 +572,16 @@ func (q *blocksStoreQuerier) queryWithConsistencyCheck(
 	}
 
 	q.metrics.blocksQueried.Add(float64(len(knownBlocks)))
+	if len(knownBlocks) > 0 {
+		blocksIDs := make([]string, 0, len(knownBlocks))
+		blocksMinT, blocksMaxT := knownBlocks[0].MinTime, knownBlocks[0].MaxTime
+		for _, b := range knownBlocks {
+			blocksIDs = append(blocksIDs, b.ID.String())
+			blocksMinT = min(blocksMinT, b.MinTime)
+			blocksMaxT = max(blocksMaxT, b.MaxTime)
+		}
+		stats.FromContext(ctx).AddQueriedBlocks(blocksIDs, blocksMinT, blocksMaxT)
+	}
 
 	spanLog.DebugLog("msg", "found blocks to query", "expected", knownBlocks.String())
 
@@ -573,7 +622,7 @@ func (q *blocksStoreQuerier) queryWithConsistencyCheck(
 
 		// Fetch series from stores. If an error occur we do not retry because retries
 		// are only meant to cover missing blocks.
-		queriedBlocks, err := queryF(clients, minT, maxT)
+		queriedBlocks, err := queryF(clients, knownBlocks, minT, maxT)
 		if err != nil {
 			return err
 		}
@@ -772,6 +821,10 @@ func (q *blocksStoreQuerier) fetchSeriesFromStores(ctx context.Context, sp *stor
 				err = gCtx.Err()
 			}
 			if err != nil {
+				if isDataCorruptionError(err) {
+					q.trackDataCorruptionError(log, c.RemoteAddress(), blockIDs, err)
+					return nil
+				}
 				if shouldRetry(err) {
 					level.Warn(log).Log("msg", "failed to fetch series", "remote", c.RemoteAddress(), "err", err)
 					return nil
@@ -800,6 +853,10 @@ func (q *blocksStoreQuerier) fetchSeriesFromStores(ctx context.Context, sp *stor
 					break
 				}
 				if err != nil {
+					if isDataCorruptionError(err) {
+						q.trackDataCorruptionError(log, c.RemoteAddress(), blockIDs, err)
+						return nil
+					}
 					if shouldRetry(err) {
 						level.Warn(log).Log("msg", "failed to receive series", "remote", c.RemoteAddress(), "err", err)
 						return nil
@@ -888,6 +945,7 @@ func (q *blocksStoreQuerier) fetchSeriesFromStores(ctx context.Context, sp *stor
 				reqStats.AddFetchedSeries(uint64(len(mySeries)))
 				reqStats.AddFetchedChunkBytes(uint64(chunkBytes))
 				reqStats.AddFetchedChunks(uint64(chunksFetched))
+				reqStats.AddFetchedFromStoreGateways(uint64(len(mySeries)), uint64(chunksFetched))
 
 				level.Debug(log).Log("msg", "received series from store-gateway",
 					"instance", c.RemoteAddress(),
@@ -907,6 +965,7 @@ func (q *blocksStoreQuerier) fetchSeriesFromStores(ctx context.Context, sp *stor
 			} else if len(myStreamingSeries) > 0 {
 				// FetchedChunks and FetchedChunkBytes are added by the SeriesChunksStreamReader.
 				reqStats.AddFetchedSeries(uint64(len(myStreamingSeries)))
+				reqStats.AddFetchedFromStoreGateways(uint64(len(myStreamingSeries)), 0)
 				streamReader = newStoreGatewayStreamReader(reqCtx, stream, len(myStreamingSeries), queryLimiter, reqStats, q.metrics, q.logger)
 				level.Debug(log).Log("msg", "received streaming series from store-gateway",
 					"instance", c.RemoteAddress(),
@@ -929,9 +988,22 @@ func (q *blocksStoreQuerier) fetchSeriesFromStores(ctx context.Context, sp *stor
 				if chunkInfo != nil {
 					chunkInfo.SetMsg("store-gateway streaming")
 				}
+				// If the store-gateway finds corrupted data while streaming the chunks, the chunks of the
+				// remaining series are fetched from another replica, because the series have already been
+				// returned and the blocks can't be queried again by the consistency check.
+				chunksReader := &replicaFallbackChunkReader{
+					reader: streamReader,
+					series: myStreamingSeries,
+					fetch: func(series [][]mimirpb.LabelAdapter) (map[string][]storepb.AggrChunk, error) {
+						return q.fetchSeriesChunksFromReplica(reqCtx, tenantID, c.RemoteAddress(), blockIDs, minT, maxT, convertedMatchers, series)
+					},
+					onCorruption: func(err error) {
+						q.trackDataCorruptionError(spanLog, c.RemoteAddress(), blockIDs, err)
+					},
+				}
 				seriesSets = append(seriesSets, &blockStreamingQuerierSeriesSet{
 					series:        myStreamingSeries,
-					streamReader:  streamReader,
+					streamReader:  chunksReader,
 					chunkInfo:     chunkInfo,
 					remoteAddress: c.RemoteAddress(),
 				})
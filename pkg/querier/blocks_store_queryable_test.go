@@ -1269,6 +1269,42 @@ func TestBlocksStoreQuerier_Select(t *testing.T) {
 			queryLimiter: limiter.NewQueryLimiter(0, 8, 0, 0, stats.NewQueryMetrics(prometheus.NewPedanticRegistry())),
 			expectedErr:  limiter.NewMaxChunkBytesHitLimitError(8),
 		},
+		"blocks of different replicas are deduplicated when replica external labels are configured": {
+			finderResult: bucketindex.Blocks{
+				{ID: block1, Labels: map[string]string{"replica": "a"}},
+				{ID: block2, Labels: map[string]string{"replica": "b"}},
+			},
+			storeSetResponses: []interface{}{
+				map[BlocksStoreClient][]ulid.ULID{
+					&storeGatewayClientMock{remoteAddr: "1.1.1.1", mockedSeriesResponses: []*storepb.SeriesResponse{
+						mockSeriesResponseWithSamples(metricNameLabel,
+							promql.FPoint{T: 0, F: 1}, promql.FPoint{T: 15000, F: 2}, promql.FPoint{T: 30000, F: 3}),
+						mockHintsResponse(block1),
+					}}: {block1},
+					&storeGatewayClientMock{remoteAddr: "2.2.2.2", mockedSeriesResponses: []*storepb.SeriesResponse{
+						mockSeriesResponseWithSamples(metricNameLabel,
+							promql.FPoint{T: 5000, F: 10}, promql.FPoint{T: 20000, F: 20}, promql.FPoint{T: 35000, F: 30},
+							promql.FPoint{T: 50000, F: 40}, promql.FPoint{T: 65000, F: 50}),
+						mockHintsResponse(block2),
+					}}: {block2},
+				},
+			},
+			limits:       &blocksStoreLimitsMock{dedupReplicaExternalLabels: []string{"replica"}},
+			queryLimiter: noOpQueryLimiter,
+			expectedSeries: []seriesResult{
+				{
+					// The samples of the first replica are used until the gap, then the
+					// second replica is used.
+					lbls: metricNameLabel,
+					values: []valueResult{
+						{t: 0, v: 1},
+						{t: 15000, v: 2},
+						{t: 30000, v: 3},
+						{t: 65000, v: 50},
+					},
+				},
+			},
+		},
 		"blocks with non-matching shard are filtered out": {
 			finderResult: bucketindex.Blocks{
 				{ID: block1, CompactorShardID: "1_of_4"},
@@ -1629,6 +1665,9 @@ func TestBlocksStoreQuerier_Select(t *testing.T) {
 						assert.Equal(t, testData.expectedSeries, actualSeries)
 						assert.Equal(t, seriesCount, int(st.FetchedSeriesCount))
 						assert.Equal(t, chunksCount, int(st.FetchedChunksCount))
+						assert.Equal(t, seriesCount, int(st.FetchedSeriesFromStoreGatewaysCount))
+						assert.Equal(t, chunksCount, int(st.FetchedChunksFromStoreGatewaysCount))
+						assert.Zero(t, st.FetchedSeriesFromIngestersCount)
 					}
 
 					// Assert on metrics (optional, only for test cases defining it).
@@ -3098,6 +3137,7 @@ type blocksStoreLimitsMock struct {
 	maxLabelsQueryLength        time.Duration
 	maxChunksPerQuery           int
 	storeGatewayTenantShardSize int
+	dedupReplicaExternalLabels  []string
 }
 
 func (m *blocksStoreLimitsMock) MaxLabelsQueryLength(_ string) time.Duration {
@@ -3112,6 +3152,10 @@ func (m *blocksStoreLimitsMock) StoreGatewayTenantShardSize(_ string) int {
 	return m.storeGatewayTenantShardSize
 }
 
+func (m *blocksStoreLimitsMock) DedupReplicaExternalLabels(_ string) []string {
+	return m.dedupReplicaExternalLabels
+}
+
 func (m *blocksStoreLimitsMock) S3SSEType(_ string) string {
 	return ""
 }
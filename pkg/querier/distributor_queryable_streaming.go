@@ -72,6 +72,7 @@ func (s *streamingChunkSeries) Iterator(it chunkenc.Iterator) chunkenc.Iterator
 	s.context.queryMetrics.IngesterChunksDeduplicated.Add(float64(totalChunks - len(uniqueChunks)))
 
 	s.context.queryStats.AddFetchedChunks(uint64(len(uniqueChunks)))
+	s.context.queryStats.AddFetchedFromIngesters(0, uint64(len(uniqueChunks)))
 
 	chunkBytes := 0
 
@@ -82,6 +82,9 @@ func TranslateToPromqlAPIError(err error) error {
 					switch errorDetails.GetCause() {
 					case mimirpb.TOO_BUSY:
 						return promql.ErrQueryTimeout(s.Message())
+					case mimirpb.TENANT_LIMIT:
+						// This will be returned with status code 422 by Prometheus API.
+						return validation.NewLimitError(s.Message())
 					}
 				}
 			}
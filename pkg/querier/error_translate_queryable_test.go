@@ -27,7 +27,10 @@ import (
 	"github.com/prometheus/prometheus/util/annotations"
 	v1 "github.com/prometheus/prometheus/web/api/v1"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
 
+	"github.com/grafana/mimir/pkg/mimirpb"
+	"github.com/grafana/mimir/pkg/util/globalerror"
 	"github.com/grafana/mimir/pkg/util/validation"
 )
 
@@ -111,6 +114,13 @@ func TestApiStatusCodes(t *testing.T) {
 			expectedString: "timeout",
 			expectedCode:   http.StatusServiceUnavailable,
 		},
+
+		// Tenant limits enforced by the ingesters are translated to 422.
+		{
+			err:            globalerror.WrapErrorWithGRPCStatus(errors.New("limit exceeded"), codes.FailedPrecondition, &mimirpb.ErrorDetails{Cause: mimirpb.TENANT_LIMIT}),
+			expectedString: "limit exceeded",
+			expectedCode:   422,
+		},
 	} {
 		for k, q := range map[string]storage.SampleAndChunkQueryable{
 			"error from queryable": errorTestQueryable{err: tc.err},
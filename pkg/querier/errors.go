@@ -15,6 +15,9 @@ import (
 var (
 	errBadLookbackConfigs = fmt.Errorf("the -%s setting must be greater than -%s otherwise queries might return partial results", validation.QueryIngestersWithinFlag, queryStoreAfterFlag)
 	errEmptyTimeRange     = errors.New("empty time range")
+
+	errInvalidInstantQueryCacheTTL        = errors.New("the instant query cache TTL must be at least 1ms")
+	errInvalidInstantQueryCacheMaxEntries = errors.New("the instant query cache max entries must be greater than 0 when the instant query cache is enabled")
 )
 
 func NewMaxQueryLengthError(actualQueryLen, maxQueryLength time.Duration) validation.LimitError {
@@ -0,0 +1,233 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+// Provenance-includes-location: https://github.com/prometheus/prometheus/blob/main/web/federate.go
+// Provenance-includes-license: Apache-2.0
+// Provenance-includes-copyright: The Prometheus Authors.
+
+package querier
+
+import (
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/gogo/protobuf/proto"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/histogram"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/value"
+	"github.com/prometheus/prometheus/promql/parser"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/prometheus/prometheus/tsdb/chunkenc"
+
+	util_log "github.com/grafana/mimir/pkg/util/log"
+)
+
+// federatedSample is the latest sample of a federated series.
+type federatedSample struct {
+	lbls labels.Labels
+	t    int64
+	f    float64
+	fh   *histogram.FloatHistogram
+}
+
+// FederateHandler exposes the latest sample of each series selected by the match[] parameters, in the Prometheus
+// exposition format, so that a Prometheus server can federate a subset of the tenant's series. Series whose latest
+// sample is older than the lookback delta or is a staleness marker are not exposed. Native histograms are only
+// exposed when the protobuf format is negotiated.
+func FederateHandler(q storage.Queryable, lookbackDelta time.Duration, logger log.Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := util_log.WithContext(ctx, logger)
+
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, "error parsing form values: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		var matcherSets [][]*labels.Matcher
+		for _, s := range r.Form["match[]"] {
+			matchers, err := parser.ParseMetricSelector(s)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			matcherSets = append(matcherSets, matchers)
+		}
+		if len(matcherSets) == 0 {
+			http.Error(w, "at least one match[] parameter must be provided", http.StatusBadRequest)
+			return
+		}
+
+		maxt := time.Now().UnixMilli()
+		mint := maxt - lookbackDelta.Milliseconds()
+
+		querier, err := q.Querier(mint, maxt)
+		if err != nil {
+			http.Error(w, err.Error(), remoteReadErrorStatusCode(err))
+			return
+		}
+		defer querier.Close()
+
+		hints := &storage.SelectHints{Start: mint, End: maxt}
+		sets := make([]storage.SeriesSet, 0, len(matcherSets))
+		for _, matchers := range matcherSets {
+			// Series sets must be sorted to be merged.
+			sets = append(sets, querier.Select(ctx, len(matcherSets) > 1, hints, matchers...))
+		}
+		set := storage.NewMergeSeriesSet(sets, storage.ChainedSeriesMerge)
+
+		format := expfmt.Negotiate(r.Header)
+		includeHistograms := format.FormatType() == expfmt.TypeProtoDelim
+
+		var samples []federatedSample
+		var it chunkenc.Iterator
+		for set.Next() {
+			series := set.At()
+			it = series.Iterator(it)
+
+			sample, ok, err := latestSample(it, maxt)
+			if err != nil {
+				http.Error(w, err.Error(), remoteReadErrorStatusCode(err))
+				return
+			}
+			if !ok || (sample.fh != nil && !includeHistograms) {
+				continue
+			}
+
+			sample.lbls = series.Labels()
+			samples = append(samples, sample)
+		}
+		if err := set.Err(); err != nil {
+			code := remoteReadErrorStatusCode(err)
+			if code/100 != 4 {
+				level.Error(logger).Log("msg", "error while processing federation request", "err", err)
+			}
+			http.Error(w, err.Error(), code)
+			return
+		}
+
+		// Group the samples by metric name and type, because each metric family must only be exposed once.
+		sort.SliceStable(samples, func(i, j int) bool {
+			if ni, nj := samples[i].lbls.Get(labels.MetricName), samples[j].lbls.Get(labels.MetricName); ni != nj {
+				return ni < nj
+			}
+			return samples[i].fh == nil && samples[j].fh != nil
+		})
+
+		w.Header().Set("Content-Type", string(format))
+		enc := expfmt.NewEncoder(w, format)
+
+		var family *dto.MetricFamily
+		flush := func() bool {
+			if family == nil {
+				return true
+			}
+			if err := enc.Encode(family); err != nil {
+				level.Warn(logger).Log("msg", "failed to encode the federation response", "err", err)
+				return false
+			}
+			return true
+		}
+
+		for _, s := range samples {
+			name := s.lbls.Get(labels.MetricName)
+			metricType := dto.MetricType_UNTYPED
+			if s.fh != nil {
+				metricType = dto.MetricType_HISTOGRAM
+			}
+
+			if family == nil || family.GetName() != name || family.GetType() != metricType {
+				if !flush() {
+					return
+				}
+				family = &dto.MetricFamily{Name: proto.String(name), Type: metricType.Enum()}
+			}
+			family.Metric = append(family.Metric, toFederatedMetric(s))
+		}
+		flush()
+	})
+}
+
+// latestSample returns the latest sample of the series not after maxt. It returns false if the series has no samples
+// or the latest one is a staleness marker.
+func latestSample(it chunkenc.Iterator, maxt int64) (federatedSample, bool, error) {
+	var (
+		s     federatedSample
+		found bool
+	)
+
+	for typ := it.Next(); typ != chunkenc.ValNone; typ = it.Next() {
+		t := it.AtT()
+		if t > maxt {
+			break
+		}
+
+		switch typ {
+		case chunkenc.ValFloat:
+			_, f := it.At()
+			s = federatedSample{t: t, f: f}
+		case chunkenc.ValHistogram, chunkenc.ValFloatHistogram:
+			_, fh := it.AtFloatHistogram(nil)
+			s = federatedSample{t: t, fh: fh}
+		}
+		found = true
+	}
+	if err := it.Err(); err != nil {
+		return federatedSample{}, false, err
+	}
+
+	if !found {
+		return federatedSample{}, false, nil
+	}
+	if (s.fh == nil && value.IsStaleNaN(s.f)) || (s.fh != nil && value.IsStaleNaN(s.fh.Sum)) {
+		return federatedSample{}, false, nil
+	}
+	return s, true, nil
+}
+
+func toFederatedMetric(s federatedSample) *dto.Metric {
+	m := &dto.Metric{TimestampMs: proto.Int64(s.t)}
+
+	hasInstance := false
+	s.lbls.Range(func(l labels.Label) {
+		if l.Name == labels.MetricName {
+			return
+		}
+		if l.Name == model.InstanceLabel {
+			hasInstance = true
+		}
+		m.Label = append(m.Label, &dto.LabelPair{Name: proto.String(l.Name), Value: proto.String(l.Value)})
+	})
+	if !hasInstance {
+		// The federating Prometheus would otherwise attach its own instance label, when honor_labels is enabled.
+		m.Label = append(m.Label, &dto.LabelPair{Name: proto.String(model.InstanceLabel), Value: proto.String("")})
+		sort.Slice(m.Label, func(i, j int) bool { return m.Label[i].GetName() < m.Label[j].GetName() })
+	}
+
+	if s.fh == nil {
+		m.Untyped = &dto.Untyped{Value: proto.Float64(s.f)}
+		return m
+	}
+
+	h := &dto.Histogram{
+		SampleCountFloat: proto.Float64(s.fh.Count),
+		SampleSum:        proto.Float64(s.fh.Sum),
+		Schema:           proto.Int32(s.fh.Schema),
+		ZeroThreshold:    proto.Float64(s.fh.ZeroThreshold),
+		ZeroCountFloat:   proto.Float64(s.fh.ZeroCount),
+		PositiveCount:    s.fh.PositiveBuckets,
+		NegativeCount:    s.fh.NegativeBuckets,
+	}
+	for _, span := range s.fh.PositiveSpans {
+		h.PositiveSpan = append(h.PositiveSpan, &dto.BucketSpan{Offset: proto.Int32(span.Offset), Length: proto.Uint32(span.Length)})
+	}
+	for _, span := range s.fh.NegativeSpans {
+		h.NegativeSpan = append(h.NegativeSpan, &dto.BucketSpan{Offset: proto.Int32(span.Offset), Length: proto.Uint32(span.Length)})
+	}
+	m.Histogram = h
+	return m
+}
@@ -0,0 +1,111 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querier
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/value"
+	"github.com/prometheus/prometheus/util/teststorage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/mimir/pkg/util/test"
+)
+
+func TestFederateHandler(t *testing.T) {
+	db := teststorage.New(t)
+	t.Cleanup(func() { require.NoError(t, db.Close()) })
+
+	now := time.Now()
+	ts := func(ago time.Duration) int64 { return now.Add(-ago).UnixMilli() }
+
+	app := db.Appender(context.Background())
+	for _, s := range []struct {
+		lbls labels.Labels
+		t    int64
+		v    float64
+	}{
+		{labels.FromStrings("__name__", "up", "job", "a"), ts(2 * time.Minute), 0},
+		{labels.FromStrings("__name__", "up", "job", "a"), ts(time.Minute), 1},
+		{labels.FromStrings("__name__", "up", "job", "b", "instance", "b-1"), ts(time.Minute), 1},
+		// Series whose latest sample is a staleness marker are not exposed.
+		{labels.FromStrings("__name__", "up", "job", "stale"), ts(2 * time.Minute), 1},
+		{labels.FromStrings("__name__", "up", "job", "stale"), ts(time.Minute), math.Float64frombits(value.StaleNaN)},
+		// Series with no samples within the lookback delta are not exposed.
+		{labels.FromStrings("__name__", "up", "job", "old"), ts(time.Hour), 1},
+		{labels.FromStrings("__name__", "requests_total", "job", "a"), ts(time.Minute), 10},
+	} {
+		_, err := app.Append(0, s.lbls, s.t, s.v)
+		require.NoError(t, err)
+	}
+	_, err := app.AppendHistogram(0, labels.FromStrings("__name__", "latency", "job", "a"), ts(time.Minute), test.GenerateTestHistogram(1), nil)
+	require.NoError(t, err)
+	require.NoError(t, app.Commit())
+
+	handler := FederateHandler(db, 5*time.Minute, log.NewNopLogger())
+
+	federate := func(accept string, matchers ...string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/federate?"+url.Values{"match[]": matchers}.Encode(), nil)
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("text format", func(t *testing.T) {
+		rec := federate("", `{job="a"}`, `up`)
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		expected := "# TYPE requests_total untyped\n" +
+			"requests_total{instance=\"\",job=\"a\"} 10 " + strconv.FormatInt(ts(time.Minute), 10) + "\n" +
+			"# TYPE up untyped\n" +
+			"up{instance=\"b-1\",job=\"b\"} 1 " + strconv.FormatInt(ts(time.Minute), 10) + "\n" +
+			"up{instance=\"\",job=\"a\"} 1 " + strconv.FormatInt(ts(time.Minute), 10) + "\n"
+		assert.Equal(t, expected, rec.Body.String())
+	})
+
+	t.Run("protobuf format", func(t *testing.T) {
+		format := expfmt.NewFormat(expfmt.TypeProtoDelim)
+		rec := federate(string(format), `{job="a"}`)
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		dec := expfmt.NewDecoder(rec.Body, format)
+		var names []string
+		for {
+			family := &dto.MetricFamily{}
+			if err := dec.Decode(family); err != nil {
+				break
+			}
+			names = append(names, family.GetName())
+			if family.GetName() == "latency" {
+				require.Len(t, family.Metric, 1)
+				assert.Equal(t, test.GenerateTestHistogram(1).Sum, family.Metric[0].GetHistogram().GetSampleSum())
+			}
+		}
+		assert.Equal(t, []string{"latency", "requests_total", "up"}, names)
+	})
+
+	t.Run("missing match[] parameter", func(t *testing.T) {
+		rec := federate("")
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("invalid match[] parameter", func(t *testing.T) {
+		rec := federate("", `{job=`)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+}
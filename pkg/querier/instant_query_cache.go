@@ -0,0 +1,212 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querier
+
+import (
+	"bytes"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/grafana/dskit/httpgrpc/server"
+	"github.com/grafana/dskit/middleware"
+	"github.com/grafana/dskit/tenant"
+	lru "github.com/hashicorp/golang-lru/v2"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	querierapi "github.com/grafana/mimir/pkg/querier/api"
+)
+
+const (
+	// instantQueryCacheMaxItemSize is the max size of an instant query response which can be cached.
+	instantQueryCacheMaxItemSize = 1024 * 1024
+
+	// instantQueryCacheControlHeader is the header which can be used by clients to skip the cache.
+	instantQueryCacheControlHeader  = "Cache-Control"
+	instantQueryCacheControlNoStore = "no-store"
+)
+
+type instantQueryCacheEntry struct {
+	header    http.Header
+	body      []byte
+	expiresAt time.Time
+}
+
+// InstantQueryCache is an in-process cache of the instant query responses. It's meant to be used
+// when the querier doesn't run behind a query-frontend (which has its own results cache).
+//
+// Only the instant queries received through the querier HTTP API without an explicit evaluation timestamp are
+// cached. Their evaluation timestamp is set to the current time rounded down to a multiple of the TTL, so that
+// requests received within the same TTL period share the same cached response. The queries with an explicit
+// timestamp, and the ones forwarded by the query-frontend, are evaluated as requested and never cached.
+type InstantQueryCache struct {
+	ttl     time.Duration
+	entries *lru.Cache[string, *instantQueryCacheEntry]
+	now     func() time.Time
+
+	requests prometheus.Counter
+	hits     prometheus.Counter
+}
+
+// NewInstantQueryCache makes a new InstantQueryCache.
+func NewInstantQueryCache(ttl time.Duration, maxEntries int, reg prometheus.Registerer) (*InstantQueryCache, error) {
+	entries, err := lru.New[string, *instantQueryCacheEntry](maxEntries)
+	if err != nil {
+		return nil, err
+	}
+
+	return &InstantQueryCache{
+		ttl:     ttl,
+		entries: entries,
+		now:     time.Now,
+		requests: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "cortex_querier_instant_query_cache_requests_total",
+			Help: "Total number of instant queries looked up in the querier instant query cache.",
+		}),
+		hits: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "cortex_querier_instant_query_cache_hits_total",
+			Help: "Total number of instant queries served from the querier instant query cache.",
+		}),
+	}, nil
+}
+
+// Wrap implements middleware.Interface.
+func (c *InstantQueryCache) Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key, evalTime, ok := c.cacheKey(r)
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		c.requests.Inc()
+		if entry, ok := c.entries.Get(key); ok && c.now().Before(entry.expiresAt) {
+			c.hits.Inc()
+			for name, values := range entry.header {
+				w.Header()[name] = values
+			}
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write(entry.body)
+			return
+		}
+
+		rec := &instantQueryCacheRecorder{ResponseWriter: w, statusCode: http.StatusOK}
+		next.ServeHTTP(rec, withEvalTime(r, evalTime))
+
+		if rec.statusCode != http.StatusOK || rec.tooBig {
+			return
+		}
+
+		c.entries.Add(key, &instantQueryCacheEntry{
+			header:    w.Header().Clone(),
+			body:      rec.body.Bytes(),
+			expiresAt: c.now().Add(c.ttl),
+		})
+	})
+}
+
+// cacheKey returns the cache key of the request, and its evaluation timestamp, which is the current time
+// rounded down to a multiple of the TTL. The last return value is false if the request can't be cached.
+// The request isn't modified.
+func (c *InstantQueryCache) cacheKey(r *http.Request) (string, string, bool) {
+	// The queries forwarded by the query-frontend have an aligned evaluation timestamp, and they're
+	// already cached by the query-frontend.
+	if server.IsHandledByHttpgrpcServer(r.Context()) {
+		return "", "", false
+	}
+
+	if strings.Contains(r.Header.Get(instantQueryCacheControlHeader), instantQueryCacheControlNoStore) {
+		return "", "", false
+	}
+
+	// Requests with a strong read consistency must always see the latest data.
+	if lvl, ok := querierapi.ReadConsistencyLevelFromContext(r.Context()); ok && lvl == querierapi.ReadConsistencyStrong {
+		return "", "", false
+	}
+
+	tenantIDs, err := tenant.TenantIDs(r.Context())
+	if err != nil {
+		return "", "", false
+	}
+
+	if err := r.ParseForm(); err != nil {
+		return "", "", false
+	}
+
+	// Only the queries for the current time are cached, because rounding an explicit
+	// timestamp would silently change the result of the query.
+	if r.Form.Has("time") {
+		return "", "", false
+	}
+
+	evalTimeMs := c.now().UnixMilli()
+	evalTimeMs -= evalTimeMs % c.ttl.Milliseconds()
+	evalTime := strconv.FormatFloat(float64(evalTimeMs)/1000, 'f', -1, 64)
+
+	params := make([]string, 0, len(r.Form)+1)
+	for name := range r.Form {
+		params = append(params, name)
+	}
+	params = append(params, "time")
+	sort.Strings(params)
+
+	key := strings.Builder{}
+	key.WriteString(tenant.JoinTenantIDs(tenantIDs))
+	for _, name := range params {
+		key.WriteByte(0)
+		key.WriteString(name)
+		key.WriteByte('=')
+		if name == "time" {
+			key.WriteString(evalTime)
+		} else {
+			key.WriteString(strings.Join(r.Form[name], ","))
+		}
+	}
+
+	// The response format depends on the accepted content type.
+	key.WriteByte(0)
+	key.WriteString(r.Header.Get("Accept"))
+
+	return key.String(), evalTime, true
+}
+
+// withEvalTime returns a copy of the request with the provided evaluation timestamp. Only the requests
+// going through the cache are evaluated at the cached timestamp, while the original request is left
+// untouched for the other middlewares.
+func withEvalTime(r *http.Request, evalTime string) *http.Request {
+	r = r.Clone(r.Context())
+	r.Form.Set("time", evalTime)
+	return r
+}
+
+// instantQueryCacheRecorder is a http.ResponseWriter which keeps a copy of the response body.
+type instantQueryCacheRecorder struct {
+	http.ResponseWriter
+
+	statusCode int
+	body       bytes.Buffer
+	tooBig     bool
+}
+
+func (r *instantQueryCacheRecorder) WriteHeader(statusCode int) {
+	r.statusCode = statusCode
+	r.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (r *instantQueryCacheRecorder) Write(b []byte) (int, error) {
+	if !r.tooBig {
+		if r.body.Len()+len(b) > instantQueryCacheMaxItemSize {
+			r.tooBig = true
+			r.body = bytes.Buffer{}
+		} else {
+			r.body.Write(b)
+		}
+	}
+
+	return r.ResponseWriter.Write(b)
+}
+
+var _ middleware.Interface = &InstantQueryCache{}
@@ -0,0 +1,234 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querier
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grafana/dskit/httpgrpc"
+	"github.com/grafana/dskit/httpgrpc/server"
+	"github.com/grafana/dskit/user"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	querierapi "github.com/grafana/mimir/pkg/querier/api"
+)
+
+func TestInstantQueryCache(t *testing.T) {
+	reg := prometheus.NewPedanticRegistry()
+	cache, err := NewInstantQueryCache(time.Minute, 10, reg)
+	require.NoError(t, err)
+
+	base := time.Now().Truncate(time.Minute)
+	now := base.Add(10500 * time.Millisecond)
+	cache.now = func() time.Time { return now }
+
+	calls := 0
+	handler := cache.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.FormValue("query") == "invalid" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprintf(w, "%s@%s", r.FormValue("query"), r.FormValue("time"))
+	}))
+
+	do := func(tenantID string, form url.Values, header http.Header) (int, string) {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/query", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		for name, values := range header {
+			req.Header[name] = values
+		}
+		req = req.WithContext(user.InjectOrgID(context.Background(), tenantID))
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code, rec.Body.String()
+	}
+
+	// The queries for the current time are evaluated at the current time rounded down to a multiple of the TTL.
+	code, body := do("user-1", url.Values{"query": {"up"}}, nil)
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "up@"+evalTime(base), body)
+	assert.Equal(t, 1, calls)
+
+	// A request within the same TTL period is served from the cache.
+	now = base.Add(59 * time.Second)
+	code, body = do("user-1", url.Values{"query": {"up"}}, nil)
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "up@"+evalTime(base), body)
+	assert.Equal(t, 1, calls)
+
+	// Requests for different tenants or queries are not shared.
+	_, body = do("user-2", url.Values{"query": {"up"}}, nil)
+	assert.Equal(t, "up@"+evalTime(base), body)
+	assert.Equal(t, 2, calls)
+
+	_, body = do("user-1", url.Values{"query": {"down"}}, nil)
+	assert.Equal(t, "down@"+evalTime(base), body)
+	assert.Equal(t, 3, calls)
+
+	// Clients can skip the cache.
+	_, body = do("user-1", url.Values{"query": {"up"}}, http.Header{"Cache-Control": {"no-store"}})
+	assert.Equal(t, "up@", body)
+	assert.Equal(t, 4, calls)
+
+	// Error responses are not cached.
+	for i := 0; i < 2; i++ {
+		code, _ = do("user-1", url.Values{"query": {"invalid"}}, nil)
+		assert.Equal(t, http.StatusBadRequest, code)
+	}
+	assert.Equal(t, 6, calls)
+
+	// The next TTL period gets a new response, once the previous one has expired.
+	now = base.Add(time.Minute + 10*time.Second)
+	_, body = do("user-1", url.Values{"query": {"up"}}, nil)
+	assert.Equal(t, "up@"+evalTime(base.Add(time.Minute)), body)
+	assert.Equal(t, 7, calls)
+
+	assert.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(`
+		# HELP cortex_querier_instant_query_cache_hits_total Total number of instant queries served from the querier instant query cache.
+		# TYPE cortex_querier_instant_query_cache_hits_total counter
+		cortex_querier_instant_query_cache_hits_total 1
+		# HELP cortex_querier_instant_query_cache_requests_total Total number of instant queries looked up in the querier instant query cache.
+		# TYPE cortex_querier_instant_query_cache_requests_total counter
+		cortex_querier_instant_query_cache_requests_total 7
+	`)))
+}
+
+func TestInstantQueryCache_ShouldNotCacheQueriesWithExplicitTimestamp(t *testing.T) {
+	cache, err := NewInstantQueryCache(time.Minute, 10, nil)
+	require.NoError(t, err)
+
+	calls := 0
+	handler := cache.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		_, _ = fmt.Fprintf(w, "%s@%s", r.FormValue("query"), r.FormValue("time"))
+	}))
+
+	// The explicit timestamps, even the ones close to the current time, are evaluated as is.
+	for _, ts := range []string{"130.5", "130.5", evalTime(time.Now())} {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/query?query=up&time="+ts, nil)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req.WithContext(user.InjectOrgID(context.Background(), "user-1")))
+		assert.Equal(t, "up@"+ts, rec.Body.String())
+	}
+
+	assert.Equal(t, 3, calls)
+}
+
+func TestInstantQueryCache_ShouldNotCacheQueriesForwardedByTheQueryFrontend(t *testing.T) {
+	cache, err := NewInstantQueryCache(time.Minute, 10, nil)
+	require.NoError(t, err)
+
+	calls := 0
+	httpgrpcServer := server.NewServer(cache.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		_, _ = fmt.Fprintf(w, "%s@%s", r.FormValue("query"), r.FormValue("time"))
+	})))
+
+	for i := 0; i < 2; i++ {
+		resp, err := httpgrpcServer.Handle(user.InjectOrgID(context.Background(), "user-1"), &httpgrpc.HTTPRequest{
+			Method: http.MethodGet,
+			Url:    "/api/v1/query?query=up",
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "up@", string(resp.Body))
+	}
+
+	assert.Equal(t, 2, calls)
+}
+
+func TestInstantQueryCache_ShouldNotCacheStrongReadConsistencyQueries(t *testing.T) {
+	cache, err := NewInstantQueryCache(time.Minute, 10, nil)
+	require.NoError(t, err)
+
+	calls := 0
+	handler := cache.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		_, _ = fmt.Fprintf(w, "%s", r.FormValue("query"))
+	}))
+
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/query?query=up", nil)
+		ctx := querierapi.ContextWithReadConsistencyLevel(user.InjectOrgID(context.Background(), "user-1"), querierapi.ReadConsistencyStrong)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req.WithContext(ctx))
+		assert.Equal(t, "up", rec.Body.String())
+	}
+
+	assert.Equal(t, 2, calls)
+}
+
+func TestInstantQueryCache_ShouldExpireEntries(t *testing.T) {
+	cache, err := NewInstantQueryCache(time.Minute, 10, nil)
+	require.NoError(t, err)
+
+	now := time.Now().Truncate(time.Minute)
+	cache.now = func() time.Time { return now }
+
+	calls := 0
+	handler := cache.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		_, _ = fmt.Fprintf(w, "%s", r.FormValue("query"))
+	}))
+
+	do := func() {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/query?query=up", nil)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req.WithContext(user.InjectOrgID(context.Background(), "user-1")))
+		assert.Equal(t, "up", rec.Body.String())
+	}
+
+	do()
+	do()
+	assert.Equal(t, 1, calls)
+
+	// Expire the entry.
+	for _, key := range cache.entries.Keys() {
+		entry, _ := cache.entries.Peek(key)
+		entry.expiresAt = now.Add(-time.Second)
+	}
+
+	do()
+	assert.Equal(t, 2, calls)
+}
+
+func TestInstantQueryCache_ShouldNotModifyTheOriginalRequest(t *testing.T) {
+	cache, err := NewInstantQueryCache(time.Minute, 10, nil)
+	require.NoError(t, err)
+
+	base := time.Now().Truncate(time.Minute)
+	cache.now = func() time.Time { return base.Add(10 * time.Second) }
+
+	handler := cache.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprintf(w, "%s@%s", r.FormValue("query"), r.FormValue("time"))
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/query?query=up", nil)
+	req = req.WithContext(user.InjectOrgID(context.Background(), "user-1"))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, "up@"+evalTime(base), rec.Body.String())
+
+	// The request seen by the other middlewares doesn't get the cached evaluation timestamp.
+	assert.False(t, req.Form.Has("time"))
+}
+
+// evalTime formats the given time like the evaluation timestamp of an instant query.
+func evalTime(t time.Time) string {
+	return strconv.FormatFloat(float64(t.UnixMilli())/1000, 'f', -1, 64)
+}
@@ -58,6 +58,9 @@ type Config struct {
 	QueryEngine               string `yaml:"query_engine" category:"experimental"`
 	EnableQueryEngineFallback bool   `yaml:"enable_query_engine_fallback" category:"experimental"`
 
+	InstantQueryCacheTTL        time.Duration `yaml:"instant_query_cache_ttl" category:"experimental"`
+	InstantQueryCacheMaxEntries int           `yaml:"instant_query_cache_max_entries" category:"experimental"`
+
 	// PromQL engine config.
 	EngineConfig engine.Config `yaml:",inline"`
 }
@@ -88,6 +91,9 @@ func (cfg *Config) RegisterFlags(f *flag.FlagSet) {
 	f.StringVar(&cfg.QueryEngine, "querier.query-engine", prometheusEngine, fmt.Sprintf("Query engine to use, either '%v' or '%v'", prometheusEngine, mimirEngine))
 	f.BoolVar(&cfg.EnableQueryEngineFallback, "querier.enable-query-engine-fallback", true, "If set to true and the Mimir query engine is in use, fall back to using the Prometheus query engine for any queries not supported by the Mimir query engine.")
 
+	f.DurationVar(&cfg.InstantQueryCacheTTL, "querier.instant-query-cache-ttl", 0, "If greater than 0, instant query responses are cached in-memory by the querier for this period. Only the instant queries without an explicit 'time' parameter are cached: their evaluation timestamp is the current time rounded down to a multiple of this period, so that queries received within the same period share the cached response. The queries forwarded by the query-frontend are never cached. This is meant for deployments not running the query-frontend, which has its own results cache. Clients can skip the cache by setting the 'Cache-Control: no-store' header. 0 to disable.")
+	f.IntVar(&cfg.InstantQueryCacheMaxEntries, "querier.instant-query-cache-max-entries", 1000, "Maximum number of instant query responses cached in-memory by the querier. Responses bigger than 1MiB are not cached.")
+
 	cfg.EngineConfig.RegisterFlags(f)
 }
 
@@ -96,6 +102,14 @@ func (cfg *Config) Validate() error {
 		return fmt.Errorf("unknown PromQL engine '%s'", cfg.QueryEngine)
 	}
 
+	if cfg.InstantQueryCacheTTL > 0 && cfg.InstantQueryCacheTTL < time.Millisecond {
+		return errInvalidInstantQueryCacheTTL
+	}
+
+	if cfg.InstantQueryCacheTTL > 0 && cfg.InstantQueryCacheMaxEntries <= 0 {
+		return errInvalidInstantQueryCacheMaxEntries
+	}
+
 	return nil
 }
 
@@ -0,0 +1,197 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querier
+
+import (
+	"math"
+	"slices"
+	"strings"
+
+	"github.com/oklog/ulid"
+	"github.com/prometheus/prometheus/model/histogram"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/prometheus/prometheus/tsdb/chunkenc"
+
+	"github.com/grafana/mimir/pkg/storage/tsdb/bucketindex"
+)
+
+// replicaDedupInitialPenalty is the penalty, in milliseconds, applied to the replicas not picked
+// for the first sample, when the scrape interval is not known yet.
+const replicaDedupInitialPenalty = 5000
+
+// groupClientsByReplica splits the blocks to query on each store-gateway by the replica which wrote them,
+// identified by the values of the replicaLabels external labels of the blocks. Blocks without any
+// replica label are all considered to be written by the same replica.
+func groupClientsByReplica(clients map[BlocksStoreClient][]ulid.ULID, knownBlocks bucketindex.Blocks, replicaLabels []string) map[string]map[BlocksStoreClient][]ulid.ULID {
+	replicas := make(map[ulid.ULID]string, len(knownBlocks))
+	for _, b := range knownBlocks {
+		values := make([]string, 0, len(replicaLabels))
+		for _, name := range replicaLabels {
+			values = append(values, b.Labels[name])
+		}
+		replicas[b.ID] = strings.Join(values, "\xff")
+	}
+
+	res := map[string]map[BlocksStoreClient][]ulid.ULID{}
+	for c, blockIDs := range clients {
+		for _, id := range blockIDs {
+			replica := replicas[id]
+			if res[replica] == nil {
+				res[replica] = map[BlocksStoreClient][]ulid.ULID{}
+			}
+			res[replica][c] = append(res[replica][c], id)
+		}
+	}
+	return res
+}
+
+// mergeReplicaSeriesSets merges the series sets queried from the blocks of each replica. The series
+// of the same replica are merged, while the same series from different replicas are deduplicated.
+func mergeReplicaSeriesSets(setsByReplica map[string][]storage.SeriesSet) storage.SeriesSet {
+	if len(setsByReplica) <= 1 {
+		var sets []storage.SeriesSet
+		for _, replicaSets := range setsByReplica {
+			sets = replicaSets
+		}
+		return storage.NewMergeSeriesSet(sets, storage.ChainedSeriesMerge)
+	}
+
+	// Sort replicas to get a deterministic deduplication.
+	replicas := make([]string, 0, len(setsByReplica))
+	for replica := range setsByReplica {
+		replicas = append(replicas, replica)
+	}
+	slices.Sort(replicas)
+
+	sets := make([]storage.SeriesSet, 0, len(replicas))
+	for _, replica := range replicas {
+		sets = append(sets, storage.NewMergeSeriesSet(setsByReplica[replica], storage.ChainedSeriesMerge))
+	}
+	return storage.NewMergeSeriesSet(sets, newReplicaDedupSeries)
+}
+
+// replicaDedupSeries is a series whose samples are deduplicated across the same series written by different replicas.
+type replicaDedupSeries struct {
+	replicas []storage.Series
+}
+
+func newReplicaDedupSeries(replicas ...storage.Series) storage.Series {
+	if len(replicas) == 1 {
+		return replicas[0]
+	}
+	return &replicaDedupSeries{replicas: replicas}
+}
+
+func (s *replicaDedupSeries) Labels() labels.Labels {
+	return s.replicas[0].Labels()
+}
+
+func (s *replicaDedupSeries) Iterator(chunkenc.Iterator) chunkenc.Iterator {
+	it := s.replicas[0].Iterator(nil)
+	for _, replica := range s.replicas[1:] {
+		it = newReplicaDedupIterator(it, replica.Iterator(nil))
+	}
+	return it
+}
+
+// replicaDedupIterator deduplicates the samples of two replicas of the same series. It keeps
+// returning the samples of a replica and only switches to the other one when there's a gap in
+// the data. Each time a sample is picked from a replica, the other replica is penalised by
+// skipping its samples for twice the interval since the previous sample, so that the resulting
+// series doesn't have a higher sampling frequency than the replicas.
+type replicaDedupIterator struct {
+	a, b         chunkenc.Iterator
+	aType, bType chunkenc.ValueType
+
+	// Penalties, in milliseconds, applied when seeking to the next sample of each replica.
+	aPenalty, bPenalty int64
+
+	lastT    int64
+	curr     chunkenc.Iterator
+	currType chunkenc.ValueType
+}
+
+func newReplicaDedupIterator(a, b chunkenc.Iterator) *replicaDedupIterator {
+	return &replicaDedupIterator{
+		a:     a,
+		b:     b,
+		aType: a.Next(),
+		bType: b.Next(),
+		lastT: math.MinInt64,
+	}
+}
+
+func (it *replicaDedupIterator) Next() chunkenc.ValueType {
+	if it.aType != chunkenc.ValNone {
+		it.aType = it.a.Seek(it.lastT + 1 + it.aPenalty)
+	}
+	if it.bType != chunkenc.ValNone {
+		it.bType = it.b.Seek(it.lastT + 1 + it.bPenalty)
+	}
+
+	switch {
+	case it.aType == chunkenc.ValNone && it.bType == chunkenc.ValNone:
+		it.curr, it.currType = nil, chunkenc.ValNone
+	case it.bType == chunkenc.ValNone:
+		it.pick(it.a, it.aType, &it.aPenalty, &it.bPenalty)
+	case it.aType == chunkenc.ValNone:
+		it.pick(it.b, it.bType, &it.bPenalty, &it.aPenalty)
+	case it.a.AtT() <= it.b.AtT():
+		it.pick(it.a, it.aType, &it.aPenalty, &it.bPenalty)
+	default:
+		it.pick(it.b, it.bType, &it.bPenalty, &it.aPenalty)
+	}
+
+	return it.currType
+}
+
+func (it *replicaDedupIterator) pick(picked chunkenc.Iterator, pickedType chunkenc.ValueType, pickedPenalty, otherPenalty *int64) {
+	t := picked.AtT()
+
+	*pickedPenalty = 0
+	if it.lastT == math.MinInt64 {
+		*otherPenalty = replicaDedupInitialPenalty
+	} else {
+		*otherPenalty = 2 * (t - it.lastT)
+	}
+
+	it.lastT = t
+	it.curr, it.currType = picked, pickedType
+}
+
+func (it *replicaDedupIterator) Seek(t int64) chunkenc.ValueType {
+	// Move forward sample by sample, so that the deduplication is the same as when iterating.
+	if it.curr != nil && it.curr.AtT() >= t {
+		return it.currType
+	}
+	for {
+		valType := it.Next()
+		if valType == chunkenc.ValNone || it.curr.AtT() >= t {
+			return valType
+		}
+	}
+}
+
+func (it *replicaDedupIterator) At() (int64, float64) {
+	return it.curr.At()
+}
+
+func (it *replicaDedupIterator) AtHistogram(h *histogram.Histogram) (int64, *histogram.Histogram) {
+	return it.curr.AtHistogram(h)
+}
+
+func (it *replicaDedupIterator) AtFloatHistogram(fh *histogram.FloatHistogram) (int64, *histogram.FloatHistogram) {
+	return it.curr.AtFloatHistogram(fh)
+}
+
+func (it *replicaDedupIterator) AtT() int64 {
+	return it.curr.AtT()
+}
+
+func (it *replicaDedupIterator) Err() error {
+	if err := it.a.Err(); err != nil {
+		return err
+	}
+	return it.b.Err()
+}
@@ -0,0 +1,190 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package querier
+
+import (
+	"testing"
+
+	"github.com/oklog/ulid"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/prometheus/prometheus/tsdb/chunkenc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/mimir/pkg/storage/series"
+	"github.com/grafana/mimir/pkg/storage/tsdb/bucketindex"
+)
+
+func TestGroupClientsByReplica(t *testing.T) {
+	var (
+		block1  = ulid.MustNew(1, nil)
+		block2  = ulid.MustNew(2, nil)
+		block3  = ulid.MustNew(3, nil)
+		block4  = ulid.MustNew(4, nil)
+		client1 = &storeGatewayClientMock{remoteAddr: "1.1.1.1"}
+		client2 = &storeGatewayClientMock{remoteAddr: "2.2.2.2"}
+	)
+
+	knownBlocks := bucketindex.Blocks{
+		{ID: block1, Labels: map[string]string{"cluster": "eu", "replica": "a"}},
+		{ID: block2, Labels: map[string]string{"cluster": "eu", "replica": "b"}},
+		{ID: block3, Labels: map[string]string{"cluster": "eu", "replica": "a"}},
+		{ID: block4},
+	}
+
+	actual := groupClientsByReplica(map[BlocksStoreClient][]ulid.ULID{
+		client1: {block1, block2, block4},
+		client2: {block3},
+	}, knownBlocks, []string{"replica"})
+
+	assert.Equal(t, map[string]map[BlocksStoreClient][]ulid.ULID{
+		"a": {client1: {block1}, client2: {block3}},
+		"b": {client1: {block2}},
+		"":  {client1: {block4}},
+	}, actual)
+}
+
+func TestReplicaDedupIterator(t *testing.T) {
+	tests := map[string]struct {
+		a, b     []sample
+		expected []sample
+	}{
+		"both replicas are empty": {},
+		"one replica is empty": {
+			a:        []sample{{0, 1}, {15000, 2}},
+			expected: []sample{{0, 1}, {15000, 2}},
+		},
+		"replicas with the same samples": {
+			a:        []sample{{0, 1}, {15000, 2}, {30000, 3}},
+			b:        []sample{{0, 1}, {15000, 2}, {30000, 3}},
+			expected: []sample{{0, 1}, {15000, 2}, {30000, 3}},
+		},
+		"replicas with shifted samples stick to a replica": {
+			a:        []sample{{0, 1}, {15000, 2}, {30000, 3}, {45000, 4}},
+			b:        []sample{{1000, 10}, {16000, 20}, {31000, 30}, {46000, 40}},
+			expected: []sample{{0, 1}, {15000, 2}, {30000, 3}, {45000, 4}},
+		},
+		"replicas with gaps fill each other's gaps": {
+			a:        []sample{{0, 1}, {15000, 2}, {90000, 7}, {105000, 8}, {120000, 9}},
+			b:        []sample{{1000, 10}, {16000, 20}, {31000, 30}, {46000, 40}, {61000, 50}},
+			expected: []sample{{0, 1}, {15000, 2}, {46000, 40}, {61000, 50}, {120000, 9}},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			it := newReplicaDedupIterator(newSampleIterator(tc.a), newSampleIterator(tc.b))
+
+			var actual []sample
+			for it.Next() != chunkenc.ValNone {
+				ts, v := it.At()
+				actual = append(actual, sample{ts, v})
+			}
+			require.NoError(t, it.Err())
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestReplicaDedupIterator_Seek(t *testing.T) {
+	a := []sample{{0, 1}, {15000, 2}, {90000, 7}, {105000, 8}}
+	b := []sample{{1000, 10}, {16000, 20}, {31000, 30}, {46000, 40}, {61000, 50}, {76000, 60}}
+
+	it := newReplicaDedupIterator(newSampleIterator(a), newSampleIterator(b))
+	require.Equal(t, chunkenc.ValFloat, it.Seek(50000))
+	ts, v := it.At()
+	assert.Equal(t, sample{61000, 50}, sample{ts, v})
+
+	// Seeking backwards has no effect.
+	require.Equal(t, chunkenc.ValFloat, it.Seek(0))
+	assert.Equal(t, int64(61000), it.AtT())
+
+	require.Equal(t, chunkenc.ValNone, it.Seek(200000))
+}
+
+func TestMergeReplicaSeriesSets(t *testing.T) {
+	series1 := labels.FromStrings(labels.MetricName, "metric", "series", "1")
+	series2 := labels.FromStrings(labels.MetricName, "metric", "series", "2")
+
+	set := mergeReplicaSeriesSets(map[string][]storage.SeriesSet{
+		"a": {
+			series.NewConcreteSeriesSetFromUnsortedSeries([]storage.Series{
+				series.NewConcreteSeries(series1, []model.SamplePair{{Timestamp: 0, Value: 1}, {Timestamp: 15000, Value: 2}}, nil),
+			}),
+			series.NewConcreteSeriesSetFromUnsortedSeries([]storage.Series{
+				series.NewConcreteSeries(series1, []model.SamplePair{{Timestamp: 30000, Value: 3}}, nil),
+			}),
+		},
+		"b": {
+			series.NewConcreteSeriesSetFromUnsortedSeries([]storage.Series{
+				series.NewConcreteSeries(series1, []model.SamplePair{{Timestamp: 1000, Value: 10}, {Timestamp: 16000, Value: 20}, {Timestamp: 31000, Value: 30}}, nil),
+				series.NewConcreteSeries(series2, []model.SamplePair{{Timestamp: 1000, Value: 100}}, nil),
+			}),
+		},
+	})
+
+	actual := map[string][]sample{}
+	for set.Next() {
+		it := set.At().Iterator(nil)
+		for it.Next() != chunkenc.ValNone {
+			ts, v := it.At()
+			actual[set.At().Labels().String()] = append(actual[set.At().Labels().String()], sample{ts, v})
+		}
+		require.NoError(t, it.Err())
+	}
+	require.NoError(t, set.Err())
+
+	assert.Equal(t, map[string][]sample{
+		series1.String(): {{0, 1}, {15000, 2}, {30000, 3}},
+		series2.String(): {{1000, 100}},
+	}, actual)
+}
+
+type sample struct {
+	t int64
+	v float64
+}
+
+type sampleIterator struct {
+	chunkenc.Iterator
+	samples []sample
+	idx     int
+}
+
+func newSampleIterator(samples []sample) *sampleIterator {
+	return &sampleIterator{samples: samples, idx: -1}
+}
+
+func (it *sampleIterator) Next() chunkenc.ValueType {
+	it.idx++
+	if it.idx >= len(it.samples) {
+		return chunkenc.ValNone
+	}
+	return chunkenc.ValFloat
+}
+
+func (it *sampleIterator) Seek(t int64) chunkenc.ValueType {
+	if it.idx < 0 {
+		it.idx = 0
+	}
+	for ; it.idx < len(it.samples); it.idx++ {
+		if it.samples[it.idx].t >= t {
+			return chunkenc.ValFloat
+		}
+	}
+	return chunkenc.ValNone
+}
+
+func (it *sampleIterator) At() (int64, float64) {
+	return it.samples[it.idx].t, it.samples[it.idx].v
+}
+
+func (it *sampleIterator) AtT() int64 {
+	return it.samples[it.idx].t
+}
+
+func (it *sampleIterator) Err() error {
+	return nil
+}
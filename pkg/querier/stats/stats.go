@@ -7,6 +7,8 @@ package stats
 
 import (
 	"context"
+	"slices"
+	"sync"
 	"sync/atomic" //lint:ignore faillint we can't use go.uber.org/atomic with a protobuf struct without wrapping it.
 	"time"
 
@@ -203,6 +205,132 @@ func (s *Stats) LoadEncodeTime() time.Duration {
 	return time.Duration(atomic.LoadInt64((*int64)(&s.EncodeTime)))
 }
 
+// AddFetchedFromIngesters adds the series and chunks fetched from ingesters. They must be
+// added to the total fetched series and chunks too.
+func (s *Stats) AddFetchedFromIngesters(series, chunks uint64) {
+	if s == nil {
+		return
+	}
+
+	atomic.AddUint64(&s.FetchedSeriesFromIngestersCount, series)
+	atomic.AddUint64(&s.FetchedChunksFromIngestersCount, chunks)
+}
+
+func (s *Stats) LoadFetchedSeriesFromIngesters() uint64 {
+	if s == nil {
+		return 0
+	}
+
+	return atomic.LoadUint64(&s.FetchedSeriesFromIngestersCount)
+}
+
+func (s *Stats) LoadFetchedChunksFromIngesters() uint64 {
+	if s == nil {
+		return 0
+	}
+
+	return atomic.LoadUint64(&s.FetchedChunksFromIngestersCount)
+}
+
+// AddFetchedFromStoreGateways adds the series and chunks fetched from store-gateways. They must be
+// added to the total fetched series and chunks too.
+func (s *Stats) AddFetchedFromStoreGateways(series, chunks uint64) {
+	if s == nil {
+		return
+	}
+
+	atomic.AddUint64(&s.FetchedSeriesFromStoreGatewaysCount, series)
+	atomic.AddUint64(&s.FetchedChunksFromStoreGatewaysCount, chunks)
+}
+
+func (s *Stats) LoadFetchedSeriesFromStoreGateways() uint64 {
+	if s == nil {
+		return 0
+	}
+
+	return atomic.LoadUint64(&s.FetchedSeriesFromStoreGatewaysCount)
+}
+
+func (s *Stats) LoadFetchedChunksFromStoreGateways() uint64 {
+	if s == nil {
+		return 0
+	}
+
+	return atomic.LoadUint64(&s.FetchedChunksFromStoreGatewaysCount)
+}
+
+// queriedBlocksMtx guards the queried blocks of all the Stats. They can't be updated atomically,
+// and the Stats being a protobuf struct we can't add a mutex to it.
+var queriedBlocksMtx sync.Mutex
+
+// AddQueriedBlocks adds the IDs of the blocks queried from store-gateways, and extends the
+// queried blocks time range to include [minT, maxT]. Blocks already added are ignored, so the
+// same block queried by multiple shards or splits of a query is only counted once.
+func (s *Stats) AddQueriedBlocks(ids []string, minT, maxT int64) {
+	if s == nil || len(ids) == 0 {
+		return
+	}
+
+	queriedBlocksMtx.Lock()
+	defer queriedBlocksMtx.Unlock()
+
+	s.addQueriedBlocks(ids, minT, maxT)
+}
+
+// addQueriedBlocks must be called with queriedBlocksMtx held.
+func (s *Stats) addQueriedBlocks(ids []string, minT, maxT int64) {
+	if len(s.QueriedBlocks) == 0 {
+		s.QueriedBlocksMinTime, s.QueriedBlocksMaxTime = minT, maxT
+	} else {
+		s.QueriedBlocksMinTime = min(s.QueriedBlocksMinTime, minT)
+		s.QueriedBlocksMaxTime = max(s.QueriedBlocksMaxTime, maxT)
+	}
+
+	// The IDs are kept sorted to quickly find the blocks already added.
+	for _, id := range ids {
+		if idx, found := slices.BinarySearch(s.QueriedBlocks, id); !found {
+			s.QueriedBlocks = slices.Insert(s.QueriedBlocks, idx, id)
+		}
+	}
+}
+
+// LoadQueriedBlocks returns the number of unique blocks queried from store-gateways.
+func (s *Stats) LoadQueriedBlocks() uint64 {
+	if s == nil {
+		return 0
+	}
+
+	queriedBlocksMtx.Lock()
+	defer queriedBlocksMtx.Unlock()
+
+	return uint64(len(s.QueriedBlocks))
+}
+
+// LoadQueriedBlocksTimeRange returns the time range of the blocks queried from store-gateways.
+// The returned bool is false if no block was queried.
+func (s *Stats) LoadQueriedBlocksTimeRange() (minT, maxT int64, ok bool) {
+	if s == nil {
+		return 0, 0, false
+	}
+
+	queriedBlocksMtx.Lock()
+	defer queriedBlocksMtx.Unlock()
+
+	if len(s.QueriedBlocks) == 0 {
+		return 0, 0, false
+	}
+	return s.QueriedBlocksMinTime, s.QueriedBlocksMaxTime, true
+}
+
+func (s *Stats) mergeQueriedBlocks(other *Stats) {
+	queriedBlocksMtx.Lock()
+	defer queriedBlocksMtx.Unlock()
+
+	if len(other.QueriedBlocks) > 0 {
+		s.addQueriedBlocks(other.QueriedBlocks, other.QueriedBlocksMinTime, other.QueriedBlocksMaxTime)
+	}
+}
+
 // Merge the provided Stats into this one.
 func (s *Stats) Merge(other *Stats) {
 	if s == nil || other == nil {
@@ -219,6 +347,9 @@ func (s *Stats) Merge(other *Stats) {
 	s.AddEstimatedSeriesCount(other.LoadEstimatedSeriesCount())
 	s.AddQueueTime(other.LoadQueueTime())
 	s.AddEncodeTime(other.LoadEncodeTime())
+	s.AddFetchedFromIngesters(other.LoadFetchedSeriesFromIngesters(), other.LoadFetchedChunksFromIngesters())
+	s.AddFetchedFromStoreGateways(other.LoadFetchedSeriesFromStoreGateways(), other.LoadFetchedChunksFromStoreGateways())
+	s.mergeQueriedBlocks(other)
 }
 
 // Copy returns a copy of the stats. Use this rather than regular struct assignment
@@ -50,6 +50,20 @@ type Stats struct {
 	QueueTime time.Duration `protobuf:"bytes,9,opt,name=queue_time,json=queueTime,proto3,stdduration" json:"queue_time"`
 	// The time spent at the frontend encoding the query's final results. Does not include time spent serializing results at the querier.
 	EncodeTime time.Duration `protobuf:"bytes,10,opt,name=encode_time,json=encodeTime,proto3,stdduration" json:"encode_time"`
+	// The number of series fetched from ingesters, after any deduplication. Included in fetched_series_count.
+	FetchedSeriesFromIngestersCount uint64 `protobuf:"varint,11,opt,name=fetched_series_from_ingesters_count,json=fetchedSeriesFromIngestersCount,proto3" json:"fetched_series_from_ingesters_count,omitempty"`
+	// The number of chunks fetched from ingesters, after any deduplication. Included in fetched_chunks_count.
+	FetchedChunksFromIngestersCount uint64 `protobuf:"varint,12,opt,name=fetched_chunks_from_ingesters_count,json=fetchedChunksFromIngestersCount,proto3" json:"fetched_chunks_from_ingesters_count,omitempty"`
+	// The number of series fetched from store-gateways. Included in fetched_series_count.
+	FetchedSeriesFromStoreGatewaysCount uint64 `protobuf:"varint,13,opt,name=fetched_series_from_store_gateways_count,json=fetchedSeriesFromStoreGatewaysCount,proto3" json:"fetched_series_from_store_gateways_count,omitempty"`
+	// The number of chunks fetched from store-gateways. Included in fetched_chunks_count.
+	FetchedChunksFromStoreGatewaysCount uint64 `protobuf:"varint,14,opt,name=fetched_chunks_from_store_gateways_count,json=fetchedChunksFromStoreGatewaysCount,proto3" json:"fetched_chunks_from_store_gateways_count,omitempty"`
+	// The IDs of the blocks queried from store-gateways, without duplicates.
+	QueriedBlocks []string `protobuf:"bytes,15,rep,name=queried_blocks,json=queriedBlocks,proto3" json:"queried_blocks,omitempty"`
+	// The minimum and maximum timestamps, in milliseconds, of the blocks queried from store-gateways.
+	// Only set if queried_blocks is not empty.
+	QueriedBlocksMinTime int64 `protobuf:"varint,16,opt,name=queried_blocks_min_time,json=queriedBlocksMinTime,proto3" json:"queried_blocks_min_time,omitempty"`
+	QueriedBlocksMaxTime int64 `protobuf:"varint,17,opt,name=queried_blocks_max_time,json=queriedBlocksMaxTime,proto3" json:"queried_blocks_max_time,omitempty"`
 }
 
 func (m *Stats) Reset()      { *m = Stats{} }
@@ -154,6 +168,55 @@ func (m *Stats) GetEncodeTime() time.Duration {
 	return 0
 }
 
+func (m *Stats) GetFetchedSeriesFromIngestersCount() uint64 {
+	if m != nil {
+		return m.FetchedSeriesFromIngestersCount
+	}
+	return 0
+}
+
+func (m *Stats) GetFetchedChunksFromIngestersCount() uint64 {
+	if m != nil {
+		return m.FetchedChunksFromIngestersCount
+	}
+	return 0
+}
+
+func (m *Stats) GetFetchedSeriesFromStoreGatewaysCount() uint64 {
+	if m != nil {
+		return m.FetchedSeriesFromStoreGatewaysCount
+	}
+	return 0
+}
+
+func (m *Stats) GetFetchedChunksFromStoreGatewaysCount() uint64 {
+	if m != nil {
+		return m.FetchedChunksFromStoreGatewaysCount
+	}
+	return 0
+}
+
+func (m *Stats) GetQueriedBlocks() []string {
+	if m != nil {
+		return m.QueriedBlocks
+	}
+	return nil
+}
+
+func (m *Stats) GetQueriedBlocksMinTime() int64 {
+	if m != nil {
+		return m.QueriedBlocksMinTime
+	}
+	return 0
+}
+
+func (m *Stats) GetQueriedBlocksMaxTime() int64 {
+	if m != nil {
+		return m.QueriedBlocksMaxTime
+	}
+	return 0
+}
+
 func init() {
 	proto.RegisterType((*Stats)(nil), "stats.Stats")
 }
@@ -161,32 +224,41 @@ func init() {
 func init() { proto.RegisterFile("stats.proto", fileDescriptor_b4756a0aec8b9d44) }
 
 var fileDescriptor_b4756a0aec8b9d44 = []byte{
-	// 394 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x8c, 0x92, 0xb1, 0x4e, 0xc2, 0x40,
-	0x18, 0xc7, 0x7b, 0x0a, 0x08, 0x87, 0x68, 0xac, 0xc4, 0x54, 0x86, 0x83, 0xe8, 0x20, 0x53, 0x31,
-	0xea, 0xe6, 0x62, 0x0a, 0x8b, 0xa3, 0xe0, 0xe4, 0xd2, 0x94, 0xf6, 0x28, 0x8d, 0xa5, 0x07, 0xbd,
-	0x6b, 0xd4, 0xcd, 0x47, 0x70, 0x34, 0xf1, 0x05, 0x7c, 0x14, 0x46, 0x46, 0x26, 0x95, 0xb2, 0x38,
-	0xf2, 0x08, 0xe6, 0xee, 0x5a, 0x02, 0x4e, 0x6c, 0xbd, 0xef, 0xf7, 0xfd, 0xee, 0xff, 0xe5, 0xbe,
-	0xc2, 0x22, 0x65, 0x16, 0xa3, 0xfa, 0x30, 0x24, 0x8c, 0xa8, 0x59, 0x71, 0xa8, 0x94, 0x5d, 0xe2,
-	0x12, 0x51, 0x69, 0xf0, 0x2f, 0x09, 0x2b, 0xc8, 0x25, 0xc4, 0xf5, 0x71, 0x43, 0x9c, 0xba, 0x51,
-	0xaf, 0xe1, 0x44, 0xa1, 0xc5, 0x3c, 0x12, 0x48, 0x7e, 0xf2, 0x91, 0x81, 0xd9, 0x0e, 0xf7, 0xd5,
-	0x1b, 0x58, 0x78, 0xb2, 0x7c, 0xdf, 0x64, 0xde, 0x00, 0x6b, 0xa0, 0x06, 0xea, 0xc5, 0x8b, 0x63,
-	0x5d, 0xda, 0x7a, 0x6a, 0xeb, 0xad, 0xc4, 0x36, 0xf2, 0xe3, 0xaf, 0xaa, 0xf2, 0xfe, 0x5d, 0x05,
-	0xed, 0x3c, 0xb7, 0xee, 0xbd, 0x01, 0x56, 0xcf, 0x61, 0xb9, 0x87, 0x99, 0xdd, 0xc7, 0x8e, 0x49,
-	0x71, 0xe8, 0x61, 0x6a, 0xda, 0x24, 0x0a, 0x98, 0xb6, 0x55, 0x03, 0xf5, 0x4c, 0x5b, 0x4d, 0x58,
-	0x47, 0xa0, 0x26, 0x27, 0xaa, 0x0e, 0x0f, 0x53, 0xc3, 0xee, 0x47, 0xc1, 0xa3, 0xd9, 0x7d, 0x61,
-	0x98, 0x6a, 0xdb, 0x42, 0x38, 0x48, 0x50, 0x93, 0x13, 0x83, 0x83, 0xd5, 0x04, 0xd1, 0x9f, 0x26,
-	0x64, 0xd6, 0x12, 0x84, 0x90, 0x24, 0x9c, 0xc1, 0x7d, 0xda, 0xb7, 0x42, 0x07, 0x3b, 0xe6, 0x28,
-	0x12, 0xc9, 0x5a, 0xb6, 0x06, 0xea, 0xa5, 0xf6, 0x5e, 0x52, 0xbe, 0x93, 0x55, 0xf5, 0x14, 0x96,
-	0xe8, 0xd0, 0xf7, 0xd8, 0xb2, 0x2d, 0x27, 0xda, 0x76, 0x45, 0x31, 0x6d, 0x5a, 0x99, 0xd7, 0x0b,
-	0x1c, 0xfc, 0x9c, 0xcc, 0xbb, 0xb3, 0x36, 0xef, 0x2d, 0x27, 0x72, 0xde, 0x2b, 0x78, 0x84, 0x29,
-	0xf3, 0x06, 0x16, 0xfb, 0xff, 0x26, 0x79, 0xa1, 0x94, 0x97, 0x74, 0xf5, 0x55, 0x0c, 0x08, 0x47,
-	0x11, 0x8e, 0xb0, 0x5c, 0x45, 0x61, 0xf3, 0x55, 0x14, 0x84, 0x26, 0x76, 0xd1, 0x82, 0x45, 0x1c,
-	0xd8, 0xc4, 0x49, 0x2e, 0x81, 0x9b, 0x5f, 0x02, 0xa5, 0xc7, 0x6f, 0x31, 0xae, 0x27, 0x33, 0xa4,
-	0x4c, 0x67, 0x48, 0x59, 0xcc, 0x10, 0x78, 0x8d, 0x11, 0xf8, 0x8c, 0x11, 0x18, 0xc7, 0x08, 0x4c,
-	0x62, 0x04, 0x7e, 0x62, 0x04, 0x7e, 0x63, 0xa4, 0x2c, 0x62, 0x04, 0xde, 0xe6, 0x48, 0x99, 0xcc,
-	0x91, 0x32, 0x9d, 0x23, 0xe5, 0x41, 0xfe, 0x90, 0xdd, 0x9c, 0x48, 0xb9, 0xfc, 0x0b, 0x00, 0x00,
-	0xff, 0xff, 0xa6, 0x97, 0xf0, 0x6e, 0xad, 0x02, 0x00, 0x00,
+	// 542 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x8c, 0x54, 0x3d, 0x6f, 0xd3, 0x40,
+	0x18, 0xf6, 0x91, 0xa6, 0x4d, 0x2e, 0x4d, 0x4a, 0x4d, 0x04, 0xa1, 0xc3, 0x25, 0x22, 0x42, 0x78,
+	0x4a, 0x10, 0x1f, 0x13, 0x0b, 0x72, 0x2b, 0x50, 0x25, 0x18, 0x70, 0x60, 0x61, 0xb1, 0x1c, 0xfb,
+	0xe2, 0x9c, 0x6a, 0xfb, 0x5a, 0xdf, 0x59, 0x4d, 0x37, 0x7e, 0x02, 0x23, 0x3f, 0x81, 0x9f, 0xd2,
+	0x31, 0x63, 0x27, 0x20, 0xce, 0xc2, 0xd8, 0x99, 0x09, 0xf9, 0xee, 0xdc, 0xda, 0xad, 0x87, 0x6e,
+	0xf1, 0xfb, 0x7c, 0xbc, 0xcf, 0xdd, 0x73, 0x0a, 0x6c, 0x31, 0xee, 0x70, 0x36, 0x3a, 0x8e, 0x29,
+	0xa7, 0x7a, 0x5d, 0x7c, 0xec, 0x75, 0x7d, 0xea, 0x53, 0x31, 0x19, 0x67, 0xbf, 0x24, 0xb8, 0x87,
+	0x7c, 0x4a, 0xfd, 0x00, 0x8f, 0xc5, 0xd7, 0x34, 0x99, 0x8d, 0xbd, 0x24, 0x76, 0x38, 0xa1, 0x91,
+	0xc4, 0x9f, 0xfc, 0xdb, 0x82, 0xf5, 0x49, 0xa6, 0xd7, 0xdf, 0xc2, 0xe6, 0xa9, 0x13, 0x04, 0x36,
+	0x27, 0x21, 0xee, 0x81, 0x01, 0x30, 0x5a, 0x2f, 0x1e, 0x8f, 0xa4, 0x7a, 0x94, 0xab, 0x47, 0x07,
+	0x4a, 0x6d, 0x36, 0xce, 0x7f, 0xf5, 0xb5, 0x1f, 0xbf, 0xfb, 0xc0, 0x6a, 0x64, 0xaa, 0xcf, 0x24,
+	0xc4, 0xfa, 0x73, 0xd8, 0x9d, 0x61, 0xee, 0xce, 0xb1, 0x67, 0x33, 0x1c, 0x13, 0xcc, 0x6c, 0x97,
+	0x26, 0x11, 0xef, 0xdd, 0x1b, 0x00, 0x63, 0xc3, 0xd2, 0x15, 0x36, 0x11, 0xd0, 0x7e, 0x86, 0xe8,
+	0x23, 0xf8, 0x20, 0x57, 0xb8, 0xf3, 0x24, 0x3a, 0xb2, 0xa7, 0x67, 0x1c, 0xb3, 0x5e, 0x4d, 0x08,
+	0x76, 0x15, 0xb4, 0x9f, 0x21, 0x66, 0x06, 0x14, 0x37, 0x08, 0x7e, 0xbe, 0x61, 0xa3, 0xb4, 0x41,
+	0x08, 0xd4, 0x86, 0x67, 0x70, 0x87, 0xcd, 0x9d, 0xd8, 0xc3, 0x9e, 0x7d, 0x92, 0x88, 0xcd, 0xbd,
+	0xfa, 0x00, 0x18, 0x6d, 0xab, 0xa3, 0xc6, 0x9f, 0xe4, 0x54, 0x1f, 0xc2, 0x36, 0x3b, 0x0e, 0x08,
+	0xbf, 0xa2, 0x6d, 0x0a, 0xda, 0xb6, 0x18, 0xe6, 0xa4, 0x42, 0x5e, 0x12, 0x79, 0x78, 0xa1, 0xf2,
+	0x6e, 0x95, 0xf2, 0x1e, 0x66, 0x88, 0xcc, 0xfb, 0x0a, 0x3e, 0xc4, 0x8c, 0x93, 0xd0, 0xe1, 0x37,
+	0xef, 0xa4, 0x21, 0x24, 0xdd, 0x2b, 0xb4, 0x78, 0x2b, 0x26, 0x84, 0x27, 0x09, 0x4e, 0xb0, 0xac,
+	0xa2, 0x79, 0xf7, 0x2a, 0x9a, 0x42, 0x26, 0xba, 0x38, 0x80, 0x2d, 0x1c, 0xb9, 0xd4, 0x53, 0x26,
+	0xf0, 0xee, 0x26, 0x50, 0xea, 0x84, 0xcb, 0x07, 0x38, 0xbc, 0xd1, 0xe8, 0x2c, 0xa6, 0xa1, 0x4d,
+	0x22, 0x1f, 0x33, 0x8e, 0xe3, 0xfc, 0x30, 0x2d, 0x71, 0x98, 0x7e, 0xa9, 0xe0, 0x77, 0x31, 0x0d,
+	0x0f, 0x73, 0x9e, 0x3c, 0x57, 0xc1, 0x4d, 0xb5, 0x57, 0xe9, 0xb6, 0x5d, 0x72, 0x93, 0x65, 0x56,
+	0xb8, 0x7d, 0x81, 0x46, 0x55, 0x36, 0xc6, 0x69, 0x8c, 0x6d, 0xdf, 0xe1, 0xf8, 0xd4, 0x39, 0xcb,
+	0x2d, 0xdb, 0xc2, 0x72, 0x78, 0x2b, 0xe0, 0x24, 0x23, 0xbf, 0x57, 0xdc, 0x5b, 0xb6, 0xc5, 0x90,
+	0x95, 0xb6, 0x9d, 0x92, 0xed, 0x75, 0xd2, 0x0a, 0xdb, 0xa7, 0xb0, 0x23, 0x1f, 0x96, 0x67, 0x4f,
+	0x03, 0xea, 0x1e, 0xb1, 0xde, 0xce, 0xa0, 0x66, 0x34, 0xad, 0xb6, 0x9a, 0x9a, 0x62, 0xa8, 0xbf,
+	0x86, 0x8f, 0xca, 0x34, 0x3b, 0x24, 0x91, 0xac, 0xf0, 0xfe, 0x00, 0x18, 0x35, 0xab, 0x5b, 0xe2,
+	0x7f, 0x24, 0x91, 0xe8, 0xa9, 0x42, 0xe6, 0x2c, 0xa4, 0x6c, 0xb7, 0x4a, 0xe6, 0x2c, 0x32, 0x99,
+	0xf9, 0x66, 0xb9, 0x42, 0xda, 0xc5, 0x0a, 0x69, 0x97, 0x2b, 0x04, 0xbe, 0xa5, 0x08, 0xfc, 0x4c,
+	0x11, 0x38, 0x4f, 0x11, 0x58, 0xa6, 0x08, 0xfc, 0x49, 0x11, 0xf8, 0x9b, 0x22, 0xed, 0x32, 0x45,
+	0xe0, 0xfb, 0x1a, 0x69, 0xcb, 0x35, 0xd2, 0x2e, 0xd6, 0x48, 0xfb, 0x2a, 0xff, 0x6f, 0xa6, 0x9b,
+	0xe2, 0x11, 0xbd, 0xfc, 0x3f, 0x00, 0xc2, 0x3c, 0xab, 0x34, 0x8c, 0x04, 0x00, 0x00,
 }
 
 func (this *Stats) Equal(that interface{}) bool {
@@ -238,13 +310,39 @@ func (this *Stats) Equal(that interface{}) bool {
 	if this.EncodeTime != that1.EncodeTime {
 		return false
 	}
+	if this.FetchedSeriesFromIngestersCount != that1.FetchedSeriesFromIngestersCount {
+		return false
+	}
+	if this.FetchedChunksFromIngestersCount != that1.FetchedChunksFromIngestersCount {
+		return false
+	}
+	if this.FetchedSeriesFromStoreGatewaysCount != that1.FetchedSeriesFromStoreGatewaysCount {
+		return false
+	}
+	if this.FetchedChunksFromStoreGatewaysCount != that1.FetchedChunksFromStoreGatewaysCount {
+		return false
+	}
+	if len(this.QueriedBlocks) != len(that1.QueriedBlocks) {
+		return false
+	}
+	for i := range this.QueriedBlocks {
+		if this.QueriedBlocks[i] != that1.QueriedBlocks[i] {
+			return false
+		}
+	}
+	if this.QueriedBlocksMinTime != that1.QueriedBlocksMinTime {
+		return false
+	}
+	if this.QueriedBlocksMaxTime != that1.QueriedBlocksMaxTime {
+		return false
+	}
 	return true
 }
 func (this *Stats) GoString() string {
 	if this == nil {
 		return "nil"
 	}
-	s := make([]string, 0, 14)
+	s := make([]string, 0, 21)
 	s = append(s, "&stats.Stats{")
 	s = append(s, "WallTime: "+fmt.Sprintf("%#v", this.WallTime)+",\n")
 	s = append(s, "FetchedSeriesCount: "+fmt.Sprintf("%#v", this.FetchedSeriesCount)+",\n")
@@ -256,6 +354,13 @@ func (this *Stats) GoString() string {
 	s = append(s, "EstimatedSeriesCount: "+fmt.Sprintf("%#v", this.EstimatedSeriesCount)+",\n")
 	s = append(s, "QueueTime: "+fmt.Sprintf("%#v", this.QueueTime)+",\n")
 	s = append(s, "EncodeTime: "+fmt.Sprintf("%#v", this.EncodeTime)+",\n")
+	s = append(s, "FetchedSeriesFromIngestersCount: "+fmt.Sprintf("%#v", this.FetchedSeriesFromIngestersCount)+",\n")
+	s = append(s, "FetchedChunksFromIngestersCount: "+fmt.Sprintf("%#v", this.FetchedChunksFromIngestersCount)+",\n")
+	s = append(s, "FetchedSeriesFromStoreGatewaysCount: "+fmt.Sprintf("%#v", this.FetchedSeriesFromStoreGatewaysCount)+",\n")
+	s = append(s, "FetchedChunksFromStoreGatewaysCount: "+fmt.Sprintf("%#v", this.FetchedChunksFromStoreGatewaysCount)+",\n")
+	s = append(s, "QueriedBlocks: "+fmt.Sprintf("%#v", this.QueriedBlocks)+",\n")
+	s = append(s, "QueriedBlocksMinTime: "+fmt.Sprintf("%#v", this.QueriedBlocksMinTime)+",\n")
+	s = append(s, "QueriedBlocksMaxTime: "+fmt.Sprintf("%#v", this.QueriedBlocksMaxTime)+",\n")
 	s = append(s, "}")
 	return strings.Join(s, "")
 }
@@ -287,6 +392,49 @@ func (m *Stats) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	_ = i
 	var l int
 	_ = l
+	if m.QueriedBlocksMaxTime != 0 {
+		i = encodeVarintStats(dAtA, i, uint64(m.QueriedBlocksMaxTime))
+		i--
+		dAtA[i] = 0x1
+		i--
+		dAtA[i] = 0x88
+	}
+	if m.QueriedBlocksMinTime != 0 {
+		i = encodeVarintStats(dAtA, i, uint64(m.QueriedBlocksMinTime))
+		i--
+		dAtA[i] = 0x1
+		i--
+		dAtA[i] = 0x80
+	}
+	if len(m.QueriedBlocks) > 0 {
+		for iNdEx := len(m.QueriedBlocks) - 1; iNdEx >= 0; iNdEx-- {
+			i -= len(m.QueriedBlocks[iNdEx])
+			copy(dAtA[i:], m.QueriedBlocks[iNdEx])
+			i = encodeVarintStats(dAtA, i, uint64(len(m.QueriedBlocks[iNdEx])))
+			i--
+			dAtA[i] = 0x7a
+		}
+	}
+	if m.FetchedChunksFromStoreGatewaysCount != 0 {
+		i = encodeVarintStats(dAtA, i, uint64(m.FetchedChunksFromStoreGatewaysCount))
+		i--
+		dAtA[i] = 0x70
+	}
+	if m.FetchedSeriesFromStoreGatewaysCount != 0 {
+		i = encodeVarintStats(dAtA, i, uint64(m.FetchedSeriesFromStoreGatewaysCount))
+		i--
+		dAtA[i] = 0x68
+	}
+	if m.FetchedChunksFromIngestersCount != 0 {
+		i = encodeVarintStats(dAtA, i, uint64(m.FetchedChunksFromIngestersCount))
+		i--
+		dAtA[i] = 0x60
+	}
+	if m.FetchedSeriesFromIngestersCount != 0 {
+		i = encodeVarintStats(dAtA, i, uint64(m.FetchedSeriesFromIngestersCount))
+		i--
+		dAtA[i] = 0x58
+	}
 	n1, err1 := github_com_gogo_protobuf_types.StdDurationMarshalTo(m.EncodeTime, dAtA[i-github_com_gogo_protobuf_types.SizeOfStdDuration(m.EncodeTime):])
 	if err1 != nil {
 		return 0, err1
@@ -393,6 +541,30 @@ func (m *Stats) Size() (n int) {
 	n += 1 + l + sovStats(uint64(l))
 	l = github_com_gogo_protobuf_types.SizeOfStdDuration(m.EncodeTime)
 	n += 1 + l + sovStats(uint64(l))
+	if m.FetchedSeriesFromIngestersCount != 0 {
+		n += 1 + sovStats(uint64(m.FetchedSeriesFromIngestersCount))
+	}
+	if m.FetchedChunksFromIngestersCount != 0 {
+		n += 1 + sovStats(uint64(m.FetchedChunksFromIngestersCount))
+	}
+	if m.FetchedSeriesFromStoreGatewaysCount != 0 {
+		n += 1 + sovStats(uint64(m.FetchedSeriesFromStoreGatewaysCount))
+	}
+	if m.FetchedChunksFromStoreGatewaysCount != 0 {
+		n += 1 + sovStats(uint64(m.FetchedChunksFromStoreGatewaysCount))
+	}
+	if len(m.QueriedBlocks) > 0 {
+		for _, s := range m.QueriedBlocks {
+			l = len(s)
+			n += 1 + l + sovStats(uint64(l))
+		}
+	}
+	if m.QueriedBlocksMinTime != 0 {
+		n += 2 + sovStats(uint64(m.QueriedBlocksMinTime))
+	}
+	if m.QueriedBlocksMaxTime != 0 {
+		n += 2 + sovStats(uint64(m.QueriedBlocksMaxTime))
+	}
 	return n
 }
 
@@ -417,6 +589,13 @@ func (this *Stats) String() string {
 		`EstimatedSeriesCount:` + fmt.Sprintf("%v", this.EstimatedSeriesCount) + `,`,
 		`QueueTime:` + strings.Replace(strings.Replace(fmt.Sprintf("%v", this.QueueTime), "Duration", "durationpb.Duration", 1), `&`, ``, 1) + `,`,
 		`EncodeTime:` + strings.Replace(strings.Replace(fmt.Sprintf("%v", this.EncodeTime), "Duration", "durationpb.Duration", 1), `&`, ``, 1) + `,`,
+		`FetchedSeriesFromIngestersCount:` + fmt.Sprintf("%v", this.FetchedSeriesFromIngestersCount) + `,`,
+		`FetchedChunksFromIngestersCount:` + fmt.Sprintf("%v", this.FetchedChunksFromIngestersCount) + `,`,
+		`FetchedSeriesFromStoreGatewaysCount:` + fmt.Sprintf("%v", this.FetchedSeriesFromStoreGatewaysCount) + `,`,
+		`FetchedChunksFromStoreGatewaysCount:` + fmt.Sprintf("%v", this.FetchedChunksFromStoreGatewaysCount) + `,`,
+		`QueriedBlocks:` + fmt.Sprintf("%v", this.QueriedBlocks) + `,`,
+		`QueriedBlocksMinTime:` + fmt.Sprintf("%v", this.QueriedBlocksMinTime) + `,`,
+		`QueriedBlocksMaxTime:` + fmt.Sprintf("%v", this.QueriedBlocksMaxTime) + `,`,
 		`}`,
 	}, "")
 	return s
@@ -690,6 +869,152 @@ func (m *Stats) Unmarshal(dAtA []byte) error {
 				return err
 			}
 			iNdEx = postIndex
+		case 11:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field FetchedSeriesFromIngestersCount", wireType)
+			}
+			m.FetchedSeriesFromIngestersCount = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowStats
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.FetchedSeriesFromIngestersCount |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 12:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field FetchedChunksFromIngestersCount", wireType)
+			}
+			m.FetchedChunksFromIngestersCount = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowStats
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.FetchedChunksFromIngestersCount |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 13:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field FetchedSeriesFromStoreGatewaysCount", wireType)
+			}
+			m.FetchedSeriesFromStoreGatewaysCount = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowStats
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.FetchedSeriesFromStoreGatewaysCount |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 14:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field FetchedChunksFromStoreGatewaysCount", wireType)
+			}
+			m.FetchedChunksFromStoreGatewaysCount = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowStats
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.FetchedChunksFromStoreGatewaysCount |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 15:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field QueriedBlocks", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowStats
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthStats
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthStats
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.QueriedBlocks = append(m.QueriedBlocks, string(dAtA[iNdEx:postIndex]))
+			iNdEx = postIndex
+		case 16:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field QueriedBlocksMinTime", wireType)
+			}
+			m.QueriedBlocksMinTime = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowStats
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.QueriedBlocksMinTime |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 17:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field QueriedBlocksMaxTime", wireType)
+			}
+			m.QueriedBlocksMaxTime = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowStats
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.QueriedBlocksMaxTime |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
 		default:
 			iNdEx = preIndex
 			skippy, err := skipStats(dAtA[iNdEx:])
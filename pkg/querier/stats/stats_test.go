@@ -132,6 +132,69 @@ func TestStats_QueueTime(t *testing.T) {
 	})
 }
 
+func TestStats_AddFetchedFromIngestersAndStoreGateways(t *testing.T) {
+	t.Run("add and load series and chunks", func(t *testing.T) {
+		stats, _ := ContextWithEmptyStats(context.Background())
+		stats.AddFetchedFromIngesters(10, 20)
+		stats.AddFetchedFromIngesters(1, 2)
+		stats.AddFetchedFromStoreGateways(30, 40)
+
+		assert.Equal(t, uint64(11), stats.LoadFetchedSeriesFromIngesters())
+		assert.Equal(t, uint64(22), stats.LoadFetchedChunksFromIngesters())
+		assert.Equal(t, uint64(30), stats.LoadFetchedSeriesFromStoreGateways())
+		assert.Equal(t, uint64(40), stats.LoadFetchedChunksFromStoreGateways())
+	})
+
+	t.Run("add and load series and chunks nil receiver", func(t *testing.T) {
+		var stats *Stats
+		stats.AddFetchedFromIngesters(10, 20)
+		stats.AddFetchedFromStoreGateways(30, 40)
+
+		assert.Equal(t, uint64(0), stats.LoadFetchedSeriesFromIngesters())
+		assert.Equal(t, uint64(0), stats.LoadFetchedChunksFromStoreGateways())
+	})
+}
+
+func TestStats_AddQueriedBlocks(t *testing.T) {
+	t.Run("add and load queried blocks", func(t *testing.T) {
+		stats, _ := ContextWithEmptyStats(context.Background())
+		stats.AddQueriedBlocks(nil, 10, 20)
+		_, _, ok := stats.LoadQueriedBlocksTimeRange()
+		assert.Equal(t, uint64(0), stats.LoadQueriedBlocks())
+		assert.False(t, ok)
+
+		stats.AddQueriedBlocks([]string{"block-1", "block-2"}, 100, 200)
+		stats.AddQueriedBlocks([]string{"block-3"}, 50, 150)
+		stats.AddQueriedBlocks([]string{"block-1", "block-3", "block-4"}, 120, 300)
+
+		minT, maxT, ok := stats.LoadQueriedBlocksTimeRange()
+		assert.Equal(t, uint64(4), stats.LoadQueriedBlocks())
+		assert.True(t, ok)
+		assert.Equal(t, int64(50), minT)
+		assert.Equal(t, int64(300), maxT)
+	})
+
+	t.Run("add and load queried blocks at the zero timestamp", func(t *testing.T) {
+		stats, _ := ContextWithEmptyStats(context.Background())
+		stats.AddQueriedBlocks([]string{"block-1"}, 0, 100)
+		stats.AddQueriedBlocks([]string{"block-2"}, 10, 200)
+
+		minT, maxT, ok := stats.LoadQueriedBlocksTimeRange()
+		assert.True(t, ok)
+		assert.Equal(t, int64(0), minT)
+		assert.Equal(t, int64(200), maxT)
+	})
+
+	t.Run("add and load queried blocks nil receiver", func(t *testing.T) {
+		var stats *Stats
+		stats.AddQueriedBlocks([]string{"block-1", "block-2"}, 100, 200)
+
+		_, _, ok := stats.LoadQueriedBlocksTimeRange()
+		assert.Equal(t, uint64(0), stats.LoadQueriedBlocks())
+		assert.False(t, ok)
+	})
+}
+
 func TestStats_Merge(t *testing.T) {
 	t.Run("merge two stats objects", func(t *testing.T) {
 		stats1 := &Stats{}
@@ -142,6 +205,9 @@ func TestStats_Merge(t *testing.T) {
 		stats1.AddShardedQueries(20)
 		stats1.AddSplitQueries(10)
 		stats1.AddQueueTime(5 * time.Second)
+		stats1.AddFetchedFromIngesters(20, 4)
+		stats1.AddFetchedFromStoreGateways(30, 6)
+		stats1.AddQueriedBlocks([]string{"block-1", "block-2"}, 100, 200)
 
 		stats2 := &Stats{}
 		stats2.AddWallTime(time.Second)
@@ -151,6 +217,8 @@ func TestStats_Merge(t *testing.T) {
 		stats2.AddShardedQueries(21)
 		stats2.AddSplitQueries(11)
 		stats2.AddQueueTime(10 * time.Second)
+		stats2.AddFetchedFromIngesters(60, 11)
+		stats2.AddQueriedBlocks([]string{"block-2", "block-3"}, 50, 150)
 
 		stats1.Merge(stats2)
 
@@ -161,6 +229,15 @@ func TestStats_Merge(t *testing.T) {
 		assert.Equal(t, uint32(41), stats1.LoadShardedQueries())
 		assert.Equal(t, uint32(21), stats1.LoadSplitQueries())
 		assert.Equal(t, 15*time.Second, stats1.LoadQueueTime())
+		assert.Equal(t, uint64(80), stats1.LoadFetchedSeriesFromIngesters())
+		assert.Equal(t, uint64(15), stats1.LoadFetchedChunksFromIngesters())
+		assert.Equal(t, uint64(30), stats1.LoadFetchedSeriesFromStoreGateways())
+		assert.Equal(t, uint64(6), stats1.LoadFetchedChunksFromStoreGateways())
+		assert.Equal(t, uint64(3), stats1.LoadQueriedBlocks())
+		minT, maxT, ok := stats1.LoadQueriedBlocksTimeRange()
+		assert.True(t, ok)
+		assert.Equal(t, int64(50), minT)
+		assert.Equal(t, int64(200), maxT)
 	})
 
 	t.Run("merge two nil stats objects", func(t *testing.T) {
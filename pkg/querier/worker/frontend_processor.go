@@ -157,7 +157,18 @@ func (fp *frontendProcessor) runRequest(ctx context.Context, request *httpgrpc.H
 		stats.AddQueueTime(queueTime)
 	}
 
-	response, err := fp.handler.Handle(ctx, request)
+	handlerCtx, cancelHandlerCtx, budgetLeft := withQueryTimeoutBudget(ctx, request, queueTime)
+	defer cancelHandlerCtx()
+
+	var (
+		response *httpgrpc.HTTPResponse
+		err      error
+	)
+	if budgetLeft {
+		response, err = fp.handler.Handle(handlerCtx, request)
+	} else {
+		err = errQueryTimeoutBudgetExhausted
+	}
 	if err != nil {
 		var ok bool
 		response, ok = httpgrpc.HTTPResponseFromError(err)
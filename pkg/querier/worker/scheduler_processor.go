@@ -267,7 +267,18 @@ func (sp *schedulerProcessor) runRequest(ctx context.Context, logger log.Logger,
 		stats.AddQueueTime(queueTime)
 	}
 
-	response, err := sp.handler.Handle(ctx, request)
+	handlerCtx, cancelHandlerCtx, budgetLeft := withQueryTimeoutBudget(ctx, request, queueTime)
+	defer cancelHandlerCtx()
+
+	var (
+		response *httpgrpc.HTTPResponse
+		err      error
+	)
+	if budgetLeft {
+		response, err = sp.handler.Handle(handlerCtx, request)
+	} else {
+		err = errQueryTimeoutBudgetExhausted
+	}
 	if err != nil {
 		var ok bool
 		response, ok = httpgrpc.HTTPResponseFromError(err)
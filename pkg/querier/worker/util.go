@@ -4,6 +4,7 @@ package worker
 
 import (
 	"context"
+	"net/http"
 	"strings"
 	"time"
 
@@ -11,10 +12,15 @@ import (
 	"github.com/go-kit/log/level"
 	"github.com/grafana/dskit/cancellation"
 	"github.com/grafana/dskit/grpcutil"
+	"github.com/grafana/dskit/httpgrpc"
 	"go.uber.org/atomic"
 	"google.golang.org/grpc/codes"
+
+	querierapi "github.com/grafana/mimir/pkg/querier/api"
 )
 
+var errQueryTimeoutBudgetExhausted = httpgrpc.Errorf(http.StatusGatewayTimeout, "query timeout budget exhausted while the query was waiting in the queue")
+
 // newExecutionContext returns a new execution context (execCtx) that wraps the input workerCtx and
 // it used to run the querier's worker loop and execute queries.
 // The purpose of the execution context is to gracefully shutdown queriers, waiting
@@ -73,3 +79,36 @@ func isErrCancel(err error, logger log.Logger) bool {
 	}
 	return false
 }
+
+// withQueryTimeoutBudget returns a context whose deadline honors the timeout budget propagated by the query-frontend
+// in the request, reduced by the time the request has spent in the queue. The deadline is then propagated to ingesters
+// and store-gateways through the gRPC requests issued by the querier, so that they stop working on the query too once
+// the budget has been exhausted.
+//
+// The returned bool is false if the budget has already been exhausted, in which case the query shouldn't be executed.
+// The caller must call the returned cancel function once done.
+func withQueryTimeoutBudget(ctx context.Context, request *httpgrpc.HTTPRequest, queueTime time.Duration) (context.Context, context.CancelFunc, bool) {
+	var (
+		budget time.Duration
+		found  bool
+	)
+
+	for _, h := range request.GetHeaders() {
+		if strings.EqualFold(h.Key, querierapi.QueryTimeoutBudgetHeader) && len(h.Values) > 0 {
+			budget, found = querierapi.DecodeQueryTimeoutBudget(h.Values[0])
+			break
+		}
+	}
+
+	if !found {
+		return ctx, func() {}, true
+	}
+
+	budget -= queueTime
+	if budget <= 0 {
+		return ctx, func() {}, false
+	}
+
+	ctx, cancel := context.WithTimeoutCause(ctx, budget, cancellation.NewErrorf("query timeout budget exhausted (budget left after queueing: %v)", budget))
+	return ctx, cancel, true
+}
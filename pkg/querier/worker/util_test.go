@@ -5,9 +5,16 @@ package worker
 import (
 	"context"
 	"errors"
+	"testing"
+	"time"
 
 	"github.com/gogo/status"
+	"github.com/grafana/dskit/httpgrpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"google.golang.org/grpc/codes"
+
+	querierapi "github.com/grafana/mimir/pkg/querier/api"
 )
 
 // This function emulates the error-translating behaviour of google.golang.org/grpc.toRPCErr(),
@@ -29,3 +36,51 @@ func toRPCErr(err error) error {
 
 	return status.Error(codes.Unknown, err.Error())
 }
+
+func TestWithQueryTimeoutBudget(t *testing.T) {
+	requestWithBudget := func(budget string) *httpgrpc.HTTPRequest {
+		return &httpgrpc.HTTPRequest{Headers: []*httpgrpc.Header{{Key: querierapi.QueryTimeoutBudgetHeader, Values: []string{budget}}}}
+	}
+
+	for name, tc := range map[string]struct {
+		request            *httpgrpc.HTTPRequest
+		queueTime          time.Duration
+		expectedBudgetLeft bool
+		expectedDeadline   time.Duration
+	}{
+		"no timeout budget": {
+			request:            &httpgrpc.HTTPRequest{},
+			queueTime:          time.Second,
+			expectedBudgetLeft: true,
+		},
+		"invalid timeout budget": {
+			request:            requestWithBudget("invalid"),
+			queueTime:          time.Second,
+			expectedBudgetLeft: true,
+		},
+		"timeout budget reduced by the queue time": {
+			request:            requestWithBudget(querierapi.EncodeQueryTimeoutBudget(time.Minute)),
+			queueTime:          10 * time.Second,
+			expectedBudgetLeft: true,
+			expectedDeadline:   50 * time.Second,
+		},
+		"timeout budget exhausted while queueing": {
+			request:            requestWithBudget(querierapi.EncodeQueryTimeoutBudget(time.Second)),
+			queueTime:          2 * time.Second,
+			expectedBudgetLeft: false,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			ctx, cancel, budgetLeft := withQueryTimeoutBudget(context.Background(), tc.request, tc.queueTime)
+			defer cancel()
+
+			require.Equal(t, tc.expectedBudgetLeft, budgetLeft)
+
+			deadline, hasDeadline := ctx.Deadline()
+			require.Equal(t, tc.expectedDeadline > 0, hasDeadline)
+			if hasDeadline {
+				assert.WithinDuration(t, time.Now().Add(tc.expectedDeadline), deadline, time.Second)
+			}
+		})
+	}
+}
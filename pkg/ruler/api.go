@@ -6,11 +6,15 @@
 package ruler
 
 import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
@@ -24,6 +28,7 @@ import (
 	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
 	"github.com/prometheus/prometheus/model/labels"
 	"github.com/prometheus/prometheus/model/rulefmt"
+	promRules "github.com/prometheus/prometheus/rules"
 	"gopkg.in/yaml.v3"
 
 	"github.com/grafana/mimir/pkg/mimirpb"
@@ -63,7 +68,14 @@ type Alert struct {
 
 // RuleDiscovery has info for all rules
 type RuleDiscovery struct {
-	RuleGroups []*RuleGroup `json:"groups"`
+	RuleGroups     []*RuleGroup `json:"groups"`
+	GroupNextToken string       `json:"groupNextToken,omitempty"`
+	// ChangeToken identifies the content of the returned rule groups. It changes whenever the
+	// definition or the state of a returned rule changes, but not on every evaluation.
+	ChangeToken string `json:"changeToken,omitempty"`
+	// Unchanged is true if the rule groups have been omitted because the change token of the
+	// response matches the one passed by the client.
+	Unchanged bool `json:"unchanged,omitempty"`
 }
 
 // RuleGroup has info for rules which are part of a group
@@ -78,6 +90,9 @@ type RuleGroup struct {
 	LastEvaluation time.Time `json:"lastEvaluation"`
 	EvaluationTime float64   `json:"evaluationTime"`
 	SourceTenants  []string  `json:"sourceTenants"`
+	// QueryOffset is the offset, in seconds, the rules of the group are evaluated with. It's either
+	// the query_offset (or deprecated evaluation_delay) of the group, or the tenant's default.
+	QueryOffset float64 `json:"queryOffset,omitempty"`
 }
 
 type rule interface{}
@@ -97,6 +112,12 @@ type alertingRule struct {
 	Type           v1.RuleType   `json:"type"`
 	LastEvaluation time.Time     `json:"lastEvaluation"`
 	EvaluationTime float64       `json:"evaluationTime"`
+	// Rule dependencies within the group, as analysed by the ruler. Rules with no dependent and no dependency rules
+	// can be evaluated concurrently.
+	NoDependentRules  bool     `json:"noDependentRules"`
+	NoDependencyRules bool     `json:"noDependencyRules"`
+	DependentRules    []string `json:"dependentRules,omitempty"`
+	DependencyRules   []string `json:"dependencyRules,omitempty"`
 }
 
 type recordingRule struct {
@@ -108,6 +129,12 @@ type recordingRule struct {
 	Type           v1.RuleType   `json:"type"`
 	LastEvaluation time.Time     `json:"lastEvaluation"`
 	EvaluationTime float64       `json:"evaluationTime"`
+	// Rule dependencies within the group, as analysed by the ruler. Rules with no dependent and no dependency rules
+	// can be evaluated concurrently.
+	NoDependentRules  bool     `json:"noDependentRules"`
+	NoDependencyRules bool     `json:"noDependencyRules"`
+	DependentRules    []string `json:"dependentRules,omitempty"`
+	DependencyRules   []string `json:"dependencyRules,omitempty"`
 }
 
 func respondError(logger log.Logger, w http.ResponseWriter, status int, errorType v1.ErrorType, msg string) {
@@ -140,17 +167,20 @@ func respondServerError(logger log.Logger, w http.ResponseWriter, msg string) {
 
 // API is used to handle HTTP requests for the ruler service
 type API struct {
-	ruler *Ruler
-	store rulestore.RuleStore
+	ruler  *Ruler
+	store  rulestore.RuleStore
+	dryRun *DryRunEvaluator
 
 	logger log.Logger
 }
 
-// NewAPI returns a new API struct with the provided ruler and rule store
-func NewAPI(r *Ruler, s rulestore.RuleStore, logger log.Logger) *API {
+// NewAPI returns a new API struct with the provided ruler, rule store and dry-run evaluator.
+// The dry-run evaluation of rule groups is not supported if dryRun is nil.
+func NewAPI(r *Ruler, s rulestore.RuleStore, dryRun *DryRunEvaluator, logger log.Logger) *API {
 	return &API{
 		ruler:  r,
 		store:  s,
+		dryRun: dryRun,
 		logger: logger,
 	}
 }
@@ -172,12 +202,25 @@ func (a *API) PrometheusRules(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	health, err := parseRuleHealth(req)
+	if err != nil {
+		respondInvalidRequest(logger, w, err.Error())
+		return
+	}
+
+	groupLimit, groupNextToken, err := parseGroupPagination(req)
+	if err != nil {
+		respondInvalidRequest(logger, w, err.Error())
+		return
+	}
+
 	rulesReq := RulesRequest{
 		Filter:        AnyRule,
 		RuleName:      req.URL.Query()["rule_name"],
 		RuleGroup:     req.URL.Query()["rule_group"],
 		File:          req.URL.Query()["file"],
 		ExcludeAlerts: excludeAlerts,
+		Health:        health,
 	}
 
 	ruleTypeFilter := strings.ToLower(req.URL.Query().Get("type"))
@@ -193,6 +236,13 @@ func (a *API) PrometheusRules(w http.ResponseWriter, req *http.Request) {
 		}
 	}
 
+	scope, err := a.ruler.RequestNamespaceScope(userID, req.Header)
+	if err != nil {
+		level.Warn(logger).Log("msg", "not allowed to access rules", "err", err.Error())
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	rgs, err := a.ruler.GetRules(ctx, rulesReq)
 
@@ -201,6 +251,9 @@ func (a *API) PrometheusRules(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	// Only return the rule groups of the namespaces within the request's scope.
+	rgs = filterGroupsByNamespaceScope(rgs, scope)
+
 	groups := make([]*RuleGroup, 0, len(rgs))
 
 	for _, g := range rgs {
@@ -212,6 +265,7 @@ func (a *API) PrometheusRules(w http.ResponseWriter, req *http.Request) {
 			LastEvaluation: g.GetEvaluationTimestamp(),
 			EvaluationTime: g.GetEvaluationDuration().Seconds(),
 			SourceTenants:  g.Group.GetSourceTenants(),
+			QueryOffset:    g.Group.GetQueryOffset().Seconds(),
 		}
 
 		for i, rl := range g.ActiveRules {
@@ -237,6 +291,11 @@ func (a *API) PrometheusRules(w http.ResponseWriter, req *http.Request) {
 					LastEvaluation: rl.GetEvaluationTimestamp(),
 					EvaluationTime: rl.GetEvaluationDuration().Seconds(),
 					Type:           v1.RuleTypeAlerting,
+
+					NoDependentRules:  rl.GetNoDependentRules(),
+					NoDependencyRules: rl.GetNoDependencyRules(),
+					DependentRules:    rl.GetDependentRules(),
+					DependencyRules:   rl.GetDependencyRules(),
 				}
 			} else {
 				grp.Rules[i] = recordingRule{
@@ -248,6 +307,11 @@ func (a *API) PrometheusRules(w http.ResponseWriter, req *http.Request) {
 					LastEvaluation: rl.GetEvaluationTimestamp(),
 					EvaluationTime: rl.GetEvaluationDuration().Seconds(),
 					Type:           v1.RuleTypeRecording,
+
+					NoDependentRules:  rl.GetNoDependentRules(),
+					NoDependencyRules: rl.GetNoDependencyRules(),
+					DependentRules:    rl.GetDependentRules(),
+					DependencyRules:   rl.GetDependencyRules(),
 				}
 			}
 		}
@@ -256,12 +320,28 @@ func (a *API) PrometheusRules(w http.ResponseWriter, req *http.Request) {
 
 	// keep data.groups are in order
 	sort.Slice(groups, func(i, j int) bool {
-		return groups[i].File < groups[j].File
+		if groups[i].File != groups[j].File {
+			return groups[i].File < groups[j].File
+		}
+		return groups[i].Name < groups[j].Name
 	})
 
+	discovery := &RuleDiscovery{}
+	discovery.RuleGroups, discovery.GroupNextToken, err = paginateRuleGroups(groups, groupLimit, groupNextToken)
+	if err != nil {
+		respondInvalidRequest(logger, w, err.Error())
+		return
+	}
+
+	discovery.ChangeToken = ruleGroupsChangeToken(discovery.RuleGroups)
+	if sinceToken := req.URL.Query().Get("since_token"); sinceToken != "" && sinceToken == discovery.ChangeToken {
+		discovery.RuleGroups = []*RuleGroup{}
+		discovery.Unchanged = true
+	}
+
 	b, err := json.Marshal(&response{
 		Status: "success",
-		Data:   &RuleDiscovery{RuleGroups: groups},
+		Data:   discovery,
 	})
 	if err != nil {
 		level.Error(logger).Log("msg", "error marshaling json response", "err", err)
@@ -275,6 +355,97 @@ func (a *API) PrometheusRules(w http.ResponseWriter, req *http.Request) {
 	}
 }
 
+func parseRuleHealth(req *http.Request) ([]string, error) {
+	health := req.URL.Query()["health"]
+	for _, h := range health {
+		switch promRules.RuleHealth(h) {
+		case promRules.HealthGood, promRules.HealthBad, promRules.HealthUnknown:
+		default:
+			return nil, fmt.Errorf("not supported health value %q", h)
+		}
+	}
+	return health, nil
+}
+
+func parseGroupPagination(req *http.Request) (int, string, error) {
+	nextToken := req.URL.Query().Get("group_next_token")
+
+	limitParam := req.URL.Query().Get("group_limit")
+	if limitParam == "" {
+		if nextToken != "" {
+			return 0, "", errors.New("group_limit needs to be set in order to use group_next_token")
+		}
+		return 0, "", nil
+	}
+
+	limit, err := strconv.Atoi(limitParam)
+	if err != nil || limit <= 0 {
+		return 0, "", errors.New("group_limit needs to be greater than 0")
+	}
+	return limit, nextToken, nil
+}
+
+// paginateRuleGroups returns the page of at most limit groups starting from the group identified by nextToken,
+// and the token of the group starting the next page, if any. The input groups must be sorted by file and name.
+func paginateRuleGroups(groups []*RuleGroup, limit int, nextToken string) ([]*RuleGroup, string, error) {
+	if limit <= 0 {
+		return groups, "", nil
+	}
+
+	start := 0
+	if nextToken != "" {
+		start = -1
+		for i, g := range groups {
+			if ruleGroupNextToken(g.File, g.Name) == nextToken {
+				start = i
+				break
+			}
+		}
+		if start < 0 {
+			return nil, "", errors.New("invalid group_next_token")
+		}
+	}
+
+	end := start + limit
+	if end >= len(groups) {
+		return groups[start:], "", nil
+	}
+	return groups[start:end], ruleGroupNextToken(groups[end].File, groups[end].Name), nil
+}
+
+func ruleGroupNextToken(file, group string) string {
+	h := sha1.New()
+	_, _ = h.Write([]byte(file + ";" + group))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// ruleGroupsChangeToken returns a token identifying the content of the rule groups, ignoring
+// the evaluation timestamps and durations which change on every evaluation.
+func ruleGroupsChangeToken(groups []*RuleGroup) string {
+	h := sha256.New()
+	enc := json.NewEncoder(h)
+	for _, g := range groups {
+		grp := *g
+		grp.LastEvaluation, grp.EvaluationTime = time.Time{}, 0
+		grp.Rules = make([]rule, 0, len(g.Rules))
+		for _, r := range g.Rules {
+			switch r := r.(type) {
+			case alertingRule:
+				r.LastEvaluation, r.EvaluationTime = time.Time{}, 0
+				grp.Rules = append(grp.Rules, r)
+			case recordingRule:
+				r.LastEvaluation, r.EvaluationTime = time.Time{}, 0
+				grp.Rules = append(grp.Rules, r)
+			default:
+				grp.Rules = append(grp.Rules, r)
+			}
+		}
+		// Rule groups only contain values which can be encoded to JSON, so this can't fail.
+		_ = enc.Encode(&grp)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
 func parseExcludeAlerts(req *http.Request) (bool, error) {
 	excludeAlerts := req.URL.Query().Get("exclude_alerts")
 	if excludeAlerts == "" {
@@ -301,6 +472,13 @@ func (a *API) PrometheusAlerts(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	scope, err := a.ruler.RequestNamespaceScope(userID, req.Header)
+	if err != nil {
+		level.Warn(logger).Log("msg", "not allowed to access alerts", "err", err.Error())
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	rgs, err := a.ruler.GetRules(ctx, RulesRequest{Filter: AlertingRule})
 
@@ -309,6 +487,9 @@ func (a *API) PrometheusAlerts(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	// Only return the alerts of the namespaces within the request's scope.
+	rgs = filterGroupsByNamespaceScope(rgs, scope)
+
 	alerts := []*Alert{}
 
 	for _, g := range rgs {
@@ -472,6 +653,16 @@ func (a *API) ListRules(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	scope, err := a.ruler.RequestNamespaceScope(userID, req.Header)
+	if err == nil && namespace != "" && !scope.Contains(namespace) {
+		err = ErrNamespaceOutOfScope
+	}
+	if err != nil {
+		level.Warn(logger).Log("msg", "not allowed to access namespace", "namespace", namespace, "err", err.Error())
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
 	level.Debug(logger).Log("msg", "retrieving rule groups with namespace", "userID", userID, "namespace", namespace)
 	rgs, err := a.store.ListRuleGroupsForUserAndNamespace(ctx, userID, namespace)
 	if err != nil {
@@ -479,6 +670,11 @@ func (a *API) ListRules(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	// Only list the rule groups of the namespaces within the request's scope.
+	if scope != nil {
+		rgs = slices.DeleteFunc(rgs, func(rg *rulespb.RuleGroupDesc) bool { return !scope.Contains(rg.Namespace) })
+	}
+
 	if len(rgs) == 0 {
 		level.Info(logger).Log("msg", "no rule groups found", "userID", userID)
 		// No rule groups, short-circuit and just return an empty map with HTTP 200
@@ -528,6 +724,12 @@ func (a *API) GetRuleGroup(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	if err := a.ruler.AllowNamespaceScope(userID, req.Header, namespace); err != nil {
+		level.Warn(logger).Log("msg", "not allowed to access namespace", "namespace", namespace, "err", err.Error())
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
 	rg, err := a.store.GetRuleGroup(ctx, userID, namespace, groupName)
 	if err != nil {
 		if errors.Is(err, rulestore.ErrGroupNotFound) {
@@ -561,6 +763,12 @@ func (a *API) CreateRuleGroup(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	if err := a.ruler.AllowNamespaceScope(userID, req.Header, namespace); err != nil {
+		level.Warn(logger).Log("msg", "not allowed to access namespace", "namespace", namespace, "err", err.Error())
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
 	if a.ruler.IsNamespaceProtected(userID, namespace) {
 		if err = AllowProtectionOverride(req.Header, namespace); err != nil {
 			level.Warn(logger).Log("msg", "not allowed to create rule group under namespace", "err", err.Error())
@@ -631,10 +839,89 @@ func (a *API) CreateRuleGroup(w http.ResponseWriter, req *http.Request) {
 	}
 
 	a.ruler.NotifySyncRulesAsync(userID)
+	a.recordRuleNamespaceVersion(ctx, logger, req, userID, namespace)
 
 	respondAccepted(w, logger)
 }
 
+// DryRunRuleGroup evaluates the rule group in the request body once, without storing it,
+// writing the results of its recording rules or sending the alerts of its alerting rules.
+func (a *API) DryRunRuleGroup(w http.ResponseWriter, req *http.Request) {
+	logger, ctx := spanlogger.NewWithLogger(req.Context(), a.logger, "API.DryRunRuleGroup")
+	defer logger.Finish()
+
+	if a.dryRun == nil {
+		respondError(logger, w, http.StatusNotImplemented, v1.ErrServer, "rule group dry-run evaluation is not supported")
+		return
+	}
+
+	userID, namespace, _, err := a.parseRequest(req, true, false)
+	if err != nil {
+		if errors.Is(err, errNoValidOrgIDFound) {
+			respondInvalidRequest(logger, w, err.Error())
+			return
+		}
+		respondServerError(logger, w, err.Error())
+		return
+	}
+
+	if err := a.ruler.AllowNamespaceScope(userID, req.Header, namespace); err != nil {
+		level.Warn(logger).Log("msg", "not allowed to access namespace", "namespace", namespace, "err", err.Error())
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	payload, err := io.ReadAll(req.Body)
+	if err != nil {
+		level.Error(logger).Log("msg", "unable to read rule group payload", "err", err.Error())
+		respondInvalidRequest(logger, w, err.Error())
+		return
+	}
+
+	rg := rulefmt.RuleGroup{}
+	if err := yaml.Unmarshal(payload, &rg); err != nil {
+		level.Error(logger).Log("msg", "unable to unmarshal rule group payload", "err", err.Error())
+		respondInvalidRequest(logger, w, ErrBadRuleGroup.Error())
+		return
+	}
+
+	if errs := a.ruler.manager.ValidateRuleGroup(rg); len(errs) > 0 {
+		e := make([]string, 0, len(errs))
+		for _, err := range errs {
+			e = append(e, err.Error())
+		}
+		respondInvalidRequest(logger, w, strings.Join(e, ", "))
+		return
+	}
+
+	if err := a.ruler.AssertMaxRulesPerRuleGroup(userID, namespace, len(rg.Rules)); err != nil {
+		level.Error(logger).Log("msg", "limit validation failure", "err", err.Error(), "user", userID)
+		respondInvalidRequest(logger, w, err.Error())
+		return
+	}
+
+	res, err := a.dryRun.Evaluate(ctx, userID, rg, time.Now())
+	if err != nil {
+		respondInvalidRequest(logger, w, err.Error())
+		return
+	}
+
+	b, err := json.Marshal(&response{
+		Status: "success",
+		Data:   res,
+	})
+	if err != nil {
+		level.Error(logger).Log("msg", "error marshaling json response", "err", err)
+		respondServerError(logger, w, "unable to marshal the requested data")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if n, err := w.Write(b); err != nil {
+		level.Error(logger).Log("msg", "error writing response", "bytesWritten", n, "err", err)
+	}
+}
+
 func (a *API) DeleteNamespace(w http.ResponseWriter, req *http.Request) {
 	logger, ctx := spanlogger.NewWithLogger(req.Context(), a.logger, "API.DeleteNamespace")
 	defer logger.Finish()
@@ -649,6 +936,12 @@ func (a *API) DeleteNamespace(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	if err := a.ruler.AllowNamespaceScope(userID, req.Header, namespace); err != nil {
+		level.Warn(logger).Log("msg", "not allowed to access namespace", "namespace", namespace, "err", err.Error())
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
 	if a.ruler.IsNamespaceProtected(userID, namespace) {
 		if err = AllowProtectionOverride(req.Header, namespace); err != nil {
 			level.Warn(logger).Log("msg", "not allowed to delete namespace", "err", err.Error())
@@ -668,6 +961,7 @@ func (a *API) DeleteNamespace(w http.ResponseWriter, req *http.Request) {
 	}
 
 	a.ruler.NotifySyncRulesAsync(userID)
+	a.recordRuleNamespaceVersion(ctx, logger, req, userID, namespace)
 
 	respondAccepted(w, logger)
 }
@@ -686,6 +980,12 @@ func (a *API) DeleteRuleGroup(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	if err := a.ruler.AllowNamespaceScope(userID, req.Header, namespace); err != nil {
+		level.Warn(logger).Log("msg", "not allowed to access namespace", "namespace", namespace, "err", err.Error())
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
 	if a.ruler.IsNamespaceProtected(userID, namespace) {
 		if err = AllowProtectionOverride(req.Header, namespace); err != nil {
 			level.Warn(logger).Log("msg", "not allowed to delete rule group under namespace", "err", err.Error())
@@ -705,6 +1005,7 @@ func (a *API) DeleteRuleGroup(w http.ResponseWriter, req *http.Request) {
 	}
 
 	a.ruler.NotifySyncRulesAsync(userID)
+	a.recordRuleNamespaceVersion(ctx, logger, req, userID, namespace)
 
 	respondAccepted(w, logger)
 }
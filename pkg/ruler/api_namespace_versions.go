@@ -0,0 +1,363 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ruler
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+
+	"github.com/grafana/mimir/pkg/ruler/rulespb"
+	"github.com/grafana/mimir/pkg/ruler/rulestore"
+	"github.com/grafana/mimir/pkg/util/spanlogger"
+)
+
+// ConfigAuthorHeader is the optional HTTP header through which the clients changing the tenant's rule groups
+// can declare who made the change. It's recorded in the rule namespace version.
+const ConfigAuthorHeader = "X-Mimir-Config-Author"
+
+// currentNamespaceVersion is the version to compare with, in the rule namespace diff API, to compare a
+// version with the current rule groups of the namespace.
+const currentNamespaceVersion = "current"
+
+// ErrInvalidNamespaceVersion is returned when the requested rule namespace version is not a valid version ID.
+var ErrInvalidNamespaceVersion = errors.New("invalid rule namespace version")
+
+// RuleNamespaceVersions is the response of the rule namespace versions listing API.
+type RuleNamespaceVersions struct {
+	Versions []rulespb.RuleNamespaceVersion `yaml:"versions"`
+}
+
+// RuleNamespaceDiff is the response of the rule namespace versions diff API. It lists the names of the rule groups
+// created, updated and deleted in the "to" version of the namespace, compared to the "from" version.
+type RuleNamespaceDiff struct {
+	From          string   `yaml:"from"`
+	To            string   `yaml:"to"`
+	GroupsCreated []string `yaml:"groups_created"`
+	GroupsUpdated []string `yaml:"groups_updated"`
+	GroupsDeleted []string `yaml:"groups_deleted"`
+}
+
+// ListRuleNamespaceVersions returns the stored versions of the tenant's rule namespace, from the most recent one.
+func (a *API) ListRuleNamespaceVersions(w http.ResponseWriter, req *http.Request) {
+	logger, ctx := spanlogger.NewWithLogger(req.Context(), a.logger, "API.ListRuleNamespaceVersions")
+	defer logger.Finish()
+
+	userID, namespace, ok := a.parseNamespaceVersionRequest(w, req, logger)
+	if !ok {
+		return
+	}
+
+	versions, err := a.store.ListRuleNamespaceVersions(ctx, userID, namespace)
+	if err != nil {
+		level.Error(logger).Log("msg", "unable to list rule namespace versions", "namespace", namespace, "err", err.Error())
+		respondServerError(logger, w, err.Error())
+		return
+	}
+
+	marshalAndSend(&RuleNamespaceVersions{Versions: versions}, w, logger)
+}
+
+// GetRuleNamespaceVersion returns the rule groups of a stored version of the tenant's rule namespace, in the same
+// format returned by ListRules.
+func (a *API) GetRuleNamespaceVersion(w http.ResponseWriter, req *http.Request) {
+	logger, ctx := spanlogger.NewWithLogger(req.Context(), a.logger, "API.GetRuleNamespaceVersion")
+	defer logger.Finish()
+
+	userID, namespace, ok := a.parseNamespaceVersionRequest(w, req, logger)
+	if !ok {
+		return
+	}
+
+	version, ok := a.getRuleNamespaceVersion(ctx, w, logger, userID, namespace, mux.Vars(req)["version"])
+	if !ok {
+		return
+	}
+
+	formatted := version.Groups.Formatted()
+	if len(formatted) == 0 {
+		// The namespace had been deleted in this version.
+		marshalAndSend(map[string]interface{}{}, w, logger)
+		return
+	}
+	marshalAndSend(formatted, w, logger)
+}
+
+// DiffRuleNamespaceVersion compares a stored version of the tenant's rule namespace with the current rule groups
+// of the namespace or, if the "to" parameter is set, with another stored version.
+func (a *API) DiffRuleNamespaceVersion(w http.ResponseWriter, req *http.Request) {
+	logger, ctx := spanlogger.NewWithLogger(req.Context(), a.logger, "API.DiffRuleNamespaceVersion")
+	defer logger.Finish()
+
+	userID, namespace, ok := a.parseNamespaceVersionRequest(w, req, logger)
+	if !ok {
+		return
+	}
+
+	from, ok := a.getRuleNamespaceVersion(ctx, w, logger, userID, namespace, mux.Vars(req)["version"])
+	if !ok {
+		return
+	}
+
+	diff := &RuleNamespaceDiff{From: from.ID, To: req.FormValue("to")}
+
+	var toGroups rulespb.RuleGroupList
+	if diff.To == "" || diff.To == currentNamespaceVersion {
+		diff.To = currentNamespaceVersion
+
+		var err error
+		if toGroups, err = a.loadRuleNamespace(ctx, userID, namespace); err != nil {
+			level.Error(logger).Log("msg", "unable to load rule groups", "namespace", namespace, "err", err.Error())
+			respondServerError(logger, w, err.Error())
+			return
+		}
+	} else {
+		to, ok := a.getRuleNamespaceVersion(ctx, w, logger, userID, namespace, diff.To)
+		if !ok {
+			return
+		}
+		toGroups = to.Groups
+	}
+
+	diff.GroupsCreated, diff.GroupsUpdated, diff.GroupsDeleted = diffRuleGroups(from.Groups, toGroups)
+	marshalAndSend(diff, w, logger)
+}
+
+// RollbackRuleNamespace replaces the rule groups of the tenant's rule namespace with the ones of a stored version.
+// The rule groups are validated against the current limits, and the rollback is recorded as a new version.
+func (a *API) RollbackRuleNamespace(w http.ResponseWriter, req *http.Request) {
+	logger, ctx := spanlogger.NewWithLogger(req.Context(), a.logger, "API.RollbackRuleNamespace")
+	defer logger.Finish()
+
+	userID, namespace, ok := a.parseNamespaceVersionRequest(w, req, logger)
+	if !ok {
+		return
+	}
+
+	if a.ruler.IsNamespaceProtected(userID, namespace) {
+		if err := AllowProtectionOverride(req.Header, namespace); err != nil {
+			level.Warn(logger).Log("msg", "not allowed to roll back namespace", "err", err.Error())
+			http.Error(w, "namespace is protected, no modification allowed", http.StatusForbidden)
+			return
+		}
+	}
+
+	version, ok := a.getRuleNamespaceVersion(ctx, w, logger, userID, namespace, mux.Vars(req)["version"])
+	if !ok {
+		return
+	}
+
+	for _, rg := range version.Groups {
+		if errs := a.ruler.manager.ValidateRuleGroup(rulespb.FromProto(rg)); len(errs) > 0 {
+			e := make([]string, 0, len(errs))
+			for _, err := range errs {
+				e = append(e, err.Error())
+			}
+			respondInvalidRequest(logger, w, strings.Join(e, ", "))
+			return
+		}
+
+		if err := a.ruler.AssertMaxRulesPerRuleGroup(userID, namespace, len(rg.Rules)); err != nil {
+			level.Error(logger).Log("msg", "limit validation failure", "err", err.Error(), "user", userID)
+			respondInvalidRequest(logger, w, err.Error())
+			return
+		}
+	}
+
+	currentGroups, err := a.store.ListRuleGroupsForUserAndNamespace(ctx, userID, namespace)
+	if err != nil {
+		level.Error(logger).Log("msg", "unable to fetch current rule groups", "err", err.Error(), "user", userID)
+		respondServerError(logger, w, err.Error())
+		return
+	}
+
+	// Only list rule groups when enforcing a max number of groups for this tenant and namespace.
+	if a.ruler.IsMaxRuleGroupsLimited(userID, namespace) {
+		rgs, err := a.store.ListRuleGroupsForUserAndNamespace(ctx, userID, "")
+		if err != nil {
+			level.Error(logger).Log("msg", "unable to fetch current rule groups for validation", "err", err.Error(), "user", userID)
+			respondServerError(logger, w, err.Error())
+			return
+		}
+
+		if err := a.ruler.AssertMaxRuleGroups(userID, namespace, len(rgs)-len(currentGroups)+len(version.Groups)); err != nil {
+			level.Error(logger).Log("msg", "limit validation failure", "err", err.Error(), "user", userID)
+			respondInvalidRequest(logger, w, err.Error())
+			return
+		}
+	}
+
+	restored := make(map[string]struct{}, len(version.Groups))
+	for _, rg := range version.Groups {
+		rg.User = userID
+		rg.Namespace = namespace
+		if err := a.store.SetRuleGroup(ctx, userID, namespace, rg); err != nil {
+			level.Error(logger).Log("msg", "unable to store rule group", "group", rg.Name, "err", err.Error())
+			respondServerError(logger, w, err.Error())
+			return
+		}
+		restored[rg.Name] = struct{}{}
+	}
+
+	for _, rg := range currentGroups {
+		if _, ok := restored[rg.Name]; ok {
+			continue
+		}
+		if err := a.store.DeleteRuleGroup(ctx, userID, namespace, rg.Name); err != nil && !errors.Is(err, rulestore.ErrGroupNotFound) {
+			level.Error(logger).Log("msg", "unable to delete rule group", "group", rg.Name, "err", err.Error())
+			respondServerError(logger, w, err.Error())
+			return
+		}
+	}
+
+	a.ruler.NotifySyncRulesAsync(userID)
+	a.recordRuleNamespaceVersion(ctx, logger, req, userID, namespace)
+
+	level.Info(logger).Log("msg", "rolled back rule namespace", "namespace", namespace, "version", version.ID)
+	respondAccepted(w, logger)
+}
+
+// parseNamespaceVersionRequest parses the userID and rules namespace out of the incoming request, and checks
+// the namespace is within the request's scope. If it returns false, the error response has already been written.
+func (a *API) parseNamespaceVersionRequest(w http.ResponseWriter, req *http.Request, logger log.Logger) (string, string, bool) {
+	userID, namespace, _, err := a.parseRequest(req, true, false)
+	if err != nil {
+		if errors.Is(err, errNoValidOrgIDFound) {
+			respondInvalidRequest(logger, w, err.Error())
+			return "", "", false
+		}
+		respondServerError(logger, w, err.Error())
+		return "", "", false
+	}
+
+	if err := a.ruler.AllowNamespaceScope(userID, req.Header, namespace); err != nil {
+		level.Warn(logger).Log("msg", "not allowed to access namespace", "namespace", namespace, "err", err.Error())
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return "", "", false
+	}
+
+	return userID, namespace, true
+}
+
+// getRuleNamespaceVersion returns the requested version of the tenant's rule namespace. If it returns false,
+// the error response has already been written.
+func (a *API) getRuleNamespaceVersion(ctx context.Context, w http.ResponseWriter, logger log.Logger, userID, namespace, versionID string) (rulespb.RuleNamespaceVersionDesc, bool) {
+	// Version IDs are made of digits only, so checking it also guarantees the ID is safe to be used as object name.
+	if _, err := strconv.ParseUint(versionID, 10, 64); err != nil {
+		respondInvalidRequest(logger, w, ErrInvalidNamespaceVersion.Error())
+		return rulespb.RuleNamespaceVersionDesc{}, false
+	}
+
+	version, err := a.store.GetRuleNamespaceVersion(ctx, userID, namespace, versionID)
+	if errors.Is(err, rulestore.ErrNamespaceVersionNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return rulespb.RuleNamespaceVersionDesc{}, false
+	}
+	if err != nil {
+		level.Error(logger).Log("msg", "unable to read rule namespace version", "namespace", namespace, "version", versionID, "err", err.Error())
+		respondServerError(logger, w, err.Error())
+		return rulespb.RuleNamespaceVersionDesc{}, false
+	}
+
+	return version, true
+}
+
+// loadRuleNamespace returns the rule groups of the tenant's rule namespace, along with their rules.
+// Rule groups deleted while loading them are skipped.
+func (a *API) loadRuleNamespace(ctx context.Context, userID, namespace string) (rulespb.RuleGroupList, error) {
+	rgs, err := a.store.ListRuleGroupsForUserAndNamespace(ctx, userID, namespace)
+	if err != nil || len(rgs) == 0 {
+		return rgs, err
+	}
+
+	missing, err := a.store.LoadRuleGroups(ctx, map[string]rulespb.RuleGroupList{userID: rgs})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(missing) > 0 {
+		rgs = slices.DeleteFunc(rgs, func(rg *rulespb.RuleGroupDesc) bool { return slices.Contains(missing, rg) })
+	}
+	return rgs, nil
+}
+
+// recordRuleNamespaceVersion records the current rule groups of the tenant's rule namespace as a new version,
+// if rule namespace versioning is enabled. Failing to record the version doesn't fail the request, because the
+// namespace has already been changed.
+func (a *API) recordRuleNamespaceVersion(ctx context.Context, logger log.Logger, req *http.Request, userID, namespace string) {
+	if a.ruler.cfg.MaxNamespaceVersions <= 0 {
+		return
+	}
+
+	if err := a.storeRuleNamespaceVersion(ctx, userID, namespace, req.Header.Get(ConfigAuthorHeader)); err != nil {
+		level.Warn(logger).Log("msg", "unable to record the rule namespace version", "namespace", namespace, "err", err)
+	}
+}
+
+// storeRuleNamespaceVersion stores the current rule groups of the namespace as a new version, unless they're equal
+// to the most recent version, and deletes the oldest versions exceeding the max number of versions to keep.
+func (a *API) storeRuleNamespaceVersion(ctx context.Context, userID, namespace, createdBy string) error {
+	groups, err := a.loadRuleNamespace(ctx, userID, namespace)
+	if err != nil {
+		return errors.Wrap(err, "load rule groups")
+	}
+
+	versions, err := a.store.ListRuleNamespaceVersions(ctx, userID, namespace)
+	if err != nil {
+		return errors.Wrap(err, "list versions")
+	}
+
+	version := rulespb.NewRuleNamespaceVersion(userID, namespace, groups, createdBy, time.Now())
+	if len(versions) > 0 && versions[0].Hash == version.Hash {
+		return nil
+	}
+
+	if err := a.store.SetRuleNamespaceVersion(ctx, version); err != nil {
+		return errors.Wrap(err, "store version")
+	}
+
+	// The new version is the most recent one, and it's not included in the listed versions.
+	for i := a.ruler.cfg.MaxNamespaceVersions - 1; i >= 0 && i < len(versions); i++ {
+		if err := a.store.DeleteRuleNamespaceVersion(ctx, userID, namespace, versions[i].ID); err != nil {
+			return errors.Wrapf(err, "delete version %s", versions[i].ID)
+		}
+	}
+	return nil
+}
+
+// diffRuleGroups returns the names of the rule groups created, updated and deleted in "to" compared to "from".
+func diffRuleGroups(from, to rulespb.RuleGroupList) (created, updated, deleted []string) {
+	fromHashes := make(map[string]string, len(from))
+	for _, rg := range from {
+		fromHashes[rg.Name] = rulespb.HashRuleGroups(rulespb.RuleGroupList{rg})
+	}
+
+	created, updated, deleted = []string{}, []string{}, []string{}
+	for _, rg := range to {
+		fromHash, ok := fromHashes[rg.Name]
+		switch {
+		case !ok:
+			created = append(created, rg.Name)
+		case fromHash != rulespb.HashRuleGroups(rulespb.RuleGroupList{rg}):
+			updated = append(updated, rg.Name)
+		}
+		delete(fromHashes, rg.Name)
+	}
+	for name := range fromHashes {
+		deleted = append(deleted, name)
+	}
+
+	sort.Strings(created)
+	sort.Strings(updated)
+	sort.Strings(deleted)
+	return created, updated, deleted
+}
@@ -0,0 +1,228 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ruler
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/objstore"
+	"gopkg.in/yaml.v3"
+
+	"github.com/grafana/mimir/pkg/ruler/rulestore/bucketclient"
+)
+
+func TestAPI_RuleNamespaceVersions(t *testing.T) {
+	const userID = "user1"
+
+	cfg := defaultRulerConfig(t)
+	cfg.PollInterval = time.Hour
+	cfg.MaxNamespaceVersions = 2
+
+	store := bucketclient.NewBucketRuleStore(objstore.NewInMemBucket(), nil, log.NewNopLogger())
+	r := prepareRuler(t, cfg, store, withStart(), withRulerAddrAutomaticMapping())
+	a := NewAPI(r, r.directStore, nil, log.NewNopLogger())
+
+	router := mux.NewRouter()
+	router.Path("/prometheus/config/v1/rules/{namespace}").Methods(http.MethodGet).HandlerFunc(a.ListRules)
+	router.Path("/prometheus/config/v1/rules/{namespace}").Methods(http.MethodPost).HandlerFunc(a.CreateRuleGroup)
+	router.Path("/prometheus/config/v1/rules/{namespace}").Methods(http.MethodDelete).HandlerFunc(a.DeleteNamespace)
+	router.Path("/prometheus/config/v1/rule-versions/{namespace}").Methods(http.MethodGet).HandlerFunc(a.ListRuleNamespaceVersions)
+	router.Path("/prometheus/config/v1/rule-versions/{namespace}/{version}").Methods(http.MethodGet).HandlerFunc(a.GetRuleNamespaceVersion)
+	router.Path("/prometheus/config/v1/rule-versions/{namespace}/{version}/diff").Methods(http.MethodGet).HandlerFunc(a.DiffRuleNamespaceVersion)
+	router.Path("/prometheus/config/v1/rule-versions/{namespace}/{version}/rollback").Methods(http.MethodPost).HandlerFunc(a.RollbackRuleNamespace)
+
+	do := func(method, path, body, author string) (int, string) {
+		req := requestFor(t, method, "https://localhost:8080/prometheus/config/v1/"+path, strings.NewReader(body), userID)
+		if author != "" {
+			req.Header.Set(ConfigAuthorHeader, author)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		res, err := io.ReadAll(w.Result().Body)
+		require.NoError(t, err)
+		return w.Code, string(res)
+	}
+
+	listVersions := func() RuleNamespaceVersions {
+		code, body := do(http.MethodGet, "rule-versions/ns", "", "")
+		require.Equal(t, http.StatusOK, code)
+
+		res := RuleNamespaceVersions{}
+		require.NoError(t, yaml.Unmarshal([]byte(body), &res))
+		return res
+	}
+
+	diff := func(path string) RuleNamespaceDiff {
+		code, body := do(http.MethodGet, path, "", "")
+		require.Equal(t, http.StatusOK, code, body)
+
+		res := RuleNamespaceDiff{}
+		require.NoError(t, yaml.Unmarshal([]byte(body), &res))
+		return res
+	}
+
+	group := func(name, expr string) string {
+		return fmt.Sprintf("name: %s\nrules:\n- record: %s:rule\n  expr: %s\n", name, name, expr)
+	}
+
+	// No versions have been stored yet.
+	assert.Empty(t, listVersions().Versions)
+
+	code, _ := do(http.MethodPost, "rules/ns", group("group1", "up"), "alice")
+	require.Equal(t, http.StatusAccepted, code)
+
+	// Storing the same rule group again doesn't create a new version.
+	code, _ = do(http.MethodPost, "rules/ns", group("group1", "up"), "alice")
+	require.Equal(t, http.StatusAccepted, code)
+
+	versions := listVersions().Versions
+	require.Len(t, versions, 1)
+	assert.Equal(t, "alice", versions[0].CreatedBy)
+	assert.Equal(t, 1, versions[0].RuleGroups)
+	firstVersion := versions[0].ID
+
+	code, _ = do(http.MethodPost, "rules/ns", group("group2", "sum(up)"), "bob")
+	require.Equal(t, http.StatusAccepted, code)
+
+	versions = listVersions().Versions
+	require.Len(t, versions, 2)
+	assert.Equal(t, "bob", versions[0].CreatedBy)
+	assert.Equal(t, 2, versions[0].RuleGroups)
+	assert.Equal(t, firstVersion, versions[1].ID)
+	secondVersion := versions[0].ID
+
+	t.Run("get a version", func(t *testing.T) {
+		code, body := do(http.MethodGet, "rule-versions/ns/"+firstVersion, "", "")
+		require.Equal(t, http.StatusOK, code)
+		assert.YAMLEq(t, "ns:\n- name: group1\n  rules:\n  - record: group1:rule\n    expr: up\n", body)
+
+		code, _ = do(http.MethodGet, "rule-versions/ns/1", "", "")
+		require.Equal(t, http.StatusNotFound, code)
+
+		code, _ = do(http.MethodGet, "rule-versions/other/"+firstVersion, "", "")
+		require.Equal(t, http.StatusNotFound, code)
+
+		code, _ = do(http.MethodGet, "rule-versions/ns/invalid", "", "")
+		require.Equal(t, http.StatusBadRequest, code)
+	})
+
+	t.Run("diff versions", func(t *testing.T) {
+		assert.Equal(t, RuleNamespaceDiff{
+			From:          firstVersion,
+			To:            currentNamespaceVersion,
+			GroupsCreated: []string{"group2"},
+			GroupsUpdated: []string{},
+			GroupsDeleted: []string{},
+		}, diff("rule-versions/ns/"+firstVersion+"/diff"))
+
+		assert.Equal(t, RuleNamespaceDiff{
+			From:          secondVersion,
+			To:            firstVersion,
+			GroupsCreated: []string{},
+			GroupsUpdated: []string{},
+			GroupsDeleted: []string{"group2"},
+		}, diff("rule-versions/ns/"+secondVersion+"/diff?to="+firstVersion))
+
+		code, _ := do(http.MethodPost, "rules/ns", group("group2", "max(up)"), "")
+		require.Equal(t, http.StatusAccepted, code)
+
+		assert.Equal(t, RuleNamespaceDiff{
+			From:          secondVersion,
+			To:            currentNamespaceVersion,
+			GroupsCreated: []string{},
+			GroupsUpdated: []string{"group2"},
+			GroupsDeleted: []string{},
+		}, diff("rule-versions/ns/"+secondVersion+"/diff"))
+	})
+
+	t.Run("roll back to a version", func(t *testing.T) {
+		// The version stored by the previous test case has pruned the first version.
+		versions := listVersions().Versions
+		require.Len(t, versions, 2)
+		require.Equal(t, secondVersion, versions[1].ID)
+		code, _ := do(http.MethodGet, "rule-versions/ns/"+firstVersion, "", "")
+		require.Equal(t, http.StatusNotFound, code)
+
+		code, body := do(http.MethodPost, "rule-versions/ns/"+secondVersion+"/rollback", "", "carol")
+		require.Equal(t, http.StatusAccepted, code, body)
+
+		code, body = do(http.MethodGet, "rules/ns", "", "")
+		require.Equal(t, http.StatusOK, code)
+		assert.Contains(t, body, "sum(up)")
+		assert.NotContains(t, body, "max(up)")
+
+		// The rollback has been recorded as a new version.
+		versions = listVersions().Versions
+		require.Len(t, versions, 2)
+		assert.Equal(t, "carol", versions[0].CreatedBy)
+
+		code, _ = do(http.MethodPost, "rule-versions/ns/"+firstVersion+"/rollback", "", "carol")
+		require.Equal(t, http.StatusNotFound, code)
+	})
+
+	t.Run("roll back to a version deleting rule groups", func(t *testing.T) {
+		code, _ := do(http.MethodPost, "rules/ns", group("group3", "min(up)"), "")
+		require.Equal(t, http.StatusAccepted, code)
+
+		versions := listVersions().Versions
+		code, body := do(http.MethodPost, "rule-versions/ns/"+versions[1].ID+"/rollback", "", "")
+		require.Equal(t, http.StatusAccepted, code, body)
+
+		code, body = do(http.MethodGet, "rules/ns", "", "")
+		require.Equal(t, http.StatusOK, code)
+		assert.NotContains(t, body, "group3")
+	})
+
+	t.Run("deleting the namespace is recorded as a version", func(t *testing.T) {
+		code, _ := do(http.MethodDelete, "rules/ns", "", "dave")
+		require.Equal(t, http.StatusAccepted, code)
+
+		versions := listVersions().Versions
+		require.Len(t, versions, 2)
+		assert.Equal(t, "dave", versions[0].CreatedBy)
+		assert.Equal(t, 0, versions[0].RuleGroups)
+
+		code, body := do(http.MethodGet, "rule-versions/ns/"+versions[0].ID, "", "")
+		require.Equal(t, http.StatusOK, code)
+		assert.YAMLEq(t, "{}", body)
+
+		// The namespace can be restored.
+		code, body = do(http.MethodPost, "rule-versions/ns/"+versions[1].ID+"/rollback", "", "")
+		require.Equal(t, http.StatusAccepted, code, body)
+
+		rgs, err := store.ListRuleGroupsForUserAndNamespace(context.Background(), userID, "ns")
+		require.NoError(t, err)
+		assert.Len(t, rgs, 2)
+	})
+}
+
+func TestAPI_RuleNamespaceVersionsDisabled(t *testing.T) {
+	cfg := defaultRulerConfig(t)
+	cfg.PollInterval = time.Hour
+
+	store := bucketclient.NewBucketRuleStore(objstore.NewInMemBucket(), nil, log.NewNopLogger())
+	r := prepareRuler(t, cfg, store, withStart(), withRulerAddrAutomaticMapping())
+	a := NewAPI(r, r.directStore, nil, log.NewNopLogger())
+
+	req := requestFor(t, http.MethodPost, "https://localhost:8080/prometheus/config/v1/rules/ns", strings.NewReader("name: group1\nrules:\n- record: up:rule\n  expr: up\n"), "user1")
+	req = mux.SetURLVars(req, map[string]string{"namespace": "ns"})
+	w := httptest.NewRecorder()
+	a.CreateRuleGroup(w, req)
+	require.Equal(t, http.StatusAccepted, w.Code)
+
+	versions, err := store.ListRuleNamespaceVersions(context.Background(), "user1", "ns")
+	require.NoError(t, err)
+	assert.Empty(t, versions)
+}
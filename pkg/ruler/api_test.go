@@ -25,7 +25,9 @@ import (
 	"github.com/grafana/dskit/user"
 	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/prometheus/model/labels"
 	"github.com/prometheus/prometheus/model/rulefmt"
+	"github.com/prometheus/prometheus/promql"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"gopkg.in/yaml.v3"
@@ -163,7 +165,7 @@ func TestRuler_ListRules(t *testing.T) {
 			store.setMissingRuleGroups(tc.missingRules)
 
 			r := prepareRuler(t, cfg, store, withStart())
-			a := NewAPI(r, r.directStore, log.NewNopLogger())
+			a := NewAPI(r, r.directStore, nil, log.NewNopLogger())
 
 			router := mux.NewRouter()
 			router.Path("/prometheus/config/v1/rules").Methods("GET").HandlerFunc(a.ListRules)
@@ -225,20 +227,24 @@ func TestRuler_PrometheusRules(t *testing.T) {
 
 	filterTestExpectedRule := func(name string) *recordingRule {
 		return &recordingRule{
-			Name:   name,
-			Query:  "up",
-			Health: "unknown",
-			Type:   "recording",
+			Name:              name,
+			Query:             "up",
+			Health:            "unknown",
+			Type:              "recording",
+			NoDependentRules:  true,
+			NoDependencyRules: true,
 		}
 	}
 	filterTestExpectedAlert := func(name string) *alertingRule {
 		return &alertingRule{
-			Name:   name,
-			Query:  "up < 1",
-			State:  "inactive",
-			Health: "unknown",
-			Type:   "alerting",
-			Alerts: []*Alert{},
+			Name:              name,
+			Query:             "up < 1",
+			State:             "inactive",
+			Health:            "unknown",
+			Type:              "alerting",
+			Alerts:            []*Alert{},
+			NoDependentRules:  true,
+			NoDependencyRules: true,
 		}
 	}
 
@@ -249,6 +255,7 @@ func TestRuler_PrometheusRules(t *testing.T) {
 		expectedStatusCode int
 		expectedErrorType  v1.ErrorType
 		expectedRules      []*RuleGroup
+		expectedNextToken  string
 		queryParams        string
 	}{
 		"should load and evaluate the configured rules": {
@@ -269,21 +276,69 @@ func TestRuler_PrometheusRules(t *testing.T) {
 					File: "namespace1",
 					Rules: []rule{
 						&recordingRule{
-							Name:   "UP_RULE",
-							Query:  "up",
-							Health: "unknown",
-							Type:   "recording",
+							Name:              "UP_RULE",
+							Query:             "up",
+							Health:            "unknown",
+							Type:              "recording",
+							NoDependentRules:  true,
+							NoDependencyRules: true,
 						},
 						&alertingRule{
-							Name:   "UP_ALERT",
-							Query:  "up < 1",
-							State:  "inactive",
-							Health: "unknown",
-							Type:   "alerting",
-							Alerts: []*Alert{},
+							Name:              "UP_ALERT",
+							Query:             "up < 1",
+							State:             "inactive",
+							Health:            "unknown",
+							Type:              "alerting",
+							Alerts:            []*Alert{},
+							NoDependentRules:  true,
+							NoDependencyRules: true,
 						},
 					},
-					Interval: 60,
+					Interval:    60,
+					QueryOffset: 60,
+				},
+			},
+		},
+		"should return the dependencies between rules in the same group": {
+			configuredRules: rulespb.RuleGroupList{
+				&rulespb.RuleGroupDesc{
+					Name:      "group1",
+					Namespace: "namespace1",
+					User:      userID,
+					Rules:     []*rulespb.RuleDesc{createRecordingRule("job:up:sum", "sum by(job) (up)"), createAlertingRule("UP_ALERT", "job:up:sum < 1")},
+					Interval:  interval,
+				},
+			},
+			limits:             validation.MockDefaultOverrides(),
+			expectedConfigured: 1,
+			expectedRules: []*RuleGroup{
+				{
+					Name: "group1",
+					File: "namespace1",
+					Rules: []rule{
+						&recordingRule{
+							Name:              "job:up:sum",
+							Query:             "sum by (job) (up)",
+							Health:            "unknown",
+							Type:              "recording",
+							NoDependentRules:  false,
+							NoDependencyRules: true,
+							DependentRules:    []string{"UP_ALERT"},
+						},
+						&alertingRule{
+							Name:              "UP_ALERT",
+							Query:             "job:up:sum < 1",
+							State:             "inactive",
+							Health:            "unknown",
+							Type:              "alerting",
+							Alerts:            []*Alert{},
+							NoDependentRules:  true,
+							NoDependencyRules: false,
+							DependencyRules:   []string{"job:up:sum"},
+						},
+					},
+					Interval:    60,
+					QueryOffset: 60,
 				},
 			},
 		},
@@ -309,13 +364,16 @@ func TestRuler_PrometheusRules(t *testing.T) {
 					File: "namespace1",
 					Rules: []rule{
 						&recordingRule{
-							Name:   "UP_RULE",
-							Query:  "up",
-							Health: "unknown",
-							Type:   "recording",
+							Name:              "UP_RULE",
+							Query:             "up",
+							Health:            "unknown",
+							Type:              "recording",
+							NoDependentRules:  true,
+							NoDependencyRules: true,
 						},
 					},
-					Interval: 60,
+					Interval:    60,
+					QueryOffset: 60,
 				},
 			},
 		},
@@ -341,15 +399,18 @@ func TestRuler_PrometheusRules(t *testing.T) {
 					File: "namespace1",
 					Rules: []rule{
 						&alertingRule{
-							Name:   "UP_ALERT",
-							Query:  "up < 1",
-							State:  "inactive",
-							Health: "unknown",
-							Type:   "alerting",
-							Alerts: []*Alert{},
+							Name:              "UP_ALERT",
+							Query:             "up < 1",
+							State:             "inactive",
+							Health:            "unknown",
+							Type:              "alerting",
+							Alerts:            []*Alert{},
+							NoDependentRules:  true,
+							NoDependencyRules: true,
 						},
 					},
-					Interval: 60,
+					Interval:    60,
+					QueryOffset: 60,
 				},
 			},
 		},
@@ -389,21 +450,26 @@ func TestRuler_PrometheusRules(t *testing.T) {
 					File: ")(_+?/|namespace1+/?",
 					Rules: []rule{
 						&recordingRule{
-							Name:   "UP_RULE",
-							Query:  "up",
-							Health: "unknown",
-							Type:   "recording",
+							Name:              "UP_RULE",
+							Query:             "up",
+							Health:            "unknown",
+							Type:              "recording",
+							NoDependentRules:  true,
+							NoDependencyRules: true,
 						},
 						&alertingRule{
-							Name:   "UP_ALERT",
-							Query:  "up < 1",
-							State:  "inactive",
-							Health: "unknown",
-							Type:   "alerting",
-							Alerts: []*Alert{},
+							Name:              "UP_ALERT",
+							Query:             "up < 1",
+							State:             "inactive",
+							Health:            "unknown",
+							Type:              "alerting",
+							Alerts:            []*Alert{},
+							NoDependentRules:  true,
+							NoDependencyRules: true,
 						},
 					},
-					Interval: 60,
+					Interval:    60,
+					QueryOffset: 60,
 				},
 			},
 		},
@@ -427,21 +493,26 @@ func TestRuler_PrometheusRules(t *testing.T) {
 					SourceTenants: []string{"tenant-1"},
 					Rules: []rule{
 						&recordingRule{
-							Name:   "UP_RULE",
-							Query:  "up",
-							Health: "unknown",
-							Type:   "recording",
+							Name:              "UP_RULE",
+							Query:             "up",
+							Health:            "unknown",
+							Type:              "recording",
+							NoDependentRules:  true,
+							NoDependencyRules: true,
 						},
 						&alertingRule{
-							Name:   "UP_ALERT",
-							Query:  "up < 1",
-							State:  "inactive",
-							Health: "unknown",
-							Type:   "alerting",
-							Alerts: []*Alert{},
+							Name:              "UP_ALERT",
+							Query:             "up < 1",
+							State:             "inactive",
+							Health:            "unknown",
+							Type:              "alerting",
+							Alerts:            []*Alert{},
+							NoDependentRules:  true,
+							NoDependencyRules: true,
 						},
 					},
-					Interval: 60,
+					Interval:    60,
+					QueryOffset: 60,
 				},
 			},
 		},
@@ -468,17 +539,76 @@ func TestRuler_PrometheusRules(t *testing.T) {
 					File: "namespace1",
 					Rules: []rule{
 						&alertingRule{
-							Name:          "UP_ALERT_WITH_KEEP_FIRING_FOR",
-							Query:         "up < 1",
-							State:         "inactive",
-							Health:        "unknown",
-							Type:          "alerting",
-							Duration:      time.Minute.Seconds(),
-							KeepFiringFor: (2 * time.Minute).Seconds(),
-							Alerts:        []*Alert{},
+							Name:              "UP_ALERT_WITH_KEEP_FIRING_FOR",
+							Query:             "up < 1",
+							State:             "inactive",
+							Health:            "unknown",
+							Type:              "alerting",
+							Duration:          time.Minute.Seconds(),
+							KeepFiringFor:     (2 * time.Minute).Seconds(),
+							Alerts:            []*Alert{},
+							NoDependentRules:  true,
+							NoDependencyRules: true,
+						},
+					},
+					Interval:    60,
+					QueryOffset: 60,
+				},
+			},
+		},
+		"should report the query offset of rule groups": {
+			configuredRules: rulespb.RuleGroupList{
+				&rulespb.RuleGroupDesc{
+					Name:        "group1",
+					Namespace:   "namespace1",
+					User:        userID,
+					Rules:       []*rulespb.RuleDesc{createRecordingRule("UP_RULE", "up")},
+					Interval:    interval,
+					QueryOffset: 2 * time.Minute,
+				},
+				&rulespb.RuleGroupDesc{
+					Name:            "group2",
+					Namespace:       "namespace1",
+					User:            userID,
+					Rules:           []*rulespb.RuleDesc{createRecordingRule("UP_RULE", "up")},
+					Interval:        interval,
+					EvaluationDelay: 3 * time.Minute,
+				},
+			},
+			expectedConfigured: 2,
+			limits:             validation.MockDefaultOverrides(),
+			expectedRules: []*RuleGroup{
+				{
+					Name: "group1",
+					File: "namespace1",
+					Rules: []rule{
+						&recordingRule{
+							Name:              "UP_RULE",
+							Query:             "up",
+							Health:            "unknown",
+							Type:              "recording",
+							NoDependentRules:  true,
+							NoDependencyRules: true,
+						},
+					},
+					Interval:    60,
+					QueryOffset: 120,
+				},
+				{
+					Name: "group2",
+					File: "namespace1",
+					Rules: []rule{
+						&recordingRule{
+							Name:              "UP_RULE",
+							Query:             "up",
+							Health:            "unknown",
+							Type:              "recording",
+							NoDependentRules:  true,
+							NoDependencyRules: true,
 						},
 					},
-					Interval: 60,
+					Interval:    60,
+					QueryOffset: 180,
 				},
 			},
 		},
@@ -501,15 +631,18 @@ func TestRuler_PrometheusRules(t *testing.T) {
 					File: "namespace1",
 					Rules: []rule{
 						&alertingRule{
-							Name:   "UP_ALERT",
-							Query:  "up < 1",
-							State:  "inactive",
-							Health: "unknown",
-							Type:   "alerting",
-							Alerts: []*Alert{},
+							Name:              "UP_ALERT",
+							Query:             "up < 1",
+							State:             "inactive",
+							Health:            "unknown",
+							Type:              "alerting",
+							Alerts:            []*Alert{},
+							NoDependentRules:  true,
+							NoDependencyRules: true,
 						},
 					},
-					Interval: 60,
+					Interval:    60,
+					QueryOffset: 60,
 				},
 			},
 		},
@@ -532,13 +665,16 @@ func TestRuler_PrometheusRules(t *testing.T) {
 					File: "namespace1",
 					Rules: []rule{
 						&recordingRule{
-							Name:   "UP_RULE",
-							Query:  "up",
-							Health: "unknown",
-							Type:   "recording",
+							Name:              "UP_RULE",
+							Query:             "up",
+							Health:            "unknown",
+							Type:              "recording",
+							NoDependentRules:  true,
+							NoDependencyRules: true,
 						},
 					},
-					Interval: 60,
+					Interval:    60,
+					QueryOffset: 60,
 				},
 			},
 		},
@@ -561,15 +697,18 @@ func TestRuler_PrometheusRules(t *testing.T) {
 					File: "namespace1",
 					Rules: []rule{
 						&alertingRule{
-							Name:   "UP_ALERT",
-							Query:  "up < 1",
-							State:  "inactive",
-							Health: "unknown",
-							Type:   "alerting",
-							Alerts: nil,
+							Name:              "UP_ALERT",
+							Query:             "up < 1",
+							State:             "inactive",
+							Health:            "unknown",
+							Type:              "alerting",
+							Alerts:            nil,
+							NoDependentRules:  true,
+							NoDependencyRules: true,
 						},
 					},
-					Interval: 60,
+					Interval:    60,
+					QueryOffset: 60,
 				},
 			},
 		},
@@ -592,15 +731,18 @@ func TestRuler_PrometheusRules(t *testing.T) {
 					File: "namespace1",
 					Rules: []rule{
 						&alertingRule{
-							Name:   "UP_ALERT",
-							Query:  "up < 1",
-							State:  "inactive",
-							Health: "unknown",
-							Type:   "alerting",
-							Alerts: []*Alert{},
+							Name:              "UP_ALERT",
+							Query:             "up < 1",
+							State:             "inactive",
+							Health:            "unknown",
+							Type:              "alerting",
+							Alerts:            []*Alert{},
+							NoDependentRules:  true,
+							NoDependencyRules: true,
 						},
 					},
-					Interval: 60,
+					Interval:    60,
+					QueryOffset: 60,
 				},
 			},
 		},
@@ -642,7 +784,8 @@ func TestRuler_PrometheusRules(t *testing.T) {
 						filterTestExpectedRule("NonUniqueNamedRule"),
 						filterTestExpectedAlert("UniqueNamedRuleN1G1"),
 					},
-					Interval: 60,
+					Interval:    60,
+					QueryOffset: 60,
 				},
 				{
 					Name: groupName(2),
@@ -651,7 +794,8 @@ func TestRuler_PrometheusRules(t *testing.T) {
 						filterTestExpectedRule("NonUniqueNamedRule"),
 						filterTestExpectedAlert("UniqueNamedRuleN1G2"),
 					},
-					Interval: 60,
+					Interval:    60,
+					QueryOffset: 60,
 				},
 				{
 					Name: groupName(3),
@@ -660,7 +804,8 @@ func TestRuler_PrometheusRules(t *testing.T) {
 						filterTestExpectedRule("NonUniqueNamedRule"),
 						filterTestExpectedAlert("UniqueNamedRuleN1G3"),
 					},
-					Interval: 60,
+					Interval:    60,
+					QueryOffset: 60,
 				},
 			},
 		},
@@ -677,7 +822,8 @@ func TestRuler_PrometheusRules(t *testing.T) {
 						filterTestExpectedRule("NonUniqueNamedRule"),
 						filterTestExpectedAlert("UniqueNamedRuleN1G1"),
 					},
-					Interval: 60,
+					Interval:    60,
+					QueryOffset: 60,
 				},
 				{
 					Name: groupName(2),
@@ -686,7 +832,8 @@ func TestRuler_PrometheusRules(t *testing.T) {
 						filterTestExpectedRule("NonUniqueNamedRule"),
 						filterTestExpectedAlert("UniqueNamedRuleN1G2"),
 					},
-					Interval: 60,
+					Interval:    60,
+					QueryOffset: 60,
 				},
 				{
 					Name: groupName(3),
@@ -695,7 +842,8 @@ func TestRuler_PrometheusRules(t *testing.T) {
 						filterTestExpectedRule("NonUniqueNamedRule"),
 						filterTestExpectedAlert("UniqueNamedRuleN1G3"),
 					},
-					Interval: 60,
+					Interval:    60,
+					QueryOffset: 60,
 				},
 				{
 					Name: groupName(1),
@@ -704,7 +852,8 @@ func TestRuler_PrometheusRules(t *testing.T) {
 						filterTestExpectedRule("NonUniqueNamedRule"),
 						filterTestExpectedAlert("UniqueNamedRuleN2G1"),
 					},
-					Interval: 60,
+					Interval:    60,
+					QueryOffset: 60,
 				},
 				{
 					Name: groupName(2),
@@ -713,7 +862,8 @@ func TestRuler_PrometheusRules(t *testing.T) {
 						filterTestExpectedRule("NonUniqueNamedRule"),
 						filterTestExpectedAlert("UniqueNamedRuleN2G2"),
 					},
-					Interval: 60,
+					Interval:    60,
+					QueryOffset: 60,
 				},
 				{
 					Name: groupName(3),
@@ -722,7 +872,8 @@ func TestRuler_PrometheusRules(t *testing.T) {
 						filterTestExpectedRule("NonUniqueNamedRule"),
 						filterTestExpectedAlert("UniqueNamedRuleN2G3"),
 					},
-					Interval: 60,
+					Interval:    60,
+					QueryOffset: 60,
 				},
 			},
 		},
@@ -746,7 +897,8 @@ func TestRuler_PrometheusRules(t *testing.T) {
 						filterTestExpectedRule("NonUniqueNamedRule"),
 						filterTestExpectedAlert("UniqueNamedRuleN1G2"),
 					},
-					Interval: 60,
+					Interval:    60,
+					QueryOffset: 60,
 				},
 				{
 					Name: groupName(2),
@@ -755,7 +907,8 @@ func TestRuler_PrometheusRules(t *testing.T) {
 						filterTestExpectedRule("NonUniqueNamedRule"),
 						filterTestExpectedAlert("UniqueNamedRuleN2G2"),
 					},
-					Interval: 60,
+					Interval:    60,
+					QueryOffset: 60,
 				},
 				{
 					Name: groupName(2),
@@ -764,7 +917,8 @@ func TestRuler_PrometheusRules(t *testing.T) {
 						filterTestExpectedRule("NonUniqueNamedRule"),
 						filterTestExpectedAlert("UniqueNamedRuleN3G2"),
 					},
-					Interval: 60,
+					Interval:    60,
+					QueryOffset: 60,
 				},
 			},
 		},
@@ -781,7 +935,8 @@ func TestRuler_PrometheusRules(t *testing.T) {
 						filterTestExpectedRule("NonUniqueNamedRule"),
 						filterTestExpectedAlert("UniqueNamedRuleN1G2"),
 					},
-					Interval: 60,
+					Interval:    60,
+					QueryOffset: 60,
 				},
 				{
 					Name: groupName(3),
@@ -790,7 +945,8 @@ func TestRuler_PrometheusRules(t *testing.T) {
 						filterTestExpectedRule("NonUniqueNamedRule"),
 						filterTestExpectedAlert("UniqueNamedRuleN1G3"),
 					},
-					Interval: 60,
+					Interval:    60,
+					QueryOffset: 60,
 				},
 				{
 					Name: groupName(2),
@@ -799,7 +955,8 @@ func TestRuler_PrometheusRules(t *testing.T) {
 						filterTestExpectedRule("NonUniqueNamedRule"),
 						filterTestExpectedAlert("UniqueNamedRuleN2G2"),
 					},
-					Interval: 60,
+					Interval:    60,
+					QueryOffset: 60,
 				},
 				{
 					Name: groupName(3),
@@ -808,7 +965,8 @@ func TestRuler_PrometheusRules(t *testing.T) {
 						filterTestExpectedRule("NonUniqueNamedRule"),
 						filterTestExpectedAlert("UniqueNamedRuleN2G3"),
 					},
-					Interval: 60,
+					Interval:    60,
+					QueryOffset: 60,
 				},
 				{
 					Name: groupName(2),
@@ -817,7 +975,8 @@ func TestRuler_PrometheusRules(t *testing.T) {
 						filterTestExpectedRule("NonUniqueNamedRule"),
 						filterTestExpectedAlert("UniqueNamedRuleN3G2"),
 					},
-					Interval: 60,
+					Interval:    60,
+					QueryOffset: 60,
 				},
 				{
 					Name: groupName(3),
@@ -826,7 +985,8 @@ func TestRuler_PrometheusRules(t *testing.T) {
 						filterTestExpectedRule("NonUniqueNamedRule"),
 						filterTestExpectedAlert("UniqueNamedRuleN3G3"),
 					},
-					Interval: 60,
+					Interval:    60,
+					QueryOffset: 60,
 				},
 			},
 		},
@@ -850,7 +1010,8 @@ func TestRuler_PrometheusRules(t *testing.T) {
 					Rules: []rule{
 						filterTestExpectedAlert("UniqueNamedRuleN1G2"),
 					},
-					Interval: 60,
+					Interval:    60,
+					QueryOffset: 60,
 				},
 			},
 		},
@@ -866,7 +1027,8 @@ func TestRuler_PrometheusRules(t *testing.T) {
 					Rules: []rule{
 						filterTestExpectedAlert("UniqueNamedRuleN1G2"),
 					},
-					Interval: 60,
+					Interval:    60,
+					QueryOffset: 60,
 				},
 				{
 					Name: groupName(3),
@@ -874,7 +1036,8 @@ func TestRuler_PrometheusRules(t *testing.T) {
 					Rules: []rule{
 						filterTestExpectedAlert("UniqueNamedRuleN2G3"),
 					},
-					Interval: 60,
+					Interval:    60,
+					QueryOffset: 60,
 				},
 			},
 		},
@@ -892,24 +1055,110 @@ func TestRuler_PrometheusRules(t *testing.T) {
 					File: namespaceName(3),
 					Rules: []rule{
 						&recordingRule{
-							Name:   "NonUniqueNamedRule",
-							Query:  "up",
-							Health: "unknown",
-							Type:   "recording",
+							Name:              "NonUniqueNamedRule",
+							Query:             "up",
+							Health:            "unknown",
+							Type:              "recording",
+							NoDependentRules:  true,
+							NoDependencyRules: true,
 						},
 						&alertingRule{
-							Name:   "UniqueNamedRuleN3G2",
-							Query:  "up < 1",
-							State:  "inactive",
-							Health: "unknown",
-							Type:   "alerting",
-							Alerts: []*Alert{},
+							Name:              "UniqueNamedRuleN3G2",
+							Query:             "up < 1",
+							State:             "inactive",
+							Health:            "unknown",
+							Type:              "alerting",
+							Alerts:            []*Alert{},
+							NoDependentRules:  true,
+							NoDependencyRules: true,
 						},
 					},
-					Interval: 60,
+					Interval:    60,
+					QueryOffset: 60,
 				},
 			},
 		},
+		"when filtering by health then the API returns only rules with that health": {
+			configuredRules:    makeFilterTestRules(),
+			expectedConfigured: len(makeFilterTestRules()),
+			queryParams:        "?" + url.Values{"health": []string{"ok", "err"}}.Encode(),
+			limits:             validation.MockDefaultOverrides(),
+			expectedRules:      []*RuleGroup{},
+		},
+		"when filtering by an invalid health then the API returns an error": {
+			configuredRules:    makeFilterTestRules(),
+			expectedConfigured: len(makeFilterTestRules()),
+			queryParams:        "?health=bad",
+			limits:             validation.MockDefaultOverrides(),
+			expectedStatusCode: http.StatusBadRequest,
+			expectedErrorType:  v1.ErrBadData,
+		},
+		"when paginating then the API returns the first page and the next token": {
+			configuredRules:    makeFilterTestRules(),
+			expectedConfigured: len(makeFilterTestRules()),
+			queryParams:        "?" + url.Values{"rule_group": []string{groupName(1)}, "group_limit": []string{"2"}}.Encode(),
+			limits:             validation.MockDefaultOverrides(),
+			expectedRules: []*RuleGroup{
+				{
+					Name:        groupName(1),
+					File:        namespaceName(1),
+					Rules:       []rule{filterTestExpectedRule("NonUniqueNamedRule"), filterTestExpectedAlert("UniqueNamedRuleN1G1")},
+					Interval:    60,
+					QueryOffset: 60,
+				},
+				{
+					Name:        groupName(1),
+					File:        namespaceName(2),
+					Rules:       []rule{filterTestExpectedRule("NonUniqueNamedRule"), filterTestExpectedAlert("UniqueNamedRuleN2G1")},
+					Interval:    60,
+					QueryOffset: 60,
+				},
+			},
+			expectedNextToken: ruleGroupNextToken(namespaceName(3), groupName(1)),
+		},
+		"when paginating with a next token then the API returns the last page": {
+			configuredRules:    makeFilterTestRules(),
+			expectedConfigured: len(makeFilterTestRules()),
+			queryParams: "?" + url.Values{
+				"rule_group":       []string{groupName(1)},
+				"group_limit":      []string{"2"},
+				"group_next_token": []string{ruleGroupNextToken(namespaceName(3), groupName(1))},
+			}.Encode(),
+			limits: validation.MockDefaultOverrides(),
+			expectedRules: []*RuleGroup{
+				{
+					Name:        groupName(1),
+					File:        namespaceName(3),
+					Rules:       []rule{filterTestExpectedRule("NonUniqueNamedRule"), filterTestExpectedAlert("UniqueNamedRuleN3G1")},
+					Interval:    60,
+					QueryOffset: 60,
+				},
+			},
+		},
+		"when paginating with an unknown next token then the API returns an error": {
+			configuredRules:    makeFilterTestRules(),
+			expectedConfigured: len(makeFilterTestRules()),
+			queryParams:        "?group_limit=2&group_next_token=unknown",
+			limits:             validation.MockDefaultOverrides(),
+			expectedStatusCode: http.StatusBadRequest,
+			expectedErrorType:  v1.ErrBadData,
+		},
+		"when passing a next token without a group limit then the API returns an error": {
+			configuredRules:    makeFilterTestRules(),
+			expectedConfigured: len(makeFilterTestRules()),
+			queryParams:        "?group_next_token=" + ruleGroupNextToken(namespaceName(3), groupName(1)),
+			limits:             validation.MockDefaultOverrides(),
+			expectedStatusCode: http.StatusBadRequest,
+			expectedErrorType:  v1.ErrBadData,
+		},
+		"when passing an invalid group limit then the API returns an error": {
+			configuredRules:    makeFilterTestRules(),
+			expectedConfigured: len(makeFilterTestRules()),
+			queryParams:        "?group_limit=0",
+			limits:             validation.MockDefaultOverrides(),
+			expectedStatusCode: http.StatusBadRequest,
+			expectedErrorType:  v1.ErrBadData,
+		},
 	}
 
 	for name, tc := range testCases {
@@ -936,7 +1185,7 @@ func TestRuler_PrometheusRules(t *testing.T) {
 				return len(rls.Groups)
 			})
 
-			a := NewAPI(r, r.directStore, log.NewNopLogger())
+			a := NewAPI(r, r.directStore, nil, log.NewNopLogger())
 
 			req := requestFor(t, http.MethodGet, "https://localhost:8080/prometheus/api/v1/rules"+tc.queryParams, nil, userID)
 			w := httptest.NewRecorder()
@@ -961,12 +1210,17 @@ func TestRuler_PrometheusRules(t *testing.T) {
 			}
 			require.Equal(t, responseJSON.Status, "success")
 
-			// Testing the running rules
+			// Testing the running rules. The change token is tested separately.
+			expectedDiscovery := &RuleDiscovery{
+				RuleGroups:     tc.expectedRules,
+				GroupNextToken: tc.expectedNextToken,
+			}
+			if data, ok := responseJSON.Data.(map[string]interface{}); ok {
+				expectedDiscovery.ChangeToken, _ = data["changeToken"].(string)
+			}
 			expectedResponse, err := json.Marshal(response{
 				Status: "success",
-				Data: &RuleDiscovery{
-					RuleGroups: tc.expectedRules,
-				},
+				Data:   expectedDiscovery,
 			})
 
 			require.NoError(t, err)
@@ -976,6 +1230,85 @@ func TestRuler_PrometheusRules(t *testing.T) {
 	}
 }
 
+func TestRuler_PrometheusRules_SinceToken(t *testing.T) {
+	const userID = "user1"
+
+	cfg := defaultRulerConfig(t)
+	storageRules := map[string]rulespb.RuleGroupList{
+		userID: {
+			&rulespb.RuleGroupDesc{
+				Name:      "group1",
+				Namespace: "namespace1",
+				User:      userID,
+				Rules:     []*rulespb.RuleDesc{createRecordingRule("UP_RULE", "up"), createAlertingRule("UP_ALERT", "up < 1")},
+				Interval:  time.Minute,
+			},
+		},
+	}
+
+	r := prepareRuler(t, cfg, newMockRuleStore(storageRules), withRulerAddrAutomaticMapping(), withStart())
+	test.Poll(t, 5*time.Second, 1, func() interface{} {
+		rls, _ := r.Rules(user.InjectOrgID(context.Background(), userID), &RulesRequest{})
+		return len(rls.Groups)
+	})
+
+	a := NewAPI(r, r.directStore, nil, log.NewNopLogger())
+	getRules := func(queryParams string) RuleDiscovery {
+		req := requestFor(t, http.MethodGet, "https://localhost:8080/prometheus/api/v1/rules"+queryParams, nil, userID)
+		w := httptest.NewRecorder()
+		a.PrometheusRules(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		resp := struct {
+			Data RuleDiscovery `json:"data"`
+		}{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return resp.Data
+	}
+
+	first := getRules("")
+	require.Len(t, first.RuleGroups, 1)
+	require.NotEmpty(t, first.ChangeToken)
+	assert.False(t, first.Unchanged)
+
+	// The rule groups are omitted if they haven't changed since the given token.
+	unchanged := getRules("?since_token=" + first.ChangeToken)
+	assert.Empty(t, unchanged.RuleGroups)
+	assert.True(t, unchanged.Unchanged)
+	assert.Equal(t, first.ChangeToken, unchanged.ChangeToken)
+
+	// The rule groups are returned if the token doesn't match.
+	changed := getRules("?since_token=outdated")
+	assert.Len(t, changed.RuleGroups, 1)
+	assert.False(t, changed.Unchanged)
+
+	// The token depends on the returned rule groups.
+	filtered := getRules("?type=record&since_token=" + first.ChangeToken)
+	assert.Len(t, filtered.RuleGroups, 1)
+	assert.NotEqual(t, first.ChangeToken, filtered.ChangeToken)
+}
+
+func TestRuleGroupsChangeToken(t *testing.T) {
+	group := func(evaluatedAt time.Time, health string) []*RuleGroup {
+		return []*RuleGroup{{
+			Name:           "group1",
+			File:           "namespace1",
+			LastEvaluation: evaluatedAt,
+			EvaluationTime: float64(evaluatedAt.Second()),
+			Rules: []rule{
+				recordingRule{Name: "UP_RULE", Query: "up", Health: health, LastEvaluation: evaluatedAt, EvaluationTime: float64(evaluatedAt.Second())},
+				alertingRule{Name: "UP_ALERT", Query: "up < 1", State: "inactive", Health: health, LastEvaluation: evaluatedAt},
+			},
+		}}
+	}
+
+	now := time.Now()
+	token := ruleGroupsChangeToken(group(now, "ok"))
+	assert.Equal(t, token, ruleGroupsChangeToken(group(now.Add(time.Minute), "ok")), "evaluation timestamps and durations must not change the token")
+	assert.NotEqual(t, token, ruleGroupsChangeToken(group(now, "err")))
+	assert.NotEqual(t, token, ruleGroupsChangeToken(nil))
+}
+
 func TestRuler_PrometheusAlerts(t *testing.T) {
 	cfg := defaultRulerConfig(t)
 
@@ -993,7 +1326,7 @@ func TestRuler_PrometheusAlerts(t *testing.T) {
 		return len(rls.Groups)
 	})
 
-	a := NewAPI(r, r.directStore, log.NewNopLogger())
+	a := NewAPI(r, r.directStore, nil, log.NewNopLogger())
 
 	req := requestFor(t, http.MethodGet, "https://localhost:8080/prometheus/api/v1/alerts", nil, "user1")
 	w := httptest.NewRecorder()
@@ -1172,7 +1505,7 @@ rules:
 
 			reg := prometheus.NewPedanticRegistry()
 			r := prepareRuler(t, rulerCfg, newMockRuleStore(make(map[string]rulespb.RuleGroupList)), withStart(), withRulerAddrAutomaticMapping(), withPrometheusRegisterer(reg))
-			a := NewAPI(r, r.directStore, log.NewNopLogger())
+			a := NewAPI(r, r.directStore, nil, log.NewNopLogger())
 
 			router := mux.NewRouter()
 			router.Path("/prometheus/config/v1/rules/{namespace}").Methods("POST").HandlerFunc(a.CreateRuleGroup)
@@ -1237,7 +1570,7 @@ func TestAPI_DeleteNamespace(t *testing.T) {
 
 	reg := prometheus.NewPedanticRegistry()
 	r := prepareRuler(t, cfg, newMockRuleStore(mockRulesNamespaces), withStart(), withRulerAddrAutomaticMapping(), withPrometheusRegisterer(reg))
-	a := NewAPI(r, r.directStore, log.NewNopLogger())
+	a := NewAPI(r, r.directStore, nil, log.NewNopLogger())
 
 	router := mux.NewRouter()
 	router.Path("/prometheus/config/v1/rules/{namespace}").Methods(http.MethodDelete).HandlerFunc(a.DeleteNamespace)
@@ -1294,7 +1627,7 @@ func TestAPI_DeleteRuleGroup(t *testing.T) {
 
 	reg := prometheus.NewPedanticRegistry()
 	r := prepareRuler(t, cfg, newMockRuleStore(mockRulesNamespaces), withStart(), withRulerAddrAutomaticMapping(), withPrometheusRegisterer(reg))
-	a := NewAPI(r, r.directStore, log.NewNopLogger())
+	a := NewAPI(r, r.directStore, nil, log.NewNopLogger())
 
 	router := mux.NewRouter()
 	router.Path("/prometheus/config/v1/rules/{namespace}/{groupName}").Methods(http.MethodDelete).HandlerFunc(a.DeleteRuleGroup)
@@ -1328,6 +1661,116 @@ func TestAPI_DeleteRuleGroup(t *testing.T) {
 	})
 }
 
+func TestAPI_NamespaceScopes(t *testing.T) {
+	cfg := defaultRulerConfig(t)
+
+	mockRulesNamespaces := map[string]rulespb.RuleGroupList{
+		"user1": {
+			&rulespb.RuleGroupDesc{
+				Name:      "group1",
+				Namespace: "team-a-frontend",
+				User:      "user1",
+				Rules:     []*rulespb.RuleDesc{createRecordingRule("UP_RULE", "up")},
+				Interval:  interval,
+			},
+			&rulespb.RuleGroupDesc{
+				Name:      "group1",
+				Namespace: "team-b-backend",
+				User:      "user1",
+				Rules:     []*rulespb.RuleDesc{createRecordingRule("UP2_RULE", "up")},
+				Interval:  interval,
+			},
+		},
+	}
+
+	limits := validation.MockOverrides(func(_ *validation.Limits, tenantLimits map[string]*validation.Limits) {
+		tenantLimits["user1"] = validation.MockDefaultLimits()
+		tenantLimits["user1"].RulerNamespaceScopes = []*validation.RulerNamespaceScope{
+			{Name: "team-a", NamespacePrefixes: []string{"team-a-"}},
+		}
+	})
+
+	r := prepareRuler(t, cfg, newMockRuleStore(mockRulesNamespaces), withStart(), withRulerAddrAutomaticMapping(), withLimits(limits))
+	a := NewAPI(r, r.directStore, nil, log.NewNopLogger())
+
+	router := mux.NewRouter()
+	router.Path("/prometheus/config/v1/rules").Methods(http.MethodGet).HandlerFunc(a.ListRules)
+	router.Path("/prometheus/config/v1/rules/{namespace}").Methods(http.MethodGet).HandlerFunc(a.ListRules)
+	router.Path("/prometheus/config/v1/rules/{namespace}").Methods(http.MethodPost).HandlerFunc(a.CreateRuleGroup)
+	router.Path("/prometheus/config/v1/rules/{namespace}").Methods(http.MethodDelete).HandlerFunc(a.DeleteNamespace)
+	router.Path("/prometheus/config/v1/rules/{namespace}/{groupName}").Methods(http.MethodGet).HandlerFunc(a.GetRuleGroup)
+	router.Path("/prometheus/config/v1/rules/{namespace}/{groupName}").Methods(http.MethodDelete).HandlerFunc(a.DeleteRuleGroup)
+
+	doRequest := func(method, path, scope string, body io.Reader) *httptest.ResponseRecorder {
+		req := requestFor(t, method, "https://localhost:8080/prometheus/config/v1/rules"+path, body, "user1")
+		if scope != "" {
+			req.Header.Set(NamespaceScopeHeader, scope)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("list rule groups within the scope", func(t *testing.T) {
+		w := doRequest(http.MethodGet, "", "team-a", nil)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		res := map[string][]rulefmt.RuleGroup{}
+		require.NoError(t, yaml.Unmarshal(w.Body.Bytes(), &res))
+		require.Len(t, res, 1)
+		require.Contains(t, res, "team-a-frontend")
+
+		require.Equal(t, http.StatusForbidden, doRequest(http.MethodGet, "/team-b-backend", "team-a", nil).Code)
+	})
+
+	t.Run("requests without scope are rejected", func(t *testing.T) {
+		require.Equal(t, http.StatusForbidden, doRequest(http.MethodGet, "", "", nil).Code)
+		require.Equal(t, http.StatusForbidden, doRequest(http.MethodGet, "/team-a-frontend/group1", "", nil).Code)
+	})
+
+	t.Run("prometheus rules API only returns the rule groups within the scope", func(t *testing.T) {
+		doPrometheusRulesRequest := func(scope string) *httptest.ResponseRecorder {
+			req := requestFor(t, http.MethodGet, "https://localhost:8080/prometheus/api/v1/rules", nil, "user1")
+			if scope != "" {
+				req.Header.Set(NamespaceScopeHeader, scope)
+			}
+			w := httptest.NewRecorder()
+			a.PrometheusRules(w, req)
+			return w
+		}
+
+		require.Equal(t, http.StatusForbidden, doPrometheusRulesRequest("").Code)
+
+		w := doPrometheusRulesRequest("team-a")
+		require.Equal(t, http.StatusOK, w.Code)
+
+		res := response{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+		data, err := json.Marshal(res.Data)
+		require.NoError(t, err)
+		discovery := RuleDiscovery{}
+		require.NoError(t, json.Unmarshal(data, &discovery))
+		require.Len(t, discovery.RuleGroups, 1)
+		require.Equal(t, "team-a-frontend", discovery.RuleGroups[0].File)
+	})
+
+	t.Run("unknown scope", func(t *testing.T) {
+		require.Equal(t, http.StatusForbidden, doRequest(http.MethodGet, "", "team-c", nil).Code)
+	})
+
+	t.Run("namespaces out of the scope can't be read or modified", func(t *testing.T) {
+		group := "name: group2\nrules:\n- record: UP_RULE\n  expr: up\n"
+
+		require.Equal(t, http.StatusForbidden, doRequest(http.MethodGet, "/team-b-backend/group1", "team-a", nil).Code)
+		require.Equal(t, http.StatusForbidden, doRequest(http.MethodPost, "/team-b-backend", "team-a", strings.NewReader(group)).Code)
+		require.Equal(t, http.StatusForbidden, doRequest(http.MethodDelete, "/team-b-backend/group1", "team-a", nil).Code)
+		require.Equal(t, http.StatusForbidden, doRequest(http.MethodDelete, "/team-b-backend", "team-a", nil).Code)
+
+		require.Equal(t, http.StatusOK, doRequest(http.MethodGet, "/team-a-frontend/group1", "team-a", nil).Code)
+		require.Equal(t, http.StatusAccepted, doRequest(http.MethodPost, "/team-a-frontend", "team-a", strings.NewReader(group)).Code)
+	})
+}
+
 func TestRuler_LimitsPerGroup(t *testing.T) {
 	cfg := defaultRulerConfig(t)
 
@@ -1336,7 +1779,7 @@ func TestRuler_LimitsPerGroup(t *testing.T) {
 		defaults.RulerMaxRulesPerRuleGroup = 1
 	})))
 
-	a := NewAPI(r, r.directStore, log.NewNopLogger())
+	a := NewAPI(r, r.directStore, nil, log.NewNopLogger())
 
 	tc := []struct {
 		name   string
@@ -1389,7 +1832,7 @@ func TestRuler_RulerGroupLimits(t *testing.T) {
 		defaults.RulerMaxRulesPerRuleGroup = 1
 	})))
 
-	a := NewAPI(r, r.directStore, log.NewNopLogger())
+	a := NewAPI(r, r.directStore, nil, log.NewNopLogger())
 
 	tc := []struct {
 		name   string
@@ -1449,7 +1892,7 @@ func TestRuler_RulerGroupLimitsDisabled(t *testing.T) {
 		defaults.RulerMaxRulesPerRuleGroup = 0
 	})))
 
-	a := NewAPI(r, r.directStore, log.NewNopLogger())
+	a := NewAPI(r, r.directStore, nil, log.NewNopLogger())
 
 	tc := []struct {
 		name   string
@@ -1551,7 +1994,7 @@ func TestAPIRoutesCorrectlyHandleInvalidOrgID(t *testing.T) {
 
 		r := prepareRuler(t, cfg, newMockRuleStore(map[string]rulespb.RuleGroupList{}), withStart())
 
-		a := NewAPI(r, r.directStore, log.NewNopLogger())
+		a := NewAPI(r, r.directStore, nil, log.NewNopLogger())
 
 		router := mux.NewRouter()
 		router.Path("/api/v1/rules").Methods(http.MethodGet).HandlerFunc(a.PrometheusRules)
@@ -1581,3 +2024,83 @@ func requestFor(t *testing.T, method string, url string, body io.Reader, userID
 
 	return req.WithContext(ctx)
 }
+
+func TestAPI_DryRunRuleGroup(t *testing.T) {
+	cfg := defaultRulerConfig(t)
+	r := prepareRuler(t, cfg, newMockRuleStore(map[string]rulespb.RuleGroupList{}), withRulerAddrAutomaticMapping())
+
+	queryFunc := func(_ context.Context, qs string, ts time.Time) (promql.Vector, error) {
+		switch qs {
+		case "up":
+			return promql.Vector{{Metric: labels.FromStrings("__name__", "up", "job", "test"), T: ts.UnixMilli(), F: 1}}, nil
+		case "failing":
+			return nil, errors.New("query failed")
+		default:
+			return nil, nil
+		}
+	}
+
+	a := NewAPI(r, r.directStore, NewDryRunEvaluator(cfg, queryFunc, r.limits, log.NewNopLogger()), log.NewNopLogger())
+
+	router := mux.NewRouter()
+	router.Path("/prometheus/config/v1/rules/{namespace}/dry-run").Methods(http.MethodPost).HandlerFunc(a.DryRunRuleGroup)
+
+	t.Run("should evaluate the rule group without storing it", func(t *testing.T) {
+		body := `
+name: test
+rules:
+  - record: job:up
+    expr: up
+  - alert: UpAlert
+    expr: up
+    for: 5m
+  - alert: FailingAlert
+    expr: failing
+`
+		req := requestFor(t, http.MethodPost, "https://localhost:8080/prometheus/config/v1/rules/namespace/dry-run", strings.NewReader(body), "user1")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		res := struct {
+			Status string      `json:"status"`
+			Data   DryRunGroup `json:"data"`
+		}{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+		require.Equal(t, "success", res.Status)
+		require.Equal(t, "test", res.Data.Name)
+		require.Len(t, res.Data.Rules, 3)
+
+		recording := res.Data.Rules[0]
+		assert.Equal(t, v1.RuleTypeRecording, recording.Type)
+		assert.Equal(t, "ok", recording.Health)
+		require.Len(t, recording.Series, 1)
+		assert.Equal(t, labels.FromStrings("__name__", "job:up", "job", "test"), recording.Series[0].Metric)
+		assert.Contains(t, w.Body.String(), `"series":[{"metric":{"__name__":"job:up","job":"test"},"value":[`)
+
+		alerting := res.Data.Rules[1]
+		assert.Equal(t, v1.RuleTypeAlerting, alerting.Type)
+		assert.Equal(t, "ok", alerting.Health)
+		require.Len(t, alerting.Alerts, 1)
+		assert.Equal(t, "pending", alerting.Alerts[0].State)
+		assert.Equal(t, labels.FromStrings("alertname", "UpAlert", "job", "test"), alerting.Alerts[0].Labels)
+
+		failing := res.Data.Rules[2]
+		assert.Equal(t, "err", failing.Health)
+		assert.Equal(t, "query failed", failing.LastError)
+		assert.Empty(t, failing.Alerts)
+
+		// The rule group has not been stored.
+		groups, err := r.directStore.ListRuleGroupsForUserAndNamespace(context.Background(), "user1", "")
+		require.NoError(t, err)
+		assert.Empty(t, groups)
+	})
+
+	t.Run("should reject an invalid rule group", func(t *testing.T) {
+		req := requestFor(t, http.MethodPost, "https://localhost:8080/prometheus/config/v1/rules/namespace/dry-run", strings.NewReader("name: test\nrules: []\n"), "user1")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "rule group 'test' has no rules")
+	})
+}
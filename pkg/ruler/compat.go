@@ -8,6 +8,8 @@ package ruler
 import (
 	"context"
 	"errors"
+	"hash/fnv"
+	"sync"
 	"time"
 
 	"github.com/go-kit/log"
@@ -16,20 +18,27 @@ import (
 	"github.com/grafana/dskit/user"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/common/model"
 	"github.com/prometheus/prometheus/model/exemplar"
 	"github.com/prometheus/prometheus/model/histogram"
 	"github.com/prometheus/prometheus/model/labels"
 	"github.com/prometheus/prometheus/model/metadata"
+	"github.com/prometheus/prometheus/model/rulefmt"
 	"github.com/prometheus/prometheus/notifier"
 	"github.com/prometheus/prometheus/promql"
 	"github.com/prometheus/prometheus/promql/parser"
 	"github.com/prometheus/prometheus/rules"
 	"github.com/prometheus/prometheus/storage"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/atomic"
 
 	"github.com/grafana/mimir/pkg/mimirpb"
 	"github.com/grafana/mimir/pkg/querier"
 	querier_stats "github.com/grafana/mimir/pkg/querier/stats"
 	util_log "github.com/grafana/mimir/pkg/util/log"
+	"github.com/grafana/mimir/pkg/util/validation"
 )
 
 // Pusher is an ingester server that accepts pushes.
@@ -192,7 +201,34 @@ type RulesLimits interface {
 	RulerAlertingRulesEvaluationEnabled(userID string) bool
 	RulerSyncRulesOnChangesEnabled(userID string) bool
 	RulerProtectedNamespaces(userID string) []string
+	RulerNamespaceScopes(userID string) []*validation.RulerNamespaceScope
 	RulerMaxIndependentRuleEvaluationConcurrencyPerTenant(userID string) int64
+	RulerEvaluationJitter(userID string) time.Duration
+	RulerMaxSeriesPerRule(userID string) int
+	AlertmanagerAlertLabelValidationScheme(userID string) model.ValidationScheme
+}
+
+// ValidateAlertLabelsNotifyFunc wraps the NotifyFunc, dropping the alerts whose labels are not valid
+// according to the tenant's alert label validation scheme, as the Alertmanager would reject them.
+func ValidateAlertLabelsNotifyFunc(next rules.NotifyFunc, userID string, limits RulesLimits, discardedAlerts prometheus.Counter, logger log.Logger) rules.NotifyFunc {
+	return func(ctx context.Context, expr string, alerts ...*rules.Alert) {
+		scheme := limits.AlertmanagerAlertLabelValidationScheme(userID)
+
+		valid := alerts[:0:0]
+		for _, alert := range alerts {
+			err := alert.Labels.Validate(func(l labels.Label) error {
+				return validation.ValidateAlertLabel(l.Name, l.Value, scheme)
+			})
+			if err != nil {
+				level.Warn(logger).Log("msg", "dropping alert with invalid labels", "labels", alert.Labels.String(), "err", err)
+				discardedAlerts.Inc()
+				continue
+			}
+			valid = append(valid, alert)
+		}
+
+		next(ctx, expr, valid...)
+	}
 }
 
 func MetricsQueryFunc(qf rules.QueryFunc, queries, failedQueries prometheus.Counter, remoteQuerier bool) rules.QueryFunc {
@@ -332,6 +368,10 @@ func DefaultTenantManagerFactory(
 		Name: "cortex_ruler_queries_failed_total",
 		Help: "Number of failed queries by ruler.",
 	})
+	discardedAlerts := promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+		Name: "cortex_ruler_discarded_alerts_total",
+		Help: "Number of alerts discarded by ruler because their labels are not valid.",
+	}, []string{"user"})
 	var rulerQuerySeconds *prometheus.CounterVec
 	var zeroFetchedSeriesQueries *prometheus.CounterVec
 	if cfg.EnableQueryStats {
@@ -367,14 +407,15 @@ func DefaultTenantManagerFactory(
 			appendeable = NewNoopAppendable()
 		}
 
-		return rules.NewManager(&rules.ManagerOptions{
+		loader := &limitsGroupLoader{GroupLoader: rules.FileLoader{}}
+		manager := rules.NewManager(&rules.ManagerOptions{
 			Appendable:                 appendeable,
 			Queryable:                  wrappedQueryable,
 			QueryFunc:                  wrappedQueryFunc,
 			Context:                    user.InjectOrgID(ctx, userID),
 			GroupEvaluationContextFunc: FederatedGroupContextFunc,
 			ExternalURL:                cfg.ExternalURL.URL,
-			NotifyFunc:                 rules.SendAlerts(notifier, cfg.ExternalURL.String()),
+			NotifyFunc:                 ValidateAlertLabelsNotifyFunc(rules.SendAlerts(notifier, cfg.ExternalURL.String()), userID, overrides, discardedAlerts.WithLabelValues(userID), logger),
 			Logger:                     log.With(logger, "component", "ruler", "insight", true, "user", userID),
 			Registerer:                 reg,
 			OutageTolerance:            cfg.OutageTolerance,
@@ -387,10 +428,202 @@ func DefaultTenantManagerFactory(
 				return overrides.EvaluationDelay(userID)
 			},
 			RuleConcurrencyController: concurrencyController.NewTenantConcurrencyControllerFor(userID),
+			GroupLoader:               loader,
 		})
+
+		return &limitsRulesManager{
+			RulesManager: newEvaluationJitterRulesManager(manager, userID, overrides),
+			userID:       userID,
+			limits:       overrides,
+			loader:       loader,
+		}
+	}
+}
+
+// evaluationJitterRulesManager is a RulesManager delaying the evaluation of each rule group
+// by a deterministic per-group offset, within the tenant's evaluation jitter.
+type evaluationJitterRulesManager struct {
+	RulesManager
+
+	userID string
+	limits RulesLimits
+
+	// jitterInterrupted is closed while the rule groups are updated or stopped, to interrupt the evaluation
+	// jitter waits: stopping a rule group waits for its in-flight evaluation to return.
+	jitterMx          sync.Mutex
+	jitterInterrupted chan struct{}
+	stopped           bool
+}
+
+func newEvaluationJitterRulesManager(manager RulesManager, userID string, limits RulesLimits) *evaluationJitterRulesManager {
+	return &evaluationJitterRulesManager{
+		RulesManager:      manager,
+		userID:            userID,
+		limits:            limits,
+		jitterInterrupted: make(chan struct{}),
 	}
 }
 
+func (m *evaluationJitterRulesManager) Update(interval time.Duration, files []string, externalLabels labels.Labels, externalURL string, groupEvalIterationFunc rules.GroupEvalIterationFunc) error {
+	if groupEvalIterationFunc == nil {
+		groupEvalIterationFunc = rules.DefaultEvalIterationFunc
+	}
+
+	// The rules manager stops the rule groups which have changed or have been removed, so the
+	// jitter waits are interrupted until the update is done.
+	m.interruptJitter()
+	defer m.resumeJitter()
+
+	groupEvalIterationFunc = RuleGroupEvaluationTracingIterationFunc(m.userID, groupEvalIterationFunc)
+	return m.RulesManager.Update(interval, files, externalLabels, externalURL, EvaluationJitterIterationFunc(m.userID, m.limits, m.jitterInterruptedChan, groupEvalIterationFunc))
+}
+
+func (m *evaluationJitterRulesManager) Stop() {
+	m.jitterMx.Lock()
+	m.stopped = true
+	m.jitterMx.Unlock()
+
+	m.interruptJitter()
+	m.RulesManager.Stop()
+}
+
+func (m *evaluationJitterRulesManager) interruptJitter() {
+	m.jitterMx.Lock()
+	defer m.jitterMx.Unlock()
+
+	select {
+	case <-m.jitterInterrupted:
+	default:
+		close(m.jitterInterrupted)
+	}
+}
+
+func (m *evaluationJitterRulesManager) resumeJitter() {
+	m.jitterMx.Lock()
+	defer m.jitterMx.Unlock()
+
+	if !m.stopped {
+		m.jitterInterrupted = make(chan struct{})
+	}
+}
+
+func (m *evaluationJitterRulesManager) jitterInterruptedChan() <-chan struct{} {
+	m.jitterMx.Lock()
+	defer m.jitterMx.Unlock()
+
+	return m.jitterInterrupted
+}
+
+// RuleGroupEvaluationTracingIterationFunc returns a rules.GroupEvalIterationFunc which runs each evaluation of the
+// rule group within a tracing span. The spans of the rules evaluated in the iteration, and of the queries they run,
+// are children of the rule group's span, so that each rule group evaluation is traced as a single trace.
+func RuleGroupEvaluationTracingIterationFunc(userID string, next rules.GroupEvalIterationFunc) rules.GroupEvalIterationFunc {
+	return func(ctx context.Context, g *rules.Group, evalTimestamp time.Time) {
+		// The span is started through OpenTelemetry, like the spans of the rules started by the Prometheus rules manager.
+		ctx, sp := otel.Tracer("").Start(ctx, "ruler.RuleGroupEvaluation", trace.WithAttributes(
+			attribute.String("user", userID),
+			attribute.String("rule_group_file", g.File()),
+			attribute.String("rule_group", g.Name()),
+			attribute.Int("rules", len(g.Rules())),
+			attribute.Int64("eval_timestamp", evalTimestamp.UnixMilli()),
+		))
+		defer sp.End()
+
+		next(ctx, g, evalTimestamp)
+	}
+}
+
+// EvaluationJitterIterationFunc returns a rules.GroupEvalIterationFunc which waits for the rule group's
+// evaluation jitter before calling next. The evaluation timestamp is not changed. The wait is interrupted
+// when the channel returned by interrupted is closed, in which case the rule group is evaluated right away:
+// the rule groups being stopped return from the evaluation without evaluating any rule.
+func EvaluationJitterIterationFunc(userID string, limits RulesLimits, interrupted func() <-chan struct{}, next rules.GroupEvalIterationFunc) rules.GroupEvalIterationFunc {
+	return func(ctx context.Context, g *rules.Group, evalTimestamp time.Time) {
+		if delay := ruleGroupEvaluationJitter(g.File(), g.Name(), g.Interval(), limits.RulerEvaluationJitter(userID)); delay > 0 {
+			timer := time.NewTimer(delay)
+			defer timer.Stop()
+
+			select {
+			case <-timer.C:
+			case <-interrupted():
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		next(ctx, g, evalTimestamp)
+	}
+}
+
+// ruleGroupEvaluationJitter returns the delay applied to each evaluation of the rule group.
+// The delay is computed hashing the rule group, so that the evaluations of a tenant's rule groups
+// are spread over the jitter, while each rule group is evaluated at regular intervals. The jitter
+// is capped to half of the rule group's interval to not miss evaluations.
+func ruleGroupEvaluationJitter(file, name string, interval, jitter time.Duration) time.Duration {
+	if jitter <= 0 || interval <= 0 {
+		return 0
+	}
+	if maxJitter := interval / 2; jitter > maxJitter {
+		jitter = maxJitter
+	}
+	if jitter <= 0 {
+		return 0
+	}
+
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(file))
+	_, _ = h.Write([]byte{0})
+	_, _ = h.Write([]byte(name))
+
+	return time.Duration(h.Sum64() % uint64(jitter))
+}
+
+// limitsGroupLoader is a rules.GroupLoader applying the tenant's limit on the number of series a single
+// rule can produce per evaluation to the loaded rule groups.
+type limitsGroupLoader struct {
+	rules.GroupLoader
+
+	// maxSeriesPerRule is the limit applied to the rule groups, set by limitsRulesManager before loading them.
+	maxSeriesPerRule atomic.Int64
+}
+
+func (l *limitsGroupLoader) Load(identifier string) (*rulefmt.RuleGroups, []error) {
+	groups, errs := l.GroupLoader.Load(identifier)
+	if groups == nil {
+		return groups, errs
+	}
+
+	if limit := int(l.maxSeriesPerRule.Load()); limit > 0 {
+		for i, g := range groups.Groups {
+			if g.Limit <= 0 || g.Limit > limit {
+				groups.Groups[i].Limit = limit
+			}
+		}
+	}
+	return groups, errs
+}
+
+// limitsRulesManager is a RulesManager applying the tenant's limits to the rule groups when they're loaded.
+// The limits are read when the rule groups are loaded, so the rule groups must be reloaded when they change.
+type limitsRulesManager struct {
+	RulesManager
+
+	userID string
+	limits RulesLimits
+	loader *limitsGroupLoader
+}
+
+func (m *limitsRulesManager) Update(interval time.Duration, files []string, externalLabels labels.Labels, externalURL string, groupEvalIterationFunc rules.GroupEvalIterationFunc) error {
+	m.loader.maxSeriesPerRule.Store(int64(m.limits.RulerMaxSeriesPerRule(m.userID)))
+	return m.RulesManager.Update(interval, files, externalLabels, externalURL, groupEvalIterationFunc)
+}
+
+// ReloadRequired implements reloadableRulesManager. It returns true if the tenant's limits have changed since
+// the rule groups were loaded. Only the rule groups whose limit changes are restarted when reloaded.
+func (m *limitsRulesManager) ReloadRequired() bool {
+	return int64(m.limits.RulerMaxSeriesPerRule(m.userID)) != m.loader.maxSeriesPerRule.Load()
+}
+
 type QueryableError struct {
 	err error
 }
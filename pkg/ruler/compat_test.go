@@ -18,6 +18,7 @@ import (
 	"github.com/go-kit/log"
 	"github.com/gogo/status"
 	"github.com/grafana/dskit/httpgrpc"
+	"github.com/opentracing/opentracing-go/mocktracer"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
 	"github.com/prometheus/client_golang/prometheus/testutil"
@@ -34,6 +35,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
 	"go.uber.org/atomic"
 	"google.golang.org/grpc/codes"
 
@@ -42,6 +44,8 @@ import (
 	"github.com/grafana/mimir/pkg/ruler/rulespb"
 	"github.com/grafana/mimir/pkg/storage/series"
 	"github.com/grafana/mimir/pkg/util/test"
+	"github.com/grafana/mimir/pkg/util/tracing"
+	"github.com/grafana/mimir/pkg/util/validation"
 )
 
 type fakePusher struct {
@@ -608,6 +612,115 @@ func TestDefaultManagerFactory_ShouldNotWriteRecordingRuleResultsWhenDisabled(t
 
 }
 
+func TestDefaultManagerFactory_ShouldApplyMaxSeriesPerRuleLimit(t *testing.T) {
+	const userID = "tenant-1"
+
+	ruleGroup := rulespb.RuleGroupDesc{
+		Name:     "test",
+		Interval: time.Second,
+		Rules: []*rulespb.RuleDesc{{
+			Record: "one_series",
+			Expr:   "vector(1)",
+		}, {
+			Record: "two_series",
+			Expr:   `vector(1) or label_replace(vector(2), "series", "second", "", "")`,
+		}},
+	}
+
+	cfg := defaultRulerConfig(t)
+	options := applyPrepareOptions(t, cfg.Ring.Common.InstanceID, withLimits(validation.MockOverrides(func(defaults *validation.Limits, _ map[string]*validation.Limits) {
+		defaults.RulerMaxSeriesPerRule = 1
+	})))
+
+	var (
+		notifierManager = notifier.NewManager(&notifier.Options{Do: func(_ context.Context, _ *http.Client, _ *http.Request) (*http.Response, error) { return nil, nil }}, options.logger)
+		ruleFiles       = writeRuleGroupToFiles(t, cfg.RulePath, options.logger, userID, ruleGroup)
+		queryable       = newMockQueryable()
+		tracker         = promql.NewActiveQueryTracker(t.TempDir(), 20, log.NewNopLogger())
+		eng             = promql.NewEngine(promql.EngineOpts{
+			MaxSamples:         1e6,
+			ActiveQueryTracker: tracker,
+			Timeout:            2 * time.Minute,
+		})
+		queryFunc = rules.EngineQueryFunc(eng, queryable)
+	)
+
+	pusher := newPusherMock()
+	pusher.MockPush(&mimirpb.WriteResponse{}, nil)
+
+	factory := DefaultTenantManagerFactory(cfg, pusher, queryable, queryFunc, &NoopMultiTenantConcurrencyController{}, options.limits, nil)
+	manager := factory(context.Background(), userID, notifierManager, options.logger, nil)
+
+	require.NoError(t, manager.Update(time.Millisecond, ruleFiles, labels.EmptyLabels(), "", nil))
+	go manager.Run()
+	t.Cleanup(manager.Stop)
+
+	groups := manager.RuleGroups()
+	require.Len(t, groups, 1)
+	require.Equal(t, 1, groups[0].Limit())
+
+	// The rule exceeding the limit fails, while the other one is successfully evaluated.
+	require.EventuallyWithT(t, func(collect *assert.CollectT) {
+		ruleList := groups[0].Rules()
+		require.Len(collect, ruleList, 2)
+		assert.Equal(collect, rules.HealthGood, ruleList[0].Health())
+		assert.Equal(collect, rules.HealthBad, ruleList[1].Health())
+		assert.ErrorContains(collect, ruleList[1].LastError(), "exceeded limit of 1 with 2 series")
+	}, 5*time.Second, 100*time.Millisecond)
+}
+
+// maxSeriesPerRuleLimits is a RulesLimits whose limit on the number of series per rule can be changed.
+type maxSeriesPerRuleLimits struct {
+	RulesLimits
+
+	maxSeriesPerRule atomic.Int64
+}
+
+func (l *maxSeriesPerRuleLimits) RulerMaxSeriesPerRule(string) int {
+	return int(l.maxSeriesPerRule.Load())
+}
+
+func TestDefaultManagerFactory_ShouldReloadRuleGroupsWhenMaxSeriesPerRuleLimitChanges(t *testing.T) {
+	const userID = "tenant-1"
+
+	limits := &maxSeriesPerRuleLimits{RulesLimits: validation.MockDefaultOverrides()}
+	limits.maxSeriesPerRule.Store(1)
+
+	cfg := defaultRulerConfig(t)
+	logger := log.NewNopLogger()
+	queryable := newMockQueryable()
+	eng := promql.NewEngine(promql.EngineOpts{MaxSamples: 1e6, Timeout: 2 * time.Minute})
+
+	factory := DefaultTenantManagerFactory(cfg, newPusherMock(), queryable, rules.EngineQueryFunc(eng, queryable), &NoopMultiTenantConcurrencyController{}, limits, nil)
+	m, err := NewDefaultMultiTenantManager(cfg, factory, nil, logger, nil)
+	require.NoError(t, err)
+	t.Cleanup(m.Stop)
+
+	ruleGroups := map[string]rulespb.RuleGroupList{
+		userID: {createRuleGroup("group-1", userID, createRecordingRule("count:metric_1", "count(metric_1)"))},
+	}
+	m.SyncFullRuleGroups(context.Background(), ruleGroups)
+	m.Start()
+
+	groupLimit := func() int {
+		groups := m.GetRules(userID)
+		require.Len(t, groups, 1)
+		return groups[0].Limit()
+	}
+	require.Equal(t, 1, groupLimit())
+	require.Equal(t, 1.0, testutil.ToFloat64(m.configUpdatesTotal.WithLabelValues(userID)))
+
+	// The rule groups are not reloaded if neither the rule files nor the limit have changed.
+	m.SyncFullRuleGroups(context.Background(), ruleGroups)
+	require.Equal(t, 1.0, testutil.ToFloat64(m.configUpdatesTotal.WithLabelValues(userID)))
+
+	// The rule groups are reloaded with the new limit when it changes.
+	limits.maxSeriesPerRule.Store(5)
+	m.SyncFullRuleGroups(context.Background(), ruleGroups)
+	require.Equal(t, 2.0, testutil.ToFloat64(m.configUpdatesTotal.WithLabelValues(userID)))
+	require.Equal(t, 5, groupLimit())
+}
+
 func TestDefaultManagerFactory_ShouldInjectReadConsistencyToContextBasedOnRuleDetail(t *testing.T) {
 	const userID = "tenant-1"
 
@@ -846,3 +959,285 @@ func mustStatusWithDetails(code codes.Code, cause mimirpb.ErrorCause) *status.St
 	}
 	return s
 }
+
+func TestRuleGroupEvaluationJitter(t *testing.T) {
+	// Jitter disabled.
+	assert.Equal(t, time.Duration(0), ruleGroupEvaluationJitter("file", "group", time.Minute, 0))
+
+	// The delay is deterministic and within the jitter.
+	delay := ruleGroupEvaluationJitter("file", "group", time.Minute, 10*time.Second)
+	assert.Equal(t, delay, ruleGroupEvaluationJitter("file", "group", time.Minute, 10*time.Second))
+	assert.GreaterOrEqual(t, delay, time.Duration(0))
+	assert.Less(t, delay, 10*time.Second)
+
+	// The jitter is capped to half of the interval.
+	for i := 0; i < 100; i++ {
+		assert.Less(t, ruleGroupEvaluationJitter("file", fmt.Sprintf("group-%d", i), 10*time.Second, time.Hour), 5*time.Second)
+	}
+
+	// The evaluations of different rule groups are spread over the jitter.
+	delays := map[time.Duration]struct{}{}
+	for i := 0; i < 100; i++ {
+		delays[ruleGroupEvaluationJitter("file", fmt.Sprintf("group-%d", i), time.Minute, 30*time.Second)] = struct{}{}
+	}
+	assert.Greater(t, len(delays), 90)
+}
+
+func TestEvaluationJitterIterationFunc(t *testing.T) {
+	const userID = "user-1"
+
+	limits := validation.MockOverrides(func(_ *validation.Limits, tenantLimits map[string]*validation.Limits) {
+		tenantLimits[userID] = validation.MockDefaultLimits()
+		tenantLimits[userID].RulerEvaluationJitter = model.Duration(200 * time.Millisecond)
+	})
+
+	group := rules.NewGroup(rules.GroupOptions{
+		File:     "namespace",
+		Name:     "group",
+		Interval: time.Minute,
+		Opts:     &rules.ManagerOptions{},
+	})
+	expectedDelay := ruleGroupEvaluationJitter("namespace", "group", time.Minute, 200*time.Millisecond)
+
+	t.Run("should delay the evaluation without changing the evaluation timestamp", func(t *testing.T) {
+		evalTimestamp := time.Now()
+
+		var calledAt, calledWithTimestamp time.Time
+		fn := EvaluationJitterIterationFunc(userID, limits, neverInterrupted, func(_ context.Context, _ *rules.Group, ts time.Time) {
+			calledAt = time.Now()
+			calledWithTimestamp = ts
+		})
+
+		fn(context.Background(), group, evalTimestamp)
+		assert.Equal(t, evalTimestamp, calledWithTimestamp)
+		assert.GreaterOrEqual(t, calledAt.Sub(evalTimestamp), expectedDelay)
+	})
+
+	t.Run("should not evaluate the group if the context is canceled while waiting", func(t *testing.T) {
+		if expectedDelay == 0 {
+			t.Skip("the rule group has no delay")
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		called := false
+		fn := EvaluationJitterIterationFunc(userID, limits, neverInterrupted, func(context.Context, *rules.Group, time.Time) {
+			called = true
+		})
+
+		fn(ctx, group, time.Now())
+		assert.False(t, called)
+	})
+
+	t.Run("should not delay the evaluation if the jitter is disabled for the tenant", func(t *testing.T) {
+		called := false
+		fn := EvaluationJitterIterationFunc("user-2", limits, neverInterrupted, func(context.Context, *rules.Group, time.Time) {
+			called = true
+		})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		fn(ctx, group, time.Now())
+		assert.True(t, called)
+	})
+
+	t.Run("should evaluate the group right away if the wait is interrupted", func(t *testing.T) {
+		if expectedDelay == 0 {
+			t.Skip("the rule group has no delay")
+		}
+
+		interrupted := make(chan struct{})
+		close(interrupted)
+
+		called := false
+		fn := EvaluationJitterIterationFunc(userID, limits, func() <-chan struct{} { return interrupted }, func(context.Context, *rules.Group, time.Time) {
+			called = true
+		})
+
+		start := time.Now()
+		fn(context.Background(), group, start)
+		assert.True(t, called)
+		assert.Less(t, time.Since(start), expectedDelay)
+	})
+}
+
+func TestEvaluationJitterRulesManager_StopDuringJitterWait(t *testing.T) {
+	const userID = "user-1"
+
+	limits := validation.MockOverrides(func(_ *validation.Limits, tenantLimits map[string]*validation.Limits) {
+		tenantLimits[userID] = validation.MockDefaultLimits()
+		tenantLimits[userID].RulerEvaluationJitter = model.Duration(time.Hour)
+	})
+
+	// The group is waiting for its jitter when it's stopped, and stopping it waits for the evaluation
+	// to return, like the Prometheus rules manager does.
+	group := rules.NewGroup(rules.GroupOptions{
+		File:     "namespace",
+		Name:     "group",
+		Interval: 24 * time.Hour,
+		Opts:     &rules.ManagerOptions{},
+	})
+	require.Greater(t, ruleGroupEvaluationJitter("namespace", "group", 24*time.Hour, time.Hour), time.Minute)
+
+	evaluated := make(chan struct{})
+	inner := &stoppingRulesManager{terminated: evaluated}
+	m := newEvaluationJitterRulesManager(inner, userID, limits)
+
+	fn := EvaluationJitterIterationFunc(userID, limits, m.jitterInterruptedChan, func(context.Context, *rules.Group, time.Time) {})
+	go func() {
+		defer close(evaluated)
+		fn(context.Background(), group, time.Now())
+	}()
+
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		m.Stop()
+	}()
+
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "the rules manager didn't stop while a rule group was waiting for its evaluation jitter")
+	}
+
+	// The jitter isn't resumed once the rules manager has been stopped.
+	require.NoError(t, m.Update(time.Minute, nil, labels.EmptyLabels(), "", nil))
+	select {
+	case <-m.jitterInterruptedChan():
+	default:
+		require.Fail(t, "the evaluation jitter has been resumed after the rules manager has been stopped")
+	}
+}
+
+func neverInterrupted() <-chan struct{} {
+	return nil
+}
+
+// stoppingRulesManager is a RulesManager whose Stop waits for terminated to be closed.
+type stoppingRulesManager struct {
+	RulesManager
+
+	terminated chan struct{}
+}
+
+func (m *stoppingRulesManager) Stop() {
+	<-m.terminated
+}
+
+func (m *stoppingRulesManager) Update(time.Duration, []string, labels.Labels, string, rules.GroupEvalIterationFunc) error {
+	return nil
+}
+
+func TestRuleGroupEvaluationTracingIterationFunc(t *testing.T) {
+	tracer := mocktracer.New()
+	prevProvider := otel.GetTracerProvider()
+	otel.SetTracerProvider(tracing.NewOpenTelemetryProviderBridge(tracer))
+	t.Cleanup(func() { otel.SetTracerProvider(prevProvider) })
+
+	expr, err := parser.ParseExpr("sum(up)")
+	require.NoError(t, err)
+
+	queryFunc := func(ctx context.Context, _ string, _ time.Time) (promql.Vector, error) {
+		_, sp := otel.Tracer("").Start(ctx, "query")
+		defer sp.End()
+		return promql.Vector{}, nil
+	}
+
+	group := rules.NewGroup(rules.GroupOptions{
+		File:     "namespace",
+		Name:     "group",
+		Interval: time.Minute,
+		Rules: []rules.Rule{
+			rules.NewRecordingRule("rule-1", expr, labels.EmptyLabels()),
+			rules.NewRecordingRule("rule-2", expr, labels.EmptyLabels()),
+		},
+		Opts: &rules.ManagerOptions{
+			Appendable: NewNoopAppendable(),
+			QueryFunc:  queryFunc,
+			Logger:     log.NewNopLogger(),
+		},
+	})
+
+	evalTimestamp := time.Now()
+	fn := RuleGroupEvaluationTracingIterationFunc("user-1", rules.DefaultEvalIterationFunc)
+	fn(context.Background(), group, evalTimestamp)
+
+	spansByName := map[string][]*mocktracer.MockSpan{}
+	for _, sp := range tracer.FinishedSpans() {
+		spansByName[sp.OperationName] = append(spansByName[sp.OperationName], sp)
+	}
+
+	// There's a single span for the rule group evaluation.
+	require.Len(t, spansByName["ruler.RuleGroupEvaluation"], 1)
+	groupSpan := spansByName["ruler.RuleGroupEvaluation"][0]
+	assert.Equal(t, 0, groupSpan.ParentID)
+	assert.Equal(t, "user-1", groupSpan.Tag("user"))
+	assert.Equal(t, "namespace", groupSpan.Tag("rule_group_file"))
+	assert.Equal(t, "group", groupSpan.Tag("rule_group"))
+	assert.Equal(t, int64(2), groupSpan.Tag("rules"))
+	assert.Equal(t, evalTimestamp.UnixMilli(), groupSpan.Tag("eval_timestamp"))
+
+	// The span of each rule is a child of the rule group's span.
+	require.Len(t, spansByName["rule"], 2)
+	ruleSpanIDs := map[int]struct{}{}
+	for _, sp := range spansByName["rule"] {
+		assert.Equal(t, groupSpan.SpanContext.TraceID, sp.SpanContext.TraceID)
+		assert.Equal(t, groupSpan.SpanContext.SpanID, sp.ParentID)
+		ruleSpanIDs[sp.SpanContext.SpanID] = struct{}{}
+	}
+
+	// The span of each query is a child of the rule's span.
+	require.Len(t, spansByName["query"], 2)
+	for _, sp := range spansByName["query"] {
+		assert.Equal(t, groupSpan.SpanContext.TraceID, sp.SpanContext.TraceID)
+		assert.Contains(t, ruleSpanIDs, sp.ParentID)
+	}
+}
+
+func TestValidateAlertLabelsNotifyFunc(t *testing.T) {
+	const userID = "user-1"
+
+	alerts := []*rules.Alert{
+		{Labels: labels.FromStrings("alertname", "a", "job", "api")},
+		{Labels: labels.FromStrings("alertname", "b", "service.name", "api")},
+	}
+
+	for name, tc := range map[string]struct {
+		scheme             string
+		expectedAlertNames []string
+		expectedDiscarded  int
+	}{
+		"legacy scheme drops alerts with UTF-8 label names": {
+			scheme:             validation.AlertLabelValidationLegacy,
+			expectedAlertNames: []string{"a"},
+			expectedDiscarded:  1,
+		},
+		"UTF-8 scheme sends all alerts": {
+			scheme:             validation.AlertLabelValidationUTF8,
+			expectedAlertNames: []string{"a", "b"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			limits := validation.MockOverrides(func(_ *validation.Limits, tenantLimits map[string]*validation.Limits) {
+				tenantLimits[userID] = validation.MockDefaultLimits()
+				tenantLimits[userID].AlertmanagerAlertLabelValidationScheme = tc.scheme
+			})
+
+			discarded := promauto.With(nil).NewCounter(prometheus.CounterOpts{})
+
+			var sentAlertNames []string
+			fn := ValidateAlertLabelsNotifyFunc(func(_ context.Context, _ string, alerts ...*rules.Alert) {
+				for _, alert := range alerts {
+					sentAlertNames = append(sentAlertNames, alert.Labels.Get("alertname"))
+				}
+			}, userID, limits, discarded, log.NewNopLogger())
+
+			fn(context.Background(), "up == 0", alerts...)
+			assert.Equal(t, tc.expectedAlertNames, sentAlertNames)
+			assert.Equal(t, tc.expectedDiscarded, int(testutil.ToFloat64(discarded)))
+		})
+	}
+}
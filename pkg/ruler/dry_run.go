@@ -0,0 +1,147 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ruler
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/go-kit/log"
+	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/rulefmt"
+	"github.com/prometheus/prometheus/promql"
+	"github.com/prometheus/prometheus/promql/parser"
+	"github.com/prometheus/prometheus/rules"
+)
+
+// DryRunGroup is the result of the dry-run evaluation of a rule group.
+type DryRunGroup struct {
+	Name           string       `json:"name"`
+	Rules          []DryRunRule `json:"rules"`
+	LastEvaluation time.Time    `json:"lastEvaluation"`
+	EvaluationTime float64      `json:"evaluationTime"`
+}
+
+// DryRunRule is the result of the dry-run evaluation of a single rule. Recording rules
+// report the series they would have written, while alerting rules report the alerts
+// they would have fired.
+type DryRunRule struct {
+	Name           string        `json:"name"`
+	Query          string        `json:"query"`
+	Type           v1.RuleType   `json:"type"`
+	Health         string        `json:"health"`
+	LastError      string        `json:"lastError"`
+	EvaluationTime float64       `json:"evaluationTime"`
+	Series         promql.Vector `json:"series,omitempty"`
+	Alerts         []*Alert      `json:"alerts,omitempty"`
+}
+
+// DryRunEvaluator evaluates a rule group once, without writing the results of recording
+// rules nor sending the alerts of alerting rules.
+type DryRunEvaluator struct {
+	queryFunc   rules.QueryFunc
+	externalURL string
+	limits      RulesLimits
+	logger      log.Logger
+}
+
+// NewDryRunEvaluator makes a new DryRunEvaluator running the queries through queryFunc.
+func NewDryRunEvaluator(cfg Config, queryFunc rules.QueryFunc, limits RulesLimits, logger log.Logger) *DryRunEvaluator {
+	return &DryRunEvaluator{
+		queryFunc:   WrapQueryFuncWithReadConsistency(queryFunc, logger),
+		externalURL: cfg.ExternalURL.String(),
+		limits:      limits,
+		logger:      logger,
+	}
+}
+
+// Evaluate evaluates all rules of the input rule group at ts, for the tenant in the context.
+// Rules are evaluated sequentially and independently: the series produced by a recording rule
+// are not visible to the rules following it in the group.
+func (e *DryRunEvaluator) Evaluate(ctx context.Context, userID string, rg rulefmt.RuleGroup, ts time.Time) (*DryRunGroup, error) {
+	queryOffset := e.limits.EvaluationDelay(userID)
+	if rg.QueryOffset != nil {
+		queryOffset = time.Duration(*rg.QueryOffset)
+	} else if rg.EvaluationDelay != nil { //nolint:staticcheck // We want to intentionally access a deprecated field
+		queryOffset = time.Duration(*rg.EvaluationDelay) //nolint:staticcheck // We want to intentionally access a deprecated field
+	}
+
+	if len(rg.SourceTenants) > 0 {
+		ctx = context.WithValue(ctx, federatedGroupSourceTenants, rg.SourceTenants)
+	}
+
+	res := &DryRunGroup{
+		Name:           rg.Name,
+		Rules:          make([]DryRunRule, 0, len(rg.Rules)),
+		LastEvaluation: ts,
+	}
+
+	groupStart := time.Now()
+	for _, r := range rg.Rules {
+		expr, err := parser.ParseExpr(r.Expr.Value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse expression %q: %w", r.Expr.Value, err)
+		}
+
+		var rule rules.Rule
+		if r.Alert.Value != "" {
+			rule = rules.NewAlertingRule(
+				r.Alert.Value,
+				expr,
+				time.Duration(r.For),
+				time.Duration(r.KeepFiringFor),
+				labels.FromMap(r.Labels),
+				labels.FromMap(r.Annotations),
+				labels.EmptyLabels(),
+				e.externalURL,
+				true,
+				log.With(e.logger, "user", userID, "alert", r.Alert.Value),
+			)
+		} else {
+			rule = rules.NewRecordingRule(r.Record.Value, expr, labels.FromMap(r.Labels))
+		}
+
+		res.Rules = append(res.Rules, e.evaluateRule(ctx, rule, queryOffset, ts, rg.Limit))
+	}
+	res.EvaluationTime = time.Since(groupStart).Seconds()
+
+	return res, nil
+}
+
+func (e *DryRunEvaluator) evaluateRule(ctx context.Context, rule rules.Rule, queryOffset time.Duration, ts time.Time, limit int) DryRunRule {
+	start := time.Now()
+	vector, err := rule.Eval(ctx, queryOffset, ts, e.queryFunc, nil, limit)
+
+	res := DryRunRule{
+		Name:           rule.Name(),
+		Query:          rule.Query().String(),
+		Health:         string(rules.HealthGood),
+		EvaluationTime: time.Since(start).Seconds(),
+	}
+	if err != nil {
+		res.Health = string(rules.HealthBad)
+		res.LastError = err.Error()
+	}
+
+	switch rule := rule.(type) {
+	case *rules.AlertingRule:
+		res.Type = v1.RuleTypeAlerting
+		for _, a := range rule.ActiveAlerts() {
+			res.Alerts = append(res.Alerts, &Alert{
+				Labels:      a.Labels,
+				Annotations: a.Annotations,
+				State:       a.State.String(),
+				ActiveAt:    &a.ActiveAt,
+				Value:       strconv.FormatFloat(a.Value, 'e', -1, 64),
+			})
+		}
+	case *rules.RecordingRule:
+		res.Type = v1.RuleTypeRecording
+		res.Series = vector
+	}
+
+	return res
+}
@@ -0,0 +1,78 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ruler
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/prometheus/promql"
+	"github.com/prometheus/prometheus/promql/parser"
+	"github.com/prometheus/prometheus/rules"
+)
+
+// ExpressionLookback returns how far back in time, relative to the evaluation timestamp,
+// the given PromQL expression reads data. Range selectors, subqueries and offsets
+// are taken into account, while the engine's lookback delta for instant vector
+// selectors is ignored because it's the same for every expression. Selectors and
+// subqueries with the @ modifier may read data arbitrarily far back, so their
+// lookback is unbounded.
+func ExpressionLookback(expr parser.Expr) time.Duration {
+	return expressionLookback(expr, 0)
+}
+
+// unboundedLookback is the lookback of the expressions using the @ modifier.
+const unboundedLookback = time.Duration(math.MaxInt64)
+
+func expressionLookback(node parser.Node, base time.Duration) time.Duration {
+	switch n := node.(type) {
+	case *parser.SubqueryExpr:
+		if n.Timestamp != nil || n.StartOrEnd != 0 {
+			return unboundedLookback
+		}
+		// The inner expression is evaluated at each step of the subquery,
+		// so its own lookback adds up to the subquery range.
+		return expressionLookback(n.Expr, base+n.Range+n.OriginalOffset)
+	case *parser.MatrixSelector:
+		if vs, ok := n.VectorSelector.(*parser.VectorSelector); ok {
+			if vs.Timestamp != nil || vs.StartOrEnd != 0 {
+				return unboundedLookback
+			}
+			return base + n.Range + vs.OriginalOffset
+		}
+		return base + n.Range
+	case *parser.VectorSelector:
+		if n.Timestamp != nil || n.StartOrEnd != 0 {
+			return unboundedLookback
+		}
+		return base + n.OriginalOffset
+	}
+
+	var lookback time.Duration
+	for _, child := range parser.Children(node) {
+		lookback = max(lookback, expressionLookback(child, base))
+	}
+	return lookback
+}
+
+// LongLookbackOffloadingQueryFunc returns a rules.QueryFunc which evaluates the expressions whose
+// lookback is greater than threshold with remoteQueryFunc (typically running through the query-frontend,
+// where queries are split, sharded and cached), and all other expressions with localQueryFunc.
+func LongLookbackOffloadingQueryFunc(localQueryFunc, remoteQueryFunc rules.QueryFunc, threshold time.Duration, offloadedQueries prometheus.Counter) rules.QueryFunc {
+	return func(ctx context.Context, qs string, t time.Time) (promql.Vector, error) {
+		expr, err := parser.ParseExpr(qs)
+		if err != nil {
+			// Let the local engine report the parsing error.
+			return localQueryFunc(ctx, qs, t)
+		}
+
+		if ExpressionLookback(expr) <= threshold {
+			return localQueryFunc(ctx, qs, t)
+		}
+
+		offloadedQueries.Inc()
+		return remoteQueryFunc(ctx, qs, t)
+	}
+}
@@ -0,0 +1,114 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ruler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/prometheus/promql"
+	"github.com/prometheus/prometheus/promql/parser"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpressionLookback(t *testing.T) {
+	tests := map[string]struct {
+		query    string
+		expected time.Duration
+	}{
+		"instant vector selector": {
+			query:    `up`,
+			expected: 0,
+		},
+		"instant vector selector with offset": {
+			query:    `up offset 1h`,
+			expected: time.Hour,
+		},
+		"range vector selector": {
+			query:    `rate(http_requests_total[5m])`,
+			expected: 5 * time.Minute,
+		},
+		"range vector selector with offset": {
+			query:    `last_over_time(http_requests_total[30d] offset 1d)`,
+			expected: 31 * 24 * time.Hour,
+		},
+		"binary expression takes the max lookback": {
+			query:    `rate(a[5m]) / rate(b[1h])`,
+			expected: time.Hour,
+		},
+		"subquery": {
+			query:    `max_over_time(rate(http_requests_total[5m])[1d:1m])`,
+			expected: 24*time.Hour + 5*time.Minute,
+		},
+		"subquery with offset": {
+			query:    `max_over_time(rate(http_requests_total[5m])[1d:1m] offset 1h)`,
+			expected: 25*time.Hour + 5*time.Minute,
+		},
+		"range vector selector with @ timestamp": {
+			query:    `sum_over_time(http_requests_total[1h] @ 1600000000)`,
+			expected: unboundedLookback,
+		},
+		"range vector selector with @ start()": {
+			query:    `rate(http_requests_total[5m] @ start())`,
+			expected: unboundedLookback,
+		},
+		"instant vector selector with @ end()": {
+			query:    `up @ end()`,
+			expected: unboundedLookback,
+		},
+		"subquery with @ timestamp": {
+			query:    `max_over_time(rate(http_requests_total[5m])[1h:1m] @ 1600000000)`,
+			expected: unboundedLookback,
+		},
+		"subquery with @ start()": {
+			query:    `max_over_time(rate(http_requests_total[5m])[1h:1m] @ start())`,
+			expected: unboundedLookback,
+		},
+		"number literal": {
+			query:    `1`,
+			expected: 0,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			expr, err := parser.ParseExpr(tc.query)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, ExpressionLookback(expr))
+		})
+	}
+}
+
+func TestLongLookbackOffloadingQueryFunc(t *testing.T) {
+	var localCalls, remoteCalls int
+	local := func(context.Context, string, time.Time) (promql.Vector, error) {
+		localCalls++
+		return nil, nil
+	}
+	remote := func(context.Context, string, time.Time) (promql.Vector, error) {
+		remoteCalls++
+		return nil, nil
+	}
+
+	offloaded := prometheus.NewCounter(prometheus.CounterOpts{})
+	queryFunc := LongLookbackOffloadingQueryFunc(local, remote, 24*time.Hour, offloaded)
+
+	for _, qs := range []string{`up`, `rate(foo[5m])`, `rate(foo[1d])`, `invalid(`} {
+		_, err := queryFunc(context.Background(), qs, time.Now())
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 4, localCalls)
+	assert.Equal(t, 0, remoteCalls)
+
+	for _, qs := range []string{`last_over_time(foo[30d])`, `rate(foo[1d] offset 1m)`, `max_over_time(foo[1d:5m] offset 1h)`, `sum_over_time(foo[5m] @ 1600000000)`, `max_over_time(foo[5m:1m] @ start())`} {
+		_, err := queryFunc(context.Background(), qs, time.Now())
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 4, localCalls)
+	assert.Equal(t, 5, remoteCalls)
+	assert.Equal(t, float64(5), testutil.ToFloat64(offloaded))
+}
@@ -32,6 +32,12 @@ import (
 	"github.com/grafana/mimir/pkg/ruler/rulespb"
 )
 
+// reloadableRulesManager is implemented by the RulesManagers which may need to reload the rule groups
+// even if the rule files haven't changed.
+type reloadableRulesManager interface {
+	ReloadRequired() bool
+}
+
 type DefaultMultiTenantManager struct {
 	cfg            Config
 	notifierCfg    *config.Config
@@ -54,6 +60,7 @@ type DefaultMultiTenantManager struct {
 	lastReloadSuccessful          *prometheus.GaugeVec
 	lastReloadSuccessfulTimestamp *prometheus.GaugeVec
 	configUpdatesTotal            *prometheus.CounterVec
+	independentRules              *prometheus.GaugeVec
 	registry                      prometheus.Registerer
 	logger                        log.Logger
 
@@ -100,6 +107,11 @@ func NewDefaultMultiTenantManager(cfg Config, managerFactory ManagerFactory, reg
 			Name:      "ruler_config_updates_total",
 			Help:      "Total number of config updates triggered by a user",
 		}, []string{"user"}),
+		independentRules: promauto.With(reg).NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "cortex",
+			Name:      "ruler_independent_rules",
+			Help:      "Number of rules which neither depend on, nor are depended on by, any other rule in the same group. Independent rules can be evaluated concurrently.",
+		}, []string{"user"}),
 		registry: reg,
 		logger:   logger,
 	}, nil
@@ -207,8 +219,13 @@ func (r *DefaultMultiTenantManager) syncRulesToManager(user string, groups rules
 		return
 	}
 
-	// We need to update the manager only if it was just created or rules on disk have changed.
-	if !(created || update) {
+	// We need to update the manager only if it was just created, rules on disk have changed, or the
+	// manager needs to reload them, for example because the tenant's limits applied to them have changed.
+	reload := false
+	if m, ok := manager.(reloadableRulesManager); ok {
+		reload = m.ReloadRequired()
+	}
+	if !(created || update || reload) {
 		level.Debug(r.logger).Log("msg", "rules have not changed, skipping rule manager update", "user", user)
 		return
 	}
@@ -225,6 +242,21 @@ func (r *DefaultMultiTenantManager) syncRulesToManager(user string, groups rules
 
 	r.lastReloadSuccessful.WithLabelValues(user).Set(1)
 	r.lastReloadSuccessfulTimestamp.WithLabelValues(user).SetToCurrentTime()
+	r.independentRules.WithLabelValues(user).Set(float64(countIndependentRules(manager.RuleGroups())))
+}
+
+// countIndependentRules returns the number of rules in groups which have no dependency and no dependent rules.
+// Rule dependencies are analysed by the Prometheus rules manager when the groups are loaded.
+func countIndependentRules(groups []*promRules.Group) int {
+	count := 0
+	for _, g := range groups {
+		for _, rule := range g.Rules() {
+			if rule.NoDependentRules() && rule.NoDependencyRules() {
+				count++
+			}
+		}
+	}
+	return count
 }
 
 // getOrCreateManager retrieves the user manager. If it doesn't exist, it will create and start it first.
@@ -352,6 +384,7 @@ func (r *DefaultMultiTenantManager) removeUsersIf(shouldRemove func(userID strin
 		r.lastReloadSuccessful.DeleteLabelValues(userID)
 		r.lastReloadSuccessfulTimestamp.DeleteLabelValues(userID)
 		r.configUpdatesTotal.DeleteLabelValues(userID)
+		r.independentRules.DeleteLabelValues(userID)
 		r.userManagerMetrics.RemoveUserRegistry(userID)
 		level.Info(r.logger).Log("msg", "deleted rule manager and local rule files", "user", userID)
 	}
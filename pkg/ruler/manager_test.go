@@ -23,6 +23,7 @@ import (
 	"github.com/prometheus/prometheus/model/labels"
 	"github.com/prometheus/prometheus/model/rulefmt"
 	"github.com/prometheus/prometheus/notifier"
+	"github.com/prometheus/prometheus/promql/parser"
 	promRules "github.com/prometheus/prometheus/rules"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -77,6 +78,7 @@ func TestDefaultMultiTenantManager_SyncFullRuleGroups(t *testing.T) {
 
 		// Check metrics.
 		assert.Equal(t, 0.0, promtest.ToFloat64(m.managersTotal))
+		assert.Equal(t, 0, promtest.CollectAndCount(m.independentRules))
 	})
 
 	t.Run("calling SyncFullRuleGroups() with the previous config restores the managers", func(t *testing.T) {
@@ -97,6 +99,7 @@ func TestDefaultMultiTenantManager_SyncFullRuleGroups(t *testing.T) {
 
 		// Check metrics.
 		assert.Equal(t, 2.0, promtest.ToFloat64(m.managersTotal))
+		assert.Equal(t, 2, promtest.CollectAndCount(m.independentRules))
 	})
 
 	t.Run("calling Stop() should stop all managers", func(t *testing.T) {
@@ -363,6 +366,31 @@ func TestDefaultMultiTenantManager_WaitsToDrainPendingNotificationsOnShutdown(t
 	}
 }
 
+func TestCountIndependentRules(t *testing.T) {
+	newRecordingRule := func(name string, noDependentRules, noDependencyRules bool) promRules.Rule {
+		rule := promRules.NewRecordingRule(name, &parser.NumberLiteral{Val: 1}, labels.EmptyLabels())
+		rule.SetNoDependentRules(noDependentRules)
+		rule.SetNoDependencyRules(noDependencyRules)
+		return rule
+	}
+
+	groups := []*promRules.Group{
+		promRules.NewGroup(promRules.GroupOptions{
+			Name:  "group-1",
+			Rules: []promRules.Rule{newRecordingRule("independent-1", true, true), newRecordingRule("with-dependents", false, true)},
+			Opts:  &promRules.ManagerOptions{},
+		}),
+		promRules.NewGroup(promRules.GroupOptions{
+			Name:  "group-2",
+			Rules: []promRules.Rule{newRecordingRule("with-dependencies", true, false), newRecordingRule("independent-2", true, true)},
+			Opts:  &promRules.ManagerOptions{},
+		}),
+	}
+
+	assert.Equal(t, 2, countIndependentRules(groups))
+	assert.Equal(t, 0, countIndependentRules(nil))
+}
+
 func waitForAlertmanagerToBeDiscovered(t *testing.T, notifier *notifier.Manager) {
 	// There is a hardcoded 5 second refresh interval in discovery.Manager, so we need to wait for that to happen at least once.
 	require.Eventually(t, func() bool {
@@ -0,0 +1,85 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ruler
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// NamespaceScopeHeader is the header carrying the name of the scope restricting the rule namespaces
+// a request can access through the ruler's configuration API. The header isn't authenticated: it must be
+// set by a trusted authenticating proxy, which strips it from the client requests.
+const NamespaceScopeHeader = "X-Mimir-Ruler-Namespace-Scope"
+
+var (
+	ErrUnknownNamespaceScope = errors.New("unknown namespace scope")
+	ErrNamespaceOutOfScope   = errors.New("namespace is out of the request's scope")
+	ErrNamespaceScopeMissing = errors.New("the tenant has namespace scopes but the request carries no " + NamespaceScopeHeader + " header")
+)
+
+// NamespaceScope is the set of namespace prefixes a request is allowed to access.
+// A nil NamespaceScope, or a NamespaceScope including the empty prefix, allows to access all namespaces.
+type NamespaceScope []string
+
+// ContainsAll returns true if all namespaces can be accessed within the scope.
+func (s NamespaceScope) ContainsAll() bool {
+	return s == nil || slices.Contains(s, "")
+}
+
+// Contains returns true if the namespace can be accessed within the scope.
+func (s NamespaceScope) Contains(namespace string) bool {
+	if s == nil {
+		return true
+	}
+	for _, prefix := range s {
+		if strings.HasPrefix(namespace, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// RequestNamespaceScope returns the namespace prefixes the request is allowed to access. The request isn't restricted
+// if the tenant has no namespace scopes. Otherwise, the request must carry the NamespaceScopeHeader: requests
+// without it are rejected, so that a misconfigured gateway can't grant access to all the tenant's namespaces.
+func (r *Ruler) RequestNamespaceScope(userID string, reqHeaders http.Header) (NamespaceScope, error) {
+	scopes := r.limits.RulerNamespaceScopes(userID)
+	if len(scopes) == 0 {
+		return nil, nil
+	}
+
+	name := reqHeaders.Get(NamespaceScopeHeader)
+	if name == "" {
+		return nil, ErrNamespaceScopeMissing
+	}
+
+	for _, s := range scopes {
+		if s.Name == name {
+			return s.NamespacePrefixes, nil
+		}
+	}
+	return nil, ErrUnknownNamespaceScope
+}
+
+// AllowNamespaceScope returns an error if the namespace can't be accessed by the request.
+func (r *Ruler) AllowNamespaceScope(userID string, reqHeaders http.Header, namespace string) error {
+	scope, err := r.RequestNamespaceScope(userID, reqHeaders)
+	if err != nil {
+		return err
+	}
+	if !scope.Contains(namespace) {
+		return ErrNamespaceOutOfScope
+	}
+	return nil
+}
+
+// filterGroupsByNamespaceScope removes the rule groups whose namespace is out of the scope.
+func filterGroupsByNamespaceScope(groups []*GroupStateDesc, scope NamespaceScope) []*GroupStateDesc {
+	if scope.ContainsAll() {
+		return groups
+	}
+	return slices.DeleteFunc(groups, func(g *GroupStateDesc) bool { return !scope.Contains(g.Group.Namespace) })
+}
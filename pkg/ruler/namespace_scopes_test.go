@@ -0,0 +1,141 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ruler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/dskit/user"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/mimir/pkg/ruler/rulespb"
+	"github.com/grafana/mimir/pkg/util/validation"
+)
+
+func Test_AllowNamespaceScope(t *testing.T) {
+	scopedLimits := validation.MockOverrides(func(_ *validation.Limits, tenantLimits map[string]*validation.Limits) {
+		tenantLimits["user1"] = validation.MockDefaultLimits()
+		tenantLimits["user1"].RulerNamespaceScopes = []*validation.RulerNamespaceScope{
+			{Name: "team-a", NamespacePrefixes: []string{"team-a/", "shared"}},
+			{Name: "team-b", NamespacePrefixes: []string{"team-b/"}},
+			{Name: "admin", NamespacePrefixes: []string{""}},
+		}
+	})
+
+	tests := map[string]struct {
+		userID      string
+		scope       string
+		namespace   string
+		limits      RulesLimits
+		expectedErr error
+	}{
+		"namespace within the scope": {
+			userID:    "user1",
+			scope:     "team-a",
+			namespace: "team-a/frontend",
+			limits:    scopedLimits,
+		},
+		"namespace within another prefix of the scope": {
+			userID:    "user1",
+			scope:     "team-a",
+			namespace: "shared-alerts",
+			limits:    scopedLimits,
+		},
+		"namespace out of the scope": {
+			userID:      "user1",
+			scope:       "team-b",
+			namespace:   "team-a/frontend",
+			limits:      scopedLimits,
+			expectedErr: ErrNamespaceOutOfScope,
+		},
+		"unknown scope": {
+			userID:      "user1",
+			scope:       "team-c",
+			namespace:   "team-a/frontend",
+			limits:      scopedLimits,
+			expectedErr: ErrUnknownNamespaceScope,
+		},
+		"scope with the empty prefix": {
+			userID:    "user1",
+			scope:     "admin",
+			namespace: "team-b/backend",
+			limits:    scopedLimits,
+		},
+		"request without scope": {
+			userID:      "user1",
+			namespace:   "team-a/frontend",
+			limits:      scopedLimits,
+			expectedErr: ErrNamespaceScopeMissing,
+		},
+		"user has no namespace scopes and request without scope": {
+			userID:    "user2",
+			namespace: "team-b/backend",
+			limits:    scopedLimits,
+		},
+		"user has no namespace scopes": {
+			userID:    "user2",
+			scope:     "team-a",
+			namespace: "team-b/backend",
+			limits:    scopedLimits,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			r := &Ruler{limits: tt.limits}
+
+			headers := http.Header{}
+			if tt.scope != "" {
+				headers.Set(NamespaceScopeHeader, tt.scope)
+			}
+			require.ErrorIs(t, r.AllowNamespaceScope(tt.userID, headers, tt.namespace), tt.expectedErr)
+		})
+	}
+}
+
+func Test_filterGroupsByNamespaceScope(t *testing.T) {
+	groups := func() []*GroupStateDesc {
+		return []*GroupStateDesc{
+			{Group: &rulespb.RuleGroupDesc{Namespace: "team-a/frontend", Name: "g1"}},
+			{Group: &rulespb.RuleGroupDesc{Namespace: "team-b/backend", Name: "g2"}},
+			{Group: &rulespb.RuleGroupDesc{Namespace: "shared", Name: "g3"}},
+		}
+	}
+
+	require.Len(t, filterGroupsByNamespaceScope(groups(), nil), 3)
+	require.Len(t, filterGroupsByNamespaceScope(groups(), NamespaceScope{""}), 3)
+
+	filtered := filterGroupsByNamespaceScope(groups(), NamespaceScope{"team-a/", "shared"})
+	require.Len(t, filtered, 2)
+	require.Equal(t, "g1", filtered[0].Group.Name)
+	require.Equal(t, "g3", filtered[1].Group.Name)
+}
+
+func TestRuler_DeleteTenantConfiguration_NamespaceScopes(t *testing.T) {
+	limits := validation.MockOverrides(func(_ *validation.Limits, tenantLimits map[string]*validation.Limits) {
+		tenantLimits["user1"] = validation.MockDefaultLimits()
+		tenantLimits["user1"].RulerNamespaceScopes = []*validation.RulerNamespaceScope{
+			{Name: "team-a", NamespacePrefixes: []string{"team-a/"}},
+		}
+	})
+	r := &Ruler{limits: limits, logger: log.NewNopLogger()}
+
+	for name, scope := range map[string]string{
+		"request with a restricted scope": "team-a",
+		"request without scope":           "",
+	} {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/ruler/delete_tenant_config", nil)
+			if scope != "" {
+				req.Header.Set(NamespaceScopeHeader, scope)
+			}
+			resp := httptest.NewRecorder()
+			r.DeleteTenantConfiguration(resp, req.WithContext(user.InjectOrgID(context.Background(), "user1")))
+			require.Equal(t, http.StatusForbidden, resp.Code)
+		})
+	}
+}
@@ -67,6 +67,10 @@ type QueryFrontendConfig struct {
 	GRPCClientConfig grpcclient.Config `yaml:"grpc_client_config" doc:"description=Configures the gRPC client used to communicate between the rulers and query-frontends."`
 
 	QueryResultResponseFormat string `yaml:"query_result_response_format"`
+
+	// LongLookbackOffloadingThreshold, if set, restricts the evaluation through the query-frontend to
+	// rule expressions reading data further back than the threshold.
+	LongLookbackOffloadingThreshold time.Duration `yaml:"long_lookback_offloading_threshold" category:"experimental"`
 }
 
 func (c *QueryFrontendConfig) RegisterFlags(f *flag.FlagSet) {
@@ -80,6 +84,14 @@ func (c *QueryFrontendConfig) RegisterFlags(f *flag.FlagSet) {
 	c.GRPCClientConfig.RegisterFlagsWithPrefix("ruler.query-frontend.grpc-client-config", f)
 
 	f.StringVar(&c.QueryResultResponseFormat, "ruler.query-frontend.query-result-response-format", formatProtobuf, fmt.Sprintf("Format to use when retrieving query results from query-frontends. Supported values: %s", strings.Join(allFormats, ", ")))
+
+	f.DurationVar(&c.LongLookbackOffloadingThreshold,
+		"ruler.query-frontend.long-lookback-offloading-threshold",
+		0,
+		"If set to a non-zero value and -ruler.query-frontend.address is configured, only rule expressions whose lookback "+
+			"(range selectors, subqueries and offsets) is greater than this threshold are evaluated through the query-frontend, "+
+			"where they can be split and cached, while all other expressions are evaluated by the ruler's embedded querier. "+
+			"0 to evaluate all rule expressions through the query-frontend.")
 }
 
 func (c *QueryFrontendConfig) Validate() error {
@@ -87,6 +99,10 @@ func (c *QueryFrontendConfig) Validate() error {
 		return fmt.Errorf("unknown query result response format '%s'. Supported values: %s", c.QueryResultResponseFormat, strings.Join(allFormats, ", "))
 	}
 
+	if c.LongLookbackOffloadingThreshold < 0 {
+		return errors.New("the long lookback offloading threshold must be greater than or equal to 0")
+	}
+
 	return nil
 }
 
@@ -233,6 +249,7 @@ func (q *RemoteQuerier) Read(ctx context.Context, query *prompb.Query, sortSerie
 func (q *RemoteQuerier) Query(ctx context.Context, qs string, t time.Time) (promql.Vector, error) {
 	logger, ctx := spanlogger.NewWithLogger(ctx, q.logger, "ruler.RemoteQuerier.Query")
 	defer logger.Span.Finish()
+	logger.Span.SetTag("query", qs)
 
 	return q.query(ctx, qs, t, logger)
 }
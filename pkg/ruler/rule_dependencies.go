@@ -0,0 +1,87 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+// Provenance-includes-location: https://github.com/prometheus/prometheus/blob/main/rules/group.go
+// Provenance-includes-license: Apache-2.0
+// Provenance-includes-copyright: The Prometheus Authors.
+
+package ruler
+
+import (
+	"slices"
+
+	"github.com/prometheus/prometheus/promql/parser"
+	promRules "github.com/prometheus/prometheus/rules"
+)
+
+const alertMetricName = "ALERTS"
+
+// ruleDependencies are the names of the rules in the same group which use the output of a rule
+// (dependents), and of the rules whose output is used by the rule (dependencies).
+type ruleDependencies struct {
+	dependents   []string
+	dependencies []string
+}
+
+// buildRuleDependencies builds the dependency graph of the rules of a group, the same way the Prometheus
+// rules manager does to find out which rules can be evaluated concurrently. A rule depends on another
+// rule if its expression selects the metric produced by the other rule.
+//
+// If the dependencies can't be inferred, because a rule has a selector without metric name or selects
+// the ALERTS or ALERTS_FOR_STATE metrics, no dependency is returned.
+func buildRuleDependencies(rules []promRules.Rule) map[promRules.Rule]ruleDependencies {
+	if len(rules) <= 1 {
+		return nil
+	}
+
+	inputs := make(map[string][]promRules.Rule, len(rules))
+	outputs := make(map[string][]promRules.Rule, len(rules))
+
+	for _, rule := range rules {
+		outputs[rule.Name()] = append(outputs[rule.Name()], rule)
+
+		indeterminate := false
+		parser.Inspect(rule.Query(), func(node parser.Node, _ []parser.Node) error {
+			n, ok := node.(*parser.VectorSelector)
+			if !ok {
+				return nil
+			}
+			if (n.Name == "" && len(n.LabelMatchers) > 0) || n.Name == alertMetricName || n.Name == alertForStateMetricName {
+				indeterminate = true
+				return nil
+			}
+			inputs[n.Name] = append(inputs[n.Name], rule)
+			return nil
+		})
+		if indeterminate {
+			return nil
+		}
+	}
+
+	graph := make(map[promRules.Rule]ruleDependencies, len(rules))
+	for output, outRules := range outputs {
+		for _, outRule := range outRules {
+			for _, inRule := range inputs[output] {
+				out := graph[outRule]
+				out.dependents = appendUnique(out.dependents, inRule.Name())
+				graph[outRule] = out
+
+				in := graph[inRule]
+				in.dependencies = appendUnique(in.dependencies, outRule.Name())
+				graph[inRule] = in
+			}
+		}
+	}
+
+	for rule, deps := range graph {
+		slices.Sort(deps.dependents)
+		slices.Sort(deps.dependencies)
+		graph[rule] = deps
+	}
+	return graph
+}
+
+func appendUnique(names []string, name string) []string {
+	if slices.Contains(names, name) {
+		return names
+	}
+	return append(names, name)
+}
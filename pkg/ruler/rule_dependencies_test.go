@@ -0,0 +1,57 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ruler
+
+import (
+	"testing"
+
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql/parser"
+	promRules "github.com/prometheus/prometheus/rules"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildRuleDependencies(t *testing.T) {
+	newRecordingRule := func(name, expr string) promRules.Rule {
+		e, err := parser.ParseExpr(expr)
+		require.NoError(t, err)
+		return promRules.NewRecordingRule(name, e, labels.EmptyLabels())
+	}
+	newAlertingRule := func(name, expr string) promRules.Rule {
+		e, err := parser.ParseExpr(expr)
+		require.NoError(t, err)
+		return promRules.NewAlertingRule(name, e, 0, 0, labels.EmptyLabels(), labels.EmptyLabels(), labels.EmptyLabels(), "", true, nil)
+	}
+
+	t.Run("should return the dependents and the dependencies of each rule", func(t *testing.T) {
+		source := newRecordingRule("job:http_requests:rate1m", `sum by (job) (rate(http_requests_total[1m]))`)
+		sum := newRecordingRule("http_requests:rate1m", `sum(job:http_requests:rate1m)`)
+		ratio := newRecordingRule("job:http_requests:ratio", `job:http_requests:rate1m / on() group_left http_requests:rate1m`)
+		alert := newAlertingRule("HighRequestRate", `job:http_requests:ratio > 0.5`)
+		independent := newRecordingRule("job:up:sum", `sum by (job) (up)`)
+
+		graph := buildRuleDependencies([]promRules.Rule{source, sum, ratio, alert, independent})
+
+		assert.Equal(t, ruleDependencies{dependents: []string{"http_requests:rate1m", "job:http_requests:ratio"}}, graph[source])
+		assert.Equal(t, ruleDependencies{dependents: []string{"job:http_requests:ratio"}, dependencies: []string{"job:http_requests:rate1m"}}, graph[sum])
+		assert.Equal(t, ruleDependencies{dependents: []string{"HighRequestRate"}, dependencies: []string{"http_requests:rate1m", "job:http_requests:rate1m"}}, graph[ratio])
+		assert.Equal(t, ruleDependencies{dependencies: []string{"job:http_requests:ratio"}}, graph[alert])
+		assert.Equal(t, ruleDependencies{}, graph[independent])
+	})
+
+	t.Run("should return no dependencies if they can't be inferred", func(t *testing.T) {
+		for _, expr := range []string{`{job="test"}`, `ALERTS`, `ALERTS_FOR_STATE`} {
+			rules := []promRules.Rule{
+				newRecordingRule("job:up:sum", `sum by (job) (up)`),
+				newRecordingRule("up:sum", `sum(job:up:sum)`),
+				newAlertingRule("Indeterminate", expr),
+			}
+			assert.Empty(t, buildRuleDependencies(rules), expr)
+		}
+	})
+
+	t.Run("should return no dependencies for a single rule", func(t *testing.T) {
+		assert.Empty(t, buildRuleDependencies([]promRules.Rule{newRecordingRule("up:sum", `sum(up:sum)`)}))
+	})
+}
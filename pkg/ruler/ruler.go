@@ -124,6 +124,8 @@ type Config struct {
 
 	EnableAPI bool `yaml:"enable_api"`
 
+	MaxNamespaceVersions int `yaml:"max_namespace_versions" category:"experimental"`
+
 	EnabledTenants  flagext.StringSliceCSV `yaml:"enabled_tenants" category:"advanced"`
 	DisabledTenants flagext.StringSliceCSV `yaml:"disabled_tenants" category:"advanced"`
 
@@ -187,6 +189,7 @@ func (cfg *Config) RegisterFlags(f *flag.FlagSet, logger log.Logger) {
 
 	f.StringVar(&cfg.RulePath, "ruler.rule-path", "./data-ruler/", "Directory to store temporary rule files loaded by the Prometheus rule managers. This directory is not required to be persisted between restarts.")
 	f.BoolVar(&cfg.EnableAPI, "ruler.enable-api", true, "Enable the ruler config API.")
+	f.IntVar(&cfg.MaxNamespaceVersions, "ruler.max-namespace-versions", 0, "Maximum number of versions of each tenant's rule namespace to keep in the storage. A new version is stored every time the rule groups of a namespace are changed through the ruler config API, and tenants can roll back a namespace to any of its stored versions. 0 to disable rule namespace versioning.")
 	f.DurationVar(&cfg.OutageTolerance, "ruler.for-outage-tolerance", time.Hour, `Max time to tolerate outage for restoring "for" state of alert.`)
 	f.DurationVar(&cfg.ForGracePeriod, "ruler.for-grace-period", 2*time.Minute, `This grace period controls which alerts the ruler restores after a restart. `+
 		`Alerts with "for" duration lower than this grace period are not restored after a ruler restart. `+
@@ -1065,6 +1068,7 @@ func (r *Ruler) getLocalRules(ctx context.Context, userID string, req RulesReque
 	fileSet := makeStringFilterSet(req.File)
 	groupSet := makeStringFilterSet(req.RuleGroup)
 	ruleSet := makeStringFilterSet(req.RuleName)
+	healthSet := makeStringFilterSet(req.Health)
 
 	for _, group := range groups {
 		if groupSet.IsFiltered(group.Name()) {
@@ -1089,13 +1093,15 @@ func (r *Ruler) getLocalRules(ctx context.Context, userID string, req RulesReque
 				Interval:      interval,
 				User:          userID,
 				SourceTenants: group.SourceTenants(),
+				QueryOffset:   group.QueryOffset(),
 			},
 
 			EvaluationTimestamp: group.GetLastEvaluation(),
 			EvaluationDuration:  group.GetEvaluationTime(),
 		}
+		dependencies := buildRuleDependencies(group.Rules())
 		for _, r := range group.Rules() {
-			if ruleSet.IsFiltered(r.Name()) {
+			if ruleSet.IsFiltered(r.Name()) || healthSet.IsFiltered(string(r.Health())) {
 				continue
 			}
 
@@ -1145,6 +1151,10 @@ func (r *Ruler) getLocalRules(ctx context.Context, userID string, req RulesReque
 					Alerts:              alerts,
 					EvaluationTimestamp: rule.GetEvaluationTimestamp(),
 					EvaluationDuration:  rule.GetEvaluationDuration(),
+					NoDependentRules:    rule.NoDependentRules(),
+					NoDependencyRules:   rule.NoDependencyRules(),
+					DependentRules:      dependencies[rule].dependents,
+					DependencyRules:     dependencies[rule].dependencies,
 				}
 			case *promRules.RecordingRule:
 				if !getRecordingRules {
@@ -1160,6 +1170,10 @@ func (r *Ruler) getLocalRules(ctx context.Context, userID string, req RulesReque
 					LastError:           lastError,
 					EvaluationTimestamp: rule.GetEvaluationTimestamp(),
 					EvaluationDuration:  rule.GetEvaluationDuration(),
+					NoDependentRules:    rule.NoDependentRules(),
+					NoDependencyRules:   rule.NoDependencyRules(),
+					DependentRules:      dependencies[rule].dependents,
+					DependencyRules:     dependencies[rule].dependencies,
 				}
 			default:
 				return nil, errors.Errorf("failed to assert type of rule '%v'", rule.Name())
@@ -1224,6 +1238,17 @@ func (r *Ruler) DeleteTenantConfiguration(w http.ResponseWriter, req *http.Reque
 		return
 	}
 
+	// Deleting the whole configuration would also delete the namespaces out of the request's scope.
+	scope, err := r.RequestNamespaceScope(userID, req.Header)
+	if err == nil && !scope.ContainsAll() {
+		err = ErrNamespaceOutOfScope
+	}
+	if err != nil {
+		level.Warn(logger).Log("msg", "not allowed to delete the tenant configuration", "user", userID, "err", err.Error())
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
 	err = r.directStore.DeleteNamespace(req.Context(), userID, "") // Empty namespace = delete all rule groups.
 	if err != nil && !errors.Is(err, rulestore.ErrGroupNamespaceNotFound) {
 		respondServerError(logger, w, err.Error())
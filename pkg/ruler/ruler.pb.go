@@ -69,6 +69,8 @@ type RulesRequest struct {
 	RuleGroup     []string              `protobuf:"bytes,3,rep,name=rule_group,json=ruleGroup,proto3" json:"rule_group,omitempty"`
 	File          []string              `protobuf:"bytes,4,rep,name=file,proto3" json:"file,omitempty"`
 	ExcludeAlerts bool                  `protobuf:"varint,5,opt,name=exclude_alerts,json=excludeAlerts,proto3" json:"exclude_alerts,omitempty"`
+	// Only rules whose health is one of the given values (ok, err or unknown) are returned, if set.
+	Health []string `protobuf:"bytes,6,rep,name=health,proto3" json:"health,omitempty"`
 }
 
 func (m *RulesRequest) Reset()      { *m = RulesRequest{} }
@@ -138,6 +140,13 @@ func (m *RulesRequest) GetExcludeAlerts() bool {
 	return false
 }
 
+func (m *RulesRequest) GetHealth() []string {
+	if m != nil {
+		return m.Health
+	}
+	return nil
+}
+
 type RulesResponse struct {
 	Groups []*GroupStateDesc `protobuf:"bytes,1,rep,name=groups,proto3" json:"groups,omitempty"`
 }
@@ -339,6 +348,14 @@ type RuleStateDesc struct {
 	Alerts              []*AlertStateDesc `protobuf:"bytes,5,rep,name=alerts,proto3" json:"alerts,omitempty"`
 	EvaluationTimestamp time.Time         `protobuf:"bytes,6,opt,name=evaluationTimestamp,proto3,stdtime" json:"evaluationTimestamp"`
 	EvaluationDuration  time.Duration     `protobuf:"bytes,7,opt,name=evaluationDuration,proto3,stdduration" json:"evaluationDuration"`
+	// Whether it's guaranteed that no other rule in the group depends on this rule's output.
+	NoDependentRules bool `protobuf:"varint,8,opt,name=noDependentRules,proto3" json:"noDependentRules,omitempty"`
+	// Whether it's guaranteed that this rule doesn't depend on the output of any other rule in the group.
+	NoDependencyRules bool `protobuf:"varint,9,opt,name=noDependencyRules,proto3" json:"noDependencyRules,omitempty"`
+	// Names of the rules in the group which depend on this rule's output.
+	DependentRules []string `protobuf:"bytes,10,rep,name=dependentRules,proto3" json:"dependentRules,omitempty"`
+	// Names of the rules in the group whose output this rule depends on.
+	DependencyRules []string `protobuf:"bytes,11,rep,name=dependencyRules,proto3" json:"dependencyRules,omitempty"`
 }
 
 func (m *RuleStateDesc) Reset()      { *m = RuleStateDesc{} }
@@ -422,6 +439,34 @@ func (m *RuleStateDesc) GetEvaluationDuration() time.Duration {
 	return 0
 }
 
+func (m *RuleStateDesc) GetNoDependentRules() bool {
+	if m != nil {
+		return m.NoDependentRules
+	}
+	return false
+}
+
+func (m *RuleStateDesc) GetNoDependencyRules() bool {
+	if m != nil {
+		return m.NoDependencyRules
+	}
+	return false
+}
+
+func (m *RuleStateDesc) GetDependentRules() []string {
+	if m != nil {
+		return m.DependentRules
+	}
+	return nil
+}
+
+func (m *RuleStateDesc) GetDependencyRules() []string {
+	if m != nil {
+		return m.DependencyRules
+	}
+	return nil
+}
+
 type AlertStateDesc struct {
 	State           string                                              `protobuf:"bytes,1,opt,name=state,proto3" json:"state,omitempty"`
 	Labels          []github_com_grafana_mimir_pkg_mimirpb.LabelAdapter `protobuf:"bytes,2,rep,name=labels,proto3,customtype=github.com/grafana/mimir/pkg/mimirpb.LabelAdapter" json:"labels"`
@@ -537,63 +582,67 @@ func init() {
 func init() { proto.RegisterFile("ruler.proto", fileDescriptor_9ecbec0a4cfddea6) }
 
 var fileDescriptor_9ecbec0a4cfddea6 = []byte{
-	// 891 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xb4, 0x55, 0xcf, 0x6f, 0x1b, 0x45,
-	0x14, 0xde, 0x71, 0xe2, 0x1f, 0xfb, 0x9c, 0xa4, 0xc9, 0x24, 0xc0, 0xd6, 0x94, 0x8d, 0x65, 0x84,
-	0x64, 0x21, 0x65, 0x03, 0x21, 0x02, 0x21, 0x21, 0xc0, 0x51, 0x5b, 0x84, 0x84, 0x50, 0xb5, 0x2e,
-	0x5c, 0xad, 0xb1, 0x3d, 0xde, 0x8c, 0xba, 0xde, 0x5d, 0x66, 0x66, 0xa3, 0xe6, 0x04, 0x7f, 0x42,
-	0x8f, 0x9c, 0x39, 0xf1, 0x77, 0x70, 0xea, 0x31, 0xc7, 0x0a, 0xa1, 0x42, 0x9c, 0x0b, 0xc7, 0x5e,
-	0xb9, 0xa1, 0x79, 0xb3, 0x1b, 0x3b, 0x6d, 0x40, 0xb5, 0xa0, 0x97, 0x78, 0xde, 0x7b, 0xdf, 0xf7,
-	0xcd, 0xfb, 0xb5, 0x13, 0x68, 0xca, 0x3c, 0xe6, 0x32, 0xc8, 0x64, 0xaa, 0x53, 0x5a, 0x45, 0xa3,
-	0xb5, 0x17, 0x09, 0x7d, 0x9c, 0x0f, 0x83, 0x51, 0x3a, 0xdd, 0x8f, 0xd2, 0x28, 0xdd, 0xc7, 0xe8,
-	0x30, 0x9f, 0xa0, 0x85, 0x06, 0x9e, 0x2c, 0xab, 0xe5, 0x47, 0x69, 0x1a, 0xc5, 0x7c, 0x8e, 0x1a,
-	0xe7, 0x92, 0x69, 0x91, 0x26, 0x45, 0x7c, 0xf7, 0xf9, 0xb8, 0x16, 0x53, 0xae, 0x34, 0x9b, 0x66,
-	0x05, 0xe0, 0xbd, 0xc5, 0xfb, 0x24, 0x9b, 0xb0, 0x84, 0xed, 0x4f, 0xc5, 0x54, 0xc8, 0xfd, 0xec,
-	0x41, 0x64, 0x4f, 0xd9, 0xd0, 0xfe, 0x16, 0x8c, 0x0f, 0xff, 0x95, 0x81, 0x55, 0xe0, 0x5f, 0x95,
-	0x0d, 0xed, 0xaf, 0xe5, 0x75, 0xfe, 0x22, 0xb0, 0x16, 0x1a, 0x3b, 0xe4, 0xdf, 0xe5, 0x5c, 0x69,
-	0x7a, 0x08, 0xb5, 0x89, 0x88, 0x35, 0x97, 0x1e, 0x69, 0x93, 0xee, 0xc6, 0xc1, 0xad, 0xc0, 0xf6,
-	0x63, 0x11, 0x84, 0xc6, 0xfd, 0xd3, 0x8c, 0x87, 0x05, 0x96, 0xbe, 0x09, 0xae, 0x81, 0x0d, 0x12,
-	0x36, 0xe5, 0x5e, 0xa5, 0xbd, 0xd2, 0x75, 0xc3, 0x86, 0x71, 0x7c, 0xcd, 0xa6, 0x9c, 0xbe, 0x05,
-	0x80, 0xc1, 0x48, 0xa6, 0x79, 0xe6, 0xad, 0x60, 0x14, 0xe1, 0x5f, 0x18, 0x07, 0xa5, 0xb0, 0x3a,
-	0x11, 0x31, 0xf7, 0x56, 0x31, 0x80, 0x67, 0xfa, 0x0e, 0x6c, 0xf0, 0x87, 0xa3, 0x38, 0x1f, 0xf3,
-	0x01, 0x8b, 0xb9, 0xd4, 0xca, 0xab, 0xb6, 0x49, 0xb7, 0x11, 0xae, 0x17, 0xde, 0x1e, 0x3a, 0x3b,
-	0x9f, 0x40, 0xa3, 0x4c, 0x85, 0x36, 0xa1, 0xde, 0x4b, 0x4e, 0x8d, 0xb9, 0xe9, 0xd0, 0x4d, 0x58,
-	0x43, 0x88, 0x48, 0x22, 0xf4, 0x10, 0xba, 0x05, 0xeb, 0x21, 0x1f, 0xa5, 0x72, 0x5c, 0xba, 0x2a,
-	0x9d, 0x4f, 0x61, 0xbd, 0xa8, 0x4a, 0x65, 0x69, 0xa2, 0x38, 0xdd, 0x83, 0x1a, 0xe6, 0xa8, 0x3c,
-	0xd2, 0x5e, 0xe9, 0x36, 0x0f, 0x5e, 0x2b, 0x6a, 0xc7, 0x3c, 0xfb, 0x9a, 0x69, 0x7e, 0x9b, 0xab,
-	0x51, 0x58, 0x80, 0x3a, 0x7b, 0xb0, 0xd9, 0x3f, 0x4d, 0x46, 0x57, 0xda, 0x77, 0x13, 0x1a, 0xb9,
-	0xe2, 0x72, 0x20, 0xc6, 0x56, 0xc4, 0x0d, 0xeb, 0xc6, 0xfe, 0x72, 0xac, 0x3a, 0xdb, 0xb0, 0xb5,
-	0x00, 0xb7, 0x57, 0x76, 0x7e, 0xaa, 0xc0, 0xc6, 0x55, 0x79, 0xfa, 0x2e, 0x54, 0x6d, 0xa7, 0xcc,
-	0x00, 0x9a, 0x07, 0x3b, 0x81, 0x9d, 0x57, 0x58, 0x36, 0x0c, 0x73, 0xb0, 0x10, 0xfa, 0x11, 0xac,
-	0xb1, 0x91, 0x16, 0x27, 0x7c, 0x80, 0x20, 0x6c, 0x7d, 0x49, 0xb1, 0x33, 0x9b, 0xa7, 0xdd, 0xb4,
-	0x48, 0xbc, 0x9f, 0x7e, 0x0b, 0xdb, 0xfc, 0x84, 0xc5, 0x39, 0xae, 0xe5, 0xfd, 0x72, 0xfd, 0xbc,
-	0x15, 0xbc, 0xb2, 0x15, 0xd8, 0x05, 0x0d, 0xca, 0x05, 0x0d, 0x2e, 0x11, 0x47, 0x8d, 0xc7, 0x4f,
-	0x77, 0x9d, 0x47, 0xbf, 0xef, 0x92, 0xf0, 0x3a, 0x01, 0xda, 0x07, 0x3a, 0x77, 0xdf, 0x2e, 0xd6,
-	0xde, 0x5b, 0x45, 0xd9, 0x9b, 0x2f, 0xc8, 0x96, 0x00, 0xab, 0xfa, 0xa3, 0x51, 0xbd, 0x86, 0xde,
-	0xf9, 0xad, 0x62, 0x27, 0x35, 0xef, 0xd1, 0xdb, 0xb0, 0x6a, 0x4a, 0x2c, 0x5a, 0x74, 0x63, 0xa1,
-	0x45, 0x58, 0x2a, 0x06, 0xe9, 0x0e, 0x54, 0x95, 0x61, 0x78, 0x95, 0x36, 0xe9, 0xba, 0xa1, 0x35,
-	0xe8, 0xeb, 0x50, 0x3b, 0xe6, 0x2c, 0xd6, 0xc7, 0x58, 0xac, 0x1b, 0x16, 0x16, 0xbd, 0x05, 0x6e,
-	0xcc, 0x94, 0xbe, 0x23, 0x65, 0x2a, 0x31, 0x61, 0x37, 0x9c, 0x3b, 0xcc, 0x6a, 0x5c, 0x2e, 0xe2,
-	0xe2, 0x6a, 0xe0, 0x96, 0x2d, 0xac, 0x86, 0x05, 0xfd, 0x53, 0x7b, 0x6b, 0xaf, 0xa6, 0xbd, 0xf5,
-	0xff, 0xd6, 0xde, 0x5f, 0xaa, 0xb0, 0x71, 0xb5, 0x8e, 0x79, 0xeb, 0xc8, 0x62, 0xeb, 0x26, 0x50,
-	0x8b, 0xd9, 0x90, 0xc7, 0xe5, 0x9e, 0x6d, 0x07, 0xa3, 0x54, 0x6a, 0xfe, 0x30, 0x1b, 0x06, 0x5f,
-	0x19, 0xff, 0x3d, 0x26, 0xe4, 0xd1, 0xc7, 0xe6, 0xae, 0x5f, 0x9f, 0xee, 0xbe, 0xff, 0x32, 0x6f,
-	0x98, 0xe5, 0xf5, 0xc6, 0x2c, 0xd3, 0x5c, 0x86, 0x85, 0x3a, 0xcd, 0xa0, 0xc9, 0x92, 0x24, 0xd5,
-	0x98, 0x9e, 0xc2, 0x17, 0xe3, 0xff, 0xbf, 0x6c, 0xf1, 0x0a, 0x53, 0xaf, 0xe9, 0x0b, 0xc7, 0xc1,
-	0x93, 0xd0, 0x1a, 0xb4, 0x07, 0x6e, 0xf1, 0x75, 0x31, 0x8d, 0x0f, 0xd0, 0xcb, 0xce, 0xae, 0x61,
-	0x69, 0x3d, 0x4d, 0x3f, 0x83, 0xc6, 0x44, 0x48, 0x3e, 0x36, 0x0a, 0xcb, 0x4c, 0xbf, 0x8e, 0xac,
-	0x9e, 0xa6, 0x77, 0xa0, 0x29, 0xb9, 0x4a, 0xe3, 0x13, 0xab, 0x51, 0x5f, 0x42, 0x03, 0x4a, 0x62,
-	0x4f, 0xd3, 0xbb, 0xb0, 0x66, 0x96, 0x79, 0xa0, 0x78, 0xa2, 0x8d, 0x4e, 0x63, 0x19, 0x1d, 0xc3,
-	0xec, 0xf3, 0x44, 0xdb, 0x74, 0x4e, 0x58, 0x2c, 0xc6, 0x83, 0x3c, 0xd1, 0x22, 0xf6, 0xdc, 0x65,
-	0x64, 0x90, 0xf8, 0x8d, 0xe1, 0xd1, 0x7b, 0xb0, 0xf5, 0x80, 0xf3, 0x6c, 0x30, 0x11, 0x52, 0x24,
-	0xd1, 0x40, 0x89, 0x64, 0xc4, 0x3d, 0x58, 0x42, 0xec, 0x86, 0xa1, 0xdf, 0x45, 0x76, 0xdf, 0x90,
-	0x0f, 0xbe, 0x87, 0xaa, 0xf9, 0xfc, 0x25, 0x3d, 0xb4, 0x07, 0x45, 0xb7, 0xaf, 0xf9, 0xcf, 0xd5,
-	0xda, 0xb9, 0xea, 0x2c, 0x5e, 0x61, 0x87, 0x7e, 0x0e, 0xee, 0xe5, 0xe3, 0x4c, 0xdf, 0x28, 0x40,
-	0xcf, 0xbf, 0xee, 0x2d, 0xef, 0xc5, 0x40, 0xa9, 0x70, 0x74, 0x78, 0x76, 0xee, 0x3b, 0x4f, 0xce,
-	0x7d, 0xe7, 0xd9, 0xb9, 0x4f, 0x7e, 0x98, 0xf9, 0xe4, 0xe7, 0x99, 0x4f, 0x1e, 0xcf, 0x7c, 0x72,
-	0x36, 0xf3, 0xc9, 0x1f, 0x33, 0x9f, 0xfc, 0x39, 0xf3, 0x9d, 0x67, 0x33, 0x9f, 0x3c, 0xba, 0xf0,
-	0x9d, 0xb3, 0x0b, 0xdf, 0x79, 0x72, 0xe1, 0x3b, 0xc3, 0x1a, 0x56, 0xf9, 0xc1, 0xdf, 0x01, 0x00,
-	0x00, 0xff, 0xff, 0x1e, 0x1c, 0xe1, 0x9c, 0x76, 0x08, 0x00, 0x00,
+	// 947 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xb4, 0x55, 0xcf, 0x6f, 0xe3, 0x44,
+	0x14, 0x8e, 0xf3, 0xdb, 0x2f, 0x6d, 0xda, 0x4e, 0x0b, 0x78, 0xc3, 0xe2, 0x46, 0x41, 0xa0, 0x68,
+	0x45, 0x5d, 0x28, 0x15, 0x08, 0x09, 0x01, 0xa9, 0xba, 0x8b, 0x90, 0x10, 0x5a, 0x39, 0x0b, 0xd7,
+	0x68, 0x62, 0x4f, 0x5c, 0x6b, 0x1d, 0xdb, 0x8c, 0xc7, 0xd5, 0xf6, 0x04, 0x47, 0x8e, 0xcb, 0x8d,
+	0x33, 0x27, 0xfe, 0x0e, 0x4e, 0x7b, 0xec, 0x71, 0xc5, 0x61, 0xa1, 0xe9, 0x85, 0xe3, 0xfe, 0x09,
+	0x68, 0xde, 0xd8, 0x8d, 0x93, 0x16, 0x44, 0xb4, 0xea, 0x25, 0x99, 0xf7, 0xde, 0xf7, 0x7d, 0x33,
+	0xf3, 0xe6, 0x1b, 0x0f, 0xb4, 0x78, 0x1a, 0x30, 0x6e, 0xc5, 0x3c, 0x12, 0x11, 0xa9, 0x61, 0xd0,
+	0xd9, 0xf3, 0x7c, 0x71, 0x92, 0x8e, 0x2d, 0x27, 0x9a, 0xee, 0x7b, 0x91, 0x17, 0xed, 0x63, 0x75,
+	0x9c, 0x4e, 0x30, 0xc2, 0x00, 0x47, 0x8a, 0xd5, 0x31, 0xbd, 0x28, 0xf2, 0x02, 0x36, 0x47, 0xb9,
+	0x29, 0xa7, 0xc2, 0x8f, 0xc2, 0xac, 0xbe, 0xbb, 0x5c, 0x17, 0xfe, 0x94, 0x25, 0x82, 0x4e, 0xe3,
+	0x0c, 0xf0, 0x7e, 0x71, 0x3e, 0x4e, 0x27, 0x34, 0xa4, 0xfb, 0x53, 0x7f, 0xea, 0xf3, 0xfd, 0xf8,
+	0xb1, 0xa7, 0x46, 0xf1, 0x58, 0xfd, 0x67, 0x8c, 0x8f, 0xfe, 0x93, 0x81, 0xbb, 0xc0, 0xdf, 0x24,
+	0x1e, 0xab, 0x7f, 0xc5, 0xeb, 0xfd, 0x5c, 0x86, 0x35, 0x5b, 0xc6, 0x36, 0xfb, 0x3e, 0x65, 0x89,
+	0x20, 0x87, 0x50, 0x9f, 0xf8, 0x81, 0x60, 0xdc, 0xd0, 0xba, 0x5a, 0xbf, 0x7d, 0x70, 0xd7, 0x52,
+	0xfd, 0x28, 0x82, 0x30, 0x78, 0x74, 0x16, 0x33, 0x3b, 0xc3, 0x92, 0x37, 0x41, 0x97, 0xb0, 0x51,
+	0x48, 0xa7, 0xcc, 0x28, 0x77, 0x2b, 0x7d, 0xdd, 0x6e, 0xca, 0xc4, 0x37, 0x74, 0xca, 0xc8, 0x5b,
+	0x00, 0x58, 0xf4, 0x78, 0x94, 0xc6, 0x46, 0x05, 0xab, 0x08, 0xff, 0x52, 0x26, 0x08, 0x81, 0xea,
+	0xc4, 0x0f, 0x98, 0x51, 0xc5, 0x02, 0x8e, 0xc9, 0x3b, 0xd0, 0x66, 0x4f, 0x9c, 0x20, 0x75, 0xd9,
+	0x88, 0x06, 0x8c, 0x8b, 0xc4, 0xa8, 0x75, 0xb5, 0x7e, 0xd3, 0x5e, 0xcf, 0xb2, 0x03, 0x4c, 0x92,
+	0xd7, 0xa1, 0x7e, 0xc2, 0x68, 0x20, 0x4e, 0x8c, 0x3a, 0x92, 0xb3, 0xa8, 0xf7, 0x29, 0x34, 0xf3,
+	0x25, 0x92, 0x16, 0x34, 0x06, 0xe1, 0x99, 0x0c, 0x37, 0x4b, 0x64, 0x13, 0xd6, 0x90, 0xea, 0x87,
+	0x1e, 0x66, 0x34, 0xb2, 0x05, 0xeb, 0x36, 0x73, 0x22, 0xee, 0xe6, 0xa9, 0x72, 0xef, 0x33, 0x58,
+	0xcf, 0x76, 0x9b, 0xc4, 0x51, 0x98, 0x30, 0xb2, 0x07, 0x75, 0x5c, 0x7b, 0x62, 0x68, 0xdd, 0x4a,
+	0xbf, 0x75, 0xf0, 0x5a, 0xd6, 0x13, 0x5c, 0xff, 0x50, 0x50, 0xc1, 0x8e, 0x59, 0xe2, 0xd8, 0x19,
+	0xa8, 0xb7, 0x07, 0x9b, 0xc3, 0xb3, 0xd0, 0x59, 0x68, 0xeb, 0x1d, 0x68, 0xa6, 0x09, 0xe3, 0x23,
+	0xdf, 0x55, 0x22, 0xba, 0xdd, 0x90, 0xf1, 0x57, 0x6e, 0xd2, 0xdb, 0x86, 0xad, 0x02, 0x5c, 0x4d,
+	0xd9, 0xfb, 0xb5, 0x0c, 0xed, 0x45, 0x79, 0x72, 0x0f, 0x6a, 0xaa, 0x83, 0xf2, 0x60, 0x5a, 0x07,
+	0x3b, 0x96, 0x3a, 0x47, 0x3b, 0x6f, 0x24, 0xae, 0x41, 0x41, 0xc8, 0xc7, 0xb0, 0x46, 0x1d, 0xe1,
+	0x9f, 0xb2, 0x11, 0x82, 0xf0, 0x48, 0x72, 0x8a, 0x3a, 0xcb, 0xf9, 0xb2, 0x5b, 0x0a, 0x89, 0xf3,
+	0x93, 0xef, 0x60, 0x9b, 0x9d, 0xd2, 0x20, 0x45, 0xbb, 0x3e, 0xca, 0x6d, 0x69, 0x54, 0x70, 0xca,
+	0x8e, 0xa5, 0x8c, 0x6b, 0xe5, 0xc6, 0xb5, 0xae, 0x10, 0x47, 0xcd, 0x67, 0x2f, 0x76, 0x4b, 0x4f,
+	0xff, 0xdc, 0xd5, 0xec, 0x9b, 0x04, 0xc8, 0x10, 0xc8, 0x3c, 0x7d, 0x9c, 0x5d, 0x07, 0xa3, 0x8a,
+	0xb2, 0x77, 0xae, 0xc9, 0xe6, 0x00, 0xa5, 0xfa, 0x8b, 0x54, 0xbd, 0x81, 0xde, 0xfb, 0xa9, 0x0a,
+	0xeb, 0x0b, 0x7b, 0x21, 0x6f, 0x43, 0x55, 0x6e, 0x31, 0x6b, 0xd1, 0x46, 0xa1, 0x45, 0xb8, 0x55,
+	0x2c, 0x92, 0x1d, 0xa8, 0x25, 0x92, 0x61, 0x94, 0xbb, 0x5a, 0x5f, 0xb7, 0x55, 0x50, 0xf0, 0x52,
+	0x05, 0xd3, 0x59, 0x44, 0xee, 0x82, 0x1e, 0xd0, 0x44, 0xdc, 0xe7, 0x3c, 0xe2, 0xb8, 0x60, 0xdd,
+	0x9e, 0x27, 0xa4, 0x35, 0xae, 0x0c, 0x5a, 0xb4, 0x06, 0xba, 0xac, 0x60, 0x0d, 0x05, 0xfa, 0xb7,
+	0xf6, 0xd6, 0x6f, 0xa7, 0xbd, 0x8d, 0x57, 0x6a, 0x2f, 0xb9, 0x07, 0x9b, 0x61, 0x74, 0xcc, 0x62,
+	0x16, 0xba, 0x2c, 0x14, 0xe8, 0x0f, 0xa3, 0x89, 0xd7, 0xf0, 0x5a, 0x9e, 0xbc, 0x07, 0x5b, 0xf3,
+	0x9c, 0x73, 0xa6, 0xc0, 0x3a, 0x82, 0xaf, 0x17, 0xc8, 0xbb, 0xd0, 0x76, 0x17, 0x75, 0x01, 0xef,
+	0xc4, 0x52, 0x96, 0xf4, 0x61, 0xc3, 0x5d, 0xd2, 0x6c, 0x21, 0x70, 0x39, 0xdd, 0xfb, 0xbd, 0x06,
+	0xed, 0xc5, 0x9e, 0xcf, 0x8f, 0x59, 0x2b, 0x1e, 0xf3, 0x04, 0xea, 0x01, 0x1d, 0xb3, 0x20, 0xbf,
+	0x13, 0xdb, 0x96, 0x13, 0x71, 0xc1, 0x9e, 0xc4, 0x63, 0xeb, 0x6b, 0x99, 0x7f, 0x48, 0x7d, 0x7e,
+	0xf4, 0x89, 0xec, 0xcb, 0x1f, 0x2f, 0x76, 0x3f, 0xf8, 0x3f, 0xdf, 0x61, 0xc5, 0x1b, 0xb8, 0x34,
+	0x16, 0x8c, 0xdb, 0x99, 0x3a, 0x89, 0xa1, 0x45, 0xc3, 0x30, 0x12, 0xd8, 0xca, 0xc4, 0xa8, 0xdc,
+	0xca, 0x64, 0xc5, 0x29, 0xe4, 0x7e, 0xe5, 0x19, 0x32, 0x34, 0xa9, 0x66, 0xab, 0x80, 0x0c, 0x40,
+	0xcf, 0xbe, 0x04, 0x54, 0x18, 0xb5, 0x15, 0x7c, 0xd6, 0x54, 0xb4, 0x81, 0x20, 0x9f, 0x43, 0x73,
+	0xe2, 0x73, 0xe6, 0x4a, 0x85, 0x55, 0x9c, 0xda, 0x40, 0xd6, 0x40, 0x90, 0xfb, 0xd0, 0xe2, 0x2c,
+	0x89, 0x82, 0x53, 0xa5, 0xd1, 0x58, 0x41, 0x03, 0x72, 0xe2, 0x40, 0x90, 0x07, 0xb0, 0x26, 0x2f,
+	0xde, 0x28, 0x61, 0xa1, 0x90, 0x3a, 0xcd, 0x55, 0x74, 0x24, 0x73, 0xc8, 0x42, 0xa1, 0x96, 0x73,
+	0x4a, 0x03, 0xdf, 0x1d, 0xa5, 0xa1, 0xf0, 0x03, 0x43, 0x5f, 0x45, 0x06, 0x89, 0xdf, 0x4a, 0x1e,
+	0x79, 0x08, 0x5b, 0x8f, 0x19, 0x8b, 0x47, 0x13, 0x9f, 0xfb, 0xa1, 0x37, 0x4a, 0xfc, 0xd0, 0x61,
+	0x06, 0xac, 0x20, 0xb6, 0x21, 0xe9, 0x0f, 0x90, 0x3d, 0x94, 0xe4, 0x83, 0x1f, 0xa0, 0x26, 0xdd,
+	0xcc, 0xc9, 0xa1, 0x1a, 0x24, 0x64, 0xfb, 0x86, 0xd7, 0xb7, 0xb3, 0xb3, 0x98, 0xcc, 0x5e, 0x8c,
+	0x12, 0xf9, 0x02, 0xf4, 0xab, 0x87, 0x84, 0xbc, 0x91, 0x81, 0x96, 0x5f, 0xa2, 0x8e, 0x71, 0xbd,
+	0x90, 0x2b, 0x1c, 0x1d, 0x9e, 0x5f, 0x98, 0xa5, 0xe7, 0x17, 0x66, 0xe9, 0xe5, 0x85, 0xa9, 0xfd,
+	0x38, 0x33, 0xb5, 0xdf, 0x66, 0xa6, 0xf6, 0x6c, 0x66, 0x6a, 0xe7, 0x33, 0x53, 0xfb, 0x6b, 0x66,
+	0x6a, 0x7f, 0xcf, 0xcc, 0xd2, 0xcb, 0x99, 0xa9, 0x3d, 0xbd, 0x34, 0x4b, 0xe7, 0x97, 0x66, 0xe9,
+	0xf9, 0xa5, 0x59, 0x1a, 0xd7, 0x71, 0x97, 0x1f, 0xfe, 0x33, 0x00, 0xc5, 0x05, 0x63, 0xbb, 0x3a,
+	0x09, 0x00, 0x00,
 }
 
 func (x RulesRequest_RuleType) String() string {
@@ -652,6 +701,14 @@ func (this *RulesRequest) Equal(that interface{}) bool {
 	if this.ExcludeAlerts != that1.ExcludeAlerts {
 		return false
 	}
+	if len(this.Health) != len(that1.Health) {
+		return false
+	}
+	for i := range this.Health {
+		if this.Health[i] != that1.Health[i] {
+			return false
+		}
+	}
 	return true
 }
 func (this *RulesResponse) Equal(that interface{}) bool {
@@ -816,6 +873,28 @@ func (this *RuleStateDesc) Equal(that interface{}) bool {
 	if this.EvaluationDuration != that1.EvaluationDuration {
 		return false
 	}
+	if this.NoDependentRules != that1.NoDependentRules {
+		return false
+	}
+	if this.NoDependencyRules != that1.NoDependencyRules {
+		return false
+	}
+	if len(this.DependentRules) != len(that1.DependentRules) {
+		return false
+	}
+	for i := range this.DependentRules {
+		if this.DependentRules[i] != that1.DependentRules[i] {
+			return false
+		}
+	}
+	if len(this.DependencyRules) != len(that1.DependencyRules) {
+		return false
+	}
+	for i := range this.DependencyRules {
+		if this.DependencyRules[i] != that1.DependencyRules[i] {
+			return false
+		}
+	}
 	return true
 }
 func (this *AlertStateDesc) Equal(that interface{}) bool {
@@ -883,13 +962,14 @@ func (this *RulesRequest) GoString() string {
 	if this == nil {
 		return "nil"
 	}
-	s := make([]string, 0, 9)
+	s := make([]string, 0, 10)
 	s = append(s, "&ruler.RulesRequest{")
 	s = append(s, "Filter: "+fmt.Sprintf("%#v", this.Filter)+",\n")
 	s = append(s, "RuleName: "+fmt.Sprintf("%#v", this.RuleName)+",\n")
 	s = append(s, "RuleGroup: "+fmt.Sprintf("%#v", this.RuleGroup)+",\n")
 	s = append(s, "File: "+fmt.Sprintf("%#v", this.File)+",\n")
 	s = append(s, "ExcludeAlerts: "+fmt.Sprintf("%#v", this.ExcludeAlerts)+",\n")
+	s = append(s, "Health: "+fmt.Sprintf("%#v", this.Health)+",\n")
 	s = append(s, "}")
 	return strings.Join(s, "")
 }
@@ -945,7 +1025,7 @@ func (this *RuleStateDesc) GoString() string {
 	if this == nil {
 		return "nil"
 	}
-	s := make([]string, 0, 11)
+	s := make([]string, 0, 13)
 	s = append(s, "&ruler.RuleStateDesc{")
 	if this.Rule != nil {
 		s = append(s, "Rule: "+fmt.Sprintf("%#v", this.Rule)+",\n")
@@ -958,6 +1038,10 @@ func (this *RuleStateDesc) GoString() string {
 	}
 	s = append(s, "EvaluationTimestamp: "+fmt.Sprintf("%#v", this.EvaluationTimestamp)+",\n")
 	s = append(s, "EvaluationDuration: "+fmt.Sprintf("%#v", this.EvaluationDuration)+",\n")
+	s = append(s, "NoDependentRules: "+fmt.Sprintf("%#v", this.NoDependentRules)+",\n")
+	s = append(s, "NoDependencyRules: "+fmt.Sprintf("%#v", this.NoDependencyRules)+",\n")
+	s = append(s, "DependentRules: "+fmt.Sprintf("%#v", this.DependentRules)+",\n")
+	s = append(s, "DependencyRules: "+fmt.Sprintf("%#v", this.DependencyRules)+",\n")
 	s = append(s, "}")
 	return strings.Join(s, "")
 }
@@ -1129,6 +1213,15 @@ func (m *RulesRequest) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	_ = i
 	var l int
 	_ = l
+	if len(m.Health) > 0 {
+		for iNdEx := len(m.Health) - 1; iNdEx >= 0; iNdEx-- {
+			i -= len(m.Health[iNdEx])
+			copy(dAtA[i:], m.Health[iNdEx])
+			i = encodeVarintRuler(dAtA, i, uint64(len(m.Health[iNdEx])))
+			i--
+			dAtA[i] = 0x32
+		}
+	}
 	if m.ExcludeAlerts {
 		i--
 		if m.ExcludeAlerts {
@@ -1351,6 +1444,44 @@ func (m *RuleStateDesc) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	_ = i
 	var l int
 	_ = l
+	if len(m.DependencyRules) > 0 {
+		for iNdEx := len(m.DependencyRules) - 1; iNdEx >= 0; iNdEx-- {
+			i -= len(m.DependencyRules[iNdEx])
+			copy(dAtA[i:], m.DependencyRules[iNdEx])
+			i = encodeVarintRuler(dAtA, i, uint64(len(m.DependencyRules[iNdEx])))
+			i--
+			dAtA[i] = 0x5a
+		}
+	}
+	if len(m.DependentRules) > 0 {
+		for iNdEx := len(m.DependentRules) - 1; iNdEx >= 0; iNdEx-- {
+			i -= len(m.DependentRules[iNdEx])
+			copy(dAtA[i:], m.DependentRules[iNdEx])
+			i = encodeVarintRuler(dAtA, i, uint64(len(m.DependentRules[iNdEx])))
+			i--
+			dAtA[i] = 0x52
+		}
+	}
+	if m.NoDependencyRules {
+		i--
+		if m.NoDependencyRules {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
+		}
+		i--
+		dAtA[i] = 0x48
+	}
+	if m.NoDependentRules {
+		i--
+		if m.NoDependentRules {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
+		}
+		i--
+		dAtA[i] = 0x40
+	}
 	n4, err4 := github_com_gogo_protobuf_types.StdDurationMarshalTo(m.EvaluationDuration, dAtA[i-github_com_gogo_protobuf_types.SizeOfStdDuration(m.EvaluationDuration):])
 	if err4 != nil {
 		return 0, err4
@@ -1570,6 +1701,12 @@ func (m *RulesRequest) Size() (n int) {
 	if m.ExcludeAlerts {
 		n += 2
 	}
+	if len(m.Health) > 0 {
+		for _, s := range m.Health {
+			l = len(s)
+			n += 1 + l + sovRuler(uint64(l))
+		}
+	}
 	return n
 }
 
@@ -1667,6 +1804,24 @@ func (m *RuleStateDesc) Size() (n int) {
 	n += 1 + l + sovRuler(uint64(l))
 	l = github_com_gogo_protobuf_types.SizeOfStdDuration(m.EvaluationDuration)
 	n += 1 + l + sovRuler(uint64(l))
+	if m.NoDependentRules {
+		n += 2
+	}
+	if m.NoDependencyRules {
+		n += 2
+	}
+	if len(m.DependentRules) > 0 {
+		for _, s := range m.DependentRules {
+			l = len(s)
+			n += 1 + l + sovRuler(uint64(l))
+		}
+	}
+	if len(m.DependencyRules) > 0 {
+		for _, s := range m.DependencyRules {
+			l = len(s)
+			n += 1 + l + sovRuler(uint64(l))
+		}
+	}
 	return n
 }
 
@@ -1726,6 +1881,7 @@ func (this *RulesRequest) String() string {
 		`RuleGroup:` + fmt.Sprintf("%v", this.RuleGroup) + `,`,
 		`File:` + fmt.Sprintf("%v", this.File) + `,`,
 		`ExcludeAlerts:` + fmt.Sprintf("%v", this.ExcludeAlerts) + `,`,
+		`Health:` + fmt.Sprintf("%v", this.Health) + `,`,
 		`}`,
 	}, "")
 	return s
@@ -1799,6 +1955,10 @@ func (this *RuleStateDesc) String() string {
 		`Alerts:` + repeatedStringForAlerts + `,`,
 		`EvaluationTimestamp:` + strings.Replace(strings.Replace(fmt.Sprintf("%v", this.EvaluationTimestamp), "Timestamp", "timestamppb.Timestamp", 1), `&`, ``, 1) + `,`,
 		`EvaluationDuration:` + strings.Replace(strings.Replace(fmt.Sprintf("%v", this.EvaluationDuration), "Duration", "durationpb.Duration", 1), `&`, ``, 1) + `,`,
+		`NoDependentRules:` + fmt.Sprintf("%v", this.NoDependentRules) + `,`,
+		`NoDependencyRules:` + fmt.Sprintf("%v", this.NoDependencyRules) + `,`,
+		`DependentRules:` + fmt.Sprintf("%v", this.DependentRules) + `,`,
+		`DependencyRules:` + fmt.Sprintf("%v", this.DependencyRules) + `,`,
 		`}`,
 	}, "")
 	return s
@@ -1994,6 +2154,38 @@ func (m *RulesRequest) Unmarshal(dAtA []byte) error {
 				}
 			}
 			m.ExcludeAlerts = bool(v != 0)
+		case 6:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Health", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowRuler
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthRuler
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthRuler
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Health = append(m.Health, string(dAtA[iNdEx:postIndex]))
+			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
 			skippy, err := skipRuler(dAtA[iNdEx:])
@@ -2693,6 +2885,110 @@ func (m *RuleStateDesc) Unmarshal(dAtA []byte) error {
 				return err
 			}
 			iNdEx = postIndex
+		case 8:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field NoDependentRules", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowRuler
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.NoDependentRules = bool(v != 0)
+		case 9:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field NoDependencyRules", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowRuler
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.NoDependencyRules = bool(v != 0)
+		case 10:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field DependentRules", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowRuler
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthRuler
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthRuler
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.DependentRules = append(m.DependentRules, string(dAtA[iNdEx:postIndex]))
+			iNdEx = postIndex
+		case 11:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field DependencyRules", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowRuler
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthRuler
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthRuler
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.DependencyRules = append(m.DependencyRules, string(dAtA[iNdEx:postIndex]))
+			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
 			skippy, err := skipRuler(dAtA[iNdEx:])
@@ -0,0 +1,93 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package rulespb
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// RuleNamespaceVersion describes a version of a tenant's rule namespace.
+type RuleNamespaceVersion struct {
+	// ID of the version. IDs sort in the order the versions have been created.
+	ID string `json:"id" yaml:"id"`
+
+	// CreatedAt is when the version has been created.
+	CreatedAt time.Time `json:"created_at" yaml:"created_at"`
+
+	// CreatedBy is who created the version, as declared by the client which changed the namespace.
+	CreatedBy string `json:"created_by,omitempty" yaml:"created_by,omitempty"`
+
+	// Hash of the rule groups of the namespace, computed by HashRuleGroups.
+	Hash string `json:"hash" yaml:"hash"`
+
+	// RuleGroups is the number of rule groups in the namespace. It's 0 if the namespace has been deleted.
+	RuleGroups int `json:"rule_groups" yaml:"rule_groups"`
+}
+
+// RuleNamespaceVersionDesc is a version of a tenant's rule namespace, along with its rule groups.
+type RuleNamespaceVersionDesc struct {
+	RuleNamespaceVersion
+
+	User      string
+	Namespace string
+	Groups    RuleGroupList
+}
+
+// NewRuleNamespaceVersion returns a new version of the input rule namespace, created at the given time.
+// The rule groups are sorted by name.
+func NewRuleNamespaceVersion(user, namespace string, groups RuleGroupList, createdBy string, createdAt time.Time) RuleNamespaceVersionDesc {
+	sorted := sortedByName(groups)
+
+	return RuleNamespaceVersionDesc{
+		RuleNamespaceVersion: RuleNamespaceVersion{
+			// The zero-padded timestamp guarantees the IDs sort lexicographically by creation time.
+			ID:         fmt.Sprintf("%020d", createdAt.UnixNano()),
+			CreatedAt:  createdAt.UTC(),
+			CreatedBy:  createdBy,
+			Hash:       HashRuleGroups(sorted),
+			RuleGroups: len(sorted),
+		},
+		User:      user,
+		Namespace: namespace,
+		Groups:    sorted,
+	}
+}
+
+// HashRuleGroups returns the hash of the input rule groups. The hash doesn't depend on the order of the groups.
+func HashRuleGroups(groups RuleGroupList) string {
+	h := sha256.New()
+	for _, g := range sortedByName(groups) {
+		// The user and namespace are the same for all groups of a namespace, so they're not hashed. This way
+		// the hash only depends on the content of the groups.
+		data, err := (&RuleGroupDesc{
+			Name:                          g.Name,
+			Interval:                      g.Interval,
+			Rules:                         g.Rules,
+			Options:                       g.Options,
+			SourceTenants:                 g.SourceTenants,
+			EvaluationDelay:               g.EvaluationDelay, //nolint:staticcheck // We want to intentionally access a deprecated field
+			QueryOffset:                   g.QueryOffset,
+			AlignEvaluationTimeOnInterval: g.AlignEvaluationTimeOnInterval,
+		}).Marshal()
+		if err != nil {
+			// Marshalling a rule group never fails, but fall back to its textual representation just in case.
+			data = []byte(g.String())
+		}
+		_, _ = h.Write([]byte{0})
+		_, _ = h.Write(data)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func sortedByName(groups RuleGroupList) RuleGroupList {
+	sorted := make(RuleGroupList, len(groups))
+	copy(sorted, groups)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Name < sorted[j].Name
+	})
+	return sorted
+}
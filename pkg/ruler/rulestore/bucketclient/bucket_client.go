@@ -9,8 +9,11 @@ import (
 	"bytes"
 	"context"
 	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"io"
+	"path"
+	"sort"
 	"strings"
 	"sync"
 
@@ -30,6 +33,9 @@ const (
 	// RulesPrefix is the bucket prefix under which all tenants rule groups are stored.
 	RulesPrefix = "rules"
 
+	// RuleNamespaceVersionsPrefix is the bucket prefix under which all tenants rule namespace versions are stored.
+	RuleNamespaceVersionsPrefix = "rules_versions"
+
 	loadConcurrency = 10
 )
 
@@ -43,19 +49,32 @@ var (
 // BucketRuleStore is used to support the RuleStore interface against an object storage backend. It is implemented
 // using the Thanos objstore.Bucket interface
 type BucketRuleStore struct {
-	bucket      objstore.Bucket
-	cfgProvider bucket.TenantConfigProvider
-	logger      log.Logger
+	bucket         objstore.Bucket
+	versionsBucket objstore.Bucket
+	cfgProvider    bucket.TenantConfigProvider
+	logger         log.Logger
 }
 
 func NewBucketRuleStore(bkt objstore.Bucket, cfgProvider bucket.TenantConfigProvider, logger log.Logger) *BucketRuleStore {
 	return &BucketRuleStore{
-		bucket:      bucket.NewPrefixedBucketClient(bkt, RulesPrefix),
-		cfgProvider: cfgProvider,
-		logger:      logger,
+		bucket:         bucket.NewPrefixedBucketClient(bkt, RulesPrefix),
+		versionsBucket: bucket.NewPrefixedBucketClient(bkt, RuleNamespaceVersionsPrefix),
+		cfgProvider:    cfgProvider,
+		logger:         logger,
 	}
 }
 
+// ruleNamespaceVersionObject is the format a rule namespace version is stored in the bucket.
+type ruleNamespaceVersionObject struct {
+	rulespb.RuleNamespaceVersion
+
+	User      string `json:"user"`
+	Namespace string `json:"namespace"`
+
+	// Groups are the rule groups of the namespace, encoded the same way rule groups are stored in the bucket.
+	Groups [][]byte `json:"groups"`
+}
+
 // getRuleGroup loads and return a rules group. If existing rule group is supplied, it is Reset and reused. If nil, new RuleGroupDesc is allocated.
 func (b *BucketRuleStore) getRuleGroup(ctx context.Context, userID, namespace, groupName string, rg *rulespb.RuleGroupDesc) (*rulespb.RuleGroupDesc, error) {
 	userBucket := bucket.NewUserBucketClient(userID, b.bucket, b.cfgProvider)
@@ -254,6 +273,122 @@ func (b *BucketRuleStore) DeleteNamespace(ctx context.Context, userID string, na
 	return nil
 }
 
+// ListRuleNamespaceVersions implements rules.RuleStore.
+func (b *BucketRuleStore) ListRuleNamespaceVersions(ctx context.Context, userID string, namespace string) ([]rulespb.RuleNamespaceVersion, error) {
+	userBucket := b.getRuleNamespaceVersionsUserBucket(userID)
+
+	var keys []string
+	err := userBucket.Iter(ctx, getNamespacePrefix(namespace), func(key string) error {
+		keys = append(keys, key)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	versions := make([]rulespb.RuleNamespaceVersion, 0, len(keys))
+	for _, key := range keys {
+		obj, err := b.getRuleNamespaceVersion(ctx, userBucket, key)
+		if userBucket.IsObjNotFoundErr(err) {
+			// The version has been deleted in the meanwhile.
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		versions = append(versions, obj.RuleNamespaceVersion)
+	}
+
+	// Sort the versions from the most recent one.
+	sort.Slice(versions, func(i, j int) bool {
+		return versions[i].ID > versions[j].ID
+	})
+	return versions, nil
+}
+
+// GetRuleNamespaceVersion implements rules.RuleStore.
+func (b *BucketRuleStore) GetRuleNamespaceVersion(ctx context.Context, userID string, namespace string, versionID string) (rulespb.RuleNamespaceVersionDesc, error) {
+	userBucket := b.getRuleNamespaceVersionsUserBucket(userID)
+
+	obj, err := b.getRuleNamespaceVersion(ctx, userBucket, getRuleNamespaceVersionObjectKey(namespace, versionID))
+	if userBucket.IsObjNotFoundErr(err) {
+		return rulespb.RuleNamespaceVersionDesc{}, rulestore.ErrNamespaceVersionNotFound
+	}
+	if err != nil {
+		return rulespb.RuleNamespaceVersionDesc{}, err
+	}
+
+	version := rulespb.RuleNamespaceVersionDesc{
+		RuleNamespaceVersion: obj.RuleNamespaceVersion,
+		User:                 obj.User,
+		Namespace:            obj.Namespace,
+		Groups:               make(rulespb.RuleGroupList, 0, len(obj.Groups)),
+	}
+	for _, data := range obj.Groups {
+		rg := &rulespb.RuleGroupDesc{}
+		if err := proto.Unmarshal(data, rg); err != nil {
+			return rulespb.RuleNamespaceVersionDesc{}, errors.Wrapf(err, "failed to unmarshal rule group of rule namespace version %s", versionID)
+		}
+		version.Groups = append(version.Groups, rg)
+	}
+	return version, nil
+}
+
+// SetRuleNamespaceVersion implements rules.RuleStore.
+func (b *BucketRuleStore) SetRuleNamespaceVersion(ctx context.Context, version rulespb.RuleNamespaceVersionDesc) error {
+	obj := ruleNamespaceVersionObject{
+		RuleNamespaceVersion: version.RuleNamespaceVersion,
+		User:                 version.User,
+		Namespace:            version.Namespace,
+		Groups:               make([][]byte, 0, len(version.Groups)),
+	}
+	for _, rg := range version.Groups {
+		data, err := proto.Marshal(rg)
+		if err != nil {
+			return err
+		}
+		obj.Groups = append(obj.Groups, data)
+	}
+
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return err
+	}
+
+	userBucket := b.getRuleNamespaceVersionsUserBucket(version.User)
+	return userBucket.Upload(ctx, getRuleNamespaceVersionObjectKey(version.Namespace, version.ID), bytes.NewBuffer(data))
+}
+
+// DeleteRuleNamespaceVersion implements rules.RuleStore.
+func (b *BucketRuleStore) DeleteRuleNamespaceVersion(ctx context.Context, userID string, namespace string, versionID string) error {
+	userBucket := b.getRuleNamespaceVersionsUserBucket(userID)
+
+	err := userBucket.Delete(ctx, getRuleNamespaceVersionObjectKey(namespace, versionID))
+	if userBucket.IsObjNotFoundErr(err) {
+		return nil
+	}
+	return err
+}
+
+func (b *BucketRuleStore) getRuleNamespaceVersion(ctx context.Context, userBucket objstore.Bucket, objectKey string) (ruleNamespaceVersionObject, error) {
+	obj := ruleNamespaceVersionObject{}
+
+	reader, err := userBucket.Get(ctx, objectKey)
+	if err != nil {
+		return obj, err
+	}
+	defer func() { _ = reader.Close() }()
+
+	if err := json.NewDecoder(reader).Decode(&obj); err != nil {
+		return obj, errors.Wrapf(err, "failed to unmarshal rule namespace version %s", path.Base(objectKey))
+	}
+	return obj, nil
+}
+
+func (b *BucketRuleStore) getRuleNamespaceVersionsUserBucket(userID string) objstore.Bucket {
+	return bucket.NewUserBucketClient(userID, b.versionsBucket, b.cfgProvider).WithExpectedErrs(b.versionsBucket.IsObjNotFoundErr)
+}
+
 func getNamespacePrefix(namespace string) string {
 	return base64.URLEncoding.EncodeToString([]byte(namespace)) + objstore.DirDelim
 }
@@ -262,6 +397,10 @@ func getRuleGroupObjectKey(namespace, group string) string {
 	return getNamespacePrefix(namespace) + base64.URLEncoding.EncodeToString([]byte(group))
 }
 
+func getRuleNamespaceVersionObjectKey(namespace, versionID string) string {
+	return getNamespacePrefix(namespace) + versionID
+}
+
 // parseRuleGroupObjectKeyWithUser parses a bucket object key in the format "<user>/<namespace>/<rules group>".
 func parseRuleGroupObjectKeyWithUser(key string) (user, namespace, group string, err error) {
 	parts := strings.SplitN(key, objstore.DirDelim, 2)
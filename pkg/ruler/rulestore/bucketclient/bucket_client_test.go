@@ -443,3 +443,57 @@ func (mb mockBucket) Iter(_ context.Context, _ string, f func(string) error, _ .
 	}
 	return nil
 }
+
+func TestRuleNamespaceVersions(t *testing.T) {
+	ctx := context.Background()
+	bkt := objstore.NewInMemBucket()
+	rs := NewBucketRuleStore(bkt, nil, log.NewNopLogger())
+
+	group1 := &rulespb.RuleGroupDesc{User: "user1", Namespace: "hello", Name: "first", Interval: time.Minute, Rules: []*rulespb.RuleDesc{{Record: "up:sum", Expr: "sum(up)"}}}
+	group2 := &rulespb.RuleGroupDesc{User: "user1", Namespace: "hello", Name: "second", Rules: []*rulespb.RuleDesc{{Alert: "Down", Expr: "up == 0"}}}
+	version1 := rulespb.NewRuleNamespaceVersion("user1", "hello", rulespb.RuleGroupList{group1}, "alice", time.Unix(10, 0))
+	version2 := rulespb.NewRuleNamespaceVersion("user1", "hello", rulespb.RuleGroupList{group2, group1}, "bob", time.Unix(20, 0))
+	version3 := rulespb.NewRuleNamespaceVersion("user1", "world", nil, "", time.Unix(30, 0))
+
+	versions, err := rs.ListRuleNamespaceVersions(ctx, "user1", "hello")
+	require.NoError(t, err)
+	require.Empty(t, versions)
+
+	_, err = rs.GetRuleNamespaceVersion(ctx, "user1", "hello", version1.ID)
+	require.ErrorIs(t, err, rulestore.ErrNamespaceVersionNotFound)
+
+	require.NoError(t, rs.SetRuleNamespaceVersion(ctx, version1))
+	require.NoError(t, rs.SetRuleNamespaceVersion(ctx, version2))
+	require.NoError(t, rs.SetRuleNamespaceVersion(ctx, version3))
+
+	// Versions are listed per namespace, from the most recent one.
+	versions, err = rs.ListRuleNamespaceVersions(ctx, "user1", "hello")
+	require.NoError(t, err)
+	require.Equal(t, []rulespb.RuleNamespaceVersion{version2.RuleNamespaceVersion, version1.RuleNamespaceVersion}, versions)
+	require.Equal(t, 2, versions[0].RuleGroups)
+
+	versions, err = rs.ListRuleNamespaceVersions(ctx, "user2", "hello")
+	require.NoError(t, err)
+	require.Empty(t, versions)
+
+	actual, err := rs.GetRuleNamespaceVersion(ctx, "user1", "hello", version2.ID)
+	require.NoError(t, err)
+	require.Equal(t, version2, actual)
+	require.Equal(t, []string{"first", "second"}, []string{actual.Groups[0].Name, actual.Groups[1].Name})
+
+	// Versions are stored in a dedicated location, so they're not listed as rule groups.
+	users, err := rs.ListAllUsers(ctx)
+	require.NoError(t, err)
+	require.Empty(t, users)
+
+	exists, err := bkt.Exists(ctx, "rules_versions/user1/"+base64.URLEncoding.EncodeToString([]byte("hello"))+"/"+version1.ID)
+	require.NoError(t, err)
+	require.True(t, exists)
+
+	require.NoError(t, rs.DeleteRuleNamespaceVersion(ctx, "user1", "hello", version1.ID))
+	require.NoError(t, rs.DeleteRuleNamespaceVersion(ctx, "user1", "hello", version1.ID))
+
+	versions, err = rs.ListRuleNamespaceVersions(ctx, "user1", "hello")
+	require.NoError(t, err)
+	require.Equal(t, []rulespb.RuleNamespaceVersion{version2.RuleNamespaceVersion}, versions)
+}
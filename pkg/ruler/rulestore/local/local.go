@@ -126,6 +126,26 @@ func (l *Client) DeleteNamespace(_ context.Context, _, _ string) error {
 	return errors.New("DeleteNamespace unsupported in rule local store")
 }
 
+// ListRuleNamespaceVersions implements RuleStore
+func (l *Client) ListRuleNamespaceVersions(_ context.Context, _, _ string) ([]rulespb.RuleNamespaceVersion, error) {
+	return nil, errors.New("ListRuleNamespaceVersions unsupported in rule local store")
+}
+
+// GetRuleNamespaceVersion implements RuleStore
+func (l *Client) GetRuleNamespaceVersion(_ context.Context, _, _, _ string) (rulespb.RuleNamespaceVersionDesc, error) {
+	return rulespb.RuleNamespaceVersionDesc{}, errors.New("GetRuleNamespaceVersion unsupported in rule local store")
+}
+
+// SetRuleNamespaceVersion implements RuleStore
+func (l *Client) SetRuleNamespaceVersion(_ context.Context, _ rulespb.RuleNamespaceVersionDesc) error {
+	return errors.New("SetRuleNamespaceVersion unsupported in rule local store")
+}
+
+// DeleteRuleNamespaceVersion implements RuleStore
+func (l *Client) DeleteRuleNamespaceVersion(_ context.Context, _, _, _ string) error {
+	return errors.New("DeleteRuleNamespaceVersion unsupported in rule local store")
+}
+
 func (l *Client) loadAllRulesGroupsForUser(ctx context.Context, userID string) (rulespb.RuleGroupList, error) {
 	var list rulespb.RuleGroupList
 
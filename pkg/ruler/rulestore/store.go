@@ -19,6 +19,8 @@ var (
 	ErrGroupNamespaceNotFound = errors.New("group namespace does not exist")
 	// ErrUserNotFound is returned if the user does not currently exist
 	ErrUserNotFound = errors.New("no rule groups found for user")
+	// ErrNamespaceVersionNotFound is returned if a rule namespace version does not exist
+	ErrNamespaceVersionNotFound = errors.New("rule namespace version does not exist")
 )
 
 // RuleStore is used to store and retrieve rules.
@@ -57,4 +59,18 @@ type RuleStore interface {
 	// DeleteNamespace lists rule groups for given user and namespace, and deletes all rule groups.
 	// If namespace is empty, deletes all rule groups for user.
 	DeleteNamespace(ctx context.Context, userID, namespace string) error
+
+	// ListRuleNamespaceVersions returns the stored versions of the user's rule namespace, from the most recent one.
+	ListRuleNamespaceVersions(ctx context.Context, userID, namespace string) ([]rulespb.RuleNamespaceVersion, error)
+
+	// GetRuleNamespaceVersion returns a stored version of the user's rule namespace, along with its rule groups.
+	// It returns ErrNamespaceVersionNotFound if the version doesn't exist.
+	GetRuleNamespaceVersion(ctx context.Context, userID, namespace, versionID string) (rulespb.RuleNamespaceVersionDesc, error)
+
+	// SetRuleNamespaceVersion stores a version of a user's rule namespace.
+	SetRuleNamespaceVersion(ctx context.Context, version rulespb.RuleNamespaceVersionDesc) error
+
+	// DeleteRuleNamespaceVersion deletes a stored version of the user's rule namespace. No error is returned
+	// if the version doesn't exist.
+	DeleteRuleNamespaceVersion(ctx context.Context, userID, namespace, versionID string) error
 }
@@ -9,6 +9,8 @@ import (
 	"context"
 	"encoding/base64"
 	"fmt"
+	"slices"
+	"sort"
 	"sync"
 	"time"
 
@@ -44,12 +46,14 @@ var (
 type mockRuleStore struct {
 	rules        map[string]rulespb.RuleGroupList
 	missingRules rulespb.RuleGroupList
+	versions     map[string][]rulespb.RuleNamespaceVersionDesc
 	mtx          sync.Mutex
 }
 
 func newMockRuleStore(rules map[string]rulespb.RuleGroupList) *mockRuleStore {
 	return &mockRuleStore{
-		rules: rules,
+		rules:    rules,
+		versions: map[string][]rulespb.RuleNamespaceVersionDesc{},
 	}
 }
 
@@ -219,3 +223,49 @@ func (m *mockRuleStore) DeleteNamespace(_ context.Context, userID, namespace str
 
 	return nil
 }
+
+func (m *mockRuleStore) ListRuleNamespaceVersions(_ context.Context, userID, namespace string) ([]rulespb.RuleNamespaceVersion, error) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	var versions []rulespb.RuleNamespaceVersion
+	for _, v := range m.versions[userID] {
+		if v.Namespace == namespace {
+			versions = append(versions, v.RuleNamespaceVersion)
+		}
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		return versions[i].ID > versions[j].ID
+	})
+	return versions, nil
+}
+
+func (m *mockRuleStore) GetRuleNamespaceVersion(_ context.Context, userID, namespace, versionID string) (rulespb.RuleNamespaceVersionDesc, error) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	for _, v := range m.versions[userID] {
+		if v.Namespace == namespace && v.ID == versionID {
+			return v, nil
+		}
+	}
+	return rulespb.RuleNamespaceVersionDesc{}, rulestore.ErrNamespaceVersionNotFound
+}
+
+func (m *mockRuleStore) SetRuleNamespaceVersion(_ context.Context, version rulespb.RuleNamespaceVersionDesc) error {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	m.versions[version.User] = append(m.versions[version.User], version)
+	return nil
+}
+
+func (m *mockRuleStore) DeleteRuleNamespaceVersion(_ context.Context, userID, namespace, versionID string) error {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	m.versions[userID] = slices.DeleteFunc(m.versions[userID], func(v rulespb.RuleNamespaceVersionDesc) bool {
+		return v.Namespace == namespace && v.ID == versionID
+	})
+	return nil
+}
@@ -99,6 +99,7 @@ func (t *MultiQueuingAlgorithmTreeQueue) Dequeue(dequeueArgs *DequeueArgs) (Queu
 
 // EnqueueBackByPath enqueues an item in the back of the local queue of the node
 // located at a given path through the tree; nodes for the path are created as needed.
+// Items with a higher priority (see itemPriority) are enqueued ahead of the items with a lower priority.
 //
 // path is relative to the root node; providing a QueuePath beginning with "root"
 // will create a child node of the root node which is also named "root."
@@ -226,7 +227,17 @@ func (n *Node) enqueueBackByPath(tree *MultiQueuingAlgorithmTreeQueue, pathFromN
 	if err != nil {
 		return err
 	}
-	childNode.localQueue.PushBack(v)
+
+	// Items are kept sorted by decreasing priority, and in FIFO order among items with the same priority.
+	// The queue is scanned from the back, so that enqueueing is O(1) when all items have the same priority.
+	priority := itemPriority(v)
+	for elt := childNode.localQueue.Back(); elt != nil; elt = elt.Prev() {
+		if itemPriority(elt.Value) >= priority {
+			childNode.localQueue.InsertAfter(v, elt)
+			return nil
+		}
+	}
+	childNode.localQueue.PushFront(v)
 	return nil
 }
 
@@ -52,8 +52,11 @@ type SchedulerRequest struct {
 	Request                   *httpgrpc.HTTPRequest
 	StatsEnabled              bool
 	AdditionalQueueDimensions []string
+	Priority                  int32
 
 	EnqueueTime time.Time
+	// Deadline is the deadline of the request set by the query-frontend, or zero if it has no deadline.
+	Deadline time.Time
 
 	Ctx        context.Context
 	CancelFunc context.CancelCauseFunc
@@ -77,6 +80,19 @@ func (sr *SchedulerRequest) ExpectedQueryComponentName() string {
 	return unknownQueueDimension
 }
 
+// itemPriority returns the priority of an item stored in the tree queue. Requests with a higher priority
+// are dequeued before the requests with a lower priority in the same leaf queue, which means that the
+// priority only affects the order of the requests of a tenant, not the fairness between tenants.
+// Items which don't carry a priority have the default priority 0.
+func itemPriority(v any) int32 {
+	if r, ok := v.(*tenantRequest); ok {
+		if schedulerRequest, ok := r.req.(*SchedulerRequest); ok {
+			return schedulerRequest.Priority
+		}
+	}
+	return 0
+}
+
 // QueryRequest represents the items stored in the queue
 // which may be a SchedulerRequest when running with the standalone scheduler process,
 // or a frontend/v1 request when running with the RequestQueue embedded in the v1 frontend.
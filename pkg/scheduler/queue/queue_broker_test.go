@@ -324,6 +324,46 @@ func TestQueuesRespectMaxTenantQueueSizeWithSubQueues(t *testing.T) {
 	}
 }
 
+func TestQueuesDequeueRequestsByPriority(t *testing.T) {
+	treeTypes := buildTreeTestsStruct()
+
+	for _, tt := range treeTypes {
+		t.Run(tt.name, func(t *testing.T) {
+			qb := newQueueBroker(100, tt.prioritizeQueryComponents, 0)
+			qb.addQuerierWorkerConn(NewUnregisteredQuerierWorkerConn(context.Background(), "querier-1"))
+
+			// Requests are identified by their query ID.
+			priorities := map[uint64]int32{1: 0, 2: -1, 3: 1, 4: 0, 5: 1, 6: -1, 7: 2}
+			for queryID := uint64(1); queryID <= uint64(len(priorities)); queryID++ {
+				req := &SchedulerRequest{
+					Ctx:                       context.Background(),
+					FrontendAddr:              "http://query-frontend:8007",
+					UserID:                    "tenant-1",
+					QueryID:                   queryID,
+					Request:                   &httpgrpc.HTTPRequest{},
+					AdditionalQueueDimensions: []string{ingesterQueueDimension},
+					Priority:                  priorities[queryID],
+				}
+				require.NoError(t, qb.enqueueRequestBack(&tenantRequest{tenantID: "tenant-1", req: req}, 0))
+			}
+
+			// Requests are dequeued by decreasing priority, and in FIFO order among the requests with the same priority.
+			var dequeued []uint64
+			lastTenantIndex := TenantIndex{-1}
+			for !qb.isEmpty() {
+				tenantReq, _, idx, err := qb.dequeueRequestForQuerier(&QuerierWorkerDequeueRequest{
+					QuerierWorkerConn: &QuerierWorkerConn{QuerierID: "querier-1"},
+					lastTenantIndex:   lastTenantIndex,
+				})
+				require.NoError(t, err)
+				lastTenantIndex.last = idx
+				dequeued = append(dequeued, tenantReq.req.(*SchedulerRequest).QueryID)
+			}
+			assert.Equal(t, []uint64{7, 3, 5, 1, 4, 2, 6}, dequeued)
+		})
+	}
+}
+
 func TestQueuesOnTerminatingQuerier(t *testing.T) {
 	treeTypes := buildTreeTestsStruct()
 	for _, tt := range treeTypes {
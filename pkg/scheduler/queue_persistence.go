@@ -0,0 +1,193 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package scheduler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-kit/log/level"
+	"github.com/grafana/dskit/cancellation"
+	"github.com/grafana/dskit/httpgrpc"
+	"github.com/opentracing/opentracing-go"
+
+	"github.com/grafana/mimir/pkg/scheduler/queue"
+	"github.com/grafana/mimir/pkg/util/atomicfs"
+)
+
+const queuePersistenceFilename = "queued-requests.json"
+
+// persistedRequestSensitiveHeaders are the headers which are not persisted with the queued requests, because
+// they carry the credentials of the client, which are not used by the queriers.
+var persistedRequestSensitiveHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie"}
+
+var (
+	errSchedulerShuttingDown   = cancellation.NewErrorf("query-scheduler is shutting down")
+	errPersistedRequestExpired = cancellation.NewErrorf("persisted request expired")
+)
+
+// persistedRequest is a queued request persisted by the query-scheduler on shutdown.
+type persistedRequest struct {
+	FrontendAddr              string                `json:"frontend_addr"`
+	UserID                    string                `json:"user_id"`
+	QueryID                   uint64                `json:"query_id"`
+	Request                   *httpgrpc.HTTPRequest `json:"request"`
+	StatsEnabled              bool                  `json:"stats_enabled,omitempty"`
+	AdditionalQueueDimensions []string              `json:"additional_queue_dimensions,omitempty"`
+	Priority                  int32                 `json:"priority,omitempty"`
+	EnqueueTime               time.Time             `json:"enqueue_time"`
+	Deadline                  time.Time             `json:"deadline"`
+}
+
+func (s *Scheduler) queuePersistencePath() string {
+	return filepath.Join(s.cfg.QueuePersistenceDir, queuePersistenceFilename)
+}
+
+// persistQueuedRequests persists the pending requests which have not been dequeued by any querier-worker, and
+// removes them from the pending requests. It must be called once the requests queue has been stopped.
+// The requests being processed by the queriers are left untouched.
+func (s *Scheduler) persistQueuedRequests() {
+	s.inflightRequestsMu.Lock()
+	var persisted []persistedRequest
+	for key, req := range s.schedulerInflightRequests {
+		if _, dequeued := s.dequeuedRequests[key]; dequeued {
+			continue
+		}
+
+		if req.Ctx.Err() == nil {
+			persisted = append(persisted, persistedRequest{
+				FrontendAddr:              req.FrontendAddr,
+				UserID:                    req.UserID,
+				QueryID:                   req.QueryID,
+				Request:                   withoutSensitiveHeaders(req.Request),
+				StatsEnabled:              req.StatsEnabled,
+				AdditionalQueueDimensions: req.AdditionalQueueDimensions,
+				Priority:                  req.Priority,
+				EnqueueTime:               req.EnqueueTime,
+				Deadline:                  req.Deadline,
+			})
+		}
+		req.CancelFunc(errSchedulerShuttingDown)
+		delete(s.schedulerInflightRequests, key)
+	}
+	s.inflightRequestsMu.Unlock()
+
+	if len(persisted) == 0 {
+		return
+	}
+
+	data, err := json.Marshal(persisted)
+	if err == nil {
+		err = os.MkdirAll(s.cfg.QueuePersistenceDir, 0o750)
+	}
+	if err == nil {
+		err = atomicfs.CreateFile(s.queuePersistencePath(), bytes.NewReader(data))
+	}
+	if err != nil {
+		level.Warn(s.log).Log("msg", "failed to persist queued requests, they will be abandoned", "requests", len(persisted), "err", err)
+		return
+	}
+
+	level.Info(s.log).Log("msg", "persisted queued requests", "requests", len(persisted))
+}
+
+// replayPersistedRequests enqueues the requests persisted by the previous query-scheduler shutdown, if any.
+// Requests whose deadline set by the query-frontend has expired, or which have been enqueued for longer than
+// the configured max age, are discarded.
+func (s *Scheduler) replayPersistedRequests() {
+	path := s.queuePersistencePath()
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return
+	}
+	if err != nil {
+		level.Warn(s.log).Log("msg", "failed to read persisted queued requests", "err", err)
+		return
+	}
+
+	// Remove the file right away, so that the requests are never replayed twice.
+	if err := os.Remove(path); err != nil {
+		level.Warn(s.log).Log("msg", "failed to remove persisted queued requests, not replaying them", "err", err)
+		return
+	}
+
+	var persisted []persistedRequest
+	if err := json.Unmarshal(data, &persisted); err != nil {
+		level.Warn(s.log).Log("msg", "failed to decode persisted queued requests", "err", err)
+		return
+	}
+
+	now := time.Now()
+	replayed, expired := 0, 0
+	for _, p := range persisted {
+		deadline := p.EnqueueTime.Add(s.cfg.QueuePersistenceMaxAge)
+		if !p.Deadline.IsZero() && p.Deadline.Before(deadline) {
+			deadline = p.Deadline
+		}
+		if !deadline.After(now) {
+			expired++
+			continue
+		}
+
+		if err := s.replayPersistedRequest(p, deadline, now); err != nil {
+			level.Warn(s.log).Log("msg", "failed to replay persisted request", "frontend", p.FrontendAddr, "user", p.UserID, "query_id", p.QueryID, "err", err)
+			continue
+		}
+		replayed++
+	}
+
+	level.Info(s.log).Log("msg", "replayed persisted queued requests", "replayed", replayed, "expired", expired, "failed", len(persisted)-replayed-expired)
+}
+
+// withoutSensitiveHeaders returns a copy of the request without the persistedRequestSensitiveHeaders.
+func withoutSensitiveHeaders(req *httpgrpc.HTTPRequest) *httpgrpc.HTTPRequest {
+	if req == nil {
+		return nil
+	}
+
+	filtered := *req
+	filtered.Headers = make([]*httpgrpc.Header, 0, len(req.Headers))
+	for _, h := range req.Headers {
+		if !slices.ContainsFunc(persistedRequestSensitiveHeaders, func(name string) bool { return strings.EqualFold(name, h.Key) }) {
+			filtered.Headers = append(filtered.Headers, h)
+		}
+	}
+	return &filtered
+}
+
+func (s *Scheduler) replayPersistedRequest(p persistedRequest, deadline, now time.Time) error {
+	// The request is cancelled when its deadline expires, or if the frontend cancels it.
+	deadlineCtx, cancelDeadline := context.WithDeadlineCause(context.Background(), deadline, errPersistedRequestExpired)
+	ctx, cancel := context.WithCancelCause(deadlineCtx)
+
+	req := &queue.SchedulerRequest{
+		FrontendAddr:              p.FrontendAddr,
+		UserID:                    p.UserID,
+		QueryID:                   p.QueryID,
+		Request:                   p.Request,
+		StatsEnabled:              p.StatsEnabled,
+		AdditionalQueueDimensions: p.AdditionalQueueDimensions,
+		Priority:                  p.Priority,
+		EnqueueTime:               p.EnqueueTime,
+		Deadline:                  p.Deadline,
+		CancelFunc: func(cause error) {
+			cancel(cause)
+			cancelDeadline()
+		},
+	}
+	req.QueueSpan, req.Ctx = opentracing.StartSpanFromContext(ctx, "queued")
+
+	err := s.submitRequestToEnqueue(req, now, func() {})
+	if err != nil {
+		req.CancelFunc(errEnqueuingRequestFailed)
+	}
+	return err
+}
@@ -65,6 +65,9 @@ type Scheduler struct {
 	// schedulerInflightRequests tracks requests from the time they are received to be enqueued by the scheduler
 	// to the time they are completed by the querier or failed due to cancel, timeout, or disconnect.
 	schedulerInflightRequests map[queue.RequestKey]*queue.SchedulerRequest
+	// dequeuedRequests tracks the inflight requests which have been dequeued by a querier-worker.
+	// It's only populated when the queue persistence is enabled.
+	dequeuedRequests map[queue.RequestKey]struct{}
 
 	// The ring is used to let other components discover query-scheduler replicas.
 	// The ring is optional.
@@ -97,6 +100,8 @@ type Config struct {
 	MaxOutstandingPerTenant   int           `yaml:"max_outstanding_requests_per_tenant"`
 	PrioritizeQueryComponents bool          `yaml:"prioritize_query_components" category:"experimental"`
 	QuerierForgetDelay        time.Duration `yaml:"querier_forget_delay" category:"experimental"`
+	QueuePersistenceDir       string        `yaml:"queue_persistence_dir" category:"experimental"`
+	QueuePersistenceMaxAge    time.Duration `yaml:"queue_persistence_max_age" category:"experimental"`
 
 	GRPCClientConfig grpcclient.Config         `yaml:"grpc_client_config" doc:"description=This configures the gRPC client used to report errors back to the query-frontend."`
 	ServiceDiscovery schedulerdiscovery.Config `yaml:",inline"`
@@ -106,6 +111,8 @@ func (cfg *Config) RegisterFlags(f *flag.FlagSet, logger log.Logger) {
 	f.IntVar(&cfg.MaxOutstandingPerTenant, "query-scheduler.max-outstanding-requests-per-tenant", 100, "Maximum number of outstanding requests per tenant per query-scheduler. In-flight requests above this limit will fail with HTTP response status code 429.")
 	f.BoolVar(&cfg.PrioritizeQueryComponents, "query-scheduler.prioritize-query-components", false, "When enabled, the query scheduler primarily prioritizes dequeuing fairly from queue components and secondarily prioritizes dequeuing fairly across tenants. When disabled, the query scheduler primarily prioritizes tenant fairness.")
 	f.DurationVar(&cfg.QuerierForgetDelay, "query-scheduler.querier-forget-delay", 0, "If a querier disconnects without sending notification about graceful shutdown, the query-scheduler will keep the querier in the tenant's shard until the forget delay has passed. This feature is useful to reduce the blast radius when shuffle-sharding is enabled.")
+	f.StringVar(&cfg.QueuePersistenceDir, "query-scheduler.queue-persistence-dir", "", "Directory where the query-scheduler persists, on shutdown, the queued requests which have not been dispatched to any querier, and from which it replays them on startup. When enabled, the requests of the query-frontends disconnecting while the query-scheduler is shutting down are not cancelled. The Authorization, Proxy-Authorization and Cookie headers of the requests are not persisted. Empty to disable.")
+	f.DurationVar(&cfg.QueuePersistenceMaxAge, "query-scheduler.queue-persistence-max-age", 2*time.Minute, "Maximum time, since a request has been enqueued, for the persisted request to be replayed. Replayed requests are cancelled once this time has elapsed, or once the deadline set by the query-frontend has expired, whichever comes first.")
 
 	cfg.GRPCClientConfig.CustomCompressors = []string{s2.Name}
 	cfg.GRPCClientConfig.RegisterFlagsWithPrefix("query-scheduler.grpc-client-config", f)
@@ -126,6 +133,7 @@ func NewScheduler(cfg Config, limits Limits, log log.Logger, registerer promethe
 		limits: limits,
 
 		schedulerInflightRequests: map[queue.RequestKey]*queue.SchedulerRequest{},
+		dequeuedRequests:          map[queue.RequestKey]struct{}{},
 		connectedFrontends:        map[string]*connectedFrontend{},
 		subservicesWatcher:        services.NewFailureWatcher(),
 	}
@@ -337,7 +345,12 @@ func (s *Scheduler) frontendDisconnected(frontendAddress string) {
 	cf.connections--
 	if cf.connections == 0 {
 		delete(s.connectedFrontends, frontendAddress)
-		cf.cancel(errFrontendDisconnected)
+
+		// When the queue is persisted, the requests of the frontends disconnecting because the scheduler is
+		// shutting down are kept, so that they're either dispatched to the queriers or persisted.
+		if s.cfg.QueuePersistenceDir == "" || s.isRunning() {
+			cf.cancel(errFrontendDisconnected)
+		}
 	}
 }
 
@@ -351,8 +364,6 @@ func (s *Scheduler) enqueueRequest(requestContext context.Context, frontendAddr
 		}
 	}()
 
-	userID := msg.GetUserID()
-
 	req := &queue.SchedulerRequest{
 		FrontendAddr:              frontendAddr,
 		UserID:                    msg.UserID,
@@ -360,6 +371,10 @@ func (s *Scheduler) enqueueRequest(requestContext context.Context, frontendAddr
 		Request:                   msg.HttpRequest,
 		StatsEnabled:              msg.StatsEnabled,
 		AdditionalQueueDimensions: msg.AdditionalQueueDimensions,
+		Priority:                  msg.Priority,
+	}
+	if msg.DeadlineUnixMillis > 0 {
+		req.Deadline = time.UnixMilli(msg.DeadlineUnixMillis)
 	}
 
 	now := time.Now()
@@ -369,16 +384,24 @@ func (s *Scheduler) enqueueRequest(requestContext context.Context, frontendAddr
 	req.EnqueueTime = now
 	req.CancelFunc = cancel
 
+	return s.submitRequestToEnqueue(req, now, func() {
+		shouldCancel = false
+	})
+}
+
+// submitRequestToEnqueue submits the request to the queue, and adds it to the pending requests once enqueued.
+// successFn is called once the request has been enqueued, before any querier can receive it.
+func (s *Scheduler) submitRequestToEnqueue(req *queue.SchedulerRequest, now time.Time, successFn func()) error {
 	// aggregate the max queriers limit in the case of a multi tenant query
-	tenantIDs, err := tenant.TenantIDsFromOrgID(userID)
+	tenantIDs, err := tenant.TenantIDsFromOrgID(req.UserID)
 	if err != nil {
 		return err
 	}
 	maxQueriers := validation.SmallestPositiveNonZeroIntPerTenant(tenantIDs, s.limits.MaxQueriersPerUser)
 
-	s.activeUsers.UpdateUserTimestamp(userID, now)
-	return s.requestQueue.SubmitRequestToEnqueue(userID, req, maxQueriers, func() {
-		shouldCancel = false
+	s.activeUsers.UpdateUserTimestamp(req.UserID, now)
+	return s.requestQueue.SubmitRequestToEnqueue(req.UserID, req, maxQueriers, func() {
+		successFn()
 		s.addRequestToPending(req)
 	})
 }
@@ -401,9 +424,23 @@ func (s *Scheduler) cancelRequestAndRemoveFromPending(key queue.RequestKey, reas
 	}
 
 	delete(s.schedulerInflightRequests, key)
+	delete(s.dequeuedRequests, key)
 	return req
 }
 
+func (s *Scheduler) markRequestDequeued(key queue.RequestKey) {
+	if s.cfg.QueuePersistenceDir == "" {
+		return
+	}
+
+	s.inflightRequestsMu.Lock()
+	defer s.inflightRequestsMu.Unlock()
+
+	if _, ok := s.schedulerInflightRequests[key]; ok {
+		s.dequeuedRequests[key] = struct{}{}
+	}
+}
+
 func (s *Scheduler) transformRequestQueueError(err error) error {
 	if errors.Is(err, queue.ErrStopped) && !s.isRunning() {
 		// Return a more clear error if the queue is stopped because the query-scheduler is not running.
@@ -445,6 +482,7 @@ func (s *Scheduler) QuerierLoop(querier schedulerpb.SchedulerForQuerier_QuerierL
 		lastTenantIdx = idx
 
 		schedulerReq := queryReq.(*queue.SchedulerRequest)
+		s.markRequestDequeued(schedulerReq.Key())
 
 		queueTime := time.Since(schedulerReq.EnqueueTime)
 		additionalQueueDimensionLabels := strings.Join(schedulerReq.AdditionalQueueDimensions, ":")
@@ -588,6 +626,10 @@ func (s *Scheduler) starting(ctx context.Context) error {
 		return errors.Wrap(err, "unable to start scheduler subservices")
 	}
 
+	if s.cfg.QueuePersistenceDir != "" {
+		s.replayPersistedRequests()
+	}
+
 	return nil
 }
 
@@ -618,7 +660,13 @@ func (s *Scheduler) running(ctx context.Context) error {
 // Close the Scheduler.
 func (s *Scheduler) stopping(_ error) error {
 	// This will also stop the requests queue, which stop accepting new requests and errors out any pending requests.
-	return services.StopManagerAndAwaitStopped(context.Background(), s.subservices)
+	err := services.StopManagerAndAwaitStopped(context.Background(), s.subservices)
+
+	if s.cfg.QueuePersistenceDir != "" {
+		s.persistQueuedRequests()
+	}
+
+	return err
 }
 
 func (s *Scheduler) cleanupMetricsForInactiveUser(user string) {
@@ -7,9 +7,12 @@ package scheduler
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net"
 	"net/http"
+	"os"
+	"path/filepath"
 	"strings"
 	"sync"
 	"testing"
@@ -50,6 +53,10 @@ func setupScheduler(t *testing.T, reg prometheus.Registerer) (*Scheduler, schedu
 	flagext.DefaultValues(&cfg)
 	cfg.MaxOutstandingPerTenant = testMaxOutstandingPerTenant
 
+	return setupSchedulerWithConfig(t, cfg, reg)
+}
+
+func setupSchedulerWithConfig(t *testing.T, cfg Config, reg prometheus.Registerer) (*Scheduler, schedulerpb.SchedulerForFrontendClient, schedulerpb.SchedulerForQuerierClient) {
 	s, err := NewScheduler(cfg, &limits{queriers: 2}, log.NewNopLogger(), reg)
 	require.NoError(t, err)
 
@@ -364,6 +371,105 @@ func TestSchedulerShutdown_QuerierLoop(t *testing.T) {
 	require.Error(t, err)
 }
 
+func TestSchedulerQueuePersistence(t *testing.T) {
+	cfg := Config{}
+	flagext.DefaultValues(&cfg)
+	cfg.MaxOutstandingPerTenant = testMaxOutstandingPerTenant
+	cfg.QueuePersistenceDir = t.TempDir()
+
+	// Enqueue some requests while no querier is connected, then stop the scheduler.
+	scheduler, frontendClient, _ := setupSchedulerWithConfig(t, cfg, nil)
+	frontendLoop := initFrontendLoop(t, frontendClient, "frontend-12345")
+	deadline := time.Now().Add(time.Minute)
+	for queryID := uint64(1); queryID <= 2; queryID++ {
+		frontendToScheduler(t, frontendLoop, &schedulerpb.FrontendToScheduler{
+			Type:    schedulerpb.ENQUEUE,
+			QueryID: queryID,
+			UserID:  "test",
+			HttpRequest: &httpgrpc.HTTPRequest{Method: "GET", Url: "/hello", Headers: []*httpgrpc.Header{
+				{Key: "X-Scope-OrgID", Values: []string{"test"}},
+				{Key: "authorization", Values: []string{"Bearer secret-token"}},
+				{Key: "Cookie", Values: []string{"session=secret-session"}},
+			}},
+			DeadlineUnixMillis: deadline.UnixMilli(),
+		})
+	}
+	require.NoError(t, services.StopAndAwaitTerminated(context.Background(), scheduler))
+	verifyNoPendingRequestsLeft(t, scheduler)
+
+	// The credentials of the clients are not persisted, while the deadline of the requests is.
+	data, err := os.ReadFile(filepath.Join(cfg.QueuePersistenceDir, queuePersistenceFilename))
+	require.NoError(t, err)
+	require.NotContains(t, string(data), "secret")
+
+	var persisted []persistedRequest
+	require.NoError(t, json.Unmarshal(data, &persisted))
+	require.Len(t, persisted, 2)
+	for _, p := range persisted {
+		require.Equal(t, deadline.UnixMilli(), p.Deadline.UnixMilli())
+	}
+
+	// The requests are replayed by the restarted scheduler.
+	scheduler, _, querierClient := setupSchedulerWithConfig(t, cfg, nil)
+	require.NoFileExists(t, filepath.Join(cfg.QueuePersistenceDir, queuePersistenceFilename))
+
+	querierLoop := initQuerierLoop(t, querierClient, "querier-1")
+	var queryIDs []uint64
+	for i := 0; i < 2; i++ {
+		msg, err := querierLoop.Recv()
+		require.NoError(t, err)
+		require.Equal(t, "frontend-12345", msg.FrontendAddress)
+		require.Equal(t, "/hello", msg.HttpRequest.Url)
+		require.Equal(t, []*httpgrpc.Header{{Key: "X-Scope-OrgID", Values: []string{"test"}}}, msg.HttpRequest.Headers)
+		queryIDs = append(queryIDs, msg.QueryID)
+		require.NoError(t, querierLoop.Send(&schedulerpb.QuerierToScheduler{}))
+	}
+	require.ElementsMatch(t, []uint64{1, 2}, queryIDs)
+
+	verifyNoPendingRequestsLeft(t, scheduler)
+}
+
+func TestSchedulerQueuePersistence_ExpiredRequestsAreNotReplayed(t *testing.T) {
+	cfg := Config{}
+	flagext.DefaultValues(&cfg)
+
+	for name, tc := range map[string]struct {
+		enqueueTime time.Time
+		deadline    time.Time
+	}{
+		"enqueued for longer than the max age": {
+			enqueueTime: time.Now().Add(-cfg.QueuePersistenceMaxAge),
+		},
+		"deadline set by the query-frontend expired": {
+			enqueueTime: time.Now(),
+			deadline:    time.Now().Add(-time.Second),
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			cfg := cfg
+			cfg.QueuePersistenceDir = t.TempDir()
+
+			data, err := json.Marshal([]persistedRequest{{
+				FrontendAddr: "frontend-12345",
+				UserID:       "test",
+				QueryID:      1,
+				Request:      &httpgrpc.HTTPRequest{Method: "GET", Url: "/hello"},
+				EnqueueTime:  tc.enqueueTime,
+				Deadline:     tc.deadline,
+			}})
+			require.NoError(t, err)
+			require.NoError(t, os.WriteFile(filepath.Join(cfg.QueuePersistenceDir, queuePersistenceFilename), data, 0o600))
+
+			scheduler, _, querierClient := setupSchedulerWithConfig(t, cfg, nil)
+			require.NoFileExists(t, filepath.Join(cfg.QueuePersistenceDir, queuePersistenceFilename))
+
+			querierLoop := initQuerierLoop(t, querierClient, "querier-1")
+			verifyQuerierDoesntReceiveRequest(t, querierLoop, 500*time.Millisecond)
+			verifyNoPendingRequestsLeft(t, scheduler)
+		})
+	}
+}
+
 func TestSchedulerMaxOutstandingRequests(t *testing.T) {
 	_, frontendClient, _ := setupScheduler(t, nil)
 
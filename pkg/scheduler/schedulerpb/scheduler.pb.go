@@ -229,6 +229,10 @@ type FrontendToScheduler struct {
 	HttpRequest               *httpgrpc.HTTPRequest `protobuf:"bytes,5,opt,name=httpRequest,proto3" json:"httpRequest,omitempty"`
 	StatsEnabled              bool                  `protobuf:"varint,6,opt,name=statsEnabled,proto3" json:"statsEnabled,omitempty"`
 	AdditionalQueueDimensions []string              `protobuf:"bytes,7,rep,name=additionalQueueDimensions,proto3" json:"additionalQueueDimensions,omitempty"`
+	// Priority of the request within the tenant queue. Requests with higher priority are dequeued first.
+	Priority int32 `protobuf:"varint,8,opt,name=priority,proto3" json:"priority,omitempty"`
+	// Deadline of the request, as a Unix timestamp in milliseconds, or 0 if the request has no deadline.
+	DeadlineUnixMillis int64 `protobuf:"varint,9,opt,name=deadlineUnixMillis,proto3" json:"deadlineUnixMillis,omitempty"`
 }
 
 func (m *FrontendToScheduler) Reset()      { *m = FrontendToScheduler{} }
@@ -312,6 +316,20 @@ func (m *FrontendToScheduler) GetAdditionalQueueDimensions() []string {
 	return nil
 }
 
+func (m *FrontendToScheduler) GetPriority() int32 {
+	if m != nil {
+		return m.Priority
+	}
+	return 0
+}
+
+func (m *FrontendToScheduler) GetDeadlineUnixMillis() int64 {
+	if m != nil {
+		return m.DeadlineUnixMillis
+	}
+	return 0
+}
+
 type SchedulerToFrontend struct {
 	Status SchedulerToFrontendStatus `protobuf:"varint,1,opt,name=status,proto3,enum=schedulerpb.SchedulerToFrontendStatus" json:"status,omitempty"`
 	Error  string                    `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
@@ -455,51 +473,53 @@ func init() {
 func init() { proto.RegisterFile("scheduler.proto", fileDescriptor_2b3fc28395a6d9c5) }
 
 var fileDescriptor_2b3fc28395a6d9c5 = []byte{
-	// 690 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x8c, 0x94, 0x4f, 0x53, 0xda, 0x40,
-	0x18, 0xc6, 0xb3, 0xfc, 0x53, 0x5e, 0xac, 0xd2, 0x55, 0x5b, 0x64, 0x6c, 0xcc, 0x30, 0x1d, 0x87,
-	0x7a, 0x00, 0x87, 0x1e, 0xda, 0x83, 0xd3, 0x19, 0xaa, 0xb1, 0x32, 0xb5, 0x41, 0x96, 0x30, 0xfd,
-	0x73, 0x61, 0x02, 0x59, 0x21, 0xa3, 0x66, 0x63, 0x36, 0x99, 0x0e, 0xb7, 0x7e, 0x84, 0x7e, 0x8c,
-	0x7e, 0x92, 0x4e, 0x8f, 0x1e, 0x3d, 0xf4, 0x50, 0xe3, 0xa5, 0x47, 0x2f, 0xbd, 0x77, 0x08, 0x81,
-	0x06, 0x0a, 0xea, 0x6d, 0xf7, 0xe5, 0x79, 0xd8, 0x7d, 0x7e, 0xef, 0x9b, 0x85, 0x25, 0xde, 0xee,
-	0x52, 0xdd, 0x3d, 0xa5, 0x76, 0xc1, 0xb2, 0x99, 0xc3, 0x70, 0x6a, 0x54, 0xb0, 0x5a, 0xd9, 0x95,
-	0x0e, 0xeb, 0x30, 0xbf, 0x5e, 0xec, 0xaf, 0x06, 0x92, 0xec, 0x76, 0xc7, 0x70, 0xba, 0x6e, 0xab,
-	0xd0, 0x66, 0x67, 0xc5, 0x8e, 0xad, 0x1d, 0x6b, 0xa6, 0x56, 0xd4, 0xf9, 0x89, 0xe1, 0x14, 0xbb,
-	0x8e, 0x63, 0x75, 0x6c, 0xab, 0x3d, 0x5a, 0x0c, 0x1c, 0xb9, 0x12, 0xe0, 0x9a, 0x4b, 0x6d, 0x83,
-	0xda, 0x2a, 0xab, 0x0f, 0xff, 0x1f, 0xaf, 0x43, 0xf2, 0x7c, 0x50, 0xad, 0xec, 0x65, 0x90, 0x84,
-	0xf2, 0x49, 0xf2, 0xaf, 0x90, 0xfb, 0x83, 0x00, 0x8f, 0xb4, 0x2a, 0x0b, 0xfc, 0x38, 0x03, 0x73,
-	0x7d, 0x4d, 0x2f, 0xb0, 0xc4, 0xc8, 0x70, 0x8b, 0x5f, 0x40, 0xaa, 0x7f, 0x2c, 0xa1, 0xe7, 0x2e,
-	0xe5, 0x4e, 0x26, 0x22, 0xa1, 0x7c, 0xaa, 0xb4, 0x5a, 0x18, 0x5d, 0xe5, 0x40, 0x55, 0x8f, 0x82,
-	0x1f, 0x49, 0x58, 0x89, 0xf3, 0xb0, 0x74, 0x6c, 0x33, 0xd3, 0xa1, 0xa6, 0x5e, 0xd6, 0x75, 0x9b,
-	0x72, 0x9e, 0x89, 0xfa, 0xb7, 0x99, 0x2c, 0xe3, 0x47, 0x90, 0x70, 0xb9, 0x7f, 0xdd, 0x98, 0x2f,
-	0x08, 0x76, 0x38, 0x07, 0x0b, 0xdc, 0xd1, 0x1c, 0x2e, 0x9b, 0x5a, 0xeb, 0x94, 0xea, 0x99, 0xb8,
-	0x84, 0xf2, 0xf3, 0x64, 0xac, 0x86, 0x37, 0x61, 0xf1, 0xdc, 0xa5, 0x2e, 0x55, 0x8d, 0x33, 0xaa,
-	0x68, 0x26, 0xe3, 0x99, 0x84, 0x84, 0xf2, 0x51, 0x32, 0x51, 0xcd, 0x7d, 0x8f, 0xc0, 0xf2, 0x7e,
-	0x70, 0x6e, 0x98, 0xd6, 0x4b, 0x88, 0x39, 0x3d, 0x8b, 0xfa, 0xa9, 0x17, 0x4b, 0x4f, 0x0b, 0xa1,
-	0x3e, 0x15, 0xa6, 0xe8, 0xd5, 0x9e, 0x45, 0x89, 0xef, 0x98, 0x96, 0x2f, 0x32, 0x3d, 0x5f, 0x08,
-	0x6e, 0x74, 0x1c, 0xee, 0xac, 0xe4, 0x13, 0xd0, 0xe3, 0xf7, 0x86, 0x3e, 0x89, 0x2c, 0x31, 0x05,
-	0xd9, 0x0e, 0xac, 0x69, 0xba, 0x6e, 0x38, 0x06, 0x33, 0xb5, 0xd3, 0x5a, 0x1f, 0xd3, 0x9e, 0x71,
-	0x46, 0x4d, 0x6e, 0x30, 0x93, 0x67, 0xe6, 0xa4, 0x68, 0x3e, 0x49, 0x66, 0x0b, 0x72, 0x27, 0xb0,
-	0x1c, 0x9a, 0x9f, 0x21, 0x22, 0xfc, 0x0a, 0x12, 0xfd, 0x43, 0x5c, 0x1e, 0x90, 0xdc, 0x1c, 0x23,
-	0x39, 0xc5, 0x51, 0xf7, 0xd5, 0x24, 0x70, 0xe1, 0x15, 0x88, 0x53, 0xdb, 0x66, 0x76, 0xc0, 0x70,
-	0xb0, 0xc9, 0xed, 0xc0, 0xba, 0xc2, 0x1c, 0xe3, 0xb8, 0x17, 0xcc, 0x69, 0xbd, 0xeb, 0x3a, 0x3a,
-	0xfb, 0x6c, 0x0e, 0xe3, 0xde, 0x3e, 0xeb, 0x1b, 0xf0, 0x64, 0x86, 0x9b, 0x5b, 0xcc, 0xe4, 0x74,
-	0x6b, 0x07, 0x1e, 0xcf, 0xe8, 0x31, 0x9e, 0x87, 0x58, 0x45, 0xa9, 0xa8, 0x69, 0x01, 0xa7, 0x60,
-	0x4e, 0x56, 0x6a, 0x0d, 0xb9, 0x21, 0xa7, 0x11, 0x06, 0x48, 0xec, 0x96, 0x95, 0x5d, 0xf9, 0x30,
-	0x1d, 0xd9, 0x6a, 0xc3, 0xda, 0xcc, 0x5c, 0x38, 0x01, 0x91, 0xea, 0xdb, 0xb4, 0x80, 0x25, 0x58,
-	0x57, 0xab, 0xd5, 0xe6, 0xbb, 0xb2, 0xf2, 0xb1, 0x49, 0xe4, 0x5a, 0x43, 0xae, 0xab, 0xf5, 0xe6,
-	0x91, 0x4c, 0x9a, 0xaa, 0xac, 0x94, 0x15, 0x35, 0x8d, 0x70, 0x12, 0xe2, 0x32, 0x21, 0x55, 0x92,
-	0x8e, 0xe0, 0x87, 0xf0, 0xa0, 0x7e, 0xd0, 0x50, 0xd5, 0x8a, 0xf2, 0xa6, 0xb9, 0x57, 0x7d, 0xaf,
-	0xa4, 0xa3, 0xa5, 0x9f, 0x28, 0xc4, 0x7b, 0x9f, 0xd9, 0xc3, 0x0f, 0xb6, 0x01, 0xa9, 0x60, 0x79,
-	0xc8, 0x98, 0x85, 0x37, 0xc6, 0x70, 0xff, 0xff, 0x2a, 0x64, 0x37, 0x66, 0xf5, 0x23, 0xd0, 0xe6,
-	0x84, 0x3c, 0xda, 0x46, 0xd8, 0x84, 0xd5, 0xa9, 0xc8, 0xf0, 0xb3, 0x31, 0xff, 0x6d, 0x4d, 0xc9,
-	0x6e, 0xdd, 0x47, 0x3a, 0xe8, 0x40, 0xc9, 0x82, 0x95, 0x70, 0xba, 0xd1, 0x38, 0x7d, 0x80, 0x85,
-	0xe1, 0xda, 0xcf, 0x27, 0xdd, 0xf5, 0x61, 0x66, 0xa5, 0xbb, 0x06, 0x6e, 0x90, 0xf0, 0x75, 0xf9,
-	0xe2, 0x4a, 0x14, 0x2e, 0xaf, 0x44, 0xe1, 0xe6, 0x4a, 0x44, 0x5f, 0x3c, 0x11, 0x7d, 0xf3, 0x44,
-	0xf4, 0xc3, 0x13, 0xd1, 0x85, 0x27, 0xa2, 0x5f, 0x9e, 0x88, 0x7e, 0x7b, 0xa2, 0x70, 0xe3, 0x89,
-	0xe8, 0xeb, 0xb5, 0x28, 0x5c, 0x5c, 0x8b, 0xc2, 0xe5, 0xb5, 0x28, 0x7c, 0x0a, 0xbf, 0xdf, 0xad,
-	0x84, 0xff, 0xfc, 0x3e, 0xff, 0x1b, 0x00, 0x00, 0xff, 0xff, 0x68, 0xe9, 0x45, 0x9c, 0xe6, 0x05,
-	0x00, 0x00,
+	// 729 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x8c, 0x55, 0xcf, 0x53, 0xda, 0x5c,
+	0x14, 0xcd, 0xe3, 0x97, 0x70, 0xf1, 0x53, 0xbe, 0xa7, 0x7e, 0x5f, 0x64, 0x6c, 0xcc, 0x30, 0x1d,
+	0x27, 0x75, 0x01, 0x0e, 0x5d, 0xb4, 0x0b, 0xa7, 0x33, 0x54, 0x63, 0x65, 0xaa, 0x41, 0x42, 0x98,
+	0xfe, 0xd8, 0x30, 0x81, 0x3c, 0x21, 0x23, 0xe6, 0xc5, 0xbc, 0x64, 0x5a, 0x76, 0xfd, 0x13, 0x3a,
+	0xfd, 0x2b, 0xfa, 0xa7, 0x74, 0xe9, 0xd2, 0x45, 0x17, 0x15, 0x37, 0x5d, 0xba, 0xe9, 0xbe, 0x43,
+	0x08, 0x34, 0x50, 0x50, 0x77, 0xef, 0xde, 0x9c, 0x43, 0xee, 0x39, 0xe7, 0xbe, 0x00, 0xcb, 0xac,
+	0xd5, 0x21, 0x86, 0xd7, 0x25, 0x4e, 0xde, 0x76, 0xa8, 0x4b, 0x71, 0x7a, 0xdc, 0xb0, 0x9b, 0xd9,
+	0xd5, 0x36, 0x6d, 0x53, 0xbf, 0x5f, 0x18, 0x9c, 0x86, 0x90, 0xec, 0x4e, 0xdb, 0x74, 0x3b, 0x5e,
+	0x33, 0xdf, 0xa2, 0xe7, 0x85, 0xb6, 0xa3, 0x9f, 0xea, 0x96, 0x5e, 0x30, 0xd8, 0x99, 0xe9, 0x16,
+	0x3a, 0xae, 0x6b, 0xb7, 0x1d, 0xbb, 0x35, 0x3e, 0x0c, 0x19, 0xb9, 0x22, 0xe0, 0xaa, 0x47, 0x1c,
+	0x93, 0x38, 0x1a, 0xad, 0x8d, 0x7e, 0x1f, 0x6f, 0x40, 0xea, 0x62, 0xd8, 0x2d, 0xef, 0xf3, 0x48,
+	0x44, 0x52, 0x4a, 0xfd, 0xd3, 0xc8, 0xfd, 0x42, 0x80, 0xc7, 0x58, 0x8d, 0x06, 0x7c, 0xcc, 0xc3,
+	0xc2, 0x00, 0xd3, 0x0b, 0x28, 0x31, 0x75, 0x54, 0xe2, 0x67, 0x90, 0x1e, 0xbc, 0x56, 0x25, 0x17,
+	0x1e, 0x61, 0x2e, 0x1f, 0x11, 0x91, 0x94, 0x2e, 0xae, 0xe5, 0xc7, 0xa3, 0x1c, 0x6a, 0xda, 0x49,
+	0xf0, 0x50, 0x0d, 0x23, 0xb1, 0x04, 0xcb, 0xa7, 0x0e, 0xb5, 0x5c, 0x62, 0x19, 0x25, 0xc3, 0x70,
+	0x08, 0x63, 0x7c, 0xd4, 0x9f, 0x66, 0xba, 0x8d, 0xff, 0x83, 0x84, 0xc7, 0xfc, 0x71, 0x63, 0x3e,
+	0x20, 0xa8, 0x70, 0x0e, 0x16, 0x99, 0xab, 0xbb, 0x4c, 0xb6, 0xf4, 0x66, 0x97, 0x18, 0x7c, 0x5c,
+	0x44, 0x52, 0x52, 0x9d, 0xe8, 0xe1, 0x2d, 0x58, 0xba, 0xf0, 0x88, 0x47, 0x34, 0xf3, 0x9c, 0x28,
+	0xba, 0x45, 0x19, 0x9f, 0x10, 0x91, 0x14, 0x55, 0xa7, 0xba, 0xb9, 0x2f, 0x51, 0x58, 0x39, 0x08,
+	0xde, 0x1b, 0x76, 0xeb, 0x39, 0xc4, 0xdc, 0x9e, 0x4d, 0x7c, 0xd5, 0x4b, 0xc5, 0xc7, 0xf9, 0x50,
+	0x4e, 0xf9, 0x19, 0x78, 0xad, 0x67, 0x13, 0xd5, 0x67, 0xcc, 0xd2, 0x17, 0x99, 0xad, 0x2f, 0x64,
+	0x6e, 0x74, 0xd2, 0xdc, 0x79, 0xca, 0xa7, 0x4c, 0x8f, 0x3f, 0xd8, 0xf4, 0x69, 0xcb, 0x12, 0x33,
+	0x2c, 0xdb, 0x85, 0x75, 0xdd, 0x30, 0x4c, 0xd7, 0xa4, 0x96, 0xde, 0xad, 0x0e, 0x6c, 0xda, 0x37,
+	0xcf, 0x89, 0xc5, 0x4c, 0x6a, 0x31, 0x7e, 0x41, 0x8c, 0x4a, 0x29, 0x75, 0x3e, 0x00, 0x67, 0x21,
+	0x69, 0x3b, 0x26, 0x75, 0x4c, 0xb7, 0xc7, 0x27, 0x45, 0x24, 0xc5, 0xd5, 0x71, 0x8d, 0xf3, 0x80,
+	0x0d, 0xa2, 0x1b, 0x5d, 0xd3, 0x22, 0x75, 0xcb, 0xfc, 0x78, 0x6c, 0x76, 0xbb, 0x26, 0xe3, 0x53,
+	0x7e, 0x20, 0x33, 0x9e, 0xe4, 0xce, 0x60, 0x25, 0xb4, 0x8b, 0x23, 0xbb, 0xf1, 0x0b, 0x48, 0x0c,
+	0x06, 0xf6, 0x58, 0x90, 0xca, 0xd6, 0x44, 0x2a, 0x33, 0x18, 0x35, 0x1f, 0xad, 0x06, 0x2c, 0xbc,
+	0x0a, 0x71, 0xe2, 0x38, 0xd4, 0x09, 0xf2, 0x18, 0x16, 0xb9, 0x5d, 0xd8, 0x50, 0xa8, 0x6b, 0x9e,
+	0xf6, 0x82, 0x9d, 0xaf, 0x75, 0x3c, 0xd7, 0xa0, 0x1f, 0xac, 0x91, 0x75, 0x77, 0xdf, 0x9b, 0x4d,
+	0x78, 0x34, 0x87, 0xcd, 0x6c, 0x6a, 0x31, 0xb2, 0xbd, 0x0b, 0xff, 0xcf, 0xd9, 0x17, 0x9c, 0x84,
+	0x58, 0x59, 0x29, 0x6b, 0x19, 0x0e, 0xa7, 0x61, 0x41, 0x56, 0xaa, 0x75, 0xb9, 0x2e, 0x67, 0x10,
+	0x06, 0x48, 0xec, 0x95, 0x94, 0x3d, 0xf9, 0x28, 0x13, 0xd9, 0x6e, 0xc1, 0xfa, 0x5c, 0x5d, 0x38,
+	0x01, 0x91, 0xca, 0xeb, 0x0c, 0x87, 0x45, 0xd8, 0xd0, 0x2a, 0x95, 0xc6, 0x71, 0x49, 0x79, 0xd7,
+	0x50, 0xe5, 0x6a, 0x5d, 0xae, 0x69, 0xb5, 0xc6, 0x89, 0xac, 0x36, 0x34, 0x59, 0x29, 0x29, 0x5a,
+	0x06, 0xe1, 0x14, 0xc4, 0x65, 0x55, 0xad, 0xa8, 0x99, 0x08, 0xfe, 0x17, 0xfe, 0xa9, 0x1d, 0xd6,
+	0x35, 0xad, 0xac, 0xbc, 0x6a, 0xec, 0x57, 0xde, 0x28, 0x99, 0x68, 0xf1, 0x3b, 0x0a, 0xf9, 0x7d,
+	0x40, 0x9d, 0xd1, 0xe5, 0xaf, 0x43, 0x3a, 0x38, 0x1e, 0x51, 0x6a, 0xe3, 0xcd, 0x09, 0xbb, 0xff,
+	0xfe, 0xc2, 0x64, 0x37, 0xe7, 0xe5, 0x11, 0x60, 0x73, 0x9c, 0x84, 0x76, 0x10, 0xb6, 0x60, 0x6d,
+	0xa6, 0x65, 0xf8, 0xc9, 0x04, 0xff, 0xae, 0x50, 0xb2, 0xdb, 0x0f, 0x81, 0x0e, 0x13, 0x28, 0xda,
+	0xb0, 0x1a, 0x56, 0x37, 0x5e, 0xa7, 0xb7, 0xb0, 0x38, 0x3a, 0xfb, 0xfa, 0xc4, 0xfb, 0x2e, 0x79,
+	0x56, 0xbc, 0x6f, 0xe1, 0x86, 0x0a, 0x5f, 0x96, 0x2e, 0xaf, 0x05, 0xee, 0xea, 0x5a, 0xe0, 0x6e,
+	0xaf, 0x05, 0xf4, 0xa9, 0x2f, 0xa0, 0xaf, 0x7d, 0x01, 0x7d, 0xeb, 0x0b, 0xe8, 0xb2, 0x2f, 0xa0,
+	0x1f, 0x7d, 0x01, 0xfd, 0xec, 0x0b, 0xdc, 0x6d, 0x5f, 0x40, 0x9f, 0x6f, 0x04, 0xee, 0xf2, 0x46,
+	0xe0, 0xae, 0x6e, 0x04, 0xee, 0x7d, 0xf8, 0xbf, 0xa0, 0x99, 0xf0, 0x3f, 0xe5, 0x4f, 0x7f, 0x0f,
+	0x00, 0xb7, 0x4b, 0xc6, 0xd1, 0x32, 0x06, 0x00, 0x00,
 }
 
 func (x FrontendToSchedulerType) String() string {
@@ -624,6 +644,12 @@ func (this *FrontendToScheduler) Equal(that interface{}) bool {
 			return false
 		}
 	}
+	if this.Priority != that1.Priority {
+		return false
+	}
+	if this.DeadlineUnixMillis != that1.DeadlineUnixMillis {
+		return false
+	}
 	return true
 }
 func (this *SchedulerToFrontend) Equal(that interface{}) bool {
@@ -729,7 +755,7 @@ func (this *FrontendToScheduler) GoString() string {
 	if this == nil {
 		return "nil"
 	}
-	s := make([]string, 0, 11)
+	s := make([]string, 0, 13)
 	s = append(s, "&schedulerpb.FrontendToScheduler{")
 	s = append(s, "Type: "+fmt.Sprintf("%#v", this.Type)+",\n")
 	s = append(s, "FrontendAddress: "+fmt.Sprintf("%#v", this.FrontendAddress)+",\n")
@@ -740,6 +766,8 @@ func (this *FrontendToScheduler) GoString() string {
 	}
 	s = append(s, "StatsEnabled: "+fmt.Sprintf("%#v", this.StatsEnabled)+",\n")
 	s = append(s, "AdditionalQueueDimensions: "+fmt.Sprintf("%#v", this.AdditionalQueueDimensions)+",\n")
+	s = append(s, "Priority: "+fmt.Sprintf("%#v", this.Priority)+",\n")
+	s = append(s, "DeadlineUnixMillis: "+fmt.Sprintf("%#v", this.DeadlineUnixMillis)+",\n")
 	s = append(s, "}")
 	return strings.Join(s, "")
 }
@@ -1180,6 +1208,16 @@ func (m *FrontendToScheduler) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	_ = i
 	var l int
 	_ = l
+	if m.DeadlineUnixMillis != 0 {
+		i = encodeVarintScheduler(dAtA, i, uint64(m.DeadlineUnixMillis))
+		i--
+		dAtA[i] = 0x48
+	}
+	if m.Priority != 0 {
+		i = encodeVarintScheduler(dAtA, i, uint64(m.Priority))
+		i--
+		dAtA[i] = 0x40
+	}
 	if len(m.AdditionalQueueDimensions) > 0 {
 		for iNdEx := len(m.AdditionalQueueDimensions) - 1; iNdEx >= 0; iNdEx-- {
 			i -= len(m.AdditionalQueueDimensions[iNdEx])
@@ -1413,6 +1451,12 @@ func (m *FrontendToScheduler) Size() (n int) {
 			n += 1 + l + sovScheduler(uint64(l))
 		}
 	}
+	if m.Priority != 0 {
+		n += 1 + sovScheduler(uint64(m.Priority))
+	}
+	if m.DeadlineUnixMillis != 0 {
+		n += 1 + sovScheduler(uint64(m.DeadlineUnixMillis))
+	}
 	return n
 }
 
@@ -1497,6 +1541,8 @@ func (this *FrontendToScheduler) String() string {
 		`HttpRequest:` + strings.Replace(fmt.Sprintf("%v", this.HttpRequest), "HTTPRequest", "httpgrpc.HTTPRequest", 1) + `,`,
 		`StatsEnabled:` + fmt.Sprintf("%v", this.StatsEnabled) + `,`,
 		`AdditionalQueueDimensions:` + fmt.Sprintf("%v", this.AdditionalQueueDimensions) + `,`,
+		`Priority:` + fmt.Sprintf("%v", this.Priority) + `,`,
+		`DeadlineUnixMillis:` + fmt.Sprintf("%v", this.DeadlineUnixMillis) + `,`,
 		`}`,
 	}, "")
 	return s
@@ -2054,6 +2100,44 @@ func (m *FrontendToScheduler) Unmarshal(dAtA []byte) error {
 			}
 			m.AdditionalQueueDimensions = append(m.AdditionalQueueDimensions, string(dAtA[iNdEx:postIndex]))
 			iNdEx = postIndex
+		case 8:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Priority", wireType)
+			}
+			m.Priority = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowScheduler
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Priority |= int32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 9:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field DeadlineUnixMillis", wireType)
+			}
+			m.DeadlineUnixMillis = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowScheduler
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.DeadlineUnixMillis |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
 		default:
 			iNdEx = preIndex
 			skippy, err := skipScheduler(dAtA[iNdEx:])
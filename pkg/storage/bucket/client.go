@@ -112,6 +112,12 @@ func (cfg *StorageBackendConfig) Validate() error {
 		}
 	}
 
+	if cfg.Backend == Filesystem {
+		if err := cfg.Filesystem.Validate(); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -121,6 +127,8 @@ type Config struct {
 
 	StoragePrefix string `yaml:"storage_prefix"`
 
+	Retries RetriesConfig `yaml:"retries"`
+
 	// Not used internally, meant to allow callers to wrap Buckets
 	// created using this config
 	Middlewares []func(objstore.InstrumentedBucket) (objstore.InstrumentedBucket, error) `yaml:"-"`
@@ -134,6 +142,7 @@ func (cfg *Config) RegisterFlags(f *flag.FlagSet) {
 func (cfg *Config) RegisterFlagsWithPrefixAndDefaultDirectory(prefix, dir string, f *flag.FlagSet) {
 	cfg.StorageBackendConfig.RegisterFlagsWithPrefixAndDefaultDirectory(prefix, dir, f)
 	f.StringVar(&cfg.StoragePrefix, prefix+"storage-prefix", "", "Prefix for all objects stored in the backend storage. For simplicity, it may only contain digits and English alphabet letters.")
+	cfg.Retries.RegisterFlagsWithPrefix(prefix+"retries.", f)
 }
 
 func (cfg *Config) RegisterFlagsWithPrefix(prefix string, f *flag.FlagSet) {
@@ -148,6 +157,10 @@ func (cfg *Config) Validate() error {
 		}
 	}
 
+	if err := cfg.Retries.Validate(); err != nil {
+		return err
+	}
+
 	return cfg.StorageBackendConfig.Validate()
 }
 
@@ -181,7 +194,7 @@ func NewClient(ctx context.Context, cfg Config, name string, logger log.Logger,
 		backendClient = NewPrefixedBucketClient(backendClient, cfg.StoragePrefix)
 	}
 
-	instrumentedClient := objstoretracing.WrapWithTraces(bucketWithMetrics(backendClient, name, reg))
+	instrumentedClient := objstoretracing.WrapWithTraces(bucketWithRetries(bucketWithMetrics(backendClient, name, reg), cfg.Retries, name, reg))
 
 	// Wrap the client with any provided middleware
 	for _, wrap := range cfg.Middlewares {
@@ -204,9 +217,5 @@ func bucketWithMetrics(bucketClient objstore.Bucket, name string, reg prometheus
 	reg = prometheus.WrapRegistererWithPrefix("thanos_", reg)
 	reg = prometheus.WrapRegistererWith(prometheus.Labels{"component": name}, reg)
 
-	return objstore.WrapWithMetrics(
-		bucketClient,
-		reg,
-		"", // bucket label value
-	)
+	return newMetricsBucketClient(bucketClient, reg)
 }
@@ -2,15 +2,456 @@
 // Provenance-includes-location: https://github.com/cortexproject/cortex/blob/master/pkg/storage/bucket/filesystem/bucket_client.go
 // Provenance-includes-license: Apache-2.0
 // Provenance-includes-copyright: The Cortex Authors.
+// Provenance-includes-location: https://github.com/thanos-io/objstore/blob/main/providers/filesystem/filesystem.go
+// Provenance-includes-license: Apache-2.0
+// Provenance-includes-copyright: The Thanos Authors.
 
 package filesystem
 
 import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/grafana/dskit/multierror"
+	"github.com/grafana/dskit/runutil"
+	"github.com/pkg/errors"
 	"github.com/thanos-io/objstore"
-	"github.com/thanos-io/objstore/providers/filesystem"
 )
 
+const (
+	// tempFilePrefix is the prefix of the temporary files objects are written to before being renamed
+	// to their final path. Temporary files are never listed.
+	tempFilePrefix = ".upload-"
+
+	// shardedLevels is the number of top-level directories of the bucket whose entries are sharded
+	// when subdirectory sharding is enabled: the tenants, and the blocks of each tenant.
+	shardedLevels = 2
+)
+
+// Bucket implements objstore.Bucket on the local filesystem.
+//
+// Objects are written to a temporary file, optionally fsynced, and atomically renamed to their final path,
+// so that readers never observe partially written objects, even after a crash.
+//
+// When subdirectory sharding is enabled, the entries of the top-level directories (the tenants and their
+// blocks) are distributed across a fixed number of shard subdirectories, selected by hashing the entry name,
+// to keep the number of entries of each directory on disk bounded even when a tenant has a very large number
+// of blocks. The content of the deeper directories, like the files of a block, is not sharded.
+//
+// This is a fork of the objstore filesystem provider (providers/filesystem/filesystem.go), at the objstore
+// version in go.mod, extended with the atomic uploads, fsync and subdirectory sharding. It's not kept in sync
+// automatically: when updating the objstore dependency, review the upstream changes to the filesystem provider
+// and port the relevant ones here.
+type Bucket struct {
+	rootDir string
+	cfg     Config
+}
+
 // NewBucketClient creates a new filesystem bucket client
 func NewBucketClient(cfg Config) (objstore.Bucket, error) {
-	return filesystem.NewBucket(cfg.Directory)
+	absDir, err := filepath.Abs(cfg.Directory)
+	if err != nil {
+		return nil, err
+	}
+	return &Bucket{rootDir: absDir, cfg: cfg}, nil
+}
+
+// shardOf returns the name of the shard subdirectory storing the directory entry with the given name.
+func (b *Bucket) shardOf(entry string) string {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(entry))
+
+	width := len(strconv.Itoa(b.cfg.SubdirectoryShards - 1))
+	return fmt.Sprintf("%0*d", width, h.Sum32()%uint32(b.cfg.SubdirectoryShards))
+}
+
+// path returns the path on disk of the object or directory with the given name.
+func (b *Bucket) path(name string) string {
+	name = strings.Trim(filepath.ToSlash(name), objstore.DirDelim)
+	if name == "" {
+		return b.rootDir
+	}
+	if b.cfg.SubdirectoryShards <= 0 {
+		return filepath.Join(b.rootDir, filepath.FromSlash(name))
+	}
+
+	elems := []string{b.rootDir}
+	for level, entry := range strings.Split(name, objstore.DirDelim) {
+		if level < shardedLevels {
+			elems = append(elems, b.shardOf(entry))
+		}
+		elems = append(elems, entry)
+	}
+	return filepath.Join(elems...)
+}
+
+// isSharded returns whether the entries of the directory with the given name are stored in shard subdirectories.
+func (b *Bucket) isSharded(dir string) bool {
+	if b.cfg.SubdirectoryShards <= 0 {
+		return false
+	}
+
+	dir = strings.Trim(dir, objstore.DirDelim)
+	if dir == "" {
+		return true
+	}
+	return strings.Count(dir, objstore.DirDelim)+1 < shardedLevels
+}
+
+// Iter calls f for each entry in the given directory. The argument to f is the full
+// object name including the prefix of the inspected directory.
+func (b *Bucket) Iter(ctx context.Context, dir string, f func(string) error, options ...objstore.IterOption) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	params := objstore.ApplyIterOptions(options...)
+	if dir != "" && !strings.HasSuffix(dir, objstore.DirDelim) {
+		dir += objstore.DirDelim
+	}
+
+	entries, err := b.readDir(b.path(dir), b.isSharded(dir))
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		name := dir + entry.name
+
+		if entry.isDir {
+			name += objstore.DirDelim
+
+			if params.Recursive {
+				// Recursively list files in the subdirectory.
+				if err := b.Iter(ctx, name, f, options...); err != nil {
+					return err
+				}
+
+				// The callback f() has already been called for the subdirectory
+				// files so we should skip to next filesystem entry.
+				continue
+			}
+		}
+		if err := f(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type dirEntry struct {
+	name  string
+	isDir bool
+}
+
+// readDir returns the sorted entries of the directory at the given path on disk, looking into the shard
+// subdirectories if sharded. Empty directories and temporary files are skipped.
+func (b *Bucket) readDir(absDir string, sharded bool) ([]dirEntry, error) {
+	dirs := []string{absDir}
+	if sharded {
+		shards, err := readDirIfExists(absDir)
+		if err != nil {
+			return nil, err
+		}
+
+		dirs = dirs[:0]
+		for _, shard := range shards {
+			if shard.IsDir() {
+				dirs = append(dirs, filepath.Join(absDir, shard.Name()))
+			}
+		}
+	}
+
+	var entries []dirEntry
+	for _, dir := range dirs {
+		files, err := readDirIfExists(dir)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, file := range files {
+			if strings.HasPrefix(file.Name(), tempFilePrefix) {
+				continue
+			}
+
+			if file.IsDir() {
+				empty, err := isDirEmpty(filepath.Join(dir, file.Name()))
+				if err != nil {
+					return nil, err
+				}
+
+				if empty {
+					// Skip empty directories.
+					continue
+				}
+			}
+
+			entries = append(entries, dirEntry{name: file.Name(), isDir: file.IsDir()})
+		}
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].name < entries[j].name
+	})
+	return entries, nil
+}
+
+func readDirIfExists(dir string) ([]os.DirEntry, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, errors.Wrapf(err, "stat %s", dir)
+	}
+	if !info.IsDir() {
+		return nil, nil
+	}
+
+	return os.ReadDir(dir)
+}
+
+// Get returns a reader for the given object name.
+func (b *Bucket) Get(ctx context.Context, name string) (io.ReadCloser, error) {
+	return b.GetRange(ctx, name, 0, -1)
+}
+
+type rangeReaderCloser struct {
+	io.Reader
+	f *os.File
+}
+
+func (r *rangeReaderCloser) Close() error {
+	return r.f.Close()
+}
+
+// Attributes returns information about the specified object.
+func (b *Bucket) Attributes(ctx context.Context, name string) (objstore.ObjectAttributes, error) {
+	if ctx.Err() != nil {
+		return objstore.ObjectAttributes{}, ctx.Err()
+	}
+
+	file := b.path(name)
+	stat, err := os.Stat(file)
+	if err != nil {
+		return objstore.ObjectAttributes{}, errors.Wrapf(err, "stat %s", file)
+	}
+
+	return objstore.ObjectAttributes{
+		Size:         stat.Size(),
+		LastModified: stat.ModTime(),
+	}, nil
+}
+
+// GetRange returns a new range reader for the given object name and range.
+func (b *Bucket) GetRange(ctx context.Context, name string, off, length int64) (io.ReadCloser, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	if name == "" {
+		return nil, errors.New("object name is empty")
+	}
+
+	file := b.path(name)
+	if _, err := os.Stat(file); err != nil {
+		return nil, errors.Wrapf(err, "stat %s", file)
+	}
+
+	f, err := os.OpenFile(filepath.Clean(file), os.O_RDONLY, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	if off > 0 {
+		_, err := f.Seek(off, 0)
+		if err != nil {
+			_ = f.Close()
+			return nil, errors.Wrapf(err, "seek %v", off)
+		}
+	}
+
+	if length == -1 {
+		return f, nil
+	}
+
+	return &rangeReaderCloser{Reader: io.LimitReader(f, length), f: f}, nil
+}
+
+// Exists checks if the given object exists.
+func (b *Bucket) Exists(ctx context.Context, name string) (bool, error) {
+	if ctx.Err() != nil {
+		return false, ctx.Err()
+	}
+
+	file := b.path(name)
+	info, err := os.Stat(file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, errors.Wrapf(err, "stat %s", file)
+	}
+	return !info.IsDir(), nil
+}
+
+// Upload writes the content of r to a temporary file which is atomically renamed to the object path.
+// If fsync is enabled, the file and all the directories created or modified are fsynced before returning.
+func (b *Bucket) Upload(ctx context.Context, name string, r io.Reader) (err error) {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	file := b.path(name)
+	dir := filepath.Dir(file)
+	if err := b.mkdirAll(dir); err != nil {
+		return err
+	}
+
+	// The temporary file is created in the same directory of the object, because rename can
+	// only operate on two files on the same filesystem.
+	f, err := os.CreateTemp(dir, tempFilePrefix+"*")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+
+	cleanup := true
+	defer func() {
+		if cleanup {
+			_ = os.Remove(tmp)
+		}
+	}()
+
+	if _, err := io.Copy(f, r); err != nil {
+		_ = f.Close()
+		return errors.Wrapf(err, "copy to %s", tmp)
+	}
+
+	merr := multierror.New()
+	if b.cfg.FsyncEnabled {
+		merr.Add(f.Sync())
+	}
+	merr.Add(f.Close())
+	if err := merr.Err(); err != nil {
+		return errors.Wrapf(err, "write %s", tmp)
+	}
+
+	if err := os.Rename(tmp, file); err != nil {
+		return errors.Wrapf(err, "rename %s to %s", tmp, file)
+	}
+	cleanup = false
+
+	// fsync the containing directory to ensure the directory entry of the file is persisted to disk.
+	if b.cfg.FsyncEnabled {
+		return syncDir(dir)
+	}
+	return nil
+}
+
+// mkdirAll creates the directory at the given path on disk along with any necessary parents. If fsync is
+// enabled, the parent of each created directory is fsynced, to persist the directory entries to disk.
+func (b *Bucket) mkdirAll(dir string) error {
+	if !b.cfg.FsyncEnabled {
+		return os.MkdirAll(dir, os.ModePerm)
+	}
+
+	// Find the directories to create, from the deepest one.
+	var missing []string
+	for d := dir; ; d = filepath.Dir(d) {
+		if _, err := os.Stat(d); err == nil {
+			break
+		} else if !os.IsNotExist(err) {
+			return errors.Wrapf(err, "stat %s", d)
+		}
+
+		missing = append(missing, d)
+		if d == filepath.Dir(d) {
+			break
+		}
+	}
+
+	for i := len(missing) - 1; i >= 0; i-- {
+		if err := os.Mkdir(missing[i], os.ModePerm); err != nil && !os.IsExist(err) {
+			return err
+		}
+		if err := syncDir(filepath.Dir(missing[i])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func syncDir(dir string) (err error) {
+	d, err := os.Open(filepath.Clean(dir))
+	if err != nil {
+		return err
+	}
+	defer runutil.CloseWithErrCapture(&err, d, "close dir")
+
+	return errors.Wrapf(d.Sync(), "fsync %s", dir)
+}
+
+func isDirEmpty(name string) (ok bool, err error) {
+	f, err := os.Open(filepath.Clean(name))
+	if os.IsNotExist(err) {
+		// The directory doesn't exist. We don't consider it an error and we treat it like empty.
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	defer runutil.CloseWithErrCapture(&err, f, "close dir")
+
+	if _, err = f.Readdir(1); err == io.EOF || os.IsNotExist(err) {
+		return true, nil
+	}
+	return false, err
+}
+
+// Delete removes all data prefixed with the name, and the directories left empty.
+func (b *Bucket) Delete(ctx context.Context, name string) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	file := b.path(name)
+	for file != b.rootDir {
+		if err := os.RemoveAll(file); err != nil {
+			return errors.Wrapf(err, "rm %s", file)
+		}
+		file = filepath.Dir(file)
+		empty, err := isDirEmpty(file)
+		if err != nil {
+			return err
+		}
+		if !empty {
+			break
+		}
+	}
+	return nil
+}
+
+// IsObjNotFoundErr returns true if error means that object is not found. Relevant to Get operations.
+func (b *Bucket) IsObjNotFoundErr(err error) bool {
+	return os.IsNotExist(errors.Cause(err))
+}
+
+// IsAccessDeniedErr returns true if access to object is denied.
+func (b *Bucket) IsAccessDeniedErr(_ error) bool {
+	return false
+}
+
+func (b *Bucket) Close() error { return nil }
+
+// Name returns the bucket name.
+func (b *Bucket) Name() string {
+	return fmt.Sprintf("fs: %s", b.rootDir)
 }
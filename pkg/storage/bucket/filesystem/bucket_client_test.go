@@ -0,0 +1,200 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package filesystem
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/objstore"
+)
+
+func TestBucket(t *testing.T) {
+	for _, cfg := range []Config{
+		{},
+		{FsyncEnabled: true},
+		{SubdirectoryShards: 16},
+		{SubdirectoryShards: 16, FsyncEnabled: true},
+	} {
+		t.Run(fmt.Sprintf("fsync enabled: %t, subdirectory shards: %d", cfg.FsyncEnabled, cfg.SubdirectoryShards), func(t *testing.T) {
+			ctx := context.Background()
+
+			cfg.Directory = t.TempDir()
+			bkt, err := NewBucketClient(cfg)
+			require.NoError(t, err)
+
+			objects := []string{
+				"user-1/block-1/chunks/000001",
+				"user-1/block-1/index",
+				"user-1/block-1/meta.json",
+				"user-1/block-2/meta.json",
+				"user-1/markers/block-2-deletion-mark.json",
+				"user-2/bucket-index.json.gz",
+			}
+			for _, name := range objects {
+				require.NoError(t, bkt.Upload(ctx, name, strings.NewReader("content of "+name)))
+			}
+
+			// Read the objects back.
+			for _, name := range objects {
+				exists, err := bkt.Exists(ctx, name)
+				require.NoError(t, err)
+				assert.True(t, exists)
+
+				attrs, err := bkt.Attributes(ctx, name)
+				require.NoError(t, err)
+				assert.Equal(t, int64(len("content of "+name)), attrs.Size)
+
+				assert.Equal(t, "content of "+name, readObject(t, bkt, name))
+			}
+
+			r, err := bkt.GetRange(ctx, "user-1/block-1/index", 3, 7)
+			require.NoError(t, err)
+			content, err := io.ReadAll(r)
+			require.NoError(t, err)
+			require.NoError(t, r.Close())
+			assert.Equal(t, "tent of", string(content))
+
+			// Directories are not objects.
+			exists, err := bkt.Exists(ctx, "user-1/block-1")
+			require.NoError(t, err)
+			assert.False(t, exists)
+
+			_, err = bkt.Get(ctx, "user-1/block-3/meta.json")
+			assert.True(t, bkt.IsObjNotFoundErr(err))
+
+			// Overwriting an object replaces its content.
+			require.NoError(t, bkt.Upload(ctx, "user-2/bucket-index.json.gz", strings.NewReader("updated")))
+			assert.Equal(t, "updated", readObject(t, bkt, "user-2/bucket-index.json.gz"))
+
+			// List the objects.
+			assert.Equal(t, []string{"user-1/", "user-2/"}, listObjects(t, bkt, ""))
+			assert.Equal(t, []string{"user-1/block-1/", "user-1/block-2/", "user-1/markers/"}, listObjects(t, bkt, "user-1"))
+			assert.Equal(t, []string{"user-1/block-1/", "user-1/block-2/", "user-1/markers/"}, listObjects(t, bkt, "user-1/"))
+			assert.Equal(t, []string{"user-1/block-1/chunks/", "user-1/block-1/index", "user-1/block-1/meta.json"}, listObjects(t, bkt, "user-1/block-1/"))
+			assert.Equal(t, objects, listObjects(t, bkt, "", objstore.WithRecursiveIter))
+			assert.Empty(t, listObjects(t, bkt, "user-3/"))
+
+			// Delete an object, and a directory.
+			require.NoError(t, bkt.Delete(ctx, "user-1/block-2/meta.json"))
+			require.NoError(t, bkt.Delete(ctx, "user-1/block-1"))
+			assert.Equal(t, []string{"user-1/markers/"}, listObjects(t, bkt, "user-1/"))
+
+			require.NoError(t, bkt.Delete(ctx, "user-1/markers/block-2-deletion-mark.json"))
+			require.NoError(t, bkt.Delete(ctx, "user-2/bucket-index.json.gz"))
+			assert.Empty(t, listObjects(t, bkt, "", objstore.WithRecursiveIter))
+
+			// The directories left empty have been removed.
+			entries, err := os.ReadDir(cfg.Directory)
+			require.NoError(t, err)
+			assert.Empty(t, entries)
+		})
+	}
+}
+
+func TestBucket_SubdirectoryShards(t *testing.T) {
+	const shards = 4
+
+	ctx := context.Background()
+	dir := t.TempDir()
+	bkt, err := NewBucketClient(Config{Directory: dir, SubdirectoryShards: shards})
+	require.NoError(t, err)
+
+	var objects, blocks []string
+	for i := 0; i < 100; i++ {
+		block := fmt.Sprintf("user-1/block-%03d/", i)
+		blocks = append(blocks, block)
+
+		for _, file := range []string{"chunks/000001", "index", "meta.json"} {
+			name := block + file
+			objects = append(objects, name)
+			require.NoError(t, bkt.Upload(ctx, name, strings.NewReader(name)))
+		}
+	}
+
+	// The blocks are spread across the shard subdirectories of the tenant.
+	tenantDir := bkt.(*Bucket).path("user-1")
+	shardDirs, err := os.ReadDir(tenantDir)
+	require.NoError(t, err)
+	require.Len(t, shardDirs, shards)
+
+	for _, shardDir := range shardDirs {
+		require.True(t, shardDir.IsDir())
+
+		entries, err := os.ReadDir(filepath.Join(tenantDir, shardDir.Name()))
+		require.NoError(t, err)
+		assert.NotEmpty(t, entries)
+		assert.Less(t, len(entries), len(blocks))
+	}
+
+	// The files of a block are not sharded.
+	blockDir := bkt.(*Bucket).path("user-1/block-000")
+	entries, err := os.ReadDir(blockDir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 3)
+	assert.Equal(t, filepath.Join(blockDir, "chunks", "000001"), bkt.(*Bucket).path("user-1/block-000/chunks/000001"))
+
+	assert.Equal(t, blocks, listObjects(t, bkt, "user-1/"))
+	assert.Equal(t, objects, listObjects(t, bkt, "user-1/", objstore.WithRecursiveIter))
+}
+
+func TestBucket_Upload_ShouldNotListTemporaryFiles(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	bkt, err := NewBucketClient(Config{Directory: dir})
+	require.NoError(t, err)
+
+	// Simulate an upload which failed while writing the temporary file.
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "user-1"), os.ModePerm))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "user-1", tempFilePrefix+"123"), []byte("partial"), 0600))
+
+	assert.Empty(t, listObjects(t, bkt, "user-1/"))
+
+	// A failing upload doesn't leave any file.
+	require.Error(t, bkt.Upload(ctx, "user-1/object", failingReader{}))
+	exists, err := bkt.Exists(ctx, "user-1/object")
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	entries, err := os.ReadDir(filepath.Join(dir, "user-1"))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
+
+func TestConfig_Validate(t *testing.T) {
+	assert.NoError(t, (&Config{SubdirectoryShards: 0}).Validate())
+	assert.NoError(t, (&Config{SubdirectoryShards: 256}).Validate())
+	assert.ErrorIs(t, (&Config{SubdirectoryShards: -1}).Validate(), errNegativeSubdirectoryShards)
+}
+
+func readObject(t *testing.T, bkt objstore.Bucket, name string) string {
+	r, err := bkt.Get(context.Background(), name)
+	require.NoError(t, err)
+	defer func() { require.NoError(t, r.Close()) }()
+
+	content, err := io.ReadAll(r)
+	require.NoError(t, err)
+	return string(content)
+}
+
+func listObjects(t *testing.T, bkt objstore.Bucket, dir string, options ...objstore.IterOption) []string {
+	var names []string
+	require.NoError(t, bkt.Iter(context.Background(), dir, func(name string) error {
+		names = append(names, name)
+		return nil
+	}, options...))
+	return names
+}
+
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) {
+	return 0, fmt.Errorf("read failed")
+}
@@ -5,11 +5,18 @@
 
 package filesystem
 
-import "flag"
+import (
+	"errors"
+	"flag"
+)
+
+var errNegativeSubdirectoryShards = errors.New("the number of filesystem subdirectory shards must be greater than or equal to 0")
 
 // Config stores the configuration for storing and accessing objects in the local filesystem.
 type Config struct {
-	Directory string `yaml:"dir"`
+	Directory          string `yaml:"dir"`
+	FsyncEnabled       bool   `yaml:"fsync_enabled" category:"experimental"`
+	SubdirectoryShards int    `yaml:"subdirectory_shards" category:"experimental"`
 }
 
 // RegisterFlags registers the flags for filesystem storage
@@ -21,9 +28,19 @@ func (cfg *Config) RegisterFlags(f *flag.FlagSet) {
 // storage with the provided prefix and sets the default directory to dir.
 func (cfg *Config) RegisterFlagsWithPrefixAndDefaultDirectory(prefix, dir string, f *flag.FlagSet) {
 	f.StringVar(&cfg.Directory, prefix+"filesystem.dir", dir, "Local filesystem storage directory.")
+	f.BoolVar(&cfg.FsyncEnabled, prefix+"filesystem.fsync-enabled", false, "Fsync the uploaded objects and their directories before reporting the upload as successful, so that uploaded objects are not lost on crashes or power failures.")
+	f.IntVar(&cfg.SubdirectoryShards, prefix+"filesystem.subdirectory-shards", 0, "If greater than 0, the tenants, and the blocks of each tenant, are distributed across this number of subdirectories, selected by hashing their name, to avoid directories with millions of entries. Changing this value makes the objects previously stored unreachable. 0 to disable.")
 }
 
 // RegisterFlagsWithPrefix registers the flags for filesystem storage with the provided prefix
 func (cfg *Config) RegisterFlagsWithPrefix(prefix string, f *flag.FlagSet) {
 	cfg.RegisterFlagsWithPrefixAndDefaultDirectory(prefix, "", f)
 }
+
+// Validate config and returns error on failure.
+func (cfg *Config) Validate() error {
+	if cfg.SubdirectoryShards < 0 {
+		return errNegativeSubdirectoryShards
+	}
+	return nil
+}
@@ -0,0 +1,348 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+// Provenance-includes-location: https://github.com/thanos-io/objstore/blob/main/objstore.go
+// Provenance-includes-license: Apache-2.0
+// Provenance-includes-copyright: The Thanos Authors.
+
+package bucket
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/grafana/dskit/instrument"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/thanos-io/objstore"
+)
+
+const (
+	operationStatusSuccess   = "success"
+	operationStatusNotFound  = "not_found"
+	operationStatusCancelled = "cancelled"
+	operationStatusError     = "error"
+)
+
+// bucketMetrics are the metrics of the bucket operations. They're the same as the ones tracked by
+// objstore.WrapWithMetrics, except that the duration of the operations is tracked by status too,
+// and the observations have the trace ID as exemplar if the operation is part of a sampled trace.
+type bucketMetrics struct {
+	ops                      *prometheus.CounterVec
+	opsFailures              *prometheus.CounterVec
+	opsFetchedBytes          *prometheus.CounterVec
+	opsTransferredBytes      *prometheus.HistogramVec
+	opsDuration              *prometheus.HistogramVec
+	lastSuccessfulUploadTime prometheus.Gauge
+}
+
+func newBucketMetrics(reg prometheus.Registerer) *bucketMetrics {
+	m := &bucketMetrics{
+		ops: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "objstore_bucket_operations_total",
+			Help: "Total number of all attempted operations against a bucket.",
+		}, []string{"operation"}),
+		opsFailures: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "objstore_bucket_operation_failures_total",
+			Help: "Total number of operations against a bucket that failed, but were not expected to fail in certain way from caller perspective. Those errors have to be investigated.",
+		}, []string{"operation"}),
+		opsFetchedBytes: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "objstore_bucket_operation_fetched_bytes_total",
+			Help: "Total number of bytes fetched from bucket, per operation.",
+		}, []string{"operation"}),
+		opsTransferredBytes: promauto.With(reg).NewHistogramVec(prometheus.HistogramOpts{
+			Name:                            "objstore_bucket_operation_transferred_bytes",
+			Help:                            "Number of bytes transferred from/to bucket per operation.",
+			Buckets:                         prometheus.ExponentialBuckets(2<<14, 2, 16), // 32KiB, 64KiB, ... 1GiB
+			NativeHistogramBucketFactor:     2,
+			NativeHistogramMaxBucketNumber:  100,
+			NativeHistogramMinResetDuration: 1 * time.Hour,
+		}, []string{"operation"}),
+		opsDuration: promauto.With(reg).NewHistogramVec(prometheus.HistogramOpts{
+			Name:                            "objstore_bucket_operation_duration_seconds",
+			Help:                            "Duration of operations against the bucket per operation and status - iter operations include time spent on each callback, get and get_range operations include the time spent reading the object.",
+			Buckets:                         []float64{0.001, 0.01, 0.1, 0.3, 0.6, 1, 3, 6, 9, 20, 30, 60, 90, 120},
+			NativeHistogramBucketFactor:     1.1,
+			NativeHistogramMaxBucketNumber:  100,
+			NativeHistogramMinResetDuration: 1 * time.Hour,
+		}, []string{"operation", "status"}),
+		lastSuccessfulUploadTime: promauto.With(reg).NewGauge(prometheus.GaugeOpts{
+			Name: "objstore_bucket_last_successful_upload_time",
+			Help: "Second timestamp of the last successful upload to the bucket.",
+		}),
+	}
+
+	for _, op := range []string{objstore.OpIter, objstore.OpGet, objstore.OpGetRange, objstore.OpExists, objstore.OpUpload, objstore.OpDelete, objstore.OpAttributes} {
+		m.ops.WithLabelValues(op)
+		m.opsFailures.WithLabelValues(op)
+		m.opsDuration.WithLabelValues(op, operationStatusSuccess)
+		m.opsFetchedBytes.WithLabelValues(op)
+	}
+	for _, op := range []string{objstore.OpGet, objstore.OpGetRange, objstore.OpUpload} {
+		m.opsTransferredBytes.WithLabelValues(op)
+	}
+
+	return m
+}
+
+// metricsBucketClient tracks the metrics of the operations run against the wrapped bucket.
+type metricsBucketClient struct {
+	bkt                 objstore.Bucket
+	metrics             *bucketMetrics
+	isOpFailureExpected objstore.IsOpFailureExpectedFunc
+}
+
+func newMetricsBucketClient(bkt objstore.Bucket, reg prometheus.Registerer) *metricsBucketClient {
+	return &metricsBucketClient{
+		bkt:                 bkt,
+		metrics:             newBucketMetrics(reg),
+		isOpFailureExpected: func(error) bool { return false },
+	}
+}
+
+// WithExpectedErrs implements objstore.InstrumentedBucket.
+func (b *metricsBucketClient) WithExpectedErrs(fn objstore.IsOpFailureExpectedFunc) objstore.Bucket {
+	return &metricsBucketClient{bkt: b.bkt, metrics: b.metrics, isOpFailureExpected: fn}
+}
+
+// ReaderWithExpectedErrs implements objstore.InstrumentedBucketReader.
+func (b *metricsBucketClient) ReaderWithExpectedErrs(fn objstore.IsOpFailureExpectedFunc) objstore.BucketReader {
+	return b.WithExpectedErrs(fn)
+}
+
+// done tracks the failure, if any, and the duration of an operation.
+func (b *metricsBucketClient) done(ctx context.Context, op string, start time.Time, err error) {
+	if err != nil && !b.isOpFailureExpected(err) && !errors.Is(ctx.Err(), context.Canceled) {
+		b.metrics.opsFailures.WithLabelValues(op).Inc()
+	}
+	b.observeDuration(ctx, op, start, err)
+}
+
+func (b *metricsBucketClient) observeDuration(ctx context.Context, op string, start time.Time, err error) {
+	instrument.ObserveWithExemplar(ctx, b.metrics.opsDuration.WithLabelValues(op, b.status(ctx, err)), time.Since(start).Seconds())
+}
+
+func (b *metricsBucketClient) status(ctx context.Context, err error) string {
+	switch {
+	case err == nil:
+		return operationStatusSuccess
+	case b.bkt.IsObjNotFoundErr(err):
+		return operationStatusNotFound
+	case errors.Is(err, context.Canceled) || errors.Is(ctx.Err(), context.Canceled):
+		return operationStatusCancelled
+	default:
+		return operationStatusError
+	}
+}
+
+func (b *metricsBucketClient) Iter(ctx context.Context, dir string, f func(string) error, options ...objstore.IterOption) error {
+	b.metrics.ops.WithLabelValues(objstore.OpIter).Inc()
+
+	start := time.Now()
+	err := b.bkt.Iter(ctx, dir, f, options...)
+	b.done(ctx, objstore.OpIter, start, err)
+	return err
+}
+
+func (b *metricsBucketClient) Attributes(ctx context.Context, name string) (objstore.ObjectAttributes, error) {
+	b.metrics.ops.WithLabelValues(objstore.OpAttributes).Inc()
+
+	start := time.Now()
+	attrs, err := b.bkt.Attributes(ctx, name)
+	b.done(ctx, objstore.OpAttributes, start, err)
+	return attrs, err
+}
+
+func (b *metricsBucketClient) Get(ctx context.Context, name string) (io.ReadCloser, error) {
+	b.metrics.ops.WithLabelValues(objstore.OpGet).Inc()
+
+	start := time.Now()
+	rc, err := b.bkt.Get(ctx, name)
+	if err != nil {
+		b.done(ctx, objstore.OpGet, start, err)
+		return nil, err
+	}
+	return newTimingReader(ctx, b, objstore.OpGet, start, rc), nil
+}
+
+func (b *metricsBucketClient) GetRange(ctx context.Context, name string, off, length int64) (io.ReadCloser, error) {
+	b.metrics.ops.WithLabelValues(objstore.OpGetRange).Inc()
+
+	start := time.Now()
+	rc, err := b.bkt.GetRange(ctx, name, off, length)
+	if err != nil {
+		b.done(ctx, objstore.OpGetRange, start, err)
+		return nil, err
+	}
+	return newTimingReader(ctx, b, objstore.OpGetRange, start, rc), nil
+}
+
+func (b *metricsBucketClient) Exists(ctx context.Context, name string) (bool, error) {
+	b.metrics.ops.WithLabelValues(objstore.OpExists).Inc()
+
+	start := time.Now()
+	ok, err := b.bkt.Exists(ctx, name)
+	b.done(ctx, objstore.OpExists, start, err)
+	return ok, err
+}
+
+func (b *metricsBucketClient) Upload(ctx context.Context, name string, r io.Reader) error {
+	b.metrics.ops.WithLabelValues(objstore.OpUpload).Inc()
+
+	start := time.Now()
+	cr := newCountingReader(r)
+	err := b.bkt.Upload(ctx, name, cr)
+	b.done(ctx, objstore.OpUpload, start, err)
+	if err != nil {
+		return err
+	}
+	b.metrics.opsTransferredBytes.WithLabelValues(objstore.OpUpload).Observe(float64(cr.n))
+	b.metrics.lastSuccessfulUploadTime.SetToCurrentTime()
+	return nil
+}
+
+func (b *metricsBucketClient) Delete(ctx context.Context, name string) error {
+	b.metrics.ops.WithLabelValues(objstore.OpDelete).Inc()
+
+	start := time.Now()
+	err := b.bkt.Delete(ctx, name)
+	b.done(ctx, objstore.OpDelete, start, err)
+	return err
+}
+
+func (b *metricsBucketClient) IsObjNotFoundErr(err error) bool {
+	return b.bkt.IsObjNotFoundErr(err)
+}
+
+func (b *metricsBucketClient) IsAccessDeniedErr(err error) bool {
+	return b.bkt.IsAccessDeniedErr(err)
+}
+
+func (b *metricsBucketClient) Close() error {
+	return b.bkt.Close()
+}
+
+func (b *metricsBucketClient) Name() string {
+	return b.bkt.Name()
+}
+
+// countingReader counts the bytes read from the wrapped reader, preserving its size.
+type countingReader struct {
+	io.Reader
+	n int64
+
+	objSize    int64
+	objSizeErr error
+}
+
+func newCountingReader(r io.Reader) *countingReader {
+	// The size is read upfront, since some readers only return the size of the unread data.
+	objSize, objSizeErr := objstore.TryToGetSize(r)
+	return &countingReader{Reader: r, objSize: objSize, objSizeErr: objSizeErr}
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	r.n += int64(n)
+	return n, err
+}
+
+func (r *countingReader) ObjectSize() (int64, error) {
+	return r.objSize, r.objSizeErr
+}
+
+// timingReader tracks the metrics of a get or get_range operation once the object reader is closed.
+type timingReader struct {
+	io.ReadCloser
+
+	ctx       context.Context
+	bkt       *metricsBucketClient
+	op        string
+	start     time.Time
+	readBytes int64
+	readErr   error
+	closed    bool
+
+	objSize    int64
+	objSizeErr error
+}
+
+func newTimingReader(ctx context.Context, bkt *metricsBucketClient, op string, start time.Time, rc io.ReadCloser) io.ReadCloser {
+	objSize, objSizeErr := objstore.TryToGetSize(rc)
+	r := timingReader{ReadCloser: rc, ctx: ctx, bkt: bkt, op: op, start: start, objSize: objSize, objSizeErr: objSizeErr}
+
+	_, isSeeker := rc.(io.Seeker)
+	_, isReaderAt := rc.(io.ReaderAt)
+	if isSeeker && isReaderAt {
+		return &timingReaderSeekerReaderAt{timingReaderSeeker: timingReaderSeeker{timingReader: r}}
+	}
+	if isSeeker {
+		return &timingReaderSeeker{timingReader: r}
+	}
+	if _, isWriterTo := rc.(io.WriterTo); isWriterTo {
+		return &timingReaderWriterTo{timingReader: r}
+	}
+	return &r
+}
+
+func (r *timingReader) ObjectSize() (int64, error) {
+	return r.objSize, r.objSizeErr
+}
+
+func (r *timingReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.updateMetrics(n, err)
+	return n, err
+}
+
+func (r *timingReader) updateMetrics(n int, err error) {
+	r.bkt.metrics.opsFetchedBytes.WithLabelValues(r.op).Add(float64(n))
+	r.readBytes += int64(n)
+	if r.readErr == nil && err != nil && !errors.Is(err, io.EOF) {
+		r.readErr = err
+	}
+}
+
+func (r *timingReader) Close() error {
+	err := r.ReadCloser.Close()
+	if r.closed {
+		return err
+	}
+	r.closed = true
+
+	opErr := r.readErr
+	if opErr == nil {
+		opErr = err
+	}
+	r.bkt.done(r.ctx, r.op, r.start, opErr)
+	if opErr == nil {
+		r.bkt.metrics.opsTransferredBytes.WithLabelValues(r.op).Observe(float64(r.readBytes))
+	}
+	return err
+}
+
+type timingReaderSeeker struct {
+	timingReader
+}
+
+func (r *timingReaderSeeker) Seek(offset int64, whence int) (int64, error) {
+	return r.ReadCloser.(io.Seeker).Seek(offset, whence)
+}
+
+type timingReaderSeekerReaderAt struct {
+	timingReaderSeeker
+}
+
+func (r *timingReaderSeekerReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	return r.ReadCloser.(io.ReaderAt).ReadAt(p, off)
+}
+
+type timingReaderWriterTo struct {
+	timingReader
+}
+
+func (r *timingReaderWriterTo) WriteTo(w io.Writer) (int64, error) {
+	n, err := r.ReadCloser.(io.WriterTo).WriteTo(w)
+	r.updateMetrics(int(n), err)
+	return n, err
+}
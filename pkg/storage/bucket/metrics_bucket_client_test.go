@@ -0,0 +1,113 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package bucket
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/objstore"
+	"github.com/uber/jaeger-client-go"
+)
+
+func TestMetricsBucketClient(t *testing.T) {
+	reg := prometheus.NewPedanticRegistry()
+	bkt := bucketWithMetrics(objstore.NewInMemBucket(), "test", reg)
+
+	tracer, closer := jaeger.NewTracer("test", jaeger.NewConstSampler(true), jaeger.NewNullReporter())
+	t.Cleanup(func() { _ = closer.Close() })
+	span, ctx := opentracing.StartSpanFromContextWithTracer(context.Background(), tracer, "test")
+	defer span.Finish()
+
+	require.NoError(t, bkt.Upload(ctx, "object", strings.NewReader("content")))
+
+	// The duration of the get operations is tracked once the object reader is closed.
+	r, err := bkt.Get(ctx, "object")
+	require.NoError(t, err)
+	require.NoError(t, r.Close())
+	_, err = bkt.Get(ctx, "missing")
+	require.Error(t, err)
+
+	canceledCtx, cancel := context.WithCancel(ctx)
+	cancel()
+	require.ErrorIs(t, bkt.Iter(canceledCtx, "", func(string) error { return canceledCtx.Err() }), context.Canceled)
+
+	families, err := reg.Gather()
+	require.NoError(t, err)
+	var duration *dto.MetricFamily
+	for _, f := range families {
+		if f.GetName() == "thanos_objstore_bucket_operation_duration_seconds" {
+			duration = f
+		}
+	}
+	require.NotNil(t, duration)
+
+	type series struct {
+		operation, status string
+	}
+	actual := map[series]uint64{}
+	for _, m := range duration.GetMetric() {
+		// Skip the series initialized when the client is created.
+		if m.GetHistogram().GetSampleCount() == 0 {
+			continue
+		}
+
+		labels := map[string]string{}
+		for _, l := range m.GetLabel() {
+			labels[l.GetName()] = l.GetValue()
+		}
+		assert.Equal(t, "test", labels["component"])
+		actual[series{labels["operation"], labels["status"]}] = m.GetHistogram().GetSampleCount()
+
+		// All operations are part of a sampled trace, so they all have an exemplar.
+		traceID := span.Context().(jaeger.SpanContext).TraceID().String()
+		assert.Contains(t, exemplarTraceIDs(m.GetHistogram()), traceID)
+	}
+
+	assert.Equal(t, map[series]uint64{
+		{objstore.OpUpload, operationStatusSuccess}: 1,
+		{objstore.OpGet, operationStatusSuccess}:    1,
+		{objstore.OpGet, operationStatusNotFound}:   1,
+		{objstore.OpIter, operationStatusCancelled}: 1,
+	}, actual)
+
+	// The failures of canceled operations are not tracked.
+	assert.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(`
+		# HELP thanos_objstore_bucket_operation_failures_total Total number of operations against a bucket that failed, but were not expected to fail in certain way from caller perspective. Those errors have to be investigated.
+		# TYPE thanos_objstore_bucket_operation_failures_total counter
+		thanos_objstore_bucket_operation_failures_total{component="test",operation="attributes"} 0
+		thanos_objstore_bucket_operation_failures_total{component="test",operation="delete"} 0
+		thanos_objstore_bucket_operation_failures_total{component="test",operation="exists"} 0
+		thanos_objstore_bucket_operation_failures_total{component="test",operation="get"} 1
+		thanos_objstore_bucket_operation_failures_total{component="test",operation="get_range"} 0
+		thanos_objstore_bucket_operation_failures_total{component="test",operation="iter"} 0
+		thanos_objstore_bucket_operation_failures_total{component="test",operation="upload"} 0
+	`), "thanos_objstore_bucket_operation_failures_total"))
+}
+
+func exemplarTraceIDs(h *dto.Histogram) []string {
+	var exemplars []*dto.Exemplar
+	exemplars = append(exemplars, h.GetExemplars()...)
+	for _, b := range h.GetBucket() {
+		if b.GetExemplar() != nil {
+			exemplars = append(exemplars, b.GetExemplar())
+		}
+	}
+
+	var ids []string
+	for _, e := range exemplars {
+		for _, l := range e.GetLabel() {
+			if l.GetName() == "trace_id" {
+				ids = append(ids, l.GetValue())
+			}
+		}
+	}
+	return ids
+}
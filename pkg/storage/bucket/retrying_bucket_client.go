@@ -0,0 +1,301 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package bucket
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/grafana/dskit/backoff"
+	"github.com/minio/minio-go/v7"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/thanos-io/objstore"
+	"google.golang.org/api/googleapi"
+)
+
+var errInvalidRetryPolicyBackoff = errors.New("the retry policy min backoff must be greater than 0 and less than or equal to the max backoff")
+
+// RetryPolicyConfig configures the retries of a type of bucket operation.
+type RetryPolicyConfig struct {
+	MaxRetries int           `yaml:"max_retries" category:"experimental"`
+	MinBackoff time.Duration `yaml:"min_backoff" category:"experimental"`
+	MaxBackoff time.Duration `yaml:"max_backoff" category:"experimental"`
+}
+
+func (cfg *RetryPolicyConfig) RegisterFlagsWithPrefix(prefix, operations string, f *flag.FlagSet) {
+	f.IntVar(&cfg.MaxRetries, prefix+"max-retries", 0, fmt.Sprintf("Maximum number of times a failed %s operation is retried by Mimir, on top of the retries done by the backend client. 0 to disable.", operations))
+	f.DurationVar(&cfg.MinBackoff, prefix+"min-backoff", 100*time.Millisecond, fmt.Sprintf("Minimum backoff between retries of a failed %s operation.", operations))
+	f.DurationVar(&cfg.MaxBackoff, prefix+"max-backoff", 10*time.Second, fmt.Sprintf("Maximum backoff between retries of a failed %s operation.", operations))
+}
+
+func (cfg *RetryPolicyConfig) Validate() error {
+	if cfg.MaxRetries > 0 && (cfg.MinBackoff <= 0 || cfg.MinBackoff > cfg.MaxBackoff) {
+		return errInvalidRetryPolicyBackoff
+	}
+	return nil
+}
+
+func (cfg RetryPolicyConfig) enabled() bool {
+	return cfg.MaxRetries > 0
+}
+
+// RetriesConfig configures the retries of bucket operations, by operation type.
+type RetriesConfig struct {
+	Get    RetryPolicyConfig `yaml:"get"`
+	List   RetryPolicyConfig `yaml:"list"`
+	Upload RetryPolicyConfig `yaml:"upload"`
+}
+
+func (cfg *RetriesConfig) RegisterFlagsWithPrefix(prefix string, f *flag.FlagSet) {
+	cfg.Get.RegisterFlagsWithPrefix(prefix+"get.", "get, get range, exists or attributes", f)
+	cfg.List.RegisterFlagsWithPrefix(prefix+"list.", "list", f)
+	cfg.Upload.RegisterFlagsWithPrefix(prefix+"upload.", "upload", f)
+}
+
+func (cfg *RetriesConfig) Validate() error {
+	for _, policy := range []RetryPolicyConfig{cfg.Get, cfg.List, cfg.Upload} {
+		if err := policy.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (cfg RetriesConfig) enabled() bool {
+	return cfg.Get.enabled() || cfg.List.enabled() || cfg.Upload.enabled()
+}
+
+type retryMetrics struct {
+	retries   *prometheus.CounterVec
+	throttles *prometheus.CounterVec
+}
+
+func newRetryMetrics(name string, reg prometheus.Registerer) *retryMetrics {
+	reg = prometheus.WrapRegistererWith(prometheus.Labels{"component": name}, reg)
+
+	return &retryMetrics{
+		retries: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "cortex_bucket_operation_retries_total",
+			Help: "Total number of bucket operations retried after a failure.",
+		}, []string{"operation"}),
+		throttles: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "cortex_bucket_operation_throttles_total",
+			Help: "Total number of bucket operations failed because throttled by the object storage.",
+		}, []string{"operation"}),
+	}
+}
+
+// retryingBucketClient retries the failed bucket operations according to the retry policy
+// configured for their type.
+type retryingBucketClient struct {
+	wrapped objstore.Bucket
+	cfg     RetriesConfig
+	metrics *retryMetrics
+}
+
+func bucketWithRetries(bucketClient objstore.Bucket, cfg RetriesConfig, name string, reg prometheus.Registerer) objstore.Bucket {
+	if !cfg.enabled() {
+		return bucketClient
+	}
+
+	return &retryingBucketClient{
+		wrapped: bucketClient,
+		cfg:     cfg,
+		metrics: newRetryMetrics(name, reg),
+	}
+}
+
+// retry calls f until it succeeds, returns a non-retryable error or the retries are exhausted.
+func (b *retryingBucketClient) retry(ctx context.Context, policy RetryPolicyConfig, op string, f func() error) error {
+	if !policy.enabled() {
+		return f()
+	}
+
+	boff := backoff.New(ctx, backoff.Config{
+		MinBackoff: policy.MinBackoff,
+		MaxBackoff: policy.MaxBackoff,
+	})
+
+	for {
+		err := f()
+		if err == nil {
+			return nil
+		}
+
+		var permanent permanentError
+		if errors.As(err, &permanent) {
+			return permanent.err
+		}
+		if !b.isRetryable(ctx, err) {
+			return err
+		}
+
+		if isThrottlingErr(err) {
+			b.metrics.throttles.WithLabelValues(op).Inc()
+		}
+		if boff.NumRetries() >= policy.MaxRetries {
+			return err
+		}
+
+		b.metrics.retries.WithLabelValues(op).Inc()
+		boff.Wait()
+		if ctx.Err() != nil {
+			return err
+		}
+	}
+}
+
+// permanentError wraps an error which must not be retried.
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (b *retryingBucketClient) isRetryable(ctx context.Context, err error) bool {
+	return ctx.Err() == nil && !b.wrapped.IsObjNotFoundErr(err) && !b.wrapped.IsAccessDeniedErr(err)
+}
+
+// isThrottlingErr returns whether the error has been returned because the object storage
+// is throttling the requests.
+func isThrottlingErr(err error) bool {
+	isThrottlingStatusCode := func(code int) bool {
+		return code == http.StatusTooManyRequests || code == http.StatusServiceUnavailable
+	}
+
+	var s3Err minio.ErrorResponse
+	if errors.As(err, &s3Err) {
+		return s3Err.Code == "SlowDown" || isThrottlingStatusCode(s3Err.StatusCode)
+	}
+
+	var gcsErr *googleapi.Error
+	if errors.As(err, &gcsErr) {
+		return isThrottlingStatusCode(gcsErr.Code)
+	}
+
+	var azureErr *azcore.ResponseError
+	if errors.As(err, &azureErr) {
+		return isThrottlingStatusCode(azureErr.StatusCode)
+	}
+
+	return false
+}
+
+func (b *retryingBucketClient) Upload(ctx context.Context, name string, r io.Reader) error {
+	// The upload can only be retried if the reader can be rewound.
+	seeker, ok := r.(io.Seeker)
+	if !ok {
+		return b.wrapped.Upload(ctx, name, r)
+	}
+
+	start, err := seeker.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return b.wrapped.Upload(ctx, name, r)
+	}
+
+	attempt := 0
+	return b.retry(ctx, b.cfg.Upload, objstore.OpUpload, func() error {
+		if attempt++; attempt > 1 {
+			if _, err := seeker.Seek(start, io.SeekStart); err != nil {
+				return permanentError{err: err}
+			}
+		}
+		return b.wrapped.Upload(ctx, name, r)
+	})
+}
+
+func (b *retryingBucketClient) Delete(ctx context.Context, name string) error {
+	return b.wrapped.Delete(ctx, name)
+}
+
+func (b *retryingBucketClient) Name() string {
+	return b.wrapped.Name()
+}
+
+func (b *retryingBucketClient) Iter(ctx context.Context, dir string, f func(string) error, options ...objstore.IterOption) error {
+	// The listing can only be retried as long as no entry has been passed to f yet,
+	// otherwise f would be called multiple times for the same entries.
+	called := false
+
+	return b.retry(ctx, b.cfg.List, objstore.OpIter, func() error {
+		err := b.wrapped.Iter(ctx, dir, func(name string) error {
+			called = true
+			return f(name)
+		}, options...)
+
+		if err != nil && called {
+			return permanentError{err: err}
+		}
+		return err
+	})
+}
+
+func (b *retryingBucketClient) Get(ctx context.Context, name string) (io.ReadCloser, error) {
+	var r io.ReadCloser
+	err := b.retry(ctx, b.cfg.Get, objstore.OpGet, func() (err error) {
+		r, err = b.wrapped.Get(ctx, name)
+		return err
+	})
+	return r, err
+}
+
+func (b *retryingBucketClient) GetRange(ctx context.Context, name string, off, length int64) (io.ReadCloser, error) {
+	var r io.ReadCloser
+	err := b.retry(ctx, b.cfg.Get, objstore.OpGetRange, func() (err error) {
+		r, err = b.wrapped.GetRange(ctx, name, off, length)
+		return err
+	})
+	return r, err
+}
+
+func (b *retryingBucketClient) Exists(ctx context.Context, name string) (bool, error) {
+	var exists bool
+	err := b.retry(ctx, b.cfg.Get, objstore.OpExists, func() (err error) {
+		exists, err = b.wrapped.Exists(ctx, name)
+		return err
+	})
+	return exists, err
+}
+
+func (b *retryingBucketClient) Attributes(ctx context.Context, name string) (objstore.ObjectAttributes, error) {
+	var attrs objstore.ObjectAttributes
+	err := b.retry(ctx, b.cfg.Get, objstore.OpAttributes, func() (err error) {
+		attrs, err = b.wrapped.Attributes(ctx, name)
+		return err
+	})
+	return attrs, err
+}
+
+func (b *retryingBucketClient) IsObjNotFoundErr(err error) bool {
+	return b.wrapped.IsObjNotFoundErr(err)
+}
+
+func (b *retryingBucketClient) IsAccessDeniedErr(err error) bool {
+	return b.wrapped.IsAccessDeniedErr(err)
+}
+
+func (b *retryingBucketClient) Close() error {
+	return b.wrapped.Close()
+}
+
+// WithExpectedErrs implements objstore.InstrumentedBucket.
+func (b *retryingBucketClient) WithExpectedErrs(fn objstore.IsOpFailureExpectedFunc) objstore.Bucket {
+	if ib, ok := b.wrapped.(objstore.InstrumentedBucket); ok {
+		return &retryingBucketClient{wrapped: ib.WithExpectedErrs(fn), cfg: b.cfg, metrics: b.metrics}
+	}
+	return b
+}
+
+// ReaderWithExpectedErrs implements objstore.InstrumentedBucketReader.
+func (b *retryingBucketClient) ReaderWithExpectedErrs(fn objstore.IsOpFailureExpectedFunc) objstore.BucketReader {
+	return b.WithExpectedErrs(fn)
+}
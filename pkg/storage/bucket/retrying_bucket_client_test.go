@@ -0,0 +1,166 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package bucket
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/objstore"
+)
+
+func TestRetriesConfig_Validate(t *testing.T) {
+	cfg := RetriesConfig{}
+	require.NoError(t, cfg.Validate())
+
+	cfg.Get = RetryPolicyConfig{MaxRetries: 3, MinBackoff: time.Second, MaxBackoff: 10 * time.Second}
+	require.NoError(t, cfg.Validate())
+
+	cfg.List = RetryPolicyConfig{MaxRetries: 3, MinBackoff: time.Minute, MaxBackoff: time.Second}
+	require.Equal(t, errInvalidRetryPolicyBackoff, cfg.Validate())
+
+	// Invalid backoffs are ignored if retries are disabled.
+	cfg.List.MaxRetries = 0
+	require.NoError(t, cfg.Validate())
+}
+
+func TestRetryingBucketClient(t *testing.T) {
+	policy := RetryPolicyConfig{MaxRetries: 2, MinBackoff: time.Millisecond, MaxBackoff: time.Millisecond}
+	throttled := minio.ErrorResponse{Code: "SlowDown", StatusCode: 503}
+
+	// failFirst returns an injector failing the first n calls.
+	failFirst := func(n int, err error) (func(Operation, string) error, *int) {
+		calls := 0
+		return func(Operation, string) error {
+			calls++
+			if calls <= n {
+				return err
+			}
+			return nil
+		}, &calls
+	}
+
+	setup := func(t *testing.T, injector func(Operation, string) error) (objstore.Bucket, objstore.Bucket, *prometheus.Registry) {
+		inmem := objstore.NewInMemBucket()
+		require.NoError(t, inmem.Upload(context.Background(), "dir/object", strings.NewReader("content")))
+
+		reg := prometheus.NewPedanticRegistry()
+		cfg := RetriesConfig{Get: policy, List: policy, Upload: policy}
+		return inmem, bucketWithRetries(&ErrorInjectedBucketClient{Bucket: inmem, Injector: injector}, cfg, "test", reg), reg
+	}
+
+	t.Run("should retry failed operations until they succeed", func(t *testing.T) {
+		injector, calls := failFirst(2, throttled)
+		_, bkt, reg := setup(t, injector)
+
+		r, err := bkt.Get(context.Background(), "dir/object")
+		require.NoError(t, err)
+		content, err := io.ReadAll(r)
+		require.NoError(t, err)
+		assert.Equal(t, "content", string(content))
+		assert.Equal(t, 3, *calls)
+
+		assert.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(`
+			# HELP cortex_bucket_operation_retries_total Total number of bucket operations retried after a failure.
+			# TYPE cortex_bucket_operation_retries_total counter
+			cortex_bucket_operation_retries_total{component="test",operation="get"} 2
+			# HELP cortex_bucket_operation_throttles_total Total number of bucket operations failed because throttled by the object storage.
+			# TYPE cortex_bucket_operation_throttles_total counter
+			cortex_bucket_operation_throttles_total{component="test",operation="get"} 2
+		`)))
+	})
+
+	t.Run("should give up once the max retries are reached", func(t *testing.T) {
+		injector, calls := failFirst(10, errors.New("connection reset"))
+		_, bkt, _ := setup(t, injector)
+
+		_, err := bkt.Exists(context.Background(), "dir/object")
+		require.EqualError(t, err, "connection reset")
+		assert.Equal(t, 3, *calls)
+	})
+
+	t.Run("should not retry an object not found error", func(t *testing.T) {
+		_, bkt, _ := setup(t, nil)
+
+		_, err := bkt.Get(context.Background(), "missing")
+		require.True(t, bkt.IsObjNotFoundErr(err))
+	})
+
+	t.Run("should rewind the reader when retrying an upload", func(t *testing.T) {
+		inmem := objstore.NewInMemBucket()
+		bkt := bucketWithRetries(&partialUploadBucketClient{Bucket: inmem, failures: 1}, RetriesConfig{Upload: policy}, "test", nil)
+
+		require.NoError(t, bkt.Upload(context.Background(), "dir/uploaded", bytes.NewReader([]byte("uploaded"))))
+
+		r, err := inmem.Get(context.Background(), "dir/uploaded")
+		require.NoError(t, err)
+		content, err := io.ReadAll(r)
+		require.NoError(t, err)
+		assert.Equal(t, "uploaded", string(content))
+	})
+
+	t.Run("should not retry an upload whose reader can't be rewound", func(t *testing.T) {
+		injector, calls := failFirst(1, errors.New("connection reset"))
+		_, bkt, _ := setup(t, injector)
+
+		require.Error(t, bkt.Upload(context.Background(), "dir/uploaded", io.MultiReader(strings.NewReader("uploaded"))))
+		assert.Equal(t, 1, *calls)
+	})
+
+	t.Run("should retry a listing failed before any entry has been returned", func(t *testing.T) {
+		injector, calls := failFirst(1, errors.New("connection reset"))
+		_, bkt, _ := setup(t, injector)
+
+		var entries []string
+		require.NoError(t, bkt.Iter(context.Background(), "dir/", func(name string) error {
+			entries = append(entries, name)
+			return nil
+		}))
+		assert.Equal(t, []string{"dir/object"}, entries)
+		assert.Equal(t, 2, *calls)
+	})
+
+	t.Run("should not retry a listing failed by the callback", func(t *testing.T) {
+		_, bkt, _ := setup(t, nil)
+
+		calls := 0
+		err := bkt.Iter(context.Background(), "dir/", func(string) error {
+			calls++
+			return errors.New("callback failed")
+		})
+		require.EqualError(t, err, "callback failed")
+		assert.Equal(t, 1, calls)
+	})
+}
+
+func TestIsThrottlingErr(t *testing.T) {
+	assert.True(t, isThrottlingErr(minio.ErrorResponse{Code: "SlowDown"}))
+	assert.True(t, isThrottlingErr(minio.ErrorResponse{StatusCode: 429}))
+	assert.False(t, isThrottlingErr(minio.ErrorResponse{Code: "NoSuchKey", StatusCode: 404}))
+	assert.False(t, isThrottlingErr(errors.New("connection reset")))
+}
+
+// partialUploadBucketClient fails the first uploads after having read part of the object.
+type partialUploadBucketClient struct {
+	objstore.Bucket
+	failures int
+}
+
+func (b *partialUploadBucketClient) Upload(ctx context.Context, name string, r io.Reader) error {
+	if b.failures > 0 {
+		b.failures--
+		_, _ = r.Read(make([]byte, 4))
+		return errors.New("connection reset")
+	}
+	return b.Bucket.Upload(ctx, name, r)
+}
@@ -37,6 +37,8 @@ const (
 	SparseIndexHeaderFilename = "sparse-index-header"
 	// ChunksDirname is the known dir name for chunks with compressed samples.
 	ChunksDirname = "chunks"
+	// ParquetFilenameExtension is the extension of the Parquet export of a block.
+	ParquetFilenameExtension = ".parquet"
 
 	// DebugMetas is a directory for debug meta files that happen in the past. Useful for debugging.
 	DebugMetas = "debug/metas"
@@ -121,6 +123,15 @@ func Upload(ctx context.Context, logger log.Logger, bkt objstore.Bucket, blockDi
 		return cleanUp(logger, bkt, id, errors.Wrap(err, "upload index"))
 	}
 
+	// The series hashes file is optional.
+	if _, err := os.Stat(filepath.Join(blockDir, SeriesHashesFilename)); err == nil {
+		if err := objstore.UploadFile(ctx, logger, bkt, filepath.Join(blockDir, SeriesHashesFilename), path.Join(id.String(), SeriesHashesFilename)); err != nil {
+			return cleanUp(logger, bkt, id, errors.Wrap(err, "upload series hashes"))
+		}
+	} else if !os.IsNotExist(err) {
+		return cleanUp(logger, bkt, id, errors.Wrap(err, "stat series hashes"))
+	}
+
 	// Meta.json always need to be uploaded as a last item. This will allow to assume block directories without meta file to be pending uploads.
 	if err := bkt.Upload(ctx, path.Join(id.String(), MetaFilename), strings.NewReader(metaEncoded.String())); err != nil {
 		// Don't call cleanUp here. Despite getting error, meta.json may have been uploaded in certain cases,
@@ -313,6 +324,13 @@ func GatherFileStats(blockDir string) (res []File, _ error) {
 	}
 	res = append(res, mf)
 
+	seriesHashesFile, err := os.Stat(filepath.Join(blockDir, SeriesHashesFilename))
+	if err == nil {
+		res = append(res, File{RelPath: seriesHashesFile.Name(), SizeBytes: seriesHashesFile.Size()})
+	} else if !os.IsNotExist(err) {
+		return nil, errors.Wrapf(err, "stat %v", filepath.Join(blockDir, SeriesHashesFilename))
+	}
+
 	metaFile, err := os.Stat(filepath.Join(blockDir, MetaFilename))
 	if err != nil {
 		return nil, errors.Wrapf(err, "stat %v", filepath.Join(blockDir, MetaFilename))
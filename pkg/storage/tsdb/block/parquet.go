@@ -0,0 +1,130 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package block
+
+import (
+	"context"
+	"io"
+	"path/filepath"
+
+	"github.com/grafana/dskit/runutil"
+	"github.com/parquet-go/parquet-go"
+	"github.com/pkg/errors"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/tsdb/chunkenc"
+	"github.com/prometheus/prometheus/tsdb/chunks"
+	"github.com/prometheus/prometheus/tsdb/index"
+)
+
+const (
+	parquetMaxRowsPerRowGroup = 1 << 20
+	parquetWriteBatchSize     = 1024
+)
+
+// ParquetSample is a row of the Parquet export of a block. Each row is a float sample of a series.
+type ParquetSample struct {
+	MetricName string            `parquet:"metric_name,dict"`
+	Labels     map[string]string `parquet:"labels"`
+	Timestamp  int64             `parquet:"timestamp,timestamp(millisecond)"`
+	Value      float64           `parquet:"value"`
+}
+
+// WriteParquet writes the float samples of the block in blockDir to w in the Parquet format, one row per sample,
+// so that they can be analyzed with tools supporting columnar formats. The labels of each row don't include the
+// metric name, which is stored in its own column. Native histograms are not exported.
+func WriteParquet(ctx context.Context, blockDir string, w io.Writer) (numSamples int, err error) {
+	ir, err := index.NewFileReader(filepath.Join(blockDir, IndexFilename))
+	if err != nil {
+		return 0, errors.Wrap(err, "open index")
+	}
+	defer runutil.CloseWithErrCapture(&err, ir, "close index reader")
+
+	cr, err := chunks.NewDirReader(filepath.Join(blockDir, ChunksDirname), nil)
+	if err != nil {
+		return 0, errors.Wrap(err, "open chunks")
+	}
+	defer runutil.CloseWithErrCapture(&err, cr, "close chunks reader")
+
+	k, v := index.AllPostingsKey()
+	postings, err := ir.Postings(ctx, k, v)
+	if err != nil {
+		return 0, errors.Wrap(err, "read postings")
+	}
+
+	pw := parquet.NewGenericWriter[ParquetSample](w, parquet.Compression(&parquet.Zstd), parquet.MaxRowsPerRowGroup(parquetMaxRowsPerRowGroup))
+	rows := make([]ParquetSample, 0, parquetWriteBatchSize)
+	flush := func() error {
+		if _, err := pw.Write(rows); err != nil {
+			return errors.Wrap(err, "write rows")
+		}
+		rows = rows[:0]
+		return nil
+	}
+
+	var (
+		builder labels.ScratchBuilder
+		chks    []chunks.Meta
+		it      chunkenc.Iterator
+	)
+	for postings.Next() {
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+
+		id := postings.At()
+		if err := ir.Series(id, &builder, &chks); err != nil {
+			return 0, errors.Wrapf(err, "read series %d", id)
+		}
+
+		// The labels map is shared by all the rows of the series.
+		lset := builder.Labels()
+		metricName := lset.Get(labels.MetricName)
+		lbls := make(map[string]string, lset.Len())
+		lset.Range(func(l labels.Label) {
+			if l.Name != labels.MetricName {
+				lbls[l.Name] = l.Value
+			}
+		})
+
+		for _, meta := range chks {
+			chk, iterable, err := cr.ChunkOrIterable(meta)
+			if err != nil {
+				return 0, errors.Wrapf(err, "read chunk %d of series %d", meta.Ref, id)
+			}
+			if iterable == nil {
+				iterable = chk
+			}
+
+			it = iterable.Iterator(it)
+			for vt := it.Next(); vt != chunkenc.ValNone; vt = it.Next() {
+				if vt != chunkenc.ValFloat {
+					continue
+				}
+
+				t, v := it.At()
+				rows = append(rows, ParquetSample{MetricName: metricName, Labels: lbls, Timestamp: t, Value: v})
+				if len(rows) == cap(rows) {
+					if err := flush(); err != nil {
+						return 0, err
+					}
+				}
+				numSamples++
+			}
+			if err := it.Err(); err != nil {
+				return 0, errors.Wrapf(err, "iterate chunk %d of series %d", meta.Ref, id)
+			}
+		}
+	}
+	if err := postings.Err(); err != nil {
+		return 0, errors.Wrap(err, "iterate postings")
+	}
+
+	if err := flush(); err != nil {
+		return 0, err
+	}
+	if err := pw.Close(); err != nil {
+		return 0, errors.Wrap(err, "close Parquet writer")
+	}
+
+	return numSamples, nil
+}
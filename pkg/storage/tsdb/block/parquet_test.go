@@ -0,0 +1,53 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package block
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteParquet(t *testing.T) {
+	ctx := context.Background()
+	tmpDir := t.TempDir()
+
+	// The first series has float samples, while the other ones have native histograms which are not exported.
+	series := []labels.Labels{
+		labels.FromStrings(labels.MetricName, "metric_1", "a", "1"),
+		labels.FromStrings(labels.MetricName, "metric_1", "a", "2"),
+		labels.FromStrings(labels.MetricName, "metric_2", "b", "1", "c", "2"),
+	}
+	id, err := CreateBlock(ctx, tmpDir, series, 10, 0, 1000, labels.FromStrings("ext1", "val1"))
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	numSamples, err := WriteParquet(ctx, filepath.Join(tmpDir, id.String()), &buf)
+	require.NoError(t, err)
+	require.Equal(t, 10, numSamples)
+
+	rows, err := parquet.Read[ParquetSample](bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	require.Len(t, rows, numSamples)
+
+	samplesPerSeries := map[string]int{}
+	for _, row := range rows {
+		require.GreaterOrEqual(t, row.Timestamp, int64(0))
+		require.LessOrEqual(t, row.Timestamp, int64(1000))
+
+		b := labels.NewScratchBuilder(len(row.Labels) + 1)
+		b.Add(labels.MetricName, row.MetricName)
+		for name, value := range row.Labels {
+			b.Add(name, value)
+		}
+		b.Sort()
+		samplesPerSeries[b.Labels().String()]++
+	}
+
+	require.Equal(t, map[string]int{series[0].String(): 10}, samplesPerSeries)
+}
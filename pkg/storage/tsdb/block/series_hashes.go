@@ -0,0 +1,158 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package block
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/prometheus/prometheus/tsdb/index"
+)
+
+const (
+	// SeriesHashesFilename is the known file name of the series hashes of a block, used to select
+	// the series belonging to a query shard without hashing their labels.
+	SeriesHashesFilename = "series-hashes"
+
+	seriesHashesMagic   = uint32(0x5E4A54E5)
+	seriesHashesVersion = byte(1)
+	seriesHashesHeader  = 5
+)
+
+// The series hashes file has the following format:
+//
+//	┌──────────────┬─────────────┬─────────────────────────────────┬──────────────┐
+//	│ magic <4b>   │ version <1b>│ entry 1 ... entry n             │ CRC32 <4b>   │
+//	└──────────────┴─────────────┴─────────────────────────────────┴──────────────┘
+//
+// Each entry is the offset of the series in the index (the reference used by the store-gateway,
+// which differs from the TSDB series ID in the index format v2), delta-encoded from the previous one as uvarint,
+// followed by the stable hash of the series labels as big-endian uint64. The CRC32 (Castagnoli)
+// covers all the preceding bytes.
+
+// WriteSeriesHashes writes the series hashes file of the block in blockDir, reading the series from the block index.
+func WriteSeriesHashes(ctx context.Context, blockDir string) (numSeries int, err error) {
+	r, err := index.NewFileReader(filepath.Join(blockDir, IndexFilename))
+	if err != nil {
+		return 0, errors.Wrap(err, "open index")
+	}
+	defer func() {
+		if closeErr := r.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+
+	f, err := os.Create(filepath.Join(blockDir, SeriesHashesFilename))
+	if err != nil {
+		return 0, errors.Wrap(err, "create series hashes file")
+	}
+	defer func() {
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+
+	crc := crc32.New(castagnoli)
+	w := bufio.NewWriter(io.MultiWriter(f, crc))
+
+	buf := make([]byte, binary.MaxVarintLen64)
+	binary.BigEndian.PutUint32(buf, seriesHashesMagic)
+	buf[4] = seriesHashesVersion
+	if _, err := w.Write(buf[:seriesHashesHeader]); err != nil {
+		return 0, err
+	}
+
+	k, v := index.AllPostingsKey()
+	postings, err := r.Postings(ctx, k, v)
+	if err != nil {
+		return 0, errors.Wrap(err, "read postings")
+	}
+
+	// As of index version 2, series IDs are the offset of the series divided by 16.
+	padding := storage.SeriesRef(1)
+	if r.Version() >= index.FormatV2 {
+		padding = 16
+	}
+
+	var (
+		builder labels.ScratchBuilder
+		prev    storage.SeriesRef
+	)
+	for postings.Next() {
+		id := postings.At()
+		if err := r.Series(id, &builder, nil); err != nil {
+			return 0, errors.Wrapf(err, "read series %d", id)
+		}
+
+		ref := id * padding
+		n := binary.PutUvarint(buf, uint64(ref-prev))
+		if _, err := w.Write(buf[:n]); err != nil {
+			return 0, err
+		}
+		binary.BigEndian.PutUint64(buf, labels.StableHash(builder.Labels()))
+		if _, err := w.Write(buf[:8]); err != nil {
+			return 0, err
+		}
+
+		prev = ref
+		numSeries++
+	}
+	if err := postings.Err(); err != nil {
+		return 0, errors.Wrap(err, "iterate postings")
+	}
+
+	if err := w.Flush(); err != nil {
+		return 0, err
+	}
+	binary.BigEndian.PutUint32(buf, crc.Sum32())
+	if _, err := f.Write(buf[:4]); err != nil {
+		return 0, err
+	}
+
+	return numSeries, nil
+}
+
+// ReadSeriesHashes reads a series hashes file from r and calls f for each series. The whole file is read and
+// its checksum is verified before calling f, so that no hash is returned from a corrupted file.
+func ReadSeriesHashes(r io.Reader, f func(ref storage.SeriesRef, hash uint64)) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return errors.Wrap(err, "read series hashes")
+	}
+
+	if len(data) < seriesHashesHeader+4 {
+		return errors.New("series hashes file is too short")
+	}
+	if magic := binary.BigEndian.Uint32(data); magic != seriesHashesMagic {
+		return errors.Errorf("invalid series hashes magic number %x", magic)
+	}
+	if version := data[4]; version != seriesHashesVersion {
+		return errors.Errorf("unsupported series hashes version %d", version)
+	}
+
+	content, checksum := data[:len(data)-4], binary.BigEndian.Uint32(data[len(data)-4:])
+	if crc32.Checksum(content, castagnoli) != checksum {
+		return errors.New("series hashes checksum mismatch")
+	}
+
+	var ref storage.SeriesRef
+	for buf := content[seriesHashesHeader:]; len(buf) > 0; {
+		delta, n := binary.Uvarint(buf)
+		if n <= 0 || len(buf) < n+8 {
+			return errors.New("invalid series hashes entry")
+		}
+		ref += storage.SeriesRef(delta)
+		f(ref, binary.BigEndian.Uint64(buf[n:]))
+		buf = buf[n+8:]
+	}
+
+	return nil
+}
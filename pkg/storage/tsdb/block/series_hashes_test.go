@@ -0,0 +1,75 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package block
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/prometheus/prometheus/tsdb/index"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/objstore"
+)
+
+func TestWriteAndReadSeriesHashes(t *testing.T) {
+	ctx := context.Background()
+	tmpDir := t.TempDir()
+
+	id, err := CreateBlock(ctx, tmpDir, fiveLabels, 10, 0, 1000, labels.FromStrings("ext1", "val1"))
+	require.NoError(t, err)
+	blockDir := filepath.Join(tmpDir, id.String())
+
+	numSeries, err := WriteSeriesHashes(ctx, blockDir)
+	require.NoError(t, err)
+	require.Equal(t, len(fiveLabels), numSeries)
+
+	data, err := os.ReadFile(filepath.Join(blockDir, SeriesHashesFilename))
+	require.NoError(t, err)
+
+	hashes := map[storage.SeriesRef]uint64{}
+	require.NoError(t, ReadSeriesHashes(bytes.NewReader(data), func(ref storage.SeriesRef, hash uint64) {
+		hashes[ref] = hash
+	}))
+	require.Len(t, hashes, len(fiveLabels))
+
+	// The references are the offsets of the series in the index.
+	r, err := index.NewFileReader(filepath.Join(blockDir, IndexFilename))
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, r.Close()) })
+
+	var builder labels.ScratchBuilder
+	for ref, hash := range hashes {
+		require.NoError(t, r.Series(ref/16, &builder, nil))
+		require.Equal(t, labels.StableHash(builder.Labels()), hash)
+	}
+
+	t.Run("should fail reading a corrupted file", func(t *testing.T) {
+		corrupted := bytes.Clone(data)
+		corrupted[seriesHashesHeader+1] ^= 0xff
+
+		called := false
+		err := ReadSeriesHashes(bytes.NewReader(corrupted), func(storage.SeriesRef, uint64) { called = true })
+		require.EqualError(t, err, "series hashes checksum mismatch")
+		require.False(t, called)
+	})
+
+	t.Run("should upload the series hashes with the block", func(t *testing.T) {
+		bkt := objstore.NewInMemBucket()
+		require.NoError(t, Upload(ctx, log.NewNopLogger(), bkt, blockDir, nil))
+
+		exists, err := bkt.Exists(ctx, path.Join(id.String(), SeriesHashesFilename))
+		require.NoError(t, err)
+		require.True(t, exists)
+
+		meta, err := DownloadMeta(ctx, log.NewNopLogger(), bkt, id)
+		require.NoError(t, err)
+		require.Contains(t, meta.Thanos.Files, File{RelPath: SeriesHashesFilename, SizeBytes: int64(len(data))})
+	})
+}
@@ -101,6 +101,9 @@ type Block struct {
 
 	// Labels contains the external labels from the block's metadata.
 	Labels map[string]string `json:"labels,omitempty"`
+
+	// NumSeries is the number of series in the block, copied from the block's metadata stats.
+	NumSeries uint64 `json:"num_series,omitempty"`
 }
 
 // Within returns whether the block contains samples within the provided range.
@@ -133,6 +136,9 @@ func (m *Block) ThanosMeta() *block.Meta {
 				Level: m.CompactionLevel,
 				Hints: compactionHints,
 			},
+			Stats: tsdb.BlockStats{
+				NumSeries: m.NumSeries,
+			},
 		},
 		Thanos: block.ThanosMeta{
 			Version:      block.ThanosVersion1,
@@ -179,6 +185,7 @@ func BlockFromThanosMeta(meta block.Meta) *Block {
 		CompactionLevel:  meta.Compaction.Level,
 		OutOfOrder:       meta.Compaction.FromOutOfOrder(),
 		Labels:           maps.Clone(meta.Thanos.Labels),
+		NumSeries:        meta.Stats.NumSeries,
 	}
 }
 
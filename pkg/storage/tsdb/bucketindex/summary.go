@@ -0,0 +1,229 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package bucketindex
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"maps"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/dskit/runutil"
+	"github.com/pkg/errors"
+	"github.com/thanos-io/objstore"
+
+	"github.com/grafana/mimir/pkg/storage/bucket"
+)
+
+const (
+	SummaryFilename           = "compaction-summary.json"
+	SummaryCompressedFilename = SummaryFilename + ".gz"
+	SummaryVersion1           = 1
+)
+
+var (
+	ErrSummaryNotFound  = errors.New("compaction summary not found")
+	ErrSummaryCorrupted = errors.New("compaction summary corrupted")
+)
+
+// Summary is a compact summary of the blocks of a tenant, maintained by the compactor
+// alongside the bucket index. Unlike the bucket index, it only contains the information
+// required to know which time ranges have data in the storage.
+type Summary struct {
+	// Version of the summary format.
+	Version int `json:"version"`
+
+	// List of blocks, sorted by MinTime.
+	Blocks []SummaryBlock `json:"blocks"`
+
+	// UpdatedAt is a unix timestamp (seconds precision) of when the summary has been updated
+	// (written in the storage) the last time.
+	UpdatedAt int64 `json:"updated_at"`
+}
+
+// SummaryBlock holds the information about a block in the summary.
+type SummaryBlock struct {
+	// MinTime and MaxTime specify the time range all samples in the block are in (millis precision).
+	MinTime int64 `json:"min_time"`
+	MaxTime int64 `json:"max_time"`
+
+	// NumSeries is the number of series in the block, if known.
+	NumSeries uint64 `json:"num_series,omitempty"`
+
+	// Labels contains the external labels from the block's metadata, including the compactor shard ID.
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// NewSummary returns the summary of the blocks in the input bucket index. Blocks marked for deletion
+// are included, because they can still be queried until they're deleted.
+func NewSummary(idx *Index) *Summary {
+	s := &Summary{
+		Version:   SummaryVersion1,
+		Blocks:    make([]SummaryBlock, 0, len(idx.Blocks)),
+		UpdatedAt: idx.UpdatedAt,
+	}
+
+	for _, b := range idx.Blocks {
+		s.Blocks = append(s.Blocks, SummaryBlock{
+			MinTime:   b.MinTime,
+			MaxTime:   b.MaxTime,
+			NumSeries: b.NumSeries,
+			Labels:    maps.Clone(b.Labels),
+		})
+	}
+
+	sort.Slice(s.Blocks, func(i, j int) bool {
+		return s.Blocks[i].MinTime < s.Blocks[j].MinTime
+	})
+
+	return s
+}
+
+func (s *Summary) GetUpdatedAt() time.Time {
+	return time.Unix(s.UpdatedAt, 0)
+}
+
+// HasDataWithin returns whether any block in the summary contains samples within the provided range.
+// Input minT and maxT are both inclusive.
+func (s *Summary) HasDataWithin(minT, maxT int64) bool {
+	for _, b := range s.Blocks {
+		if b.MinTime > maxT {
+			// Blocks are sorted by MinTime, so no other block can be within the range.
+			return false
+		}
+
+		// NOTE: Block intervals are half-open: [MinTime, MaxTime).
+		if minT < b.MaxTime {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ReadSummary reads, parses and returns a compaction summary from the bucket.
+// ReadSummary has a one-minute timeout for completing the read against the bucket.
+func ReadSummary(ctx context.Context, bkt objstore.Bucket, userID string, cfgProvider bucket.TenantConfigProvider, logger log.Logger) (*Summary, error) {
+	ctx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+
+	userBkt := bucket.NewUserBucketClient(userID, bkt, cfgProvider)
+
+	reader, err := userBkt.WithExpectedErrs(userBkt.IsObjNotFoundErr).Get(ctx, SummaryCompressedFilename)
+	if err != nil {
+		if userBkt.IsObjNotFoundErr(err) {
+			return nil, ErrSummaryNotFound
+		}
+		return nil, errors.Wrap(err, "read compaction summary")
+	}
+	defer runutil.CloseWithLogOnErr(logger, reader, "close compaction summary reader")
+
+	gzipReader, err := gzip.NewReader(reader)
+	if err != nil {
+		return nil, ErrSummaryCorrupted
+	}
+	defer runutil.CloseWithLogOnErr(logger, gzipReader, "close compaction summary gzip reader")
+
+	summary := &Summary{}
+	if err := json.NewDecoder(gzipReader).Decode(summary); err != nil {
+		return nil, ErrSummaryCorrupted
+	}
+
+	return summary, nil
+}
+
+// WriteSummary uploads the provided compaction summary to the storage.
+func WriteSummary(ctx context.Context, bkt objstore.Bucket, userID string, cfgProvider bucket.TenantConfigProvider, summary *Summary) error {
+	bkt = bucket.NewUserBucketClient(userID, bkt, cfgProvider)
+
+	content, err := json.Marshal(summary)
+	if err != nil {
+		return errors.Wrap(err, "marshal compaction summary")
+	}
+
+	var gzipContent bytes.Buffer
+	gzip := gzip.NewWriter(&gzipContent)
+	gzip.Name = SummaryFilename
+
+	if _, err := gzip.Write(content); err != nil {
+		return errors.Wrap(err, "gzip compaction summary")
+	}
+	if err := gzip.Close(); err != nil {
+		return errors.Wrap(err, "close gzip compaction summary")
+	}
+
+	if err := bkt.Upload(ctx, SummaryCompressedFilename, &gzipContent); err != nil {
+		return errors.Wrap(err, "upload compaction summary")
+	}
+
+	return nil
+}
+
+// DeleteSummary deletes the compaction summary from the storage. No error is returned if the summary
+// does not exist.
+func DeleteSummary(ctx context.Context, bkt objstore.Bucket, userID string, cfgProvider bucket.TenantConfigProvider) error {
+	bkt = bucket.NewUserBucketClient(userID, bkt, cfgProvider)
+
+	err := bkt.Delete(ctx, SummaryCompressedFilename)
+	if err != nil && !bkt.IsObjNotFoundErr(err) {
+		return errors.Wrap(err, "delete compaction summary")
+	}
+	return nil
+}
+
+// SummaryReader reads the tenants' compaction summaries from the storage, caching them in memory
+// (including the failures) for the configured TTL.
+type SummaryReader struct {
+	bkt         objstore.Bucket
+	cfgProvider bucket.TenantConfigProvider
+	ttl         time.Duration
+	logger      log.Logger
+
+	summariesMx sync.Mutex
+	summaries   map[string]*cachedSummary
+}
+
+type cachedSummary struct {
+	summary   *Summary
+	err       error
+	fetchedAt time.Time
+}
+
+// NewSummaryReader makes a new SummaryReader.
+func NewSummaryReader(bkt objstore.Bucket, cfgProvider bucket.TenantConfigProvider, ttl time.Duration, logger log.Logger) *SummaryReader {
+	return &SummaryReader{
+		bkt:         bkt,
+		cfgProvider: cfgProvider,
+		ttl:         ttl,
+		logger:      logger,
+		summaries:   map[string]*cachedSummary{},
+	}
+}
+
+// GetSummary returns the compaction summary of the tenant.
+func (r *SummaryReader) GetSummary(ctx context.Context, userID string) (*Summary, error) {
+	r.summariesMx.Lock()
+	entry, ok := r.summaries[userID]
+	r.summariesMx.Unlock()
+
+	if ok && time.Since(entry.fetchedAt) < r.ttl {
+		return entry.summary, entry.err
+	}
+
+	summary, err := ReadSummary(ctx, r.bkt, userID, r.cfgProvider, r.logger)
+	if errors.Is(err, context.Canceled) {
+		// Do not cache failures caused by the cancellation of the request.
+		return nil, err
+	}
+
+	r.summariesMx.Lock()
+	r.summaries[userID] = &cachedSummary{summary: summary, err: err, fetchedAt: time.Now()}
+	r.summariesMx.Unlock()
+
+	return summary, err
+}
@@ -0,0 +1,136 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package bucketindex
+
+import (
+	"context"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/oklog/ulid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	mimir_testutil "github.com/grafana/mimir/pkg/storage/tsdb/testutil"
+)
+
+func TestNewSummary(t *testing.T) {
+	idx := &Index{
+		Version: IndexVersion2,
+		Blocks: Blocks{
+			{ID: ulid.MustNew(1, nil), MinTime: 20, MaxTime: 30, NumSeries: 5},
+			{ID: ulid.MustNew(2, nil), MinTime: 10, MaxTime: 20, NumSeries: 10, Labels: map[string]string{"__compactor_shard_id__": "1_of_2"}},
+		},
+		UpdatedAt: 100,
+	}
+
+	assert.Equal(t, &Summary{
+		Version: SummaryVersion1,
+		Blocks: []SummaryBlock{
+			{MinTime: 10, MaxTime: 20, NumSeries: 10, Labels: map[string]string{"__compactor_shard_id__": "1_of_2"}},
+			{MinTime: 20, MaxTime: 30, NumSeries: 5},
+		},
+		UpdatedAt: 100,
+	}, NewSummary(idx))
+}
+
+func TestSummary_HasDataWithin(t *testing.T) {
+	summary := &Summary{Blocks: []SummaryBlock{
+		{MinTime: 10, MaxTime: 20},
+		{MinTime: 15, MaxTime: 25},
+		{MinTime: 40, MaxTime: 50},
+	}}
+
+	tests := map[string]struct {
+		minT, maxT int64
+		expected   bool
+	}{
+		"range before all blocks":                {minT: 0, maxT: 9, expected: false},
+		"range ending at the first block min":    {minT: 0, maxT: 10, expected: true},
+		"range within a block":                   {minT: 12, maxT: 13, expected: true},
+		"range starting at the previous max":     {minT: 25, maxT: 30, expected: false},
+		"range in a gap between blocks":          {minT: 26, maxT: 39, expected: false},
+		"range spanning multiple blocks":         {minT: 0, maxT: 100, expected: true},
+		"range after all blocks":                 {minT: 50, maxT: 100, expected: false},
+		"range overlapping the end of the block": {minT: 49, maxT: 100, expected: true},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, summary.HasDataWithin(tc.minT, tc.maxT))
+		})
+	}
+
+	assert.False(t, (&Summary{}).HasDataWithin(0, 100))
+}
+
+func TestReadSummary(t *testing.T) {
+	const userID = "user-1"
+
+	ctx := context.Background()
+	logger := log.NewNopLogger()
+
+	t.Run("should return error if the summary does not exist", func(t *testing.T) {
+		bkt, _ := mimir_testutil.PrepareFilesystemBucket(t)
+
+		summary, err := ReadSummary(ctx, bkt, userID, nil, logger)
+		require.Equal(t, ErrSummaryNotFound, err)
+		require.Nil(t, summary)
+	})
+
+	t.Run("should return error if the summary is corrupted", func(t *testing.T) {
+		bkt, _ := mimir_testutil.PrepareFilesystemBucket(t)
+		require.NoError(t, bkt.Upload(ctx, path.Join(userID, SummaryCompressedFilename), strings.NewReader("invalid!}")))
+
+		summary, err := ReadSummary(ctx, bkt, userID, nil, logger)
+		require.Equal(t, ErrSummaryCorrupted, err)
+		require.Nil(t, summary)
+	})
+
+	t.Run("should return the parsed summary on success", func(t *testing.T) {
+		bkt, _ := mimir_testutil.PrepareFilesystemBucket(t)
+
+		expected := &Summary{
+			Version:   SummaryVersion1,
+			Blocks:    []SummaryBlock{{MinTime: 10, MaxTime: 20, NumSeries: 3, Labels: map[string]string{"a": "b"}}},
+			UpdatedAt: time.Now().Unix(),
+		}
+		require.NoError(t, WriteSummary(ctx, bkt, userID, nil, expected))
+
+		actual, err := ReadSummary(ctx, bkt, userID, nil, logger)
+		require.NoError(t, err)
+		assert.Equal(t, expected, actual)
+
+		require.NoError(t, DeleteSummary(ctx, bkt, userID, nil))
+		_, err = ReadSummary(ctx, bkt, userID, nil, logger)
+		require.Equal(t, ErrSummaryNotFound, err)
+
+		// Deleting a summary which doesn't exist should not fail.
+		require.NoError(t, DeleteSummary(ctx, bkt, userID, nil))
+	})
+}
+
+func TestSummaryReader_GetSummary(t *testing.T) {
+	const userID = "user-1"
+
+	ctx := context.Background()
+	bkt, _ := mimir_testutil.PrepareFilesystemBucket(t)
+	reader := NewSummaryReader(bkt, nil, time.Hour, log.NewNopLogger())
+
+	// The failure is cached too.
+	_, err := reader.GetSummary(ctx, userID)
+	require.Equal(t, ErrSummaryNotFound, err)
+
+	require.NoError(t, WriteSummary(ctx, bkt, userID, nil, &Summary{Version: SummaryVersion1}))
+	_, err = reader.GetSummary(ctx, userID)
+	require.Equal(t, ErrSummaryNotFound, err)
+
+	// Once the TTL expires, the summary is read again from the storage.
+	reader.ttl = 0
+	summary, err := reader.GetSummary(ctx, userID)
+	require.NoError(t, err)
+	assert.Equal(t, SummaryVersion1, summary.Version)
+}
@@ -34,6 +34,9 @@ var (
 type Updater struct {
 	bkt    objstore.InstrumentedBucket
 	logger log.Logger
+
+	// Whether the blocks whose meta.json has another block ID are considered corrupted.
+	metaBlockIDCheckEnabled bool
 }
 
 func NewUpdater(bkt objstore.Bucket, userID string, cfgProvider bucket.TenantConfigProvider, logger log.Logger) *Updater {
@@ -43,6 +46,12 @@ func NewUpdater(bkt objstore.Bucket, userID string, cfgProvider bucket.TenantCon
 	}
 }
 
+// EnableMetaBlockIDCheck configures the Updater to consider corrupted the blocks whose meta.json has a block ID
+// different than the block's own one, which can be caused by an interrupted upload.
+func (w *Updater) EnableMetaBlockIDCheck() {
+	w.metaBlockIDCheckEnabled = true
+}
+
 // UpdateIndex generates the bucket index and returns it, without storing it to the storage.
 // If the old index is not passed in input, then the bucket index will be generated from scratch.
 func (w *Updater) UpdateIndex(ctx context.Context, old *Index) (*Index, map[ulid.ULID]error, error) {
@@ -173,6 +182,11 @@ func (w *Updater) updateBlockIndexEntry(ctx context.Context, id ulid.ULID) (*Blo
 		return nil, errors.Errorf("unexpected block meta version: %s version: %d", metaFile, m.Version)
 	}
 
+	// The meta.json could have been copied from another block by an interrupted upload.
+	if w.metaBlockIDCheckEnabled && m.ULID != id {
+		return nil, errors.Wrapf(ErrBlockMetaCorrupted, "block meta file %s has unexpected block ID %s", metaFile, m.ULID)
+	}
+
 	block := BlockFromThanosMeta(m)
 
 	// Get the meta.json attributes.
@@ -8,6 +8,7 @@ package bucketindex
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"maps"
 	"path"
 	"testing"
@@ -130,6 +131,34 @@ func TestUpdater_UpdateIndex_ShouldSkipBlocksWithCorruptedMeta(t *testing.T) {
 	assert.True(t, errors.Is(partials[block3.ULID], ErrBlockMetaCorrupted))
 }
 
+func TestUpdater_UpdateIndex_ShouldSkipBlocksWithMetaOfAnotherBlockIfEnabled(t *testing.T) {
+	const userID = "user-1"
+
+	bkt, _ := testutil.PrepareFilesystemBucket(t)
+
+	ctx := context.Background()
+	logger := log.NewNopLogger()
+
+	// Mock some blocks in the storage.
+	bkt = block.BucketWithGlobalMarkers(bkt)
+	block1 := block.MockStorageBlockWithExtLabels(t, bkt, userID, 10, 20, nil)
+	block2 := block.MockStorageBlockWithExtLabels(t, bkt, userID, 20, 30, nil)
+
+	// Overwrite a block's meta.json with the meta.json of another block.
+	block1MetaContent, err := json.Marshal(block1)
+	require.NoError(t, err)
+	require.NoError(t, bkt.Upload(ctx, path.Join(userID, block2.ULID.String(), block.MetaFilename), bytes.NewReader(block1MetaContent)))
+
+	w := NewUpdater(bkt, userID, nil, logger)
+	w.EnableMetaBlockIDCheck()
+	idx, partials, err := w.UpdateIndex(ctx, nil)
+	require.NoError(t, err)
+	assertBucketIndexEqual(t, idx, bkt, userID, []block.Meta{block1}, nil)
+
+	assert.Len(t, partials, 1)
+	assert.True(t, errors.Is(partials[block2.ULID], ErrBlockMetaCorrupted))
+}
+
 func TestUpdater_UpdateIndex_ShouldSkipCorruptedDeletionMarks(t *testing.T) {
 	const userID = "user-1"
 
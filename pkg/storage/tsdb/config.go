@@ -130,8 +130,10 @@ var (
 	errInvalidWALReplayConcurrency                  = errors.New("invalid TSDB WAL replay concurrency")
 	errInvalidStripeSize                            = errors.New("invalid TSDB stripe size")
 	errInvalidStreamingBatchSize                    = errors.New("invalid store-gateway streaming batch size")
+	errInvalidScrubChunksSampleSize                 = errors.New("invalid store-gateway scrub chunks sample size; must be non-negative")
 	errInvalidEarlyHeadCompactionMinSeriesReduction = errors.New("early compaction minimum series reduction percentage must be a value between 0 and 100 (included)")
 	errEarlyCompactionRequiresActiveSeries          = fmt.Errorf("early compaction requires -%s to be enabled", activeseries.EnabledFlag)
+	errWALShippingRequiresBlocksShipping            = errors.New("WAL shipping requires blocks shipping to be enabled")
 	errEmptyBlockranges                             = errors.New("empty block ranges for TSDB")
 	errInvalidIgnoreDeletionMarksDelayConfig        = fmt.Errorf("value for -%s must be less than -%s", ignoreDeletionMarksWhileQueryingDelayFlag, ignoreDeletionMarksInStoreGatewayDelayFlag)
 	errIgnoreDeletionMarksDelayTooShort             = fmt.Errorf("value for -%s must be greater than %v× -%s to ensure that newly compacted blocks are queried before old blocks are ignored", ignoreDeletionMarksWhileQueryingDelayFlag, NewBlockDiscoveryDelayMultiplier, syncIntervalFlag)
@@ -282,6 +284,9 @@ type TSDBConfig struct {
 	// TimelyHeadCompaction allows head compaction to happen when min block range can no longer be appended,
 	// without requiring 1.5x the chunk range worth of data in the head.
 	TimelyHeadCompaction bool `yaml:"timely_head_compaction_enabled" category:"experimental"`
+
+	// WALShippingEnabled uploads the WAL to the storage, so that it can be replayed in another region after a regional failure.
+	WALShippingEnabled bool `yaml:"wal_shipping_enabled" category:"experimental"`
 }
 
 // RegisterFlags registers the TSDBConfig flags.
@@ -327,6 +332,7 @@ func (cfg *TSDBConfig) RegisterFlags(f *flag.FlagSet) {
 	f.Int64Var(&cfg.EarlyHeadCompactionMinInMemorySeries, "blocks-storage.tsdb.early-head-compaction-min-in-memory-series", 0, fmt.Sprintf("When the number of in-memory series in the ingester is equal to or greater than this setting, the ingester tries to compact the TSDB Head. The early compaction removes from the memory all samples and inactive series up until -%s time ago. After an early compaction, the ingester will not accept any sample with a timestamp older than -%s time ago (unless out of order ingestion is enabled). The ingester checks every -%s whether an early compaction is required. Use 0 to disable it.", activeseries.IdleTimeoutFlag, activeseries.IdleTimeoutFlag, headCompactionIntervalFlag))
 	f.IntVar(&cfg.EarlyHeadCompactionMinEstimatedSeriesReductionPercentage, "blocks-storage.tsdb.early-head-compaction-min-estimated-series-reduction-percentage", 15, "When the early compaction is enabled, the early compaction is triggered only if the estimated series reduction is at least the configured percentage (0-100).")
 	f.BoolVar(&cfg.TimelyHeadCompaction, "blocks-storage.tsdb.timely-head-compaction-enabled", false, "Allows head compaction to happen when the min block range can no longer be appended, without requiring 1.5x the chunk range worth of data in the head.")
+	f.BoolVar(&cfg.WALShippingEnabled, "blocks-storage.tsdb.wal-shipping-enabled", false, "Upload the WAL segments and checkpoints of each tenant to the storage every -blocks-storage.tsdb.ship-interval, under <tenant>/wal-shipping/<ingester ID>/, so that recent data can be replayed by a standby ingester after a regional failure. The bytes appended to the segment being written are uploaded to <segment>.parts/<offset>, and replaced by the whole segment once it's closed, so the uploaded WAL lags behind by up to the ship interval. Segments are deleted from the storage once they're truncated from the local WAL. The WAL shipped by the ingesters which are not in the ring anymore is deleted by the other ingesters, and the WAL of the tenants marked for deletion is deleted by the compactor. Requires blocks shipping to be enabled.")
 
 	cfg.HeadCompactionIntervalJitterEnabled = true
 	cfg.HeadCompactionIntervalWhileStarting = 30 * time.Second
@@ -374,6 +380,10 @@ func (cfg *TSDBConfig) Validate(activeSeriesCfg activeseries.Config) error {
 		return errInvalidEarlyHeadCompactionMinSeriesReduction
 	}
 
+	if cfg.WALShippingEnabled && !cfg.IsBlocksShippingEnabled() {
+		return errWALShippingRequiresBlocksShipping
+	}
+
 	return nil
 }
 
@@ -414,7 +424,11 @@ type BucketStoreConfig struct {
 	IgnoreBlocksWithin                     time.Duration       `yaml:"ignore_blocks_within" category:"advanced"`
 
 	// Series hash cache.
-	SeriesHashCacheMaxBytes uint64 `yaml:"series_hash_cache_max_size_bytes" category:"advanced"`
+	SeriesHashCacheMaxBytes       uint64 `yaml:"series_hash_cache_max_size_bytes" category:"advanced"`
+	SeriesHashCachePreloadEnabled bool   `yaml:"series_hash_cache_preload_enabled" category:"experimental"`
+
+	// Per-block query statistics.
+	BlockQueryStatsEnabled bool `yaml:"block_query_stats_enabled" category:"experimental"`
 
 	// Controls the partitioner, used to aggregate multiple GET object API requests.
 	PartitionerMaxGapBytes uint64 `yaml:"partitioner_max_gap_bytes" category:"advanced"`
@@ -429,6 +443,9 @@ type BucketStoreConfig struct {
 	// Controls advanced options for index-header file reading.
 	IndexHeader indexheader.Config `yaml:"index_header" category:"advanced"`
 
+	// Number of series whose chunks are verified by the background scrubber, for each block.
+	ScrubChunksSampleSize int `yaml:"scrub_chunks_sample_size" category:"experimental"`
+
 	StreamingBatchSize    int     `yaml:"streaming_series_batch_size" category:"advanced"`
 	SeriesFetchPreference float64 `yaml:"series_fetch_preference" category:"advanced"`
 }
@@ -444,6 +461,8 @@ func (cfg *BucketStoreConfig) RegisterFlags(f *flag.FlagSet) {
 	f.StringVar(&cfg.SyncDir, "blocks-storage.bucket-store.sync-dir", "./tsdb-sync/", "Directory to store synchronized TSDB index headers. This directory is not required to be persisted between restarts, but it's highly recommended in order to improve the store-gateway startup time.")
 	f.DurationVar(&cfg.SyncInterval, syncIntervalFlag, 15*time.Minute, "How frequently to scan the bucket, or to refresh the bucket index (if enabled), in order to look for changes (new blocks shipped by ingesters and blocks deleted by retention or compaction).")
 	f.Uint64Var(&cfg.SeriesHashCacheMaxBytes, "blocks-storage.bucket-store.series-hash-cache-max-size-bytes", uint64(1*units.Gibibyte), "Max size - in bytes - of the in-memory series hash cache. The cache is shared across all tenants and it's used only when query sharding is enabled.")
+	f.BoolVar(&cfg.SeriesHashCachePreloadEnabled, "blocks-storage.bucket-store.series-hash-cache-preload-enabled", false, "If enabled, the series hashes uploaded by the compactor alongside the blocks are loaded into the series hash cache in the background, the first time a block is queried with query sharding. Enable -compactor.upload-series-hashes to upload them.")
+	f.BoolVar(&cfg.BlockQueryStatsEnabled, "blocks-storage.bucket-store.block-query-stats-enabled", false, "If enabled, the store-gateway tracks the number of requests touching each block and the timestamp of the last one. The statistics are exposed by the /store-gateway/tenant/{tenant}/block_query_stats endpoint, and persisted to the object storage every -store-gateway.block-query-stats-persist-interval.")
 	f.IntVar(&cfg.MaxConcurrent, "blocks-storage.bucket-store.max-concurrent", 200, "Max number of concurrent queries to execute against the long-term storage. The limit is shared across all tenants.")
 	f.DurationVar(&cfg.MaxConcurrentQueueTimeout, "blocks-storage.bucket-store.max-concurrent-queue-timeout", 5*time.Second, "Timeout for the queue of queries waiting for execution. If the queue is full and the timeout is reached, the query will be retried on another store-gateway. 0 means no timeout and all queries will wait indefinitely for their turn.")
 	f.IntVar(&cfg.TenantSyncConcurrency, "blocks-storage.bucket-store.tenant-sync-concurrency", 1, "Maximum number of concurrent tenants synching blocks.")
@@ -456,6 +475,7 @@ func (cfg *BucketStoreConfig) RegisterFlags(f *flag.FlagSet) {
 	f.DurationVar(&cfg.IgnoreBlocksWithin, "blocks-storage.bucket-store.ignore-blocks-within", 10*time.Hour, "Blocks with minimum time within this duration are ignored, and not loaded by store-gateway. Useful when used together with -querier.query-store-after to prevent loading young blocks, because there are usually many of them (depending on number of ingesters) and they are not yet compacted. Negative values or 0 disable the filter.")
 	f.IntVar(&cfg.PostingOffsetsInMemSampling, "blocks-storage.bucket-store.posting-offsets-in-mem-sampling", DefaultPostingOffsetInMemorySampling, "Controls what is the ratio of postings offsets that the store will hold in memory.")
 	f.Uint64Var(&cfg.PartitionerMaxGapBytes, "blocks-storage.bucket-store.partitioner-max-gap-bytes", DefaultPartitionerMaxGapSize, "Max size - in bytes - of a gap for which the partitioner aggregates together two bucket GET object requests.")
+	f.IntVar(&cfg.ScrubChunksSampleSize, "blocks-storage.bucket-store.scrub-chunks-sample-size", 10, "Number of randomly sampled series of each block whose chunks are read from the object storage and verified against their checksums by the background scrubber. The scrubber runs every -blocks-storage.bucket-store.index-header.scrub-interval. 0 to verify only the index-headers.")
 	f.IntVar(&cfg.StreamingBatchSize, "blocks-storage.bucket-store.batch-series-size", 5000, "This option controls how many series to fetch per batch. The batch size must be greater than 0.")
 	f.Float64Var(&cfg.SeriesFetchPreference, "blocks-storage.bucket-store.series-fetch-preference", 0.75, "This parameter controls the trade-off in fetching series versus fetching postings to fulfill a series request. Increasing the series preference results in fetching more series and reducing the volume of postings fetched. Reducing the series preference results in the opposite. Increase this parameter to reduce the rate of fetched series bytes (see \"Mimir / Queries\" dashboard) or API calls to the object store. Must be a positive floating point number.")
 }
@@ -465,6 +485,9 @@ func (cfg *BucketStoreConfig) Validate() error {
 	if cfg.StreamingBatchSize <= 0 {
 		return errInvalidStreamingBatchSize
 	}
+	if cfg.ScrubChunksSampleSize < 0 {
+		return errInvalidScrubChunksSampleSize
+	}
 	if cfg.IgnoreDeletionMarksWhileQueryingDelay >= cfg.IgnoreDeletionMarksInStoreGatewayDelay {
 		// If we ignore deletion marks for longer while querying, we'll try to query blocks that store-gateways have
 		// already unloaded, which will cause consistency check failures.
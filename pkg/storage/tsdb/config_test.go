@@ -128,6 +128,13 @@ func TestConfig_Validate(t *testing.T) {
 			},
 			expectedErr: errEarlyCompactionRequiresActiveSeries,
 		},
+		"should fail if WAL shipping is enabled but blocks shipping is not": {
+			setup: func(cfg *BlocksStorageConfig, _ *activeseries.Config) {
+				cfg.TSDB.WALShippingEnabled = true
+				cfg.TSDB.ShipInterval = 0
+			},
+			expectedErr: errWALShippingRequiresBlocksShipping,
+		},
 		"should fail on invalid forced compaction min series reduction percentage": {
 			setup: func(cfg *BlocksStorageConfig, _ *activeseries.Config) {
 				cfg.TSDB.EarlyHeadCompactionMinEstimatedSeriesReductionPercentage = 101
@@ -31,6 +31,9 @@ const (
 	// IndexCacheBackendRedis is the value for the Redis index cache backend.
 	IndexCacheBackendRedis = cache.BackendRedis
 
+	// IndexCacheBackendDisk is the value for the disk index cache backend.
+	IndexCacheBackendDisk = "disk"
+
 	// IndexCacheBackendDefault is the value for the default index cache backend.
 	IndexCacheBackendDefault = IndexCacheBackendInMemory
 
@@ -38,14 +41,17 @@ const (
 )
 
 var (
-	supportedIndexCacheBackends = []string{IndexCacheBackendInMemory, IndexCacheBackendMemcached, IndexCacheBackendRedis}
+	supportedIndexCacheBackends = []string{IndexCacheBackendInMemory, IndexCacheBackendMemcached, IndexCacheBackendRedis, IndexCacheBackendDisk}
 
 	errUnsupportedIndexCacheBackend = errors.New("unsupported index cache backend")
+	errNoIndexCacheDiskDir          = errors.New("the disk index cache directory is required")
+	errInvalidIndexCacheDiskMaxSize = errors.New("the disk index cache max size must be greater than 0")
 )
 
 type IndexCacheConfig struct {
 	cache.BackendConfig `yaml:",inline"`
 	InMemory            InMemoryIndexCacheConfig `yaml:"inmemory"`
+	Disk                DiskIndexCacheConfig     `yaml:"disk"`
 }
 
 func (cfg *IndexCacheConfig) RegisterFlags(f *flag.FlagSet) {
@@ -56,6 +62,7 @@ func (cfg *IndexCacheConfig) RegisterFlagsWithPrefix(f *flag.FlagSet, prefix str
 	f.StringVar(&cfg.Backend, prefix+"backend", IndexCacheBackendDefault, fmt.Sprintf("The index cache backend type. Supported values: %s.", strings.Join(supportedIndexCacheBackends, ", ")))
 
 	cfg.InMemory.RegisterFlagsWithPrefix(prefix+"inmemory.", f)
+	cfg.Disk.RegisterFlagsWithPrefix(prefix+"disk.", f)
 	cfg.Memcached.RegisterFlagsWithPrefix(prefix+"memcached.", f)
 	cfg.Redis.RegisterFlagsWithPrefix(prefix+"redis.", f)
 }
@@ -73,6 +80,12 @@ func (cfg *IndexCacheConfig) Validate() error {
 		}
 	}
 
+	if cfg.Backend == IndexCacheBackendDisk {
+		if err := cfg.Disk.Validate(); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -84,6 +97,27 @@ func (cfg *InMemoryIndexCacheConfig) RegisterFlagsWithPrefix(prefix string, f *f
 	f.Uint64Var(&cfg.MaxSizeBytes, prefix+"max-size-bytes", uint64(1*units.Gibibyte), "Maximum size in bytes of in-memory index cache used to speed up blocks index lookups (shared between all tenants).")
 }
 
+type DiskIndexCacheConfig struct {
+	Dir          string `yaml:"dir" category:"experimental"`
+	MaxSizeBytes uint64 `yaml:"max_size_bytes" category:"experimental"`
+}
+
+func (cfg *DiskIndexCacheConfig) RegisterFlagsWithPrefix(prefix string, f *flag.FlagSet) {
+	f.StringVar(&cfg.Dir, prefix+"dir", "./index-cache/", "Directory where the disk index cache stores the cached items. The cached items are loaded back in the background at startup, so this directory should be persisted across restarts.")
+	f.Uint64Var(&cfg.MaxSizeBytes, prefix+"max-size-bytes", uint64(10*units.Gibibyte), "Maximum size in bytes of the disk index cache used to speed up blocks index lookups (shared between all tenants). Each item is stored in its own file, so its size is accounted in 4KiB filesystem blocks. The least recently used items are evicted once the max size is reached.")
+}
+
+// Validate the config.
+func (cfg *DiskIndexCacheConfig) Validate() error {
+	if cfg.Dir == "" {
+		return errNoIndexCacheDiskDir
+	}
+	if cfg.MaxSizeBytes == 0 {
+		return errInvalidIndexCacheDiskMaxSize
+	}
+	return nil
+}
+
 // NewIndexCache creates a new index cache based on the input configuration.
 func NewIndexCache(cfg IndexCacheConfig, logger log.Logger, registerer prometheus.Registerer) (indexcache.IndexCache, error) {
 	switch cfg.Backend {
@@ -93,6 +127,8 @@ func NewIndexCache(cfg IndexCacheConfig, logger log.Logger, registerer prometheu
 		return newMemcachedIndexCache(cfg.Memcached, logger, registerer)
 	case IndexCacheBackendRedis:
 		return newRedisIndexCache(cfg.Redis, logger, registerer)
+	case IndexCacheBackendDisk:
+		return newDiskIndexCache(cfg.Disk, logger, registerer)
 	default:
 		return nil, errUnsupportedIndexCacheBackend
 	}
@@ -140,3 +176,29 @@ func newRedisIndexCache(cfg cache.RedisClientConfig, logger log.Logger, register
 
 	return indexcache.NewTracingIndexCache(c, logger), nil
 }
+
+func newDiskIndexCache(cfg DiskIndexCacheConfig, logger log.Logger, registerer prometheus.Registerer) (indexcache.IndexCache, error) {
+	maxCacheSize := flagext.Bytes(cfg.MaxSizeBytes)
+
+	// Calculate the max item size.
+	maxItemSize := defaultMaxItemSize
+	if maxItemSize > maxCacheSize {
+		maxItemSize = maxCacheSize
+	}
+
+	client, err := indexcache.NewDiskCache(logger, registerer, indexcache.DiskCacheConfig{
+		Dir:         cfg.Dir,
+		MaxSize:     maxCacheSize,
+		MaxItemSize: maxItemSize,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "create index cache disk client")
+	}
+
+	c, err := indexcache.NewRemoteIndexCache(logger, client, registerer)
+	if err != nil {
+		return nil, errors.Wrap(err, "create disk-based index cache")
+	}
+
+	return indexcache.NewTracingIndexCache(c, logger), nil
+}
@@ -90,6 +90,40 @@ func TestIndexCacheConfig_Validate(t *testing.T) {
 				return cfg
 			}(),
 		},
+		"disk should pass": {
+			cfg: func() IndexCacheConfig {
+				cfg := IndexCacheConfig{}
+				flagext.DefaultValues(&cfg)
+
+				cfg.Backend = IndexCacheBackendDisk
+
+				return cfg
+			}(),
+		},
+		"disk without directory should fail": {
+			cfg: func() IndexCacheConfig {
+				cfg := IndexCacheConfig{}
+				flagext.DefaultValues(&cfg)
+
+				cfg.Backend = IndexCacheBackendDisk
+				cfg.Disk.Dir = ""
+
+				return cfg
+			}(),
+			expected: errNoIndexCacheDiskDir,
+		},
+		"disk with zero max size should fail": {
+			cfg: func() IndexCacheConfig {
+				cfg := IndexCacheConfig{}
+				flagext.DefaultValues(&cfg)
+
+				cfg.Backend = IndexCacheBackendDisk
+				cfg.Disk.MaxSizeBytes = 0
+
+				return cfg
+			}(),
+			expected: errInvalidIndexCacheDiskMaxSize,
+		},
 	}
 
 	for testName, testData := range tests {
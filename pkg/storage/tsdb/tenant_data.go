@@ -0,0 +1,14 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package tsdb
+
+// The directories, within the tenant's bucket prefix, of the data written by the Mimir components
+// next to the blocks. They're deleted along with the blocks when the tenant is deleted.
+const (
+	// BlockQueryStatsDir is where the store-gateways persist the summary of the blocks query statistics.
+	// Each store-gateway writes its own file.
+	BlockQueryStatsDir = "store-gateway-block-query-stats"
+
+	// WALShippingDir is where the ingesters ship the WAL of the tenant. Each ingester ships to its own subdirectory.
+	WALShippingDir = "wal-shipping"
+)
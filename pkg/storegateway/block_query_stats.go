@@ -0,0 +1,243 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package storegateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"path"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/oklog/ulid"
+	"github.com/pkg/errors"
+	"github.com/thanos-io/objstore"
+
+	"github.com/grafana/mimir/pkg/storage/tsdb"
+	"github.com/grafana/mimir/pkg/storage/tsdb/block"
+	"github.com/grafana/mimir/pkg/util"
+)
+
+// BlockQueryStats holds the query statistics of a single block.
+type BlockQueryStats struct {
+	BlockID ulid.ULID `json:"block_id"`
+	MinTime int64     `json:"min_time"`
+	MaxTime int64     `json:"max_time"`
+
+	// Hits is the number of requests (series, label names and label values) which touched the block.
+	Hits uint64 `json:"hits"`
+
+	// LastQueriedAt is the unix timestamp, in milliseconds, of the last request which touched the block.
+	LastQueriedAt int64 `json:"last_queried_at"`
+}
+
+// BlockQueryStatsSummary is the summary of the blocks query statistics of a tenant tracked by a store-gateway.
+type BlockQueryStatsSummary struct {
+	InstanceID string            `json:"instance_id,omitempty"`
+	UpdatedAt  int64             `json:"updated_at"`
+	Blocks     []BlockQueryStats `json:"blocks"`
+}
+
+// BlockQueryStatsPath returns the path of the blocks query statistics summary written by the store-gateway instance.
+func BlockQueryStatsPath(instanceID string) string {
+	return path.Join(tsdb.BlockQueryStatsDir, instanceID+".json")
+}
+
+// blockQueryStatsTracker keeps track of the query statistics of the blocks of a tenant.
+// Nil tracker ignores all calls.
+type blockQueryStatsTracker struct {
+	mtx sync.Mutex
+
+	// persisted holds the statistics as of the last persistence. It's nil until the summary
+	// previously stored in the bucket has been loaded.
+	persisted map[ulid.ULID]BlockQueryStats
+
+	// pending holds the statistics collected since the last persistence.
+	pending map[ulid.ULID]BlockQueryStats
+}
+
+func newBlockQueryStatsTracker() *blockQueryStatsTracker {
+	return &blockQueryStatsTracker{
+		pending: map[ulid.ULID]BlockQueryStats{},
+	}
+}
+
+// recordHits records a hit, at the given time, for each of the blocks touched by a request.
+func (t *blockQueryStatsTracker) recordHits(metas []*block.Meta, now time.Time) {
+	if t == nil || len(metas) == 0 {
+		return
+	}
+
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	for _, meta := range metas {
+		stats := t.pending[meta.ULID]
+		stats.BlockID = meta.ULID
+		stats.MinTime = meta.MinTime
+		stats.MaxTime = meta.MaxTime
+		stats.Hits++
+		stats.LastQueriedAt = now.UnixMilli()
+		t.pending[meta.ULID] = stats
+	}
+}
+
+// snapshot returns the statistics of all blocks, sorted by block ID.
+func (t *blockQueryStatsTracker) snapshot() []BlockQueryStats {
+	if t == nil {
+		return nil
+	}
+
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	return mergeBlockQueryStats(t.persisted, t.pending, nil)
+}
+
+// persist merges the statistics collected since the last persistence into the summary stored in the bucket.
+// Statistics of blocks not included in loaded are dropped from the summary.
+func (t *blockQueryStatsTracker) persist(ctx context.Context, bkt objstore.Bucket, instanceID string, loaded map[ulid.ULID]struct{}, now time.Time) error {
+	if t == nil {
+		return nil
+	}
+
+	t.mtx.Lock()
+	persisted := t.persisted
+	t.mtx.Unlock()
+
+	summaryPath := BlockQueryStatsPath(instanceID)
+	if persisted == nil {
+		var err error
+		if persisted, err = readBlockQueryStatsSummary(ctx, bkt, summaryPath); err != nil {
+			return err
+		}
+	}
+
+	// Take the pending statistics, so that hits recorded while uploading are not lost.
+	t.mtx.Lock()
+	pending := t.pending
+	t.pending = map[ulid.ULID]BlockQueryStats{}
+	t.mtx.Unlock()
+
+	summary := BlockQueryStatsSummary{
+		InstanceID: instanceID,
+		UpdatedAt:  now.UnixMilli(),
+		Blocks:     mergeBlockQueryStats(persisted, pending, loaded),
+	}
+
+	data, err := json.Marshal(summary)
+	if err == nil {
+		err = bkt.Upload(ctx, summaryPath, bytes.NewReader(data))
+	}
+	if err != nil {
+		// Put back the pending statistics, so that they're persisted next time.
+		t.mtx.Lock()
+		t.persisted = persisted
+		for id, stats := range pending {
+			t.pending[id] = mergeBlockQueryStatsEntry(stats, t.pending[id])
+		}
+		t.mtx.Unlock()
+		return errors.Wrap(err, "upload block query stats summary")
+	}
+
+	t.mtx.Lock()
+	t.persisted = make(map[ulid.ULID]BlockQueryStats, len(summary.Blocks))
+	for _, stats := range summary.Blocks {
+		t.persisted[stats.BlockID] = stats
+	}
+	t.mtx.Unlock()
+
+	return nil
+}
+
+func readBlockQueryStatsSummary(ctx context.Context, bkt objstore.BucketReader, summaryPath string) (map[ulid.ULID]BlockQueryStats, error) {
+	res := map[ulid.ULID]BlockQueryStats{}
+
+	reader, err := bkt.Get(ctx, summaryPath)
+	if bkt.IsObjNotFoundErr(err) {
+		return res, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "read block query stats summary")
+	}
+	defer reader.Close()
+
+	summary := BlockQueryStatsSummary{}
+	if err := json.NewDecoder(reader).Decode(&summary); err != nil {
+		// Start from scratch rather than failing forever because of a corrupted summary.
+		return res, nil
+	}
+
+	for _, stats := range summary.Blocks {
+		res[stats.BlockID] = stats
+	}
+	return res, nil
+}
+
+// mergeBlockQueryStats returns the sum of the two sets of statistics, sorted by block ID. If filter
+// is not nil, only the blocks included in filter are returned.
+func mergeBlockQueryStats(a, b map[ulid.ULID]BlockQueryStats, filter map[ulid.ULID]struct{}) []BlockQueryStats {
+	merged := make(map[ulid.ULID]BlockQueryStats, len(a)+len(b))
+	for _, set := range []map[ulid.ULID]BlockQueryStats{a, b} {
+		for id, stats := range set {
+			if filter != nil {
+				if _, ok := filter[id]; !ok {
+					continue
+				}
+			}
+			merged[id] = mergeBlockQueryStatsEntry(merged[id], stats)
+		}
+	}
+
+	res := make([]BlockQueryStats, 0, len(merged))
+	for _, stats := range merged {
+		res = append(res, stats)
+	}
+	slices.SortFunc(res, func(a, b BlockQueryStats) int {
+		return a.BlockID.Compare(b.BlockID)
+	})
+	return res
+}
+
+func mergeBlockQueryStatsEntry(a, b BlockQueryStats) BlockQueryStats {
+	if a.Hits == 0 {
+		return b
+	}
+	if b.Hits == 0 {
+		return a
+	}
+
+	a.Hits += b.Hits
+	a.LastQueriedAt = max(a.LastQueriedAt, b.LastQueriedAt)
+	return a
+}
+
+// BlockQueryStatsHandler returns the query statistics of the blocks of a tenant tracked by this store-gateway.
+func (s *StoreGateway) BlockQueryStatsHandler(w http.ResponseWriter, req *http.Request) {
+	tenantID := mux.Vars(req)["tenant"]
+	if tenantID == "" {
+		http.Error(w, "Tenant ID can't be empty", http.StatusBadRequest)
+		return
+	}
+
+	if !s.storageCfg.BucketStore.BlockQueryStatsEnabled {
+		http.Error(w, "Block query stats are disabled", http.StatusNotFound)
+		return
+	}
+
+	store := s.stores.getStore(tenantID)
+	if store == nil {
+		http.Error(w, "Tenant not loaded by this store-gateway", http.StatusNotFound)
+		return
+	}
+
+	util.WriteJSONResponse(w, BlockQueryStatsSummary{
+		InstanceID: s.gatewayCfg.ShardingRing.InstanceID,
+		UpdatedAt:  time.Now().UnixMilli(),
+		Blocks:     store.blockQueryStats.snapshot(),
+	})
+}
@@ -0,0 +1,103 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package storegateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/oklog/ulid"
+	"github.com/prometheus/prometheus/tsdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/objstore"
+
+	"github.com/grafana/mimir/pkg/storage/bucket"
+	"github.com/grafana/mimir/pkg/storage/tsdb/block"
+)
+
+func TestBlockQueryStatsTracker(t *testing.T) {
+	var (
+		ctx    = context.Background()
+		bkt    = objstore.NewInMemBucket()
+		block1 = &block.Meta{BlockMeta: tsdb.BlockMeta{ULID: ulid.MustNew(1, nil), MinTime: 0, MaxTime: 10}}
+		block2 = &block.Meta{BlockMeta: tsdb.BlockMeta{ULID: ulid.MustNew(2, nil), MinTime: 10, MaxTime: 20}}
+		block3 = &block.Meta{BlockMeta: tsdb.BlockMeta{ULID: ulid.MustNew(3, nil), MinTime: 20, MaxTime: 30}}
+		loaded = map[ulid.ULID]struct{}{block1.ULID: {}, block2.ULID: {}}
+	)
+
+	readSummary := func(t *testing.T) BlockQueryStatsSummary {
+		reader, err := bkt.Get(ctx, BlockQueryStatsPath("instance-1"))
+		require.NoError(t, err)
+		defer reader.Close()
+
+		summary := BlockQueryStatsSummary{}
+		require.NoError(t, json.NewDecoder(reader).Decode(&summary))
+		return summary
+	}
+
+	// Start from a previously persisted summary.
+	previous, err := json.Marshal(BlockQueryStatsSummary{Blocks: []BlockQueryStats{
+		{BlockID: block1.ULID, MinTime: 0, MaxTime: 10, Hits: 5, LastQueriedAt: 1000},
+		{BlockID: block3.ULID, MinTime: 20, MaxTime: 30, Hits: 1, LastQueriedAt: 1000},
+	}})
+	require.NoError(t, err)
+	require.NoError(t, bkt.Upload(ctx, BlockQueryStatsPath("instance-1"), bytes.NewReader(previous)))
+
+	tracker := newBlockQueryStatsTracker()
+	tracker.recordHits([]*block.Meta{block1}, time.UnixMilli(2000))
+	tracker.recordHits([]*block.Meta{block1}, time.UnixMilli(3000))
+	tracker.recordHits([]*block.Meta{block2}, time.UnixMilli(2500))
+
+	assert.Equal(t, []BlockQueryStats{
+		{BlockID: block1.ULID, MinTime: 0, MaxTime: 10, Hits: 2, LastQueriedAt: 3000},
+		{BlockID: block2.ULID, MinTime: 10, MaxTime: 20, Hits: 1, LastQueriedAt: 2500},
+	}, tracker.snapshot())
+
+	// Persisting merges the pending stats with the previous summary, dropping blocks which are not loaded anymore.
+	require.NoError(t, tracker.persist(ctx, bkt, "instance-1", loaded, time.UnixMilli(4000)))
+
+	expected := []BlockQueryStats{
+		{BlockID: block1.ULID, MinTime: 0, MaxTime: 10, Hits: 7, LastQueriedAt: 3000},
+		{BlockID: block2.ULID, MinTime: 10, MaxTime: 20, Hits: 1, LastQueriedAt: 2500},
+	}
+	summary := readSummary(t)
+	assert.Equal(t, "instance-1", summary.InstanceID)
+	assert.Equal(t, int64(4000), summary.UpdatedAt)
+	assert.Equal(t, expected, summary.Blocks)
+	assert.Equal(t, expected, tracker.snapshot())
+
+	// Stats keep accumulating after the persistence.
+	tracker.recordHits([]*block.Meta{block2}, time.UnixMilli(5000))
+	require.NoError(t, tracker.persist(ctx, bkt, "instance-1", loaded, time.UnixMilli(6000)))
+
+	assert.Equal(t, []BlockQueryStats{
+		{BlockID: block1.ULID, MinTime: 0, MaxTime: 10, Hits: 7, LastQueriedAt: 3000},
+		{BlockID: block2.ULID, MinTime: 10, MaxTime: 20, Hits: 2, LastQueriedAt: 5000},
+	}, readSummary(t).Blocks)
+}
+
+func TestBlockQueryStatsTracker_ShouldKeepPendingStatsOnUploadFailure(t *testing.T) {
+	ctx := context.Background()
+	meta := &block.Meta{BlockMeta: tsdb.BlockMeta{ULID: ulid.MustNew(1, nil), MinTime: 0, MaxTime: 10}}
+
+	bkt := &bucket.ClientMock{}
+	bkt.MockGet(BlockQueryStatsPath("instance-1"), "", nil)
+	bkt.On("Upload", mock.Anything, BlockQueryStatsPath("instance-1"), mock.Anything).Return(errors.New("failed upload")).Once()
+	bkt.On("Upload", mock.Anything, BlockQueryStatsPath("instance-1"), mock.Anything).Return(nil).Once()
+
+	tracker := newBlockQueryStatsTracker()
+	tracker.recordHits([]*block.Meta{meta}, time.UnixMilli(1000))
+	require.Error(t, tracker.persist(ctx, bkt, "instance-1", map[ulid.ULID]struct{}{meta.ULID: {}}, time.Now()))
+
+	tracker.recordHits([]*block.Meta{meta}, time.UnixMilli(2000))
+	assert.Equal(t, []BlockQueryStats{{BlockID: meta.ULID, MinTime: 0, MaxTime: 10, Hits: 2, LastQueriedAt: 2000}}, tracker.snapshot())
+
+	require.NoError(t, tracker.persist(ctx, bkt, "instance-1", map[ulid.ULID]struct{}{meta.ULID: {}}, time.Now()))
+	assert.Equal(t, []BlockQueryStats{{BlockID: meta.ULID, MinTime: 0, MaxTime: 10, Hits: 2, LastQueriedAt: 2000}}, tracker.snapshot())
+}
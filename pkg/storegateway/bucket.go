@@ -93,6 +93,10 @@ type BucketStore struct {
 	indexReaderPool *indexheader.ReaderPool
 	seriesHashCache *hashcache.SeriesHashCache
 
+	// seriesHashCachePreloadEnabled controls whether the series hashes uploaded by the compactor
+	// are loaded into the series hash cache when a block is loaded.
+	seriesHashCachePreloadEnabled bool
+
 	snapshotter services.Service
 
 	// Set of blocks that have the same labels
@@ -127,6 +131,15 @@ type BucketStore struct {
 
 	// postingsStrategy is a strategy shared among all tenants.
 	postingsStrategy postingsSelectionStrategy
+
+	// blockQueryStats keeps track of the query statistics of each block.
+	blockQueryStats *blockQueryStatsTracker
+
+	// blocksQueryLookback returns how far back in time the blocks can be queried. 0 or nil means no limit.
+	blocksQueryLookback func() time.Duration
+
+	// syncMtx serializes the blocks sync with the reload of the blocks whose index-header is corrupted.
+	syncMtx sync.Mutex
 }
 
 type noopCache struct{}
@@ -197,6 +210,13 @@ func WithLazyLoadingGate(lazyLoadingGate gate.Gate) BucketStoreOption {
 	}
 }
 
+// WithBlocksQueryLookback sets the function returning how far back in time the blocks can be queried.
+func WithBlocksQueryLookback(blocksQueryLookback func() time.Duration) BucketStoreOption {
+	return func(s *BucketStore) {
+		s.blocksQueryLookback = blocksQueryLookback
+	}
+}
+
 // NewBucketStore creates a new bucket backed store that implements the store API against
 // an object store bucket. It is optimized to work against high latency backends.
 func NewBucketStore(
@@ -214,25 +234,29 @@ func NewBucketStore(
 	options ...BucketStoreOption,
 ) (*BucketStore, error) {
 	s := &BucketStore{
-		logger:                      log.NewNopLogger(),
-		bkt:                         bkt,
-		fetcher:                     fetcher,
-		dir:                         dir,
-		indexCache:                  noopCache{},
-		blockSet:                    newBucketBlockSet(),
-		blockSyncConcurrency:        bucketStoreConfig.BlockSyncConcurrency,
-		queryGate:                   gate.NewNoop(),
-		lazyLoadingGate:             gate.NewNoop(),
-		chunksLimiterFactory:        chunksLimiterFactory,
-		seriesLimiterFactory:        seriesLimiterFactory,
-		partitioners:                partitioners,
-		postingOffsetsInMemSampling: bucketStoreConfig.PostingOffsetsInMemSampling,
-		indexHeaderCfg:              bucketStoreConfig.IndexHeader,
-		seriesHashCache:             seriesHashCache,
-		metrics:                     metrics,
-		userID:                      userID,
-		maxSeriesPerBatch:           bucketStoreConfig.StreamingBatchSize,
-		postingsStrategy:            postingsStrategy,
+		logger:                        log.NewNopLogger(),
+		bkt:                           bkt,
+		fetcher:                       fetcher,
+		dir:                           dir,
+		indexCache:                    noopCache{},
+		blockSet:                      newBucketBlockSet(),
+		blockSyncConcurrency:          bucketStoreConfig.BlockSyncConcurrency,
+		queryGate:                     gate.NewNoop(),
+		lazyLoadingGate:               gate.NewNoop(),
+		chunksLimiterFactory:          chunksLimiterFactory,
+		seriesLimiterFactory:          seriesLimiterFactory,
+		partitioners:                  partitioners,
+		postingOffsetsInMemSampling:   bucketStoreConfig.PostingOffsetsInMemSampling,
+		indexHeaderCfg:                bucketStoreConfig.IndexHeader,
+		seriesHashCache:               seriesHashCache,
+		seriesHashCachePreloadEnabled: bucketStoreConfig.SeriesHashCachePreloadEnabled,
+		metrics:                       metrics,
+		userID:                        userID,
+		maxSeriesPerBatch:             bucketStoreConfig.StreamingBatchSize,
+		postingsStrategy:              postingsStrategy,
+	}
+	if bucketStoreConfig.BlockQueryStatsEnabled {
+		s.blockQueryStats = newBlockQueryStatsTracker()
 	}
 
 	for _, option := range options {
@@ -303,6 +327,9 @@ func (s *BucketStore) SyncBlocks(ctx context.Context) error {
 }
 
 func (s *BucketStore) syncBlocks(ctx context.Context) error {
+	s.syncMtx.Lock()
+	defer s.syncMtx.Unlock()
+
 	metas, _, metaFetchErr := s.fetcher.Fetch(ctx)
 	// For partial view allow adding new blocks at least.
 	if metaFetchErr != nil && metas == nil {
@@ -501,6 +528,47 @@ func (s *BucketStore) addBlock(ctx context.Context, meta *block.Meta) (err error
 	return nil
 }
 
+// preloadSeriesHashesInBackground starts loading the series hashes of the block into the series hash cache
+// the first time the block is queried with query sharding, so that the series hashes of the blocks which are
+// never queried with query sharding are not downloaded. The caller must hold the block open.
+func (s *BucketStore) preloadSeriesHashesInBackground(b *bucketBlock) {
+	if !s.seriesHashCachePreloadEnabled {
+		return
+	}
+
+	b.seriesHashesPreload.Do(func() {
+		// Prevent the block from being closed while loading its series hashes.
+		b.pendingReaders.Add(1)
+		go func() {
+			defer b.pendingReaders.Done()
+			s.preloadSeriesHashes(b.seriesHashesPreloadCtx, b.meta)
+		}()
+	})
+}
+
+// preloadSeriesHashes loads the series hashes uploaded by the compactor alongside the block into the
+// series hash cache. Blocks without series hashes are skipped, and failures are only logged given
+// the hashes are computed from the series labels when they're not cached.
+func (s *BucketStore) preloadSeriesHashes(ctx context.Context, meta *block.Meta) {
+	bkt := s.bkt.ReaderWithExpectedErrs(s.bkt.IsObjNotFoundErr)
+	r, err := bkt.Get(ctx, path.Join(meta.ULID.String(), block.SeriesHashesFilename))
+	if s.bkt.IsObjNotFoundErr(err) {
+		return
+	}
+	if err != nil {
+		if ctx.Err() == nil {
+			level.Warn(s.logger).Log("msg", "failed to download series hashes", "id", meta.ULID, "err", err)
+		}
+		return
+	}
+	defer runutil.CloseWithLogOnErr(s.logger, r, "series hashes")
+
+	cache := s.seriesHashCache.GetBlockCache(meta.ULID.String())
+	if err := block.ReadSeriesHashes(r, cache.Store); err != nil && ctx.Err() == nil {
+		level.Warn(s.logger).Log("msg", "failed to load series hashes", "id", meta.ULID, "err", err)
+	}
+}
+
 func (s *BucketStore) removeBlock(id ulid.ULID) (returnErr error) {
 	defer func() {
 		if returnErr != nil {
@@ -526,6 +594,63 @@ func (s *BucketStore) removeBlock(id ulid.ULID) (returnErr error) {
 	return nil
 }
 
+// PersistBlockQueryStats merges the query statistics collected since the last call into the summary stored
+// in the bucket by the store-gateway instance. Only the blocks currently loaded are kept in the summary.
+func (s *BucketStore) PersistBlockQueryStats(ctx context.Context, bkt objstore.Bucket, instanceID string) error {
+	loaded := map[ulid.ULID]struct{}{}
+	s.blockSet.forEach(func(b *bucketBlock) {
+		loaded[b.meta.ULID] = struct{}{}
+	})
+
+	return s.blockQueryStats.persist(ctx, bkt, instanceID, loaded, time.Now())
+}
+
+// BlockWarmupResult is the outcome of warming up a block.
+type BlockWarmupResult struct {
+	ID      ulid.ULID
+	MinTime int64
+	MaxTime int64
+	Err     error
+}
+
+// WarmupBlocks loads the index-headers of the blocks overlapping the input time range (in milliseconds).
+// When the index-headers are lazy loaded, they're also pinned until pinUntil, so that they're not unloaded
+// because of inactivity before the time range gets queried.
+func (s *BucketStore) WarmupBlocks(ctx context.Context, minT, maxT int64, pinUntil time.Time) []BlockWarmupResult {
+	var blocks []*bucketBlock
+	s.blockSet.filter(minT, maxT, nil, func(b *bucketBlock) {
+		// Prevent the block from being closed while warming it up.
+		b.pendingReaders.Add(1)
+		blocks = append(blocks, b)
+	})
+
+	results := make([]BlockWarmupResult, len(blocks))
+	g := errgroup.Group{}
+	g.SetLimit(s.blockSyncConcurrency)
+
+	for i, b := range blocks {
+		g.Go(func() error {
+			defer b.pendingReaders.Done()
+
+			var err error
+			if r, ok := b.indexHeaderReader.(*indexheader.LazyBinaryReader); ok {
+				err = r.Pin(ctx, pinUntil)
+			} else {
+				_, err = b.indexHeaderReader.IndexVersion(ctx)
+			}
+			if err != nil {
+				level.Warn(s.logger).Log("msg", "failed to warm up block", "id", b.meta.ULID, "err", err)
+			}
+
+			results[i] = BlockWarmupResult{ID: b.meta.ULID, MinTime: b.meta.MinTime, MaxTime: b.meta.MaxTime, Err: err}
+			return nil
+		})
+	}
+
+	_ = g.Wait()
+	return results
+}
+
 func (s *BucketStore) closeAllBlocks() error {
 	return s.blockSet.closeAll()
 }
@@ -595,7 +720,10 @@ func (s *BucketStore) Series(req *storepb.SeriesRequest, srv storegatewaypb.Stor
 
 	logSeriesRequestToSpan(srv.Context(), s.logger, req.MinTime, req.MaxTime, matchers, reqBlockMatchers, shardSelector, req.StreamingChunksBatchSize)
 
-	blocks, indexReaders, chunkReaders := s.openBlocksForReading(ctx, req.SkipChunks, req.MinTime, req.MaxTime, reqBlockMatchers, stats)
+	resHints := &hintspb.SeriesResponseHints{}
+	blocks, indexReaders, chunkReaders, minQueryableT := s.openBlocksForReading(ctx, req.SkipChunks, req.MinTime, req.MaxTime, reqBlockMatchers, resHints.AddQueriedBlock, stats)
+	// Blocks straddling the blocks query lookback are queried, but their samples older than the lookback must not be returned.
+	req.MinTime = minQueryableT
 	// We must keep the readers open until all their data has been sent.
 	for _, r := range indexReaders {
 		defer runutil.CloseWithLogOnErr(s.logger, r, "close block index reader")
@@ -617,10 +745,7 @@ func (s *BucketStore) Series(req *storepb.SeriesRequest, srv storegatewaypb.Stor
 	}
 	defer done()
 
-	var (
-		streamingIterators *streamingSeriesIterators
-		resHints           = &hintspb.SeriesResponseHints{}
-	)
+	var streamingIterators *streamingSeriesIterators
 	for _, b := range blocks {
 		resHints.AddQueriedBlock(b.meta.ULID)
 
@@ -710,8 +835,13 @@ func mapSeriesError(err error) error {
 		return err
 	}
 
-	var stGwErr storeGatewayError
+	var (
+		stGwErr      storeGatewayError
+		corruptedErr corruptedBlockError
+	)
 	switch {
+	case errors.As(err, &corruptedErr):
+		return status.Error(codes.DataLoss, corruptedErr.Error())
 	case errors.As(err, &stGwErr):
 		switch cause := stGwErr.errorCause(); cause {
 		case mimirpb.INSTANCE_LIMIT:
@@ -1134,6 +1264,7 @@ func (s *BucketStore) getSeriesIteratorFromBlocks(
 		var blockSeriesHashCache *hashcache.BlockSeriesHashCache
 		if shardSelector != nil {
 			blockSeriesHashCache = s.seriesHashCache.GetBlockCache(b.meta.ULID.String())
+			s.preloadSeriesHashesInBackground(b)
 		}
 		g.Go(func() error {
 			part, err := openBlockSeriesChunkRefsSetsIterator(
@@ -1300,7 +1431,7 @@ func (s *BucketStore) recordSeriesHashCacheStats(stats *queryStats) {
 	s.metrics.seriesHashCacheHits.Add(float64(stats.seriesHashCacheHits))
 }
 
-func (s *BucketStore) openBlocksForReading(ctx context.Context, skipChunks bool, minT, maxT int64, blockMatchers []*labels.Matcher, stats *safeQueryStats) ([]*bucketBlock, map[ulid.ULID]*bucketIndexReader, map[ulid.ULID]chunkReader) {
+func (s *BucketStore) openBlocksForReading(ctx context.Context, skipChunks bool, minT, maxT int64, blockMatchers []*labels.Matcher, onExcluded func(ulid.ULID), stats *safeQueryStats) ([]*bucketBlock, map[ulid.ULID]*bucketIndexReader, map[ulid.ULID]chunkReader, int64) {
 	span, spanCtx := opentracing.StartSpanFromContext(ctx, "bucket_store_open_blocks_for_reading")
 	defer span.Finish()
 
@@ -1311,7 +1442,7 @@ func (s *BucketStore) openBlocksForReading(ctx context.Context, skipChunks bool,
 	)
 
 	// Find all blocks owned by this store-gateway instance and matching the request.
-	s.blockSet.filter(minT, maxT, blockMatchers, func(b *bucketBlock) {
+	minQueryableT := s.filterQueryableBlocks(minT, maxT, blockMatchers, onExcluded, func(b *bucketBlock) {
 		blocks = append(blocks, b)
 
 		// Unlike below, ensureIndexHeaderLoaded() does not retain the context after it returns.
@@ -1333,7 +1464,49 @@ func (s *BucketStore) openBlocksForReading(ctx context.Context, skipChunks bool,
 		chunkReaders[b.meta.ULID] = b.chunkReader(ctx)
 	})
 
-	return blocks, indexReaders, chunkReaders
+	return blocks, indexReaders, chunkReaders, minQueryableT
+}
+
+// filterQueryableBlocks calls fn for each block overlapping the closed interval [minT, maxT] and matching
+// the block matchers. The blocks whose time range is entirely older than the blocks query lookback are
+// excluded from the query and passed to onExcluded instead, so that they can be reported as queried: they
+// still exist in the object storage and the querier would otherwise look for them in other store-gateways.
+//
+// It returns minT clamped to the blocks query lookback: the blocks straddling the lookback are queried, so
+// the callers reading samples must not return the ones older than the returned timestamp.
+func (s *BucketStore) filterQueryableBlocks(minT, maxT int64, blockMatchers []*labels.Matcher, onExcluded func(ulid.ULID), fn func(b *bucketBlock)) int64 {
+	if s.blockQueryStats != nil {
+		// Record the hits once the blocks have been filtered, so that the tracker is locked once per request.
+		var queried []*block.Meta
+		next := fn
+		fn = func(b *bucketBlock) {
+			queried = append(queried, b.meta)
+			next(b)
+		}
+		defer func() {
+			s.blockQueryStats.recordHits(queried, time.Now())
+		}()
+	}
+
+	var lookback time.Duration
+	if s.blocksQueryLookback != nil {
+		lookback = s.blocksQueryLookback()
+	}
+	if lookback <= 0 {
+		s.blockSet.filter(minT, maxT, blockMatchers, fn)
+		return minT
+	}
+
+	minQueryableT := time.Now().Add(-lookback).UnixMilli()
+	s.blockSet.filter(minT, maxT, blockMatchers, func(b *bucketBlock) {
+		// Blocks time range is half-open: [MinTime, MaxTime).
+		if b.meta.MaxTime <= minQueryableT {
+			onExcluded(b.meta.ULID)
+			return
+		}
+		fn(b)
+	})
+	return max(minT, minQueryableT)
 }
 
 // LabelNames implements the storegatewaypb.StoreGatewayServer interface.
@@ -1372,7 +1545,7 @@ func (s *BucketStore) LabelNames(ctx context.Context, req *storepb.LabelNamesReq
 	var blocksQueriedByBlockMeta = make(map[blockQueriedMeta]int)
 	seriesLimiter := s.seriesLimiterFactory(s.metrics.queriesDropped.WithLabelValues("series"))
 
-	s.blockSet.filter(req.Start, req.End, reqBlockMatchers, func(b *bucketBlock) {
+	s.filterQueryableBlocks(req.Start, req.End, reqBlockMatchers, resHints.AddQueriedBlock, func(b *bucketBlock) {
 		resHints.AddQueriedBlock(b.meta.ULID)
 		blocksQueriedByBlockMeta[newBlockQueriedMeta(b.meta)]++
 
@@ -1555,7 +1728,7 @@ func (s *BucketStore) LabelValues(ctx context.Context, req *storepb.LabelValuesR
 
 	var setsMtx sync.Mutex
 	var sets [][]string
-	s.blockSet.filter(req.Start, req.End, reqBlockMatchers, func(b *bucketBlock) {
+	s.filterQueryableBlocks(req.Start, req.End, reqBlockMatchers, resHints.AddQueriedBlock, func(b *bucketBlock) {
 		resHints.AddQueriedBlock(b.meta.ULID)
 
 		// This index reader shouldn't be used for ExpandedPostings, since it doesn't have the correct strategy.
@@ -1981,6 +2154,12 @@ type bucketBlock struct {
 
 	// Indicates whether the block was queried.
 	queried atomic.Bool
+
+	// seriesHashesPreload loads the series hashes of the block once, when it's first queried with query sharding.
+	// The loading is canceled when the block is closed.
+	seriesHashesPreload       sync.Once
+	seriesHashesPreloadCtx    context.Context
+	seriesHashesPreloadCancel context.CancelFunc
 }
 
 func newBucketBlock(
@@ -2008,6 +2187,7 @@ func newBucketBlock(
 		// Inject the block ID as a label to allow to match blocks by ID.
 		blockLabels: labels.FromStrings(block.BlockIDLabel, meta.ULID.String()),
 	}
+	b.seriesHashesPreloadCtx, b.seriesHashesPreloadCancel = context.WithCancel(context.Background())
 
 	// Get object handles for all chunk files (segment files) from meta.json, if available.
 	if len(meta.Thanos.SegmentFiles) > 0 {
@@ -2115,6 +2295,9 @@ func (b *bucketBlock) Close() error {
 	b.closed = true
 	b.closedMtx.Unlock()
 
+	if b.seriesHashesPreloadCancel != nil {
+		b.seriesHashesPreloadCancel()
+	}
 	b.pendingReaders.Wait()
 
 	return b.indexHeaderReader.Close()
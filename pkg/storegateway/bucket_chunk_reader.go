@@ -143,7 +143,7 @@ func (r *bucketChunkReader) loadChunks(ctx context.Context, res []seriesChunks,
 		}
 		chunkDataLen, err := binary.ReadUvarint(reader)
 		if err != nil {
-			return errors.Wrap(err, "parsing chunk length")
+			return newCorruptedBlockError(r.block.meta.ULID, errors.Wrap(err, "parsing chunk length"))
 		}
 		// We ignore the crc32 after the chunk data.
 		chunkEncDataLen := chunks.ChunkEncodingSize + int(chunkDataLen)
@@ -155,7 +155,7 @@ func (r *bucketChunkReader) loadChunks(ctx context.Context, res []seriesChunks,
 			if chunksLeft := len(pIdxs) - 1 - i; chunksLeft != 0 {
 				// Unexpected EOF for last chunk could be a valid case if we have underestimated the length of the chunk.
 				// Any other errors are definitely unexpected.
-				return fmt.Errorf("underread with %d more remaining chunks in seq %d start %d end %d", chunksLeft, seq, part.Start, part.End)
+				return newCorruptedBlockError(r.block.meta.ULID, fmt.Errorf("underread with %d more remaining chunks in seq %d start %d end %d", chunksLeft, seq, part.Start, part.End))
 			}
 			if err = r.fetchChunkRemainder(ctx, seq, int64(reader.offset), int64(chunkEncDataLen-fullyRead), cb[fullyRead:], localStats); err != nil {
 				return errors.Wrapf(err, "refetching chunk seq %d offset %x length %d", seq, pIdx.offset, pIdx.length)
@@ -166,7 +166,7 @@ func (r *bucketChunkReader) loadChunks(ctx context.Context, res []seriesChunks,
 
 		err = populateChunk(&(res[pIdx.seriesEntry].chks[pIdx.chunkEntry]), cb)
 		if err != nil {
-			return errors.Wrap(err, "populate chunk")
+			return newCorruptedBlockError(r.block.meta.ULID, errors.Wrap(err, "populate chunk"))
 		}
 		localStats.chunksTouched++
 		// Also account for the crc32 at the end. We ignore the bytes, but include the size of crc32 and the length varint size encoding.
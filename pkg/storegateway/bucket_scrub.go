@@ -0,0 +1,267 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package storegateway
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math/rand"
+	"os"
+
+	"github.com/go-kit/log/level"
+	"github.com/grafana/dskit/runutil"
+	"github.com/oklog/ulid"
+	"github.com/pkg/errors"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/prometheus/prometheus/tsdb/chunks"
+	"github.com/prometheus/prometheus/tsdb/index"
+	"github.com/thanos-io/objstore"
+	"golang.org/x/exp/slices"
+
+	mimir_tsdb "github.com/grafana/mimir/pkg/storage/tsdb"
+	"github.com/grafana/mimir/pkg/storage/tsdb/block"
+	"github.com/grafana/mimir/pkg/storegateway/indexheader"
+	streamindex "github.com/grafana/mimir/pkg/storegateway/indexheader/index"
+	"github.com/grafana/mimir/pkg/util/pool"
+)
+
+// maxScrubPostingsLength is the max length of the postings list read to sample the series whose chunks are scrubbed,
+// so that scrubbing a block doesn't read the postings of all its series.
+const maxScrubPostingsLength = 64 * 1024
+
+var castagnoliTable = crc32.MakeTable(crc32.Castagnoli)
+
+// ScrubBlocks verifies the integrity of the loaded blocks, one block at a time. The index-headers stored on the
+// local disk are verified against their checksums, and blocks whose index-header is corrupted are reloaded,
+// rebuilding the index-header from the object storage. Then the chunks of a random sample of chunksSampleSize
+// series of each block are read from the object storage and verified against their checksums. The blocks with
+// corrupted chunks in the object storage are marked for no-compaction in userBkt, so that the corruption isn't
+// propagated to the compacted blocks.
+func (s *BucketStore) ScrubBlocks(ctx context.Context, userBkt objstore.Bucket, chunksSampleSize int) {
+	var blocks []*bucketBlock
+	s.blockSet.forEach(func(b *bucketBlock) {
+		blocks = append(blocks, b)
+	})
+
+	for _, b := range blocks {
+		if ctx.Err() != nil {
+			return
+		}
+
+		if !s.scrubIndexHeader(ctx, b.meta) {
+			continue
+		}
+		if chunksSampleSize > 0 && s.scrubChunks(ctx, b, chunksSampleSize) {
+			s.markCorruptedBlock(ctx, userBkt, b.meta.ULID)
+		}
+	}
+}
+
+// scrubIndexHeader verifies the index-header of the block stored on the local disk, and reloads the block if
+// the index-header is corrupted. It returns false if the block has been reloaded.
+func (s *BucketStore) scrubIndexHeader(ctx context.Context, meta *block.Meta) bool {
+	err := indexheader.VerifyIndexHeader(s.dir, meta.ULID, s.metrics.indexHeaderReaderMetrics)
+	if errors.Is(err, os.ErrNotExist) {
+		// The index-header has not been built yet (e.g. lazy loading is enabled and the block has never been queried).
+		return true
+	}
+	s.metrics.indexHeaderScrubs.Inc()
+	if err == nil {
+		return true
+	}
+
+	s.metrics.indexHeaderCorrupted.Inc()
+	level.Warn(s.logger).Log("msg", "detected corrupted index-header on local disk, reloading block", "id", meta.ULID, "err", err)
+
+	s.syncMtx.Lock()
+	defer s.syncMtx.Unlock()
+
+	if !s.blockSet.contains(meta.ULID) {
+		// The block has been removed by a blocks sync in the meanwhile.
+		return false
+	}
+	if err := s.removeBlock(meta.ULID); err != nil {
+		level.Warn(s.logger).Log("msg", "failed to remove block with corrupted index-header", "id", meta.ULID, "err", err)
+		return false
+	}
+	// If loading the block fails, it will be retried at the next blocks sync.
+	_ = s.addBlock(ctx, meta)
+	return false
+}
+
+// scrubChunks verifies the checksums of one chunk of each series in a random sample of the block's series.
+// The chunks are read from the object storage, through the chunks cache if any. It returns true if a corrupted
+// chunk has been found, in which case the remaining chunks of the sample are not verified.
+func (s *BucketStore) scrubChunks(ctx context.Context, b *bucketBlock, sampleSize int) bool {
+	// The index reader prevents the block from being closed while its chunks are being verified.
+	indexr := b.indexReader(s.postingsStrategy)
+	defer runutil.CloseWithLogOnErr(s.logger, indexr, "close index reader used to scrub chunks")
+
+	refs, err := sampleChunkRefs(ctx, b.indexHeaderReader, indexr, sampleSize)
+	if err != nil {
+		level.Warn(s.logger).Log("msg", "failed to sample the chunks to scrub", "id", b.meta.ULID, "err", err)
+		return false
+	}
+
+	for _, ref := range refs {
+		if ctx.Err() != nil {
+			return false
+		}
+
+		err := verifyChunk(ctx, b, ref)
+		var corruptedErr corruptedBlockError
+		switch {
+		case err == nil:
+			s.metrics.chunkScrubs.Inc()
+		case errors.As(err, &corruptedErr):
+			s.metrics.chunkScrubs.Inc()
+			s.metrics.chunkCorrupted.Inc()
+			level.Warn(s.logger).Log("msg", "detected corrupted chunk", "id", b.meta.ULID, "segment_file", chunkSegmentFile(ref), "offset", chunkOffset(ref), "err", err)
+			return true
+		default:
+			level.Warn(s.logger).Log("msg", "failed to read the chunk to scrub", "id", b.meta.ULID, "segment_file", chunkSegmentFile(ref), "offset", chunkOffset(ref), "err", err)
+		}
+	}
+	return false
+}
+
+// markCorruptedBlock marks the block with corrupted chunks in the object storage for no-compaction. The block keeps
+// being queried, because all the replicas read the same corrupted chunks from the object storage.
+func (s *BucketStore) markCorruptedBlock(ctx context.Context, userBkt objstore.Bucket, id ulid.ULID) {
+	if err := block.MarkForNoCompact(ctx, s.logger, userBkt, id, block.CriticalNoCompactReason, "corrupted chunk detected by the store-gateway scrubber", s.metrics.chunkCorruptedMarked); err != nil {
+		level.Warn(s.logger).Log("msg", "failed to mark block with corrupted chunks for no-compaction", "id", id, "err", err)
+	}
+}
+
+// sampleChunkRefs returns the reference of a random chunk for each series in a random sample of sampleSize
+// series of the block. The series are sampled from the postings list of a random label value, among the ones
+// whose postings list is shorter than maxScrubPostingsLength.
+func sampleChunkRefs(ctx context.Context, indexHeader indexheader.Reader, indexr *bucketIndexReader, sampleSize int) ([]chunks.ChunkRef, error) {
+	lbl, ok, err := sampleScrubPostingsLabel(ctx, indexHeader)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, nil
+	}
+
+	stats := newSafeQueryStats()
+	postings, err := indexr.FetchPostings(ctx, []labels.Label{lbl}, stats)
+	if err != nil {
+		return nil, errors.Wrap(err, "fetch postings")
+	}
+	seriesRefs, err := index.ExpandPostings(postings[0])
+	if err != nil {
+		return nil, errors.Wrap(err, "expand postings")
+	}
+
+	// Pick the sample with a partial Fisher-Yates shuffle.
+	sampleSize = min(sampleSize, len(seriesRefs))
+	for i := 0; i < sampleSize; i++ {
+		j := i + rand.Intn(len(seriesRefs)-i)
+		seriesRefs[i], seriesRefs[j] = seriesRefs[j], seriesRefs[i]
+	}
+	sample := seriesRefs[:sampleSize]
+	slices.Sort(sample)
+
+	loaded, err := indexr.preloadSeries(ctx, sample, stats)
+	if err != nil {
+		return nil, errors.Wrap(err, "preload series")
+	}
+
+	lsetPool := pool.NewSlabPool[symbolizedLabel](symbolizedLabelsPool, 1024)
+	defer lsetPool.Release()
+
+	var (
+		chks []chunks.Meta
+		refs = make([]chunks.ChunkRef, 0, len(sample))
+	)
+	for _, ref := range sample {
+		ok, _, err := loaded.unsafeLoadSeries(storage.SeriesRef(ref), &chks, false, newQueryStats(), lsetPool)
+		if err != nil {
+			return nil, errors.Wrap(err, "load series")
+		}
+		if ok && len(chks) > 0 {
+			refs = append(refs, chks[rand.Intn(len(chks))].Ref)
+		}
+	}
+	return refs, nil
+}
+
+// sampleScrubPostingsLabel returns a random label value of the block whose postings list is shorter than
+// maxScrubPostingsLength. The label names are looked up in random order, and the returned bool is false if
+// no label value has a short enough postings list.
+func sampleScrubPostingsLabel(ctx context.Context, indexHeader indexheader.Reader) (labels.Label, bool, error) {
+	names, err := indexHeader.LabelNames(ctx)
+	if err != nil {
+		return labels.Label{}, false, errors.Wrap(err, "read label names")
+	}
+	rand.Shuffle(len(names), func(i, j int) { names[i], names[j] = names[j], names[i] })
+
+	for _, name := range names {
+		offsets, err := indexHeader.LabelValuesOffsets(ctx, name, "", nil)
+		if err != nil {
+			return labels.Label{}, false, errors.Wrapf(err, "read postings offsets of label %s", name)
+		}
+		offsets = slices.DeleteFunc(offsets, func(o streamindex.PostingListOffset) bool {
+			return o.Off.End-o.Off.Start > maxScrubPostingsLength
+		})
+		if len(offsets) > 0 {
+			return labels.Label{Name: name, Value: offsets[rand.Intn(len(offsets))].LabelValue}, true, nil
+		}
+	}
+	return labels.Label{}, false, nil
+}
+
+// verifyChunk reads the chunk from the object storage and verifies its CRC32 checksum. A corruptedBlockError
+// is returned if the chunk is corrupted.
+func verifyChunk(ctx context.Context, b *bucketBlock, ref chunks.ChunkRef) error {
+	seq, offset := chunkSegmentFile(ref), int64(chunkOffset(ref))
+
+	// The length of the chunk isn't stored in the index, so it's read first. A chunk is always longer
+	// than the max length of the varint, because it's followed by the encoding and the checksum.
+	lengthBuf, err := readChunkRange(ctx, b, seq, offset, binary.MaxVarintLen32)
+	if err != nil {
+		return err
+	}
+	dataLen, n := binary.Uvarint(lengthBuf)
+	if n <= 0 {
+		return newCorruptedBlockError(b.meta.ULID, errors.New("parsing chunk length"))
+	}
+	// The length is read from the object storage, so it's capped before allocating the buffer for the chunk.
+	// A length beyond the end of the segment file is reported as a truncated chunk when reading it.
+	if dataLen > mimir_tsdb.EstimatedMaxChunkSize {
+		return newCorruptedBlockError(b.meta.ULID, fmt.Errorf("chunk in segment file %d at offset %d has length %d, exceeding the max chunk size %d", seq, offset, dataLen, mimir_tsdb.EstimatedMaxChunkSize))
+	}
+
+	chunkBuf, err := readChunkRange(ctx, b, seq, offset+int64(n), chunks.ChunkEncodingSize+int64(dataLen)+crc32.Size)
+	if err != nil {
+		return err
+	}
+	encData, sum := chunkBuf[:len(chunkBuf)-crc32.Size], chunkBuf[len(chunkBuf)-crc32.Size:]
+	if expected, actual := binary.BigEndian.Uint32(sum), crc32.Checksum(encData, castagnoliTable); expected != actual {
+		return newCorruptedBlockError(b.meta.ULID, fmt.Errorf("checksum mismatch in segment file %d at offset %d: expected %x, got %x", seq, offset, expected, actual))
+	}
+	return nil
+}
+
+func readChunkRange(ctx context.Context, b *bucketBlock, seq int, offset, length int64) ([]byte, error) {
+	r, err := b.chunkRangeReader(ctx, seq, offset, length)
+	if err != nil {
+		return nil, errors.Wrap(err, "get range reader")
+	}
+	defer runutil.CloseWithLogOnErr(b.logger, r, "close chunk range reader")
+
+	buf := make([]byte, length)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, newCorruptedBlockError(b.meta.ULID, fmt.Errorf("chunk in segment file %d at offset %d is truncated", seq, offset))
+		}
+		return nil, errors.Wrap(err, "read chunk range")
+	}
+	return buf, nil
+}
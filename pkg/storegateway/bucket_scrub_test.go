@@ -0,0 +1,112 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package storegateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/oklog/ulid"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/tsdb"
+	"github.com/prometheus/prometheus/tsdb/chunks"
+	"github.com/prometheus/prometheus/tsdb/index"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/objstore"
+
+	"github.com/grafana/mimir/pkg/storage/tsdb/block"
+	"github.com/grafana/mimir/pkg/storegateway/indexheader"
+	streamindex "github.com/grafana/mimir/pkg/storegateway/indexheader/index"
+)
+
+func TestSampleScrubPostingsLabel(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("should only sample label values whose postings list is short enough", func(t *testing.T) {
+		r := &scrubPostingsIndexHeaderReader{offsets: map[string][]streamindex.PostingListOffset{
+			labels.MetricName: {
+				{LabelValue: "up", Off: index.Range{Start: 0, End: maxScrubPostingsLength + 1}},
+			},
+			"instance": {
+				{LabelValue: "a", Off: index.Range{Start: 0, End: maxScrubPostingsLength + 1}},
+				{LabelValue: "b", Off: index.Range{Start: 100, End: 200}},
+			},
+		}}
+
+		for i := 0; i < 100; i++ {
+			lbl, ok, err := sampleScrubPostingsLabel(ctx, r)
+			require.NoError(t, err)
+			require.True(t, ok)
+			assert.Equal(t, labels.Label{Name: "instance", Value: "b"}, lbl)
+		}
+	})
+
+	t.Run("should sample nothing if all postings lists are too long", func(t *testing.T) {
+		r := &scrubPostingsIndexHeaderReader{offsets: map[string][]streamindex.PostingListOffset{
+			labels.MetricName: {
+				{LabelValue: "up", Off: index.Range{Start: 0, End: maxScrubPostingsLength + 1}},
+			},
+		}}
+
+		_, ok, err := sampleScrubPostingsLabel(ctx, r)
+		require.NoError(t, err)
+		assert.False(t, ok)
+	})
+}
+
+func TestVerifyChunk(t *testing.T) {
+	ctx := context.Background()
+	blockID := ulid.MustNew(1, nil)
+
+	// Write a segment file whose only chunk has the given length, followed by 100 bytes of data.
+	setupBlock := func(t *testing.T, dataLen uint64) *bucketBlock {
+		segment := make([]byte, chunks.SegmentHeaderSize, chunks.SegmentHeaderSize+binary.MaxVarintLen64+100)
+		segment = binary.AppendUvarint(segment, dataLen)
+		segment = append(segment, make([]byte, 100)...)
+
+		bkt := objstore.NewInMemBucket()
+		require.NoError(t, bkt.Upload(ctx, "segment", bytes.NewReader(segment)))
+
+		return &bucketBlock{
+			logger:    log.NewNopLogger(),
+			bkt:       bkt,
+			meta:      &block.Meta{BlockMeta: tsdb.BlockMeta{ULID: blockID}},
+			chunkObjs: []string{"segment"},
+		}
+	}
+	ref := chunks.ChunkRef(chunks.NewBlockChunkRef(0, chunks.SegmentHeaderSize))
+
+	t.Run("should report a chunk length over the max chunk size as a corrupted block", func(t *testing.T) {
+		err := verifyChunk(ctx, setupBlock(t, 1<<30), ref)
+		require.ErrorAs(t, err, &corruptedBlockError{})
+		assert.ErrorContains(t, err, "exceeding the max chunk size")
+	})
+
+	t.Run("should report a chunk length beyond the end of the segment file as a corrupted block", func(t *testing.T) {
+		err := verifyChunk(ctx, setupBlock(t, 1000), ref)
+		require.ErrorAs(t, err, &corruptedBlockError{})
+		assert.ErrorContains(t, err, "is truncated")
+	})
+}
+
+type scrubPostingsIndexHeaderReader struct {
+	indexheader.Reader
+
+	offsets map[string][]streamindex.PostingListOffset
+}
+
+func (r *scrubPostingsIndexHeaderReader) LabelNames(context.Context) ([]string, error) {
+	names := make([]string, 0, len(r.offsets))
+	for name := range r.offsets {
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+func (r *scrubPostingsIndexHeaderReader) LabelValuesOffsets(_ context.Context, name string, _ string, _ func(string) bool) ([]streamindex.PostingListOffset, error) {
+	return append([]streamindex.PostingListOffset(nil), r.offsets[name]...), nil
+}
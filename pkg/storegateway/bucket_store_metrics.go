@@ -24,6 +24,11 @@ type BucketStoreMetrics struct {
 	blockLoadFailures     prometheus.Counter
 	blockDrops            prometheus.Counter
 	blockDropFailures     prometheus.Counter
+	indexHeaderScrubs     prometheus.Counter
+	indexHeaderCorrupted  prometheus.Counter
+	chunkScrubs           prometheus.Counter
+	chunkCorrupted        prometheus.Counter
+	chunkCorruptedMarked  prometheus.Counter
 	seriesDataTouched     *prometheus.SummaryVec
 	seriesDataFetched     *prometheus.SummaryVec
 	seriesDataSizeTouched *prometheus.SummaryVec
@@ -74,6 +79,26 @@ func NewBucketStoreMetrics(reg prometheus.Registerer) *BucketStoreMetrics {
 		Name: "cortex_bucket_store_block_drop_failures_total",
 		Help: "Total number of local blocks that failed to be dropped.",
 	})
+	m.indexHeaderScrubs = promauto.With(reg).NewCounter(prometheus.CounterOpts{
+		Name: "cortex_bucket_store_index_header_scrubs_total",
+		Help: "Total number of local index-headers verified by the background scrubber.",
+	})
+	m.indexHeaderCorrupted = promauto.With(reg).NewCounter(prometheus.CounterOpts{
+		Name: "cortex_bucket_store_index_header_scrub_corruptions_total",
+		Help: "Total number of corrupted local index-headers detected by the background scrubber.",
+	})
+	m.chunkScrubs = promauto.With(reg).NewCounter(prometheus.CounterOpts{
+		Name: "cortex_bucket_store_chunk_scrubs_total",
+		Help: "Total number of sampled chunks verified by the background scrubber.",
+	})
+	m.chunkCorrupted = promauto.With(reg).NewCounter(prometheus.CounterOpts{
+		Name: "cortex_bucket_store_chunk_scrub_corruptions_total",
+		Help: "Total number of corrupted chunks detected by the background scrubber.",
+	})
+	m.chunkCorruptedMarked = promauto.With(reg).NewCounter(prometheus.CounterOpts{
+		Name: "cortex_bucket_store_chunk_scrub_blocks_marked_for_no_compaction_total",
+		Help: "Total number of blocks with corrupted chunks marked for no-compaction by the background scrubber.",
+	})
 	m.seriesDataTouched = promauto.With(reg).NewSummaryVec(prometheus.SummaryOpts{
 		Name: "cortex_bucket_store_series_data_touched",
 		Help: "How many items of a data type in a block were touched for a single Series/LabelValues/LabelNames request.",
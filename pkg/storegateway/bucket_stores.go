@@ -205,6 +205,46 @@ func (u *BucketStores) initialSync(ctx context.Context) error {
 	return nil
 }
 
+// ScrubBlocks verifies the integrity of the index-headers stored on the local disk and of a sample of the chunks
+// for every user, and marks the blocks with corrupted chunks for no-compaction. Users are scrubbed sequentially
+// to keep the scrubber a low-priority background activity.
+func (u *BucketStores) ScrubBlocks(ctx context.Context) {
+	u.storesMu.RLock()
+	stores := make(map[string]*BucketStore, len(u.stores))
+	for userID, store := range u.stores {
+		stores[userID] = store
+	}
+	u.storesMu.RUnlock()
+
+	for userID, store := range stores {
+		if ctx.Err() != nil {
+			return
+		}
+		store.ScrubBlocks(ctx, bucket.NewUserBucketClient(userID, u.bucket, u.limits), u.cfg.BucketStore.ScrubChunksSampleSize)
+	}
+}
+
+// PersistBlockQueryStats persists the blocks query statistics summary of every user to the bucket.
+func (u *BucketStores) PersistBlockQueryStats(ctx context.Context, instanceID string) {
+	u.storesMu.RLock()
+	stores := make(map[string]*BucketStore, len(u.stores))
+	for userID, store := range u.stores {
+		stores[userID] = store
+	}
+	u.storesMu.RUnlock()
+
+	for userID, store := range stores {
+		if ctx.Err() != nil {
+			return
+		}
+
+		userBkt := bucket.NewUserBucketClient(userID, u.bucket, u.limits)
+		if err := store.PersistBlockQueryStats(ctx, userBkt, instanceID); err != nil {
+			level.Warn(u.logger).Log("msg", "failed to persist block query stats", "user", userID, "err", err)
+		}
+	}
+}
+
 // SyncBlocks synchronizes the stores state with the Bucket store for every user.
 func (u *BucketStores) SyncBlocks(ctx context.Context) error {
 	return u.syncUsersBlocksWithRetries(ctx, func(ctx context.Context, store *BucketStore) error {
@@ -524,6 +564,9 @@ func (u *BucketStores) getOrCreateStore(ctx context.Context, userID string) (*Bu
 		WithIndexCache(u.indexCache),
 		WithQueryGate(u.queryGate),
 		WithLazyLoadingGate(u.lazyLoadingGate),
+		WithBlocksQueryLookback(func() time.Duration {
+			return u.limits.StoreGatewayBlocksQueryLookback(userID)
+		}),
 	}
 
 	bs, err := NewBucketStore(
@@ -539,7 +582,10 @@ func (u *BucketStores) getOrCreateStore(ctx context.Context, userID string) (*Bu
 		NewSeriesLimiterFactory(func() uint64 {
 			return uint64(u.limits.MaxFetchedSeriesPerQuery(userID))
 		}),
-		u.partitioners,
+		u.partitioners.withTenantChunksRanges(
+			func() uint64 { return uint64(max(0, u.limits.StoreGatewayChunksRangeMaxGapBytes(userID))) },
+			func() uint64 { return uint64(max(0, u.limits.StoreGatewayChunksPrefetchBytes(userID))) },
+		),
 		u.seriesHashCache,
 		u.bucketStoreMetrics,
 		bucketStoreOpts...,
@@ -7,6 +7,7 @@ package storegateway
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -19,12 +20,14 @@ import (
 	"time"
 
 	"github.com/go-kit/log"
+	"github.com/gogo/protobuf/types"
 	"github.com/grafana/dskit/flagext"
 	"github.com/grafana/dskit/services"
 	"github.com/oklog/ulid"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
 	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/common/model"
 	"github.com/prometheus/prometheus/model/labels"
 	"github.com/prometheus/prometheus/model/timestamp"
 	"github.com/prometheus/prometheus/tsdb"
@@ -44,7 +47,9 @@ import (
 	"github.com/grafana/mimir/pkg/storage/bucket/filesystem"
 	mimir_tsdb "github.com/grafana/mimir/pkg/storage/tsdb"
 	"github.com/grafana/mimir/pkg/storage/tsdb/block"
+	"github.com/grafana/mimir/pkg/storegateway/hintspb"
 	"github.com/grafana/mimir/pkg/storegateway/indexcache"
+	"github.com/grafana/mimir/pkg/storegateway/indexheader"
 	"github.com/grafana/mimir/pkg/storegateway/storepb"
 	"github.com/grafana/mimir/pkg/util"
 	"github.com/grafana/mimir/pkg/util/test"
@@ -284,6 +289,260 @@ func TestBucketStores_SyncBlocks(t *testing.T) {
 	assert.Greater(t, testutil.ToFloat64(stores.syncLastSuccess), float64(0))
 }
 
+func TestBucketStores_ScrubBlocks(t *testing.T) {
+	const (
+		userID     = "user-1"
+		metricName = "series_1"
+	)
+
+	ctx := context.Background()
+	cfg := prepareStorageConfig(t)
+
+	storageDir := t.TempDir()
+
+	bucket, err := filesystem.NewBucketClient(filesystem.Config{Directory: storageDir})
+	require.NoError(t, err)
+
+	reg := prometheus.NewPedanticRegistry()
+	stores, err := NewBucketStores(cfg, newNoShardingStrategy(), bucket, nil, defaultLimitsOverrides(t), log.NewNopLogger(), reg)
+	require.NoError(t, err)
+
+	generateStorageBlock(t, storageDir, userID, metricName, 10, 100, 15)
+	createBucketIndex(t, bucket, userID)
+	require.NoError(t, services.StartAndAwaitRunning(ctx, stores))
+	t.Cleanup(func() {
+		require.NoError(t, services.StopAndAwaitTerminated(context.Background(), stores))
+	})
+
+	// Query the block to make sure its index-header is built on the local disk.
+	seriesSet, _, err := querySeries(t, stores, userID, metricName, 10, 100)
+	require.NoError(t, err)
+	require.Len(t, seriesSet, 1)
+
+	indexHeaders, err := filepath.Glob(filepath.Join(cfg.BucketStore.SyncDir, userID, "*", block.IndexHeaderFilename))
+	require.NoError(t, err)
+	require.Len(t, indexHeaders, 1)
+
+	// A valid index-header is left untouched.
+	stores.ScrubBlocks(ctx)
+
+	// Corrupt the index-header.
+	data, err := os.ReadFile(indexHeaders[0])
+	require.NoError(t, err)
+	data[len(data)/2] ^= 0xff
+	require.NoError(t, os.WriteFile(indexHeaders[0], data, 0644))
+
+	// The corrupted index-header is detected and the block is reloaded.
+	stores.ScrubBlocks(ctx)
+
+	assert.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(`
+			# HELP cortex_bucket_store_blocks_loaded Number of currently loaded blocks.
+			# TYPE cortex_bucket_store_blocks_loaded gauge
+			cortex_bucket_store_blocks_loaded 1
+
+			# HELP cortex_bucket_store_block_loads_total Total number of remote block loading attempts.
+			# TYPE cortex_bucket_store_block_loads_total counter
+			cortex_bucket_store_block_loads_total 2
+
+			# HELP cortex_bucket_store_block_drops_total Total number of local blocks that were dropped.
+			# TYPE cortex_bucket_store_block_drops_total counter
+			cortex_bucket_store_block_drops_total 1
+
+			# HELP cortex_bucket_store_index_header_scrubs_total Total number of local index-headers verified by the background scrubber.
+			# TYPE cortex_bucket_store_index_header_scrubs_total counter
+			cortex_bucket_store_index_header_scrubs_total 2
+
+			# HELP cortex_bucket_store_index_header_scrub_corruptions_total Total number of corrupted local index-headers detected by the background scrubber.
+			# TYPE cortex_bucket_store_index_header_scrub_corruptions_total counter
+			cortex_bucket_store_index_header_scrub_corruptions_total 1
+	`),
+		"cortex_bucket_store_blocks_loaded",
+		"cortex_bucket_store_block_loads_total",
+		"cortex_bucket_store_block_drops_total",
+		"cortex_bucket_store_index_header_scrubs_total",
+		"cortex_bucket_store_index_header_scrub_corruptions_total",
+	))
+
+	// The block can still be queried, and its index-header is rebuilt from the object storage.
+	seriesSet, _, err = querySeries(t, stores, userID, metricName, 10, 100)
+	require.NoError(t, err)
+	require.Len(t, seriesSet, 1)
+
+	stores.ScrubBlocks(ctx)
+	assert.Equal(t, float64(1), testutil.ToFloat64(stores.bucketStoreMetrics.indexHeaderCorrupted))
+
+	// The chunks of the block have been verified by the scrubs which didn't reload the block.
+	assert.Equal(t, float64(2), testutil.ToFloat64(stores.bucketStoreMetrics.chunkScrubs))
+	assert.Equal(t, float64(0), testutil.ToFloat64(stores.bucketStoreMetrics.chunkCorrupted))
+
+	// Corrupt the chunks in the object storage.
+	chunkFiles, err := filepath.Glob(filepath.Join(storageDir, userID, "*", block.ChunksDirname, "*"))
+	require.NoError(t, err)
+	require.Len(t, chunkFiles, 1)
+	data, err = os.ReadFile(chunkFiles[0])
+	require.NoError(t, err)
+	for i := chunks.SegmentHeaderSize; i < len(data); i++ {
+		data[i] ^= 0xff
+	}
+	require.NoError(t, os.WriteFile(chunkFiles[0], data, 0644))
+
+	stores.ScrubBlocks(ctx)
+	assert.Equal(t, float64(1), testutil.ToFloat64(stores.bucketStoreMetrics.chunkCorrupted))
+
+	// The block with corrupted chunks has been marked for no-compaction.
+	assert.Equal(t, float64(1), testutil.ToFloat64(stores.bucketStoreMetrics.chunkCorruptedMarked))
+	blockID := filepath.Base(filepath.Dir(filepath.Dir(chunkFiles[0])))
+	mark := block.NoCompactMark{}
+	markData, err := os.ReadFile(filepath.Join(storageDir, userID, blockID, block.NoCompactMarkFilename))
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(markData, &mark))
+	assert.Equal(t, blockID, mark.ID.String())
+	assert.Equal(t, block.NoCompactReason(block.CriticalNoCompactReason), mark.Reason)
+}
+
+func TestBucketStores_WarmupBlocks(t *testing.T) {
+	const (
+		userID     = "user-1"
+		metricName = "series_1"
+	)
+
+	ctx := context.Background()
+	cfg := prepareStorageConfig(t)
+
+	storageDir := t.TempDir()
+
+	bucket, err := filesystem.NewBucketClient(filesystem.Config{Directory: storageDir})
+	require.NoError(t, err)
+
+	stores, err := NewBucketStores(cfg, newNoShardingStrategy(), bucket, nil, defaultLimitsOverrides(t), log.NewNopLogger(), nil)
+	require.NoError(t, err)
+
+	generateStorageBlock(t, storageDir, userID, metricName, 10, 100, 15)
+	generateStorageBlock(t, storageDir, userID, metricName, 100, 200, 15)
+	createBucketIndex(t, bucket, userID)
+	require.NoError(t, services.StartAndAwaitRunning(ctx, stores))
+	t.Cleanup(func() {
+		require.NoError(t, services.StopAndAwaitTerminated(context.Background(), stores))
+	})
+
+	store := stores.getStore(userID)
+	require.NotNil(t, store)
+
+	readers := map[int64]*indexheader.LazyBinaryReader{}
+	store.blockSet.forEach(func(b *bucketBlock) {
+		r, ok := b.indexHeaderReader.(*indexheader.LazyBinaryReader)
+		require.True(t, ok)
+		readers[b.meta.MinTime] = r
+	})
+	require.Len(t, readers, 2)
+
+	// The index-headers are lazy loaded, so none of them has been loaded yet.
+	for _, r := range readers {
+		require.Zero(t, r.LoadedLastUse())
+	}
+
+	// Only the block overlapping the time range is warmed up.
+	pinUntil := time.Now().Add(time.Hour)
+	results := store.WarmupBlocks(ctx, 150, 180, pinUntil)
+	require.Len(t, results, 1)
+	require.NoError(t, results[0].Err)
+
+	warmed, notWarmed := readers[results[0].MinTime], readers[10]
+	require.NotNil(t, warmed)
+	assert.NotZero(t, warmed.LoadedLastUse())
+	assert.Equal(t, pinUntil.UnixNano(), warmed.PinnedUntil().UnixNano())
+	assert.Zero(t, notWarmed.LoadedLastUse())
+	assert.True(t, notWarmed.PinnedUntil().IsZero())
+
+	// A time range without blocks doesn't warm up anything.
+	assert.Empty(t, store.WarmupBlocks(ctx, 1000, 2000, pinUntil))
+}
+
+func TestBucketStores_ShouldNotQueryBlocksOlderThanBlocksQueryLookback(t *testing.T) {
+	const (
+		userID     = "user-1"
+		metricName = "series_1"
+	)
+
+	ctx := context.Background()
+	cfg := prepareStorageConfig(t)
+	cfg.BucketStore.IgnoreBlocksWithin = 0
+
+	storageDir := t.TempDir()
+
+	bucket, err := filesystem.NewBucketClient(filesystem.Config{Directory: storageDir})
+	require.NoError(t, err)
+
+	tenantLimits := defaultLimitsConfig()
+	tenantLimits.StoreGatewayBlocksQueryLookback = model.Duration(time.Hour)
+	overrides, err := validation.NewOverrides(defaultLimitsConfig(), validation.NewMockTenantLimits(map[string]*validation.Limits{userID: &tenantLimits}))
+	require.NoError(t, err)
+
+	stores, err := NewBucketStores(cfg, newNoShardingStrategy(), bucket, nil, overrides, log.NewNopLogger(), nil)
+	require.NoError(t, err)
+
+	// Generate a block older than the lookback, and a recent one straddling the lookback.
+	now := time.Now()
+	step := int(15 * time.Second / time.Millisecond)
+	generateStorageBlock(t, storageDir, userID, metricName, now.Add(-3*time.Hour).UnixMilli(), now.Add(-150*time.Minute).UnixMilli(), step)
+	generateStorageBlock(t, storageDir, userID, metricName, now.Add(-2*time.Hour).UnixMilli(), now.UnixMilli(), step)
+	createBucketIndex(t, bucket, userID)
+	require.NoError(t, services.StartAndAwaitRunning(ctx, stores))
+	t.Cleanup(func() {
+		require.NoError(t, services.StopAndAwaitTerminated(context.Background(), stores))
+	})
+
+	store := stores.getStore(userID)
+	require.NotNil(t, store)
+
+	var oldBlockID, recentBlockID ulid.ULID
+	store.blockSet.forEach(func(b *bucketBlock) {
+		if b.meta.MaxTime < now.Add(-time.Hour).UnixMilli() {
+			oldBlockID = b.meta.ULID
+		} else {
+			recentBlockID = b.meta.ULID
+		}
+	})
+	require.NotZero(t, oldBlockID)
+	require.NotZero(t, recentBlockID)
+
+	// Only the chunks of the recent block within the lookback are returned, but both blocks are reported
+	// as queried, so that the querier doesn't look for the old block in other store-gateways.
+	seriesReq := &storepb.SeriesRequest{
+		MinTime:  now.Add(-4 * time.Hour).UnixMilli(),
+		MaxTime:  now.UnixMilli(),
+		Matchers: []storepb.LabelMatcher{{Type: storepb.LabelMatcher_EQ, Name: labels.MetricName, Value: metricName}},
+	}
+	seriesSet, _, hints, _, err := newStoreGatewayTestServer(t, stores).Series(setUserIDToGRPCContext(ctx, userID), seriesReq)
+	require.NoError(t, err)
+	require.Len(t, seriesSet, 1)
+	require.NotEmpty(t, seriesSet[0].Chunks)
+	for _, chk := range seriesSet[0].Chunks {
+		assert.GreaterOrEqual(t, chk.MaxTime, now.Add(-time.Hour).UnixMilli())
+	}
+	assert.ElementsMatch(t, []string{oldBlockID.String(), recentBlockID.String()}, queriedBlockIDs(hints.QueriedBlocks))
+
+	// The label names of the old block are not returned either.
+	labelNamesRes, err := store.LabelNames(ctx, &storepb.LabelNamesRequest{
+		Start: now.Add(-4 * time.Hour).UnixMilli(),
+		End:   now.Add(-140 * time.Minute).UnixMilli(),
+	})
+	require.NoError(t, err)
+	assert.Empty(t, labelNamesRes.Names)
+
+	labelNamesHints := hintspb.LabelNamesResponseHints{}
+	require.NoError(t, types.UnmarshalAny(labelNamesRes.Hints, &labelNamesHints))
+	assert.Equal(t, []string{oldBlockID.String()}, queriedBlockIDs(labelNamesHints.QueriedBlocks))
+}
+
+func queriedBlockIDs(blocks []hintspb.Block) []string {
+	ids := make([]string, 0, len(blocks))
+	for _, b := range blocks {
+		ids = append(ids, b.Id)
+	}
+	return ids
+}
+
 func TestBucketStores_ownedUsers(t *testing.T) {
 	allUsers := []string{"user-1", "user-2", "user-3"}
 
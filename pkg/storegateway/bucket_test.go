@@ -2040,6 +2040,87 @@ func TestBucketStore_Series_ErrorUnmarshallingRequestHints(t *testing.T) {
 	assert.Equal(t, true, regexp.MustCompile(".*unmarshal series request hints.*").MatchString(err.Error()))
 }
 
+func TestBucketStore_SeriesHashCachePreload(t *testing.T) {
+	ctx := context.Background()
+	tmpDir := t.TempDir()
+	logger := log.NewNopLogger()
+
+	bkt, err := filesystem.NewBucket(filepath.Join(tmpDir, "bkt"))
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, bkt.Close()) })
+
+	// Create a block with the series hashes and another one without them.
+	blockDir := filepath.Join(tmpDir, "blocks")
+	series := []labels.Labels{
+		labels.FromStrings("a", "1"),
+		labels.FromStrings("a", "2"),
+		labels.FromStrings("a", "3"),
+	}
+	withHashes, err := block.CreateBlock(ctx, blockDir, series, 10, 0, 1000, labels.EmptyLabels())
+	require.NoError(t, err)
+	_, err = block.WriteSeriesHashes(ctx, filepath.Join(blockDir, withHashes.String()))
+	require.NoError(t, err)
+	require.NoError(t, block.Upload(ctx, logger, bkt, filepath.Join(blockDir, withHashes.String()), nil))
+
+	withoutHashes, err := block.CreateBlock(ctx, blockDir, series, 10, 1000, 2000, labels.EmptyLabels())
+	require.NoError(t, err)
+	require.NoError(t, block.Upload(ctx, logger, bkt, filepath.Join(blockDir, withoutHashes.String()), nil))
+
+	instrBkt := objstore.WithNoopInstr(bkt)
+	syncDir := filepath.Join(tmpDir, "sync")
+	fetcher, err := block.NewMetaFetcher(logger, 10, instrBkt, syncDir, nil, nil, nil)
+	require.NoError(t, err)
+
+	seriesHashCache := hashcache.NewSeriesHashCache(1024 * 1024)
+	store, err := NewBucketStore(
+		"test",
+		instrBkt,
+		fetcher,
+		syncDir,
+		mimir_tsdb.BucketStoreConfig{
+			StreamingBatchSize:            5000,
+			BlockSyncConcurrency:          10,
+			PostingOffsetsInMemSampling:   mimir_tsdb.DefaultPostingOffsetInMemorySampling,
+			SeriesHashCachePreloadEnabled: true,
+		},
+		selectAllStrategy{},
+		newStaticChunksLimiterFactory(100),
+		newStaticSeriesLimiterFactory(0),
+		newGapBasedPartitioners(mimir_tsdb.DefaultPartitionerMaxGapSize, nil),
+		seriesHashCache,
+		NewBucketStoreMetrics(nil),
+		WithLogger(logger),
+	)
+	require.NoError(t, err)
+	require.NoError(t, services.StartAndAwaitRunning(ctx, store))
+	t.Cleanup(func() { require.NoError(t, store.RemoveBlocksAndClose()) })
+
+	require.NoError(t, store.SyncBlocks(ctx))
+
+	hashes, err := os.ReadFile(filepath.Join(blockDir, withHashes.String(), block.SeriesHashesFilename))
+	require.NoError(t, err)
+	cache := seriesHashCache.GetBlockCache(withHashes.String())
+
+	// The series hashes are not loaded when the blocks are loaded.
+	require.NoError(t, block.ReadSeriesHashes(bytes.NewReader(hashes), func(ref storage.SeriesRef, _ uint64) {
+		_, ok := cache.Fetch(ref)
+		require.False(t, ok)
+	}))
+
+	// The series hashes are loaded in the background the first time the blocks are queried with query sharding.
+	store.blockSet.forEach(store.preloadSeriesHashesInBackground)
+
+	numSeries := 0
+	require.NoError(t, block.ReadSeriesHashes(bytes.NewReader(hashes), func(ref storage.SeriesRef, expected uint64) {
+		require.Eventually(t, func() bool {
+			hash, ok := cache.Fetch(ref)
+			return ok && hash == expected
+		}, 5*time.Second, 10*time.Millisecond)
+		numSeries++
+	}))
+	require.Equal(t, len(series), numSeries)
+}
+
 func TestBucketStore_Series_CanceledRequest(t *testing.T) {
 	tmpDir := t.TempDir()
 	bktDir := filepath.Join(tmpDir, "bkt")
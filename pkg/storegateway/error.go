@@ -2,7 +2,13 @@
 
 package storegateway
 
-import "github.com/grafana/mimir/pkg/mimirpb"
+import (
+	"fmt"
+
+	"github.com/oklog/ulid"
+
+	"github.com/grafana/mimir/pkg/mimirpb"
+)
 
 type storeGatewayError interface {
 	error
@@ -21,3 +27,22 @@ func (s staticError) Error() string {
 func (s staticError) errorCause() mimirpb.ErrorCause {
 	return s.cause
 }
+
+// corruptedBlockError indicates that the data read from a block is corrupted. It's returned to the
+// querier with the DataLoss gRPC status code, so that the querier can read the block from another replica.
+type corruptedBlockError struct {
+	blockID ulid.ULID
+	err     error
+}
+
+func newCorruptedBlockError(blockID ulid.ULID, err error) corruptedBlockError {
+	return corruptedBlockError{blockID: blockID, err: err}
+}
+
+func (e corruptedBlockError) Error() string {
+	return fmt.Sprintf("corrupted data in block %s: %s", e.blockID, e.err)
+}
+
+func (e corruptedBlockError) Unwrap() error {
+	return e.err
+}
@@ -22,6 +22,7 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
 	"github.com/thanos-io/objstore"
+	"go.uber.org/atomic"
 
 	"github.com/grafana/mimir/pkg/storage/bucket"
 	mimir_tsdb "github.com/grafana/mimir/pkg/storage/tsdb"
@@ -37,8 +38,8 @@ const (
 	syncReasonPeriodic   = "periodic"
 	syncReasonRingChange = "ring-change"
 
-	// ringAutoForgetUnhealthyPeriods is how many consecutive timeout periods an unhealthy instance
-	// in the ring will be automatically removed.
+	// ringAutoForgetUnhealthyPeriods is the default number of consecutive timeout periods after which
+	// an unhealthy instance in the ring will be automatically removed.
 	ringAutoForgetUnhealthyPeriods = 10
 
 	// ringNumTokensDefault is the number of tokens registered in the ring by each store-gateway
@@ -48,7 +49,15 @@ const (
 
 var (
 	// Validation errors.
-	errInvalidTenantShardSize = errors.New("invalid tenant shard size, the value must be greater or equal to 0")
+	errInvalidTenantShardSize                = errors.New("invalid tenant shard size, the value must be greater or equal to 0")
+	errInvalidBlockQueryStatsPersistInterval = errors.New("invalid block query stats persist interval, the value must be greater or equal to 0")
+	errInvalidRingInstanceWeight             = errors.New("invalid ring instance weight, the value must be greater than 0")
+	errInvalidRingAutoForgetUnhealthyPeriods = errors.New("invalid ring auto-forget unhealthy periods, the value must be greater than 0")
+	errInvalidRingTokenGenerationStrategy    = errors.New("invalid ring token generation strategy")
+
+	// errRingSpreadMinimizingMisconfigured is a sentinel error wrapping the validation errors of the
+	// spread-minimizing token generation strategy.
+	errRingSpreadMinimizingMisconfigured = errors.Errorf("%q token generation strategy is misconfigured", tokenGenerationSpreadMinimizing)
 )
 
 // Config holds the store gateway config.
@@ -57,6 +66,8 @@ type Config struct {
 
 	EnabledTenants  flagext.StringSliceCSV `yaml:"enabled_tenants" category:"advanced"`
 	DisabledTenants flagext.StringSliceCSV `yaml:"disabled_tenants" category:"advanced"`
+
+	BlockQueryStatsPersistInterval time.Duration `yaml:"block_query_stats_persist_interval" category:"experimental"`
 }
 
 // RegisterFlags registers the Config flags.
@@ -65,6 +76,7 @@ func (cfg *Config) RegisterFlags(f *flag.FlagSet, logger log.Logger) {
 
 	f.Var(&cfg.EnabledTenants, "store-gateway.enabled-tenants", "Comma separated list of tenants that can be loaded by the store-gateway. If specified, only blocks for these tenants will be loaded by the store-gateway, otherwise all tenants can be loaded. Subject to sharding.")
 	f.Var(&cfg.DisabledTenants, "store-gateway.disabled-tenants", "Comma separated list of tenants that cannot be loaded by the store-gateway. If specified, and the store-gateway would normally load a given tenant for (via -store-gateway.enabled-tenants or sharding), it will be ignored instead.")
+	f.DurationVar(&cfg.BlockQueryStatsPersistInterval, "store-gateway.block-query-stats-persist-interval", 0, "How frequently the store-gateway persists to the object storage a summary of the per-block query hit counts and last query timestamps of each tenant. Requires -blocks-storage.bucket-store.block-query-stats-enabled. 0 to disable.")
 }
 
 // Validate the Config.
@@ -72,6 +84,18 @@ func (cfg *Config) Validate(limits validation.Limits) error {
 	if limits.StoreGatewayTenantShardSize < 0 {
 		return errInvalidTenantShardSize
 	}
+	if cfg.BlockQueryStatsPersistInterval < 0 {
+		return errInvalidBlockQueryStatsPersistInterval
+	}
+	if cfg.ShardingRing.InstanceWeight <= 0 {
+		return errInvalidRingInstanceWeight
+	}
+	if cfg.ShardingRing.AutoForgetEnabled && cfg.ShardingRing.AutoForgetUnhealthyPeriods <= 0 {
+		return errInvalidRingAutoForgetUnhealthyPeriods
+	}
+	if err := cfg.ShardingRing.validateTokenGeneration(); err != nil {
+		return err
+	}
 
 	return nil
 }
@@ -86,6 +110,7 @@ type StoreGateway struct {
 	storageCfg mimir_tsdb.BlocksStorageConfig
 	logger     log.Logger
 	stores     *BucketStores
+	limits     ShardingLimits
 	tracker    *activitytracker.ActivityTracker
 
 	// Ring used for sharding blocks.
@@ -99,6 +124,10 @@ type StoreGateway struct {
 	bucketSync *prometheus.CounterVec
 	// Shutdown marker for store-gateway scale down
 	shutdownMarker prometheus.Gauge
+
+	// prepareShutdownRequested is true when the store-gateway has been requested to prepare for shutdown,
+	// in which case it's LEAVING in the ring and doesn't synchronize blocks anymore.
+	prepareShutdownRequested *atomic.Bool
 }
 
 func NewStoreGateway(gatewayCfg Config, storageCfg mimir_tsdb.BlocksStorageConfig, limits *validation.Overrides, logger log.Logger, reg prometheus.Registerer, tracker *activitytracker.ActivityTracker) (*StoreGateway, error) {
@@ -129,6 +158,7 @@ func newStoreGateway(gatewayCfg Config, storageCfg mimir_tsdb.BlocksStorageConfi
 		gatewayCfg: gatewayCfg,
 		storageCfg: storageCfg,
 		logger:     logger,
+		limits:     limits,
 		tracker:    tracker,
 		bucketSync: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
 			Name: "cortex_storegateway_bucket_sync_total",
@@ -138,6 +168,7 @@ func newStoreGateway(gatewayCfg Config, storageCfg mimir_tsdb.BlocksStorageConfi
 			Name: "cortex_storegateway_prepare_shutdown_requested",
 			Help: "If the store-gateway has been requested to prepare for shutdown via endpoint or marker file.",
 		}),
+		prepareShutdownRequested: atomic.NewBool(false),
 	}
 
 	// Init metrics.
@@ -159,7 +190,7 @@ func newStoreGateway(gatewayCfg Config, storageCfg mimir_tsdb.BlocksStorageConfi
 	delegate = ring.NewLeaveOnStoppingDelegate(delegate, logger)
 	delegate = ring.NewTokensPersistencyDelegate(gatewayCfg.ShardingRing.TokensFilePath, ring.JOINING, delegate, logger)
 	if gatewayCfg.ShardingRing.AutoForgetEnabled {
-		delegate = ring.NewAutoForgetDelegate(ringAutoForgetUnhealthyPeriods*gatewayCfg.ShardingRing.HeartbeatTimeout, delegate, logger)
+		delegate = ring.NewAutoForgetDelegate(time.Duration(gatewayCfg.ShardingRing.AutoForgetUnhealthyPeriods)*gatewayCfg.ShardingRing.HeartbeatTimeout, delegate, logger)
 	}
 
 	g.ringLifecycler, err = ring.NewBasicLifecycler(lifecyclerCfg, RingNameForServer, RingKey, ringStore, delegate, logger, prometheus.WrapRegistererWithPrefix("cortex_", reg))
@@ -214,7 +245,27 @@ func (g *StoreGateway) starting(ctx context.Context) (err error) {
 
 	// First of all we register the instance in the ring and wait
 	// until the lifecycler successfully started.
-	if g.subservices, err = services.NewManager(g.ringLifecycler, g.ring); err != nil {
+	subservices := []services.Service{g.ringLifecycler, g.ring}
+
+	// The blocks scrubber runs in its own service, so that it doesn't delay the blocks sync.
+	// It's disabled if the interval is 0.
+	if scrubInterval := g.storageCfg.BucketStore.IndexHeader.ScrubInterval; scrubInterval > 0 {
+		subservices = append(subservices, services.NewTimerService(util.DurationWithJitter(scrubInterval, 0.2), nil, func(ctx context.Context) error {
+			g.stores.ScrubBlocks(ctx)
+			return nil
+		}, nil))
+	}
+
+	// The block query stats are persisted in their own service too, so that uploading the summaries
+	// doesn't delay the blocks sync. The persistence is disabled if the interval is 0.
+	if interval := g.gatewayCfg.BlockQueryStatsPersistInterval; interval > 0 && g.storageCfg.BucketStore.BlockQueryStatsEnabled {
+		subservices = append(subservices, services.NewTimerService(util.DurationWithJitter(interval, 0.2), nil, func(ctx context.Context) error {
+			g.stores.PersistBlockQueryStats(ctx, g.gatewayCfg.ShardingRing.InstanceID)
+			return nil
+		}, nil))
+	}
+
+	if g.subservices, err = services.NewManager(subservices...); err != nil {
 		return errors.Wrap(err, "unable to start store-gateway dependencies")
 	}
 
@@ -274,6 +325,15 @@ func (g *StoreGateway) starting(ctx context.Context) (err error) {
 	}
 	level.Info(g.logger).Log("msg", "store-gateway is ACTIVE in the ring")
 
+	// If the store-gateway has been requested to prepare for shutdown before restarting, then
+	// switch back to LEAVING now that the previously owned blocks have been loaded.
+	if g.prepareShutdownRequested.Load() {
+		if err = g.ringLifecycler.ChangeState(ctx, ring.LEAVING); err != nil {
+			return errors.Wrapf(err, "switch instance to %s in the ring", ring.LEAVING)
+		}
+		level.Info(g.logger).Log("msg", "store-gateway is LEAVING in the ring because of the shutdown marker")
+	}
+
 	return nil
 }
 
@@ -324,6 +384,13 @@ func (g *StoreGateway) stopping(_ error) error {
 }
 
 func (g *StoreGateway) syncStores(ctx context.Context, reason string) {
+	// The blocks currently loaded keep being served until the store-gateway shuts down, but no new block
+	// is loaded or unloaded while it's preparing for shutdown.
+	if g.prepareShutdownRequested.Load() {
+		level.Info(g.logger).Log("msg", "skipped synchronizing TSDB blocks because the store-gateway is preparing for shutdown", "reason", reason)
+		return
+	}
+
 	level.Info(g.logger).Log("msg", "synchronizing TSDB blocks for all users", "reason", reason)
 	g.bucketSync.WithLabelValues(reason).Inc()
 
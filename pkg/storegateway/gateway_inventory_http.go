@@ -0,0 +1,150 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package storegateway
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/go-kit/log/level"
+	"github.com/gorilla/mux"
+	"github.com/grafana/dskit/ring"
+
+	mimir_tsdb "github.com/grafana/mimir/pkg/storage/tsdb"
+	"github.com/grafana/mimir/pkg/storage/tsdb/block"
+	"github.com/grafana/mimir/pkg/util"
+	"github.com/grafana/mimir/pkg/util/listblocks"
+)
+
+// blockInventory is the inventory of the blocks of a tenant in the bucket.
+type blockInventory struct {
+	Tenant      string    `json:"tenant"`
+	GeneratedAt time.Time `json:"generatedAt"`
+
+	// Total aggregates all the blocks of the tenant.
+	Total blockInventoryStats `json:"total"`
+	// ByCompactionLevel aggregates the blocks by compaction level.
+	ByCompactionLevel map[string]*blockInventoryStats `json:"byCompactionLevel"`
+	// BySource aggregates the blocks by the component which created them, as recorded in the block meta.
+	BySource map[string]*blockInventoryStats `json:"bySource"`
+	// ByStoreGateway aggregates the blocks by the store-gateway instances owning them in the ring.
+	ByStoreGateway map[string]*blockInventoryStats `json:"byStoreGateway"`
+	// Unowned aggregates the blocks whose owners couldn't be found in the ring.
+	Unowned blockInventoryStats `json:"unowned"`
+
+	Blocks []blockInventoryEntry `json:"blocks"`
+}
+
+type blockInventoryStats struct {
+	Blocks     int    `json:"blocks"`
+	SizeBytes  uint64 `json:"sizeBytes"`
+	NumSeries  uint64 `json:"numSeries"`
+	NumSamples uint64 `json:"numSamples"`
+	// MinTime and MaxTime are the time range covered by the blocks, in milliseconds.
+	MinTime int64 `json:"minTime"`
+	MaxTime int64 `json:"maxTime"`
+}
+
+func (s *blockInventoryStats) add(m *block.Meta, size uint64) {
+	if s.Blocks == 0 || m.MinTime < s.MinTime {
+		s.MinTime = m.MinTime
+	}
+	if s.Blocks == 0 || m.MaxTime > s.MaxTime {
+		s.MaxTime = m.MaxTime
+	}
+	s.Blocks++
+	s.SizeBytes += size
+	s.NumSeries += m.Stats.NumSeries
+	s.NumSamples += m.Stats.NumSamples
+}
+
+type blockInventoryEntry struct {
+	ID              string `json:"id"`
+	MinTime         int64  `json:"minTime"`
+	MaxTime         int64  `json:"maxTime"`
+	CompactionLevel int    `json:"compactionLevel"`
+	Source          string `json:"source"`
+	CompactorShard  string `json:"compactorShard,omitempty"`
+	SizeBytes       uint64 `json:"sizeBytes"`
+	NumSeries       uint64 `json:"numSeries"`
+	NumSamples      uint64 `json:"numSamples"`
+	// StoreGateways are the IDs of the store-gateway instances owning the block in the ring.
+	StoreGateways []string `json:"storeGateways"`
+}
+
+// BlockInventoryHandler returns a downloadable inventory of the tenant's blocks in the bucket, aggregated
+// by compaction level, source and store-gateway instances owning them in the ring. Blocks marked for
+// deletion are not included.
+func (s *StoreGateway) BlockInventoryHandler(w http.ResponseWriter, req *http.Request) {
+	tenantID := mux.Vars(req)["tenant"]
+	if tenantID == "" {
+		http.Error(w, "Tenant ID can't be empty", http.StatusBadRequest)
+		return
+	}
+
+	metasMap, _, _, err := listblocks.LoadMetaFilesAndMarkers(req.Context(), s.stores.bucket, tenantID, false, time.Time{})
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to read block metadata: %s", err), http.StatusInternalServerError)
+		return
+	}
+
+	inventory := blockInventory{
+		Tenant:            tenantID,
+		GeneratedAt:       time.Now(),
+		ByCompactionLevel: map[string]*blockInventoryStats{},
+		BySource:          map[string]*blockInventoryStats{},
+		ByStoreGateway:    map[string]*blockInventoryStats{},
+		Blocks:            []blockInventoryEntry{},
+	}
+
+	subRing := GetShuffleShardingSubring(s.ring, tenantID, s.limits)
+	bufDescs, bufHosts, bufZones := ring.MakeBuffersForGet()
+
+	for _, m := range listblocks.SortBlocks(metasMap) {
+		size := listblocks.GetBlockSizeBytes(m)
+		entry := blockInventoryEntry{
+			ID:              m.ULID.String(),
+			MinTime:         m.MinTime,
+			MaxTime:         m.MaxTime,
+			CompactionLevel: m.Compaction.Level,
+			Source:          string(m.Thanos.Source),
+			CompactorShard:  m.Thanos.Labels[mimir_tsdb.CompactorShardIDExternalLabel],
+			SizeBytes:       size,
+			NumSeries:       m.Stats.NumSeries,
+			NumSamples:      m.Stats.NumSamples,
+			StoreGateways:   []string{},
+		}
+
+		set, err := subRing.Get(mimir_tsdb.HashBlockID(m.ULID), BlocksOwnerSync, bufDescs, bufHosts, bufZones)
+		if err != nil {
+			level.Warn(s.logger).Log("msg", "failed to find the store-gateways owning the block", "user", tenantID, "block", entry.ID, "err", err)
+			inventory.Unowned.add(m, size)
+		} else {
+			for _, instance := range set.Instances {
+				entry.StoreGateways = append(entry.StoreGateways, instance.Id)
+				inventoryStatsFor(inventory.ByStoreGateway, instance.Id).add(m, size)
+			}
+			sort.Strings(entry.StoreGateways)
+		}
+
+		inventory.Total.add(m, size)
+		inventoryStatsFor(inventory.ByCompactionLevel, strconv.Itoa(entry.CompactionLevel)).add(m, size)
+		inventoryStatsFor(inventory.BySource, entry.Source).add(m, size)
+		inventory.Blocks = append(inventory.Blocks, entry)
+	}
+
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", tenantID+"-block-inventory.json"))
+	util.WriteJSONResponse(w, inventory)
+}
+
+func inventoryStatsFor(stats map[string]*blockInventoryStats, key string) *blockInventoryStats {
+	s, ok := stats[key]
+	if !ok {
+		s = &blockInventoryStats{}
+		stats[key] = s
+	}
+	return s
+}
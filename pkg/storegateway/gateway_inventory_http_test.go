@@ -0,0 +1,110 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package storegateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/gorilla/mux"
+	"github.com/grafana/dskit/services"
+	"github.com/oklog/ulid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/prometheus/tsdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/mimir/pkg/storage/bucket"
+	mimir_tsdb "github.com/grafana/mimir/pkg/storage/tsdb"
+	"github.com/grafana/mimir/pkg/storage/tsdb/block"
+)
+
+func TestStoreGateway_BlockInventoryHandler(t *testing.T) {
+	const tenantID = "user-1"
+
+	g, _ := createStoreGateway(t, nil)
+	ctx := context.Background()
+	require.NoError(t, services.StartAndAwaitRunning(ctx, g))
+
+	uploadMeta := func(id ulid.ULID, minT, maxT int64, level int, source block.SourceType, shard string, size int64) {
+		meta := block.Meta{
+			BlockMeta: tsdb.BlockMeta{
+				ULID:       id,
+				MinTime:    minT,
+				MaxTime:    maxT,
+				Version:    block.TSDBVersion1,
+				Compaction: tsdb.BlockMetaCompaction{Level: level},
+				Stats:      tsdb.BlockStats{NumSeries: 10, NumSamples: 100},
+			},
+			Thanos: block.ThanosMeta{
+				Source: source,
+				Files:  []block.File{{RelPath: "index", SizeBytes: size}},
+			},
+		}
+		if shard != "" {
+			meta.Thanos.Labels = map[string]string{mimir_tsdb.CompactorShardIDExternalLabel: shard}
+		}
+
+		buf := bytes.Buffer{}
+		require.NoError(t, meta.Write(&buf))
+		require.NoError(t, g.stores.bucket.Upload(ctx, path.Join(tenantID, id.String(), block.MetaFilename), &buf))
+	}
+
+	block1 := ulid.MustNew(1, nil)
+	block2 := ulid.MustNew(2, nil)
+	block3 := ulid.MustNew(3, nil)
+	deleted := ulid.MustNew(4, nil)
+
+	uploadMeta(block1, 0, 7200000, 1, block.ReceiveSource, "", 100)
+	uploadMeta(block2, 7200000, 14400000, 1, block.ReceiveSource, "", 200)
+	uploadMeta(block3, 0, 14400000, 2, block.CompactorSource, "1_of_2", 250)
+	uploadMeta(deleted, 0, 14400000, 2, block.CompactorSource, "", 1000)
+	userBkt := block.BucketWithGlobalMarkers(bucket.NewUserBucketClient(tenantID, g.stores.bucket, nil))
+	require.NoError(t, block.MarkForDeletion(ctx, log.NewNopLogger(), userBkt, deleted, "test", prometheus.NewCounter(prometheus.CounterOpts{})))
+
+	req := mux.SetURLVars(httptest.NewRequest(http.MethodGet, "/store-gateway/tenant/user-1/inventory", nil), map[string]string{"tenant": tenantID})
+	rec := httptest.NewRecorder()
+	g.BlockInventoryHandler(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, `attachment; filename="user-1-block-inventory.json"`, rec.Header().Get("Content-Disposition"))
+
+	inventory := blockInventory{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &inventory))
+	assert.Equal(t, tenantID, inventory.Tenant)
+
+	instanceID := g.ringLifecycler.GetInstanceID()
+	expectedOwners := []string{instanceID}
+	require.Len(t, inventory.Blocks, 3)
+	assert.Equal(t, blockInventoryEntry{
+		ID:              block3.String(),
+		MinTime:         0,
+		MaxTime:         14400000,
+		CompactionLevel: 2,
+		Source:          string(block.CompactorSource),
+		CompactorShard:  "1_of_2",
+		SizeBytes:       250,
+		NumSeries:       10,
+		NumSamples:      100,
+		StoreGateways:   expectedOwners,
+	}, inventory.Blocks[1])
+
+	assert.Equal(t, blockInventoryStats{Blocks: 3, SizeBytes: 550, NumSeries: 30, NumSamples: 300, MinTime: 0, MaxTime: 14400000}, inventory.Total)
+	assert.Equal(t, map[string]*blockInventoryStats{
+		"1": {Blocks: 2, SizeBytes: 300, NumSeries: 20, NumSamples: 200, MinTime: 0, MaxTime: 14400000},
+		"2": {Blocks: 1, SizeBytes: 250, NumSeries: 10, NumSamples: 100, MinTime: 0, MaxTime: 14400000},
+	}, inventory.ByCompactionLevel)
+	assert.Equal(t, map[string]*blockInventoryStats{
+		string(block.ReceiveSource):   {Blocks: 2, SizeBytes: 300, NumSeries: 20, NumSamples: 200, MinTime: 0, MaxTime: 14400000},
+		string(block.CompactorSource): {Blocks: 1, SizeBytes: 250, NumSeries: 10, NumSamples: 100, MinTime: 0, MaxTime: 14400000},
+	}, inventory.BySource)
+	assert.Equal(t, map[string]*blockInventoryStats{
+		instanceID: {Blocks: 3, SizeBytes: 550, NumSeries: 30, NumSamples: 300, MinTime: 0, MaxTime: 14400000},
+	}, inventory.ByStoreGateway)
+	assert.Equal(t, blockInventoryStats{}, inventory.Unowned)
+}
@@ -6,6 +6,7 @@ import (
 	"net/http"
 
 	"github.com/go-kit/log/level"
+	"github.com/grafana/dskit/ring"
 	"github.com/grafana/dskit/services"
 	"github.com/pkg/errors"
 
@@ -16,6 +17,10 @@ import (
 // PrepareShutdownHandler possibly changes the configuration of the store-gateway in such a way
 // that when it is stopped, it gets unregistered from the ring.
 //
+// While preparing for shutdown, the store-gateway is LEAVING in the ring and stops synchronizing
+// blocks, but keeps serving queries for the blocks it has already loaded while the next store-gateway
+// in the ring loads them.
+//
 // Moreover, it creates a file on disk which is used to re-apply the desired configuration if the
 // store-gateway crashes and restarts before being permanently shutdown.
 //
@@ -56,6 +61,12 @@ func (g *StoreGateway) PrepareShutdownHandler(w http.ResponseWriter, req *http.R
 		g.setPrepareShutdown()
 		level.Info(g.logger).Log("msg", "created prepare-shutdown marker file", "path", shutdownMarkerPath)
 
+		if err := g.ringLifecycler.ChangeState(req.Context(), ring.LEAVING); err != nil {
+			level.Error(g.logger).Log("msg", "unable to switch store-gateway to LEAVING in the ring", "err", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
 		w.WriteHeader(http.StatusNoContent)
 	case http.MethodDelete:
 		if err := shutdownmarker.Remove(shutdownMarkerPath); err != nil {
@@ -67,6 +78,12 @@ func (g *StoreGateway) PrepareShutdownHandler(w http.ResponseWriter, req *http.R
 		g.unsetPrepareShutdown()
 		level.Info(g.logger).Log("msg", "removed prepare-shutdown marker file", "path", shutdownMarkerPath)
 
+		if err := g.ringLifecycler.ChangeState(req.Context(), ring.ACTIVE); err != nil {
+			level.Error(g.logger).Log("msg", "unable to switch store-gateway to ACTIVE in the ring", "err", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
 		w.WriteHeader(http.StatusNoContent)
 	default:
 		w.WriteHeader(http.StatusMethodNotAllowed)
@@ -76,12 +93,14 @@ func (g *StoreGateway) PrepareShutdownHandler(w http.ResponseWriter, req *http.R
 // setPrepareShutdown changes store-gateway lifecycler config to prepare for shutdown
 func (g *StoreGateway) setPrepareShutdown() {
 	g.ringLifecycler.SetKeepInstanceInTheRingOnShutdown(false)
+	g.prepareShutdownRequested.Store(true)
 	g.shutdownMarker.Set(1)
 }
 
 // unsetPrepareShutdown reverts to the shutdown settings to their default values
 func (g *StoreGateway) unsetPrepareShutdown() {
 	g.ringLifecycler.SetKeepInstanceInTheRingOnShutdown(!g.gatewayCfg.ShardingRing.UnregisterOnShutdown)
+	g.prepareShutdownRequested.Store(false)
 	g.shutdownMarker.Set(0)
 }
 
@@ -5,19 +5,27 @@ package storegateway
 import (
 	"bytes"
 	"context"
+	"fmt"
+	"math"
 	"net/http/httptest"
+	"path"
 	"testing"
+	"time"
 
 	"github.com/go-kit/log"
 	"github.com/grafana/dskit/kv/consul"
 	"github.com/grafana/dskit/ring"
 	"github.com/grafana/dskit/services"
+	dstest "github.com/grafana/dskit/test"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	"github.com/grafana/mimir/pkg/storage/bucket/filesystem"
+	mimir_tsdb "github.com/grafana/mimir/pkg/storage/tsdb"
+	mimir_testutil "github.com/grafana/mimir/pkg/storage/tsdb/testutil"
+	"github.com/grafana/mimir/pkg/storegateway/storepb"
 	"github.com/grafana/mimir/pkg/util/shutdownmarker"
 	"github.com/grafana/mimir/pkg/util/test"
 )
@@ -47,6 +55,12 @@ func getRingDesc(ctx context.Context, t *testing.T, ringStore *consul.Client) *r
 	return desc.(*ring.Desc)
 }
 
+func getInstanceState(ctx context.Context, t *testing.T, ringStore *consul.Client, instanceID string) ring.InstanceState {
+	instance, ok := getRingDesc(ctx, t, ringStore).GetIngesters()[instanceID]
+	require.True(t, ok)
+	return instance.GetState()
+}
+
 func TestStoreGateway_PrepareShutdownHandler(t *testing.T) {
 	test.VerifyNoLeak(t)
 	reg := prometheus.NewPedanticRegistry()
@@ -93,6 +107,11 @@ func TestStoreGateway_PrepareShutdownHandler(t *testing.T) {
 	require.NoError(t, err)
 	require.True(t, exists)
 	require.False(t, g.ringLifecycler.ShouldKeepInstanceInTheRingOnShutdown())
+	require.Equal(t, ring.LEAVING, getInstanceState(ctx, t, ringStore, g.ringLifecycler.GetInstanceID()))
+
+	// while preparing for shutdown, the blocks are not synchronized anymore
+	g.syncStores(ctx, syncReasonPeriodic)
+	require.Equal(t, 0.0, testutil.ToFloat64(g.bucketSync.WithLabelValues(syncReasonPeriodic)))
 
 	// after GET is invoked, the expected result is now "set"
 	response3 := httptest.NewRecorder()
@@ -114,6 +133,11 @@ func TestStoreGateway_PrepareShutdownHandler(t *testing.T) {
 	require.NoError(t, err)
 	require.False(t, exists)
 	require.True(t, g.ringLifecycler.ShouldKeepInstanceInTheRingOnShutdown())
+	require.Equal(t, ring.ACTIVE, getInstanceState(ctx, t, ringStore, g.ringLifecycler.GetInstanceID()))
+
+	// the blocks are synchronized again
+	g.syncStores(ctx, syncReasonPeriodic)
+	require.Equal(t, 1.0, testutil.ToFloat64(g.bucketSync.WithLabelValues(syncReasonPeriodic)))
 
 	// after POST is invoked, and store-gateway is stopped, it is required that it gets removed from the ring
 	response5 := httptest.NewRecorder()
@@ -151,7 +175,9 @@ func TestStoreGateway_InitialisePrepareShutdownAtStartup(t *testing.T) {
 	ctx := context.Background()
 	require.NoError(t, services.StartAndAwaitRunning(ctx, g))
 	// since the shutdown marker is present, ensure that unregistering is required
+	// and that the store-gateway is LEAVING in the ring
 	require.False(t, g.ringLifecycler.ShouldKeepInstanceInTheRingOnShutdown())
+	require.Equal(t, ring.LEAVING, getInstanceState(ctx, t, ringStore, g.ringLifecycler.GetInstanceID()))
 
 	// after GET is invoked, the expected result is "set"
 	response1 := httptest.NewRecorder()
@@ -173,3 +199,127 @@ func TestStoreGateway_InitialisePrepareShutdownAtStartup(t *testing.T) {
 	ringDesc = getRingDesc(ctx, t, ringStore)
 	assert.Empty(t, ringDesc.GetIngesters())
 }
+
+func TestStoreGateway_PrepareShutdownHandler_ShouldKeepServingBlocksUntilTheNextOwnerLoadsThem(t *testing.T) {
+	test.VerifyNoLeak(t)
+
+	const (
+		userID    = "user-1"
+		numBlocks = 12
+	)
+
+	ctx := context.Background()
+	bucketClient, storageDir := mimir_testutil.PrepareFilesystemBucket(t)
+
+	now := time.Now()
+	mockTSDB(t, path.Join(storageDir, userID), 24, numBlocks, now.Add(-24*time.Hour).Unix()*1000, now.Unix()*1000)
+	idx := createBucketIndex(t, bucketClient, userID)
+	require.Len(t, idx.Blocks, numBlocks)
+
+	ringStore, closer := consul.NewInMemoryClient(ring.GetCodec(), log.NewNopLogger(), nil)
+	t.Cleanup(func() { assert.NoError(t, closer.Close()) })
+
+	// Create 2 store-gateways with RF = 1, so that each block is owned by a single store-gateway.
+	var gateways []*StoreGateway
+	for i := 1; i <= 2; i++ {
+		storageCfg := mockStorageConfig(t)
+		storageCfg.BucketStore.SyncInterval = time.Hour // Do not trigger the periodic sync in this test.
+
+		gatewayCfg := mockGatewayConfig()
+		gatewayCfg.ShardingRing.ReplicationFactor = 1
+		gatewayCfg.ShardingRing.InstanceID = fmt.Sprintf("gateway-%d", i)
+		gatewayCfg.ShardingRing.InstanceAddr = fmt.Sprintf("127.0.0.%d", i)
+		gatewayCfg.ShardingRing.RingCheckPeriod = time.Hour // Do not check the ring topology changes in this test.
+
+		g, err := newStoreGateway(gatewayCfg, storageCfg, bucketClient, ringStore, defaultLimitsOverrides(t), log.NewNopLogger(), nil, nil)
+		require.NoError(t, err)
+		require.NoError(t, services.StartAndAwaitRunning(ctx, g))
+		t.Cleanup(func() { assert.NoError(t, services.StopAndAwaitTerminated(ctx, g)) })
+
+		gateways = append(gateways, g)
+	}
+
+	// Wait until both store-gateways see each other ACTIVE in the ring, then re-sync
+	// so that each one only keeps the blocks it owns.
+	for _, g := range gateways {
+		dstest.Poll(t, 5*time.Second, 2, func() interface{} {
+			set, err := g.ring.GetAllHealthy(BlocksOwnerRead)
+			if err != nil {
+				return 0
+			}
+			return len(set.Instances)
+		})
+	}
+	for _, g := range gateways {
+		g.syncStores(ctx, syncReasonRingChange)
+	}
+
+	queryBlocks := func(g *StoreGateway) map[string]struct{} {
+		srv := newStoreGatewayTestServer(t, g)
+		req := &storepb.SeriesRequest{MinTime: math.MinInt64, MaxTime: math.MaxInt64}
+		_, _, hints, _, err := srv.Series(setUserIDToGRPCContext(ctx, userID), req)
+		require.NoError(t, err)
+
+		queried := map[string]struct{}{}
+		for _, b := range hints.QueriedBlocks {
+			queried[b.Id] = struct{}{}
+		}
+		return queried
+	}
+
+	// The store-gateway preparing for shutdown is the one owning the most blocks.
+	leaving, next := gateways[0], gateways[1]
+	if len(queryBlocks(leaving)) < len(queryBlocks(next)) {
+		leaving, next = next, leaving
+	}
+
+	leavingBlocks := queryBlocks(leaving)
+	require.NotEmpty(t, leavingBlocks)
+	require.Len(t, queryBlocks(next), numBlocks-len(leavingBlocks))
+
+	response := httptest.NewRecorder()
+	leaving.PrepareShutdownHandler(response, httptest.NewRequest("POST", "/store-gateway/prepare-shutdown", nil))
+	require.Equal(t, 204, response.Code)
+
+	dstest.Poll(t, 5*time.Second, ring.LEAVING, func() interface{} {
+		state, _ := next.ring.GetInstanceState(leaving.ringLifecycler.GetInstanceID())
+		return state
+	})
+
+	// While draining, the blocks of the LEAVING store-gateway are read from both the LEAVING
+	// store-gateway and the next one in the ring.
+	bufDescs, bufHosts, bufZones := ring.MakeBuffersForGet()
+	for _, b := range idx.Blocks {
+		if _, ok := leavingBlocks[b.ID.String()]; !ok {
+			continue
+		}
+
+		set, err := next.ring.Get(mimir_tsdb.HashBlockID(b.ID), BlocksRead, bufDescs, bufHosts, bufZones)
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []string{leaving.ringLifecycler.GetInstanceAddr(), next.ringLifecycler.GetInstanceAddr()}, set.GetAddresses())
+	}
+
+	// The LEAVING store-gateway keeps serving its blocks until the next one has loaded them.
+	assert.Equal(t, leavingBlocks, queryBlocks(leaving))
+
+	next.syncStores(ctx, syncReasonRingChange)
+	assert.Len(t, queryBlocks(next), numBlocks)
+
+	// The LEAVING store-gateway doesn't sync anymore, and still serves its blocks.
+	leaving.syncStores(ctx, syncReasonPeriodic)
+	assert.Equal(t, leavingBlocks, queryBlocks(leaving))
+
+	// Once the LEAVING store-gateway is stopped, it gets removed from the ring and the
+	// next one serves all the blocks.
+	require.NoError(t, services.StopAndAwaitTerminated(ctx, leaving))
+	dstest.Poll(t, 5*time.Second, 1, func() interface{} {
+		return next.ring.InstancesCount()
+	})
+
+	for _, b := range idx.Blocks {
+		set, err := next.ring.Get(mimir_tsdb.HashBlockID(b.ID), BlocksRead, bufDescs, bufHosts, bufZones)
+		require.NoError(t, err)
+		assert.Equal(t, []string{next.ringLifecycler.GetInstanceAddr()}, set.GetAddresses())
+	}
+	assert.Len(t, queryBlocks(next), numBlocks)
+}
@@ -11,6 +11,7 @@ import (
 	"net"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/go-kit/log"
@@ -19,6 +20,8 @@ import (
 	"github.com/grafana/dskit/kv"
 	"github.com/grafana/dskit/netutil"
 	"github.com/grafana/dskit/ring"
+
+	"github.com/grafana/mimir/pkg/util"
 )
 
 const (
@@ -36,14 +39,31 @@ const (
 	// set on the components running the store-gateway ring client.
 	sharedOptionWithRingClient = " This option needs be set both on the store-gateway, querier and ruler when running in microservices mode."
 
-	ringFlagsPrefix          = "store-gateway.sharding-ring."
-	ringHeartbeatTimeoutFlag = ringFlagsPrefix + "heartbeat-timeout"
+	ringFlagsPrefix                    = "store-gateway.sharding-ring."
+	ringHeartbeatTimeoutFlag           = ringFlagsPrefix + "heartbeat-timeout"
+	ringAutoForgetUnhealthyPeriodsFlag = ringFlagsPrefix + "auto-forget-unhealthy-periods"
+	ringTokensFilePathFlag             = ringFlagsPrefix + "tokens-file-path"
+	ringInstanceWeightFlag             = ringFlagsPrefix + "instance-weight"
+	ringTokenGenerationStrategyFlag    = ringFlagsPrefix + "token-generation-strategy"
+	ringSpreadMinimizingZonesFlag      = ringFlagsPrefix + "spread-minimizing-zones"
+
+	// Allowed values for the token generation strategy.
+	tokenGenerationRandom           = "random"
+	tokenGenerationSpreadMinimizing = "spread-minimizing"
+
+	// spreadMinimizingMaxNumTokens is the max number of tokens generated for each instance by the
+	// spread-minimizing token generator.
+	spreadMinimizingMaxNumTokens = 512
 )
 
 var (
 	// BlocksOwnerSync is the operation used to check the authoritative owners of a block
 	// (replicas included).
-	BlocksOwnerSync = ring.NewOp([]ring.InstanceState{ring.JOINING, ring.ACTIVE, ring.LEAVING}, nil)
+	BlocksOwnerSync = ring.NewOp([]ring.InstanceState{ring.JOINING, ring.ACTIVE, ring.LEAVING}, func(s ring.InstanceState) bool {
+		// A LEAVING store-gateway keeps its blocks, but the replication set is extended so that
+		// the next instance in the ring loads them before the LEAVING one is shut down.
+		return s == ring.LEAVING
+	})
 
 	// BlocksOwnerRead is the operation used to check the authoritative owners of a block
 	// (replicas included) that are available for queries (a store-gateway is available for
@@ -51,11 +71,14 @@ var (
 	BlocksOwnerRead = ring.NewOp([]ring.InstanceState{ring.ACTIVE}, nil)
 
 	// BlocksRead is the operation run by the querier to query blocks via the store-gateway.
-	BlocksRead = ring.NewOp([]ring.InstanceState{ring.ACTIVE}, func(s ring.InstanceState) bool {
-		// Blocks can only be queried from ACTIVE instances. However, if the block belongs to
+	BlocksRead = ring.NewOp([]ring.InstanceState{ring.ACTIVE, ring.LEAVING}, func(s ring.InstanceState) bool {
+		// Blocks can only be queried from ACTIVE and LEAVING instances. However, if the block belongs to
 		// a non-active instance, then we should extend the replication set and try to query it
 		// from the next ACTIVE instance in the ring (which is expected to have it because a
 		// store-gateway keeps their previously owned blocks until new owners are ACTIVE).
+		// A LEAVING instance is kept in the replication set because it still serves its blocks
+		// while the next instance is loading them: the querier retries the blocks missing from
+		// one instance on the other.
 		return s != ring.ACTIVE
 	})
 )
@@ -71,9 +94,15 @@ type RingConfig struct {
 	ReplicationFactor    int           `yaml:"replication_factor" category:"advanced"`
 	TokensFilePath       string        `yaml:"tokens_file_path"`
 	NumTokens            int           `yaml:"num_tokens" category:"advanced"`
+	InstanceWeight       float64       `yaml:"instance_weight" category:"experimental"`
 	ZoneAwarenessEnabled bool          `yaml:"zone_awareness_enabled"`
 	AutoForgetEnabled    bool          `yaml:"auto_forget_enabled"`
 
+	TokenGenerationStrategy string                 `yaml:"token_generation_strategy" category:"experimental"`
+	SpreadMinimizingZones   flagext.StringSliceCSV `yaml:"spread_minimizing_zones" category:"experimental"`
+
+	AutoForgetUnhealthyPeriods int `yaml:"auto_forget_unhealthy_periods" category:"advanced"`
+
 	// Wait ring stability.
 	WaitStabilityMinDuration time.Duration `yaml:"wait_stability_min_duration" category:"advanced"`
 	WaitStabilityMaxDuration time.Duration `yaml:"wait_stability_max_duration" category:"advanced"`
@@ -107,10 +136,14 @@ func (cfg *RingConfig) RegisterFlags(f *flag.FlagSet, logger log.Logger) {
 	f.DurationVar(&cfg.HeartbeatPeriod, ringFlagsPrefix+"heartbeat-period", 15*time.Second, "Period at which to heartbeat to the ring. 0 = disabled.")
 	f.DurationVar(&cfg.HeartbeatTimeout, ringHeartbeatTimeoutFlag, time.Minute, "The heartbeat timeout after which store gateways are considered unhealthy within the ring. 0 = never (timeout disabled)."+sharedOptionWithRingClient)
 	f.IntVar(&cfg.ReplicationFactor, ringFlagsPrefix+"replication-factor", 3, "The replication factor to use when sharding blocks."+sharedOptionWithRingClient)
-	f.StringVar(&cfg.TokensFilePath, ringFlagsPrefix+"tokens-file-path", "", "File path where tokens are stored. If empty, tokens are not stored at shutdown and restored at startup.")
+	f.StringVar(&cfg.TokensFilePath, ringTokensFilePathFlag, "", fmt.Sprintf("File path where tokens are stored. If empty, tokens are not stored at shutdown and restored at startup. Must be empty if -%s is set to %q.", ringTokenGenerationStrategyFlag, tokenGenerationSpreadMinimizing))
 	f.BoolVar(&cfg.ZoneAwarenessEnabled, ringFlagsPrefix+"zone-awareness-enabled", false, "True to enable zone-awareness and replicate blocks across different availability zones."+sharedOptionWithRingClient)
-	f.IntVar(&cfg.NumTokens, ringFlagsPrefix+"num-tokens", ringNumTokensDefault, "Number of tokens for each store-gateway.")
-	f.BoolVar(&cfg.AutoForgetEnabled, ringFlagsPrefix+"auto-forget-enabled", true, fmt.Sprintf("When enabled, a store-gateway is automatically removed from the ring after failing to heartbeat the ring for a period longer than %d times the configured -%s.", ringAutoForgetUnhealthyPeriods, ringHeartbeatTimeoutFlag))
+	f.IntVar(&cfg.NumTokens, ringFlagsPrefix+"num-tokens", ringNumTokensDefault, fmt.Sprintf("Number of tokens for each store-gateway. Must not be greater than %d if -%s is set to %q.", spreadMinimizingMaxNumTokens, ringTokenGenerationStrategyFlag, tokenGenerationSpreadMinimizing))
+	f.Float64Var(&cfg.InstanceWeight, ringInstanceWeightFlag, 1, fmt.Sprintf("Weight of this store-gateway in the ring. The number of tokens registered by the store-gateway is the number of tokens multiplied by the weight, so that store-gateways running on bigger machines can own a proportionally larger share of the blocks. Lowering the weight doesn't remove the tokens already owned by the store-gateway, for example when they're loaded from the tokens file. Must be 1 if -%s is set to %q.", ringTokenGenerationStrategyFlag, tokenGenerationSpreadMinimizing))
+	f.StringVar(&cfg.TokenGenerationStrategy, ringTokenGenerationStrategyFlag, tokenGenerationRandom, fmt.Sprintf("Specifies the strategy used for generating tokens for store-gateways. Supported values are: %s.", strings.Join([]string{tokenGenerationRandom, tokenGenerationSpreadMinimizing}, ",")))
+	f.Var(&cfg.SpreadMinimizingZones, ringSpreadMinimizingZonesFlag, fmt.Sprintf("Comma-separated list of zones in which spread minimizing strategy is used for token generation. This value must include all zones in which store-gateways are deployed, and must not change over time. This configuration is used only when -%s is set to %q.", ringTokenGenerationStrategyFlag, tokenGenerationSpreadMinimizing))
+	f.BoolVar(&cfg.AutoForgetEnabled, ringFlagsPrefix+"auto-forget-enabled", true, fmt.Sprintf("When enabled, a store-gateway is automatically removed from the ring after failing to heartbeat the ring for a period longer than -%s times the configured -%s.", ringAutoForgetUnhealthyPeriodsFlag, ringHeartbeatTimeoutFlag))
+	f.IntVar(&cfg.AutoForgetUnhealthyPeriods, ringAutoForgetUnhealthyPeriodsFlag, ringAutoForgetUnhealthyPeriods, fmt.Sprintf("Number of consecutive -%s periods after which an unhealthy store-gateway is automatically removed from the ring, when -%s is enabled.", ringHeartbeatTimeoutFlag, ringFlagsPrefix+"auto-forget-enabled"))
 
 	// Wait stability flags.
 	f.DurationVar(&cfg.WaitStabilityMinDuration, ringFlagsPrefix+"wait-stability-min-duration", 0, "Minimum time to wait for ring stability at startup, if set to positive value.")
@@ -152,6 +185,11 @@ func (cfg *RingConfig) ToLifecyclerConfig(logger log.Logger) (ring.BasicLifecycl
 
 	instancePort := ring.GetInstancePort(cfg.InstancePort, cfg.ListenPort)
 
+	tokenGenerator, err := cfg.tokenGenerator()
+	if err != nil {
+		return ring.BasicLifecyclerConfig{}, err
+	}
+
 	return ring.BasicLifecyclerConfig{
 		ID:                              cfg.InstanceID,
 		Addr:                            net.JoinHostPort(instanceAddr, strconv.Itoa(instancePort)),
@@ -159,7 +197,42 @@ func (cfg *RingConfig) ToLifecyclerConfig(logger log.Logger) (ring.BasicLifecycl
 		HeartbeatPeriod:                 cfg.HeartbeatPeriod,
 		HeartbeatTimeout:                cfg.HeartbeatTimeout,
 		TokensObservePeriod:             0,
-		NumTokens:                       cfg.NumTokens,
+		NumTokens:                       util.WeightedNumTokens(cfg.NumTokens, cfg.InstanceWeight),
 		KeepInstanceInTheRingOnShutdown: !cfg.UnregisterOnShutdown,
+		RingTokenGenerator:              tokenGenerator,
 	}, nil
 }
+
+func (cfg *RingConfig) validateTokenGeneration() error {
+	if cfg.TokenGenerationStrategy == tokenGenerationRandom {
+		return nil
+	}
+	if cfg.TokenGenerationStrategy != tokenGenerationSpreadMinimizing {
+		return fmt.Errorf("%w: %q", errInvalidRingTokenGenerationStrategy, cfg.TokenGenerationStrategy)
+	}
+
+	if cfg.TokensFilePath != "" {
+		return fmt.Errorf("%w: strategy requires -%s to be empty", errRingSpreadMinimizingMisconfigured, ringTokensFilePathFlag)
+	}
+	if cfg.InstanceWeight != 1 {
+		return fmt.Errorf("%w: strategy requires -%s to be 1", errRingSpreadMinimizingMisconfigured, ringInstanceWeightFlag)
+	}
+	if cfg.NumTokens > spreadMinimizingMaxNumTokens {
+		return fmt.Errorf("%w: strategy requires -%s to be not greater than %d", errRingSpreadMinimizingMisconfigured, ringFlagsPrefix+"num-tokens", spreadMinimizingMaxNumTokens)
+	}
+	if _, err := cfg.tokenGenerator(); err != nil {
+		return fmt.Errorf("%w: %w", errRingSpreadMinimizingMisconfigured, err)
+	}
+
+	return nil
+}
+
+// tokenGenerator returns the ring.TokenGenerator for the configured token generation strategy.
+func (cfg *RingConfig) tokenGenerator() (ring.TokenGenerator, error) {
+	switch cfg.TokenGenerationStrategy {
+	case tokenGenerationSpreadMinimizing:
+		return ring.NewSpreadMinimizingTokenGenerator(cfg.InstanceID, cfg.InstanceZone, cfg.SpreadMinimizingZones, false)
+	default:
+		return ring.NewRandomTokenGenerator(), nil
+	}
+}
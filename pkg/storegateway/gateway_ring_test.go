@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/go-kit/log"
+	"github.com/grafana/dskit/flagext"
 	"github.com/grafana/dskit/ring"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -51,7 +52,7 @@ func TestIsHealthyForStoreGatewayOperations(t *testing.T) {
 			timeout:           time.Minute,
 			ownerSyncExpected: true,
 			ownerReadExpected: false,
-			readExpected:      false,
+			readExpected:      true,
 		},
 		"PENDING instance with last keepalive newer than timeout": {
 			instance:          &ring.InstanceDesc{State: ring.PENDING, Timestamp: time.Now().Add(-30 * time.Second).Unix()},
@@ -92,3 +93,41 @@ func TestUnregisterOnShutdownFlag(t *testing.T) {
 		assert.True(t, lcCfg.KeepInstanceInTheRingOnShutdown)
 	}
 }
+
+func TestInstanceWeightFlag(t *testing.T) {
+	cfg := RingConfig{}
+	flagext.DefaultValues(&cfg)
+	cfg.InstanceAddr = "test"
+
+	lcCfg, err := cfg.ToLifecyclerConfig(log.NewNopLogger())
+	require.NoError(t, err)
+	assert.Equal(t, ringNumTokensDefault, lcCfg.NumTokens)
+
+	cfg.InstanceWeight = 2
+	lcCfg, err = cfg.ToLifecyclerConfig(log.NewNopLogger())
+	require.NoError(t, err)
+	assert.Equal(t, 2*ringNumTokensDefault, lcCfg.NumTokens)
+}
+
+func TestTokenGenerationStrategyFlag(t *testing.T) {
+	cfg := RingConfig{}
+	flagext.DefaultValues(&cfg)
+	cfg.InstanceAddr = "test"
+
+	lcCfg, err := cfg.ToLifecyclerConfig(log.NewNopLogger())
+	require.NoError(t, err)
+	assert.IsType(t, &ring.RandomTokenGenerator{}, lcCfg.RingTokenGenerator)
+
+	cfg.TokenGenerationStrategy = tokenGenerationSpreadMinimizing
+	cfg.InstanceID = "store-gateway-zone-a-1"
+	cfg.InstanceZone = "zone-a"
+	cfg.SpreadMinimizingZones = []string{"zone-a", "zone-b"}
+	lcCfg, err = cfg.ToLifecyclerConfig(log.NewNopLogger())
+	require.NoError(t, err)
+	assert.IsType(t, &ring.SpreadMinimizingTokenGenerator{}, lcCfg.RingTokenGenerator)
+	assert.Len(t, lcCfg.RingTokenGenerator.GenerateTokens(lcCfg.NumTokens, nil), ringNumTokensDefault)
+
+	cfg.InstanceID = "store-gateway"
+	_, err = cfg.ToLifecyclerConfig(log.NewNopLogger())
+	require.Error(t, err)
+}
@@ -78,6 +78,65 @@ func TestConfig_Validate(t *testing.T) {
 			},
 			expected: nil,
 		},
+		"should fail if ring instance weight is not positive": {
+			setup: func(cfg *Config, _ *validation.Limits) {
+				cfg.ShardingRing.InstanceWeight = 0
+			},
+			expected: errInvalidRingInstanceWeight,
+		},
+		"should fail if ring auto-forget unhealthy periods is not positive": {
+			setup: func(cfg *Config, _ *validation.Limits) {
+				cfg.ShardingRing.AutoForgetUnhealthyPeriods = 0
+			},
+			expected: errInvalidRingAutoForgetUnhealthyPeriods,
+		},
+		"should pass if ring auto-forget unhealthy periods is not positive but auto-forget is disabled": {
+			setup: func(cfg *Config, _ *validation.Limits) {
+				cfg.ShardingRing.AutoForgetEnabled = false
+				cfg.ShardingRing.AutoForgetUnhealthyPeriods = 0
+			},
+			expected: nil,
+		},
+		"should fail if ring token generation strategy is unsupported": {
+			setup: func(cfg *Config, _ *validation.Limits) {
+				cfg.ShardingRing.TokenGenerationStrategy = "unknown"
+			},
+			expected: errInvalidRingTokenGenerationStrategy,
+		},
+		"should pass if ring token generation strategy is spread-minimizing": {
+			setup: func(cfg *Config, _ *validation.Limits) {
+				setupSpreadMinimizingRingConfig(&cfg.ShardingRing)
+			},
+			expected: nil,
+		},
+		"should fail if ring token generation strategy is spread-minimizing and tokens file path is set": {
+			setup: func(cfg *Config, _ *validation.Limits) {
+				setupSpreadMinimizingRingConfig(&cfg.ShardingRing)
+				cfg.ShardingRing.TokensFilePath = "/tokens"
+			},
+			expected: errRingSpreadMinimizingMisconfigured,
+		},
+		"should fail if ring token generation strategy is spread-minimizing and instance weight is not 1": {
+			setup: func(cfg *Config, _ *validation.Limits) {
+				setupSpreadMinimizingRingConfig(&cfg.ShardingRing)
+				cfg.ShardingRing.InstanceWeight = 2
+			},
+			expected: errRingSpreadMinimizingMisconfigured,
+		},
+		"should fail if ring token generation strategy is spread-minimizing and num tokens is too high": {
+			setup: func(cfg *Config, _ *validation.Limits) {
+				setupSpreadMinimizingRingConfig(&cfg.ShardingRing)
+				cfg.ShardingRing.NumTokens = spreadMinimizingMaxNumTokens + 1
+			},
+			expected: errRingSpreadMinimizingMisconfigured,
+		},
+		"should fail if ring token generation strategy is spread-minimizing and instance zone is not a spread-minimizing zone": {
+			setup: func(cfg *Config, _ *validation.Limits) {
+				setupSpreadMinimizingRingConfig(&cfg.ShardingRing)
+				cfg.ShardingRing.InstanceZone = "zone-d"
+			},
+			expected: errRingSpreadMinimizingMisconfigured,
+		},
 	}
 
 	for testName, testData := range tests {
@@ -87,11 +146,22 @@ func TestConfig_Validate(t *testing.T) {
 			flagext.DefaultValues(cfg, limits)
 			testData.setup(cfg, limits)
 
-			assert.Equal(t, testData.expected, cfg.Validate(*limits))
+			if testData.expected == nil {
+				assert.NoError(t, cfg.Validate(*limits))
+			} else {
+				assert.ErrorIs(t, cfg.Validate(*limits), testData.expected)
+			}
 		})
 	}
 }
 
+func setupSpreadMinimizingRingConfig(cfg *RingConfig) {
+	cfg.TokenGenerationStrategy = tokenGenerationSpreadMinimizing
+	cfg.InstanceID = "store-gateway-zone-a-1"
+	cfg.InstanceZone = "zone-a"
+	cfg.SpreadMinimizingZones = []string{"zone-a", "zone-b", "zone-c"}
+}
+
 func TestStoreGateway_InitialSyncWithDefaultShardingEnabled(t *testing.T) {
 	test.VerifyNoLeak(t)
 
@@ -923,7 +993,7 @@ func TestStoreGateway_SyncShouldKeepPreviousBlocksIfInstanceIsUnhealthyInTheRing
 }
 
 func TestStoreGateway_RingLifecyclerAutoForgetUnhealthyInstances(t *testing.T) {
-	runTest := func(t *testing.T, autoForgetEnabled bool) {
+	runTest := func(t *testing.T, autoForgetEnabled bool, autoForgetUnhealthyPeriods int) {
 		test.VerifyNoLeak(t)
 
 		const unhealthyInstanceID = "unhealthy-id"
@@ -934,6 +1004,7 @@ func TestStoreGateway_RingLifecyclerAutoForgetUnhealthyInstances(t *testing.T) {
 		gatewayCfg.ShardingRing.HeartbeatPeriod = 100 * time.Millisecond
 		gatewayCfg.ShardingRing.HeartbeatTimeout = heartbeatTimeout
 		gatewayCfg.ShardingRing.AutoForgetEnabled = autoForgetEnabled
+		gatewayCfg.ShardingRing.AutoForgetUnhealthyPeriods = autoForgetUnhealthyPeriods
 
 		storageCfg := mockStorageConfig(t)
 
@@ -953,7 +1024,7 @@ func TestStoreGateway_RingLifecyclerAutoForgetUnhealthyInstances(t *testing.T) {
 			ringDesc := ring.GetOrCreateRingDesc(in)
 
 			instance := ringDesc.AddIngester(unhealthyInstanceID, "1.1.1.1", "", generateSortedTokens(ringNumTokensDefault), ring.ACTIVE, time.Now(), false, time.Time{})
-			instance.Timestamp = time.Now().Add(-(ringAutoForgetUnhealthyPeriods + 1) * heartbeatTimeout).Unix()
+			instance.Timestamp = time.Now().Add(-time.Duration(autoForgetUnhealthyPeriods+1) * heartbeatTimeout).Unix()
 			ringDesc.Ingesters[unhealthyInstanceID] = instance
 
 			return ringDesc, true, nil
@@ -986,11 +1057,15 @@ func TestStoreGateway_RingLifecyclerAutoForgetUnhealthyInstances(t *testing.T) {
 	}
 
 	t.Run("should auto-forget unhealthy instances in the ring when auto-forget is enabled", func(t *testing.T) {
-		runTest(t, true)
+		runTest(t, true, ringAutoForgetUnhealthyPeriods)
+	})
+
+	t.Run("should auto-forget unhealthy instances in the ring after the configured number of unhealthy periods", func(t *testing.T) {
+		runTest(t, true, 2)
 	})
 
 	t.Run("should not auto-forget unhealthy instances in the ring when auto-forget is disabled", func(t *testing.T) {
-		runTest(t, false)
+		runTest(t, false, ringAutoForgetUnhealthyPeriods)
 	})
 }
 
@@ -0,0 +1,100 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package storegateway
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-kit/log/level"
+	"github.com/gorilla/mux"
+
+	"github.com/grafana/mimir/pkg/util"
+)
+
+const (
+	defaultWarmupPinDuration = time.Hour
+	maxWarmupPinDuration     = 24 * time.Hour
+)
+
+type warmupResponse struct {
+	Tenant   string `json:"tenant"`
+	Instance string `json:"instance"`
+	// PinnedUntil is the time until which the lazy loaded index-headers of the warmed up blocks are
+	// kept loaded regardless of the idle timeout.
+	PinnedUntil time.Time           `json:"pinnedUntil"`
+	Blocks      []warmupBlockResult `json:"blocks"`
+}
+
+type warmupBlockResult struct {
+	ID      string `json:"id"`
+	MinTime int64  `json:"minTime"`
+	MaxTime int64  `json:"maxTime"`
+	Error   string `json:"error,omitempty"`
+}
+
+// WarmupHandler loads the index-headers of the tenant's blocks owned by this store-gateway and overlapping
+// the requested time range, and pins them so that they're not unloaded before the time range gets queried.
+// It's meant to be called ahead of scheduled heavy queries, on each store-gateway owning the tenant's blocks.
+func (s *StoreGateway) WarmupHandler(w http.ResponseWriter, req *http.Request) {
+	tenantID := mux.Vars(req)["tenant"]
+	if tenantID == "" {
+		http.Error(w, "Tenant ID can't be empty", http.StatusBadRequest)
+		return
+	}
+
+	if req.FormValue("start") == "" || req.FormValue("end") == "" {
+		http.Error(w, "The start and end parameters are required", http.StatusBadRequest)
+		return
+	}
+	start, err := util.ParseTimeParam(req, "start", 0)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	end, err := util.ParseTimeParam(req, "end", 0)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if end < start {
+		http.Error(w, "The end time can't be before the start time", http.StatusBadRequest)
+		return
+	}
+
+	pinDuration := defaultWarmupPinDuration
+	if v := req.FormValue("pin_duration"); v != "" {
+		pinDuration, err = time.ParseDuration(v)
+		if err != nil || pinDuration < 0 || pinDuration > maxWarmupPinDuration {
+			http.Error(w, fmt.Sprintf("Invalid pin_duration: it must be a duration between 0 and %s", maxWarmupPinDuration), http.StatusBadRequest)
+			return
+		}
+	}
+
+	store := s.stores.getStore(tenantID)
+	if store == nil {
+		http.Error(w, "Tenant not loaded by this store-gateway", http.StatusNotFound)
+		return
+	}
+
+	pinUntil := time.Now().Add(pinDuration)
+	results := store.WarmupBlocks(req.Context(), start, end, pinUntil)
+	level.Info(s.logger).Log("msg", "warmed up blocks", "user", tenantID, "start", start, "end", end, "pinned_until", pinUntil, "blocks", len(results))
+
+	res := warmupResponse{
+		Tenant:      tenantID,
+		Instance:    s.gatewayCfg.ShardingRing.InstanceID,
+		PinnedUntil: pinUntil,
+		Blocks:      make([]warmupBlockResult, 0, len(results)),
+	}
+	for _, r := range results {
+		entry := warmupBlockResult{ID: r.ID.String(), MinTime: r.MinTime, MaxTime: r.MaxTime}
+		if r.Err != nil {
+			entry.Error = r.Err.Error()
+		}
+		res.Blocks = append(res.Blocks, entry)
+	}
+
+	util.WriteJSONResponse(w, res)
+}
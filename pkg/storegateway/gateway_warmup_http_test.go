@@ -0,0 +1,73 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package storegateway
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/grafana/dskit/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStoreGateway_WarmupHandler(t *testing.T) {
+	g, _ := createStoreGateway(t, nil)
+	require.NoError(t, services.StartAndAwaitRunning(context.Background(), g))
+
+	tests := map[string]struct {
+		tenant       string
+		query        string
+		expectedCode int
+		expectedBody string
+	}{
+		"missing tenant": {
+			query:        "start=0&end=1000",
+			expectedCode: http.StatusBadRequest,
+			expectedBody: "Tenant ID can't be empty",
+		},
+		"missing time range": {
+			tenant:       "user-1",
+			query:        "start=0",
+			expectedCode: http.StatusBadRequest,
+			expectedBody: "The start and end parameters are required",
+		},
+		"invalid start": {
+			tenant:       "user-1",
+			query:        "start=foo&end=1000",
+			expectedCode: http.StatusBadRequest,
+			expectedBody: "invalid time value for 'start'",
+		},
+		"end before start": {
+			tenant:       "user-1",
+			query:        "start=1000&end=0",
+			expectedCode: http.StatusBadRequest,
+			expectedBody: "The end time can't be before the start time",
+		},
+		"pin duration too long": {
+			tenant:       "user-1",
+			query:        "start=0&end=1000&pin_duration=48h",
+			expectedCode: http.StatusBadRequest,
+			expectedBody: "Invalid pin_duration",
+		},
+		"tenant not loaded": {
+			tenant:       "user-1",
+			query:        "start=0&end=1000&pin_duration=2h",
+			expectedCode: http.StatusNotFound,
+			expectedBody: "Tenant not loaded by this store-gateway",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			req := mux.SetURLVars(httptest.NewRequest(http.MethodPost, "/store-gateway/tenant/"+tc.tenant+"/warmup?"+tc.query, nil), map[string]string{"tenant": tc.tenant})
+			rec := httptest.NewRecorder()
+			g.WarmupHandler(rec, req)
+			assert.Equal(t, tc.expectedCode, rec.Code)
+			assert.Contains(t, rec.Body.String(), tc.expectedBody)
+		})
+	}
+}
@@ -0,0 +1,469 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package indexcache
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/grafana/dskit/cache"
+	"github.com/grafana/dskit/concurrency"
+	"github.com/grafana/dskit/flagext"
+	lru "github.com/hashicorp/golang-lru/v2/simplelru"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"golang.org/x/crypto/blake2b"
+)
+
+const (
+	// diskCacheMaxConcurrentWrites is the maximum number of items concurrently written to the disk cache.
+	// Items stored while the limit is reached are dropped.
+	diskCacheMaxConcurrentWrites = 16
+
+	// diskCacheMaxConcurrentReads is the maximum number of items concurrently read by a single GetMulti call.
+	diskCacheMaxConcurrentReads = 16
+
+	// diskCacheHeaderSize is the size of the header of each cached item, holding its expiration time.
+	diskCacheHeaderSize = 8
+
+	// diskCacheBlockSize is the filesystem block size the items are accounted in. Each item is stored in its own
+	// file, which takes a whole number of blocks on disk, so small items take much more space than their size.
+	diskCacheBlockSize = 4096
+
+	// diskCacheTmpDir is the directory, relative to the cache directory, where the items are written before
+	// being moved to their final path.
+	diskCacheTmpDir = "tmp"
+
+	diskCacheOpRead   = "read"
+	diskCacheOpWrite  = "write"
+	diskCacheOpDelete = "delete"
+)
+
+// DiskCacheConfig holds the disk cache config.
+type DiskCacheConfig struct {
+	// Dir is the directory where the cached items are stored.
+	Dir string
+	// MaxSize represents overall maximum number of bytes the cache can store on disk.
+	MaxSize flagext.Bytes
+	// MaxItemSize represents maximum size of single item.
+	MaxItemSize flagext.Bytes
+}
+
+// DiskCache is a cache.Cache storing each item in a file on the local disk. The size of the items is
+// accounted in filesystem blocks, and the cached items are evicted in LRU order once the configured max
+// size is reached. The cached items survive restarts: the items found on disk at startup are loaded back
+// in the cache in the background, from the least to the most recently written.
+type DiskCache struct {
+	logger           log.Logger
+	dir              string
+	maxSizeBytes     uint64
+	maxItemSizeBytes uint64
+
+	mtx     sync.Mutex
+	lru     *lru.LRU[string, uint64]
+	curSize uint64
+
+	// writes limits the number of concurrent writes.
+	writes chan struct{}
+
+	// loaded is closed once the items found on disk at startup have been loaded.
+	loaded   chan struct{}
+	stopLoad context.CancelFunc
+
+	evicted       prometheus.Counter
+	droppedWrites prometheus.Counter
+	failures      *prometheus.CounterVec
+}
+
+// NewDiskCache creates a new DiskCache storing the items in the configured directory, and starts
+// loading the items previously stored in it in the background.
+func NewDiskCache(logger log.Logger, reg prometheus.Registerer, config DiskCacheConfig) (*DiskCache, error) {
+	if config.MaxItemSize > config.MaxSize {
+		return nil, errors.Errorf("max item size (%v) cannot be bigger than overall cache size (%v)", config.MaxItemSize, config.MaxSize)
+	}
+
+	// Remove the leftovers of the writes interrupted by a previous shutdown.
+	if err := os.RemoveAll(filepath.Join(config.Dir, diskCacheTmpDir)); err != nil {
+		return nil, errors.Wrap(err, "remove disk cache temporary directory")
+	}
+	if err := os.MkdirAll(filepath.Join(config.Dir, diskCacheTmpDir), os.ModePerm); err != nil {
+		return nil, errors.Wrap(err, "create disk cache directory")
+	}
+
+	c := &DiskCache{
+		logger:           logger,
+		dir:              config.Dir,
+		maxSizeBytes:     uint64(config.MaxSize),
+		maxItemSizeBytes: uint64(config.MaxItemSize),
+		writes:           make(chan struct{}, diskCacheMaxConcurrentWrites),
+		loaded:           make(chan struct{}),
+	}
+
+	// Initialize LRU cache with a high size limit since we will manage evictions ourselves
+	// based on stored size using `RemoveOldest` method.
+	l, err := lru.NewLRU(maxInt, c.onEvict)
+	if err != nil {
+		return nil, err
+	}
+	c.lru = l
+
+	c.evicted = promauto.With(reg).NewCounter(prometheus.CounterOpts{
+		Name: "thanos_store_index_cache_disk_items_evicted_total",
+		Help: "Total number of items that were evicted from the disk index cache.",
+	})
+	c.droppedWrites = promauto.With(reg).NewCounter(prometheus.CounterOpts{
+		Name: "thanos_store_index_cache_disk_writes_dropped_total",
+		Help: "Total number of items that were not written to the disk index cache because too many writes were in progress.",
+	})
+	c.failures = promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+		Name: "thanos_store_index_cache_disk_operation_failures_total",
+		Help: "Total number of disk index cache operations that failed.",
+	}, []string{"operation"})
+	for _, op := range []string{diskCacheOpRead, diskCacheOpWrite, diskCacheOpDelete} {
+		c.failures.WithLabelValues(op)
+	}
+
+	_ = promauto.With(reg).NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "thanos_store_index_cache_disk_items",
+		Help: "Current number of items in the disk index cache.",
+	}, func() float64 {
+		c.mtx.Lock()
+		defer c.mtx.Unlock()
+		return float64(c.lru.Len())
+	})
+	_ = promauto.With(reg).NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "thanos_store_index_cache_disk_size_bytes",
+		Help: "Current disk space in bytes taken by the items in the disk index cache, accounted in filesystem blocks.",
+	}, func() float64 {
+		c.mtx.Lock()
+		defer c.mtx.Unlock()
+		return float64(c.curSize)
+	})
+	_ = promauto.With(reg).NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "thanos_store_index_cache_disk_max_size_bytes",
+		Help: "Maximum number of bytes to be held in the disk index cache.",
+	}, func() float64 {
+		return float64(c.maxSizeBytes)
+	})
+
+	level.Info(logger).Log(
+		"msg", "created disk index cache",
+		"dir", c.dir,
+		"maxItemSizeBytes", c.maxItemSizeBytes,
+		"maxSizeBytes", c.maxSizeBytes,
+	)
+
+	// Loading the items can take a long time on a big cache, so it doesn't delay the startup:
+	// the items not loaded yet are cache misses in the meantime.
+	loadCtx, stopLoad := context.WithCancel(context.Background())
+	c.stopLoad = stopLoad
+	go func() {
+		defer close(c.loaded)
+
+		if err := c.load(loadCtx); err != nil {
+			if errors.Is(err, context.Canceled) {
+				return
+			}
+			level.Warn(logger).Log("msg", "failed to load items from disk index cache", "dir", c.dir, "err", err)
+			return
+		}
+
+		c.mtx.Lock()
+		items, curSize := c.lru.Len(), c.curSize
+		c.mtx.Unlock()
+		level.Info(logger).Log("msg", "loaded disk index cache", "dir", c.dir, "items", items, "curSize", curSize)
+	}()
+
+	return c, nil
+}
+
+// load adds the items found on disk to the cache, from the least to the most recently written.
+// The items written since the cache has been created are more recent, so they're kept as is.
+func (c *DiskCache) load(ctx context.Context) error {
+	type item struct {
+		name    string
+		size    uint64
+		modTime time.Time
+	}
+	var items []item
+
+	err := filepath.WalkDir(c.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path == filepath.Join(c.dir, diskCacheTmpDir) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !isDiskCacheItemName(d.Name()) || filepath.Join(c.dir, d.Name()[:2], d.Name()) != path {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			if os.IsNotExist(err) {
+				// The item has been concurrently replaced or evicted.
+				return nil
+			}
+			return err
+		}
+		items = append(items, item{name: d.Name(), size: diskCacheAllocatedSize(uint64(info.Size())), modTime: info.ModTime()})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].modTime.Before(items[j].modTime)
+	})
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	// The loaded items are less recent than the ones already in the cache, so they're added as the
+	// least recently used ones: the LRU can only add an item as the most recent one, so the items
+	// already in the cache are moved back to the front after the loaded ones.
+	recent := c.lru.Keys()
+	for _, it := range items {
+		if c.lru.Contains(it.name) {
+			continue
+		}
+		c.lru.Add(it.name, it.size)
+		c.curSize += it.size
+	}
+	for _, name := range recent {
+		c.lru.Get(name)
+	}
+
+	for c.curSize > c.maxSizeBytes {
+		if _, _, ok := c.lru.RemoveOldest(); !ok {
+			break
+		}
+		c.evicted.Inc()
+	}
+	return nil
+}
+
+// GetMulti implements cache.Cache. The items are read from disk concurrently.
+func (c *DiskCache) GetMulti(ctx context.Context, keys []string, _ ...cache.Option) map[string][]byte {
+	values := make([][]byte, len(keys))
+	now := time.Now()
+
+	_ = concurrency.ForEachJob(ctx, len(keys), diskCacheMaxConcurrentReads, func(_ context.Context, idx int) error {
+		values[idx] = c.get(keys[idx], now)
+		return nil
+	})
+
+	hits := map[string][]byte{}
+	for idx, value := range values {
+		if value != nil {
+			hits[keys[idx]] = value
+		}
+	}
+	return hits
+}
+
+// get returns the value of the item of a given key, or nil if it's not found or expired.
+func (c *DiskCache) get(key string, now time.Time) []byte {
+	name := diskCacheItemName(key)
+
+	c.mtx.Lock()
+	_, ok := c.lru.Get(name)
+	c.mtx.Unlock()
+	if !ok {
+		return nil
+	}
+
+	data, err := os.ReadFile(c.itemPath(name))
+	if err != nil {
+		if !os.IsNotExist(err) {
+			c.failures.WithLabelValues(diskCacheOpRead).Inc()
+			level.Warn(c.logger).Log("msg", "failed to read item from disk index cache", "path", c.itemPath(name), "err", err)
+		}
+		c.remove(name)
+		return nil
+	}
+	if len(data) < diskCacheHeaderSize {
+		c.remove(name)
+		return nil
+	}
+
+	// Remove the expired items.
+	if expiration := int64(binary.BigEndian.Uint64(data)); expiration > 0 && now.UnixMilli() >= expiration {
+		c.remove(name)
+		return nil
+	}
+
+	return data[diskCacheHeaderSize:]
+}
+
+// SetAsync implements cache.Cache.
+func (c *DiskCache) SetAsync(key string, value []byte, ttl time.Duration) {
+	c.SetMultiAsync(map[string][]byte{key: value}, ttl)
+}
+
+// SetMultiAsync implements cache.Cache.
+func (c *DiskCache) SetMultiAsync(data map[string][]byte, ttl time.Duration) {
+	var expiration int64
+	if ttl > 0 {
+		expiration = time.Now().Add(ttl).UnixMilli()
+	}
+
+	for key, value := range data {
+		size := uint64(diskCacheHeaderSize + len(value))
+		if size > c.maxItemSizeBytes {
+			level.Debug(c.logger).Log(
+				"msg", "item bigger than maxItemSizeBytes. Ignoring..",
+				"maxItemSizeBytes", c.maxItemSizeBytes,
+				"itemSize", size,
+			)
+			continue
+		}
+
+		select {
+		case c.writes <- struct{}{}:
+		default:
+			c.droppedWrites.Inc()
+			continue
+		}
+
+		// The value must be copied, because the caller may reuse it once this function returns.
+		buf := make([]byte, size)
+		binary.BigEndian.PutUint64(buf, uint64(expiration))
+		copy(buf[diskCacheHeaderSize:], value)
+
+		go func(name string) {
+			defer func() { <-c.writes }()
+
+			if err := c.write(name, buf); err != nil {
+				c.failures.WithLabelValues(diskCacheOpWrite).Inc()
+				level.Warn(c.logger).Log("msg", "failed to write item to disk index cache", "path", c.itemPath(name), "err", err)
+			}
+		}(diskCacheItemName(key))
+	}
+}
+
+// write writes the item to disk and adds it to the cache, evicting the least recently used items
+// if the cache is full. The item is first written to a temporary file, so that partially written
+// items are never read.
+func (c *DiskCache) write(name string, data []byte) error {
+	path := c.itemPath(name)
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Join(c.dir, diskCacheTmpDir), name+".*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+
+	// The item may have been concurrently written, in which case it's replaced.
+	if prevSize, ok := c.lru.Peek(name); ok {
+		c.curSize -= prevSize
+	}
+	size := diskCacheAllocatedSize(uint64(len(data)))
+	c.lru.Add(name, size)
+	c.curSize += size
+
+	for c.curSize > c.maxSizeBytes {
+		if oldest, _, ok := c.lru.GetOldest(); !ok || oldest == name {
+			break
+		}
+		c.lru.RemoveOldest()
+		c.evicted.Inc()
+	}
+	return nil
+}
+
+// Delete implements cache.Cache.
+func (c *DiskCache) Delete(_ context.Context, key string) error {
+	c.remove(diskCacheItemName(key))
+	return nil
+}
+
+// remove removes the item from the cache and deletes its file.
+func (c *DiskCache) remove(name string) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	c.lru.Remove(name)
+}
+
+// onEvict deletes the file of an item removed from the LRU. It's called with the lock held.
+func (c *DiskCache) onEvict(name string, size uint64) {
+	c.curSize -= size
+
+	if err := os.Remove(c.itemPath(name)); err != nil && !os.IsNotExist(err) {
+		c.failures.WithLabelValues(diskCacheOpDelete).Inc()
+		level.Warn(c.logger).Log("msg", "failed to delete item from disk index cache", "path", c.itemPath(name), "err", err)
+	}
+}
+
+// Stop implements cache.Cache. It interrupts the loading of the items found on disk at startup.
+func (c *DiskCache) Stop() {
+	c.stopLoad()
+	<-c.loaded
+}
+
+// Name implements cache.Cache.
+func (c *DiskCache) Name() string {
+	return "disk"
+}
+
+// itemPath returns the path of the file of an item. Items are spread across sub-directories
+// to keep the number of files in each directory manageable.
+func (c *DiskCache) itemPath(name string) string {
+	return filepath.Join(c.dir, name[:2], name)
+}
+
+// diskCacheAllocatedSize returns the disk space taken by an item of the given size.
+func diskCacheAllocatedSize(size uint64) uint64 {
+	return (size + diskCacheBlockSize - 1) / diskCacheBlockSize * diskCacheBlockSize
+}
+
+// diskCacheItemName returns the name of the file storing the item of a given key.
+func diskCacheItemName(key string) string {
+	checksum := blake2b.Sum256([]byte(key))
+	return hex.EncodeToString(checksum[:])
+}
+
+func isDiskCacheItemName(name string) bool {
+	if len(name) != hex.EncodedLen(blake2b.Size256) {
+		return false
+	}
+	_, err := hex.DecodeString(name)
+	return err == nil
+}
@@ -0,0 +1,217 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package indexcache
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/oklog/ulid"
+	"github.com/prometheus/client_golang/prometheus"
+	promtest "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiskCache(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	reg := prometheus.NewPedanticRegistry()
+
+	// Each item takes 8 bytes of header and 8 bytes of value, stored in a filesystem block, so the cache fits 3 items.
+	cfg := DiskCacheConfig{Dir: dir, MaxSize: 3*diskCacheBlockSize + 100, MaxItemSize: 20}
+	c := newTestDiskCache(t, reg, cfg)
+
+	c.SetMultiAsync(map[string][]byte{"key-1": []byte("value-01"), "key-2": []byte("value-02")}, time.Hour)
+	waitDiskCacheWrites(c)
+	assert.Equal(t, map[string][]byte{"key-1": []byte("value-01"), "key-2": []byte("value-02")}, c.GetMulti(ctx, []string{"key-1", "key-2", "key-3"}))
+
+	// Items bigger than the max item size are not stored.
+	c.SetAsync("too-big", []byte(strings.Repeat("x", 20)), time.Hour)
+	waitDiskCacheWrites(c)
+	assert.Empty(t, c.GetMulti(ctx, []string{"too-big"}))
+
+	// Expired items are not returned.
+	c.SetAsync("expired", []byte("value-00"), time.Millisecond)
+	waitDiskCacheWrites(c)
+	time.Sleep(10 * time.Millisecond)
+	assert.Empty(t, c.GetMulti(ctx, []string{"expired"}))
+
+	// The least recently used item is evicted once the cache is full.
+	c.GetMulti(ctx, []string{"key-1"})
+	c.SetMultiAsync(map[string][]byte{"key-3": []byte("value-03")}, time.Hour)
+	waitDiskCacheWrites(c)
+	c.SetMultiAsync(map[string][]byte{"key-4": []byte("value-04")}, time.Hour)
+	waitDiskCacheWrites(c)
+	assert.Equal(t, map[string][]byte{"key-1": []byte("value-01"), "key-3": []byte("value-03"), "key-4": []byte("value-04")}, c.GetMulti(ctx, []string{"key-1", "key-2", "key-3", "key-4"}))
+	_, err := os.Stat(c.itemPath(diskCacheItemName("key-2")))
+	assert.True(t, os.IsNotExist(err))
+
+	require.NoError(t, c.Delete(ctx, "key-4"))
+	assert.Empty(t, c.GetMulti(ctx, []string{"key-4"}))
+
+	assert.NoError(t, promtest.GatherAndCompare(reg, strings.NewReader(`
+		# HELP thanos_store_index_cache_disk_items Current number of items in the disk index cache.
+		# TYPE thanos_store_index_cache_disk_items gauge
+		thanos_store_index_cache_disk_items 2
+		# HELP thanos_store_index_cache_disk_size_bytes Current disk space in bytes taken by the items in the disk index cache, accounted in filesystem blocks.
+		# TYPE thanos_store_index_cache_disk_size_bytes gauge
+		thanos_store_index_cache_disk_size_bytes 8192
+		# HELP thanos_store_index_cache_disk_items_evicted_total Total number of items that were evicted from the disk index cache.
+		# TYPE thanos_store_index_cache_disk_items_evicted_total counter
+		thanos_store_index_cache_disk_items_evicted_total 1
+	`), "thanos_store_index_cache_disk_items", "thanos_store_index_cache_disk_size_bytes", "thanos_store_index_cache_disk_items_evicted_total"))
+
+	// The items are loaded back when the cache is created again, and the leftovers of interrupted writes are removed.
+	tmpPath := filepath.Join(dir, diskCacheTmpDir, diskCacheItemName("key-5")+".123")
+	require.NoError(t, os.WriteFile(tmpPath, []byte("partial"), 0o644))
+
+	c.Stop()
+	c = newTestDiskCache(t, nil, cfg)
+	assert.Equal(t, map[string][]byte{"key-1": []byte("value-01"), "key-3": []byte("value-03")}, c.GetMulti(ctx, []string{"key-1", "key-2", "key-3", "key-4"}))
+	assert.Equal(t, uint64(2*diskCacheBlockSize), c.curSize)
+	_, err = os.Stat(tmpPath)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestDiskCache_ShouldEvictItemsExceedingMaxSizeOnLoad(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	c := newTestDiskCache(t, nil, DiskCacheConfig{Dir: dir, MaxSize: 3 * diskCacheBlockSize, MaxItemSize: 20})
+
+	now := time.Now()
+	for i, key := range []string{"key-1", "key-2", "key-3"} {
+		c.SetAsync(key, []byte(fmt.Sprintf("value-0%d", i+1)), time.Hour)
+		waitDiskCacheWrites(c)
+
+		// Ensure the items have distinct modification times.
+		modTime := now.Add(-time.Duration(3-i) * time.Minute)
+		require.NoError(t, os.Chtimes(c.itemPath(diskCacheItemName(key)), modTime, modTime))
+	}
+
+	// With a smaller max size, only the most recently written item fits.
+	c.Stop()
+	c = newTestDiskCache(t, nil, DiskCacheConfig{Dir: dir, MaxSize: diskCacheBlockSize, MaxItemSize: 20})
+	assert.Equal(t, map[string][]byte{"key-3": []byte("value-03")}, c.GetMulti(ctx, []string{"key-1", "key-2", "key-3"}))
+}
+
+func TestDiskCache_ShouldKeepItemsWrittenWhileLoading(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	// Write the items found on disk at startup.
+	prev := newTestDiskCache(t, nil, DiskCacheConfig{Dir: t.TempDir(), MaxSize: 2 * diskCacheBlockSize, MaxItemSize: 20})
+	prev.SetMultiAsync(map[string][]byte{"key-1": []byte("value-01"), "key-2": []byte("value-02")}, time.Hour)
+	waitDiskCacheWrites(prev)
+
+	c := newTestDiskCache(t, nil, DiskCacheConfig{Dir: dir, MaxSize: 2 * diskCacheBlockSize, MaxItemSize: 20})
+	c.SetAsync("key-3", []byte("value-03"), time.Hour)
+	waitDiskCacheWrites(c)
+
+	// Simulate the items being found on disk while the item written in the meantime is already cached.
+	modTime := time.Now().Add(-time.Hour)
+	for i, key := range []string{"key-1", "key-2"} {
+		data, err := os.ReadFile(prev.itemPath(diskCacheItemName(key)))
+		require.NoError(t, err)
+		require.NoError(t, os.MkdirAll(filepath.Dir(c.itemPath(diskCacheItemName(key))), os.ModePerm))
+		require.NoError(t, os.WriteFile(c.itemPath(diskCacheItemName(key)), data, 0o644))
+
+		itemModTime := modTime.Add(time.Duration(i) * time.Minute)
+		require.NoError(t, os.Chtimes(c.itemPath(diskCacheItemName(key)), itemModTime, itemModTime))
+	}
+	require.NoError(t, c.load(ctx))
+
+	// The loaded items are less recent than the one written in the meantime, so the oldest loaded one is evicted.
+	assert.Equal(t, map[string][]byte{"key-2": []byte("value-02"), "key-3": []byte("value-03")}, c.GetMulti(ctx, []string{"key-1", "key-2", "key-3"}))
+	assert.Equal(t, uint64(2*diskCacheBlockSize), c.curSize)
+}
+
+func TestDiskCache_ShouldAccountItemsInFilesystemBlocks(t *testing.T) {
+	ctx := context.Background()
+	c := newTestDiskCache(t, nil, DiskCacheConfig{Dir: t.TempDir(), MaxSize: 4 * diskCacheBlockSize, MaxItemSize: 2 * diskCacheBlockSize})
+
+	// A small item takes a whole block, while a bigger one takes as many blocks as needed.
+	c.SetMultiAsync(map[string][]byte{"small": []byte("value"), "big": []byte(strings.Repeat("x", diskCacheBlockSize))}, time.Hour)
+	waitDiskCacheWrites(c)
+	assert.Equal(t, uint64(3*diskCacheBlockSize), c.curSize)
+
+	// The next small item doesn't fit in the remaining space, so the least recently used one is evicted.
+	c.GetMulti(ctx, []string{"big"})
+	c.SetAsync("other", []byte("value"), time.Hour)
+	waitDiskCacheWrites(c)
+	c.SetAsync("another", []byte("value"), time.Hour)
+	waitDiskCacheWrites(c)
+	assert.Equal(t, uint64(4*diskCacheBlockSize), c.curSize)
+	assert.Len(t, c.GetMulti(ctx, []string{"small", "big", "other", "another"}), 3)
+}
+
+func TestDiskCache_GetMultiShouldReadItemsConcurrently(t *testing.T) {
+	ctx := context.Background()
+	c := newTestDiskCache(t, nil, DiskCacheConfig{Dir: t.TempDir(), MaxSize: 1000 * diskCacheBlockSize, MaxItemSize: 20})
+
+	expected := map[string][]byte{}
+	keys := make([]string, 0, 101)
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprintf("key-%d", i)
+		expected[key] = []byte(fmt.Sprintf("value-%03d", i))
+		keys = append(keys, key)
+
+		c.SetAsync(key, expected[key], time.Hour)
+		waitDiskCacheWrites(c)
+	}
+	keys = append(keys, "missing")
+
+	assert.Equal(t, expected, c.GetMulti(ctx, keys))
+}
+
+func TestDiskCache_RemoteIndexCache(t *testing.T) {
+	ctx := context.Background()
+	reg := prometheus.NewPedanticRegistry()
+
+	client := newTestDiskCache(t, reg, DiskCacheConfig{Dir: t.TempDir(), MaxSize: 4 * diskCacheBlockSize, MaxItemSize: 1024})
+	c, err := NewRemoteIndexCache(log.NewNopLogger(), client, reg)
+	require.NoError(t, err)
+
+	blockID := ulid.MustNew(1, nil)
+	c.StorePostings("user-1", blockID, labels.Label{Name: "foo", Value: "bar"}, []byte("postings"), time.Hour)
+	waitDiskCacheWrites(client)
+
+	res := c.FetchMultiPostings(ctx, "user-1", blockID, []labels.Label{{Name: "foo", Value: "bar"}, {Name: "foo", Value: "baz"}})
+	hit, ok := res.Next()
+	require.True(t, ok)
+	assert.Equal(t, []byte("postings"), hit)
+	miss, ok := res.Next()
+	require.True(t, ok)
+	assert.Nil(t, miss)
+
+	assert.Equal(t, float64(2), promtest.ToFloat64(c.requests.WithLabelValues(cacheTypePostings)))
+	assert.Equal(t, float64(1), promtest.ToFloat64(c.hits.WithLabelValues(cacheTypePostings)))
+}
+
+// newTestDiskCache creates a DiskCache and waits until the items found on disk have been loaded.
+func newTestDiskCache(t *testing.T, reg prometheus.Registerer, cfg DiskCacheConfig) *DiskCache {
+	c, err := NewDiskCache(log.NewNopLogger(), reg, cfg)
+	require.NoError(t, err)
+	t.Cleanup(c.Stop)
+
+	<-c.loaded
+	return c
+}
+
+// waitDiskCacheWrites waits until the in-flight writes of the disk cache have completed.
+func waitDiskCacheWrites(c *DiskCache) {
+	for i := 0; i < cap(c.writes); i++ {
+		c.writes <- struct{}{}
+	}
+	for i := 0; i < cap(c.writes); i++ {
+		<-c.writes
+	}
+}
@@ -35,7 +35,7 @@ var (
 	}}
 )
 
-// RemoteIndexCache is a memcached or redis based index cache.
+// RemoteIndexCache is an index cache based on a cache.Cache, like memcached, redis or the disk cache.
 type RemoteIndexCache struct {
 	logger log.Logger
 	remote cache.Cache
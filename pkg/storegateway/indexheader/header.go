@@ -26,6 +26,7 @@ var NotFoundRangeErr = errors.New("range not found") //nolint:revive
 
 var (
 	errInvalidIndexHeaderLazyLoadingConcurrency = errors.New("invalid index-header lazy loading max concurrency; must be non-negative")
+	errInvalidIndexHeaderScrubInterval          = errors.New("invalid index-header scrub interval; must be non-negative")
 )
 
 // Reader is an interface allowing to read essential, minimal number of index fields from the small portion of index file called header.
@@ -77,6 +78,8 @@ type Config struct {
 
 	VerifyOnLoad bool `yaml:"verify_on_load" category:"advanced"`
 
+	ScrubInterval time.Duration `yaml:"scrub_interval" category:"experimental"`
+
 	// EagerLoadingPersistInterval is injected for testing purposes only.
 	EagerLoadingPersistInterval time.Duration `yaml:"-" doc:"hidden"`
 }
@@ -90,11 +93,15 @@ func (cfg *Config) RegisterFlagsWithPrefix(f *flag.FlagSet, prefix string) {
 	f.BoolVar(&cfg.EagerLoadingStartupEnabled, prefix+"eager-loading-startup-enabled", true, "If enabled, store-gateway will periodically persist block IDs of lazy loaded index-headers and load them eagerly during startup. Ignored if index-header lazy loading is disabled.")
 	f.DurationVar(&cfg.EagerLoadingPersistInterval, prefix+"eager-loading-persist-interval", time.Minute, "Interval at which the store-gateway persists block IDs of lazy loaded index-headers. Ignored if index-header eager loading is disabled.")
 	f.BoolVar(&cfg.VerifyOnLoad, prefix+"verify-on-load", false, "If true, verify the checksum of index headers upon loading them (either on startup or lazily when lazy loading is enabled). Setting to true helps detect disk corruption at the cost of slowing down index header loading.")
+	f.DurationVar(&cfg.ScrubInterval, prefix+"scrub-interval", 0, "How frequently the store-gateway verifies the checksums of the index-headers stored on the local disk, in the background. Blocks whose index-header is corrupted are reloaded and their index-header is rebuilt from the object storage. The chunks of a sample of the series of each block are verified too. 0 to disable.")
 }
 
 func (cfg *Config) Validate() error {
 	if cfg.LazyLoadingConcurrency < 0 {
 		return errInvalidIndexHeaderLazyLoadingConcurrency
 	}
+	if cfg.ScrubInterval < 0 {
+		return errInvalidIndexHeaderScrubInterval
+	}
 	return nil
 }
@@ -85,6 +85,10 @@ type LazyBinaryReader struct {
 	// Keep track of the last time it was used.
 	usedAt *atomic.Int64
 
+	// pinnedUntil is the unix nano timestamp until which the reader is kept loaded regardless of
+	// the idle timeout, or 0 if the reader has never been pinned.
+	pinnedUntil *atomic.Int64
+
 	readerFactory func() (Reader, error)
 
 	blockID ulid.ULID
@@ -153,6 +157,7 @@ func NewLazyBinaryReader(
 		filepath:        path,
 		metrics:         metrics,
 		usedAt:          atomic.NewInt64(0),
+		pinnedUntil:     atomic.NewInt64(0),
 		onClosed:        onClosed,
 		readerFactory:   readerFactory,
 		blockID:         id,
@@ -363,8 +368,8 @@ func (r *LazyBinaryReader) controlLoop() {
 				continue
 			}
 
-			// Do not unloadIfIdleSince if not idle.
-			if ts := unloadPromise.idleSinceNanos; ts > 0 && r.usedAt.Load() > ts {
+			// Do not unloadIfIdleSince if not idle or pinned.
+			if ts := unloadPromise.idleSinceNanos; ts > 0 && (r.usedAt.Load() > ts || r.isPinnedAt(time.Now())) {
 				unloadPromise.response <- errNotIdle
 				continue
 			}
@@ -406,9 +411,40 @@ func waitReadersOrPanic(wg *sync.WaitGroup) {
 }
 
 // IsIdleSince returns true if the reader is idle since given time (as unix nano).
+// A pinned reader is never idle.
 func (r *LazyBinaryReader) IsIdleSince(ts int64) bool {
 	lastUse := r.LoadedLastUse()
-	return lastUse != 0 && lastUse <= ts
+	return lastUse != 0 && lastUse <= ts && !r.isPinnedAt(time.Now())
+}
+
+// Pin loads the index-header, if not loaded yet, and keeps it loaded until the given time regardless
+// of the idle timeout. Pinning an already pinned reader can extend the pin, but never shorten it.
+func (r *LazyBinaryReader) Pin(ctx context.Context, until time.Time) error {
+	loaded := r.getOrLoadReader(ctx)
+	if loaded.err != nil {
+		return loaded.err
+	}
+	loaded.inUse.Done()
+
+	untilNanos := until.UnixNano()
+	for {
+		current := r.pinnedUntil.Load()
+		if current >= untilNanos || r.pinnedUntil.CompareAndSwap(current, untilNanos) {
+			return nil
+		}
+	}
+}
+
+// PinnedUntil returns the time until which the reader is pinned, or the zero time if the reader has never been pinned.
+func (r *LazyBinaryReader) PinnedUntil() time.Time {
+	if ts := r.pinnedUntil.Load(); ts > 0 {
+		return time.Unix(0, ts)
+	}
+	return time.Time{}
+}
+
+func (r *LazyBinaryReader) isPinnedAt(t time.Time) bool {
+	return r.pinnedUntil.Load() > t.UnixNano()
 }
 
 // LoadedLastUse returns 0 if the reader is not loaded.
@@ -127,6 +127,37 @@ func TestLazyBinaryReader_unload_ShouldReturnErrorIfNotIdle(t *testing.T) {
 	})
 }
 
+func TestLazyBinaryReader_Pin(t *testing.T) {
+	tmpDir, bkt, blockID := initBucketAndBlocksForTest(t)
+
+	testLazyBinaryReader(t, bkt, tmpDir, blockID, func(t *testing.T, r *LazyBinaryReader, err error) {
+		require.NoError(t, err)
+		require.True(t, r.PinnedUntil().IsZero())
+
+		// Pinning should load the index-header.
+		pinUntil := time.Now().Add(time.Hour)
+		require.NoError(t, r.Pin(context.Background(), pinUntil))
+		require.Equal(t, float64(1), promtestutil.ToFloat64(r.metrics.loadCount))
+		require.Equal(t, pinUntil.UnixNano(), r.PinnedUntil().UnixNano())
+
+		// Pinning again with an earlier time shouldn't shorten the pin.
+		require.NoError(t, r.Pin(context.Background(), time.Now().Add(time.Minute)))
+		require.Equal(t, pinUntil.UnixNano(), r.PinnedUntil().UnixNano())
+		require.Equal(t, float64(1), promtestutil.ToFloat64(r.metrics.loadCount))
+
+		// A pinned reader is never idle, so it's not unloaded.
+		require.False(t, r.IsIdleSince(time.Now().UnixNano()))
+		require.Equal(t, errNotIdle, r.unloadIfIdleSince(time.Now().UnixNano()))
+		require.Equal(t, float64(0), promtestutil.ToFloat64(r.metrics.unloadCount))
+
+		// Once the pin has expired, the reader can be unloaded.
+		r.pinnedUntil.Store(time.Now().Add(-time.Second).UnixNano())
+		require.True(t, r.IsIdleSince(time.Now().UnixNano()))
+		require.NoError(t, r.unloadIfIdleSince(time.Now().UnixNano()))
+		require.Equal(t, float64(1), promtestutil.ToFloat64(r.metrics.unloadCount))
+	})
+}
+
 func TestLazyBinaryReader_LoadUnloadRaceCondition(t *testing.T) {
 	t.Parallel()
 	// Run the test for a fixed amount of time.
@@ -0,0 +1,100 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package indexheader
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/grafana/dskit/runutil"
+	"github.com/oklog/ulid"
+
+	"github.com/grafana/mimir/pkg/storage/tsdb/block"
+	streamencoding "github.com/grafana/mimir/pkg/storegateway/indexheader/encoding"
+	"github.com/grafana/mimir/pkg/storegateway/indexheader/indexheaderpb"
+)
+
+// VerifyIndexHeader verifies the integrity of the index-header of the block id stored on disk in dir.
+// The table of contents, the symbols and the postings offset table of the index-header are verified
+// against their CRC32 checksums, and the sparse index-header, if any, is decoded. The returned error
+// wraps os.ErrNotExist if the index-header hasn't been built on disk yet.
+func VerifyIndexHeader(dir string, id ulid.ULID, metrics *ReaderPoolMetrics) (err error) {
+	binPath := filepath.Join(dir, id.String(), block.IndexHeaderFilename)
+	if _, err := os.Stat(binPath); err != nil {
+		return err
+	}
+
+	factory := streamencoding.NewDecbufFactory(binPath, 0, metrics.streamReader.decbufFactory)
+	defer factory.Stop()
+
+	d := factory.NewRawDecbuf()
+	defer runutil.CloseWithErrCapture(&err, &d, "verify index-header")
+	if err = d.Err(); err != nil {
+		return fmt.Errorf("cannot create decoding buffer: %w", err)
+	}
+
+	indexHeaderSize := d.Len()
+	if magic := d.Be32(); magic != MagicIndex {
+		return fmt.Errorf("invalid magic number %x", magic)
+	}
+	if version := int(d.Byte()); version != BinaryFormatV1 {
+		return fmt.Errorf("unknown index-header file version %d", version)
+	}
+
+	toc, err := newBinaryTOCFromFile(d, indexHeaderSize)
+	if err != nil {
+		return fmt.Errorf("cannot read table-of-contents: %w", err)
+	}
+
+	for _, section := range []struct {
+		name   string
+		offset uint64
+	}{
+		{name: "symbols", offset: toc.Symbols},
+		{name: "postings offset table", offset: toc.PostingsOffsetTable},
+	} {
+		sd := factory.NewDecbufAtChecked(int(section.offset), castagnoliTable)
+		sectionErr := sd.Err()
+		if closeErr := sd.Close(); sectionErr == nil {
+			sectionErr = closeErr
+		}
+		if sectionErr != nil {
+			return fmt.Errorf("cannot verify %s: %w", section.name, sectionErr)
+		}
+	}
+
+	sparseData, err := os.ReadFile(filepath.Join(dir, id.String(), block.SparseIndexHeaderFilename))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("cannot read sparse index-header: %w", err)
+	}
+
+	return verifySparseIndexHeader(sparseData)
+}
+
+func verifySparseIndexHeader(sparseData []byte) error {
+	gzipReader, err := gzip.NewReader(bytes.NewReader(sparseData))
+	if err != nil {
+		return fmt.Errorf("failed to create sparse index-header reader: %w", err)
+	}
+
+	// The gzip reader verifies the checksum of the decompressed data once it reaches the end of the stream.
+	decompressed, err := io.ReadAll(gzipReader)
+	if err != nil {
+		return fmt.Errorf("failed to read sparse index-header: %w", err)
+	}
+	if err := gzipReader.Close(); err != nil {
+		return fmt.Errorf("failed to close sparse index-header reader: %w", err)
+	}
+
+	if err := (&indexheaderpb.Sparse{}).Unmarshal(decompressed); err != nil {
+		return fmt.Errorf("failed to decode sparse index-header file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package indexheader
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/oklog/ulid"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/objstore/providers/filesystem"
+
+	"github.com/grafana/mimir/pkg/storage/tsdb/block"
+)
+
+func TestVerifyIndexHeader(t *testing.T) {
+	ctx := context.Background()
+
+	prepare := func(t *testing.T) (string, ulid.ULID) {
+		tmpDir := t.TempDir()
+		bkt, err := filesystem.NewBucket(filepath.Join(tmpDir, "bkt"))
+		require.NoError(t, err)
+		t.Cleanup(func() { require.NoError(t, bkt.Close()) })
+
+		blockID, err := block.CreateBlock(ctx, tmpDir, []labels.Labels{
+			labels.FromStrings("a", "1"),
+			labels.FromStrings("a", "2"),
+			labels.FromStrings("a", "3"),
+		}, 100, 0, 1000, labels.FromStrings("ext1", "1"))
+		require.NoError(t, err)
+		require.NoError(t, block.Upload(ctx, log.NewNopLogger(), bkt, filepath.Join(tmpDir, blockID.String()), nil))
+
+		headersDir := filepath.Join(tmpDir, "headers")
+		r, err := NewStreamBinaryReader(ctx, log.NewNopLogger(), bkt, headersDir, blockID, 3, NewStreamBinaryReaderMetrics(nil), Config{})
+		require.NoError(t, err)
+		require.NoError(t, r.Close())
+
+		return headersDir, blockID
+	}
+
+	t.Run("should succeed on valid index-header", func(t *testing.T) {
+		dir, blockID := prepare(t)
+		require.NoError(t, VerifyIndexHeader(dir, blockID, NewReaderPoolMetrics(nil)))
+	})
+
+	t.Run("should return not exist error if the index-header has not been built", func(t *testing.T) {
+		dir, _ := prepare(t)
+		err := VerifyIndexHeader(dir, ulid.MustNew(1, nil), NewReaderPoolMetrics(nil))
+		require.ErrorIs(t, err, os.ErrNotExist)
+	})
+
+	t.Run("should fail on corrupted symbols", func(t *testing.T) {
+		dir, blockID := prepare(t)
+		binPath := filepath.Join(dir, blockID.String(), block.IndexHeaderFilename)
+
+		data, err := os.ReadFile(binPath)
+		require.NoError(t, err)
+		// The symbols table starts right after the index-header preamble, and begins with its length.
+		data[headerLen+4] ^= 0xff
+		require.NoError(t, os.WriteFile(binPath, data, 0644))
+
+		err = VerifyIndexHeader(dir, blockID, NewReaderPoolMetrics(nil))
+		require.ErrorContains(t, err, "cannot verify symbols")
+	})
+
+	t.Run("should fail on corrupted sparse index-header", func(t *testing.T) {
+		dir, blockID := prepare(t)
+		sparsePath := filepath.Join(dir, blockID.String(), block.SparseIndexHeaderFilename)
+
+		data, err := os.ReadFile(sparsePath)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(sparsePath, data[:len(data)/2], 0644))
+
+		err = VerifyIndexHeader(dir, blockID, NewReaderPoolMetrics(nil))
+		require.ErrorContains(t, err, "sparse index-header")
+	})
+}
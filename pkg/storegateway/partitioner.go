@@ -62,12 +62,46 @@ func newGapBasedPartitioner(maxGapBytes uint64, reg prometheus.Registerer) *gapB
 	}
 }
 
+// withTenantChunksRanges returns the partitioners to use for a tenant, whose chunks partitioner uses the
+// per-tenant max gap and prefetch window returned by the given functions. A max gap of 0 means the
+// max gap of the shared partitioner is used.
+func (p blockPartitioners) withTenantChunksRanges(maxGapBytes, prefetchBytes func() uint64) blockPartitioners {
+	if chunks, ok := p.chunks.(*gapBasedPartitioner); ok {
+		p.chunks = &tenantChunksPartitioner{
+			shared:              chunks,
+			tenantMaxGapBytes:   maxGapBytes,
+			tenantPrefetchBytes: prefetchBytes,
+		}
+	}
+	return p
+}
+
+// tenantChunksPartitioner is a gap-based partitioner of chunks byte ranges using the per-tenant settings,
+// and tracking the metrics of the partitioner shared across all tenants.
+type tenantChunksPartitioner struct {
+	shared              *gapBasedPartitioner
+	tenantMaxGapBytes   func() uint64
+	tenantPrefetchBytes func() uint64
+}
+
+func (t *tenantChunksPartitioner) Partition(length int, rng func(int) (uint64, uint64)) []Part {
+	maxGapBytes := t.tenantMaxGapBytes()
+	if maxGapBytes == 0 {
+		maxGapBytes = t.shared.maxGapBytes
+	}
+	return t.shared.partitionAndTrack(length, rng, maxGapBytes, t.tenantPrefetchBytes())
+}
+
 // Partition partitions length entries into n <= length ranges that cover all
 // input ranges by combining entries that are separated by reasonably small gaps.
 // It is used to combine multiple small ranges from object storage into bigger, more efficient/cheaper ones.
 func (g *gapBasedPartitioner) Partition(length int, rng func(int) (uint64, uint64)) []Part {
+	return g.partitionAndTrack(length, rng, g.maxGapBytes, 0)
+}
+
+func (g *gapBasedPartitioner) partitionAndTrack(length int, rng func(int) (uint64, uint64), maxGapBytes, prefetchBytes uint64) []Part {
 	// Run the upstream partitioner to compute the actual ranges that will be fetched.
-	parts, stats := g.partition(length, rng)
+	parts, stats := partition(length, rng, maxGapBytes, prefetchBytes)
 
 	// Calculate the size of ranges that will be fetched.
 	expandedBytes := uint64(0)
@@ -89,7 +123,12 @@ type partitionStats struct {
 	requestedBytesTotal          uint64
 }
 
-func (g *gapBasedPartitioner) partition(length int, rng func(int) (uint64, uint64)) (parts []Part, stats partitionStats) {
+// partition combines the ranges separated by gaps up to maxGapBytes, and extends the end of each resulting
+// range by prefetchBytes. Ranges starting within the prefetch window of the previous range are combined too,
+// because their bytes are read anyway.
+func partition(length int, rng func(int) (uint64, uint64), maxGapBytes, prefetchBytes uint64) (parts []Part, stats partitionStats) {
+	maxGapBytes = max(maxGapBytes, prefetchBytes)
+
 	j := 0
 	k := 0
 	for k < length {
@@ -111,7 +150,7 @@ func (g *gapBasedPartitioner) partition(length int, rng func(int) (uint64, uint6
 					// then we count the extra bytes between the current range's end and the next one's end.
 					stats.requestedBytesTotal += e - p.End
 				}
-			} else if p.End+g.maxGapBytes >= s {
+			} else if p.End+maxGapBytes >= s {
 				// We can afford to fill a gap between the current range's end and the next range's start.
 				// We do so, but we also keep track of how much of it we do.
 				stats.extendedNonOverlappingRanges++
@@ -125,6 +164,7 @@ func (g *gapBasedPartitioner) partition(length int, rng func(int) (uint64, uint6
 				p.End = e
 			}
 		}
+		p.End += prefetchBytes
 		p.ElemRng = [2]int{j, k}
 		parts = append(parts, p)
 	}
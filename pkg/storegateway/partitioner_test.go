@@ -149,3 +149,50 @@ func TestGapBasedPartitioner_Partition(t *testing.T) {
 		assert.Equal(t, c.expected, res)
 	}
 }
+
+func TestBlockPartitioners_WithTenantChunksRanges(t *testing.T) {
+	reg := prometheus.NewPedanticRegistry()
+	shared := newGapBasedPartitioners(10, reg)
+
+	var maxGapBytes, prefetchBytes uint64
+	p := shared.withTenantChunksRanges(func() uint64 { return maxGapBytes }, func() uint64 { return prefetchBytes })
+	assert.Same(t, shared.series, p.series)
+	assert.Same(t, shared.postings, p.postings)
+
+	input := [][2]uint64{{0, 10}, {15, 20}, {40, 50}, {75, 80}}
+	partition := func() []Part {
+		return p.chunks.Partition(len(input), func(i int) (uint64, uint64) {
+			return input[i][0], input[i][1]
+		})
+	}
+
+	// The max gap of the shared partitioner is used by default.
+	assert.Equal(t, []Part{
+		{Start: 0, End: 20, ElemRng: [2]int{0, 2}},
+		{Start: 40, End: 50, ElemRng: [2]int{2, 3}},
+		{Start: 75, End: 80, ElemRng: [2]int{3, 4}},
+	}, partition())
+
+	// The per-tenant max gap overrides the shared one.
+	maxGapBytes = 20
+	assert.Equal(t, []Part{
+		{Start: 0, End: 50, ElemRng: [2]int{0, 3}},
+		{Start: 75, End: 80, ElemRng: [2]int{3, 4}},
+	}, partition())
+
+	// The prefetch window extends each range, and the ranges starting within it are combined.
+	maxGapBytes, prefetchBytes = 0, 30
+	assert.Equal(t, []Part{
+		{Start: 0, End: 110, ElemRng: [2]int{0, 4}},
+	}, partition())
+
+	maxGapBytes, prefetchBytes = 0, 5
+	assert.Equal(t, []Part{
+		{Start: 0, End: 25, ElemRng: [2]int{0, 2}},
+		{Start: 40, End: 55, ElemRng: [2]int{2, 3}},
+		{Start: 75, End: 85, ElemRng: [2]int{3, 4}},
+	}, partition())
+
+	// The metrics of the shared partitioner are tracked.
+	assert.Equal(t, float64(4*len(input)), testutil.ToFloat64(shared.chunks.(*gapBasedPartitioner).requestedRanges))
+}
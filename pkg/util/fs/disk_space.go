@@ -0,0 +1,21 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+//go:build !windows
+
+package fs
+
+import (
+	"golang.org/x/sys/unix"
+)
+
+// DiskSpace returns the space, in bytes, available to the process and the total capacity of the filesystem
+// holding the dir.
+func DiskSpace(dir string) (available, capacity uint64, err error) {
+	var stat unix.Statfs_t
+	if err := unix.Statfs(dir, &stat); err != nil {
+		return 0, 0, err
+	}
+
+	//nolint:unconvert // The types of the fields differ between operating systems.
+	return uint64(stat.Bavail) * uint64(stat.Bsize), uint64(stat.Blocks) * uint64(stat.Bsize), nil
+}
@@ -0,0 +1,23 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+//go:build windows
+
+package fs
+
+import (
+	"golang.org/x/sys/windows"
+)
+
+// DiskSpace returns the space, in bytes, available to the process and the total capacity of the filesystem
+// holding the dir.
+func DiskSpace(dir string) (available, capacity uint64, err error) {
+	path, err := windows.UTF16PtrFromString(dir)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if err := windows.GetDiskFreeSpaceEx(path, &available, &capacity, nil); err != nil {
+		return 0, 0, err
+	}
+	return available, capacity, nil
+}
@@ -30,12 +30,14 @@ const (
 	MaxSeriesPerMetric                    ID = "max-series-per-metric"
 	MaxMetadataPerMetric                  ID = "max-metadata-per-metric"
 	MaxSeriesPerUser                      ID = "max-series-per-user"
+	MaxNewSeriesPerMinute                 ID = "max-new-series-per-minute"
 	MaxMetadataPerUser                    ID = "max-metadata-per-user"
 	MaxChunksPerQuery                     ID = "max-chunks-per-query"
 	MaxSeriesPerQuery                     ID = "max-series-per-query"
 	MaxChunkBytesPerQuery                 ID = "max-chunks-bytes-per-query"
 	MaxEstimatedChunksPerQuery            ID = "max-estimated-chunks-per-query"
 	MaxEstimatedMemoryConsumptionPerQuery ID = "max-estimated-memory-consumption-per-query"
+	MaxLabelValuesPerRequest              ID = "max-label-values-per-request"
 
 	DistributorMaxIngestionRate             ID = "distributor-max-ingestion-rate"
 	DistributorMaxInflightPushRequests      ID = "distributor-max-inflight-push-requests"
@@ -46,6 +48,7 @@ const (
 	IngesterMaxInMemorySeries            ID = "ingester-max-series"
 	IngesterMaxInflightPushRequests      ID = "ingester-max-inflight-push-requests"
 	IngesterMaxInflightPushRequestsBytes ID = "ingester-max-inflight-push-requests-bytes"
+	IngesterDiskSpaceReadOnly            ID = "ingester-disk-space-read-only"
 
 	ExemplarLabelsMissing    ID = "exemplar-labels-missing"
 	ExemplarLabelsTooLong    ID = "exemplar-labels-too-long"
@@ -56,6 +59,8 @@ const (
 	MetricMetadataHelpTooLong       ID = "help-too-long" // unused, left here to prevent reuse for different purpose
 	MetricMetadataUnitTooLong       ID = "unit-too-long"
 
+	MetricRegistryViolation ID = "metric-registry-violation"
+
 	MaxQueryLength              ID = "max-query-length"
 	MaxTotalQueryLength         ID = "max-total-query-length"
 	MaxQueryExpressionSizeBytes ID = "max-query-expression-size-bytes"
@@ -63,6 +68,8 @@ const (
 	IngestionRateLimited        ID = "tenant-max-ingestion-rate"
 	TooManyHAClusters           ID = "tenant-too-many-ha-clusters"
 	QueryBlocked                ID = "query-blocked"
+	QueryBytesBudgetExhausted   ID = "query-bytes-budget-exhausted"
+	QueryExperimentalFunction   ID = "query-experimental-function"
 
 	SampleTimestampTooOld    ID = "sample-timestamp-too-old"
 	SampleOutOfOrder         ID = "sample-out-of-order"
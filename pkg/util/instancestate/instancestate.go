@@ -0,0 +1,162 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+// Package instancestate provides a memberlist.Mergeable holding the latest state advertised by each instance
+// of a component, and the codec to store it in the KV store.
+package instancestate
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/grafana/dskit/kv/codec"
+	"github.com/grafana/dskit/kv/memberlist"
+)
+
+// EntryTimeout is how long the state advertised by an instance is kept in the KV store after its last update.
+// Instances which stopped advertising their state, for example because they left the ring, are removed after
+// this timeout.
+const EntryTimeout = 5 * time.Minute
+
+// State is the state advertised by an instance.
+type State[T any] interface {
+	// GetTimestamp returns the unix timestamp, in milliseconds, of the last update of the state.
+	GetTimestamp() int64
+
+	// Clone returns a deep copy of the state.
+	Clone() T
+}
+
+// Desc holds the latest state advertised by each instance, keyed by instance ID.
+type Desc[T State[T]] struct {
+	Instances map[string]T `json:"instances"`
+}
+
+// NewDesc returns an empty Desc.
+func NewDesc[T State[T]]() *Desc[T] {
+	return &Desc[T]{Instances: map[string]T{}}
+}
+
+func isExpired[T State[T]](entry T, now time.Time) bool {
+	return now.Sub(time.UnixMilli(entry.GetTimestamp())) > EntryTimeout
+}
+
+// Set updates the state advertised by the instance, and removes the expired states of the other instances.
+func (d *Desc[T]) Set(instanceID string, state T, now time.Time) {
+	if d.Instances == nil {
+		d.Instances = map[string]T{}
+	}
+
+	for id, entry := range d.Instances {
+		if isExpired(entry, now) {
+			delete(d.Instances, id)
+		}
+	}
+	d.Instances[instanceID] = state
+}
+
+// Merge implements memberlist.Mergeable. For each instance, the most recently updated state wins.
+func (d *Desc[T]) Merge(mergeable memberlist.Mergeable, localCAS bool) (memberlist.Mergeable, error) {
+	if mergeable == nil {
+		return nil, nil
+	}
+
+	other, ok := mergeable.(*Desc[T])
+	if !ok {
+		return nil, fmt.Errorf("expected %T, got %T", d, mergeable)
+	}
+	if other == nil {
+		return nil, nil
+	}
+
+	if d.Instances == nil {
+		d.Instances = map[string]T{}
+	}
+
+	now := time.Now()
+	change := NewDesc[T]()
+	for id, entry := range other.Instances {
+		// Do not resurrect expired entries.
+		if isExpired(entry, now) {
+			continue
+		}
+
+		if current, ok := d.Instances[id]; !ok || entry.GetTimestamp() > current.GetTimestamp() {
+			d.Instances[id] = entry
+			change.Instances[id] = entry
+		}
+	}
+
+	if localCAS {
+		// Entries removed by the local CAS operation are expired, so they can be dropped.
+		for id, entry := range d.Instances {
+			if _, ok := other.Instances[id]; !ok && isExpired(entry, now) {
+				delete(d.Instances, id)
+			}
+		}
+	}
+
+	if len(change.Instances) == 0 {
+		return nil, nil
+	}
+	return change, nil
+}
+
+// MergeContent implements memberlist.Mergeable.
+func (d *Desc[T]) MergeContent() []string {
+	res := make([]string, 0, len(d.Instances))
+	for id := range d.Instances {
+		res = append(res, id)
+	}
+	return res
+}
+
+// RemoveTombstones implements memberlist.Mergeable. Expired entries are removed by Merge, so there
+// are no tombstones.
+func (d *Desc[T]) RemoveTombstones(time.Time) (total, removed int) {
+	return 0, 0
+}
+
+// Clone implements memberlist.Mergeable.
+func (d *Desc[T]) Clone() memberlist.Mergeable {
+	clone := NewDesc[T]()
+	for id, entry := range d.Instances {
+		clone.Instances[id] = entry.Clone()
+	}
+	return clone
+}
+
+type descCodec[T State[T]] struct {
+	id string
+}
+
+// NewCodec returns the codec, with the given ID, used to store a Desc in the KV store.
+func NewCodec[T State[T]](id string) codec.Codec {
+	return descCodec[T]{id: id}
+}
+
+func (c descCodec[T]) CodecID() string {
+	return c.id
+}
+
+func (descCodec[T]) Decode(data []byte) (interface{}, error) {
+	data, err := snappy.Decode(nil, data)
+	if err != nil {
+		return nil, err
+	}
+
+	desc := NewDesc[T]()
+	if err := json.Unmarshal(data, desc); err != nil {
+		return nil, err
+	}
+	return desc, nil
+}
+
+func (descCodec[T]) Encode(v interface{}) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return snappy.Encode(nil, data), nil
+}
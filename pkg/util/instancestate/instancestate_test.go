@@ -0,0 +1,113 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package instancestate
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testState struct {
+	Timestamp int64            `json:"timestamp"`
+	Values    map[string]int64 `json:"values"`
+}
+
+func (s testState) GetTimestamp() int64 {
+	return s.Timestamp
+}
+
+func (s testState) Clone() testState {
+	values := make(map[string]int64, len(s.Values))
+	for k, v := range s.Values {
+		values[k] = v
+	}
+	return testState{Timestamp: s.Timestamp, Values: values}
+}
+
+func TestDesc_Merge(t *testing.T) {
+	now := time.Now()
+	expired := now.Add(-2 * EntryTimeout).UnixMilli()
+
+	local := &Desc[testState]{Instances: map[string]testState{
+		"instance-1": {Timestamp: now.UnixMilli(), Values: map[string]int64{"a": 10}},
+		"instance-2": {Timestamp: now.Add(-time.Second).UnixMilli(), Values: map[string]int64{"a": 20}},
+	}}
+
+	// Newer entries win, older and expired ones are ignored.
+	change, err := local.Merge(&Desc[testState]{Instances: map[string]testState{
+		"instance-1": {Timestamp: now.Add(-time.Second).UnixMilli(), Values: map[string]int64{"a": 1}},
+		"instance-2": {Timestamp: now.UnixMilli(), Values: map[string]int64{"a": 30}},
+		"instance-3": {Timestamp: expired, Values: map[string]int64{"a": 40}},
+	}}, false)
+	require.NoError(t, err)
+
+	assert.Equal(t, &Desc[testState]{Instances: map[string]testState{
+		"instance-2": {Timestamp: now.UnixMilli(), Values: map[string]int64{"a": 30}},
+	}}, change)
+	assert.Equal(t, &Desc[testState]{Instances: map[string]testState{
+		"instance-1": {Timestamp: now.UnixMilli(), Values: map[string]int64{"a": 10}},
+		"instance-2": {Timestamp: now.UnixMilli(), Values: map[string]int64{"a": 30}},
+	}}, local)
+
+	// Merging the same state again is a no-op.
+	change, err = local.Merge(local.Clone(), false)
+	require.NoError(t, err)
+	assert.Nil(t, change)
+
+	// Expired entries removed by a local CAS operation are dropped.
+	local.Instances["instance-3"] = testState{Timestamp: expired}
+	cas := local.Clone().(*Desc[testState])
+	delete(cas.Instances, "instance-3")
+
+	change, err = local.Merge(cas, true)
+	require.NoError(t, err)
+	assert.Nil(t, change)
+	assert.NotContains(t, local.Instances, "instance-3")
+}
+
+func TestDesc_Clone(t *testing.T) {
+	desc := &Desc[testState]{Instances: map[string]testState{
+		"instance-1": {Timestamp: 1000, Values: map[string]int64{"a": 10}},
+	}}
+
+	clone := desc.Clone().(*Desc[testState])
+	clone.Instances["instance-1"].Values["a"] = 20
+
+	assert.Equal(t, int64(10), desc.Instances["instance-1"].Values["a"])
+}
+
+func TestDesc_Set(t *testing.T) {
+	now := time.Now()
+
+	desc := &Desc[testState]{Instances: map[string]testState{
+		"instance-1": {Timestamp: now.Add(-time.Second).UnixMilli()},
+		"instance-2": {Timestamp: now.Add(-2 * EntryTimeout).UnixMilli()},
+		"instance-3": {Timestamp: now.Add(-time.Second).UnixMilli()},
+	}}
+	desc.Set("instance-1", testState{Timestamp: now.UnixMilli()}, now)
+
+	// The expired entries are removed.
+	assert.Equal(t, &Desc[testState]{Instances: map[string]testState{
+		"instance-1": {Timestamp: now.UnixMilli()},
+		"instance-3": {Timestamp: now.Add(-time.Second).UnixMilli()},
+	}}, desc)
+}
+
+func TestCodec(t *testing.T) {
+	desc := &Desc[testState]{Instances: map[string]testState{
+		"instance-1": {Timestamp: 1000, Values: map[string]int64{"a": 10}},
+	}}
+
+	c := NewCodec[testState]("testDesc")
+	assert.Equal(t, "testDesc", c.CodecID())
+
+	data, err := c.Encode(desc)
+	require.NoError(t, err)
+
+	decoded, err := c.Decode(data)
+	require.NoError(t, err)
+	assert.Equal(t, desc, decoded)
+}
@@ -5,6 +5,7 @@ package util
 import (
 	"flag"
 	"fmt"
+	"math"
 	"os"
 	"time"
 
@@ -66,3 +67,10 @@ func (cfg *CommonRingConfig) ToRingConfig() ring.Config {
 	rc.HeartbeatTimeout = cfg.HeartbeatTimeout
 	return rc
 }
+
+// WeightedNumTokens returns the number of tokens registered in the ring by an instance with the given weight,
+// so that instances with a higher weight own a proportionally larger share of the ring. The returned number
+// of tokens is always at least 1.
+func WeightedNumTokens(numTokens int, weight float64) int {
+	return max(1, int(math.Round(float64(numTokens)*weight)))
+}
@@ -0,0 +1,153 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package tenantmapping
+
+import (
+	"context"
+	"sync"
+
+	"github.com/grafana/dskit/multierror"
+	"github.com/grafana/dskit/tenant"
+	"github.com/grafana/dskit/user"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/prometheus/prometheus/util/annotations"
+)
+
+// NewQueryable returns a queryable which reads the data of a tenant along with the data stored under the aliases
+// mapped to it, so that the data written before a tenant has been renamed can still be queried. The series of the
+// tenant and of its aliases are merged, so a series written under both IDs is returned as a single series.
+// Queries for multiple tenants are passed through, because the federated queryable splits them per tenant.
+// Validate must have been called on the config before.
+func NewQueryable(cfg Config, next storage.Queryable) storage.Queryable {
+	if len(cfg.formerIDs) == 0 {
+		return next
+	}
+	return &queryable{cfg: cfg, next: next}
+}
+
+type queryable struct {
+	cfg  Config
+	next storage.Queryable
+}
+
+func (q *queryable) Querier(mint, maxt int64) (storage.Querier, error) {
+	next, err := q.next.Querier(mint, maxt)
+	if err != nil {
+		return nil, err
+	}
+	return &querier{cfg: q.cfg, next: next, nextQueryable: q.next, mint: mint, maxt: maxt}, nil
+}
+
+type querier struct {
+	cfg           Config
+	next          storage.Querier
+	nextQueryable storage.Queryable
+	mint, maxt    int64
+
+	// aliasQueriers are the queriers opened to read the data of the aliases. They're closed along with the
+	// querier, because the series they return can be read until then.
+	aliasQueriersMx sync.Mutex
+	aliasQueriers   []storage.Querier
+}
+
+func (q *querier) Select(ctx context.Context, sortSeries bool, hints *storage.SelectHints, matchers ...*labels.Matcher) storage.SeriesSet {
+	merged, err := q.mergeQuerier(ctx)
+	if err != nil {
+		return storage.ErrSeriesSet(err)
+	}
+	if merged == nil {
+		return q.next.Select(ctx, sortSeries, hints, matchers...)
+	}
+	return merged.Select(ctx, sortSeries, hints, matchers...)
+}
+
+func (q *querier) LabelValues(ctx context.Context, name string, hints *storage.LabelHints, matchers ...*labels.Matcher) ([]string, annotations.Annotations, error) {
+	merged, err := q.mergeQuerier(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	if merged == nil {
+		return q.next.LabelValues(ctx, name, hints, matchers...)
+	}
+	return merged.LabelValues(ctx, name, hints, matchers...)
+}
+
+func (q *querier) LabelNames(ctx context.Context, hints *storage.LabelHints, matchers ...*labels.Matcher) ([]string, annotations.Annotations, error) {
+	merged, err := q.mergeQuerier(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	if merged == nil {
+		return q.next.LabelNames(ctx, hints, matchers...)
+	}
+	return merged.LabelNames(ctx, hints, matchers...)
+}
+
+func (q *querier) Close() error {
+	q.aliasQueriersMx.Lock()
+	defer q.aliasQueriersMx.Unlock()
+
+	errs := multierror.New()
+	for _, aliasQuerier := range q.aliasQueriers {
+		errs.Add(aliasQuerier.Close())
+	}
+	q.aliasQueriers = nil
+	errs.Add(q.next.Close())
+	return errs.Err()
+}
+
+// mergeQuerier returns a querier merging the data of the tenant in the context with the data of its aliases,
+// or nil if the tenant has no aliases.
+func (q *querier) mergeQuerier(ctx context.Context) (storage.Querier, error) {
+	ids, err := tenant.TenantIDs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(ids) != 1 || len(q.cfg.formerIDs[ids[0]]) == 0 {
+		return nil, nil
+	}
+
+	formerIDs := q.cfg.formerIDs[ids[0]]
+	queriers := make([]storage.Querier, 0, len(formerIDs)+1)
+	queriers = append(queriers, &tenantQuerier{Querier: q.next, tenantID: ids[0]})
+	for _, id := range formerIDs {
+		aliasQuerier, err := q.nextQueryable.Querier(q.mint, q.maxt)
+		if err != nil {
+			return nil, err
+		}
+
+		q.aliasQueriersMx.Lock()
+		q.aliasQueriers = append(q.aliasQueriers, aliasQuerier)
+		q.aliasQueriersMx.Unlock()
+
+		queriers = append(queriers, &tenantQuerier{Querier: aliasQuerier, tenantID: id})
+	}
+
+	// All the queriers are primaries, so that failing to read the data of an alias fails the query,
+	// instead of silently returning partial results.
+	return storage.NewMergeQuerier(queriers, nil, storage.ChainedSeriesMerge), nil
+}
+
+// tenantQuerier is a querier reading the data of a given tenant, regardless of the tenant in the context.
+// It doesn't close the wrapped querier, which is closed by the parent querier.
+type tenantQuerier struct {
+	storage.Querier
+	tenantID string
+}
+
+func (q *tenantQuerier) Select(ctx context.Context, sortSeries bool, hints *storage.SelectHints, matchers ...*labels.Matcher) storage.SeriesSet {
+	return q.Querier.Select(user.InjectOrgID(ctx, q.tenantID), sortSeries, hints, matchers...)
+}
+
+func (q *tenantQuerier) LabelValues(ctx context.Context, name string, hints *storage.LabelHints, matchers ...*labels.Matcher) ([]string, annotations.Annotations, error) {
+	return q.Querier.LabelValues(user.InjectOrgID(ctx, q.tenantID), name, hints, matchers...)
+}
+
+func (q *tenantQuerier) LabelNames(ctx context.Context, hints *storage.LabelHints, matchers ...*labels.Matcher) ([]string, annotations.Annotations, error) {
+	return q.Querier.LabelNames(user.InjectOrgID(ctx, q.tenantID), hints, matchers...)
+}
+
+func (q *tenantQuerier) Close() error {
+	return nil
+}
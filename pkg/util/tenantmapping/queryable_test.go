@@ -0,0 +1,189 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package tenantmapping
+
+import (
+	"context"
+	"testing"
+
+	"github.com/grafana/dskit/flagext"
+	"github.com/grafana/dskit/tenant"
+	"github.com/grafana/dskit/user"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/prometheus/prometheus/tsdb/chunkenc"
+	"github.com/prometheus/prometheus/util/annotations"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/mimir/pkg/storage/series"
+)
+
+func TestNewQueryable(t *testing.T) {
+	cfg := Config{Aliases: flagext.StringSliceCSV{"legacy-a=tenant-a", "former-a=tenant-a", "legacy-b=tenant-c"}}
+	require.NoError(t, cfg.Validate())
+
+	next := &mockTenantsQueryable{series: map[string][]*series.ConcreteSeries{
+		"tenant-a": {
+			series.NewConcreteSeries(labels.FromStrings(labels.MetricName, "up", "job", "a"), []model.SamplePair{{Timestamp: 20, Value: 1}, {Timestamp: 30, Value: 1}}, nil),
+		},
+		"legacy-a": {
+			series.NewConcreteSeries(labels.FromStrings(labels.MetricName, "up", "job", "a"), []model.SamplePair{{Timestamp: 0, Value: 1}, {Timestamp: 10, Value: 1}}, nil),
+		},
+		"former-a": {
+			series.NewConcreteSeries(labels.FromStrings(labels.MetricName, "up", "job", "b"), []model.SamplePair{{Timestamp: 0, Value: 1}}, nil),
+		},
+		"tenant-b": {
+			series.NewConcreteSeries(labels.FromStrings(labels.MetricName, "up", "job", "c"), []model.SamplePair{{Timestamp: 0, Value: 1}}, nil),
+		},
+	}}
+
+	q, err := NewQueryable(cfg, next).Querier(0, 100)
+	require.NoError(t, err)
+
+	matcher := labels.MustNewMatcher(labels.MatchEqual, labels.MetricName, "up")
+
+	t.Run("the data of a tenant is merged with the data of its aliases", func(t *testing.T) {
+		ctx := user.InjectOrgID(context.Background(), "tenant-a")
+
+		assert.Equal(t, map[string][]int64{
+			`{__name__="up", job="a"}`: {0, 10, 20, 30},
+			`{__name__="up", job="b"}`: {0},
+		}, readSeriesSet(t, q.Select(ctx, true, nil, matcher)))
+
+		values, _, err := q.LabelValues(ctx, "job", nil, matcher)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"a", "b"}, values)
+
+		names, _, err := q.LabelNames(ctx, nil, matcher)
+		require.NoError(t, err)
+		assert.Equal(t, []string{labels.MetricName, "job"}, names)
+	})
+
+	t.Run("the data of a tenant without aliases is read as is", func(t *testing.T) {
+		ctx := user.InjectOrgID(context.Background(), "tenant-b")
+
+		assert.Equal(t, map[string][]int64{
+			`{__name__="up", job="c"}`: {0},
+		}, readSeriesSet(t, q.Select(ctx, true, nil, matcher)))
+	})
+
+	t.Run("a tenant whose aliases have no data", func(t *testing.T) {
+		ctx := user.InjectOrgID(context.Background(), "tenant-c")
+
+		assert.Empty(t, readSeriesSet(t, q.Select(ctx, true, nil, matcher)))
+	})
+
+	require.NoError(t, q.Close())
+	assert.Positive(t, next.opened)
+	assert.Equal(t, next.opened, next.closed)
+}
+
+func TestNewQueryable_WithoutAliases(t *testing.T) {
+	cfg := Config{Lowercase: true}
+	require.NoError(t, cfg.Validate())
+
+	next := &mockTenantsQueryable{}
+	assert.Same(t, next, NewQueryable(cfg, next))
+}
+
+func readSeriesSet(t *testing.T, set storage.SeriesSet) map[string][]int64 {
+	res := map[string][]int64{}
+	for set.Next() {
+		s := set.At()
+		var timestamps []int64
+		it := s.Iterator(nil)
+		for it.Next() == chunkenc.ValFloat {
+			ts, _ := it.At()
+			timestamps = append(timestamps, ts)
+		}
+		require.NoError(t, it.Err())
+		res[s.Labels().String()] = timestamps
+	}
+	require.NoError(t, set.Err())
+	return res
+}
+
+// mockTenantsQueryable returns the series of the tenant in the context.
+type mockTenantsQueryable struct {
+	series map[string][]*series.ConcreteSeries
+
+	opened, closed int
+}
+
+func (m *mockTenantsQueryable) Querier(_, _ int64) (storage.Querier, error) {
+	m.opened++
+	return &mockTenantsQuerier{parent: m}, nil
+}
+
+type mockTenantsQuerier struct {
+	parent *mockTenantsQueryable
+}
+
+func (m *mockTenantsQuerier) Select(ctx context.Context, _ bool, _ *storage.SelectHints, matchers ...*labels.Matcher) storage.SeriesSet {
+	matching, err := m.matchingSeries(ctx, matchers)
+	if err != nil {
+		return storage.ErrSeriesSet(err)
+	}
+
+	res := make([]storage.Series, 0, len(matching))
+	for _, s := range matching {
+		res = append(res, s)
+	}
+	return series.NewConcreteSeriesSetFromUnsortedSeries(res)
+}
+
+func (m *mockTenantsQuerier) LabelValues(ctx context.Context, name string, _ *storage.LabelHints, matchers ...*labels.Matcher) ([]string, annotations.Annotations, error) {
+	matching, err := m.matchingSeries(ctx, matchers)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var values []string
+	for _, s := range matching {
+		if v := s.Labels().Get(name); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values, nil, nil
+}
+
+func (m *mockTenantsQuerier) LabelNames(ctx context.Context, _ *storage.LabelHints, matchers ...*labels.Matcher) ([]string, annotations.Annotations, error) {
+	matching, err := m.matchingSeries(ctx, matchers)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var names []string
+	for _, s := range matching {
+		s.Labels().Range(func(l labels.Label) {
+			names = append(names, l.Name)
+		})
+	}
+	return names, nil, nil
+}
+
+func (m *mockTenantsQuerier) Close() error {
+	m.parent.closed++
+	return nil
+}
+
+func (m *mockTenantsQuerier) matchingSeries(ctx context.Context, matchers []*labels.Matcher) ([]*series.ConcreteSeries, error) {
+	userID, err := tenant.TenantID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []*series.ConcreteSeries
+	for _, s := range m.parent.series[userID] {
+		matches := true
+		for _, matcher := range matchers {
+			matches = matches && matcher.Matches(s.Labels().Get(matcher.Name))
+		}
+		if matches {
+			res = append(res, s)
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,121 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+// Package tenantmapping provides a middleware rewriting the tenant IDs of incoming HTTP requests,
+// so that clients can keep sending a tenant's former IDs once it's been renamed, and a queryable
+// reading the data stored under the former IDs of a tenant along with the tenant's data.
+package tenantmapping
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/grafana/dskit/flagext"
+	"github.com/grafana/dskit/middleware"
+	"github.com/grafana/dskit/tenant"
+	"github.com/grafana/dskit/user"
+)
+
+type Config struct {
+	StripPrefixes flagext.StringSliceCSV `yaml:"strip_prefixes" category:"experimental"`
+	Lowercase     bool                   `yaml:"lowercase" category:"experimental"`
+	Aliases       flagext.StringSliceCSV `yaml:"aliases" category:"experimental"`
+
+	aliases map[string]string
+
+	// formerIDs are the aliases mapped to each tenant ID, sorted.
+	formerIDs map[string][]string
+}
+
+func (cfg *Config) RegisterFlags(f *flag.FlagSet) {
+	f.Var(&cfg.StripPrefixes, "tenant-mapping.strip-prefixes", "Comma-separated list of prefixes removed from the tenant IDs of incoming requests. Only the first matching prefix is removed.")
+	f.BoolVar(&cfg.Lowercase, "tenant-mapping.lowercase", false, "Convert the tenant IDs of incoming requests to lowercase. Applied after removing the prefixes.")
+	f.Var(&cfg.Aliases, "tenant-mapping.aliases", "Comma-separated list of tenant ID aliases in the form <alias>=<tenant ID>. Requests for an alias are served as requests for the tenant ID it maps to. Applied after removing the prefixes and converting to lowercase. The queries of a tenant also read the data stored under its aliases, so that the data written before renaming a tenant can still be queried. To keep querying the data of a tenant ID rewritten by the prefixes or the lowercase conversion, add it as an alias of the new tenant ID.")
+}
+
+func (cfg *Config) Validate() error {
+	cfg.aliases = make(map[string]string, len(cfg.Aliases))
+	cfg.formerIDs = map[string][]string{}
+
+	for _, entry := range cfg.Aliases {
+		from, to, ok := strings.Cut(entry, "=")
+		if !ok || from == "" || to == "" {
+			return fmt.Errorf("invalid tenant mapping alias %q: expected <alias>=<tenant ID>", entry)
+		}
+		// The alias is validated too, because the data stored under it is queried along with the tenant's data.
+		if err := tenant.ValidTenantID(from); err != nil {
+			return fmt.Errorf("invalid tenant mapping alias %q: %w", entry, err)
+		}
+		if err := tenant.ValidTenantID(to); err != nil {
+			return fmt.Errorf("invalid tenant mapping alias %q: %w", entry, err)
+		}
+		if _, ok := cfg.aliases[from]; ok {
+			return fmt.Errorf("duplicated tenant mapping alias %q", from)
+		}
+		cfg.aliases[from] = to
+		cfg.formerIDs[to] = append(cfg.formerIDs[to], from)
+	}
+	for _, ids := range cfg.formerIDs {
+		slices.Sort(ids)
+	}
+
+	// Requests are mapped once at the edge, but the tenant ID is authenticated again by some
+	// internal handlers, so the mapping must give the same result when applied twice.
+	for from, to := range cfg.aliases {
+		if mapped := cfg.mapTenantID(to); mapped != to {
+			return fmt.Errorf("invalid tenant mapping alias %q: the tenant ID %q would be further mapped to %q", from, to, mapped)
+		}
+	}
+
+	return nil
+}
+
+// Enabled returns whether any mapping is configured.
+func (cfg *Config) Enabled() bool {
+	return len(cfg.StripPrefixes) > 0 || cfg.Lowercase || len(cfg.Aliases) > 0
+}
+
+// mapTenantID returns the tenant ID which id maps to.
+func (cfg *Config) mapTenantID(id string) string {
+	for _, prefix := range cfg.StripPrefixes {
+		if trimmed, ok := strings.CutPrefix(id, prefix); ok && trimmed != "" {
+			id = trimmed
+			break
+		}
+	}
+	if cfg.Lowercase {
+		id = strings.ToLower(id)
+	}
+	if to, ok := cfg.aliases[id]; ok {
+		id = to
+	}
+	return id
+}
+
+// Middleware returns an HTTP middleware rewriting the tenant IDs injected in the request context by
+// the authentication middleware, which must run before it. The tenant ID header is rewritten too,
+// so that requests forwarded to other components carry the mapped tenant IDs.
+// Validate must have been called on the config before.
+func Middleware(cfg Config) middleware.Interface {
+	return middleware.Func(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ids, err := tenant.TenantIDs(r.Context())
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+				return
+			}
+
+			mapped := make([]string, 0, len(ids))
+			for _, id := range ids {
+				mapped = append(mapped, cfg.mapTenantID(id))
+			}
+			// Aliases of the same tenant are deduplicated, as the tenant IDs of a federated request must be unique.
+			orgID := tenant.JoinTenantIDs(tenant.NormalizeTenantIDs(mapped))
+
+			r.Header.Set(user.OrgIDHeaderName, orgID)
+			next.ServeHTTP(w, r.WithContext(user.InjectOrgID(r.Context(), orgID)))
+		})
+	})
+}
@@ -0,0 +1,101 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package tenantmapping
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/grafana/dskit/flagext"
+	"github.com/grafana/dskit/middleware"
+	"github.com/grafana/dskit/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig_Validate(t *testing.T) {
+	for name, tc := range map[string]struct {
+		cfg         Config
+		expectedErr string
+	}{
+		"empty config": {
+			cfg: Config{},
+		},
+		"valid aliases": {
+			cfg: Config{Aliases: flagext.StringSliceCSV{"legacy-a=tenant-a", "legacy-b=tenant-a"}},
+		},
+		"alias without target": {
+			cfg:         Config{Aliases: flagext.StringSliceCSV{"legacy-a"}},
+			expectedErr: `invalid tenant mapping alias "legacy-a": expected <alias>=<tenant ID>`,
+		},
+		"alias to an invalid tenant ID": {
+			cfg:         Config{Aliases: flagext.StringSliceCSV{"legacy-a=tenant|a"}},
+			expectedErr: `invalid tenant mapping alias "legacy-a=tenant|a": tenant ID 'tenant|a' contains unsupported character '|'`,
+		},
+		"invalid alias": {
+			cfg:         Config{Aliases: flagext.StringSliceCSV{"legacy|a=tenant-a"}},
+			expectedErr: `invalid tenant mapping alias "legacy|a=tenant-a": tenant ID 'legacy|a' contains unsupported character '|'`,
+		},
+		"duplicated alias": {
+			cfg:         Config{Aliases: flagext.StringSliceCSV{"legacy-a=tenant-a", "legacy-a=tenant-b"}},
+			expectedErr: `duplicated tenant mapping alias "legacy-a"`,
+		},
+		"chained aliases": {
+			cfg:         Config{Aliases: flagext.StringSliceCSV{"legacy-a=tenant-a", "tenant-a=tenant-b"}},
+			expectedErr: `invalid tenant mapping alias "legacy-a": the tenant ID "tenant-a" would be further mapped to "tenant-b"`,
+		},
+		"alias to a tenant ID which is lowercased": {
+			cfg:         Config{Lowercase: true, Aliases: flagext.StringSliceCSV{"legacy-a=Tenant-A"}},
+			expectedErr: `invalid tenant mapping alias "legacy-a": the tenant ID "Tenant-A" would be further mapped to "tenant-a"`,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			err := tc.cfg.Validate()
+			if tc.expectedErr == "" {
+				require.NoError(t, err)
+			} else {
+				require.EqualError(t, err, tc.expectedErr)
+			}
+		})
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	cfg := Config{
+		StripPrefixes: flagext.StringSliceCSV{"org-", "team-"},
+		Lowercase:     true,
+		Aliases:       flagext.StringSliceCSV{"legacy=tenant-a", "old-b=tenant-b"},
+	}
+	require.NoError(t, cfg.Validate())
+
+	for orgID, expected := range map[string]string{
+		"tenant-a":             "tenant-a",
+		"legacy":               "tenant-a",
+		"LEGACY":               "tenant-a",
+		"org-legacy":           "tenant-a",
+		"team-Old-B":           "tenant-b",
+		"org-team-x":           "team-x",
+		"org-":                 "org-",
+		"legacy|tenant-b":      "tenant-a|tenant-b",
+		"org-legacy|tenant-a":  "tenant-a",
+		"old-b|Tenant-C|other": "other|tenant-b|tenant-c",
+	} {
+		t.Run(orgID, func(t *testing.T) {
+			var actualCtx, actualHeader string
+			handler := middleware.Merge(middleware.AuthenticateUser, Middleware(cfg)).Wrap(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+				actualCtx, _ = user.ExtractOrgID(r.Context())
+				actualHeader = r.Header.Get(user.OrgIDHeaderName)
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set(user.OrgIDHeaderName, orgID)
+			resp := httptest.NewRecorder()
+			handler.ServeHTTP(resp, req)
+
+			require.Equal(t, http.StatusOK, resp.Code)
+			assert.Equal(t, expected, actualCtx)
+			assert.Equal(t, expected, actualHeader)
+		})
+	}
+}
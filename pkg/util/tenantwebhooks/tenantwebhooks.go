@@ -0,0 +1,379 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package tenantwebhooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/felixge/httpsnoop"
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/grafana/dskit/concurrency"
+	"github.com/grafana/dskit/flagext"
+	"github.com/grafana/dskit/middleware"
+	"github.com/grafana/dskit/services"
+	"github.com/grafana/dskit/tenant"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/thanos-io/objstore"
+)
+
+const (
+	// EventFirstWrite is emitted the first time a tenant writes through this process.
+	EventFirstWrite = "first_write"
+	// EventFirstQuery is emitted the first time a tenant queries through this process.
+	EventFirstQuery = "first_query"
+	// EventDeleted is emitted when a tenant is marked for deletion.
+	EventDeleted = "deleted"
+
+	// queueSize is the max number of notifications waiting to be sent. Additional notifications are dropped.
+	queueSize = 1024
+
+	// BucketPrefix is the prefix, relative to the Mimir internals prefix, of the objects recording the
+	// tenants already notified.
+	BucketPrefix = "tenant-webhooks"
+
+	// seededMarkerPath is the path of the object recording that the tenants existing when the tenant webhooks
+	// were enabled have been recorded as already notified.
+	seededMarkerPath = "seeded"
+
+	// seedConcurrency is the max number of tenants concurrently recorded as already notified.
+	seedConcurrency = 16
+)
+
+var supportedEvents = []string{EventFirstWrite, EventFirstQuery, EventDeleted}
+
+type Config struct {
+	URL     string                 `yaml:"url" category:"experimental"`
+	Events  flagext.StringSliceCSV `yaml:"events" category:"experimental"`
+	Timeout time.Duration          `yaml:"timeout" category:"experimental"`
+}
+
+func (cfg *Config) RegisterFlags(f *flag.FlagSet) {
+	cfg.Events = supportedEvents
+
+	f.StringVar(&cfg.URL, "tenant-webhooks.url", "", "URL of the webhook receiving tenant provisioning events with an HTTP POST request. If empty, tenant webhooks are disabled. When the tenant webhooks are enabled for the first time, the tenants found in the blocks storage are recorded as already notified of their first write and first query.")
+	f.Var(&cfg.Events, "tenant-webhooks.events", fmt.Sprintf("Comma-separated list of tenant events sent to the webhook. Supported values are: %s.", strings.Join(supportedEvents, ", ")))
+	f.DurationVar(&cfg.Timeout, "tenant-webhooks.timeout", 10*time.Second, "Timeout of each webhook request.")
+}
+
+func (cfg *Config) Validate() error {
+	if cfg.URL == "" {
+		return nil
+	}
+
+	if _, err := url.ParseRequestURI(cfg.URL); err != nil {
+		return errors.Wrap(err, "invalid tenant webhooks URL")
+	}
+	for _, event := range cfg.Events {
+		if !slices.Contains(supportedEvents, event) {
+			return fmt.Errorf("unsupported tenant webhooks event: %s", event)
+		}
+	}
+	if cfg.Timeout <= 0 {
+		return errors.New("tenant webhooks timeout must be greater than 0")
+	}
+
+	return nil
+}
+
+// Payload is the body of the webhook requests.
+type Payload struct {
+	TenantID  string    `json:"tenant_id"`
+	Event     string    `json:"event"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// Notifier sends tenant provisioning events to the configured webhook.
+//
+// The tenants already notified of the first write or first query are recorded with a marker object in the
+// bucket, so the events are notified once across all replicas and restarts. Each process also keeps track in
+// memory of the tenants it has already seen, to avoid checking the bucket on every request. Replicas handling
+// the first requests of a tenant at the same time may still send duplicated notifications, so receivers are
+// expected to handle them. Notifications are sent asynchronously, and failed notifications are sent again on
+// the next event.
+//
+// When the tenant webhooks are enabled on an existing cluster, the tenants found in the blocks storage are
+// recorded as already notified at startup, so that they don't get first write and first query events. This
+// only happens once: the tenants which only have data in the ingesters at that time are still notified.
+type Notifier struct {
+	services.Service
+
+	cfg         Config
+	bkt         objstore.Bucket
+	listTenants func(context.Context) ([]string, error)
+	client      *http.Client
+	logger      log.Logger
+	queue       chan Payload
+
+	seenMtx sync.Mutex
+	seen    map[string]map[string]struct{} // Tenants notified by event.
+
+	sent         *prometheus.CounterVec
+	failed       *prometheus.CounterVec
+	dropped      *prometheus.CounterVec
+	deduplicated *prometheus.CounterVec
+}
+
+// NewNotifier makes a new Notifier. The bucket is used to record the tenants already notified, while listTenants
+// returns the tenants existing in the blocks storage, which are recorded as already notified the first time.
+func NewNotifier(cfg Config, bkt objstore.Bucket, listTenants func(context.Context) ([]string, error), logger log.Logger, reg prometheus.Registerer) *Notifier {
+	n := &Notifier{
+		cfg:         cfg,
+		bkt:         bkt,
+		listTenants: listTenants,
+		client:      &http.Client{Timeout: cfg.Timeout},
+		logger:      log.With(logger, "component", "tenant-webhooks"),
+		queue:       make(chan Payload, queueSize),
+		seen:        map[string]map[string]struct{}{},
+		sent: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "cortex_tenant_webhooks_sent_total",
+			Help: "Total number of tenant webhook notifications successfully sent.",
+		}, []string{"event"}),
+		failed: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "cortex_tenant_webhooks_failed_total",
+			Help: "Total number of tenant webhook notifications which failed to be sent.",
+		}, []string{"event"}),
+		dropped: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "cortex_tenant_webhooks_dropped_total",
+			Help: "Total number of tenant webhook notifications dropped because the queue was full.",
+		}, []string{"event"}),
+		deduplicated: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "cortex_tenant_webhooks_deduplicated_total",
+			Help: "Total number of tenant webhook notifications not sent because the tenant has already been notified by another replica or before a restart.",
+		}, []string{"event"}),
+	}
+
+	for _, event := range cfg.Events {
+		n.seen[event] = map[string]struct{}{}
+	}
+
+	n.Service = services.NewBasicService(n.starting, n.running, nil)
+	return n
+}
+
+// starting records the tenants existing in the blocks storage as already notified, unless it has already been done.
+func (n *Notifier) starting(ctx context.Context) error {
+	seeded, err := n.bkt.Exists(ctx, seededMarkerPath)
+	if err != nil {
+		return errors.Wrap(err, "check tenant webhooks seeded marker")
+	}
+	if seeded {
+		return nil
+	}
+
+	tenants, err := n.listTenants(ctx)
+	if err != nil {
+		return errors.Wrap(err, "list tenants")
+	}
+
+	now := time.Now()
+	err = concurrency.ForEachJob(ctx, len(tenants), seedConcurrency, func(ctx context.Context, idx int) error {
+		for _, event := range []string{EventFirstWrite, EventFirstQuery} {
+			if err := n.uploadMarker(ctx, Payload{TenantID: tenants[idx], Event: event, Timestamp: now}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "record existing tenants as notified")
+	}
+
+	if err := n.bkt.Upload(ctx, seededMarkerPath, strings.NewReader(now.Format(time.RFC3339))); err != nil {
+		return errors.Wrap(err, "upload tenant webhooks seeded marker")
+	}
+
+	level.Info(n.logger).Log("msg", "recorded existing tenants as already notified", "tenants", len(tenants))
+	return nil
+}
+
+// Notify enqueues the notification of the event for the tenant, unless the tenant has already been
+// notified for the same event or the event is not enabled.
+func (n *Notifier) Notify(tenantID, event string) {
+	if !n.markSeen(tenantID, event) {
+		return
+	}
+
+	select {
+	case n.queue <- Payload{TenantID: tenantID, Event: event, Timestamp: time.Now()}:
+	default:
+		n.dropped.WithLabelValues(event).Inc()
+		n.forget(tenantID, event)
+	}
+}
+
+// markSeen records the tenant as notified for the event and returns whether it should be notified.
+func (n *Notifier) markSeen(tenantID, event string) bool {
+	n.seenMtx.Lock()
+	defer n.seenMtx.Unlock()
+
+	tenants, enabled := n.seen[event]
+	if !enabled {
+		return false
+	}
+
+	if event == EventDeleted {
+		// A deleted tenant which comes back must be notified again.
+		for _, tenants := range n.seen {
+			delete(tenants, tenantID)
+		}
+		return true
+	}
+
+	if _, ok := tenants[tenantID]; ok {
+		return false
+	}
+	tenants[tenantID] = struct{}{}
+	return true
+}
+
+func (n *Notifier) forget(tenantID, event string) {
+	if event == EventDeleted {
+		return
+	}
+
+	n.seenMtx.Lock()
+	delete(n.seen[event], tenantID)
+	n.seenMtx.Unlock()
+}
+
+func (n *Notifier) running(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case p := <-n.queue:
+			if err := n.process(ctx, p); err != nil {
+				level.Warn(n.logger).Log("msg", "failed to send tenant webhook notification", "user", p.TenantID, "event", p.Event, "err", err)
+				n.failed.WithLabelValues(p.Event).Inc()
+
+				// Notify the tenant again on the next event.
+				n.forget(p.TenantID, p.Event)
+			}
+		}
+	}
+}
+
+// process sends the notification, unless the tenant has already been notified of the event, and records
+// the tenant as notified in the bucket.
+func (n *Notifier) process(ctx context.Context, p Payload) error {
+	if p.Event == EventDeleted {
+		if err := n.send(ctx, p); err != nil {
+			return err
+		}
+		n.sent.WithLabelValues(p.Event).Inc()
+
+		// A deleted tenant which comes back must be notified again.
+		for _, event := range []string{EventFirstWrite, EventFirstQuery} {
+			if err := n.bkt.Delete(ctx, markerPath(p.TenantID, event)); err != nil && !n.bkt.IsObjNotFoundErr(err) {
+				level.Warn(n.logger).Log("msg", "failed to delete tenant webhook marker", "user", p.TenantID, "event", event, "err", err)
+			}
+		}
+		return nil
+	}
+
+	marker := markerPath(p.TenantID, p.Event)
+	notified, err := n.bkt.Exists(ctx, marker)
+	if err != nil {
+		return errors.Wrap(err, "check tenant webhook marker")
+	}
+	if notified {
+		n.deduplicated.WithLabelValues(p.Event).Inc()
+		return nil
+	}
+
+	if err := n.send(ctx, p); err != nil {
+		return err
+	}
+	n.sent.WithLabelValues(p.Event).Inc()
+
+	if err := n.uploadMarker(ctx, p); err != nil {
+		// The notification has been sent, so it's not notified again by this process.
+		level.Warn(n.logger).Log("msg", "failed to upload tenant webhook marker", "user", p.TenantID, "event", p.Event, "err", err)
+	}
+	return nil
+}
+
+// uploadMarker records the tenant as notified of the event.
+func (n *Notifier) uploadMarker(ctx context.Context, p Payload) error {
+	data, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	return n.bkt.Upload(ctx, markerPath(p.TenantID, p.Event), bytes.NewReader(data))
+}
+
+// markerPath returns the path of the object recording the tenant as notified of the event.
+func markerPath(tenantID, event string) string {
+	return path.Join(event, tenantID)
+}
+
+func (n *Notifier) send(ctx context.Context, p Payload) error {
+	body, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Middleware returns a HTTP middleware notifying the event for the tenants of each successful request.
+// The requests rejected with a non-2xx response, for example because of invalid parameters or limits, are not notified.
+func (n *Notifier) Middleware(event string) middleware.Interface {
+	return middleware.Func(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// The response writer is wrapped keeping the optional interfaces, such as http.Flusher, of the original one.
+			statusCode := 0
+			w = httpsnoop.Wrap(w, httpsnoop.Hooks{
+				WriteHeader: func(next httpsnoop.WriteHeaderFunc) httpsnoop.WriteHeaderFunc {
+					return func(code int) {
+						if statusCode == 0 {
+							statusCode = code
+						}
+						next(code)
+					}
+				},
+			})
+
+			next.ServeHTTP(w, r)
+
+			// The status code is implicitly 200 if the handler didn't write it.
+			if statusCode != 0 && statusCode/100 != 2 {
+				return
+			}
+			if tenantIDs, err := tenant.TenantIDs(r.Context()); err == nil {
+				for _, tenantID := range tenantIDs {
+					n.Notify(tenantID, event)
+				}
+			}
+		})
+	})
+}
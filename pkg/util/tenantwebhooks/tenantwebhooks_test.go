@@ -0,0 +1,287 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package tenantwebhooks
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/dskit/services"
+	"github.com/grafana/dskit/user"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/objstore"
+)
+
+type webhookReceiver struct {
+	mtx      sync.Mutex
+	payloads []Payload
+	fail     bool
+}
+
+func (r *webhookReceiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	if r.fail {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	p := Payload{}
+	if err := json.NewDecoder(req.Body).Decode(&p); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	r.payloads = append(r.payloads, p)
+}
+
+func (r *webhookReceiver) received() []string {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	var res []string
+	for _, p := range r.payloads {
+		res = append(res, p.TenantID+":"+p.Event)
+	}
+	return res
+}
+
+func (r *webhookReceiver) setFail(fail bool) {
+	r.mtx.Lock()
+	r.fail = fail
+	r.mtx.Unlock()
+}
+
+func newTestNotifier(t *testing.T, url string, events ...string) *Notifier {
+	return newTestNotifierWithBucket(t, url, objstore.NewInMemBucket(), events...)
+}
+
+func newTestNotifierWithBucket(t *testing.T, url string, bkt objstore.Bucket, events ...string) *Notifier {
+	return newTestNotifierWithTenants(t, url, bkt, func(context.Context) ([]string, error) { return nil, nil }, events...)
+}
+
+func newTestNotifierWithTenants(t *testing.T, url string, bkt objstore.Bucket, listTenants func(context.Context) ([]string, error), events ...string) *Notifier {
+	cfg := Config{URL: url, Events: events, Timeout: time.Second}
+	require.NoError(t, cfg.Validate())
+
+	n := NewNotifier(cfg, bkt, listTenants, log.NewNopLogger(), nil)
+	require.NoError(t, services.StartAndAwaitRunning(context.Background(), n))
+	t.Cleanup(func() {
+		require.NoError(t, services.StopAndAwaitTerminated(context.Background(), n))
+	})
+	return n
+}
+
+func TestNotifier(t *testing.T) {
+	receiver := &webhookReceiver{}
+	srv := httptest.NewServer(receiver)
+	t.Cleanup(srv.Close)
+
+	n := newTestNotifier(t, srv.URL, EventFirstWrite, EventDeleted)
+
+	n.Notify("user-1", EventFirstWrite)
+	n.Notify("user-1", EventFirstWrite)
+	n.Notify("user-2", EventFirstWrite)
+	n.Notify("user-1", EventFirstQuery) // Not enabled.
+
+	require.Eventually(t, func() bool {
+		return len(receiver.received()) == 2
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, []string{"user-1:first_write", "user-2:first_write"}, receiver.received())
+
+	// A deleted tenant is notified again when it comes back.
+	n.Notify("user-1", EventDeleted)
+	n.Notify("user-1", EventFirstWrite)
+
+	require.Eventually(t, func() bool {
+		return len(receiver.received()) == 4
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, []string{"user-1:first_write", "user-2:first_write", "user-1:deleted", "user-1:first_write"}, receiver.received())
+}
+
+func TestNotifier_ShouldNotifyOnceAcrossReplicas(t *testing.T) {
+	receiver := &webhookReceiver{}
+	srv := httptest.NewServer(receiver)
+	t.Cleanup(srv.Close)
+
+	bkt := objstore.NewInMemBucket()
+	first := newTestNotifierWithBucket(t, srv.URL, bkt, EventFirstWrite, EventDeleted)
+
+	first.Notify("user-1", EventFirstWrite)
+	require.Eventually(t, func() bool {
+		ok, err := bkt.Exists(context.Background(), markerPath("user-1", EventFirstWrite))
+		return err == nil && ok
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// Another replica, or the same one after a restart, doesn't notify the tenant again.
+	second := newTestNotifierWithBucket(t, srv.URL, bkt, EventFirstWrite, EventDeleted)
+	second.Notify("user-1", EventFirstWrite)
+	require.Eventually(t, func() bool {
+		return testutil.ToFloat64(second.deduplicated.WithLabelValues(EventFirstWrite)) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, []string{"user-1:first_write"}, receiver.received())
+
+	// Once the tenant has been deleted, it's notified again when it comes back.
+	second.Notify("user-1", EventDeleted)
+	require.Eventually(t, func() bool {
+		ok, err := bkt.Exists(context.Background(), markerPath("user-1", EventFirstWrite))
+		return err == nil && !ok
+	}, 5*time.Second, 10*time.Millisecond)
+
+	third := newTestNotifierWithBucket(t, srv.URL, bkt, EventFirstWrite, EventDeleted)
+	third.Notify("user-1", EventFirstWrite)
+	require.Eventually(t, func() bool {
+		return len(receiver.received()) == 3
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, []string{"user-1:first_write", "user-1:deleted", "user-1:first_write"}, receiver.received())
+}
+
+func TestNotifier_ShouldNotifyAgainAfterFailure(t *testing.T) {
+	receiver := &webhookReceiver{fail: true}
+	srv := httptest.NewServer(receiver)
+	t.Cleanup(srv.Close)
+
+	n := newTestNotifier(t, srv.URL, EventFirstQuery)
+
+	n.Notify("user-1", EventFirstQuery)
+	require.Eventually(t, func() bool {
+		n.seenMtx.Lock()
+		defer n.seenMtx.Unlock()
+		_, seen := n.seen[EventFirstQuery]["user-1"]
+		return !seen
+	}, 5*time.Second, 10*time.Millisecond)
+
+	receiver.setFail(false)
+	n.Notify("user-1", EventFirstQuery)
+
+	require.Eventually(t, func() bool {
+		return len(receiver.received()) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestNotifier_ShouldNotNotifyTheTenantsExistingAtEnablement(t *testing.T) {
+	receiver := &webhookReceiver{}
+	srv := httptest.NewServer(receiver)
+	t.Cleanup(srv.Close)
+
+	bkt := objstore.NewInMemBucket()
+	listCalls := 0
+	listTenants := func(context.Context) ([]string, error) {
+		listCalls++
+		return []string{"user-1"}, nil
+	}
+
+	first := newTestNotifierWithTenants(t, srv.URL, bkt, listTenants, EventFirstWrite, EventFirstQuery)
+	require.Equal(t, 1, listCalls)
+
+	first.Notify("user-1", EventFirstWrite)
+	first.Notify("user-1", EventFirstQuery)
+	first.Notify("user-2", EventFirstWrite)
+	require.Eventually(t, func() bool {
+		return testutil.ToFloat64(first.deduplicated.WithLabelValues(EventFirstWrite)) == 1 &&
+			testutil.ToFloat64(first.deduplicated.WithLabelValues(EventFirstQuery)) == 1 &&
+			len(receiver.received()) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, []string{"user-2:first_write"}, receiver.received())
+
+	// The existing tenants are only recorded the first time the tenant webhooks are enabled.
+	newTestNotifierWithTenants(t, srv.URL, bkt, listTenants, EventFirstWrite, EventFirstQuery)
+	assert.Equal(t, 1, listCalls)
+}
+
+func TestNotifier_Middleware(t *testing.T) {
+	receiver := &webhookReceiver{}
+	srv := httptest.NewServer(receiver)
+	t.Cleanup(srv.Close)
+
+	n := newTestNotifier(t, srv.URL, EventFirstQuery)
+	handler := n.Middleware(EventFirstQuery).Wrap(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/query", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req.WithContext(user.InjectOrgID(context.Background(), "user-1|user-2")))
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+
+	require.Eventually(t, func() bool {
+		return len(receiver.received()) == 2
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.ElementsMatch(t, []string{"user-1:first_query", "user-2:first_query"}, receiver.received())
+}
+
+func TestNotifier_Middleware_ShouldNotNotifyFailedRequests(t *testing.T) {
+	receiver := &webhookReceiver{}
+	srv := httptest.NewServer(receiver)
+	t.Cleanup(srv.Close)
+
+	n := newTestNotifier(t, srv.URL, EventFirstWrite)
+	handler := n.Middleware(EventFirstWrite).Wrap(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Query().Get("valid") != "true" {
+			http.Error(w, "invalid payload", http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/push", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req.WithContext(user.InjectOrgID(context.Background(), "user-1")))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	// The rejected request isn't notified, while the next successful one is.
+	req = httptest.NewRequest(http.MethodPost, "/api/v1/push?valid=true", nil)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req.WithContext(user.InjectOrgID(context.Background(), "user-2")))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	require.Eventually(t, func() bool {
+		return len(receiver.received()) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, []string{"user-2:first_write"}, receiver.received())
+}
+
+func TestConfig_Validate(t *testing.T) {
+	tests := map[string]struct {
+		cfg         Config
+		expectedErr bool
+	}{
+		"disabled": {
+			cfg: Config{},
+		},
+		"valid": {
+			cfg: Config{URL: "http://provisioner/hook", Events: supportedEvents, Timeout: time.Second},
+		},
+		"invalid URL": {
+			cfg:         Config{URL: "provisioner", Events: supportedEvents, Timeout: time.Second},
+			expectedErr: true,
+		},
+		"unsupported event": {
+			cfg:         Config{URL: "http://provisioner/hook", Events: []string{"first_login"}, Timeout: time.Second},
+			expectedErr: true,
+		},
+		"invalid timeout": {
+			cfg:         Config{URL: "http://provisioner/hook", Events: supportedEvents},
+			expectedErr: true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := tc.cfg.Validate()
+			if tc.expectedErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -169,6 +169,7 @@ func parseStructure(structure interface{}, fields map[uintptr]reflect.StructFiel
 // then gets confused.
 var ignoredStructTypes = []reflect.Type{
 	reflect.TypeOf(flagext.Secret{}),
+	reflect.TypeOf(flagext.URLValue{}),
 	reflect.TypeOf(asmodel.CustomTrackersConfig{}),
 }
 
@@ -0,0 +1,37 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package validation
+
+import (
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/prometheus/common/model"
+)
+
+const (
+	// AlertLabelValidationLegacy requires alert label names to match the Prometheus naming rules.
+	AlertLabelValidationLegacy = "legacy"
+	// AlertLabelValidationUTF8 requires alert label names and values to be valid UTF-8.
+	AlertLabelValidationUTF8 = "utf8"
+)
+
+var alertLabelValidationSchemes = []string{AlertLabelValidationLegacy, AlertLabelValidationUTF8}
+
+// ValidateAlertLabel returns an error if the alert label name or value isn't valid according to the scheme.
+func ValidateAlertLabel(name, value string, scheme model.ValidationScheme) error {
+	validName := false
+	switch scheme {
+	case model.LegacyValidation:
+		validName = model.LabelName(name).IsValidLegacy()
+	default:
+		validName = len(name) > 0 && utf8.ValidString(name)
+	}
+	if !validName {
+		return fmt.Errorf("invalid label name %q", name)
+	}
+	if !utf8.ValidString(value) {
+		return fmt.Errorf("invalid value %q for label %q", value, name)
+	}
+	return nil
+}
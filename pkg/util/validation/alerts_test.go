@@ -0,0 +1,50 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package validation
+
+import (
+	"testing"
+
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateAlertLabel(t *testing.T) {
+	for name, tc := range map[string]struct {
+		name, value string
+		scheme      model.ValidationScheme
+		expectedErr string
+	}{
+		"legacy name with legacy scheme": {
+			name: "alertname", value: "HighLatency", scheme: model.LegacyValidation,
+		},
+		"UTF-8 name with legacy scheme": {
+			name: "service.name", value: "api", scheme: model.LegacyValidation,
+			expectedErr: `invalid label name "service.name"`,
+		},
+		"UTF-8 name with UTF-8 scheme": {
+			name: "service.name", value: "api", scheme: model.UTF8Validation,
+		},
+		"empty name with UTF-8 scheme": {
+			name: "", value: "api", scheme: model.UTF8Validation,
+			expectedErr: `invalid label name ""`,
+		},
+		"invalid UTF-8 name with UTF-8 scheme": {
+			name: "service\xff", value: "api", scheme: model.UTF8Validation,
+			expectedErr: `invalid label name "service\xff"`,
+		},
+		"invalid UTF-8 value with legacy scheme": {
+			name: "service", value: "api\xff", scheme: model.LegacyValidation,
+			expectedErr: `invalid value "api\xff" for label "service"`,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			err := ValidateAlertLabel(tc.name, tc.value, tc.scheme)
+			if tc.expectedErr == "" {
+				require.NoError(t, err)
+			} else {
+				require.EqualError(t, err, tc.expectedErr)
+			}
+		})
+	}
+}
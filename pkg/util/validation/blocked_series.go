@@ -0,0 +1,43 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package validation
+
+import (
+	"fmt"
+
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql/parser"
+)
+
+// BlockedSeries is a series selector of the series dropped by the distributor on the write path.
+type BlockedSeries struct {
+	// Match is the series selector of the series to drop.
+	Match string `yaml:"match" json:"match"`
+
+	// matchers are the parsed matchers of Match, set when the blocked series are validated.
+	matchers []*labels.Matcher
+}
+
+// Matchers returns the matchers of the series selector.
+func (b *BlockedSeries) Matchers() ([]*labels.Matcher, error) {
+	if b.matchers != nil {
+		return b.matchers, nil
+	}
+	return parser.ParseMetricSelector(b.Match)
+}
+
+func validateBlockedSeries(blocked []*BlockedSeries) error {
+	for _, b := range blocked {
+		if b == nil {
+			return fmt.Errorf("invalid blocked_series: empty series selector")
+		}
+
+		matchers, err := parser.ParseMetricSelector(b.Match)
+		if err != nil {
+			return fmt.Errorf("invalid blocked_series: invalid series selector %q: %w", b.Match, err)
+		}
+		b.matchers = matchers
+	}
+
+	return nil
+}
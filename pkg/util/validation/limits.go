@@ -32,6 +32,8 @@ const (
 	MaxSeriesPerMetricFlag                    = "ingester.max-global-series-per-metric"
 	MaxMetadataPerMetricFlag                  = "ingester.max-global-metadata-per-metric"
 	MaxSeriesPerUserFlag                      = "ingester.max-global-series-per-user"
+	MaxNewSeriesPerMinuteFlag                 = "ingester.max-global-new-series-per-minute"
+	MaxLabelValuesPerRequestFlag              = "ingester.max-label-values-per-request"
 	MaxMetadataPerUserFlag                    = "ingester.max-global-metadata-per-user"
 	MaxChunksPerQueryFlag                     = "querier.max-fetched-chunks-per-query"
 	MaxChunkBytesPerQueryFlag                 = "querier.max-fetched-chunk-bytes-per-query"
@@ -44,11 +46,16 @@ const (
 	MaxMetadataLengthFlag                     = "validation.max-metadata-length"
 	maxNativeHistogramBucketsFlag             = "validation.max-native-histogram-buckets"
 	ReduceNativeHistogramOverMaxBucketsFlag   = "validation.reduce-native-histogram-over-max-buckets"
+	TruncateLabelValueOverMaxLengthFlag       = "validation.truncate-label-value-over-max-length"
 	CreationGracePeriodFlag                   = "validation.create-grace-period"
 	PastGracePeriodFlag                       = "validation.past-grace-period"
+	MinSampleIntervalFlag                     = "validation.min-sample-interval"
+	MaxTimestampSkewCorrectionFlag            = "validation.max-timestamp-skew-correction"
 	MaxPartialQueryLengthFlag                 = "querier.max-partial-query-length"
 	MaxTotalQueryLengthFlag                   = "query-frontend.max-total-query-length"
 	MaxQueryExpressionSizeBytesFlag           = "query-frontend.max-query-expression-size-bytes"
+	MaxQueryBytesPerDayFlag                   = "query-frontend.max-query-bytes-per-day"
+	EnabledPromQLExperimentalFunctionsFlag    = "query-frontend.enabled-promql-experimental-functions"
 	RequestRateFlag                           = "distributor.request-rate-limit"
 	RequestBurstSizeFlag                      = "distributor.request-burst-size"
 	IngestionRateFlag                         = "distributor.ingestion-rate-limit"
@@ -58,13 +65,20 @@ const (
 	resultsCacheTTLFlag                       = "query-frontend.results-cache-ttl"
 	resultsCacheTTLForOutOfOrderWindowFlag    = "query-frontend.results-cache-ttl-for-out-of-order-time-window"
 	alignQueriesWithStepFlag                  = "query-frontend.align-queries-with-step"
+	splitQueriesByIntervalTimezoneFlag        = "query-frontend.split-queries-by-interval-timezone"
+	maxQueryPriorityFlag                      = "query-frontend.max-query-priority"
+	compactorFirstLevelOnlyUntilFlag          = "compactor.first-level-only-until"
 	QueryIngestersWithinFlag                  = "querier.query-ingesters-within"
 
 	// MinCompactorPartialBlockDeletionDelay is the minimum partial blocks deletion delay that can be configured in Mimir.
 	MinCompactorPartialBlockDeletionDelay = 4 * time.Hour
+
+	// AllPromQLExperimentalFunctions enables all the experimental PromQL functions and aggregations for a tenant.
+	AllPromQLExperimentalFunctions = "all"
 )
 
 var (
+	errInvalidAlertLabelValidationScheme           = fmt.Errorf("invalid alert label validation scheme (supported values: %s)", strings.Join(alertLabelValidationSchemes, ", "))
 	errInvalidIngestStorageReadConsistency         = fmt.Errorf("invalid ingest storage read consistency (supported values: %s)", strings.Join(api.ReadConsistencies, ", "))
 	errInvalidMaxEstimatedChunksPerQueryMultiplier = errors.New("invalid value for -" + MaxEstimatedChunksPerQueryMultiplierFlag + ": must be 0 or greater than or equal to 1")
 )
@@ -110,6 +124,7 @@ type Limits struct {
 	DropLabels                                  flagext.StringSlice `yaml:"drop_labels" json:"drop_labels" category:"advanced"`
 	MaxLabelNameLength                          int                 `yaml:"max_label_name_length" json:"max_label_name_length"`
 	MaxLabelValueLength                         int                 `yaml:"max_label_value_length" json:"max_label_value_length"`
+	TruncateLabelValueOverMaxLength             bool                `yaml:"truncate_label_value_over_max_length" json:"truncate_label_value_over_max_length" category:"experimental"`
 	MaxLabelNamesPerSeries                      int                 `yaml:"max_label_names_per_series" json:"max_label_names_per_series"`
 	MaxMetadataLength                           int                 `yaml:"max_metadata_length" json:"max_metadata_length"`
 	MaxNativeHistogramBuckets                   int                 `yaml:"max_native_histogram_buckets" json:"max_native_histogram_buckets"`
@@ -117,15 +132,22 @@ type Limits struct {
 	ReduceNativeHistogramOverMaxBuckets         bool                `yaml:"reduce_native_histogram_over_max_buckets" json:"reduce_native_histogram_over_max_buckets"`
 	CreationGracePeriod                         model.Duration      `yaml:"creation_grace_period" json:"creation_grace_period" category:"advanced"`
 	PastGracePeriod                             model.Duration      `yaml:"past_grace_period" json:"past_grace_period" category:"advanced"`
+	MinSampleInterval                           model.Duration      `yaml:"min_sample_interval" json:"min_sample_interval" category:"experimental"`
+	MaxTimestampSkewCorrection                  model.Duration      `yaml:"max_timestamp_skew_correction" json:"max_timestamp_skew_correction" category:"experimental"`
 	EnforceMetadataMetricName                   bool                `yaml:"enforce_metadata_metric_name" json:"enforce_metadata_metric_name" category:"advanced"`
 	IngestionTenantShardSize                    int                 `yaml:"ingestion_tenant_shard_size" json:"ingestion_tenant_shard_size"`
 	MetricRelabelConfigs                        []*relabel.Config   `yaml:"metric_relabel_configs,omitempty" json:"metric_relabel_configs,omitempty" doc:"nocli|description=List of metric relabel configurations. Note that in most situations, it is more effective to use metrics relabeling directly in the Prometheus server, e.g. remote_write.write_relabel_configs. Labels available during the relabeling phase and cleaned afterwards: __meta_tenant_id" category:"experimental"`
 	MetricRelabelingEnabled                     bool                `yaml:"metric_relabeling_enabled" json:"metric_relabeling_enabled" category:"experimental"`
 	ServiceOverloadStatusCodeOnRateLimitEnabled bool                `yaml:"service_overload_status_code_on_rate_limit_enabled" json:"service_overload_status_code_on_rate_limit_enabled" category:"experimental"`
+	MetricRegistry                              []*MetricDefinition `yaml:"metric_registry,omitempty" json:"metric_registry,omitempty" doc:"nocli|description=List of metrics declared by the tenant. Each entry has a name, an optional type and an optional list of allowed label names. When the list is not empty, the distributor checks incoming series and metadata against it and reports violations through the conformance report." category:"experimental"`
+	MetricRegistryEnforcementEnabled            bool                `yaml:"metric_registry_enforcement_enabled" json:"metric_registry_enforcement_enabled" category:"experimental"`
+	BlockedSeries                               []*BlockedSeries    `yaml:"blocked_series,omitempty" json:"blocked_series,omitempty" doc:"nocli|description=List of series selectors (match) of the series dropped by the distributor on the write path, before any other processing. The dropped samples and histograms are counted as discarded with the blocked_series reason. Useful to stop ingesting misbehaving series, for example a job suddenly emitting series at a huge volume." category:"experimental"`
 	// Ingester enforced limits.
 	// Series
 	MaxGlobalSeriesPerUser   int `yaml:"max_global_series_per_user" json:"max_global_series_per_user"`
 	MaxGlobalSeriesPerMetric int `yaml:"max_global_series_per_metric" json:"max_global_series_per_metric"`
+	// Series churn
+	MaxGlobalNewSeriesPerMinute int `yaml:"max_global_new_series_per_minute" json:"max_global_new_series_per_minute" category:"experimental"`
 	// Metadata
 	MaxGlobalMetricsWithMetadataPerUser int `yaml:"max_global_metadata_per_user" json:"max_global_metadata_per_user"`
 	MaxGlobalMetadataPerMetric          int `yaml:"max_global_metadata_per_metric" json:"max_global_metadata_per_metric"`
@@ -141,27 +163,31 @@ type Limits struct {
 	// Max allowed time window for out-of-order samples.
 	OutOfOrderTimeWindow                 model.Duration `yaml:"out_of_order_time_window" json:"out_of_order_time_window" category:"experimental"`
 	OutOfOrderBlocksExternalLabelEnabled bool           `yaml:"out_of_order_blocks_external_label_enabled" json:"out_of_order_blocks_external_label_enabled" category:"experimental"`
+	// Disable the TSDB write-ahead log.
+	WALDisabled bool `yaml:"wal_disabled" json:"wal_disabled" category:"experimental"`
 
 	// User defined label to give the option of subdividing specific metrics by another label
 	SeparateMetricsGroupLabel string `yaml:"separate_metrics_group_label" json:"separate_metrics_group_label" category:"experimental"`
 
 	// Querier enforced limits.
-	MaxChunksPerQuery                     int            `yaml:"max_fetched_chunks_per_query" json:"max_fetched_chunks_per_query"`
-	MaxEstimatedChunksPerQueryMultiplier  float64        `yaml:"max_estimated_fetched_chunks_per_query_multiplier" json:"max_estimated_fetched_chunks_per_query_multiplier" category:"experimental"`
-	MaxFetchedSeriesPerQuery              int            `yaml:"max_fetched_series_per_query" json:"max_fetched_series_per_query"`
-	MaxFetchedChunkBytesPerQuery          int            `yaml:"max_fetched_chunk_bytes_per_query" json:"max_fetched_chunk_bytes_per_query"`
-	MaxEstimatedMemoryConsumptionPerQuery uint64         `yaml:"max_estimated_memory_consumption_per_query" json:"max_estimated_memory_consumption_per_query" category:"experimental"`
-	MaxQueryLookback                      model.Duration `yaml:"max_query_lookback" json:"max_query_lookback"`
-	MaxPartialQueryLength                 model.Duration `yaml:"max_partial_query_length" json:"max_partial_query_length"`
-	MaxQueryParallelism                   int            `yaml:"max_query_parallelism" json:"max_query_parallelism"`
-	MaxLabelsQueryLength                  model.Duration `yaml:"max_labels_query_length" json:"max_labels_query_length"`
-	MaxCacheFreshness                     model.Duration `yaml:"max_cache_freshness" json:"max_cache_freshness" category:"advanced"`
-	MaxQueriersPerTenant                  int            `yaml:"max_queriers_per_tenant" json:"max_queriers_per_tenant"`
-	QueryShardingTotalShards              int            `yaml:"query_sharding_total_shards" json:"query_sharding_total_shards"`
-	QueryShardingMaxShardedQueries        int            `yaml:"query_sharding_max_sharded_queries" json:"query_sharding_max_sharded_queries"`
-	QueryShardingMaxRegexpSizeBytes       int            `yaml:"query_sharding_max_regexp_size_bytes" json:"query_sharding_max_regexp_size_bytes"`
-	SplitInstantQueriesByInterval         model.Duration `yaml:"split_instant_queries_by_interval" json:"split_instant_queries_by_interval" category:"experimental"`
-	QueryIngestersWithin                  model.Duration `yaml:"query_ingesters_within" json:"query_ingesters_within" category:"advanced"`
+	MaxChunksPerQuery                     int                    `yaml:"max_fetched_chunks_per_query" json:"max_fetched_chunks_per_query"`
+	MaxEstimatedChunksPerQueryMultiplier  float64                `yaml:"max_estimated_fetched_chunks_per_query_multiplier" json:"max_estimated_fetched_chunks_per_query_multiplier" category:"experimental"`
+	MaxFetchedSeriesPerQuery              int                    `yaml:"max_fetched_series_per_query" json:"max_fetched_series_per_query"`
+	MaxFetchedChunkBytesPerQuery          int                    `yaml:"max_fetched_chunk_bytes_per_query" json:"max_fetched_chunk_bytes_per_query"`
+	MaxEstimatedMemoryConsumptionPerQuery uint64                 `yaml:"max_estimated_memory_consumption_per_query" json:"max_estimated_memory_consumption_per_query" category:"experimental"`
+	MaxQueryLookback                      model.Duration         `yaml:"max_query_lookback" json:"max_query_lookback"`
+	MaxPartialQueryLength                 model.Duration         `yaml:"max_partial_query_length" json:"max_partial_query_length"`
+	MaxQueryParallelism                   int                    `yaml:"max_query_parallelism" json:"max_query_parallelism"`
+	MaxLabelsQueryLength                  model.Duration         `yaml:"max_labels_query_length" json:"max_labels_query_length"`
+	MaxLabelValuesPerRequest              int                    `yaml:"max_label_values_per_request" json:"max_label_values_per_request" category:"experimental"`
+	MaxCacheFreshness                     model.Duration         `yaml:"max_cache_freshness" json:"max_cache_freshness" category:"advanced"`
+	MaxQueriersPerTenant                  int                    `yaml:"max_queriers_per_tenant" json:"max_queriers_per_tenant"`
+	QueryShardingTotalShards              int                    `yaml:"query_sharding_total_shards" json:"query_sharding_total_shards"`
+	QueryShardingMaxShardedQueries        int                    `yaml:"query_sharding_max_sharded_queries" json:"query_sharding_max_sharded_queries"`
+	QueryShardingMaxRegexpSizeBytes       int                    `yaml:"query_sharding_max_regexp_size_bytes" json:"query_sharding_max_regexp_size_bytes"`
+	SplitInstantQueriesByInterval         model.Duration         `yaml:"split_instant_queries_by_interval" json:"split_instant_queries_by_interval" category:"experimental"`
+	QueryIngestersWithin                  model.Duration         `yaml:"query_ingesters_within" json:"query_ingesters_within" category:"advanced"`
+	DedupReplicaExternalLabels            flagext.StringSliceCSV `yaml:"dedup_replica_external_labels" json:"dedup_replica_external_labels" category:"experimental"`
 
 	// Query-frontend limits.
 	MaxTotalQueryLength                    model.Duration  `yaml:"max_total_query_length" json:"max_total_query_length"`
@@ -172,8 +198,14 @@ type Limits struct {
 	ResultsCacheTTLForErrors               model.Duration  `yaml:"results_cache_ttl_for_errors" json:"results_cache_ttl_for_errors" category:"experimental"`
 	ResultsCacheForUnalignedQueryEnabled   bool            `yaml:"cache_unaligned_requests" json:"cache_unaligned_requests" category:"advanced"`
 	MaxQueryExpressionSizeBytes            int             `yaml:"max_query_expression_size_bytes" json:"max_query_expression_size_bytes"`
+	MaxQueryBytesPerDay                    int             `yaml:"max_query_bytes_per_day" json:"max_query_bytes_per_day" category:"experimental"`
 	BlockedQueries                         []*BlockedQuery `yaml:"blocked_queries,omitempty" json:"blocked_queries,omitempty" doc:"nocli|description=List of queries to block." category:"experimental"`
 	AlignQueriesWithStep                   bool            `yaml:"align_queries_with_step" json:"align_queries_with_step"`
+	SplitQueriesByIntervalTimezone         string          `yaml:"split_queries_by_interval_timezone" json:"split_queries_by_interval_timezone" category:"experimental"`
+	MaxQueryPriority                       int             `yaml:"max_query_priority" json:"max_query_priority" category:"experimental"`
+
+	// PromQL experimental functions
+	EnabledPromQLExperimentalFunctions flagext.StringSliceCSV `yaml:"enabled_promql_experimental_functions" json:"enabled_promql_experimental_functions" category:"experimental"`
 
 	// Cardinality
 	CardinalityAnalysisEnabled                    bool `yaml:"cardinality_analysis_enabled" json:"cardinality_analysis_enabled"`
@@ -192,10 +224,16 @@ type Limits struct {
 	RulerMaxRulesPerRuleGroupByNamespace                  LimitsMap[int]         `yaml:"ruler_max_rules_per_rule_group_by_namespace" json:"ruler_max_rules_per_rule_group_by_namespace" category:"experimental"`
 	RulerMaxRuleGroupsPerTenantByNamespace                LimitsMap[int]         `yaml:"ruler_max_rule_groups_per_tenant_by_namespace" json:"ruler_max_rule_groups_per_tenant_by_namespace" category:"experimental"`
 	RulerProtectedNamespaces                              flagext.StringSliceCSV `yaml:"ruler_protected_namespaces" json:"ruler_protected_namespaces" category:"experimental"`
+	RulerNamespaceScopes                                  []*RulerNamespaceScope `yaml:"ruler_namespace_scopes,omitempty" json:"ruler_namespace_scopes,omitempty" doc:"nocli|description=List of scopes restricting the rule namespaces which can be accessed through the ruler's configuration API. Each scope has a name and the list of prefixes of the rule namespaces it's allowed to read and modify. A request carrying the X-Mimir-Ruler-Namespace-Scope header can only access the namespaces of its scope, including through the Prometheus rules and alerts APIs. Once the tenant has scopes, requests without the header are rejected, and the tenant configuration can only be deleted by a scope including the empty prefix. Mimir doesn't authenticate the header, which any client with the tenant's credentials can set to any scope: it must be set by a trusted authenticating proxy, which must also strip the header from the client requests." category:"experimental"`
 	RulerMaxIndependentRuleEvaluationConcurrencyPerTenant int64                  `yaml:"ruler_max_independent_rule_evaluation_concurrency_per_tenant" json:"ruler_max_independent_rule_evaluation_concurrency_per_tenant" category:"experimental"`
+	RulerEvaluationJitter                                 model.Duration         `yaml:"ruler_evaluation_jitter" json:"ruler_evaluation_jitter" category:"experimental"`
+	RulerMaxSeriesPerRule                                 int                    `yaml:"ruler_max_series_per_rule" json:"ruler_max_series_per_rule" category:"experimental"`
 
 	// Store-gateway.
-	StoreGatewayTenantShardSize int `yaml:"store_gateway_tenant_shard_size" json:"store_gateway_tenant_shard_size"`
+	StoreGatewayTenantShardSize        int            `yaml:"store_gateway_tenant_shard_size" json:"store_gateway_tenant_shard_size"`
+	StoreGatewayChunksRangeMaxGapBytes int            `yaml:"store_gateway_chunks_range_max_gap_bytes" json:"store_gateway_chunks_range_max_gap_bytes" category:"experimental"`
+	StoreGatewayChunksPrefetchBytes    int            `yaml:"store_gateway_chunks_prefetch_bytes" json:"store_gateway_chunks_prefetch_bytes" category:"experimental"`
+	StoreGatewayBlocksQueryLookback    model.Duration `yaml:"store_gateway_blocks_query_lookback" json:"store_gateway_blocks_query_lookback" category:"experimental"`
 
 	// Compactor.
 	CompactorBlocksRetentionPeriod        model.Duration `yaml:"compactor_blocks_retention_period" json:"compactor_blocks_retention_period"`
@@ -208,6 +246,8 @@ type Limits struct {
 	CompactorBlockUploadVerifyChunks      bool           `yaml:"compactor_block_upload_verify_chunks" json:"compactor_block_upload_verify_chunks"`
 	CompactorBlockUploadMaxBlockSizeBytes int64          `yaml:"compactor_block_upload_max_block_size_bytes" json:"compactor_block_upload_max_block_size_bytes" category:"advanced"`
 	CompactorInMemoryTenantMetaCacheSize  int            `yaml:"compactor_in_memory_tenant_meta_cache_size" json:"compactor_in_memory_tenant_meta_cache_size" category:"experimental" doc:"hidden"`
+	CompactorTenantPool                   string         `yaml:"compactor_tenant_pool" json:"compactor_tenant_pool" category:"experimental"`
+	CompactorFirstLevelOnlyUntil          string         `yaml:"compactor_first_level_only_until" json:"compactor_first_level_only_until" category:"experimental"`
 
 	// This config doesn't have a CLI flag registered here because they're registered in
 	// their own original config struct.
@@ -222,15 +262,18 @@ type Limits struct {
 	NotificationRateLimit               float64            `yaml:"alertmanager_notification_rate_limit" json:"alertmanager_notification_rate_limit"`
 	NotificationRateLimitPerIntegration LimitsMap[float64] `yaml:"alertmanager_notification_rate_limit_per_integration" json:"alertmanager_notification_rate_limit_per_integration"`
 
-	AlertmanagerMaxGrafanaConfigSizeBytes      int `yaml:"alertmanager_max_grafana_config_size_bytes" json:"alertmanager_max_grafana_config_size_bytes"`
-	AlertmanagerMaxConfigSizeBytes             int `yaml:"alertmanager_max_config_size_bytes" json:"alertmanager_max_config_size_bytes"`
-	AlertmanagerMaxSilencesCount               int `yaml:"alertmanager_max_silences_count" json:"alertmanager_max_silences_count"`
-	AlertmanagerMaxSilenceSizeBytes            int `yaml:"alertmanager_max_silence_size_bytes" json:"alertmanager_max_silence_size_bytes"`
-	AlertmanagerMaxTemplatesCount              int `yaml:"alertmanager_max_templates_count" json:"alertmanager_max_templates_count"`
-	AlertmanagerMaxTemplateSizeBytes           int `yaml:"alertmanager_max_template_size_bytes" json:"alertmanager_max_template_size_bytes"`
-	AlertmanagerMaxDispatcherAggregationGroups int `yaml:"alertmanager_max_dispatcher_aggregation_groups" json:"alertmanager_max_dispatcher_aggregation_groups"`
-	AlertmanagerMaxAlertsCount                 int `yaml:"alertmanager_max_alerts_count" json:"alertmanager_max_alerts_count"`
-	AlertmanagerMaxAlertsSizeBytes             int `yaml:"alertmanager_max_alerts_size_bytes" json:"alertmanager_max_alerts_size_bytes"`
+	AlertmanagerMaxGrafanaConfigSizeBytes          int            `yaml:"alertmanager_max_grafana_config_size_bytes" json:"alertmanager_max_grafana_config_size_bytes"`
+	AlertmanagerMaxConfigSizeBytes                 int            `yaml:"alertmanager_max_config_size_bytes" json:"alertmanager_max_config_size_bytes"`
+	AlertmanagerMaxSilencesCount                   int            `yaml:"alertmanager_max_silences_count" json:"alertmanager_max_silences_count"`
+	AlertmanagerMaxSilenceSizeBytes                int            `yaml:"alertmanager_max_silence_size_bytes" json:"alertmanager_max_silence_size_bytes"`
+	AlertmanagerMaxTemplatesCount                  int            `yaml:"alertmanager_max_templates_count" json:"alertmanager_max_templates_count"`
+	AlertmanagerMaxTemplateSizeBytes               int            `yaml:"alertmanager_max_template_size_bytes" json:"alertmanager_max_template_size_bytes"`
+	AlertmanagerMaxDispatcherAggregationGroups     int            `yaml:"alertmanager_max_dispatcher_aggregation_groups" json:"alertmanager_max_dispatcher_aggregation_groups"`
+	AlertmanagerMaxAlertsCount                     int            `yaml:"alertmanager_max_alerts_count" json:"alertmanager_max_alerts_count"`
+	AlertmanagerMaxAlertsSizeBytes                 int            `yaml:"alertmanager_max_alerts_size_bytes" json:"alertmanager_max_alerts_size_bytes"`
+	AlertmanagerMaxAlertsPerNotification           int            `yaml:"alertmanager_max_alerts_per_notification" json:"alertmanager_max_alerts_per_notification" category:"experimental"`
+	AlertmanagerMaxAlertsPerNotificationByReceiver LimitsMap[int] `yaml:"alertmanager_max_alerts_per_notification_by_receiver" json:"alertmanager_max_alerts_per_notification_by_receiver" category:"experimental"`
+	AlertmanagerAlertLabelValidationScheme         string         `yaml:"alertmanager_alert_label_validation_scheme" json:"alertmanager_alert_label_validation_scheme" category:"experimental"`
 
 	// OpenTelemetry
 	OTelMetricSuffixesEnabled                bool `yaml:"otel_metric_suffixes_enabled" json:"otel_metric_suffixes_enabled" category:"advanced"`
@@ -258,6 +301,7 @@ func (l *Limits) RegisterFlags(f *flag.FlagSet) {
 	f.Var(&l.DropLabels, "distributor.drop-label", "This flag can be used to specify label names that to drop during sample ingestion within the distributor and can be repeated in order to drop multiple labels.")
 	f.IntVar(&l.MaxLabelNameLength, MaxLabelNameLengthFlag, 1024, "Maximum length accepted for label names")
 	f.IntVar(&l.MaxLabelValueLength, MaxLabelValueLengthFlag, 2048, "Maximum length accepted for label value. This setting also applies to the metric name")
+	f.BoolVar(&l.TruncateLabelValueOverMaxLength, TruncateLabelValueOverMaxLengthFlag, false, "Whether to truncate or reject series with label values longer than the configured limit. Truncated label values end with the '(truncated:<hash>)' marker, where <hash> is a short hash of the original label value. Metric names longer than the limit are always rejected.")
 	f.IntVar(&l.MaxLabelNamesPerSeries, MaxLabelNamesPerSeriesFlag, 30, "Maximum number of label names per series.")
 	f.IntVar(&l.MaxMetadataLength, MaxMetadataLengthFlag, 1024, "Maximum length accepted for metric metadata. Metadata refers to Metric Name, HELP and UNIT. Longer metadata is dropped except for HELP which is truncated.")
 	f.IntVar(&l.MaxNativeHistogramBuckets, maxNativeHistogramBucketsFlag, 0, "Maximum number of buckets per native histogram sample. 0 to disable the limit.")
@@ -266,14 +310,18 @@ func (l *Limits) RegisterFlags(f *flag.FlagSet) {
 	_ = l.CreationGracePeriod.Set("10m")
 	f.Var(&l.CreationGracePeriod, CreationGracePeriodFlag, "Controls how far into the future incoming samples and exemplars are accepted compared to the wall clock. Any sample or exemplar will be rejected if its timestamp is greater than '(now + creation_grace_period)'. This configuration is enforced in the distributor and ingester.")
 	f.Var(&l.PastGracePeriod, PastGracePeriodFlag, "Controls how far into the past incoming samples and exemplars are accepted compared to the wall clock. Any sample or exemplar will be rejected if its timestamp is lower than '(now - OOO window - past_grace_period)'. This configuration is enforced in the distributor and ingester. 0 to disable.")
+	f.Var(&l.MinSampleInterval, MinSampleIntervalFlag, "Minimum interval between the samples of a series. The samples closer than the interval to the last sample appended to the same series are dropped by the ingester, including across write requests. 0 to disable.")
+	f.Var(&l.MaxTimestampSkewCorrection, MaxTimestampSkewCorrectionFlag, "Maximum clock skew corrected by the distributor. The samples and histograms whose timestamp is outside of the accepted time window, configured by -"+CreationGracePeriodFlag+" and -"+PastGracePeriodFlag+", by no more than this duration are not rejected: the timestamps of all the samples of their series are shifted by the same offset, so that they fit into the accepted time window. 0 to disable.")
 	f.BoolVar(&l.EnforceMetadataMetricName, "validation.enforce-metadata-metric-name", true, "Enforce every metadata has a metric name.")
 	f.BoolVar(&l.MetricRelabelingEnabled, "distributor.metric-relabeling-enabled", true, "Enable metric relabeling for the tenant. This configuration option can be used to forcefully disable metric relabeling on a per-tenant basis.")
+	f.BoolVar(&l.MetricRegistryEnforcementEnabled, "distributor.metric-registry-enforcement-enabled", false, "If enabled, series and metadata which don't conform to the tenant's metric registry are discarded. If disabled, violations are only reported. This option has no effect when the tenant's metric registry is empty.")
 	f.BoolVar(&l.ServiceOverloadStatusCodeOnRateLimitEnabled, "distributor.service-overload-status-code-on-rate-limit-enabled", false, "If enabled, rate limit errors will be reported to the client with HTTP status code 529 (Service is overloaded). If disabled, status code 429 (Too Many Requests) is used. Enabling -distributor.retry-after-header.enabled before utilizing this option is strongly recommended as it helps prevent premature request retries by the client.")
 	f.BoolVar(&l.OTelMetricSuffixesEnabled, "distributor.otel-metric-suffixes-enabled", false, "Whether to enable automatic suffixes to names of metrics ingested through OTLP.")
 	f.BoolVar(&l.OTelCreatedTimestampZeroIngestionEnabled, "distributor.otel-created-timestamp-zero-ingestion-enabled", false, "Whether to enable translation of OTel start timestamps to Prometheus zero samples in the OTLP endpoint.")
 
 	f.IntVar(&l.MaxGlobalSeriesPerUser, MaxSeriesPerUserFlag, 150000, "The maximum number of in-memory series per tenant, across the cluster before replication. 0 to disable.")
 	f.IntVar(&l.MaxGlobalSeriesPerMetric, MaxSeriesPerMetricFlag, 0, "The maximum number of in-memory series per metric name, across the cluster before replication. 0 to disable.")
+	f.IntVar(&l.MaxGlobalNewSeriesPerMinute, MaxNewSeriesPerMinuteFlag, 0, "The maximum number of new in-memory series that can be created per minute for a tenant, across the cluster before replication. It limits the series churn independently of the total number of series. 0 to disable.")
 
 	f.IntVar(&l.MaxGlobalMetricsWithMetadataPerUser, MaxMetadataPerUserFlag, 0, "The maximum number of in-memory metrics with metadata per tenant, across the cluster. 0 to disable.")
 	f.IntVar(&l.MaxGlobalMetadataPerMetric, MaxMetadataPerMetricFlag, 0, "The maximum number of metadata per metric, across the cluster. 0 to disable.")
@@ -284,6 +332,7 @@ func (l *Limits) RegisterFlags(f *flag.FlagSet) {
 	f.BoolVar(&l.NativeHistogramsIngestionEnabled, "ingester.native-histograms-ingestion-enabled", false, "Enable ingestion of native histogram samples. If false, native histogram samples are ignored without an error. To query native histograms with query-sharding enabled make sure to set -query-frontend.query-result-response-format to 'protobuf'.")
 	f.BoolVar(&l.OOONativeHistogramsIngestionEnabled, "ingester.ooo-native-histograms-ingestion-enabled", false, "Enable experimental out-of-order native histogram ingestion. This only takes effect if the `-ingester.out-of-order-time-window` value is greater than zero and if `-ingester.native-histograms-ingestion-enabled = true`")
 	f.BoolVar(&l.OutOfOrderBlocksExternalLabelEnabled, "ingester.out-of-order-blocks-external-label-enabled", false, "Whether the shipper should label out-of-order blocks with an external label before uploading them. Setting this label will compact out-of-order blocks separately from non-out-of-order blocks")
+	f.BoolVar(&l.WALDisabled, "ingester.wal-disabled", false, "Disable the TSDB write-ahead log for the tenant. Samples not yet compacted into a block are lost when the ingester restarts or crashes, so this should only be enabled for ephemeral tenants, such as load-testing tenants. The setting is applied when the tenant's TSDB is opened.")
 
 	f.StringVar(&l.SeparateMetricsGroupLabel, "validation.separate-metrics-group-label", "", "Label used to define the group label for metrics separation. For each write request, the group is obtained from the first non-empty group label from the first timeseries in the incoming list of timeseries. Specific distributor and ingester metrics will be further separated adding a 'group' label with group label's value. Currently applies to the following metrics: cortex_discarded_samples_total")
 
@@ -296,6 +345,7 @@ func (l *Limits) RegisterFlags(f *flag.FlagSet) {
 	f.Var(&l.MaxQueryLookback, "querier.max-query-lookback", "Limit how long back data (series and metadata) can be queried, up until <lookback> duration ago. This limit is enforced in the query-frontend, querier and ruler for instant, range and remote read queries. For metadata queries like series, label names, label values queries the limit is enforced in the querier and ruler. If the requested time range is outside the allowed range, the request will not fail but will be manipulated to only query data within the allowed time range. 0 to disable.")
 	f.IntVar(&l.MaxQueryParallelism, "querier.max-query-parallelism", 14, "Maximum number of split (by time) or partial (by shard) queries that will be scheduled in parallel by the query-frontend for a single input query. This limit is introduced to have a fairer query scheduling and avoid a single query over a large time range saturating all available queriers.")
 	f.Var(&l.MaxLabelsQueryLength, "store.max-labels-query-length", "Limit the time range (end - start time) of series, label names and values queries. This limit is enforced in the querier. If the requested time range is outside the allowed range, the request will not fail but will be manipulated to only query data within the allowed time range. 0 to disable.")
+	f.IntVar(&l.MaxLabelValuesPerRequest, MaxLabelValuesPerRequestFlag, 0, "Maximum number of values an ingester can return for a single label values request. The request fails once the limit is exceeded, before the ingester finishes reading all the values. This limit is enforced in the ingester. 0 to disable.")
 	f.IntVar(&l.LabelNamesAndValuesResultsMaxSizeBytes, "querier.label-names-and-values-results-max-size-bytes", 400*1024*1024, "Maximum size in bytes of distinct label names and values. When querier receives response from ingester, it merges the response with responses from other ingesters. This maximum size limit is applied to the merged(distinct) results. If the limit is reached, an error is returned.")
 	f.IntVar(&l.ActiveSeriesResultsMaxSizeBytes, "querier.active-series-results-max-size-bytes", 400*1024*1024, "Maximum size of an active series or active native histogram series request result shard in bytes. 0 to disable.")
 	f.BoolVar(&l.CardinalityAnalysisEnabled, "querier.cardinality-analysis-enabled", false, "Enables endpoints used for cardinality analysis.")
@@ -310,6 +360,7 @@ func (l *Limits) RegisterFlags(f *flag.FlagSet) {
 	f.Var(&l.SplitInstantQueriesByInterval, "query-frontend.split-instant-queries-by-interval", "Split instant queries by an interval and execute in parallel. 0 to disable it.")
 	_ = l.QueryIngestersWithin.Set("13h")
 	f.Var(&l.QueryIngestersWithin, QueryIngestersWithinFlag, "Maximum lookback beyond which queries are not sent to ingester. 0 means all queries are sent to ingester.")
+	f.Var(&l.DedupReplicaExternalLabels, "querier.dedup-replica-external-labels", "Comma-separated list of block external labels identifying the replica which wrote a block, such as the replica labels of blocks uploaded by HA Thanos sidecars. If set, series queried from blocks with different replica label values are deduplicated at query time, picking the samples of one replica at a time and switching replica only when there are gaps in the data.")
 
 	_ = l.RulerEvaluationDelay.Set("1m")
 	f.Var(&l.RulerEvaluationDelay, "ruler.evaluation-delay-duration", "Duration to delay the evaluation of rules to ensure the underlying metrics have been pushed.")
@@ -331,6 +382,8 @@ func (l *Limits) RegisterFlags(f *flag.FlagSet) {
 	f.Var(&l.RulerMaxRuleGroupsPerTenantByNamespace, "ruler.max-rule-groups-per-tenant-by-namespace", "Maximum number of rule groups per tenant by namespace. Value is a map, where each key is the namespace and value is the number of rule groups allowed in the namespace (int). On the command line, this map is given in a JSON format. The number of rule groups specified has the same meaning as -ruler.max-rule-groups-per-tenant, but only applies for the specific namespace. If specified, it supersedes -ruler.max-rule-groups-per-tenant.")
 	f.Var(&l.RulerProtectedNamespaces, "ruler.protected-namespaces", "List of namespaces that are protected from modification unless a special HTTP header is used. If a namespace is protected, it can only be read, not modified via the ruler's configuration API. The value is a list of strings, where each string is a namespace name. On the command line, this list is given as a comma-separated list.")
 	f.Int64Var(&l.RulerMaxIndependentRuleEvaluationConcurrencyPerTenant, "ruler.max-independent-rule-evaluation-concurrency-per-tenant", 4, "Maximum number of independent rules that can run concurrently for each tenant. Depends on ruler.max-independent-rule-evaluation-concurrency being greater than 0. Ideally this flag should be a lower value. 0 to disable.")
+	f.Var(&l.RulerEvaluationJitter, "ruler.evaluation-jitter", "Maximum delay applied to the evaluations of each tenant's rule group, to spread the queries of rule groups with the same evaluation interval over time. Each rule group is delayed by a deterministic offset, computed hashing the rule group, within the jitter. The evaluation timestamp is not changed. The jitter is capped to half of the rule group's evaluation interval. 0 to disable.")
+	f.IntVar(&l.RulerMaxSeriesPerRule, "ruler.max-series-per-rule", 0, "Maximum number of series a single rule can produce per evaluation: the series written by a recording rule, or the alerts of an alerting rule. If a rule exceeds the limit, its evaluation fails, no series are written, and the error is reported in the rule's health and last error. The limit is applied when the tenant's rule groups are loaded, and the rule groups are reloaded within the ruler poll interval when the limit changes. If a rule group has a lower limit, the rule group's limit is used. 0 to disable.")
 
 	f.Var(&l.CompactorBlocksRetentionPeriod, "compactor.blocks-retention-period", "Delete blocks containing samples older than the specified retention period. Also used by query-frontend to avoid querying beyond the retention period by instant, range or remote read queries. 0 to disable.")
 	f.IntVar(&l.CompactorSplitAndMergeShards, "compactor.split-and-merge-shards", 0, "The number of shards to use when splitting blocks. 0 to disable splitting.")
@@ -342,6 +395,8 @@ func (l *Limits) RegisterFlags(f *flag.FlagSet) {
 	f.BoolVar(&l.CompactorBlockUploadValidationEnabled, "compactor.block-upload-validation-enabled", true, "Enable block upload validation for the tenant.")
 	f.BoolVar(&l.CompactorBlockUploadVerifyChunks, "compactor.block-upload-verify-chunks", true, "Verify chunks when uploading blocks via the upload API for the tenant.")
 	f.Int64Var(&l.CompactorBlockUploadMaxBlockSizeBytes, "compactor.block-upload-max-block-size-bytes", 0, "Maximum size in bytes of a block that is allowed to be uploaded or validated. 0 = no limit.")
+	f.StringVar(&l.CompactorTenantPool, "compactor.tenant-pool", "", "Name of the compactors pool compacting the tenant's blocks. The tenant is only compacted by the compactors configured with the same -compactor.ring.pool, isolating its compaction from the other tenants. An empty value means the default pool.")
+	f.StringVar(&l.CompactorFirstLevelOnlyUntil, compactorFirstLevelOnlyUntilFlag, "", "Date (YYYY-MM-DD) or RFC3339 timestamp until which the compactor only runs the compaction jobs of the first block range for the tenant, merging the most recent blocks but not merging them with larger blocks, for example while backfilled blocks are still being verified. Empty to disable.")
 	f.IntVar(&l.CompactorInMemoryTenantMetaCacheSize, "compactor.in-memory-tenant-meta-cache-size", 0, "Size of per-tenant in-memory cache for parsed meta.json files. This is useful when meta.json files are big and parsing is expensive. Small meta.json files are not cached. 0 means this cache is disabled.")
 
 	// Query-frontend.
@@ -356,10 +411,18 @@ func (l *Limits) RegisterFlags(f *flag.FlagSet) {
 	f.Var(&l.ResultsCacheTTLForErrors, "query-frontend.results-cache-ttl-for-errors", "Time to live duration for cached non-transient errors")
 	f.BoolVar(&l.ResultsCacheForUnalignedQueryEnabled, "query-frontend.cache-unaligned-requests", false, "Cache requests that are not step-aligned.")
 	f.IntVar(&l.MaxQueryExpressionSizeBytes, MaxQueryExpressionSizeBytesFlag, 0, "Max size of the raw query, in bytes. This limit is enforced by the query-frontend for instant, range and remote read queries. 0 to not apply a limit to the size of the query.")
+	f.IntVar(&l.MaxQueryBytesPerDay, MaxQueryBytesPerDayFlag, 0, "Maximum number of chunk and index bytes that the tenant's instant, range and remote read queries can fetch per day, in UTC. Once the budget is exhausted, the query-frontend rejects the tenant's queries until the end of the day. Each query-frontend tracks the fetched bytes independently and in memory, so the budget is enforced per query-frontend replica, rather than cluster-wide, and it's reset when the query-frontend restarts. Requires -query-frontend.query-stats-enabled. 0 to disable.")
+	l.EnabledPromQLExperimentalFunctions = []string{AllPromQLExperimentalFunctions}
+	f.Var(&l.EnabledPromQLExperimentalFunctions, EnabledPromQLExperimentalFunctionsFlag, "Comma-separated list of experimental PromQL functions and aggregations, such as sort_by_label or limitk, that the tenant's queries can use. Set to 'all' to enable all of them, or to an empty value to disable all of them. This limit is enforced by the query-frontend, and requires -querier.promql-experimental-functions-enabled.")
 	f.BoolVar(&l.AlignQueriesWithStep, alignQueriesWithStepFlag, false, "Mutate incoming queries to align their start and end with their step to improve result caching.")
+	f.StringVar(&l.SplitQueriesByIntervalTimezone, splitQueriesByIntervalTimezoneFlag, "", "IANA timezone name (for example, Europe/Berlin) used to align the range queries split boundaries, and the results cache extents, to the tenant's local midnight. When empty, boundaries are aligned to UTC. This setting is ignored for queries spanning tenants with different timezones.")
+	f.IntVar(&l.MaxQueryPriority, maxQueryPriorityFlag, 0, "Maximum priority the tenant's queries can be assigned through the header configured with -query-frontend.query-priority-header. Higher priorities are capped to this value, while lower priorities, which are useful to deprioritize background queries, are always allowed.")
 
 	// Store-gateway.
 	f.IntVar(&l.StoreGatewayTenantShardSize, "store-gateway.tenant-shard-size", 0, "The tenant's shard size, used when store-gateway sharding is enabled. Value of 0 disables shuffle sharding for the tenant, that is all tenant blocks are sharded across all store-gateway replicas.")
+	f.IntVar(&l.StoreGatewayChunksRangeMaxGapBytes, "store-gateway.chunks-range-max-gap-bytes", 0, "Max size - in bytes - of a gap for which the store-gateway coalesces together two chunks byte ranges into a single GET object request. Higher values read more unneeded bytes but issue fewer requests to the object storage. 0 to use -blocks-storage.bucket-store.partitioner-max-gap-bytes.")
+	f.IntVar(&l.StoreGatewayChunksPrefetchBytes, "store-gateway.chunks-prefetch-bytes", 0, "Number of additional bytes the store-gateway reads after the end of each chunks byte range, to avoid a further GET object request when the size of the last chunk of the range has been underestimated. 0 to disable.")
+	f.Var(&l.StoreGatewayBlocksQueryLookback, "store-gateway.blocks-query-lookback", "Limit how far back in time the store-gateway queries the blocks of the tenant. Blocks whose time range is entirely older than the lookback are not queried, even if they still exist in the object storage, for example because they're awaiting deletion after the retention period. The chunks older than the lookback are not returned from the blocks straddling it. 0 to disable.")
 
 	// Alertmanager.
 	f.Var(&l.AlertmanagerReceiversBlockCIDRNetworks, "alertmanager.receivers-firewall-block-cidr-networks", "Comma-separated list of network CIDRs to block in Alertmanager receiver integrations.")
@@ -381,6 +444,12 @@ func (l *Limits) RegisterFlags(f *flag.FlagSet) {
 	f.IntVar(&l.AlertmanagerMaxDispatcherAggregationGroups, "alertmanager.max-dispatcher-aggregation-groups", 0, "Maximum number of aggregation groups in Alertmanager's dispatcher that a tenant can have. Each active aggregation group uses single goroutine. When the limit is reached, dispatcher will not dispatch alerts that belong to additional aggregation groups, but existing groups will keep working properly. 0 = no limit.")
 	f.IntVar(&l.AlertmanagerMaxAlertsCount, "alertmanager.max-alerts-count", 0, "Maximum number of alerts that a single tenant can have. Inserting more alerts will fail with a log message and metric increment. 0 = no limit.")
 	f.IntVar(&l.AlertmanagerMaxAlertsSizeBytes, "alertmanager.max-alerts-size-bytes", 0, "Maximum total size of alerts that a single tenant can have, alert size is the sum of the bytes of its labels, annotations and generatorURL. Inserting more alerts will fail with a log message and metric increment. 0 = no limit.")
+	f.IntVar(&l.AlertmanagerMaxAlertsPerNotification, "alertmanager.max-alerts-per-notification", 0, "Maximum number of alerts sent in a single notification by the Alertmanager Slack and webhook integrations. Notifications with more alerts are split into multiple messages, each one counting towards the notification rate limit, so that they're not truncated or rejected by the receiver because of their size. The notifications of the other integrations aren't split, because they deduplicate the notifications by group key. 0 = no limit.")
+	if !l.AlertmanagerMaxAlertsPerNotificationByReceiver.IsInitialized() {
+		l.AlertmanagerMaxAlertsPerNotificationByReceiver = NewLimitsMap[int](nil)
+	}
+	f.Var(&l.AlertmanagerMaxAlertsPerNotificationByReceiver, "alertmanager.max-alerts-per-notification-by-receiver", "Maximum number of alerts sent in a single notification by receiver. Value is a map, where each key is the receiver name and value is the number of alerts (int). On the command line, this map is given in a JSON format. The number of alerts specified has the same meaning as -alertmanager.max-alerts-per-notification, but only applies for the specific receiver, so that each route can have its own limit. If specified, it supersedes -alertmanager.max-alerts-per-notification.")
+	f.StringVar(&l.AlertmanagerAlertLabelValidationScheme, "alertmanager.alert-label-validation-scheme", AlertLabelValidationUTF8, fmt.Sprintf("Validation of the label names and values of the alerts sent by the ruler and received by the Alertmanager API. Supported values are: %s. %q requires label names to match the Prometheus naming rules. %q only requires label names and values to be valid UTF-8, and is enforced by the Alertmanager only if -alertmanager.utf8-strict-mode-enabled is enabled.", strings.Join(alertLabelValidationSchemes, ", "), AlertLabelValidationLegacy, AlertLabelValidationUTF8))
 
 	// Ingest storage.
 	f.StringVar(&l.IngestStorageReadConsistency, "ingest-storage.read-consistency", api.ReadConsistencyEventual, fmt.Sprintf("The default consistency level to enforce for queries when using the ingest storage. Supports values: %s.", strings.Join(api.ReadConsistencies, ", ")))
@@ -414,6 +483,7 @@ func (l *Limits) unmarshal(decode func(any) error) error {
 		l.NotificationRateLimitPerIntegration = defaultLimits.NotificationRateLimitPerIntegration.Clone()
 		l.RulerMaxRulesPerRuleGroupByNamespace = defaultLimits.RulerMaxRulesPerRuleGroupByNamespace.Clone()
 		l.RulerMaxRuleGroupsPerTenantByNamespace = defaultLimits.RulerMaxRuleGroupsPerTenantByNamespace.Clone()
+		l.AlertmanagerMaxAlertsPerNotificationByReceiver = defaultLimits.AlertmanagerMaxAlertsPerNotificationByReceiver.Clone()
 	}
 
 	// Decode into a reflection-crafted struct that has fields for the extensions.
@@ -451,6 +521,18 @@ func (l *Limits) validate() error {
 		}
 	}
 
+	if err := validateMetricRegistry(l.MetricRegistry); err != nil {
+		return err
+	}
+
+	if err := validateBlockedSeries(l.BlockedSeries); err != nil {
+		return err
+	}
+
+	if err := validateRulerNamespaceScopes(l.RulerNamespaceScopes); err != nil {
+		return err
+	}
+
 	if l.MaxEstimatedChunksPerQueryMultiplier < 1 && l.MaxEstimatedChunksPerQueryMultiplier != 0 {
 		return errInvalidMaxEstimatedChunksPerQueryMultiplier
 	}
@@ -459,6 +541,18 @@ func (l *Limits) validate() error {
 		return errInvalidIngestStorageReadConsistency
 	}
 
+	if !util.StringsContain(alertLabelValidationSchemes, l.AlertmanagerAlertLabelValidationScheme) {
+		return errInvalidAlertLabelValidationScheme
+	}
+
+	if _, err := time.LoadLocation(l.SplitQueriesByIntervalTimezone); err != nil {
+		return fmt.Errorf("invalid value for -%s: %w", splitQueriesByIntervalTimezoneFlag, err)
+	}
+
+	if _, err := parseCompactorFirstLevelOnlyUntil(l.CompactorFirstLevelOnlyUntil); err != nil {
+		return fmt.Errorf("invalid value for -%s: %w", compactorFirstLevelOnlyUntilFlag, err)
+	}
+
 	return nil
 }
 
@@ -581,6 +675,12 @@ func (o *Overrides) MaxLabelValueLength(userID string) int {
 	return o.getOverridesForUser(userID).MaxLabelValueLength
 }
 
+// TruncateLabelValueOverMaxLength returns whether to truncate or reject
+// series with label values longer than the configured limit.
+func (o *Overrides) TruncateLabelValueOverMaxLength(userID string) bool {
+	return o.getOverridesForUser(userID).TruncateLabelValueOverMaxLength
+}
+
 // MaxLabelNamesPerSeries returns maximum number of label/value pairs timeseries.
 func (o *Overrides) MaxLabelNamesPerSeries(userID string) int {
 	return o.getOverridesForUser(userID).MaxLabelNamesPerSeries
@@ -616,6 +716,18 @@ func (o *Overrides) PastGracePeriod(userID string) time.Duration {
 	return time.Duration(o.getOverridesForUser(userID).PastGracePeriod)
 }
 
+// MinSampleInterval returns the minimum interval between the samples of a series.
+// Zero means disabled.
+func (o *Overrides) MinSampleInterval(userID string) time.Duration {
+	return time.Duration(o.getOverridesForUser(userID).MinSampleInterval)
+}
+
+// MaxTimestampSkewCorrection returns the maximum clock skew corrected by the distributor outside of the accepted
+// time window. Zero means disabled.
+func (o *Overrides) MaxTimestampSkewCorrection(userID string) time.Duration {
+	return time.Duration(o.getOverridesForUser(userID).MaxTimestampSkewCorrection)
+}
+
 // MaxGlobalSeriesPerUser returns the maximum number of series a user is allowed to store across the cluster.
 func (o *Overrides) MaxGlobalSeriesPerUser(userID string) int {
 	return o.getOverridesForUser(userID).MaxGlobalSeriesPerUser
@@ -626,6 +738,12 @@ func (o *Overrides) MaxGlobalSeriesPerMetric(userID string) int {
 	return o.getOverridesForUser(userID).MaxGlobalSeriesPerMetric
 }
 
+// MaxGlobalNewSeriesPerMinute returns the maximum number of new series that can be created per minute for a tenant,
+// across the cluster.
+func (o *Overrides) MaxGlobalNewSeriesPerMinute(userID string) int {
+	return o.getOverridesForUser(userID).MaxGlobalNewSeriesPerMinute
+}
+
 func (o *Overrides) MaxChunksPerQuery(userID string) int {
 	return o.getOverridesForUser(userID).MaxChunksPerQuery
 }
@@ -647,6 +765,11 @@ func (o *Overrides) MaxFetchedChunkBytesPerQuery(userID string) int {
 	return o.getOverridesForUser(userID).MaxFetchedChunkBytesPerQuery
 }
 
+// MaxLabelValuesPerRequest returns the maximum number of values an ingester can return for a single label values request.
+func (o *Overrides) MaxLabelValuesPerRequest(userID string) int {
+	return o.getOverridesForUser(userID).MaxLabelValuesPerRequest
+}
+
 // MaxEstimatedMemoryConsumptionPerQuery returns the maximum allowed estimated memory consumption of a single query.
 // This is only effective when using Mimir's query engine (not Prometheus' engine).
 func (o *Overrides) MaxEstimatedMemoryConsumptionPerQuery(userID string) uint64 {
@@ -678,6 +801,12 @@ func (o *Overrides) BlockedQueries(userID string) []*BlockedQuery {
 	return o.getOverridesForUser(userID).BlockedQueries
 }
 
+// EnabledPromQLExperimentalFunctions returns the experimental PromQL functions and aggregations the tenant's queries can use.
+// AllPromQLExperimentalFunctions enables all of them.
+func (o *Overrides) EnabledPromQLExperimentalFunctions(userID string) []string {
+	return o.getOverridesForUser(userID).EnabledPromQLExperimentalFunctions
+}
+
 // MaxLabelsQueryLength returns the limit of the length (in time) of a label names or values request.
 func (o *Overrides) MaxLabelsQueryLength(userID string) time.Duration {
 	return time.Duration(o.getOverridesForUser(userID).MaxLabelsQueryLength)
@@ -731,6 +860,27 @@ func (o *Overrides) QueryIngestersWithin(userID string) time.Duration {
 	return time.Duration(o.getOverridesForUser(userID).QueryIngestersWithin)
 }
 
+// DedupReplicaExternalLabels returns the block external labels identifying the replica which wrote a block.
+// Series queried from blocks with different replicas are deduplicated at query time.
+func (o *Overrides) DedupReplicaExternalLabels(userID string) []string {
+	return o.getOverridesForUser(userID).DedupReplicaExternalLabels
+}
+
+// MetricRegistry returns the metrics declared by the tenant.
+func (o *Overrides) MetricRegistry(userID string) []*MetricDefinition {
+	return o.getOverridesForUser(userID).MetricRegistry
+}
+
+// MetricRegistryEnforcementEnabled returns whether series and metadata not conforming to the tenant's metric registry should be discarded.
+func (o *Overrides) MetricRegistryEnforcementEnabled(userID string) bool {
+	return o.getOverridesForUser(userID).MetricRegistryEnforcementEnabled
+}
+
+// BlockedSeries returns the series selectors of the tenant's series dropped by the distributor.
+func (o *Overrides) BlockedSeries(userID string) []*BlockedSeries {
+	return o.getOverridesForUser(userID).BlockedSeries
+}
+
 // EnforceMetadataMetricName whether to enforce the presence of a metric name on metadata.
 func (o *Overrides) EnforceMetadataMetricName(userID string) bool {
 	return o.getOverridesForUser(userID).EnforceMetadataMetricName
@@ -770,6 +920,11 @@ func (o *Overrides) OutOfOrderBlocksExternalLabelEnabled(userID string) bool {
 	return o.getOverridesForUser(userID).OutOfOrderBlocksExternalLabelEnabled
 }
 
+// WALDisabled returns whether the TSDB write-ahead log is disabled for the user.
+func (o *Overrides) WALDisabled(userID string) bool {
+	return o.getOverridesForUser(userID).WALDisabled
+}
+
 // SeparateMetricsGroupLabel returns the custom label used to separate specific metrics
 func (o *Overrides) SeparateMetricsGroupLabel(userID string) string {
 	return o.getOverridesForUser(userID).SeparateMetricsGroupLabel
@@ -785,10 +940,35 @@ func (o *Overrides) CompactorTenantShardSize(userID string) int {
 	return o.getOverridesForUser(userID).CompactorTenantShardSize
 }
 
+// CompactorTenantPool returns the name of the compactors pool compacting the tenant's blocks. Empty = default pool.
+func (o *Overrides) CompactorTenantPool(userID string) string {
+	return o.getOverridesForUser(userID).CompactorTenantPool
+}
+
 func (o *Overrides) CompactorInMemoryTenantMetaCacheSize(userID string) int {
 	return o.getOverridesForUser(userID).CompactorInMemoryTenantMetaCacheSize
 }
 
+// CompactorFirstLevelOnlyUntil returns the time until which only the first level compaction is run for the tenant.
+// The zero time is returned if the setting is disabled.
+func (o *Overrides) CompactorFirstLevelOnlyUntil(userID string) time.Time {
+	// The value has already been validated when loading the limits.
+	until, _ := parseCompactorFirstLevelOnlyUntil(o.getOverridesForUser(userID).CompactorFirstLevelOnlyUntil)
+	return until
+}
+
+// parseCompactorFirstLevelOnlyUntil parses a date (YYYY-MM-DD) or a RFC3339 timestamp. An empty string
+// is parsed as the zero time.
+func parseCompactorFirstLevelOnlyUntil(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.DateOnly, s); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
 // EvaluationDelay returns the rules evaluation delay for a given user.
 func (o *Overrides) EvaluationDelay(userID string) time.Duration {
 	return time.Duration(o.getOverridesForUser(userID).RulerEvaluationDelay)
@@ -908,6 +1088,11 @@ func (o *Overrides) RulerProtectedNamespaces(userID string) []string {
 	return o.getOverridesForUser(userID).RulerProtectedNamespaces
 }
 
+// RulerNamespaceScopes returns the scopes restricting the rule namespaces accessible through the ruler's configuration API.
+func (o *Overrides) RulerNamespaceScopes(userID string) []*RulerNamespaceScope {
+	return o.getOverridesForUser(userID).RulerNamespaceScopes
+}
+
 // RulerRecordingRulesEvaluationEnabled returns whether the recording rules evaluation is enabled for a given user.
 func (o *Overrides) RulerRecordingRulesEvaluationEnabled(userID string) bool {
 	return o.getOverridesForUser(userID).RulerRecordingRulesEvaluationEnabled
@@ -928,11 +1113,38 @@ func (o *Overrides) RulerMaxIndependentRuleEvaluationConcurrencyPerTenant(userID
 	return o.getOverridesForUser(userID).RulerMaxIndependentRuleEvaluationConcurrencyPerTenant
 }
 
+// RulerEvaluationJitter returns the maximum delay applied to the evaluations of the user's rule groups.
+func (o *Overrides) RulerEvaluationJitter(userID string) time.Duration {
+	return time.Duration(o.getOverridesForUser(userID).RulerEvaluationJitter)
+}
+
+// RulerMaxSeriesPerRule returns the maximum number of series a single rule of the user can produce per evaluation.
+func (o *Overrides) RulerMaxSeriesPerRule(userID string) int {
+	return o.getOverridesForUser(userID).RulerMaxSeriesPerRule
+}
+
 // StoreGatewayTenantShardSize returns the store-gateway shard size for a given user.
 func (o *Overrides) StoreGatewayTenantShardSize(userID string) int {
 	return o.getOverridesForUser(userID).StoreGatewayTenantShardSize
 }
 
+// StoreGatewayChunksRangeMaxGapBytes returns the max gap for which the store-gateway coalesces two chunks byte ranges of a given user.
+// 0 means the store-gateway default is used.
+func (o *Overrides) StoreGatewayChunksRangeMaxGapBytes(userID string) int {
+	return o.getOverridesForUser(userID).StoreGatewayChunksRangeMaxGapBytes
+}
+
+// StoreGatewayChunksPrefetchBytes returns the number of additional bytes the store-gateway reads after each chunks byte range of a given user.
+func (o *Overrides) StoreGatewayChunksPrefetchBytes(userID string) int {
+	return o.getOverridesForUser(userID).StoreGatewayChunksPrefetchBytes
+}
+
+// StoreGatewayBlocksQueryLookback returns how far back in time the store-gateway queries the blocks of a given user.
+// 0 means no limit.
+func (o *Overrides) StoreGatewayBlocksQueryLookback(userID string) time.Duration {
+	return time.Duration(o.getOverridesForUser(userID).StoreGatewayBlocksQueryLookback)
+}
+
 // MaxHAClusters returns maximum number of clusters that HA tracker will track for a user.
 func (o *Overrides) MaxHAClusters(user string) int {
 	return o.getOverridesForUser(user).HAMaxClusters
@@ -1017,6 +1229,15 @@ func (o *Overrides) AlertmanagerMaxGrafanaConfigSize(userID string) int {
 	return o.getOverridesForUser(userID).AlertmanagerMaxGrafanaConfigSizeBytes
 }
 
+// AlertmanagerAlertLabelValidationScheme returns the validation scheme of the label names and values
+// of the alerts for the given user.
+func (o *Overrides) AlertmanagerAlertLabelValidationScheme(userID string) model.ValidationScheme {
+	if o.getOverridesForUser(userID).AlertmanagerAlertLabelValidationScheme == AlertLabelValidationLegacy {
+		return model.LegacyValidation
+	}
+	return model.UTF8Validation
+}
+
 func (o *Overrides) AlertmanagerMaxConfigSize(userID string) int {
 	return o.getOverridesForUser(userID).AlertmanagerMaxConfigSizeBytes
 }
@@ -1049,6 +1270,22 @@ func (o *Overrides) AlertmanagerMaxAlertsSizeBytes(userID string) int {
 	return o.getOverridesForUser(userID).AlertmanagerMaxAlertsSizeBytes
 }
 
+// AlertmanagerMaxAlertsPerNotification returns the maximum number of alerts sent in a single notification
+// by the given receiver. Limits are returned in the following order:
+// 1. Per tenant limit for the given receiver.
+// 2. Default limit for the given receiver.
+// 3. Per tenant limit set by AlertmanagerMaxAlertsPerNotification
+// 4. Default limit set by AlertmanagerMaxAlertsPerNotification
+func (o *Overrides) AlertmanagerMaxAlertsPerNotification(userID, receiver string) int {
+	u := o.getOverridesForUser(userID)
+
+	if receiverLimit, ok := u.AlertmanagerMaxAlertsPerNotificationByReceiver.data[receiver]; ok {
+		return receiverLimit
+	}
+
+	return u.AlertmanagerMaxAlertsPerNotification
+}
+
 func (o *Overrides) ResultsCacheTTL(user string) time.Duration {
 	return time.Duration(o.getOverridesForUser(user).ResultsCacheTTL)
 }
@@ -1085,6 +1322,22 @@ func (o *Overrides) AlignQueriesWithStep(userID string) bool {
 	return o.getOverridesForUser(userID).AlignQueriesWithStep
 }
 
+// MaxQueryBytesPerDay returns the maximum number of bytes the tenant's queries can fetch per day.
+func (o *Overrides) MaxQueryBytesPerDay(userID string) int {
+	return o.getOverridesForUser(userID).MaxQueryBytesPerDay
+}
+
+// SplitQueriesByIntervalTimezone returns the IANA timezone name used to align the range queries split boundaries.
+// Empty means UTC.
+func (o *Overrides) SplitQueriesByIntervalTimezone(userID string) string {
+	return o.getOverridesForUser(userID).SplitQueriesByIntervalTimezone
+}
+
+// MaxQueryPriority returns the maximum priority the tenant's queries can be assigned through a request header.
+func (o *Overrides) MaxQueryPriority(userID string) int {
+	return o.getOverridesForUser(userID).MaxQueryPriority
+}
+
 // IngestStorageReadConsistency returns the default read consistency for the tenant.
 func (o *Overrides) IngestStorageReadConsistency(userID string) string {
 	return o.getOverridesForUser(userID).IngestStorageReadConsistency
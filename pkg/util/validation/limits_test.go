@@ -1076,6 +1076,99 @@ metric_relabel_configs:
 			cfg:         `ingest_storage_read_consistency: xyz`,
 			expectedErr: errInvalidIngestStorageReadConsistency.Error(),
 		},
+		"should pass on valid split_queries_by_interval_timezone": {
+			cfg:         `split_queries_by_interval_timezone: Europe/Berlin`,
+			expectedErr: "",
+		},
+		"should fail on invalid split_queries_by_interval_timezone": {
+			cfg:         `split_queries_by_interval_timezone: Mars/Olympus_Mons`,
+			expectedErr: "invalid value for -query-frontend.split-queries-by-interval-timezone: unknown time zone Mars/Olympus_Mons",
+		},
+		"should pass on compactor_first_level_only_until date": {
+			cfg:         `compactor_first_level_only_until: 2024-10-01`,
+			expectedErr: "",
+		},
+		"should pass on compactor_first_level_only_until timestamp": {
+			cfg:         `compactor_first_level_only_until: 2024-10-01T12:00:00Z`,
+			expectedErr: "",
+		},
+		"should fail on invalid compactor_first_level_only_until": {
+			cfg:         `compactor_first_level_only_until: next week`,
+			expectedErr: "invalid value for -compactor.first-level-only-until",
+		},
+		"should pass on valid metric_registry": {
+			cfg: `
+metric_registry:
+  - name: http_requests_total
+    type: counter
+    allowed_labels: [method, status]
+  - name: up
+`,
+			expectedErr: "",
+		},
+		"should fail on metric_registry with invalid metric name": {
+			cfg: `
+metric_registry:
+  - name: 1invalid
+`,
+			expectedErr: `invalid metric_registry: invalid metric name "1invalid"`,
+		},
+		"should fail on metric_registry with duplicated metric": {
+			cfg: `
+metric_registry:
+  - name: up
+  - name: up
+`,
+			expectedErr: `invalid metric_registry: metric "up" is declared multiple times`,
+		},
+		"should fail on metric_registry with unsupported type": {
+			cfg: `
+metric_registry:
+  - name: up
+    type: foo
+`,
+			expectedErr: `invalid metric_registry: metric "up" has unsupported type "foo"`,
+		},
+		"should pass on valid blocked_series": {
+			cfg: `
+blocked_series:
+  - match: 'http_requests_total{job="garbage"}'
+  - match: '{__name__=~"debug_.*"}'
+`,
+			expectedErr: "",
+		},
+		"should fail on blocked_series with invalid series selector": {
+			cfg: `
+blocked_series:
+  - match: 'http_requests_total{'
+`,
+			expectedErr: `invalid blocked_series: invalid series selector "http_requests_total{"`,
+		},
+		"should pass on valid ruler_namespace_scopes": {
+			cfg: `
+ruler_namespace_scopes:
+  - name: team-a
+    namespace_prefixes: ["team-a/", "shared/"]
+`,
+			expectedErr: "",
+		},
+		"should fail on ruler_namespace_scopes with duplicated scope": {
+			cfg: `
+ruler_namespace_scopes:
+  - name: team-a
+    namespace_prefixes: ["team-a/"]
+  - name: team-a
+    namespace_prefixes: ["shared/"]
+`,
+			expectedErr: `invalid ruler_namespace_scopes: duplicated scope "team-a"`,
+		},
+		"should fail on ruler_namespace_scopes without namespace prefixes": {
+			cfg: `
+ruler_namespace_scopes:
+  - name: team-a
+`,
+			expectedErr: `invalid ruler_namespace_scopes: scope "team-a" has no namespace prefixes`,
+		},
 	}
 
 	for testName, testData := range tests {
@@ -0,0 +1,62 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package validation
+
+import (
+	"fmt"
+
+	"github.com/prometheus/common/model"
+)
+
+// MetricDefinition is a metric declared by a tenant in its metric registry.
+type MetricDefinition struct {
+	// Name is the metric family name.
+	Name string `yaml:"name" json:"name"`
+
+	// Type is the expected metric type. If empty, any type is accepted.
+	Type model.MetricType `yaml:"type,omitempty" json:"type,omitempty"`
+
+	// AllowedLabels is the list of label names allowed on the series of this metric. If empty, any label is accepted.
+	AllowedLabels []string `yaml:"allowed_labels,omitempty" json:"allowed_labels,omitempty"`
+}
+
+var validMetricDefinitionTypes = map[model.MetricType]struct{}{
+	model.MetricTypeCounter:        {},
+	model.MetricTypeGauge:          {},
+	model.MetricTypeHistogram:      {},
+	model.MetricTypeGaugeHistogram: {},
+	model.MetricTypeSummary:        {},
+	model.MetricTypeInfo:           {},
+	model.MetricTypeStateset:       {},
+	model.MetricTypeUnknown:        {},
+}
+
+func validateMetricRegistry(definitions []*MetricDefinition) error {
+	names := make(map[string]struct{}, len(definitions))
+
+	for _, def := range definitions {
+		if def == nil {
+			return fmt.Errorf("invalid metric_registry: empty metric definition")
+		}
+		if !model.IsValidMetricName(model.LabelValue(def.Name)) {
+			return fmt.Errorf("invalid metric_registry: invalid metric name %q", def.Name)
+		}
+		if _, ok := names[def.Name]; ok {
+			return fmt.Errorf("invalid metric_registry: metric %q is declared multiple times", def.Name)
+		}
+		names[def.Name] = struct{}{}
+
+		if def.Type != "" {
+			if _, ok := validMetricDefinitionTypes[def.Type]; !ok {
+				return fmt.Errorf("invalid metric_registry: metric %q has unsupported type %q", def.Name, def.Type)
+			}
+		}
+		for _, name := range def.AllowedLabels {
+			if !model.LabelName(name).IsValid() {
+				return fmt.Errorf("invalid metric_registry: metric %q has invalid allowed label name %q", def.Name, name)
+			}
+		}
+	}
+
+	return nil
+}
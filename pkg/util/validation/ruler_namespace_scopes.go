@@ -0,0 +1,36 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package validation
+
+import (
+	"fmt"
+)
+
+// RulerNamespaceScope restricts the rule namespaces which can be accessed through the ruler's
+// configuration API by the requests carrying the scope name.
+type RulerNamespaceScope struct {
+	// Name is the value of the scope header set on the requests.
+	Name string `yaml:"name" json:"name"`
+
+	// NamespacePrefixes are the prefixes of the rule namespaces the scope is allowed to access.
+	NamespacePrefixes []string `yaml:"namespace_prefixes" json:"namespace_prefixes"`
+}
+
+func validateRulerNamespaceScopes(scopes []*RulerNamespaceScope) error {
+	names := make(map[string]struct{}, len(scopes))
+	for _, s := range scopes {
+		if s == nil || s.Name == "" {
+			return fmt.Errorf("invalid ruler_namespace_scopes: the scope name is required")
+		}
+		if _, ok := names[s.Name]; ok {
+			return fmt.Errorf("invalid ruler_namespace_scopes: duplicated scope %q", s.Name)
+		}
+		names[s.Name] = struct{}{}
+
+		if len(s.NamespacePrefixes) == 0 {
+			return fmt.Errorf("invalid ruler_namespace_scopes: scope %q has no namespace prefixes", s.Name)
+		}
+	}
+
+	return nil
+}
@@ -365,6 +365,12 @@ func getFieldCustomType(t reflect.Type) (string, bool) {
 		return "relabel_config...", true
 	case reflect.TypeOf([]*validation.BlockedQuery{}).String():
 		return "blocked_queries_config...", true
+	case reflect.TypeOf([]*validation.MetricDefinition{}).String():
+		return "metric_registry_config...", true
+	case reflect.TypeOf([]*validation.BlockedSeries{}).String():
+		return "blocked_series_config...", true
+	case reflect.TypeOf([]*validation.RulerNamespaceScope{}).String():
+		return "ruler_namespace_scopes_config...", true
 	case reflect.TypeOf(asmodel.CustomTrackersConfig{}).String():
 		return "map of tracker name (string) to matcher (string)", true
 	default:
@@ -451,6 +457,12 @@ func getCustomFieldType(t reflect.Type) (string, bool) {
 		return "relabel_config...", true
 	case reflect.TypeOf([]*validation.BlockedQuery{}).String():
 		return "blocked_queries_config...", true
+	case reflect.TypeOf([]*validation.MetricDefinition{}).String():
+		return "metric_registry_config...", true
+	case reflect.TypeOf([]*validation.BlockedSeries{}).String():
+		return "blocked_series_config...", true
+	case reflect.TypeOf([]*validation.RulerNamespaceScope{}).String():
+		return "ruler_namespace_scopes_config...", true
 	case reflect.TypeOf(asmodel.CustomTrackersConfig{}).String():
 		return "map of tracker name (string) to matcher (string)", true
 	default:
@@ -484,6 +496,12 @@ func ReflectType(typ string) reflect.Type {
 		return reflect.TypeOf([]*relabel.Config{})
 	case "blocked_queries_config...":
 		return reflect.TypeOf([]*validation.BlockedQuery{})
+	case "metric_registry_config...":
+		return reflect.TypeOf([]*validation.MetricDefinition{})
+	case "blocked_series_config...":
+		return reflect.TypeOf([]*validation.BlockedSeries{})
+	case "ruler_namespace_scopes_config...":
+		return reflect.TypeOf([]*validation.RulerNamespaceScope{})
 	case "map of string to float64":
 		return reflect.TypeOf(validation.LimitsMap[float64]{})
 	case "map of string to int":
@@ -0,0 +1,203 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package main
+
+import (
+	"slices"
+	"sort"
+	"time"
+)
+
+// gapCause is the most likely reason why data is missing in a gap.
+type gapCause string
+
+const (
+	// causeRetention is used for gaps older than the tenant's blocks retention period:
+	// the data may have been deleted by the compactor.
+	causeRetention gapCause = "retention"
+
+	// causeBlockAbsence is used for gaps overlapping time ranges not covered by any block
+	// nor by the ingesters: the data may be in a block which hasn't been uploaded or has
+	// been deleted.
+	causeBlockAbsence gapCause = "block-absence"
+
+	// causeIngestionOutage is used for gaps in time ranges covered by blocks or ingesters:
+	// the samples have never been ingested.
+	causeIngestionOutage gapCause = "ingestion-outage"
+)
+
+// interval is a time range, in milliseconds, with inclusive start and exclusive end.
+type interval struct {
+	Start int64 `json:"start"`
+	End   int64 `json:"end"`
+}
+
+type gap struct {
+	Start int64    `json:"start"`
+	End   int64    `json:"end"`
+	Cause gapCause `json:"cause"`
+}
+
+type seriesGaps struct {
+	Labels         string `json:"labels"`
+	Samples        int    `json:"samples"`
+	IntervalMillis int64  `json:"intervalMillis"`
+	Gaps           []gap  `json:"gaps"`
+}
+
+type report struct {
+	MinTime          int64            `json:"minTime"`
+	MaxTime          int64            `json:"maxTime"`
+	BlocksCoverage   []interval       `json:"blocksCoverage"`
+	IngestersMinTime int64            `json:"ingestersMinTime,omitempty"`
+	TotalSeries      int              `json:"totalSeries"`
+	TotalGaps        map[gapCause]int `json:"totalGaps"`
+	Series           []seriesGaps     `json:"series"`
+}
+
+type analyzer struct {
+	minTime, maxTime int64
+
+	// scrapeInterval is the expected interval between samples. If 0, the most common
+	// interval of each series is used.
+	scrapeInterval time.Duration
+
+	// retentionCutoff is the timestamp before which the data may have been deleted because
+	// of the retention. 0 if retention is disabled.
+	retentionCutoff int64
+
+	// blocks are the time ranges covered by the tenant's blocks.
+	blocks []interval
+
+	// ingestersMinTime is the minimum timestamp of the samples returned by the ingesters,
+	// which are assumed to cover the time range up to maxTime. 0 if no ingester returned data.
+	ingestersMinTime int64
+}
+
+// analyze detects the gaps in the input series, whose timestamps must be sorted, and attributes each of them to a cause.
+func (a *analyzer) analyze(series map[string][]int64) report {
+	res := report{
+		MinTime:          a.minTime,
+		MaxTime:          a.maxTime,
+		BlocksCoverage:   mergeIntervals(a.blocks),
+		IngestersMinTime: a.ingestersMinTime,
+		TotalSeries:      len(series),
+		TotalGaps:        map[gapCause]int{},
+	}
+
+	covered := mergeIntervals(a.blocks)
+	if a.ingestersMinTime > 0 {
+		covered = mergeIntervals(append(covered, interval{Start: a.ingestersMinTime, End: a.maxTime + 1}))
+	}
+
+	for lbls, timestamps := range series {
+		if len(timestamps) < 2 {
+			continue
+		}
+
+		step := a.scrapeInterval.Milliseconds()
+		if step <= 0 {
+			step = mostCommonInterval(timestamps)
+		}
+
+		// Tolerate some jitter in the interval between samples.
+		threshold := step + step/2
+
+		var gaps []gap
+		for i := 1; i < len(timestamps); i++ {
+			if timestamps[i]-timestamps[i-1] <= threshold {
+				continue
+			}
+
+			g := gap{Start: timestamps[i-1], End: timestamps[i]}
+			g.Cause = a.cause(g.Start, g.End, covered)
+			gaps = append(gaps, g)
+			res.TotalGaps[g.Cause]++
+		}
+
+		if len(gaps) > 0 {
+			res.Series = append(res.Series, seriesGaps{Labels: lbls, Samples: len(timestamps), IntervalMillis: step, Gaps: gaps})
+		}
+	}
+
+	sort.Slice(res.Series, func(i, j int) bool {
+		return res.Series[i].Labels < res.Series[j].Labels
+	})
+
+	return res
+}
+
+// cause returns the most likely cause of a gap between start and end, given the
+// time ranges covered by the blocks and the ingesters.
+func (a *analyzer) cause(start, end int64, covered []interval) gapCause {
+	if a.retentionCutoff > 0 && end <= a.retentionCutoff {
+		return causeRetention
+	}
+
+	// The samples surrounding the gap are in the data, so only the time range in between must be covered.
+	for _, c := range covered {
+		if c.Start <= start && c.End >= end {
+			return causeIngestionOutage
+		}
+	}
+	return causeBlockAbsence
+}
+
+// mergeIntervals returns the sorted union of the input intervals.
+func mergeIntervals(in []interval) []interval {
+	if len(in) == 0 {
+		return nil
+	}
+
+	sorted := slices.Clone(in)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Start < sorted[j].Start
+	})
+
+	out := []interval{sorted[0]}
+	for _, i := range sorted[1:] {
+		last := &out[len(out)-1]
+		if i.Start <= last.End {
+			last.End = max(last.End, i.End)
+			continue
+		}
+		out = append(out, i)
+	}
+	return out
+}
+
+// mostCommonInterval returns the most common difference between consecutive timestamps.
+func mostCommonInterval(timestamps []int64) int64 {
+	counts := map[int64]int{}
+	for i := 1; i < len(timestamps); i++ {
+		counts[timestamps[i]-timestamps[i-1]]++
+	}
+
+	var res int64
+	best := 0
+	for diff, count := range counts {
+		if count > best || (count == best && diff < res) {
+			res, best = diff, count
+		}
+	}
+	return res
+}
+
+// mergeTimestamps merges two sorted slices of timestamps, removing duplicates.
+func mergeTimestamps(a, b []int64) []int64 {
+	out := make([]int64, 0, len(a)+len(b))
+	for len(a) > 0 || len(b) > 0 {
+		var t int64
+		switch {
+		case len(b) == 0 || (len(a) > 0 && a[0] <= b[0]):
+			t, a = a[0], a[1:]
+		default:
+			t, b = b[0], b[1:]
+		}
+
+		if len(out) == 0 || out[len(out)-1] != t {
+			out = append(out, t)
+		}
+	}
+	return out
+}
@@ -0,0 +1,55 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAnalyzer_analyze(t *testing.T) {
+	a := &analyzer{
+		minTime:         0,
+		maxTime:         1000,
+		retentionCutoff: 150,
+		blocks:          []interval{{Start: 0, End: 400}, {Start: 400, End: 600}},
+		// Ingesters cover the time range from 800 to the end.
+		ingestersMinTime: 800,
+	}
+
+	res := a.analyze(map[string][]int64{
+		`{series="complete"}`:      {0, 10, 20, 30, 40, 50},
+		`{series="retention"}`:     {0, 10, 20, 100, 110, 120},
+		`{series="outage"}`:        {300, 310, 320, 500, 510, 520},
+		`{series="missing-block"}`: {580, 590, 600, 810, 820, 830},
+		`{series="single-sample"}`: {0},
+	})
+
+	assert.Equal(t, 5, res.TotalSeries)
+	assert.Equal(t, []interval{{Start: 0, End: 600}}, res.BlocksCoverage)
+	assert.Equal(t, map[gapCause]int{causeRetention: 1, causeIngestionOutage: 1, causeBlockAbsence: 1}, res.TotalGaps)
+	assert.Equal(t, []seriesGaps{
+		{Labels: `{series="missing-block"}`, Samples: 6, IntervalMillis: 10, Gaps: []gap{{Start: 600, End: 810, Cause: causeBlockAbsence}}},
+		{Labels: `{series="outage"}`, Samples: 6, IntervalMillis: 10, Gaps: []gap{{Start: 320, End: 500, Cause: causeIngestionOutage}}},
+		{Labels: `{series="retention"}`, Samples: 6, IntervalMillis: 10, Gaps: []gap{{Start: 20, End: 100, Cause: causeRetention}}},
+	}, res.Series)
+}
+
+func TestMergeIntervals(t *testing.T) {
+	assert.Nil(t, mergeIntervals(nil))
+	assert.Equal(t, []interval{{Start: 0, End: 30}, {Start: 40, End: 50}}, mergeIntervals([]interval{
+		{Start: 40, End: 50},
+		{Start: 10, End: 30},
+		{Start: 0, End: 20},
+	}))
+}
+
+func TestMergeTimestamps(t *testing.T) {
+	assert.Equal(t, []int64{1, 2, 3, 4, 5}, mergeTimestamps([]int64{1, 3, 5}, []int64{2, 3, 4}))
+	assert.Equal(t, []int64{1, 2}, mergeTimestamps(nil, []int64{1, 2}))
+}
+
+func TestMostCommonInterval(t *testing.T) {
+	assert.Equal(t, int64(15), mostCommonInterval([]int64{0, 15, 30, 45, 100, 115}))
+}
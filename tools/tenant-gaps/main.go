@@ -0,0 +1,294 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"syscall"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/grafana/dskit/flagext"
+	"github.com/grafana/dskit/user"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql/parser"
+	"github.com/prometheus/prometheus/tsdb"
+	"github.com/prometheus/prometheus/tsdb/chunkenc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	"github.com/grafana/mimir/pkg/ingester/client"
+	"github.com/grafana/mimir/pkg/mimirpb"
+	"github.com/grafana/mimir/pkg/storage/bucket"
+	"github.com/grafana/mimir/pkg/storage/tsdb/block"
+	"github.com/grafana/mimir/pkg/util/listblocks"
+)
+
+type config struct {
+	bucket         bucket.Config
+	userID         string
+	selector       string
+	minTime        flagext.Time
+	maxTime        flagext.Time
+	ingesters      flagext.StringSliceCSV
+	blocksDir      string
+	scrapeInterval time.Duration
+	retention      time.Duration
+}
+
+func (c *config) registerFlags(f *flag.FlagSet) {
+	c.bucket.RegisterFlags(f)
+	f.StringVar(&c.userID, "user", "", "Tenant to analyze.")
+	f.StringVar(&c.selector, "select", "", "PromQL metric selector of the series to analyze (e.g. '{__name__=\"up\"}').")
+	f.Var(&c.minTime, "mint", "Minimum timestamp to consider (default: 24h before -maxt).")
+	f.Var(&c.maxTime, "maxt", "Maximum timestamp to consider (default: now).")
+	f.Var(&c.ingesters, "ingesters", "Comma-separated list of ingester gRPC addresses to query. If empty, only blocks are analyzed.")
+	f.StringVar(&c.blocksDir, "blocks-dir", "./blocks", "Local directory where the blocks are downloaded. Blocks already in the directory are not downloaded again.")
+	f.DurationVar(&c.scrapeInterval, "scrape-interval", 0, "Expected interval between samples. If 0, the most common interval of each series is used.")
+	f.DurationVar(&c.retention, "retention", 0, "Blocks retention period of the tenant. Gaps older than the retention are attributed to the retention. 0 to disable.")
+}
+
+func (c *config) validate() error {
+	if c.userID == "" {
+		return errors.New("no tenant specified")
+	}
+	if c.selector == "" {
+		return errors.New("no selector specified")
+	}
+	if time.Time(c.minTime).After(time.Time(c.maxTime)) {
+		return errors.New("minimum timestamp is greater than maximum timestamp")
+	}
+	return nil
+}
+
+func main() {
+	// Clean up all flags registered via init() methods of 3rd-party libraries.
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+
+	cfg := config{}
+	cfg.registerFlags(flag.CommandLine)
+
+	if err := flagext.ParseFlagsWithoutArguments(flag.CommandLine); err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(1)
+	}
+
+	if time.Time(cfg.maxTime).IsZero() {
+		cfg.maxTime = flagext.Time(time.Now())
+	}
+	if time.Time(cfg.minTime).IsZero() {
+		cfg.minTime = flagext.Time(time.Time(cfg.maxTime).Add(-24 * time.Hour))
+	}
+
+	if err := cfg.validate(); err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(1)
+	}
+
+	logger := log.NewLogfmtLogger(os.Stderr)
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT)
+	defer cancel()
+
+	res, err := run(ctx, cfg, logger)
+	if err != nil {
+		level.Error(logger).Log("msg", "failed to analyze gaps", "err", err)
+		os.Exit(1)
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(res); err != nil {
+		level.Error(logger).Log("msg", "failed to encode the report", "err", err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, cfg config, logger log.Logger) (report, error) {
+	matchers, err := parser.ParseMetricSelector(cfg.selector)
+	if err != nil {
+		return report{}, fmt.Errorf("failed to parse the selector: %w", err)
+	}
+
+	a := &analyzer{
+		minTime:        time.Time(cfg.minTime).UnixMilli(),
+		maxTime:        time.Time(cfg.maxTime).UnixMilli(),
+		scrapeInterval: cfg.scrapeInterval,
+	}
+	if cfg.retention > 0 {
+		a.retentionCutoff = time.Now().Add(-cfg.retention).UnixMilli()
+	}
+
+	series := map[string][]int64{}
+	add := func(lbls labels.Labels, timestamps []int64) {
+		key := lbls.String()
+		series[key] = mergeTimestamps(series[key], timestamps)
+	}
+
+	blocks, err := readBlocks(ctx, cfg, a.minTime, a.maxTime, matchers, add, logger)
+	if err != nil {
+		return report{}, err
+	}
+	a.blocks = blocks
+
+	for _, addr := range cfg.ingesters {
+		minT, err := readIngester(ctx, addr, cfg.userID, a.minTime, a.maxTime, matchers, add)
+		if err != nil {
+			return report{}, fmt.Errorf("failed to query ingester %s: %w", addr, err)
+		}
+		if minT > 0 && (a.ingestersMinTime == 0 || minT < a.ingestersMinTime) {
+			a.ingestersMinTime = minT
+		}
+	}
+
+	return a.analyze(series), nil
+}
+
+// readBlocks downloads the tenant's blocks overlapping the time range and reads the timestamps of the
+// samples of the matching series. It returns the time ranges covered by the blocks.
+func readBlocks(ctx context.Context, cfg config, minT, maxT int64, matchers []*labels.Matcher, add func(labels.Labels, []int64), logger log.Logger) ([]interval, error) {
+	bkt, err := bucket.NewClient(ctx, cfg.bucket, "bucket", logger, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the bucket client: %w", err)
+	}
+
+	metas, _, _, err := listblocks.LoadMetaFilesAndMarkers(ctx, bkt, cfg.userID, false, time.Time{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the blocks: %w", err)
+	}
+
+	userBkt := bucket.NewUserBucketClient(cfg.userID, bkt, nil)
+
+	var coverage []interval
+	for _, meta := range listblocks.SortBlocks(metas) {
+		if meta.MinTime > maxT || meta.MaxTime <= minT {
+			continue
+		}
+		coverage = append(coverage, interval{Start: meta.MinTime, End: meta.MaxTime})
+
+		dir := filepath.Join(cfg.blocksDir, cfg.userID, meta.ULID.String())
+		if _, err := os.Stat(dir); errors.Is(err, os.ErrNotExist) {
+			level.Info(logger).Log("msg", "downloading block", "block", meta.ULID.String())
+			if err := block.Download(ctx, logger, userBkt, meta.ULID, dir); err != nil {
+				return nil, fmt.Errorf("failed to download block %s: %w", meta.ULID.String(), err)
+			}
+		}
+
+		if err := readBlock(ctx, dir, minT, maxT, matchers, add, logger); err != nil {
+			return nil, fmt.Errorf("failed to read block %s: %w", meta.ULID.String(), err)
+		}
+	}
+
+	return coverage, nil
+}
+
+func readBlock(ctx context.Context, dir string, minT, maxT int64, matchers []*labels.Matcher, add func(labels.Labels, []int64), logger log.Logger) error {
+	b, err := tsdb.OpenBlock(logger, dir, nil)
+	if err != nil {
+		return err
+	}
+	defer b.Close()
+
+	q, err := tsdb.NewBlockQuerier(b, minT, maxT)
+	if err != nil {
+		return err
+	}
+	defer q.Close()
+
+	set := q.Select(ctx, true, nil, matchers...)
+	var it chunkenc.Iterator
+	for set.Next() {
+		s := set.At()
+		it = s.Iterator(it)
+
+		var timestamps []int64
+		for it.Next() != chunkenc.ValNone {
+			timestamps = append(timestamps, it.AtT())
+		}
+		if err := it.Err(); err != nil {
+			return err
+		}
+
+		add(s.Labels(), timestamps)
+	}
+	return set.Err()
+}
+
+// readIngester queries the ingester for the matching series and reads the timestamps of their samples.
+// It returns the minimum timestamp of the samples returned by the ingester, or 0 if no sample was returned.
+func readIngester(ctx context.Context, addr, userID string, minT, maxT int64, matchers []*labels.Matcher, add func(labels.Labels, []int64)) (int64, error) {
+	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+
+	ctx, err = user.InjectIntoGRPCRequest(user.InjectOrgID(ctx, userID))
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := client.ToQueryRequest(model.Time(minT), model.Time(maxT), matchers)
+	if err != nil {
+		return 0, err
+	}
+
+	stream, err := client.NewIngesterClient(conn).QueryStream(ctx, req)
+	if err != nil {
+		return 0, err
+	}
+
+	var ingesterMinT int64
+	for {
+		resp, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+
+		for _, s := range resp.Chunkseries {
+			lbls := mimirpb.FromLabelAdaptersToLabels(s.Labels)
+			chunks, err := client.FromChunks(lbls, s.Chunks)
+			if err != nil {
+				return 0, err
+			}
+
+			var timestamps []int64
+			for _, c := range chunks {
+				it := c.Data.NewIterator(nil)
+				for it.Scan() != chunkenc.ValNone {
+					if t := it.Timestamp(); t >= minT && t <= maxT {
+						timestamps = append(timestamps, t)
+					}
+				}
+				if err := it.Err(); err != nil {
+					return 0, err
+				}
+			}
+
+			// Chunks may overlap, so timestamps must be sorted and deduplicated.
+			slices.Sort(timestamps)
+			timestamps = slices.Compact(timestamps)
+			if len(timestamps) > 0 && (ingesterMinT == 0 || timestamps[0] < ingesterMinT) {
+				ingesterMinT = timestamps[0]
+			}
+
+			add(lbls, timestamps)
+		}
+	}
+
+	return ingesterMinT, nil
+}